@@ -18,6 +18,8 @@ package algo
 
 import (
 	"container/heap"
+	"math"
+	"math/bits"
 	"sort"
 
 	"github.com/dgraph-io/dgraph/codec"
@@ -158,6 +160,9 @@ func DifferencePacked(u, v *pb.UidPack) *pb.UidPack {
 
 		return nil
 	}
+	if hasBitmaps(v) {
+		return differenceWithBitmaps(u, v)
+	}
 
 	result := codec.Encoder{BlockSize: int(u.BlockSize)}
 
@@ -229,11 +234,50 @@ func DifferencePacked(u, v *pb.UidPack) *pb.UidPack {
 	return result.Done()
 }
 
+// differenceWithBitmaps returns the uids of u that aren't in v. The uids are looked up in the
+// bitmap blocks of v without unpacking them, and the other blocks of v are unpacked once.
+func differenceWithBitmaps(u, v *pb.UidPack) *pb.UidPack {
+	result := codec.Encoder{BlockSize: int(u.BlockSize)}
+	vDec := codec.Decoder{Pack: v}
+	// vuids has the uids of the block of v at vIdx, if it isn't a bitmap.
+	var vuids []uint64
+	vIdx := -1
+
+	for uDec := codec.NewDecoder(u); uDec.Valid(); uDec.Next() {
+		for _, uid := range uDec.Uids() {
+			vDec.SeekBlock(uid)
+			block := vDec.Block()
+			switch {
+			case block == nil || uid < block.Base:
+				result.Add(uid)
+			case codec.IsBitmap(block):
+				if !codec.BitmapContains(block, uid) {
+					result.Add(uid)
+				}
+			default:
+				if vIdx != vDec.BlockIdx() {
+					vuids, vIdx = vDec.UnpackBlock(), vDec.BlockIdx()
+				}
+				i := sort.Search(len(vuids), func(i int) bool { return vuids[i] >= uid })
+				if i == len(vuids) || vuids[i] != uid {
+					result.Add(uid)
+				}
+			}
+		}
+	}
+	return result.Done()
+}
+
 // MergeSortedPacked merges already sorted UidPack objects into a single UidPack.
 func MergeSortedPacked(lists []*pb.UidPack) *pb.UidPack {
 	if len(lists) == 0 {
 		return nil
 	}
+	for _, l := range lists {
+		if l != nil && hasBitmaps(l) {
+			return mergeWithBitmaps(lists)
+		}
+	}
 
 	h := &uint64Heap{}
 	heap.Init(h)
@@ -307,6 +351,122 @@ func MergeSortedPacked(lists []*pb.UidPack) *pb.UidPack {
 	return result.Done()
 }
 
+// mergeWindow is the number of uids merged at a time by mergeWithBitmaps.
+const mergeWindow = 1 << 12
+
+// mergeWithBitmaps merges the lists a window of mergeWindow uids at a time. The uids of a window
+// are set in a bitmap, into which the bitmap blocks of the lists are ORed without unpacking them,
+// and are then read back in order. Each window starts at the smallest uid that hasn't been
+// merged yet, so the gaps between the uids of the lists are skipped.
+func mergeWithBitmaps(lists []*pb.UidPack) *pb.UidPack {
+	var cursors []*mergeCursor
+	blockSize := 0
+	for _, l := range lists {
+		if l == nil {
+			continue
+		}
+		if blockSize == 0 {
+			blockSize = int(l.BlockSize)
+		}
+		cursors = append(cursors, &mergeCursor{dec: codec.Decoder{Pack: l}, idx: -1})
+	}
+
+	result := codec.Encoder{BlockSize: blockSize}
+	window := make([]byte, mergeWindow/8)
+	var start uint64
+	for {
+		lo, found := uint64(math.MaxUint64), false
+		for _, c := range cursors {
+			if uid, ok := c.next(start); ok && uid <= lo {
+				lo, found = uid, true
+			}
+		}
+		if !found {
+			break
+		}
+		hi := lo + mergeWindow - 1
+		if hi < lo {
+			hi = math.MaxUint64
+		}
+
+		for _, c := range cursors {
+			c.set(window, lo, hi)
+		}
+		for i, b := range window {
+			for b != 0 {
+				result.Add(lo + uint64(i*8+bits.TrailingZeros8(b)))
+				b &= b - 1
+			}
+			window[i] = 0
+		}
+
+		if hi == math.MaxUint64 {
+			break
+		}
+		start = hi + 1
+	}
+	return result.Done()
+}
+
+// mergeCursor reads the blocks of a list merged by mergeWithBitmaps in order.
+type mergeCursor struct {
+	dec codec.Decoder
+	// uids has the uids of the block at idx, if it isn't a bitmap.
+	uids []uint64
+	idx  int
+}
+
+// unpack returns the uids of the current block, which isn't a bitmap.
+func (c *mergeCursor) unpack() []uint64 {
+	if c.idx != c.dec.BlockIdx() {
+		c.uids, c.idx = c.dec.UnpackBlock(), c.dec.BlockIdx()
+	}
+	return c.uids
+}
+
+// next moves the cursor to the block of the first uid of the list that is at least from, and
+// returns that uid. It returns false if there isn't one.
+func (c *mergeCursor) next(from uint64) (uint64, bool) {
+	c.dec.SeekBlock(from)
+	for block := c.dec.Block(); block != nil; block = c.dec.NextBlock() {
+		if codec.IsBitmap(block) {
+			if uid, ok := codec.BitmapNext(block, from); ok {
+				return uid, true
+			}
+			continue
+		}
+		uids := c.unpack()
+		if i := sort.Search(len(uids), func(i int) bool { return uids[i] >= from }); i < len(uids) {
+			return uids[i], true
+		}
+	}
+	return 0, false
+}
+
+// set sets the uids of the list from lo to hi in the window, in which the i-th bit stands for the
+// uid lo+i. The cursor must be at the block of the first uid that is at least lo.
+func (c *mergeCursor) set(window []byte, lo, hi uint64) {
+	for block := c.dec.Block(); block != nil && block.Base <= hi; block = c.dec.NextBlock() {
+		if codec.IsBitmap(block) {
+			codec.BitmapOr(window, lo, hi, block)
+		} else {
+			uids := c.unpack()
+			i := sort.Search(len(uids), func(i int) bool { return uids[i] >= lo })
+			for _, uid := range uids[i:] {
+				if uid > hi {
+					break
+				}
+				off := uid - lo
+				window[off/8] |= 1 << (off % 8)
+			}
+		}
+		// The next block might only have uids after hi, so the cursor stays at this one.
+		if c.dec.PeekNextBase() > hi {
+			return
+		}
+	}
+}
+
 // IndexOfPacked finds the index of the given uid in the UidPack. If it doesn't find it,
 // it returns -1.
 func IndexOfPacked(u *pb.UidPack, uid uint64) int {
@@ -18,10 +18,13 @@ package algo
 
 import (
 	"math"
+	"math/rand"
+	"sort"
 	"testing"
 
 	"github.com/dgraph-io/dgraph/codec"
 	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/x"
 	"github.com/stretchr/testify/require"
 )
 
@@ -353,3 +356,66 @@ func TestApplyFilterUintPacked(t *testing.T) {
 	res := ApplyFilterPacked(u, func(a uint64, idx int) bool { return (l[idx] % 2) == 1 })
 	require.Equal(t, []uint64{1, 3, 5, 7, 9}, codec.Decode(res, 0))
 }
+
+// denseAndSparseUids returns sorted uids with dense runs, which are packed as bitmaps, between
+// sparse ones.
+func denseAndSparseUids(rnd *rand.Rand) []uint64 {
+	var uids []uint64
+	uid := uint64(rnd.Intn(100) + 1)
+	for len(uids) < 2000 {
+		if rnd.Intn(2) == 0 {
+			for i := 0; i < 300; i++ {
+				uid += uint64(rnd.Intn(3) + 1)
+				uids = append(uids, uid)
+			}
+		} else {
+			for i := 0; i < 20; i++ {
+				uid += uint64(rnd.Intn(100000) + 1)
+				uids = append(uids, uid)
+			}
+		}
+	}
+	return uids
+}
+
+func packWithBitmaps(t *testing.T, uids []uint64) *pb.UidPack {
+	pack := codec.Encode(uids, 256)
+	require.True(t, hasBitmaps(pack))
+	return pack
+}
+
+func TestMergeSortedPackedWithBitmaps(t *testing.T) {
+	x.WorkerConfig.UidBitmaps = true
+	defer func() { x.WorkerConfig.UidBitmaps = false }()
+
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 10; i++ {
+		a, b := denseAndSparseUids(rnd), denseAndSparseUids(rnd)
+		c := []uint64{a[0], a[len(a)/2] + 1, math.MaxUint64 - 1, math.MaxUint64}
+		packs := []*pb.UidPack{packWithBitmaps(t, a), nil, packWithBitmaps(t, b),
+			codec.Encode(c, 256)}
+		expected := MergeSorted([]*pb.List{newList(a), newList(b), newList(c)}).Uids
+		require.Equal(t, expected, codec.Decode(MergeSortedPacked(packs), 0))
+	}
+}
+
+func TestDiffPackedWithBitmaps(t *testing.T) {
+	x.WorkerConfig.UidBitmaps = true
+	defer func() { x.WorkerConfig.UidBitmaps = false }()
+
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 10; i++ {
+		a, b := denseAndSparseUids(rnd), denseAndSparseUids(rnd)
+		// Half of the uids of b are taken from a, so that some of them are removed.
+		for j := range b {
+			if j%2 == 0 {
+				b[j] = a[rnd.Intn(len(a))]
+			}
+		}
+		sort.Slice(b, func(i, j int) bool { return b[i] < b[j] })
+		b = MergeSorted([]*pb.List{newList(b)}).Uids // Removes the duplicates.
+		u, v := packWithBitmaps(t, a), packWithBitmaps(t, b)
+		expected := Difference(newList(a), newList(b)).Uids
+		require.Equal(t, expected, codec.Decode(DifferencePacked(u, v), 0))
+	}
+}
@@ -18,6 +18,7 @@ package algo
 
 import (
 	"container/heap"
+	"math/rand"
 	"sort"
 
 	"github.com/dgraph-io/dgraph/codec"
@@ -60,7 +61,9 @@ func IntersectCompressedWith(pack *pb.UidPack, afterUID uint64, v, o *pb.List) {
 
 	// Select appropriate function based on heuristics.
 	ratio := float64(m) / float64(n)
-	if ratio < 500 {
+	if hasBitmaps(pack) {
+		IntersectCompressedWithBitmaps(&dec, afterUID, v.Uids, &dst)
+	} else if ratio < 500 {
 		IntersectCompressedWithLinJump(&dec, v.Uids, &dst)
 	} else {
 		IntersectCompressedWithBin(&dec, v.Uids, &dst)
@@ -68,6 +71,43 @@ func IntersectCompressedWith(pack *pb.UidPack, afterUID uint64, v, o *pb.List) {
 	o.Uids = dst
 }
 
+// hasBitmaps returns true if some of the blocks of the pack are bitmaps.
+func hasBitmaps(pack *pb.UidPack) bool {
+	for _, block := range pack.Blocks {
+		if codec.IsBitmap(block) {
+			return true
+		}
+	}
+	return false
+}
+
+// IntersectCompressedWithBitmaps intersects the blocks of the decoder with the uids of v from
+// afterUID, one block at a time. The uids are looked up in the bitmap blocks without unpacking
+// them, and the other blocks are unpacked and intersected linearly.
+func IntersectCompressedWithBitmaps(dec *codec.Decoder, afterUID uint64, v []uint64,
+	o *[]uint64) {
+	v = v[sort.Search(len(v), func(i int) bool { return v[i] >= afterUID }):]
+	for len(v) > 0 {
+		dec.SeekBlock(v[0])
+		block := dec.Block()
+		if block == nil {
+			return
+		}
+		next := dec.PeekNextBase()
+		n := sort.Search(len(v), func(i int) bool { return v[i] >= next })
+		if codec.IsBitmap(block) {
+			for _, uid := range v[:n] {
+				if codec.BitmapContains(block, uid) {
+					*o = append(*o, uid)
+				}
+			}
+		} else {
+			IntersectWithLin(dec.UnpackBlock(), v[:n], o)
+		}
+		v = v[n:]
+	}
+}
+
 // IntersectCompressedWithLinJump performs the intersection linearly.
 func IntersectCompressedWithLinJump(dec *codec.Decoder, v []uint64, o *[]uint64) {
 	m := len(v)
@@ -418,6 +458,29 @@ func IndexOf(u *pb.List, uid uint64) int {
 	return -1
 }
 
+// Sample returns a uniformly random subset of at most n uids from u, picked using reservoir
+// sampling. The uids retain their relative order, so the result of a sorted list is sorted.
+func Sample(u *pb.List, n int, rnd *rand.Rand) *pb.List {
+	if n < 0 || len(u.Uids) <= n {
+		return u
+	}
+	reservoir := make([]int, n)
+	for i := range reservoir {
+		reservoir[i] = i
+	}
+	for i := n; i < len(u.Uids); i++ {
+		if j := rnd.Intn(i + 1); j < n {
+			reservoir[j] = i
+		}
+	}
+	sort.Ints(reservoir)
+	out := make([]uint64, 0, n)
+	for _, idx := range reservoir {
+		out = append(out, u.Uids[idx])
+	}
+	return &pb.List{Uids: out}
+}
+
 // ToUintsListForTest converts to list of uints for testing purpose only.
 func ToUintsListForTest(ul []*pb.List) [][]uint64 {
 	out := make([][]uint64, 0, len(ul))
@@ -25,6 +25,7 @@ import (
 
 	"github.com/dgraph-io/dgraph/codec"
 	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/x"
 	"github.com/stretchr/testify/require"
 )
 
@@ -523,3 +524,71 @@ func TestIntersectCompressedWithBinMissingSize(t *testing.T) {
 		}
 	}
 }
+
+func TestSample(t *testing.T) {
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	uids := make([]uint64, 0, 1000)
+	for i := 1; i <= 1000; i++ {
+		uids = append(uids, uint64(i*3))
+	}
+
+	l := Sample(newList(uids), 10, rnd)
+	require.Len(t, l.Uids, 10)
+	require.True(t, sort.SliceIsSorted(l.Uids, func(i, j int) bool { return l.Uids[i] < l.Uids[j] }))
+	for i := 1; i < len(l.Uids); i++ {
+		require.NotEqual(t, l.Uids[i-1], l.Uids[i])
+	}
+	for _, uid := range l.Uids {
+		require.True(t, IndexOf(newList(uids), uid) >= 0)
+	}
+
+	l = Sample(newList([]uint64{1, 2, 3}), 5, rnd)
+	require.Equal(t, []uint64{1, 2, 3}, l.Uids)
+
+	l = Sample(newList([]uint64{1, 2, 3}), 0, rnd)
+	require.Empty(t, l.Uids)
+}
+
+func TestSampleUniform(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	counts := make(map[uint64]int)
+	for i := 0; i < 10000; i++ {
+		l := Sample(newList([]uint64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}), 2, rnd)
+		for _, uid := range l.Uids {
+			counts[uid]++
+		}
+	}
+	// Each uid should be picked ~2000 times.
+	for uid := uint64(1); uid <= 10; uid++ {
+		require.InDelta(t, 2000, counts[uid], 250, "uid %d", uid)
+	}
+}
+
+func TestIntersectCompressedWithBitmaps(t *testing.T) {
+	x.WorkerConfig.UidBitmaps = true
+	defer func() { x.WorkerConfig.UidBitmaps = false }()
+
+	// The pack has a bitmap block of the even uids from 100 to 298, and a block of deltas.
+	var blockNums []uint64
+	for uid := uint64(100); uid < 300; uid += 2 {
+		blockNums = append(blockNums, uid)
+	}
+	blockNums = append(blockNums, 1000, 5000, 9000)
+	pack := codec.Encode(blockNums, 100)
+	defer codec.FreePack(pack)
+	require.True(t, codec.IsBitmap(pack.Blocks[0]))
+	require.False(t, codec.IsBitmap(pack.Blocks[1]))
+
+	otherNums := []uint64{1, 100, 101, 150, 297, 298, 299, 1000, 4000, 9000, 10000}
+	out := &pb.List{}
+	IntersectCompressedWith(pack, 0, newList(otherNums), out)
+	require.Equal(t, []uint64{100, 150, 298, 1000, 9000}, out.Uids)
+
+	out = &pb.List{}
+	IntersectCompressedWith(pack, 150, newList(otherNums), out)
+	require.Equal(t, []uint64{150, 298, 1000, 9000}, out.Uids)
+
+	out = &pb.List{}
+	IntersectCompressedWith(pack, 1001, newList(otherNums), out)
+	require.Equal(t, []uint64{9000}, out.Uids)
+}
@@ -359,8 +359,10 @@ var typeMap = map[string]types.TypeID{
 	"xs:boolean":         types.BoolID,
 	"xs:double":          types.FloatID,
 	"xs:float":           types.FloatID,
+	"xs:decimal":         types.DecimalID,
 	"xs:base64Binary":    types.BinaryID,
 	"geo:geojson":        types.GeoID,
+	"xs:float32vector":   types.VFloatID,
 	"http://www.w3.org/2001/XMLSchema#string":          types.StringID,
 	"http://www.w3.org/2001/XMLSchema#dateTime":        types.DateTimeID,
 	"http://www.w3.org/2001/XMLSchema#date":            types.DateTimeID,
@@ -370,6 +372,7 @@ var typeMap = map[string]types.TypeID{
 	"http://www.w3.org/2001/XMLSchema#boolean":         types.BoolID,
 	"http://www.w3.org/2001/XMLSchema#double":          types.FloatID,
 	"http://www.w3.org/2001/XMLSchema#float":           types.FloatID,
+	"http://www.w3.org/2001/XMLSchema#decimal":         types.DecimalID,
 	"http://www.w3.org/2001/XMLSchema#gYear":           types.DateTimeID,
 	"http://www.w3.org/2001/XMLSchema#gYearMonth":      types.DateTimeID,
 }
@@ -120,7 +120,7 @@ func TestSeek(t *testing.T) {
 	}
 	pack := enc.Done()
 	defer FreePack(pack)
-	dec := Decoder{Pack: pack}
+	dec := &Decoder{Pack: pack}
 
 	tests := []struct {
 		in, out uint64
@@ -169,7 +169,7 @@ func TestLinearSeek(t *testing.T) {
 	}
 	pack := enc.Done()
 	defer FreePack(pack)
-	dec := Decoder{Pack: pack}
+	dec := &Decoder{Pack: pack}
 
 	for i := 0; i < 2*N; i += 10 {
 		uids := dec.LinearSeek(uint64(i))
@@ -200,7 +200,7 @@ func TestDecoder(t *testing.T) {
 	pack := enc.Done()
 	defer FreePack(pack)
 
-	dec := Decoder{Pack: pack}
+	dec := &Decoder{Pack: pack}
 	for i := 3; i < N; i += 3 {
 		uids := dec.Seek(uint64(i), SeekStart)
 		require.Equal(t, uint64(i), uids[0])
@@ -356,3 +356,64 @@ func TestCopyUidPack(t *testing.T) {
 	copy := CopyUidPack(pack)
 	require.Equal(t, Decode(pack, 0), Decode(copy, 0))
 }
+
+func TestBitmapBlocks(t *testing.T) {
+	// The dense uids are packed as bitmaps, and the sparse ones as deltas.
+	var uids []uint64
+	for uid := uint64(1000); uid < 1600; uid += 2 {
+		uids = append(uids, uid)
+	}
+	uids = append(uids, 1<<20, 1<<21, 1<<22)
+
+	// Bitmaps are only written if the option is set.
+	pack := Encode(uids, 256)
+	require.False(t, IsBitmap(pack.Blocks[0]))
+	FreePack(pack)
+
+	x.WorkerConfig.UidBitmaps = true
+	defer func() { x.WorkerConfig.UidBitmaps = false }()
+	pack = Encode(uids, 256)
+	defer FreePack(pack)
+	require.Len(t, pack.Blocks, 2)
+	require.True(t, IsBitmap(pack.Blocks[0]))
+	require.Len(t, pack.Blocks[0].Bitmap, 64)
+	require.Empty(t, pack.Blocks[0].Deltas)
+	require.False(t, IsBitmap(pack.Blocks[1]))
+	require.Equal(t, len(uids), ExactLen(pack))
+	require.Equal(t, uids, Decode(pack, 0))
+	require.Equal(t, uids[100:], Decode(pack, 1199))
+	require.Equal(t, uids[100:], Decode(pack, 1200))
+
+	dec := &Decoder{Pack: pack}
+	require.Equal(t, uids[101:256], dec.Seek(1200, SeekCurrent))
+	require.Equal(t, uids[256:], dec.Seek(1510, SeekCurrent))
+
+	require.True(t, BitmapContains(pack.Blocks[0], 1000))
+	require.True(t, BitmapContains(pack.Blocks[0], 1510))
+	require.False(t, BitmapContains(pack.Blocks[0], 1511))
+	require.False(t, BitmapContains(pack.Blocks[0], 999))
+	require.False(t, BitmapContains(pack.Blocks[0], 1<<20))
+
+	next, ok := BitmapNext(pack.Blocks[0], 1201)
+	require.True(t, ok)
+	require.Equal(t, uint64(1202), next)
+	_, ok = BitmapNext(pack.Blocks[0], 1511)
+	require.False(t, ok)
+
+	// The even uids from 1202 to 1216 are set at the odd bits of a window starting at 1201.
+	window := make([]byte, 3)
+	BitmapOr(window, 1201, 1217, pack.Blocks[0])
+	require.Equal(t, []byte{0xaa, 0xaa, 0x00}, window)
+
+	dec = NewDecoder(pack)
+	dec.SeekBlock(1 << 20)
+	require.Equal(t, pack.Blocks[1], dec.Block())
+	require.Equal(t, uint64(1512), dec.UnpackBlock()[0])
+
+	data, err := pack.Marshal()
+	require.NoError(t, err)
+	var unmarshaled pb.UidPack
+	require.NoError(t, unmarshaled.Unmarshal(data))
+	require.Equal(t, uids, Decode(&unmarshaled, 0))
+	require.Equal(t, uids, Decode(CopyUidPack(pack), 0))
+}
@@ -167,6 +167,11 @@ func (w *RaftServer) IsPeer(ctx context.Context, rc *pb.RaftContext) (
 			return &pb.PeerResponse{Status: true}, nil
 		}
 	}
+	for _, raftIdx := range confState.Learners {
+		if rc.Id == raftIdx {
+			return &pb.PeerResponse{Status: true}, nil
+		}
+	}
 	return &pb.PeerResponse{}, nil
 }
 
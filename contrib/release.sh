@@ -23,7 +23,7 @@ mkdir $GOPATH
 PATH="$GOPATH/bin:$PATH"
 
 # The Go version used for release builds must match this version.
-GOVERSION="1.14.1"
+GOVERSION="1.18.10"
 
 # Turn off go modules by default. Only enable go modules when needed.
 export GO111MODULE=off
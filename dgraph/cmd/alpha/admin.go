@@ -22,6 +22,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/dgraph-io/dgraph/graphql/schema"
 	"github.com/dgraph-io/dgraph/graphql/web"
@@ -99,6 +100,137 @@ func drainingHandler(w http.ResponseWriter, r *http.Request, adminServer web.ISe
 		`"message": "draining mode has been set to %v"}`, enable))))
 }
 
+func maintenanceHandler(w http.ResponseWriter, r *http.Request, adminServer web.IServeGraphQL) {
+	input := map[string]interface{}{}
+	if runStr := r.URL.Query().Get("run"); runStr != "" {
+		run, err := strconv.ParseBool(runStr)
+		if err != nil {
+			x.SetStatus(w, x.ErrorInvalidRequest, "Found invalid value for the run parameter")
+			return
+		}
+		input["run"] = run
+	}
+	if deferFor := r.URL.Query().Get("defer"); deferFor != "" {
+		input["deferFor"] = deferFor
+	}
+
+	gqlReq := &schema.Request{
+		Query: `
+		mutation maintenance($input: MaintenanceInput!) {
+		  maintenance(input: $input) {
+			response {
+			  message
+			}
+			deferredUntil
+		  }
+		}`,
+		Variables: map[string]interface{}{"input": input},
+	}
+	resp := resolveWithAdminServer(gqlReq, r, adminServer)
+	if len(resp.Errors) != 0 {
+		x.SetStatus(w, x.ErrorInvalidRequest, resp.Errors[0].Message)
+		return
+	}
+	var data struct {
+		Maintenance struct {
+			Response struct {
+				Message string
+			}
+			DeferredUntil *string
+		}
+	}
+	x.Check(json.Unmarshal(resp.Data.Bytes(), &data))
+	out, err := json.Marshal(map[string]interface{}{
+		"code":          "Success",
+		"message":       data.Maintenance.Response.Message,
+		"deferredUntil": data.Maintenance.DeferredUntil,
+	})
+	x.Check(err)
+	w.Header().Set("Content-Type", "application/json")
+	x.Check2(w.Write(out))
+}
+
+// integrityHandler starts a verification of the postings on PUT and POST, optionally of the
+// predicates given as a comma separated list, and returns the report of the last one on GET.
+func integrityHandler(w http.ResponseWriter, r *http.Request, adminServer web.IServeGraphQL) {
+	var gqlReq *schema.Request
+	if r.Method == http.MethodGet {
+		gqlReq = &schema.Request{
+			Query: `
+			query {
+			  integrityReport {
+				startedAt
+				finishedAt
+				readTs
+				healthy
+				checksumError
+				error
+				predicates {
+				  predicate
+				  dataLists
+				  indexLists
+				  corruptLists
+				  missingIndexEntries
+				  danglingIndexEntries
+				  problems
+				}
+				skipped
+			  }
+			}`,
+		}
+	} else {
+		input := map[string]interface{}{}
+		if preds := r.URL.Query().Get("predicates"); preds != "" {
+			var list []interface{}
+			for _, pred := range strings.Split(preds, ",") {
+				list = append(list, strings.TrimSpace(pred))
+			}
+			input["predicates"] = list
+		}
+		gqlReq = &schema.Request{
+			Query: `
+			mutation verifyIntegrity($input: VerifyIntegrityInput) {
+			  verifyIntegrity(input: $input) {
+				response {
+				  message
+				}
+			  }
+			}`,
+			Variables: map[string]interface{}{"input": input},
+		}
+	}
+	resp := resolveWithAdminServer(gqlReq, r, adminServer)
+	if len(resp.Errors) != 0 {
+		x.SetStatus(w, x.ErrorInvalidRequest, resp.Errors[0].Message)
+		return
+	}
+	var data struct {
+		IntegrityReport json.RawMessage
+		VerifyIntegrity struct {
+			Response struct {
+				Message string
+			}
+		}
+	}
+	x.Check(json.Unmarshal(resp.Data.Bytes(), &data))
+	var out []byte
+	var err error
+	if r.Method == http.MethodGet {
+		out, err = json.Marshal(map[string]interface{}{
+			"code":   "Success",
+			"report": data.IntegrityReport,
+		})
+	} else {
+		out, err = json.Marshal(map[string]interface{}{
+			"code":    "Success",
+			"message": data.VerifyIntegrity.Response.Message,
+		})
+	}
+	x.Check(err)
+	w.Header().Set("Content-Type", "application/json")
+	x.Check2(w.Write(out))
+}
+
 func shutDownHandler(w http.ResponseWriter, r *http.Request, adminServer web.IServeGraphQL) {
 	gqlReq := &schema.Request{
 		Query: `
@@ -57,6 +57,8 @@ func keywordHandler(w http.ResponseWriter, r *http.Request) {
 	var kws keywords
 	predefined := []string{
 		"@cascade",
+		"@else",
+		"@elseif",
 		"@facets",
 		"@filter",
 		"@if",
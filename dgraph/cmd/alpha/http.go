@@ -45,6 +45,7 @@ import (
 	"github.com/golang/protobuf/jsonpb"
 	"github.com/pkg/errors"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 func allowed(method string) bool {
@@ -168,6 +169,11 @@ func queryHandler(w http.ResponseWriter, r *http.Request) {
 		x.SetStatus(w, x.ErrorInvalidRequest, err.Error())
 		return
 	}
+	isDetailedMetrics, err := query.ParseMetricsLevel(r.URL.Query().Get("metrics"))
+	if err != nil {
+		x.SetStatus(w, x.ErrorInvalidRequest, err.Error())
+		return
+	}
 
 	body := readRequest(w, r)
 	if body == nil {
@@ -208,6 +214,11 @@ func queryHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := context.WithValue(r.Context(), query.DebugKey, isDebugMode)
 	ctx = x.AttachAccessJwt(ctx, r)
 	ctx = x.AttachRemoteIP(ctx, r)
+	ctx = x.AttachQueryLimits(ctx, r)
+	var detailedMetrics *query.DetailedMetrics
+	if isDetailedMetrics {
+		ctx, detailedMetrics = query.WithDetailedMetrics(ctx)
+	}
 
 	if queryTimeout != 0 {
 		var cancel context.CancelFunc
@@ -242,10 +253,32 @@ func queryHandler(w http.ResponseWriter, r *http.Request) {
 		if isReadOnly {
 			req.ReadOnly = true
 		}
+
+		// If asOf is set, read the data as it was at that time.
+		if asOf := r.URL.Query().Get("asOf"); asOf != "" {
+			t, err := time.Parse(time.RFC3339, asOf)
+			if err != nil {
+				x.SetStatus(w, x.ErrorInvalidRequest, fmt.Sprintf(
+					"asOf should be a time in RFC3339 format, got: %s", asOf))
+				return
+			}
+			if req.StartTs, err = worker.TsAsOf(t); err != nil {
+				x.SetStatus(w, x.ErrorInvalidRequest, err.Error())
+				return
+			}
+			req.ReadOnly = true
+		}
+	} else if r.URL.Query().Get("asOf") != "" {
+		x.SetStatus(w, x.ErrorInvalidRequest, "asOf can't be used along with startTs")
+		return
 	}
 
 	// Core processing happens here.
 	resp, err := (&edgraph.Server{}).Query(ctx, &req)
+	if lerr, ok := err.(*x.LimitExceededError); ok {
+		x.SetStatusLimitExceeded(w, lerr)
+		return
+	}
 	if err != nil {
 		x.SetStatusWithData(w, x.ErrorInvalidRequest, err.Error())
 		return
@@ -254,9 +287,10 @@ func queryHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set(x.DgraphCostHeader, fmt.Sprint(resp.Metrics.NumUids["_total"]))
 
 	e := query.Extensions{
-		Txn:     resp.Txn,
-		Latency: resp.Latency,
-		Metrics: resp.Metrics,
+		Txn:      resp.Txn,
+		Latency:  resp.Latency,
+		Metrics:  resp.Metrics,
+		Detailed: detailedMetrics,
 	}
 	js, err := json.Marshal(e)
 	if err != nil {
@@ -300,6 +334,11 @@ func mutationHandler(w http.ResponseWriter, r *http.Request) {
 		x.SetStatus(w, x.ErrorInvalidRequest, err.Error())
 		return
 	}
+	isReport, err := parseBool(r, "report")
+	if err != nil {
+		x.SetStatus(w, x.ErrorInvalidRequest, err.Error())
+		return
+	}
 	body := readRequest(w, r)
 	if body == nil {
 		return
@@ -322,70 +361,10 @@ func mutationHandler(w http.ResponseWriter, r *http.Request) {
 
 	switch mediaType {
 	case "application/json":
-		ms := make(map[string]*skipJSONUnmarshal)
-		if err := json.Unmarshal(body, &ms); err != nil {
-			jsonErr := convertJSONError(string(body), err)
-			x.SetStatus(w, x.ErrorInvalidRequest, jsonErr.Error())
-			return
-		}
-
-		req = &api.Request{}
-		if queryText, ok := ms["query"]; ok && queryText != nil {
-			req.Query, err = strconv.Unquote(string(queryText.bs))
-			if err != nil {
-				x.SetStatus(w, x.ErrorInvalidRequest, err.Error())
-				return
-			}
-		}
-
-		// JSON API support both keys 1. mutations  2. set,delete,cond
-		// We want to maintain the backward compatibility of the API here.
-		extractMutation := func(jsMap map[string]*skipJSONUnmarshal) (*api.Mutation, error) {
-			mu := &api.Mutation{}
-			empty := true
-			if setJSON, ok := jsMap["set"]; ok && setJSON != nil {
-				empty = false
-				mu.SetJson = setJSON.bs
-			}
-			if delJSON, ok := jsMap["delete"]; ok && delJSON != nil {
-				empty = false
-				mu.DeleteJson = delJSON.bs
-			}
-			if condText, ok := jsMap["cond"]; ok && condText != nil {
-				mu.Cond, err = strconv.Unquote(string(condText.bs))
-				if err != nil {
-					return nil, err
-				}
-			}
-
-			if empty {
-				return nil, nil
-			}
-
-			return mu, nil
-		}
-		if mu, err := extractMutation(ms); err != nil {
+		req, err = parseJSONMutation(body)
+		if err != nil {
 			x.SetStatus(w, x.ErrorInvalidRequest, err.Error())
 			return
-		} else if mu != nil {
-			req.Mutations = append(req.Mutations, mu)
-		}
-		if mus, ok := ms["mutations"]; ok && mus != nil {
-			var mm []map[string]*skipJSONUnmarshal
-			if err := json.Unmarshal(mus.bs, &mm); err != nil {
-				jsonErr := convertJSONError(string(mus.bs), err)
-				x.SetStatus(w, x.ErrorInvalidRequest, jsonErr.Error())
-				return
-			}
-
-			for _, m := range mm {
-				if mu, err := extractMutation(m); err != nil {
-					x.SetStatus(w, x.ErrorInvalidRequest, err.Error())
-					return
-				} else if mu != nil {
-					req.Mutations = append(req.Mutations, mu)
-				}
-			}
 		}
 
 	case "application/rdf":
@@ -396,9 +375,17 @@ func mutationHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+	case "application/json-patch+json":
+		req, err = parseJSONPatch(body)
+		if err != nil {
+			x.SetStatus(w, x.ErrorInvalidRequest, err.Error())
+			return
+		}
+
 	default:
 		x.SetStatus(w, x.ErrorInvalidRequest, "Unsupported Content-Type. "+
-			"Supported content types are application/json, application/rdf")
+			"Supported content types are application/json, application/rdf, "+
+			"application/json-patch+json")
 		return
 	}
 
@@ -409,7 +396,20 @@ func mutationHandler(w http.ResponseWriter, r *http.Request) {
 	req.CommitNow = commitNow
 
 	ctx := x.AttachAccessJwt(context.Background(), r)
+	ctx = x.AttachIdempotencyKey(ctx, r)
+	var report *edgraph.MutationReport
+	if isReport {
+		ctx, report = edgraph.WithMutationReport(ctx)
+	}
 	resp, err := (&edgraph.Server{}).Query(ctx, req)
+	switch cerr := errors.Cause(err).(type) {
+	case *edgraph.ValueCheckError:
+		replyWithExtensions(w, cerr.Error(), cerr.Extensions())
+		return
+	case *edgraph.ReferenceError:
+		replyWithExtensions(w, cerr.Error(), cerr.Extensions())
+		return
+	}
 	if err != nil {
 		x.SetStatusWithData(w, x.ErrorInvalidRequest, err.Error())
 		return
@@ -437,6 +437,146 @@ func mutationHandler(w http.ResponseWriter, r *http.Request) {
 	mp["message"] = "Done"
 	mp["uids"] = resp.Uids
 	mp["queries"] = json.RawMessage(resp.Json)
+	if report != nil {
+		mp["report"] = report
+	}
+	response["data"] = mp
+
+	js, err := json.Marshal(response)
+	if err != nil {
+		x.SetStatusWithData(w, x.Error, err.Error())
+		return
+	}
+
+	_, _ = x.WriteResponse(w, r, js)
+}
+
+// parseJSONMutation parses the JSON body of a mutation request, which holds either a single
+// mutation in the set, delete and cond keys, or a list of them in the mutations key.
+func parseJSONMutation(body []byte) (*api.Request, error) {
+	ms := make(map[string]*skipJSONUnmarshal)
+	if err := json.Unmarshal(body, &ms); err != nil {
+		return nil, convertJSONError(string(body), err)
+	}
+
+	var err error
+	req := &api.Request{}
+	if queryText, ok := ms["query"]; ok && queryText != nil {
+		req.Query, err = strconv.Unquote(string(queryText.bs))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// JSON API support both keys 1. mutations  2. set,delete,cond
+	// We want to maintain the backward compatibility of the API here.
+	extractMutation := func(jsMap map[string]*skipJSONUnmarshal) (*api.Mutation, error) {
+		mu := &api.Mutation{}
+		empty := true
+		if setJSON, ok := jsMap["set"]; ok && setJSON != nil {
+			empty = false
+			mu.SetJson = setJSON.bs
+		}
+		if delJSON, ok := jsMap["delete"]; ok && delJSON != nil {
+			empty = false
+			mu.DeleteJson = delJSON.bs
+		}
+		if condText, ok := jsMap["cond"]; ok && condText != nil {
+			mu.Cond, err = strconv.Unquote(string(condText.bs))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if empty {
+			return nil, nil
+		}
+
+		return mu, nil
+	}
+	if mu, err := extractMutation(ms); err != nil {
+		return nil, err
+	} else if mu != nil {
+		req.Mutations = append(req.Mutations, mu)
+	}
+	if mus, ok := ms["mutations"]; ok && mus != nil {
+		var mm []map[string]*skipJSONUnmarshal
+		if err := json.Unmarshal(mus.bs, &mm); err != nil {
+			return nil, convertJSONError(string(mus.bs), err)
+		}
+
+		for _, m := range mm {
+			if mu, err := extractMutation(m); err != nil {
+				return nil, err
+			} else if mu != nil {
+				req.Mutations = append(req.Mutations, mu)
+			}
+		}
+	}
+	return req, nil
+}
+
+// mutateBatchHandler applies a batch of independent mutation requests, each one in its own
+// transaction, and reports the outcome of each of them.
+func mutateBatchHandler(w http.ResponseWriter, r *http.Request) {
+	if commonHandler(w, r) {
+		return
+	}
+
+	concurrency, err := parseUint64(r, "concurrency")
+	if err != nil {
+		x.SetStatus(w, x.ErrorInvalidRequest, err.Error())
+		return
+	}
+	body := readRequest(w, r)
+	if body == nil {
+		return
+	}
+
+	var params struct {
+		Requests []json.RawMessage `json:"requests"`
+	}
+	if err := json.Unmarshal(body, &params); err != nil {
+		jsonErr := convertJSONError(string(body), err)
+		x.SetStatus(w, x.ErrorInvalidRequest, jsonErr.Error())
+		return
+	}
+	// A request that can't be parsed is reported as invalid, the others are still applied.
+	results := make([]*edgraph.BatchItemResponse, len(params.Requests))
+	breq := &edgraph.BatchRequest{Concurrency: int(concurrency)}
+	var positions []int
+	for i, raw := range params.Requests {
+		req, err := parseJSONMutation(raw)
+		if err != nil {
+			results[i] = &edgraph.BatchItemResponse{
+				Status: edgraph.BatchInvalid,
+				Error:  errors.Wrapf(err, "while parsing request %d", i).Error(),
+			}
+			continue
+		}
+		breq.Requests = append(breq.Requests, req)
+		positions = append(positions, i)
+	}
+
+	// An empty batch is still passed on, so that it's rejected like any other invalid batch.
+	if len(breq.Requests) > 0 || len(params.Requests) == 0 {
+		ctx := x.AttachAccessJwt(context.Background(), r)
+		ctx = x.AttachRemoteIP(ctx, r)
+		resps, err := (&edgraph.Server{}).MutateBatch(ctx, breq)
+		if err != nil {
+			x.SetStatusWithData(w, x.ErrorInvalidRequest, err.Error())
+			return
+		}
+		for i, resp := range resps {
+			results[positions[i]] = resp
+		}
+	}
+
+	response := map[string]interface{}{}
+	mp := map[string]interface{}{}
+	mp["code"] = x.Success
+	mp["message"] = "Done"
+	mp["results"] = results
 	response["data"] = mp
 
 	js, err := json.Marshal(response)
@@ -448,6 +588,359 @@ func mutationHandler(w http.ResponseWriter, r *http.Request) {
 	_, _ = x.WriteResponse(w, r, js)
 }
 
+// deleteByQueryHandler deletes the nodes matched by a query. If the progress parameter is set,
+// the response is streamed as newline-delimited JSON objects, one after every batch with the
+// numbers so far, followed by the final response.
+func deleteByQueryHandler(w http.ResponseWriter, r *http.Request) {
+	if commonHandler(w, r) {
+		return
+	}
+
+	progress, err := parseBool(r, "progress")
+	if err != nil {
+		x.SetStatus(w, x.ErrorInvalidRequest, err.Error())
+		return
+	}
+	body := readRequest(w, r)
+	if body == nil {
+		return
+	}
+
+	var params struct {
+		Query      string            `json:"query"`
+		Variables  map[string]string `json:"variables"`
+		Var        string            `json:"var"`
+		Predicates []string          `json:"predicates"`
+		BatchSize  int               `json:"batchSize"`
+	}
+	if err := json.Unmarshal(body, &params); err != nil {
+		jsonErr := convertJSONError(string(body), err)
+		x.SetStatus(w, x.ErrorInvalidRequest, jsonErr.Error())
+		return
+	}
+
+	req := &edgraph.DeleteByQueryRequest{
+		Query:      params.Query,
+		Vars:       params.Variables,
+		Var:        params.Var,
+		Predicates: params.Predicates,
+		BatchSize:  params.BatchSize,
+	}
+	flusher, ok := w.(http.Flusher)
+	if progress && ok {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		req.Progress = func(resp *edgraph.DeleteByQueryResponse) {
+			js, err := json.Marshal(map[string]interface{}{"progress": resp})
+			if err != nil {
+				glog.Errorf("Error while marshalling delete by query progress: %v", err)
+				return
+			}
+			if _, err := w.Write(append(js, '\n')); err != nil {
+				glog.Errorf("Error while writing delete by query progress: %v", err)
+				return
+			}
+			flusher.Flush()
+		}
+	}
+
+	ctx := x.AttachAccessJwt(context.Background(), r)
+	ctx = x.AttachRemoteIP(ctx, r)
+	ctx = x.AttachQueryLimits(ctx, r)
+	resp, err := (&edgraph.Server{}).DeleteByQuery(ctx, req)
+	if req.Progress != nil {
+		// The final response is the last line of the stream.
+		defer func() { _, _ = w.Write([]byte{'\n'}) }()
+	}
+	if lerr, ok := err.(*x.LimitExceededError); ok {
+		x.SetStatusLimitExceeded(w, lerr)
+		return
+	}
+	if err != nil {
+		x.SetStatusWithData(w, x.ErrorInvalidRequest, err.Error())
+		return
+	}
+
+	response := map[string]interface{}{}
+	mp := map[string]interface{}{}
+	mp["code"] = x.Success
+	mp["message"] = "Done"
+	mp["matched"] = resp.Matched
+	mp["deleted"] = resp.Deleted
+	mp["batches"] = resp.Batches
+	response["data"] = mp
+
+	js, err := json.Marshal(response)
+	if err != nil {
+		x.SetStatusWithData(w, x.Error, err.Error())
+		return
+	}
+
+	if req.Progress != nil {
+		_, _ = w.Write(js)
+		return
+	}
+	_, _ = x.WriteResponse(w, r, js)
+}
+
+// leaseUidsHandler leases the number of uids given in the num parameter from Zero, and responds
+// with the first and last uid of the range.
+func leaseUidsHandler(w http.ResponseWriter, r *http.Request) {
+	if commonHandler(w, r) {
+		return
+	}
+
+	num, err := parseUint64(r, "num")
+	if err != nil {
+		x.SetStatus(w, x.ErrorInvalidRequest, err.Error())
+		return
+	}
+
+	ctx := x.AttachAccessJwt(context.Background(), r)
+	ids, err := (&edgraph.Server{}).LeaseUids(ctx, num)
+	if err != nil {
+		x.SetStatusWithData(w, x.ErrorInvalidRequest, err.Error())
+		return
+	}
+
+	response := map[string]interface{}{}
+	mp := map[string]interface{}{}
+	mp["code"] = x.Success
+	mp["message"] = "Done"
+	mp["startId"] = fmt.Sprintf("%#x", ids.StartId)
+	mp["endId"] = fmt.Sprintf("%#x", ids.EndId)
+	response["data"] = mp
+
+	js, err := json.Marshal(response)
+	if err != nil {
+		x.SetStatusWithData(w, x.Error, err.Error())
+		return
+	}
+
+	_, _ = x.WriteResponse(w, r, js)
+}
+
+// blobWriter streams a blob in the response. The headers are only sent on the first write, so
+// that errors found before any of the value is read can still be reported as such.
+type blobWriter struct {
+	w       http.ResponseWriter
+	gzip    bool
+	out     io.Writer
+	gzw     *gzip.Writer
+	started bool
+}
+
+func (bw *blobWriter) start() {
+	bw.started = true
+	bw.out = bw.w
+	bw.w.Header().Set("Content-Type", "application/octet-stream")
+	if bw.gzip {
+		bw.w.Header().Set("Content-Encoding", "gzip")
+		bw.gzw = gzip.NewWriter(bw.w)
+		bw.out = bw.gzw
+	}
+}
+
+func (bw *blobWriter) Write(p []byte) (int, error) {
+	if !bw.started {
+		bw.start()
+	}
+	return bw.out.Write(p)
+}
+
+func (bw *blobWriter) Close() error {
+	if !bw.started {
+		bw.start()
+	}
+	if bw.gzw != nil {
+		return bw.gzw.Close()
+	}
+	return nil
+}
+
+// blobHandler reads or writes the raw value of the predicate given in the predicate parameter
+// for the node given in the uid parameter. GET requests respond with the value as is, while POST
+// and PUT requests set the value to the request body and commit it immediately. The value is
+// streamed in both directions.
+func blobHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		x.AddCorsHeaders(w)
+		w.Header().Set("Content-Type", "application/json")
+	} else if commonHandler(w, r) {
+		return
+	}
+
+	uid, err := parseUint64(r, "uid")
+	if err != nil {
+		x.SetStatus(w, x.ErrorInvalidRequest, err.Error())
+		return
+	}
+	attr := r.URL.Query().Get("predicate")
+
+	ctx := x.AttachAccessJwt(context.Background(), r)
+	if r.Method == http.MethodGet {
+		bw := &blobWriter{w: w, gzip: strings.Contains(r.Header.Get("Accept-Encoding"), "gzip")}
+		err := (&edgraph.Server{}).ReadBlob(ctx, uid, attr, bw)
+		if err == nil {
+			err = bw.Close()
+		}
+		switch {
+		case err == nil:
+		case !bw.started:
+			x.SetStatusWithData(w, x.ErrorInvalidRequest, err.Error())
+		default:
+			// Part of the value has already been sent, so the response can only be cut short.
+			glog.Errorf("Error while streaming predicate %s of node %#x: %v", attr, uid, err)
+			panic(http.ErrAbortHandler)
+		}
+		return
+	}
+
+	var in io.Reader = r.Body
+	if enc := r.Header.Get("Content-Encoding"); enc == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			x.SetStatus(w, x.Error, "Unable to create decompressor")
+			return
+		}
+		defer gz.Close()
+		in = gz
+	} else if enc != "" && enc != "identity" {
+		x.SetStatus(w, x.ErrorInvalidRequest, "Unsupported content encoding")
+		return
+	}
+
+	resp, err := (&edgraph.Server{}).WriteBlob(ctx, uid, attr, in)
+	if err != nil {
+		x.SetStatusWithData(w, x.ErrorInvalidRequest, err.Error())
+		return
+	}
+
+	// Don't send keys array which is part of txn context as the blob is committed immediately.
+	resp.Txn.Keys = resp.Txn.Keys[:0]
+	response := map[string]interface{}{}
+	response["extensions"] = query.Extensions{Txn: resp.Txn, Latency: resp.Latency}
+	mp := map[string]interface{}{}
+	mp["code"] = x.Success
+	mp["message"] = "Done"
+	response["data"] = mp
+
+	js, err := json.Marshal(response)
+	if err != nil {
+		x.SetStatusWithData(w, x.Error, err.Error())
+		return
+	}
+
+	_, _ = x.WriteResponse(w, r, js)
+}
+
+func compareAndSetHandler(w http.ResponseWriter, r *http.Request) {
+	if commonHandler(w, r) {
+		return
+	}
+
+	body := readRequest(w, r)
+	if body == nil {
+		return
+	}
+
+	var params struct {
+		Uid       string          `json:"uid"`
+		Predicate string          `json:"predicate"`
+		Expected  json.RawMessage `json:"expected"`
+		Value     json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(body, &params); err != nil {
+		jsonErr := convertJSONError(string(body), err)
+		x.SetStatus(w, x.ErrorInvalidRequest, jsonErr.Error())
+		return
+	}
+	uid, err := strconv.ParseUint(params.Uid, 0, 64)
+	if err != nil {
+		x.SetStatus(w, x.ErrorInvalidRequest, fmt.Sprintf("invalid uid %q", params.Uid))
+		return
+	}
+	expected, err := casParam(params.Expected)
+	if err != nil {
+		x.SetStatus(w, x.ErrorInvalidRequest, err.Error())
+		return
+	}
+	value, err := casParam(params.Value)
+	if err != nil {
+		x.SetStatus(w, x.ErrorInvalidRequest, err.Error())
+		return
+	}
+	if value == nil {
+		x.SetStatus(w, x.ErrorInvalidRequest, "compare and set requires a value")
+		return
+	}
+
+	ctx := x.AttachAccessJwt(context.Background(), r)
+	ctx = x.AttachRemoteIP(ctx, r)
+	resp, err := (&edgraph.Server{}).CompareAndSet(ctx, &edgraph.CompareAndSetRequest{
+		Uid:       uid,
+		Predicate: params.Predicate,
+		Expected:  expected,
+		Value:     *value,
+	})
+	if merr, ok := err.(*edgraph.ValueMismatchError); ok {
+		replyWithExtensions(w, merr.Error(), merr.Extensions())
+		return
+	}
+	if err != nil {
+		x.SetStatusWithData(w, x.ErrorInvalidRequest, err.Error())
+		return
+	}
+
+	response := map[string]interface{}{}
+	e := query.Extensions{
+		Txn:     resp.Txn,
+		Latency: resp.Latency,
+	}
+	response["extensions"] = e
+	mp := map[string]interface{}{}
+	mp["code"] = x.Success
+	mp["message"] = "Done"
+	response["data"] = mp
+
+	js, err := json.Marshal(response)
+	if err != nil {
+		x.SetStatusWithData(w, x.Error, err.Error())
+		return
+	}
+
+	_, _ = x.WriteResponse(w, r, js)
+}
+
+// replyWithExtensions replies with an error having the given message and extensions, which
+// give the details of the error along with its code.
+func replyWithExtensions(w http.ResponseWriter, msg string, ext map[string]interface{}) {
+	var qr x.QueryResWithData
+	qr.Errors = append(qr.Errors, &x.GqlError{Message: msg, Extensions: ext})
+	x.Reply(w, qr)
+}
+
+// casParam returns the value of a compare and set parameter, which can be any JSON scalar, as
+// a string. It returns nil if the parameter is null or missing.
+func casParam(raw json.RawMessage) (*string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var val interface{}
+	if err := json.Unmarshal(raw, &val); err != nil {
+		return nil, err
+	}
+	var str string
+	switch v := val.(type) {
+	case string:
+		str = v
+	case float64, bool:
+		str = string(raw)
+	default:
+		return nil, errors.Errorf("compare and set values must be scalars, got: %s", raw)
+	}
+	return &str, nil
+}
+
 func commitHandler(w http.ResponseWriter, r *http.Request) {
 	if commonHandler(w, r) {
 		return
@@ -480,7 +973,8 @@ func commitHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		response, err = handleCommit(startTs, reqText)
+		ctx := x.AttachIdempotencyKey(context.Background(), r)
+		response, err = handleCommit(ctx, startTs, reqText)
 	}
 	if err != nil {
 		x.SetStatus(w, x.ErrorInvalidRequest, err.Error())
@@ -516,7 +1010,8 @@ func handleAbort(startTs uint64) (map[string]interface{}, error) {
 	}
 }
 
-func handleCommit(startTs uint64, reqText []byte) (map[string]interface{}, error) {
+func handleCommit(ctx context.Context, startTs uint64,
+	reqText []byte) (map[string]interface{}, error) {
 	tc := &api.TxnContext{
 		StartTs: startTs,
 	}
@@ -539,14 +1034,19 @@ func handleCommit(startTs uint64, reqText []byte) (map[string]interface{}, error
 		tc.Preds = reqMap["preds"]
 	}
 
-	cts, err := worker.CommitOverNetwork(context.Background(), tc)
+	committed, err := (&edgraph.Server{}).CommitOrAbort(ctx, tc)
 	if err != nil {
+		if st, ok := status.FromError(err); ok {
+			return nil, errors.New(st.Message())
+		}
 		return nil, err
 	}
 
 	resp := &api.Response{}
 	resp.Txn = tc
-	resp.Txn.CommitTs = cts
+	// A retried commit reports the transaction that was committed with its idempotency key.
+	resp.Txn.StartTs = committed.StartTs
+	resp.Txn.CommitTs = committed.CommitTs
 	e := query.Extensions{
 		Txn: resp.Txn,
 	}
@@ -571,17 +1071,11 @@ func alterHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	op := &api.Operation{}
-	if err := jsonpb.UnmarshalString(string(b), op); err != nil {
-		op.Schema = string(b)
-	}
-
 	runInBackground, err := parseBool(r, "runInBackground")
 	if err != nil {
 		x.SetStatus(w, x.ErrorInvalidRequest, err.Error())
 		return
 	}
-	op.RunInBackground = runInBackground
 
 	glog.Infof("Got alter request via HTTP from %s\n", r.RemoteAddr)
 	fwd := r.Header.Get("X-Forwarded-For")
@@ -593,6 +1087,28 @@ func alterHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := x.AttachAuthToken(context.Background(), r)
 	ctx = x.AttachAccessJwt(ctx, r)
 	ctx = x.AttachRemoteIP(ctx, r)
+
+	// Renames aren't part of api.Operation, so they're read separately.
+	var rename struct {
+		From string `json:"rename_attr"`
+		To   string `json:"rename_to"`
+	}
+	if err := json.Unmarshal(b, &rename); err == nil && (rename.From != "" || rename.To != "") {
+		err := (&edgraph.Server{}).RenamePredicate(ctx, rename.From, rename.To, !runInBackground)
+		if err != nil {
+			x.SetStatus(w, x.Error, err.Error())
+			return
+		}
+		writeSuccessResponse(w, r)
+		return
+	}
+
+	op := &api.Operation{}
+	if err := jsonpb.UnmarshalString(string(b), op); err != nil {
+		op.Schema = string(b)
+	}
+	op.RunInBackground = runInBackground
+
 	if _, err := (&edgraph.Server{}).Alter(ctx, op); err != nil {
 		x.SetStatus(w, x.Error, err.Error())
 		return
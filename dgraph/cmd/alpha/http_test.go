@@ -34,6 +34,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 
+	"github.com/dgraph-io/dgraph/edgraph"
 	"github.com/dgraph-io/dgraph/protos/pb"
 	"github.com/dgraph-io/dgraph/query"
 	"github.com/dgraph-io/dgraph/testutil"
@@ -635,6 +636,545 @@ func TestTransactionBasicOldCommitFormat(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestMutationReport(t *testing.T) {
+	require.NoError(t, dropAll())
+	require.NoError(t, alterSchema(`name: string @index(exact) .`))
+
+	mr, err := mutationWithTs(`{ set {
+		_:a <name> "a" .
+		_:b <name> "b" .
+	} }`, "application/rdf", false, true, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"name"}, splitPreds(mr.preds))
+
+	q := `{ q(func: eq(name, "a")) { a as uid } }`
+	m := `
+upsert {
+  query ` + q + `
+  mutation {
+    set {
+      uid(a) <age> "30" .
+      _:c <name> "c" .
+      _:c <friend> uid(a) .
+    }
+  }
+  mutation {
+    delete {
+      uid(a) <name> * .
+    }
+  }
+}`
+	_, body, err := runWithRetries("POST", "application/rdf",
+		addr+"/mutate?commitNow=true&report=true", m)
+	require.NoError(t, err)
+
+	var r struct {
+		Data struct {
+			Uids   map[string]string      `json:"uids"`
+			Report edgraph.MutationReport `json:"report"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(body, &r))
+	require.Len(t, r.Data.Report.Mutations, 2)
+
+	set := r.Data.Report.Mutations[0]
+	require.Equal(t, []string{r.Data.Uids["c"]}, set.Created)
+	require.Len(t, set.Updated, 1)
+	require.Empty(t, set.Deleted)
+	require.Equal(t, []string{"age", "friend", "name"}, set.Predicates)
+
+	del := r.Data.Report.Mutations[1]
+	require.Empty(t, del.Created)
+	require.Empty(t, del.Updated)
+	require.Equal(t, set.Updated, del.Deleted)
+	require.Equal(t, []string{"name"}, del.Predicates)
+
+	// The report is only sent when it's asked for.
+	_, body, err = runWithRetries("POST", "application/rdf", addr+"/mutate?commitNow=true",
+		`{ set { _:d <name> "d" . } }`)
+	require.NoError(t, err)
+	require.NotContains(t, string(body), `"report"`)
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	require.NoError(t, dropAll())
+	require.NoError(t, alterSchema(`name: string @index(exact) .`))
+
+	mutate := func(key string) (uint64, map[string]string) {
+		req, err := createRequest("POST", "application/rdf", addr+"/mutate?commitNow=true",
+			`{ set { _:a <name> "idempotent" . } }`)
+		require.NoError(t, err)
+		req.Header.Set("X-Dgraph-IdempotencyKey", key)
+		_, body, err := runRequest(req)
+		require.NoError(t, err)
+
+		var r struct {
+			Data struct {
+				Uids map[string]string `json:"uids"`
+			} `json:"data"`
+			Extensions struct {
+				Txn struct {
+					CommitTs uint64 `json:"commit_ts"`
+				} `json:"txn"`
+			} `json:"extensions"`
+		}
+		require.NoError(t, json.Unmarshal(body, &r))
+		return r.Extensions.Txn.CommitTs, r.Data.Uids
+	}
+
+	// The retry of a commit gets the response of the original one and isn't applied again.
+	cts, uids := mutate("TestIdempotencyKey-1")
+	require.NotZero(t, cts)
+	retryCts, retryUids := mutate("TestIdempotencyKey-1")
+	require.Equal(t, cts, retryCts)
+	require.Equal(t, uids, retryUids)
+
+	otherCts, _ := mutate("TestIdempotencyKey-2")
+	require.Greater(t, otherCts, cts)
+
+	data, _, err := queryWithTs(`{ q(func: eq(name, "idempotent")) { count(uid) } }`,
+		"application/graphql+-", "", 0)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data":{"q":[{"count":2}]}}`, data)
+}
+
+func TestJSONPatch(t *testing.T) {
+	require.NoError(t, dropAll())
+	require.NoError(t, alterSchema(`
+		email: string @index(exact) @upsert .
+		tags: [string] .`))
+
+	m := `{ set {
+		_:a <email> "a@company.io" .
+		_:a <name> "A" .
+		_:a <tags> "x" .
+	} }`
+	mr, err := mutationWithTs(m, "application/rdf", false, true, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"email", "name", "tags"}, splitPreds(mr.preds))
+
+	patch := `{
+		"id": {"email": "a@company.io"},
+		"patch": [
+			{"op": "replace", "path": "/name", "value": "B"},
+			{"op": "add", "path": "/tags/-", "value": ["y", "z"]},
+			{"op": "remove", "path": "/tags", "value": "x"}
+		]
+	}`
+	_, err = mutationWithTs(patch, "application/json-patch+json", false, true, 0)
+	require.NoError(t, err)
+
+	q := `{ q(func: eq(email, "a@company.io")) { name tags } }`
+	res, _, err := queryWithTs(q, "application/graphql+-", "", 0)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data": {"q": [{"name": "B", "tags": ["y", "z"]}]}}`, res)
+
+	// Nothing is changed if no node has the id.
+	patch = `{
+		"id": {"email": "b@company.io"},
+		"patch": [{"op": "add", "path": "/name", "value": "C"}]
+	}`
+	_, err = mutationWithTs(patch, "application/json-patch+json", false, true, 0)
+	require.NoError(t, err)
+	res, _, err = queryWithTs(`{ q(func: has(name)) { name } }`, "application/graphql+-", "", 0)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data": {"q": [{"name": "B"}]}}`, res)
+
+	patch = `{"uid": "0x1", "patch": [{"op": "move", "from": "/name", "path": "/title"}]}`
+	_, err = mutationWithTs(patch, "application/json-patch+json", false, true, 0)
+	require.EqualError(t, err, `while parsing operation 0: unsupported JSON Patch operation `+
+		`"move", supported operations are add, remove and replace`)
+}
+
+func TestDeleteByQuery(t *testing.T) {
+	require.NoError(t, dropAll())
+	require.NoError(t, alterSchema(`
+		name: string @index(exact) .
+		age: int .
+		type Item {
+			name
+			age
+		}`))
+
+	m := `
+{
+  set {
+    _:a <name> "stale" .
+    _:a <age> "10" .
+    _:b <name> "stale" .
+    _:b <age> "20" .
+    _:c <name> "stale" .
+    _:d <name> "fresh" .
+    _:a <dgraph.type> "Item" .
+    _:b <dgraph.type> "Item" .
+    _:c <dgraph.type> "Item" .
+    _:d <dgraph.type> "Item" .
+  }
+}`
+	_, err := mutationWithTs(m, "application/rdf", false, true, 0)
+	require.NoError(t, err)
+
+	body := `{"query": "{ v as var(func: eq(name, \"stale\")) }", "batchSize": 2}`
+	_, resp, err := runWithRetries("POST", "application/json", addr+"/deleteByQuery", body)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data": {"code": "Success", "message": "Done", "matched": 3,
+		"deleted": 3, "batches": 2}}`, string(resp))
+
+	q := `{ q(func: has(name)) { name } }`
+	res, _, err := queryWithTs(q, "application/graphql+-", "", 0)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data": {"q": [{"name": "fresh"}]}}`, res)
+
+	// Only the given predicates are deleted.
+	body = `{"query": "{ v as var(func: eq(name, \"fresh\")) }", "predicates": ["name"]}`
+	_, resp, err = runWithRetries("POST", "application/json", addr+"/deleteByQuery", body)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data": {"code": "Success", "message": "Done", "matched": 1,
+		"deleted": 1, "batches": 1}}`, string(resp))
+
+	_, _, err = runWithRetries("POST", "application/json", addr+"/deleteByQuery",
+		`{"query": "{ q(func: has(name)) { uid } }"}`)
+	require.EqualError(t, err, "query for delete by query doesn't define any variable")
+}
+
+func TestDeleteByQueryVars(t *testing.T) {
+	require.NoError(t, dropAll())
+	require.NoError(t, alterSchema(`
+		name: string @index(exact) .
+		age: int .
+		type Item {
+			name
+			age
+		}`))
+
+	m := `
+{
+  set {
+    _:a <name> "stale" .
+    _:a <age> "10" .
+    _:b <name> "stale" .
+    _:c <name> "fresh" .
+    _:c <age> "30" .
+    _:a <dgraph.type> "Item" .
+    _:b <dgraph.type> "Item" .
+    _:c <dgraph.type> "Item" .
+  }
+}`
+	_, err := mutationWithTs(m, "application/rdf", false, true, 0)
+	require.NoError(t, err)
+
+	// The value variable a only holds the ages of the nodes, so only the nodes of v are deleted.
+	body := `{"query": "{ v as var(func: eq(name, \"stale\")) q(func: has(age)) { a as age } }"}`
+	_, resp, err := runWithRetries("POST", "application/json", addr+"/deleteByQuery", body)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data": {"code": "Success", "message": "Done", "matched": 2,
+		"deleted": 2, "batches": 1}}`, string(resp))
+
+	q := `{ q(func: has(name)) { name } }`
+	res, _, err := queryWithTs(q, "application/graphql+-", "", 0)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data": {"q": [{"name": "fresh"}]}}`, res)
+
+	body = `{"query": "{ q(func: has(age)) { a as age } }", "var": "a"}`
+	_, _, err = runWithRetries("POST", "application/json", addr+"/deleteByQuery", body)
+	require.EqualError(t, err,
+		"variable a for delete by query is a value variable, expected a uid variable")
+}
+
+func TestDeleteByQueryProgress(t *testing.T) {
+	require.NoError(t, dropAll())
+	require.NoError(t, alterSchema(`name: string @index(exact) .`))
+
+	var rdf strings.Builder
+	for i := 0; i < 5; i++ {
+		rdf.WriteString(fmt.Sprintf("_:n%d <name> \"stale\" .\n", i))
+	}
+	_, err := mutationWithTs("{ set { "+rdf.String()+" } }", "application/rdf", false, true, 0)
+	require.NoError(t, err)
+
+	// The root block is paged, so the nodes are matched two at a time.
+	body := `{"query": "{ v as var(func: eq(name, \"stale\")) }", "batchSize": 2}`
+	_, resp, err := runWithRetries("POST", "application/json",
+		addr+"/deleteByQuery?progress=true", body)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(resp)), "\n")
+	require.Equal(t, []string{
+		`{"progress":{"matched":2,"deleted":2,"batches":1}}`,
+		`{"progress":{"matched":4,"deleted":4,"batches":2}}`,
+		`{"progress":{"matched":5,"deleted":5,"batches":3}}`,
+	}, lines[:len(lines)-1])
+	require.JSONEq(t, `{"data": {"code": "Success", "message": "Done", "matched": 5,
+		"deleted": 5, "batches": 3}}`, lines[len(lines)-1])
+}
+
+func TestMutateBatch(t *testing.T) {
+	require.NoError(t, dropAll())
+	require.NoError(t, alterSchema(`name: string @index(exact) .
+		age: int .`))
+
+	body := `
+	{
+		"requests": [
+			{"set": {"uid": "_:a", "name": "a"}},
+			{"set": {"uid": "_:b", "name": "b"}},
+			{"set": {"uid": "_:c", "name": "c", "age": "old"}},
+			"oops"
+		]
+	}`
+	_, resp, err := runWithRetries("POST", "application/json", addr+"/mutateBatch?concurrency=2",
+		body)
+	require.NoError(t, err)
+
+	var res struct {
+		Data struct {
+			Results []*edgraph.BatchItemResponse `json:"results"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(resp, &res))
+	results := res.Data.Results
+	require.Len(t, results, 4)
+	for _, r := range results[:2] {
+		require.Equal(t, edgraph.BatchCommitted, r.Status)
+		require.NotZero(t, r.CommitTs)
+		require.Len(t, r.Uids, 1)
+	}
+	// The invalid requests don't affect the others.
+	require.Equal(t, edgraph.BatchInvalid, results[2].Status)
+	require.Contains(t, results[2].Error, `Input for predicate "age" of type int is scalar`)
+	require.Equal(t, edgraph.BatchInvalid, results[3].Status)
+	require.Contains(t, results[3].Error, "while parsing request 3")
+
+	q := `{ q(func: has(name), orderasc: name) { name } }`
+	qres, _, err := queryWithTs(q, "application/graphql+-", "", 0)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data": {"q": [{"name": "a"}, {"name": "b"}]}}`, qres)
+
+	_, _, err = runWithRetries("POST", "application/json", addr+"/mutateBatch", `{"requests": []}`)
+	require.EqualError(t, err, "batch doesn't contain any request")
+}
+
+func TestLeaseUids(t *testing.T) {
+	lease := func(num uint64) (uint64, uint64) {
+		_, body, err := runWithRetries("POST", "", fmt.Sprintf("%s/leaseUids?num=%d", addr, num),
+			"")
+		require.NoError(t, err)
+
+		var r struct {
+			Data struct {
+				StartId string `json:"startId"`
+				EndId   string `json:"endId"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(body, &r))
+		start, err := strconv.ParseUint(r.Data.StartId, 0, 64)
+		require.NoError(t, err)
+		end, err := strconv.ParseUint(r.Data.EndId, 0, 64)
+		require.NoError(t, err)
+		return start, end
+	}
+
+	start, end := lease(1000)
+	require.Equal(t, uint64(999), end-start)
+	next, _ := lease(1)
+	require.Greater(t, next, end)
+
+	_, _, err := runWithRetries("POST", "", addr+"/leaseUids?num=0", "")
+	require.EqualError(t, err,
+		"number of uids to lease should be between 1 and 1000000000, got: 0")
+
+	// The leased uids can be used in mutations.
+	_, err = mutationWithTs(fmt.Sprintf(`{ set { <%#x> <name> "leased" . } }`, end),
+		"application/rdf", false, true, 0)
+	require.NoError(t, err)
+}
+
+func TestBlob(t *testing.T) {
+	require.NoError(t, dropAll())
+	require.NoError(t, alterSchema(`doc: string .`))
+
+	// Make the value big enough to be split into several chunks, and include bytes that would
+	// be escaped in a JSON response.
+	var buf bytes.Buffer
+	for i := 0; buf.Len() < 3<<20; i++ {
+		buf.WriteByte(byte(i % 256))
+	}
+	blob := buf.String()
+
+	_, _, err := runWithRetries("POST", "", addr+"/blob?uid=0x1&predicate=doc", blob)
+	require.NoError(t, err)
+
+	_, body, err := runWithRetries("GET", "", addr+"/blob?uid=0x1&predicate=doc", "")
+	require.NoError(t, err)
+	require.Equal(t, blob, string(body))
+
+	_, err = mutationWithTs(`{ set { <0x2> <doc> "small" . } }`, "application/rdf", false,
+		true, 0)
+	require.NoError(t, err)
+	_, body, err = runWithRetries("GET", "", addr+"/blob?uid=0x2&predicate=doc", "")
+	require.NoError(t, err)
+	require.Equal(t, "small", string(body))
+
+	_, _, err = runWithRetries("GET", "", addr+"/blob?uid=0x3&predicate=doc", "")
+	require.EqualError(t, err, "no value found for predicate doc of node 0x3")
+}
+
+func TestCompareAndSet(t *testing.T) {
+	require.NoError(t, dropAll())
+	require.NoError(t, alterSchema(`counter: int .`))
+
+	m := `{ set { <0x1> <counter> "5" . } }`
+	_, err := mutationWithTs(m, "application/rdf", false, true, 0)
+	require.NoError(t, err)
+
+	body := `{"uid": "0x1", "predicate": "counter", "expected": 5, "value": 6}`
+	_, _, err = runWithRetries("POST", "application/json", addr+"/cas", body)
+	require.NoError(t, err)
+
+	// The value is now 6, so the same compare and set fails.
+	_, _, err = runWithRetries("POST", "application/json", addr+"/cas", body)
+	require.EqualError(t, err,
+		"Current value of predicate counter of node 0x1 doesn't match the expected value")
+
+	// A null expected value only matches nodes without the predicate.
+	body = `{"uid": "0x2", "predicate": "counter", "expected": null, "value": 1}`
+	_, _, err = runWithRetries("POST", "application/json", addr+"/cas", body)
+	require.NoError(t, err)
+
+	q := `{ q(func: has(counter), orderasc: counter) { counter } }`
+	res, _, err := queryWithTs(q, "application/graphql+-", "", 0)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data": {"q": [{"counter": 1}, {"counter": 6}]}}`, res)
+}
+
+func TestEdgeProperties(t *testing.T) {
+	require.NoError(t, dropAll())
+	require.NoError(t, alterSchema(`
+		name: string @index(exact) .
+		friend: [uid] .
+		<friend|since>: int @index(int) .
+	`))
+	require.Error(t, alterSchema(`<name|since>: int .`))
+
+	m := `
+	{
+		set {
+			_:a <name> "a" .
+			_:b <name> "b" .
+			_:c <name> "c" .
+			_:a <friend> _:b (since=2015, weight=0.5) .
+			_:a <friend> _:c (since=2020, weight=0.9) .
+			_:b <friend> _:c .
+		}
+	}`
+	_, err := mutationWithTs(m, "application/rdf", false, true, 0)
+	require.NoError(t, err)
+
+	q := `{ q(func: ge(<friend|since>, 2018)) { name <friend|since> } }`
+	res, _, err := queryWithTs(q, "application/graphql+-", "", 0)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data": {"q": [{"name": "a", "friend|since": [2015, 2020]}]}}`, res)
+
+	// A filter on the property of the edges keeps the matching edges.
+	q = `{ q(func: eq(name, "a")) { name friend @filter(ge(<friend|since>, 2018)) { name } } }`
+	res, _, err = queryWithTs(q, "application/graphql+-", "", 0)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data": {"q": [{"name": "a", "friend": [{"name": "c"}]}]}}`, res)
+
+	q = `{ q(func: eq(name, "a")) { friend @filter(ge(<friend|since>, 2018) and has(name)) { name } } }`
+	_, _, err = queryWithTs(q, "application/graphql+-", "", 0)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "can't be combined with other filters")
+
+	// A property declared after the edges have been written is built from their facets.
+	require.NoError(t, alterSchema(`<friend|weight>: float .`))
+	q = `{ q(func: eq(name, "a")) { friend @filter(lt(<friend|weight>, 0.7)) { name } } }`
+	res, _, err = queryWithTs(q, "application/graphql+-", "", 0)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data": {"q": [{"friend": [{"name": "b"}]}]}}`, res)
+
+	q = `{ q(func: eq(name, "b")) @filter(has(<friend|since>)) { name } }`
+	res, _, err = queryWithTs(q, "application/graphql+-", "", 0)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data": {"q": []}}`, res)
+
+	// Deleting the edge deletes its properties too.
+	m = `
+	upsert {
+		query {
+			a as var(func: eq(name, "a"))
+			c as var(func: eq(name, "c"))
+		}
+		mutation {
+			delete {
+				uid(a) <friend> uid(c) .
+			}
+		}
+	}`
+	_, err = mutationWithTs(m, "application/rdf", false, true, 0)
+	require.NoError(t, err)
+
+	q = `{ q(func: ge(<friend|since>, 2018)) { name } }`
+	res, _, err = queryWithTs(q, "application/graphql+-", "", 0)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data": {"q": []}}`, res)
+}
+
+func TestRenamePredicate(t *testing.T) {
+	require.NoError(t, dropAll())
+	require.NoError(t, alterSchema(`
+		name: string @index(exact) .
+		follows: [uid] @reverse @count .
+		type Person {
+			name
+			follows
+		}
+	`))
+
+	m := `
+	{
+		set {
+			_:a <name> "a" .
+			_:b <name> "b" .
+			_:a <follows> _:b .
+			_:a <dgraph.type> "Person" .
+		}
+	}`
+	_, err := mutationWithTs(m, "application/rdf", false, true, 0)
+	require.NoError(t, err)
+
+	rename := func(from, to string) error {
+		op := fmt.Sprintf(`{"rename_attr": %q, "rename_to": %q}`, from, to)
+		_, _, err := runWithRetries("PUT", "", addr+"/alter", op)
+		return err
+	}
+	require.NoError(t, rename("name", "fullName"))
+	require.NoError(t, rename("follows", "knows"))
+	require.Error(t, rename("name", "title"))
+	require.Error(t, rename("fullName", "knows"))
+
+	q := `{ q(func: eq(fullName, "a")) { fullName count(knows) knows { fullName ~knows { fullName } } } }`
+	res, _, err := queryWithTs(q, "application/graphql+-", "", 0)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data": {"q": [{"fullName": "a", "count(knows)": 1,
+		"knows": [{"fullName": "b", "~knows": [{"fullName": "a"}]}]}]}}`, res)
+
+	q = `{ q(func: type(Person)) { expand(_all_) } }`
+	res, _, err = queryWithTs(q, "application/graphql+-", "", 0)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data": {"q": [{"fullName": "a"}]}}`, res)
+
+	q = `schema(pred: [name, fullName]) { type index }`
+	res, _, err = queryWithTs(q, "application/graphql+-", "", 0)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data": {"schema": [{"predicate": "fullName", "type": "string",
+		"index": true}]}}`, res)
+
+	// The renamed predicate accepts mutations once the rename is done.
+	m = `{ set { _:c <fullName> "c" . } }`
+	_, err = mutationWithTs(m, "application/rdf", false, true, 0)
+	require.NoError(t, err)
+}
+
 func TestAlterAllFieldsShouldBeSet(t *testing.T) {
 	req, err := http.NewRequest("PUT", "/alter", bytes.NewBufferString(
 		`{"dropall":true}`, // "dropall" is spelt incorrect - should be "drop_all"
@@ -922,6 +1462,574 @@ func TestDrainingMode(t *testing.T) {
 	runRequests(false)
 }
 
+func setStrictSchema(t *testing.T, enable bool, accessJwt string) {
+	params := &testutil.GraphQLParams{
+		Query: `mutation config($enable: Boolean) {
+			config(input: {strictSchema: $enable}) {
+				response {
+					code
+				}
+			}
+		}`,
+		Variables: map[string]interface{}{"enable": enable},
+	}
+	resp := testutil.MakeGQLRequestWithAccessJwt(t, params, accessJwt)
+	resp.RequireNoGraphQLErrors(t)
+	require.JSONEq(t, `{"config":{"response":{"code":"Success"}}}`, string(resp.Data))
+}
+
+func TestStrictSchema(t *testing.T) {
+	require.NoError(t, dropAll())
+	require.NoError(t, alterSchema(`
+		name: string @index(exact) .
+		type Person {
+			name
+		}`))
+
+	grootJwt, _ := testutil.GrootHttpLogin(addr + "/admin")
+	setStrictSchema(t, true, grootJwt)
+	defer setStrictSchema(t, false, grootJwt)
+
+	_, err := mutationWithTs(`{ set {
+		_:a <name> "Alice" .
+		_:a <dgraph.type> "Person" .
+	} }`, "application/rdf", false, true, 0)
+	require.NoError(t, err)
+
+	_, err = mutationWithTs(`{ set {
+		_:a <nmae> "Bob" .
+		_:a <dgraph.type> "Persn" .
+	} }`, "application/rdf", false, true, 0)
+	require.EqualError(t, err, "Strict schema mode is enabled, but the mutation uses "+
+		"predicates [nmae] and types [Persn] not defined in the schema. Add them to the "+
+		"schema before using them.")
+
+	setStrictSchema(t, false, grootJwt)
+	_, err = mutationWithTs(`{ set { _:a <nmae> "Bob" . } }`, "application/rdf", false, true, 0)
+	require.NoError(t, err)
+}
+
+func TestRequiredPredicates(t *testing.T) {
+	require.NoError(t, dropAll())
+	require.NoError(t, alterSchema(`
+		name: string @index(exact) .
+		email: string .
+		type Person {
+			name @required
+			email @required
+		}`))
+
+	_, err := mutationWithTs(`{ set {
+		_:a <name> "Alice" .
+		_:a <dgraph.type> "Person" .
+	} }`,
+		"application/rdf", false, true, 0)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "has no value for the required predicates [email]")
+
+	_, err = mutationWithTs(`{ set { <0x1000> <name> "Bob" . } }`, "application/rdf", false,
+		true, 0)
+	require.NoError(t, err)
+	_, err = mutationWithTs(`{ set { <0x1000> <dgraph.type> "Person" . } }`, "application/rdf",
+		false, true, 0)
+	require.EqualError(t, err, "Node 0x1000 of type Person has no value for the required"+
+		" predicates [email]")
+
+	// The values the node already has count.
+	_, err = mutationWithTs(`{ set { <0x1000> <email> "bob@example.com" . } }`,
+		"application/rdf", false, true, 0)
+	require.NoError(t, err)
+	_, err = mutationWithTs(`{ set { <0x1000> <dgraph.type> "Person" . } }`, "application/rdf",
+		false, true, 0)
+	require.NoError(t, err)
+}
+
+func TestCompositeIndex(t *testing.T) {
+	require.NoError(t, dropAll())
+	require.NoError(t, alterSchema(`
+		country: string @index(exact) .
+		city: string @index(exact) .`))
+
+	// The nodes existing when the index is added are indexed.
+	_, err := mutationWithTs(`{ set {
+		<0x2000> <country> "US" .
+		<0x2000> <city> "Austin" .
+		<0x2001> <country> "US" .
+		<0x2001> <city> "Boston" .
+		<0x2002> <country> "UK" .
+		<0x2002> <city> "Bath" .
+	} }`, "application/rdf", false, true, 0)
+	require.NoError(t, err)
+	require.NoError(t, alterSchema(`<country^city>: string @index(exact) .`))
+
+	query := `{
+		eq(func: eq(<country^city>, "US", "Austin")) { city }
+		ge(func: ge(<country^city>, "US", "B")) { city }
+		lt(func: lt(<country^city>, "UK", "Z")) { city }
+		filter(func: has(city)) @filter(between(<country^city>, "US", "A", "Bz")) { city }
+	}`
+	data, _, err := queryWithTs(query, "application/graphql+-", "", 0)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data": {
+		"eq": [{"city": "Austin"}],
+		"ge": [{"city": "Boston"}],
+		"lt": [{"city": "Bath"}],
+		"filter": [{"city": "Austin"}, {"city": "Boston"}]
+	}}`, data)
+
+	// Changing one of the predicates moves the node in the index, and deleting one of them
+	// removes it.
+	_, err = mutationWithTs(`{
+		set {
+			<0x2000> <country> "UK" .
+			<0x2003> <country> "US" .
+			<0x2003> <city> "Chicago" .
+		}
+		delete {
+			<0x2002> <city> * .
+		}
+	}`, "application/rdf", false, true, 0)
+	require.NoError(t, err)
+	data, _, err = queryWithTs(query, "application/graphql+-", "", 0)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data": {
+		"eq": [],
+		"ge": [{"city": "Boston"}, {"city": "Chicago"}],
+		"lt": [{"city": "Austin"}],
+		"filter": [{"city": "Boston"}]
+	}}`, data)
+
+	_, err = mutationWithTs(`{"set": [{"uid": "0x2000", "country^city": "x"}]}`,
+		"application/json", false, true, 0)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "can only be set through the predicates it spans")
+	_, _, err = queryWithTs(`{ q(func: eq(<country^city>, "US")) { uid } }`,
+		"application/graphql+-", "", 0)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "requires 2 arguments, but got 1")
+
+	err = alterSchema(`<country^name>: string @index(exact) .`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "spans predicate name, which isn't in the schema")
+}
+
+func TestPartialIndex(t *testing.T) {
+	require.NoError(t, dropAll())
+	require.NoError(t, alterSchema(`status: string @index(exact) @where(ne: "archived") .`))
+	_, err := mutationWithTs(`{ set {
+		<0x3000> <status> "active" .
+		<0x3001> <status> "archived" .
+		<0x3002> <status> "pending" .
+	} }`, "application/rdf", false, true, 0)
+	require.NoError(t, err)
+
+	query := `{
+		eq(func: eq(status, "active", "pending")) { status }
+		filter(func: has(status)) @filter(eq(status, "archived")) { status }
+		sorted(func: has(status), orderasc: status) { status }
+	}`
+	data, _, err := queryWithTs(query, "application/graphql+-", "", 0)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data": {
+		"eq": [{"status": "active"}, {"status": "pending"}],
+		"filter": [{"status": "archived"}],
+		"sorted": [{"status": "active"}, {"status": "archived"}, {"status": "pending"}]
+	}}`, data)
+
+	// The archived values aren't indexed, so they can't be looked up at the root.
+	archived := `{ q(func: eq(status, "archived")) { status } }`
+	_, _, err = queryWithTs(archived, "application/graphql+-", "", 0)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "doesn't have all the values matched by eq(archived)")
+
+	// Changing the condition rebuilds the index.
+	require.NoError(t, alterSchema(`status: string @index(exact) @where(ne: "pending") .`))
+	data, _, err = queryWithTs(archived, "application/graphql+-", "", 0)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data": {"q": [{"status": "archived"}]}}`, data)
+	data, _, err = queryWithTs(`schema(pred: [status]) { index_where }`,
+		"application/graphql+-", "", 0)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data": {"schema": [{"predicate": "status",
+		"index_where": "ne: \"pending\""}]}}`, data)
+}
+
+func TestValueEncoding(t *testing.T) {
+	require.NoError(t, dropAll())
+	require.NoError(t, alterSchema(`
+		color: string @index(exact) @encoding(dictionary) .
+		seq: int @index(int) @encoding(delta) .
+		at: datetime @encoding(delta) .
+	`))
+	_, err := mutationWithTs(`{ set {
+		<0x4000> <color> "red" .
+		<0x4000> <seq> "1000" .
+		<0x4000> <at> "2020-06-01T10:00:00+02:00" .
+		<0x4001> <color> "blue" .
+		<0x4001> <seq> "1001" .
+		<0x4001> <at> "2020-06-01T10:00:05.5Z" .
+		<0x4002> <color> "red" .
+		<0x4002> <seq> "990" .
+	} }`, "application/rdf", false, true, 0)
+	require.NoError(t, err)
+
+	query := `{
+		q(func: eq(color, "red"), orderasc: seq) { color seq at }
+		ge(func: ge(seq, 1000)) { seq }
+	}`
+	data, _, err := queryWithTs(query, "application/graphql+-", "", 0)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data": {
+		"q": [{"color": "red", "seq": 990},
+			{"color": "red", "seq": 1000, "at": "2020-06-01T10:00:00+02:00"}],
+		"ge": [{"seq": 1000}, {"seq": 1001}]
+	}}`, data)
+
+	// Deleting an encoded value removes it from the index as well.
+	_, err = mutationWithTs(`{ delete { <0x4002> <color> "red" . } }`, "application/rdf",
+		false, true, 0)
+	require.NoError(t, err)
+	data, _, err = queryWithTs(`{ q(func: eq(color, "red")) { uid } }`,
+		"application/graphql+-", "", 0)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data": {"q": [{"uid": "0x4000"}]}}`, data)
+
+	data, _, err = queryWithTs(`schema(pred: [color, seq]) { encoding }`,
+		"application/graphql+-", "", 0)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data": {"schema": [{"predicate": "color", "encoding": "dictionary"},
+		{"predicate": "seq", "encoding": "delta"}]}}`, data)
+
+	// The values stored encoded can still be read once the encoding is removed.
+	require.NoError(t, alterSchema(`color: string @index(exact) .`))
+	data, _, err = queryWithTs(`{ q(func: uid(0x4000, 0x4001)) { color at } }`,
+		"application/graphql+-", "", 0)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data": {"q": [
+		{"color": "red", "at": "2020-06-01T10:00:00+02:00"},
+		{"color": "blue", "at": "2020-06-01T10:00:05.5Z"}]}}`, data)
+}
+
+func TestEnumPredicate(t *testing.T) {
+	require.NoError(t, dropAll())
+	require.NoError(t, alterSchema(`state: string @index(hash) @enum(ACTIVE, PENDING) .`))
+	_, err := mutationWithTs(`{ set {
+		<0x4000> <state> "ACTIVE" .
+		<0x4001> <state> "PENDING" .
+	} }`, "application/rdf", false, true, 0)
+	require.NoError(t, err)
+
+	_, err = mutationWithTs(`{ set { <0x4002> <state> "DONE" . } }`, "application/rdf",
+		false, true, 0)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `Value "DONE" isn't one of the values of enum predicate`)
+
+	data, _, err := queryWithTs(`{ q(func: eq(state, "ACTIVE")) { uid state } }`,
+		"application/graphql+-", "", 0)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data": {"q": [{"uid": "0x4000", "state": "ACTIVE"}]}}`, data)
+
+	// New values can be added to the enum later.
+	require.NoError(t, alterSchema(`state: string @index(hash) @enum(ACTIVE, PENDING, DONE) .`))
+	_, err = mutationWithTs(`{ set { <0x4002> <state> "DONE" . } }`, "application/rdf",
+		false, true, 0)
+	require.NoError(t, err)
+
+	data, _, err = queryWithTs(`schema(pred: [state]) { enum_values }`,
+		"application/graphql+-", "", 0)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data": {"schema": [{"predicate": "state",
+		"enum_values": ["ACTIVE", "PENDING", "DONE"]}]}}`, data)
+}
+
+func TestValueCheck(t *testing.T) {
+	require.NoError(t, dropAll())
+	require.NoError(t, alterSchema(`
+		age: int @check(min: 0, max: 150) .
+		handle: string @check(maxlength: 8, pattern: "^[a-z]+$") .
+	`))
+	_, err := mutationWithTs(`{ set {
+		_:a <age> "30" .
+		_:a <handle> "alice" .
+	} }`, "application/rdf", false, true, 0)
+	require.NoError(t, err)
+
+	// The violations are reported in the extensions of the error, with the offending triples.
+	req, err := http.NewRequest("POST", addr+"/mutate?commitNow=true", bytes.NewBufferString(
+		`{ set {
+			_:b <age> "151" .
+			_:b <handle> "Bob" .
+		} }`))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/rdf")
+	req.Header.Set("X-Dgraph-AccessToken", grootAccessJwt)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var qr x.QueryResWithData
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&qr))
+	require.Len(t, qr.Errors, 1)
+	require.Equal(t, x.ErrorValueCheck, qr.Errors[0].Extensions["code"])
+	violations, err := json.Marshal(qr.Errors[0].Extensions["violations"])
+	require.NoError(t, err)
+	require.JSONEq(t, `[
+		{"subject": "_:b", "predicate": "age", "object": "151", "constraint": "max: 150"},
+		{"subject": "_:b", "predicate": "handle", "object": "Bob",
+			"constraint": "pattern: \"^[a-z]+$\""}
+	]`, string(violations))
+
+	data, _, err := queryWithTs(`{ q(func: has(age)) { age handle } }`,
+		"application/graphql+-", "", 0)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data": {"q": [{"age": 30, "handle": "alice"}]}}`, data)
+
+	data, _, err = queryWithTs(`schema(pred: [age]) { check }`, "application/graphql+-", "", 0)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data": {"schema": [{"predicate": "age", "check_min": "0",
+		"check_max": "150"}]}}`, data)
+}
+
+func TestReferences(t *testing.T) {
+	require.NoError(t, dropAll())
+	require.NoError(t, alterSchema(`
+		name: string @index(exact) .
+		owner: uid @reverse @references .
+		member: uid @reverse @references(ondelete: cascade) .
+		friend: uid @reverse @references(ondelete: setnull) .
+		type Person {
+			name
+			friend
+		}
+		type Pet {
+			name
+			owner
+		}
+		type Team {
+			name
+		}
+		type Membership {
+			name
+			member
+		}
+	`))
+	_, err := mutationWithTs(`{ set {
+		_:alice <dgraph.type> "Person" .
+		_:alice <name> "alice" .
+		_:bob <dgraph.type> "Person" .
+		_:bob <name> "bob" .
+		_:bob <friend> _:alice .
+		_:rex <dgraph.type> "Pet" .
+		_:rex <name> "rex" .
+		_:rex <owner> _:alice .
+		_:club <dgraph.type> "Team" .
+		_:club <name> "club" .
+		_:card <dgraph.type> "Membership" .
+		_:card <name> "card" .
+		_:card <member> _:club .
+	} }`, "application/rdf", false, true, 0)
+	require.NoError(t, err)
+
+	// The node pointed to must exist, which is having a type.
+	_, err = mutationWithTs(`{ set {
+		_:ghost <name> "ghost" .
+		_:fido <dgraph.type> "Pet" .
+		_:fido <owner> _:ghost .
+	} }`, "application/rdf", false, true, 0)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "pointed to by predicate owner doesn't exist")
+
+	deleteNode := func(name string) error {
+		_, err := mutationWithTs(fmt.Sprintf(`upsert {
+			query { n as var(func: eq(name, "%s")) }
+			mutation { delete { uid(n) * * . } }
+		}`, name), "application/rdf", false, true, 0)
+		return err
+	}
+
+	// A node can't be deleted while edges of a predicate with restrict point to it.
+	err = deleteNode("alice")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "can't be deleted, as the edges of predicate owner of nodes")
+
+	// The nodes pointing to a deleted node with cascade are deleted.
+	require.NoError(t, deleteNode("club"))
+	data, _, err := queryWithTs(`{ q(func: has(name), orderasc: name) { name } }`,
+		"application/graphql+-", "", 0)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data": {"q": [{"name": "alice"}, {"name": "bob"}, {"name": "rex"}]}}`,
+		data)
+
+	// The edges pointing to a deleted node with setnull are deleted.
+	require.NoError(t, deleteNode("rex"))
+	require.NoError(t, deleteNode("alice"))
+	data, _, err = queryWithTs(`{ q(func: has(name)) { name friend { uid } } }`,
+		"application/graphql+-", "", 0)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data": {"q": [{"name": "bob"}]}}`, data)
+
+	data, _, err = queryWithTs(`schema(pred: [owner, member]) { references }`,
+		"application/graphql+-", "", 0)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data": {"schema": [{"predicate": "member", "references": "cascade"},
+		{"predicate": "owner", "references": "restrict"}]}}`, data)
+}
+
+func TestCasefoldIndex(t *testing.T) {
+	require.NoError(t, dropAll())
+	require.NoError(t, alterSchema(`street: string @index(term) @lang .`))
+	_, err := mutationWithTs(`{ set {
+		_:a <street> "Hauptstraße" .
+		_:b <street> "HAUPTSTRASSE" .
+		_:c <street> "Marktstraße"@de .
+	} }`, "application/rdf", false, true, 0)
+	require.NoError(t, err)
+
+	query := func(q string) string {
+		data, _, err := queryWithTs(q, "application/graphql+-", "", 0)
+		require.NoError(t, err)
+		return data
+	}
+	terms := `{ q(func: anyofterms(street, "hauptstrasse"), orderasc: street) { street } }`
+	require.JSONEq(t, `{"data": {"q": [{"street": "HAUPTSTRASSE"}]}}`, query(terms))
+
+	// Switching to the folded index rebuilds it, and the query is folded in the same way.
+	require.NoError(t, alterSchema(`street: string @index(term(normalize: "nfkc_casefold"), `+
+		`fulltext(normalize: "nfkc_casefold")) @lang .`))
+	require.JSONEq(t, `{"data": {"q": [{"street": "HAUPTSTRASSE"}, {"street": "Hauptstraße"}]}}`,
+		query(terms))
+	require.JSONEq(t, `{"data": {"q": [{"street": "HAUPTSTRASSE"}, {"street": "Hauptstraße"}]}}`,
+		query(`{ q(func: has(street), orderasc: street) @filter(allofterms(street, "HAUPTSTRAßE")) {
+			street
+		} }`))
+	require.JSONEq(t, `{"data": {"q": [{"street@de": "Marktstraße"}]}}`,
+		query(`{ q(func: anyoftext(street@de, "MARKTSTRASSE")) { street@de } }`))
+
+	data := query(`schema(pred: [street]) { tokenizer }`)
+	require.JSONEq(t, `{"data": {"schema": [{"predicate": "street", "tokenizer": `+
+		`["term(normalize: \"nfkc_casefold\")", "fulltext(normalize: \"nfkc_casefold\")"]}]}}`,
+		data)
+}
+
+func TestGeoIndexLevels(t *testing.T) {
+	require.NoError(t, dropAll())
+	require.NoError(t, alterSchema(`loc: geo @index(geo) .`))
+	_, err := mutationWithTs(`{ set {
+		_:a <loc> "{'type':'Point','coordinates':[-122.4220186,37.772318]}"^^<geo:geojson> .
+		_:b <loc> "{'type':'Point','coordinates':[-122.4194155,37.7749295]}"^^<geo:geojson> .
+	} }`, "application/rdf", false, true, 0)
+	require.NoError(t, err)
+
+	query := func(q string) string {
+		data, _, err := queryWithTs(q, "application/graphql+-", "", 0)
+		require.NoError(t, err)
+		return data
+	}
+	near := `{ q(func: near(loc, [-122.4220186, 37.772318], 100)) { count(uid) } }`
+	require.JSONEq(t, `{"data": {"q": [{"count": 1}]}}`, query(near))
+
+	// Changing the levels rebuilds the index, and the queries look it up with the new levels.
+	require.NoError(t,
+		alterSchema(`loc: geo @index(geo(minlevel: 8, maxlevel: 22, maxcells: 30)) .`))
+	require.JSONEq(t, `{"data": {"q": [{"count": 1}]}}`, query(near))
+	require.JSONEq(t, `{"data": {"q": [{"count": 2}]}}`,
+		query(`{ q(func: near(loc, [-122.4220186, 37.772318], 1000)) { count(uid) } }`))
+
+	require.JSONEq(t, `{"data": {"schema": [{"predicate": "loc", "tokenizer": `+
+		`["geo(minlevel: 8, maxlevel: 22, maxcells: 30)"]}]}}`,
+		query(`schema(pred: [loc]) { tokenizer }`))
+}
+
+func querySchemaChanges(t *testing.T, accessJwt string, first int) string {
+	params := &testutil.GraphQLParams{
+		Query: `query changes($first: Int) {
+			querySchemaChanges(first: $first) {
+				operation
+				predicates { name before after }
+				types { name before after }
+				irreversible
+			}
+		}`,
+		Variables: map[string]interface{}{"first": first},
+	}
+	resp := testutil.MakeGQLRequestWithAccessJwt(t, params, accessJwt)
+	resp.RequireNoGraphQLErrors(t)
+	return string(resp.Data)
+}
+
+func rollbackSchemaChange(t *testing.T, accessJwt string) *testutil.GraphQLResponse {
+	params := &testutil.GraphQLParams{
+		Query: `mutation { rollbackSchemaChange { operation } }`,
+	}
+	return testutil.MakeGQLRequestWithAccessJwt(t, params, accessJwt)
+}
+
+func TestSchemaChangeRollback(t *testing.T) {
+	require.NoError(t, dropAll())
+	require.NoError(t, alterSchema(`nick: string .`))
+	require.NoError(t, alterSchema(`
+		nick: string @index(exact) .
+		type Pet {
+			nick
+		}`))
+
+	grootJwt, _ := testutil.GrootHttpLogin(addr + "/admin")
+	require.JSONEq(t, `{"querySchemaChanges": [{
+		"operation": "alter",
+		"predicates": [{"name": "nick", "before": "<nick>:string .",
+			"after": "<nick>:string @index(exact) ."}],
+		"types": [{"name": "Pet", "before": null, "after": "type <Pet> {\n\tnick\n}"}],
+		"irreversible": null
+	}, {
+		"operation": "alter",
+		"predicates": [{"name": "nick", "before": null, "after": "<nick>:string ."}],
+		"types": [],
+		"irreversible": null
+	}]}`, querySchemaChanges(t, grootJwt, 2))
+
+	// Rolling back drops the index and the type added by the last change.
+	resp := rollbackSchemaChange(t, grootJwt)
+	resp.RequireNoGraphQLErrors(t)
+	require.JSONEq(t, `{"rollbackSchemaChange": {"operation": "alter"}}`, string(resp.Data))
+	query := `schema(pred: [nick]) { index }`
+	data, _, err := queryWithTs(query, "application/graphql+-", "", 0)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data": {"schema": [{"predicate": "nick"}]}}`, data)
+	require.JSONEq(t, `{"querySchemaChanges": [{
+		"operation": "rollback",
+		"predicates": [{"name": "nick", "before": "<nick>:string @index(exact) .",
+			"after": "<nick>:string ."}],
+		"types": [{"name": "Pet", "before": "type <Pet> {\n\tnick\n}", "after": null}],
+		"irreversible": null
+	}]}`, querySchemaChanges(t, grootJwt, 1))
+
+	// Rolling back the rollback applies the change again.
+	resp = rollbackSchemaChange(t, grootJwt)
+	resp.RequireNoGraphQLErrors(t)
+	require.JSONEq(t, `{"rollbackSchemaChange": {"operation": "rollback"}}`, string(resp.Data))
+	data, _, err = queryWithTs(query, "application/graphql+-", "", 0)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data": {"schema": [{"predicate": "nick", "index": true}]}}`, data)
+	data, _, err = queryWithTs(`schema(type: Pet) {}`, "application/graphql+-", "", 0)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data": {"types": [{"name": "Pet", "fields": [{"name": "nick"}]}]}}`,
+		data)
+
+	// Predicates added by the last change can't be dropped once they have data.
+	require.NoError(t, alterSchema(`age: int .`))
+	_, err = mutationWithTs(`{ set { _:a <age> "3" . } }`, "application/rdf", false, true, 0)
+	require.NoError(t, err)
+	resp = rollbackSchemaChange(t, grootJwt)
+	require.Len(t, resp.Errors, 1)
+	require.Contains(t, resp.Errors[0].Message, "predicate age has data since it was added")
+
+	// Changes of the type of a predicate can't be rolled back.
+	require.NoError(t, alterSchema(`age: string .`))
+	resp = rollbackSchemaChange(t, grootJwt)
+	require.Len(t, resp.Errors, 1)
+	require.Contains(t, resp.Errors[0].Message,
+		"The type of predicate age was changed from int to string")
+}
+
 func TestOptionsForUiKeywords(t *testing.T) {
 	req, err := http.NewRequest(http.MethodOptions, fmt.Sprintf("%s/ui/keywords", addr), nil)
 	require.NoError(t, err)
@@ -0,0 +1,185 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package alpha
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/pkg/errors"
+)
+
+// jsonPatchVar is the uid variable holding the node a JSON Patch document identified by an
+// external ID applies to.
+const jsonPatchVar = "jsonPatchTarget"
+
+// jsonPatchOp is a single operation of a JSON Patch (RFC 6902) document.
+type jsonPatchOp struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// jsonPatchRequest is the body of a mutation request with the application/json-patch+json
+// content type. The node the patch applies to is given either by its uid, or by a predicate
+// and value that identify it, like a predicate with the @upsert directive would.
+type jsonPatchRequest struct {
+	Uid   string                     `json:"uid"`
+	ID    map[string]json.RawMessage `json:"id"`
+	Patch []jsonPatchOp              `json:"patch"`
+}
+
+// parseJSONPatch converts a JSON Patch request into a request with one JSON mutation per
+// operation, so that the operations are applied in order. If the node is identified by an
+// external ID, the mutations are conditioned on exactly one node having it.
+func parseJSONPatch(body []byte) (*api.Request, error) {
+	var jp jsonPatchRequest
+	if err := json.Unmarshal(body, &jp); err != nil {
+		return nil, convertJSONError(string(body), err)
+	}
+	if len(jp.Patch) == 0 {
+		return nil, errors.Errorf("JSON Patch request has no operations")
+	}
+
+	req := &api.Request{}
+	var subject, cond string
+	switch {
+	case jp.Uid != "" && len(jp.ID) > 0:
+		return nil, errors.Errorf("JSON Patch request should have either uid or id, not both")
+	case jp.Uid != "":
+		if strings.HasPrefix(jp.Uid, "_:") || strings.HasPrefix(jp.Uid, "uid(") {
+			return nil, errors.Errorf("JSON Patch request should target an existing uid, got: %s",
+				jp.Uid)
+		}
+		subject = jp.Uid
+	case len(jp.ID) == 1:
+		for pred, val := range jp.ID {
+			q, vars, err := jsonPatchIDQuery(pred, val)
+			if err != nil {
+				return nil, err
+			}
+			req.Query, req.Vars = q, vars
+		}
+		subject = "uid(" + jsonPatchVar + ")"
+		cond = "@if(eq(len(" + jsonPatchVar + "), 1))"
+	default:
+		return nil, errors.Errorf("JSON Patch request should identify the node by uid or by id " +
+			"with a single predicate")
+	}
+
+	for i, op := range jp.Patch {
+		mu, err := jsonPatchMutation(subject, op)
+		if err != nil {
+			return nil, errors.Wrapf(err, "while parsing operation %d", i)
+		}
+		mu.Cond = cond
+		req.Mutations = append(req.Mutations, mu)
+	}
+	return req, nil
+}
+
+// jsonPatchIDQuery returns the query storing the node with the given value of pred in
+// jsonPatchVar, along with its variables.
+func jsonPatchIDQuery(pred string, val json.RawMessage) (string, map[string]string, error) {
+	if pred == "" || strings.ContainsAny(pred, "<> \t\n") {
+		return "", nil, errors.Errorf("invalid predicate in JSON Patch id: %q", pred)
+	}
+	var v interface{}
+	if err := json.Unmarshal(val, &v); err != nil {
+		return "", nil, err
+	}
+	var id string
+	switch v := v.(type) {
+	case string:
+		id = v
+	case float64, bool:
+		id = string(val)
+	default:
+		return "", nil, errors.Errorf("JSON Patch id of %s should be a string, a number or "+
+			"a boolean", pred)
+	}
+
+	q := fmt.Sprintf(`query q($id: string) { q(func: eq(<%s>, $id)) { %s as uid } }`,
+		pred, jsonPatchVar)
+	return q, map[string]string{"$id": id}, nil
+}
+
+// jsonPatchMutation returns the JSON mutation applying the operation to the subject.
+func jsonPatchMutation(subject string, op jsonPatchOp) (*api.Mutation, error) {
+	pred, isAppend, err := jsonPatchPredicate(op.Path)
+	if err != nil {
+		return nil, err
+	}
+	if isAppend && op.Op != "add" {
+		return nil, errors.Errorf("JSON Patch path %q can only be used with add", op.Path)
+	}
+	hasValue := len(op.Value) > 0
+
+	uid, err := json.Marshal(subject)
+	if err != nil {
+		return nil, err
+	}
+	node := func(val json.RawMessage) ([]byte, error) {
+		return json.Marshal(map[string]json.RawMessage{"uid": uid, pred: val})
+	}
+	mu := &api.Mutation{}
+	switch op.Op {
+	case "add", "replace":
+		if !hasValue {
+			return nil, errors.Errorf("%s operation on %s requires a value", op.Op, op.Path)
+		}
+		if mu.SetJson, err = node(op.Value); err != nil {
+			return nil, err
+		}
+		if op.Op == "replace" {
+			mu.DeleteJson, err = node(json.RawMessage("null"))
+		}
+	case "remove":
+		// The value is an extension to RFC 6902. As the lists of Dgraph aren't ordered, the
+		// element to remove from a list is given by its value instead of by its index.
+		val := op.Value
+		if !hasValue {
+			val = json.RawMessage("null")
+		}
+		mu.DeleteJson, err = node(val)
+	default:
+		return nil, errors.Errorf("unsupported JSON Patch operation %q, supported operations "+
+			"are add, remove and replace", op.Op)
+	}
+	return mu, err
+}
+
+// jsonPatchPredicate returns the predicate referred to by the JSON Pointer path, which is
+// either /<predicate> or, to add to a list, /<predicate>/-. The returned bool is true for the
+// latter.
+func jsonPatchPredicate(path string) (string, bool, error) {
+	if !strings.HasPrefix(path, "/") {
+		return "", false, errors.Errorf("JSON Patch path should start with /, got: %q", path)
+	}
+	tokens := strings.Split(path[1:], "/")
+	if len(tokens) > 2 || (len(tokens) == 2 && tokens[1] != "-") {
+		return "", false, errors.Errorf("JSON Patch path should refer to a predicate of "+
+			"the node, got: %q", path)
+	}
+	pred := strings.NewReplacer("~1", "/", "~0", "~").Replace(tokens[0])
+	if pred == "" || pred == "uid" {
+		return "", false, errors.Errorf("JSON Patch path can't refer to %q", pred)
+	}
+	return pred, len(tokens) == 2, nil
+}
@@ -40,6 +40,7 @@ import (
 	"github.com/dgraph-io/dgraph/edgraph"
 	"github.com/dgraph-io/dgraph/ee/enc"
 	"github.com/dgraph-io/dgraph/graphql/admin"
+	"github.com/dgraph-io/dgraph/graphql/lambda"
 	"github.com/dgraph-io/dgraph/graphql/web"
 	"github.com/dgraph-io/dgraph/posting"
 	"github.com/dgraph-io/dgraph/protos/pb"
@@ -108,8 +109,21 @@ they form a Raft group and provide synchronous replication.
 	x.FillCommonFlags(flag)
 
 	flag.StringP("postings", "p", "p", "Directory to store posting lists.")
+	flag.Bool("in_memory", false,
+		"Keep all the data in memory, without using the postings and WAL directories. The"+
+			" data is volatile: it's lost once the Alpha stops.")
+	flag.Bool("read_only", false,
+		"Open an existing postings directory read-only and serve queries from it without"+
+			" joining a cluster: Zero isn't contacted, and mutations and schema changes are"+
+			" rejected.")
 
 	// Options around how to set up Badger.
+	flag.String("badger.tables", worker.IOModeMmap,
+		"I/O mode of the tables of the postings: mmap reads them from the disk as they're"+
+			" accessed, ram also reads them into the page cache as soon as they're written.")
+	flag.String("badger.vlog", worker.IOModeMmap,
+		"I/O mode of the value log files of the postings: mmap reads them from the disk as"+
+			" they're accessed, ram also reads them into the page cache as soon as they're written.")
 	flag.String("badger.compression", "snappy",
 		"[none, zstd:level, snappy] Specifies the compression algorithm and the compression"+
 			"level (if applicable) for the postings directory. none would disable compression,"+
@@ -124,6 +138,12 @@ they form a Raft group and provide synchronous replication.
 	flag.String("abort_older_than", "5m",
 		"Abort any pending transactions older than this duration. The liveness of a"+
 			" transaction is determined by its last mutation.")
+	flag.String("history_retention", "0s",
+		"Keep the versions of the data overwritten within this duration, so that read-only"+
+			" queries can read the data as of a past time or timestamp. 0s disables it.")
+	flag.Bool("uid_bitmaps", false,
+		"Store the dense blocks of uids of the posting lists as bitmaps. Older versions of"+
+			" Alpha can't read them, so only set it once all the Alphas have been upgraded.")
 
 	flag.StringP("wal", "w", "w", "Directory to store raft write-ahead logs.")
 	flag.String("whitelist", "",
@@ -131,12 +151,54 @@ they form a Raft group and provide synchronous replication.
 			"wish to whitelist for performing admin actions (i.e., --whitelist 144.142.126.254,"+
 			"127.0.0.1:127.0.0.3,192.168.0.0/16,host.docker.internal)")
 	flag.String("export", "export", "Folder in which to store exports.")
+	flag.String("tier_location", "",
+		"Directory, or s3:// or minio:// URI, the data of the predicates offloaded to the cold"+
+			" storage tier is uploaded to. It must be reachable by all the Alphas.")
+	flag.Duration("tier_after", 0,
+		"How long a predicate must not be read or written before its data is offloaded to the"+
+			" cold storage tier. Set to 0 to disable offloading.")
+	flag.String("maintenance_windows", "",
+		"Comma separated daily windows in UTC, as HH:MM-HH:MM, in which the value log GC and the"+
+			" full compactions of the postings run. They run at any time if empty.")
+	flag.Int("vlog_gc_max_runs", 0,
+		"Most value log files rewritten by the value log GC every minute. Set to 0 for no limit.")
+	flag.Float64("vlog_gc_discard_ratio", 0.7,
+		"Fraction of a value log file that has to be discarded for the value log GC to rewrite it.")
+	flag.Duration("compaction_interval", 0,
+		"How often the postings are fully compacted within the maintenance windows. Set to 0 to"+
+			" only compact them when asked through /admin/maintenance.")
+	flag.Duration("integrity_check_interval", 0,
+		"How often the checksums of the postings, and the consistency of their data and indexes,"+
+			" are verified within the maintenance windows. Set to 0 to only verify them when asked"+
+			" through /admin/integrity.")
+	flag.Int("integrity_check_rate", 10000,
+		"Most posting lists read per second when verifying the postings. Set to 0 for no limit.")
+	flag.Int64("snapshot_send_rate_mb", 0,
+		"Most MB per second sent by the snapshots streamed to the other members of the group."+
+			" Set to 0 for no limit.")
+	flag.Int64("snapshot_receive_rate_mb", 0,
+		"Most MB per second received by the snapshots streamed from the other members of the"+
+			" group. Set to 0 for no limit.")
+	flag.Int("snapshot_goroutines", 16,
+		"Number of goroutines reading the data of a snapshot streamed to another member of the"+
+			" group.")
 	flag.Int("pending_proposals", 256,
 		"Number of pending mutation proposals. Useful for rate limiting.")
 	flag.StringP("zero", "z", fmt.Sprintf("localhost:%d", x.PortZeroGrpc),
 		"Comma separated list of Dgraph zero addresses of the form IP_ADDRESS:PORT.")
 	flag.Uint64("idx", 0,
 		"Optional Raft ID that this Dgraph Alpha will use to join RAFT groups.")
+	flag.Bool("learner", false,
+		"Join a group as a Raft learner, which replicates the data of the group and serves"+
+			" queries, but doesn't vote. Learners don't count towards the replicas of a group.")
+	flag.Uint32("learner_group", 0,
+		"Group a learner joins. If 0, Zero picks the existing group with the fewest learners.")
+	flag.Duration("learner_max_staleness", 0,
+		"How far behind the leader of its group a learner can be for its read-only queries to"+
+			" read its data without getting a timestamp from Zero. Set to 0 to always get one.")
+	flag.Duration("replication_interval", 30*time.Second,
+		"How often a replica cluster replicates the data committed in its primary cluster."+
+			" The cluster is made a replica through the replica_of flag of Zero.")
 	flag.Int("max_retries", -1,
 		"Commits to disk will give up after these number of retries to prevent locking the worker"+
 			" in a failed state. Use -1 to retry infinitely.")
@@ -165,8 +227,42 @@ they form a Raft group and provide synchronous replication.
 	flag.Uint64("normalize_node_limit", 1e4,
 		"Limit for the maximum number of nodes that can be returned in a query that uses the "+
 			"normalize directive.")
+	flag.Duration("query_timeout_limit", 0,
+		"Maximum execution timeout for a query. Per-request timeouts higher than this are"+
+			" capped to it. Set to 0 to disable.")
+	flag.Uint64("query_node_limit", 0,
+		"Maximum number of nodes and edges that a query can expand. The query stops as soon"+
+			" as it goes over it. Per-request limits higher than this are capped to it."+
+			" Set to 0 to disable.")
+	flag.Uint64("query_depth_limit", 0,
+		"Maximum number of uid edges a query can traverse from its root, including @recurse"+
+			" and shortest path depth. Per-request"+
+			" limits higher than this are capped to it. Set to 0 to disable.")
+	flag.Uint64("query_cost_budget", 0,
+		"Maximum estimated cost (uids scanned times predicates touched) of a query."+
+			" Set to 0 to disable the cost check.")
+	flag.Int("query_cost_queue", 0,
+		"Number of queries over the cost budget that are allowed to run concurrently; the"+
+			" rest are queued. Set to 0 to reject queries over the budget instead.")
+	flag.Int64("query_memory_mb", 0,
+		"Memory in MB that the intermediate results of all the queries being run can take."+
+			" Sorts going over it spill to disk, other queries going over it fail."+
+			" Set to 0 to disable.")
+	flag.String("query_spill_dir", "",
+		"Directory where the sorts going over query_memory_mb spill to. Defaults to the"+
+			" directory for temporary files.")
 	flag.Uint64("mutations_nquad_limit", 1e6,
 		"Limit for the maximum number of nquads that can be inserted in a mutation request")
+	flag.Duration("idempotency_window", 10*time.Minute,
+		"Duration for which the outcome of a commit made with an idempotency key is returned to"+
+			" the retries of the commit, instead of applying them. Set to 0 to disable.")
+	flag.Duration("ttl_interval", time.Minute,
+		"How often the data of predicates and types with @ttl is checked for expired values."+
+			" Set to 0 to disable the expiry.")
+	flag.Int("ttl_batch_size", 1000,
+		"Number of nodes whose expired data is deleted per transaction.")
+	flag.Duration("ttl_batch_delay", 100*time.Millisecond,
+		"Pause between two transactions deleting expired data, to throttle the expiry.")
 
 	// TLS configurations
 	flag.String("tls_dir", "", "Path to directory that has TLS certificates and keys.")
@@ -175,7 +271,10 @@ they form a Raft group and provide synchronous replication.
 
 	//Custom plugins.
 	flag.String("custom_tokenizers", "",
-		"Comma separated list of tokenizer plugins")
+		"Comma separated list of tokenizer plugins, as Go plugin .so files or WebAssembly .wasm "+
+			"files")
+	flag.Duration("custom_tokenizer_timeout", time.Second,
+		"Time limit for each call to a WebAssembly tokenizer plugin.")
 
 	// By default Go GRPC traces all requests.
 	grpc.EnableTracing = false
@@ -191,8 +290,39 @@ they form a Raft group and provide synchronous replication.
 	flag.Duration("graphql_poll_interval", time.Second, "polling interval for graphql subscription.")
 	flag.String("graphql_lambda_url", "",
 		"URL of lambda server that implements custom GraphQL JavaScript resolvers")
+	flag.String("graphql_lambda_script", "",
+		"JavaScript file that implements custom GraphQL JavaScript resolvers and mutation hook "+
+			"webhooks, run inside Alpha instead of by a lambda server.")
+	flag.Duration("graphql_lambda_timeout", 10*time.Second,
+		"Maximum duration of a call to a function of the lambda script.")
+	flag.Int64("graphql_lambda_memory_mb", 64,
+		"Maximum growth of the heap during a call to a function of the lambda script, in MB. "+
+			"0 means no limit.")
+	flag.Int("graphql_persisted_query_cache_size", 1000,
+		"Number of GraphQL persisted queries to keep in memory on each Alpha.")
+	flag.Bool("graphql_persisted_queries_only", false,
+		"Set to true to only serve GraphQL persisted queries that were added through /admin.")
+	flag.Int64("graphql_max_complexity", 0,
+		"Maximum complexity of a GraphQL request. Each field adds 1, or the value of its "+
+			"@complexity directive, and list fields multiply the complexity of their selection "+
+			"set. A value of 0 means no limit.")
+	flag.Int64("graphql_complexity_list_multiplier", 10,
+		"Multiplier of the complexity of the selection set of a GraphQL list field that has no "+
+			"first argument or @complexity multiplier.")
+	flag.String("graphql_error_redaction", "NONE",
+		"Which messages of errors to redact from GraphQL responses. NONE returns all messages, "+
+			"INTERNAL replaces the messages of internal errors by a generic message and logs them.")
+	flag.String("mutation_hooks", "",
+		"Path to a JSON file configuring HTTP endpoints to be called before or after the commit"+
+			" of the mutations touching some predicates or types.")
 
 	// Cache flags
+	flag.String("posting_cache_pin", "",
+		"Comma separated list of predicates whose posting lists are kept in the posting list"+
+			" cache until they change, whatever its size. For small and frequently read predicates.")
+	flag.String("posting_cache_exclude", "",
+		"Comma separated list of predicates whose posting lists are never cached, so that scans"+
+			" over large predicates don't evict the lists of the others.")
 	flag.String("cache_percentage", "0,65,35,0",
 		`Cache percentages summing up to 100 for various caches (FORMAT:
 		PostingListCache,PstoreBlockCache,PstoreIndexCache,WAL).`)
@@ -203,8 +333,13 @@ func setupCustomTokenizers() {
 	if customTokenizers == "" {
 		return
 	}
-	for _, soFile := range strings.Split(customTokenizers, ",") {
-		tok.LoadCustomTokenizer(soFile)
+	timeout := Alpha.Conf.GetDuration("custom_tokenizer_timeout")
+	for _, file := range strings.Split(customTokenizers, ",") {
+		if strings.HasSuffix(file, ".wasm") {
+			tok.LoadWasmTokenizer(file, timeout)
+			continue
+		}
+		tok.LoadCustomTokenizer(file)
 	}
 }
 
@@ -368,6 +503,17 @@ func storeStatsHandler(w http.ResponseWriter, r *http.Request) {
 	x.Check2(w.Write([]byte("</pre>")))
 }
 
+// splitPredicates returns the predicates of a comma separated list.
+func splitPredicates(str string) []string {
+	var preds []string
+	for _, pred := range strings.Split(str, ",") {
+		if pred = strings.TrimSpace(pred); pred != "" {
+			preds = append(preds, pred)
+		}
+	}
+	return preds
+}
+
 func setupListener(addr string, port int) (net.Listener, error) {
 	return net.Listen("tcp", fmt.Sprintf("%s:%d", addr, port))
 }
@@ -418,6 +564,13 @@ func serveHTTP(l net.Listener, tlsCfg *tls.Config, closer *z.Closer) {
 	}
 }
 
+// lambdaClient runs the DQL requests of the lambda script like the ones of the clients, and its
+// GraphQL requests on the main GraphQL server.
+type lambdaClient struct {
+	*edgraph.Server
+	web.IServeGraphQL
+}
+
 func setupServer(closer *z.Closer) {
 	go worker.RunServer(bindall) // For pb.communication.
 
@@ -445,6 +598,11 @@ func setupServer(closer *z.Closer) {
 	http.HandleFunc("/query/", queryHandler)
 	http.HandleFunc("/mutate", mutationHandler)
 	http.HandleFunc("/mutate/", mutationHandler)
+	http.HandleFunc("/mutateBatch", mutateBatchHandler)
+	http.HandleFunc("/deleteByQuery", deleteByQueryHandler)
+	http.HandleFunc("/cas", compareAndSetHandler)
+	http.HandleFunc("/leaseUids", leaseUidsHandler)
+	http.HandleFunc("/blob", blobHandler)
 	http.HandleFunc("/commit", commitHandler)
 	http.HandleFunc("/alter", alterHandler)
 	http.HandleFunc("/health", healthCheck)
@@ -473,6 +631,13 @@ func setupServer(closer *z.Closer) {
 	var gqlHealthStore *admin.GraphQLHealthStore
 	// Do not use := notation here because adminServer is a global variable.
 	mainServer, adminServer, gqlHealthStore = admin.NewServers(introspection, &globalEpoch, closer)
+	if x.Config.GraphqlLambdaScript != "" {
+		x.Checkf(lambda.Init(lambda.Config{
+			Script:      x.Config.GraphqlLambdaScript,
+			Timeout:     Alpha.Conf.GetDuration("graphql_lambda_timeout"),
+			MemoryLimit: uint64(Alpha.Conf.GetInt64("graphql_lambda_memory_mb")) << 20,
+		}, lambdaClient{&edgraph.Server{}, mainServer}), "could not load lambda script")
+	}
 	http.Handle("/graphql", mainServer.HTTPHandler())
 	http.HandleFunc("/probe/graphql", func(w http.ResponseWriter, r *http.Request) {
 		healthStatus := gqlHealthStore.GetHealth()
@@ -524,6 +689,21 @@ func setupServer(closer *z.Closer) {
 		drainingHandler(w, r, adminServer)
 	}))))
 
+	http.Handle("/admin/maintenance", allowedMethodsHandler(allowedMethods{
+		http.MethodPut:  true,
+		http.MethodPost: true,
+	}, adminAuthHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		maintenanceHandler(w, r, adminServer)
+	}))))
+
+	http.Handle("/admin/integrity", allowedMethodsHandler(allowedMethods{
+		http.MethodGet:  true,
+		http.MethodPut:  true,
+		http.MethodPost: true,
+	}, adminAuthHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		integrityHandler(w, r, adminServer)
+	}))))
+
 	http.Handle("/admin/export", allowedMethodsHandler(allowedMethods{http.MethodGet: true},
 		adminAuthHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			exportHandler(w, r, adminServer)
@@ -603,16 +783,56 @@ func run() {
 	opts := worker.Options{
 		PostingDir:                 Alpha.Conf.GetString("postings"),
 		WALDir:                     Alpha.Conf.GetString("wal"),
+		InMemory:                   Alpha.Conf.GetBool("in_memory"),
+		ReadOnly:                   Alpha.Conf.GetBool("read_only"),
 		PostingDirCompression:      ctype,
 		PostingDirCompressionLevel: clevel,
 		CachePercentage:            cachePercentage,
+		CacheMb:                    totalCache,
 		PBlockCacheSize:            pstoreBlockCacheSize,
 		PIndexCacheSize:            pstoreIndexCacheSize,
 		WalCache:                   walCache,
+		VlogGCMaxRuns:              Alpha.Conf.GetInt("vlog_gc_max_runs"),
+		VlogGCDiscardRatio:         Alpha.Conf.GetFloat64("vlog_gc_discard_ratio"),
+		CompactionInterval:         Alpha.Conf.GetDuration("compaction_interval"),
+		TableIOMode:                Alpha.Conf.GetString("badger.tables"),
+		VlogIOMode:                 Alpha.Conf.GetString("badger.vlog"),
+		IntegrityCheckInterval:     Alpha.Conf.GetDuration("integrity_check_interval"),
+		IntegrityCheckRate:         Alpha.Conf.GetInt("integrity_check_rate"),
+		SnapshotSendRate:           Alpha.Conf.GetInt64("snapshot_send_rate_mb") << 20,
+		SnapshotReceiveRate:        Alpha.Conf.GetInt64("snapshot_receive_rate_mb") << 20,
+		SnapshotGoroutines:         Alpha.Conf.GetInt("snapshot_goroutines"),
 
 		MutationsMode: worker.AllowMutations,
 		AuthToken:     Alpha.Conf.GetString("auth_token"),
 	}
+	if opts.MaintenanceWindows, err = worker.ParseMaintenanceWindows(
+		Alpha.Conf.GetString("maintenance_windows")); err != nil {
+		glog.Errorf("Invalid maintenance_windows: %v", err)
+		return
+	}
+	if err := worker.ValidateIOMode(opts.TableIOMode); err != nil {
+		glog.Errorf("Invalid badger.tables: %v", err)
+		return
+	}
+	if err := worker.ValidateIOMode(opts.VlogIOMode); err != nil {
+		glog.Errorf("Invalid badger.vlog: %v", err)
+		return
+	}
+	if opts.VlogGCDiscardRatio <= 0 || opts.VlogGCDiscardRatio >= 1 {
+		glog.Errorf("Invalid vlog_gc_discard_ratio: %v. It must be between 0 and 1",
+			opts.VlogGCDiscardRatio)
+		return
+	}
+	if opts.SnapshotSendRate < 0 || opts.SnapshotReceiveRate < 0 {
+		glog.Errorf("snapshot_send_rate_mb and snapshot_receive_rate_mb can't be negative")
+		return
+	}
+	if opts.SnapshotGoroutines <= 0 {
+		glog.Errorf("snapshot_goroutines must be greater than zero, got: %d",
+			opts.SnapshotGoroutines)
+		return
+	}
 
 	secretFile := Alpha.Conf.GetString("acl_secret_file")
 	if secretFile != "" {
@@ -650,6 +870,8 @@ func run() {
 
 	abortDur, err := time.ParseDuration(Alpha.Conf.GetString("abort_older_than"))
 	x.Check(err)
+	retention, err := time.ParseDuration(Alpha.Conf.GetString("history_retention"))
+	x.Check(err)
 
 	x.WorkerConfig = x.WorkerOptions{
 		ExportPath:           Alpha.Conf.GetString("export"),
@@ -661,12 +883,45 @@ func run() {
 		StrictMutations:      opts.MutationsMode == worker.StrictMutations,
 		AclEnabled:           secretFile != "",
 		SnapshotAfter:        Alpha.Conf.GetInt("snapshot_after"),
+		TierLocation:         Alpha.Conf.GetString("tier_location"),
+		TierAfter:            Alpha.Conf.GetDuration("tier_after"),
 		AbortOlderThan:       abortDur,
+		HistoryRetention:     retention,
 		StartTime:            startTime,
 		LudicrousMode:        Alpha.Conf.GetBool("ludicrous_mode"),
 		LudicrousConcurrency: Alpha.Conf.GetInt("ludicrous_concurrency"),
+		Learner:              Alpha.Conf.GetBool("learner"),
+		LearnerGroup:         cast.ToUint32(Alpha.Conf.GetString("learner_group")),
+		LearnerMaxStaleness:  Alpha.Conf.GetDuration("learner_max_staleness"),
+		ReplicationInterval:  Alpha.Conf.GetDuration("replication_interval"),
+		UidBitmaps:           Alpha.Conf.GetBool("uid_bitmaps"),
 	}
 	x.WorkerConfig.Parse(Alpha.Conf)
+	if opts.InMemory {
+		// There's nothing to survive a crash, so the writes are never synced.
+		x.WorkerConfig.HardSync = false
+	}
+	if opts.InMemory && opts.ReadOnly {
+		glog.Errorf("in_memory and read_only can't be set together")
+		return
+	}
+	if x.WorkerConfig.Learner && opts.ReadOnly {
+		glog.Errorf("learner and read_only can't be set together")
+		return
+	}
+	if !x.WorkerConfig.Learner &&
+		(x.WorkerConfig.LearnerGroup > 0 || x.WorkerConfig.LearnerMaxStaleness > 0) {
+		glog.Errorf("learner must be set to use learner_group or learner_max_staleness")
+		return
+	}
+	if x.WorkerConfig.ReplicationInterval <= 0 {
+		glog.Errorf("replication_interval must be greater than zero")
+		return
+	}
+	if x.WorkerConfig.TierAfter > 0 && x.WorkerConfig.TierLocation == "" {
+		glog.Errorf("tier_location must be set to offload predicates after tier_after")
+		return
+	}
 
 	if x.WorkerConfig.EncryptionKey, err = enc.ReadKey(Alpha.Conf); err != nil {
 		glog.Infof("unable to read key %v", err)
@@ -678,11 +933,38 @@ func run() {
 	x.Config.PortOffset = Alpha.Conf.GetInt("port_offset")
 	x.Config.QueryEdgeLimit = cast.ToUint64(Alpha.Conf.GetString("query_edge_limit"))
 	x.Config.NormalizeNodeLimit = cast.ToInt(Alpha.Conf.GetString("normalize_node_limit"))
+	x.Config.QueryTimeoutLimit = Alpha.Conf.GetDuration("query_timeout_limit")
+	x.Config.QueryNodeLimit = cast.ToUint64(Alpha.Conf.GetString("query_node_limit"))
+	x.Config.QueryDepthLimit = cast.ToUint64(Alpha.Conf.GetString("query_depth_limit"))
+	x.Config.QueryCostBudget = cast.ToUint64(Alpha.Conf.GetString("query_cost_budget"))
+	x.Config.QueryCostQueue = Alpha.Conf.GetInt("query_cost_queue")
+	x.Config.QueryMemoryLimit = Alpha.Conf.GetInt64("query_memory_mb") << 20
+	x.Config.QuerySpillDir = Alpha.Conf.GetString("query_spill_dir")
 	x.Config.MutationsNQuadLimit = cast.ToInt(Alpha.Conf.GetString("mutations_nquad_limit"))
+	x.Config.IdempotencyWindow = Alpha.Conf.GetDuration("idempotency_window")
+	x.Config.TTLInterval = Alpha.Conf.GetDuration("ttl_interval")
+	x.Config.TTLBatchSize = Alpha.Conf.GetInt("ttl_batch_size")
+	x.Config.TTLBatchDelay = Alpha.Conf.GetDuration("ttl_batch_delay")
+	if x.Config.TTLBatchSize <= 0 {
+		glog.Errorf("ttl_batch_size should be a positive number, got: %d", x.Config.TTLBatchSize)
+		return
+	}
 	x.Config.PollInterval = Alpha.Conf.GetDuration("graphql_poll_interval")
 	x.Config.GraphqlExtension = Alpha.Conf.GetBool("graphql_extensions")
 	x.Config.GraphqlDebug = Alpha.Conf.GetBool("graphql_debug")
 	x.Config.GraphqlLambdaUrl = Alpha.Conf.GetString("graphql_lambda_url")
+	x.Config.GraphqlLambdaScript = Alpha.Conf.GetString("graphql_lambda_script")
+	x.Config.GraphqlPersistedQueryCacheSize = Alpha.Conf.GetInt("graphql_persisted_query_cache_size")
+	x.Config.GraphqlPersistedQueriesOnly = Alpha.Conf.GetBool("graphql_persisted_queries_only")
+	x.Config.GraphqlMaxComplexity = Alpha.Conf.GetInt64("graphql_max_complexity")
+	x.Config.GraphqlComplexityListMultiplier =
+		Alpha.Conf.GetInt64("graphql_complexity_list_multiplier")
+	x.Config.GraphqlErrorRedaction = strings.ToUpper(Alpha.Conf.GetString("graphql_error_redaction"))
+	if x.Config.GraphqlErrorRedaction != "NONE" && x.Config.GraphqlErrorRedaction != "INTERNAL" {
+		glog.Errorf("expecting graphql_error_redaction to be NONE or INTERNAL, got: %s",
+			x.Config.GraphqlErrorRedaction)
+		return
+	}
 	if x.Config.GraphqlLambdaUrl != "" {
 		graphqlLambdaUrl, err := url.Parse(x.Config.GraphqlLambdaUrl)
 		if err != nil {
@@ -696,6 +978,27 @@ func run() {
 		}
 	}
 
+	if x.Config.GraphqlLambdaScript != "" {
+		if x.Config.GraphqlLambdaUrl != "" {
+			glog.Errorf("graphql_lambda_url and graphql_lambda_script can't be set together")
+			return
+		}
+		if Alpha.Conf.GetDuration("graphql_lambda_timeout") <= 0 {
+			glog.Errorf("graphql_lambda_timeout should be positive, got: %s",
+				Alpha.Conf.GetDuration("graphql_lambda_timeout"))
+			return
+		}
+		if Alpha.Conf.GetInt64("graphql_lambda_memory_mb") < 0 {
+			glog.Errorf("graphql_lambda_memory_mb can't be negative, got: %d",
+				Alpha.Conf.GetInt64("graphql_lambda_memory_mb"))
+			return
+		}
+	}
+
+	if hooksFile := Alpha.Conf.GetString("mutation_hooks"); hooksFile != "" {
+		x.Check(edgraph.LoadMutationHooks(hooksFile))
+	}
+
 	x.PrintVersion()
 	glog.Infof("x.Config: %+v", x.Config)
 	glog.Infof("x.WorkerConfig: %+v", x.WorkerConfig)
@@ -718,6 +1021,8 @@ func run() {
 	// schema before calling posting.Init().
 	schema.Init(worker.State.Pstore)
 	posting.Init(worker.State.Pstore, postingListCacheSize)
+	posting.SetCachePolicy(splitPredicates(Alpha.Conf.GetString("posting_cache_pin")),
+		splitPredicates(Alpha.Conf.GetString("posting_cache_exclude")))
 	defer posting.Cleanup()
 	worker.Init(worker.State.Pstore)
 
@@ -753,16 +1058,27 @@ func run() {
 		}
 	}()
 
-	updaters := z.NewCloser(4)
+	updaters := z.NewCloser(5)
 	go func() {
-		worker.StartRaftNodes(worker.State.WALstore, bindall)
-		atomic.AddUint32(&initDone, 1)
-
-		// initialization of the admin account can only be done after raft nodes are running
-		// and health check passes
-		edgraph.ResetAcl(updaters)
+		if worker.Config.ReadOnly {
+			worker.StartReadOnly()
+			atomic.AddUint32(&initDone, 1)
+			// Nothing can be written, so the expired values, the groot account and the cors
+			// origins are left as they're stored.
+			updaters.Done()
+			updaters.Done()
+			updaters.Done()
+		} else {
+			worker.StartRaftNodes(worker.State.WALstore, bindall)
+			atomic.AddUint32(&initDone, 1)
+			go edgraph.ExpireTTLs(updaters)
+
+			// initialization of the admin account can only be done after raft nodes are running
+			// and health check passes
+			edgraph.ResetAcl(updaters)
+			edgraph.ResetCors(updaters)
+		}
 		edgraph.RefreshAcls(updaters)
-		edgraph.ResetCors(updaters)
 		// Update the accepted cors origins.
 		for updaters.Ctx().Err() == nil {
 			origins, err := edgraph.GetCorsOrigins(updaters.Ctx())
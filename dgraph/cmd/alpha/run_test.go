@@ -248,6 +248,7 @@ func TestDeletePredicate(t *testing.T) {
 		`{"predicate":"age","type":"default"},`+
 		`{"predicate":"name","type":"string","index":true, "tokenizer":["term"]},`+
 		x.AclPredicates+","+x.GraphqlPredicates+","+x.CorsPredicate+","+
+		x.SchemaHistoryPredicates+","+
 		`{"predicate":"dgraph.type","type":"string","index":true, "tokenizer":["exact"],
 			"list":true}],`+x.InitialTypes+`}}`, output)
 
@@ -1078,6 +1079,7 @@ func TestListTypeSchemaChange(t *testing.T) {
 	require.NoError(t, err)
 	testutil.CompareJSON(t, `{"data":{"schema":[`+
 		x.AclPredicates+","+x.GraphqlPredicates+","+x.CorsPredicate+","+
+		x.SchemaHistoryPredicates+","+
 		`{"predicate":"occupations","type":"string"},`+
 		`{"predicate":"dgraph.type", "type":"string", "index":true, "tokenizer": ["exact"],
 			"list":true}],`+x.InitialTypes+`}}`, res)
@@ -1326,6 +1328,7 @@ func TestDropAll(t *testing.T) {
 	testutil.CompareJSON(t,
 		`{"data":{"schema":[`+
 			x.AclPredicates+","+x.GraphqlPredicates+","+x.CorsPredicate+","+
+			x.SchemaHistoryPredicates+","+
 			`{"predicate":"dgraph.type", "type":"string", "index":true, "tokenizer":["exact"],
 				"list":true}],`+x.InitialTypes+`}}`, output)
 
@@ -992,6 +992,125 @@ upsert {
 	require.Contains(t, res, "Ashish")
 }
 
+func TestConditionalUpsertElseIfElse(t *testing.T) {
+	require.NoError(t, dropAll())
+	require.NoError(t, alterSchema(`email: string @index(exact) .`))
+
+	m1 := `
+upsert {
+  query {
+    q(func: eq(email, "email@company.io")) {
+      v as uid
+    }
+  }
+
+  mutation @if(eq(len(v), 0)) {
+    set {
+      _:user <name> "Created" .
+      _:user <email> "email@company.io" .
+    }
+  }
+
+  mutation @elseif(eq(len(v), 1)) {
+    set {
+      uid(v) <name> "Updated" .
+    }
+  }
+
+  mutation @else {
+    set {
+      _:conflict <conflict> "email@company.io" .
+    }
+  }
+}`
+	// The user doesn't exist, so it's created.
+	mr, err := mutationWithTs(m1, "application/rdf", false, true, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"email", "name"}, splitPreds(mr.preds))
+
+	// The user exists, so it's updated.
+	mr, err = mutationWithTs(m1, "application/rdf", false, true, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"name"}, splitPreds(mr.preds))
+
+	q1 := `
+{
+  q(func: eq(email, "email@company.io")) {
+    name
+  }
+}`
+	res, _, err := queryWithTs(q1, "application/graphql+-", "", 0)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data": {"q": [{"name": "Updated"}]}}`, res)
+
+	// There are two users with the same email, so the conflict is logged.
+	_, err = mutationWithTs(`{ set { _:other <email> "email@company.io" . } }`,
+		"application/rdf", false, true, 0)
+	require.NoError(t, err)
+	mr, err = mutationWithTs(m1, "application/rdf", false, true, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"conflict"}, splitPreds(mr.preds))
+}
+
+func TestUpsertEmptyVarRefersToBlankNode(t *testing.T) {
+	require.NoError(t, dropAll())
+	require.NoError(t, alterSchema(`
+name: string @index(exact) .
+title: string @index(exact) .
+author: uid .`))
+
+	upsert := func(title string) {
+		m := fmt.Sprintf(`
+upsert {
+  query {
+    q(func: eq(name, "Alice")) {
+      a as uid
+    }
+  }
+
+  mutation @if(eq(len(a), 0)) {
+    set {
+      _:a <name> "Alice" .
+    }
+  }
+
+  mutation {
+    set {
+      _:post <title> %q .
+      _:post <author> uid(a) .
+    }
+  }
+}`, title)
+		_, err := mutationWithTs(m, "application/rdf", false, true, 0)
+		require.NoError(t, err)
+	}
+	// The author is created along with the first post, and reused by the second one.
+	upsert("First")
+	upsert("Second")
+
+	q := `
+{
+  q(func: has(title), orderasc: title) {
+    title
+    author {
+      name
+    }
+  }
+  authors(func: eq(name, "Alice")) {
+    count(uid)
+  }
+}`
+	res, _, err := queryWithTs(q, "application/graphql+-", "", 0)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data": {
+		"q": [
+			{"title": "First", "author": {"name": "Alice"}},
+			{"title": "Second", "author": {"name": "Alice"}}
+		],
+		"authors": [{"count": 1}]
+	}}`, res)
+}
+
 func TestConditionalUpsertExample0JSON(t *testing.T) {
 	require.NoError(t, dropAll())
 	require.NoError(t, alterSchema(`email: string @index(exact) .`))
@@ -65,6 +65,7 @@ type options struct {
 	HttpAddr         string
 	IgnoreErrors     bool
 	CustomTokenizers string
+	TokenizerTimeout time.Duration
 	NewUids          bool
 	ClientDir        string
 	Encrypted        bool
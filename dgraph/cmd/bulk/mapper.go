@@ -317,6 +317,40 @@ func (m *mapper) processNQuad(nq gql.NQuad) {
 		m.addMapEntry(key, rev, shard)
 	}
 	m.addIndexMapEntries(nq, de)
+	if oid != 0 && len(nq.Facets) > 0 {
+		m.addEdgePropertyMapEntries(nq, sid, oid)
+	}
+}
+
+// addEdgePropertyMapEntries stores the facets of a uid edge declared as properties of the edges of
+// its predicate, along with their indexes, as the Alphas do for mutations.
+func (m *mapper) addEdgePropertyMapEntries(nq gql.NQuad, sid, oid uint64) {
+	for _, prop := range m.schema.edgeProperties(nq.Predicate) {
+		_, key := x.ParseEdgeProperty(prop)
+		for _, f := range nq.Facets {
+			if f.Key != key {
+				continue
+			}
+			tid, err := facets.TypeIDFor(f)
+			x.Check(err)
+			de := &pb.DirectedEdge{
+				Entity:    sid,
+				Attr:      prop,
+				ValueId:   oid,
+				Value:     f.Value,
+				ValueType: tid.Enum(),
+				Op:        pb.DirectedEdge_SET,
+			}
+			// Converts the value to the type of the property.
+			m.schema.validateType(de, false)
+
+			// The value is kept under the uid of the destination of the edge.
+			p := posting.NewPosting(de)
+			p.PostingType = pb.Posting_VALUE
+			m.addMapEntry(x.DataKey(prop, sid), p, m.state.shards.shardFor(prop))
+			m.addIndexTokens(de, "")
+		}
+	}
 }
 
 func (m *mapper) uid(xid string) uint64 {
@@ -403,8 +437,12 @@ func (m *mapper) addIndexMapEntries(nq gql.NQuad, de *pb.DirectedEdge) {
 	if nq.GetObjectValue() == nil {
 		return // Cannot index UIDs
 	}
+	m.addIndexTokens(de, nq.Lang)
+}
 
-	sch := m.schema.getSchema(nq.GetPredicate())
+// addIndexTokens adds the index entries of the value of the edge.
+func (m *mapper) addIndexTokens(de *pb.DirectedEdge, lang string) {
+	sch := m.schema.getSchema(de.Attr)
 	for _, tokerName := range sch.GetTokenizer() {
 		// Find tokeniser.
 		toker, ok := tok.GetTokenizer(tokerName)
@@ -425,18 +463,18 @@ func (m *mapper) addIndexMapEntries(nq gql.NQuad, de *pb.DirectedEdge) {
 		x.Check(err)
 
 		// Extract tokens.
-		toks, err := tok.BuildTokens(schemaVal.Value, tok.GetTokenizerForLang(toker, nq.Lang))
+		toks, err := tok.BuildTokens(schemaVal.Value, tok.GetTokenizerForLang(toker, lang))
 		x.Check(err)
 
 		// Store index posting.
 		for _, t := range toks {
 			m.addMapEntry(
-				x.IndexKey(nq.Predicate, t),
+				x.IndexKey(de.Attr, t),
 				&pb.Posting{
 					Uid:         de.GetEntity(),
 					PostingType: pb.Posting_REF,
 				},
-				m.state.shards.shardFor(nq.Predicate),
+				m.state.shards.shardFor(de.Attr),
 			)
 		}
 	}
@@ -28,6 +28,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/dgraph-io/dgraph/ee/enc"
 	"github.com/dgraph-io/dgraph/tok"
@@ -103,7 +104,10 @@ func init() {
 			"cluster. Increasing this potentially decreases the reduce stage runtime by using "+
 			"more parallelism, but increases memory usage.")
 	flag.String("custom_tokenizers", "",
-		"Comma separated list of tokenizer plugins")
+		"Comma separated list of tokenizer plugins, as Go plugin .so files or WebAssembly .wasm "+
+			"files")
+	flag.Duration("custom_tokenizer_timeout", time.Second,
+		"Time limit for each call to a WebAssembly tokenizer plugin.")
 	flag.Bool("new_uids", false,
 		"Ignore UIDs in load files and assign new ones.")
 
@@ -147,6 +151,7 @@ func run() {
 		MapShards:        Bulk.Conf.GetInt("map_shards"),
 		ReduceShards:     Bulk.Conf.GetInt("reduce_shards"),
 		CustomTokenizers: Bulk.Conf.GetString("custom_tokenizers"),
+		TokenizerTimeout: Bulk.Conf.GetDuration("custom_tokenizer_timeout"),
 		NewUids:          Bulk.Conf.GetBool("new_uids"),
 		ClientDir:        Bulk.Conf.GetString("xidmap"),
 		// Badger options
@@ -225,8 +230,12 @@ func run() {
 		os.Exit(1)
 	}
 	if opt.CustomTokenizers != "" {
-		for _, soFile := range strings.Split(opt.CustomTokenizers, ",") {
-			tok.LoadCustomTokenizer(soFile)
+		for _, file := range strings.Split(opt.CustomTokenizers, ",") {
+			if strings.HasSuffix(file, ".wasm") {
+				tok.LoadWasmTokenizer(file, opt.TokenizerTimeout)
+				continue
+			}
+			tok.LoadCustomTokenizer(file)
 		}
 	}
 	if opt.MapBufSize <= 0 || opt.PartitionBufSize <= 0 {
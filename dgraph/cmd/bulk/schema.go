@@ -34,6 +34,9 @@ type schemaStore struct {
 	sync.RWMutex
 	schemaMap map[string]*pb.SchemaUpdate
 	types     []*pb.TypeUpdate
+	// edgeProps maps the predicates of uid edges to the predicates storing the properties of
+	// their edges.
+	edgeProps map[string][]string
 	*state
 }
 
@@ -44,6 +47,7 @@ func newSchemaStore(initial *schema.ParsedSchema, opt *options, state *state) *s
 
 	s := &schemaStore{
 		schemaMap: map[string]*pb.SchemaUpdate{},
+		edgeProps: map[string][]string{},
 		state:     state,
 	}
 
@@ -70,6 +74,10 @@ func newSchemaStore(initial *schema.ParsedSchema, opt *options, state *state) *s
 			continue
 		}
 		s.schemaMap[p] = sch
+		if x.IsEdgeProperty(p) {
+			edge, _ := x.ParseEdgeProperty(p)
+			s.edgeProps[edge] = append(s.edgeProps[edge], p)
+		}
 	}
 
 	s.types = initial.Types
@@ -83,6 +91,12 @@ func (s *schemaStore) getSchema(pred string) *pb.SchemaUpdate {
 	return s.schemaMap[pred]
 }
 
+// edgeProperties returns the predicates storing the properties of the edges of the predicate.
+// They are only declared in the initial schema, so no lock is needed.
+func (s *schemaStore) edgeProperties(pred string) []string {
+	return s.edgeProps[pred]
+}
+
 func (s *schemaStore) setSchemaAsList(pred string) {
 	s.Lock()
 	defer s.Unlock()
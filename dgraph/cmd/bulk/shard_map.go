@@ -41,6 +41,11 @@ func (m *shardMap) shardFor(pred string) int {
 	if x.IsReservedPredicate(pred) {
 		return 0
 	}
+	// Keep the properties of the edges of a predicate in its shard, so that they're served by
+	// the same group.
+	if x.IsEdgeProperty(pred) {
+		pred, _ = x.ParseEdgeProperty(pred)
+	}
 
 	m.RLock()
 	shard, ok := m.predToShard[pred]
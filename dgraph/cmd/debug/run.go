@@ -33,6 +33,7 @@ import (
 	"github.com/dgraph-io/dgraph/posting"
 	"github.com/dgraph-io/dgraph/protos/pb"
 	"github.com/dgraph-io/dgraph/raftwal"
+	"github.com/dgraph-io/dgraph/schema"
 	"github.com/dgraph-io/dgraph/types"
 	"github.com/dgraph-io/dgraph/x"
 	"github.com/spf13/cobra"
@@ -394,6 +395,8 @@ func history(lookup []byte, itr *badger.Iterator) {
 		}
 		if meta&posting.BitCompletePosting > 0 {
 			var plist pb.PostingList
+			val, err = posting.DecompressPostingList(item.Key(), val)
+			x.Check(err)
 			x.Check(plist.Unmarshal(val))
 
 			for _, p := range plist.Postings {
@@ -528,7 +531,10 @@ func printKeys(db *badger.DB) {
 			x.Check2(buf.WriteString("{r}"))
 		}
 		x.Check2(buf.WriteString(" attr: " + pk.Attr))
-		if len(pk.Term) > 0 {
+		switch {
+		case pk.IsBlob():
+			fmt.Fprintf(&buf, " blob: %x chunk: %d ", pk.Term, pk.Count)
+		case len(pk.Term) > 0:
 			fmt.Fprintf(&buf, " term: [%d] %s ", pk.Term[0], pk.Term[1:])
 		}
 		if pk.Uid > 0 {
@@ -813,6 +819,7 @@ func run() {
 
 	// If this is a new format WAL, print and return.
 	if isWal && !opt.oldWalFormat {
+		x.WorkerConfig.EncryptionKey = opt.key
 		store := raftwal.Init(dir)
 		fmt.Printf("RaftID: %+v\n", store.Uint(raftwal.RaftId))
 
@@ -848,6 +855,12 @@ func run() {
 		return
 	}
 
+	// The schema holds the dictionaries some of the posting lists are compressed with.
+	schema.Init(db)
+	if err := schema.LoadSchemaFromDb(); err != nil {
+		fmt.Printf("Unable to load the schema: %v\n", err)
+	}
+
 	// Commenting the following out because on large Badger DBs, this can take a LONG time.
 	// min, max := getMinMax(db, opt.readTs)
 	// fmt.Printf("Min commit: %d. Max commit: %d, w.r.t %d\n", min, max, opt.readTs)
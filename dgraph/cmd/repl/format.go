@@ -0,0 +1,186 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package repl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// printResult writes the JSON result of a request to out in the given format.
+func printResult(out io.Writer, format string, data []byte) error {
+	if len(bytes.TrimSpace(data)) == 0 {
+		fmt.Fprintln(out, "(empty)")
+		return nil
+	}
+	if format == "json" {
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, data, "", "  "); err != nil {
+			return err
+		}
+		fmt.Fprintln(out, buf.String())
+		return nil
+	}
+
+	var res map[string]interface{}
+	if err := json.Unmarshal(data, &res); err != nil {
+		return err
+	}
+	for _, block := range sortedKeys(res) {
+		if format == "table" {
+			printTable(out, block, res[block])
+		} else {
+			printGraph(out, block, res[block], 0)
+		}
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of the map in sorted order, with uid first.
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i] == "uid" || keys[j] == "uid" {
+			return keys[i] == "uid"
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// cell returns the string representation of a value within a table. Nested objects and
+// lists are shown as compact JSON.
+func cell(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case map[string]interface{}, []interface{}:
+		js, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(js)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func printTable(out io.Writer, block string, v interface{}) {
+	var rows []map[string]interface{}
+	switch v := v.(type) {
+	case []interface{}:
+		for _, r := range v {
+			if m, ok := r.(map[string]interface{}); ok {
+				rows = append(rows, m)
+			} else {
+				rows = append(rows, map[string]interface{}{block: r})
+			}
+		}
+	case map[string]interface{}:
+		rows = append(rows, v)
+	default:
+		rows = append(rows, map[string]interface{}{block: v})
+	}
+
+	columns := make(map[string]interface{})
+	for _, r := range rows {
+		for k := range r {
+			columns[k] = nil
+		}
+	}
+	keys := sortedKeys(columns)
+
+	fmt.Fprintf(out, "%s:\n", block)
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(keys, "\t"))
+	sep := make([]string, len(keys))
+	for i, k := range keys {
+		sep[i] = strings.Repeat("-", len(k))
+	}
+	fmt.Fprintln(tw, strings.Join(sep, "\t"))
+	for _, r := range rows {
+		vals := make([]string, len(keys))
+		for i, k := range keys {
+			vals[i] = cell(r[k])
+		}
+		fmt.Fprintln(tw, strings.Join(vals, "\t"))
+	}
+	_ = tw.Flush()
+	fmt.Fprintf(out, "(%d rows)\n", len(rows))
+}
+
+func printGraph(out io.Writer, name string, v interface{}, depth int) {
+	indent := strings.Repeat("  ", depth)
+	switch v := v.(type) {
+	case []interface{}:
+		fmt.Fprintf(out, "%s%s\n", indent, name)
+		for _, item := range v {
+			if m, ok := item.(map[string]interface{}); ok {
+				printNode(out, m, depth+1)
+			} else {
+				fmt.Fprintf(out, "%s  - %s\n", indent, cell(item))
+			}
+		}
+	case map[string]interface{}:
+		fmt.Fprintf(out, "%s%s\n", indent, name)
+		printNode(out, v, depth+1)
+	default:
+		fmt.Fprintf(out, "%s%s: %s\n", indent, name, cell(v))
+	}
+}
+
+func printNode(out io.Writer, node map[string]interface{}, depth int) {
+	indent := strings.Repeat("  ", depth)
+	for i, k := range sortedKeys(node) {
+		marker := "  "
+		if i == 0 {
+			marker = "- "
+		}
+		switch v := node[k].(type) {
+		case map[string]interface{}, []interface{}:
+			fmt.Fprintf(out, "%s%s%s\n", indent, marker, k)
+			printChildren(out, v, depth+2)
+		default:
+			fmt.Fprintf(out, "%s%s%s: %s\n", indent, marker, k, cell(v))
+		}
+	}
+}
+
+func printChildren(out io.Writer, v interface{}, depth int) {
+	switch v := v.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if m, ok := item.(map[string]interface{}); ok {
+				printNode(out, m, depth)
+			} else {
+				fmt.Fprintf(out, "%s- %s\n", strings.Repeat("  ", depth), cell(item))
+			}
+		}
+	case map[string]interface{}:
+		printNode(out, v, depth)
+	}
+}
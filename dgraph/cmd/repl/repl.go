@@ -0,0 +1,491 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package repl implements an interactive shell to run DQL and GraphQL requests against a
+// Dgraph cluster.
+package repl
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/dgo/v200"
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/dgraph-io/dgraph/gql"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// Repl is the sub-command invoked when running "dgraph repl".
+var Repl x.SubCommand
+
+const helpText = `Statements are sent once all their braces are closed, or when a line ends with ';'.
+Statements starting with "upsert" or "{ set" / "{ delete" are run as mutations.
+
+Commands:
+  \q                     Quit.
+  \h                     Show this help.
+  \mode dql|graphql      Switch the query language.
+  \format json|table|graph
+                         Switch the output format.
+  \set <name> <value>    Set a query variable. DQL variables are used as $name.
+  \unset <name>          Remove a query variable.
+  \vars                  List the query variables.
+  \begin                 Start a transaction. Statements run in it until \commit or \abort.
+  \commit                Commit the current transaction.
+  \abort                 Discard the current transaction.
+  \history [n]           Show the last n statements (default 20).
+  \alter <schema>        Apply a DQL schema update.
+  \c                     Clear the statement being edited.`
+
+var mutationRegexp = regexp.MustCompile(`^(upsert\b|\{\s*(set|delete)\b)`)
+
+func init() {
+	Repl.Cmd = &cobra.Command{
+		Use:   "repl",
+		Short: "Run an interactive shell for DQL and GraphQL",
+		Long: `
+The repl opens an interactive shell connected to a Dgraph Alpha. It supports multi-line
+editing of DQL and GraphQL requests, query variables, transactions and several output
+formats. Statements are saved to a history file between sessions.
+
+Type \h inside the shell for the list of commands.
+`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := run(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		},
+	}
+	Repl.EnvPrefix = "DGRAPH_REPL"
+
+	flag := Repl.Cmd.Flags()
+	flag.StringP("alpha", "a", "localhost:9080", "Comma separated list of Dgraph Alpha gRPC "+
+		"addresses.")
+	flag.String("http", "localhost:8080", "Dgraph Alpha HTTP address, used for GraphQL. "+
+		"It's reached over HTTPS with the TLS flags if they're set.")
+	flag.Int("retries", 1, "How many times to retry setting up the connection.")
+	flag.StringP("user", "u", "", "Username if login is required.")
+	flag.StringP("password", "p", "", "Password of the user.")
+	flag.String("mode", "dql", "Query language to start with, one of [dql, graphql].")
+	flag.String("format", "json", "Output format to start with, one of [json, table, graph].")
+	flag.String("history", filepath.Join(os.Getenv("HOME"), ".dgraph_repl_history"),
+		"File to persist the history of statements to. Set to empty to disable.")
+	flag.Duration("timeout", time.Minute, "Timeout for each statement.")
+	x.RegisterClientTLSFlags(flag)
+}
+
+// session holds the state of a single repl session.
+type session struct {
+	dg      *dgo.Dgraph
+	httpURL string
+	client  *http.Client
+	timeout time.Duration
+	out     io.Writer
+
+	// accessJwt and refreshJwt are the tokens of the ACL login, sent along with the GraphQL
+	// requests. The DQL requests are authenticated by the dgo client.
+	accessJwt  string
+	refreshJwt string
+
+	mode    string
+	format  string
+	vars    map[string]string
+	txn     *dgo.Txn
+	history *history
+}
+
+func run() error {
+	conf := Repl.Conf
+	s := &session{
+		httpURL: "http://" + conf.GetString("http"),
+		client:  &http.Client{},
+		timeout: conf.GetDuration("timeout"),
+		out:     os.Stdout,
+		vars:    make(map[string]string),
+		history: newHistory(conf.GetString("history")),
+	}
+	if err := s.setMode(conf.GetString("mode")); err != nil {
+		return err
+	}
+	if err := s.setFormat(conf.GetString("format")); err != nil {
+		return err
+	}
+
+	// GraphQL requests use the same TLS configuration as the gRPC connection.
+	tlsCfg, err := x.LoadClientTLSConfig(conf)
+	if err != nil {
+		return errors.Wrapf(err, "while loading TLS configuration")
+	}
+	if tlsCfg != nil {
+		s.httpURL = "https://" + conf.GetString("http")
+		s.client.Transport = &http.Transport{TLSClientConfig: tlsCfg}
+	}
+
+	dg, closeFunc := x.GetDgraphClient(conf, false)
+	defer closeFunc()
+	s.dg = dg
+	if user := conf.GetString("user"); user != "" {
+		password := conf.GetString("password")
+		if password == "" {
+			if password, err = x.AskUserPassword(user, "Current", 1); err != nil {
+				return err
+			}
+		}
+		if err := s.login(user, password); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintln(s.out, `Type \h for help, \q to quit.`)
+	s.loop(os.Stdin)
+	return s.history.save()
+}
+
+func (s *session) prompt(editing bool) string {
+	p := s.mode
+	if s.txn != nil {
+		p += "*"
+	}
+	if editing {
+		return strings.Repeat(" ", len(p)) + "> "
+	}
+	return p + "> "
+}
+
+func (s *session) loop(in io.Reader) {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64<<10), 16<<20)
+	var st statement
+
+	fmt.Fprint(s.out, s.prompt(false))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if st.empty() && strings.HasPrefix(strings.TrimSpace(line), `\`) {
+			if quit := s.command(strings.TrimSpace(line)); quit {
+				return
+			}
+			fmt.Fprint(s.out, s.prompt(false))
+			continue
+		}
+		if strings.TrimSpace(line) == `\c` {
+			st.reset()
+			fmt.Fprint(s.out, s.prompt(false))
+			continue
+		}
+
+		if text, done := st.add(line); done {
+			s.history.add(text)
+			if err := s.execute(text); err != nil {
+				fmt.Fprintln(s.out, "Error:", err)
+			}
+		}
+		fmt.Fprint(s.out, s.prompt(!st.empty()))
+	}
+	if s.txn != nil {
+		fmt.Fprintln(s.out, "Discarding the open transaction.")
+		x.Ignore(s.txn.Discard(context.Background()))
+	}
+}
+
+// command runs a backslash command. It returns true if the session should end.
+func (s *session) command(line string) bool {
+	fields := strings.Fields(line)
+	arg := strings.TrimSpace(strings.TrimPrefix(line, fields[0]))
+
+	var err error
+	switch fields[0] {
+	case `\q`:
+		return true
+	case `\h`, `\?`:
+		fmt.Fprintln(s.out, helpText)
+	case `\mode`:
+		err = s.setMode(arg)
+	case `\format`:
+		err = s.setFormat(arg)
+	case `\set`:
+		if len(fields) < 3 {
+			err = errors.Errorf(`Usage: \set <name> <value>`)
+			break
+		}
+		name := strings.TrimPrefix(fields[1], "$")
+		s.vars[name] = strings.TrimSpace(strings.TrimPrefix(arg, fields[1]))
+	case `\unset`:
+		delete(s.vars, strings.TrimPrefix(arg, "$"))
+	case `\vars`:
+		names := make([]string, 0, len(s.vars))
+		for name := range s.vars {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(s.out, "$%s = %s\n", name, s.vars[name])
+		}
+	case `\begin`:
+		if s.txn != nil {
+			err = errors.Errorf("A transaction is already open")
+			break
+		}
+		s.txn = s.dg.NewTxn()
+	case `\commit`, `\abort`:
+		if s.txn == nil {
+			err = errors.Errorf("No transaction is open")
+			break
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+		if fields[0] == `\commit` {
+			err = s.txn.Commit(ctx)
+		} else {
+			err = s.txn.Discard(ctx)
+		}
+		cancel()
+		s.txn = nil
+	case `\history`:
+		n := 20
+		if arg != "" {
+			if _, err = fmt.Sscan(arg, &n); err != nil {
+				break
+			}
+		}
+		for _, h := range s.history.last(n) {
+			fmt.Fprintln(s.out, h)
+		}
+	case `\alter`:
+		ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+		err = s.dg.Alter(ctx, &api.Operation{Schema: arg})
+		cancel()
+	default:
+		err = errors.Errorf(`Unknown command %s. Type \h for help.`, fields[0])
+	}
+	if err != nil {
+		fmt.Fprintln(s.out, "Error:", err)
+	}
+	return false
+}
+
+func (s *session) setMode(mode string) error {
+	if mode != "dql" && mode != "graphql" {
+		return errors.Errorf("Invalid mode %q, must be one of [dql, graphql]", mode)
+	}
+	s.mode = mode
+	return nil
+}
+
+func (s *session) setFormat(format string) error {
+	if format != "json" && format != "table" && format != "graph" {
+		return errors.Errorf("Invalid format %q, must be one of [json, table, graph]", format)
+	}
+	s.format = format
+	return nil
+}
+
+func (s *session) execute(text string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	start := time.Now()
+	var data []byte
+	var err error
+	if s.mode == "graphql" {
+		data, err = s.runGraphQL(ctx, text)
+	} else {
+		data, err = s.runDQL(ctx, text)
+	}
+	if err != nil {
+		return err
+	}
+	if err := printResult(s.out, s.format, data); err != nil {
+		return err
+	}
+	fmt.Fprintf(s.out, "(%s)\n", time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+func (s *session) dqlVars() map[string]string {
+	vars := make(map[string]string, len(s.vars))
+	for name, val := range s.vars {
+		vars["$"+name] = val
+	}
+	return vars
+}
+
+func (s *session) runDQL(ctx context.Context, text string) ([]byte, error) {
+	txn := s.txn
+	isMutation := mutationRegexp.MatchString(text)
+	if txn == nil {
+		if isMutation {
+			txn = s.dg.NewTxn()
+		} else {
+			txn = s.dg.NewReadOnlyTxn()
+		}
+		defer func() {
+			x.Ignore(txn.Discard(context.Background()))
+		}()
+	}
+
+	if !isMutation {
+		resp, err := txn.QueryWithVars(ctx, text, s.dqlVars())
+		if err != nil {
+			return nil, err
+		}
+		return resp.Json, nil
+	}
+
+	req, err := gql.ParseMutation(text)
+	if err != nil {
+		return nil, err
+	}
+	req.Vars = s.dqlVars()
+	req.CommitNow = s.txn == nil
+	resp, err := txn.Do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Report the uids assigned to blank nodes along with any query results of an upsert.
+	res := map[string]interface{}{"uids": resp.Uids}
+	if len(resp.Json) > 0 {
+		var q interface{}
+		if err := json.Unmarshal(resp.Json, &q); err != nil {
+			return nil, err
+		}
+		res["queries"] = q
+	}
+	return json.Marshal(res)
+}
+
+func (s *session) runGraphQL(ctx context.Context, text string) ([]byte, error) {
+	vars := make(map[string]interface{}, len(s.vars))
+	for name, val := range s.vars {
+		// Values are JSON if possible, otherwise they're passed as strings.
+		var v interface{}
+		if err := json.Unmarshal([]byte(val), &v); err != nil {
+			v = val
+		}
+		vars[name] = v
+	}
+	body, err := json.Marshal(map[string]interface{}{"query": text, "variables": vars})
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := s.post(ctx, "/graphql", body, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var res struct {
+		Data   json.RawMessage `json:"data"`
+		Errors x.GqlErrorList  `json:"errors"`
+	}
+	if err := json.Unmarshal(out, &res); err != nil {
+		return nil, errors.Wrapf(err, "while reading GraphQL response")
+	}
+	if len(res.Errors) > 0 {
+		return nil, res.Errors
+	}
+	return res.Data, nil
+}
+
+// login logs the user in, both into the dgo client for the DQL requests and over HTTP to get
+// the tokens for the GraphQL requests.
+func (s *session) login(user, password string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := s.dg.Login(ctx, user, password); err != nil {
+		return errors.Wrapf(err, "unable to login to the %v account", user)
+	}
+	if err := s.loginHTTP(ctx, &api.LoginRequest{Userid: user, Password: password}); err != nil {
+		return errors.Wrapf(err, "unable to login to the %v account", user)
+	}
+	fmt.Fprintln(s.out, "Login successful.")
+	return nil
+}
+
+func (s *session) loginHTTP(ctx context.Context, req *api.LoginRequest) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	out, err := s.post(ctx, "/login", body, false)
+	if err != nil {
+		return err
+	}
+
+	var res struct {
+		Data struct {
+			AccessJWT  string `json:"accessJWT"`
+			RefreshJWT string `json:"refreshJWT"`
+		} `json:"data"`
+		Errors x.GqlErrorList `json:"errors"`
+	}
+	if err := json.Unmarshal(out, &res); err != nil {
+		return errors.Wrapf(err, "while reading login response")
+	}
+	if len(res.Errors) > 0 {
+		return res.Errors
+	}
+	s.accessJwt, s.refreshJwt = res.Data.AccessJWT, res.Data.RefreshJWT
+	return nil
+}
+
+// post sends the body to the given path of the Alpha HTTP endpoint along with the access
+// token, if any. If the access token has expired, it's refreshed and the request is retried.
+func (s *session) post(ctx context.Context, path string, body []byte, retry bool) (
+	[]byte, error) {
+	req, err := http.NewRequest(http.MethodPost, s.httpURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	if s.accessJwt != "" {
+		req.Header.Set("X-Dgraph-AccessToken", s.accessJwt)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	out, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if retry && s.refreshJwt != "" && bytes.Contains(out, []byte("Token is expired")) {
+		if err := s.loginHTTP(ctx, &api.LoginRequest{RefreshToken: s.refreshJwt}); err != nil {
+			return nil, errors.Wrapf(err, "while refreshing the access token")
+		}
+		return s.post(ctx, path, body, false)
+	}
+	return out, nil
+}
@@ -0,0 +1,143 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package repl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatement(t *testing.T) {
+	var st statement
+	_, done := st.add("{")
+	require.False(t, done)
+	_, done = st.add(`  q(func: eq(name, "{")) {  # unbalanced { in a comment`)
+	require.False(t, done)
+	_, done = st.add("    name")
+	require.False(t, done)
+	_, done = st.add("  }")
+	require.False(t, done)
+	text, done := st.add("}")
+	require.True(t, done)
+	require.Equal(t, "{\n  q(func: eq(name, \"{\")) {  # unbalanced { in a comment\n"+
+		"    name\n  }\n}", text)
+	require.True(t, st.empty())
+
+	text, done = st.add("schema {};")
+	require.True(t, done)
+	require.Equal(t, "schema {}", text)
+
+	_, done = st.add("   ")
+	require.False(t, done)
+	require.True(t, st.empty())
+}
+
+func TestMutationRegexp(t *testing.T) {
+	require.True(t, mutationRegexp.MatchString("upsert { query {} mutation { set {} } }"))
+	require.True(t, mutationRegexp.MatchString("{\n  set {\n _:a <name> \"A\" .\n }\n}"))
+	require.True(t, mutationRegexp.MatchString("{ delete { <0x1> * * . } }"))
+	require.False(t, mutationRegexp.MatchString("{ setting(func: has(name)) { uid } }"))
+}
+
+func TestPrintResult(t *testing.T) {
+	data := []byte(`{"q":[{"uid":"0x1","name":"Alice","age":30},{"uid":"0x2","name":"Bob"}]}`)
+
+	var buf bytes.Buffer
+	require.NoError(t, printResult(&buf, "table", data))
+	require.Equal(t, "q:\n"+
+		"uid  age  name\n"+
+		"---  ---  ----\n"+
+		"0x1  30   Alice\n"+
+		"0x2       Bob\n"+
+		"(2 rows)\n", buf.String())
+
+	buf.Reset()
+	data = []byte(`{"q":[{"uid":"0x1","name":"Alice","friend":[{"uid":"0x2","name":"Bob"}]}]}`)
+	require.NoError(t, printResult(&buf, "graph", data))
+	require.Equal(t, "q\n"+
+		"  - uid: 0x1\n"+
+		"    friend\n"+
+		"      - uid: 0x2\n"+
+		"        name: Bob\n"+
+		"    name: Alice\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, printResult(&buf, "json", []byte(`{"q":[]}`)))
+	require.Equal(t, "{\n  \"q\": []\n}\n", buf.String())
+}
+
+func TestHistory(t *testing.T) {
+	h := newHistory("")
+	h.add("a")
+	h.add("a")
+	h.add("b")
+	require.Equal(t, []string{"a", "b"}, h.last(0))
+	require.Equal(t, []string{"b"}, h.last(1))
+}
+
+func TestGraphQLOverTLSWithLogin(t *testing.T) {
+	var logins, refreshes int
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		switch r.URL.Path {
+		case "/login":
+			var req api.LoginRequest
+			require.NoError(t, json.Unmarshal(body, &req))
+			if req.RefreshToken == "refresh" {
+				refreshes++
+				_, _ = w.Write([]byte(`{"data":{"accessJWT":"new","refreshJWT":"refresh"}}`))
+				return
+			}
+			require.Equal(t, "groot", req.Userid)
+			require.Equal(t, "password", req.Password)
+			logins++
+			_, _ = w.Write([]byte(`{"data":{"accessJWT":"old","refreshJWT":"refresh"}}`))
+		case "/graphql":
+			switch r.Header.Get("X-Dgraph-AccessToken") {
+			case "new":
+				_, _ = w.Write([]byte(`{"data":{"q":[]}}`))
+			case "old":
+				_, _ = w.Write([]byte(`{"errors":[{"message":"unable to parse jwt token: ` +
+					`Token is expired"}]}`))
+			default:
+				_, _ = w.Write([]byte(`{"errors":[{"message":"no access token"}]}`))
+			}
+		}
+	}))
+	defer srv.Close()
+
+	s := &session{httpURL: srv.URL, client: srv.Client(), out: ioutil.Discard}
+	require.NoError(t, s.loginHTTP(context.Background(),
+		&api.LoginRequest{Userid: "groot", Password: "password"}))
+	require.Equal(t, 1, logins)
+
+	// The expired token is refreshed and the request retried.
+	data, err := s.runGraphQL(context.Background(), "query { q { id } }")
+	require.NoError(t, err)
+	require.JSONEq(t, `{"q":[]}`, string(data))
+	require.Equal(t, 1, refreshes)
+	require.Equal(t, "new", s.accessJwt)
+}
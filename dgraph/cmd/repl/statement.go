@@ -0,0 +1,125 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package repl
+
+import (
+	"io/ioutil"
+	"strings"
+)
+
+// statement accumulates the lines of a multi-line statement until it is complete.
+type statement struct {
+	lines []string
+	depth int
+	// opened is true once the statement has seen its first opening brace.
+	opened bool
+}
+
+func (st *statement) empty() bool {
+	return len(st.lines) == 0
+}
+
+func (st *statement) reset() {
+	*st = statement{}
+}
+
+// add appends a line to the statement. It returns the complete statement and true once all
+// its braces are balanced, or the line ends with a ';'.
+func (st *statement) add(line string) (string, bool) {
+	if st.empty() && strings.TrimSpace(line) == "" {
+		return "", false
+	}
+	st.lines = append(st.lines, line)
+
+	var inString, escaped bool
+scan:
+	for _, r := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = inString
+		case r == '"':
+			inString = !inString
+		case inString:
+		case r == '{' || r == '(':
+			st.depth++
+			st.opened = true
+		case r == '}' || r == ')':
+			st.depth--
+		case r == '#':
+			// The rest of the line is a comment.
+			break scan
+		}
+	}
+
+	trimmed := strings.TrimSpace(line)
+	endsWithSemicolon := strings.HasSuffix(trimmed, ";")
+	if (st.opened && st.depth <= 0) || endsWithSemicolon {
+		text := strings.TrimSpace(strings.Join(st.lines, "\n"))
+		text = strings.TrimSpace(strings.TrimSuffix(text, ";"))
+		st.reset()
+		return text, text != ""
+	}
+	return "", false
+}
+
+// history keeps the statements run in the session, and persists them to a file.
+type history struct {
+	path    string
+	entries []string
+}
+
+// maxHistory is the maximum number of statements persisted to the history file.
+const maxHistory = 1000
+
+// historySeparator separates the statements in the history file, as they can span
+// multiple lines.
+const historySeparator = "\n\x00\n"
+
+func newHistory(path string) *history {
+	h := &history{path: path}
+	if path == "" {
+		return h
+	}
+	if data, err := ioutil.ReadFile(path); err == nil && len(data) > 0 {
+		h.entries = strings.Split(string(data), historySeparator)
+	}
+	return h
+}
+
+func (h *history) add(text string) {
+	if n := len(h.entries); n > 0 && h.entries[n-1] == text {
+		return
+	}
+	h.entries = append(h.entries, text)
+}
+
+func (h *history) last(n int) []string {
+	if n <= 0 || n > len(h.entries) {
+		n = len(h.entries)
+	}
+	return h.entries[len(h.entries)-n:]
+}
+
+func (h *history) save() error {
+	if h.path == "" || len(h.entries) == 0 {
+		return nil
+	}
+	entries := h.last(maxHistory)
+	return ioutil.WriteFile(h.path, []byte(strings.Join(entries, historySeparator)), 0600)
+}
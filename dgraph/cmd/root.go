@@ -31,6 +31,7 @@ import (
 	"github.com/dgraph-io/dgraph/dgraph/cmd/live"
 	"github.com/dgraph-io/dgraph/dgraph/cmd/migrate"
 	raftmigrate "github.com/dgraph-io/dgraph/dgraph/cmd/raft-migrate"
+	"github.com/dgraph-io/dgraph/dgraph/cmd/repl"
 	"github.com/dgraph-io/dgraph/dgraph/cmd/version"
 	"github.com/dgraph-io/dgraph/dgraph/cmd/zero"
 	"github.com/dgraph-io/dgraph/upgrade"
@@ -77,7 +78,7 @@ var rootConf = viper.New()
 var subcommands = []*x.SubCommand{
 	&bulk.Bulk, &cert.Cert, &conv.Conv, &live.Live, &alpha.Alpha, &zero.Zero, &version.Version,
 	&debug.Debug, &increment.Increment, &migrate.Migrate, &debuginfo.DebugInfo, &upgrade.Upgrade,
-	&raftmigrate.RaftMigrate,
+	&raftmigrate.RaftMigrate, &repl.Repl,
 }
 
 func initCmds() {
@@ -23,6 +23,7 @@ func init() {
 		&backup.Restore,
 		&backup.LsBackup,
 		&backup.ExportBackup,
+		&backup.DumpBackup,
 		&acl.CmdAcl,
 	)
 }
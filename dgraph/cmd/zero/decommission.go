@@ -0,0 +1,281 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zero
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+const decommissionInterval = 30 * time.Second
+
+// decommission is the progress of the decommission of an Alpha, as reported by /decommission.
+type decommission struct {
+	Id      uint64 `json:"id"`
+	GroupId uint32 `json:"group"`
+	// Status is one of draining, removing, removed or failed.
+	Status string `json:"status"`
+	// Tablets is the number of tablets to move away from the group, if the Alpha is its last
+	// voting member, and Moved the number of them already moved.
+	Tablets int    `json:"tablets"`
+	Moved   int    `json:"moved"`
+	Moving  string `json:"moving,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// drainingGroups returns the groups whose voting members are all being decommissioned. They're
+// assigned no new tablets, and their tablets are moved to the other groups.
+func (s *Server) drainingGroups() map[uint32]bool {
+	s.AssertRLock()
+
+	draining := make(map[uint32]bool)
+	for gid, group := range s.state.Groups {
+		var voters, leaving int
+		for _, m := range group.Members {
+			if m.Learner {
+				continue
+			}
+			voters++
+			if m.Decommissioning {
+				leaving++
+			}
+		}
+		if leaving > 0 && leaving == voters {
+			draining[gid] = true
+		}
+	}
+	return draining
+}
+
+// drainDestination returns the lightest group that isn't draining and has a voting member, or 0
+// if there's none.
+func (s *Server) drainDestination(draining map[uint32]bool) uint32 {
+	s.AssertRLock()
+
+	weights := s.tabletWeights()
+	var dst uint32
+	var min float64
+	for gid, group := range s.state.Groups {
+		if draining[gid] || numVoters(group) == 0 {
+			continue
+		}
+		var weight float64
+		for pred := range group.Tablets {
+			weight += weights[pred]
+		}
+		if dst == 0 || weight < min || (weight == min && gid < dst) {
+			dst, min = gid, weight
+		}
+	}
+	return dst
+}
+
+// checkDecommission returns an error if the tablets of the group of the member would have to be
+// moved away to decommission it, and they can't be.
+func (s *Server) checkDecommission(member *pb.Member) error {
+	s.AssertRLock()
+
+	group := s.state.Groups[member.GroupId]
+	if member.Learner || len(group.GetTablets()) == 0 {
+		return nil
+	}
+	draining := s.drainingGroups()
+	for _, m := range group.Members {
+		if m.Id != member.Id && !m.Learner && !m.Decommissioning {
+			// Another voting member keeps serving the tablets of the group.
+			return nil
+		}
+	}
+	if s.state.GetReplication().GetPrimary() != "" {
+		return errors.Errorf("The tablets of group %d can't be moved on a replica cluster.",
+			member.GroupId)
+	}
+	for pred := range group.Tablets {
+		if x.IsReservedPredicate(pred) {
+			return errors.Errorf("Node %d is the last one of group %d, which serves the reserved"+
+				" predicates. It can't be decommissioned.", member.Id, member.GroupId)
+		}
+	}
+	draining[member.GroupId] = true
+	if s.drainDestination(draining) == 0 {
+		return errors.Errorf("No other group to move the tablets of group %d to.",
+			member.GroupId)
+	}
+	return nil
+}
+
+// decommissionNode marks the member as being decommissioned. The leader then moves the tablets
+// of its group to the other groups if it's the last voting member of the group, and removes it.
+func (s *Server) decommissionNode(ctx context.Context, nodeId uint64, groupId uint32) error {
+	s.RLock()
+	group, ok := s.state.Groups[groupId]
+	if !ok {
+		s.RUnlock()
+		return errors.Errorf("No group with groupId %d found", groupId)
+	}
+	member, ok := group.Members[nodeId]
+	if !ok {
+		s.RUnlock()
+		return errors.Errorf("No node with nodeId %d found in group %d", nodeId, groupId)
+	}
+	if member.Decommissioning {
+		s.RUnlock()
+		return nil
+	}
+	err := s.checkDecommission(member)
+	member = proto.Clone(member).(*pb.Member)
+	s.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	member.Decommissioning = true
+	if err := s.Node.proposeAndWait(ctx, &pb.ZeroProposal{Member: member}); err != nil {
+		return err
+	}
+	select {
+	case s.decommissionCh <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// processDecommissions drains and removes the members being decommissioned, when this Zero is
+// the leader. The decommissions that failed, or were stopped by a change of leader, are resumed
+// every decommissionInterval.
+func (s *Server) processDecommissions() {
+	ticker := time.NewTicker(decommissionInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.decommissionCh:
+		}
+		if !s.Node.AmLeader() {
+			continue
+		}
+
+		var members []*pb.Member
+		s.RLock()
+		for _, group := range s.state.Groups {
+			for _, m := range group.Members {
+				if m.Decommissioning {
+					members = append(members, proto.Clone(m).(*pb.Member))
+				}
+			}
+		}
+		s.RUnlock()
+		sort.Slice(members, func(i, j int) bool { return members[i].Id < members[j].Id })
+		for _, m := range members {
+			if err := s.decommission(m); err != nil {
+				glog.Errorf("While decommissioning node %d of group %d: %v", m.Id, m.GroupId, err)
+				s.updateDecommission(m, func(d *decommission) {
+					d.Status = "failed"
+					d.Moving = ""
+					d.Error = err.Error()
+				})
+			}
+		}
+	}
+}
+
+// decommission moves the tablets of the group of the member away while the group is draining,
+// and then removes the member. The edge predicates are moved along with the properties of their
+// edges, to the same group.
+func (s *Server) decommission(m *pb.Member) error {
+	s.updateDecommission(m, func(d *decommission) {
+		d.Status = "draining"
+		d.Error = ""
+	})
+	for {
+		var tablets []string
+		s.RLock()
+		draining := s.drainingGroups()
+		if draining[m.GroupId] {
+			for pred := range s.state.Groups[m.GroupId].GetTablets() {
+				tablets = append(tablets, pred)
+			}
+		}
+		dst := s.drainDestination(draining)
+		s.RUnlock()
+		if len(tablets) == 0 {
+			break
+		}
+		if dst == 0 {
+			return errors.Errorf("No other group to move the tablets of group %d to.", m.GroupId)
+		}
+
+		sort.Strings(tablets)
+		s.updateDecommission(m, func(d *decommission) { d.Tablets = d.Moved + len(tablets) })
+		edge, _ := x.ParseEdgeProperty(tablets[0])
+		for _, pred := range tablets {
+			if e, _ := x.ParseEdgeProperty(pred); e != edge {
+				continue
+			}
+			s.updateDecommission(m, func(d *decommission) { d.Moving = pred })
+			if err := s.moveTablet(pred, m.GroupId, dst); err != nil {
+				return err
+			}
+			s.updateDecommission(m, func(d *decommission) { d.Moved++ })
+		}
+	}
+
+	s.updateDecommission(m, func(d *decommission) {
+		d.Status = "removing"
+		d.Moving = ""
+	})
+	if err := s.removeNode(context.Background(), m.Id, m.GroupId); err != nil {
+		return err
+	}
+	glog.Infof("Decommissioned node %d of group %d", m.Id, m.GroupId)
+	s.updateDecommission(m, func(d *decommission) { d.Status = "removed" })
+	return nil
+}
+
+// updateDecommission applies the update to the progress of the decommission of the member.
+func (s *Server) updateDecommission(m *pb.Member, update func(d *decommission)) {
+	s.decommissionLock.Lock()
+	defer s.decommissionLock.Unlock()
+
+	d, ok := s.decommissions[m.Id]
+	if !ok {
+		d = &decommission{Id: m.Id, GroupId: m.GroupId}
+		s.decommissions[m.Id] = d
+	}
+	update(d)
+}
+
+// decommissionStatus returns the progress of the decommissions run by this Zero.
+func (s *Server) decommissionStatus() []decommission {
+	s.decommissionLock.Lock()
+	defer s.decommissionLock.Unlock()
+
+	status := make([]decommission, 0, len(s.decommissions))
+	for _, d := range s.decommissions {
+		status = append(status, *d)
+	}
+	sort.Slice(status, func(i, j int) bool { return status[i].Id < status[j].Id })
+	return status
+}
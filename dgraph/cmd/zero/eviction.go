@@ -0,0 +1,221 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zero
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	ostats "go.opencensus.io/stats"
+
+	"github.com/dgraph-io/dgraph/conn"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+const evictionInterval = 10 * time.Second
+
+// deadNode is an Alpha the leader can't reach.
+type deadNode struct {
+	since time.Time
+	// handled is set once the node has been unreachable for --dead_node_timeout, and the policy
+	// has been applied to it.
+	handled bool
+}
+
+// deadNodeEvent is posted to the --dead_node_webhook when an Alpha is found dead, or when a
+// dead Alpha that wasn't removed is reachable again.
+type deadNodeEvent struct {
+	// Event is either dead or recovered.
+	Event            string    `json:"event"`
+	Id               uint64    `json:"id"`
+	GroupId          uint32    `json:"group"`
+	Addr             string    `json:"addr"`
+	Learner          bool      `json:"learner"`
+	UnreachableSince time.Time `json:"unreachableSince"`
+	// Replicas is the number of reachable voting members left in the group, out of the number
+	// of replicas of the cluster.
+	Replicas    int    `json:"replicas"`
+	NumReplicas int    `json:"numReplicas"`
+	Removed     bool   `json:"removed"`
+	Error       string `json:"error,omitempty"`
+}
+
+// evictDeadNodes applies the dead node policy to the Alphas unreachable from the leader for
+// longer than --dead_node_timeout, if it's set.
+func (s *Server) evictDeadNodes() {
+	if opts.deadNodeTimeout <= 0 {
+		return
+	}
+
+	reachable := func(addr string) bool {
+		pool, err := conn.GetPools().Get(addr)
+		return err == nil && pool.IsHealthy()
+	}
+	ticker := time.NewTicker(evictionInterval)
+	defer ticker.Stop()
+
+	dead := make(map[uint64]*deadNode)
+	for range ticker.C {
+		if !s.Node.AmLeader() {
+			// A new leader waits for the whole timeout, as it doesn't know since when the
+			// nodes are unreachable.
+			dead = make(map[uint64]*deadNode)
+			continue
+		}
+		events := s.checkDeadNodes(dead, reachable, time.Now())
+		for _, ev := range events {
+			s.notifyDeadNode(ev)
+		}
+		var n int64
+		for _, d := range dead {
+			if d.handled {
+				n++
+			}
+		}
+		ostats.Record(context.Background(), x.DeadNodes.M(n))
+	}
+}
+
+// checkDeadNodes updates the unreachable Alphas, and returns the events of the Alphas found dead,
+// or recovered, at the given time. The dead Alphas are removed if --dead_node_remove is set.
+func (s *Server) checkDeadNodes(dead map[uint64]*deadNode, reachable func(addr string) bool,
+	now time.Time) []deadNodeEvent {
+	var members []*pb.Member
+	// The reachable voting members, and whether the leader is reachable, of each group.
+	live := make(map[uint32]int)
+	leaders := make(map[uint32]bool)
+	s.RLock()
+	for gid, group := range s.state.Groups {
+		for _, m := range group.Members {
+			members = append(members, proto.Clone(m).(*pb.Member))
+			if !reachable(m.Addr) {
+				continue
+			}
+			if !m.Learner {
+				live[gid]++
+			}
+			if m.Leader {
+				leaders[gid] = true
+			}
+		}
+	}
+	s.RUnlock()
+	sort.Slice(members, func(i, j int) bool { return members[i].Id < members[j].Id })
+
+	ids := make(map[uint64]bool)
+	var events []deadNodeEvent
+	for _, m := range members {
+		ids[m.Id] = true
+		ev := deadNodeEvent{
+			Id:          m.Id,
+			GroupId:     m.GroupId,
+			Addr:        m.Addr,
+			Learner:     m.Learner,
+			Replicas:    live[m.GroupId],
+			NumReplicas: s.NumReplicas,
+		}
+
+		d, ok := dead[m.Id]
+		if reachable(m.Addr) {
+			if ok {
+				delete(dead, m.Id)
+			}
+			if ok && d.handled {
+				glog.Infof("Node %d of group %d is reachable again", m.Id, m.GroupId)
+				ev.Event = "recovered"
+				ev.UnreachableSince = d.since
+				events = append(events, ev)
+			}
+			continue
+		}
+		if !ok {
+			d = &deadNode{since: now}
+			dead[m.Id] = d
+		}
+		if d.handled || now.Sub(d.since) < opts.deadNodeTimeout {
+			continue
+		}
+		d.handled = true
+
+		ev.Event = "dead"
+		ev.UnreachableSince = d.since
+		glog.Warningf("Node %d of group %d at %s has been unreachable since %s. %d of the %d"+
+			" replicas of the group are reachable.", m.Id, m.GroupId, m.Addr,
+			d.since.Format(time.RFC3339), ev.Replicas, ev.NumReplicas)
+		if opts.deadNodeRemove {
+			if err := s.evictNode(m, leaders[m.GroupId]); err != nil {
+				glog.Errorf("While removing dead node %d of group %d: %v", m.Id, m.GroupId, err)
+				ev.Error = err.Error()
+			} else {
+				glog.Infof("Removed dead node %d of group %d", m.Id, m.GroupId)
+				ev.Removed = true
+				delete(dead, m.Id)
+			}
+		}
+		events = append(events, ev)
+	}
+	// Forget the nodes removed from the cluster.
+	for id := range dead {
+		if !ids[id] {
+			delete(dead, id)
+		}
+	}
+	return events
+}
+
+// evictNode removes the dead member from the cluster, so that a new Alpha joins its group and
+// receives a snapshot of it. The leader of the group must be reachable to remove the member from
+// the Raft group.
+func (s *Server) evictNode(m *pb.Member, leader bool) error {
+	if !leader {
+		return errors.Errorf("The leader of group %d isn't reachable", m.GroupId)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	return s.removeNode(ctx, m.Id, m.GroupId)
+}
+
+// notifyDeadNode posts the event to the --dead_node_webhook, if it's set.
+func (s *Server) notifyDeadNode(ev deadNodeEvent) {
+	if opts.deadNodeWebhook == "" {
+		return
+	}
+	data, err := json.Marshal(ev)
+	if err != nil {
+		glog.Errorf("While marshalling dead node event: %v", err)
+		return
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(opts.deadNodeWebhook, "application/json", bytes.NewReader(data))
+	if err != nil {
+		glog.Errorf("While posting dead node event to %s: %v", opts.deadNodeWebhook, err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		glog.Errorf("Webhook %s replied to the dead node event with %s", opts.deadNodeWebhook,
+			resp.Status)
+	}
+}
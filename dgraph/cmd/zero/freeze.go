@@ -0,0 +1,163 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zero
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+)
+
+const freezeTimeout = time.Minute
+
+// applyFreeze adds the freeze point to the state, or updates the one with the same tag.
+func applyFreeze(state *pb.MembershipState, fp *pb.FreezePoint) {
+	for i, existing := range state.FreezePoints {
+		if existing.Tag == fp.Tag {
+			state.FreezePoints[i] = fp
+			return
+		}
+	}
+	state.FreezePoints = append(state.FreezePoints, fp)
+}
+
+// releaseFreeze removes the freeze point with the tag from the state.
+func releaseFreeze(state *pb.MembershipState, tag string) {
+	points := state.FreezePoints[:0]
+	for _, fp := range state.FreezePoints {
+		if fp.Tag != tag {
+			points = append(points, fp)
+		}
+	}
+	state.FreezePoints = points
+}
+
+// freezePoint returns a copy of the freeze point with the tag, or nil if there's none.
+func (s *Server) freezePoint(tag string) *pb.FreezePoint {
+	s.RLock()
+	defer s.RUnlock()
+	for _, fp := range s.state.GetFreezePoints() {
+		if fp.Tag == tag {
+			return proto.Clone(fp).(*pb.FreezePoint)
+		}
+	}
+	return nil
+}
+
+// freezePoints returns a copy of the freeze points, from the oldest one.
+func (s *Server) freezePoints() []*pb.FreezePoint {
+	s.RLock()
+	defer s.RUnlock()
+	points := make([]*pb.FreezePoint, 0, len(s.state.GetFreezePoints()))
+	for _, fp := range s.state.GetFreezePoints() {
+		points = append(points, proto.Clone(fp).(*pb.FreezePoint))
+	}
+	return points
+}
+
+// freeze creates a freeze point tagged with tag. It leases a read timestamp, and waits for the
+// leader of every group to apply it. The freeze point is recorded before the groups are asked, so
+// that the Alphas keep the versions needed to read at it from then on. If a group fails to apply
+// the timestamp, the freeze point is released.
+func (s *Server) freeze(ctx context.Context, tag string) (*pb.FreezePoint, error) {
+	if tag == "" {
+		return nil, errors.Errorf("The tag of a freeze point can't be empty.")
+	}
+	if s.freezePoint(tag) != nil {
+		return nil, errors.Errorf("A freeze point tagged %q already exists.", tag)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, freezeTimeout)
+	defer cancel()
+	ids, err := s.Timestamps(ctx, &pb.Num{Val: 1})
+	if err != nil {
+		return nil, errors.Wrapf(err, "while leasing the timestamp of the freeze point")
+	}
+	fp := &pb.FreezePoint{Tag: tag, ReadTs: ids.StartId, CreatedAt: time.Now().Unix()}
+	if err := s.Node.proposeAndWait(ctx, &pb.ZeroProposal{Freeze: fp}); err != nil {
+		return nil, err
+	}
+	glog.Infof("Created freeze point %q at ts %d", tag, fp.ReadTs)
+
+	frozen, err := s.freezeGroups(ctx, fp.ReadTs)
+	if err != nil {
+		glog.Errorf("While freezing the groups at ts %d: %v", fp.ReadTs, err)
+		rctx, rcancel := context.WithTimeout(context.Background(), freezeTimeout)
+		defer rcancel()
+		if rerr := s.release(rctx, tag); rerr != nil {
+			glog.Errorf("While releasing freeze point %q: %v", tag, rerr)
+		}
+		return nil, err
+	}
+
+	fp = proto.Clone(fp).(*pb.FreezePoint)
+	fp.Groups = frozen
+	fp.Ready = true
+	if err := s.Node.proposeAndWait(ctx, &pb.ZeroProposal{Freeze: fp}); err != nil {
+		return nil, err
+	}
+	glog.Infof("Freeze point %q is ready, all the %d groups applied ts %d", tag, len(frozen),
+		fp.ReadTs)
+	return fp, nil
+}
+
+// freezeGroups waits for the leader of every group with members to apply readTs.
+func (s *Server) freezeGroups(ctx context.Context, readTs uint64) ([]*pb.FrozenGroup, error) {
+	var gids []uint32
+	s.RLock()
+	for gid, group := range s.state.Groups {
+		if len(group.Members) > 0 {
+			gids = append(gids, gid)
+		}
+	}
+	s.RUnlock()
+	sort.Slice(gids, func(i, j int) bool { return gids[i] < gids[j] })
+
+	frozen := make([]*pb.FrozenGroup, 0, len(gids))
+	for _, gid := range gids {
+		pl := s.Leader(gid)
+		if pl == nil {
+			return nil, errors.Errorf("No healthy connection found to leader of group %d", gid)
+		}
+		wc := pb.NewWorkerClient(pl.Get())
+		fg, err := wc.FreezeGroup(ctx, &pb.FrozenGroup{GroupId: gid, ReadTs: readTs})
+		if err != nil {
+			return nil, errors.Wrapf(err, "while freezing group %d", gid)
+		}
+		frozen = append(frozen, fg)
+	}
+	return frozen, nil
+}
+
+// release removes the freeze point with the tag. The Alphas may then discard the versions that
+// were only kept for it.
+func (s *Server) release(ctx context.Context, tag string) error {
+	if s.freezePoint(tag) == nil {
+		return errors.Errorf("No freeze point tagged %q found.", tag)
+	}
+	if err := s.Node.proposeAndWait(ctx, &pb.ZeroProposal{ReleaseFreeze: tag}); err != nil {
+		return err
+	}
+	glog.Infof("Released freeze point %q", tag)
+	return nil
+}
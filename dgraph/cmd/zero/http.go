@@ -20,6 +20,7 @@ import (
 	"bufio"
 	"context"
 	"crypto/tls"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net"
@@ -134,8 +135,155 @@ func (st *state) removeNode(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// decommission removes an Alpha gracefully. If it's the last voting member of its group, the
+// tablets of the group are moved to the other groups before it's removed. Without a node, it
+// returns the progress of the decommissions.
+func (st *state) decommission(w http.ResponseWriter, r *http.Request) {
+	x.AddCorsHeaders(w)
+	if r.Method == "OPTIONS" {
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusBadRequest)
+		x.SetStatus(w, x.ErrorInvalidMethod, "Invalid method")
+		return
+	}
+
+	if !st.node.AmLeader() {
+		w.WriteHeader(http.StatusBadRequest)
+		x.SetStatus(w, x.ErrorInvalidRequest,
+			"This Zero server is not the leader. Re-run command on leader.")
+		return
+	}
+
+	if r.URL.Query().Get("id") == "" {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(st.zero.decommissionStatus()); err != nil {
+			glog.Warningf("Error while writing response: %+v", err)
+		}
+		return
+	}
+	nodeId, ok := intFromQueryParam(w, r, "id")
+	if !ok {
+		return
+	}
+	groupId, ok := intFromQueryParam(w, r, "group")
+	if !ok {
+		return
+	}
+
+	if err := st.zero.decommissionNode(r.Context(), nodeId, uint32(groupId)); err != nil {
+		x.SetStatus(w, x.Error, err.Error())
+		return
+	}
+	_, err := fmt.Fprintf(w, "Decommissioning node with group: %v, idx: %v", groupId, nodeId)
+	if err != nil {
+		glog.Warningf("Error while writing response: %+v", err)
+	}
+}
+
+// freeze creates a freeze point with the given tag, once all the groups have applied its read
+// timestamp, or releases it if release is set. Without a tag, it returns the freeze points.
+func (st *state) freeze(w http.ResponseWriter, r *http.Request) {
+	x.AddCorsHeaders(w)
+	if r.Method == "OPTIONS" {
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusBadRequest)
+		x.SetStatus(w, x.ErrorInvalidMethod, "Invalid method")
+		return
+	}
+
+	tag := r.URL.Query().Get("tag")
+	if tag == "" {
+		points := []json.RawMessage{}
+		m := jsonpb.Marshaler{EmitDefaults: true}
+		for _, fp := range st.zero.freezePoints() {
+			data, err := m.MarshalToString(fp)
+			if err != nil {
+				x.SetStatus(w, x.ErrorNoData, err.Error())
+				return
+			}
+			points = append(points, json.RawMessage(data))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(points); err != nil {
+			glog.Warningf("Error while writing response: %+v", err)
+		}
+		return
+	}
+
+	if !st.node.AmLeader() {
+		w.WriteHeader(http.StatusBadRequest)
+		x.SetStatus(w, x.ErrorInvalidRequest,
+			"This Zero server is not the leader. Re-run command on leader.")
+		return
+	}
+
+	if release, _ := strconv.ParseBool(r.URL.Query().Get("release")); release {
+		if err := st.zero.release(r.Context(), tag); err != nil {
+			x.SetStatus(w, x.Error, err.Error())
+			return
+		}
+		if _, err := fmt.Fprintf(w, "Released freeze point %q", tag); err != nil {
+			glog.Warningf("Error while writing response: %+v", err)
+		}
+		return
+	}
+
+	fp, err := st.zero.freeze(r.Context(), tag)
+	if err != nil {
+		x.SetStatus(w, x.Error, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	m := jsonpb.Marshaler{EmitDefaults: true}
+	if err := m.Marshal(w, fp); err != nil {
+		x.SetStatus(w, x.ErrorNoData, err.Error())
+		return
+	}
+}
+
+// moves returns the progress of the tablet move in progress, and of the last finished ones. With
+// cancel set to a tablet, it cancels its move.
+func (st *state) moves(w http.ResponseWriter, r *http.Request) {
+	x.AddCorsHeaders(w)
+	if r.Method == "OPTIONS" {
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusBadRequest)
+		x.SetStatus(w, x.ErrorInvalidMethod, "Invalid method")
+		return
+	}
+
+	if !st.node.AmLeader() {
+		w.WriteHeader(http.StatusBadRequest)
+		x.SetStatus(w, x.ErrorInvalidRequest,
+			"This Zero server is not the leader. Re-run command on leader.")
+		return
+	}
+
+	if tablet := r.URL.Query().Get("cancel"); tablet != "" {
+		if err := st.zero.moves.cancelMove(tablet); err != nil {
+			x.SetStatus(w, x.Error, err.Error())
+			return
+		}
+		if _, err := fmt.Fprintf(w, "Cancelling the move of tablet [%s]", tablet); err != nil {
+			glog.Warningf("Error while writing response: %+v", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(st.zero.moveStatus()); err != nil {
+		glog.Warningf("Error while writing response: %+v", err)
+	}
+}
+
 // moveTablet can be used to move a tablet to a specific group. It takes in tablet and group as
-// argument.
+// argument. With dryRun set, it returns what the move would take without making it.
 func (st *state) moveTablet(w http.ResponseWriter, r *http.Request) {
 	x.AddCorsHeaders(w)
 	if r.Method == "OPTIONS" {
@@ -199,6 +347,15 @@ func (st *state) moveTablet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dryRun")); dryRun {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(
+			st.zero.estimateMove(tab, srcGroup, dstGroup)); err != nil {
+			glog.Warningf("Error while writing response: %+v", err)
+		}
+		return
+	}
+
 	if err := st.zero.movePredicate(tablet, srcGroup, dstGroup); err != nil {
 		glog.Errorf("While moving predicate %s from %d -> %d. Error: %v",
 			tablet, srcGroup, dstGroup, err)
@@ -229,6 +386,14 @@ func (st *state) getState(w http.ResponseWriter, r *http.Request) {
 		x.SetStatus(w, x.ErrorNoData, "No membership state found.")
 		return
 	}
+	// Show the rates of the tablets used to rebalance them, as known to this Zero.
+	queries, mutations := st.zero.loads.rates(time.Now())
+	for _, group := range mstate.Groups {
+		for pred, tab := range group.Tablets {
+			tab.QueriesPerMin = uint64(queries[pred])
+			tab.MutationsPerMin = uint64(mutations[pred])
+		}
+	}
 
 	m := jsonpb.Marshaler{EmitDefaults: true}
 	if err := m.Marshal(w, mstate); err != nil {
@@ -0,0 +1,167 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zero
+
+import (
+	"context"
+	"time"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const idempotencyPruneInterval = time.Minute
+
+// idempotencyFromContext returns the record of the commit passed by Alpha along with a commit
+// made with an idempotency key, or nil if there isn't any.
+func idempotencyFromContext(ctx context.Context) (*pb.IdempotencyRecord, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, nil
+	}
+	vals := md.Get(x.IdempotencyRecordKey)
+	if len(vals) == 0 {
+		return nil, nil
+	}
+	rec := &pb.IdempotencyRecord{}
+	if err := rec.Unmarshal([]byte(vals[0])); err != nil {
+		return nil, errors.Wrapf(err, "while parsing idempotency record")
+	}
+	if rec.Key == "" {
+		return nil, errors.Errorf("idempotency record doesn't have a key")
+	}
+	return rec, nil
+}
+
+// idempotencyRecord returns the unexpired record of the commit made with the key. It must be
+// called with the lock held.
+func (s *Server) idempotencyRecord(key string) *pb.IdempotencyRecord {
+	rec, ok := s.idempotency[key]
+	if !ok || rec.ExpiresAt <= time.Now().UnixNano() {
+		return nil
+	}
+	return rec
+}
+
+// reserveIdempotency looks up the commit made with the key of the record. If there's one, its
+// record is returned, or an error if its payload differs from the one of the record. Otherwise,
+// the key is reserved until the returned function is called, so that a concurrent commit with
+// the same key is aborted instead of being applied twice.
+func (s *Server) reserveIdempotency(rec *pb.IdempotencyRecord) (
+	*pb.IdempotencyRecord, func(), error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if prev := s.idempotencyRecord(rec.Key); prev != nil {
+		if prev.Fingerprint != rec.Fingerprint {
+			return nil, nil, status.Errorf(codes.InvalidArgument,
+				"idempotency key %q has already been used by a different request", rec.Key)
+		}
+		return proto.Clone(prev).(*pb.IdempotencyRecord), nil, nil
+	}
+	if _, ok := s.idempotencyPending[rec.Key]; ok {
+		return nil, nil, status.Errorf(codes.Aborted,
+			"a commit with idempotency key %q is already running", rec.Key)
+	}
+	s.idempotencyPending[rec.Key] = struct{}{}
+	return nil, func() {
+		s.Lock()
+		defer s.Unlock()
+		delete(s.idempotencyPending, rec.Key)
+	}, nil
+}
+
+// applyIdempotency records the commit made with an idempotency key. It must be called with the
+// lock held.
+func (s *Server) applyIdempotency(rec *pb.IdempotencyRecord) {
+	s.state.Idempotency = append(s.state.Idempotency, rec)
+	s.idempotency[rec.Key] = rec
+}
+
+// pruneIdempotency removes the records expiring at or before the cutoff chosen by the leader, so
+// that every Zero removes the same records. It must be called with the lock held.
+func (s *Server) pruneIdempotency(cutoff int64) {
+	records := s.state.Idempotency[:0]
+	for _, rec := range s.state.Idempotency {
+		if rec.ExpiresAt > cutoff {
+			records = append(records, rec)
+			continue
+		}
+		if s.idempotency[rec.Key] == rec {
+			delete(s.idempotency, rec.Key)
+		}
+	}
+	for i := len(records); i < len(s.state.Idempotency); i++ {
+		s.state.Idempotency[i] = nil
+	}
+	s.state.Idempotency = records
+}
+
+// pruneIdempotencyRecords has the expired idempotency records removed every
+// idempotencyPruneInterval, when this Zero is the leader.
+func (s *Server) pruneIdempotencyRecords() {
+	ticker := time.NewTicker(idempotencyPruneInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if !s.Node.AmLeader() {
+			continue
+		}
+		cutoff := time.Now().UnixNano()
+		var expired bool
+		s.RLock()
+		for _, rec := range s.state.GetIdempotency() {
+			if rec.ExpiresAt <= cutoff {
+				expired = true
+				break
+			}
+		}
+		s.RUnlock()
+		if !expired {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		err := s.Node.proposeAndWait(ctx, &pb.ZeroProposal{IdempotencyCutoff: cutoff})
+		cancel()
+		if err != nil {
+			glog.Errorf("While removing the expired idempotency records: %v", err)
+		}
+	}
+}
+
+// Idempotency returns the record of the commit made with the idempotency key of the given
+// record, or an empty record if there isn't any.
+func (s *Server) Idempotency(ctx context.Context, req *pb.IdempotencyRecord) (
+	*pb.IdempotencyRecord, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if !s.Node.AmLeader() {
+		return nil, errors.Errorf("Only leader can look up idempotency keys")
+	}
+	s.RLock()
+	defer s.RUnlock()
+	if rec := s.idempotencyRecord(req.Key); rec != nil {
+		return proto.Clone(rec).(*pb.IdempotencyRecord), nil
+	}
+	return &pb.IdempotencyRecord{}, nil
+}
@@ -0,0 +1,105 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zero
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestIdempotencyReserve(t *testing.T) {
+	server := &Server{
+		state:              &pb.MembershipState{},
+		idempotency:        make(map[string]*pb.IdempotencyRecord),
+		idempotencyPending: make(map[string]struct{}),
+	}
+	expiresAt := time.Now().Add(time.Minute).UnixNano()
+	rec := &pb.IdempotencyRecord{Key: "a", Fingerprint: 1, ExpiresAt: expiresAt}
+
+	prev, release, err := server.reserveIdempotency(rec)
+	require.NoError(t, err)
+	require.Nil(t, prev)
+
+	// A concurrent commit with the same key is aborted.
+	_, _, err = server.reserveIdempotency(rec)
+	require.Equal(t, codes.Aborted, status.Code(err))
+
+	server.Lock()
+	server.applyIdempotency(&pb.IdempotencyRecord{Key: "a", Fingerprint: 1, StartTs: 5,
+		CommitTs: 6, ExpiresAt: expiresAt})
+	server.Unlock()
+	release()
+
+	// Retries get the record of the commit, and a different payload is rejected.
+	prev, _, err = server.reserveIdempotency(rec)
+	require.NoError(t, err)
+	require.Equal(t, uint64(6), prev.CommitTs)
+	_, _, err = server.reserveIdempotency(&pb.IdempotencyRecord{Key: "a", Fingerprint: 2})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// The records expiring at or before the cutoff are removed, wherever they are.
+	cutoff := time.Now().UnixNano()
+	server.Lock()
+	server.applyIdempotency(&pb.IdempotencyRecord{Key: "b", ExpiresAt: expiresAt})
+	server.applyIdempotency(&pb.IdempotencyRecord{Key: "c", ExpiresAt: cutoff})
+	server.state.Idempotency[0].ExpiresAt = cutoff - 1
+	server.pruneIdempotency(cutoff)
+	server.Unlock()
+	require.Len(t, server.state.Idempotency, 1)
+	require.Equal(t, "b", server.state.Idempotency[0].Key)
+	require.Len(t, server.idempotency, 1)
+	prev, release, err = server.reserveIdempotency(rec)
+	require.NoError(t, err)
+	require.Nil(t, prev)
+	release()
+
+	// The records are restored along with the state.
+	server.SetMembershipState(&pb.MembershipState{Idempotency: []*pb.IdempotencyRecord{rec}})
+	prev, _, err = server.reserveIdempotency(rec)
+	require.NoError(t, err)
+	require.Equal(t, "a", prev.Key)
+	require.Nil(t, server.membershipState().Idempotency)
+}
+
+func TestIdempotencyFromContext(t *testing.T) {
+	rec, err := idempotencyFromContext(context.Background())
+	require.NoError(t, err)
+	require.Nil(t, rec)
+
+	data, err := (&pb.IdempotencyRecord{Key: "a", Fingerprint: 1}).Marshal()
+	require.NoError(t, err)
+	ctx := metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs(x.IdempotencyRecordKey, string(data)))
+	rec, err = idempotencyFromContext(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "a", rec.Key)
+
+	data, err = (&pb.IdempotencyRecord{Fingerprint: 1}).Marshal()
+	require.NoError(t, err)
+	ctx = metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs(x.IdempotencyRecordKey, string(data)))
+	_, err = idempotencyFromContext(ctx)
+	require.Error(t, err)
+}
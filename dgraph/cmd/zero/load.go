@@ -0,0 +1,119 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zero
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+)
+
+const (
+	// loadWindow is the time constant of the moving averages of the rates of the tablets, so
+	// that a burst of queries doesn't move a tablet on its own.
+	loadWindow = 5 * time.Minute
+	// loadExpiry is how long the rates reported by a member are kept once it stops reporting.
+	loadExpiry = time.Minute
+)
+
+// memberLoad holds the moving averages of the rates per minute of the queries and mutations of
+// the tablets, as reported by a member of a group.
+type memberLoad struct {
+	at        time.Time
+	queries   map[string]float64
+	mutations map[string]float64
+}
+
+// tabletLoads keeps the rates of the tablets reported by the Alphas along with their membership
+// updates. The rates are only needed by the leader to rebalance the tablets, so they aren't
+// proposed, and are collected again after a change of leader.
+type tabletLoads struct {
+	sync.Mutex
+	members map[uint64]*memberLoad
+}
+
+func newTabletLoads() *tabletLoads {
+	return &tabletLoads{members: make(map[uint64]*memberLoad)}
+}
+
+// update averages the rates reported by the member into the ones it reported before. The
+// tablets missing from the report haven't been used since the last one.
+func (l *tabletLoads) update(id uint64, tablets []*pb.Tablet, now time.Time) {
+	l.Lock()
+	defer l.Unlock()
+
+	m, ok := l.members[id]
+	if !ok {
+		// The first report is taken as is.
+		m = &memberLoad{
+			queries:   make(map[string]float64),
+			mutations: make(map[string]float64),
+		}
+		l.members[id] = m
+	}
+	alpha := 1.0
+	if ok {
+		alpha = 1 - math.Exp(-float64(now.Sub(m.at))/float64(loadWindow))
+	}
+	m.at = now
+
+	average := func(rates map[string]float64, pred string, sample uint64) {
+		rates[pred] += (float64(sample) - rates[pred]) * alpha
+	}
+	seen := make(map[string]bool)
+	for _, t := range tablets {
+		seen[t.Predicate] = true
+		average(m.queries, t.Predicate, t.QueriesPerMin)
+		average(m.mutations, t.Predicate, t.MutationsPerMin)
+	}
+	for _, rates := range []map[string]float64{m.queries, m.mutations} {
+		for pred := range rates {
+			if seen[pred] {
+				continue
+			}
+			average(rates, pred, 0)
+			if rates[pred] < 0.01 {
+				delete(rates, pred)
+			}
+		}
+	}
+}
+
+// rates returns the rates per minute of the queries and mutations of the tablets, summed over
+// the members that reported them recently.
+func (l *tabletLoads) rates(now time.Time) (queries, mutations map[string]float64) {
+	l.Lock()
+	defer l.Unlock()
+
+	queries = make(map[string]float64)
+	mutations = make(map[string]float64)
+	for id, m := range l.members {
+		if now.Sub(m.at) > loadExpiry {
+			delete(l.members, id)
+			continue
+		}
+		for pred, r := range m.queries {
+			queries[pred] += r
+		}
+		for pred, r := range m.mutations {
+			mutations[pred] += r
+		}
+	}
+	return queries, mutations
+}
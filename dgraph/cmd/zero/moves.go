@@ -0,0 +1,298 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zero
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+)
+
+const (
+	// maxRecentMoves is the number of finished moves reported by /moves.
+	maxRecentMoves = 10
+	// moveProgressTimeout bounds the time taken to ask the source group for its progress.
+	moveProgressTimeout = 5 * time.Second
+)
+
+// tabletMove is the progress of a tablet move, as reported by /moves.
+type tabletMove struct {
+	Tablet   string `json:"tablet"`
+	SrcGroup uint32 `json:"srcGroup"`
+	DstGroup uint32 `json:"dstGroup"`
+	// Bytes is the size of the tablet on disk, as last reported by the leader of its group.
+	Bytes uint64 `json:"bytes"`
+	// Status is one of starting, streaming, reassigning, cleaning, done, failed or cancelled.
+	Status         string    `json:"status"`
+	StartedAt      time.Time `json:"startedAt"`
+	ElapsedSeconds float64   `json:"elapsedSeconds"`
+	// KeysSent and BytesSent are the posting lists sent so far by the source group. The lists
+	// are sent uncompressed, so their bytes can go past the size of the tablet on disk.
+	KeysSent  uint64 `json:"keysSent"`
+	BytesSent uint64 `json:"bytesSent"`
+	Error     string `json:"error,omitempty"`
+
+	streamingAt time.Time
+	finishedAt  time.Time
+}
+
+// tabletMoves keeps track of the tablet moves run by this Zero. Only one tablet is moved at a
+// time.
+type tabletMoves struct {
+	sync.Mutex
+	current   *tabletMove
+	cancel    context.CancelFunc
+	cancelled bool
+	// recent holds the last finished moves, from the oldest one.
+	recent []tabletMove
+	// rate is the average number of bytes per second the tablets were streamed at by the
+	// previous moves. It's zero until a move is done.
+	rate float64
+}
+
+func newTabletMoves() *tabletMoves {
+	return &tabletMoves{}
+}
+
+// start records the move of the tablet, and returns the context to run it with, which is
+// cancelled if the move is.
+func (m *tabletMoves) start(ctx context.Context, tablet string, srcGroup, dstGroup uint32,
+	bytes uint64) context.Context {
+	m.Lock()
+	defer m.Unlock()
+
+	ctx, m.cancel = context.WithCancel(ctx)
+	m.cancelled = false
+	m.current = &tabletMove{
+		Tablet:    tablet,
+		SrcGroup:  srcGroup,
+		DstGroup:  dstGroup,
+		Bytes:     bytes,
+		Status:    "starting",
+		StartedAt: time.Now(),
+	}
+	return ctx
+}
+
+// setStatus moves the current move to the next step. Once the source group is done streaming the
+// tablet, the rate of the moves is updated.
+func (m *tabletMoves) setStatus(status string) {
+	m.Lock()
+	defer m.Unlock()
+
+	cur := m.current
+	if cur == nil {
+		return
+	}
+	now := time.Now()
+	switch {
+	case status == "streaming":
+		cur.streamingAt = now
+	case cur.Status == "streaming" && cur.Bytes > 0:
+		if secs := now.Sub(cur.streamingAt).Seconds(); secs > 0 {
+			rate := float64(cur.Bytes) / secs
+			if m.rate > 0 {
+				rate = (m.rate + rate) / 2
+			}
+			m.rate = rate
+		}
+	}
+	cur.Status = status
+}
+
+// finish records the end of the current move.
+func (m *tabletMoves) finish(err error) {
+	m.Lock()
+	defer m.Unlock()
+
+	cur := m.current
+	if cur == nil {
+		return
+	}
+	m.cancel()
+	switch {
+	case err == nil:
+		cur.Status = "done"
+	case m.cancelled:
+		cur.Status = "cancelled"
+	default:
+		cur.Status = "failed"
+		cur.Error = err.Error()
+	}
+	cur.finishedAt = time.Now()
+	cur.ElapsedSeconds = cur.finishedAt.Sub(cur.StartedAt).Seconds()
+	m.recent = append(m.recent, *cur)
+	if len(m.recent) > maxRecentMoves {
+		m.recent = m.recent[len(m.recent)-maxRecentMoves:]
+	}
+	m.current = nil
+}
+
+// cancelMove cancels the move of the tablet, if it's in progress. A move can't be cancelled once
+// the tablet is being reassigned to the destination group.
+func (m *tabletMoves) cancelMove(tablet string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	cur := m.current
+	if cur == nil || cur.Tablet != tablet {
+		return errors.Errorf("No move of tablet %s in progress.", tablet)
+	}
+	if cur.Status != "starting" && cur.Status != "streaming" {
+		return errors.Errorf("The move of tablet %s can't be cancelled once it's %s.",
+			tablet, cur.Status)
+	}
+	m.cancelled = true
+	m.cancel()
+	return nil
+}
+
+// estimate returns how many seconds it would take to stream the bytes at the rate of the previous
+// moves, or zero if no move is done yet.
+func (m *tabletMoves) estimate(bytes uint64) float64 {
+	m.Lock()
+	defer m.Unlock()
+	if m.rate == 0 {
+		return 0
+	}
+	return float64(bytes) / m.rate
+}
+
+// status returns the move in progress, if any, and the last finished moves.
+func (m *tabletMoves) status() (*tabletMove, []tabletMove) {
+	m.Lock()
+	defer m.Unlock()
+
+	recent := append([]tabletMove{}, m.recent...)
+	if m.current == nil {
+		return nil, recent
+	}
+	cur := *m.current
+	cur.ElapsedSeconds = time.Since(cur.StartedAt).Seconds()
+	return &cur, recent
+}
+
+// setSent records the keys, and their bytes, sent so far by the source group of the current move.
+func (m *tabletMoves) setSent(tablet string, keys, bytes uint64) {
+	m.Lock()
+	defer m.Unlock()
+	if cur := m.current; cur != nil && cur.Tablet == tablet {
+		cur.KeysSent, cur.BytesSent = keys, bytes
+	}
+}
+
+// updateMoveProgress asks the leader of the source group of the current move for its progress.
+func (s *Server) updateMoveProgress() {
+	cur, _ := s.moves.status()
+	if cur == nil {
+		return
+	}
+	pl := s.Leader(cur.SrcGroup)
+	if pl == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), moveProgressTimeout)
+	defer cancel()
+	wc := pb.NewWorkerClient(pl.Get())
+	progress, err := wc.MoveProgress(ctx, &pb.MovePredicatePayload{
+		Predicate: cur.Tablet,
+		SourceGid: cur.SrcGroup,
+		DestGid:   cur.DstGroup,
+	})
+	if err != nil {
+		glog.V(2).Infof("While getting the progress of the move of %s: %v", cur.Tablet, err)
+		return
+	}
+	s.moves.setSent(cur.Tablet, progress.Keys, progress.Bytes)
+}
+
+// moveStatus returns the moves run by this Zero, from the oldest one. The progress of the move
+// being streamed is asked to the leader of its source group.
+func (s *Server) moveStatus() []tabletMove {
+	if cur, _ := s.moves.status(); cur != nil && cur.Status == "streaming" {
+		s.updateMoveProgress()
+	}
+	cur, moves := s.moves.status()
+	if cur == nil {
+		return moves
+	}
+	return append(moves, *cur)
+}
+
+// moveEstimate is what /moveTablet reports about a move without making it, with dryRun set.
+type moveEstimate struct {
+	Tablet   string `json:"tablet"`
+	SrcGroup uint32 `json:"srcGroup"`
+	DstGroup uint32 `json:"dstGroup"`
+	// Bytes is the size of the tablet on disk, as last reported by the leader of its group.
+	Bytes uint64 `json:"bytes"`
+	// EstimatedSeconds is the time the move would take at the rate of the previous moves run by
+	// this Zero, or zero if none is done yet.
+	EstimatedSeconds float64 `json:"estimatedSeconds"`
+	// DstBytes and DstBytesAfter are the size of the destination group before and after the
+	// move. Headroom is how far the destination group would stay below the average size of the
+	// groups after the move. It's negative if the destination would be above the average.
+	DstBytes      uint64 `json:"dstBytes"`
+	DstBytesAfter uint64 `json:"dstBytesAfter"`
+	Headroom      int64  `json:"headroom"`
+	// Error is the reason the move would be refused, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// estimateMove returns what the move of the tablet from srcGroup to dstGroup would take.
+func (s *Server) estimateMove(tab *pb.Tablet, srcGroup, dstGroup uint32) moveEstimate {
+	est := moveEstimate{
+		Tablet:           tab.Predicate,
+		SrcGroup:         srcGroup,
+		DstGroup:         dstGroup,
+		Bytes:            uint64(tab.Space),
+		EstimatedSeconds: s.moves.estimate(uint64(tab.Space)),
+	}
+	if err := s.checkEdgeMove(tab.Predicate); err != nil {
+		est.Error = err.Error()
+	} else if err := s.checkMove(tab.Predicate, dstGroup); err != nil {
+		est.Error = err.Error()
+	}
+
+	s.RLock()
+	defer s.RUnlock()
+	// The groups being drained end up empty, their tablets are shared by the other groups.
+	draining := s.drainingGroups()
+	var total uint64
+	var groups int64
+	for gid, group := range s.state.Groups {
+		for _, t := range group.Tablets {
+			total += uint64(t.Space)
+		}
+		if !draining[gid] {
+			groups++
+		}
+	}
+	for _, t := range s.state.Groups[dstGroup].GetTablets() {
+		est.DstBytes += uint64(t.Space)
+	}
+	est.DstBytesAfter = est.DstBytes + est.Bytes
+	if groups > 0 {
+		est.Headroom = int64(total)/groups - int64(est.DstBytesAfter)
+	}
+	return est
+}
@@ -27,9 +27,12 @@ import (
 	"github.com/dgraph-io/dgo/v200/protos/api"
 	"github.com/dgraph-io/dgraph/protos/pb"
 	"github.com/dgraph-io/dgraph/x"
+	"github.com/gogo/protobuf/proto"
 	"github.com/golang/glog"
 	"github.com/pkg/errors"
 	otrace "go.opencensus.io/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type syncMark struct {
@@ -302,12 +305,24 @@ var ErrConflict = errors.New("Transaction conflict")
 // proposeTxn proposes a txn update, and then updates src to reflect the state
 // of the commit after proposal is run.
 func (s *Server) proposeTxn(ctx context.Context, src *api.TxnContext) error {
+	return s.proposeTxnWithIdempotency(ctx, src, nil)
+}
+
+// proposeTxnWithIdempotency proposes the status of the txn. If the txn is committed, the commit
+// is recorded along with it for the idempotency key of rec, if not nil.
+func (s *Server) proposeTxnWithIdempotency(ctx context.Context, src *api.TxnContext,
+	rec *pb.IdempotencyRecord) error {
 	var zp pb.ZeroProposal
 	zp.Txn = &api.TxnContext{
 		StartTs:  src.StartTs,
 		CommitTs: src.CommitTs,
 		Aborted:  src.Aborted,
 	}
+	if rec != nil && !src.Aborted {
+		zp.Idempotency = proto.Clone(rec).(*pb.IdempotencyRecord)
+		zp.Idempotency.StartTs = src.StartTs
+		zp.Idempotency.CommitTs = src.CommitTs
+	}
 
 	// NOTE: It is important that we continue retrying proposeTxn until we succeed. This should
 	// happen, irrespective of what the user context timeout might be. We check for it before
@@ -343,6 +358,37 @@ func (s *Server) commit(ctx context.Context, src *api.TxnContext) error {
 		return s.proposeTxn(ctx, src)
 	}
 
+	rec, err := idempotencyFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if rec != nil {
+		prev, release, err := s.reserveIdempotency(rec)
+		switch {
+		case err != nil:
+			src.Aborted = true
+			if perr := s.proposeTxn(ctx, src); perr != nil {
+				return perr
+			}
+			return err
+		case prev != nil && prev.StartTs == src.StartTs:
+			// A retry of a commit that already went through.
+			src.CommitTs = prev.CommitTs
+			return nil
+		case prev != nil:
+			// The request was already committed in another txn, so this one is discarded. The
+			// Alpha looks up the outcome of the first one.
+			span.Annotatef(nil, "Idempotency key %q was used by txn %d", rec.Key, prev.StartTs)
+			src.Aborted = true
+			if perr := s.proposeTxn(ctx, src); perr != nil {
+				return perr
+			}
+			return status.Errorf(codes.AlreadyExists,
+				"a commit with idempotency key %q already went through", rec.Key)
+		}
+		defer release()
+	}
+
 	// Use the start timestamp to check if we have a conflict, before we need to assign a commit ts.
 	s.orc.RLock()
 	conflict := s.orc.hasConflict(src)
@@ -411,7 +457,7 @@ func (s *Server) commit(ctx context.Context, src *api.TxnContext) error {
 		src.Aborted = true
 	}
 	// Propose txn should be used to set watermark as done.
-	return s.proposeTxn(ctx, src)
+	return s.proposeTxnWithIdempotency(ctx, src, rec)
 }
 
 // CommitOrAbort either commits a transaction or aborts it.
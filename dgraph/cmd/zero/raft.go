@@ -207,18 +207,23 @@ func (n *node) handleMemberProposal(member *pb.Member) error {
 		}
 		return nil
 	}
-	if !has && len(group.Members) >= n.server.NumReplicas {
+	if !has && !member.Learner && numVoters(group) >= n.server.NumReplicas {
 		// We shouldn't allow more members than the number of replicas.
 		return errors.Errorf("Group reached replication level. Can't add another member: %+v", member)
 	}
 
+	// Only Zero starts decommissioning a member, so the updates from the member keep it.
+	if has && m.Decommissioning {
+		member.Decommissioning = true
+	}
+
 	// Create a connection to this server.
 	go conn.GetPools().Connect(member.Addr)
 
 	group.Members[member.Id] = member
 	// Increment nextGroup when we have enough replicas
 	if member.GroupId == n.server.nextGroup &&
-		len(group.Members) >= n.server.NumReplicas {
+		numVoters(group) >= n.server.NumReplicas {
 		n.server.nextGroup++
 	}
 	if member.Leader {
@@ -371,6 +376,18 @@ func (n *node) applyProposal(e raftpb.Entry) (string, error) {
 		expiry := time.Unix(state.License.ExpiryTs, 0).UTC()
 		state.License.Enabled = time.Now().UTC().Before(expiry)
 	}
+	if p.StrictSchema != nil {
+		state.StrictSchema = p.StrictSchema
+	}
+	if p.Replication != nil {
+		state.Replication = p.Replication
+	}
+	if p.Freeze != nil {
+		applyFreeze(state, p.Freeze)
+	}
+	if p.ReleaseFreeze != "" {
+		releaseFreeze(state, p.ReleaseFreeze)
+	}
 
 	switch {
 	case p.MaxLeaseId > state.MaxLeaseId:
@@ -385,6 +402,14 @@ func (n *node) applyProposal(e raftpb.Entry) (string, error) {
 	}
 	if p.Txn != nil {
 		n.server.orc.updateCommitStatus(e.Index, p.Txn)
+		// The commit is only recorded if it's the one that decided the status of the txn.
+		if rec := p.Idempotency; rec != nil && rec.CommitTs > 0 &&
+			n.server.orc.commitTs(p.Txn.StartTs) == rec.CommitTs {
+			n.server.applyIdempotency(rec)
+		}
+	}
+	if p.IdempotencyCutoff > 0 {
+		n.server.pruneIdempotency(p.IdempotencyCutoff)
 	}
 
 	return p.Key, nil
@@ -459,6 +484,20 @@ func (n *node) proposeNewCID() {
 		time.Sleep(3 * time.Second)
 	}
 
+	if Zero.Conf.GetBool("strict_schema") {
+		zp := &pb.ZeroProposal{StrictSchema: &pb.StrictSchema{Enabled: true}}
+		if err := n.proposeAndWait(context.Background(), zp); err != nil {
+			glog.Errorf("While enabling the strict schema mode: %v", err)
+		}
+	}
+
+	if primary := Zero.Conf.GetString("replica_of"); primary != "" {
+		zp := &pb.ZeroProposal{Replication: &pb.ReplicationState{Primary: primary}}
+		if err := n.proposeAndWait(context.Background(), zp); err != nil {
+			glog.Errorf("While making the cluster a replica of %s: %v", primary, err)
+		}
+	}
+
 	// Apply trial license only if not already licensed and no enterprise license provided.
 	if n.server.license() == nil && Zero.Conf.GetString("enterprise_license") == "" {
 		if err := n.proposeTrialLicense(); err != nil {
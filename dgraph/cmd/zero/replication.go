@@ -0,0 +1,86 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zero
+
+import (
+	"context"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+)
+
+// isReplica returns true if this cluster replicates the data of a primary cluster.
+func (s *Server) isReplica() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.state.GetReplication().GetPrimary() != ""
+}
+
+// mergeReplication returns the replication state resulting from the update of the current one.
+// A request to promote the replica only marks the state, the progress is recorded by the Alpha
+// replicating the data, which clears the primary once it has promoted the cluster.
+func mergeReplication(cur, req *pb.ReplicationState) (*pb.ReplicationState, error) {
+	if cur.GetPrimary() == "" {
+		return nil, errors.Errorf("This cluster isn't a replica.")
+	}
+	if req.Promote {
+		res := proto.Clone(cur).(*pb.ReplicationState)
+		res.Promote = true
+		return res, nil
+	}
+	res := proto.Clone(req).(*pb.ReplicationState)
+	if res.Primary != "" {
+		res.Promote = cur.Promote
+	}
+	return res, nil
+}
+
+// UpdateReplication records the progress of the replication of this replica cluster, or requests
+// its promotion. The state is kept in the membership state, which is streamed to all the Alphas.
+func (s *Server) UpdateReplication(ctx context.Context, req *pb.ReplicationState) (
+	*api.Payload, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	s.replicationLock.Lock()
+	defer s.replicationLock.Unlock()
+
+	s.RLock()
+	cur := s.state.GetReplication()
+	s.RUnlock()
+	state, err := mergeReplication(cur, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Node.proposeAndWait(ctx, &pb.ZeroProposal{Replication: state}); err != nil {
+		return nil, err
+	}
+	switch {
+	case state.Primary == "":
+		glog.Infof("Replica promoted, it's no longer replicating %s", cur.Primary)
+	case req.Promote:
+		glog.Infof("Promotion of the replica of %s requested", state.Primary)
+	default:
+		glog.V(2).Infof("Replicated %s up to %d at %d", state.Primary, state.PrimaryTs,
+			state.ReadTs)
+	}
+	return &api.Payload{Data: []byte("OK")}, nil
+}
@@ -52,9 +52,18 @@ type options struct {
 	peer              string
 	w                 string
 	rebalanceInterval time.Duration
+	// The weights of the size, and of the rates of the queries and mutations of the tablets
+	// when rebalancing them.
+	sizeWeight        float64
+	queryWeight       float64
+	mutationWeight    float64
 	tlsDir            string
 	tlsDisabledRoutes []string
 	totalCache        int64
+	// The dead node policy.
+	deadNodeTimeout time.Duration
+	deadNodeWebhook string
+	deadNodeRemove  bool
 }
 
 var opts options
@@ -89,13 +98,36 @@ instances to achieve high-availability.
 	flag.String("peer", "", "Address of another dgraphzero server.")
 	flag.StringP("wal", "w", "zw", "Directory storing WAL.")
 	flag.Duration("rebalance_interval", 8*time.Minute, "Interval for trying a predicate move.")
+	flag.Float64("rebalance_size_weight", 1,
+		"Weight of the on-disk size of the tablets when balancing the groups.")
+	flag.Float64("rebalance_query_weight", 1,
+		"Weight of the rate of queries of the tablets when balancing the groups.")
+	flag.Float64("rebalance_mutation_weight", 1,
+		"Weight of the rate of mutations of the tablets when balancing the groups.")
+	flag.Duration("dead_node_timeout", 0,
+		"Time after which an Alpha unreachable from the Zero leader is considered dead, and the "+
+			"dead node policy is applied to it. Zero disables the policy.")
+	flag.String("dead_node_webhook", "",
+		"URL the Zero leader posts an event to when an Alpha is found dead, or recovers.")
+	flag.Bool("dead_node_remove", false,
+		"Remove the dead Alphas from the cluster, unless they're the last voting member of a group "+
+			"with tablets, so that new Alphas join their groups and replicate them.")
 	flag.String("enterprise_license", "", "Path to the enterprise license file.")
+	// Encryption of the WAL, with the same key as the Alphas.
+	enc.RegisterFlags(flag)
+	flag.Bool("strict_schema", false,
+		"Reject the mutations using predicates or types that aren't defined in the schema, from "+
+			"the creation of the cluster. Can be changed at runtime through the config mutation "+
+			"of the admin API of Alpha.")
+	flag.String("replica_of", "",
+		"Address of a Zero of the primary cluster this cluster replicates, from the creation of "+
+			"the cluster. A replica is read-only until it's promoted through the admin API of Alpha.")
 	// TLS configurations
 	flag.String("tls_dir", "", "Path to directory that has TLS certificates and keys.")
 	flag.Bool("tls_use_system_ca", true, "Include System CA into CA Certs.")
 	flag.String("tls_client_auth", "VERIFYIFGIVEN", "Enable TLS client authentication")
 	flag.String("tls_disabled_route", "", "comma separated zero endpoint which will be disabled from TLS encryption."+
-		"Valid values are /health,/state,/removeNode,/moveTablet,/assign,/enterpriseLicense,/debug.")
+		"Valid values are /health,/state,/removeNode,/decommission,/freeze,/moveTablet,/moves,/assign,/enterpriseLicense,/debug.")
 }
 
 func setupListener(addr string, port int, kind string) (listener net.Listener, err error) {
@@ -181,9 +213,15 @@ func run() {
 		peer:              Zero.Conf.GetString("peer"),
 		w:                 Zero.Conf.GetString("wal"),
 		rebalanceInterval: Zero.Conf.GetDuration("rebalance_interval"),
+		sizeWeight:        Zero.Conf.GetFloat64("rebalance_size_weight"),
+		queryWeight:       Zero.Conf.GetFloat64("rebalance_query_weight"),
+		mutationWeight:    Zero.Conf.GetFloat64("rebalance_mutation_weight"),
 		totalCache:        int64(Zero.Conf.GetInt("cache_mb")),
 		tlsDir:            Zero.Conf.GetString("tls_dir"),
 		tlsDisabledRoutes: tlsDisRoutes,
+		deadNodeTimeout:   Zero.Conf.GetDuration("dead_node_timeout"),
+		deadNodeWebhook:   Zero.Conf.GetString("dead_node_webhook"),
+		deadNodeRemove:    Zero.Conf.GetBool("dead_node_remove"),
 	}
 	glog.Infof("Setting Config to: %+v", opts)
 
@@ -195,6 +233,11 @@ func run() {
 	if !enc.EeBuild && Zero.Conf.GetString("enterprise_license") != "" {
 		log.Fatalf("ERROR: enterprise_license option cannot be applied to OSS builds. ")
 	}
+	key, err := enc.ReadKey(Zero.Conf)
+	if err != nil {
+		log.Fatalf("ERROR: unable to read the encryption key: %v", err)
+	}
+	x.WorkerConfig.EncryptionKey = key
 
 	if opts.numReplicas < 0 || opts.numReplicas%2 == 0 {
 		log.Fatalf("ERROR: Number of replicas must be odd for consensus. Found: %d",
@@ -212,6 +255,19 @@ func run() {
 		log.Fatalf("ERROR: Rebalance interval must be greater than zero. Found: %d",
 			opts.rebalanceInterval)
 	}
+	if opts.deadNodeTimeout < 0 {
+		log.Fatalf("ERROR: Dead node timeout can't be negative. Found: %v", opts.deadNodeTimeout)
+	}
+	if opts.deadNodeRemove && opts.deadNodeTimeout == 0 {
+		log.Fatalf("ERROR: --dead_node_remove requires --dead_node_timeout.")
+	}
+	if opts.sizeWeight < 0 || opts.queryWeight < 0 || opts.mutationWeight < 0 {
+		log.Fatalf("ERROR: Rebalance weights can't be negative. Found: size %v, query %v, "+
+			"mutation %v", opts.sizeWeight, opts.queryWeight, opts.mutationWeight)
+	}
+	if opts.sizeWeight+opts.queryWeight+opts.mutationWeight == 0 {
+		log.Fatalf("ERROR: At least one of the rebalance weights must be greater than zero.")
+	}
 
 	grpc.EnableTracing = false
 	otrace.ApplyConfig(otrace.Config{
@@ -246,7 +302,10 @@ func run() {
 	http.HandleFunc("/health", st.pingResponse)
 	http.HandleFunc("/state", st.getState)
 	http.HandleFunc("/removeNode", st.removeNode)
+	http.HandleFunc("/decommission", st.decommission)
+	http.HandleFunc("/freeze", st.freeze)
 	http.HandleFunc("/moveTablet", st.moveTablet)
+	http.HandleFunc("/moves", st.moves)
 	http.HandleFunc("/assign", st.assign)
 	http.HandleFunc("/enterpriseLicense", st.applyEnterpriseLicense)
 	zpages.Handle(http.DefaultServeMux, "/z")
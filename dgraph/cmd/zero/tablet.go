@@ -62,6 +62,10 @@ This would trigger G1 to get latest state. Wait for it.
 func (s *Server) rebalanceTablets() {
 	ticker := time.NewTicker(opts.rebalanceInterval)
 	for range ticker.C {
+		if s.isReplica() {
+			// The tablets of a replica follow the data replicated from the primary.
+			continue
+		}
 		predicate, srcGroup, dstGroup := s.chooseTablet()
 		if len(predicate) == 0 {
 			continue
@@ -76,6 +80,48 @@ func (s *Server) rebalanceTablets() {
 // for the entire duration of predicate move. If this Zero stops being the leader, the final
 // proposal of reassigning the tablet to the destination would fail automatically.
 func (s *Server) movePredicate(predicate string, srcGroup, dstGroup uint32) error {
+	if err := s.checkEdgeMove(predicate); err != nil {
+		return err
+	}
+	return s.moveTablet(predicate, srcGroup, dstGroup)
+}
+
+// checkEdgeMove ensures that edge predicates stay with the properties of their edges.
+func (s *Server) checkEdgeMove(predicate string) error {
+	s.RLock()
+	gid := s.edgeGroup(predicate)
+	s.RUnlock()
+	if gid != 0 {
+		return errors.Errorf("Unable to move predicate %s, it's served along with the"+
+			" properties of its edges", predicate)
+	}
+	return nil
+}
+
+// checkMove returns an error if the predicate can't be moved to dstGroup.
+func (s *Server) checkMove(predicate string, dstGroup uint32) error {
+	// Ensure that the tablets of a replica stay where the replicated data is written.
+	if s.isReplica() {
+		return errors.Errorf("Unable to move predicate %s on a replica cluster", predicate)
+	}
+	// Ensure that reserved predicates cannot be moved.
+	if x.IsReservedPredicate(predicate) {
+		return errors.Errorf("Unable to move reserved predicate %s", predicate)
+	}
+	// Ensure that the groups being decommissioned aren't assigned tablets.
+	s.RLock()
+	draining := s.drainingGroups()
+	s.RUnlock()
+	if draining[dstGroup] {
+		return errors.Errorf("Unable to move predicate %s to group %d, which is being drained",
+			predicate, dstGroup)
+	}
+	return nil
+}
+
+// moveTablet moves the predicate from srcGroup to dstGroup. Unlike movePredicate, it doesn't keep
+// the edge predicates with the properties of their edges, which the caller moves together.
+func (s *Server) moveTablet(predicate string, srcGroup, dstGroup uint32) (err error) {
 	s.moveOngoing <- struct{}{}
 	defer func() {
 		<-s.moveOngoing
@@ -87,11 +133,9 @@ func (s *Server) movePredicate(predicate string, srcGroup, dstGroup uint32) erro
 	ctx, span := otrace.StartSpan(ctx, "Zero.MovePredicate")
 	defer span.End()
 
-	// Ensure that reserved predicates cannot be moved.
-	if x.IsReservedPredicate(predicate) {
-		return errors.Errorf("Unable to move reserved predicate %s", predicate)
+	if err := s.checkMove(predicate, dstGroup); err != nil {
+		return err
 	}
-
 	// Ensure that I'm connected to the rest of the Zero group, and am the leader.
 	if _, err := s.latestMembershipState(ctx); err != nil {
 		return errors.Wrapf(err, "unable to reach quorum")
@@ -103,6 +147,11 @@ func (s *Server) movePredicate(predicate string, srcGroup, dstGroup uint32) erro
 	if tab == nil {
 		return errors.Errorf("Tablet to be moved: [%v] is not being served", predicate)
 	}
+	// The move can be followed, and cancelled until the tablet is reassigned, through /moves.
+	ctx = s.moves.start(ctx, predicate, srcGroup, dstGroup, uint64(tab.Space))
+	defer func() {
+		s.moves.finish(err)
+	}()
 	msg := fmt.Sprintf("Going to move predicate: [%v], size: [%v] from group %d to %d\n", predicate,
 		humanize.Bytes(uint64(tab.Space)), srcGroup, dstGroup)
 	glog.Info(msg)
@@ -133,9 +182,16 @@ func (s *Server) movePredicate(predicate string, srcGroup, dstGroup uint32) erro
 	}
 	span.Annotatef(nil, "Starting move: %+v", in)
 	glog.Infof("Starting move: %+v", in)
+	s.moves.setStatus("streaming")
 	if _, err := wc.MovePredicate(ctx, in); err != nil {
 		return errors.Wrapf(err, "while calling MovePredicate")
 	}
+	s.updateMoveProgress()
+	// The move can't be cancelled from now on.
+	s.moves.setStatus("reassigning")
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
 	p := &pb.ZeroProposal{}
 	p.Tablet = &pb.Tablet{
@@ -161,6 +217,7 @@ func (s *Server) movePredicate(predicate string, srcGroup, dstGroup uint32) erro
 	// served by the destination group. For that, we pass in the expected checksum for the source
 	// group. Only once the source group membership checksum matches, would the source group delete
 	// the predicate. This ensures that it does not service any transaction after deletion of data.
+	s.moves.setStatus("cleaning")
 	checksums := s.groupChecksums()
 	in.ExpectedChecksum = checksums[in.SourceGid]
 	in.DestGid = 0 // Indicates deletion of predicate in the source group.
@@ -177,6 +234,36 @@ func (s *Server) movePredicate(predicate string, srcGroup, dstGroup uint32) erro
 	return nil
 }
 
+// tabletWeights returns the weight of every tablet, used to balance the groups. Each of the
+// size, and the rates of the queries and mutations of a tablet counts as its share of the total
+// over all the tablets, times the weight given to it by the rebalance flags.
+func (s *Server) tabletWeights() map[string]float64 {
+	queries, mutations := s.loads.rates(time.Now())
+	var totalSpace, totalQueries, totalMutations float64
+	for _, group := range s.state.Groups {
+		for pred, tab := range group.Tablets {
+			totalSpace += float64(tab.Space)
+			totalQueries += queries[pred]
+			totalMutations += mutations[pred]
+		}
+	}
+	share := func(v, total, weight float64) float64 {
+		if total == 0 {
+			return 0
+		}
+		return weight * v / total
+	}
+	weights := make(map[string]float64)
+	for _, group := range s.state.Groups {
+		for pred, tab := range group.Tablets {
+			weights[pred] = share(float64(tab.Space), totalSpace, opts.sizeWeight) +
+				share(queries[pred], totalQueries, opts.queryWeight) +
+				share(mutations[pred], totalMutations, opts.mutationWeight)
+		}
+	}
+	return weights
+}
+
 func (s *Server) chooseTablet() (predicate string, srcGroup uint32, dstGroup uint32) {
 	s.RLock()
 	defer s.RUnlock()
@@ -188,54 +275,65 @@ func (s *Server) chooseTablet() (predicate string, srcGroup uint32, dstGroup uin
 		return
 	}
 
-	// Sort all groups by their sizes.
+	// Sort all groups by the weights of their tablets.
+	weights := s.tabletWeights()
 	type kv struct {
-		gid  uint32
-		size int64 // in bytes
+		gid    uint32
+		weight float64
 	}
 	var groups []kv
+	draining := s.drainingGroups()
 	for k, v := range s.state.Groups {
-		space := int64(0)
-		for _, tab := range v.Tablets {
-			space += tab.Space
+		// The tablets of the groups being drained are moved by their decommission.
+		if draining[k] {
+			continue
+		}
+		weight := 0.0
+		for pred := range v.Tablets {
+			weight += weights[pred]
 		}
-		groups = append(groups, kv{k, space})
+		groups = append(groups, kv{k, weight})
 	}
 	sort.Slice(groups, func(i, j int) bool {
-		return groups[i].size < groups[j].size
+		return groups[i].weight < groups[j].weight
 	})
+	numGroups = len(groups)
 
-	glog.Infof("\n\nGroups sorted by size: %+v\n\n", groups)
+	glog.Infof("\n\nGroups sorted by weight: %+v\n\n", groups)
 	for lastGroup := numGroups - 1; lastGroup > 0; lastGroup-- {
 		srcGroup = groups[lastGroup].gid
 		dstGroup = groups[0].gid
-		sizeDiff := groups[lastGroup].size - groups[0].size
-		glog.Infof("size_diff %v\n", sizeDiff)
+		weightDiff := groups[lastGroup].weight - groups[0].weight
+		glog.Infof("weight_diff %v\n", weightDiff)
 		// Don't move a node unless you receive atleast one update regarding tablet size.
 		// Tablet size would have come up with leader update.
 		if !s.hasLeader(dstGroup) {
 			return
 		}
-		// We move the predicate only if the difference between size of both machines is
+		// We move the predicate only if the difference between the weights of both groups is
 		// atleast 10% of dst group.
-		if float64(sizeDiff) < 0.1*float64(groups[0].size) {
+		if weightDiff < 0.1*groups[0].weight {
 			continue
 		}
 
 		// Try to find a predicate which we can move.
-		size := int64(0)
+		weight := 0.0
 		group := s.state.Groups[srcGroup]
 		for _, tab := range group.Tablets {
 			// Reserved predicates should always be in group 1 so do not re-balance them.
 			if x.IsReservedPredicate(tab.Predicate) {
 				continue
 			}
+			// Edge predicates and the properties of their edges are served together.
+			if s.edgeGroup(tab.Predicate) != 0 {
+				continue
+			}
 
-			// Finds a tablet as big a possible such that on moving it dstGroup's size is
+			// Finds a tablet as heavy as possible such that on moving it dstGroup's weight is
 			// less than or equal to srcGroup.
-			if tab.Space <= sizeDiff/2 && tab.Space > size {
+			if w := weights[tab.Predicate]; w <= weightDiff/2 && w > weight {
 				predicate = tab.Predicate
-				size = tab.Space
+				weight = w
 			}
 		}
 		if len(predicate) > 0 {
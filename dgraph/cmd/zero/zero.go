@@ -71,6 +71,23 @@ type Server struct {
 
 	moveOngoing    chan struct{}
 	blockCommitsOn *sync.Map
+
+	// idempotency indexes the records of state.Idempotency by key.
+	idempotency map[string]*pb.IdempotencyRecord
+	// idempotencyPending holds the idempotency keys of the commits that are running.
+	idempotencyPending map[string]struct{}
+	// loads holds the query and mutation rates of the tablets reported by the Alphas.
+	loads *tabletLoads
+	// replicationLock serializes the updates of the replication state.
+	replicationLock sync.Mutex
+
+	// decommissions holds the progress of the decommissions run by this Zero, by Raft ID.
+	decommissions    map[uint64]*decommission
+	decommissionLock sync.Mutex
+	decommissionCh   chan struct{}
+
+	// moves holds the progress of the tablet moves run by this Zero.
+	moves *tabletMoves
 }
 
 // Init initializes the zero server.
@@ -92,8 +109,17 @@ func (s *Server) Init() {
 	s.closer = z.NewCloser(2) // grpc and http
 	s.blockCommitsOn = new(sync.Map)
 	s.moveOngoing = make(chan struct{}, 1)
+	s.idempotency = make(map[string]*pb.IdempotencyRecord)
+	s.idempotencyPending = make(map[string]struct{})
+	s.loads = newTabletLoads()
+	s.decommissions = make(map[uint64]*decommission)
+	s.decommissionCh = make(chan struct{}, 1)
+	s.moves = newTabletMoves()
 
 	go s.rebalanceTablets()
+	go s.processDecommissions()
+	go s.evictDeadNodes()
+	go s.pruneIdempotencyRecords()
 }
 
 func (s *Server) periodicallyPostTelemetry() {
@@ -239,6 +265,11 @@ func (s *Server) SetMembershipState(state *pb.MembershipState) {
 		}
 	}
 	s.nextGroup = uint32(len(state.Groups) + 1)
+
+	s.idempotency = make(map[string]*pb.IdempotencyRecord, len(state.Idempotency))
+	for _, rec := range state.Idempotency {
+		s.idempotency[rec.Key] = rec
+	}
 }
 
 // MarshalMembershipState returns the marshaled membership state.
@@ -251,7 +282,10 @@ func (s *Server) MarshalMembershipState() ([]byte, error) {
 func (s *Server) membershipState() *pb.MembershipState {
 	s.RLock()
 	defer s.RUnlock()
-	return proto.Clone(s.state).(*pb.MembershipState)
+	state := proto.Clone(s.state).(*pb.MembershipState)
+	// The idempotency records are only used by Zero.
+	state.Idempotency = nil
+	return state
 }
 
 func (s *Server) groupChecksums() map[uint32]uint64 {
@@ -306,6 +340,24 @@ func (s *Server) ServingTablet(tablet string) *pb.Tablet {
 	return nil
 }
 
+// edgeGroup returns the group serving the edge predicate of the given predicate, or the
+// properties of its edges, or 0 if there's none. An edge predicate and the properties of its edges
+// are served by the same group, so that the properties can be built from the edges locally.
+func (s *Server) edgeGroup(pred string) uint32 {
+	s.AssertRLock()
+
+	edge, _ := x.ParseEdgeProperty(pred)
+	prefix := x.EdgeProperty(edge, "")
+	for gid, group := range s.state.Groups {
+		for key := range group.Tablets {
+			if key != pred && (key == edge || strings.HasPrefix(key, prefix)) {
+				return gid
+			}
+		}
+	}
+	return 0
+}
+
 func (s *Server) blockTablet(pred string) func() {
 	s.blockCommitsOn.Store(pred, struct{}{})
 	return func() {
@@ -406,13 +458,63 @@ func (s *Server) removeNode(ctx context.Context, nodeId uint64, groupId uint32)
 	if _, ok := s.state.Groups[groupId].Members[nodeId]; !ok {
 		return errors.Errorf("No node with nodeId %d found in group %d", nodeId, groupId)
 	}
-	if len(s.state.Groups[groupId].Members) == 1 && len(s.state.Groups[groupId].Tablets) > 0 {
+	if member := s.state.Groups[groupId].Members[nodeId]; !member.Learner &&
+		numVoters(s.state.Groups[groupId]) == 1 && len(s.state.Groups[groupId].Tablets) > 0 {
 		return errors.Errorf("Move all tablets from group %d before removing the last node", groupId)
 	}
 
 	return s.Node.proposeAndWait(ctx, zp)
 }
 
+// numVoters returns the number of members of the group that aren't learners. Only they count
+// towards the replicas of the group.
+func numVoters(group *pb.Group) int {
+	var n int
+	for _, m := range group.GetMembers() {
+		if !m.Learner {
+			n++
+		}
+	}
+	return n
+}
+
+// assignLearnerGroup checks that the learner joins a group with a voting member, and picks the
+// group with the fewest learners if the learner has no preference.
+func (s *Server) assignLearnerGroup(m *pb.Member) error {
+	s.RLock()
+	defer s.RUnlock()
+
+	for _, group := range s.state.Groups {
+		if _, has := group.Members[m.Id]; has {
+			return nil
+		}
+	}
+	if m.GroupId > 0 {
+		if numVoters(s.state.Groups[m.GroupId]) == 0 {
+			return errors.Errorf("A learner can't join group %d, which has no voting member.",
+				m.GroupId)
+		}
+		return nil
+	}
+	var gid uint32
+	learners := math.MaxInt32
+	for id, group := range s.state.Groups {
+		voters := numVoters(group)
+		if voters == 0 {
+			continue
+		}
+		n := len(group.Members) - voters
+		if n < learners || (n == learners && id < gid) {
+			gid, learners = id, n
+		}
+	}
+	if gid == 0 {
+		return errors.Errorf("A learner can't join the cluster before a group has a voting member.")
+	}
+	m.GroupId = gid
+	return nil
+}
+
 // Connect is used by Alpha nodes to connect the very first time with group zero.
 func (s *Server) Connect(ctx context.Context,
 	m *pb.Member) (resp *pb.ConnectionState, err error) {
@@ -484,6 +586,12 @@ func (s *Server) Connect(ctx context.Context,
 	// Create a connection and check validity of the address by doing an Echo.
 	conn.GetPools().Connect(m.Addr)
 
+	if m.Learner {
+		if err := s.assignLearnerGroup(m); err != nil {
+			return &emptyConnectionState, err
+		}
+	}
+
 	createProposal := func() *pb.ZeroProposal {
 		s.Lock()
 		defer s.Unlock()
@@ -522,7 +630,7 @@ func (s *Server) Connect(ctx context.Context,
 			}
 
 			// We don't have this server in the list.
-			if len(group.Members) < s.NumReplicas {
+			if m.Learner || numVoters(group) < s.NumReplicas {
 				// We need more servers here, so let's add it.
 				proposal.Member = m
 				return proposal
@@ -537,7 +645,7 @@ func (s *Server) Connect(ctx context.Context,
 		}
 		// Let's assign this server to a new group.
 		for gid, group := range s.state.Groups {
-			if len(group.Members) < s.NumReplicas {
+			if numVoters(group) < s.NumReplicas {
 				m.GroupId = gid
 				proposal.Member = m
 				return proposal
@@ -614,6 +722,18 @@ func (s *Server) ShouldServe(
 		// This will also make it easier to restore the reserved predicates after
 		// a DropAll operation.
 		tablet.GroupId = 1
+	} else if !tablet.Force {
+		// Keep the tablet with its edge predicate or the properties of its edges, if any.
+		s.RLock()
+		if gid := s.edgeGroup(tablet.Predicate); gid != 0 {
+			tablet.GroupId = gid
+		} else if draining := s.drainingGroups(); draining[tablet.GroupId] {
+			// The groups being drained by a decommission aren't assigned new tablets.
+			if gid := s.drainDestination(draining); gid != 0 {
+				tablet.GroupId = gid
+			}
+		}
+		s.RUnlock()
 	}
 	proposal.Tablet = tablet
 	if err := s.Node.proposeAndWait(ctx, &proposal); err != nil && err != errTabletAlreadyServed {
@@ -628,6 +748,9 @@ func (s *Server) ShouldServe(
 
 // UpdateMembership updates the membership of the given group.
 func (s *Server) UpdateMembership(ctx context.Context, group *pb.Group) (*api.Payload, error) {
+	for id := range group.Members {
+		s.loads.update(id, group.Loads, time.Now())
+	}
 	proposals, err := s.createProposals(group)
 	if err != nil {
 		// Sleep here so the caller doesn't keep on retrying indefinitely, creating a busy
@@ -675,6 +798,21 @@ func (s *Server) UpdateMembership(ctx context.Context, group *pb.Group) (*api.Pa
 	return &api.Payload{Data: []byte("OK")}, nil
 }
 
+// UpdateStrictSchema enables or disables the strict schema mode of the cluster. The mode is
+// kept in the membership state, which is streamed to all the Alphas.
+func (s *Server) UpdateStrictSchema(ctx context.Context, req *pb.StrictSchema) (
+	*api.Payload, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	zp := &pb.ZeroProposal{StrictSchema: &pb.StrictSchema{Enabled: req.Enabled}}
+	if err := s.Node.proposeAndWait(ctx, zp); err != nil {
+		return nil, err
+	}
+	glog.Infof("Strict schema mode set to %v", req.Enabled)
+	return &api.Payload{Data: []byte("OK")}, nil
+}
+
 func (s *Server) deletePredicates(ctx context.Context, group *pb.Group) error {
 	if group == nil || group.Tablets == nil {
 		return nil
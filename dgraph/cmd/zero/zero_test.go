@@ -18,7 +18,10 @@ package zero
 
 import (
 	"context"
+	"errors"
+	"math"
 	"testing"
+	"time"
 
 	"github.com/dgraph-io/dgraph/protos/pb"
 	"github.com/stretchr/testify/require"
@@ -35,3 +38,303 @@ func TestRemoveNode(t *testing.T) {
 	err = server.removeNode(context.TODO(), 1, 2)
 	require.Error(t, err)
 }
+
+func TestEdgeGroup(t *testing.T) {
+	server := &Server{
+		state: &pb.MembershipState{
+			Groups: map[uint32]*pb.Group{
+				1: {Tablets: map[string]*pb.Tablet{"name": {}, "friend": {}}},
+				2: {Tablets: map[string]*pb.Tablet{"follows|since": {}}},
+			},
+		},
+	}
+	server.RLock()
+	defer server.RUnlock()
+	// The properties of the edges are served by the group of their predicate, and the other
+	// way around.
+	require.Equal(t, uint32(1), server.edgeGroup("friend|since"))
+	require.Equal(t, uint32(2), server.edgeGroup("follows"))
+	require.Equal(t, uint32(2), server.edgeGroup("follows|weight"))
+	require.Equal(t, uint32(0), server.edgeGroup("friend"))
+	require.Equal(t, uint32(0), server.edgeGroup("name"))
+	require.Equal(t, uint32(0), server.edgeGroup("age"))
+}
+
+func TestAssignLearnerGroup(t *testing.T) {
+	server := &Server{
+		state: &pb.MembershipState{
+			Groups: map[uint32]*pb.Group{
+				1: {Members: map[uint64]*pb.Member{
+					1: {Id: 1, GroupId: 1},
+					4: {Id: 4, GroupId: 1, Learner: true},
+				}},
+				2: {Members: map[uint64]*pb.Member{2: {Id: 2, GroupId: 2}}},
+				3: {Members: map[uint64]*pb.Member{3: {Id: 3, GroupId: 3, Learner: true}}},
+			},
+		},
+	}
+	require.Equal(t, 1, numVoters(server.state.Groups[1]))
+	require.Equal(t, 0, numVoters(server.state.Groups[3]))
+
+	// Without a preference, the learner joins the group with a voter and the fewest learners.
+	m := &pb.Member{Id: 5, Learner: true}
+	require.NoError(t, server.assignLearnerGroup(m))
+	require.Equal(t, uint32(2), m.GroupId)
+
+	m = &pb.Member{Id: 5, GroupId: 1, Learner: true}
+	require.NoError(t, server.assignLearnerGroup(m))
+	require.Equal(t, uint32(1), m.GroupId)
+
+	// A group without voters can't be joined by learners.
+	require.Error(t, server.assignLearnerGroup(&pb.Member{Id: 5, GroupId: 3, Learner: true}))
+	require.Error(t, server.assignLearnerGroup(&pb.Member{Id: 5, GroupId: 7, Learner: true}))
+}
+
+func TestTabletWeights(t *testing.T) {
+	defer func(o options) { opts = o }(opts)
+	opts.sizeWeight, opts.queryWeight, opts.mutationWeight = 1, 1, 0
+
+	server := &Server{
+		state: &pb.MembershipState{
+			Groups: map[uint32]*pb.Group{
+				1: {Tablets: map[string]*pb.Tablet{"name": {Space: 300}, "age": {Space: 100}}},
+				2: {Tablets: map[string]*pb.Tablet{"hot": {Space: 0}}},
+			},
+		},
+		loads: newTabletLoads(),
+	}
+	now := time.Now()
+	server.loads.update(1, []*pb.Tablet{{Predicate: "name", QueriesPerMin: 100}}, now)
+	server.loads.update(2, []*pb.Tablet{{Predicate: "hot", QueriesPerMin: 300,
+		MutationsPerMin: 50}}, now)
+
+	// The empty tablet weighs as much as the size of the largest one from its queries, and the
+	// mutations aren't weighed.
+	weights := server.tabletWeights()
+	require.InDelta(t, 0.75+0.25, weights["name"], 1e-9)
+	require.InDelta(t, 0.25, weights["age"], 1e-9)
+	require.InDelta(t, 0.75, weights["hot"], 1e-9)
+
+	// The rates of a tablet that stopped being used decay, and the reports of the members that
+	// went away expire.
+	server.loads.update(1, nil, now.Add(loadWindow))
+	queries, _ := server.loads.rates(now.Add(loadWindow))
+	require.InDelta(t, 100/math.E, queries["name"], 1e-9)
+	require.Zero(t, queries["hot"])
+}
+
+func TestMergeReplication(t *testing.T) {
+	_, err := mergeReplication(nil, &pb.ReplicationState{Promote: true})
+	require.Error(t, err)
+
+	cur := &pb.ReplicationState{Primary: "zero:5080", PrimaryTs: 10, ReadTs: 20}
+	next, err := mergeReplication(cur, &pb.ReplicationState{Promote: true})
+	require.NoError(t, err)
+	require.Equal(t, &pb.ReplicationState{Primary: "zero:5080", PrimaryTs: 10, ReadTs: 20,
+		Promote: true}, next)
+
+	// The progress recorded while the replica is being promoted keeps the request.
+	next, err = mergeReplication(next, &pb.ReplicationState{Primary: "zero:5080", PrimaryTs: 30,
+		ReadTs: 40})
+	require.NoError(t, err)
+	require.True(t, next.Promote)
+	require.Equal(t, uint64(30), next.PrimaryTs)
+
+	// The promotion clears the primary, and the request along with it.
+	next, err = mergeReplication(next, &pb.ReplicationState{PrimaryTs: 30, ReadTs: 40})
+	require.NoError(t, err)
+	require.Equal(t, &pb.ReplicationState{PrimaryTs: 30, ReadTs: 40}, next)
+}
+
+func TestDrainingGroups(t *testing.T) {
+	server := &Server{
+		state: &pb.MembershipState{
+			Groups: map[uint32]*pb.Group{
+				1: {
+					Members: map[uint64]*pb.Member{1: {Id: 1, GroupId: 1}},
+					Tablets: map[string]*pb.Tablet{"dgraph.type": {Space: 10}},
+				},
+				2: {
+					Members: map[uint64]*pb.Member{
+						2: {Id: 2, GroupId: 2, Decommissioning: true},
+						5: {Id: 5, GroupId: 2, Learner: true},
+					},
+					Tablets: map[string]*pb.Tablet{"name": {Space: 100}},
+				},
+				3: {
+					Members: map[uint64]*pb.Member{3: {Id: 3, GroupId: 3}},
+					Tablets: map[string]*pb.Tablet{"age": {Space: 50}},
+				},
+			},
+		},
+		loads: newTabletLoads(),
+	}
+	server.RLock()
+	defer server.RUnlock()
+
+	// The learners don't serve the group once its voting members are decommissioned.
+	draining := server.drainingGroups()
+	require.Equal(t, map[uint32]bool{2: true}, draining)
+	require.Equal(t, uint32(1), server.drainDestination(draining))
+
+	// The last voting member of the group serving the reserved predicates can't be
+	// decommissioned, unlike the learners.
+	group1 := server.state.Groups[1]
+	require.Error(t, server.checkDecommission(group1.Members[1]))
+	require.NoError(t, server.checkDecommission(server.state.Groups[2].Members[5]))
+
+	// The tablets of group 3 can't be moved once the other groups are drained.
+	group1.Members[4] = &pb.Member{Id: 4, GroupId: 1}
+	require.NoError(t, server.checkDecommission(group1.Members[1]))
+	group1.Members[1].Decommissioning = true
+	group1.Members[4].Decommissioning = true
+	require.Error(t, server.checkDecommission(server.state.Groups[3].Members[3]))
+}
+
+func TestCheckDeadNodes(t *testing.T) {
+	defer func(o options) { opts = o }(opts)
+	opts.deadNodeTimeout = time.Minute
+
+	server := &Server{
+		NumReplicas: 3,
+		state: &pb.MembershipState{
+			Groups: map[uint32]*pb.Group{1: {Members: map[uint64]*pb.Member{
+				1: {Id: 1, GroupId: 1, Addr: "alpha1", Leader: true},
+				2: {Id: 2, GroupId: 1, Addr: "alpha2"},
+				3: {Id: 3, GroupId: 1, Addr: "alpha3"},
+			}}},
+		},
+	}
+	down := map[string]bool{"alpha2": true}
+	reachable := func(addr string) bool { return !down[addr] }
+
+	// The node is found dead once it has been unreachable for the whole timeout.
+	dead := make(map[uint64]*deadNode)
+	now := time.Now()
+	require.Empty(t, server.checkDeadNodes(dead, reachable, now))
+	require.Empty(t, server.checkDeadNodes(dead, reachable, now.Add(30*time.Second)))
+	events := server.checkDeadNodes(dead, reachable, now.Add(time.Minute))
+	require.Len(t, events, 1)
+	require.Equal(t, "dead", events[0].Event)
+	require.Equal(t, uint64(2), events[0].Id)
+	require.Equal(t, now, events[0].UnreachableSince)
+	require.Equal(t, 2, events[0].Replicas)
+	require.False(t, events[0].Removed)
+	require.Empty(t, server.checkDeadNodes(dead, reachable, now.Add(2*time.Minute)))
+
+	delete(down, "alpha2")
+	events = server.checkDeadNodes(dead, reachable, now.Add(3*time.Minute))
+	require.Len(t, events, 1)
+	require.Equal(t, "recovered", events[0].Event)
+	require.Empty(t, dead)
+
+	// The dead node isn't removed without a reachable leader in its group.
+	opts.deadNodeRemove = true
+	down["alpha1"] = true
+	server.checkDeadNodes(dead, reachable, now)
+	events = server.checkDeadNodes(dead, reachable, now.Add(time.Minute))
+	require.Len(t, events, 1)
+	require.False(t, events[0].Removed)
+	require.NotEmpty(t, events[0].Error)
+}
+
+func TestApplyFreeze(t *testing.T) {
+	state := &pb.MembershipState{}
+	applyFreeze(state, &pb.FreezePoint{Tag: "nightly", ReadTs: 10})
+	applyFreeze(state, &pb.FreezePoint{Tag: "weekly", ReadTs: 20})
+	// The freeze point is updated once the groups have applied its timestamp.
+	applyFreeze(state, &pb.FreezePoint{Tag: "nightly", ReadTs: 10, Ready: true})
+	require.Len(t, state.FreezePoints, 2)
+	require.Equal(t, "nightly", state.FreezePoints[0].Tag)
+	require.True(t, state.FreezePoints[0].Ready)
+
+	releaseFreeze(state, "nightly")
+	require.Len(t, state.FreezePoints, 1)
+	require.Equal(t, "weekly", state.FreezePoints[0].Tag)
+	releaseFreeze(state, "unknown")
+	require.Len(t, state.FreezePoints, 1)
+
+	server := &Server{state: state}
+	require.Nil(t, server.freezePoint("nightly"))
+	require.Equal(t, uint64(20), server.freezePoint("weekly").ReadTs)
+	_, err := server.freeze(context.Background(), "weekly")
+	require.Error(t, err)
+	require.Error(t, server.release(context.Background(), "nightly"))
+}
+
+func TestTabletMoves(t *testing.T) {
+	moves := newTabletMoves()
+	require.Zero(t, moves.estimate(100))
+	require.Error(t, moves.cancelMove("name"))
+
+	ctx := moves.start(context.Background(), "name", 1, 2, 100)
+	moves.setStatus("streaming")
+	time.Sleep(10 * time.Millisecond)
+	moves.setStatus("reassigning")
+	// The tablet can't be cancelled once it's reassigned to the destination group.
+	require.Error(t, moves.cancelMove("name"))
+	require.NoError(t, ctx.Err())
+	moves.finish(nil)
+	require.Error(t, ctx.Err())
+	require.NotZero(t, moves.estimate(100))
+
+	ctx = moves.start(context.Background(), "age", 1, 2, 10)
+	require.Error(t, moves.cancelMove("name"))
+	require.NoError(t, moves.cancelMove("age"))
+	require.Error(t, ctx.Err())
+	moves.finish(ctx.Err())
+
+	moves.start(context.Background(), "friend", 1, 2, 10)
+	moves.finish(errors.New("stream failed"))
+
+	cur, recent := moves.status()
+	require.Nil(t, cur)
+	require.Len(t, recent, 3)
+	require.Equal(t, "done", recent[0].Status)
+	require.Equal(t, "cancelled", recent[1].Status)
+	require.Equal(t, "failed", recent[2].Status)
+	require.Equal(t, "stream failed", recent[2].Error)
+
+	for i := 0; i < maxRecentMoves; i++ {
+		moves.start(context.Background(), "name", 1, 2, 100)
+		moves.finish(nil)
+	}
+	_, recent = moves.status()
+	require.Len(t, recent, maxRecentMoves)
+	require.Equal(t, "name", recent[0].Tablet)
+}
+
+func TestEstimateMove(t *testing.T) {
+	server := &Server{
+		state: &pb.MembershipState{
+			Groups: map[uint32]*pb.Group{
+				1: {
+					Members: map[uint64]*pb.Member{1: {Id: 1, GroupId: 1}},
+					Tablets: map[string]*pb.Tablet{"dgraph.type": {Space: 10}},
+				},
+				2: {
+					Members: map[uint64]*pb.Member{2: {Id: 2, GroupId: 2, Decommissioning: true}},
+					Tablets: map[string]*pb.Tablet{"name": {Space: 100}},
+				},
+				3: {
+					Members: map[uint64]*pb.Member{3: {Id: 3, GroupId: 3}},
+					Tablets: map[string]*pb.Tablet{"age": {Predicate: "age", Space: 50}},
+				},
+			},
+		},
+		moves: newTabletMoves(),
+	}
+
+	// The size of the groups is shared by the groups that aren't being drained.
+	tab := server.state.Groups[3].Tablets["age"]
+	est := server.estimateMove(tab, 3, 1)
+	require.Empty(t, est.Error)
+	require.Equal(t, uint64(50), est.Bytes)
+	require.Equal(t, uint64(10), est.DstBytes)
+	require.Equal(t, uint64(60), est.DstBytesAfter)
+	require.Equal(t, int64(20), est.Headroom)
+
+	est = server.estimateMove(tab, 3, 2)
+	require.NotEmpty(t, est.Error)
+	require.Equal(t, int64(-70), est.Headroom)
+}
@@ -57,6 +57,15 @@ func authorizeAlter(ctx context.Context, op *api.Operation) error {
 	return nil
 }
 
+func authorizeRename(ctx context.Context, from, to string) error {
+	return nil
+}
+
+// alterUserId returns an empty user id since ACL is only supported in the enterprise version.
+func alterUserId(ctx context.Context) string {
+	return ""
+}
+
 func authorizeMutation(ctx context.Context, gmu *gql.Mutation) error {
 	return nil
 }
@@ -615,6 +615,35 @@ func authorizeAlter(ctx context.Context, op *api.Operation) error {
 		}
 	}
 
+	return authorizeAlterPreds(ctx, preds, isDropAll(op) || op.DropOp == api.Operation_DATA)
+}
+
+// authorizeRename checks that the user is allowed to alter both the predicate being renamed
+// and its new name.
+func authorizeRename(ctx context.Context, from, to string) error {
+	if len(worker.Config.HmacSecret) == 0 {
+		// the user has not turned on the acl feature
+		return nil
+	}
+	return authorizeAlterPreds(ctx, []string{from, to}, false)
+}
+
+// alterUserId returns the id of the user altering the schema, or an empty string if ACL isn't
+// turned on or the context doesn't carry a valid access JWT.
+func alterUserId(ctx context.Context) string {
+	if len(worker.Config.HmacSecret) == 0 {
+		return ""
+	}
+	userData, err := extractUserAndGroups(ctx)
+	if err != nil {
+		return ""
+	}
+	return userData[0]
+}
+
+// authorizeAlterPreds checks that the user is allowed to alter the given predicates, or to drop
+// all the data if dropAll is true.
+func authorizeAlterPreds(ctx context.Context, preds []string, dropAll bool) error {
 	var userId string
 	var groupIds []string
 
@@ -636,7 +665,7 @@ func authorizeAlter(ctx context.Context, op *api.Operation) error {
 		}
 
 		// if we get here, we know the user is not a guardian.
-		if isDropAll(op) || op.DropOp == api.Operation_DATA {
+		if dropAll {
 			return errors.Errorf(
 				"only guardians are allowed to drop all data, but the current user is %s", userId)
 		}
@@ -788,6 +817,12 @@ func parsePredsFromQuery(gqls []*gql.GraphQuery) predsAndvars {
 	for _, gq := range gqls {
 		if gq.Func != nil {
 			predsMap[gq.Func.Attr] = struct{}{}
+			for _, f := range gq.Func.Union {
+				predsMap[f.Attr] = struct{}{}
+			}
+			for _, pred := range hasArgPreds(gq.Func) {
+				predsMap[pred] = struct{}{}
+			}
 		}
 		if len(gq.Var) > 0 {
 			varsMap[gq.Var] = gq.Attr
@@ -834,12 +869,26 @@ func parsePredsFromFilter(f *gql.FilterTree) []string {
 	if f.Func != nil && len(f.Func.Attr) > 0 {
 		preds = append(preds, f.Func.Attr)
 	}
+	preds = append(preds, hasArgPreds(f.Func)...)
 	for _, ch := range f.Child {
 		preds = append(preds, parsePredsFromFilter(ch)...)
 	}
 	return preds
 }
 
+// hasArgPreds returns the predicates given to has(p1, p2, ...) or hasall(p1, p2, ...) after the
+// first one, which is stored as the attribute of the function.
+func hasArgPreds(f *gql.Function) []string {
+	if f == nil || (f.Name != "has" && f.Name != "hasall") {
+		return nil
+	}
+	preds := make([]string, 0, len(f.Args))
+	for _, arg := range f.Args {
+		preds = append(preds, arg.Value)
+	}
+	return preds
+}
+
 type accessEntry struct {
 	userId    string
 	groups    []string
@@ -1199,6 +1248,18 @@ L:
 				continue
 			}
 		}
+		if gq.Func != nil {
+			for _, f := range gq.Func.Union {
+				if _, ok := blockedPreds[f.Attr]; ok {
+					continue L
+				}
+			}
+			for _, pred := range hasArgPreds(gq.Func) {
+				if _, ok := blockedPreds[pred]; ok {
+					continue L
+				}
+			}
+		}
 		if len(gq.Attr) > 0 {
 			if _, ok := blockedPreds[gq.Attr]; ok {
 				continue
@@ -1265,6 +1326,11 @@ func removeFilters(f *gql.FilterTree, blockedPreds map[string]struct{}) *gql.Fil
 			return nil
 		}
 	}
+	for _, pred := range hasArgPreds(f.Func) {
+		if _, ok := blockedPreds[pred]; ok {
+			return nil
+		}
+	}
 
 	filteredChildren := f.Child[:0]
 	for _, ch := range f.Child {
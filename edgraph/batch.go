@@ -0,0 +1,174 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"context"
+
+	"github.com/dgraph-io/badger/v2/y"
+	"github.com/dgraph-io/dgo/v200"
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/query"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/pkg/errors"
+	otrace "go.opencensus.io/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// DefaultBatchConcurrency is the number of requests MutateBatch applies at a time when the
+	// batch doesn't set a concurrency.
+	DefaultBatchConcurrency = 4
+	// MaxBatchConcurrency is the largest concurrency a batch can ask for.
+	MaxBatchConcurrency = 64
+)
+
+// The statuses of the requests of a batch.
+const (
+	// BatchCommitted is the status of a request whose transaction has been committed.
+	BatchCommitted = "Committed"
+	// BatchConflict is the status of a request whose transaction has been aborted because of a
+	// conflict with another transaction. The request can be retried.
+	BatchConflict = "Conflict"
+	// BatchInvalid is the status of a request that failed validation and hasn't been applied.
+	BatchInvalid = "Invalid"
+	// BatchFailed is the status of a request that couldn't be applied for any other reason.
+	BatchFailed = "Failed"
+)
+
+// BatchRequest is a request to apply several independent mutations.
+type BatchRequest struct {
+	// Requests are applied and committed each in its own transaction. A request can have a
+	// query, in which case it's applied as an upsert.
+	Requests []*api.Request
+	// Concurrency is the number of requests applied at a time.
+	Concurrency int
+}
+
+// BatchItemResponse is the outcome of one of the requests of a batch.
+type BatchItemResponse struct {
+	Status   string            `json:"status"`
+	Error    string            `json:"error,omitempty"`
+	CommitTs uint64            `json:"commit_ts,omitempty"`
+	Uids     map[string]string `json:"uids,omitempty"`
+}
+
+// MutateBatch applies the requests of the batch, req.Concurrency of them at a time, each one in
+// its own transaction. The failure of a request doesn't affect the others, so an error is only
+// returned if the batch itself is invalid. The outcome of each request is reported in the
+// response at the same position as the request.
+func (s *Server) MutateBatch(ctx context.Context, req *BatchRequest) (
+	[]*BatchItemResponse, error) {
+	ctx, span := otrace.StartSpan(ctx, "Server.MutateBatch")
+	defer span.End()
+
+	if len(req.Requests) == 0 {
+		return nil, errors.Errorf("batch doesn't contain any request")
+	}
+	if req.Concurrency < 0 || req.Concurrency > MaxBatchConcurrency {
+		return nil, errors.Errorf("batch concurrency should be between 1 and %d, got: %d",
+			MaxBatchConcurrency, req.Concurrency)
+	}
+	concurrency := req.Concurrency
+	if concurrency == 0 {
+		concurrency = DefaultBatchConcurrency
+	}
+	if err := x.HealthCheck(); err != nil {
+		return nil, err
+	}
+	span.Annotatef(nil, "Applying %d requests, %d at a time", len(req.Requests), concurrency)
+
+	resps := make([]*BatchItemResponse, len(req.Requests))
+	throttle := y.NewThrottle(concurrency)
+	for i, r := range req.Requests {
+		if err := throttle.Do(); err != nil {
+			return nil, err
+		}
+		go func(i int, r *api.Request) {
+			resps[i] = s.mutateBatchItem(ctx, r)
+			throttle.Done(nil)
+		}(i, r)
+	}
+	if err := throttle.Finish(); err != nil {
+		return nil, err
+	}
+	return resps, nil
+}
+
+// mutateBatchItem applies one of the requests of a batch and returns its outcome.
+func (s *Server) mutateBatchItem(ctx context.Context, req *api.Request) *BatchItemResponse {
+	invalid := func(err error) *BatchItemResponse {
+		return &BatchItemResponse{Status: BatchInvalid, Error: err.Error()}
+	}
+	switch {
+	case len(req.Mutations) == 0:
+		return invalid(errors.Errorf("request doesn't contain any mutation"))
+	case req.StartTs != 0:
+		return invalid(errors.Errorf("requests of a batch can't be part of a transaction"))
+	}
+	if err := ctx.Err(); err != nil {
+		return &BatchItemResponse{Status: BatchFailed, Error: err.Error()}
+	}
+
+	// Parsing the request up front tells the requests that can never be applied apart from
+	// the ones that failed while being applied.
+	qc := &queryContext{req: req, latency: &query.Latency{}, span: otrace.FromContext(ctx)}
+	if err := parseRequest(qc); err != nil {
+		return invalid(err)
+	}
+
+	req.CommitNow = true
+	resp, err := s.Query(ctx, req)
+	switch {
+	case err == nil:
+		return &BatchItemResponse{
+			Status:   BatchCommitted,
+			CommitTs: resp.GetTxn().GetCommitTs(),
+			Uids:     resp.GetUids(),
+		}
+	case err == dgo.ErrAborted || status.Code(err) == codes.Aborted:
+		return &BatchItemResponse{Status: BatchConflict, Error: err.Error()}
+	case x.IsInvalidArgument(err) || isStrictSchemaError(err) || isRequiredPredicateError(err) ||
+		isValueCheckError(err) || isReferenceError(err):
+		// The mutation doesn't agree with the schema, e.g. its values can't be converted to
+		// the types of their predicates.
+		return invalid(err)
+	default:
+		return &BatchItemResponse{Status: BatchFailed, Error: err.Error()}
+	}
+}
+
+func isStrictSchemaError(err error) bool {
+	_, ok := errors.Cause(err).(*StrictSchemaError)
+	return ok
+}
+
+func isRequiredPredicateError(err error) bool {
+	_, ok := errors.Cause(err).(*RequiredPredicateError)
+	return ok
+}
+
+func isValueCheckError(err error) bool {
+	_, ok := errors.Cause(err).(*ValueCheckError)
+	return ok
+}
+
+func isReferenceError(err error) bool {
+	_, ok := errors.Cause(err).(*ReferenceError)
+	return ok
+}
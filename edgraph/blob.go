@@ -0,0 +1,198 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/gql"
+	"github.com/dgraph-io/dgraph/posting"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/worker"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// blobRef refers to a value that WriteBlob has already stored in chunks, for the mutation that
+// sets it as the value of the predicate attr of the node uid.
+type blobRef struct {
+	uid  uint64
+	attr string
+	id   []byte
+	size uint64
+}
+
+type blobRefKey struct{}
+
+func blobRefFromContext(ctx context.Context) *blobRef {
+	ref, _ := ctx.Value(blobRefKey{}).(*blobRef)
+	return ref
+}
+
+// apply makes the edge setting the value refer to the chunks instead.
+func (ref *blobRef) apply(edges []*pb.DirectedEdge) {
+	for _, edge := range edges {
+		if edge.Entity == ref.uid && edge.Attr == ref.attr && edge.Lang == "" &&
+			edge.Op == pb.DirectedEdge_SET {
+			edge.Value = ref.id
+			edge.BlobSize = ref.size
+		}
+	}
+}
+
+func blobRequest(uid uint64, attr string, val []byte) *api.Request {
+	return &api.Request{
+		Mutations: []*api.Mutation{{
+			Set: []*api.NQuad{{
+				Subject:     fmt.Sprintf("%#x", uid),
+				Predicate:   attr,
+				ObjectValue: &api.Value{Val: &api.Value_StrVal{StrVal: string(val)}},
+			}},
+		}},
+		CommitNow: true,
+	}
+}
+
+// WriteBlob sets the value of the predicate attr of the node uid to the content read from r,
+// and commits the mutation immediately. Values larger than a single chunk are streamed to the
+// group serving the predicate one chunk at a time, so they're never held in memory at once.
+func (s *Server) WriteBlob(ctx context.Context, uid uint64, attr string,
+	r io.Reader) (*api.Response, error) {
+	if uid == 0 || len(attr) == 0 {
+		return nil, errors.Errorf("both uid and predicate are required to write a blob")
+	}
+	first := make([]byte, posting.BlobChunkSize()+1)
+	n, err := io.ReadFull(r, first)
+	switch err {
+	case nil:
+		return s.writeBlobChunks(ctx, uid, attr, io.MultiReader(bytes.NewReader(first), r))
+	case io.EOF, io.ErrUnexpectedEOF:
+		// The value fits in a single chunk, so it's set by a regular mutation.
+		return s.Query(ctx, blobRequest(uid, attr, first[:n]))
+	default:
+		return nil, err
+	}
+}
+
+func (s *Server) writeBlobChunks(ctx context.Context, uid uint64, attr string,
+	r io.Reader) (resp *api.Response, rerr error) {
+	if err := x.HealthCheck(); err != nil {
+		return nil, err
+	}
+	if !isMutationAllowed(ctx) {
+		return nil, errors.Errorf("no mutations allowed")
+	}
+	if err := validatePredName(attr); err != nil {
+		return nil, err
+	}
+	// Check the permissions before writing any chunk. They're checked again by the mutation
+	// setting the value.
+	req := blobRequest(uid, attr, nil)
+	if err := authorizeMutation(ctx, &gql.Mutation{Set: req.Mutations[0].Set}); err != nil {
+		return nil, err
+	}
+
+	req.StartTs = worker.State.GetTimestamp(false)
+	defer func() {
+		if rerr == nil {
+			return
+		}
+		// The chunks written so far are deleted when the value of the node is next rolled up.
+		tctx := &api.TxnContext{StartTs: req.StartTs, Aborted: true}
+		if _, err := worker.CommitOverNetwork(context.Background(), tctx); err != nil {
+			glog.Warningf("Error while aborting blob write at %d: %v", req.StartTs, err)
+		}
+	}()
+
+	var size uint64
+	buf := make([]byte, posting.BlobChunkSize())
+	for idx := uint32(0); ; idx++ {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			m := &pb.Mutations{
+				StartTs: req.StartTs,
+				BlobChunks: []*pb.BlobChunk{{
+					Attr: attr,
+					Uid:  uid,
+					Idx:  idx,
+					Data: buf[:n],
+				}},
+			}
+			if _, err := worker.MutateOverNetwork(ctx, m); err != nil {
+				return nil, err
+			}
+			size += uint64(n)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ctx = context.WithValue(ctx, blobRefKey{}, &blobRef{
+		uid:  uid,
+		attr: attr,
+		id:   posting.BlobID(uid, math.MaxUint64, req.StartTs),
+		size: size,
+	})
+	return s.Query(ctx, req)
+}
+
+// ReadBlob writes the value of the predicate attr of the node uid to w, without any of the
+// escaping done when encoding the value in a query response. The value is streamed from the
+// group serving the predicate one chunk at a time.
+func (s *Server) ReadBlob(ctx context.Context, uid uint64, attr string, w io.Writer) error {
+	if err := x.HealthCheck(); err != nil {
+		return err
+	}
+	if uid == 0 || len(attr) == 0 {
+		return errors.Errorf("both uid and predicate are required to read a blob")
+	}
+	if err := validatePredName(attr); err != nil {
+		return err
+	}
+
+	// Check the permissions as if the value was requested by a query.
+	parsed, err := gql.Parse(gql.Request{Str: fmt.Sprintf("{ q(func: uid(%#x)) { <%s> } }",
+		uid, attr)})
+	if err != nil {
+		return err
+	}
+	if err := authorizeQuery(ctx, &parsed, false); err != nil {
+		return err
+	}
+	if len(parsed.Query) == 0 || len(parsed.Query[0].Children) == 0 {
+		return status.Errorf(codes.PermissionDenied, "unauthorized to query predicate %s", attr)
+	}
+
+	return worker.ReadBlobOverNetwork(ctx, &pb.BlobRequest{
+		Attr:   attr,
+		Uid:    uid,
+		ReadTs: worker.State.GetTimestamp(true),
+	}, w)
+}
@@ -0,0 +1,49 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dgraph-io/dgraph/posting"
+	"github.com/dgraph-io/dgraph/protos/pb"
+)
+
+func TestBlobRefApply(t *testing.T) {
+	ref := &blobRef{uid: 1, attr: "file", id: posting.BlobID(1, math.MaxUint64, 5), size: 100}
+	ctx := context.WithValue(context.Background(), blobRefKey{}, ref)
+	require.Equal(t, ref, blobRefFromContext(ctx))
+	require.Nil(t, blobRefFromContext(context.Background()))
+
+	edges := []*pb.DirectedEdge{
+		{Entity: 1, Attr: "file"},
+		{Entity: 1, Attr: "file", Lang: "en"},
+		{Entity: 2, Attr: "file"},
+		{Entity: 1, Attr: "name", Value: []byte("name")},
+	}
+	ref.apply(edges)
+	require.Equal(t, ref.id, edges[0].Value)
+	require.Equal(t, uint64(100), edges[0].BlobSize)
+	for _, edge := range edges[1:] {
+		require.Zero(t, edge.BlobSize)
+	}
+	require.Equal(t, []byte("name"), edges[3].Value)
+}
@@ -0,0 +1,231 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/gql"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/worker"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/pkg/errors"
+	otrace "go.opencensus.io/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CompareAndSetRequest is a request to set a scalar predicate of a node, only if its current
+// value is the expected one.
+type CompareAndSetRequest struct {
+	Uid       uint64
+	Predicate string
+	// Expected is the value the predicate must have for it to be set, or nil if the predicate
+	// must not have any value.
+	Expected *string
+	// Value is the new value of the predicate.
+	Value string
+}
+
+// ValueMismatchError is returned by CompareAndSet when the current value of the predicate isn't
+// the expected one.
+type ValueMismatchError struct {
+	Uid       uint64
+	Predicate string
+	// Current is the current value of the predicate, or nil if it doesn't have any.
+	Current *string
+}
+
+func (e *ValueMismatchError) Error() string {
+	return fmt.Sprintf("Current value of predicate %s of node %#x doesn't match the expected value",
+		e.Predicate, e.Uid)
+}
+
+// GRPCStatus lets gRPC report the error to clients with the FailedPrecondition code.
+func (e *ValueMismatchError) GRPCStatus() *status.Status {
+	return status.New(codes.FailedPrecondition, e.Error())
+}
+
+// Extensions returns the fields to be reported in the extensions of an HTTP error.
+func (e *ValueMismatchError) Extensions() map[string]interface{} {
+	ext := map[string]interface{}{
+		"code":    x.ErrorValueMismatch,
+		"current": nil,
+	}
+	if e.Current != nil {
+		ext["current"] = *e.Current
+	}
+	return ext
+}
+
+// CompareAndSet atomically sets the predicate of the node in the request to the new value, if
+// its current value is the expected one. The value is read and written in the same
+// transaction, so a concurrent change of the predicate makes the transaction abort instead of
+// being overwritten. A ValueMismatchError is returned if the current value isn't the expected
+// one.
+func (s *Server) CompareAndSet(ctx context.Context, req *CompareAndSetRequest) (
+	*api.Response, error) {
+	ctx, span := otrace.StartSpan(ctx, "Server.CompareAndSet")
+	defer span.End()
+
+	if req.Uid == 0 {
+		return nil, errors.Errorf("compare and set requires the uid of a node")
+	}
+	if strings.TrimSpace(req.Predicate) == "" {
+		return nil, errors.Errorf("compare and set requires a predicate")
+	}
+	if err := validatePredName(req.Predicate); err != nil {
+		return nil, err
+	}
+	if x.WorkerConfig.LudicrousMode {
+		return nil, errors.Errorf("compare and set is not supported in ludicrous mode")
+	}
+
+	if err := x.HealthCheck(); err != nil {
+		return nil, err
+	}
+	tid, err := casType(ctx, req.Predicate)
+	if err != nil {
+		return nil, err
+	}
+	var expected *types.Val
+	if req.Expected != nil {
+		src := types.Val{Tid: types.StringID, Value: []byte(*req.Expected)}
+		val, err := types.Convert(src, tid)
+		if err != nil {
+			return nil, errors.Wrapf(err, "while converting the expected value")
+		}
+		expected = &val
+	}
+
+	if auth := ctx.Value(Authorize); auth == nil || auth.(bool) {
+		parsed, err := gql.Parse(gql.Request{
+			Str: fmt.Sprintf("{ q(func: uid(%#x)) { <%s> } }", req.Uid, req.Predicate),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := authorizeQuery(ctx, &parsed, false); err != nil {
+			return nil, err
+		}
+	}
+
+	startTs := worker.State.GetTimestamp(false)
+	res, err := worker.ProcessTaskOverNetwork(ctx, &pb.Query{
+		Attr:    req.Predicate,
+		UidList: &pb.List{Uids: []uint64{req.Uid}},
+		ReadTs:  startTs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	current, err := casCurrent(res, tid)
+	if err != nil {
+		return nil, err
+	}
+	if !casMatches(expected, current) {
+		mismatch := &ValueMismatchError{Uid: req.Uid, Predicate: req.Predicate}
+		if current != nil {
+			str := types.ValueForType(types.StringID)
+			if err := types.Marshal(*current, &str); err != nil {
+				return nil, err
+			}
+			cur := str.Value.(string)
+			mismatch.Current = &cur
+		}
+		return nil, mismatch
+	}
+
+	nq := &api.NQuad{
+		Subject:     fmt.Sprintf("%#x", req.Uid),
+		Predicate:   req.Predicate,
+		ObjectValue: &api.Value{Val: &api.Value_DefaultVal{DefaultVal: req.Value}},
+	}
+	return s.Query(ctx, &api.Request{
+		StartTs:   startTs,
+		Mutations: []*api.Mutation{{Set: []*api.NQuad{nq}}},
+		CommitNow: true,
+	})
+}
+
+// casType returns the type of the predicate, checking that compare and set can be used on it.
+func casType(ctx context.Context, pred string) (types.TypeID, error) {
+	nodes, err := worker.GetSchemaOverNetwork(ctx, &pb.SchemaRequest{
+		Predicates: []string{pred},
+		Fields:     []string{"type", "list", "lang", "no_conflict"},
+	})
+	if err != nil {
+		return types.DefaultID, err
+	}
+	if len(nodes) == 0 {
+		return types.DefaultID, nil
+	}
+	node := nodes[0]
+	tid, ok := types.TypeForName(node.Type)
+	switch {
+	case !ok:
+		return tid, errors.Errorf("Predicate %s has an unknown type %s", pred, node.Type)
+	case node.List:
+		return tid, errors.Errorf("compare and set can't be used on list predicate %s", pred)
+	case node.Lang:
+		return tid, errors.Errorf("compare and set can't be used on predicate %s with @lang", pred)
+	case node.NoConflict:
+		return tid, errors.Errorf("compare and set can't be used on predicate %s with @noconflict",
+			pred)
+	}
+	switch tid {
+	case types.DefaultID, types.StringID, types.IntID, types.FloatID, types.BoolID,
+		types.DateTimeID:
+		return tid, nil
+	default:
+		return tid, errors.Errorf("compare and set can't be used on predicate %s of type %s",
+			pred, node.Type)
+	}
+}
+
+// casCurrent returns the current value of the predicate in the given type, or nil if it
+// doesn't have any.
+func casCurrent(res *pb.Result, tid types.TypeID) (*types.Val, error) {
+	if len(res.ValueMatrix) == 0 || len(res.ValueMatrix[0].Values) == 0 {
+		return nil, nil
+	}
+	tv := res.ValueMatrix[0].Values[0]
+	if bytes.Equal(tv.Val, x.Nilbyte) {
+		return nil, nil
+	}
+	// The values are returned in the type of the predicate.
+	val, err := types.Convert(types.Val{Tid: types.BinaryID, Value: tv.Val}, tid)
+	if err != nil {
+		return nil, err
+	}
+	return &val, nil
+}
+
+// casMatches returns whether the current value of a predicate is the expected one. A nil value
+// stands for the predicate not having any value.
+func casMatches(expected, current *types.Val) bool {
+	if expected == nil || current == nil {
+		return expected == current
+	}
+	eq, err := types.Equal(*expected, *current)
+	return err == nil && eq
+}
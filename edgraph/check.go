@@ -0,0 +1,196 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/worker"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CheckViolation is a triple set by a mutation whose value doesn't satisfy a constraint of the
+// @check directive of its predicate.
+type CheckViolation struct {
+	// Subject is the node of the triple, as the blank node the mutation names it with if it's
+	// created by the mutation, or as its uid otherwise.
+	Subject    string `json:"subject"`
+	Predicate  string `json:"predicate"`
+	Object     string `json:"object"`
+	Lang       string `json:"lang,omitempty"`
+	Constraint string `json:"constraint"`
+}
+
+// ValueCheckError is returned when a mutation sets values that don't satisfy the constraints of
+// the @check directives of their predicates. It holds all the offending triples.
+type ValueCheckError struct {
+	Violations []CheckViolation
+}
+
+func (e *ValueCheckError) Error() string {
+	v := e.Violations[0]
+	msg := fmt.Sprintf("Value %q of predicate %s of node %s doesn't satisfy the constraint"+
+		" %s of its schema", v.Object, v.Predicate, v.Subject, v.Constraint)
+	if n := len(e.Violations) - 1; n > 0 {
+		msg += fmt.Sprintf(", along with %d more values", n)
+	}
+	return msg
+}
+
+// GRPCStatus lets gRPC report the error to clients with the InvalidArgument code.
+func (e *ValueCheckError) GRPCStatus() *status.Status {
+	return status.New(codes.InvalidArgument, e.Error())
+}
+
+// Extensions returns the fields to be reported in the extensions of an HTTP error.
+func (e *ValueCheckError) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"code":       x.ErrorValueCheck,
+		"violations": e.Violations,
+	}
+}
+
+// checkedPredicates returns the schema of the predicates the edges set values of that have
+// constraints given with @check. The schema of the predicates served by this Alpha is known
+// locally, and the one of the other predicates is kept by the groups serving them.
+func checkedPredicates(ctx context.Context,
+	edges []*pb.DirectedEdge) (map[string]*pb.SchemaUpdate, error) {
+
+	checked := make(map[string]*pb.SchemaUpdate)
+	remote := make(map[string]struct{})
+	for _, edge := range edges {
+		if !isCheckedEdge(edge) {
+			continue
+		}
+		if _, ok := checked[edge.Attr]; ok {
+			continue
+		}
+		su, ok := schema.State().Get(ctx, edge.Attr)
+		switch {
+		case !ok:
+			remote[edge.Attr] = struct{}{}
+		case schema.HasValueCheck(&su):
+			checked[edge.Attr] = &su
+		}
+	}
+	if len(remote) == 0 {
+		return checked, nil
+	}
+
+	preds := make([]string, 0, len(remote))
+	for pred := range remote {
+		preds = append(preds, pred)
+	}
+	sort.Strings(preds)
+	nodes, err := worker.GetSchemaOverNetwork(ctx, &pb.SchemaRequest{
+		Predicates: preds,
+		Fields:     []string{"type", "check"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, node := range nodes {
+		typ, ok := types.TypeForName(node.Type)
+		if !ok {
+			continue
+		}
+		su := &pb.SchemaUpdate{
+			Predicate:      node.Predicate,
+			ValueType:      pb.Posting_ValType(typ),
+			CheckMin:       node.CheckMin,
+			CheckMax:       node.CheckMax,
+			CheckMaxLength: node.CheckMaxLength,
+			CheckPattern:   node.CheckPattern,
+		}
+		if schema.HasValueCheck(su) {
+			checked[node.Predicate] = su
+		}
+	}
+	return checked, nil
+}
+
+// isCheckedEdge returns whether the edge sets a value that the @check directive of its
+// predicate may apply to.
+func isCheckedEdge(edge *pb.DirectedEdge) bool {
+	return edge.Op == pb.DirectedEdge_SET && edge.ValueId == 0 &&
+		edge.ValueType != pb.Posting_UID && !bytes.Equal(edge.Value, []byte(x.Star)) &&
+		!x.IsReservedPredicate(edge.Attr)
+}
+
+// checkValues returns a ValueCheckError if the edges set values that don't satisfy the
+// constraints of the @check directives of their predicates. Values that can't be converted to
+// the types of their predicates are left to the checks of the mutation by the groups.
+func checkValues(ctx context.Context, edges []*pb.DirectedEdge,
+	newUids map[string]uint64) error {
+
+	checked, err := checkedPredicates(ctx, edges)
+	if err != nil || len(checked) == 0 {
+		return err
+	}
+	blankNodes := make(map[uint64]string, len(newUids))
+	for name, uid := range newUids {
+		if strings.HasPrefix(name, "_:") {
+			blankNodes[uid] = name
+		}
+	}
+
+	var cerr ValueCheckError
+	for _, edge := range edges {
+		su, ok := checked[edge.Attr]
+		if !ok || !isCheckedEdge(edge) {
+			continue
+		}
+		if edge.BlobSize > 0 {
+			return x.InvalidArgument(errors.Errorf("Large values can't be written to predicate"+
+				" %q with @check", edge.Attr))
+		}
+		src := types.Val{Tid: types.TypeID(edge.ValueType), Value: edge.Value}
+		constraint, err := schema.ValueCheckViolation(su, src)
+		if err != nil || constraint == "" {
+			continue
+		}
+		obj, err := types.Convert(src, types.StringID)
+		if err != nil {
+			continue
+		}
+		subject, ok := blankNodes[edge.Entity]
+		if !ok {
+			subject = fmt.Sprintf("%#x", edge.Entity)
+		}
+		cerr.Violations = append(cerr.Violations, CheckViolation{
+			Subject:    subject,
+			Predicate:  edge.Attr,
+			Object:     obj.Value.(string),
+			Lang:       edge.Lang,
+			Constraint: constraint,
+		})
+	}
+	if len(cerr.Violations) > 0 {
+		return &cerr
+	}
+	return nil
+}
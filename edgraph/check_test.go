@@ -0,0 +1,63 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckValues(t *testing.T) {
+	require.NoError(t, schema.ParseBytes([]byte(`
+		age: int @check(min: 0, max: 150) .
+		handle: string @check(maxlength: 8, pattern: "^[a-z]+$") .
+		name: string .
+	`), 1))
+	defer schema.State().DeleteAll()
+
+	set := func(uid uint64, attr, value string) *pb.DirectedEdge {
+		return &pb.DirectedEdge{Entity: uid, Attr: attr, Value: []byte(value),
+			Op: pb.DirectedEdge_SET}
+	}
+	newUids := map[string]uint64{"_:a": 1, "uid(v)": 2}
+	ctx := context.Background()
+	require.NoError(t, checkValues(ctx, []*pb.DirectedEdge{
+		set(1, "age", "30"), set(1, "handle", "alice"), set(1, "name", "Alice A."),
+		// Deleting a value that doesn't satisfy the constraints is fine.
+		{Entity: 3, Attr: "age", Value: []byte("200"), Op: pb.DirectedEdge_DEL},
+	}, newUids))
+
+	err := checkValues(ctx, []*pb.DirectedEdge{
+		set(1, "age", "151"), set(2, "handle", "Bob"), set(3, "handle", "abcdefghi"),
+		set(3, "name", "Bob"),
+	}, newUids)
+	require.Error(t, err)
+	cerr, ok := err.(*ValueCheckError)
+	require.True(t, ok)
+	require.Equal(t, []CheckViolation{
+		{Subject: "_:a", Predicate: "age", Object: "151", Constraint: "max: 150"},
+		{Subject: "0x2", Predicate: "handle", Object: "Bob", Constraint: `pattern: "^[a-z]+$"`},
+		{Subject: "0x3", Predicate: "handle", Object: "abcdefghi", Constraint: "maxlength: 8"},
+	}, cerr.Violations)
+	require.Equal(t, `Value "151" of predicate age of node _:a doesn't satisfy the constraint`+
+		` max: 150 of its schema, along with 2 more values`, err.Error())
+	require.True(t, isValueCheckError(err))
+}
@@ -0,0 +1,201 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"context"
+	"sort"
+
+	"github.com/dgraph-io/dgo/v200"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/dgraph/dgraph/cmd/zero"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/query"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/worker"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// compositeBatchSize is the number of nodes stored per transaction in a new composite index.
+const compositeBatchSize = 1000
+
+// validateCompositeIndexes checks the composite indexes declared in the schema updates, and the
+// ones spanning the predicates of the updates. The predicates an index spans must be scalars
+// that aren't lists and don't have @lang, either in the same updates or in the current schema,
+// and the last one must have the type of the index. It returns the new indexes, sorted.
+func validateCompositeIndexes(ctx context.Context, updates []*pb.SchemaUpdate) ([]string, error) {
+	nodes := make(map[string]*pb.SchemaNode, len(updates))
+	indexSet := make(map[string]struct{})
+	for _, update := range updates {
+		nodes[update.Predicate] = &pb.SchemaNode{
+			Predicate: update.Predicate,
+			Type:      types.TypeID(update.ValueType).Name(),
+			List:      update.List,
+			Lang:      update.Lang,
+		}
+		if x.IsCompositeIndex(update.Predicate) {
+			indexSet[update.Predicate] = struct{}{}
+		}
+		for _, index := range worker.CompositeIndexes(update.Predicate) {
+			indexSet[index] = struct{}{}
+		}
+	}
+	if len(indexSet) == 0 {
+		return nil, nil
+	}
+
+	// The current schema tells which indexes already exist, along with the predicates that
+	// aren't in the updates.
+	predSet := make(map[string]struct{})
+	for index := range indexSet {
+		predSet[index] = struct{}{}
+		for _, pred := range x.CompositeIndexPredicates(index) {
+			if _, ok := nodes[pred]; !ok {
+				predSet[pred] = struct{}{}
+			}
+		}
+	}
+	preds := make([]string, 0, len(predSet))
+	for pred := range predSet {
+		preds = append(preds, pred)
+	}
+	sort.Strings(preds)
+	current, err := worker.GetSchemaOverNetwork(ctx, &pb.SchemaRequest{
+		Predicates: preds,
+		Fields:     []string{"type", "list", "lang"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]bool, len(current))
+	for _, node := range current {
+		existing[node.Predicate] = true
+		if _, ok := nodes[node.Predicate]; !ok {
+			nodes[node.Predicate] = node
+		}
+	}
+
+	var created []string
+	for index := range indexSet {
+		if _, ok := nodes[index]; !ok {
+			// The index has been dropped, but its tablet is still known to this Alpha.
+			continue
+		}
+		if !existing[index] {
+			created = append(created, index)
+		}
+		if err := checkCompositeIndex(index, nodes); err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(created)
+	return created, nil
+}
+
+// checkCompositeIndex checks a composite index against the schema of the predicates it spans.
+func checkCompositeIndex(index string, nodes map[string]*pb.SchemaNode) error {
+	preds := x.CompositeIndexPredicates(index)
+	for _, pred := range preds {
+		node, ok := nodes[pred]
+		switch {
+		case !ok:
+			return errors.Errorf("Composite index %s spans predicate %s, which isn't in the"+
+				" schema", index, pred)
+		case node.List || node.Lang:
+			return errors.Errorf("Composite index %s can't span predicate %s, which is a list"+
+				" or has @lang", index, pred)
+		}
+		switch node.Type {
+		case types.UidID.Name(), types.PasswordID.Name(), types.GeoID.Name(),
+			types.VFloatID.Name():
+			return errors.Errorf("Composite index %s can't span predicate %s of type %s",
+				index, pred, node.Type)
+		}
+	}
+	last := preds[len(preds)-1]
+	if indexType, lastType := nodes[index].Type, nodes[last].Type; indexType != lastType {
+		return errors.Errorf("Composite index %s must have the type %s of its last predicate"+
+			" %s, got %s", index, lastType, last, indexType)
+	}
+	return nil
+}
+
+// buildCompositeIndex stores the values of the nodes that already have the predicates spanned
+// by a new composite index, in batches of compositeBatchSize nodes. The nodes are paged through
+// the ones with the first predicate, and each batch is committed in its own transaction, retried
+// if it conflicts with another one.
+func buildCompositeIndex(ctx context.Context, index string) error {
+	preds := x.CompositeIndexPredicates(index)
+	readTs := worker.State.GetTimestamp(false)
+	var total int
+	var after uint64
+	for {
+		res, err := worker.ProcessTaskOverNetwork(ctx, &pb.Query{
+			Attr:     preds[0],
+			SrcFunc:  &pb.SrcFunction{Name: "has"},
+			ReadTs:   readTs,
+			AfterUid: after,
+			First:    compositeBatchSize,
+		})
+		if err != nil {
+			return err
+		}
+		if len(res.UidMatrix) == 0 || len(res.UidMatrix[0].Uids) == 0 {
+			break
+		}
+		uids := res.UidMatrix[0].Uids
+		for {
+			err := commitCompositeIndexEdges(ctx, index, uids)
+			if err != dgo.ErrAborted {
+				if err != nil {
+					return err
+				}
+				break
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+		}
+		total += len(uids)
+		after = uids[len(uids)-1]
+	}
+	glog.Infof("Built composite index %s for %d nodes", index, total)
+	return nil
+}
+
+// commitCompositeIndexEdges stores the values of the given nodes in a composite index, as of a
+// new transaction. It returns dgo.ErrAborted if the transaction conflicts with another one.
+func commitCompositeIndexEdges(ctx context.Context, index string, uids []uint64) error {
+	startTs := worker.State.GetTimestamp(false)
+	edges, err := query.CompositeIndexEdges(ctx, index, uids, startTs)
+	if err != nil {
+		return err
+	}
+	tctx, err := worker.MutateOverNetwork(ctx, &pb.Mutations{Edges: edges, StartTs: startTs})
+	if err != nil {
+		tctx.Aborted = true
+		_, _ = worker.CommitOverNetwork(ctx, tctx)
+		if err == zero.ErrConflict {
+			return dgo.ErrAborted
+		}
+		return err
+	}
+	_, err = worker.CommitOverNetwork(ctx, tctx)
+	return err
+}
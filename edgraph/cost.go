@@ -0,0 +1,239 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"sync"
+
+	"github.com/dgraph-io/dgraph/gql"
+	"github.com/dgraph-io/dgraph/worker"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+const (
+	// defaultRootUids is the number of uids a root function is estimated to return when the
+	// query doesn't list the uids explicitly and there are no statistics about its predicate.
+	defaultRootUids = 1000
+	// defaultFanout is the number of edges a uid predicate is estimated to have per node when
+	// the query doesn't bound them with first.
+	defaultFanout = 10
+)
+
+var (
+	// tabletKeys and eqUids return the statistics the number of uids of a root function is
+	// estimated from. They are variables so that the tests can provide their own statistics.
+	tabletKeys = worker.TabletKeys
+	eqUids     = worker.EqUids
+
+	costQueueOnce sync.Once
+	// costQueue limits the number of queries over the cost budget that run concurrently.
+	costQueue chan struct{}
+)
+
+// saturatingMul multiplies a and b, returning math.MaxUint64 on overflow.
+func saturatingMul(a, b uint64) uint64 {
+	if a != 0 && b > math.MaxUint64/a {
+		return math.MaxUint64
+	}
+	return a * b
+}
+
+// saturatingAdd adds a and b, returning math.MaxUint64 on overflow.
+func saturatingAdd(a, b uint64) uint64 {
+	if a > math.MaxUint64-b {
+		return math.MaxUint64
+	}
+	return a + b
+}
+
+// boundByFirst returns the smaller of uids and the values of the first and sample arguments,
+// if any.
+func boundByFirst(gq *gql.GraphQuery, uids uint64) uint64 {
+	for _, arg := range []string{"first", "sample"} {
+		if first, err := strconv.ParseInt(gq.Args[arg], 10, 64); err == nil {
+			if first < 0 {
+				first = -first
+			}
+			if uint64(first) < uids {
+				uids = uint64(first)
+			}
+		}
+	}
+	return uids
+}
+
+// numFilterPreds returns the number of predicates touched by a filter tree.
+func numFilterPreds(ft *gql.FilterTree) uint64 {
+	if ft == nil {
+		return 0
+	}
+	var num uint64
+	if ft.Func != nil && ft.Func.Attr != "" {
+		num++
+	}
+	if ft.Func != nil && (ft.Func.Name == "has" || ft.Func.Name == "hasall") {
+		num = saturatingAdd(num, uint64(len(ft.Func.Args)))
+	}
+	if ft.Func != nil && ft.Func.CountFilter != nil {
+		// The filter given to count runs over the edges of every candidate.
+		num = saturatingAdd(num, saturatingMul(defaultFanout, numFilterPreds(ft.Func.CountFilter)))
+	}
+	for _, child := range ft.Child {
+		num += numFilterPreds(child)
+	}
+	return num
+}
+
+// predicateUids returns the estimated number of nodes with the given predicate, which is the
+// number of keys of its tablet, or defaultRootUids if the keys haven't been counted.
+func predicateUids(attr string) uint64 {
+	if keys, ok := tabletKeys(attr); ok {
+		return keys
+	}
+	return defaultRootUids
+}
+
+// funcUids returns the estimated number of uids a root function returns. The functions that scan
+// a predicate, like has, are estimated at the number of nodes with the predicate, and eq at the
+// length of the index posting lists of its values. The others return at most the nodes with
+// the predicate.
+func funcUids(ctx context.Context, f *gql.Function) uint64 {
+	switch f.Name {
+	case "uid":
+		if len(f.UID) > 0 {
+			return uint64(len(f.UID))
+		}
+	case "has", "hasall":
+		// The has function is evaluated for each of the predicates given to it.
+		uids := predicateUids(f.Attr)
+		for _, arg := range f.Args {
+			uids = saturatingAdd(uids, predicateUids(arg.Value))
+		}
+		return uids
+	case "regexp", "match":
+		return predicateUids(f.Attr)
+	case "eq", "type":
+		if f.IsCount || f.IsValueVar || f.IsLenVar {
+			break
+		}
+		attr := f.Attr
+		if f.Name == "type" {
+			attr = "dgraph.type"
+		}
+		vals := make([]string, 0, len(f.Args))
+		for _, arg := range f.Args {
+			if arg.IsValueVar {
+				break
+			}
+			vals = append(vals, arg.Value)
+		}
+		if len(vals) != len(f.Args) {
+			break
+		}
+		if uids, ok := eqUids(ctx, attr, vals); ok {
+			return uids
+		}
+	}
+	if keys, ok := tabletKeys(f.Attr); ok && keys < defaultRootUids {
+		return keys
+	}
+	return defaultRootUids
+}
+
+// estimateQueryCost returns the estimated cost of a query block, computed as the sum of the
+// number of uids scanned times the number of predicates touched at each level.
+func estimateQueryCost(ctx context.Context, gq *gql.GraphQuery) uint64 {
+	uids := uint64(defaultRootUids)
+	switch {
+	case len(gq.UID) > 0 && (gq.Func == nil || gq.Func.Name == "uid"):
+		uids = uint64(len(gq.UID))
+	case gq.Func != nil && len(gq.Func.Union) > 0:
+		// Each of the functions merged by union is evaluated on its own.
+		uids = 0
+		for _, f := range gq.Func.Union {
+			uids = saturatingAdd(uids, funcUids(ctx, f))
+		}
+	case gq.Func != nil:
+		uids = funcUids(ctx, gq.Func)
+	}
+	// The root function and filters are evaluated over all the uids, before pagination.
+	cost := saturatingMul(uids, 1+numFilterPreds(gq.Filter))
+	return saturatingAdd(cost, estimateLevelCost(gq, boundByFirst(gq, uids)))
+}
+
+func estimateLevelCost(gq *gql.GraphQuery, uids uint64) uint64 {
+	if gq.Recurse {
+		// Each level of a recurse query traverses all the predicates in the block.
+		depth := gq.RecurseArgs.Depth
+		if depth == 0 {
+			return math.MaxUint64
+		}
+		var cost uint64
+		for i := uint64(0); i < depth && uids > 0; i++ {
+			cost = saturatingAdd(cost, saturatingMul(uids, uint64(len(gq.Children))))
+			uids = saturatingMul(uids, defaultFanout)
+		}
+		return cost
+	}
+
+	var cost uint64
+	for _, child := range gq.Children {
+		cost = saturatingAdd(cost, saturatingMul(uids, 1+numFilterPreds(child.Filter)))
+		if len(child.Children) > 0 {
+			childUids := boundByFirst(child, saturatingMul(uids, defaultFanout))
+			cost = saturatingAdd(cost, estimateLevelCost(child, childUids))
+		}
+	}
+	return cost
+}
+
+// admitQuery checks the estimated cost of the queries against the configured budget. Queries
+// over the budget are rejected, unless queueing is enabled, in which case they wait for
+// their turn to run. The returned function must be called once the query is done.
+func admitQuery(ctx context.Context, queries []*gql.GraphQuery) (func(), error) {
+	noop := func() {}
+	budget := x.Config.QueryCostBudget
+	if budget == 0 {
+		return noop, nil
+	}
+
+	var cost uint64
+	for _, gq := range queries {
+		if gq != nil {
+			cost = saturatingAdd(cost, estimateQueryCost(ctx, gq))
+		}
+	}
+	if cost <= budget {
+		return noop, nil
+	}
+	if x.Config.QueryCostQueue <= 0 {
+		return noop, &x.LimitExceededError{Limit: x.LimitCost, Max: budget}
+	}
+
+	costQueueOnce.Do(func() {
+		costQueue = make(chan struct{}, x.Config.QueryCostQueue)
+	})
+	select {
+	case costQueue <- struct{}{}:
+		return func() { <-costQueue }, nil
+	case <-ctx.Done():
+		return noop, ctx.Err()
+	}
+}
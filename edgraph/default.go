@@ -0,0 +1,157 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"context"
+	"sort"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/worker"
+)
+
+// addDefaultValues adds the edges setting the default values, given with @default in the
+// schema, of the predicates of the types the nodes created by a mutation get. The predicates
+// the mutation sets for a node, and the ones with a virtual default, are left out.
+func addDefaultValues(ctx context.Context, edges []*pb.DirectedEdge,
+	newUids map[string]uint64) ([]*pb.DirectedEdge, error) {
+
+	preds := typeFieldsOfNewNodes(edges, newUids)
+	if len(preds) == 0 {
+		return edges, nil
+	}
+	defaults, err := getDefaultValues(ctx, preds)
+	if err != nil {
+		return edges, err
+	}
+	return setDefaultValues(edges, newUids, defaults), nil
+}
+
+// typedEdges returns the edges giving a type to the nodes created by a mutation, along with
+// the predicates the mutation sets for each of these nodes.
+func typedEdges(edges []*pb.DirectedEdge,
+	newUids map[string]uint64) ([]*pb.DirectedEdge, map[uint64]map[string]bool) {
+
+	created := make(map[uint64]map[string]bool, len(newUids))
+	for _, uid := range newUids {
+		created[uid] = make(map[string]bool)
+	}
+	var typed []*pb.DirectedEdge
+	for _, edge := range edges {
+		preds, ok := created[edge.Entity]
+		if !ok || edge.Op != pb.DirectedEdge_SET {
+			continue
+		}
+		preds[edge.Attr] = true
+		if edge.Attr == "dgraph.type" {
+			typed = append(typed, edge)
+		}
+	}
+	return typed, created
+}
+
+// typeFieldsOfNewNodes returns the fields of the types given to the nodes created by a
+// mutation, sorted. Types are known by all the groups.
+func typeFieldsOfNewNodes(edges []*pb.DirectedEdge, newUids map[string]uint64) []string {
+	typed, _ := typedEdges(edges, newUids)
+	predSet := make(map[string]struct{})
+	for _, edge := range typed {
+		typ, ok := schema.State().GetType(string(edge.Value))
+		if !ok {
+			continue
+		}
+		for _, field := range typ.Fields {
+			predSet[field.Predicate] = struct{}{}
+		}
+	}
+	preds := make([]string, 0, len(predSet))
+	for pred := range predSet {
+		preds = append(preds, pred)
+	}
+	sort.Strings(preds)
+	return preds
+}
+
+// setDefaultValues adds the edges setting the given default values to the nodes created by a
+// mutation, for the fields of their types that the mutation doesn't set.
+func setDefaultValues(edges []*pb.DirectedEdge, newUids map[string]uint64,
+	defaults map[string]defaultValue) []*pb.DirectedEdge {
+
+	typed, created := typedEdges(edges, newUids)
+	for _, edge := range typed {
+		typ, ok := schema.State().GetType(string(edge.Value))
+		if !ok {
+			continue
+		}
+		preds := created[edge.Entity]
+		for _, field := range typ.Fields {
+			def, ok := defaults[field.Predicate]
+			if !ok || preds[field.Predicate] {
+				continue
+			}
+			preds[field.Predicate] = true
+			edges = append(edges, &pb.DirectedEdge{
+				Entity:    edge.Entity,
+				Attr:      field.Predicate,
+				Value:     def.val.Value.([]byte),
+				ValueType: def.tid.Enum(),
+				Op:        pb.DirectedEdge_SET,
+			})
+		}
+	}
+	return edges
+}
+
+// defaultValue is the default value of a predicate, marshalled to binary, and its type.
+type defaultValue struct {
+	tid types.TypeID
+	val types.Val
+}
+
+// getDefaultValues returns the materialized default values of the given predicates. The schema
+// of a predicate is kept by the group serving it.
+func getDefaultValues(ctx context.Context, preds []string) (map[string]defaultValue, error) {
+	nodes, err := worker.GetSchemaOverNetwork(ctx, &pb.SchemaRequest{
+		Predicates: preds,
+		Fields:     []string{"type", "default"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	defaults := make(map[string]defaultValue)
+	for _, node := range nodes {
+		if node.DefaultValue == "" || node.DefaultVirtual {
+			continue
+		}
+		tid, ok := types.TypeForName(node.Type)
+		if !ok {
+			continue
+		}
+		val, err := schema.DefaultValue(&pb.SchemaUpdate{
+			ValueType:    tid.Enum(),
+			DefaultValue: node.DefaultValue,
+		})
+		if err != nil {
+			return nil, err
+		}
+		defaults[node.Predicate] = defaultValue{tid: tid, val: val}
+	}
+	return defaults, nil
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"testing"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetDefaultValues(t *testing.T) {
+	require.NoError(t, schema.ParseBytes(nil, 1))
+	schema.State().SetType("Player", pb.TypeUpdate{
+		TypeName: "Player",
+		Fields: []*pb.SchemaUpdate{
+			{Predicate: "name"}, {Predicate: "status"}, {Predicate: "level"},
+		},
+	})
+	defer schema.State().DeleteAll()
+
+	edges := []*pb.DirectedEdge{
+		{Entity: 1, Attr: "dgraph.type", Value: []byte("Player"), Op: pb.DirectedEdge_SET},
+		{Entity: 1, Attr: "level", Value: []byte("5"), Op: pb.DirectedEdge_SET},
+		// An existing node gets no defaults.
+		{Entity: 2, Attr: "dgraph.type", Value: []byte("Player"), Op: pb.DirectedEdge_SET},
+		// A new node without a type gets no defaults.
+		{Entity: 3, Attr: "name", Value: []byte("Bob"), Op: pb.DirectedEdge_SET},
+	}
+	newUids := map[string]uint64{"_:a": 1, "_:b": 3}
+	require.Equal(t, []string{"level", "name", "status"}, typeFieldsOfNewNodes(edges, newUids))
+	require.Empty(t, typeFieldsOfNewNodes(edges[2:], newUids))
+
+	status, err := schema.DefaultValue(&pb.SchemaUpdate{
+		ValueType:    pb.Posting_STRING,
+		DefaultValue: "active",
+	})
+	require.NoError(t, err)
+	level, err := schema.DefaultValue(&pb.SchemaUpdate{
+		ValueType:    pb.Posting_INT,
+		DefaultValue: "1",
+	})
+	require.NoError(t, err)
+	edges = setDefaultValues(edges, newUids, map[string]defaultValue{
+		"status": {tid: types.StringID, val: status},
+		"level":  {tid: types.IntID, val: level},
+	})
+	require.Len(t, edges, 5)
+	require.Equal(t, &pb.DirectedEdge{
+		Entity:    1,
+		Attr:      "status",
+		Value:     []byte("active"),
+		ValueType: pb.Posting_STRING,
+		Op:        pb.DirectedEdge_SET,
+	}, edges[4])
+}
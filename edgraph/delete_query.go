@@ -0,0 +1,318 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/gql"
+	"github.com/dgraph-io/dgraph/query"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	otrace "go.opencensus.io/trace"
+)
+
+// DefaultDeleteBatchSize is the number of nodes deleted per transaction by DeleteByQuery when
+// the request doesn't set a batch size.
+const DefaultDeleteBatchSize = 1000
+
+// DeleteByQueryRequest is a request to delete the nodes matched by a query.
+type DeleteByQueryRequest struct {
+	// Query is the DQL query matching the nodes to delete.
+	Query string
+	// Vars are the values of the GraphQL variables used in Query.
+	Vars map[string]string
+	// Var is the uid variable holding the nodes to delete. If it's empty, the nodes of every uid
+	// variable defined in Query are deleted. Value variables are never deleted, as their uids are
+	// only the keys of their values.
+	Var string
+	// Predicates are the predicates deleted from each node. All the predicates of the nodes
+	// are deleted if it's empty.
+	Predicates []string
+	// BatchSize is the number of nodes deleted per transaction.
+	BatchSize int
+	// Progress, if set, is called after each batch is committed.
+	Progress func(resp *DeleteByQueryResponse)
+}
+
+// DeleteByQueryResponse reports the outcome of a DeleteByQuery request.
+type DeleteByQueryResponse struct {
+	// Matched is the number of nodes matched by the query so far.
+	Matched int `json:"matched"`
+	// Deleted is the number of nodes deleted so far.
+	Deleted int `json:"deleted"`
+	// Batches is the number of transactions committed so far.
+	Batches int `json:"batches"`
+}
+
+// DeleteByQuery runs the query in the request and deletes the matched nodes, or the given
+// predicates of them, in batches of req.BatchSize nodes. Each batch is committed in its own
+// transaction, so if a batch fails, the nodes in the previous batches stay deleted and the
+// response reports how many of them there were.
+//
+// If the variable is defined by a query block at the root, the block is paged with first and
+// after, so that only one batch of nodes is matched at a time. Otherwise, all the nodes are
+// matched at once, and the variable can hold at most a million of them.
+func (s *Server) DeleteByQuery(ctx context.Context, req *DeleteByQueryRequest) (
+	*DeleteByQueryResponse, error) {
+	ctx, span := otrace.StartSpan(ctx, "Server.DeleteByQuery")
+	defer span.End()
+
+	if strings.TrimSpace(req.Query) == "" {
+		return nil, errors.Errorf("delete by query requires a query")
+	}
+	if req.BatchSize < 0 {
+		return nil, errors.Errorf("batch size should be a positive number, got: %d", req.BatchSize)
+	}
+	batchSize := req.BatchSize
+	if batchSize == 0 {
+		batchSize = DefaultDeleteBatchSize
+	}
+	for _, pred := range req.Predicates {
+		if strings.TrimSpace(pred) == "" {
+			return nil, errors.Errorf("predicate to delete can't be empty")
+		}
+		if err := validatePredName(pred); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := x.HealthCheck(); err != nil {
+		return nil, err
+	}
+
+	resp := &DeleteByQueryResponse{}
+	deleteBatch := func(uids []string) error {
+		mu := &api.Mutation{Del: deleteNQuads(uids, req.Predicates)}
+		if _, err := s.Query(ctx, &api.Request{Mutations: []*api.Mutation{mu},
+			CommitNow: true}); err != nil {
+			return errors.Wrapf(err, "while deleting batch %d, after deleting %d of %d nodes",
+				resp.Batches+1, resp.Deleted, resp.Matched)
+		}
+
+		resp.Deleted += len(uids)
+		resp.Batches++
+		glog.Infof("Delete by query deleted %d of %d nodes", resp.Deleted, resp.Matched)
+		if req.Progress != nil {
+			req.Progress(resp)
+		}
+		return nil
+	}
+
+	var after uint64
+	for {
+		page := &deletePage{first: batchSize, after: after}
+		uids, err := s.matchedUids(ctx, req, page)
+		if err != nil {
+			return resp, err
+		}
+		resp.Matched += len(uids)
+		if !page.paged {
+			glog.Infof("Delete by query matched %d nodes", len(uids))
+		}
+
+		for start := 0; start < len(uids); start += batchSize {
+			end := start + batchSize
+			if end > len(uids) {
+				end = len(uids)
+			}
+			if err := deleteBatch(uids[start:end]); err != nil {
+				return resp, err
+			}
+		}
+
+		// A page with fewer nodes than asked for is the last one.
+		if !page.paged || len(uids) < batchSize {
+			return resp, nil
+		}
+		last, err := strconv.ParseUint(uids[len(uids)-1], 10, 64)
+		if err != nil {
+			return resp, err
+		}
+		if last <= after {
+			return resp, errors.Errorf("delete by query didn't move past uid %#x", after)
+		}
+		after = last
+	}
+}
+
+// deletePage is the page of nodes matched by one run of the query of DeleteByQuery.
+type deletePage struct {
+	// first and after are the pagination arguments added to the block defining the variable.
+	first int
+	after uint64
+	// paged is set by matchedUids if the block could be paged. If it's false, all the
+	// nodes have been matched at once.
+	paged bool
+}
+
+// matchedUids runs the query in the request and returns the sorted and deduplicated uids
+// stored in the uid variables to delete. If the nodes to delete come from a variable defined by
+// a single root block, the block is paged with the arguments in page.
+func (s *Server) matchedUids(ctx context.Context, req *DeleteByQueryRequest,
+	page *deletePage) ([]string, error) {
+	qc := &queryContext{
+		req:     &api.Request{Query: req.Query, Vars: req.Vars, ReadOnly: true},
+		latency: &query.Latency{},
+		span:    otrace.FromContext(ctx),
+	}
+	var err error
+	if qc.limits, err = getQueryLimits(ctx); err != nil {
+		return nil, err
+	}
+	// The variables aren't used by the query itself, they hold the nodes to delete.
+	if qc.gqlRes, err = gql.ParseWithDefinedVarsNeeded(gql.Request{
+		Str:       req.Query,
+		Variables: req.Vars,
+	}); err != nil {
+		return nil, err
+	}
+	if err = validateQuery(qc.gqlRes.Query); err != nil {
+		return nil, err
+	}
+	if err = checkQueryDepth(qc.gqlRes.Query, qc.limits.maxDepth); err != nil {
+		return nil, err
+	}
+
+	// Every variable is looked up as a value variable too, to tell the two kinds apart.
+	qc.uidRes = make(map[string][]string)
+	qc.valRes = make(map[string]map[uint64]types.Val)
+	for _, vars := range qc.gqlRes.QueryVars {
+		for _, name := range vars.Defines {
+			if req.Var == "" || name == req.Var {
+				qc.uidRes[name] = nil
+				qc.valRes[name] = nil
+			}
+		}
+	}
+	switch {
+	case len(qc.uidRes) == 0 && req.Var != "":
+		return nil, errors.Errorf("query for delete by query doesn't define variable %s", req.Var)
+	case len(qc.uidRes) == 0:
+		return nil, errors.Errorf("query for delete by query doesn't define any variable")
+	}
+	page.paged = pageDeleteQuery(qc.gqlRes.Query, qc.uidRes, page)
+
+	if auth := ctx.Value(Authorize); auth == nil || auth.(bool) {
+		if err = authorizeRequest(ctx, qc); err != nil {
+			return nil, err
+		}
+		done, err := admitQuery(ctx, qc.gqlRes.Query)
+		if err != nil {
+			return nil, err
+		}
+		defer done()
+	}
+	if _, err = processQuery(ctx, qc); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[uint64]struct{})
+	for name, list := range qc.uidRes {
+		if len(qc.valRes[name]) > 0 {
+			// The uids of a value variable are the nodes its values belong to.
+			if req.Var != "" {
+				return nil, errors.Errorf("variable %s for delete by query is a value variable,"+
+					" expected a uid variable", name)
+			}
+			continue
+		}
+		for _, u := range list {
+			uid, err := strconv.ParseUint(u, 10, 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "while parsing uid %s", u)
+			}
+			seen[uid] = struct{}{}
+		}
+	}
+	sorted := make([]uint64, 0, len(seen))
+	for uid := range seen {
+		sorted = append(sorted, uid)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	uids := make([]string, len(sorted))
+	for i, uid := range sorted {
+		uids[i] = strconv.FormatUint(uid, 10)
+	}
+	return uids, nil
+}
+
+// pageDeleteQuery adds the pagination arguments of page to the root block defining the variable
+// to delete, and returns whether it could. That's only done when there's one such variable and
+// the block doesn't have any pagination, ordering or @cascade of its own, which would change the
+// nodes it matches.
+func pageDeleteQuery(blocks []*gql.GraphQuery, vars map[string][]string, page *deletePage) bool {
+	if len(vars) != 1 {
+		return false
+	}
+	var root *gql.GraphQuery
+	for _, gq := range blocks {
+		if _, ok := vars[gq.Var]; ok && gq.Var != "" {
+			if root != nil {
+				return false
+			}
+			root = gq
+		}
+	}
+	if root == nil || root.Func == nil || len(root.Order) > 0 || len(root.Cascade) > 0 ||
+		root.Recurse || root.IsGroupby || root.Alias == "shortest" {
+		return false
+	}
+	// The members of has(p1, p2, ...) and hasall(...) aren't paged with after.
+	if (root.Func.Name == "has" && len(root.Func.Args) > 0) || root.Func.Name == "hasall" {
+		return false
+	}
+	for _, arg := range []string{"first", "offset", "after", "sample"} {
+		if _, ok := root.Args[arg]; ok {
+			return false
+		}
+	}
+	if root.Args == nil {
+		root.Args = make(map[string]string)
+	}
+	root.Args["first"] = strconv.Itoa(page.first)
+	if page.after > 0 {
+		root.Args["after"] = strconv.FormatUint(page.after, 10)
+	}
+	return true
+}
+
+// deleteNQuads returns the N-Quads deleting the given predicates of the nodes, or all the
+// predicates of them if preds is empty.
+func deleteNQuads(uids []string, preds []string) []*api.NQuad {
+	if len(preds) == 0 {
+		preds = []string{x.Star}
+	}
+	nquads := make([]*api.NQuad, 0, len(uids)*len(preds))
+	for _, uid := range uids {
+		for _, pred := range preds {
+			nquads = append(nquads, &api.NQuad{
+				Subject:     uid,
+				Predicate:   pred,
+				ObjectValue: &api.Value{Val: &api.Value_DefaultVal{DefaultVal: x.Star}},
+			})
+		}
+	}
+	return nquads
+}
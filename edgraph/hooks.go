@@ -0,0 +1,608 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/dgraph/graphql/lambda"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/worker"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// PreCommitHook is the phase of the hooks called before a mutation is applied. A pre-commit
+	// hook can reject the mutation by returning a non 2xx status code. It's called for each
+	// mutation of a transaction, not when the transaction is committed.
+	PreCommitHook = "pre_commit"
+	// PostCommitHook is the phase of the hooks notified after a mutation is committed.
+	PostCommitHook = "post_commit"
+
+	defaultHookTimeout = 5 * time.Second
+	defaultHookRetries = 3
+	// hookWorkers is the number of goroutines delivering the post-commit notifications.
+	hookWorkers = 4
+	// hookQueueSize is the number of post-commit notifications that can wait for delivery
+	// before new ones are sent to the dead-letter file.
+	hookQueueSize = 1024
+	// maxHookResponse is the size of the response of a hook kept to be reported in errors.
+	maxHookResponse = 1 << 10
+)
+
+// hookRetryDelay is the delay before the first retry of a post-commit notification. It's
+// doubled on every retry.
+var hookRetryDelay = time.Second
+
+// MutationHook is an HTTP endpoint, or a webhook of the lambda script, called with the edges of
+// the mutations that touch the predicates or the types it's configured for.
+type MutationHook struct {
+	// Name identifies the hook in the notifications and errors. It defaults to the URL, or to
+	// the lambda webhook.
+	Name string `json:"name"`
+	// URL is the endpoint to which the events are sent with a POST request.
+	URL string `json:"url"`
+	// Lambda is the name of the webhook of the lambda script called with the events, instead of
+	// an endpoint. A pre-commit webhook rejects the mutation by throwing an error.
+	Lambda string `json:"lambda"`
+	// Phase is either PreCommitHook or PostCommitHook.
+	Phase string `json:"phase"`
+	// Predicates are the predicates the hook is called for.
+	Predicates []string `json:"predicates"`
+	// Types are the types the hook is called for. An edge belongs to a type if its predicate is
+	// a field of the type or if it sets the dgraph.type of a node to it.
+	Types []string `json:"types"`
+	// Timeout is the maximum duration of a call to the hook. It defaults to 5s.
+	Timeout string `json:"timeout"`
+	// Retries is the number of times a post-commit notification is retried before being sent
+	// to the dead-letter file. It defaults to 3.
+	Retries *int `json:"retries"`
+
+	timeout time.Duration
+	retries int
+	preds   map[string]struct{}
+	types   map[string]struct{}
+}
+
+// MutationHooksConfig is the content of the file given with the --mutation_hooks flag.
+type MutationHooksConfig struct {
+	// DeadLetterFile is the file to which the post-commit notifications that couldn't be
+	// delivered are appended, one JSON object per line. If empty, they are only logged.
+	DeadLetterFile string          `json:"dead_letter_file"`
+	Hooks          []*MutationHook `json:"hooks"`
+}
+
+// HookEdge is an edge of a mutation, as sent to the hooks.
+type HookEdge struct {
+	Uid       string `json:"uid"`
+	Predicate string `json:"predicate"`
+	// Object is the uid the edge points to, for uid predicates.
+	Object string `json:"object,omitempty"`
+	// Value is the value of the edge converted to a string, for scalar predicates.
+	Value interface{} `json:"value,omitempty"`
+	Lang  string      `json:"lang,omitempty"`
+}
+
+// HookEvent is the body of the requests sent to the hooks.
+type HookEvent struct {
+	Hook     string      `json:"hook"`
+	Phase    string      `json:"phase"`
+	StartTs  uint64      `json:"start_ts"`
+	CommitTs uint64      `json:"commit_ts,omitempty"`
+	Set      []*HookEdge `json:"set,omitempty"`
+	Delete   []*HookEdge `json:"delete,omitempty"`
+}
+
+// MutationRejectedError is returned when a pre-commit hook rejects a mutation.
+type MutationRejectedError struct {
+	Hook   string
+	Reason string
+}
+
+func (e *MutationRejectedError) Error() string {
+	return fmt.Sprintf("Mutation rejected by hook %s: %s", e.Hook, e.Reason)
+}
+
+// GRPCStatus lets gRPC report the error to clients with the FailedPrecondition code.
+func (e *MutationRejectedError) GRPCStatus() *status.Status {
+	return status.New(codes.FailedPrecondition, e.Error())
+}
+
+// Extensions returns the fields to be reported in the extensions of an HTTP error.
+func (e *MutationRejectedError) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"code": x.ErrorMutationRejected,
+		"hook": e.Hook,
+	}
+}
+
+// hookDelivery is a post-commit notification waiting to be delivered.
+type hookDelivery struct {
+	hook  *MutationHook
+	event *HookEvent
+}
+
+// pendingHookEvents are the post-commit notifications of a transaction that isn't committed yet.
+type pendingHookEvents struct {
+	deliveries []*hookDelivery
+	added      time.Time
+	// savepoints keeps the number of deliveries recorded by each savepoint, in creation order.
+	savepoints []hookSavepoint
+}
+
+type hookSavepoint struct {
+	name       string
+	deliveries int
+}
+
+type hookRegistry struct {
+	pre  []*MutationHook
+	post []*MutationHook
+
+	client *http.Client
+	queue  chan *hookDelivery
+
+	deadLetterFile string
+	deadLetterMu   sync.Mutex
+
+	pendingMu sync.Mutex
+	pending   map[uint64]*pendingHookEvents
+}
+
+// mutationHooks holds the configured hooks. It's nil if there aren't any.
+var mutationHooks *hookRegistry
+
+// LoadMutationHooks reads the configuration of the mutation hooks from the given file and
+// starts delivering the post-commit notifications.
+func LoadMutationHooks(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "while reading mutation hooks file")
+	}
+	var conf MutationHooksConfig
+	if err := json.Unmarshal(data, &conf); err != nil {
+		return errors.Wrapf(err, "while parsing mutation hooks file")
+	}
+	r, err := newHookRegistry(&conf)
+	if err != nil {
+		return err
+	}
+	r.start()
+	mutationHooks = r
+	worker.SetTxnListener(r)
+	glog.Infof("Loaded %d pre-commit and %d post-commit mutation hooks",
+		len(r.pre), len(r.post))
+	return nil
+}
+
+func newHookRegistry(conf *MutationHooksConfig) (*hookRegistry, error) {
+	r := &hookRegistry{
+		client:         &http.Client{},
+		queue:          make(chan *hookDelivery, hookQueueSize),
+		deadLetterFile: conf.DeadLetterFile,
+		pending:        make(map[uint64]*pendingHookEvents),
+	}
+	for i, h := range conf.Hooks {
+		if err := h.init(); err != nil {
+			return nil, errors.Wrapf(err, "invalid mutation hook at index %d", i)
+		}
+		if h.Phase == PreCommitHook {
+			r.pre = append(r.pre, h)
+		} else {
+			r.post = append(r.post, h)
+		}
+	}
+	return r, nil
+}
+
+// init validates the configuration of the hook and fills in the defaults.
+func (h *MutationHook) init() error {
+	var err error
+	if h.Lambda != "" {
+		if h.URL != "" {
+			return errors.Errorf("hook can't have both a url and a lambda webhook")
+		}
+		if x.Config.GraphqlLambdaScript == "" {
+			return errors.Errorf("lambda webhook %s needs the --graphql_lambda_script flag",
+				h.Lambda)
+		}
+		if h.Name == "" {
+			h.Name = h.Lambda
+		}
+	} else {
+		u, err := url.Parse(h.URL)
+		if err != nil {
+			return errors.Wrapf(err, "while parsing url")
+		}
+		if !u.IsAbs() {
+			return errors.Errorf("expecting an absolute url, got: %q", h.URL)
+		}
+		if h.Name == "" {
+			h.Name = h.URL
+		}
+	}
+	if h.Phase != PreCommitHook && h.Phase != PostCommitHook {
+		return errors.Errorf("phase should be either %s or %s, got: %q",
+			PreCommitHook, PostCommitHook, h.Phase)
+	}
+	if len(h.Predicates) == 0 && len(h.Types) == 0 {
+		return errors.Errorf("hook %s doesn't have any predicate or type", h.Name)
+	}
+
+	h.timeout = defaultHookTimeout
+	if h.Timeout != "" {
+		if h.timeout, err = time.ParseDuration(h.Timeout); err != nil {
+			return errors.Wrapf(err, "while parsing timeout")
+		}
+		if h.timeout <= 0 {
+			return errors.Errorf("timeout should be positive, got: %s", h.Timeout)
+		}
+	}
+	h.retries = defaultHookRetries
+	if h.Retries != nil {
+		if *h.Retries < 0 {
+			return errors.Errorf("retries can't be negative, got: %d", *h.Retries)
+		}
+		h.retries = *h.Retries
+	}
+
+	h.preds = make(map[string]struct{}, len(h.Predicates))
+	for _, pred := range h.Predicates {
+		h.preds[pred] = struct{}{}
+	}
+	h.types = make(map[string]struct{}, len(h.Types))
+	for _, typ := range h.Types {
+		h.types[typ] = struct{}{}
+	}
+	return nil
+}
+
+// fields returns the predicates of the hook, along with the fields of its types.
+func (h *MutationHook) fields() map[string]struct{} {
+	if len(h.types) == 0 {
+		return h.preds
+	}
+	fields := make(map[string]struct{}, len(h.preds))
+	for pred := range h.preds {
+		fields[pred] = struct{}{}
+	}
+	for typ := range h.types {
+		typeDef, ok := schema.State().GetType(typ)
+		if !ok {
+			continue
+		}
+		for _, field := range typeDef.Fields {
+			fields[field.Predicate] = struct{}{}
+		}
+	}
+	return fields
+}
+
+// matches tells whether the hook should be called for the edge. A deletion of all the
+// predicates of a node matches every hook, since it might remove any of them.
+func (h *MutationHook) matches(edge *pb.DirectedEdge, fields map[string]struct{}) bool {
+	if edge.Attr == x.Star {
+		return true
+	}
+	if _, ok := fields[edge.Attr]; ok {
+		return true
+	}
+	if edge.Attr == "dgraph.type" {
+		_, ok := h.types[string(edge.Value)]
+		return ok
+	}
+	return false
+}
+
+// hookEdge converts the edge to the form in which it's sent to the hooks.
+func hookEdge(edge *pb.DirectedEdge) *HookEdge {
+	he := &HookEdge{
+		Uid:       fmt.Sprintf("%#x", edge.Entity),
+		Predicate: edge.Attr,
+		Lang:      edge.Lang,
+	}
+	if edge.Attr == x.Star {
+		he.Predicate = "*"
+	}
+	switch {
+	case edge.ValueId != 0:
+		he.Object = fmt.Sprintf("%#x", edge.ValueId)
+	case bytes.Equal(edge.Value, []byte(x.Star)):
+		he.Value = "*"
+	default:
+		src := types.Val{Tid: types.TypeID(edge.ValueType), Value: edge.Value}
+		if val, err := types.Convert(src, types.StringID); err == nil {
+			he.Value = val.Value
+		} else {
+			he.Value = string(edge.Value)
+		}
+	}
+	return he
+}
+
+// events returns the events to be sent to each of the hooks for the given edges. Hooks that
+// don't match any of the edges don't get an event.
+func events(hooks []*MutationHook, edges []*pb.DirectedEdge, startTs uint64) []*hookDelivery {
+	var out []*hookDelivery
+	for _, h := range hooks {
+		fields := h.fields()
+		event := &HookEvent{Hook: h.Name, Phase: h.Phase, StartTs: startTs}
+		for _, edge := range edges {
+			if !h.matches(edge, fields) {
+				continue
+			}
+			if edge.Op == pb.DirectedEdge_DEL {
+				event.Delete = append(event.Delete, hookEdge(edge))
+			} else {
+				event.Set = append(event.Set, hookEdge(edge))
+			}
+		}
+		if len(event.Set) > 0 || len(event.Delete) > 0 {
+			out = append(out, &hookDelivery{hook: h, event: event})
+		}
+	}
+	return out
+}
+
+// call sends the event to the hook and returns an error if it didn't respond with a 2xx
+// status code, or if its lambda webhook threw an error.
+func (r *hookRegistry) call(ctx context.Context, h *MutationHook, event *HookEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+	if h.Lambda != "" {
+		return lambda.Webhook(ctx, h.Lambda, body)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	msg, _ := ioutil.ReadAll(&io.LimitedReader{R: resp.Body, N: maxHookResponse})
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		if reason := strings.TrimSpace(string(msg)); reason != "" {
+			return errors.Errorf("hook responded with status %d: %s", resp.StatusCode, reason)
+		}
+		return errors.Errorf("hook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// preCommit calls the pre-commit hooks matching the edges, one after the other. The mutation
+// is rejected if any of them fails, including when the hook can't be reached.
+func (r *hookRegistry) preCommit(ctx context.Context, edges []*pb.DirectedEdge,
+	startTs uint64) error {
+	if r == nil || len(r.pre) == 0 {
+		return nil
+	}
+	for _, d := range events(r.pre, edges, startTs) {
+		if err := r.call(ctx, d.hook, d.event); err != nil {
+			return &MutationRejectedError{Hook: d.hook.Name, Reason: err.Error()}
+		}
+	}
+	return nil
+}
+
+// postCommitEvents returns the post-commit notifications for the edges of a mutation. They
+// are computed when the mutation is applied, so that they match the schema it was applied with.
+func (r *hookRegistry) postCommitEvents(edges []*pb.DirectedEdge,
+	startTs uint64) []*hookDelivery {
+	if r == nil || len(r.post) == 0 {
+		return nil
+	}
+	return events(r.post, edges, startTs)
+}
+
+// addPending keeps the notifications of a mutation until its transaction is committed or
+// aborted. The notifications of transactions whose status never reaches this server are
+// discarded once the transactions are old enough to be aborted by Dgraph.
+func (r *hookRegistry) addPending(startTs uint64, deliveries []*hookDelivery) {
+	if r == nil || len(deliveries) == 0 {
+		return
+	}
+	r.pendingMu.Lock()
+	defer r.pendingMu.Unlock()
+
+	p := r.getPending(startTs)
+	p.deliveries = append(p.deliveries, deliveries...)
+}
+
+// getPending returns the pending notifications of the transaction, creating them if needed.
+// It must be called with pendingMu held.
+func (r *hookRegistry) getPending(startTs uint64) *pendingHookEvents {
+	if maxAge := x.WorkerConfig.AbortOlderThan; maxAge > 0 {
+		for ts, p := range r.pending {
+			if time.Since(p.added) > maxAge {
+				delete(r.pending, ts)
+			}
+		}
+	}
+	p, ok := r.pending[startTs]
+	if !ok {
+		p = &pendingHookEvents{added: time.Now()}
+		r.pending[startTs] = p
+	}
+	return p
+}
+
+// Savepoint records the pending notifications of the transaction under the savepoint, so that
+// the ones of the mutations applied after it are discarded if the transaction is rolled back to
+// it. A savepoint created earlier with the same name is replaced.
+func (r *hookRegistry) Savepoint(startTs uint64, name string) {
+	r.pendingMu.Lock()
+	defer r.pendingMu.Unlock()
+
+	p := r.getPending(startTs)
+	for i, sp := range p.savepoints {
+		if sp.name == name {
+			p.savepoints = append(p.savepoints[:i], p.savepoints[i+1:]...)
+			break
+		}
+	}
+	p.savepoints = append(p.savepoints, hookSavepoint{name: name, deliveries: len(p.deliveries)})
+}
+
+// RollbackTo discards the pending notifications of the mutations applied after the savepoint,
+// along with the savepoints created after it.
+func (r *hookRegistry) RollbackTo(startTs uint64, name string) {
+	r.pendingMu.Lock()
+	defer r.pendingMu.Unlock()
+
+	p, ok := r.pending[startTs]
+	if !ok {
+		return
+	}
+	for i, sp := range p.savepoints {
+		if sp.name == name {
+			p.deliveries = p.deliveries[:sp.deliveries]
+			p.savepoints = p.savepoints[:i+1]
+			return
+		}
+	}
+}
+
+// Finished delivers the pending notifications of the transaction if it was committed, or
+// discards them otherwise. It's called once the status of the transaction reaches this server,
+// whichever server the transaction was committed or aborted through.
+func (r *hookRegistry) Finished(startTs, commitTs uint64) {
+	r.pendingMu.Lock()
+	p, ok := r.pending[startTs]
+	delete(r.pending, startTs)
+	r.pendingMu.Unlock()
+
+	if !ok || commitTs == 0 {
+		return
+	}
+	// Merge the notifications of the mutations of the transaction, so that each hook is
+	// notified once per commit.
+	merged := make(map[*MutationHook]*hookDelivery)
+	var order []*hookDelivery
+	for _, d := range p.deliveries {
+		if m, ok := merged[d.hook]; ok {
+			m.event.Set = append(m.event.Set, d.event.Set...)
+			m.event.Delete = append(m.event.Delete, d.event.Delete...)
+			continue
+		}
+		merged[d.hook] = d
+		order = append(order, d)
+	}
+	r.postCommit(order, commitTs)
+}
+
+// postCommit queues the notifications of a committed mutation for delivery.
+func (r *hookRegistry) postCommit(deliveries []*hookDelivery, commitTs uint64) {
+	if r == nil {
+		return
+	}
+	for _, d := range deliveries {
+		d.event.CommitTs = commitTs
+		select {
+		case r.queue <- d:
+		default:
+			r.deadLetter(d, errors.New("delivery queue is full"))
+		}
+	}
+}
+
+func (r *hookRegistry) start() {
+	for i := 0; i < hookWorkers; i++ {
+		go func() {
+			for d := range r.queue {
+				r.deliver(d)
+			}
+		}()
+	}
+}
+
+// deliver sends the notification to the hook, retrying with an exponential backoff. The
+// notification is sent to the dead-letter file once all the retries have failed.
+func (r *hookRegistry) deliver(d *hookDelivery) {
+	delay := hookRetryDelay
+	var err error
+	for attempt := 0; attempt <= d.hook.retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+		if err = r.call(context.Background(), d.hook, d.event); err == nil {
+			return
+		}
+		glog.Warningf("Attempt %d to notify mutation hook %s failed: %v",
+			attempt+1, d.hook.Name, err)
+	}
+	r.deadLetter(d, err)
+}
+
+// deadLetter records a notification that couldn't be delivered.
+func (r *hookRegistry) deadLetter(d *hookDelivery, cause error) {
+	record := map[string]interface{}{
+		"hook":      d.hook.Name,
+		"url":       d.hook.URL,
+		"lambda":    d.hook.Lambda,
+		"error":     cause.Error(),
+		"failed_at": time.Now().UTC().Format(time.RFC3339),
+		"event":     d.event,
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		glog.Errorf("Unable to encode undelivered notification of hook %s: %v", d.hook.Name, err)
+		return
+	}
+	if r.deadLetterFile == "" {
+		glog.Errorf("Unable to deliver notification to mutation hook: %s", line)
+		return
+	}
+
+	r.deadLetterMu.Lock()
+	defer r.deadLetterMu.Unlock()
+	f, err := os.OpenFile(r.deadLetterFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err == nil {
+		_, err = f.Write(append(line, '\n'))
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		glog.Errorf("Unable to write to dead-letter file %s: %v. Undelivered notification: %s",
+			r.deadLetterFile, err, line)
+	}
+}
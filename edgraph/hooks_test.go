@@ -0,0 +1,329 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/dgraph/graphql/lambda"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/worker"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/stretchr/testify/require"
+)
+
+func hookRetries(n int) *int {
+	return &n
+}
+
+func TestMutationHooksConfigErrors(t *testing.T) {
+	tests := []struct {
+		hook *MutationHook
+		err  string
+	}{
+		{&MutationHook{URL: "/hook", Phase: PreCommitHook, Predicates: []string{"name"}},
+			`invalid mutation hook at index 0: expecting an absolute url, got: "/hook"`},
+		{&MutationHook{URL: "http://hook", Phase: "before", Predicates: []string{"name"}},
+			`invalid mutation hook at index 0: phase should be either pre_commit or ` +
+				`post_commit, got: "before"`},
+		{&MutationHook{URL: "http://hook", Phase: PreCommitHook},
+			"invalid mutation hook at index 0: hook http://hook doesn't have any predicate or type"},
+		{&MutationHook{URL: "http://hook", Phase: PreCommitHook, Predicates: []string{"name"},
+			Timeout: "-1s"},
+			"invalid mutation hook at index 0: timeout should be positive, got: -1s"},
+		{&MutationHook{URL: "http://hook", Phase: PostCommitHook, Predicates: []string{"name"},
+			Retries: hookRetries(-1)},
+			"invalid mutation hook at index 0: retries can't be negative, got: -1"},
+		{&MutationHook{Lambda: "names", Phase: PreCommitHook, Predicates: []string{"name"}},
+			"invalid mutation hook at index 0: lambda webhook names needs the " +
+				"--graphql_lambda_script flag"},
+		{&MutationHook{URL: "http://hook", Lambda: "names", Phase: PreCommitHook,
+			Predicates: []string{"name"}},
+			"invalid mutation hook at index 0: hook can't have both a url and a lambda webhook"},
+	}
+	for _, test := range tests {
+		_, err := newHookRegistry(&MutationHooksConfig{Hooks: []*MutationHook{test.hook}})
+		require.EqualError(t, err, test.err)
+	}
+}
+
+func TestMutationHookEvents(t *testing.T) {
+	require.NoError(t, schema.ParseBytes([]byte(""), 1))
+	schema.State().SetType("Person", pb.TypeUpdate{
+		TypeName: "Person",
+		Fields:   []*pb.SchemaUpdate{{Predicate: "name"}, {Predicate: "friend"}},
+	})
+
+	r, err := newHookRegistry(&MutationHooksConfig{Hooks: []*MutationHook{
+		{Name: "people", URL: "http://hook", Phase: PostCommitHook, Types: []string{"Person"}},
+		{Name: "age", URL: "http://hook", Phase: PostCommitHook, Predicates: []string{"age"}},
+		{Name: "city", URL: "http://hook", Phase: PostCommitHook, Predicates: []string{"city"}},
+	}})
+	require.NoError(t, err)
+
+	edges := []*pb.DirectedEdge{
+		{Entity: 1, Attr: "dgraph.type", Value: []byte("Person")},
+		{Entity: 1, Attr: "name", Value: []byte("Alice"), Lang: "en"},
+		{Entity: 1, Attr: "friend", ValueId: 2, Op: pb.DirectedEdge_DEL},
+		{Entity: 1, Attr: "age", Value: []byte{26, 0, 0, 0, 0, 0, 0, 0},
+			ValueType: pb.Posting_INT},
+		{Entity: 2, Attr: "dgraph.type", Value: []byte("Company")},
+	}
+	deliveries := r.postCommitEvents(edges, 10)
+	require.Len(t, deliveries, 2)
+	require.Equal(t, &HookEvent{
+		Hook:    "people",
+		Phase:   PostCommitHook,
+		StartTs: 10,
+		Set: []*HookEdge{
+			{Uid: "0x1", Predicate: "dgraph.type", Value: "Person"},
+			{Uid: "0x1", Predicate: "name", Value: "Alice", Lang: "en"},
+		},
+		Delete: []*HookEdge{{Uid: "0x1", Predicate: "friend", Object: "0x2"}},
+	}, deliveries[0].event)
+	require.Equal(t, &HookEvent{
+		Hook:    "age",
+		Phase:   PostCommitHook,
+		StartTs: 10,
+		Set:     []*HookEdge{{Uid: "0x1", Predicate: "age", Value: "26"}},
+	}, deliveries[1].event)
+
+	// Deleting all the predicates of a node matches every hook.
+	star := []*pb.DirectedEdge{{Entity: 3, Attr: "_STAR_ALL", Value: []byte("_STAR_ALL"),
+		Op: pb.DirectedEdge_DEL}}
+	deliveries = r.postCommitEvents(star, 11)
+	require.Len(t, deliveries, 3)
+	require.Equal(t, []*HookEdge{{Uid: "0x3", Predicate: "*", Value: "*"}},
+		deliveries[2].event.Delete)
+}
+
+func TestPreCommitHook(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		var event HookEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for _, edge := range event.Set {
+			if edge.Value == "admin" {
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte("name admin is reserved\n"))
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+
+	r, err := newHookRegistry(&MutationHooksConfig{Hooks: []*MutationHook{
+		{Name: "names", URL: srv.URL, Phase: PreCommitHook, Predicates: []string{"name"}},
+	}})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	name := func(val string) []*pb.DirectedEdge {
+		return []*pb.DirectedEdge{{Entity: 1, Attr: "name", Value: []byte(val)}}
+	}
+	require.NoError(t, r.preCommit(ctx, name("alice"), 5))
+	err = r.preCommit(ctx, name("admin"), 6)
+	require.Equal(t, &MutationRejectedError{
+		Hook:   "names",
+		Reason: "hook responded with status 403: name admin is reserved",
+	}, err)
+	require.EqualError(t, err,
+		"Mutation rejected by hook names: hook responded with status 403: name admin is reserved")
+
+	// The hook isn't called for mutations that don't touch its predicates.
+	require.NoError(t, r.preCommit(ctx, []*pb.DirectedEdge{{Entity: 1, Attr: "age",
+		Value: []byte("26")}}, 7))
+	require.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	// The mutation is rejected if the hook can't be reached.
+	srv.Close()
+	err = r.preCommit(ctx, name("alice"), 8)
+	require.IsType(t, &MutationRejectedError{}, err)
+}
+
+func TestPreCommitLambdaHook(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hooks")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	script := filepath.Join(dir, "lambda.js")
+	require.NoError(t, ioutil.WriteFile(script, []byte(`
+		self.addWebHookResolvers({
+			"names": function(e) {
+				e.event.set.forEach(function(edge) {
+					if (edge.value === "admin") {
+						throw new Error("name admin is reserved");
+					}
+				});
+			}
+		});`), 0600))
+	x.Config.GraphqlLambdaScript = script
+	defer func() { x.Config.GraphqlLambdaScript = "" }()
+	require.NoError(t, lambda.Init(lambda.Config{Script: script, Timeout: time.Second}, nil))
+
+	r, err := newHookRegistry(&MutationHooksConfig{Hooks: []*MutationHook{
+		{Lambda: "names", Phase: PreCommitHook, Predicates: []string{"name"}},
+	}})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	name := func(val string) []*pb.DirectedEdge {
+		return []*pb.DirectedEdge{{Entity: 1, Attr: "name", Value: []byte(val)}}
+	}
+	require.NoError(t, r.preCommit(ctx, name("alice"), 5))
+	err = r.preCommit(ctx, name("admin"), 6)
+	require.IsType(t, &MutationRejectedError{}, err)
+	require.Contains(t, err.Error(), "Mutation rejected by hook names: Error: name admin is reserved")
+}
+
+func TestPostCommitHookDeadLetter(t *testing.T) {
+	defer func(delay time.Duration) { hookRetryDelay = delay }(hookRetryDelay)
+	hookRetryDelay = time.Millisecond
+
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "hooks")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	deadLetter := filepath.Join(dir, "dead_letter.json")
+
+	r, err := newHookRegistry(&MutationHooksConfig{
+		DeadLetterFile: deadLetter,
+		Hooks: []*MutationHook{{Name: "audit", URL: srv.URL, Phase: PostCommitHook,
+			Predicates: []string{"name"}, Retries: hookRetries(2)}},
+	})
+	require.NoError(t, err)
+
+	edges := []*pb.DirectedEdge{{Entity: 1, Attr: "name", Value: []byte("alice"),
+		ValueType: pb.Posting_ValType(types.StringID)}}
+	for _, d := range r.postCommitEvents(edges, 5) {
+		d.event.CommitTs = 6
+		r.deliver(d)
+	}
+	require.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	data, err := ioutil.ReadFile(deadLetter)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 1)
+	var record struct {
+		Hook  string     `json:"hook"`
+		Error string     `json:"error"`
+		Event *HookEvent `json:"event"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+	require.Equal(t, "audit", record.Hook)
+	require.Equal(t, "hook responded with status 503", record.Error)
+	require.Equal(t, &HookEvent{
+		Hook:     "audit",
+		Phase:    PostCommitHook,
+		StartTs:  5,
+		CommitTs: 6,
+		Set:      []*HookEdge{{Uid: "0x1", Predicate: "name", Value: "alice"}},
+	}, record.Event)
+}
+
+func TestPostCommitHookPending(t *testing.T) {
+	r, err := newHookRegistry(&MutationHooksConfig{Hooks: []*MutationHook{
+		{Name: "audit", URL: "http://hook", Phase: PostCommitHook, Predicates: []string{"name"}},
+	}})
+	require.NoError(t, err)
+
+	name := func(uid uint64) []*pb.DirectedEdge {
+		return []*pb.DirectedEdge{{Entity: uid, Attr: "name", Value: []byte("alice")}}
+	}
+	r.addPending(5, r.postCommitEvents(name(1), 5))
+	r.addPending(5, r.postCommitEvents(name(2), 5))
+	r.addPending(7, r.postCommitEvents(name(3), 7))
+
+	// The mutations of a transaction are notified together once it's committed. The status of
+	// the transaction reaches every Alpha, so this one notifies the hooks even if the commit
+	// was sent to another one.
+	var l worker.TxnListener = r
+	l.Finished(5, 9)
+	require.Len(t, r.queue, 1)
+	d := <-r.queue
+	require.Equal(t, uint64(9), d.event.CommitTs)
+	require.Equal(t, []*HookEdge{
+		{Uid: "0x1", Predicate: "name", Value: "alice"},
+		{Uid: "0x2", Predicate: "name", Value: "alice"},
+	}, d.event.Set)
+
+	// Nothing is notified for aborted transactions.
+	l.Finished(7, 0)
+	require.Len(t, r.queue, 0)
+	require.Len(t, r.pending, 0)
+}
+
+func TestPostCommitHookSavepoint(t *testing.T) {
+	r, err := newHookRegistry(&MutationHooksConfig{Hooks: []*MutationHook{
+		{Name: "audit", URL: "http://hook", Phase: PostCommitHook, Predicates: []string{"name"}},
+	}})
+	require.NoError(t, err)
+
+	name := func(uid uint64) []*pb.DirectedEdge {
+		return []*pb.DirectedEdge{{Entity: uid, Attr: "name", Value: []byte("alice")}}
+	}
+	r.Savepoint(5, "empty")
+	r.addPending(5, r.postCommitEvents(name(1), 5))
+	r.Savepoint(5, "one")
+	r.addPending(5, r.postCommitEvents(name(2), 5))
+	r.Savepoint(5, "two")
+	r.addPending(5, r.postCommitEvents(name(3), 5))
+
+	// The notifications of the mutations after the savepoint are discarded, along with the
+	// savepoints created after it.
+	r.RollbackTo(5, "one")
+	r.RollbackTo(5, "two")
+	r.addPending(5, r.postCommitEvents(name(4), 5))
+	r.Finished(5, 9)
+	require.Len(t, r.queue, 1)
+	d := <-r.queue
+	require.Equal(t, []*HookEdge{
+		{Uid: "0x1", Predicate: "name", Value: "alice"},
+		{Uid: "0x4", Predicate: "name", Value: "alice"},
+	}, d.event.Set)
+
+	// The notifications of a transaction are all discarded by rolling back to a savepoint
+	// created before its first mutation.
+	r.Savepoint(7, "empty")
+	r.addPending(7, r.postCommitEvents(name(1), 7))
+	r.RollbackTo(7, "empty")
+	r.Finished(7, 8)
+	require.Len(t, r.queue, 0)
+	require.Len(t, r.pending, 0)
+}
@@ -0,0 +1,303 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"container/list"
+	"context"
+	"encoding/binary"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/worker"
+	"github.com/dgraph-io/dgraph/x"
+	farm "github.com/dgryski/go-farm"
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// maxIdempotencyKeyLen is the maximum length of an idempotency key.
+const maxIdempotencyKeyLen = 256
+
+// idempotencyEntry is the state of the commits made with an idempotency key.
+type idempotencyEntry struct {
+	// done is closed once the commit that first used the key finishes.
+	done chan struct{}
+	// fingerprint identifies the payload of the request that first used the key.
+	fingerprint uint64
+	// resp is the response of the commit, or nil if it's still running.
+	resp        *api.Response
+	committedAt time.Time
+}
+
+// idempotentRequest is a commit made with an idempotency key that hasn't gone through yet.
+type idempotentRequest struct {
+	key         string
+	fingerprint uint64
+	finish      func(*api.Response)
+}
+
+type idempotentRequestKey struct{}
+
+// idempotencyCache keeps the responses of the commits made with an idempotency key during
+// the retention window.
+type idempotencyCache struct {
+	sync.Mutex
+	entries map[string]*idempotencyEntry
+	// committed holds the keys of the entries with a response, from the oldest commit.
+	committed *list.List
+}
+
+var idempotency = newIdempotencyCache()
+
+func newIdempotencyCache() *idempotencyCache {
+	return &idempotencyCache{
+		entries:   make(map[string]*idempotencyEntry),
+		committed: list.New(),
+	}
+}
+
+// idempotencyKeyFromContext returns the idempotency key passed by the client as metadata, or
+// an empty string if there isn't any.
+func idempotencyKeyFromContext(ctx context.Context) (string, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", nil
+	}
+	vals := md.Get(x.IdempotencyKey)
+	if len(vals) == 0 {
+		return "", nil
+	}
+	if len(vals[0]) > maxIdempotencyKeyLen {
+		return "", errors.Errorf("idempotency key can't be longer than %d bytes",
+			maxIdempotencyKeyLen)
+	}
+	return vals[0], nil
+}
+
+// errIdempotencyKeyReused is returned when a request reuses the idempotency key of a different
+// one within the retention window.
+func errIdempotencyKeyReused(key string) error {
+	return x.InvalidArgument(errors.Errorf(
+		"idempotency key %q has already been used by a different request", key))
+}
+
+// idempotentCommit looks up the idempotency key of a commit whose payload has the given
+// fingerprint. If a commit with the same key went through within the retention window, on this
+// Alpha or on any other one, a copy of its response is returned and the commit shouldn't be
+// applied again. A key reused by a request with a different payload is rejected. Otherwise, the
+// returned request must be done once the commit is. Both are nil if the request doesn't have an
+// idempotency key.
+func idempotentCommit(ctx context.Context, fingerprint uint64) (
+	*api.Response, *idempotentRequest, error) {
+	if x.Config.IdempotencyWindow <= 0 {
+		return nil, nil, nil
+	}
+	key, err := idempotencyKeyFromContext(ctx)
+	if err != nil || key == "" {
+		return nil, nil, err
+	}
+	prev, finish, err := idempotency.begin(ctx, key, fingerprint, x.Config.IdempotencyWindow)
+	if err != nil || prev != nil {
+		return prev, nil, err
+	}
+
+	// The commit might have gone through on another Alpha, in which case Zero has its record.
+	rec, err := worker.IdempotencyOverNetwork(ctx, key)
+	if err != nil {
+		finish(nil)
+		return nil, nil, err
+	}
+	if rec.Key != "" {
+		prev, err := recordResponse(rec, fingerprint)
+		finish(prev)
+		return prev, nil, err
+	}
+	return nil, &idempotentRequest{key: key, fingerprint: fingerprint, finish: finish}, nil
+}
+
+// recordResponse returns the response of the commit recorded by Zero, or an error if the
+// payload of the commit differs from the one with the given fingerprint.
+func recordResponse(rec *pb.IdempotencyRecord, fingerprint uint64) (*api.Response, error) {
+	if rec.Fingerprint != fingerprint {
+		return nil, errIdempotencyKeyReused(rec.Key)
+	}
+	resp := &api.Response{}
+	if err := proto.Unmarshal(rec.Response, resp); err != nil {
+		return nil, errors.Wrapf(err, "while parsing response of idempotency key %q", rec.Key)
+	}
+	resp.Txn = &api.TxnContext{StartTs: rec.StartTs, CommitTs: rec.CommitTs}
+	return resp, nil
+}
+
+// withIdempotentRequest returns a copy of ctx that carries the request.
+func withIdempotentRequest(ctx context.Context, r *idempotentRequest) context.Context {
+	return context.WithValue(ctx, idempotentRequestKey{}, r)
+}
+
+// idempotentRequestFromContext returns the request carried by ctx, or nil if there isn't any.
+func idempotentRequestFromContext(ctx context.Context) *idempotentRequest {
+	r, _ := ctx.Value(idempotentRequestKey{}).(*idempotentRequest)
+	return r
+}
+
+// commitContext returns the context to commit the request with. It passes to Zero the record of
+// the commit, which Zero replicates along with the commit so that a retry sent to any Alpha
+// within the retention window gets resp instead of being applied again.
+func (r *idempotentRequest) commitContext(ctx context.Context, resp *api.Response) (
+	context.Context, error) {
+	data, err := proto.Marshal(&api.Response{Json: resp.Json, Uids: resp.Uids})
+	if err != nil {
+		return ctx, err
+	}
+	rec := &pb.IdempotencyRecord{
+		Key:         r.key,
+		Fingerprint: r.fingerprint,
+		ExpiresAt:   time.Now().Add(x.Config.IdempotencyWindow).UnixNano(),
+		Response:    data,
+	}
+	if data, err = rec.Marshal(); err != nil {
+		return ctx, err
+	}
+	return metadata.AppendToOutgoingContext(ctx, x.IdempotencyRecordKey, string(data)), nil
+}
+
+// done records the outcome of the commit, with its response if it succeeded. If Zero discarded
+// the commit because another one with the same key went through first, the response of that
+// one is returned instead.
+func (r *idempotentRequest) done(ctx context.Context, resp *api.Response, err error) (
+	*api.Response, error) {
+	if status.Code(errors.Cause(err)) == codes.AlreadyExists {
+		if rec, lerr := worker.IdempotencyOverNetwork(ctx, r.key); lerr == nil && rec.Key != "" {
+			resp, err = recordResponse(rec, r.fingerprint)
+		}
+	}
+	if err != nil {
+		r.finish(nil)
+		return nil, err
+	}
+	r.finish(resp)
+	return resp, nil
+}
+
+// requestFingerprint returns the fingerprint of the payload of a request that is committed
+// right away.
+func requestFingerprint(req *api.Request) uint64 {
+	var buf []byte
+	buf = appendChunk(buf, []byte(req.Query))
+	vars := make([]string, 0, len(req.Vars))
+	for k := range req.Vars {
+		vars = append(vars, k)
+	}
+	sort.Strings(vars)
+	for _, k := range vars {
+		buf = appendChunk(buf, []byte(k))
+		buf = appendChunk(buf, []byte(req.Vars[k]))
+	}
+	for _, mu := range req.Mutations {
+		data, _ := proto.Marshal(mu)
+		buf = appendChunk(buf, data)
+	}
+	return farm.Fingerprint64(buf)
+}
+
+// commitFingerprint returns the fingerprint of the commit of the txn with the given startTs.
+func commitFingerprint(startTs uint64) uint64 {
+	var buf [9]byte
+	buf[0] = 'c'
+	binary.BigEndian.PutUint64(buf[1:], startTs)
+	return farm.Fingerprint64(buf[:])
+}
+
+// appendChunk appends data to buf, prefixed by its length.
+func appendChunk(buf, data []byte) []byte {
+	var l [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(l[:], uint64(len(data)))
+	return append(append(buf, l[:n]...), data...)
+}
+
+// begin returns the response of the commit made with the key, waiting for it if it's still
+// running. If there isn't any, the caller becomes the one committing with the key. A key used by
+// a request with a different fingerprint is rejected.
+func (c *idempotencyCache) begin(ctx context.Context, key string, fingerprint uint64,
+	window time.Duration) (*api.Response, func(*api.Response), error) {
+	for {
+		c.Lock()
+		c.expire(window)
+		e, ok := c.entries[key]
+		if !ok {
+			e = &idempotencyEntry{done: make(chan struct{}), fingerprint: fingerprint}
+			c.entries[key] = e
+			c.Unlock()
+			return nil, func(resp *api.Response) { c.finish(key, e, resp) }, nil
+		}
+		if e.resp != nil {
+			if e.fingerprint != fingerprint {
+				c.Unlock()
+				return nil, nil, errIdempotencyKeyReused(key)
+			}
+			resp := proto.Clone(e.resp).(*api.Response)
+			c.Unlock()
+			return resp, nil, nil
+		}
+		c.Unlock()
+
+		// A commit with the same key is running. Its outcome decides what happens to this one.
+		select {
+		case <-e.done:
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+}
+
+// finish records the response of the commit made with the key. If the commit failed, the key
+// is released so that the next commit with it is applied.
+func (c *idempotencyCache) finish(key string, e *idempotencyEntry, resp *api.Response) {
+	c.Lock()
+	defer c.Unlock()
+
+	if resp == nil {
+		delete(c.entries, key)
+	} else {
+		e.resp = proto.Clone(resp).(*api.Response)
+		e.committedAt = time.Now()
+		c.committed.PushBack(key)
+	}
+	close(e.done)
+}
+
+// expire removes the entries committed before the retention window. It must be called with
+// the lock held.
+func (c *idempotencyCache) expire(window time.Duration) {
+	for front := c.committed.Front(); front != nil; front = c.committed.Front() {
+		key := front.Value.(string)
+		if e, ok := c.entries[key]; ok && e.resp != nil {
+			if time.Since(e.committedAt) < window {
+				return
+			}
+			delete(c.entries, key)
+		}
+		c.committed.Remove(front)
+	}
+}
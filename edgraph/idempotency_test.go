@@ -0,0 +1,160 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestIdempotencyCache(t *testing.T) {
+	c := newIdempotencyCache()
+	ctx := context.Background()
+
+	prev, finish, err := c.begin(ctx, "a", 1, time.Minute)
+	require.NoError(t, err)
+	require.Nil(t, prev)
+	finish(&api.Response{Txn: &api.TxnContext{StartTs: 5, CommitTs: 6}})
+
+	// Retries get the response of the original commit.
+	prev, finish, err = c.begin(ctx, "a", 1, time.Minute)
+	require.NoError(t, err)
+	require.Nil(t, finish)
+	require.Equal(t, uint64(6), prev.Txn.CommitTs)
+
+	// A failed commit releases its key.
+	_, finish, err = c.begin(ctx, "b", 1, time.Minute)
+	require.NoError(t, err)
+	finish(nil)
+	prev, finish, err = c.begin(ctx, "b", 1, time.Minute)
+	require.NoError(t, err)
+	require.Nil(t, prev)
+	require.NotNil(t, finish)
+
+	// A commit made while another one with the same key is running waits for its outcome.
+	got := make(chan *api.Response)
+	go func() {
+		prev, _, err := c.begin(ctx, "b", 1, time.Minute)
+		require.NoError(t, err)
+		got <- prev
+	}()
+	select {
+	case <-got:
+		t.Fatal("commit with a running key didn't wait")
+	case <-time.After(50 * time.Millisecond):
+	}
+	finish(&api.Response{Txn: &api.TxnContext{StartTs: 7, CommitTs: 8}})
+	require.Equal(t, uint64(8), (<-got).Txn.CommitTs)
+
+	// A key can't be reused by a request with a different payload.
+	_, _, err = c.begin(ctx, "a", 2, time.Minute)
+	require.EqualError(t, err, `idempotency key "a" has already been used by a different request`)
+	require.True(t, x.IsInvalidArgument(err))
+
+	// The responses are forgotten after the retention window.
+	prev, finish, err = c.begin(ctx, "a", 1, 0)
+	require.NoError(t, err)
+	require.Nil(t, prev)
+	require.NotNil(t, finish)
+	require.Len(t, c.entries, 1)
+}
+
+func TestIdempotencyFingerprint(t *testing.T) {
+	req := &api.Request{
+		Query:     `query q($a: string) { q(func: eq(name, $a)) { v as uid } }`,
+		Vars:      map[string]string{"$a": "alice", "$b": "bob"},
+		Mutations: []*api.Mutation{{SetNquads: []byte(`uid(v) <age> "30" .`)}},
+		CommitNow: true,
+	}
+	fp := requestFingerprint(req)
+
+	// The fingerprint doesn't depend on the order of the variables or on the txn.
+	same := proto.Clone(req).(*api.Request)
+	same.Vars = map[string]string{"$b": "bob", "$a": "alice"}
+	same.StartTs = 10
+	require.Equal(t, fp, requestFingerprint(same))
+
+	other := proto.Clone(req).(*api.Request)
+	other.Mutations[0].SetNquads = []byte(`uid(v) <age> "31" .`)
+	require.NotEqual(t, fp, requestFingerprint(other))
+	other = proto.Clone(req).(*api.Request)
+	other.Vars["$a"] = "alicebob"
+	other.Vars["$b"] = ""
+	require.NotEqual(t, fp, requestFingerprint(other))
+
+	require.Equal(t, commitFingerprint(5), commitFingerprint(5))
+	require.NotEqual(t, commitFingerprint(5), commitFingerprint(6))
+}
+
+func TestIdempotencyRecordResponse(t *testing.T) {
+	data, err := proto.Marshal(&api.Response{Uids: map[string]string{"a": "0x1"}})
+	require.NoError(t, err)
+	rec := &pb.IdempotencyRecord{
+		Key:         "k",
+		Fingerprint: 1,
+		StartTs:     5,
+		CommitTs:    6,
+		Response:    data,
+	}
+	resp, err := recordResponse(rec, 1)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"a": "0x1"}, resp.Uids)
+	require.Equal(t, &api.TxnContext{StartTs: 5, CommitTs: 6}, resp.Txn)
+
+	_, err = recordResponse(rec, 2)
+	require.True(t, x.IsInvalidArgument(err))
+
+	// The record passed to Zero along with the commit.
+	r := &idempotentRequest{key: "k", fingerprint: 1}
+	ctx, err := r.commitContext(context.Background(), &api.Response{Uids: resp.Uids})
+	require.NoError(t, err)
+	md, ok := metadata.FromOutgoingContext(ctx)
+	require.True(t, ok)
+	vals := md.Get(x.IdempotencyRecordKey)
+	require.Len(t, vals, 1)
+	var got pb.IdempotencyRecord
+	require.NoError(t, got.Unmarshal([]byte(vals[0])))
+	require.Equal(t, "k", got.Key)
+	require.Equal(t, uint64(1), got.Fingerprint)
+	require.Equal(t, data, got.Response)
+}
+
+func TestIdempotencyKeyFromContext(t *testing.T) {
+	key, err := idempotencyKeyFromContext(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, key)
+
+	ctx := metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs(x.IdempotencyKey, "order-1"))
+	key, err = idempotencyKeyFromContext(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "order-1", key)
+
+	ctx = metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs(x.IdempotencyKey, strings.Repeat("k", maxIdempotencyKeyLen+1)))
+	_, err = idempotencyKeyFromContext(ctx)
+	require.EqualError(t, err, "idempotency key can't be longer than 256 bytes")
+}
@@ -0,0 +1,142 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/dgraph-io/dgraph/gql"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// queryLimits contains the limits that apply to a single query request.
+type queryLimits struct {
+	// timeout is the maximum execution time of the request.
+	timeout time.Duration
+	// maxNodes is the maximum number of nodes and edges the query can return.
+	maxNodes uint64
+	// maxDepth is the maximum traversal depth of the query.
+	maxDepth uint64
+}
+
+// boundLimit returns the limit requested by the client, capped to the server maximum. A zero
+// value means no limit, so the server maximum applies if the client didn't ask for one.
+func boundLimit(requested, max uint64) uint64 {
+	if max == 0 {
+		return requested
+	}
+	if requested == 0 || requested > max {
+		return max
+	}
+	return requested
+}
+
+// getQueryLimits reads the per-request limit overrides from the gRPC metadata (HTTP headers
+// are attached to it by the HTTP handlers) and bounds them by the server maximums.
+func getQueryLimits(ctx context.Context) (queryLimits, error) {
+	var timeout time.Duration
+	var maxNodes, maxDepth uint64
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if vals := md.Get(x.QueryTimeoutKey); len(vals) > 0 {
+			d, err := time.ParseDuration(vals[0])
+			if err != nil || d < 0 {
+				return queryLimits{}, errors.Errorf("Invalid value for %s: %q",
+					x.QueryTimeoutKey, vals[0])
+			}
+			timeout = d
+		}
+		parse := func(key string) (uint64, error) {
+			vals := md.Get(key)
+			if len(vals) == 0 {
+				return 0, nil
+			}
+			v, err := strconv.ParseUint(vals[0], 10, 64)
+			if err != nil {
+				return 0, errors.Errorf("Invalid value for %s: %q", key, vals[0])
+			}
+			return v, nil
+		}
+		var err error
+		if maxNodes, err = parse(x.QueryMaxNodesKey); err != nil {
+			return queryLimits{}, err
+		}
+		if maxDepth, err = parse(x.QueryMaxDepthKey); err != nil {
+			return queryLimits{}, err
+		}
+	}
+
+	return queryLimits{
+		timeout: time.Duration(boundLimit(uint64(timeout),
+			uint64(x.Config.QueryTimeoutLimit))),
+		maxNodes: boundLimit(maxNodes, x.Config.QueryNodeLimit),
+		maxDepth: boundLimit(maxDepth, x.Config.QueryDepthLimit),
+	}, nil
+}
+
+// queryDepth returns the traversal depth of the given query block, that is the number of uid
+// edges that have to be followed from its root. Scalar predicates are leaves and don't count
+// as a level. @recurse and shortest path blocks without a depth are unbounded.
+func queryDepth(gq *gql.GraphQuery) uint64 {
+	switch {
+	case gq.Recurse:
+		if gq.RecurseArgs.Depth == 0 {
+			return math.MaxUint64
+		}
+		return gq.RecurseArgs.Depth
+	case gq.Alias == "shortest":
+		depth, err := strconv.ParseUint(gq.Args["depth"], 0, 64)
+		if err != nil || depth == 0 {
+			return math.MaxUint64
+		}
+		return depth
+	}
+
+	var depth uint64
+	for _, child := range gq.Children {
+		if len(child.Children) == 0 {
+			continue
+		}
+		d := queryDepth(child)
+		if d == math.MaxUint64 {
+			return d
+		}
+		if d+1 > depth {
+			depth = d + 1
+		}
+	}
+	return depth
+}
+
+// checkQueryDepth returns an error if any of the query blocks is deeper than maxDepth.
+func checkQueryDepth(queries []*gql.GraphQuery, maxDepth uint64) error {
+	if maxDepth == 0 {
+		return nil
+	}
+	for _, gq := range queries {
+		if gq != nil && queryDepth(gq) > maxDepth {
+			return &x.LimitExceededError{Limit: x.LimitMaxDepth, Max: maxDepth}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/dgraph-io/dgraph/gql"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+func TestGetQueryLimits(t *testing.T) {
+	defer func(old x.Options) { x.Config = old }(x.Config)
+	x.Config.QueryTimeoutLimit = time.Second
+	x.Config.QueryNodeLimit = 100
+	x.Config.QueryDepthLimit = 0
+
+	// Server maximums apply when the client doesn't ask for anything.
+	limits, err := getQueryLimits(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, queryLimits{timeout: time.Second, maxNodes: 100}, limits)
+
+	// Client overrides are capped to the server maximums.
+	md := metadata.Pairs(x.QueryTimeoutKey, "100ms", x.QueryMaxNodesKey, "1000",
+		x.QueryMaxDepthKey, "3")
+	limits, err = getQueryLimits(metadata.NewIncomingContext(context.Background(), md))
+	require.NoError(t, err)
+	require.Equal(t, queryLimits{timeout: 100 * time.Millisecond, maxNodes: 100, maxDepth: 3},
+		limits)
+
+	md = metadata.Pairs(x.QueryMaxDepthKey, "three")
+	_, err = getQueryLimits(metadata.NewIncomingContext(context.Background(), md))
+	require.Contains(t, err.Error(), "Invalid value for query-max-depth")
+}
+
+func TestCheckQueryDepth(t *testing.T) {
+	res, err := gql.Parse(gql.Request{Str: `{
+		me(func: uid(0x1)) {
+			name
+			friend {
+				name
+				friend {
+					name
+				}
+			}
+		}
+	}`})
+	require.NoError(t, err)
+	require.NoError(t, checkQueryDepth(res.Query, 0))
+	require.NoError(t, checkQueryDepth(res.Query, 2))
+	err = checkQueryDepth(res.Query, 1)
+	require.Equal(t, &x.LimitExceededError{Limit: x.LimitMaxDepth, Max: 1}, err)
+
+	// Scalar predicates don't count as a level.
+	res, err = gql.Parse(gql.Request{Str: `{
+		me(func: uid(0x1)) {
+			name
+			age
+		}
+	}`})
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), queryDepth(res.Query[0]))
+
+	res, err = gql.Parse(gql.Request{Str: `{
+		me(func: uid(0x1)) @recurse {
+			friend
+		}
+	}`})
+	require.NoError(t, err)
+	require.Error(t, checkQueryDepth(res.Query, 10))
+
+	res, err = gql.Parse(gql.Request{Str: `{
+		me(func: uid(0x1)) @recurse(depth: 5) {
+			friend
+		}
+	}`})
+	require.NoError(t, err)
+	require.NoError(t, checkQueryDepth(res.Query, 5))
+	require.Error(t, checkQueryDepth(res.Query, 4))
+
+	res, err = gql.Parse(gql.Request{Str: `{
+		path as shortest(from: 0x1, to: 0x2) {
+			friend
+		}
+		path(func: uid(path)) {
+			name
+		}
+	}`})
+	require.NoError(t, err)
+	require.Error(t, checkQueryDepth(res.Query, 10))
+
+	res, err = gql.Parse(gql.Request{Str: `{
+		shortest(from: 0x1, to: 0x2, depth: 3) {
+			friend
+		}
+	}`})
+	require.NoError(t, err)
+	require.NoError(t, checkQueryDepth(res.Query, 3))
+	require.Error(t, checkQueryDepth(res.Query, 2))
+}
+
+func TestEstimateQueryCost(t *testing.T) {
+	res, err := gql.Parse(gql.Request{Str: `{
+		me(func: uid(0x1, 0x2)) @filter(has(name)) {
+			name
+			friend(first: 5) {
+				name
+				age
+			}
+		}
+	}`})
+	require.NoError(t, err)
+	// Root: 2 uids x (func + filter). Level 1: 2 uids x 2 preds. Level 2: 5 uids x 2 preds.
+	require.Equal(t, uint64(2*2+2*2+5*2), estimateQueryCost(context.Background(), res.Query[0]))
+
+	res, err = gql.Parse(gql.Request{Str: `{
+		me(func: has(name), first: 10) {
+			name
+		}
+	}`})
+	require.NoError(t, err)
+	require.Equal(t, uint64(defaultRootUids+10), estimateQueryCost(context.Background(), res.Query[0]))
+
+	res, err = gql.Parse(gql.Request{Str: `{
+		me(func: has(name), sample: 20, first: 50) {
+			name
+		}
+	}`})
+	require.NoError(t, err)
+	require.Equal(t, uint64(defaultRootUids+20), estimateQueryCost(context.Background(), res.Query[0]))
+
+	res, err = gql.Parse(gql.Request{Str: `{
+		me(func: union(uid(0x1, 0x2), has(name)), first: 10) {
+			name
+		}
+	}`})
+	require.NoError(t, err)
+	// Root: 2 uids for uid() plus the default for has(). Level 1: 10 uids x 1 pred.
+	require.Equal(t, uint64(2+defaultRootUids+10), estimateQueryCost(context.Background(), res.Query[0]))
+
+	res, err = gql.Parse(gql.Request{Str: `{
+		me(func: has(name, age), first: 10) @filter(hasall(friend, alias)) {
+			name
+		}
+	}`})
+	require.NoError(t, err)
+	// Root: the default for each predicate of has() x (func + 2 filter preds). Level 1: 10 uids.
+	require.Equal(t, uint64(2*defaultRootUids*3+10), estimateQueryCost(context.Background(), res.Query[0]))
+}
+
+func TestAdmitQuery(t *testing.T) {
+	defer func(old x.Options) { x.Config = old }(x.Config)
+	res, err := gql.Parse(gql.Request{Str: `{ me(func: has(name)) { name } }`})
+	require.NoError(t, err)
+
+	x.Config.QueryCostBudget = 0
+	done, err := admitQuery(context.Background(), res.Query)
+	require.NoError(t, err)
+	done()
+
+	x.Config.QueryCostBudget = 10
+	_, err = admitQuery(context.Background(), res.Query)
+	require.Equal(t, &x.LimitExceededError{Limit: x.LimitCost, Max: 10}, err)
+
+	x.Config.QueryCostQueue = 1
+	done, err = admitQuery(context.Background(), res.Query)
+	require.NoError(t, err)
+	// The queue is full, so the next query waits until its context is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = admitQuery(ctx, res.Query)
+	require.Equal(t, context.DeadlineExceeded, err)
+	done()
+}
+
+func TestAdmitQueryStats(t *testing.T) {
+	defer func(old x.Options) { x.Config = old }(x.Config)
+	oldKeys, oldEq := tabletKeys, eqUids
+	defer func() { tabletKeys, eqUids = oldKeys, oldEq }()
+	// The name predicate has 100M nodes, and its index has 3 of them under "alice".
+	tabletKeys = func(attr string) (uint64, bool) {
+		if attr == "name" {
+			return 100000000, true
+		}
+		return 0, false
+	}
+	eqUids = func(_ context.Context, attr string, vals []string) (uint64, bool) {
+		if attr == "name" && len(vals) == 1 && vals[0] == "alice" {
+			return 3, true
+		}
+		return 0, false
+	}
+	x.Config.QueryCostBudget = 100000
+	x.Config.QueryCostQueue = 0
+
+	has, err := gql.Parse(gql.Request{Str: `{ me(func: has(name), first: 10) { name } }`})
+	require.NoError(t, err)
+	require.Equal(t, uint64(100000000+10), estimateQueryCost(context.Background(), has.Query[0]))
+	_, err = admitQuery(context.Background(), has.Query)
+	require.Equal(t, &x.LimitExceededError{Limit: x.LimitCost, Max: 100000}, err)
+
+	eq, err := gql.Parse(gql.Request{Str: `{ me(func: eq(name, "alice")) { name friend { name } } }`})
+	require.NoError(t, err)
+	// Root: 3 uids. Level 1: 3 uids x 2 preds. Level 2: 3 uids x 10 edges x 1 pred.
+	require.Equal(t, uint64(3+3*2+3*defaultFanout), estimateQueryCost(context.Background(),
+		eq.Query[0]))
+	done, err := admitQuery(context.Background(), eq.Query)
+	require.NoError(t, err)
+	done()
+
+	// Without statistics, the root function is estimated at the default.
+	age, err := gql.Parse(gql.Request{Str: `{ me(func: has(age)) { age } }`})
+	require.NoError(t, err)
+	require.Equal(t, uint64(2*defaultRootUids), estimateQueryCost(context.Background(),
+		age.Query[0]))
+}
@@ -0,0 +1,213 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/graphql/schema"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/pkg/errors"
+)
+
+// errPersistedQueryNotFound is the error that Apollo clients expect when the hash of a persisted
+// query isn't known, so that they send the hash again along with the query.
+var errPersistedQueryNotFound = errors.New("PersistedQueryNotFound")
+
+// persistedQueryCache keeps the most recently used persisted queries of this Alpha in memory.
+// All the persisted queries are stored in Dgraph, so that every Alpha can serve them. As a query
+// is identified by the hash of its text, the cached entries never go stale.
+type persistedQueryCache struct {
+	sync.Mutex
+	queries map[string]*list.Element
+	// lru holds the hashes of the queries, from the most recently used one.
+	lru *list.List
+}
+
+type persistedQueryEntry struct {
+	hash  string
+	query string
+}
+
+var persistedQueries = &persistedQueryCache{
+	queries: make(map[string]*list.Element),
+	lru:     list.New(),
+}
+
+func (c *persistedQueryCache) get(hash string) (string, bool) {
+	c.Lock()
+	defer c.Unlock()
+	elem, ok := c.queries[hash]
+	if !ok {
+		return "", false
+	}
+	c.lru.MoveToFront(elem)
+	return elem.Value.(*persistedQueryEntry).query, true
+}
+
+func (c *persistedQueryCache) add(hash, query string, size int) {
+	if size <= 0 {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	if elem, ok := c.queries[hash]; ok {
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.queries[hash] = c.lru.PushFront(&persistedQueryEntry{hash: hash, query: query})
+	for c.lru.Len() > size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.queries, oldest.Value.(*persistedQueryEntry).hash)
+	}
+}
+
+// ProcessPersistedQuery handles the persistedQuery extension of a GraphQL request. If the
+// request has only the hash of a persisted query, the query is filled in from the stored one. If
+// it has both, the query is stored under the hash so that later requests can send just the hash.
+func ProcessPersistedQuery(ctx context.Context, gqlReq *schema.Request) error {
+	hash := gqlReq.Extensions.PersistedQuery.Sha256Hash
+	if hash == "" {
+		if x.Config.GraphqlPersistedQueriesOnly {
+			return errors.New("Only persisted queries are allowed.")
+		}
+		return nil
+	}
+
+	query, err := getPersistedQuery(ctx, hash)
+	if err != nil {
+		return err
+	}
+
+	if gqlReq.Query != "" && !hashMatches(gqlReq.Query, hash) {
+		return errors.New("provided sha does not match query")
+	}
+	if query != "" {
+		gqlReq.Query = query
+		return nil
+	}
+
+	if gqlReq.Query == "" {
+		return errPersistedQueryNotFound
+	}
+	if x.Config.GraphqlPersistedQueriesOnly {
+		return errors.Errorf("Persisted query %s hasn't been added, and only persisted queries "+
+			"are allowed.", hash)
+	}
+	return storePersistedQuery(ctx, hash, gqlReq.Query)
+}
+
+// AddPersistedQuery stores query as a persisted query, and returns its hash.
+func AddPersistedQuery(ctx context.Context, query string) (string, error) {
+	hash := sha256Hash(query)
+	return hash, storePersistedQuery(ctx, hash, query)
+}
+
+func sha256Hash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
+
+func hashMatches(query, hash string) bool {
+	return sha256Hash(query) == hash
+}
+
+// getPersistedQuery returns the persisted query with the given hash, or an empty string if there
+// isn't any.
+func getPersistedQuery(ctx context.Context, hash string) (string, error) {
+	if query, ok := persistedQueries.get(hash); ok {
+		return query, nil
+	}
+
+	req := &api.Request{
+		Query: `query PersistedQuery($hash: string) {
+			me(func: eq(dgraph.graphql.p_sha256hash, $hash)) {
+				dgraph.graphql.p_query
+			}
+		}`,
+		Vars:     map[string]string{"$hash": hash},
+		ReadOnly: true,
+	}
+	res, err := (&Server{}).doQuery(context.WithValue(ctx, IsGraphql, true), req, NoAuthorize)
+	if err != nil {
+		return "", errors.Wrapf(err, "while reading persisted query %s", hash)
+	}
+
+	type persistedQueryResponse struct {
+		Me []struct {
+			Query string `json:"dgraph.graphql.p_query"`
+		} `json:"me"`
+	}
+	pqRes := &persistedQueryResponse{}
+	if err := json.Unmarshal(res.Json, pqRes); err != nil {
+		return "", err
+	}
+	if len(pqRes.Me) == 0 {
+		return "", nil
+	}
+
+	query := pqRes.Me[0].Query
+	persistedQueries.add(hash, query, x.Config.GraphqlPersistedQueryCacheSize)
+	return query, nil
+}
+
+// storePersistedQuery stores query under hash, unless a query is already stored under it.
+func storePersistedQuery(ctx context.Context, hash, query string) error {
+	req := &api.Request{
+		Query: `query PersistedQuery($hash: string) {
+			pq as var(func: eq(dgraph.graphql.p_sha256hash, $hash))
+		}`,
+		Vars: map[string]string{"$hash": hash},
+		Mutations: []*api.Mutation{
+			{
+				Set: []*api.NQuad{
+					{
+						Subject:     "_:a",
+						Predicate:   "dgraph.graphql.p_query",
+						ObjectValue: &api.Value{Val: &api.Value_StrVal{StrVal: query}},
+					},
+					{
+						Subject:     "_:a",
+						Predicate:   "dgraph.graphql.p_sha256hash",
+						ObjectValue: &api.Value{Val: &api.Value_StrVal{StrVal: hash}},
+					},
+					{
+						Subject:   "_:a",
+						Predicate: "dgraph.type",
+						ObjectValue: &api.Value{Val: &api.Value_StrVal{
+							StrVal: "dgraph.graphql.persisted_query"}},
+					},
+				},
+				Cond: `@if(eq(len(pq), 0))`,
+			},
+		},
+		CommitNow: true,
+	}
+	if _, err := (&Server{}).doQuery(context.WithValue(ctx, IsGraphql, true), req,
+		NoAuthorize); err != nil {
+		return errors.Wrapf(err, "while storing persisted query %s", hash)
+	}
+	persistedQueries.add(hash, query, x.Config.GraphqlPersistedQueryCacheSize)
+	return nil
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"container/list"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dgraph-io/dgraph/graphql/schema"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+func TestPersistedQueryCache(t *testing.T) {
+	c := &persistedQueryCache{queries: make(map[string]*list.Element), lru: list.New()}
+	c.add("a", "query a", 2)
+	c.add("b", "query b", 2)
+
+	// Reading a makes b the least recently used entry, so adding c evicts b.
+	q, ok := c.get("a")
+	require.True(t, ok)
+	require.Equal(t, "query a", q)
+	c.add("c", "query c", 2)
+
+	_, ok = c.get("b")
+	require.False(t, ok)
+	_, ok = c.get("a")
+	require.True(t, ok)
+	_, ok = c.get("c")
+	require.True(t, ok)
+
+	// A non-positive size disables the cache.
+	c.add("d", "query d", 0)
+	_, ok = c.get("d")
+	require.False(t, ok)
+}
+
+func TestProcessPersistedQuery(t *testing.T) {
+	defer func(old x.Options) { x.Config = old }(x.Config)
+	query := "query { queryPost { title } }"
+	hash := sha256Hash(query)
+	persistedQueries.add(hash, query, 10)
+
+	withHash := func(q, h string) *schema.Request {
+		req := &schema.Request{Query: q}
+		req.Extensions.PersistedQuery.Sha256Hash = h
+		return req
+	}
+
+	// The stored query is filled in when only the hash is sent.
+	req := withHash("", hash)
+	require.NoError(t, ProcessPersistedQuery(context.Background(), req))
+	require.Equal(t, query, req.Query)
+
+	err := ProcessPersistedQuery(context.Background(), withHash("query { q }", hash))
+	require.EqualError(t, err, "provided sha does not match query")
+
+	// Requests without a hash pass through, unless only persisted queries are allowed.
+	require.NoError(t, ProcessPersistedQuery(context.Background(), withHash(query, "")))
+	x.Config.GraphqlPersistedQueriesOnly = true
+	err = ProcessPersistedQuery(context.Background(), withHash(query, ""))
+	require.EqualError(t, err, "Only persisted queries are allowed.")
+}
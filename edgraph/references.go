@@ -0,0 +1,362 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/worker"
+	"github.com/dgraph-io/dgraph/x"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ReferenceError is returned when a mutation breaks the referential integrity of a predicate
+// with @references: either it sets an edge of the predicate pointing to a node that doesn't
+// exist, or it deletes a node that edges of a predicate with @references(ondelete: restrict)
+// still point to.
+type ReferenceError struct {
+	Uid       uint64
+	Predicate string
+	// Referrers are the nodes whose edges point to the deleted node, if it's being deleted.
+	Referrers []uint64
+}
+
+func (e *ReferenceError) Error() string {
+	if len(e.Referrers) == 0 {
+		return fmt.Sprintf("Node %#x pointed to by predicate %s doesn't exist", e.Uid,
+			e.Predicate)
+	}
+	return fmt.Sprintf("Node %#x can't be deleted, as the edges of predicate %s of nodes [%s]"+
+		" point to it", e.Uid, e.Predicate, strings.Join(e.referrers(), " "))
+}
+
+func (e *ReferenceError) referrers() []string {
+	referrers := make([]string, 0, len(e.Referrers))
+	for _, uid := range e.Referrers {
+		referrers = append(referrers, fmt.Sprintf("%#x", uid))
+	}
+	return referrers
+}
+
+// GRPCStatus lets gRPC report the error to clients with the FailedPrecondition code.
+func (e *ReferenceError) GRPCStatus() *status.Status {
+	return status.New(codes.FailedPrecondition, e.Error())
+}
+
+// Extensions returns the fields to be reported in the extensions of an HTTP error.
+func (e *ReferenceError) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"code":      x.ErrorReference,
+		"uid":       fmt.Sprintf("%#x", e.Uid),
+		"predicate": e.Predicate,
+		"referrers": e.referrers(),
+	}
+}
+
+// referencePredicates returns what's done on delete for the predicates with @references among
+// the given ones, or among all the predicates if none are given. The schema of the predicates
+// served by this Alpha is known locally, and the one of the other predicates is kept by the
+// groups serving them.
+func referencePredicates(ctx context.Context, preds []string) (map[string]string, error) {
+	refs := make(map[string]string)
+	var remote []string
+	for _, pred := range preds {
+		su, ok := schema.State().Get(ctx, pred)
+		switch {
+		case !ok:
+			remote = append(remote, pred)
+		case su.References != "":
+			refs[pred] = su.References
+		}
+	}
+	if len(preds) > 0 && len(remote) == 0 {
+		return refs, nil
+	}
+	nodes, err := worker.GetSchemaOverNetwork(ctx, &pb.SchemaRequest{
+		Predicates: remote,
+		Fields:     []string{"references"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, node := range nodes {
+		if node.References != "" {
+			refs[node.Predicate] = node.References
+		}
+	}
+	return refs, nil
+}
+
+// referenceChanges is what a mutation does to the nodes that edges of predicates with
+// @references may point to.
+type referenceChanges struct {
+	// typed are the nodes the mutation gives a type to.
+	typed map[uint64]bool
+	// deleted are the nodes the mutation deletes, with the edge deleting each of them.
+	deleted map[uint64]*pb.DirectedEdge
+	// unlinked are the edges the mutation deletes, by node and predicate. A nil set means all
+	// the edges of the predicate of the node are deleted.
+	unlinked map[uint64]map[string]map[uint64]bool
+}
+
+func newReferenceChanges(edges []*pb.DirectedEdge) *referenceChanges {
+	c := &referenceChanges{
+		typed:    make(map[uint64]bool),
+		deleted:  make(map[uint64]*pb.DirectedEdge),
+		unlinked: make(map[uint64]map[string]map[uint64]bool),
+	}
+	for _, edge := range edges {
+		star := bytes.Equal(edge.Value, []byte(x.Star))
+		switch {
+		case edge.Op == pb.DirectedEdge_SET && edge.Attr == "dgraph.type":
+			c.typed[edge.Entity] = true
+		case edge.Op != pb.DirectedEdge_DEL || edge.Entity == 0:
+		case edge.Attr == x.Star || (edge.Attr == "dgraph.type" && star):
+			c.deleted[edge.Entity] = edge
+		case star:
+			c.unlink(edge.Entity, edge.Attr, 0)
+		case edge.ValueId != 0:
+			c.unlink(edge.Entity, edge.Attr, edge.ValueId)
+		}
+	}
+	// A node deleted and given a type again by the same mutation still exists.
+	for uid := range c.typed {
+		delete(c.deleted, uid)
+	}
+	return c
+}
+
+// unlink records the deletion of the edge of the predicate from the node to dst, or of all the
+// edges of the predicate of the node if dst is 0.
+func (c *referenceChanges) unlink(uid uint64, pred string, dst uint64) {
+	preds, ok := c.unlinked[uid]
+	if !ok {
+		preds = make(map[string]map[uint64]bool)
+		c.unlinked[uid] = preds
+	}
+	dsts, ok := preds[pred]
+	switch {
+	case ok && dsts == nil:
+	case dst == 0:
+		preds[pred] = nil
+	case !ok:
+		preds[pred] = map[uint64]bool{dst: true}
+	default:
+		dsts[dst] = true
+	}
+}
+
+// isUnlinked returns whether the mutation deletes the edge of the predicate from the node to
+// dst.
+func (c *referenceChanges) isUnlinked(uid uint64, pred string, dst uint64) bool {
+	dsts, ok := c.unlinked[uid][pred]
+	return ok && (dsts == nil || dsts[dst])
+}
+
+// applyReferences enforces the referential integrity of the predicates with @references. The
+// edges pointing to the nodes deleted by the mutation are handled as given by the predicates:
+// the deletion is rejected for restrict, the nodes the edges are from are deleted too for
+// cascade, and the edges are deleted for setnull. The edges the mutation sets must then point to
+// nodes that exist, which are the nodes with a type. The stored edges and types are read as of
+// the start of the transaction.
+func applyReferences(ctx context.Context, edges []*pb.DirectedEdge,
+	startTs uint64) ([]*pb.DirectedEdge, error) {
+
+	predSet := make(map[string]struct{})
+	for _, edge := range edges {
+		if edge.Op == pb.DirectedEdge_SET && edge.ValueId != 0 {
+			predSet[edge.Attr] = struct{}{}
+		}
+	}
+	changes := newReferenceChanges(edges)
+	if len(predSet) == 0 && len(changes.deleted) == 0 {
+		return edges, nil
+	}
+	var preds []string
+	// All the predicates with @references may point to the deleted nodes.
+	if len(changes.deleted) == 0 {
+		for pred := range predSet {
+			preds = append(preds, pred)
+		}
+		sort.Strings(preds)
+	}
+	refs, err := referencePredicates(ctx, preds)
+	if err != nil || len(refs) == 0 {
+		return edges, err
+	}
+
+	edges, err = applyDeletes(ctx, edges, changes, refs, startTs)
+	if err != nil {
+		return edges, err
+	}
+	return edges, checkReferences(ctx, edges, changes, refs, startTs)
+}
+
+// applyDeletes handles the edges of the predicates with @references pointing to the nodes deleted
+// by the mutation. The nodes deleted by cascade are handled in turn.
+func applyDeletes(ctx context.Context, edges []*pb.DirectedEdge, changes *referenceChanges,
+	refs map[string]string, startTs uint64) ([]*pb.DirectedEdge, error) {
+
+	refPreds := make([]string, 0, len(refs))
+	for pred := range refs {
+		refPreds = append(refPreds, pred)
+	}
+	sort.Strings(refPreds)
+
+	pending := make([]uint64, 0, len(changes.deleted))
+	for uid := range changes.deleted {
+		pending = append(pending, uid)
+	}
+	// The deletions restricted by a predicate are checked once all the cascades are known, as
+	// they may delete the nodes pointing to the deleted ones.
+	var restricted []*ReferenceError
+	for len(pending) > 0 {
+		sort.Slice(pending, func(i, j int) bool { return pending[i] < pending[j] })
+		uids := pending
+		pending = nil
+		for _, pred := range refPreds {
+			res, err := worker.ProcessTaskOverNetwork(ctx, &pb.Query{
+				Attr:    pred,
+				UidList: &pb.List{Uids: uids},
+				ReadTs:  startTs,
+				Reverse: true,
+			})
+			if err != nil {
+				return edges, err
+			}
+			for i, list := range res.GetUidMatrix() {
+				if i >= len(uids) {
+					break
+				}
+				uid := uids[i]
+				for _, src := range list.GetUids() {
+					if changes.isUnlinked(src, pred, uid) {
+						continue
+					}
+					_, deleted := changes.deleted[src]
+					switch {
+					case deleted:
+					case refs[pred] == schema.ReferencesRestrict:
+						restricted = append(restricted, &ReferenceError{Uid: uid,
+							Predicate: pred, Referrers: []uint64{src}})
+						continue
+					case refs[pred] == schema.ReferencesCascade:
+						del := &pb.DirectedEdge{
+							Entity:       src,
+							Attr:         x.Star,
+							Value:        []byte(x.Star),
+							Op:           pb.DirectedEdge_DEL,
+							AllowedPreds: changes.deleted[uid].AllowedPreds,
+						}
+						edges = append(edges, del)
+						changes.deleted[src] = del
+						pending = append(pending, src)
+					}
+					// The edge is deleted even if its node is, as the predicate may not be part
+					// of the types of the node.
+					edges = append(edges, unlinkEdge(src, pred, uid))
+					changes.unlink(src, pred, uid)
+				}
+			}
+		}
+	}
+
+	var rerr *ReferenceError
+	for _, r := range restricted {
+		src := r.Referrers[0]
+		if _, ok := changes.deleted[src]; ok {
+			// The node pointing to the deleted one is deleted by a cascade.
+			edges = append(edges, unlinkEdge(src, r.Predicate, r.Uid))
+			continue
+		}
+		if rerr == nil {
+			rerr = &ReferenceError{Uid: r.Uid, Predicate: r.Predicate}
+		}
+		if rerr.Uid == r.Uid && rerr.Predicate == r.Predicate {
+			rerr.Referrers = append(rerr.Referrers, src)
+		}
+	}
+	if rerr != nil {
+		return edges, rerr
+	}
+	return edges, nil
+}
+
+// unlinkEdge returns the edge deleting the edge of the predicate from the node to dst.
+func unlinkEdge(uid uint64, pred string, dst uint64) *pb.DirectedEdge {
+	return &pb.DirectedEdge{Entity: uid, Attr: pred, ValueId: dst, Op: pb.DirectedEdge_DEL}
+}
+
+// checkReferences returns a ReferenceError if the edges set edges of predicates with @references
+// pointing to nodes that don't exist once the mutation is applied.
+func checkReferences(ctx context.Context, edges []*pb.DirectedEdge, changes *referenceChanges,
+	refs map[string]string, startTs uint64) error {
+
+	var lookups []uint64
+	lookedUp := make(map[uint64]bool)
+	for _, edge := range edges {
+		if _, ok := refs[edge.Attr]; !ok || edge.Op != pb.DirectedEdge_SET ||
+			edge.ValueId == 0 {
+			continue
+		}
+		dst := edge.ValueId
+		if _, ok := changes.deleted[dst]; ok {
+			return &ReferenceError{Uid: dst, Predicate: edge.Attr}
+		}
+		if !changes.typed[dst] && !lookedUp[dst] {
+			lookedUp[dst] = true
+			lookups = append(lookups, dst)
+		}
+	}
+	if len(lookups) == 0 {
+		return nil
+	}
+
+	sort.Slice(lookups, func(i, j int) bool { return lookups[i] < lookups[j] })
+	res, err := worker.ProcessTaskOverNetwork(ctx, &pb.Query{
+		Attr:    "dgraph.type",
+		UidList: &pb.List{Uids: lookups},
+		ReadTs:  startTs,
+		DoCount: true,
+	})
+	if err != nil {
+		return err
+	}
+	exists := make(map[uint64]bool, len(lookups))
+	for i, count := range res.GetCounts() {
+		if i < len(lookups) && count > 0 {
+			exists[lookups[i]] = true
+		}
+	}
+	for _, edge := range edges {
+		if _, ok := refs[edge.Attr]; !ok || edge.Op != pb.DirectedEdge_SET ||
+			edge.ValueId == 0 {
+			continue
+		}
+		if dst := edge.ValueId; !changes.typed[dst] && !exists[dst] {
+			return &ReferenceError{Uid: dst, Predicate: edge.Attr}
+		}
+	}
+	return nil
+}
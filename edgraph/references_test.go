@@ -0,0 +1,66 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"testing"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReferenceChanges(t *testing.T) {
+	star := []byte(x.Star)
+	edges := []*pb.DirectedEdge{
+		// Deleted nodes.
+		{Entity: 1, Attr: x.Star, Value: star, Op: pb.DirectedEdge_DEL},
+		{Entity: 2, Attr: "dgraph.type", Value: star, Op: pb.DirectedEdge_DEL},
+		// A node deleted and given a type again.
+		{Entity: 3, Attr: x.Star, Value: star, Op: pb.DirectedEdge_DEL},
+		{Entity: 3, Attr: "dgraph.type", Value: []byte("Item"), Op: pb.DirectedEdge_SET},
+		// Deleted edges.
+		{Entity: 4, Attr: "owner", ValueId: 1, Op: pb.DirectedEdge_DEL},
+		{Entity: 5, Attr: "owner", Value: star, Op: pb.DirectedEdge_DEL},
+		// A dropped predicate doesn't delete any node.
+		{Attr: "owner", Value: star, Op: pb.DirectedEdge_DEL},
+	}
+	c := newReferenceChanges(edges)
+	require.Len(t, c.deleted, 2)
+	require.Equal(t, edges[0], c.deleted[1])
+	require.Equal(t, edges[1], c.deleted[2])
+	require.True(t, c.typed[3])
+
+	require.True(t, c.isUnlinked(4, "owner", 1))
+	require.False(t, c.isUnlinked(4, "owner", 2))
+	require.True(t, c.isUnlinked(5, "owner", 2))
+	require.False(t, c.isUnlinked(5, "manager", 2))
+	c.unlink(4, "owner", 2)
+	require.True(t, c.isUnlinked(4, "owner", 2))
+	c.unlink(5, "owner", 3)
+	require.True(t, c.isUnlinked(5, "owner", 6))
+}
+
+func TestReferenceError(t *testing.T) {
+	err := &ReferenceError{Uid: 1, Predicate: "owner"}
+	require.Equal(t, "Node 0x1 pointed to by predicate owner doesn't exist", err.Error())
+	err = &ReferenceError{Uid: 1, Predicate: "owner", Referrers: []uint64{4, 5}}
+	require.Equal(t, "Node 0x1 can't be deleted, as the edges of predicate owner of nodes"+
+		" [0x4 0x5] point to it", err.Error())
+	require.Equal(t, []string{"0x4", "0x5"}, err.Extensions()["referrers"])
+	require.True(t, isReferenceError(err))
+}
@@ -0,0 +1,168 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"context"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	otrace "go.opencensus.io/trace"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/query"
+	"github.com/dgraph-io/dgraph/worker"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// RenamePredicate renames the predicate from to to across the cluster. The schema of from is
+// copied over to to and the types are updated to use to right away, while the data is re-keyed
+// in the background. Until then, to is read from the data of from, and mutations of both
+// predicates are rejected. If wait is true, it returns once the rename is done.
+func (s *Server) RenamePredicate(ctx context.Context, from, to string, wait bool) error {
+	ctx, span := otrace.StartSpan(ctx, "Server.RenamePredicate")
+	defer span.End()
+
+	// Always print out renames because they are important and rare.
+	glog.Infof("Received request to rename predicate %s to %s", from, to)
+
+	if err := x.HealthCheck(); err != nil {
+		return err
+	}
+	if !isMutationAllowed(ctx) {
+		return errors.Errorf("No mutations allowed by server.")
+	}
+	if _, err := hasAdminAuth(ctx, "Alter"); err != nil {
+		glog.Warningf("Rename denied with error: %v\n", err)
+		return err
+	}
+	if err := authorizeRename(ctx, from, to); err != nil {
+		glog.Warningf("Rename denied with error: %v\n", err)
+		return err
+	}
+	if err := validateRename(ctx, from, to); err != nil {
+		return err
+	}
+
+	startTs := worker.State.GetTimestamp(false)
+	if err := worker.RenamePredicateOverNetwork(ctx, from, to, startTs); err != nil {
+		return err
+	}
+
+	types, err := worker.GetTypes(ctx, &pb.SchemaRequest{})
+	if err != nil {
+		return err
+	}
+	if types = renameInTypes(types, from, to); len(types) > 0 {
+		m := &pb.Mutations{StartTs: startTs, Types: types}
+		if _, err := query.ApplyMutations(ctx, m); err != nil {
+			return errors.Wrapf(err, "while updating the types using %s", from)
+		}
+	}
+
+	if !wait {
+		return nil
+	}
+	return worker.WaitForRename(ctx, to)
+}
+
+// validateRename checks that from can be renamed to to.
+func validateRename(ctx context.Context, from, to string) error {
+	if len(from) == 0 || len(to) == 0 {
+		return errors.Errorf("Both the predicate to rename and its new name must be set")
+	}
+	if from == to {
+		return errors.Errorf("Predicate %s can't be renamed to itself", from)
+	}
+	for _, pred := range []string{from, to} {
+		if err := validatePredName(pred); err != nil {
+			return err
+		}
+		if x.IsReservedPredicate(pred) {
+			return errors.Errorf("Can't rename predicate `%s` as it is prefixed with `dgraph.`"+
+				" which is reserved as the namespace for dgraph's internal types/predicates.", pred)
+		}
+		if x.IsEdgeProperty(pred) {
+			return errors.Errorf("Can't rename predicate %s as it is a property of edges", pred)
+		}
+		if x.IsCompositeIndex(pred) {
+			return errors.Errorf("Can't rename predicate %s as it is a composite index", pred)
+		}
+	}
+	if len(worker.EdgeProperties(from)) > 0 {
+		return errors.Errorf("Can't rename predicate %s as its edges have properties", from)
+	}
+	if len(worker.CompositeIndexes(from)) > 0 {
+		return errors.Errorf("Can't rename predicate %s as composite indexes span it", from)
+	}
+
+	nodes, err := worker.GetSchemaOverNetwork(ctx, &pb.SchemaRequest{
+		Predicates: []string{from, to},
+		Fields:     []string{"type", "renamed_from"},
+	})
+	if err != nil {
+		return err
+	}
+	var found bool
+	for _, node := range nodes {
+		switch {
+		case node.Predicate == from && len(node.RenamedFrom) > 0:
+			return errors.Errorf("Predicate %s is being renamed from %s", from, node.RenamedFrom)
+		case node.Predicate == from:
+			found = true
+		case node.RenamedFrom == from:
+			return errors.Errorf("Predicate %s is already being renamed to %s", from, to)
+		default:
+			return errors.Errorf("Predicate %s already exists", to)
+		}
+	}
+	if !found {
+		return errors.Errorf("Predicate %s doesn't exist", from)
+	}
+	return nil
+}
+
+// renameInTypes returns the types that use the predicate from, updated to use to instead.
+func renameInTypes(types []*pb.TypeUpdate, from, to string) []*pb.TypeUpdate {
+	var updated []*pb.TypeUpdate
+	for _, typ := range types {
+		var uses bool
+		fields := make([]*pb.SchemaUpdate, 0, len(typ.Fields))
+		for _, field := range typ.Fields {
+			if field.Predicate == from {
+				uses = true
+				renamed := *field
+				renamed.Predicate = to
+				field = &renamed
+			}
+			fields = append(fields, field)
+		}
+		if typ.TtlFrom == from {
+			uses = true
+		}
+		if !uses {
+			continue
+		}
+		renamed := *typ
+		renamed.Fields = fields
+		if renamed.TtlFrom == from {
+			renamed.TtlFrom = to
+		}
+		updated = append(updated, &renamed)
+	}
+	return updated
+}
@@ -0,0 +1,43 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"testing"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenameInTypes(t *testing.T) {
+	types := []*pb.TypeUpdate{
+		{TypeName: "Person", Fields: []*pb.SchemaUpdate{{Predicate: "name"}, {Predicate: "age"}}},
+		{TypeName: "Pet", Fields: []*pb.SchemaUpdate{{Predicate: "species"}}},
+		{TypeName: "Session", Ttl: "24h0m0s", TtlFrom: "name",
+			Fields: []*pb.SchemaUpdate{{Predicate: "name"}}},
+	}
+	require.Equal(t, []*pb.TypeUpdate{
+		{TypeName: "Person", Fields: []*pb.SchemaUpdate{{Predicate: "fullName"}, {Predicate: "age"}}},
+		{TypeName: "Session", Ttl: "24h0m0s", TtlFrom: "fullName",
+			Fields: []*pb.SchemaUpdate{{Predicate: "fullName"}}},
+	}, renameInTypes(types, "name", "fullName"))
+
+	// The given types are left as they are.
+	require.Equal(t, "name", types[0].Fields[0].Predicate)
+	require.Equal(t, "name", types[2].TtlFrom)
+	require.Empty(t, renameInTypes(types, "color", "colour"))
+}
@@ -0,0 +1,142 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	"github.com/dgraph-io/dgraph/gql"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/query"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/metadata"
+)
+
+type reportContextKey int
+
+// ReportKey is the key used to pass the MutationReport collector of a request.
+const ReportKey reportContextKey = iota
+
+// MutationResult describes the changes made by a single mutation of a request.
+type MutationResult struct {
+	// Created are the uids allocated for the blank nodes and undefined uid variables used in
+	// the mutation.
+	Created []string `json:"created"`
+	// Updated are the uids of the existing nodes the mutation set edges on.
+	Updated []string `json:"updated"`
+	// Deleted are the uids of the nodes the mutation deleted edges from.
+	Deleted []string `json:"deleted"`
+	// Predicates are the predicates changed by the mutation. A delete of all the predicates of
+	// a node is reported as "*".
+	Predicates []string `json:"predicates"`
+}
+
+// MutationReport collects the changes made by the mutations of a request that asked for the
+// mutation report, in the order of the mutations in the request. It's passed around in the
+// context using ReportKey.
+type MutationReport struct {
+	Mutations []*MutationResult `json:"mutations"`
+}
+
+// WithMutationReport returns a context carrying a new MutationReport collector.
+func WithMutationReport(ctx context.Context) (context.Context, *MutationReport) {
+	mr := &MutationReport{}
+	return context.WithValue(ctx, ReportKey, mr), mr
+}
+
+// MutationReportFromContext returns the MutationReport collector attached to the context, or
+// nil if the request didn't ask for the mutation report.
+func MutationReportFromContext(ctx context.Context) *MutationReport {
+	mr, _ := ctx.Value(ReportKey).(*MutationReport)
+	return mr
+}
+
+// IsMutationReportRequested returns whether a gRPC client asked for the mutation report, which
+// it does by passing report as metadata.
+func IsMutationReportRequested(ctx context.Context) (bool, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok || len(md["report"]) == 0 {
+		return false, nil
+	}
+	report, err := strconv.ParseBool(md["report"][0])
+	return report, errors.Wrapf(err, "while parsing report")
+}
+
+// collect records the changes made by each of the mutations, using the uids assigned to their
+// blank nodes and uid variables.
+func (mr *MutationReport) collect(gmuList []*gql.Mutation, newUids map[string]uint64) error {
+	for _, gmu := range gmuList {
+		edges, err := query.ToDirectedEdges([]*gql.Mutation{gmu}, newUids)
+		if err != nil {
+			return err
+		}
+
+		created := make(map[uint64]struct{})
+		for _, nq := range gmu.Set {
+			for _, id := range []string{nq.Subject, nq.ObjectId} {
+				if uid, ok := newUids[id]; ok {
+					created[uid] = struct{}{}
+				}
+			}
+		}
+		updated := make(map[uint64]struct{})
+		deleted := make(map[uint64]struct{})
+		preds := make(map[string]struct{})
+		for _, edge := range edges {
+			if edge.Op == pb.DirectedEdge_DEL {
+				deleted[edge.Entity] = struct{}{}
+			} else if _, ok := created[edge.Entity]; !ok {
+				updated[edge.Entity] = struct{}{}
+			}
+			if edge.Attr == x.Star {
+				preds["*"] = struct{}{}
+			} else {
+				preds[edge.Attr] = struct{}{}
+			}
+		}
+
+		res := &MutationResult{
+			Created:    sortedHexUids(created),
+			Updated:    sortedHexUids(updated),
+			Deleted:    sortedHexUids(deleted),
+			Predicates: make([]string, 0, len(preds)),
+		}
+		for pred := range preds {
+			res.Predicates = append(res.Predicates, pred)
+		}
+		sort.Strings(res.Predicates)
+		mr.Mutations = append(mr.Mutations, res)
+	}
+	return nil
+}
+
+func sortedHexUids(set map[uint64]struct{}) []string {
+	uids := make([]uint64, 0, len(set))
+	for uid := range set {
+		uids = append(uids, uid)
+	}
+	sort.Slice(uids, func(i, j int) bool { return uids[i] < uids[j] })
+
+	res := make([]string, len(uids))
+	for i, uid := range uids {
+		res[i] = query.UidToHex(uid)
+	}
+	return res
+}
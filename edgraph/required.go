@@ -0,0 +1,200 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/worker"
+	"github.com/dgraph-io/dgraph/x"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RequiredPredicateError is returned when a mutation gives a type to a node that has no value
+// for some of the fields of the type marked with @required.
+type RequiredPredicateError struct {
+	Uid        uint64
+	Type       string
+	Predicates []string
+}
+
+func (e *RequiredPredicateError) Error() string {
+	return fmt.Sprintf("Node %#x of type %s has no value for the required predicates [%s]",
+		e.Uid, e.Type, strings.Join(e.Predicates, " "))
+}
+
+// GRPCStatus lets gRPC report the error to clients with the InvalidArgument code.
+func (e *RequiredPredicateError) GRPCStatus() *status.Status {
+	return status.New(codes.InvalidArgument, e.Error())
+}
+
+// Extensions returns the fields to be reported in the extensions of an HTTP error.
+func (e *RequiredPredicateError) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"code":       x.ErrorRequiredPredicate,
+		"uid":        fmt.Sprintf("%#x", e.Uid),
+		"type":       e.Type,
+		"predicates": e.Predicates,
+	}
+}
+
+// requiredFields returns the fields of a type marked with @required.
+func requiredFields(typeName string) []string {
+	typ, ok := schema.State().GetType(typeName)
+	if !ok {
+		return nil
+	}
+	var fields []string
+	for _, field := range typ.Fields {
+		if field.NonNullable {
+			fields = append(fields, field.Predicate)
+		}
+	}
+	return fields
+}
+
+// missingPredicate is a required predicate that a mutation doesn't set for a node it gives a
+// type to.
+type missingPredicate struct {
+	uid  uint64
+	typ  string
+	pred string
+	// stored is true if the node may already have a value for the predicate, as it isn't
+	// created or stripped of the predicate by the mutation.
+	stored bool
+}
+
+// missingPredicates returns the required predicates of the types the edges give to nodes that
+// the edges don't set for these nodes.
+func missingPredicates(edges []*pb.DirectedEdge, newUids map[string]uint64) []missingPredicate {
+	var typed []*pb.DirectedEdge
+	for _, edge := range edges {
+		if edge.Attr == "dgraph.type" && edge.Op == pb.DirectedEdge_SET &&
+			len(requiredFields(string(edge.Value))) > 0 {
+			typed = append(typed, edge)
+		}
+	}
+	if len(typed) == 0 {
+		return nil
+	}
+
+	// Only the predicates of the nodes given a type with required fields are tracked.
+	set := make(map[uint64]map[string]bool, len(typed))
+	deleted := make(map[uint64]map[string]bool, len(typed))
+	for _, edge := range typed {
+		set[edge.Entity] = make(map[string]bool)
+		deleted[edge.Entity] = make(map[string]bool)
+	}
+	for _, edge := range edges {
+		preds, ok := set[edge.Entity]
+		switch {
+		case !ok:
+		case edge.Op == pb.DirectedEdge_SET:
+			preds[edge.Attr] = true
+		case edge.Op == pb.DirectedEdge_DEL && bytes.Equal(edge.Value, []byte(x.Star)):
+			deleted[edge.Entity][edge.Attr] = true
+		}
+	}
+	created := make(map[uint64]bool, len(newUids))
+	for _, uid := range newUids {
+		created[uid] = true
+	}
+
+	var missing []missingPredicate
+	for _, edge := range typed {
+		typ := string(edge.Value)
+		for _, pred := range requiredFields(typ) {
+			if set[edge.Entity][pred] {
+				continue
+			}
+			stored := !created[edge.Entity] && !deleted[edge.Entity][pred] &&
+				!deleted[edge.Entity][x.Star]
+			missing = append(missing, missingPredicate{
+				uid: edge.Entity, typ: typ, pred: pred, stored: stored})
+		}
+	}
+	return missing
+}
+
+// checkRequiredPredicates returns a RequiredPredicateError if the edges give a type to a node
+// that has no value for a field of the type marked with @required once the edges are applied.
+// The values the nodes already have are read as of the start of the transaction.
+func checkRequiredPredicates(ctx context.Context, edges []*pb.DirectedEdge,
+	newUids map[string]uint64, startTs uint64) error {
+
+	missing := missingPredicates(edges, newUids)
+	if len(missing) == 0 {
+		return nil
+	}
+	lookups := make(map[string][]uint64)
+	for _, m := range missing {
+		if m.stored {
+			lookups[m.pred] = append(lookups[m.pred], m.uid)
+		}
+	}
+
+	has := make(map[string]map[uint64]bool, len(lookups))
+	for pred, uids := range lookups {
+		sort.Slice(uids, func(i, j int) bool { return uids[i] < uids[j] })
+		uniq := uids[:0]
+		for i, uid := range uids {
+			if i == 0 || uid != uids[i-1] {
+				uniq = append(uniq, uid)
+			}
+		}
+		res, err := worker.ProcessTaskOverNetwork(ctx, &pb.Query{
+			Attr:    pred,
+			UidList: &pb.List{Uids: uniq},
+			ReadTs:  startTs,
+			DoCount: true,
+		})
+		if err != nil {
+			return err
+		}
+		has[pred] = make(map[uint64]bool, len(uniq))
+		for i, count := range res.GetCounts() {
+			if i < len(uniq) && count > 0 {
+				has[pred][uniq[i]] = true
+			}
+		}
+	}
+
+	// The error reports all the predicates missing from the first node found without them.
+	var rerr *RequiredPredicateError
+	for _, m := range missing {
+		if m.stored && has[m.pred][m.uid] {
+			continue
+		}
+		if rerr == nil {
+			rerr = &RequiredPredicateError{Uid: m.uid, Type: m.typ}
+		}
+		if rerr.Uid == m.uid && rerr.Type == m.typ {
+			rerr.Predicates = append(rerr.Predicates, m.pred)
+		}
+	}
+	if rerr != nil {
+		return rerr
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"testing"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMissingPredicates(t *testing.T) {
+	require.NoError(t, schema.ParseBytes(nil, 1))
+	schema.State().SetType("Person", pb.TypeUpdate{
+		TypeName: "Person",
+		Fields: []*pb.SchemaUpdate{
+			{Predicate: "name", NonNullable: true},
+			{Predicate: "email", NonNullable: true},
+			{Predicate: "age"},
+		},
+	})
+	schema.State().SetType("Tag", pb.TypeUpdate{
+		TypeName: "Tag",
+		Fields:   []*pb.SchemaUpdate{{Predicate: "name"}},
+	})
+	defer schema.State().DeleteAll()
+
+	set := func(uid uint64, attr, value string) *pb.DirectedEdge {
+		return &pb.DirectedEdge{Entity: uid, Attr: attr, Value: []byte(value),
+			Op: pb.DirectedEdge_SET}
+	}
+	edges := []*pb.DirectedEdge{
+		// A new node with all the required predicates.
+		set(1, "dgraph.type", "Person"), set(1, "name", "A"), set(1, "email", "a@example.com"),
+		// A new node without its email.
+		set(2, "dgraph.type", "Person"), set(2, "name", "B"),
+		// An existing node given the type, whose stored email must be looked up.
+		set(3, "dgraph.type", "Person"), set(3, "name", "C"),
+		// An existing node given the type while its email is deleted.
+		{Entity: 4, Attr: "email", Value: []byte(x.Star), Op: pb.DirectedEdge_DEL},
+		set(4, "dgraph.type", "Person"), set(4, "name", "D"),
+		// A type without required fields.
+		set(5, "dgraph.type", "Tag"),
+	}
+	require.Equal(t, []missingPredicate{
+		{uid: 2, typ: "Person", pred: "email"},
+		{uid: 3, typ: "Person", pred: "email", stored: true},
+		{uid: 4, typ: "Person", pred: "email"},
+	}, missingPredicates(edges, map[string]uint64{"_:a": 1, "_:b": 2, "_:e": 5}))
+	require.Empty(t, missingPredicates(edges[9:], map[string]uint64{"_:e": 5}))
+
+	err := &RequiredPredicateError{Uid: 2, Type: "Person", Predicates: []string{"email", "name"}}
+	require.Equal(t, "Node 0x2 of type Person has no value for the required predicates"+
+		" [email name]", err.Error())
+	require.True(t, isRequiredPredicateError(err))
+}
@@ -0,0 +1,81 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"context"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/gql"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/query"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/metadata"
+)
+
+// savepointFromMetadata returns the name of the savepoint to create and the name of the one to
+// roll back to, which a gRPC client passes as savepoint and rollback_to metadata.
+func savepointFromMetadata(ctx context.Context) (savepoint, rollbackTo string) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", ""
+	}
+	if vals := md.Get("savepoint"); len(vals) > 0 {
+		savepoint = vals[0]
+	}
+	if vals := md.Get("rollback_to"); len(vals) > 0 {
+		rollbackTo = vals[0]
+	}
+	return savepoint, rollbackTo
+}
+
+// doSavepoint records the pending changes of the transaction of the request under a savepoint,
+// or reverts them to the ones recorded by a savepoint, in all the groups.
+func (s *Server) doSavepoint(ctx context.Context, req *api.Request, savepoint,
+	rollbackTo string, doAuth AuthMode) (*api.Response, error) {
+	switch {
+	case len(savepoint) > 0 && len(rollbackTo) > 0:
+		return nil, errors.Errorf("savepoint and rollback_to can't be used together")
+	case len(req.Query) > 0 || len(req.Mutations) > 0:
+		return nil, errors.Errorf("a savepoint request can't contain a query or mutations")
+	case req.StartTs == 0:
+		return nil, errors.Errorf("savepoints can only be used inside a transaction")
+	case req.CommitNow:
+		return nil, errors.Errorf("commitNow can't be used along with savepoints")
+	case x.WorkerConfig.LudicrousMode:
+		return nil, errors.Errorf("savepoints are not supported in ludicrous mode")
+	}
+
+	if err := x.HealthCheck(); err != nil {
+		return nil, err
+	}
+	if !isMutationAllowed(ctx) {
+		return nil, errors.Errorf("no mutations allowed")
+	}
+	if doAuth == NeedAuthorize {
+		if err := authorizeMutation(ctx, &gql.Mutation{}); err != nil {
+			return nil, err
+		}
+	}
+
+	m := &pb.Mutations{StartTs: req.StartTs, Savepoint: savepoint, RollbackTo: rollbackTo}
+	if _, err := query.ApplyMutations(ctx, m); err != nil {
+		return nil, err
+	}
+	return &api.Response{Txn: &api.TxnContext{StartTs: req.StartTs}}, nil
+}
@@ -0,0 +1,191 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/worker"
+)
+
+// PredicateChange is how updating the GraphQL schema would change the schema of a predicate.
+type PredicateChange struct {
+	Predicate string
+	// OldSchema is the current schema of the predicate. It's empty if the predicate doesn't
+	// have a schema yet.
+	OldSchema string
+	NewSchema string
+	// IndexesAdded are the indexes that would be built, and IndexesDropped the ones that would
+	// be dropped. Beside the tokenizers, they can be count and reverse.
+	IndexesAdded   []string
+	IndexesDropped []string
+}
+
+// DiffGQLSchema returns the changes that applying dgraphSchema, the Dgraph schema generated from
+// a GraphQL schema, would make to the schema of the predicates, without applying them. The
+// predicates that aren't in dgraphSchema are left as they are by a GraphQL schema update, so
+// they aren't in the result.
+func DiffGQLSchema(ctx context.Context, dgraphSchema string) ([]*PredicateChange, error) {
+	if dgraphSchema == "" {
+		return nil, nil
+	}
+	parsed, err := schema.Parse(dgraphSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	preds := make([]string, 0, len(parsed.Preds))
+	for _, pred := range parsed.Preds {
+		preds = append(preds, pred.Predicate)
+	}
+	nodes, err := worker.GetSchemaOverNetwork(ctx, &pb.SchemaRequest{
+		Predicates: preds,
+		Fields:     []string{"type", "index", "tokenizer", "reverse", "count", "list", "upsert", "lang"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	current := make(map[string]*pb.SchemaNode, len(nodes))
+	for _, node := range nodes {
+		current[node.Predicate] = node
+	}
+	return diffPredicates(parsed.Preds, current), nil
+}
+
+// predicateSchema is the part of the schema of a predicate that a GraphQL schema update can
+// change. It gives the same form to a schema update and to the current schema of a predicate, so
+// that they can be compared.
+type predicateSchema struct {
+	typ        string
+	list       bool
+	tokenizers []string
+	count      bool
+	reverse    bool
+	upsert     bool
+	lang       bool
+}
+
+func (ps *predicateSchema) indexes() []string {
+	var indexes []string
+	indexes = append(indexes, ps.tokenizers...)
+	if ps.count {
+		indexes = append(indexes, "count")
+	}
+	if ps.reverse {
+		indexes = append(indexes, "reverse")
+	}
+	sort.Strings(indexes)
+	return indexes
+}
+
+func (ps *predicateSchema) format(pred string) string {
+	var b strings.Builder
+	typ := ps.typ
+	if ps.list {
+		typ = "[" + typ + "]"
+	}
+	fmt.Fprintf(&b, "<%s>: %s", pred, typ)
+	if len(ps.tokenizers) > 0 {
+		tokenizers := append([]string{}, ps.tokenizers...)
+		sort.Strings(tokenizers)
+		fmt.Fprintf(&b, " @index(%s)", strings.Join(tokenizers, ", "))
+	}
+	if ps.reverse {
+		b.WriteString(" @reverse")
+	}
+	if ps.count {
+		b.WriteString(" @count")
+	}
+	if ps.upsert {
+		b.WriteString(" @upsert")
+	}
+	if ps.lang {
+		b.WriteString(" @lang")
+	}
+	b.WriteString(" .")
+	return b.String()
+}
+
+func diffPredicates(updates []*pb.SchemaUpdate,
+	current map[string]*pb.SchemaNode) []*PredicateChange {
+	var changes []*PredicateChange
+	for _, update := range updates {
+		newSchema := &predicateSchema{
+			typ:     types.TypeID(update.ValueType).Name(),
+			list:    update.List,
+			count:   update.Count,
+			reverse: update.Directive == pb.SchemaUpdate_REVERSE,
+			upsert:  update.Upsert,
+			lang:    update.Lang,
+		}
+		if update.Directive == pb.SchemaUpdate_INDEX {
+			newSchema.tokenizers = update.Tokenizer
+		}
+
+		change := &PredicateChange{
+			Predicate:    update.Predicate,
+			NewSchema:    newSchema.format(update.Predicate),
+			IndexesAdded: newSchema.indexes(),
+		}
+		if node, ok := current[update.Predicate]; ok {
+			oldSchema := &predicateSchema{
+				typ:        node.Type,
+				list:       node.List,
+				tokenizers: node.Tokenizer,
+				count:      node.Count,
+				reverse:    node.Reverse,
+				upsert:     node.Upsert,
+				lang:       node.Lang,
+			}
+			change.OldSchema = oldSchema.format(update.Predicate)
+			if change.OldSchema == change.NewSchema {
+				continue
+			}
+			oldIndexes := oldSchema.indexes()
+			change.IndexesAdded = difference(newSchema.indexes(), oldIndexes)
+			change.IndexesDropped = difference(oldIndexes, newSchema.indexes())
+		}
+		changes = append(changes, change)
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Predicate < changes[j].Predicate
+	})
+	return changes
+}
+
+// difference returns the strings in a that aren't in b.
+func difference(a, b []string) []string {
+	in := make(map[string]bool, len(b))
+	for _, s := range b {
+		in[s] = true
+	}
+	var result []string
+	for _, s := range a {
+		if !in[s] {
+			result = append(result, s)
+		}
+	}
+	return result
+}
@@ -0,0 +1,65 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+)
+
+func TestDiffPredicates(t *testing.T) {
+	parsed, err := schema.Parse(`
+		Post.title: string @index(term, hash) .
+		Post.text: string @index(fulltext) .
+		Post.author: uid @reverse .
+		Post.tags: [string] .`)
+	require.NoError(t, err)
+
+	current := map[string]*pb.SchemaNode{
+		// Post.title changes its indexes.
+		"Post.title": {Predicate: "Post.title", Type: "string", Tokenizer: []string{"exact", "term"}},
+		// Post.text doesn't change.
+		"Post.text": {Predicate: "Post.text", Type: "string", Tokenizer: []string{"fulltext"}},
+		// Post.author loses its count index and gets a reverse one.
+		"Post.author": {Predicate: "Post.author", Type: "uid", Count: true},
+	}
+
+	require.Equal(t, []*PredicateChange{
+		{
+			Predicate:      "Post.author",
+			OldSchema:      "<Post.author>: uid @count .",
+			NewSchema:      "<Post.author>: uid @reverse .",
+			IndexesAdded:   []string{"reverse"},
+			IndexesDropped: []string{"count"},
+		},
+		{
+			Predicate: "Post.tags",
+			NewSchema: "<Post.tags>: [string] .",
+		},
+		{
+			Predicate:      "Post.title",
+			OldSchema:      "<Post.title>: string @index(exact, term) .",
+			NewSchema:      "<Post.title>: string @index(hash, term) .",
+			IndexesAdded:   []string{"hash"},
+			IndexesDropped: []string{"exact"},
+		},
+	}, diffPredicates(parsed.Preds, current))
+}
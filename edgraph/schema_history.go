@@ -0,0 +1,488 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/peer"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/worker"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// The changes of the schema are recorded in nodes of type dgraph.schema.history, one per change.
+// Each of them holds when the change was made, by whom, and the definitions of the predicates
+// and types it changed, before and after it. The last change can be rolled back by applying the
+// definitions it replaced, unless it lost some data.
+const (
+	// SchemaChangeAlter is the operation of the changes made by altering the schema.
+	SchemaChangeAlter = "alter"
+	// SchemaChangeGraphQL is the operation of the changes made by updating the GraphQL schema.
+	SchemaChangeGraphQL = "update_graphql_schema"
+	// SchemaChangeDropAttr is the operation of the changes dropping a predicate.
+	SchemaChangeDropAttr = "drop_attr"
+	// SchemaChangeDropType is the operation of the changes dropping a type.
+	SchemaChangeDropType = "drop_type"
+	// SchemaChangeDropData is the operation of the changes dropping all the data.
+	SchemaChangeDropData = "drop_data"
+	// SchemaChangeDropAll is the operation of the changes dropping all the data and the schema.
+	SchemaChangeDropAll = "drop_all"
+	// SchemaChangeRollback is the operation of the changes rolling back the previous one.
+	SchemaChangeRollback = "rollback"
+)
+
+type schemaHistoryContextKey int
+
+// skipSchemaHistory is set in the context of the alter operations made by a rollback, which is
+// recorded as a single change once it's done.
+const skipSchemaHistory schemaHistoryContextKey = iota
+
+// SchemaChangeEntry is the definition of a predicate or a type before and after a change. Before
+// is empty if the change added it, and After is empty if the change dropped it.
+type SchemaChangeEntry struct {
+	Name   string `json:"name"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// SchemaChange is a change of the schema.
+type SchemaChange struct {
+	Operation  string               `json:"operation"`
+	Predicates []*SchemaChangeEntry `json:"predicates,omitempty"`
+	Types      []*SchemaChangeEntry `json:"types,omitempty"`
+	// Irreversible is the reason why the change can't be rolled back, if it can't.
+	Irreversible string `json:"irreversible,omitempty"`
+}
+
+// SchemaHistoryEntry is a change of the schema as recorded in the history.
+type SchemaHistoryEntry struct {
+	Uid       string
+	ChangedAt time.Time
+	ChangedBy string
+	Change    *SchemaChange
+}
+
+// predicateSchemas returns the schema of the given predicates that exist, or of all the
+// predicates if none is given. Reserved predicates are left out.
+func predicateSchemas(ctx context.Context, preds []string) (map[string]*pb.SchemaUpdate, error) {
+	nodes, err := worker.GetSchemaOverNetwork(ctx, &pb.SchemaRequest{Predicates: preds})
+	if err != nil {
+		return nil, err
+	}
+	updates := make(map[string]*pb.SchemaUpdate)
+	for _, node := range nodes {
+		if x.IsReservedPredicate(node.Predicate) {
+			continue
+		}
+		typ, ok := types.TypeForName(node.Type)
+		if !ok {
+			return nil, errors.Errorf("Invalid type %s of predicate %s", node.Type, node.Predicate)
+		}
+		update := &pb.SchemaUpdate{
+			Predicate:      node.Predicate,
+			ValueType:      pb.Posting_ValType(typ),
+			Count:          node.Count,
+			List:           node.List,
+			Upsert:         node.Upsert,
+			Lang:           node.Lang,
+			NoConflict:     node.NoConflict,
+			Unique:         node.Unique,
+			Ttl:            node.Ttl,
+			TtlFrom:        node.TtlFrom,
+			DefaultValue:   node.DefaultValue,
+			DefaultVirtual: node.DefaultVirtual,
+			IndexWhere:     node.IndexWhere,
+			Encoding:       node.Encoding,
+			EnumValues:     node.EnumValues,
+			CheckMin:       node.CheckMin,
+			CheckMax:       node.CheckMax,
+			CheckMaxLength: node.CheckMaxLength,
+			CheckPattern:   node.CheckPattern,
+			References:     node.References,
+		}
+		switch {
+		case node.Index:
+			update.Directive = pb.SchemaUpdate_INDEX
+			update.Tokenizer = node.Tokenizer
+		case node.Reverse:
+			update.Directive = pb.SchemaUpdate_REVERSE
+		}
+		updates[node.Predicate] = update
+	}
+	return updates, nil
+}
+
+// typeSchemas returns the definitions of the given types that exist, or of all the types if
+// none is given. Pre-defined types are left out.
+func typeSchemas(ctx context.Context, names []string) (map[string]string, error) {
+	updates, err := worker.GetTypes(ctx, &pb.SchemaRequest{Types: names})
+	if err != nil {
+		return nil, err
+	}
+	defs := make(map[string]string)
+	for _, update := range updates {
+		if x.IsPreDefinedType(update.TypeName) {
+			continue
+		}
+		defs[update.TypeName] = worker.TypeSchema(update.TypeName, *update)
+	}
+	return defs, nil
+}
+
+// schemaChange returns the change of the schema made by applying the given predicates and
+// types. The ones that the change leaves as they are are left out.
+func schemaChange(ctx context.Context, operation string, preds []*pb.SchemaUpdate,
+	typeUpdates []*pb.TypeUpdate) (*SchemaChange, error) {
+	change := &SchemaChange{Operation: operation}
+	if len(preds) > 0 {
+		names := make([]string, 0, len(preds))
+		for _, update := range preds {
+			names = append(names, update.Predicate)
+		}
+		current, err := predicateSchemas(ctx, names)
+		if err != nil {
+			return nil, err
+		}
+		for _, update := range preds {
+			if x.IsReservedPredicate(update.Predicate) {
+				continue
+			}
+			entry := &SchemaChangeEntry{
+				Name:  update.Predicate,
+				After: worker.PredicateSchema(update.Predicate, update),
+			}
+			if before, ok := current[update.Predicate]; ok {
+				entry.Before = worker.PredicateSchema(before.Predicate, before)
+				if change.Irreversible == "" {
+					change.Irreversible = irreversiblePredicateChange(before, update)
+				}
+			}
+			if entry.Before != entry.After {
+				change.Predicates = append(change.Predicates, entry)
+			}
+		}
+	}
+
+	if len(typeUpdates) > 0 {
+		names := make([]string, 0, len(typeUpdates))
+		for _, update := range typeUpdates {
+			names = append(names, update.TypeName)
+		}
+		current, err := typeSchemas(ctx, names)
+		if err != nil {
+			return nil, err
+		}
+		for _, update := range typeUpdates {
+			if x.IsPreDefinedType(update.TypeName) {
+				continue
+			}
+			entry := &SchemaChangeEntry{
+				Name:   update.TypeName,
+				Before: current[update.TypeName],
+				After:  worker.TypeSchema(update.TypeName, *update),
+			}
+			if entry.Before != entry.After {
+				change.Types = append(change.Types, entry)
+			}
+		}
+	}
+	if operation == SchemaChangeGraphQL && (len(change.Predicates) > 0 || len(change.Types) > 0) {
+		change.Irreversible = "The change was made by updating the GraphQL schema, which must" +
+			" be updated again to roll it back"
+	}
+	return change, nil
+}
+
+// irreversiblePredicateChange returns why the change of the schema of a predicate from before
+// to after can't be rolled back, or an empty string if it can be. The values of a predicate
+// whose type changed may not convert back to the previous type.
+func irreversiblePredicateChange(before, after *pb.SchemaUpdate) string {
+	switch {
+	case before.ValueType != after.ValueType:
+		return fmt.Sprintf("The type of predicate %s was changed from %s to %s",
+			after.Predicate, types.TypeID(before.ValueType).Name(),
+			types.TypeID(after.ValueType).Name())
+	case before.List != after.List:
+		return fmt.Sprintf("Predicate %s was changed from or to a list", after.Predicate)
+	case before.Lang != after.Lang:
+		return fmt.Sprintf("The @lang directive of predicate %s was changed", after.Predicate)
+	}
+	return ""
+}
+
+// dropChange returns the change of the schema made by dropping the given predicates and types,
+// or all of them for the drop all operation.
+func dropChange(ctx context.Context, operation string, preds, typeNames []string) (
+	*SchemaChange, error) {
+	change := &SchemaChange{Operation: operation}
+	if len(preds) > 0 || operation == SchemaChangeDropAll {
+		current, err := predicateSchemas(ctx, preds)
+		if err != nil {
+			return nil, err
+		}
+		for name, update := range current {
+			change.Predicates = append(change.Predicates, &SchemaChangeEntry{
+				Name:   name,
+				Before: worker.PredicateSchema(name, update),
+			})
+		}
+		sort.Slice(change.Predicates, func(i, j int) bool {
+			return change.Predicates[i].Name < change.Predicates[j].Name
+		})
+	}
+	if len(typeNames) > 0 || operation == SchemaChangeDropAll {
+		current, err := typeSchemas(ctx, typeNames)
+		if err != nil {
+			return nil, err
+		}
+		for name, def := range current {
+			change.Types = append(change.Types, &SchemaChangeEntry{Name: name, Before: def})
+		}
+		sort.Slice(change.Types, func(i, j int) bool {
+			return change.Types[i].Name < change.Types[j].Name
+		})
+	}
+
+	switch operation {
+	case SchemaChangeDropAll:
+		change.Irreversible = "All the data was dropped along with the schema"
+	case SchemaChangeDropData:
+		change.Irreversible = "All the data was dropped"
+	case SchemaChangeDropAttr:
+		change.Irreversible = fmt.Sprintf("Predicate %s was dropped along with its data",
+			preds[0])
+	}
+	return change, nil
+}
+
+// schemaChangeActor returns who is making the change of the schema in the context. That's the
+// user altering the schema when ACL is turned on, or else the address the request came from.
+func schemaChangeActor(ctx context.Context) string {
+	if userId := alterUserId(ctx); userId != "" {
+		return userId
+	}
+	if peerInfo, ok := peer.FromContext(ctx); ok {
+		if ip, _, err := net.SplitHostPort(peerInfo.Addr.String()); err == nil {
+			return ip
+		}
+	}
+	return ""
+}
+
+// recordSchemaChange adds the change to the history of the schema. The change has already been
+// applied, so failing to record it is only logged. Changes that left the schema as it was are
+// not recorded, except for the ones that dropped data.
+func recordSchemaChange(ctx context.Context, change *SchemaChange) {
+	if skip, _ := ctx.Value(skipSchemaHistory).(bool); skip || change == nil {
+		return
+	}
+	if len(change.Predicates) == 0 && len(change.Types) == 0 && change.Irreversible == "" {
+		return
+	}
+	if err := addSchemaHistoryEntry(ctx, change); err != nil {
+		glog.Errorf("Unable to record the change of the schema %+v: %v", change, err)
+	}
+}
+
+func addSchemaHistoryEntry(ctx context.Context, change *SchemaChange) error {
+	data, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+	str := func(val string) *api.Value {
+		return &api.Value{Val: &api.Value_StrVal{StrVal: val}}
+	}
+	nquads := []*api.NQuad{
+		{Subject: "_:a", Predicate: "dgraph.type", ObjectValue: str("dgraph.schema.history")},
+		{Subject: "_:a", Predicate: "dgraph.schema.change", ObjectValue: str(string(data))},
+		{Subject: "_:a", Predicate: "dgraph.schema.changed_at",
+			ObjectValue: str(time.Now().UTC().Format(time.RFC3339Nano))},
+	}
+	if actor := schemaChangeActor(ctx); actor != "" {
+		nquads = append(nquads, &api.NQuad{Subject: "_:a", Predicate: "dgraph.schema.changed_by",
+			ObjectValue: str(actor)})
+	}
+	req := &api.Request{
+		Mutations: []*api.Mutation{{Set: nquads}},
+		CommitNow: true,
+	}
+	_, err = (&Server{}).doQuery(context.WithValue(ctx, IsGraphql, true), req, NoAuthorize)
+	return err
+}
+
+// GetSchemaHistory returns the changes of the schema, the latest first. At most first changes
+// are returned, after skipping offset of them.
+func GetSchemaHistory(ctx context.Context, first, offset int) ([]*SchemaHistoryEntry, error) {
+	if first <= 0 {
+		first = math.MaxInt32
+	}
+	req := &api.Request{
+		Query: fmt.Sprintf(`{
+			history(func: type(dgraph.schema.history), orderdesc: dgraph.schema.changed_at,
+				first: %d, offset: %d) {
+				uid
+				dgraph.schema.change
+				dgraph.schema.changed_by
+				dgraph.schema.changed_at
+			}
+		}`, first, offset),
+		ReadOnly: true,
+	}
+	resp, err := (&Server{}).doQuery(context.WithValue(ctx, IsGraphql, true), req, NoAuthorize)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		History []struct {
+			Uid       string    `json:"uid"`
+			Change    string    `json:"dgraph.schema.change"`
+			ChangedBy string    `json:"dgraph.schema.changed_by"`
+			ChangedAt time.Time `json:"dgraph.schema.changed_at"`
+		} `json:"history"`
+	}
+	if err := json.Unmarshal(resp.GetJson(), &result); err != nil {
+		return nil, errors.Wrap(err, "while reading the history of the schema")
+	}
+	entries := make([]*SchemaHistoryEntry, 0, len(result.History))
+	for _, node := range result.History {
+		entry := &SchemaHistoryEntry{
+			Uid:       node.Uid,
+			ChangedAt: node.ChangedAt,
+			ChangedBy: node.ChangedBy,
+			Change:    &SchemaChange{},
+		}
+		if err := json.Unmarshal([]byte(node.Change), entry.Change); err != nil {
+			return nil, errors.Wrapf(err, "while reading the change of the schema %s", node.Uid)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// RollbackSchemaChange rolls back the last change of the schema by applying the definitions of
+// the predicates and types it replaced, and dropping the ones it added. It fails if the change
+// lost some data, or if a predicate it added has data since then. The rollback is recorded as a
+// change of its own, so rolling back again applies the change back. It returns the change that
+// was rolled back.
+func RollbackSchemaChange(ctx context.Context) (*SchemaHistoryEntry, error) {
+	entries, err := GetSchemaHistory(ctx, 1, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, errors.New("There is no change of the schema to roll back")
+	}
+	last := entries[0]
+	if last.Change.Irreversible != "" {
+		return nil, errors.Errorf("The last change of the schema can't be rolled back: %s",
+			last.Change.Irreversible)
+	}
+
+	var defs bytes.Buffer
+	var dropPreds, dropTypes []string
+	for _, entry := range last.Change.Predicates {
+		if entry.Before == "" {
+			dropPreds = append(dropPreds, entry.Name)
+			continue
+		}
+		x.Check2(defs.WriteString(entry.Before + "\n"))
+	}
+	for _, entry := range last.Change.Types {
+		if entry.Before == "" {
+			dropTypes = append(dropTypes, entry.Name)
+			continue
+		}
+		x.Check2(defs.WriteString(entry.Before + "\n"))
+	}
+	// Dropping the predicates the change added is only safe while they don't have data.
+	for _, pred := range dropPreds {
+		ok, err := hasData(ctx, pred)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return nil, errors.Errorf("The last change of the schema can't be rolled back:"+
+				" predicate %s has data since it was added", pred)
+		}
+	}
+
+	alterCtx := context.WithValue(ctx, skipSchemaHistory, true)
+	s := &Server{}
+	if defs.Len() > 0 {
+		if _, err := s.Alter(alterCtx, &api.Operation{Schema: defs.String()}); err != nil {
+			return nil, err
+		}
+	}
+	for _, pred := range dropPreds {
+		if _, err := s.Alter(alterCtx, &api.Operation{DropAttr: pred}); err != nil {
+			return nil, err
+		}
+	}
+	for _, typ := range dropTypes {
+		op := &api.Operation{DropOp: api.Operation_TYPE, DropValue: typ}
+		if _, err := s.Alter(alterCtx, op); err != nil {
+			return nil, err
+		}
+	}
+
+	rollback := &SchemaChange{Operation: SchemaChangeRollback}
+	for _, entry := range last.Change.Predicates {
+		rollback.Predicates = append(rollback.Predicates,
+			&SchemaChangeEntry{Name: entry.Name, Before: entry.After, After: entry.Before})
+	}
+	for _, entry := range last.Change.Types {
+		rollback.Types = append(rollback.Types,
+			&SchemaChangeEntry{Name: entry.Name, Before: entry.After, After: entry.Before})
+	}
+	recordSchemaChange(ctx, rollback)
+	return last, nil
+}
+
+// hasData returns whether any node has a value for the predicate.
+func hasData(ctx context.Context, pred string) (bool, error) {
+	req := &api.Request{
+		Query:    fmt.Sprintf(`{ q(func: has(<%s>), first: 1) { uid } }`, pred),
+		ReadOnly: true,
+	}
+	resp, err := (&Server{}).doQuery(context.WithValue(ctx, IsGraphql, true), req, NoAuthorize)
+	if err != nil {
+		return false, err
+	}
+	var result struct {
+		Q []struct {
+			Uid string `json:"uid"`
+		} `json:"q"`
+	}
+	if err := json.Unmarshal(resp.GetJson(), &result); err != nil {
+		return false, err
+	}
+	return len(result.Q) > 0, nil
+}
@@ -0,0 +1,46 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+)
+
+func TestIrreversiblePredicateChange(t *testing.T) {
+	before := &pb.SchemaUpdate{Predicate: "age", ValueType: pb.Posting_INT}
+
+	// Changes of the indexes and directives other than @lang can be rolled back.
+	after := &pb.SchemaUpdate{Predicate: "age", ValueType: pb.Posting_INT,
+		Directive: pb.SchemaUpdate_INDEX, Tokenizer: []string{"int"}, Count: true}
+	require.Empty(t, irreversiblePredicateChange(before, after))
+
+	after = &pb.SchemaUpdate{Predicate: "age", ValueType: pb.Posting_STRING}
+	require.Equal(t, "The type of predicate age was changed from int to string",
+		irreversiblePredicateChange(before, after))
+
+	after = &pb.SchemaUpdate{Predicate: "age", ValueType: pb.Posting_INT, List: true}
+	require.Equal(t, "Predicate age was changed from or to a list",
+		irreversiblePredicateChange(before, after))
+
+	after = &pb.SchemaUpdate{Predicate: "age", ValueType: pb.Posting_INT, Lang: true}
+	require.Equal(t, "The @lang directive of predicate age was changed",
+		irreversiblePredicateChange(before, after))
+}
@@ -179,6 +179,12 @@ func GetGQLSchema() (uid, graphQLSchema string, err error) {
 // Then it sends an update request to the worker, which is executed only on Group-1 leader.
 func UpdateGQLSchema(ctx context.Context, gqlSchema,
 	dgraphSchema string) (*pb.UpdateGraphQLSchemaResponse, error) {
+	if worker.Config.ReadOnly {
+		return nil, errors.Errorf("The GraphQL schema can't be updated on a read-only server.")
+	}
+	if worker.IsReplica() {
+		return nil, errors.Errorf("The GraphQL schema can't be updated on a replica cluster.")
+	}
 	var err error
 	parsedDgraphSchema := &schema.ParsedSchema{}
 
@@ -193,12 +199,22 @@ func UpdateGQLSchema(ctx context.Context, gqlSchema,
 		}
 	}
 
-	return worker.UpdateGQLSchemaOverNetwork(ctx, &pb.UpdateGraphQLSchemaRequest{
+	change, err := schemaChange(ctx, SchemaChangeGraphQL, parsedDgraphSchema.Preds,
+		parsedDgraphSchema.Types)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := worker.UpdateGQLSchemaOverNetwork(ctx, &pb.UpdateGraphQLSchemaRequest{
 		StartTs:       worker.State.GetTimestamp(false),
 		GraphqlSchema: gqlSchema,
 		DgraphPreds:   parsedDgraphSchema.Preds,
 		DgraphTypes:   parsedDgraphSchema.Types,
 	})
+	if err != nil {
+		return nil, err
+	}
+	recordSchemaChange(ctx, change)
+	return resp, nil
 }
 
 // validateAlterOperation validates the given operation for alter.
@@ -315,8 +331,12 @@ func (s *Server) Alter(ctx context.Context, op *api.Operation) (*api.Payload, er
 			return empty, errors.Errorf("If DropOp is set to ALL, DropValue must be empty")
 		}
 
+		change, err := dropChange(ctx, SchemaChangeDropAll, nil, nil)
+		if err != nil {
+			return empty, err
+		}
 		m.DropOp = pb.Mutations_ALL
-		_, err := query.ApplyMutations(ctx, m)
+		_, err = query.ApplyMutations(ctx, m)
 		if err != nil {
 			return empty, err
 		}
@@ -327,6 +347,8 @@ func (s *Server) Alter(ctx context.Context, op *api.Operation) (*api.Payload, er
 		// recreate the admin account after a drop all operation
 		ResetAcl(nil)
 		ResetCors(nil)
+		// The history of the schema was dropped too, so it starts again from this change.
+		recordSchemaChange(ctx, change)
 		return empty, err
 	}
 
@@ -352,6 +374,11 @@ func (s *Server) Alter(ctx context.Context, op *api.Operation) (*api.Payload, er
 		// recreate the admin account after a drop data operation
 		ResetAcl(nil)
 		ResetCors(nil)
+		// The history of the schema was dropped too, so it starts again from this change.
+		recordSchemaChange(ctx, &SchemaChange{
+			Operation:    SchemaChangeDropData,
+			Irreversible: "All the data was dropped",
+		})
 		return empty, err
 	}
 
@@ -383,10 +410,30 @@ func (s *Server) Alter(ctx context.Context, op *api.Operation) (*api.Payload, er
 		if err != nil {
 			return empty, err
 		}
+		// The properties of the edges, and the composite indexes spanning the predicate, go
+		// away along with it. Each predicate is dropped by a separate mutation.
+		dependents := append(worker.EdgeProperties(attr), worker.CompositeIndexes(attr)...)
+		change, err := dropChange(ctx, SchemaChangeDropAttr, append([]string{attr}, dependents...),
+			nil)
+		if err != nil {
+			return empty, err
+		}
+
 		edges := []*pb.DirectedEdge{edge}
 		m.Edges = edges
-		_, err = query.ApplyMutations(ctx, m)
-		return empty, err
+		if _, err = query.ApplyMutations(ctx, m); err != nil {
+			return empty, err
+		}
+		for _, prop := range dependents {
+			propEdge := *edge
+			propEdge.Attr = prop
+			m.Edges = []*pb.DirectedEdge{&propEdge}
+			if _, err = query.ApplyMutations(ctx, m); err != nil {
+				return empty, err
+			}
+		}
+		recordSchemaChange(ctx, change)
+		return empty, nil
 	}
 
 	if op.DropOp == api.Operation_TYPE {
@@ -400,10 +447,17 @@ func (s *Server) Alter(ctx context.Context, op *api.Operation) (*api.Payload, er
 				op.DropValue)
 		}
 
+		change, err := dropChange(ctx, SchemaChangeDropType, nil, []string{op.DropValue})
+		if err != nil {
+			return empty, err
+		}
 		m.DropOp = pb.Mutations_TYPE
 		m.DropValue = op.DropValue
-		_, err := query.ApplyMutations(ctx, m)
-		return empty, err
+		if _, err = query.ApplyMutations(ctx, m); err != nil {
+			return empty, err
+		}
+		recordSchemaChange(ctx, change)
+		return empty, nil
 	}
 
 	result, err := parseSchemaFromAlterOperation(op)
@@ -411,6 +465,19 @@ func (s *Server) Alter(ctx context.Context, op *api.Operation) (*api.Payload, er
 		return nil, err
 	}
 
+	if err := validateEdgeProperties(ctx, result.Preds); err != nil {
+		return nil, err
+	}
+	indexes, err := validateCompositeIndexes(ctx, result.Preds)
+	if err != nil {
+		return nil, err
+	}
+
+	change, err := schemaChange(ctx, SchemaChangeAlter, result.Preds, result.Types)
+	if err != nil {
+		return nil, err
+	}
+
 	glog.Infof("Got schema: %+v\n", result)
 	// TODO: Maybe add some checks about the schema.
 	m.Schema = result.Preds
@@ -419,12 +486,28 @@ func (s *Server) Alter(ctx context.Context, op *api.Operation) (*api.Payload, er
 	if err != nil {
 		return empty, err
 	}
+	recordSchemaChange(ctx, change)
 
 	// wait for indexing to complete or context to be canceled.
 	if err = worker.WaitForIndexingOrCtxError(ctx, !op.RunInBackground); err != nil {
 		return empty, err
 	}
 
+	// New composite indexes are built from the predicates they span, which may be served by
+	// other groups.
+	for _, index := range indexes {
+		if !op.RunInBackground {
+			if err := buildCompositeIndex(ctx, index); err != nil {
+				return empty, errors.Wrapf(err, "while building composite index %s", index)
+			}
+			continue
+		}
+		go func(index string) {
+			if err := buildCompositeIndex(context.Background(), index); err != nil {
+				glog.Errorf("While building composite index %s: %v", index, err)
+			}
+		}(index)
+	}
 	return empty, nil
 }
 
@@ -466,8 +549,31 @@ func (s *Server) doMutate(ctx context.Context, qc *queryContext, resp *api.Respo
 	resp.Uids = query.UidsToHex(query.StripBlankNode(newUids))
 	edges, err := query.ToDirectedEdges(qc.gmuList, newUids)
 	if err != nil {
+		return x.InvalidArgument(err)
+	}
+	if ref := blobRefFromContext(ctx); ref != nil {
+		ref.apply(edges)
+	}
+	if edges, err = addDefaultValues(ctx, edges, newUids); err != nil {
+		return err
+	}
+	if err := checkStrictSchema(ctx, edges); err != nil {
+		return err
+	}
+	if err := checkRequiredPredicates(ctx, edges, newUids, qc.req.StartTs); err != nil {
+		return err
+	}
+	if err := checkValues(ctx, edges, newUids); err != nil {
+		return err
+	}
+	if edges, err = applyReferences(ctx, edges, qc.req.StartTs); err != nil {
 		return err
 	}
+	if report := MutationReportFromContext(ctx); report != nil {
+		if err := report.collect(qc.gmuList, newUids); err != nil {
+			return err
+		}
+	}
 
 	predHints := make(map[string]pb.Metadata_HintType)
 	for _, gmu := range qc.gmuList {
@@ -486,6 +592,11 @@ func (s *Server) doMutate(ctx context.Context, qc *queryContext, resp *api.Respo
 		},
 	}
 
+	if err := mutationHooks.preCommit(ctx, edges, qc.req.StartTs); err != nil {
+		return err
+	}
+	hookEvents := mutationHooks.postCommitEvents(edges, qc.req.StartTs)
+
 	qc.span.Annotatef(nil, "Applying mutations: %+v", m)
 	resp.Txn, err = query.ApplyMutations(ctx, m)
 	qc.span.Annotatef(nil, "Txn Context: %+v. Err=%v", resp.Txn, err)
@@ -498,6 +609,9 @@ func (s *Server) doMutate(ctx context.Context, qc *queryContext, resp *api.Respo
 		}
 		resp.Txn.Keys = resp.Txn.Keys[:0]
 		resp.Txn.CommitTs = qc.req.StartTs
+		if err == nil {
+			mutationHooks.postCommit(hookEvents, qc.req.StartTs)
+		}
 		return err
 	}
 	// calculateMutationMetrics calculate cost for the mutation.
@@ -511,6 +625,10 @@ func (s *Server) doMutate(ctx context.Context, qc *queryContext, resp *api.Respo
 		if err == zero.ErrConflict {
 			err = status.Error(codes.FailedPrecondition, err.Error())
 		}
+		if err == nil {
+			// The post-commit hooks are notified when the transaction gets committed.
+			mutationHooks.addPending(qc.req.StartTs, hookEvents)
+		}
 
 		return err
 	}
@@ -537,8 +655,14 @@ func (s *Server) doMutate(ctx context.Context, qc *queryContext, resp *api.Respo
 
 	qc.span.Annotatef(nil, "Prewrites err: %v. Attempting to commit/abort immediately.", err)
 	ctxn := resp.Txn
+	cctx := ctx
+	if ireq := idempotentRequestFromContext(ctx); ireq != nil {
+		if cctx, err = ireq.commitContext(ctx, resp); err != nil {
+			return err
+		}
+	}
 	// zero would assign the CommitTs
-	cts, err := worker.CommitOverNetwork(ctx, ctxn)
+	cts, err := worker.CommitOverNetwork(cctx, ctxn)
 	qc.span.Annotatef(nil, "Status of commit at ts: %d: %v", ctxn.StartTs, err)
 	if err != nil {
 		if err == dgo.ErrAborted {
@@ -553,9 +677,68 @@ func (s *Server) doMutate(ctx context.Context, qc *queryContext, resp *api.Respo
 	resp.Txn.Keys = resp.Txn.Keys[:0]
 	resp.Txn.CommitTs = cts
 	calculateMutationMetrics()
+	mutationHooks.postCommit(hookEvents, cts)
+	return nil
+}
+
+// resolveCondChains rewrites the @elseif(...) and @else conditions of the mutations into the @if
+// conditions they stand for. A chain of conditions starts at a mutation with @if and continues
+// with the mutations right after it with @elseif or @else. The mutation of a branch is executed
+// only if its own condition is true and the conditions of all the branches before it are false.
+//
+// For example -
+//      mutation @if(A) {...}
+//      mutation @elseif(B) {...}
+//      mutation @else {...}
+//
+// is the same as -
+//      mutation @if(A) {...}
+//      mutation @if((B) AND NOT (A)) {...}
+//      mutation @if(NOT (A) AND NOT (B)) {...}
+func resolveCondChains(gmuList []*gql.Mutation) error {
+	// chain holds the conditions of the branches seen so far in the current chain.
+	var chain []string
+	notChain := func() string {
+		nots := make([]string, 0, len(chain))
+		for _, c := range chain {
+			nots = append(nots, "NOT ("+c+")")
+		}
+		return strings.Join(nots, " AND ")
+	}
+
+	for _, gmu := range gmuList {
+		cond := strings.TrimSpace(gmu.Cond)
+		switch {
+		case strings.HasPrefix(cond, "@elseif"):
+			if len(chain) == 0 {
+				return errors.Errorf("@elseif must follow a mutation with @if or @elseif")
+			}
+			c := condBody(strings.TrimPrefix(cond, "@elseif"))
+			gmu.Cond = "@if((" + c + ") AND " + notChain() + ")"
+			chain = append(chain, c)
+		case cond == "@else":
+			if len(chain) == 0 {
+				return errors.Errorf("@else must follow a mutation with @if or @elseif")
+			}
+			gmu.Cond = "@if(" + notChain() + ")"
+			chain = nil
+		case strings.HasPrefix(cond, "@if"):
+			chain = []string{condBody(strings.TrimPrefix(cond, "@if"))}
+		default:
+			chain = nil
+		}
+	}
 	return nil
 }
 
+// condBody returns the condition inside the brackets of a directive, e.g. eq(len(v), 0) for
+// (eq(len(v), 0)).
+func condBody(cond string) string {
+	cond = strings.TrimSpace(cond)
+	cond = strings.TrimPrefix(cond, "(")
+	return strings.TrimSuffix(cond, ")")
+}
+
 // buildUpsertQuery modifies the query to evaluate the
 // @if condition defined in Conditional Upsert.
 func buildUpsertQuery(qc *queryContext) string {
@@ -609,10 +792,29 @@ func updateMutations(qc *queryContext) error {
 			if !(ok && len(uids) == 1) {
 				gmu.Set = nil
 				gmu.Del = nil
-				continue
 			}
 		}
+	}
 
+	// Find the blank nodes created by the mutations that are run, so that the uid variables
+	// with the same name in the other mutations can refer to them when they're empty.
+	qc.blankNodes = make(map[string][]*gql.Mutation)
+	addBlankNode := func(name string, gmu *gql.Mutation) {
+		if !strings.HasPrefix(name, "_:") {
+			return
+		}
+		if gmus := qc.blankNodes[name]; len(gmus) == 0 || gmus[len(gmus)-1] != gmu {
+			qc.blankNodes[name] = append(gmus, gmu)
+		}
+	}
+	for _, gmu := range qc.gmuList {
+		for _, nq := range gmu.Set {
+			addBlankNode(nq.Subject, gmu)
+			addBlankNode(nq.ObjectId, gmu)
+		}
+	}
+
+	for _, gmu := range qc.gmuList {
 		if err := updateUIDInMutations(gmu, qc); err != nil {
 			return err
 		}
@@ -768,6 +970,14 @@ func updateUIDInMutations(gmu *gql.Mutation, qc *queryContext) error {
 			if uids, ok := qc.uidRes[varName]; ok && len(uids) != 0 {
 				return uids
 			}
+			// An empty variable refers to the node created for the blank node with the same
+			// name by another mutation, if there's one. Within a mutation, the two are
+			// different nodes.
+			for _, other := range qc.blankNodes["_:"+varName] {
+				if other != gmu {
+					return []string{"_:" + varName}
+				}
+			}
 
 			return []string{"_:" + s}
 		}
@@ -775,6 +985,12 @@ func updateUIDInMutations(gmu *gql.Mutation, qc *queryContext) error {
 		return []string{s}
 	}
 
+	// isEmptyVar returns whether the uid variable was replaced by a blank node, because it
+	// was empty.
+	isEmptyVar := func(orig, s string) bool {
+		return strings.HasPrefix(orig, "uid(") && strings.HasPrefix(s, "_:")
+	}
+
 	getNewNQuad := func(nq *api.NQuad, s, o string) *api.NQuad {
 		// The following copy is fine because we only modify Subject and ObjectId.
 		// The pointer values are not modified across different copies of NQuad.
@@ -796,8 +1012,7 @@ func updateUIDInMutations(gmu *gql.Mutation, qc *queryContext) error {
 		for _, s := range newSubs {
 			for _, o := range newObs {
 				// Blank node has no meaning in case of deletion.
-				if strings.HasPrefix(s, "_:uid(") ||
-					strings.HasPrefix(o, "_:uid(") {
+				if isEmptyVar(nq.Subject, s) || isEmptyVar(nq.ObjectId, o) {
 					continue
 				}
 
@@ -868,6 +1083,11 @@ type queryContext struct {
 	// 1B) and resulting in OOM. We are limiting number of nquads which can be inserted in
 	// a single request.
 	nquadsCount int
+	// limits are the per-request limits that apply to the query part of the request.
+	limits queryLimits
+	// blankNodes are the blank nodes used in the set nquads of the mutations that are run,
+	// along with the mutations using them.
+	blankNodes map[string][]*gql.Mutation
 }
 
 // Health handles /health and /health?all requests.
@@ -937,11 +1157,15 @@ func (s *Server) State(ctx context.Context) (*api.Response, error) {
 
 // Query handles queries or mutations
 func (s *Server) Query(ctx context.Context, req *api.Request) (*api.Response, error) {
-	auth := ctx.Value(Authorize)
-	if auth == nil || auth.(bool) {
-		return s.doQuery(ctx, req, NeedAuthorize)
+	doAuth := NoAuthorize
+	if auth := ctx.Value(Authorize); auth == nil || auth.(bool) {
+		doAuth = NeedAuthorize
 	}
-	return s.doQuery(ctx, req, NoAuthorize)
+	if savepoint, rollbackTo := savepointFromMetadata(ctx); len(savepoint) > 0 ||
+		len(rollbackTo) > 0 {
+		return s.doSavepoint(ctx, req, savepoint, rollbackTo, doAuth)
+	}
+	return s.doQuery(ctx, req, doAuth)
 }
 
 func (s *Server) doQuery(ctx context.Context, req *api.Request, doAuth AuthMode) (
@@ -1007,14 +1231,82 @@ func (s *Server) doQuery(ctx context.Context, req *api.Request, doAuth AuthMode)
 	}
 
 	qc := &queryContext{req: req, latency: l, span: span, graphql: isGraphQL}
+	if qc.limits, rerr = getQueryLimits(ctx); rerr != nil {
+		return
+	}
+	// HTTP requests attach their own collector, gRPC clients ask for one through metadata.
+	detailedMetrics := query.DetailedMetricsFromContext(ctx)
+	if detailedMetrics == nil {
+		var isDetailed bool
+		if isDetailed, rerr = query.IsDetailedMetricsRequested(ctx); rerr != nil {
+			return
+		}
+		if isDetailed {
+			ctx, detailedMetrics = query.WithDetailedMetrics(ctx)
+		}
+	}
+	// Likewise for the mutation report.
+	report := MutationReportFromContext(ctx)
+	if report == nil && isMutation {
+		var isReport bool
+		if isReport, rerr = IsMutationReportRequested(ctx); rerr != nil {
+			return
+		}
+		if isReport {
+			ctx, report = WithMutationReport(ctx)
+		}
+	}
 	if rerr = parseRequest(qc); rerr != nil {
 		return
 	}
+	if rerr = checkQueryDepth(qc.gqlRes.Query, qc.limits.maxDepth); rerr != nil {
+		return
+	}
+	if qc.limits.timeout > 0 {
+		parentCtx := ctx
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, qc.limits.timeout)
+		defer cancel()
+		defer func() {
+			// Report the timeout as a limit error only if it was caused by our deadline and
+			// not by the client going away.
+			if rerr != nil && ctx.Err() == context.DeadlineExceeded && parentCtx.Err() == nil {
+				rerr = &x.LimitExceededError{
+					Limit: x.LimitTimeout,
+					Max:   uint64(qc.limits.timeout / time.Millisecond),
+				}
+			}
+		}()
+	}
 
 	if doAuth == NeedAuthorize {
 		if rerr = authorizeRequest(ctx, qc); rerr != nil {
 			return
 		}
+		// Internal requests, which skip authorization, aren't subject to the cost budget.
+		var done func()
+		if done, rerr = admitQuery(ctx, qc.gqlRes.Query); rerr != nil {
+			return
+		}
+		defer done()
+	}
+
+	// A retry of a commit that already went through gets the response of the original
+	// commit, instead of applying the mutations again.
+	if isMutation && req.CommitNow {
+		prev, ireq, err := idempotentCommit(ctx, requestFingerprint(req))
+		if err != nil {
+			return nil, err
+		}
+		if prev != nil {
+			return prev, nil
+		}
+		if ireq != nil {
+			ctx = withIdempotentRequest(ctx, ireq)
+			defer func() {
+				resp, rerr = ireq.done(ctx, resp, rerr)
+			}()
+		}
 	}
 
 	// We use defer here because for queries, startTs will be
@@ -1044,6 +1336,20 @@ func (s *Server) doQuery(ctx context.Context, req *api.Request, doAuth AuthMode)
 		TotalNs:           uint64((time.Since(l.Start)).Nanoseconds()),
 	}
 	md := metadata.Pairs(x.DgraphCostHeader, fmt.Sprint(resp.Metrics.NumUids["_total"]))
+	if detailedMetrics != nil {
+		js, err := json.Marshal(detailedMetrics)
+		if err != nil {
+			return nil, err
+		}
+		md.Set(x.DgraphMetricsHeader, string(js))
+	}
+	if report != nil {
+		js, err := json.Marshal(report)
+		if err != nil {
+			return nil, err
+		}
+		md.Set(x.DgraphReportHeader, string(js))
+	}
 	grpc.SendHeader(ctx, md)
 	return resp, nil
 }
@@ -1060,12 +1366,19 @@ func processQuery(ctx context.Context, qc *queryContext) (*api.Response, error)
 	if ctx.Err() != nil {
 		return resp, ctx.Err()
 	}
+	// A read-only query can read the data as of a past timestamp if it's still retained.
+	if qc.req.ReadOnly && qc.req.StartTs != 0 {
+		if err := worker.CheckHistoricalRead(qc.req.StartTs); err != nil {
+			return resp, err
+		}
+	}
 	if x.WorkerConfig.LudicrousMode {
 		qc.req.StartTs = posting.Oracle().MaxAssigned()
 	}
 	qr := query.Request{
 		Latency:  qc.latency,
 		GqlQuery: &qc.gqlRes,
+		MaxNodes: qc.limits.maxNodes,
 	}
 
 	// Here we try our best effort to not contact Zero for a timestamp. If we succeed,
@@ -1090,6 +1403,20 @@ func processQuery(ctx context.Context, qc *queryContext) (*api.Response, error)
 		}
 		qr.Cache = worker.NoCache
 	}
+	if qc.req.StartTs == 0 {
+		// A replica reads the data replicated so far from its primary.
+		if ts, ok := worker.ReplicaReadTs(); ok {
+			qc.req.StartTs = ts
+			qr.Cache = worker.NoCache
+		}
+	}
+	if qc.req.ReadOnly && qc.req.StartTs == 0 {
+		// A learner that's caught up recently enough reads its own data.
+		if ts, ok := worker.StaleReadTs(); ok {
+			qc.req.StartTs = ts
+			qr.Cache = worker.NoCache
+		}
+	}
 
 	if qc.req.StartTs == 0 {
 		assignTimestampStart := time.Now()
@@ -1102,6 +1429,9 @@ func processQuery(ctx context.Context, qc *queryContext) (*api.Response, error)
 
 	// Core processing happens here.
 	er, err := qr.Process(ctx)
+	if lerr, ok := err.(*x.LimitExceededError); ok {
+		return resp, lerr
+	}
 	if err != nil {
 		return resp, errors.Wrap(err, "")
 	}
@@ -1209,6 +1539,9 @@ func parseRequest(qc *queryContext) error {
 
 			qc.gmuList = append(qc.gmuList, gmu)
 		}
+		if err := resolveCondChains(qc.gmuList); err != nil {
+			return err
+		}
 
 		qc.uidRes = make(map[string][]string)
 		qc.valRes = make(map[string]map[uint64]types.Val)
@@ -1255,7 +1588,8 @@ func authorizeRequest(ctx context.Context, qc *queryContext) error {
 }
 
 // CommitOrAbort commits or aborts a transaction.
-func (s *Server) CommitOrAbort(ctx context.Context, tc *api.TxnContext) (*api.TxnContext, error) {
+func (s *Server) CommitOrAbort(ctx context.Context, tc *api.TxnContext) (
+	res *api.TxnContext, rerr error) {
 	ctx, span := otrace.StartSpan(ctx, "Server.CommitOrAbort")
 	defer span.End()
 
@@ -1270,6 +1604,32 @@ func (s *Server) CommitOrAbort(ctx context.Context, tc *api.TxnContext) (*api.Tx
 	}
 	annotateStartTs(span, tc.StartTs)
 
+	if !tc.Aborted {
+		// A retry of a commit that already went through gets the original commit timestamp.
+		prev, ireq, err := idempotentCommit(ctx, commitFingerprint(tc.StartTs))
+		if err != nil {
+			return &api.TxnContext{}, err
+		}
+		if prev != nil {
+			return prev.Txn, nil
+		}
+		if ireq != nil {
+			if ctx, err = ireq.commitContext(ctx, &api.Response{}); err != nil {
+				ireq.finish(nil)
+				return &api.TxnContext{}, err
+			}
+			defer func() {
+				var resp *api.Response
+				if rerr == nil && !res.Aborted {
+					resp = &api.Response{Txn: res}
+				}
+				if resp, rerr = ireq.done(ctx, resp, rerr); resp != nil {
+					res = resp.Txn
+				}
+			}()
+		}
+	}
+
 	span.Annotatef(nil, "Txn Context received: %+v", tc)
 	commitTs, err := worker.CommitOverNetwork(ctx, tc)
 	if err == dgo.ErrAborted {
@@ -1287,6 +1647,19 @@ func (s *Server) CommitOrAbort(ctx context.Context, tc *api.TxnContext) (*api.Tx
 	return tctx, err
 }
 
+// LeaseUids leases a contiguous range of num uids from Zero. Dgraph never assigns the uids of
+// the range to any node, so clients can assign them to their own nodes, e.g. when generating
+// the RDF files given to the bulk loader. Only guardians can lease uids if ACL is enabled.
+func (s *Server) LeaseUids(ctx context.Context, num uint64) (*pb.AssignedIds, error) {
+	if err := x.HealthCheck(); err != nil {
+		return nil, err
+	}
+	if err := AuthorizeGuardians(ctx); err != nil {
+		return nil, err
+	}
+	return worker.LeaseUids(ctx, num)
+}
+
 // CheckVersion returns the version of this Dgraph instance.
 func (s *Server) CheckVersion(ctx context.Context, c *api.Check) (v *api.Version, err error) {
 	if err := x.HealthCheck(); err != nil {
@@ -1302,6 +1675,13 @@ func (s *Server) CheckVersion(ctx context.Context, c *api.Check) (v *api.Version
 // HELPER FUNCTIONS
 //-------------------------------------------------------------------------------------------------
 func isMutationAllowed(ctx context.Context) bool {
+	if worker.Config.ReadOnly {
+		return false
+	}
+	// The data of a replica is only written by the replication from its primary.
+	if worker.IsReplica() {
+		return false
+	}
 	if worker.Config.MutationsMode != worker.DisallowMutations {
 		return true
 	}
@@ -1513,6 +1893,49 @@ func validateQuery(queries []*gql.GraphQuery) error {
 	return nil
 }
 
+// validateEdgeProperties checks that the edges whose properties are declared in the schema
+// updates are of type uid, either in the same updates or in the current schema.
+func validateEdgeProperties(ctx context.Context, updates []*pb.SchemaUpdate) error {
+	uidPreds := make(map[string]bool)
+	for _, update := range updates {
+		uidPreds[update.Predicate] = update.ValueType == pb.Posting_UID
+	}
+
+	var missing []string
+	for _, update := range updates {
+		if !x.IsEdgeProperty(update.Predicate) {
+			continue
+		}
+		edge, _ := x.ParseEdgeProperty(update.Predicate)
+		if _, ok := uidPreds[edge]; !ok {
+			missing = append(missing, edge)
+		}
+	}
+	if len(missing) > 0 {
+		nodes, err := worker.GetSchemaOverNetwork(ctx, &pb.SchemaRequest{
+			Predicates: missing,
+			Fields:     []string{"type"},
+		})
+		if err != nil {
+			return err
+		}
+		for _, node := range nodes {
+			uidPreds[node.Predicate] = node.Type == types.UidID.Name()
+		}
+	}
+
+	for _, update := range updates {
+		if !x.IsEdgeProperty(update.Predicate) {
+			continue
+		}
+		if edge, _ := x.ParseEdgeProperty(update.Predicate); !uidPreds[edge] {
+			return errors.Errorf("Edge property %s requires %s to be a predicate of type uid",
+				update.Predicate, edge)
+		}
+	}
+	return nil
+}
+
 func validatePredName(name string) error {
 	if len(name) > math.MaxUint16 {
 		return errors.Errorf("Predicate name length cannot be bigger than 2^16. Predicate: %v",
@@ -1528,14 +1951,21 @@ func formatTypes(typeList []*pb.TypeUpdate) []map[string]interface{} {
 	for _, typ := range typeList {
 		typeMap := make(map[string]interface{})
 		typeMap["name"] = typ.TypeName
-		fields := make([]map[string]string, len(typ.Fields))
+		fields := make([]map[string]interface{}, len(typ.Fields))
 
 		for i, field := range typ.Fields {
-			m := make(map[string]string, 1)
+			m := make(map[string]interface{}, 1)
 			m["name"] = field.Predicate
+			if field.NonNullable {
+				m["required"] = true
+			}
 			fields[i] = m
 		}
 		typeMap["fields"] = fields
+		if typ.Ttl != "" {
+			typeMap["ttl"] = typ.Ttl
+			typeMap["ttl_from"] = typ.TtlFrom
+		}
 
 		res = append(res, typeMap)
 	}
@@ -17,12 +17,18 @@
 package edgraph
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/dgraph-io/dgo/v200/protos/api"
 	"github.com/dgraph-io/dgraph/chunker"
+	"github.com/dgraph-io/dgraph/gql"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/types"
 	"github.com/dgraph-io/dgraph/x"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
 )
 
 func makeNquad(sub, pred string, val *api.Value) *api.NQuad {
@@ -119,3 +125,246 @@ func TestValidateKeys(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveCondChains(t *testing.T) {
+	gmuList := []*gql.Mutation{
+		{Cond: "@if(eq(len(v), 0))"},
+		{Cond: "@elseif (eq(len(v), 1))"},
+		{Cond: " @else "},
+		{},
+		{Cond: "@if(gt(len(u), 0))"},
+		{Cond: "@else"},
+	}
+	require.NoError(t, resolveCondChains(gmuList))
+	require.Equal(t, "@if(eq(len(v), 0))", gmuList[0].Cond)
+	require.Equal(t, "@if((eq(len(v), 1)) AND NOT (eq(len(v), 0)))", gmuList[1].Cond)
+	require.Equal(t, "@if(NOT (eq(len(v), 0)) AND NOT (eq(len(v), 1)))", gmuList[2].Cond)
+	require.Equal(t, "", gmuList[3].Cond)
+	require.Equal(t, "@if(gt(len(u), 0))", gmuList[4].Cond)
+	require.Equal(t, "@if(NOT (gt(len(u), 0)))", gmuList[5].Cond)
+}
+
+func TestResolveCondChainsErrors(t *testing.T) {
+	err := resolveCondChains([]*gql.Mutation{{Cond: "@elseif(eq(len(v), 1))"}})
+	require.EqualError(t, err, "@elseif must follow a mutation with @if or @elseif")
+
+	err = resolveCondChains([]*gql.Mutation{{Cond: "@if(eq(len(v), 1))"}, {}, {Cond: "@else"}})
+	require.EqualError(t, err, "@else must follow a mutation with @if or @elseif")
+
+	err = resolveCondChains([]*gql.Mutation{{Cond: "@if(eq(len(v), 1))"}, {Cond: "@else"},
+		{Cond: "@else"}})
+	require.EqualError(t, err, "@else must follow a mutation with @if or @elseif")
+}
+
+func TestDeleteNQuads(t *testing.T) {
+	star := &api.Value{Val: &api.Value_DefaultVal{DefaultVal: x.Star}}
+	require.Equal(t, []*api.NQuad{
+		makeNquad("1", x.Star, star),
+		makeNquad("2", x.Star, star),
+	}, deleteNQuads([]string{"1", "2"}, nil))
+	require.Equal(t, []*api.NQuad{
+		makeNquad("1", "name", star),
+		makeNquad("1", "age", star),
+	}, deleteNQuads([]string{"1"}, []string{"name", "age"}))
+}
+
+func TestDeleteByQueryInvalidRequest(t *testing.T) {
+	s := &Server{}
+	_, err := s.DeleteByQuery(context.Background(), &DeleteByQueryRequest{Query: " "})
+	require.EqualError(t, err, "delete by query requires a query")
+	_, err = s.DeleteByQuery(context.Background(), &DeleteByQueryRequest{
+		Query:     "{ v as var(func: has(name)) }",
+		BatchSize: -1,
+	})
+	require.EqualError(t, err, "batch size should be a positive number, got: -1")
+}
+
+func TestPageDeleteQuery(t *testing.T) {
+	page := func(q string, vars ...string) (*gql.GraphQuery, bool) {
+		res, err := gql.ParseWithDefinedVarsNeeded(gql.Request{Str: q})
+		require.NoError(t, err)
+		uidRes := make(map[string][]string)
+		for _, v := range vars {
+			uidRes[v] = nil
+		}
+		paged := pageDeleteQuery(res.Query, uidRes, &deletePage{first: 10, after: 0x20})
+		return res.Query[0], paged
+	}
+
+	gq, paged := page(`{ v as var(func: eq(name, "stale")) @filter(has(age)) }`, "v")
+	require.True(t, paged)
+	require.Equal(t, map[string]string{"first": "10", "after": "32"}, gq.Args)
+
+	// The block's own pagination, ordering and @cascade change the nodes it matches.
+	_, paged = page(`{ v as var(func: eq(name, "stale"), first: 5) }`, "v")
+	require.False(t, paged)
+	_, paged = page(`{ v as var(func: eq(name, "stale"), orderasc: name) }`, "v")
+	require.False(t, paged)
+	_, paged = page(`{ v as var(func: eq(name, "stale")) @cascade { name } }`, "v")
+	require.False(t, paged)
+
+	// Variables defined below the root or in more than one block aren't paged.
+	gq, paged = page(`{ var(func: eq(name, "stale")) { v as friend } }`, "v")
+	require.False(t, paged)
+	require.Empty(t, gq.Args)
+	_, paged = page(`{ v as var(func: has(name)) w as var(func: has(age)) }`, "v", "w")
+	require.False(t, paged)
+}
+
+func TestSavepointInvalidRequest(t *testing.T) {
+	s := &Server{}
+	ctx := metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs("savepoint", "sp", "rollback_to", "sp"))
+	_, err := s.Query(ctx, &api.Request{StartTs: 5})
+	require.EqualError(t, err, "savepoint and rollback_to can't be used together")
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs("savepoint", "sp"))
+	_, err = s.Query(ctx, &api.Request{StartTs: 5, Query: "{ q(func: uid(1)) { uid } }"})
+	require.EqualError(t, err, "a savepoint request can't contain a query or mutations")
+	_, err = s.Query(ctx, &api.Request{})
+	require.EqualError(t, err, "savepoints can only be used inside a transaction")
+	_, err = s.Query(ctx, &api.Request{StartTs: 5, CommitNow: true})
+	require.EqualError(t, err, "commitNow can't be used along with savepoints")
+}
+
+func TestCompareAndSetInvalidRequest(t *testing.T) {
+	s := &Server{}
+	_, err := s.CompareAndSet(context.Background(), &CompareAndSetRequest{Predicate: "count"})
+	require.EqualError(t, err, "compare and set requires the uid of a node")
+	_, err = s.CompareAndSet(context.Background(), &CompareAndSetRequest{Uid: 1})
+	require.EqualError(t, err, "compare and set requires a predicate")
+}
+
+func TestMutateBatchInvalidRequest(t *testing.T) {
+	s := &Server{}
+	_, err := s.MutateBatch(context.Background(), &BatchRequest{})
+	require.EqualError(t, err, "batch doesn't contain any request")
+	_, err = s.MutateBatch(context.Background(), &BatchRequest{
+		Requests:    []*api.Request{{}},
+		Concurrency: MaxBatchConcurrency + 1,
+	})
+	require.EqualError(t, err, "batch concurrency should be between 1 and 64, got: 65")
+
+	tests := []struct {
+		req *api.Request
+		err string
+	}{
+		{&api.Request{}, "request doesn't contain any mutation"},
+		{&api.Request{StartTs: 5, Mutations: []*api.Mutation{{SetNquads: []byte(`_:a <name> "a" .`)}}},
+			"requests of a batch can't be part of a transaction"},
+		{&api.Request{Mutations: []*api.Mutation{{SetNquads: []byte(`_:a <name> .`)}}},
+			"Invalid end of input. Input: [_:a <name> .]"},
+	}
+	for _, test := range tests {
+		resp := s.mutateBatchItem(context.Background(), test.req)
+		require.Equal(t, &BatchItemResponse{Status: BatchInvalid, Error: test.err}, resp)
+	}
+}
+
+func TestCasMatches(t *testing.T) {
+	val := func(tid types.TypeID, v interface{}) *types.Val {
+		return &types.Val{Tid: tid, Value: v}
+	}
+	require.True(t, casMatches(nil, nil))
+	require.False(t, casMatches(val(types.IntID, int64(1)), nil))
+	require.False(t, casMatches(nil, val(types.IntID, int64(1))))
+	require.True(t, casMatches(val(types.IntID, int64(1)), val(types.IntID, int64(1))))
+	require.False(t, casMatches(val(types.IntID, int64(1)), val(types.IntID, int64(2))))
+	require.True(t, casMatches(val(types.DateTimeID, time.Unix(10, 0).UTC()),
+		val(types.DateTimeID, time.Unix(10, 0).In(time.FixedZone("", 3600)))))
+}
+
+func TestUpdateMutationsEmptyVar(t *testing.T) {
+	defer func(limit int) { x.Config.MutationsNQuadLimit = limit }(x.Config.MutationsNQuadLimit)
+	x.Config.MutationsNQuadLimit = 100
+
+	qc := &queryContext{
+		gmuList: []*gql.Mutation{
+			{Set: []*api.NQuad{makeNquad("_:a", "name", &api.Value{})}},
+			{Set: []*api.NQuad{makeNquadEdge("_:post", "author", "uid(a)")}},
+			{Set: []*api.NQuad{makeNquadEdge("_:post", "editor", "uid(b)")}},
+		},
+		condVars: []string{"", "", ""},
+		uidRes:   map[string][]string{"a": nil, "b": nil},
+	}
+	require.NoError(t, updateMutations(qc))
+	// The empty variable a refers to the blank node _:a, while b gets a node of its own.
+	require.Equal(t, "_:a", qc.gmuList[1].Set[0].ObjectId)
+	require.Equal(t, "_:uid(b)", qc.gmuList[2].Set[0].ObjectId)
+
+	// The blank node isn't used if the mutation creating it doesn't run.
+	qc.gmuList = []*gql.Mutation{
+		{Set: []*api.NQuad{makeNquad("_:a", "name", &api.Value{})}},
+		{Set: []*api.NQuad{makeNquadEdge("_:post", "author", "uid(a)")}},
+	}
+	qc.condVars = []string{"cond", ""}
+	require.NoError(t, updateMutations(qc))
+	require.Empty(t, qc.gmuList[0].Set)
+	require.Equal(t, "_:uid(a)", qc.gmuList[1].Set[0].ObjectId)
+
+	// Within a mutation, the variable and the blank node are different nodes.
+	qc.gmuList = []*gql.Mutation{{Set: []*api.NQuad{
+		makeNquad("_:a", "name", &api.Value{}),
+		makeNquadEdge("_:post", "author", "uid(a)"),
+	}}}
+	qc.condVars = []string{""}
+	require.NoError(t, updateMutations(qc))
+	require.Equal(t, "_:uid(a)", qc.gmuList[0].Set[1].ObjectId)
+}
+
+func TestMutationReport(t *testing.T) {
+	str := func(s string) *api.Value {
+		return &api.Value{Val: &api.Value_DefaultVal{DefaultVal: s}}
+	}
+	gmuList := []*gql.Mutation{
+		{
+			Set: []*api.NQuad{
+				makeNquad("_:a", "name", str("Alice")),
+				makeNquadEdge("_:a", "friend", "0x5"),
+				makeNquad("0x5", "age", str("20")),
+			},
+		},
+		{
+			Del: []*api.NQuad{
+				makeNquad("0x6", x.Star, str(x.Star)),
+				makeNquad("0x7", "name", str(x.Star)),
+			},
+		},
+		// A conditional mutation whose condition was false.
+		{},
+	}
+	report := &MutationReport{}
+	require.NoError(t, report.collect(gmuList, map[string]uint64{"_:a": 10}))
+	require.Equal(t, []*MutationResult{
+		{
+			Created:    []string{"0xa"},
+			Updated:    []string{"0x5"},
+			Deleted:    []string{},
+			Predicates: []string{"age", "friend", "name"},
+		},
+		{
+			Created:    []string{},
+			Updated:    []string{},
+			Deleted:    []string{"0x6", "0x7"},
+			Predicates: []string{"*", "name"},
+		},
+		{
+			Created:    []string{},
+			Updated:    []string{},
+			Deleted:    []string{},
+			Predicates: []string{},
+		},
+	}, report.Mutations)
+}
+
+func TestStrictSchemaError(t *testing.T) {
+	require.NoError(t, checkStrictSchema(context.Background(), []*pb.DirectedEdge{
+		{Entity: 1, Attr: "undefined", Value: []byte("a")}}))
+
+	err := &StrictSchemaError{Predicates: []string{"nmae", "agee"}}
+	require.EqualError(t, err, "Strict schema mode is enabled, but the mutation uses predicates "+
+		"[nmae agee] not defined in the schema. Add them to the schema before using them.")
+	err = &StrictSchemaError{Types: []string{"Persn"}}
+	require.EqualError(t, err, "Strict schema mode is enabled, but the mutation uses types "+
+		"[Persn] not defined in the schema. Add them to the schema before using them.")
+}
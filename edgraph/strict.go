@@ -0,0 +1,128 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/worker"
+	"github.com/dgraph-io/dgraph/x"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// StrictSchemaError is returned when the strict schema mode is enabled and a mutation uses
+// predicates or types that aren't defined in the schema.
+type StrictSchemaError struct {
+	Predicates []string
+	Types      []string
+}
+
+func (e *StrictSchemaError) Error() string {
+	var missing []string
+	if len(e.Predicates) > 0 {
+		missing = append(missing, fmt.Sprintf("predicates [%s]", strings.Join(e.Predicates, " ")))
+	}
+	if len(e.Types) > 0 {
+		missing = append(missing, fmt.Sprintf("types [%s]", strings.Join(e.Types, " ")))
+	}
+	return fmt.Sprintf("Strict schema mode is enabled, but the mutation uses %s not defined in "+
+		"the schema. Add them to the schema before using them.", strings.Join(missing, " and "))
+}
+
+// GRPCStatus lets gRPC report the error to clients with the FailedPrecondition code.
+func (e *StrictSchemaError) GRPCStatus() *status.Status {
+	return status.New(codes.FailedPrecondition, e.Error())
+}
+
+// Extensions returns the fields to be reported in the extensions of an HTTP error.
+func (e *StrictSchemaError) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"code":       x.ErrorStrictSchema,
+		"predicates": e.Predicates,
+		"types":      e.Types,
+	}
+}
+
+// checkStrictSchema returns a StrictSchemaError if the strict schema mode is enabled and the
+// edges use predicates that aren't in the schema, or set the dgraph.type of nodes to types that
+// aren't defined.
+func checkStrictSchema(ctx context.Context, edges []*pb.DirectedEdge) error {
+	if !worker.StrictSchemaEnabled() {
+		return nil
+	}
+
+	predSet := make(map[string]struct{})
+	typeSet := make(map[string]struct{})
+	for _, edge := range edges {
+		if edge.Attr == x.Star {
+			continue
+		}
+		// The schema of the predicates served by this Alpha is known locally.
+		if _, ok := schema.State().Get(ctx, edge.Attr); !ok {
+			predSet[edge.Attr] = struct{}{}
+		}
+		if edge.Attr == "dgraph.type" && edge.Op == pb.DirectedEdge_SET &&
+			!bytes.Equal(edge.Value, []byte(x.Star)) {
+			typeSet[string(edge.Value)] = struct{}{}
+		}
+	}
+	preds := make([]string, 0, len(predSet))
+	for pred := range predSet {
+		preds = append(preds, pred)
+	}
+	sort.Strings(preds)
+
+	// The schema of the other predicates is kept by the groups serving them.
+	if len(preds) > 0 {
+		nodes, err := worker.GetSchemaOverNetwork(ctx, &pb.SchemaRequest{
+			Predicates: preds,
+			Fields:     []string{"type"},
+		})
+		if err != nil {
+			return err
+		}
+		for _, node := range nodes {
+			delete(predSet, node.Predicate)
+		}
+	}
+
+	var serr StrictSchemaError
+	for _, pred := range preds {
+		if _, ok := predSet[pred]; ok {
+			serr.Predicates = append(serr.Predicates, pred)
+		}
+	}
+	// Types are known by all the groups.
+	for typ := range typeSet {
+		if _, ok := schema.State().GetType(typ); !ok {
+			serr.Types = append(serr.Types, typ)
+		}
+	}
+	sort.Strings(serr.Types)
+
+	if len(serr.Predicates) > 0 || len(serr.Types) > 0 {
+		return &serr
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/worker"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/golang/glog"
+	ostats "go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+)
+
+// ttlRule is the expiry of the values of a predicate, or of the nodes of a type, with @ttl.
+type ttlRule struct {
+	// pred is the predicate whose values expire. It's empty if the nodes of typ expire.
+	pred string
+	typ  string
+	// The data expires once the datetime value of from is older than ttl.
+	ttl  time.Duration
+	from string
+}
+
+func (r ttlRule) String() string {
+	if r.typ != "" {
+		return "type " + r.typ
+	}
+	return "predicate " + r.pred
+}
+
+// query returns the query defining the variable expired, which holds the nodes whose data is
+// expired at the given time.
+func (r ttlRule) query(now time.Time) string {
+	var filter string
+	switch {
+	case r.typ != "":
+		filter = fmt.Sprintf(" @filter(type(%s))", r.typ)
+	case r.pred != r.from:
+		filter = fmt.Sprintf(" @filter(has(<%s>))", r.pred)
+	}
+	cutoff := now.Add(-r.ttl).UTC().Format(time.RFC3339Nano)
+	return fmt.Sprintf("{\n  expired as var(func: le(<%s>, %q))%s\n}", r.from, cutoff, filter)
+}
+
+// ttlRules returns the expiry rules of the predicates and types with @ttl.
+func ttlRules(nodes []*pb.SchemaNode, types []*pb.TypeUpdate) []ttlRule {
+	var rules []ttlRule
+	add := func(rule ttlRule, ttl string) {
+		var err error
+		if rule.ttl, err = time.ParseDuration(ttl); err != nil {
+			glog.Errorf("Invalid @ttl %q of %s: %v", ttl, rule, err)
+			return
+		}
+		rules = append(rules, rule)
+	}
+	for _, node := range nodes {
+		if node.Ttl != "" {
+			add(ttlRule{pred: node.Predicate, from: node.TtlFrom}, node.Ttl)
+		}
+	}
+	for _, typ := range types {
+		if typ.Ttl != "" {
+			add(ttlRule{typ: typ.TypeName, from: typ.TtlFrom}, typ.Ttl)
+		}
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].String() < rules[j].String() })
+	return rules
+}
+
+// ExpireTTLs deletes the expired data of the predicates and types with @ttl every
+// x.Config.TTLInterval, until the closer is signalled. Only the leader of group one looks for
+// expired data, so that it's deleted once in the cluster.
+func ExpireTTLs(closer *z.Closer) {
+	defer func() {
+		glog.Infoln("ExpireTTLs closed")
+		closer.Done()
+	}()
+	if x.Config.TTLInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(x.Config.TTLInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-closer.HasBeenClosed():
+			return
+		case <-ticker.C:
+			// The data of a replica expires on its primary.
+			if !worker.IsGroupOneLeader() || worker.IsReplica() {
+				continue
+			}
+			if err := expireTTLs(closer.Ctx(), time.Now()); err != nil {
+				glog.Errorf("While expiring data with @ttl: %v", err)
+			}
+		}
+	}
+}
+
+// expireTTLs deletes the data expired at the given time, in batches of x.Config.TTLBatchSize
+// nodes with a pause of x.Config.TTLBatchDelay between them.
+func expireTTLs(ctx context.Context, now time.Time) error {
+	nodes, err := worker.GetSchemaOverNetwork(ctx, &pb.SchemaRequest{Fields: []string{"ttl"}})
+	if err != nil {
+		return err
+	}
+	types, err := worker.GetTypes(ctx, &pb.SchemaRequest{})
+	if err != nil {
+		return err
+	}
+
+	// The expiry isn't done on behalf of any user, so it isn't checked against the ACLs.
+	ctx = context.WithValue(ctx, Authorize, false)
+	for _, rule := range ttlRules(nodes, types) {
+		start := time.Now()
+		var deleted int
+		req := &DeleteByQueryRequest{
+			Query:     rule.query(now),
+			Var:       "expired",
+			BatchSize: x.Config.TTLBatchSize,
+			Progress: func(resp *DeleteByQueryResponse) {
+				ostats.Record(ctx, x.TTLExpired.M(int64(resp.Deleted-deleted)))
+				deleted = resp.Deleted
+				select {
+				case <-ctx.Done():
+				case <-time.After(x.Config.TTLBatchDelay):
+				}
+			},
+		}
+		if rule.pred != "" {
+			req.Predicates = []string{rule.pred}
+		}
+
+		status := x.TagValueStatusOK
+		if _, err := (&Server{}).DeleteByQuery(ctx, req); err != nil {
+			glog.Errorf("While expiring the data of %s: %v", rule, err)
+			status = x.TagValueStatusError
+		} else if deleted > 0 {
+			glog.Infof("Expired the data of %d nodes for %s", deleted, rule)
+		}
+		_ = ostats.RecordWithTags(ctx, []tag.Mutator{
+			tag.Upsert(x.KeyMethod, "ttl.Expire"),
+			tag.Upsert(x.KeyStatus, status),
+		}, x.LatencyMs.M(float64(time.Since(start))/1e6))
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+	return nil
+}
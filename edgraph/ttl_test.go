@@ -0,0 +1,57 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/dgraph/gql"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTTLRules(t *testing.T) {
+	nodes := []*pb.SchemaNode{
+		{Predicate: "name"},
+		{Predicate: "token", Ttl: "1h0m0s", TtlFrom: "issuedAt"},
+		{Predicate: "lastSeen", Ttl: "720h0m0s", TtlFrom: "lastSeen"},
+		{Predicate: "broken", Ttl: "soon", TtlFrom: "lastSeen"},
+	}
+	types := []*pb.TypeUpdate{
+		{TypeName: "Person"},
+		{TypeName: "Session", Ttl: "24h0m0s", TtlFrom: "createdAt"},
+	}
+	rules := ttlRules(nodes, types)
+	require.Equal(t, []ttlRule{
+		{pred: "lastSeen", ttl: 720 * time.Hour, from: "lastSeen"},
+		{pred: "token", ttl: time.Hour, from: "issuedAt"},
+		{typ: "Session", ttl: 24 * time.Hour, from: "createdAt"},
+	}, rules)
+
+	now := time.Date(2020, 6, 2, 12, 30, 0, 0, time.FixedZone("", 3600))
+	require.Equal(t, "{\n  expired as var(func: le(<lastSeen>, \"2020-05-03T11:30:00Z\"))\n}",
+		rules[0].query(now))
+	require.Equal(t, "{\n  expired as var(func: le(<issuedAt>, \"2020-06-02T10:30:00Z\"))"+
+		" @filter(has(<token>))\n}", rules[1].query(now))
+	require.Equal(t, "{\n  expired as var(func: le(<createdAt>, \"2020-06-01T11:30:00Z\"))"+
+		" @filter(type(Session))\n}", rules[2].query(now))
+	for _, rule := range rules {
+		_, err := gql.ParseWithDefinedVarsNeeded(gql.Request{Str: rule.query(now)})
+		require.NoError(t, err, rule.String())
+	}
+}
@@ -149,9 +149,36 @@ func TestCurlAuthorization(t *testing.T) {
 	})
 }
 
+func TestCurlLeaseUids(t *testing.T) {
+	resetUser(t)
+
+	leaseArgs := func(jwt string) []string {
+		return []string{"-H", fmt.Sprintf("X-Dgraph-AccessToken:%s", jwt),
+			"-X", "POST", curlLeaseUidsEndpoint + "?num=10"}
+	}
+
+	// Only guardians can lease uids.
+	accessJwt, _, err := testutil.HttpLogin(&testutil.LoginParams{
+		Endpoint: adminEndpoint,
+		UserID:   userid,
+		Passwd:   userpassword,
+	})
+	require.NoError(t, err, "login failed")
+	testutil.VerifyCurlCmd(t, leaseArgs(accessJwt), &testutil.CurlFailureConfig{
+		ShouldFail:   true,
+		DgraphErrMsg: "PermissionDenied",
+	})
+
+	accessJwt, _ = testutil.GrootHttpLogin(adminEndpoint)
+	testutil.VerifyCurlCmd(t, leaseArgs(accessJwt), &testutil.CurlFailureConfig{
+		ShouldFail: false,
+	})
+}
+
 const (
-	curlLoginEndpoint  = "localhost:8180/login"
-	curlQueryEndpoint  = "localhost:8180/query"
-	curlMutateEndpoint = "localhost:8180/mutate"
-	curlAlterEndpoint  = "localhost:8180/alter"
+	curlLoginEndpoint     = "localhost:8180/login"
+	curlQueryEndpoint     = "localhost:8180/query"
+	curlMutateEndpoint    = "localhost:8180/mutate"
+	curlAlterEndpoint     = "localhost:8180/alter"
+	curlLeaseUidsEndpoint = "localhost:8180/leaseUids"
 )
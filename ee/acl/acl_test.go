@@ -2146,6 +2146,19 @@ func TestSchemaQueryWithACL(t *testing.T) {
       	],
       	"upsert": true
 	},
+    {
+      "predicate": "dgraph.graphql.p_query",
+      "type": "string"
+    },
+    {
+      "predicate": "dgraph.graphql.p_sha256hash",
+      "type": "string",
+      "index": true,
+      "tokenizer": [
+        "exact"
+      ],
+      "upsert": true
+    },
     {
       "predicate": "dgraph.graphql.schema",
       "type": "string"
@@ -2184,6 +2197,18 @@ func TestSchemaQueryWithACL(t *testing.T) {
       ],
       "upsert": true
     },
+    {
+      "predicate": "dgraph.schema.change",
+      "type": "string"
+    },
+    {
+      "predicate": "dgraph.schema.changed_at",
+      "type": "datetime"
+    },
+    {
+      "predicate": "dgraph.schema.changed_by",
+      "type": "string"
+    },
     {
       "predicate": "dgraph.type",
       "type": "string",
@@ -2231,6 +2256,31 @@ func TestSchemaQueryWithACL(t *testing.T) {
 		],
 		"name": "dgraph.graphql.history"
 	},
+    {
+      "fields": [
+        {
+          "name": "dgraph.graphql.p_query"
+        },
+        {
+          "name": "dgraph.graphql.p_sha256hash"
+        }
+      ],
+      "name": "dgraph.graphql.persisted_query"
+    },
+    {
+      "fields": [
+        {
+          "name": "dgraph.schema.change"
+        },
+        {
+          "name": "dgraph.schema.changed_by"
+        },
+        {
+          "name": "dgraph.schema.changed_at"
+        }
+      ],
+      "name": "dgraph.schema.history"
+    },
     {
       "fields": [
         {
@@ -2289,6 +2339,14 @@ func TestSchemaQueryWithACL(t *testing.T) {
 		"fields": [],
 		"name": "dgraph.graphql.history"
 	},
+    {
+      "fields": [],
+      "name": "dgraph.graphql.persisted_query"
+    },
+    {
+      "fields": [],
+      "name": "dgraph.schema.history"
+    },
     {
       "fields": [],
       "name": "dgraph.type.Group"
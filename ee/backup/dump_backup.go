@@ -0,0 +1,242 @@
+// +build !oss
+
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Dgraph Community License (the "License"); you
+ * may not use this file except in compliance with the License. You
+ * may obtain a copy of the License at
+ *
+ *     https://github.com/dgraph-io/dgraph/blob/master/licenses/DCL.txt
+ */
+
+package backup
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/dgraph-io/dgraph/ee/enc"
+	"github.com/dgraph-io/dgraph/posting"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/worker"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// DumpBackup is the sub-command used to dump the contents of a backup without restoring it.
+var DumpBackup x.SubCommand
+
+func initDumpBackup() {
+	DumpBackup.Cmd = &cobra.Command{
+		Use:   "dump-backup",
+		Short: "Dump the data or the schema of a backup series, without restoring it",
+		Long: `
+dump-backup reads a backup series and prints the values and edges of its predicates, or their
+schema, without the need to restore it to a cluster first. It is a dump tool meant for ad-hoc
+investigation of historical data; it does not run DQL queries.
+
+The --location flag indicates a source URI with Dgraph backup objects. This URI supports all
+the schemes used for backup.
+
+If --pred is set, only that predicate is loaded from the backup, and the backup files of the
+groups that do not serve it are not read at all. Otherwise, the whole backup series is loaded.
+
+By default, the loaded data is held in memory. Set --tmp to a directory to store it on disk
+instead while the command runs, which is needed for backups larger than the memory available.
+The directory is deleted when the command exits.
+
+The dump is written to stdout and the status messages to stderr.
+
+Usage examples:
+
+# Print the schema of the latest backup series stored in S3:
+$ dgraph dump-backup -l s3://s3.us-west-2.amazonaws.com/srfrog/dgraph --schema
+
+# Print all the values of the name predicate:
+$ dgraph dump-backup -l /var/backups/dgraph --pred name
+
+# Print the edges of a single node:
+$ dgraph dump-backup -l /var/backups/dgraph --pred friend --uid 0x2a
+
+# Print the values of the name predicate, storing the backup on disk under /tmp:
+$ dgraph dump-backup -l /var/backups/dgraph --tmp /tmp --pred name
+		`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer x.StartProfile(DumpBackup.Conf).Stop()
+			if err := runDumpBackupCmd(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	flag := DumpBackup.Cmd.Flags()
+	flag.StringVarP(&opt.location, "location", "l", "",
+		"Sets the source location URI (required).")
+	flag.StringVarP(&opt.backupId, "backup_id", "", "", "The ID of the backup series to "+
+		"attach. If empty, it will attach the latest series.")
+	flag.StringVarP(&opt.predicate, "pred", "r", "", "Only output the given predicate.")
+	flag.StringVarP(&opt.uid, "uid", "u", "", "Only output the given node. Accepts decimal "+
+		"or hex (0x) uids.")
+	flag.BoolVar(&opt.schema, "schema", false, "Output the schema instead of the data.")
+	flag.StringVar(&opt.tmpDir, "tmp", "", "Store the backup on disk in a temporary directory "+
+		"created inside this one, instead of in memory.")
+	enc.RegisterFlags(flag)
+	_ = DumpBackup.Cmd.MarkFlagRequired("location")
+}
+
+func runDumpBackupCmd() error {
+	var err error
+	if opt.key, err = enc.ReadKey(DumpBackup.Conf); err != nil {
+		return err
+	}
+
+	var uid uint64
+	if opt.uid != "" {
+		if uid, err = strconv.ParseUint(opt.uid, 0, 64); err != nil {
+			return errors.Wrapf(err, "while parsing uid %s", opt.uid)
+		}
+	}
+
+	var dir string
+	if opt.tmpDir != "" {
+		if dir, err = ioutil.TempDir(opt.tmpDir, "dump-backup"); err != nil {
+			return errors.Wrapf(err, "while creating temporary directory")
+		}
+		defer os.RemoveAll(dir)
+	}
+
+	var attrs []string
+	if opt.predicate != "" {
+		attrs = []string{opt.predicate}
+	}
+
+	fmt.Fprintln(os.Stderr, "Loading backups from:", opt.location)
+	db, result := worker.AttachBackup(opt.location, opt.backupId, dir, attrs, nil, opt.key)
+	if result.Err != nil {
+		return result.Err
+	}
+	defer db.Close()
+	fmt.Fprintf(os.Stderr, "Backup version: %d\n", result.Version)
+
+	// The posting lists split in several parts, the large values and the encoded values are read
+	// through the posting package, which decodes the values with the schema of the backup.
+	posting.Init(db, 0)
+	schema.Init(db)
+	if err := schema.LoadFromDb(); err != nil {
+		return errors.Wrapf(err, "while loading the schema of the backup")
+	}
+
+	if opt.schema {
+		return printBackupSchema(db)
+	}
+	return printBackupData(db, opt.predicate, uid)
+}
+
+func printBackupSchema(db *badger.DB) error {
+	txn := db.NewTransactionAt(math.MaxUint64, false)
+	defer txn.Discard()
+
+	itr := txn.NewIterator(badger.DefaultIteratorOptions)
+	defer itr.Close()
+
+	prefix := x.SchemaPrefix()
+	for itr.Seek(prefix); itr.ValidForPrefix(prefix); itr.Next() {
+		item := itr.Item()
+		pk, err := x.Parse(item.Key())
+		if err != nil {
+			return err
+		}
+		if opt.predicate != "" && pk.Attr != opt.predicate {
+			continue
+		}
+		var su pb.SchemaUpdate
+		err = item.Value(func(val []byte) error {
+			return su.Unmarshal(val)
+		})
+		if err != nil {
+			return errors.Wrapf(err, "while reading schema of %s", pk.Attr)
+		}
+		fmt.Printf("%s: %s %v\n", pk.Attr, types.TypeID(su.ValueType).Name(), su.Tokenizer)
+	}
+	return nil
+}
+
+func printBackupData(db *badger.DB, pred string, uid uint64) error {
+	txn := db.NewTransactionAt(math.MaxUint64, false)
+	defer txn.Discard()
+
+	iopts := badger.DefaultIteratorOptions
+	iopts.AllVersions = true
+	iopts.PrefetchValues = false
+	itr := txn.NewIterator(iopts)
+	defer itr.Close()
+
+	var prefix []byte
+	switch {
+	case pred != "" && uid > 0:
+		prefix = x.DataKey(pred, uid)
+	case pred != "":
+		prefix = x.PredicatePrefix(pred)
+	}
+
+	var numKeys int
+	for itr.Seek(prefix); itr.ValidForPrefix(prefix); {
+		item := itr.Item()
+		key := item.KeyCopy(nil)
+		pk, err := x.Parse(key)
+		if err != nil {
+			return err
+		}
+		if !pk.IsData() || pk.HasStartUid || (uid > 0 && pk.Uid != uid) {
+			// Skip all the versions of this key.
+			for ; itr.Valid() && bytes.Equal(itr.Item().Key(), key); itr.Next() {
+			}
+			continue
+		}
+
+		pl, err := posting.ReadPostingList(key, itr)
+		if err != nil {
+			return err
+		}
+		// ReadPostingList stops at the latest complete version, so skip the older ones.
+		for ; itr.Valid() && bytes.Equal(itr.Item().Key(), key); itr.Next() {
+		}
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, "%#x %s:", pk.Uid, pk.Attr)
+		err = pl.Iterate(math.MaxUint64, 0, func(p *pb.Posting) error {
+			if len(p.Value) == 0 {
+				fmt.Fprintf(&buf, " %#x", p.Uid)
+				return nil
+			}
+			out, err := types.Convert(types.Val{
+				Tid:   types.TypeID(p.ValType),
+				Value: p.Value,
+			}, types.StringID)
+			if err != nil {
+				fmt.Fprintf(&buf, " %q", p.Value)
+			} else {
+				fmt.Fprintf(&buf, " %q", out.Value)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Println(buf.String())
+		numKeys++
+	}
+	fmt.Fprintf(os.Stderr, "Found %d keys\n", numKeys)
+	return nil
+}
@@ -44,12 +44,17 @@ var opt struct {
 	forceZero   bool
 	destination string
 	format      string
+	predicate   string
+	uid         string
+	schema      bool
+	tmpDir      string
 }
 
 func init() {
 	initRestore()
 	initBackupLs()
 	initExportBackup()
+	initDumpBackup()
 }
 
 func initRestore() {
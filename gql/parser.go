@@ -32,10 +32,12 @@ import (
 
 const (
 	uidFunc   = "uid"
+	unionFunc = "union"
 	valueFunc = "val"
 	typFunc   = "type"
 	lenFunc   = "len"
 	countFunc = "count"
+	depthFunc = "depth"
 	uidInFunc = "uid_in"
 )
 
@@ -51,6 +53,7 @@ type GraphQuery struct {
 	Langs      []string
 	Alias      string
 	IsCount    bool
+	IsDistinct bool // count(distinct friend) or count(distinct val(x))
 	IsInternal bool
 	IsGroupby  bool
 	Var        string
@@ -65,10 +68,12 @@ type GraphQuery struct {
 	Filter           *FilterTree
 	MathExp          *MathTree
 	Normalize        bool
+	NormalizeLists   bool // @normalize(lists) keeps the lists of nodes as arrays.
 	Recurse          bool
 	RecurseArgs      RecurseArgs
 	ShortestPathArgs ShortestPathArgs
 	Cascade          []string
+	CascadeDepth     uint64 // The number of levels @cascade applies to, 0 meaning all of them.
 	IgnoreReflex     bool
 	Facets           *pb.FacetParams
 	FacetsFilter     *FilterTree
@@ -92,6 +97,10 @@ type GraphQuery struct {
 type RecurseArgs struct {
 	Depth     uint64
 	AllowLoop bool
+	// DepthVar is the value variable storing the depth at which each node was first reached.
+	DepthVar string
+	// Undirected is true if the edges are followed in both directions.
+	Undirected bool
 	varMap    map[string]string //varMap holds the variable args name. So, that we can substitute the
 	// argument in the substitution part.
 }
@@ -185,6 +194,13 @@ type Function struct {
 	IsCount    bool         // gt(count(friends),0)
 	IsValueVar bool         // eq(val(s), 5)
 	IsLenVar   bool         // eq(len(s), 5)
+	IsDepth    bool         // le(depth(), 2), only allowed inside @recurse
+	// CountFilter is applied to the edges being counted before the count is compared,
+	// e.g. ge(count(post @filter(gt(date, "2020"))), 3).
+	CountFilter *FilterTree
+	// Union holds the functions whose results are merged by union() at root,
+	// e.g. union(eq(name, "Alice"), uid(0x1)).
+	Union []*Function
 }
 
 // filterOpPrecedence is a map from filterOp (a string) to its precedence.
@@ -231,6 +247,11 @@ func (f *Function) IsPasswordVerifier() bool {
 	return f.Name == "checkpwd"
 }
 
+// IsRelevanceScore returns true if the function name is "bm25".
+func (f *Function) IsRelevanceScore() bool {
+	return f.Name == "bm25"
+}
+
 // DebugPrint is useful for debugging.
 func (gq *GraphQuery) DebugPrint(prefix string) {
 	glog.Infof("%s[%x %q %q]\n", prefix, gq.UID, gq.Attr, gq.Alias)
@@ -399,6 +420,11 @@ func substituteVariables(gq *GraphQuery, vmap varMap) error {
 				}
 			}
 		}
+		for _, f := range gq.Func.Union {
+			if err := substituteVariablesFunc(f, vmap); err != nil {
+				return err
+			}
+		}
 	}
 
 	for _, child := range gq.Children {
@@ -445,6 +471,19 @@ func substituteVariables(gq *GraphQuery, vmap varMap) error {
 			gq.RecurseArgs.AllowLoop = allowLoop
 		}
 
+		// Update undirected if it's given as a variable in the query.
+		varName, ok = gq.RecurseArgs.varMap["undirected"]
+		if ok {
+			val, ok := vmap[varName]
+			if !ok {
+				return errors.Errorf("variable %s not defined", varName)
+			}
+			undirected, err := strconv.ParseBool(val.Value)
+			if err != nil {
+				return errors.Wrapf(err, varName+" should be type of boolean")
+			}
+			gq.RecurseArgs.Undirected = undirected
+		}
 	}
 	return nil
 }
@@ -464,45 +503,57 @@ func regExpVariableFilter(f *Function, idx int) error {
 	return nil
 }
 
-func substituteVariablesFilter(f *FilterTree, vmap varMap) error {
-	if f == nil {
-		return nil
+// substituteVariablesFunc substitutes the GraphQL variables used in the arguments of a function
+// that isn't the function at root of a query block.
+func substituteVariablesFunc(f *Function, vmap varMap) error {
+	if err := substituteVar(f.Attr, &f.Attr, vmap); err != nil {
+		return err
 	}
 
-	if f.Func != nil {
-		if err := substituteVar(f.Func.Attr, &f.Func.Attr, vmap); err != nil {
-			return err
+	for idx, v := range f.Args {
+		if !v.IsGraphQLVar {
+			continue
 		}
-
-		for idx, v := range f.Func.Args {
-			if !v.IsGraphQLVar {
-				continue
+		if f.Name == uidFunc {
+			// This is to support GraphQL variables in uid functions.
+			idVal, ok := vmap[v.Value]
+			if !ok {
+				return errors.Errorf("Couldn't find value for GraphQL variable: [%s]", v.Value)
 			}
-			if f.Func.Name == uidFunc {
-				// This is to support GraphQL variables in uid functions.
-				idVal, ok := vmap[v.Value]
-				if !ok {
-					return errors.Errorf("Couldn't find value for GraphQL variable: [%s]", v.Value)
-				}
-				uids, err := parseID(idVal.Value)
-				if err != nil {
-					return err
-				}
-				f.Func.UID = append(f.Func.UID, uids...)
-				continue
+			uids, err := parseID(idVal.Value)
+			if err != nil {
+				return err
 			}
+			f.UID = append(f.UID, uids...)
+			continue
+		}
 
-			if err := substituteVar(v.Value, &f.Func.Args[idx].Value, vmap); err != nil {
+		if err := substituteVar(v.Value, &f.Args[idx].Value, vmap); err != nil {
+			return err
+		}
+
+		// We need to parse the regexp after substituting it from a GraphQL Variable.
+		_, ok := vmap[v.Value]
+		if f.Name == "regexp" && ok {
+			if err := regExpVariableFilter(f, idx); err != nil {
 				return err
 			}
+		}
+	}
+	return nil
+}
 
-			// We need to parse the regexp after substituting it from a GraphQL Variable.
-			_, ok := vmap[v.Value]
-			if f.Func.Name == "regexp" && ok {
-				if err := regExpVariableFilter(f.Func, idx); err != nil {
-					return err
-				}
-			}
+func substituteVariablesFilter(f *FilterTree, vmap varMap) error {
+	if f == nil {
+		return nil
+	}
+
+	if f.Func != nil {
+		if err := substituteVariablesFunc(f.Func, vmap); err != nil {
+			return err
+		}
+		if err := substituteVariablesFilter(f.Func.CountFilter, vmap); err != nil {
+			return err
 		}
 	}
 
@@ -557,6 +608,17 @@ func Parse(r Request) (Result, error) {
 // The variable name v needs to be passed through the needVars parameter. Otherwise, an error
 // is reported complaining that the variable v is defined but not used in the query block.
 func ParseWithNeedVars(r Request, needVars []string) (res Result, rerr error) {
+	return parse(r, needVars, false)
+}
+
+// ParseWithDefinedVarsNeeded performs parsing of a query whose variables are all used outside of
+// it, as the query of a delete by query request, so defining a variable that isn't used in the
+// query isn't an error.
+func ParseWithDefinedVarsNeeded(r Request) (Result, error) {
+	return parse(r, nil, true)
+}
+
+func parse(r Request, needVars []string, needDefined bool) (res Result, rerr error) {
 	query := r.Str
 	vmap := convertToVarMap(r.Variables)
 
@@ -640,6 +702,9 @@ func ParseWithNeedVars(r Request, needVars []string) (res Result, rerr error) {
 		// Add the variables that are needed outside the query block.
 		// For example, mutation block in upsert block will be using
 		// variables from the query block that is getting parsed here.
+		if needDefined {
+			_, needVars = flatten(res.QueryVars)
+		}
 		if len(needVars) != 0 {
 			allVars = append(allVars, &Vars{Needs: needVars})
 		}
@@ -710,6 +775,9 @@ func (gq *GraphQuery) collectVars(v *Vars) {
 	if gq.Var != "" {
 		v.Defines = append(v.Defines, gq.Var)
 	}
+	if gq.RecurseArgs.DepthVar != "" {
+		v.Defines = append(v.Defines, gq.RecurseArgs.DepthVar)
+	}
 	if gq.FacetVar != nil {
 		for _, va := range gq.FacetVar {
 			v.Defines = append(v.Defines, va)
@@ -757,6 +825,9 @@ func (f *FilterTree) collectVars(v *Vars) {
 		for _, va := range f.Func.NeedsVar {
 			v.Needs = append(v.Needs, va.Name)
 		}
+		if f.Func.CountFilter != nil {
+			f.Func.CountFilter.collectVars(v)
+		}
 	}
 	for _, fch := range f.Child {
 		fch.collectVars(v)
@@ -767,6 +838,9 @@ func (f *FilterTree) hasVars() bool {
 	if (f.Func != nil) && (len(f.Func.NeedsVar) > 0) {
 		return true
 	}
+	if f.Func != nil && f.Func.CountFilter != nil && f.Func.CountFilter.hasVars() {
+		return true
+	}
 	for _, fch := range f.Child {
 		if fch.hasVars() {
 			return true
@@ -903,6 +977,29 @@ func parseRecurseArgs(it *lex.ItemIterator, gq *GraphQuery) error {
 				}
 				gq.RecurseArgs.AllowLoop = allowLoop
 			}
+		case "undirected":
+			if item.Typ == itemDollar {
+				// Consume the variable name.
+				varName, err := parseVarName(it)
+				if err != nil {
+					return err
+				}
+				if gq.RecurseArgs.varMap == nil {
+					gq.RecurseArgs.varMap = make(map[string]string)
+				}
+				gq.RecurseArgs.varMap["undirected"] = varName
+			} else {
+				undirected, err := strconv.ParseBool(val)
+				if err != nil {
+					return errors.New("Value inside undirected should be type of boolean")
+				}
+				gq.RecurseArgs.Undirected = undirected
+			}
+		case "depth_var":
+			if item.Typ != itemName {
+				return item.Errorf("Expected variable name inside @recurse() for key: %s", key)
+			}
+			gq.RecurseArgs.DepthVar = val
 		default:
 			return item.Errorf("Unexpected key: [%s] inside @recurse block", key)
 		}
@@ -957,7 +1054,9 @@ L:
 				gq.Filter = filter
 
 			case "normalize":
-				gq.Normalize = true
+				if err := parseNormalize(it, gq); err != nil {
+					return nil, err
+				}
 			case "cascade":
 				if err := parseCascade(it, gq); err != nil {
 					return nil, err
@@ -1392,9 +1491,11 @@ func (f *FilterTree) stringHelper(buf *bytes.Buffer) {
 		x.Check2(buf.WriteRune('('))
 		x.Check2(buf.WriteString(f.Func.Name))
 
-		if len(f.Func.Attr) > 0 {
+		if len(f.Func.Attr) > 0 || f.Func.IsDepth {
 			x.Check2(buf.WriteRune(' '))
 			switch {
+			case f.Func.IsDepth:
+				x.Check2(buf.WriteString("depth("))
 			case f.Func.IsCount:
 				x.Check2(buf.WriteString("count("))
 			case f.Func.IsValueVar:
@@ -1403,7 +1504,11 @@ func (f *FilterTree) stringHelper(buf *bytes.Buffer) {
 				x.Check2(buf.WriteString("len("))
 			}
 			x.Check2(buf.WriteString(f.Func.Attr))
-			if f.Func.IsCount || f.Func.IsValueVar || f.Func.IsLenVar {
+			if f.Func.CountFilter != nil {
+				x.Check2(buf.WriteString(" @filter"))
+				f.Func.CountFilter.stringHelper(buf)
+			}
+			if f.Func.IsCount || f.Func.IsValueVar || f.Func.IsLenVar || f.Func.IsDepth {
 				x.Check2(buf.WriteRune(')'))
 			}
 			if len(f.Func.Lang) > 0 {
@@ -1619,12 +1724,53 @@ func validFuncName(name string) bool {
 
 	switch name {
 	case "regexp", "anyofterms", "allofterms", "alloftext", "anyoftext",
-		"has", "uid", "uid_in", "anyof", "allof", "type", "match":
+		"has", "hasall", "uid", "uid_in", "anyof", "allof", "type", "match", "similar_to",
+		unionFunc:
 		return true
 	}
 	return false
 }
 
+// parseUnion parses the union function at root, whose arguments are the root functions whose
+// results are merged, e.g. union(eq(name, "Alice"), anyofterms(nickname, "bob")).
+func parseUnion(it *lex.ItemIterator) (*Function, error) {
+	it.Next() // Consume the function name.
+	if _, ok := tryParseItemType(it, itemLeftRound); !ok {
+		return nil, it.Errorf("Expected ( after func name [%s]", unionFunc)
+	}
+
+	function := &Function{Name: unionFunc}
+	for {
+		if peekIt, err := it.Peek(1); err == nil && strings.ToLower(peekIt[0].Val) == unionFunc {
+			return nil, peekIt[0].Errorf("union can't be used inside union")
+		}
+		// The uids given to uid() are stored in the function instead of the query block.
+		sub, err := parseFunction(it, nil)
+		if err != nil {
+			return nil, err
+		}
+		if !validFuncName(sub.Name) {
+			return nil, it.Errorf("Function name: %s is not valid inside union", sub.Name)
+		}
+		function.Union = append(function.Union, sub)
+		function.NeedsVar = append(function.NeedsVar, sub.NeedsVar...)
+
+		if !it.Next() {
+			return nil, it.Errorf("Invalid use of union")
+		}
+		switch item := it.Item(); item.Typ {
+		case itemRightRound:
+			if len(function.Union) < 2 {
+				return nil, item.Errorf("union requires at least two functions")
+			}
+			return function, nil
+		case itemComma:
+		default:
+			return nil, item.Errorf("Expected , or ) in union, got: %s", item.Val)
+		}
+	}
+}
+
 type regexArgs struct {
 	expr  string
 	flags string
@@ -1657,6 +1803,9 @@ L:
 
 		name := collectName(it, item.Val)
 		function.Name = strings.ToLower(name)
+		if function.Name == unionFunc {
+			return nil, item.Errorf("union is only supported as the root function")
+		}
 		if _, ok := tryParseItemType(it, itemLeftRound); !ok {
 			return nil, it.Errorf("Expected ( after func name [%s]", function.Name)
 		}
@@ -1725,6 +1874,17 @@ L:
 				case countFunc:
 					function.Attr = nestedFunc.Attr
 					function.IsCount = true
+					function.CountFilter = nestedFunc.CountFilter
+				case depthFunc:
+					if !IsInequalityFn(function.Name) {
+						return nil,
+							itemInFunc.Errorf("depth function only allowed inside inequality" +
+								" function")
+					}
+					if len(nestedFunc.Attr) > 0 || len(nestedFunc.Args) > 0 {
+						return nil, itemInFunc.Errorf("depth function doesn't take any arguments")
+					}
+					function.IsDepth = true
 				case uidFunc:
 					// TODO (Anurag): See if is is possible to support uid(1,2,3) when
 					// uid is nested inside a function like @filter(uid_in(predicate, uid()))
@@ -1750,6 +1910,26 @@ L:
 					return nil, itemInFunc.Errorf("Invalid usage of '@' in function " +
 						"argument, must only appear immediately after attr.")
 				}
+				if next, ok := it.PeekOne(); ok && next.Typ == itemName &&
+					strings.ToLower(next.Val) == "filter" {
+					// The edges being counted can be filtered, count(post @filter(...)).
+					if function.Name != countFunc {
+						return nil, itemInFunc.Errorf("@filter is only allowed inside " +
+							"count function")
+					}
+					if function.CountFilter != nil {
+						return nil, itemInFunc.Errorf("Only one @filter allowed inside " +
+							"count function")
+					}
+					it.Next()
+					filter, err := parseFilter(it)
+					if err != nil {
+						return nil, err
+					}
+					function.CountFilter = filter
+					expectArg = false
+					continue
+				}
 				expectLang = true
 				continue
 			case itemMathOp:
@@ -1783,6 +1963,10 @@ L:
 				case function.Name == "uid_in":
 					err = parseFuncArgs(it, function)
 
+				case function.Name == "similar_to":
+					// The vector is put back together like the coordinates of geo functions.
+					err = parseGeoArgs(it, function)
+
 				default:
 					err = itemInFunc.Errorf("Unexpected character [ while parsing request.")
 				}
@@ -1848,7 +2032,7 @@ L:
 			// Unlike other functions, uid function has no attribute, everything is args.
 			switch {
 			case len(function.Attr) == 0 && function.Name != uidFunc &&
-				function.Name != typFunc:
+				function.Name != typFunc && !function.IsDepth:
 
 				if strings.ContainsRune(itemInFunc.Val, '"') {
 					return nil, itemInFunc.Errorf("Attribute in function"+
@@ -1918,7 +2102,8 @@ L:
 		}
 	}
 
-	if function.Name != uidFunc && function.Name != typFunc && len(function.Attr) == 0 {
+	if function.Name != uidFunc && function.Name != typFunc && function.Name != depthFunc &&
+		!function.IsDepth && len(function.Attr) == 0 {
 		return nil, it.Errorf("Got empty attr for function: [%s]", function.Name)
 	}
 
@@ -1926,6 +2111,15 @@ L:
 		return nil, it.Errorf("type function only supports one argument. Got: %v", function.Args)
 	}
 
+	if function.Name == "regexp" {
+		for _, arg := range function.Args {
+			if arg.IsValueVar && len(function.Args) != 1 {
+				return nil, it.Errorf("regexp function expects either a regular expression or " +
+					"a value variable as argument")
+			}
+		}
+	}
+
 	return function, nil
 }
 
@@ -2111,10 +2305,39 @@ func tryParseFacetList(it *lex.ItemIterator) (res facetRes, parseOk bool, err er
 	}
 }
 
+// parseNormalize parses the normalize directive, which can be followed by the lists argument as
+// @normalize(lists).
+func parseNormalize(it *lex.ItemIterator, gq *GraphQuery) error {
+	gq.Normalize = true
+	items, err := it.Peek(1)
+	if err != nil {
+		return it.Item().Errorf("Unable to peek lexer after normalize")
+	}
+	if items[0].Typ != itemLeftRound {
+		return nil
+	}
+
+	it.Next()
+	if !it.Next() {
+		return it.Item().Errorf("Expected an argument for normalize")
+	}
+	item := it.Item()
+	if item.Typ != itemName || item.Val != "lists" {
+		return item.Errorf("Expected lists as the argument for normalize, got: %s", item.Val)
+	}
+	gq.NormalizeLists = true
+	if _, ok := tryParseItemType(it, itemRightRound); !ok {
+		return it.Item().Errorf("Expected ) after normalize(lists")
+	}
+	return nil
+}
+
 // parseCascade parses the cascade directive.
 // Two formats:
 // 	1. @cascade
 //  2. @cascade(pred1, pred2, ...)
+// Both can be limited to a number of levels, e.g. @cascade(depth: 2) or
+// @cascade(pred1, depth: 2).
 func parseCascade(it *lex.ItemIterator, gq *GraphQuery) error {
 	item := it.Item()
 	items, err := it.Peek(1)
@@ -2157,9 +2380,22 @@ loop:
 			if !expectArg {
 				return item.Errorf("Expected a comma or right round but got: %v", item.Val)
 			}
+			expectArg = false
+			if next, err := it.Peek(1); err == nil && next[0].Typ == itemColon &&
+				strings.ToLower(item.Val) == "depth" {
+				it.Next()
+				if !it.Next() || it.Item().Typ != itemName {
+					return item.Errorf("Expected a value for depth in cascade()")
+				}
+				depth, err := strconv.ParseUint(it.Item().Val, 0, 64)
+				if err != nil || depth == 0 {
+					return item.Errorf("Value of depth in cascade() should be a positive integer")
+				}
+				gq.CascadeDepth = depth
+				continue
+			}
 			gq.Cascade = append(gq.Cascade, collectName(it, item.Val))
 			count++
-			expectArg = false
 		default:
 			return item.Errorf("Unexpected item while parsing: %v", item.Val)
 		}
@@ -2169,7 +2405,11 @@ loop:
 		return item.Errorf("Unnecessary comma in cascade()")
 	}
 	if count == 0 {
-		return item.Errorf("At least one predicate required in parameterized cascade()")
+		if gq.CascadeDepth == 0 {
+			return item.Errorf("At least one predicate required in parameterized cascade()")
+		}
+		// @cascade(depth: n) applies to all the predicates.
+		gq.Cascade = append(gq.Cascade, "__all__")
 	}
 	return nil
 }
@@ -2517,7 +2757,9 @@ func parseDirective(it *lex.ItemIterator, curp *GraphQuery) error {
 			return err
 		}
 	case item.Val == "normalize":
-		curp.Normalize = true
+		if err := parseNormalize(it, curp); err != nil {
+			return err
+		}
 	case peek[0].Typ == itemLeftRound:
 		// this is directive
 		switch item.Val {
@@ -2595,9 +2837,9 @@ func parseLanguageList(it *lex.ItemIterator) ([]string, error) {
 
 func validKeyAtRoot(k string) bool {
 	switch k {
-	case "func", "orderasc", "orderdesc", "first", "offset", "after":
+	case "func", "orderasc", "orderdesc", "first", "offset", "after", "sample":
 		return true
-	case "from", "to", "numpaths", "minweight", "maxweight":
+	case "from", "to", "numpaths", "minweight", "maxweight", "undirected":
 		// Specific to shortest path
 		return true
 	case "depth":
@@ -2712,7 +2954,14 @@ loop:
 			if gq.Func != nil {
 				return gq, item.Errorf("Only one function allowed at root")
 			}
-			gen, err := parseFunction(it, gq)
+			var gen *Function
+			var err error
+			if peekIt, perr := it.Peek(1); perr == nil && peekIt[0].Typ == itemName &&
+				strings.ToLower(peekIt[0].Val) == unionFunc {
+				gen, err = parseUnion(it)
+			} else {
+				gen, err = parseFunction(it, gq)
+			}
 			if err != nil {
 				return gq, err
 			}
@@ -2994,6 +3243,28 @@ func godeep(it *lex.ItemIterator, gq *GraphQuery) error {
 				gq.Children = append(gq.Children, child)
 				curp = nil
 				continue
+			case valLower == "bm25":
+				if peekIt, err = it.Peek(1); err != nil || peekIt[0].Typ != itemLeftRound {
+					goto Fall
+				}
+				child := &GraphQuery{
+					Args:  make(map[string]string),
+					Var:   varName,
+					Alias: alias,
+				}
+				varName, alias = "", ""
+				it.Prev()
+				if child.Func, err = parseFunction(it, gq); err != nil {
+					return err
+				}
+				if len(child.Func.Args) != 1 {
+					return it.Errorf("Function bm25 requires a predicate and the search text. "+
+						"Got %d arguments", len(child.Func.Args)+1)
+				}
+				child.Attr = child.Func.Attr
+				gq.Children = append(gq.Children, child)
+				curp = nil
+				continue
 			case isAggregator(valLower):
 				child := &GraphQuery{
 					Attr:       valueFunc,
@@ -3136,6 +3407,43 @@ func godeep(it *lex.ItemIterator, gq *GraphQuery) error {
 				switch {
 				case peekIt[0].Typ == itemRightRound:
 					return it.Errorf("Cannot use count(), please use count(uid)")
+				case strings.ToLower(peekIt[0].Val) == "distinct" && peekIt[1].Typ == itemName:
+					// count(distinct friend) and count(distinct val(x)) count the distinct
+					// uids or values across all the nodes at this level.
+					if gq.IsGroupby {
+						return it.Errorf("count(distinct) is not supported inside @groupby")
+					}
+					if varName != "" {
+						return it.Errorf("Cannot assign a variable to count(distinct)")
+					}
+					it.Next() // Skip distinct.
+					it.Next()
+					item = it.Item()
+					child := &GraphQuery{
+						Args:       make(map[string]string),
+						Attr:       item.Val,
+						Alias:      alias,
+						IsCount:    true,
+						IsDistinct: true,
+					}
+					if strings.ToLower(item.Val) == valueFunc {
+						child.Attr = valueFunc
+						child.IsInternal = true
+						numVars, err := parseVarList(it, child)
+						if err != nil {
+							return err
+						}
+						if numVars != 1 {
+							return it.Errorf("Invalid use of val(). Exactly one variable expected.")
+						}
+						child.NeedsVar[len(child.NeedsVar)-1].Typ = ValueVar
+					}
+					if _, ok := tryParseItemType(it, itemRightRound); !ok {
+						return it.Errorf("Expected ) after count(distinct %s", child.Attr)
+					}
+					count = notSeen
+					gq.Children = append(gq.Children, child)
+					varName, alias = "", ""
 				case peekIt[0].Val == uidFunc && peekIt[1].Typ == itemRightRound:
 					if gq.IsGroupby {
 						// count(uid) case which occurs inside @groupby
@@ -114,6 +114,8 @@ func parseUpsertBlock(it *lex.ItemIterator) (*api.Request, error) {
 			}
 
 			// upsert { mutation ===>@if(...)<=== {....} query{...}}
+			// The condition can also be @elseif(...) or @else.
+			condText = ""
 			item = it.Item()
 			if item.Typ == itemUpsertBlockOpContent {
 				condText = item.Val
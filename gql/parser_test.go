@@ -927,6 +927,28 @@ func TestParseQueryWithVarError2(t *testing.T) {
 	require.Contains(t, err.Error(), "Some variables are used but not defined")
 }
 
+func TestParseWithDefinedVarsNeeded(t *testing.T) {
+	query := `
+	{
+		var(func: uid(0x0a)) {L AS friends}
+		me(func: uid(L)) {J AS friends}
+	}
+`
+	res, err := ParseWithDefinedVarsNeeded(Request{Str: query})
+	require.NoError(t, err)
+	require.Len(t, res.Query, 2)
+
+	query = `
+	{
+		var(func: uid(0x0a)) {L AS friends}
+		me(func: uid(K)) {name}
+	}
+`
+	_, err = ParseWithDefinedVarsNeeded(Request{Str: query})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Some variables are used but not defined")
+}
+
 func TestParseQueryFilterError1A(t *testing.T) {
 	query := `
 	{
@@ -1231,6 +1253,19 @@ func TestParseShortestPath(t *testing.T) {
 	require.Equal(t, "6", res.Query[0].Args["maxweight"])
 }
 
+func TestParseShortestPathUndirected(t *testing.T) {
+	query := `
+	{
+		shortest(from:0x0a, to:0x0b, undirected: true) {
+			friends
+		}
+	}
+`
+	res, err := Parse(Request{Str: query})
+	require.NoError(t, err)
+	require.Equal(t, "true", res.Query[0].Args["undirected"])
+}
+
 func TestParseShortestPathWithUidVars(t *testing.T) {
 	query := `{
 		a as var(func: uid(0x01))
@@ -1530,6 +1565,36 @@ func TestParse_error2(t *testing.T) {
 
 }
 
+func TestParseRootSample(t *testing.T) {
+	query := `
+	query {
+		me(func: has(name), sample: 10, first: 5) {
+			name
+		}
+	}
+`
+	res, err := Parse(Request{Str: query})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(res.Query))
+	require.Equal(t, "10", res.Query[0].Args["sample"])
+	require.Equal(t, "5", res.Query[0].Args["first"])
+}
+
+func TestParseSampleNotAtRoot(t *testing.T) {
+	query := `
+	query {
+		me(func: uid(0x0a)) {
+			friends(sample: 10) {
+				name
+			}
+		}
+	}
+`
+	_, err := Parse(Request{Str: query})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Got invalid keyword: sample")
+}
+
 func TestParse_pass1(t *testing.T) {
 	query := `
 		{
@@ -2672,6 +2737,111 @@ func TestParseCountAsFunc(t *testing.T) {
 
 }
 
+func TestParseCountWithFilterInFilter(t *testing.T) {
+	query := `{
+		me(func: uid(1)) {
+			friend @filter(ge(count(post @filter(gt(date, "2020-01-01") and has(title))), 3)) {
+				name
+			}
+		}
+	}
+`
+	gq, err := Parse(Request{Str: query})
+	require.NoError(t, err)
+	ft := gq.Query[0].Children[0].Filter
+	require.NotNil(t, ft.Func)
+	require.True(t, ft.Func.IsCount)
+	require.Equal(t, "post", ft.Func.Attr)
+	require.Equal(t, []Arg{{Value: "3"}}, ft.Func.Args)
+	require.Equal(t, `(ge count(post @filter(AND (gt date "2020-01-01") (has title))) "3")`,
+		ft.debugString())
+}
+
+func TestParseCountWithFilterVars(t *testing.T) {
+	query := `query test($d: string) {
+		var(func: has(date)) {
+			p as uid
+		}
+		me(func: uid(1)) {
+			friend @filter(gt(count(post @filter(uid(p) and ge(date, $d))), 0)) {
+				name
+			}
+		}
+	}
+`
+	gq, err := Parse(Request{Str: query, Variables: map[string]string{"$d": "2020"}})
+	require.NoError(t, err)
+	require.Contains(t, gq.QueryVars[1].Needs, "p")
+	cf := gq.Query[1].Children[0].Filter.Func.CountFilter
+	require.NotNil(t, cf)
+	require.Equal(t, "2020", cf.Child[1].Func.Args[0].Value)
+}
+
+func TestParseFilterOutsideCountError(t *testing.T) {
+	query := `{
+		me(func: uid(1)) {
+			friend @filter(eq(name @filter(has(age)), "a")) {
+				name
+			}
+		}
+	}
+`
+	_, err := Parse(Request{Str: query})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "@filter is only allowed inside count function")
+}
+
+func TestParseCountDistinct(t *testing.T) {
+	query := `{
+		var(func: uid(1)) {
+			a as age
+		}
+		me(func: uid(1)) {
+			count(distinct friend)
+			ages: count(distinct val(a))
+			name
+		}
+	}
+`
+	gq, err := Parse(Request{Str: query})
+	require.NoError(t, err)
+	children := gq.Query[1].Children
+	require.Equal(t, 3, len(children))
+	require.Equal(t, "friend", children[0].Attr)
+	require.True(t, children[0].IsCount)
+	require.True(t, children[0].IsDistinct)
+	require.False(t, children[0].IsInternal)
+
+	require.Equal(t, "val", children[1].Attr)
+	require.Equal(t, "ages", children[1].Alias)
+	require.True(t, children[1].IsDistinct)
+	require.True(t, children[1].IsInternal)
+	require.Equal(t, []VarContext{{Name: "a", Typ: ValueVar}}, children[1].NeedsVar)
+	require.Equal(t, "name", children[2].Attr)
+}
+
+func TestParseCountDistinctError(t *testing.T) {
+	query := `{
+		me(func: uid(1)) {
+			n as count(distinct friend)
+		}
+	}
+`
+	_, err := Parse(Request{Str: query})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Cannot assign a variable to count(distinct)")
+
+	query = `{
+		me(func: uid(1)) {
+			count(distinct friend name)
+		}
+	}
+`
+	_, err = Parse(Request{Str: query})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Expected ) after count(distinct friend")
+}
+
 func TestParseCountError1(t *testing.T) {
 	query := `{
 		me(func: uid(1)) {
@@ -2702,6 +2872,86 @@ func TestParseCountError2(t *testing.T) {
 		"Unrecognized character inside a func: U+007D '}'")
 }
 
+func TestParseUnion(t *testing.T) {
+	query := `{
+		var(func: has(nickname)) {
+			a as friend
+		}
+
+		me(func: union(eq(name, "Alice"), uid(0x1, 0x2), uid(a), ge(count(friend), 2)), first: 10) {
+			name
+		}
+	}
+`
+	res, err := Parse(Request{Str: query})
+	require.NoError(t, err)
+	fn := res.Query[1].Func
+	require.Equal(t, "union", fn.Name)
+	require.Len(t, fn.Union, 4)
+	require.Equal(t, "eq", fn.Union[0].Name)
+	require.Equal(t, "name", fn.Union[0].Attr)
+	require.Equal(t, []uint64{1, 2}, fn.Union[1].UID)
+	require.Equal(t, []VarContext{{Name: "a", Typ: UidVar}}, fn.Union[2].NeedsVar)
+	require.True(t, fn.Union[3].IsCount)
+	require.Empty(t, res.Query[1].UID)
+	require.Equal(t, []string{"a"}, res.QueryVars[1].Needs)
+}
+
+func TestParseUnionGraphQLVar(t *testing.T) {
+	query := `query test($name: string, $id: string) {
+		me(func: union(eq(name, $name), uid($id))) {
+			name
+		}
+	}`
+	res, err := Parse(Request{
+		Str:       query,
+		Variables: map[string]string{"$name": "Alice", "$id": "0x3"},
+	})
+	require.NoError(t, err)
+	fn := res.Query[0].Func
+	require.Equal(t, "Alice", fn.Union[0].Args[0].Value)
+	require.Equal(t, []uint64{3}, fn.Union[1].UID)
+}
+
+func TestParseUnionErrors(t *testing.T) {
+	tests := []struct {
+		in, err string
+	}{
+		{`{me(func: union(eq(name, "Alice"))) {name}}`, "union requires at least two functions"},
+		{`{me(func: union(eq(name, "Alice"), union(has(name), has(age)))) {name}}`,
+			"union can't be used inside union"},
+		{`{me(func: union(eq(name, "Alice") has(name))) {name}}`, "Expected , or ) in union"},
+		{`{me(func: union(eq(name, "Alice"), foo(name))) {name}}`,
+			"Function name: foo is not valid inside union"},
+		{`{me(func: uid(1)) @filter(union(uid(1), uid(2))) {name}}`,
+			"union is only supported as the root function"},
+	}
+	for _, tc := range tests {
+		_, err := Parse(Request{Str: tc.in})
+		require.Error(t, err, tc.in)
+		require.Contains(t, err.Error(), tc.err, tc.in)
+	}
+}
+
+func TestParseHasMultiplePredicates(t *testing.T) {
+	query := `{
+		me(func: has(name, ~friend)) @filter(hasall(age, alias)) {
+			name
+		}
+	}`
+	res, err := Parse(Request{Str: query})
+	require.NoError(t, err)
+	f := res.Query[0].Func
+	require.Equal(t, "has", f.Name)
+	require.Equal(t, "name", f.Attr)
+	require.Equal(t, []Arg{{Value: "~friend"}}, f.Args)
+
+	f = res.Query[0].Filter.Func
+	require.Equal(t, "hasall", f.Name)
+	require.Equal(t, "age", f.Attr)
+	require.Equal(t, []Arg{{Value: "alias"}}, f.Args)
+}
+
 func TestParseCheckPwd(t *testing.T) {
 
 	query := `{
@@ -2718,6 +2968,69 @@ func TestParseCheckPwd(t *testing.T) {
 	require.Equal(t, "password", gq.Query[0].Children[0].Attr)
 }
 
+func TestParseBm25(t *testing.T) {
+	query := `{
+		me(func: anyoftext(description, "quick fox")) {
+			score as bm25(description, "quick fox")
+			relevance: val(score)
+		}
+	}
+`
+	gq, err := Parse(Request{Str: query})
+	require.NoError(t, err)
+	child := gq.Query[0].Children[0]
+	require.Equal(t, "bm25", child.Func.Name)
+	require.Equal(t, "description", child.Attr)
+	require.Equal(t, "score", child.Var)
+	require.Len(t, child.Func.Args, 1)
+	require.Equal(t, "quick fox", child.Func.Args[0].Value)
+}
+
+func TestParseBm25AsPredicate(t *testing.T) {
+	query := `{
+		me(func: uid(1)) {
+			bm25
+		}
+	}
+`
+	gq, err := Parse(Request{Str: query})
+	require.NoError(t, err)
+	require.Nil(t, gq.Query[0].Children[0].Func)
+	require.Equal(t, "bm25", gq.Query[0].Children[0].Attr)
+}
+
+func TestParseBm25Error(t *testing.T) {
+	query := `{
+		me(func: uid(1)) {
+			bm25(description)
+		}
+	}
+`
+	_, err := Parse(Request{Str: query})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Function bm25 requires a predicate and the search text")
+}
+
+func TestParseSimilarTo(t *testing.T) {
+	query := `query test($vec: string = "[0.1, 0.2]") {
+		me(func: similar_to(embedding, 3, [0.5, -1.25, 2e-3])) @filter(similar_to(embedding, 2, $vec)) {
+			name
+		}
+	}
+`
+	gq, err := Parse(Request{Str: query})
+	require.NoError(t, err)
+	fn := gq.Query[0].Func
+	require.Equal(t, "similar_to", fn.Name)
+	require.Equal(t, "embedding", fn.Attr)
+	require.Len(t, fn.Args, 2)
+	require.Equal(t, "3", fn.Args[0].Value)
+	require.Equal(t, "[0.5,-1.25,2e-3]", fn.Args[1].Value)
+	filter := gq.Query[0].Filter.Func
+	require.Equal(t, "similar_to", filter.Name)
+	require.Equal(t, "[0.1, 0.2]", filter.Args[1].Value)
+}
+
 func TestParseComments(t *testing.T) {
 	query := `
 	# Something
@@ -2829,6 +3142,29 @@ func TestParseIRIRefInvalidChar(t *testing.T) {
 	require.Contains(t, err.Error(), "Unexpected character '^' while parsing IRI")
 }
 
+func TestParsePredicateRef(t *testing.T) {
+	query := `{
+		me(func: eq(<country^city>, "US", "Austin")) @filter(ge(<friend|since>, 2018)) {
+			<friend|since>
+		}
+	      }`
+	gq, err := Parse(Request{Str: query})
+	require.NoError(t, err)
+	require.Equal(t, "country^city", gq.Query[0].Func.Attr)
+	require.Equal(t, "friend|since", gq.Query[0].Filter.Func.Attr)
+	require.Equal(t, "friend|since", gq.Query[0].Children[0].Attr)
+
+	// Only the names of predicates accept the separators of edge properties and composite
+	// indexes.
+	query = `{
+		me(func: uid(<friend|since>)) {
+		}
+	      }`
+	_, err = Parse(Request{Str: query})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Unexpected character '|' while parsing IRI")
+}
+
 func TestLangs(t *testing.T) {
 	query := `
 	query {
@@ -3123,6 +3459,54 @@ func TestParseNormalize(t *testing.T) {
 	require.True(t, res.Query[0].Normalize)
 }
 
+func TestParseNormalizeLists(t *testing.T) {
+	query := `
+	query {
+		me(func: uid( 0x3)) @normalize(lists) {
+			friends @normalize {
+				name
+			}
+			pets @normalize(lists) @filter(has(name)) {
+				name
+			}
+		}
+}
+`
+	res, err := Parse(Request{Str: query})
+	require.NoError(t, err)
+	require.NotNil(t, res.Query[0])
+	require.True(t, res.Query[0].Normalize)
+	require.True(t, res.Query[0].NormalizeLists)
+	require.True(t, res.Query[0].Children[0].Normalize)
+	require.False(t, res.Query[0].Children[0].NormalizeLists)
+	require.True(t, res.Query[0].Children[1].NormalizeLists)
+	require.NotNil(t, res.Query[0].Children[1].Filter)
+}
+
+func TestParseNormalizeError(t *testing.T) {
+	query := `
+	query {
+		me(func: uid( 0x3)) @normalize(maps) {
+			name
+		}
+}
+`
+	_, err := Parse(Request{Str: query})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Expected lists as the argument for normalize, got: maps")
+
+	query = `
+	query {
+		me(func: uid( 0x3)) @normalize(lists, maps) {
+			name
+		}
+}
+`
+	_, err = Parse(Request{Str: query})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Expected ) after normalize(lists")
+}
+
 func TestParseGroupbyRoot(t *testing.T) {
 	query := `
 	query {
@@ -4010,6 +4394,42 @@ func TestParseRegexp6(t *testing.T) {
 		"Unclosed regexp")
 }
 
+func TestParseRegexpValueVar(t *testing.T) {
+	query := `
+	{
+	  var(func: uid(0x1)) {
+	    p as pattern
+	  }
+	  me(func: uid(0x2)) @filter(regexp(email, val(p))) {
+	    email
+	  }
+	}
+`
+	res, err := Parse(Request{Str: query})
+	require.NoError(t, err)
+	f := res.Query[1].Filter.Func
+	require.Equal(t, "email", f.Attr)
+	require.Equal(t, []Arg{{Value: "p", IsValueVar: true}}, f.Args)
+	require.Equal(t, []VarContext{{Name: "p", Typ: ValueVar}}, f.NeedsVar)
+}
+
+func TestParseRegexpValueVarWithExpression(t *testing.T) {
+	query := `
+	{
+	  var(func: uid(0x1)) {
+	    p as pattern
+	  }
+	  me(func: regexp(email, /^alice/, val(p))) {
+	    email
+	  }
+	}
+`
+	_, err := Parse(Request{Str: query})
+	require.Error(t, err)
+	require.Contains(t, err.Error(),
+		"regexp function expects either a regular expression or a value variable as argument")
+}
+
 func TestMain(m *testing.M) {
 	os.Exit(m.Run())
 }
@@ -5198,6 +5618,36 @@ func TestRecurseWithArgs(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, gq.Query[0].RecurseArgs.AllowLoop, true)
 	require.Equal(t, gq.Query[0].RecurseArgs.Depth, uint64(1))
+
+	query = `
+	{
+		me(func: eq(name, "sad"))@recurse(depth: 2, undirected: $undirected) {
+		}
+	}`
+	gq, err = Parse(Request{Str: query, Variables: map[string]string{"$undirected": "true"}})
+	require.NoError(t, err)
+	require.True(t, gq.Query[0].RecurseArgs.Undirected)
+}
+
+func TestRecurseUndirected(t *testing.T) {
+	query := `
+	{
+		me(func: eq(name, "sad"))@recurse(depth: 2, undirected: true) {
+		}
+	}`
+	gq, err := Parse(Request{Str: query})
+	require.NoError(t, err)
+	require.True(t, gq.Query[0].RecurseArgs.Undirected)
+	require.Equal(t, uint64(2), gq.Query[0].RecurseArgs.Depth)
+
+	query = `
+	{
+		me(func: eq(name, "sad"))@recurse(depth: 2, undirected: yes) {
+		}
+	}`
+	_, err = Parse(Request{Str: query})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Value inside undirected should be type of boolean")
 }
 
 func TestRecurseWithArgsWithError(t *testing.T) {
@@ -5268,6 +5718,60 @@ func TestRecurseWithError(t *testing.T) {
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "Value inside loop should be type of boolean")
 }
+func TestRecurseWithDepthVar(t *testing.T) {
+	query := `
+	{
+		me(func: eq(name, "sad")) @recurse(depth: 3, depth_var: lvl) {
+			friend
+		}
+		levels(func: uid(lvl)) {
+			val(lvl)
+		}
+	}`
+	gq, err := Parse(Request{Str: query})
+	require.NoError(t, err)
+	require.Equal(t, "lvl", gq.Query[0].RecurseArgs.DepthVar)
+	require.Equal(t, []string{"lvl"}, gq.QueryVars[0].Defines)
+}
+
+func TestRecurseWithDepthFilter(t *testing.T) {
+	query := `
+	{
+		me(func: eq(name, "sad")) @recurse(depth: 3) {
+			friend @filter(lt(depth(), 2) or (eq(depth(), 2) and has(manager)))
+		}
+	}`
+	gq, err := Parse(Request{Str: query})
+	require.NoError(t, err)
+	ft := gq.Query[0].Children[0].Filter
+	require.Equal(t, `(OR (lt depth() "2") (AND (eq depth() "2") (has manager)))`,
+		ft.debugString())
+	require.True(t, ft.Child[0].Func.IsDepth)
+	require.Empty(t, ft.Child[0].Func.Attr)
+}
+
+func TestDepthFuncWithError(t *testing.T) {
+	query := `
+	{
+		me(func: eq(name, "sad")) @recurse(depth: 3) {
+			friend @filter(has(depth()))
+		}
+	}`
+	_, err := Parse(Request{Str: query})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "depth function only allowed inside inequality function")
+
+	query = `
+	{
+		me(func: eq(name, "sad")) @recurse(depth: 3) {
+			friend @filter(eq(depth(friend), 2))
+		}
+	}`
+	_, err = Parse(Request{Str: query})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "depth function doesn't take any arguments")
+}
+
 func TestParseExpandFilter(t *testing.T) {
 	query := `
 		{
@@ -5390,6 +5894,32 @@ func TestCascadeParameterized(t *testing.T) {
 	require.Equal(t, gq.Query[0].Cascade[1], "age")
 }
 
+func TestCascadeDepth(t *testing.T) {
+	query := `{
+		names(func: has(name)) @cascade(depth: 2) {
+		  name
+		  friend @cascade(name, depth: 1) {
+		    name
+		  }
+		}
+	  }`
+	gq, err := Parse(Request{
+		Str: query,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"__all__"}, gq.Query[0].Cascade)
+	require.Equal(t, uint64(2), gq.Query[0].CascadeDepth)
+	require.Equal(t, []string{"name"}, gq.Query[0].Children[1].Cascade)
+	require.Equal(t, uint64(1), gq.Query[0].Children[1].CascadeDepth)
+
+	for _, bad := range []string{"depth: 0", "depth: x", "depth:", "name, depth: -1"} {
+		_, err := Parse(Request{
+			Str: `{ names(func: has(name)) @cascade(` + bad + `) { name } }`,
+		})
+		require.Error(t, err, bad)
+	}
+}
+
 func TestBadCascadeParameterized(t *testing.T) {
 	badQueries := []string{
 		`{
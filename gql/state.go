@@ -18,6 +18,8 @@
 package gql
 
 import (
+	"strings"
+
 	"github.com/dgraph-io/dgraph/lex"
 )
 
@@ -156,7 +158,8 @@ func lexBlockContent(l *lex.Lexer) lex.StateFn {
 	return lexContent(l, leftCurl, rightCurl, lexUpsertBlock)
 }
 
-// lexIfContent lexes the whole of @if directive in a mutation block (covered by small brackets)
+// lexIfContent lexes the whole of @if or @elseif directive in a mutation block (covered by small
+// brackets), or the @else directive which doesn't have a condition.
 func lexIfContent(l *lex.Lexer) lex.StateFn {
 	if r := l.Next(); r != at {
 		return l.Errorf("Expected [@], found; [%#U]", r)
@@ -164,7 +167,12 @@ func lexIfContent(l *lex.Lexer) lex.StateFn {
 
 	l.AcceptRun(isNameSuffix)
 	word := l.Input[l.Start:l.Pos]
-	if word != "@if" {
+	switch word {
+	case "@if", "@elseif":
+	case "@else":
+		l.Emit(itemUpsertBlockOpContent)
+		return lexInsideMutation
+	default:
 		return l.Errorf("Expected @if, found [%v]", word)
 	}
 
@@ -254,7 +262,7 @@ func lexInsideSchema(l *lex.Lexer) lex.StateFn {
 		case isSpace(r) || lex.IsEndOfLine(r):
 			l.Ignore()
 		case r == lsThan:
-			return lexIRIRef
+			return lexPredicateRef
 		case isNameBegin(r):
 			return lexArgName
 		case r == '#':
@@ -299,6 +307,9 @@ func lexFuncOrArg(l *lex.Lexer) lex.StateFn {
 			if r == lsThan {
 				if !isSpace(l.Peek()) && l.Peek() != '=' {
 					// as long as its not '=' or ' '
+					if isPredicateArg(l) {
+						return lexPredicateRef
+					}
 					return lexIRIRef
 				}
 			}
@@ -447,7 +458,7 @@ func lexQuery(l *lex.Lexer) lex.StateFn {
 			l.Emit(itemAt)
 			return lexDirectiveOrLangList
 		case r == lsThan:
-			return lexIRIRef
+			return lexPredicateRef
 		case r == star:
 			l.Emit(itemStar)
 		default:
@@ -463,6 +474,25 @@ func lexIRIRef(l *lex.Lexer) lex.StateFn {
 	return l.Mode
 }
 
+// lexPredicateRef lexes an IRI naming a predicate, which can also be an edge property, as in
+// <friend|since>, or a composite index, as in <country^city>.
+func lexPredicateRef(l *lex.Lexer) lex.StateFn {
+	if err := lex.PredicateRef(l, itemName); err != nil {
+		return l.Errorf(err.Error())
+	}
+	return l.Mode
+}
+
+// isPredicateArg returns whether the argument being lexed is the first one of a function, which
+// names the predicate it applies to. The arguments of uid are uids or variables.
+func isPredicateArg(l *lex.Lexer) bool {
+	if l.LastItem(1).Typ != itemLeftRound {
+		return false
+	}
+	fn := l.LastItem(2)
+	return fn.Typ == itemName && strings.ToLower(fn.Val) != uidFunc
+}
+
 // lexDirectiveOrLangList is called right after we see a @.
 func lexDirectiveOrLangList(l *lex.Lexer) lex.StateFn {
 	r := l.Next()
@@ -531,3 +531,43 @@ upsert {
 	require.NoError(t, err)
 	require.Equal(t, 3, len(req.Mutations))
 }
+
+func TestConditionalUpsertElseIfElse(t *testing.T) {
+	query := `
+upsert {
+  query {
+    v as var(func: eq(email, "user@company1.io"))
+  }
+
+  mutation @if(eq(len(v), 0)) {
+    set {
+      _:user <email> "user@company1.io" .
+    }
+  }
+
+  mutation @elseif(eq(len(v), 1)) {
+    set {
+      uid(v) <name> "user" .
+    }
+  }
+
+  mutation @else {
+    set {
+      _:conflict <conflict> "user@company1.io" .
+    }
+  }
+
+  mutation {
+    set {
+      _:log <logged> "true" .
+    }
+  }
+}`
+	req, err := ParseMutation(query)
+	require.NoError(t, err)
+	require.Equal(t, 4, len(req.Mutations))
+	require.Equal(t, "@if(eq(len(v), 0))", req.Mutations[0].Cond)
+	require.Equal(t, "@elseif(eq(len(v), 1))", req.Mutations[1].Cond)
+	require.Equal(t, "@else", req.Mutations[2].Cond)
+	require.Equal(t, "", req.Mutations[3].Cond)
+}
@@ -165,6 +165,7 @@ const (
 		lastUpdate: Int
 		clusterInfoOnly: Boolean
 		forceGroupId: Boolean
+		learner: Boolean
 	}
 
 	type Tablet {
@@ -201,6 +202,50 @@ const (
 		schema: String!
 	}
 
+	input ValidateGQLSchemaInput {
+		schema: String!
+	}
+
+	type ValidateGQLSchemaPayload {
+		"""
+		Whether the schema can be applied with updateGQLSchema.
+		"""
+		valid: Boolean!
+
+		"""
+		The errors found in the schema, if it isn't valid.
+		"""
+		errors: [String!]
+
+		"""
+		The GraphQL schema that would be served at /graphql.
+		"""
+		generatedSchema: String
+
+		"""
+		The Dgraph schema that would be applied to the cluster.
+		"""
+		dgraphSchema: String
+
+		"""
+		The predicates whose schema would be added or changed, with the indexes that would be
+		built or dropped.
+		"""
+		predicateChanges: [PredicateChange!]
+	}
+
+	type PredicateChange {
+		predicate: String!
+
+		"""
+		The current schema of the predicate, or null if the predicate isn't in the schema yet.
+		"""
+		oldSchema: String
+		newSchema: String!
+		indexesAdded: [String!]
+		indexesDropped: [String!]
+	}
+
 	input ExportInput {
 		format: String
 
@@ -248,6 +293,145 @@ const (
 		response: Response
 	}
 
+	input MaintenanceInput {
+		"""
+		Run the value log GC and a full compaction of the postings right away, regardless of
+		the maintenance windows and of any deferral.
+		"""
+		run: Boolean
+
+		"""
+		Keep the scheduled maintenance from running for a duration, such as 2h.  A duration
+		of 0s cancels the deferral.
+		"""
+		deferFor: String
+	}
+
+	type MaintenancePayload {
+		response: Response
+
+		"""
+		When the deferred maintenance can run again, or null if it isn't deferred.
+		"""
+		deferredUntil: DateTime
+	}
+
+	input VerifyIntegrityInput {
+		"""
+		The predicates to verify. All the predicates stored by the node are verified if
+		none is given.
+		"""
+		predicates: [String]
+	}
+
+	type VerifyIntegrityPayload {
+		response: Response
+	}
+
+	"""
+	The outcome of a verification of the checksums of the postings stored by a node, and of
+	the consistency of their data and indexes.
+	"""
+	type IntegrityReport {
+		startedAt: DateTime!
+
+		"""
+		When the verification finished, or null while it's running.
+		"""
+		finishedAt: DateTime
+
+		"""
+		The timestamp the posting lists were read at.
+		"""
+		readTs: Int
+
+		"""
+		Whether the verification is done and found no problem.
+		"""
+		healthy: Boolean!
+
+		"""
+		The error found verifying the checksums of the blocks of the postings.
+		"""
+		checksumError: String
+
+		"""
+		Why the verification stopped before all the predicates were verified.
+		"""
+		error: String
+		predicates: [PredicateIntegrity]
+
+		"""
+		The predicates that weren't verified because they were being indexed.
+		"""
+		skipped: [String]
+	}
+
+	type PredicateIntegrity {
+		predicate: String!
+		dataLists: Int
+		indexLists: Int
+
+		"""
+		The posting lists that couldn't be read or decoded.
+		"""
+		corruptLists: Int
+
+		"""
+		The tokens of indexed values that don't have the uid of the value in their index.
+		"""
+		missingIndexEntries: Int
+
+		"""
+		The uids in the index of a token that have no value producing the token.
+		"""
+		danglingIndexEntries: Int
+
+		"""
+		Descriptions of the first problems found.
+		"""
+		problems: [String]
+	}
+
+	"""
+	The progress of the replication of the data of a primary cluster by this replica cluster.
+	"""
+	type ReplicationStatus {
+		"""
+		The address of the Zero of the primary cluster.
+		"""
+		primary: String!
+
+		"""
+		The timestamp of the primary cluster the data is replicated up to.
+		"""
+		primaryTs: Int
+
+		"""
+		The timestamp the queries read the replicated data at.
+		"""
+		readTs: Int
+
+		"""
+		When the data of the primary was last replicated, or null before the first time.
+		"""
+		replicatedAt: DateTime
+
+		"""
+		Seconds since the data of the primary was last replicated.
+		"""
+		lagSeconds: Float
+
+		"""
+		Whether the promotion of the replica was requested, and is in progress.
+		"""
+		promoting: Boolean!
+	}
+
+	type PromoteReplicaPayload {
+		response: Response
+	}
+
 	input ConfigInput {
 		"""
 		Estimated memory the caches can take. Actual usage by the process would be
@@ -256,19 +440,153 @@ const (
 		"""
 		cacheMb: Float
 
+		"""
+		Size in MB of the cache of the posting lists, set regardless of the cache_percentage
+		flag. Can't be set along with cacheMb.
+		"""
+		postingListCacheMb: Float
+
+		"""
+		Size in MB of the cache of the blocks of the postings, set regardless of the
+		cache_percentage flag. Can't be set along with cacheMb.
+		"""
+		blockCacheMb: Float
+
+		"""
+		Size in MB of the cache of the indexes and bloom filters of the postings, set regardless
+		of the cache_percentage flag. Can't be set along with cacheMb.
+		"""
+		indexCacheMb: Float
+
+		"""
+		Fraction of a value log file that has to be discarded for the value log GC to rewrite
+		it, between 0 and 1.
+		"""
+		vlogGCDiscardRatio: Float
+
+		"""
+		Most value log files rewritten by the scheduled value log GC every minute. 0 means no
+		limit.
+		"""
+		vlogGCMaxRuns: Int
+
 		"""
 		True value of logRequest enables logging of all the requests coming to alphas.
 		False value of logRequest disables above.
 		"""
 		logRequest: Boolean
+
+		"""
+		True value of strictSchema rejects the mutations using predicates or types that
+		aren't defined in the schema. False value of strictSchema disables above.
+		"""
+		strictSchema: Boolean
+
+		"""
+		Sets who can introspect the schema of /graphql on this alpha.
+		"""
+		introspection: IntrospectionMode
+
+		"""
+		Sets which messages of errors are redacted from the GraphQL responses of this alpha.
+		"""
+		errorRedaction: ErrorRedaction
+	}
+
+	enum IntrospectionMode {
+		"""
+		Anyone can introspect the schema.
+		"""
+		ENABLED
+
+		"""
+		Nobody can introspect the schema.
+		"""
+		DISABLED
+
+		"""
+		Only the requests with a valid JWT, as set up in the Dgraph.Authorization of the
+		schema, can introspect the schema.
+		"""
+		AUTHENTICATED
+	}
+
+	enum ErrorRedaction {
+		"""
+		The messages of all errors are returned.
+		"""
+		NONE
+
+		"""
+		The messages of internal errors, which have the code INTERNAL_SERVER_ERROR, are
+		replaced by a generic message and logged by the alpha instead.
+		"""
+		INTERNAL
 	}
 
 	type ConfigPayload {
 		response: Response
 	}
 
+	type PersistQueryPayload {
+		"""
+		The hex encoded SHA-256 hash of the query, which clients send to /graphql as the
+		sha256Hash of the persistedQuery extension.
+		"""
+		sha256Hash: String!
+		query: String!
+	}
+
 	type Config {
 		cacheMb: Float
+
+		"""
+		The sizes in MB the caches are currently limited to. A disabled cache has a size of 0.
+		"""
+		postingListCacheMb: Float
+		blockCacheMb: Float
+		indexCacheMb: Float
+
+		vlogGCDiscardRatio: Float
+		vlogGCMaxRuns: Int
+		strictSchema: Boolean
+		introspection: IntrospectionMode
+		errorRedaction: ErrorRedaction
+	}
+
+	"""
+	A change of the Dgraph schema.
+	"""
+	type SchemaChange {
+		"""
+		The operation that made the change: alter, update_graphql_schema, drop_attr,
+		drop_type, drop_data, drop_all or rollback.
+		"""
+		operation: String!
+		changedAt: DateTime!
+
+		"""
+		The user who made the change when ACL is turned on, or else the IP address the
+		change came from.
+		"""
+		changedBy: String
+		predicates: [SchemaChangeEntry]
+		types: [SchemaChangeEntry]
+
+		"""
+		Why the change can't be rolled back, if it can't.
+		"""
+		irreversible: String
+	}
+
+	"""
+	The definition of a predicate or a type before and after a change of the Dgraph schema.
+	before is null if the change added it, and after is null if the change dropped it.
+	"""
+	type SchemaChangeEntry {
+		name: String!
+		before: String
+		after: String
 	}
 
 	` + adminTypes + `
@@ -280,6 +598,22 @@ const (
 		config: Config
 		getAllowedCORSOrigins: Cors
 		querySchemaHistory(first: Int, offset: Int): [SchemaHistory]
+
+		"""
+		The changes of the Dgraph schema, the latest first.
+		"""
+		querySchemaChanges(first: Int, offset: Int): [SchemaChange]
+
+		"""
+		The report of the running verification of the postings on this node, or of the last one.
+		"""
+		integrityReport: IntegrityReport
+
+		"""
+		The progress of the replication of the primary cluster, or null if this cluster isn't a
+		replica.
+		"""
+		replicationStatus: ReplicationStatus
 		` + adminQueries + `
 	}
 
@@ -291,6 +625,12 @@ const (
 		"""
 		updateGQLSchema(input: UpdateGQLSchemaInput!) : UpdateGQLSchemaPayload
 
+		"""
+		Validate the input schema without applying it, and report the changes that
+		updateGQLSchema would make to the Dgraph schema.
+		"""
+		validateGQLSchema(input: ValidateGQLSchemaInput!) : ValidateGQLSchemaPayload
+
 		"""
 		Starts an export of all data in the cluster.  Export format should be 'rdf' (the default
 		if no format is given), or 'json'.
@@ -308,6 +648,24 @@ const (
 		"""
 		shutdown: ShutdownPayload
 
+		"""
+		Run or defer the value log GC and the full compactions of the postings on this node.
+		"""
+		maintenance(input: MaintenanceInput!): MaintenancePayload
+
+		"""
+		Verify the checksums of the postings on this node, and the consistency of their data
+		and indexes, right away.  The report is returned by integrityReport.
+		"""
+		verifyIntegrity(input: VerifyIntegrityInput): VerifyIntegrityPayload
+
+		"""
+		Promote this replica cluster, once it has replicated the data committed in the primary
+		cluster last time, if it can be reached.  The cluster accepts writes, and stops
+		replicating the primary, once it's promoted.
+		"""
+		promoteReplica: PromoteReplicaPayload
+
 		"""
 		Alter the node's config.
 		"""
@@ -315,6 +673,20 @@ const (
 		
 		replaceAllowedCORSOrigins(origins: [String]): Cors
 
+		"""
+		Add a persisted query, that clients can run on /graphql by sending just its hash.  When
+		Dgraph is run with --graphql_persisted_queries_only, only queries added this way are run.
+		"""
+		persistQuery(query: String!): PersistQueryPayload
+
+		"""
+		Roll back the last change of the Dgraph schema, by applying the definitions of the
+		predicates and types it replaced and dropping the ones it added.  Changes that dropped
+		data, or changed the type of a predicate, can't be rolled back.  Returns the change
+		that was rolled back.
+		"""
+		rollbackSchemaChange: SchemaChange
+
 		` + adminMutations + `
 	}
  `
@@ -336,12 +708,15 @@ var (
 		resolve.LoggingMWMutation,
 	}
 	adminQueryMWConfig = map[string]resolve.QueryMiddlewares{
-		"health":        {resolve.IpWhitelistingMW4Query, resolve.LoggingMWQuery}, // dgraph checks Guardian auth for health
-		"state":         {resolve.IpWhitelistingMW4Query, resolve.LoggingMWQuery}, // dgraph checks Guardian auth for state
-		"config":        commonAdminQueryMWs,
-		"listBackups":   commonAdminQueryMWs,
-		"restoreStatus": commonAdminQueryMWs,
-		"getGQLSchema":  commonAdminQueryMWs,
+		"health":             {resolve.IpWhitelistingMW4Query, resolve.LoggingMWQuery}, // dgraph checks Guardian auth for health
+		"state":              {resolve.IpWhitelistingMW4Query, resolve.LoggingMWQuery}, // dgraph checks Guardian auth for state
+		"config":             commonAdminQueryMWs,
+		"listBackups":        commonAdminQueryMWs,
+		"restoreStatus":      commonAdminQueryMWs,
+		"getGQLSchema":       commonAdminQueryMWs,
+		"querySchemaChanges": commonAdminQueryMWs,
+		"integrityReport":    commonAdminQueryMWs,
+		"replicationStatus":  commonAdminQueryMWs,
 		// for queries and mutations related to User/Group, dgraph handles Guardian auth,
 		// so no need to apply GuardianAuth Middleware
 		"queryGroup":            {resolve.IpWhitelistingMW4Query, resolve.LoggingMWQuery},
@@ -353,14 +728,20 @@ var (
 		"getAllowedCORSOrigins": {resolve.IpWhitelistingMW4Query, resolve.LoggingMWQuery},
 	}
 	adminMutationMWConfig = map[string]resolve.MutationMiddlewares{
-		"backup":          commonAdminMutationMWs,
-		"config":          commonAdminMutationMWs,
-		"draining":        commonAdminMutationMWs,
-		"export":          commonAdminMutationMWs,
-		"login":           {resolve.IpWhitelistingMW4Mutation, resolve.LoggingMWMutation},
-		"restore":         commonAdminMutationMWs,
-		"shutdown":        commonAdminMutationMWs,
-		"updateGQLSchema": commonAdminMutationMWs,
+		"backup":               commonAdminMutationMWs,
+		"config":               commonAdminMutationMWs,
+		"draining":             commonAdminMutationMWs,
+		"export":               commonAdminMutationMWs,
+		"login":                {resolve.IpWhitelistingMW4Mutation, resolve.LoggingMWMutation},
+		"maintenance":          commonAdminMutationMWs,
+		"restore":              commonAdminMutationMWs,
+		"shutdown":             commonAdminMutationMWs,
+		"updateGQLSchema":      commonAdminMutationMWs,
+		"validateGQLSchema":    commonAdminMutationMWs,
+		"persistQuery":         commonAdminMutationMWs,
+		"rollbackSchemaChange": commonAdminMutationMWs,
+		"verifyIntegrity":      commonAdminMutationMWs,
+		"promoteReplica":       commonAdminMutationMWs,
 		// for queries and mutations related to User/Group, dgraph handles Guardian auth,
 		// so no need to apply GuardianAuth Middleware
 		"addUser":                   {resolve.IpWhitelistingMW4Mutation, resolve.LoggingMWMutation},
@@ -433,9 +814,8 @@ type adminServer struct {
 
 	// When the schema changes, we use these to create a new RequestResolver for
 	// the main graphql endpoint (gqlServer) and thus refresh the API.
-	fns               *resolve.ResolverFns
-	withIntrospection bool
-	globalEpoch       *uint64
+	fns         *resolve.ResolverFns
+	globalEpoch *uint64
 }
 
 // NewServers initializes the GraphQL servers.  It sets up an empty server for the
@@ -457,7 +837,13 @@ func NewServers(withIntrospection bool, globalEpoch *uint64, closer *z.Closer) (
 		Drw: resolve.NewDeleteRewriter(),
 		Ex:  resolve.NewDgraphExecutor(),
 	}
-	adminResolvers := newAdminResolver(mainServer, fns, withIntrospection, globalEpoch, closer)
+	if !withIntrospection {
+		setIntrospectionMode(introspectionDisabled)
+	}
+	if x.Config.GraphqlErrorRedaction != "" {
+		schema.SetErrorRedaction(x.Config.GraphqlErrorRedaction)
+	}
+	adminResolvers := newAdminResolver(mainServer, fns, globalEpoch, closer)
 	adminServer := web.NewServer(globalEpoch, adminResolvers, true)
 
 	return mainServer, adminServer, mainHealthStore
@@ -467,7 +853,6 @@ func NewServers(withIntrospection bool, globalEpoch *uint64, closer *z.Closer) (
 func newAdminResolver(
 	gqlServer web.IServeGraphQL,
 	fns *resolve.ResolverFns,
-	withIntrospection bool,
 	epoch *uint64,
 	closer *z.Closer) *resolve.RequestResolver {
 
@@ -479,12 +864,11 @@ func newAdminResolver(
 	rf := newAdminResolverFactory()
 
 	server := &adminServer{
-		rf:                rf,
-		resolver:          resolve.New(adminSchema, rf),
-		gqlServer:         gqlServer,
-		fns:               fns,
-		withIntrospection: withIntrospection,
-		globalEpoch:       epoch,
+		rf:          rf,
+		resolver:    resolve.New(adminSchema, rf),
+		gqlServer:   gqlServer,
+		fns:         fns,
+		globalEpoch: epoch,
 	}
 
 	prefix := x.DataKey(worker.GqlSchemaPred, 0)
@@ -551,13 +935,16 @@ func newAdminResolver(
 func newAdminResolverFactory() resolve.ResolverFactory {
 
 	adminMutationResolvers := map[string]resolve.MutationResolverFunc{
-		"backup":   resolveBackup,
-		"config":   resolveUpdateConfig,
-		"draining": resolveDraining,
-		"export":   resolveExport,
-		"login":    resolveLogin,
-		"restore":  resolveRestore,
-		"shutdown": resolveShutdown,
+		"backup":          resolveBackup,
+		"config":          resolveUpdateConfig,
+		"draining":        resolveDraining,
+		"export":          resolveExport,
+		"login":           resolveLogin,
+		"maintenance":     resolveMaintenance,
+		"restore":         resolveRestore,
+		"shutdown":        resolveShutdown,
+		"verifyIntegrity": resolveVerifyIntegrity,
+		"promoteReplica":  resolvePromoteReplica,
 	}
 
 	rf := resolverFactoryWithErrorMsg(errResolverNotFound).
@@ -578,6 +965,12 @@ func newAdminResolverFactory() resolve.ResolverFactory {
 		WithQueryResolver("restoreStatus", func(q schema.Query) resolve.QueryResolver {
 			return resolve.QueryResolverFunc(resolveRestoreStatus)
 		}).
+		WithQueryResolver("integrityReport", func(q schema.Query) resolve.QueryResolver {
+			return resolve.QueryResolverFunc(resolveIntegrityReport)
+		}).
+		WithQueryResolver("replicationStatus", func(q schema.Query) resolve.QueryResolver {
+			return resolve.QueryResolverFunc(resolveReplicationStatus)
+		}).
 		WithMutationResolver("updateGQLSchema", func(m schema.Mutation) resolve.MutationResolver {
 			return resolve.MutationResolverFunc(
 				func(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
@@ -592,6 +985,27 @@ func newAdminResolverFactory() resolve.ResolverFactory {
 						false
 				})
 		}).
+		WithMutationResolver("validateGQLSchema", func(m schema.Mutation) resolve.MutationResolver {
+			return resolve.MutationResolverFunc(
+				func(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
+					return &resolve.Resolved{Err: errors.Errorf(errMsgServerNotReady), Field: m},
+						false
+				})
+		}).
+		WithMutationResolver("persistQuery", func(m schema.Mutation) resolve.MutationResolver {
+			return resolve.MutationResolverFunc(
+				func(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
+					return &resolve.Resolved{Err: errors.Errorf(errMsgServerNotReady), Field: m},
+						false
+				})
+		}).
+		WithMutationResolver("rollbackSchemaChange", func(m schema.Mutation) resolve.MutationResolver {
+			return resolve.MutationResolverFunc(
+				func(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
+					return &resolve.Resolved{Err: errors.Errorf(errMsgServerNotReady), Field: m},
+						false
+				})
+		}).
 		WithQueryResolver("getGQLSchema", func(q schema.Query) resolve.QueryResolver {
 			return resolve.QueryResolverFunc(
 				func(ctx context.Context, query schema.Query) *resolve.Resolved {
@@ -609,6 +1023,12 @@ func newAdminResolverFactory() resolve.ResolverFactory {
 				func(ctx context.Context, query schema.Query) *resolve.Resolved {
 					return &resolve.Resolved{Err: errors.Errorf(errMsgServerNotReady), Field: q}
 				})
+		}).
+		WithQueryResolver("querySchemaChanges", func(q schema.Query) resolve.QueryResolver {
+			return resolve.QueryResolverFunc(
+				func(ctx context.Context, query schema.Query) *resolve.Resolved {
+					return &resolve.Resolved{Err: errors.Errorf(errMsgServerNotReady), Field: q}
+				})
 		})
 	for gqlMut, resolver := range adminMutationResolvers {
 		// gotta force go to evaluate the right function at each loop iteration
@@ -813,6 +1233,18 @@ func (as *adminServer) addConnectedAdminResolvers() {
 			}).
 		WithMutationResolver("replaceAllowedCORSOrigins", func(m schema.Mutation) resolve.MutationResolver {
 			return resolve.MutationResolverFunc(resolveReplaceAllowedCORSOrigins)
+		}).
+		WithMutationResolver("validateGQLSchema", func(m schema.Mutation) resolve.MutationResolver {
+			return resolve.MutationResolverFunc(resolveValidateGQLSchema)
+		}).
+		WithMutationResolver("persistQuery", func(m schema.Mutation) resolve.MutationResolver {
+			return resolve.MutationResolverFunc(resolvePersistQuery)
+		}).
+		WithQueryResolver("querySchemaChanges", func(q schema.Query) resolve.QueryResolver {
+			return resolve.QueryResolverFunc(resolveQuerySchemaChanges)
+		}).
+		WithMutationResolver("rollbackSchemaChange", func(m schema.Mutation) resolve.MutationResolver {
+			return resolve.MutationResolverFunc(resolveRollbackSchemaChange)
 		})
 }
 
@@ -842,11 +1274,15 @@ func (as *adminServer) resetSchema(gqlSchema schema.Schema) {
 		resolverFactory = resolverFactoryWithErrorMsg(errNoGraphQLSchema)
 		gqlSchema, _ = schema.FromString("")
 	} else {
+		// Introspection is checked against the introspection mode for each request, as the mode
+		// can be changed with the config mutation.
 		resolverFactory = resolverFactoryWithErrorMsg(errResolverNotFound).
-			WithConventionResolvers(gqlSchema, as.fns)
-		if as.withIntrospection {
-			resolverFactory.WithSchemaIntrospection()
-		}
+			WithConventionResolvers(gqlSchema, as.fns).
+			WithSchemaIntrospection().
+			WithQueryMiddlewareConfig(map[string]resolve.QueryMiddlewares{
+				"__schema": {introspectionMW4Query},
+				"__type":   {introspectionMW4Query},
+			})
 	}
 
 	// Increment the Epoch when you get a new schema. So, that subscription's local epoch
@@ -19,19 +19,77 @@ package admin
 import (
 	"context"
 	"encoding/json"
+	"sync/atomic"
 
+	"github.com/dgraph-io/dgraph/graphql/authorization"
 	"github.com/dgraph-io/dgraph/graphql/resolve"
 	"github.com/dgraph-io/dgraph/graphql/schema"
 	"github.com/dgraph-io/dgraph/worker"
 	"github.com/golang/glog"
+	"github.com/pkg/errors"
 )
 
 type configInput struct {
-	CacheMb float64
+	// CacheMb is kept as *float64 so that the caches are only resized when it's specified.
+	CacheMb *float64
+	// PostingListCacheMb, BlockCacheMb and IndexCacheMb resize a single cache each.
+	PostingListCacheMb *float64
+	BlockCacheMb       *float64
+	IndexCacheMb       *float64
+	// VlogGCDiscardRatio and VlogGCMaxRuns are the thresholds of the value log GC.
+	VlogGCDiscardRatio *float64
+	VlogGCMaxRuns      *int
 	// LogRequest is used to update WorkerOptions.LogRequest. true value of LogRequest enables
 	// logging of all requests coming to alphas. LogRequest type has been kept as *bool instead of
 	// bool to avoid updating WorkerOptions.LogRequest when it has default value of false.
 	LogRequest *bool
+	// StrictSchema is used to enable or disable the strict schema mode.
+	StrictSchema *bool
+	// Introspection is the IntrospectionMode to set for /graphql.
+	Introspection *string
+	// ErrorRedaction is the ErrorRedaction policy to set for GraphQL responses.
+	ErrorRedaction *string
+}
+
+const (
+	introspectionEnabled       = "ENABLED"
+	introspectionDisabled      = "DISABLED"
+	introspectionAuthenticated = "AUTHENTICATED"
+)
+
+// introspection holds the IntrospectionMode of /graphql. It starts from the
+// --graphql_introspection flag and can be changed with the config mutation.
+var introspection atomic.Value
+
+func init() {
+	introspection.Store(introspectionEnabled)
+}
+
+func setIntrospectionMode(mode string) {
+	introspection.Store(mode)
+}
+
+func introspectionMode() string {
+	return introspection.Load().(string)
+}
+
+// introspectionMW4Query blocks the introspection queries of /graphql that aren't allowed by the
+// introspection mode.
+func introspectionMW4Query(resolver resolve.QueryResolver) resolve.QueryResolver {
+	return resolve.QueryResolverFunc(func(ctx context.Context, q schema.Query) *resolve.Resolved {
+		switch introspectionMode() {
+		case introspectionDisabled:
+			return resolve.EmptyResult(q, errors.New("Introspection is disabled."))
+		case introspectionAuthenticated:
+			// ExtractCustomClaims validates the JWT, if the request has one.
+			_, err := authorization.ExtractCustomClaims(ctx)
+			if err != nil || authorization.GetJwtToken(ctx) == "" {
+				return resolve.EmptyResult(q,
+					errors.New("Introspection requires a request with a valid JWT."))
+			}
+		}
+		return resolver.Resolve(ctx, q)
+	})
 }
 
 func resolveUpdateConfig(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
@@ -42,15 +100,61 @@ func resolveUpdateConfig(ctx context.Context, m schema.Mutation) (*resolve.Resol
 		return resolve.EmptyResult(m, err), false
 	}
 
-	if err = worker.UpdateCacheMb(int64(input.CacheMb)); err != nil {
+	if err = validateConfigInput(input); err != nil {
 		return resolve.EmptyResult(m, err), false
 	}
 
+	if input.CacheMb != nil {
+		if err = worker.UpdateCacheMb(int64(*input.CacheMb)); err != nil {
+			return resolve.EmptyResult(m, err), false
+		}
+	}
+
+	sizes := worker.CacheSizes{
+		PostingList: mbToBytes(input.PostingListCacheMb),
+		Block:       mbToBytes(input.BlockCacheMb),
+		Index:       mbToBytes(input.IndexCacheMb),
+	}
+	if sizes != (worker.CacheSizes{}) {
+		if err = worker.UpdateCacheSizes(sizes); err != nil {
+			return resolve.EmptyResult(m, err), false
+		}
+	}
+
+	if input.VlogGCDiscardRatio != nil || input.VlogGCMaxRuns != nil {
+		discardRatio, maxRuns := worker.VlogGCThresholds()
+		if input.VlogGCDiscardRatio != nil {
+			discardRatio = *input.VlogGCDiscardRatio
+		}
+		if input.VlogGCMaxRuns != nil {
+			maxRuns = *input.VlogGCMaxRuns
+		}
+		if err = worker.UpdateVlogGCThresholds(discardRatio, maxRuns); err != nil {
+			return resolve.EmptyResult(m, err), false
+		}
+	}
+
 	// input.LogRequest will be nil, when it is not specified explicitly in config request.
 	if input.LogRequest != nil {
 		worker.UpdateLogRequest(*input.LogRequest)
 	}
 
+	if input.StrictSchema != nil {
+		if err = worker.UpdateStrictSchema(ctx, *input.StrictSchema); err != nil {
+			return resolve.EmptyResult(m, err), false
+		}
+	}
+
+	if input.Introspection != nil {
+		glog.Infof("Setting the GraphQL introspection mode to %s", *input.Introspection)
+		setIntrospectionMode(*input.Introspection)
+	}
+
+	if input.ErrorRedaction != nil {
+		glog.Infof("Setting the GraphQL error redaction to %s", *input.ErrorRedaction)
+		schema.SetErrorRedaction(*input.ErrorRedaction)
+	}
+
 	return &resolve.Resolved{
 		Data:  map[string]interface{}{m.Name(): response("Success", "Config updated successfully")},
 		Field: m,
@@ -62,6 +166,14 @@ func resolveGetConfig(ctx context.Context, q schema.Query) *resolve.Resolved {
 
 	conf := make(map[string]interface{})
 	conf["cacheMb"] = float64(worker.Config.CacheMb)
+	sizes := worker.CurrentCacheSizes()
+	conf["postingListCacheMb"] = bytesToMb(sizes.PostingList)
+	conf["blockCacheMb"] = bytesToMb(sizes.Block)
+	conf["indexCacheMb"] = bytesToMb(sizes.Index)
+	conf["vlogGCDiscardRatio"], conf["vlogGCMaxRuns"] = worker.VlogGCThresholds()
+	conf["strictSchema"] = worker.StrictSchemaEnabled()
+	conf["introspection"] = introspectionMode()
+	conf["errorRedaction"] = schema.ErrorRedaction()
 
 	return &resolve.Resolved{
 		Data:  map[string]interface{}{q.Name(): conf},
@@ -70,6 +182,45 @@ func resolveGetConfig(ctx context.Context, q schema.Query) *resolve.Resolved {
 
 }
 
+// validateConfigInput checks the whole input before any of it is applied, so that an invalid
+// config update doesn't change anything.
+func validateConfigInput(input *configInput) error {
+	for name, mb := range map[string]*float64{
+		"postingListCacheMb": input.PostingListCacheMb,
+		"blockCacheMb":       input.BlockCacheMb,
+		"indexCacheMb":       input.IndexCacheMb,
+	} {
+		if mb == nil {
+			continue
+		}
+		if input.CacheMb != nil {
+			return errors.Errorf("cacheMb and %s can't be set together.", name)
+		}
+		if mbToBytes(mb) <= 0 {
+			return errors.Errorf("%s must be positive.", name)
+		}
+	}
+	if r := input.VlogGCDiscardRatio; r != nil && (*r <= 0 || *r >= 1) {
+		return errors.New("vlogGCDiscardRatio must be between 0 and 1.")
+	}
+	if n := input.VlogGCMaxRuns; n != nil && *n < 0 {
+		return errors.New("vlogGCMaxRuns must be non-negative.")
+	}
+	return nil
+}
+
+// mbToBytes returns the size in bytes of the given size in MB, or 0 if it's not set.
+func mbToBytes(mb *float64) int64 {
+	if mb == nil {
+		return 0
+	}
+	return int64(*mb * (1 << 20))
+}
+
+func bytesToMb(b int64) float64 {
+	return float64(b) / (1 << 20)
+}
+
 func getConfigInput(m schema.Mutation) (*configInput, error) {
 	inputArg := m.ArgValue(schema.InputArgName)
 	inputByts, err := json.Marshal(inputArg)
@@ -0,0 +1,71 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package admin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dgraph-io/dgraph/graphql/resolve"
+	"github.com/dgraph-io/dgraph/graphql/schema"
+	"github.com/dgraph-io/dgraph/graphql/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntrospectionMode(t *testing.T) {
+	defer setIntrospectionMode(introspectionMode())
+
+	gqlSchema := test.LoadSchema(t, graphqlAdminSchema)
+	op, err := gqlSchema.Operation(&schema.Request{Query: `query { __schema { types { name } } }`})
+	require.NoError(t, err)
+	q := test.GetQuery(t, op)
+
+	resolver := introspectionMW4Query(resolve.QueryResolverFunc(
+		func(ctx context.Context, q schema.Query) *resolve.Resolved {
+			return &resolve.Resolved{Field: q}
+		}))
+
+	setIntrospectionMode(introspectionEnabled)
+	require.Nil(t, resolver.Resolve(context.Background(), q).Err)
+
+	setIntrospectionMode(introspectionDisabled)
+	resolved := resolver.Resolve(context.Background(), q)
+	require.Error(t, resolved.Err)
+	require.Contains(t, resolved.Err.Error(), "Introspection is disabled.")
+
+	setIntrospectionMode(introspectionAuthenticated)
+	resolved = resolver.Resolve(context.Background(), q)
+	require.Error(t, resolved.Err)
+	require.Contains(t, resolved.Err.Error(), "Introspection requires a request with a valid JWT.")
+}
+
+func TestValidateConfigInput(t *testing.T) {
+	mb, ratio, runs := 64.0, 0.5, 2
+	require.NoError(t, validateConfigInput(&configInput{
+		BlockCacheMb: &mb, VlogGCDiscardRatio: &ratio, VlogGCMaxRuns: &runs}))
+
+	zero, negative := 0.0, -1
+	for input, msg := range map[*configInput]string{
+		{CacheMb: &mb, IndexCacheMb: &mb}: "cacheMb and indexCacheMb can't be set together.",
+		{PostingListCacheMb: &zero}:       "postingListCacheMb must be positive.",
+		{VlogGCDiscardRatio: &mb}:         "vlogGCDiscardRatio must be between 0 and 1.",
+		{VlogGCDiscardRatio: &zero}:       "vlogGCDiscardRatio must be between 0 and 1.",
+		{VlogGCMaxRuns: &negative}:        "vlogGCMaxRuns must be non-negative.",
+	} {
+		require.EqualError(t, validateConfigInput(input), msg)
+	}
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package admin
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/dgraph-io/dgraph/graphql/resolve"
+	"github.com/dgraph-io/dgraph/graphql/schema"
+	"github.com/dgraph-io/dgraph/worker"
+	"github.com/golang/glog"
+)
+
+func resolveVerifyIntegrity(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
+	glog.Info("Got verifyIntegrity request through GraphQL admin API")
+
+	var preds []string
+	input, _ := m.ArgValue(schema.InputArgName).(map[string]interface{})
+	list, _ := input["predicates"].([]interface{})
+	for _, p := range list {
+		if pred, ok := p.(string); ok {
+			preds = append(preds, pred)
+		}
+	}
+	if err := worker.VerifyIntegrity(preds); err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	return &resolve.Resolved{
+		Data:  map[string]interface{}{m.Name(): response("Success", "Integrity verification started")},
+		Field: m,
+	}, true
+}
+
+func resolveIntegrityReport(ctx context.Context, q schema.Query) *resolve.Resolved {
+	report := worker.LastIntegrityReport()
+	if report == nil {
+		return &resolve.Resolved{
+			Data:  map[string]interface{}{q.Name(): nil},
+			Field: q,
+		}
+	}
+
+	b, err := json.Marshal(report)
+	if err != nil {
+		return resolve.EmptyResult(q, err)
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal(b, &result); err != nil {
+		return resolve.EmptyResult(q, err)
+	}
+	result["healthy"] = report.Healthy()
+
+	return &resolve.Resolved{
+		Data:  map[string]interface{}{q.Name(): result},
+		Field: q,
+	}
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package admin
+
+import (
+	"context"
+	"time"
+
+	"github.com/dgraph-io/dgraph/graphql/resolve"
+	"github.com/dgraph-io/dgraph/graphql/schema"
+	"github.com/dgraph-io/dgraph/worker"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+func resolveMaintenance(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
+	glog.Info("Got maintenance request through GraphQL admin API")
+
+	input, _ := m.ArgValue(schema.InputArgName).(map[string]interface{})
+	run, _ := input["run"].(bool)
+	deferFor, hasDefer := input["deferFor"].(string)
+	if run && hasDefer {
+		return resolve.EmptyResult(m, errors.New("run and deferFor can't be set together.")), false
+	}
+
+	msg := "Maintenance schedule unchanged"
+	switch {
+	case run:
+		if err := worker.RunMaintenance(); err != nil {
+			return resolve.EmptyResult(m, err), false
+		}
+		msg = "Maintenance started"
+	case hasDefer:
+		d, err := time.ParseDuration(deferFor)
+		if err != nil || d < 0 {
+			err = errors.Errorf("Invalid deferFor %q, expected a duration such as 2h.", deferFor)
+			return resolve.EmptyResult(m, err), false
+		}
+		if d == 0 {
+			msg = "Maintenance is no longer deferred"
+		} else {
+			msg = "Maintenance deferred"
+		}
+		worker.DeferMaintenance(d)
+	}
+
+	payload := response("Success", msg)
+	payload["deferredUntil"] = nil
+	if until := worker.MaintenanceDeferredUntil(); !until.IsZero() {
+		payload["deferredUntil"] = until.UTC().Format(time.RFC3339)
+	}
+	return &resolve.Resolved{
+		Data:  map[string]interface{}{m.Name(): payload},
+		Field: m,
+	}, true
+}
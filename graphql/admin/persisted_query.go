@@ -0,0 +1,48 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package admin
+
+import (
+	"context"
+
+	"github.com/dgraph-io/dgraph/edgraph"
+	"github.com/dgraph-io/dgraph/graphql/resolve"
+	"github.com/dgraph-io/dgraph/graphql/schema"
+	"github.com/pkg/errors"
+)
+
+// resolvePersistQuery stores a persisted query, so that it can be run on /graphql by its hash.
+func resolvePersistQuery(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
+	query, _ := m.ArgValue("query").(string)
+	if query == "" {
+		return resolve.EmptyResult(m, errors.New("A persisted query can't be empty.")), false
+	}
+
+	hash, err := edgraph.AddPersistedQuery(ctx, query)
+	if err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	return &resolve.Resolved{
+		Data: map[string]interface{}{
+			m.Name(): map[string]interface{}{
+				"sha256Hash": hash,
+				"query":      query,
+			},
+		},
+		Field: m,
+	}, true
+}
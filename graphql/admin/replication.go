@@ -0,0 +1,67 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package admin
+
+import (
+	"context"
+	"time"
+
+	"github.com/dgraph-io/dgraph/graphql/resolve"
+	"github.com/dgraph-io/dgraph/graphql/schema"
+	"github.com/dgraph-io/dgraph/worker"
+	"github.com/golang/glog"
+)
+
+func resolvePromoteReplica(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
+	glog.Info("Got promoteReplica request through GraphQL admin API")
+
+	if err := worker.PromoteReplica(ctx); err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	return &resolve.Resolved{
+		Data:  map[string]interface{}{m.Name(): response("Success", "Replica promoted")},
+		Field: m,
+	}, true
+}
+
+func resolveReplicationStatus(ctx context.Context, q schema.Query) *resolve.Resolved {
+	rs := worker.ReplicationStatus()
+	if rs == nil {
+		return &resolve.Resolved{
+			Data:  map[string]interface{}{q.Name(): nil},
+			Field: q,
+		}
+	}
+
+	result := map[string]interface{}{
+		"primary":      rs.Primary,
+		"primaryTs":    int64(rs.PrimaryTs),
+		"readTs":       int64(rs.ReadTs),
+		"replicatedAt": nil,
+		"lagSeconds":   nil,
+		"promoting":    rs.Promote,
+	}
+	if rs.SnapshotAt > 0 {
+		at := time.Unix(rs.SnapshotAt, 0)
+		result["replicatedAt"] = at.UTC().Format(time.RFC3339)
+		result["lagSeconds"] = time.Since(at).Seconds()
+	}
+	return &resolve.Resolved{
+		Data:  map[string]interface{}{q.Name(): result},
+		Field: q,
+	}
+}
@@ -93,6 +93,77 @@ func (usr *updateSchemaResolver) Resolve(ctx context.Context, m schema.Mutation)
 	}, true
 }
 
+// resolveValidateGQLSchema does the checks of updateGQLSchema on the input schema, and reports
+// the changes that applying it would make to the Dgraph schema, but doesn't apply it.
+func resolveValidateGQLSchema(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
+	glog.Info("Got validateGQLSchema request")
+
+	input, err := getValidateSchemaInput(m)
+	if err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+
+	invalid := func(err error) (*resolve.Resolved, bool) {
+		var errs []interface{}
+		for _, gqlErr := range schema.AsGQLErrors(err) {
+			errs = append(errs, gqlErr.Error())
+		}
+		return &resolve.Resolved{
+			Data: map[string]interface{}{
+				m.Name(): map[string]interface{}{
+					"valid":  false,
+					"errors": errs,
+				}},
+			Field: m,
+		}, true
+	}
+
+	schHandler, err := schema.NewHandler(input.Schema, true)
+	if err != nil {
+		return invalid(err)
+	}
+	if _, err = schema.FromString(schHandler.GQLSchema()); err != nil {
+		return invalid(err)
+	}
+
+	changes, err := edgraph.DiffGQLSchema(ctx, schHandler.DGSchema())
+	if err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	predChanges := make([]interface{}, 0, len(changes))
+	for _, change := range changes {
+		predChange := map[string]interface{}{
+			"predicate":      change.Predicate,
+			"newSchema":      change.NewSchema,
+			"indexesAdded":   toInterfaceSlice(change.IndexesAdded),
+			"indexesDropped": toInterfaceSlice(change.IndexesDropped),
+		}
+		if change.OldSchema != "" {
+			predChange["oldSchema"] = change.OldSchema
+		}
+		predChanges = append(predChanges, predChange)
+	}
+
+	return &resolve.Resolved{
+		Data: map[string]interface{}{
+			m.Name(): map[string]interface{}{
+				"valid":            true,
+				"generatedSchema":  schHandler.GQLSchema(),
+				"dgraphSchema":     schHandler.DGSchema(),
+				"predicateChanges": predChanges,
+			}},
+		Field: m,
+	}, true
+}
+
+func toInterfaceSlice(strs []string) []interface{} {
+	result := make([]interface{}, 0, len(strs))
+	for _, s := range strs {
+		result = append(result, s)
+	}
+	return result
+}
+
 func (gsr *getSchemaResolver) Rewrite(ctx context.Context,
 	gqlQuery schema.Query) (*gql.GraphQuery, error) {
 	gsr.gqlQuery = gqlQuery
@@ -160,3 +231,15 @@ func getSchemaInput(m schema.Mutation) (*updateGQLSchemaInput, error) {
 	err = json.Unmarshal(inputByts, &input)
 	return &input, schema.GQLWrapf(err, "couldn't get input argument")
 }
+
+func getValidateSchemaInput(m schema.Mutation) (*gqlSchema, error) {
+	inputArg := m.ArgValue(schema.InputArgName)
+	inputByts, err := json.Marshal(inputArg)
+	if err != nil {
+		return nil, schema.GQLWrapf(err, "couldn't get input argument")
+	}
+
+	var input gqlSchema
+	err = json.Unmarshal(inputByts, &input)
+	return &input, schema.GQLWrapf(err, "couldn't get input argument")
+}
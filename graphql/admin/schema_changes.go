@@ -0,0 +1,99 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package admin
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/dgraph-io/dgraph/edgraph"
+	"github.com/dgraph-io/dgraph/graphql/resolve"
+	"github.com/dgraph-io/dgraph/graphql/schema"
+)
+
+// resolveQuerySchemaChanges returns the changes of the Dgraph schema, the latest first.
+func resolveQuerySchemaChanges(ctx context.Context, q schema.Query) *resolve.Resolved {
+	first, offset := intArgValue(q, "first"), intArgValue(q, "offset")
+	entries, err := edgraph.GetSchemaHistory(ctx, first, offset)
+	if err != nil {
+		return resolve.EmptyResult(q, err)
+	}
+
+	changes := make([]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		changes = append(changes, schemaChangeData(entry))
+	}
+	return &resolve.Resolved{
+		Data:  map[string]interface{}{q.Name(): changes},
+		Field: q,
+	}
+}
+
+// resolveRollbackSchemaChange rolls back the last change of the Dgraph schema, and returns it.
+func resolveRollbackSchemaChange(ctx context.Context, m schema.Mutation) (*resolve.Resolved,
+	bool) {
+	entry, err := edgraph.RollbackSchemaChange(ctx)
+	if err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	return &resolve.Resolved{
+		Data:  map[string]interface{}{m.Name(): schemaChangeData(entry)},
+		Field: m,
+	}, true
+}
+
+func schemaChangeData(entry *edgraph.SchemaHistoryEntry) map[string]interface{} {
+	entries := func(changes []*edgraph.SchemaChangeEntry) []interface{} {
+		out := make([]interface{}, 0, len(changes))
+		for _, change := range changes {
+			out = append(out, map[string]interface{}{
+				"name":   change.Name,
+				"before": optionalString(change.Before),
+				"after":  optionalString(change.After),
+			})
+		}
+		return out
+	}
+	return map[string]interface{}{
+		"operation":    entry.Change.Operation,
+		"changedAt":    entry.ChangedAt.Format(time.RFC3339Nano),
+		"changedBy":    optionalString(entry.ChangedBy),
+		"predicates":   entries(entry.Change.Predicates),
+		"types":        entries(entry.Change.Types),
+		"irreversible": optionalString(entry.Change.Irreversible),
+	}
+}
+
+// optionalString returns nil for the empty string, which is returned as null.
+func optionalString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// intArgValue returns the value of the Int argument name of the query, or 0 if it isn't given.
+func intArgValue(q schema.Query, name string) int {
+	val := q.ArgValue(name)
+	if val == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(fmt.Sprintf("%v", val))
+	return n
+}
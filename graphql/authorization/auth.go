@@ -31,6 +31,7 @@ import (
 	"time"
 
 	"github.com/dgrijalva/jwt-go/v4"
+	"github.com/golang/glog"
 	"github.com/pkg/errors"
 	"github.com/vektah/gqlparser/v2/gqlerror"
 	"google.golang.org/grpc/metadata"
@@ -48,6 +49,17 @@ const (
 	AuthMetaHeader = "# Dgraph.Authorization "
 )
 
+const (
+	// issuerCheckInterval is how often the JWKs of the issuers are checked for expiry.
+	issuerCheckInterval = time.Minute
+	// jwkRefreshInterval is how often the JWKs of an issuer are fetched again, if the JWKUrl
+	// doesn't say how long they can be cached for.
+	jwkRefreshInterval = time.Hour
+	// minJWKRefetchInterval is the least time between two fetches of the JWKs of an issuer when
+	// a JWT is signed with a key id that isn't in them, as happens after the keys are rotated.
+	minJWKRefetchInterval = time.Minute
+)
+
 var (
 	authMeta = &AuthMeta{}
 )
@@ -62,6 +74,25 @@ type AuthMeta struct {
 	Namespace       string
 	Algo            string
 	Audience        []string
+	// Issuers are the identity providers that JWTs can come from. They are used instead of
+	// VerificationKey, Algo and JWKUrl when JWTs from more than one provider are accepted.
+	Issuers []*JWTIssuer
+	// ClockSkew is the number of seconds by which the exp, nbf and iat claims of a JWT may be
+	// off from the clock of Dgraph.
+	ClockSkew   int64
+	stopRefresh chan struct{}
+	sync.RWMutex
+}
+
+// JWTIssuer is an identity provider whose JWTs have Issuer as the `iss` claim, and are signed
+// with one of the keys at JWKUrl.  The JWKs are refreshed in the background.
+type JWTIssuer struct {
+	Issuer     string
+	JWKUrl     string
+	Audience   []string
+	jwkSet     *jose.JSONWebKeySet
+	expiryTime time.Time
+	fetchedAt  time.Time
 	sync.RWMutex
 }
 
@@ -69,9 +100,32 @@ type AuthMeta struct {
 func (a *AuthMeta) validate() error {
 	var fields string
 
+	if a.ClockSkew < 0 {
+		return fmt.Errorf("ClockSkew in Dgraph.Authorization can't be negative")
+	}
+
+	// If Issuers are provided, we don't expect JWKUrl or (VerificationKey, Algo), as each
+	// issuer has its own JWKUrl.
 	// If JWKUrl is provided, we don't expect (VerificationKey, Algo),
 	// they are needed only if JWKUrl is not present there.
-	if a.JWKUrl != "" {
+	if len(a.Issuers) > 0 {
+		if a.JWKUrl != "" || a.VerificationKey != "" || a.Algo != "" {
+			return fmt.Errorf("expecting either Issuers, JWKUrl or (VerificationKey, Algo), " +
+				"more than one were given")
+		}
+		seen := make(map[string]bool)
+		for _, iss := range a.Issuers {
+			if iss.Issuer == "" || iss.JWKUrl == "" {
+				return fmt.Errorf("required field missing in Dgraph.Authorization Issuers: " +
+					"each issuer needs an `Issuer` and a `JWKUrl`")
+			}
+			if seen[iss.Issuer] {
+				return fmt.Errorf("issuer %s is given more than once in Dgraph.Authorization",
+					iss.Issuer)
+			}
+			seen[iss.Issuer] = true
+		}
+	} else if a.JWKUrl != "" {
 		if a.VerificationKey != "" || a.Algo != "" {
 			return fmt.Errorf("expecting either JWKUrl or (VerificationKey, Algo), both were given")
 		}
@@ -202,6 +256,24 @@ func (a *AuthMeta) namespace() string {
 	return a.Namespace
 }
 
+func (a *AuthMeta) clockSkew() time.Duration {
+	a.RLock()
+	defer a.RUnlock()
+	return time.Duration(a.ClockSkew) * time.Second
+}
+
+// issuer returns the issuer of JWTs with iss as the `iss` claim, or nil if there's none.
+func (a *AuthMeta) issuer(iss string) *JWTIssuer {
+	a.RLock()
+	defer a.RUnlock()
+	for _, i := range a.Issuers {
+		if i.Issuer == iss {
+			return i
+		}
+	}
+	return nil
+}
+
 func (a *AuthMeta) getJWKSet() *jose.JSONWebKeySet {
 	a.RLock()
 	defer a.RUnlock()
@@ -233,6 +305,18 @@ func SetAuthMeta(m *AuthMeta) {
 	authMeta.Namespace = m.Namespace
 	authMeta.Algo = m.Algo
 	authMeta.Audience = m.Audience
+	authMeta.Issuers = m.Issuers
+	authMeta.ClockSkew = m.ClockSkew
+
+	// The JWKs of the previous issuers don't need to be refreshed anymore.
+	if authMeta.stopRefresh != nil {
+		close(authMeta.stopRefresh)
+		authMeta.stopRefresh = nil
+	}
+	if len(m.Issuers) > 0 {
+		authMeta.stopRefresh = make(chan struct{})
+		go refreshIssuers(m.Issuers, authMeta.stopRefresh)
+	}
 }
 
 // AttachAuthorizationJwt adds any incoming JWT authorization data into the grpc context metadata.
@@ -281,20 +365,20 @@ func (c *CustomClaims) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
-func (c *CustomClaims) validateAudience() error {
+func (c *CustomClaims) validateAudience(audience []string) error {
 	// If there's no audience claim, ignore
 	if c.Audience == nil || len(c.Audience) == 0 {
 		return nil
 	}
 
 	// If there is an audience claim, but no value provided, fail
-	if authMeta.Audience == nil {
+	if audience == nil {
 		return fmt.Errorf("audience value was expected but not provided")
 	}
 
 	var match = false
 	for _, audStr := range c.Audience {
-		for _, expectedAudStr := range authMeta.Audience {
+		for _, expectedAudStr := range audience {
 			if subtle.ConstantTimeCompare([]byte(audStr), []byte(expectedAudStr)) == 1 {
 				match = true
 				break
@@ -307,6 +391,15 @@ func (c *CustomClaims) validateAudience() error {
 	return nil
 }
 
+// An InvalidJWTError is returned when the JWT of a request can't be verified.
+type InvalidJWTError struct {
+	Err error
+}
+
+func (e *InvalidJWTError) Error() string {
+	return e.Err.Error()
+}
+
 func ExtractCustomClaims(ctx context.Context) (*CustomClaims, error) {
 	// return CustomClaims containing jwt and authvariables.
 	md, ok := metadata.FromIncomingContext(ctx)
@@ -318,9 +411,13 @@ func ExtractCustomClaims(ctx context.Context) (*CustomClaims, error) {
 	if len(jwtToken) == 0 {
 		return &CustomClaims{}, nil
 	} else if len(jwtToken) > 1 {
-		return nil, fmt.Errorf("invalid jwt auth token")
+		return nil, &InvalidJWTError{Err: fmt.Errorf("invalid jwt auth token")}
 	}
-	return validateJWTCustomClaims(jwtToken[0])
+	claims, err := validateJWTCustomClaims(jwtToken[0])
+	if err != nil {
+		return nil, &InvalidJWTError{Err: err}
+	}
+	return claims, nil
 }
 
 func GetJwtToken(ctx context.Context) string {
@@ -337,11 +434,34 @@ func GetJwtToken(ctx context.Context) string {
 
 func validateJWTCustomClaims(jwtStr string) (*CustomClaims, error) {
 	jwkURL := authMeta.jwkURL()
+	leeway := jwt.WithLeeway(authMeta.clockSkew())
+	audience := authMeta.Audience
 
 	var token *jwt.Token
 	var err error
-	// Verification through JWKUrl
-	if jwkURL != "" {
+	// Verification through the JWKUrl of the issuer of the JWT
+	if authMeta.hasIssuers() {
+		token, err =
+			jwt.ParseWithClaims(jwtStr, &CustomClaims{}, func(token *jwt.Token) (interface{}, error) {
+				claims, _ := token.Claims.(*CustomClaims)
+				issuer := authMeta.issuer(claims.Issuer)
+				if issuer == nil {
+					return nil, errors.Errorf("JWT `iss` value doesn't match any of the issuers")
+				}
+				audience = issuer.Audience
+
+				kid, _ := token.Header["kid"].(string)
+				if kid == "" {
+					return nil, errors.Errorf("kid not present in JWT")
+				}
+				key := issuer.signingKey(kid)
+				if key == nil {
+					return nil, errors.Errorf("Invalid kid")
+				}
+				return key, nil
+			}, jwt.WithoutAudienceValidation(), leeway)
+	} else if jwkURL != "" {
+		// Verification through JWKUrl
 		if authMeta.isExpired() {
 			err = authMeta.refreshJWK()
 			if err != nil {
@@ -362,7 +482,7 @@ func validateJWTCustomClaims(jwtStr string) (*CustomClaims, error) {
 					return nil, errors.Errorf("Invalid kid")
 				}
 				return signingKeys[0].Key, nil
-			}, jwt.WithoutAudienceValidation())
+			}, jwt.WithoutAudienceValidation(), leeway)
 	} else {
 		amAlgo := authMeta.algo()
 		if amAlgo == "" {
@@ -390,7 +510,7 @@ func validateJWTCustomClaims(jwtStr string) (*CustomClaims, error) {
 					}
 				}
 				return nil, errors.Errorf("couldn't parse signing method from token header: %s", algo)
-			}, jwt.WithoutAudienceValidation())
+			}, jwt.WithoutAudienceValidation(), leeway)
 	}
 
 	if err != nil {
@@ -402,35 +522,60 @@ func validateJWTCustomClaims(jwtStr string) (*CustomClaims, error) {
 		return nil, errors.Errorf("claims in jwt token is not map claims")
 	}
 
-	if err := claims.validateAudience(); err != nil {
+	if err := claims.validateAudience(audience); err != nil {
 		return nil, err
 	}
 	return claims, nil
 }
 
-// FetchJWKs fetches the JSON Web Key set from a JWKUrl. It acquires a Lock over a as some of the
-// properties of AuthMeta are modified in the process.
+func (a *AuthMeta) hasIssuers() bool {
+	a.RLock()
+	defer a.RUnlock()
+	return len(a.Issuers) > 0
+}
+
+// FetchJWKs fetches the JSON Web Key set from a JWKUrl, or from the JWKUrl of each of the
+// Issuers. It acquires a Lock over a as some of the properties of AuthMeta are modified in the
+// process.
 func (a *AuthMeta) FetchJWKs() error {
 	a.Lock()
 	defer a.Unlock()
 
+	if len(a.Issuers) > 0 {
+		for _, iss := range a.Issuers {
+			if err := iss.fetchJWKs(); err != nil {
+				return errors.Wrapf(err, "while fetching JWKs of issuer %s", iss.Issuer)
+			}
+		}
+		return nil
+	}
+
 	if a.JWKUrl == "" {
 		return errors.Errorf("No JWKUrl supplied")
 	}
 
-	req, err := http.NewRequest("GET", a.JWKUrl, nil)
+	var err error
+	a.jwkSet, a.expiryTime, err = fetchJWKs(a.JWKUrl)
+	return err
+}
+
+// fetchJWKs fetches the JSON Web Key set at jwkURL, along with the time at which it expires.
+// The time is zero if the keys don't expire.
+func fetchJWKs(jwkURL string) (*jose.JSONWebKeySet, time.Time, error) {
+	req, err := http.NewRequest("GET", jwkURL, nil)
 	if err != nil {
-		return err
+		return nil, time.Time{}, err
 	}
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return err
+		return nil, time.Time{}, err
 	}
+	defer resp.Body.Close()
 
 	data, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return err
+		return nil, time.Time{}, err
 	}
 
 	type JwkArray struct {
@@ -440,14 +585,14 @@ func (a *AuthMeta) FetchJWKs() error {
 	var jwkArray JwkArray
 	err = json.Unmarshal(data, &jwkArray)
 	if err != nil {
-		return err
+		return nil, time.Time{}, err
 	}
 
-	a.jwkSet = &jose.JSONWebKeySet{Keys: make([]jose.JSONWebKey, len(jwkArray.JWKs))}
+	jwkSet := &jose.JSONWebKeySet{Keys: make([]jose.JSONWebKey, len(jwkArray.JWKs))}
 	for i, jwk := range jwkArray.JWKs {
-		err = a.jwkSet.Keys[i].UnmarshalJSON(jwk)
+		err = jwkSet.Keys[i].UnmarshalJSON(jwk)
 		if err != nil {
-			return err
+			return nil, time.Time{}, err
 		}
 	}
 
@@ -460,12 +605,9 @@ func (a *AuthMeta) FetchJWKs() error {
 	}
 
 	if maxAge == 0 {
-		a.expiryTime = time.Time{}
-	} else {
-		a.expiryTime = time.Now().Add(time.Duration(maxAge) * time.Second)
+		return jwkSet, time.Time{}, nil
 	}
-
-	return nil
+	return jwkSet, time.Now().Add(time.Duration(maxAge) * time.Second), nil
 }
 
 func (a *AuthMeta) refreshJWK() error {
@@ -493,3 +635,78 @@ func (a *AuthMeta) isExpired() bool {
 	}
 	return time.Now().After(a.expiryTime)
 }
+
+func (i *JWTIssuer) fetchJWKs() error {
+	jwkSet, expiryTime, err := fetchJWKs(i.JWKUrl)
+
+	i.Lock()
+	defer i.Unlock()
+	i.fetchedAt = time.Now()
+	if err != nil {
+		return err
+	}
+	i.jwkSet = jwkSet
+	i.expiryTime = expiryTime
+	return nil
+}
+
+// needsRefresh returns true if the JWKs of i have expired, or if they don't expire but were
+// fetched more than jwkRefreshInterval ago.
+func (i *JWTIssuer) needsRefresh() bool {
+	i.RLock()
+	defer i.RUnlock()
+
+	if i.expiryTime.IsZero() {
+		return time.Since(i.fetchedAt) > jwkRefreshInterval
+	}
+	return time.Now().After(i.expiryTime)
+}
+
+// signingKey returns the key of i with the key id kid.  If there's no such key, the issuer might
+// have rotated its keys, so they are fetched again unless that was done very recently.
+func (i *JWTIssuer) signingKey(kid string) interface{} {
+	keyFor := func() (interface{}, bool) {
+		i.RLock()
+		defer i.RUnlock()
+		if i.jwkSet != nil {
+			if keys := i.jwkSet.Key(kid); len(keys) > 0 {
+				return keys[0].Key, true
+			}
+		}
+		return nil, time.Since(i.fetchedAt) < minJWKRefetchInterval
+	}
+
+	key, done := keyFor()
+	if done {
+		return key
+	}
+	if err := i.fetchJWKs(); err != nil {
+		glog.Errorf("Error while fetching JWKs of issuer %s: %v", i.Issuer, err)
+		return nil
+	}
+	key, _ = keyFor()
+	return key
+}
+
+// refreshIssuers fetches the JWKs of the issuers again when they need a refresh, until stop is
+// closed.
+func refreshIssuers(issuers []*JWTIssuer, stop chan struct{}) {
+	ticker := time.NewTicker(issuerCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			for _, iss := range issuers {
+				if !iss.needsRefresh() {
+					continue
+				}
+				if err := iss.fetchJWKs(); err != nil {
+					glog.Errorf("Error while refreshing JWKs of issuer %s: %v", iss.Issuer, err)
+				}
+			}
+		}
+	}
+}
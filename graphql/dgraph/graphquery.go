@@ -51,10 +51,17 @@ func writeQuery(b *strings.Builder, query *gql.GraphQuery, prefix string) {
 		x.Check2(b.WriteString(query.Alias))
 		x.Check2(b.WriteString(" : "))
 	}
-	x.Check2(b.WriteString(query.Attr))
-
-	if query.Func != nil {
-		writeRoot(b, query)
+	switch {
+	case query.IsCount:
+		x.Check2(b.WriteString(fmt.Sprintf("count(%s)", query.Attr)))
+	case query.Func != nil && isAggregator(query.Func.Name):
+		// An aggregate of the nodes in a group, like min(Post.numLikes) inside @groupby.
+		x.Check2(b.WriteString(fmt.Sprintf("%s(%s)", query.Func.Name, query.Attr)))
+	default:
+		x.Check2(b.WriteString(query.Attr))
+		if query.Func != nil {
+			writeRoot(b, query)
+		}
 	}
 
 	if query.Filter != nil {
@@ -70,15 +77,38 @@ func writeQuery(b *strings.Builder, query *gql.GraphQuery, prefix string) {
 	}
 
 	if len(query.Cascade) != 0 {
-		if query.Cascade[0] == "__all__" {
+		args := query.Cascade
+		if args[0] == "__all__" {
+			args = nil
+		}
+		if query.CascadeDepth > 0 {
+			args = append(args[:len(args):len(args)],
+				fmt.Sprintf("depth: %d", query.CascadeDepth))
+		}
+		if len(args) == 0 {
 			x.Check2(b.WriteString(" @cascade"))
 		} else {
 			x.Check2(b.WriteString(" @cascade("))
-			x.Check2(b.WriteString(strings.Join(query.Cascade, ", ")))
+			x.Check2(b.WriteString(strings.Join(args, ", ")))
 			x.Check2(b.WriteRune(')'))
 		}
 	}
 
+	if query.IsGroupby {
+		x.Check2(b.WriteString(" @groupby("))
+		for i, attr := range query.GroupbyAttrs {
+			if i != 0 {
+				x.Check2(b.WriteString(", "))
+			}
+			if attr.Alias != "" {
+				x.Check2(b.WriteString(attr.Alias))
+				x.Check2(b.WriteString(": "))
+			}
+			x.Check2(b.WriteString(attr.Attr))
+		}
+		x.Check2(b.WriteRune(')'))
+	}
+
 	switch {
 	case len(query.Children) > 0:
 		prefixAdd := ""
@@ -98,6 +128,14 @@ func writeQuery(b *strings.Builder, query *gql.GraphQuery, prefix string) {
 	}
 }
 
+func isAggregator(fn string) bool {
+	switch fn {
+	case "min", "max", "sum", "avg":
+		return true
+	}
+	return false
+}
+
 func writeUIDFunc(b *strings.Builder, uids []uint64, args []gql.Arg) {
 	x.Check2(b.WriteString("uid("))
 	if len(uids) > 0 {
@@ -123,7 +161,7 @@ func writeUIDFunc(b *strings.Builder, uids []uint64, args []gql.Arg) {
 // writeRoot writes the root function as well as any ordering and paging
 // specified in q.
 //
-// Only uid(0x123, 0x124) and type(...) functions are supported at root.
+// Only uid(0x123, 0x124), type(...) and eq(...) functions are supported at root.
 func writeRoot(b *strings.Builder, q *gql.GraphQuery) {
 	if q.Func == nil {
 		return
@@ -135,9 +173,10 @@ func writeRoot(b *strings.Builder, q *gql.GraphQuery) {
 		writeUIDFunc(b, q.Func.UID, q.Func.Args)
 	case q.Func.Name == "type" && len(q.Func.Args) == 1:
 		x.Check2(b.WriteString(fmt.Sprintf("(func: type(%s)", q.Func.Args[0].Value)))
-	case q.Func.Name == "eq" && len(q.Func.Args) == 2:
-		x.Check2(b.WriteString(fmt.Sprintf("(func: eq(%s, %s)", q.Func.Args[0].Value,
-			q.Func.Args[1].Value)))
+	case q.Func.Name == "eq" && len(q.Func.Args) >= 2:
+		x.Check2(b.WriteString("(func: eq("))
+		writeFilterArguments(b, q.Func.Args)
+		x.Check2(b.WriteRune(')'))
 	}
 	writeOrderAndPage(b, q, true)
 	x.Check2(b.WriteRune(')'))
@@ -196,14 +235,15 @@ func writeFilter(b *strings.Builder, ft *gql.FilterTree) {
 func hasOrderOrPage(q *gql.GraphQuery) bool {
 	_, hasFirst := q.Args["first"]
 	_, hasOffset := q.Args["offset"]
-	return len(q.Order) > 0 || hasFirst || hasOffset
+	_, hasAfter := q.Args["after"]
+	return len(q.Order) > 0 || hasFirst || hasOffset || hasAfter
 }
 
 func writeOrderAndPage(b *strings.Builder, query *gql.GraphQuery, root bool) {
-	var wroteOrder, wroteFirst bool
+	var wroteOrder, wroteFirst, wroteOffset bool
 
 	for _, ord := range query.Order {
-		if root {
+		if root || wroteOrder {
 			x.Check2(b.WriteString(", "))
 		}
 		if ord.Desc {
@@ -230,5 +270,14 @@ func writeOrderAndPage(b *strings.Builder, query *gql.GraphQuery, root bool) {
 		}
 		x.Check2(b.WriteString("offset: "))
 		x.Check2(b.WriteString(offset))
+		wroteOffset = true
+	}
+
+	if after, ok := query.Args["after"]; ok {
+		if root || wroteOrder || wroteFirst || wroteOffset {
+			x.Check2(b.WriteString(", "))
+		}
+		x.Check2(b.WriteString("after: "))
+		x.Check2(b.WriteString(after))
 	}
 }
@@ -26,6 +26,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/dgraph-io/dgraph/graphql/e2e/common"
+	"github.com/dgraph-io/dgraph/graphql/schema"
 )
 
 const (
@@ -112,6 +113,7 @@ func assertMissingAclError(t *testing.T, params *common.GraphQLParams) {
 			Line:   2,
 			Column: 4,
 		}},
+		Extensions: map[string]interface{}{"code": schema.ErrCodeForbidden},
 	}}, resp.Errors)
 }
 
@@ -123,6 +125,7 @@ func assertBadAclError(t *testing.T, params *common.GraphQLParams) {
 			Line:   2,
 			Column: 4,
 		}},
+		Extensions: map[string]interface{}{"code": schema.ErrCodeUnauthenticated},
 	}}, resp.Errors)
 }
 
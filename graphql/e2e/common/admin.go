@@ -53,6 +53,19 @@ const (
             ],
             "upsert": true
         },
+        {
+            "predicate": "dgraph.graphql.p_query",
+            "type": "string"
+        },
+        {
+            "predicate": "dgraph.graphql.p_sha256hash",
+            "type": "string",
+            "index": true,
+            "tokenizer": [
+                "exact"
+            ],
+            "upsert": true
+        },
         {
             "predicate": "dgraph.graphql.schema",
             "type": "string"
@@ -74,6 +87,18 @@ const (
             ],
             "upsert": true
         },
+        {
+            "predicate": "dgraph.schema.change",
+            "type": "string"
+        },
+        {
+            "predicate": "dgraph.schema.changed_at",
+            "type": "datetime"
+        },
+        {
+            "predicate": "dgraph.schema.changed_by",
+            "type": "string"
+        },
         {
             "predicate": "dgraph.type",
             "type": "string",
@@ -104,6 +129,31 @@ const (
                 }
             ],
             "name": "dgraph.graphql.history"
+        },
+        {
+            "fields": [
+                {
+                    "name": "dgraph.graphql.p_query"
+                },
+                {
+                    "name": "dgraph.graphql.p_sha256hash"
+                }
+            ],
+            "name": "dgraph.graphql.persisted_query"
+        },
+        {
+            "fields": [
+                {
+                    "name": "dgraph.schema.change"
+                },
+                {
+                    "name": "dgraph.schema.changed_by"
+                },
+                {
+                    "name": "dgraph.schema.changed_at"
+                }
+            ],
+            "name": "dgraph.schema.history"
         }
     ]
 }`
@@ -128,6 +178,19 @@ const (
             ],
             "upsert": true
         },
+        {
+            "predicate": "dgraph.graphql.p_query",
+            "type": "string"
+        },
+        {
+            "predicate": "dgraph.graphql.p_sha256hash",
+            "type": "string",
+            "index": true,
+            "tokenizer": [
+                "exact"
+            ],
+            "upsert": true
+        },
         {
             "predicate": "dgraph.graphql.schema",
             "type": "string"
@@ -149,6 +212,18 @@ const (
             ],
             "upsert": true
         },
+        {
+            "predicate": "dgraph.schema.change",
+            "type": "string"
+        },
+        {
+            "predicate": "dgraph.schema.changed_at",
+            "type": "datetime"
+        },
+        {
+            "predicate": "dgraph.schema.changed_by",
+            "type": "string"
+        },
         {
             "predicate": "dgraph.type",
             "type": "string",
@@ -187,6 +262,31 @@ const (
                 }
             ],
             "name": "dgraph.graphql.history"
+        },
+        {
+            "fields": [
+                {
+                    "name": "dgraph.graphql.p_query"
+                },
+                {
+                    "name": "dgraph.graphql.p_sha256hash"
+                }
+            ],
+            "name": "dgraph.graphql.persisted_query"
+        },
+        {
+            "fields": [
+                {
+                    "name": "dgraph.schema.change"
+                },
+                {
+                    "name": "dgraph.schema.changed_by"
+                },
+                {
+                    "name": "dgraph.schema.changed_at"
+                }
+            ],
+            "name": "dgraph.schema.history"
         }
     ]
 }`
@@ -226,6 +326,19 @@ const (
             ],
             "upsert": true
         },
+        {
+            "predicate": "dgraph.graphql.p_query",
+            "type": "string"
+        },
+        {
+            "predicate": "dgraph.graphql.p_sha256hash",
+            "type": "string",
+            "index": true,
+            "tokenizer": [
+                "exact"
+            ],
+            "upsert": true
+        },
         {
             "predicate": "dgraph.graphql.schema",
             "type": "string"
@@ -247,6 +360,18 @@ const (
             ],
             "upsert": true
         },
+        {
+            "predicate": "dgraph.schema.change",
+            "type": "string"
+        },
+        {
+            "predicate": "dgraph.schema.changed_at",
+            "type": "datetime"
+        },
+        {
+            "predicate": "dgraph.schema.changed_by",
+            "type": "string"
+        },
         {
             "predicate": "dgraph.type",
             "type": "string",
@@ -288,6 +413,31 @@ const (
                 }
             ],
             "name": "dgraph.graphql.history"
+        },
+        {
+            "fields": [
+                {
+                    "name": "dgraph.graphql.p_query"
+                },
+                {
+                    "name": "dgraph.graphql.p_sha256hash"
+                }
+            ],
+            "name": "dgraph.graphql.persisted_query"
+        },
+        {
+            "fields": [
+                {
+                    "name": "dgraph.schema.change"
+                },
+                {
+                    "name": "dgraph.schema.changed_by"
+                },
+                {
+                    "name": "dgraph.schema.changed_at"
+                }
+            ],
+            "name": "dgraph.schema.history"
         }
     ]
 }`
@@ -335,6 +485,19 @@ const (
             ],
             "upsert": true
         },
+        {
+            "predicate": "dgraph.graphql.p_query",
+            "type": "string"
+        },
+        {
+            "predicate": "dgraph.graphql.p_sha256hash",
+            "type": "string",
+            "index": true,
+            "tokenizer": [
+                "exact"
+            ],
+            "upsert": true
+        },
         {
             "predicate": "dgraph.graphql.schema",
             "type": "string"
@@ -356,6 +519,18 @@ const (
             ],
             "upsert": true
         },
+        {
+            "predicate": "dgraph.schema.change",
+            "type": "string"
+        },
+        {
+            "predicate": "dgraph.schema.changed_at",
+            "type": "datetime"
+        },
+        {
+            "predicate": "dgraph.schema.changed_by",
+            "type": "string"
+        },
         {
             "predicate": "dgraph.type",
             "type": "string",
@@ -400,6 +575,31 @@ const (
                 }
             ],
             "name": "dgraph.graphql.history"
+        },
+        {
+            "fields": [
+                {
+                    "name": "dgraph.graphql.p_query"
+                },
+                {
+                    "name": "dgraph.graphql.p_sha256hash"
+                }
+            ],
+            "name": "dgraph.graphql.persisted_query"
+        },
+        {
+            "fields": [
+                {
+                    "name": "dgraph.schema.change"
+                },
+                {
+                    "name": "dgraph.schema.changed_by"
+                },
+                {
+                    "name": "dgraph.schema.changed_at"
+                }
+            ],
+            "name": "dgraph.schema.history"
         }
     ]
 }`
@@ -0,0 +1,158 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package federation mirrors the checks of the Apollo Federation subgraph compatibility suite
+// (https://github.com/apollographql/apollo-federation-subgraph-compatibility) against a
+// running cluster: the _service SDL, resolving entities by their @key through _entities, and
+// the Federation v2 directives.
+package federation
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/dgraph/graphql/e2e/common"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	alphaURL      = "http://localhost:8180/graphql"
+	alphaAdminURL = "http://localhost:8180/admin"
+
+	// The products subgraph of the compatibility suite, in Dgraph's dialect.
+	productsSchema = `
+	type Product @key(fields: "id") {
+		id: ID!
+		sku: String @search(by: [hash])
+		package: String
+		notes: String @inaccessible
+		createdBy: User
+	}
+
+	type User @key(fields: "email") @shareable {
+		email: String! @id
+		name: String @override(from: "users")
+		totalProductsCreated: Int
+	}
+	`
+)
+
+func updateSchema(t *testing.T, sch string) {
+	params := &common.GraphQLParams{
+		Query: `mutation updateGQLSchema($sch: String!) {
+			updateGQLSchema(input: { set: { schema: $sch }}) {
+				gqlSchema {
+					schema
+				}
+			}
+		}`,
+		Variables: map[string]interface{}{"sch": sch},
+	}
+	common.RequireNoGQLErrors(t, params.ExecuteAsPost(t, alphaAdminURL))
+	time.Sleep(2 * time.Second)
+}
+
+func TestFederation(t *testing.T) {
+	updateSchema(t, productsSchema)
+
+	add := &common.GraphQLParams{
+		Query: `mutation {
+			addProduct(input: [
+				{ sku: "federation", package: "@apollo/federation",
+					createdBy: { email: "support@apollographql.com", name: "Jane Smith" } },
+				{ sku: "studio", package: "" }
+			]) {
+				product { id sku }
+			}
+		}`,
+	}
+	resp := add.ExecuteAsPost(t, alphaURL)
+	common.RequireNoGQLErrors(t, resp)
+
+	var added struct {
+		AddProduct struct {
+			Product []struct {
+				ID  string
+				Sku string
+			}
+		}
+	}
+	require.NoError(t, json.Unmarshal(resp.Data, &added))
+	require.Len(t, added.AddProduct.Product, 2)
+
+	t.Run("service sdl", func(t *testing.T) {
+		params := &common.GraphQLParams{Query: `query { _service { sdl } }`}
+		resp := params.ExecuteAsPost(t, alphaURL)
+		common.RequireNoGQLErrors(t, resp)
+
+		var service struct {
+			Service struct{ Sdl string } `json:"_service"`
+		}
+		require.NoError(t, json.Unmarshal(resp.Data, &service))
+		sdl := service.Service.Sdl
+		require.Contains(t, sdl, `@link(url: "https://specs.apollo.dev/federation/v2.0"`)
+		require.Contains(t, sdl, `type Product @key(fields: "id")`)
+		require.Contains(t, sdl, `type User @key(fields: "email") @shareable`)
+		require.Contains(t, sdl, `name: String @override(from: "users")`)
+		require.Contains(t, sdl, `notes: String @inaccessible`)
+		require.NotContains(t, sdl, "_Entity")
+		require.NotContains(t, sdl, "_Any")
+	})
+
+	t.Run("entities", func(t *testing.T) {
+		params := &common.GraphQLParams{
+			Query: `query entities($representations: [_Any!]!) {
+				_entities(representations: $representations) {
+					__typename
+					... on Product { id sku package createdBy { email name } }
+					... on User { email name }
+				}
+			}`,
+			Variables: map[string]interface{}{
+				"representations": []interface{}{
+					map[string]interface{}{"__typename": "User", "email": "support@apollographql.com"},
+					map[string]interface{}{"__typename": "Product",
+						"id": added.AddProduct.Product[0].ID},
+					map[string]interface{}{"__typename": "User", "email": "nobody@example.com"},
+				},
+			},
+		}
+		resp := params.ExecuteAsPost(t, alphaURL)
+		common.RequireNoGQLErrors(t, resp)
+
+		require.JSONEq(t, `{ "_entities": [
+			{ "__typename": "User", "email": "support@apollographql.com", "name": "Jane Smith" },
+			{ "__typename": "Product", "id": "`+added.AddProduct.Product[0].ID+`",
+				"sku": "federation", "package": "@apollo/federation",
+				"createdBy": { "email": "support@apollographql.com", "name": "Jane Smith" } },
+			null
+		] }`, string(resp.Data))
+	})
+
+	t.Run("representation without its key", func(t *testing.T) {
+		params := &common.GraphQLParams{
+			Query: `query {
+				_entities(representations: [{ __typename: "Product", sku: "federation" }]) {
+					__typename
+				}
+			}`,
+		}
+		resp := params.ExecuteAsPost(t, alphaURL)
+		require.Len(t, resp.Errors, 1)
+		require.Contains(t, resp.Errors[0].Message, "has no string value for the key field id")
+	})
+}
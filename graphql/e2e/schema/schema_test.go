@@ -220,6 +220,19 @@ func TestConcurrentSchemaUpdates(t *testing.T) {
       		"upsert": true
 		},
 		{
+            "predicate": "dgraph.graphql.p_query",
+            "type": "string"
+		},
+		{
+            "predicate": "dgraph.graphql.p_sha256hash",
+            "type": "string",
+            "index": true,
+            "tokenizer": [
+                "exact"
+            ],
+            "upsert": true
+		},
+		{
             "predicate": "dgraph.graphql.schema",
             "type": "string"
 		},
@@ -240,6 +253,18 @@ func TestConcurrentSchemaUpdates(t *testing.T) {
             ],
             "upsert": true
         },
+        {
+            "predicate": "dgraph.schema.change",
+            "type": "string"
+        },
+        {
+            "predicate": "dgraph.schema.changed_at",
+            "type": "datetime"
+        },
+        {
+            "predicate": "dgraph.schema.changed_by",
+            "type": "string"
+        },
         {
             "predicate": "dgraph.type",
             "type": "string",
@@ -278,6 +303,30 @@ func TestConcurrentSchemaUpdates(t *testing.T) {
                 }
             ],
             "name": "dgraph.graphql.history"
+        },
+        {
+            "fields": [
+                {
+                    "name": "dgraph.graphql.p_query"
+                },{
+                    "name": "dgraph.graphql.p_sha256hash"
+                }
+            ],
+            "name": "dgraph.graphql.persisted_query"
+        },
+        {
+            "fields": [
+                {
+                    "name": "dgraph.schema.change"
+                },
+                {
+                    "name": "dgraph.schema.changed_by"
+                },
+                {
+                    "name": "dgraph.schema.changed_at"
+                }
+            ],
+            "name": "dgraph.schema.history"
         }
     ]
 }`, tcases[lastSuccessTcaseIdx].dgraphSchema)
@@ -0,0 +1,338 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package lambda runs the JavaScript resolvers and webhooks of @lambda fields inside Alpha,
+// instead of sending them to a separate lambda server.
+//
+// The script registers its functions the same way as for the lambda server:
+//
+//	self.addGraphQLResolvers({"Type.field": function(env) { ... }})
+//	self.addMultiParentGraphQLResolvers({"Type.field": function(env) { ... }})
+//	self.addWebHookResolvers({"name": function(env) { ... }})
+//
+// where env has the parent (or parents) and args of the field, the authHeader of the request,
+// a dql client with query and mutate functions, and a graphql function. The scripts run on an
+// ECMAScript 5.1 engine, so every function is synchronous: fetch, dql and graphql return their
+// results instead of promises. Each call is interrupted once it runs for longer than the
+// timeout, or once the heap grows by more than the memory limit while it runs.
+package lambda
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dop251/goja"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/dgraph/graphql/schema"
+)
+
+// memCheckInterval is how often the heap is sampled while calls run.
+var memCheckInterval = 10 * time.Millisecond
+
+// Config is the configuration of the embedded lambda runtime.
+type Config struct {
+	// Script is the JavaScript file that registers the resolvers and webhooks. The modules it
+	// requires are loaded from its directory.
+	Script string
+	// Timeout is the maximum duration of a call, including the requests it makes.
+	Timeout time.Duration
+	// MemoryLimit is the maximum growth of the heap during a call, in bytes. Zero means no
+	// limit. The heap is shared by the calls running at the same time, so all of them are
+	// interrupted when it grows by more than the limit.
+	MemoryLimit uint64
+}
+
+// Client runs the DQL and GraphQL requests of the scripts. The requests carry the context of
+// the call, so they are authorized with the credentials of the request that caused it.
+type Client interface {
+	Query(ctx context.Context, req *api.Request) (*api.Response, error)
+	Resolve(ctx context.Context, req *schema.Request) *schema.Response
+}
+
+// lambdas runs the script given at startup. It's nil if there isn't one.
+var lambdas *pool
+
+// Init loads the script and checks that it runs. The resolvers and webhooks it registers are
+// called with Resolve and Webhook.
+func Init(conf Config, client Client) error {
+	script, err := filepath.Abs(conf.Script)
+	if err != nil {
+		return err
+	}
+	conf.Script = script
+	p := &pool{
+		conf:     conf,
+		client:   client,
+		dir:      filepath.Dir(script),
+		programs: make(map[string]*goja.Program),
+		runtimes: make(chan *jsRuntime, runtime.NumCPU()),
+		mem:      &memWatch{limit: conf.MemoryLimit, calls: make(map[*jsRuntime]uint64)},
+	}
+	// Loading a runtime runs the script, which reports the errors in it before any call.
+	if err := p.with(context.Background(), func(*jsRuntime) error { return nil }); err != nil {
+		return errors.Wrapf(err, "while loading lambda script %s", script)
+	}
+	lambdas = p
+	glog.Infof("Loaded lambda script %s", script)
+	return nil
+}
+
+// Enabled tells whether the @lambda fields are resolved by the embedded runtime.
+func Enabled() bool {
+	return lambdas != nil
+}
+
+// Resolve calls the resolver of a @lambda field. The body is the one that would be sent to
+// the lambda server, and the result is returned as JSON.
+func Resolve(ctx context.Context, body string) ([]byte, error) {
+	if lambdas == nil {
+		return nil, errors.New("lambda script isn't loaded")
+	}
+	var out string
+	err := lambdas.with(ctx, func(r *jsRuntime) error {
+		res, err := r.resolve(goja.Undefined(), r.vm.ToValue(body))
+		if err != nil {
+			return err
+		}
+		out = res.String()
+		return nil
+	})
+	return []byte(out), err
+}
+
+// Webhook calls the webhook registered with the given name with the JSON event. An exception
+// thrown by the webhook is returned as an error.
+func Webhook(ctx context.Context, name string, event []byte) error {
+	if lambdas == nil {
+		return errors.New("lambda script isn't loaded")
+	}
+	return lambdas.with(ctx, func(r *jsRuntime) error {
+		_, err := r.webhook(goja.Undefined(), r.vm.ToValue(name), r.vm.ToValue(string(event)))
+		return err
+	})
+}
+
+// pool keeps the runtimes that loaded the script, since a runtime can only run one call at a
+// time. The runtimes in which a call fails are dropped instead of being put back, as the call
+// might have left them in any state.
+type pool struct {
+	conf   Config
+	client Client
+	// dir is the directory of the script. Modules can't be required from outside of it.
+	dir string
+
+	programsMu sync.Mutex
+	programs   map[string]*goja.Program
+
+	runtimes chan *jsRuntime
+	mem      *memWatch
+}
+
+func (p *pool) with(ctx context.Context, f func(r *jsRuntime) error) error {
+	parent := ctx
+	if p.conf.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.conf.Timeout)
+		defer cancel()
+	}
+
+	var r *jsRuntime
+	select {
+	case r = <-p.runtimes:
+	default:
+		r = newRuntime(p)
+	}
+	r.ctx = ctx
+
+	// Interrupt the call when ctx is done. Interrupt doesn't stop the host functions, so they
+	// use ctx as well.
+	done, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			if err := parent.Err(); err != nil {
+				r.vm.Interrupt(err)
+			} else {
+				r.vm.Interrupt(errors.Errorf("lambda call timed out after %s", p.conf.Timeout))
+			}
+		case <-done:
+		}
+	}()
+	p.mem.add(r)
+
+	err := r.load()
+	if err == nil {
+		err = f(r)
+	}
+
+	p.mem.remove(r)
+	close(done)
+	<-stopped
+	r.ctx = nil
+	if err != nil {
+		return jsError(err)
+	}
+	// An interrupt that came after the call returned would stop the next call.
+	r.vm.ClearInterrupt()
+	select {
+	case p.runtimes <- r:
+	default:
+	}
+	return nil
+}
+
+// program returns the compiled module at the given path. The code of the module is wrapped in
+// a function that gets the CommonJS variables, like in Node.
+func (p *pool) program(path string) (*goja.Program, error) {
+	p.programsMu.Lock()
+	defer p.programsMu.Unlock()
+	if prg, ok := p.programs[path]; ok {
+		return prg, nil
+	}
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	prg, err := goja.Compile(path, "(function(exports, require, module, __filename, __dirname) {"+
+		string(src)+"\n})", false)
+	if err != nil {
+		return nil, err
+	}
+	p.programs[path] = prg
+	return prg, nil
+}
+
+// modulePath returns the path of the module required with the given name from a module in
+// dir. Only the relative names of files in the directory of the script can be required.
+func (p *pool) modulePath(dir, name string) (string, error) {
+	if !strings.HasPrefix(name, "./") && !strings.HasPrefix(name, "../") {
+		return "", errors.Errorf("can't require %q: only relative paths are supported", name)
+	}
+	path := filepath.Join(dir, name)
+	if filepath.Ext(path) == "" {
+		path += ".js"
+	}
+	if rel, err := filepath.Rel(p.dir, path); err != nil || strings.HasPrefix(rel, "..") {
+		return "", errors.Errorf("can't require %q: it's outside of the directory of the "+
+			"lambda script", name)
+	}
+	return path, nil
+}
+
+// memWatch samples the heap while calls run, and interrupts the calls during which it grew by
+// more than the limit. The sampling goroutine only runs while there are calls.
+type memWatch struct {
+	sync.Mutex
+	limit uint64
+	// calls has the size of the heap when each of the running calls started.
+	calls   map[*jsRuntime]uint64
+	running bool
+}
+
+func (w *memWatch) add(r *jsRuntime) {
+	if w.limit == 0 {
+		return
+	}
+	heap := heapAlloc()
+	w.Lock()
+	defer w.Unlock()
+	w.calls[r] = heap
+	if !w.running {
+		w.running = true
+		go w.run()
+	}
+}
+
+// remove stops watching the call. The call isn't interrupted by the watch once remove returns.
+func (w *memWatch) remove(r *jsRuntime) {
+	if w.limit == 0 {
+		return
+	}
+	w.Lock()
+	defer w.Unlock()
+	delete(w.calls, r)
+}
+
+func (w *memWatch) run() {
+	ticker := time.NewTicker(memCheckInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		w.Lock()
+		if len(w.calls) == 0 {
+			w.running = false
+			w.Unlock()
+			return
+		}
+		w.Unlock()
+
+		heap := heapAlloc()
+		if !w.exceeded(heap, false) {
+			continue
+		}
+		// The heap also has the garbage of the calls, which doesn't count towards the limit.
+		runtime.GC()
+		w.exceeded(heapAlloc(), true)
+	}
+}
+
+// exceeded tells whether the heap grew by more than the limit during any of the calls. If
+// interrupt is true, those calls are interrupted.
+func (w *memWatch) exceeded(heap uint64, interrupt bool) bool {
+	w.Lock()
+	defer w.Unlock()
+	var found bool
+	for r, start := range w.calls {
+		if heap > start+w.limit {
+			found = true
+			if interrupt {
+				r.vm.Interrupt(errors.Errorf("lambda call exceeded the memory limit of %d MB",
+					w.limit>>20))
+			}
+		}
+	}
+	return found
+}
+
+func heapAlloc() uint64 {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return ms.HeapAlloc
+}
+
+// jsError converts the errors of the runtime, which include the stack of the exception, to
+// errors with just the message and the location where it was thrown.
+func jsError(err error) error {
+	switch e := err.(type) {
+	case *goja.InterruptedError:
+		if cause, ok := e.Value().(error); ok {
+			return cause
+		}
+		return errors.New(fmt.Sprint(e.Value()))
+	case *goja.Exception:
+		return errors.New(e.Error())
+	}
+	return err
+}
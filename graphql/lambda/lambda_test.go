@@ -0,0 +1,287 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package lambda
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/dgraph-io/dgraph/graphql/authorization"
+	"github.com/dgraph-io/dgraph/graphql/schema"
+)
+
+// testClient records the requests of the scripts and answers them with fixed responses.
+type testClient struct {
+	reqs    []*api.Request
+	gqlReqs []*schema.Request
+	gqlJwt  []string
+}
+
+func (c *testClient) Query(ctx context.Context, req *api.Request) (*api.Response, error) {
+	c.reqs = append(c.reqs, req)
+	if len(req.Mutations) > 0 {
+		return &api.Response{Uids: map[string]string{"a": "0x1"}}, nil
+	}
+	return &api.Response{Json: []byte(`{"q":[{"name":"Alice"}]}`)}, nil
+}
+
+func (c *testClient) Resolve(ctx context.Context, req *schema.Request) *schema.Response {
+	c.gqlReqs = append(c.gqlReqs, req)
+	md, _ := metadata.FromIncomingContext(ctx)
+	c.gqlJwt = append(c.gqlJwt, md.Get(string(authorization.AuthJwtCtxKey))...)
+	resp := &schema.Response{}
+	resp.AddData([]byte(`{"getUser":{"name":"Bob"}}`))
+	return resp
+}
+
+// initScript writes the files into a new directory and loads the one named main.js.
+func initScript(t *testing.T, conf Config, files map[string]string) *testClient {
+	dir, err := ioutil.TempDir("", "lambda")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+		require.NoError(t, ioutil.WriteFile(path, []byte(src), 0600))
+	}
+	conf.Script = filepath.Join(dir, "main.js")
+	if conf.Timeout == 0 {
+		conf.Timeout = 5 * time.Second
+	}
+	client := &testClient{}
+	require.NoError(t, Init(conf, client))
+	t.Cleanup(func() { lambdas = nil })
+	return client
+}
+
+func TestResolve(t *testing.T) {
+	initScript(t, Config{}, map[string]string{"main.js": `
+		self.addGraphQLResolvers({
+			"Query.add": function(e) { return e.args.a + e.args.b; },
+			"User.fullName": function(e) { return e.parent.first + " " + e.parent.last; },
+			"Query.token": function(e) { return e.authHeader.value; }
+		});
+		self.addMultiParentGraphQLResolvers({
+			"User.rank": function(e) {
+				return e.parents.map(function(p, i) { return i + 1; });
+			}
+		});`})
+
+	ctx := context.Background()
+	out, err := Resolve(ctx, `{"resolver":"Query.add","args":{"a":1,"b":2}}`)
+	require.NoError(t, err)
+	require.JSONEq(t, `3`, string(out))
+
+	out, err = Resolve(ctx, `{"resolver":"User.fullName","parents":[
+		{"first":"Ada","last":"Lovelace"},{"first":"Alan","last":"Turing"}]}`)
+	require.NoError(t, err)
+	require.JSONEq(t, `["Ada Lovelace","Alan Turing"]`, string(out))
+
+	out, err = Resolve(ctx, `{"resolver":"User.rank","parents":[{},{}]}`)
+	require.NoError(t, err)
+	require.JSONEq(t, `[1,2]`, string(out))
+
+	out, err = Resolve(ctx, `{"resolver":"Query.token","authHeader":{"key":"X-Auth","value":"t"}}`)
+	require.NoError(t, err)
+	require.JSONEq(t, `"t"`, string(out))
+
+	_, err = Resolve(ctx, `{"resolver":"Query.missing","args":{}}`)
+	require.Contains(t, err.Error(), "resolver Query.missing isn't registered by the lambda script")
+}
+
+func TestRequire(t *testing.T) {
+	initScript(t, Config{}, map[string]string{
+		"main.js": `
+			var greet = require("./lib/greet");
+			self.addGraphQLResolvers({
+				"Query.hello": function(e) { return greet(e.args.name); }
+			});`,
+		"lib/greet.js": `
+			var punct = require("../punct.js");
+			module.exports = function(name) { return "Hello, " + name + punct.mark; };`,
+		"punct.js": `exports.mark = "!";`,
+	})
+	out, err := Resolve(context.Background(), `{"resolver":"Query.hello","args":{"name":"Ada"}}`)
+	require.NoError(t, err)
+	require.JSONEq(t, `"Hello, Ada!"`, string(out))
+
+	dir, err := ioutil.TempDir("", "lambda")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	script := filepath.Join(dir, "main.js")
+	require.NoError(t, ioutil.WriteFile(script, []byte(`require("../secret.js");`), 0600))
+	err = Init(Config{Script: script}, &testClient{})
+	require.Contains(t, err.Error(), "outside of the directory of the lambda script")
+
+	require.NoError(t, ioutil.WriteFile(script, []byte(`require("fs");`), 0600))
+	err = Init(Config{Script: script}, &testClient{})
+	require.Contains(t, err.Error(), "only relative paths are supported")
+}
+
+func TestFetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"method":"` + r.Method + `","token":"` +
+			r.Header.Get("X-Token") + `","body":` + string(body) + `}`))
+	}))
+	defer srv.Close()
+
+	initScript(t, Config{}, map[string]string{"main.js": `
+		self.addGraphQLResolvers({
+			"Query.remote": function(e) {
+				var res = fetch(e.args.url, {method: "POST", headers: {"X-Token": "abc"},
+					body: JSON.stringify({n: 1})});
+				var out = res.json();
+				out.status = res.status;
+				out.ok = res.ok;
+				out.type = res.headers["content-type"];
+				return out;
+			}
+		});`})
+	out, err := Resolve(context.Background(),
+		`{"resolver":"Query.remote","args":{"url":"`+srv.URL+`"}}`)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"method":"POST","token":"abc","body":{"n":1},"status":201,"ok":true,
+		"type":"application/json"}`, string(out))
+}
+
+func TestClient(t *testing.T) {
+	client := initScript(t, Config{}, map[string]string{"main.js": `
+		self.addGraphQLResolvers({
+			"Query.dql": function(e) {
+				return e.dql.query("query q($n: string) { q(func: eq(name, $n)) { name } }",
+					{"$n": "Alice"}).data.q[0].name;
+			},
+			"Mutation.json": function(e) {
+				return e.dql.mutate({set: {name: "Carol"}}).data.uids.a;
+			},
+			"Mutation.rdf": function(e) {
+				return e.dql.mutate("{ set { _:a <name> \"Dave\" . } }").data.uids.a;
+			},
+			"Query.gql": function(e) {
+				return e.graphql("query { getUser { name } }", {id: 1}).data.getUser.name;
+			},
+			"Query.gqlAs": function(e) {
+				return e.graphql("query { getUser { name } }", {}, {key: "X-Auth", value: "jwt"});
+			}
+		});`})
+	ctx := context.Background()
+
+	out, err := Resolve(ctx, `{"resolver":"Query.dql","args":{}}`)
+	require.NoError(t, err)
+	require.JSONEq(t, `"Alice"`, string(out))
+	require.True(t, client.reqs[0].ReadOnly)
+	require.Equal(t, map[string]string{"$n": "Alice"}, client.reqs[0].Vars)
+
+	out, err = Resolve(ctx, `{"resolver":"Mutation.json","args":{}}`)
+	require.NoError(t, err)
+	require.JSONEq(t, `"0x1"`, string(out))
+	require.True(t, client.reqs[1].CommitNow)
+	require.JSONEq(t, `{"name":"Carol"}`, string(client.reqs[1].Mutations[0].SetJson))
+
+	out, err = Resolve(ctx, `{"resolver":"Mutation.rdf","args":{}}`)
+	require.NoError(t, err)
+	require.JSONEq(t, `"0x1"`, string(out))
+	require.True(t, client.reqs[2].CommitNow)
+	require.Contains(t, string(client.reqs[2].Mutations[0].SetNquads), `"Dave"`)
+
+	out, err = Resolve(ctx, `{"resolver":"Query.gql","args":{}}`)
+	require.NoError(t, err)
+	require.JSONEq(t, `"Bob"`, string(out))
+	require.Equal(t, map[string]interface{}{"id": 1.0}, client.gqlReqs[0].Variables)
+
+	_, err = Resolve(ctx, `{"resolver":"Query.gqlAs","args":{}}`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"jwt"}, client.gqlJwt)
+	require.Equal(t, "jwt", client.gqlReqs[1].Header.Get("X-Auth"))
+}
+
+func TestWebhook(t *testing.T) {
+	initScript(t, Config{}, map[string]string{"main.js": `
+		self.addWebHookResolvers({
+			"noBob": function(e) {
+				e.event.set.forEach(function(edge) {
+					if (edge.value === "Bob") {
+						throw new Error("Bob isn't allowed");
+					}
+				});
+			}
+		});`})
+	ctx := context.Background()
+	require.NoError(t, Webhook(ctx, "noBob", []byte(`{"set":[{"value":"Alice"}]}`)))
+	err := Webhook(ctx, "noBob", []byte(`{"set":[{"value":"Bob"}]}`))
+	require.Contains(t, err.Error(), "Bob isn't allowed")
+	err = Webhook(ctx, "missing", []byte(`{}`))
+	require.Contains(t, err.Error(), "webhook missing isn't registered by the lambda script")
+}
+
+func TestTimeout(t *testing.T) {
+	initScript(t, Config{Timeout: 100 * time.Millisecond}, map[string]string{"main.js": `
+		self.addGraphQLResolvers({
+			"Query.loop": function(e) { while (true) {} },
+			"Query.ok": function(e) { return 1; }
+		});`})
+	ctx := context.Background()
+	_, err := Resolve(ctx, `{"resolver":"Query.loop","args":{}}`)
+	require.EqualError(t, err, "lambda call timed out after 100ms")
+
+	// The interrupted runtime is dropped, and the next call runs on a new one.
+	out, err := Resolve(ctx, `{"resolver":"Query.ok","args":{}}`)
+	require.NoError(t, err)
+	require.JSONEq(t, `1`, string(out))
+
+	// The call is also interrupted when the request is canceled.
+	cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = Resolve(cctx, `{"resolver":"Query.loop","args":{}}`)
+	require.EqualError(t, err, context.DeadlineExceeded.Error())
+}
+
+func TestMemoryLimit(t *testing.T) {
+	initScript(t, Config{MemoryLimit: 32 << 20}, map[string]string{"main.js": `
+		self.addGraphQLResolvers({
+			"Query.grow": function(e) {
+				var keep = [];
+				while (true) { keep.push("some string that is kept " + keep.length); }
+			},
+			"Query.garbage": function(e) {
+				// Allocates more than the limit in total, but keeps little of it.
+				var s;
+				for (var i = 0; i < 200000; i++) { s = ["some garbage " + i, i, {i: i}]; }
+				return s[1];
+			}
+		});`})
+	ctx := context.Background()
+	_, err := Resolve(ctx, `{"resolver":"Query.grow","args":{}}`)
+	require.EqualError(t, err, "lambda call exceeded the memory limit of 32 MB")
+
+	out, err := Resolve(ctx, `{"resolver":"Query.garbage","args":{}}`)
+	require.NoError(t, err)
+	require.JSONEq(t, `199999`, string(out))
+}
@@ -0,0 +1,340 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package lambda
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dop251/goja"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/dgraph-io/dgraph/gql"
+	"github.com/dgraph-io/dgraph/graphql/authorization"
+	"github.com/dgraph-io/dgraph/graphql/schema"
+)
+
+// prelude defines the functions the scripts use to register their resolvers and to reach
+// Dgraph and the network, on top of the host functions in __dgraph. It also defines the
+// resolve and webhook functions called by Alpha, which take and return JSON.
+var prelude = goja.MustCompile("prelude.js", `(function(global) {
+	var host = global.__dgraph;
+	delete global.__dgraph;
+
+	var resolvers = {}, multiParentResolvers = {}, webhooks = {};
+	function has(fns, name) {
+		return Object.prototype.hasOwnProperty.call(fns, name);
+	}
+	function register(fns) {
+		return function(add) {
+			for (var name in add) {
+				if (typeof add[name] !== "function") {
+					throw new TypeError(name + " isn't a function");
+				}
+				fns[name] = add[name];
+			}
+		};
+	}
+	global.self = global;
+	global.addGraphQLResolvers = register(resolvers);
+	global.addMultiParentGraphQLResolvers = register(multiParentResolvers);
+	global.addWebHookResolvers = register(webhooks);
+
+	global.fetch = function(url, init) {
+		init = init || {};
+		var res = JSON.parse(host.fetch(String(url), init.method || "GET",
+			JSON.stringify(init.headers || {}), init.body == null ? "" : String(init.body)));
+		return {
+			status: res.status,
+			ok: res.status >= 200 && res.status < 300,
+			headers: res.headers,
+			text: function() { return res.body; },
+			json: function() { return JSON.parse(res.body); }
+		};
+	};
+
+	function env(authHeader, fields) {
+		var e = {
+			authHeader: authHeader,
+			dql: {
+				query: function(query, variables) {
+					return JSON.parse(host.query(String(query), JSON.stringify(variables || {})));
+				},
+				mutate: function(mutation) {
+					if (typeof mutation === "string") {
+						return JSON.parse(host.mutate(mutation, true));
+					}
+					return JSON.parse(host.mutate(JSON.stringify(mutation), false));
+				}
+			},
+			graphql: function(query, variables, header) {
+				header = header || authHeader || {};
+				return JSON.parse(host.graphql(String(query), JSON.stringify(variables || {}),
+					header.key || "", header.value || ""));
+			}
+		};
+		for (var k in fields) {
+			e[k] = fields[k];
+		}
+		return e;
+	}
+
+	host.resolve = function(body) {
+		var req = JSON.parse(body), name = req.resolver, result;
+		if (req.parents && has(multiParentResolvers, name)) {
+			result = multiParentResolvers[name](env(req.authHeader, {parents: req.parents}));
+		} else if (req.parents && has(resolvers, name)) {
+			result = req.parents.map(function(parent) {
+				return resolvers[name](env(req.authHeader, {parent: parent}));
+			});
+		} else if (has(resolvers, name)) {
+			result = resolvers[name](env(req.authHeader, {args: req.args}));
+		} else {
+			throw new Error("resolver " + name + " isn't registered by the lambda script");
+		}
+		return result === undefined ? "null" : JSON.stringify(result);
+	};
+	host.webhook = function(name, event) {
+		if (!has(webhooks, name)) {
+			throw new Error("webhook " + name + " isn't registered by the lambda script");
+		}
+		webhooks[name](env(undefined, {event: JSON.parse(event)}));
+	};
+})(this);`, false)
+
+// fetchClient makes the requests of fetch. Their timeout is the one of the call.
+var fetchClient = &http.Client{}
+
+// jsRuntime is a JavaScript runtime that has loaded the script.
+type jsRuntime struct {
+	vm   *goja.Runtime
+	pool *pool
+	// ctx is the context of the running call, used by the host functions.
+	ctx context.Context
+
+	loaded  bool
+	host    *goja.Object
+	modules map[string]*goja.Object
+	resolve goja.Callable
+	webhook goja.Callable
+}
+
+func newRuntime(p *pool) *jsRuntime {
+	r := &jsRuntime{
+		vm:      goja.New(),
+		pool:    p,
+		modules: make(map[string]*goja.Object),
+	}
+	r.host = r.vm.NewObject()
+	_ = r.host.Set("fetch", r.fetch)
+	_ = r.host.Set("query", r.query)
+	_ = r.host.Set("mutate", r.mutate)
+	_ = r.host.Set("graphql", r.graphql)
+	r.vm.Set("__dgraph", r.host)
+	return r
+}
+
+// load runs the prelude and the script, unless the runtime has already loaded them.
+func (r *jsRuntime) load() error {
+	if r.loaded {
+		return nil
+	}
+	if _, err := r.vm.RunProgram(prelude); err != nil {
+		return err
+	}
+	var ok bool
+	if r.resolve, ok = goja.AssertFunction(r.host.Get("resolve")); !ok {
+		return errors.New("prelude didn't define resolve")
+	}
+	if r.webhook, ok = goja.AssertFunction(r.host.Get("webhook")); !ok {
+		return errors.New("prelude didn't define webhook")
+	}
+	if _, err := r.require(r.pool.conf.Script); err != nil {
+		return err
+	}
+	r.loaded = true
+	return nil
+}
+
+// require returns the exports of the module at the given path, running it the first time it's
+// required. Like in Node, a module that is required again while it runs gets the exports it
+// has set so far.
+func (r *jsRuntime) require(path string) (goja.Value, error) {
+	if module, ok := r.modules[path]; ok {
+		return module.Get("exports"), nil
+	}
+	prg, err := r.pool.program(path)
+	if err != nil {
+		return nil, err
+	}
+	fnv, err := r.vm.RunProgram(prg)
+	if err != nil {
+		return nil, err
+	}
+	fn, ok := goja.AssertFunction(fnv)
+	if !ok {
+		return nil, errors.Errorf("module %s didn't compile to a function", path)
+	}
+
+	module, exports := r.vm.NewObject(), r.vm.NewObject()
+	_ = module.Set("exports", exports)
+	r.modules[path] = module
+	dir := filepath.Dir(path)
+	require := func(name string) (goja.Value, error) {
+		path, err := r.pool.modulePath(dir, name)
+		if err != nil {
+			return nil, err
+		}
+		return r.require(path)
+	}
+	if _, err := fn(exports, exports, r.vm.ToValue(require), module, r.vm.ToValue(path),
+		r.vm.ToValue(dir)); err != nil {
+		delete(r.modules, path)
+		return nil, err
+	}
+	return module.Get("exports"), nil
+}
+
+// fetch makes an HTTP request and returns the status, the headers and the body of the
+// response as JSON. The body can't be larger than the memory limit.
+func (r *jsRuntime) fetch(url, method, headers, body string) (string, error) {
+	var reqBody io.Reader
+	if body != "" {
+		reqBody = strings.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(r.ctx, method, url, reqBody)
+	if err != nil {
+		return "", err
+	}
+	var h map[string]string
+	if err := json.Unmarshal([]byte(headers), &h); err != nil {
+		return "", errors.Wrapf(err, "while reading the headers of the request")
+	}
+	for k, v := range h {
+		req.Header.Set(k, v)
+	}
+	resp, err := fetchClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var rd io.Reader = resp.Body
+	if limit := r.pool.conf.MemoryLimit; limit > 0 {
+		rd = io.LimitReader(resp.Body, int64(limit)+1)
+	}
+	b, err := ioutil.ReadAll(rd)
+	if err != nil {
+		return "", err
+	}
+	if limit := r.pool.conf.MemoryLimit; limit > 0 && uint64(len(b)) > limit {
+		return "", errors.Errorf("response of %s is larger than the memory limit", url)
+	}
+
+	out := struct {
+		Status  int               `json:"status"`
+		Headers map[string]string `json:"headers"`
+		Body    string            `json:"body"`
+	}{Status: resp.StatusCode, Headers: make(map[string]string), Body: string(b)}
+	for k := range resp.Header {
+		out.Headers[strings.ToLower(k)] = resp.Header.Get(k)
+	}
+	res, err := json.Marshal(out)
+	return string(res), err
+}
+
+// query runs a read-only DQL query with the JSON variables, and returns its result as JSON.
+func (r *jsRuntime) query(query, variables string) (string, error) {
+	var vars map[string]string
+	if err := json.Unmarshal([]byte(variables), &vars); err != nil {
+		return "", errors.Wrapf(err, "while reading the variables of the query")
+	}
+	resp, err := r.pool.client.Query(r.ctx, &api.Request{
+		Query:    query,
+		Vars:     vars,
+		ReadOnly: true,
+	})
+	if err != nil {
+		return "", err
+	}
+	data := resp.GetJson()
+	if len(data) == 0 {
+		data = []byte("{}")
+	}
+	return `{"data":` + string(data) + `}`, nil
+}
+
+// mutate commits a DQL mutation, either in RDF with set and delete blocks or in JSON with set
+// and delete fields, and returns the uids it assigned.
+func (r *jsRuntime) mutate(mutation string, rdf bool) (string, error) {
+	var req *api.Request
+	if rdf {
+		var err error
+		if req, err = gql.ParseMutation(mutation); err != nil {
+			return "", err
+		}
+	} else {
+		var m struct {
+			Set    json.RawMessage `json:"set"`
+			Delete json.RawMessage `json:"delete"`
+		}
+		if err := json.Unmarshal([]byte(mutation), &m); err != nil {
+			return "", errors.Wrapf(err, "while reading the mutation")
+		}
+		req = &api.Request{Mutations: []*api.Mutation{{SetJson: m.Set, DeleteJson: m.Delete}}}
+	}
+	req.CommitNow = true
+	resp, err := r.pool.client.Query(r.ctx, req)
+	if err != nil {
+		return "", err
+	}
+	out, err := json.Marshal(map[string]interface{}{
+		"data": map[string]interface{}{"uids": resp.GetUids()},
+	})
+	return string(out), err
+}
+
+// graphql runs a GraphQL request against the deployed schema, and returns the response as
+// JSON. If the script gave an auth header, it replaces the one of the call.
+func (r *jsRuntime) graphql(query, variables, authKey, authValue string) (string, error) {
+	var vars map[string]interface{}
+	if err := json.Unmarshal([]byte(variables), &vars); err != nil {
+		return "", errors.Wrapf(err, "while reading the variables of the request")
+	}
+	ctx, header := r.ctx, http.Header{}
+	if authKey != "" && authValue != "" {
+		header.Set(authKey, authValue)
+		md, _ := metadata.FromIncomingContext(ctx)
+		md = md.Copy()
+		md.Set(string(authorization.AuthJwtCtxKey), authValue)
+		ctx = metadata.NewIncomingContext(ctx, md)
+	}
+	resp := r.pool.client.Resolve(ctx, &schema.Request{
+		Query:     query,
+		Variables: vars,
+		Header:    header,
+	})
+	out, err := json.Marshal(resp.Output())
+	return string(out), err
+}
@@ -18,14 +18,21 @@ package resolve
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/dgrijalva/jwt-go/v4"
 	"google.golang.org/grpc/metadata"
+	"gopkg.in/square/go-jose.v2"
 
 	dgoapi "github.com/dgraph-io/dgo/v200/protos/api"
 	"github.com/dgraph-io/dgraph/gql"
@@ -210,7 +217,8 @@ func TestAudienceClaim(t *testing.T) {
 		{
 			name:  `Token with invalid audience: { "aud": "invalidAudience" }`,
 			token: "eyJraWQiOiIyRWplN2tIRklLZS92MFRVT3JRYlVJWWJxSWNNUHZ2TFBjM3RSQ25EclBBPSIsImFsZyI6IkhTMjU2In0.eyJzdWIiOiI1MDk1MGI0MC0yNjJmLTRiMjYtODhhNy1jYmJiNzgwYjIxNzYiLCJjb2duaXRvOmdyb3VwcyI6WyJBRE1JTiJdLCJlbWFpbF92ZXJpZmllZCI6dHJ1ZSwiaXNzIjoiaHR0cHM6Ly9jb2duaXRvLWlkcC5hcC1zb3V0aGVhc3QtMi5hbWF6b25hd3MuY29tL2FwLXNvdXRoZWFzdC0yX0dmbWVIZEZ6NCIsImNvZ25pdG86dXNlcm5hbWUiOiI1MDk1MGI0MC0yNjJmLTRiMjYtODhhNy1jYmJiNzgwYjIxNzYiLCJodHRwczovL3h5ei5pby9qd3QvY2xhaW1zIjoie1wiVVNFUlwiOiBcIjUwOTUwYjQwLTI2MmYtNGIyNi04OGE3LWNiYmI3ODBiMjE3NlwiLCBcIlJPTEVcIjogXCJBRE1JTlwifSIsImF1ZCI6ImludmFsaWRBdWRpZW5jZSIsImV2ZW50X2lkIjoiMzFjOWQ2ODQtMWQ0NS00NmY3LThjMmItY2MyN2IxZjZmMDFiIiwidG9rZW5fdXNlIjoiaWQiLCJhdXRoX3RpbWUiOjE1OTAzMzMzNTYsIm5hbWUiOiJEYXZpZCBQZWVrIiwiZXhwIjo0NTkwMzc2MDMyLCJpYXQiOjE1OTAzNzI0MzIsImVtYWlsIjoiZGF2aWRAdHlwZWpvaW4uY29tIn0.-8UxKvv6_0_hCbV3f6KEoP223BrCrP0eWWdoG-Gf3FQ",
-			err:   fmt.Errorf("JWT `aud` value doesn't match with the audience"),
+			err: &authorization.InvalidJWTError{
+				Err: fmt.Errorf("JWT `aud` value doesn't match with the audience")},
 		},
 		{
 			name:  "Token without audience field",
@@ -285,6 +293,166 @@ func TestVerificationWithJWKUrl(t *testing.T) {
 
 }
 
+// jwkServer serves a JWK set with the public key of a newly generated RSA key, and returns the
+// key along with the server.
+func jwkServer(t *testing.T, kid string) (*rsa.PrivateKey, *httptest.Server) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	jwk, err := jose.JSONWebKey{Key: &key.PublicKey, KeyID: kid, Algorithm: "RS256", Use: "sig"}.
+		MarshalJSON()
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		fmt.Fprintf(w, `{"keys": [%s]}`, jwk)
+	}))
+	return key, srv
+}
+
+func signedToken(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	tokenStr, err := token.SignedString(key)
+	require.NoError(t, err)
+	return tokenStr
+}
+
+func TestVerificationWithMultipleIssuers(t *testing.T) {
+	sch, err := ioutil.ReadFile("../e2e/auth/schema.graphql")
+	require.NoError(t, err, "Unable to read schema file")
+
+	key1, srv1 := jwkServer(t, "kid1")
+	defer srv1.Close()
+	key2, srv2 := jwkServer(t, "kid2")
+	defer srv2.Close()
+
+	authSchema := string(sch) + fmt.Sprintf(`# Dgraph.Authorization {"Header":"X-Test-Auth",`+
+		`"Namespace":"https://xyz.io/jwt/claims","ClockSkew":60,"Issuers":[`+
+		`{"Issuer":"https://issuer1.io","JWKUrl":"%s","Audience":["aud1"]},`+
+		`{"Issuer":"https://issuer2.io","JWKUrl":"%s","Audience":["aud2"]}]}`, srv1.URL, srv2.URL)
+	test.LoadSchemaFromString(t, authSchema)
+
+	metainfo := authorization.GetAuthMeta()
+	require.Len(t, metainfo.Issuers, 2)
+	require.Equal(t, int64(60), metainfo.ClockSkew)
+
+	authVars := map[string]interface{}{"USER": "user1", "ROLE": "ADMIN"}
+	claims := func(iss, aud string, exp time.Time) jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":                       iss,
+			"aud":                       aud,
+			"exp":                       exp.Unix(),
+			"https://xyz.io/jwt/claims": authVars,
+		}
+	}
+	inAnHour := time.Now().Add(time.Hour)
+
+	testCases := []struct {
+		name  string
+		token string
+		err   string
+	}{
+		{
+			name:  "Token from the first issuer",
+			token: signedToken(t, key1, "kid1", claims("https://issuer1.io", "aud1", inAnHour)),
+		},
+		{
+			name:  "Token from the second issuer",
+			token: signedToken(t, key2, "kid2", claims("https://issuer2.io", "aud2", inAnHour)),
+		},
+		{
+			name:  "Token with the audience of another issuer",
+			token: signedToken(t, key1, "kid1", claims("https://issuer1.io", "aud2", inAnHour)),
+			err:   "JWT `aud` value doesn't match with the audience",
+		},
+		{
+			name:  "Token signed with the key of another issuer",
+			token: signedToken(t, key2, "kid2", claims("https://issuer1.io", "aud1", inAnHour)),
+			err:   "Keyfunc returned an error",
+		},
+		{
+			name: "Token from an unknown issuer",
+			token: signedToken(t, key1, "kid1",
+				claims("https://unknown.io", "aud1", inAnHour)),
+			err: "Keyfunc returned an error",
+		},
+		{
+			name: "Token expired within the clock skew",
+			token: signedToken(t, key1, "kid1",
+				claims("https://issuer1.io", "aud1", time.Now().Add(-30*time.Second))),
+		},
+		{
+			name: "Token expired before the clock skew",
+			token: signedToken(t, key1, "kid1",
+				claims("https://issuer1.io", "aud1", time.Now().Add(-2*time.Minute))),
+			err: "token is expired",
+		},
+	}
+
+	for _, tcase := range testCases {
+		t.Run(tcase.name, func(t *testing.T) {
+			md := metadata.New(map[string]string{"authorizationJwt": tcase.token})
+			ctx := metadata.NewIncomingContext(context.Background(), md)
+
+			customClaims, err := authorization.ExtractCustomClaims(ctx)
+			if tcase.err != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tcase.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, authVars, customClaims.AuthVariables)
+		})
+	}
+}
+
+func TestInvalidIssuers(t *testing.T) {
+	sch, err := ioutil.ReadFile("../e2e/auth/schema.graphql")
+	require.NoError(t, err, "Unable to read schema file")
+
+	testCases := []struct {
+		name     string
+		authInfo string
+		err      string
+	}{
+		{
+			name: "Issuers with a JWKUrl",
+			authInfo: `{"Header":"X-Test-Auth","Namespace":"https://xyz.io/jwt/claims",` +
+				`"JWKUrl":"some-url","Issuers":[{"Issuer":"iss","JWKUrl":"some-url"}]}`,
+			err: "expecting either Issuers, JWKUrl or (VerificationKey, Algo), " +
+				"more than one were given",
+		},
+		{
+			name: "Issuer without a JWKUrl",
+			authInfo: `{"Header":"X-Test-Auth","Namespace":"https://xyz.io/jwt/claims",` +
+				`"Issuers":[{"Issuer":"iss"}]}`,
+			err: "each issuer needs an `Issuer` and a `JWKUrl`",
+		},
+		{
+			name: "Same issuer twice",
+			authInfo: `{"Header":"X-Test-Auth","Namespace":"https://xyz.io/jwt/claims",` +
+				`"Issuers":[{"Issuer":"iss","JWKUrl":"url1"},{"Issuer":"iss","JWKUrl":"url2"}]}`,
+			err: "issuer iss is given more than once in Dgraph.Authorization",
+		},
+		{
+			name: "Negative clock skew",
+			authInfo: `{"Header":"X-Test-Auth","Namespace":"https://xyz.io/jwt/claims",` +
+				`"Algo":"HS256","VerificationKey":"secretkey","ClockSkew":-1}`,
+			err: "ClockSkew in Dgraph.Authorization can't be negative",
+		},
+	}
+
+	for _, tcase := range testCases {
+		t.Run(tcase.name, func(t *testing.T) {
+			_, err := schema.NewHandler(string(sch)+"# Dgraph.Authorization "+tcase.authInfo,
+				false)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tcase.err)
+		})
+	}
+}
+
 // TODO(arijit): Generate the JWT token instead of using pre generated token.
 func TestJWTExpiry(t *testing.T) {
 	sch, err := ioutil.ReadFile("../e2e/auth/schema.graphql")
@@ -0,0 +1,180 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package resolve
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dgraph-io/dgraph/gql"
+	"github.com/dgraph-io/dgraph/graphql/schema"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// cursorPrefix is prepended to the uid of a node before it's encoded as an opaque cursor.
+const cursorPrefix = "uid:"
+
+func encodeCursor(uid string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + uid))
+}
+
+func decodeCursor(cursor string) (uint64, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(b), cursorPrefix) {
+		return 0, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	return strconv.ParseUint(strings.TrimPrefix(string(b), cursorPrefix), 0, 64)
+}
+
+// connectionFirst returns the first argument of a connection query, if it was given.
+func connectionFirst(q schema.Query) (int64, bool) {
+	first := q.ArgValue("first")
+	if first == nil {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(fmt.Sprintf("%v", first), 10, 64)
+	return n, err == nil
+}
+
+// rewriteAsConnectionQuery rewrites a connection query as the query for its nodes.  The nodes
+// are in uid order, so the query after a cursor starts from the uid in the cursor, and one node
+// more than asked for is fetched to find out if there's a next page.  For
+// queryPostConnection(first: 10, after: ...) { edges { node { title } } } that's
+//
+//	queryPostConnection(func: type(Post), first: 11, after: 0x4) {
+//	  title : Post.title
+//	  dgraph.uid : uid
+//	}
+func (qr *queryRewriter) rewriteAsConnectionQuery(
+	ctx context.Context,
+	gqlQuery schema.Query) (*gql.GraphQuery, error) {
+
+	var after uint64
+	if cursor, ok := gqlQuery.ArgValue("after").(string); ok && cursor != "" {
+		uid, err := decodeCursor(cursor)
+		if err != nil {
+			return nil, x.GqlErrorf("The after argument of %s is not a valid cursor.",
+				gqlQuery.Name()).WithLocations(gqlQuery.Location())
+		}
+		after = uid
+	}
+	first, hasFirst := connectionFirst(gqlQuery)
+	if hasFirst && first < 0 {
+		return nil, x.GqlErrorf("The first argument of %s can't be negative.",
+			gqlQuery.Name()).WithLocations(gqlQuery.Location())
+	}
+
+	nodes := gqlQuery.ConnectionNodes()
+	dgQuery, err := qr.Rewrite(ctx, nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	root := findQuery(dgQuery, nodes.Name())
+	if root == nil {
+		// The nodes can't be queried, e.g. auth rules evaluated to false.
+		return dgQuery, nil
+	}
+	if root.Args == nil {
+		root.Args = make(map[string]string)
+	}
+	if hasFirst {
+		root.Args["first"] = strconv.FormatInt(first+1, 10)
+	}
+	if after > 0 {
+		root.Args["after"] = fmt.Sprintf("%#x", after)
+	}
+
+	// The uid of each node is needed for its cursor.
+	hasUID := false
+	for _, c := range root.Children {
+		if c.Attr == "uid" && c.Alias == "dgraph.uid" {
+			hasUID = true
+		}
+	}
+	if !hasUID {
+		root.Children = append(root.Children, &gql.GraphQuery{
+			Attr:  "uid",
+			Alias: "dgraph.uid",
+		})
+	}
+
+	return dgQuery, nil
+}
+
+// findQuery finds the query block called name in a rewritten query, which might be wrapped
+// together with the var blocks for auth rules.
+func findQuery(q *gql.GraphQuery, name string) *gql.GraphQuery {
+	if q.Attr == name {
+		return q
+	}
+	if q.Attr != "" {
+		return nil
+	}
+	for _, c := range q.Children {
+		if found := findQuery(c, name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// completeConnection turns the list of nodes that Dgraph returned for a connection query into
+// the edges and page info of the connection.
+func completeConnection(q schema.Query, val interface{}) map[string]interface{} {
+	nodes, _ := val.([]interface{})
+
+	hasNextPage := false
+	if first, ok := connectionFirst(q); ok && int64(len(nodes)) > first {
+		nodes = nodes[:first]
+		hasNextPage = true
+	}
+
+	edges := make([]interface{}, 0, len(nodes))
+	var startCursor, endCursor interface{}
+	for _, n := range nodes {
+		node, ok := n.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		uid, _ := node["dgraph.uid"].(string)
+		cursor := encodeCursor(uid)
+		if startCursor == nil {
+			startCursor = cursor
+		}
+		endCursor = cursor
+		edges = append(edges, map[string]interface{}{
+			"cursor": cursor,
+			"node":   node,
+		})
+	}
+
+	return map[string]interface{}{
+		"edges": edges,
+		"pageInfo": map[string]interface{}{
+			"startCursor": startCursor,
+			"endCursor":   endCursor,
+			"hasNextPage": hasNextPage,
+			// Paging is only forwards, so there's no cheap way to tell if there are nodes
+			// before the first one, which the Relay spec allows to be reported as false.
+			"hasPreviousPage": false,
+		},
+	}
+}
@@ -0,0 +1,120 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package resolve
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dgraph-io/dgraph/graphql/dgraph"
+	"github.com/dgraph-io/dgraph/graphql/schema"
+	"github.com/dgraph-io/dgraph/graphql/test"
+	"github.com/stretchr/testify/require"
+)
+
+const connectionSchema = `
+	type Post {
+		id: ID!
+		title: String! @search(by: [term])
+	}
+`
+
+func TestConnectionQueryRewriting(t *testing.T) {
+	gqlSchema := test.LoadSchemaFromString(t, connectionSchema)
+
+	op, err := gqlSchema.Operation(&schema.Request{Query: `query {
+		queryPostConnection(filter: { title: { anyofterms: "GraphQL" } }, first: 2,
+			after: "` + encodeCursor("0x4") + `") {
+			edges { node { title } }
+			pageInfo { hasNextPage }
+		}
+	}`})
+	require.NoError(t, err)
+	gqlQuery := test.GetQuery(t, op)
+	require.Equal(t, schema.ConnectionQuery, gqlQuery.QueryType())
+
+	dgQuery, err := NewQueryRewriter().Rewrite(context.Background(), gqlQuery)
+	require.NoError(t, err)
+	require.Equal(t, `query {
+  queryPostConnection(func: type(Post), first: 3, after: 0x4) @filter(anyofterms(Post.title, "GraphQL")) {
+    title : Post.title
+    dgraph.uid : uid
+  }
+}`, dgraph.AsString(dgQuery))
+}
+
+func TestConnectionQueryInvalidCursor(t *testing.T) {
+	gqlSchema := test.LoadSchemaFromString(t, connectionSchema)
+
+	resp := resolve(gqlSchema, `query {
+		queryPostConnection(after: "not a cursor") { edges { cursor } }
+	}`, `{}`)
+	require.NotNil(t, resp.Errors)
+	require.Equal(t, "couldn't rewrite query queryPostConnection because The after argument "+
+		"of queryPostConnection is not a valid cursor.", resp.Errors[0].Message)
+}
+
+func TestConnectionCompletion(t *testing.T) {
+	gqlSchema := test.LoadSchemaFromString(t, connectionSchema)
+
+	// Dgraph returns one more node than asked for, which tells that there's a next page.
+	dgResponse := `{ "queryPostConnection": [
+		{ "title": "First", "dgraph.uid": "0x1" },
+		{ "title": "Second", "dgraph.uid": "0x2" },
+		{ "title": "Third", "dgraph.uid": "0x3" }
+	] }`
+
+	resp := resolve(gqlSchema, `query {
+		queryPostConnection(first: 2) {
+			edges { cursor node { title } }
+			pageInfo { startCursor endCursor hasNextPage hasPreviousPage }
+		}
+	}`, dgResponse)
+	require.Nil(t, resp.Errors)
+	require.JSONEq(t, `{ "queryPostConnection": {
+		"edges": [
+			{ "cursor": "`+encodeCursor("0x1")+`", "node": { "title": "First" } },
+			{ "cursor": "`+encodeCursor("0x2")+`", "node": { "title": "Second" } }
+		],
+		"pageInfo": {
+			"startCursor": "`+encodeCursor("0x1")+`",
+			"endCursor": "`+encodeCursor("0x2")+`",
+			"hasNextPage": true,
+			"hasPreviousPage": false
+		}
+	} }`, resp.Data.String())
+
+	uid, err := decodeCursor(encodeCursor("0x2"))
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), uid)
+}
+
+func TestConnectionCompletionEmpty(t *testing.T) {
+	gqlSchema := test.LoadSchemaFromString(t, connectionSchema)
+
+	resp := resolve(gqlSchema, `query {
+		queryPostConnection(first: 2) {
+			edges { cursor }
+			pageInfo { endCursor hasNextPage }
+		}
+	}`, `{ "queryPostConnection": [] }`)
+	require.Nil(t, resp.Errors)
+	require.JSONEq(t, `{ "queryPostConnection": {
+		"edges": [],
+		"pageInfo": { "endCursor": null, "hasNextPage": false }
+	} }`, resp.Data.String())
+}
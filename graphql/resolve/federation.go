@@ -0,0 +1,305 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package resolve
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/dgraph-io/dgraph/gql"
+	"github.com/dgraph-io/dgraph/graphql/schema"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// entityKeyPrefix prefixes the aliases under which an _entities query fetches the @id keys of
+// the entities, so completion can match them back to the representations.
+const entityKeyPrefix = "dgraph.key."
+
+// entityRepresentation is one of the representations an Apollo gateway passes to the _entities
+// query: the type of an entity and the value of its @key field.
+type entityRepresentation struct {
+	typ   schema.Type
+	byUID bool
+	uid   uint64
+	xid   string
+}
+
+func entityRepresentations(q schema.Query) ([]entityRepresentation, error) {
+	members := make(map[string]schema.Type)
+	for _, typ := range q.Type().UnionMembers(nil) {
+		members[typ.Name()] = typ
+	}
+
+	reps, _ := q.ArgValue(schema.RepresentationsArg).([]interface{})
+	result := make([]entityRepresentation, 0, len(reps))
+	for i, r := range reps {
+		rep, ok := r.(map[string]interface{})
+		if !ok {
+			return nil, x.GqlErrorf("Representation %d of %s is not an object.",
+				i, q.Name()).WithLocations(q.Location())
+		}
+
+		typName, _ := rep[schema.Typename].(string)
+		typ, ok := members[typName]
+		if !ok {
+			return nil, x.GqlErrorf("Representation %d of %s has __typename %q, which is not "+
+				"an entity type.", i, q.Name(), typName).WithLocations(q.Location())
+		}
+
+		key := typ.KeyField()
+		val, ok := rep[key.Name()].(string)
+		if !ok {
+			return nil, x.GqlErrorf("Representation %d of %s has no string value for the key "+
+				"field %s of type %s.", i, q.Name(), key.Name(), typName).
+				WithLocations(q.Location())
+		}
+
+		er := entityRepresentation{typ: typ, byUID: key.IsID(), xid: val}
+		if er.byUID {
+			uid, err := strconv.ParseUint(val, 0, 64)
+			if err != nil {
+				return nil, x.GqlErrorf("ID argument (%s) of %s was not able to be parsed",
+					val, q.Name()).WithLocations(q.Location())
+			}
+			er.uid = uid
+		}
+		result = append(result, er)
+	}
+
+	return result, nil
+}
+
+// rewriteAsEntitiesQuery rewrites an Apollo Federation _entities query.  The representations are
+// grouped by type, so all the entities of a type are found by one root function however many
+// of them the gateway asks for.  For representations of Author (keyed by ID) and Post (keyed by
+// an @id field) that's
+//
+//	_entities(func: uid(_EntityRoot)) {
+//	  dgraph.type
+//	  ...
+//	  dgraph.uid : uid
+//	  dgraph.key.Post : Post.slug
+//	}
+//	_EntityRoot as var(func: uid(Author1, Post2))
+//	Author1 as var(func: uid(0x1, 0x2)) @filter(type(Author))
+//	Post2 as var(func: eq(Post.slug, "a", "b")) @filter(type(Post))
+//
+// If a type has rules for querying, they are applied to the var block of that type.
+func rewriteAsEntitiesQuery(field schema.Query, authRw *authRewriter) (*gql.GraphQuery, error) {
+	reps, err := entityRepresentations(field)
+	if err != nil {
+		return nil, err
+	}
+
+	// Group the keys by type, keeping the types in the order they first appear so that the
+	// rewritten query is deterministic.
+	var types []schema.Type
+	seen := make(map[string]bool)
+	uids := make(map[string][]uint64)
+	xids := make(map[string][]gql.Arg)
+	for _, rep := range reps {
+		name := rep.typ.Name()
+		if !seen[name] {
+			seen[name] = true
+			types = append(types, rep.typ)
+		}
+		if rep.byUID {
+			uids[name] = append(uids[name], rep.uid)
+		} else {
+			xids[name] = append(xids[name], gql.Arg{Value: maybeQuoteArg("eq", rep.xid)})
+		}
+	}
+
+	var entityTypes []schema.Type
+	var entityVars []gql.Arg
+	var varQueries []*gql.GraphQuery
+	for _, typ := range types {
+		rbac := authRw.evaluateStaticRules(typ)
+		if rbac == schema.Negative {
+			continue
+		}
+
+		varName := authRw.varGen.Next(typ, "", "", authRw.isWritingAuth)
+		varQry := &gql.GraphQuery{
+			Var:  varName,
+			Attr: "var",
+		}
+		if key := typ.KeyField(); key.IsID() {
+			addUIDFunc(varQry, uids[typ.Name()])
+		} else {
+			varQry.Func = &gql.Function{
+				Name: "eq",
+				Args: append([]gql.Arg{{Value: key.DgraphPredicate()}}, xids[typ.Name()]...),
+			}
+		}
+		addTypeFilter(varQry, typ)
+		varQueries = append(varQueries, varQry)
+
+		if rbac == schema.Uncertain {
+			// The auth queries start from the nodes found for the keys, and the nodes that
+			// satisfy them are the entities of this type.
+			authRw.varName = varName
+			authQueries, filter := authRw.rewriteAuthQueries(typ)
+			if filter != nil {
+				varName = authRw.varGen.Next(typ, "", "", authRw.isWritingAuth)
+				varQueries = append(varQueries, &gql.GraphQuery{
+					Var:  varName,
+					Attr: "var",
+					Func: &gql.Function{
+						Name: "uid",
+						Args: []gql.Arg{{Value: authRw.varName}},
+					},
+					Filter: filter,
+				})
+				varQueries = append(varQueries, authQueries...)
+			}
+		}
+
+		entityTypes = append(entityTypes, typ)
+		entityVars = append(entityVars, gql.Arg{Value: varName})
+	}
+
+	if len(entityVars) == 0 {
+		return &gql.GraphQuery{Attr: field.Name() + "()"}, nil
+	}
+
+	rootQry := &gql.GraphQuery{
+		Var:  authRw.parentVarName,
+		Attr: "var",
+		Func: &gql.Function{
+			Name: "uid",
+			Args: entityVars,
+		},
+	}
+
+	dgQuery := &gql.GraphQuery{
+		Attr: field.Name(),
+		Func: &gql.Function{
+			Name: "uid",
+			Args: []gql.Arg{{Value: authRw.parentVarName}},
+		},
+	}
+	selectionAuth := addSelectionSetFrom(dgQuery, field, authRw)
+
+	// The uids and @id keys let entitiesCompletion match the results to the representations.
+	dgQuery.Children = append(dgQuery.Children, &gql.GraphQuery{
+		Attr:  "uid",
+		Alias: "dgraph.uid",
+	})
+	for _, typ := range entityTypes {
+		if key := typ.KeyField(); !key.IsID() {
+			dgQuery.Children = append(dgQuery.Children, &gql.GraphQuery{
+				Attr:  key.DgraphPredicate(),
+				Alias: entityKeyPrefix + typ.Name(),
+			})
+		}
+	}
+	addUID(dgQuery)
+	addCascadeDirective(dgQuery, field)
+
+	children := append([]*gql.GraphQuery{dgQuery, rootQry}, varQueries...)
+	return &gql.GraphQuery{Children: append(children, selectionAuth...)}, nil
+}
+
+// entitiesCompletion orders the entities found by an _entities query like the representations
+// they were asked for, with null for any that weren't found, as the Apollo Federation spec
+// requires.
+func entitiesCompletion() CompletionFunc {
+	return CompletionFunc(func(ctx context.Context, resolved *Resolved) {
+		data, ok := resolved.Data.(map[string]interface{})
+		if !ok {
+			return
+		}
+		query, ok := resolved.Field.(schema.Query)
+		if !ok {
+			return
+		}
+		reps, err := entityRepresentations(query)
+		if err != nil {
+			// Rewriting failed with the same error, which is already in the response.
+			return
+		}
+
+		byUID := make(map[uint64]map[string]interface{})
+		byKey := make(map[string]map[string]map[string]interface{})
+		found, _ := data[query.DgraphAlias()].([]interface{})
+		for _, f := range found {
+			entity, ok := f.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if uid, ok := entity["dgraph.uid"].(string); ok {
+				if u, err := strconv.ParseUint(uid, 0, 64); err == nil {
+					byUID[u] = entity
+				}
+			}
+			for alias, val := range entity {
+				key, ok := val.(string)
+				if !ok || !strings.HasPrefix(alias, entityKeyPrefix) {
+					continue
+				}
+				if byKey[alias] == nil {
+					byKey[alias] = make(map[string]map[string]interface{})
+				}
+				byKey[alias][key] = entity
+			}
+		}
+
+		entities := make([]interface{}, len(reps))
+		for i, rep := range reps {
+			var entity map[string]interface{}
+			if rep.byUID {
+				entity = byUID[rep.uid]
+			} else {
+				entity = byKey[entityKeyPrefix+rep.typ.Name()][rep.xid]
+			}
+			if entity != nil && hasDgraphType(entity, rep.typ) {
+				entities[i] = entity
+			}
+		}
+		data[query.DgraphAlias()] = entities
+	})
+}
+
+func hasDgraphType(entity map[string]interface{}, typ schema.Type) bool {
+	types, _ := entity["dgraph.type"].([]interface{})
+	for _, t := range types {
+		if t == typ.DgraphName() {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveApolloService resolves the _service query that an Apollo gateway uses to fetch the
+// SDL of the subgraph.
+func resolveApolloService(ctx context.Context, q schema.Query) *Resolved {
+	sdl, err := schema.ServiceSDL(q)
+	if err != nil {
+		return &Resolved{
+			Data:  map[string]interface{}{q.DgraphAlias(): nil},
+			Field: q,
+			Err:   err,
+		}
+	}
+
+	return &Resolved{
+		Data:  map[string]interface{}{q.DgraphAlias(): map[string]interface{}{"sdl": sdl}},
+		Field: q,
+	}
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package resolve
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dgraph-io/dgraph/graphql/dgraph"
+	"github.com/dgraph-io/dgraph/graphql/schema"
+	"github.com/dgraph-io/dgraph/graphql/test"
+	"github.com/stretchr/testify/require"
+)
+
+const federationSchema = `
+	type Author @key(fields: "id") {
+		id: ID!
+		name: String! @search(by: [hash])
+	}
+
+	type Post @key(fields: "slug") {
+		slug: String! @id
+		title: String
+	}
+`
+
+const entitiesQuery = `query {
+	_entities(representations: [
+		{ __typename: "Post", slug: "federation" },
+		{ __typename: "Author", id: "0x2" },
+		{ __typename: "Post", slug: "missing" },
+		{ __typename: "Author", id: "0x1" }
+	]) {
+		... on Author { name }
+		... on Post { title }
+	}
+}`
+
+func TestEntitiesQueryRewriting(t *testing.T) {
+	gqlSchema := test.LoadSchemaFromString(t, federationSchema)
+
+	op, err := gqlSchema.Operation(&schema.Request{Query: entitiesQuery})
+	require.NoError(t, err)
+	gqlQuery := test.GetQuery(t, op)
+	require.Equal(t, schema.EntitiesQuery, gqlQuery.QueryType())
+
+	dgQuery, err := NewQueryRewriter().Rewrite(context.Background(), gqlQuery)
+	require.NoError(t, err)
+	require.Equal(t, `query {
+  _entities(func: uid(_EntityRoot)) {
+    dgraph.type
+    name : Author.name
+    title : Post.title
+    dgraph.uid : uid
+    dgraph.key.Post : Post.slug
+  }
+  _EntityRoot as var(func: uid(Post1, Author2))
+  Post1 as var(func: eq(Post.slug, "federation", "missing")) @filter(type(Post))
+  Author2 as var(func: uid(0x2, 0x1)) @filter(type(Author))
+}`, dgraph.AsString(dgQuery))
+}
+
+func TestEntitiesCompletion(t *testing.T) {
+	gqlSchema := test.LoadSchemaFromString(t, federationSchema)
+
+	// Dgraph returns the entities in uid order, not in the order of the representations.
+	dgResponse := `{ "_entities": [
+		{ "dgraph.type": ["Author"], "name": "A. Author", "dgraph.uid": "0x1" },
+		{ "dgraph.type": ["Author"], "name": "B. Author", "dgraph.uid": "0x2" },
+		{ "dgraph.type": ["Post"], "title": "Federated", "dgraph.uid": "0x3",
+			"dgraph.key.Post": "federation" }
+	] }`
+
+	resp := resolve(gqlSchema, entitiesQuery, dgResponse)
+	require.Nil(t, resp.Errors)
+	require.JSONEq(t, `{ "_entities": [
+		{ "title": "Federated" },
+		{ "name": "B. Author" },
+		null,
+		{ "name": "A. Author" }
+	] }`, resp.Data.String())
+}
+
+func TestEntitiesInvalidRepresentation(t *testing.T) {
+	gqlSchema := test.LoadSchemaFromString(t, federationSchema)
+
+	resp := resolve(gqlSchema, `query {
+		_entities(representations: [{ __typename: "Author", name: "A. Author" }]) {
+			... on Author { name }
+		}
+	}`, `{}`)
+	require.NotNil(t, resp.Errors)
+	require.Equal(t, "couldn't rewrite query _entities because Representation 0 of _entities has no string value for the key field "+
+		"id of type Author.", resp.Errors[0].Message)
+}
+
+func TestServiceQuery(t *testing.T) {
+	gqlSchema := test.LoadSchemaFromString(t, federationSchema)
+
+	resp := resolve(gqlSchema, `query { _service { sdl } }`, `{}`)
+	require.Nil(t, resp.Errors)
+	require.Contains(t, resp.Data.String(), `@link(url: \"https://specs.apollo.dev/federation/v2.0\"`)
+	require.Contains(t, resp.Data.String(), `type Author @key(fields: \"id\")`)
+}
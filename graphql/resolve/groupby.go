@@ -0,0 +1,141 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package resolve
+
+import (
+	"context"
+	"strings"
+
+	"github.com/dgraph-io/dgraph/gql"
+	"github.com/dgraph-io/dgraph/graphql/schema"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// aggregateSuffixes maps the suffix of an aggregate field in a group type to the Dgraph
+// aggregation that computes it.
+var aggregateSuffixes = map[string]string{
+	"Min": "min",
+	"Max": "max",
+	"Avg": "avg",
+}
+
+// rewriteAsGroupByQuery rewrites a group by query as the query for the nodes it groups, with
+// @groupby on the fields being grouped by and the requested aggregates as the children.  For
+// aggregatePostGroupBy(groupBy: [isPublished]) { isPublished count numLikesMax } that's
+//
+//	aggregatePostGroupBy(func: type(Post)) @groupby(isPublished: Post.isPublished) {
+//	  count : count(uid)
+//	  numLikesMax : max(Post.numLikes)
+//	}
+func (qr *queryRewriter) rewriteAsGroupByQuery(
+	ctx context.Context,
+	gqlQuery schema.Query) (*gql.GraphQuery, error) {
+
+	groupBy, _ := gqlQuery.ArgValue("groupBy").([]interface{})
+	if len(groupBy) == 0 {
+		return nil, x.GqlErrorf("The groupBy argument of %s needs at least one field.",
+			gqlQuery.Name()).WithLocations(gqlQuery.Location())
+	}
+
+	nodes := gqlQuery.GroupByNodes()
+	dgQuery, err := qr.Rewrite(ctx, nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	root := findQuery(dgQuery, nodes.Name())
+	if root == nil {
+		// The nodes can't be queried, e.g. auth rules evaluated to false.
+		return dgQuery, nil
+	}
+
+	nodeType := nodes.Type()
+	root.IsGroupby = true
+	for _, fld := range groupBy {
+		name, _ := fld.(string)
+		root.GroupbyAttrs = append(root.GroupbyAttrs, gql.GroupByAttr{
+			Attr:  nodeType.DgraphPredicate(name),
+			Alias: name,
+		})
+	}
+
+	// Only aggregates can be children of a @groupby block.  The values of the fields grouped by
+	// come back with each group anyway.
+	isField := make(map[string]bool)
+	for _, fld := range nodeType.Fields() {
+		isField[fld.Name()] = true
+	}
+	added := make(map[string]bool)
+	root.Children = nil
+	for _, f := range gqlQuery.SelectionSet() {
+		if f.Skip() || !f.Include() || isField[f.Name()] || added[f.Name()] {
+			continue
+		}
+
+		if f.Name() == "count" {
+			root.Children = append(root.Children, &gql.GraphQuery{
+				Alias:   f.Name(),
+				Attr:    "uid",
+				IsCount: true,
+			})
+			added[f.Name()] = true
+			continue
+		}
+
+		for suffix, fn := range aggregateSuffixes {
+			if !strings.HasSuffix(f.Name(), suffix) {
+				continue
+			}
+			if fldName := strings.TrimSuffix(f.Name(), suffix); isField[fldName] {
+				root.Children = append(root.Children, &gql.GraphQuery{
+					Alias: f.Name(),
+					Attr:  nodeType.DgraphPredicate(fldName),
+					Func:  &gql.Function{Name: fn},
+				})
+				added[f.Name()] = true
+			}
+		}
+	}
+
+	if len(root.Children) == 0 {
+		// A @groupby block needs at least one child, so count the groups' nodes even if only
+		// the fields grouped by were asked for.
+		root.Children = append(root.Children, &gql.GraphQuery{
+			Alias:   "count",
+			Attr:    "uid",
+			IsCount: true,
+		})
+	}
+
+	return dgQuery, nil
+}
+
+// completeGroupBy turns the result of a @groupby query, which Dgraph returns as
+// [{ "@groupby": [ ...groups... ] }], into the list of groups.
+func completeGroupBy(val interface{}) []interface{} {
+	res, _ := val.([]interface{})
+	groups := make([]interface{}, 0)
+	for _, r := range res {
+		obj, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		grps, _ := obj["@groupby"].([]interface{})
+		groups = append(groups, grps...)
+	}
+	return groups
+}
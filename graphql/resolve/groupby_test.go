@@ -0,0 +1,125 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package resolve
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dgraph-io/dgraph/graphql/dgraph"
+	"github.com/dgraph-io/dgraph/graphql/schema"
+	"github.com/dgraph-io/dgraph/graphql/test"
+	"github.com/stretchr/testify/require"
+)
+
+const groupBySchema = `
+	type Post {
+		id: ID!
+		title: String! @search(by: [term])
+		isPublished: Boolean
+		numLikes: Int
+	}
+`
+
+func TestGroupByQueryRewriting(t *testing.T) {
+	gqlSchema := test.LoadSchemaFromString(t, groupBySchema)
+
+	op, err := gqlSchema.Operation(&schema.Request{Query: `query {
+		aggregatePostGroupBy(filter: { title: { anyofterms: "GraphQL" } },
+			groupBy: [isPublished, title]) {
+			isPublished
+			title
+			count
+			likes: numLikesMax
+			numLikesAvg
+		}
+	}`})
+	require.NoError(t, err)
+	gqlQuery := test.GetQuery(t, op)
+	require.Equal(t, schema.GroupByQuery, gqlQuery.QueryType())
+
+	dgQuery, err := NewQueryRewriter().Rewrite(context.Background(), gqlQuery)
+	require.NoError(t, err)
+	require.Equal(t, `query {
+  aggregatePostGroupBy(func: type(Post)) @filter(anyofterms(Post.title, "GraphQL")) @groupby(isPublished: Post.isPublished, title: Post.title) {
+    count : count(uid)
+    numLikesMax : max(Post.numLikes)
+    numLikesAvg : avg(Post.numLikes)
+  }
+}`, dgraph.AsString(dgQuery))
+}
+
+func TestGroupByQueryOnlyGroupedFields(t *testing.T) {
+	gqlSchema := test.LoadSchemaFromString(t, groupBySchema)
+
+	op, err := gqlSchema.Operation(&schema.Request{Query: `query {
+		aggregatePostGroupBy(groupBy: [isPublished]) { isPublished }
+	}`})
+	require.NoError(t, err)
+
+	dgQuery, err := NewQueryRewriter().Rewrite(context.Background(), test.GetQuery(t, op))
+	require.NoError(t, err)
+	require.Equal(t, `query {
+  aggregatePostGroupBy(func: type(Post)) @groupby(isPublished: Post.isPublished) {
+    count : count(uid)
+  }
+}`, dgraph.AsString(dgQuery))
+}
+
+func TestGroupByQueryEmptyGroupBy(t *testing.T) {
+	gqlSchema := test.LoadSchemaFromString(t, groupBySchema)
+
+	resp := resolve(gqlSchema, `query {
+		aggregatePostGroupBy(groupBy: []) { count }
+	}`, `{}`)
+	require.NotNil(t, resp.Errors)
+	require.Equal(t, "couldn't rewrite query aggregatePostGroupBy because The groupBy argument "+
+		"of aggregatePostGroupBy needs at least one field.", resp.Errors[0].Message)
+}
+
+func TestGroupByCompletion(t *testing.T) {
+	gqlSchema := test.LoadSchemaFromString(t, groupBySchema)
+
+	dgResponse := `{ "aggregatePostGroupBy": [ { "@groupby": [
+		{ "isPublished": true, "count": 2, "numLikesMax": 10 },
+		{ "isPublished": false, "count": 1, "numLikesMax": 3 }
+	] } ] }`
+
+	resp := resolve(gqlSchema, `query {
+		aggregatePostGroupBy(groupBy: [isPublished]) {
+			isPublished
+			count
+			numLikesMax
+			title
+		}
+	}`, dgResponse)
+	require.Nil(t, resp.Errors)
+	require.JSONEq(t, `{ "aggregatePostGroupBy": [
+		{ "isPublished": true, "count": 2, "numLikesMax": 10, "title": null },
+		{ "isPublished": false, "count": 1, "numLikesMax": 3, "title": null }
+	] }`, resp.Data.String())
+}
+
+func TestGroupByCompletionNoGroups(t *testing.T) {
+	gqlSchema := test.LoadSchemaFromString(t, groupBySchema)
+
+	resp := resolve(gqlSchema, `query {
+		aggregatePostGroupBy(groupBy: [isPublished]) { count }
+	}`, `{}`)
+	require.Nil(t, resp.Errors)
+	require.JSONEq(t, `{ "aggregatePostGroupBy": [] }`, resp.Data.String())
+}
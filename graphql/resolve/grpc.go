@@ -0,0 +1,292 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package resolve
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/gogo/protobuf/protoc-gen-gogo/descriptor"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+
+	"github.com/dgraph-io/dgraph/graphql/lambda"
+	"github.com/dgraph-io/dgraph/graphql/schema"
+)
+
+// grpcClients caches the connections to the gRPC servers used by @custom fields and the
+// descriptors of the methods called on them. Descriptors are loaded on the first call of a
+// method, from the descriptor file if there is one or else using server reflection.
+var grpcClients = struct {
+	sync.Mutex
+	conns   map[string]*grpc.ClientConn
+	methods map[string]*grpcMethod
+}{
+	conns:   make(map[string]*grpc.ClientConn),
+	methods: make(map[string]*grpcMethod),
+}
+
+type grpcMethod struct {
+	files         *protoFiles
+	input, output *descriptor.DescriptorProto
+}
+
+// rawCodec sends and receives messages that are already encoded in the protobuf wire format.
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	return *(v.(*[]byte)), nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	*(v.(*[]byte)) = append([]byte(nil), data...)
+	return nil
+}
+
+func (rawCodec) Name() string {
+	return "proto"
+}
+
+// makeCustomRequest makes the request to the remote endpoint of a @custom field, over gRPC if
+// the field has a grpc config and over HTTP otherwise. The @lambda fields resolved by the lambda
+// script are resolved in process instead.
+func makeCustomRequest(ctx context.Context, client *http.Client, fconf schema.FieldHTTPConfig,
+	url, body string) ([]byte, int, error) {
+	if fconf.Lambda {
+		b, err := lambda.Resolve(ctx, body)
+		if err != nil {
+			return nil, 0, err
+		}
+		return b, http.StatusOK, nil
+	}
+	if fconf.GRPC == nil {
+		return makeRequest(client, fconf.Method, url, body, fconf.ForwardHeaders)
+	}
+	b, err := makeGRPCRequest(ctx, fconf, body)
+	if err != nil {
+		return nil, 0, err
+	}
+	return b, http.StatusOK, nil
+}
+
+// makeGRPCRequest calls the gRPC method in fconf with the JSON body converted to the request
+// message, and returns the response message converted to JSON. The forward headers are sent as
+// metadata, and the deadline of ctx is propagated to the server.
+func makeGRPCRequest(ctx context.Context, fconf schema.FieldHTTPConfig, body string) ([]byte,
+	error) {
+	if _, ok := ctx.Deadline(); !ok {
+		// Same as the timeout of HTTP requests to remote endpoints.
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Minute)
+		defer cancel()
+	}
+
+	conn, err := grpcConn(fconf.URL)
+	if err != nil {
+		return nil, err
+	}
+	m, err := grpcMethodFor(ctx, conn, fconf)
+	if err != nil {
+		return nil, err
+	}
+
+	var input interface{} = map[string]interface{}{}
+	if body != "" && body != "null" {
+		d := json.NewDecoder(bytes.NewBufferString(body))
+		d.UseNumber()
+		if err := d.Decode(&input); err != nil {
+			return nil, err
+		}
+	}
+	if fconf.Mode == schema.BATCH {
+		// A message can't be a list, so the inputs are sent in the only field of the request.
+		fd, err := singleField(m.input)
+		if err != nil {
+			return nil, err
+		}
+		input = map[string]interface{}{fd.GetName(): input}
+	}
+	req, err := m.files.encodeMessage(m.input, input)
+	if err != nil {
+		return nil, err
+	}
+
+	md := metadata.MD{}
+	for k, v := range fconf.ForwardHeaders {
+		md.Set(strings.ToLower(k), v...)
+	}
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	var resp []byte
+	if err := conn.Invoke(ctx, "/"+fconf.Method, &req, &resp,
+		grpc.ForceCodec(rawCodec{})); err != nil {
+		return nil, err
+	}
+
+	obj, err := m.files.decodeMessage(m.output, resp)
+	if err != nil {
+		return nil, err
+	}
+	var result interface{} = obj
+	if fconf.Mode == schema.BATCH || fconf.GRPC.Unwrap {
+		fd, err := singleField(m.output)
+		if err != nil {
+			return nil, err
+		}
+		result = obj[protoJSONName(fd)]
+	}
+	return json.Marshal(result)
+}
+
+// singleField returns the only field of md, which is used to wrap the values that a message can't
+// represent directly, like lists.
+func singleField(md *descriptor.DescriptorProto) (*descriptor.FieldDescriptorProto, error) {
+	if len(md.Field) != 1 {
+		return nil, errors.Errorf("expected message %s to have exactly one field, found %d",
+			md.GetName(), len(md.Field))
+	}
+	return md.Field[0], nil
+}
+
+func grpcConn(target string) (*grpc.ClientConn, error) {
+	grpcClients.Lock()
+	defer grpcClients.Unlock()
+	if conn, ok := grpcClients.conns[target]; ok {
+		return conn, nil
+	}
+	conn, err := grpc.Dial(target, grpc.WithInsecure())
+	if err != nil {
+		return nil, err
+	}
+	grpcClients.conns[target] = conn
+	return conn, nil
+}
+
+func grpcMethodFor(ctx context.Context, conn *grpc.ClientConn,
+	fconf schema.FieldHTTPConfig) (*grpcMethod, error) {
+	key := fconf.URL + "|" + fconf.GRPC.DescriptorFile + "|" + fconf.Method
+	grpcClients.Lock()
+	m, ok := grpcClients.methods[key]
+	grpcClients.Unlock()
+	if ok {
+		return m, nil
+	}
+
+	var files []*descriptor.FileDescriptorProto
+	var err error
+	if fconf.GRPC.DescriptorFile != "" {
+		files, err = readDescriptorFile(fconf.GRPC.DescriptorFile)
+	} else {
+		files, err = reflectDescriptors(ctx, conn, strings.Split(fconf.Method, "/")[0])
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	m = &grpcMethod{files: newProtoFiles(files)}
+	if m.input, m.output, err = m.files.method(fconf.Method); err != nil {
+		return nil, err
+	}
+	grpcClients.Lock()
+	grpcClients.methods[key] = m
+	grpcClients.Unlock()
+	return m, nil
+}
+
+// readDescriptorFile reads a FileDescriptorSet, like the one generated by
+// protoc --include_imports --descriptor_set_out.
+func readDescriptorFile(path string) ([]*descriptor.FileDescriptorProto, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "while reading gRPC descriptor file")
+	}
+	var set descriptor.FileDescriptorSet
+	if err := proto.Unmarshal(b, &set); err != nil {
+		return nil, errors.Wrapf(err, "while parsing gRPC descriptor file %s", path)
+	}
+	return set.File, nil
+}
+
+// reflectDescriptors fetches the file defining service along with its dependencies using gRPC
+// server reflection.
+func reflectDescriptors(ctx context.Context, conn *grpc.ClientConn,
+	service string) ([]*descriptor.FileDescriptorProto, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := rpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "while fetching gRPC descriptors using server reflection")
+	}
+
+	var files []*descriptor.FileDescriptorProto
+	// seen has the files received so far, the server may send dependencies along with a file.
+	seen := make(map[string]bool)
+	requested := make(map[string]bool)
+	pending := []*rpb.ServerReflectionRequest{{
+		MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{
+			FileContainingSymbol: service,
+		},
+	}}
+	for len(pending) > 0 {
+		if err := stream.Send(pending[0]); err != nil {
+			return nil, errors.Wrapf(err, "while fetching gRPC descriptors using server reflection")
+		}
+		pending = pending[1:]
+		resp, err := stream.Recv()
+		if err != nil {
+			return nil, errors.Wrapf(err, "while fetching gRPC descriptors using server reflection")
+		}
+		if errResp := resp.GetErrorResponse(); errResp != nil {
+			return nil, errors.Errorf("gRPC server reflection failed for %s: %s", service,
+				errResp.GetErrorMessage())
+		}
+		for _, b := range resp.GetFileDescriptorResponse().GetFileDescriptorProto() {
+			fd := &descriptor.FileDescriptorProto{}
+			if err := proto.Unmarshal(b, fd); err != nil {
+				return nil, err
+			}
+			if seen[fd.GetName()] {
+				continue
+			}
+			seen[fd.GetName()] = true
+			files = append(files, fd)
+		}
+		for _, fd := range files {
+			for _, dep := range fd.Dependency {
+				if seen[dep] || requested[dep] {
+					continue
+				}
+				requested[dep] = true
+				pending = append(pending, &rpb.ServerReflectionRequest{
+					MessageRequest: &rpb.ServerReflectionRequest_FileByFilename{
+						FileByFilename: dep,
+					},
+				})
+			}
+		}
+	}
+	return files, nil
+}
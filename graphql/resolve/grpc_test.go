@@ -0,0 +1,292 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package resolve
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	dgoapi "github.com/dgraph-io/dgo/v200/protos/api"
+	gogoproto "github.com/gogo/protobuf/proto"
+	"github.com/gogo/protobuf/protoc-gen-gogo/descriptor"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+
+	"github.com/dgraph-io/dgraph/graphql/schema"
+)
+
+// apiDescriptor returns the descriptor of the dgo api.proto, which is used as a real world proto
+// to check that the JSON conversion is compatible with generated code.
+func apiDescriptor(t *testing.T) *descriptor.FileDescriptorProto {
+	zr, err := gzip.NewReader(bytes.NewReader(proto.FileDescriptor("api.proto")))
+	require.NoError(t, err)
+	b, err := ioutil.ReadAll(zr)
+	require.NoError(t, err)
+	fd := &descriptor.FileDescriptorProto{}
+	require.NoError(t, gogoproto.Unmarshal(b, fd))
+	return fd
+}
+
+func TestProtoJSONEncode(t *testing.T) {
+	pf := newProtoFiles([]*descriptor.FileDescriptorProto{apiDescriptor(t)})
+	in, _, err := pf.method("api.Dgraph/Query")
+	require.NoError(t, err)
+
+	b, err := pf.encodeMessage(in, map[string]interface{}{
+		"query":      "{ q(func: uid(0x1)) { uid } }",
+		"vars":       map[string]interface{}{"$a": "1"},
+		"startTs":    "5",
+		"read_only":  true,
+		"respFormat": "RDF",
+		"mutations":  []interface{}{map[string]interface{}{"setJson": "e30="}},
+	})
+	require.NoError(t, err)
+
+	var req dgoapi.Request
+	require.NoError(t, proto.Unmarshal(b, &req))
+	require.Equal(t, "{ q(func: uid(0x1)) { uid } }", req.Query)
+	require.Equal(t, map[string]string{"$a": "1"}, req.Vars)
+	require.Equal(t, uint64(5), req.StartTs)
+	require.True(t, req.ReadOnly)
+	require.Equal(t, dgoapi.Request_RDF, req.RespFormat)
+	require.Len(t, req.Mutations, 1)
+	require.Equal(t, []byte("{}"), req.Mutations[0].SetJson)
+
+	_, err = pf.encodeMessage(in, map[string]interface{}{"queries": "q"})
+	require.EqualError(t, err, "message Request has no field queries")
+	_, err = pf.encodeMessage(in, map[string]interface{}{"respFormat": "XML"})
+	require.EqualError(t, err, "while encoding field resp_format of message Request: enum "+
+		"RespFormat has no value XML")
+}
+
+func TestProtoJSONDecode(t *testing.T) {
+	pf := newProtoFiles([]*descriptor.FileDescriptorProto{apiDescriptor(t)})
+	_, out, err := pf.method("api.Dgraph/Query")
+	require.NoError(t, err)
+
+	b, err := proto.Marshal(&dgoapi.Response{
+		Json: []byte("{}"),
+		Txn:  &dgoapi.TxnContext{StartTs: 10, Keys: []string{"k1", "k2"}},
+		Uids: map[string]string{"a": "0x1"},
+	})
+	require.NoError(t, err)
+
+	obj, err := pf.decodeMessage(out, b)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"json": "e30=",
+		"txn": map[string]interface{}{
+			"startTs":  uint64(10),
+			"commitTs": uint64(0),
+			"aborted":  false,
+			"keys":     []interface{}{"k1", "k2"},
+			"preds":    []interface{}{},
+		},
+		"latency": nil,
+		"metrics": nil,
+		"uids":    map[string]interface{}{"a": "0x1"},
+		"rdf":     "",
+	}, obj)
+}
+
+type testDgraphServer struct {
+	dgoapi.UnimplementedDgraphServer
+}
+
+func (s *testDgraphServer) Query(ctx context.Context, req *dgoapi.Request) (*dgoapi.Response,
+	error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	_, hasDeadline := ctx.Deadline()
+	uids := map[string]string{"query": req.Query}
+	if hasDeadline {
+		uids["deadline"] = "true"
+	}
+	if v := md.Get("x-user"); len(v) > 0 {
+		uids["user"] = v[0]
+	}
+	return &dgoapi.Response{Uids: uids}, nil
+}
+
+func (s *testDgraphServer) CheckVersion(ctx context.Context,
+	c *dgoapi.Check) (*dgoapi.Version, error) {
+	return &dgoapi.Version{Tag: "v20.07"}, nil
+}
+
+func startGRPCServer(t *testing.T, opts []grpc.ServerOption,
+	register func(*grpc.Server)) (string, func()) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := grpc.NewServer(opts...)
+	register(s)
+	go func() { _ = s.Serve(lis) }()
+	return lis.Addr().String(), s.Stop
+}
+
+func writeDescriptorFile(t *testing.T, file *descriptor.FileDescriptorProto) (string, func()) {
+	set, err := gogoproto.Marshal(&descriptor.FileDescriptorSet{
+		File: []*descriptor.FileDescriptorProto{file},
+	})
+	require.NoError(t, err)
+	dir, err := ioutil.TempDir("", "grpc")
+	require.NoError(t, err)
+	path := filepath.Join(dir, file.GetName()+"set")
+	require.NoError(t, ioutil.WriteFile(path, set, 0644))
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func TestGRPCRequest(t *testing.T) {
+	addr, stop := startGRPCServer(t, nil, func(s *grpc.Server) {
+		dgoapi.RegisterDgraphServer(s, &testDgraphServer{})
+		reflection.Register(s)
+	})
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	t.Run("reflection with unwrapped result", func(t *testing.T) {
+		b, err := makeGRPCRequest(ctx, schema.FieldHTTPConfig{
+			URL:    addr,
+			Method: "api.Dgraph/CheckVersion",
+			GRPC:   &schema.GRPCConfig{Unwrap: true},
+		}, "")
+		require.NoError(t, err)
+		require.JSONEq(t, `"v20.07"`, string(b))
+	})
+
+	t.Run("descriptor file with forwarded headers", func(t *testing.T) {
+		path, cleanup := writeDescriptorFile(t, apiDescriptor(t))
+		defer cleanup()
+
+		b, err := makeGRPCRequest(ctx, schema.FieldHTTPConfig{
+			URL:            addr,
+			Method:         "api.Dgraph/Query",
+			ForwardHeaders: http.Header{"X-User": []string{"alice"}},
+			GRPC:           &schema.GRPCConfig{DescriptorFile: path},
+		}, `{"query": "{ me }"}`)
+		require.NoError(t, err)
+		require.JSONEq(t, `{
+			"json": "",
+			"txn": null,
+			"latency": null,
+			"metrics": null,
+			"uids": {"query": "{ me }", "deadline": "true", "user": "alice"},
+			"rdf": ""
+		}`, string(b))
+	})
+
+	t.Run("unknown method", func(t *testing.T) {
+		_, err := makeGRPCRequest(ctx, schema.FieldHTTPConfig{
+			URL:    addr,
+			Method: "api.Dgraph/Mutate",
+			GRPC:   &schema.GRPCConfig{},
+		}, "")
+		require.EqualError(t, err, "gRPC method api.Dgraph/Mutate not found in descriptors")
+	})
+}
+
+// echoCodec lets the test server echo the request bytes back as the response.
+type echoCodec struct {
+	rawCodec
+}
+
+func (echoCodec) String() string {
+	return "proto"
+}
+
+func TestGRPCRequestBatchMode(t *testing.T) {
+	str := func(s string) *string { return &s }
+	num := func(n int32) *int32 { return &n }
+	file := &descriptor.FileDescriptorProto{
+		Name:    str("echo.proto"),
+		Package: str("test"),
+		MessageType: []*descriptor.DescriptorProto{{
+			Name: str("Item"),
+			Field: []*descriptor.FieldDescriptorProto{{
+				Name:   str("id"),
+				Number: num(1),
+				Label:  descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:   descriptor.FieldDescriptorProto_TYPE_INT64.Enum(),
+			}, {
+				Name:   str("display_name"),
+				Number: num(2),
+				Label:  descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:   descriptor.FieldDescriptorProto_TYPE_STRING.Enum(),
+			}},
+		}, {
+			Name: str("Items"),
+			Field: []*descriptor.FieldDescriptorProto{{
+				Name:     str("items"),
+				Number:   num(1),
+				Label:    descriptor.FieldDescriptorProto_LABEL_REPEATED.Enum(),
+				Type:     descriptor.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+				TypeName: str(".test.Item"),
+			}},
+		}},
+		Service: []*descriptor.ServiceDescriptorProto{{
+			Name: str("Echo"),
+			Method: []*descriptor.MethodDescriptorProto{{
+				Name:       str("Echo"),
+				InputType:  str(".test.Items"),
+				OutputType: str(".test.Items"),
+			}},
+		}},
+	}
+	path, cleanup := writeDescriptorFile(t, file)
+	defer cleanup()
+
+	addr, stop := startGRPCServer(t, []grpc.ServerOption{grpc.CustomCodec(echoCodec{})},
+		func(s *grpc.Server) {
+			s.RegisterService(&grpc.ServiceDesc{
+				ServiceName: "test.Echo",
+				HandlerType: (*interface{})(nil),
+				Methods: []grpc.MethodDesc{{
+					MethodName: "Echo",
+					Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error,
+						interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+						var in []byte
+						err := dec(&in)
+						return &in, err
+					},
+				}},
+			}, struct{}{})
+		})
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	b, err := makeGRPCRequest(ctx, schema.FieldHTTPConfig{
+		URL:    addr,
+		Method: "test.Echo/Echo",
+		Mode:   schema.BATCH,
+		GRPC:   &schema.GRPCConfig{DescriptorFile: path},
+	}, `[{"id": "1", "displayName": "Alice"}, {"id": 2}]`)
+	require.NoError(t, err)
+	require.JSONEq(t, `[{"id": 1, "displayName": "Alice"}, {"id": 2, "displayName": ""}]`,
+		string(b))
+}
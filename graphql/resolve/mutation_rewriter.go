@@ -24,6 +24,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	dgoapi "github.com/dgraph-io/dgo/v200/protos/api"
 	"github.com/dgraph-io/dgraph/gql"
@@ -31,6 +32,7 @@ import (
 	"github.com/dgraph-io/dgraph/graphql/schema"
 	"github.com/dgraph-io/dgraph/x"
 
+	"github.com/google/uuid"
 	"github.com/pkg/errors"
 )
 
@@ -40,6 +42,13 @@ const (
 	updateMutationCondition = `gt(len(x), 0)`
 )
 
+// defaultGenerators make the values of the @default directives that are generated when a
+// mutation is rewritten.
+var defaultGenerators = map[schema.DefaultGenerator]func() interface{}{
+	schema.DefaultNow:  func() interface{} { return time.Now().UTC().Format(time.RFC3339Nano) },
+	schema.DefaultUUID: func() interface{} { return uuid.New().String() },
+}
+
 type AddRewriter struct {
 	frags [][]*mutationFragment
 }
@@ -64,6 +73,7 @@ type mutationFragment struct {
 	conditions []string
 	fragment   interface{}
 	deletes    []interface{}
+	uploads    []*dgoapi.NQuad
 	check      resultChecker
 	newNodes   map[string]schema.Type
 	err        error
@@ -78,6 +88,13 @@ type xidMetadata struct {
 	seenAtTopLevel map[string]bool
 	// queryExists tells whether the query part in upsert has already been created for xidVariable
 	queryExists map[string]bool
+	// upsert tells, for each nesting level of an add mutation, whether objects at that level
+	// that have an existing xid should be updated instead of being an error or a reference.
+	upsert []bool
+	// depth is the nesting level of the object currently being rewritten.
+	depth int
+	// compositeKeys stores the composite keys of the objects added so far, to find duplicates.
+	compositeKeys map[string]bool
 }
 
 // A mutationBuilder can build a json mutation []byte from a mutationFragment
@@ -154,7 +171,25 @@ func newXidMetadata() *xidMetadata {
 		variableObjMap: make(map[string]map[string]interface{}),
 		seenAtTopLevel: make(map[string]bool),
 		queryExists:    make(map[string]bool),
+		compositeKeys:  make(map[string]bool),
+	}
+}
+
+// upsertAt returns true if objects at the given nesting level should be upserted.
+func (xidMetadata *xidMetadata) upsertAt(level int) bool {
+	return level < len(xidMetadata.upsert) && xidMetadata.upsert[level]
+}
+
+// upsertLevels returns the per level upsert flags from the `upsert` argument of an add
+// mutation, e.g. `upsert: [false, true]` upserts only the objects one level below the top.
+func upsertLevels(m schema.Mutation) []bool {
+	val, _ := m.ArgValue(schema.UpsertArgName).([]interface{})
+	levels := make([]bool, 0, len(val))
+	for _, v := range val {
+		b, _ := v.(bool)
+		levels = append(levels, b)
 	}
+	return levels
 }
 
 // isDuplicateXid returns true if:
@@ -267,6 +302,7 @@ func (mrw *AddRewriter) Rewrite(ctx context.Context, m schema.Mutation) ([]*Upse
 
 	varGen := NewVariableGenerator()
 	xidMd := newXidMetadata()
+	xidMd.upsert = upsertLevels(m)
 	var errs error
 
 	mutationsAllSec := []*dgoapi.Mutation{}
@@ -366,6 +402,11 @@ func (mrw *AddRewriter) FromMutationResult(
 		node := strings.TrimPrefix(frag[0].
 			fragment.(map[string]interface{})["uid"].(string), "_:")
 		val, ok := assigned[node]
+		if !ok {
+			// An upserted node that already existed isn't assigned a uid, but was found by
+			// the xid query.
+			val, ok = upsertedUID(node, result)
+		}
 		if !ok {
 			continue
 		}
@@ -380,7 +421,7 @@ func (mrw *AddRewriter) FromMutationResult(
 		uids = append(uids, uid)
 	}
 
-	if len(assigned) == 0 && errs == nil {
+	if len(assigned) == 0 && len(uids) == 0 && errs == nil {
 		errs = schema.AsGQLErrors(errors.Errorf("no new node was created"))
 	}
 
@@ -400,6 +441,20 @@ func (mrw *AddRewriter) FromMutationResult(
 	return rewriteAsQueryByIds(mutation.QueryField(), uids, authRw), errs
 }
 
+// upsertedUID returns the uid found by the xid query for an upserted node uid(variable).
+func upsertedUID(node string, result map[string]interface{}) (string, bool) {
+	if !strings.HasPrefix(node, "uid(") || !strings.HasSuffix(node, ")") {
+		return "", false
+	}
+	data, _ := result[node[4:len(node)-1]].([]interface{})
+	if len(data) != 1 {
+		return "", false
+	}
+	obj, _ := data[0].(map[string]interface{})
+	uid, ok := obj["uid"].(string)
+	return uid, ok
+}
+
 // Rewrite rewrites set and remove update patches into GraphQL+- upsert mutations.
 // The GraphQL updates look like:
 //
@@ -435,6 +490,10 @@ func (urw *UpdateRewriter) Rewrite(
 	if setArg == nil && delArg == nil {
 		return nil, nil
 	}
+	if setArg == nil && len(mutatedType.DefaultValues(true)) > 0 {
+		// The defaults of an update are set even if the update only removes values.
+		setArg = map[string]interface{}{}
+	}
 
 	varGen := NewVariableGenerator()
 
@@ -690,10 +749,20 @@ func RewriteUpsertQueryFromMutation(m schema.Mutation, authRw *authRewriter) *gq
 		addTypeFunc(dgQuery, m.MutatedType().DgraphName())
 	}
 
-	_ = addFilter(dgQuery, m.MutatedType(), filter)
+	filterQueries, _ := addFilter(dgQuery, m.MutatedType(), filter, authRw.varGen)
 
 	dgQuery = authRw.addAuthQueries(m.MutatedType(), dgQuery, rbac)
 
+	// The upsert query must stay the first query, so any var blocks needed by the filter go
+	// after it.
+	if len(filterQueries) > 0 {
+		if dgQuery.Attr == "" {
+			dgQuery.Children = append(dgQuery.Children, filterQueries...)
+		} else {
+			dgQuery = &gql.GraphQuery{Children: append([]*gql.GraphQuery{dgQuery}, filterQueries...)}
+		}
+	}
+
 	return dgQuery
 }
 
@@ -890,6 +959,7 @@ func mutationsFromFragments(
 		mutations = append(mutations, &dgoapi.Mutation{
 			SetJson:    set,
 			DeleteJson: del,
+			Set:        frag.uploads,
 			Cond:       conditions,
 		})
 	}
@@ -954,6 +1024,10 @@ func rewriteObject(
 	atTopLevel := srcField == nil
 	topLevelAdd := srcUID == ""
 
+	level := xidMetadata.depth
+	xidMetadata.depth++
+	defer func() { xidMetadata.depth-- }()
+
 	variable := varGen.Next(typ, "", "", false)
 
 	id := typ.IDField()
@@ -972,6 +1046,15 @@ func rewriteObject(
 	var xidString string
 	xid := typ.XIDField()
 	xidEncounteredFirstTime := false
+	if xid != nil && withAdditionalDeletes && (!atTopLevel || topLevelAdd) {
+		// A new object with a generated xid, e.g. from `@id @default(value: "uuid()")`, needs
+		// its xid before it's checked against the other xids of the mutation.
+		if xidVal, ok := obj[xid.Name()]; !ok || xidVal == nil {
+			if def, ok := typ.DefaultValues(false)[xid.Name()]; ok {
+				obj = withDefaults(obj, map[string]interface{}{xid.Name(): def})
+			}
+		}
+	}
 	if xid != nil {
 		if xidVal, ok := obj[xid.Name()]; ok && xidVal != nil {
 			xidString, ok = xidVal.(string)
@@ -1002,15 +1085,35 @@ func rewriteObject(
 				xidMetadata.seenAtTopLevel[variable] = atTopLevel
 			}
 		}
+	}
 
+	// An upserted object is written to uid(variable), which Dgraph resolves to the existing
+	// node with that xid, or to a new node if there isn't one.  So it needs neither the
+	// conditions nor the first pass that other xid objects need.
+	upsert := xidString != "" && withAdditionalDeletes && (!atTopLevel || topLevelAdd) &&
+		xidMetadata.upsertAt(level)
+	if upsert && updateAuthSelector(typ) != nil {
+		errFrag := newFragment(nil)
+		errFrag.err = errors.Errorf("upsert isn't allowed for type %s because it has update "+
+			"auth rules", typ.Name())
+		return &mutationRes{secondPass: []*mutationFragment{errFrag}}
+	}
+
+	if xid != nil && !upsert {
 		deepXID += 1
 	}
 
+	// An upserted object might already exist, so it only gets the defaults of an update.
+	if withAdditionalDeletes {
+		obj = withDefaults(obj, typ.DefaultValues(upsert || (atTopLevel && !topLevelAdd)))
+	}
+
 	var parentFrags []*mutationFragment
 
 	if !atTopLevel { // top level is never a reference - it's a new addition.
 		// this is the case of a lower level having xid which is a reference.
-		if xid != nil && xidString != "" {
+		// An upserted object is linked to its parent as if it were a new object.
+		if xid != nil && xidString != "" && !upsert {
 			xidFrag = asXIDReference(ctx, srcField, srcUID, typ, xid.Name(), xidString,
 				variable, withAdditionalDeletes, varGen, xidMetadata)
 
@@ -1098,8 +1201,11 @@ func rewriteObject(
 		dgraphTypes = append(dgraphTypes, typ.Interfaces()...)
 		newObj["dgraph.type"] = dgraphTypes
 		myUID = fmt.Sprintf("_:%s", variable)
+		if upsert {
+			myUID = fmt.Sprintf("uid(%s)", variable)
+		}
 
-		if xid == nil || deepXID > 2 {
+		if xid == nil || deepXID > 2 || upsert {
 			// If this object had an overwritten value for the inverse field, then we don't want to
 			// use that value as we will add the link to the inverse field in the below
 			// function call with the parent of this object
@@ -1134,10 +1240,26 @@ func rewriteObject(
 
 	newObj["uid"] = myUID
 	frag := newFragment(newObj)
-	frag.newNodes[variable] = typ
+	if upsert {
+		// Dgraph reports a node created for an empty uid(variable) as uid(variable).
+		frag.newNodes[myUID] = typ
+	} else {
+		frag.newNodes[variable] = typ
+	}
 
 	results := &mutationRes{secondPass: []*mutationFragment{frag}}
-	if xid != nil && !atTopLevel && !xidEncounteredFirstTime && deepXID <= 2 {
+	if upsert {
+		if !xidMetadata.queryExists[variable] {
+			frag.queries = []*gql.GraphQuery{
+				xidQuery(variable, xidString, xid.Name(), typ),
+			}
+			xidMetadata.queryExists[variable] = true
+		}
+		// If the node exists, the link to its parent might replace an existing one.
+		if !atTopLevel {
+			addAdditionalDeletes(ctx, frag, varGen, srcField, srcUID, variable)
+		}
+	} else if xid != nil && !atTopLevel && !xidEncounteredFirstTime && deepXID <= 2 {
 		// If this is an xid that has been encountered before, e.g. think add mutations with
 		// multiple objects as input. In that case we don't need to add the fragment to create this
 		// object, so we clear it out. We do need other fragments for linking this node to its
@@ -1149,7 +1271,7 @@ func rewriteObject(
 
 	// if xidString != "", then we are adding with an xid.  In which case, we have to ensure
 	// as part of the upsert that the xid doesn't already exist.
-	if xidString != "" {
+	if xidString != "" && !upsert {
 		if atTopLevel && !xidMetadata.queryExists[variable] {
 			// If not at top level, the query is already added by asXIDReference
 			frag.queries = []*gql.GraphQuery{
@@ -1171,7 +1293,19 @@ func rewriteObject(
 		frag.check = checkQueryResult(variable, err, nil)
 	}
 
-	if xid != nil && !atTopLevel {
+	// A new object with a composite key is only added if no object has that key.  Upserts are
+	// only matched by their xid, so they aren't checked.
+	if compositeFields := typ.CompositeIDFields(); len(compositeFields) > 0 &&
+		(!atTopLevel || topLevelAdd) && !upsert {
+		if err := addCompositeIDCheck(frag, typ, compositeFields, obj, varGen,
+			xidMetadata); err != nil {
+			errFrag := newFragment(nil)
+			errFrag.err = err
+			return &mutationRes{secondPass: []*mutationFragment{errFrag}}
+		}
+	}
+
+	if xid != nil && !atTopLevel && !upsert {
 		if deepXID <= 2 { // elements in firstPass or not
 			// duplicate query in elements >= 2, as the pair firstPass element would already have
 			// the same query.
@@ -1207,6 +1341,7 @@ func rewriteObject(
 		}
 		sort.Strings(fields)
 
+		var uploads []*dgoapi.NQuad
 		for _, field := range fields {
 			val := obj[field]
 			var frags *mutationRes
@@ -1248,7 +1383,34 @@ func rewriteObject(
 							withAdditionalDeletes, val, deepXID, xidMetadata)
 				}
 
+			case *schema.UploadedFile:
+				// The content of a file is set as bytes, rather than in the JSON of the
+				// mutation, so that binary content is stored as is.
+				if withAdditionalDeletes {
+					uploads = append(uploads, &dgoapi.NQuad{
+						Subject:   myUID,
+						Predicate: fieldName,
+						ObjectValue: &dgoapi.Value{
+							Val: &dgoapi.Value_BytesVal{BytesVal: val.Content},
+						},
+					})
+					continue
+				}
+				// Removing a file removes the content of the field, whatever file is given.
+				frags = &mutationRes{secondPass: []*mutationFragment{newFragment(nil)}}
 			case []interface{}:
+				if fieldDef.IsVector() {
+					// A field searched by hnsw is stored as a float32vector, which Dgraph
+					// parses from a string like "[0.1,0.2]" rather than from a list.
+					vec, err := json.Marshal(val)
+					if err != nil {
+						errFrag := newFragment(nil)
+						errFrag.err = err
+						return &mutationRes{secondPass: []*mutationFragment{errFrag}}
+					}
+					frags = &mutationRes{secondPass: []*mutationFragment{newFragment(string(vec))}}
+					break
+				}
 				// This field is either:
 				// 1) A list of objects: e.g. if the schema said `categories: [Categories]`
 				//   Which can be references to existing objects
@@ -1286,10 +1448,13 @@ func rewriteObject(
 			results.secondPass = squashFragments(squashIntoObject(fieldName), results.secondPass,
 				frags.secondPass)
 		}
+		for _, frag := range results.secondPass {
+			frag.uploads = append(frag.uploads, uploads...)
+		}
 	}
 
 	// In the case of an XID, move the secondPass (creation mutation) to firstPass
-	if xid != nil && !atTopLevel {
+	if xid != nil && !atTopLevel && !upsert {
 		results.firstPass = appendFragments(results.firstPass, results.secondPass)
 		results.secondPass = []*mutationFragment{}
 	}
@@ -1320,6 +1485,31 @@ func rewriteObject(
 	return results
 }
 
+// withDefaults returns obj with the defaults for the fields that obj doesn't have a value for.
+// obj itself isn't changed, because the input objects are compared to find duplicate xids.
+func withDefaults(obj, defaults map[string]interface{}) map[string]interface{} {
+	var res map[string]interface{}
+	for field, def := range defaults {
+		if val, ok := obj[field]; ok && val != nil {
+			continue
+		}
+		if res == nil {
+			res = make(map[string]interface{}, len(obj)+len(defaults))
+			for k, v := range obj {
+				res[k] = v
+			}
+		}
+		if gen, ok := def.(schema.DefaultGenerator); ok {
+			def = defaultGenerators[gen]()
+		}
+		res[field] = def
+	}
+	if res == nil {
+		return obj
+	}
+	return res
+}
+
 // if this is a union field, then obj should have only one key which will be a ref
 // to one of the member types. Eg:
 // { "dogRef" : { ... } }
@@ -1851,6 +2041,72 @@ func xidQuery(xidVariable, xidString, xidPredicate string, typ schema.Type) *gql
 	return qry
 }
 
+// addCompositeIDCheck guards the mutation in frag, which adds obj, so that it only runs if
+// there's no object of typ with the same values for the fields of its composite key, and reports
+// an error if there is one.  It's an error for a mutation to add two objects with the same key.
+func addCompositeIDCheck(
+	frag *mutationFragment,
+	typ schema.Type,
+	compositeFields []schema.FieldDefinition,
+	obj map[string]interface{},
+	varGen *VariableGenerator,
+	xidMetadata *xidMetadata) error {
+
+	var eqFuncs []*gql.Function
+	var vals []string
+	for _, fld := range compositeFields {
+		val, ok := obj[fld.Name()]
+		if !ok || val == nil {
+			// There's no complete key, which is reported by the non-null checks.
+			return nil
+		}
+		eqFuncs = append(eqFuncs, &gql.Function{
+			Name: "eq",
+			Args: []gql.Arg{
+				{Value: fld.DgraphPredicate()},
+				{Value: maybeQuoteArg("eq", val)},
+			},
+		})
+		vals = append(vals, fmt.Sprintf("%s: %v", fld.Name(), val))
+	}
+
+	key := fmt.Sprintf("%s(%s)", typ.Name(), strings.Join(vals, ", "))
+	if xidMetadata.compositeKeys[key] {
+		return errors.Errorf("duplicate composite key found: %s", key)
+	}
+	xidMetadata.compositeKeys[key] = true
+
+	variable := varGen.Next(typ, "", "", false)
+	qry := &gql.GraphQuery{
+		Var:      variable,
+		Attr:     variable,
+		Func:     eqFuncs[0],
+		Children: []*gql.GraphQuery{{Attr: "uid"}},
+	}
+	for _, eqFunc := range eqFuncs[1:] {
+		addToFilterTree(qry, &gql.FilterTree{Func: eqFunc})
+	}
+	addTypeFilter(qry, typ)
+
+	frag.queries = append(frag.queries, qry)
+	frag.conditions = append(frag.conditions, fmt.Sprintf("eq(len(%s), 0)", variable))
+
+	// Like for xids, the key is concealed if the user might not be allowed to query the object.
+	var err error
+	if queryAuthSelector(typ) == nil {
+		err = x.GqlErrorf("id %s already exists for type %s", key, typ.Name())
+	} else {
+		// This error will only be reported in debug mode.
+		err = x.GqlErrorf("GraphQL debug: id already exists for type %s", typ.Name())
+	}
+	check := frag.check
+	exists := checkQueryResult(variable, err, nil)
+	frag.check = func(m map[string]interface{}) error {
+		return schema.AppendGQLErrs(check(m), exists(m))
+	}
+	return nil
+}
+
 func rewriteList(
 	ctx context.Context,
 	parentTyp schema.Type,
@@ -2072,6 +2328,7 @@ func squashFragments(
 		for _, r := range right {
 			var conds []string
 			var deletes []interface{}
+			var uploads []*dgoapi.NQuad
 
 			if len(l.conditions) > 0 {
 				conds = make([]string, len(l.conditions), len(l.conditions)+len(r.conditions))
@@ -2083,9 +2340,15 @@ func squashFragments(
 				copy(deletes, l.deletes)
 			}
 
+			if len(l.uploads) > 0 {
+				uploads = make([]*dgoapi.NQuad, len(l.uploads), len(l.uploads)+len(r.uploads))
+				copy(uploads, l.uploads)
+			}
+
 			result = append(result, &mutationFragment{
 				conditions: append(conds, r.conditions...),
 				deletes:    append(deletes, r.deletes...),
+				uploads:    append(uploads, r.uploads...),
 				fragment:   combiner(l.fragment, r.fragment, len(right) > 1),
 				check: func(lcheck, rcheck resultChecker) resultChecker {
 					return func(m map[string]interface{}) error {
@@ -334,3 +334,167 @@ func TestCustomHTTPMutation(t *testing.T) {
 		})
 	}
 }
+
+func TestUploadMutationRewriting(t *testing.T) {
+	gqlSchema := test.LoadSchemaFromFile(t, "schema.graphql")
+
+	tests := map[string]struct {
+		gqlMutation string
+		rewriter    func() MutationRewriter
+		setJSON     string
+		deleteJSON  string
+		subject     string
+		uploads     map[string]string
+	}{
+		"add mutation sets the content of files as bytes": {
+			gqlMutation: `mutation addAttachment($content: Upload!, $preview: Upload) {
+				addAttachment(input: [{name: "a", content: $content, preview: $preview}]) {
+					attachment { name }
+				}
+			}`,
+			rewriter: NewAddRewriter,
+			setJSON: `{ "uid": "_:Attachment2", "dgraph.type": ["Attachment"],
+				"Attachment.name": "a" }`,
+			subject: "_:Attachment2",
+			uploads: map[string]string{
+				"Attachment.content": "file content",
+				"Attachment.preview": "preview content",
+			},
+		},
+		"update mutation sets the content of files as bytes": {
+			gqlMutation: `mutation updateAttachment($content: Upload!) {
+				updateAttachment(input: {filter: {id: ["0x1"]}, set: {content: $content}}) {
+					attachment { name }
+				}
+			}`,
+			rewriter: NewUpdateRewriter,
+			setJSON:  `{ "uid": "uid(x)" }`,
+			subject:  "uid(x)",
+			uploads:  map[string]string{"Attachment.content": "file content"},
+		},
+		"update mutation removes the content of files": {
+			gqlMutation: `mutation updateAttachment($preview: Upload!) {
+				updateAttachment(input: {filter: {id: ["0x1"]}, remove: {preview: $preview}}) {
+					attachment { name }
+				}
+			}`,
+			rewriter:   NewUpdateRewriter,
+			deleteJSON: `{ "uid": "uid(x)", "Attachment.preview": null }`,
+		},
+	}
+
+	for name, tcase := range tests {
+		t.Run(name, func(t *testing.T) {
+			op, err := gqlSchema.Operation(
+				&schema.Request{
+					Query: tcase.gqlMutation,
+					Variables: map[string]interface{}{
+						"content": &schema.UploadedFile{
+							Filename: "content.txt", Content: []byte("file content")},
+						"preview": &schema.UploadedFile{
+							Filename: "preview.txt", Content: []byte("preview content")},
+					},
+				})
+			require.NoError(t, err)
+			mut := test.GetMutation(t, op)
+
+			upserts, err := tcase.rewriter().Rewrite(context.Background(), mut)
+			require.NoError(t, err)
+			mutations := upserts[len(upserts)-1].Mutations
+			require.Len(t, mutations, 1)
+
+			if tcase.setJSON != "" {
+				require.JSONEq(t, tcase.setJSON, string(mutations[0].SetJson))
+			}
+			if tcase.deleteJSON != "" {
+				require.JSONEq(t, tcase.deleteJSON, string(mutations[0].DeleteJson))
+			}
+
+			uploads := make(map[string]string)
+			for _, nq := range mutations[0].Set {
+				require.Equal(t, tcase.subject, nq.Subject)
+				uploads[nq.Predicate] = string(nq.ObjectValue.GetBytesVal())
+			}
+			if tcase.uploads == nil {
+				require.Empty(t, uploads)
+			} else {
+				require.Equal(t, tcase.uploads, uploads)
+			}
+		})
+	}
+}
+
+func TestDefaultMutationRewriting(t *testing.T) {
+	gqlSchema := test.LoadSchemaFromFile(t, "schema.graphql")
+
+	generators := defaultGenerators
+	defaultGenerators = map[schema.DefaultGenerator]func() interface{}{
+		schema.DefaultNow:  func() interface{} { return "2020-07-01T00:00:00Z" },
+		schema.DefaultUUID: func() interface{} { return "a-uuid" },
+	}
+	defer func() { defaultGenerators = generators }()
+
+	tests := map[string]struct {
+		gqlMutation string
+		rewriter    func() MutationRewriter
+		setJSON     []string
+	}{
+		"add mutation sets the defaults of missing fields": {
+			gqlMutation: `mutation {
+				addNote(input: [{text: "a note", stars: 5, labels: [{name: "new"}]}]) {
+					note { text }
+				}
+			}`,
+			rewriter: NewAddRewriter,
+			setJSON: []string{
+				`{ "uid": "_:Label3", "dgraph.type": ["Label"], "Label.code": "a-uuid",
+					"Label.name": "new" }`,
+				`{ "uid": "_:Note1", "dgraph.type": ["Note"], "Note.text": "a note",
+					"Note.kind": "Fact", "Note.stars": 5, "Note.createdAt": "2020-07-01T00:00:00Z",
+					"Note.updatedAt": "2020-07-01T00:00:00Z", "Note.labels": [{ "uid": "uid(Label3)" }] }`,
+			},
+		},
+		"update mutation sets the defaults marked onUpdate": {
+			gqlMutation: `mutation {
+				updateNote(input: {filter: {id: ["0x1"]}, set: {text: "new text"}}) {
+					note { text }
+				}
+			}`,
+			rewriter: NewUpdateRewriter,
+			setJSON: []string{`{ "uid": "uid(x)", "Note.text": "new text",
+				"Note.updatedAt": "2020-07-01T00:00:00Z" }`},
+		},
+		"update mutation that only removes values sets the defaults marked onUpdate": {
+			gqlMutation: `mutation {
+				updateNote(input: {filter: {id: ["0x1"]}, remove: {stars: 5}}) {
+					note { text }
+				}
+			}`,
+			rewriter: NewUpdateRewriter,
+			setJSON:  []string{`{ "uid": "uid(x)", "Note.updatedAt": "2020-07-01T00:00:00Z" }`},
+		},
+	}
+
+	for name, tcase := range tests {
+		t.Run(name, func(t *testing.T) {
+			op, err := gqlSchema.Operation(&schema.Request{Query: tcase.gqlMutation})
+			require.NoError(t, err)
+			mut := test.GetMutation(t, op)
+
+			upserts, err := tcase.rewriter().Rewrite(context.Background(), mut)
+			require.NoError(t, err)
+			var setJSON []string
+			for _, upsert := range upserts {
+				for _, mutation := range upsert.Mutations {
+					if mutation.SetJson != nil {
+						setJSON = append(setJSON, string(mutation.SetJson))
+					}
+				}
+			}
+			require.Len(t, setJSON, len(tcase.setJSON))
+			for i := range setJSON {
+				require.JSONEq(t, tcase.setJSON[i], setJSON[i])
+			}
+		})
+	}
+}
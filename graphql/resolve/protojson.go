@@ -0,0 +1,597 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package resolve
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/gogo/protobuf/protoc-gen-gogo/descriptor"
+	"github.com/pkg/errors"
+)
+
+// protoFiles indexes the messages, enums and services of a set of proto files by their fully
+// qualified names. Names have a leading dot, the same as the type names in field descriptors.
+// It is used to convert between JSON and the protobuf wire format without generated code, using
+// the proto3 JSON mapping for field names and values.
+type protoFiles struct {
+	messages map[string]*descriptor.DescriptorProto
+	enums    map[string]*descriptor.EnumDescriptorProto
+	services map[string]*descriptor.ServiceDescriptorProto
+}
+
+func newProtoFiles(files []*descriptor.FileDescriptorProto) *protoFiles {
+	pf := &protoFiles{
+		messages: make(map[string]*descriptor.DescriptorProto),
+		enums:    make(map[string]*descriptor.EnumDescriptorProto),
+		services: make(map[string]*descriptor.ServiceDescriptorProto),
+	}
+	for _, f := range files {
+		prefix := ""
+		if f.GetPackage() != "" {
+			prefix = "." + f.GetPackage()
+		}
+		pf.addMessages(prefix, f.MessageType)
+		for _, e := range f.EnumType {
+			pf.enums[prefix+"."+e.GetName()] = e
+		}
+		for _, s := range f.Service {
+			pf.services[prefix+"."+s.GetName()] = s
+		}
+	}
+	return pf
+}
+
+func (pf *protoFiles) addMessages(prefix string, msgs []*descriptor.DescriptorProto) {
+	for _, m := range msgs {
+		name := prefix + "." + m.GetName()
+		pf.messages[name] = m
+		for _, e := range m.EnumType {
+			pf.enums[name+"."+e.GetName()] = e
+		}
+		pf.addMessages(name, m.NestedType)
+	}
+}
+
+// method returns the request and response messages of a method given as package.Service/Method.
+func (pf *protoFiles) method(fullMethod string) (*descriptor.DescriptorProto,
+	*descriptor.DescriptorProto, error) {
+	parts := strings.Split(fullMethod, "/")
+	if len(parts) != 2 {
+		return nil, nil, errors.Errorf("invalid gRPC method %s", fullMethod)
+	}
+	svc, ok := pf.services["."+parts[0]]
+	if !ok {
+		return nil, nil, errors.Errorf("gRPC service %s not found in descriptors", parts[0])
+	}
+	for _, m := range svc.Method {
+		if m.GetName() != parts[1] {
+			continue
+		}
+		if m.GetClientStreaming() || m.GetServerStreaming() {
+			return nil, nil, errors.Errorf("gRPC method %s is streaming, only unary methods "+
+				"are supported", fullMethod)
+		}
+		in, ok := pf.messages[m.GetInputType()]
+		if !ok {
+			return nil, nil, errors.Errorf("message %s not found in descriptors",
+				m.GetInputType())
+		}
+		out, ok := pf.messages[m.GetOutputType()]
+		if !ok {
+			return nil, nil, errors.Errorf("message %s not found in descriptors",
+				m.GetOutputType())
+		}
+		return in, out, nil
+	}
+	return nil, nil, errors.Errorf("gRPC method %s not found in descriptors", fullMethod)
+}
+
+// protoJSONName returns the name of the field in the proto3 JSON mapping.
+func protoJSONName(fd *descriptor.FieldDescriptorProto) string {
+	if fd.GetJsonName() != "" {
+		return fd.GetJsonName()
+	}
+	var sb strings.Builder
+	upper := false
+	for _, r := range fd.GetName() {
+		switch {
+		case r == '_':
+			upper = true
+		case upper && r >= 'a' && r <= 'z':
+			sb.WriteRune(r - 'a' + 'A')
+			upper = false
+		default:
+			sb.WriteRune(r)
+			upper = false
+		}
+	}
+	return sb.String()
+}
+
+func (pf *protoFiles) isMap(fd *descriptor.FieldDescriptorProto) bool {
+	if fd.GetType() != descriptor.FieldDescriptorProto_TYPE_MESSAGE ||
+		fd.GetLabel() != descriptor.FieldDescriptorProto_LABEL_REPEATED {
+		return false
+	}
+	m, ok := pf.messages[fd.GetTypeName()]
+	return ok && m.GetOptions().GetMapEntry()
+}
+
+// encodeMessage encodes val, which should be a JSON object decoded with json.Decoder.UseNumber,
+// as the message md.
+func (pf *protoFiles) encodeMessage(md *descriptor.DescriptorProto, val interface{}) ([]byte,
+	error) {
+	obj, ok := val.(map[string]interface{})
+	if !ok {
+		return nil, errors.Errorf("expected an object for message %s, got: %v", md.GetName(), val)
+	}
+
+	buf := proto.NewBuffer(nil)
+	found := 0
+	for _, fd := range md.Field {
+		v, ok := obj[protoJSONName(fd)]
+		if !ok {
+			v, ok = obj[fd.GetName()]
+		}
+		if !ok {
+			continue
+		}
+		found++
+		if v == nil {
+			continue
+		}
+
+		var err error
+		switch {
+		case pf.isMap(fd):
+			err = pf.encodeMap(buf, fd, v)
+		case fd.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED:
+			list, ok := v.([]interface{})
+			if !ok {
+				return nil, errors.Errorf("expected a list for field %s of message %s, got: %v",
+					fd.GetName(), md.GetName(), v)
+			}
+			for _, elem := range list {
+				if err = pf.encodeField(buf, fd, elem); err != nil {
+					break
+				}
+			}
+		default:
+			err = pf.encodeField(buf, fd, v)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "while encoding field %s of message %s", fd.GetName(),
+				md.GetName())
+		}
+	}
+
+	if found != len(obj) {
+		for k := range obj {
+			if protoField(md, k) == nil {
+				return nil, errors.Errorf("message %s has no field %s", md.GetName(), k)
+			}
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func protoField(md *descriptor.DescriptorProto, name string) *descriptor.FieldDescriptorProto {
+	for _, fd := range md.Field {
+		if fd.GetName() == name || protoJSONName(fd) == name {
+			return fd
+		}
+	}
+	return nil
+}
+
+func (pf *protoFiles) encodeMap(buf *proto.Buffer, fd *descriptor.FieldDescriptorProto,
+	v interface{}) error {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("expected an object, got: %v", v)
+	}
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		entry := map[string]interface{}{"key": k, "value": obj[k]}
+		if err := pf.encodeField(buf, fd, entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (pf *protoFiles) encodeField(buf *proto.Buffer, fd *descriptor.FieldDescriptorProto,
+	v interface{}) error {
+	key := uint64(fd.GetNumber()) << 3
+	var err error
+	switch fd.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_DOUBLE:
+		var f float64
+		if f, err = jsonFloat(v); err == nil {
+			_ = buf.EncodeVarint(key | proto.WireFixed64)
+			_ = buf.EncodeFixed64(math.Float64bits(f))
+		}
+	case descriptor.FieldDescriptorProto_TYPE_FLOAT:
+		var f float64
+		if f, err = jsonFloat(v); err == nil {
+			_ = buf.EncodeVarint(key | proto.WireFixed32)
+			_ = buf.EncodeFixed32(uint64(math.Float32bits(float32(f))))
+		}
+	case descriptor.FieldDescriptorProto_TYPE_INT64, descriptor.FieldDescriptorProto_TYPE_INT32:
+		var i int64
+		if i, err = jsonInt(v); err == nil {
+			_ = buf.EncodeVarint(key | proto.WireVarint)
+			_ = buf.EncodeVarint(uint64(i))
+		}
+	case descriptor.FieldDescriptorProto_TYPE_UINT64, descriptor.FieldDescriptorProto_TYPE_UINT32:
+		var u uint64
+		if u, err = jsonUint(v); err == nil {
+			_ = buf.EncodeVarint(key | proto.WireVarint)
+			_ = buf.EncodeVarint(u)
+		}
+	case descriptor.FieldDescriptorProto_TYPE_SINT64, descriptor.FieldDescriptorProto_TYPE_SINT32:
+		var i int64
+		if i, err = jsonInt(v); err == nil {
+			_ = buf.EncodeVarint(key | proto.WireVarint)
+			_ = buf.EncodeZigzag64(uint64(i))
+		}
+	case descriptor.FieldDescriptorProto_TYPE_FIXED64:
+		var u uint64
+		if u, err = jsonUint(v); err == nil {
+			_ = buf.EncodeVarint(key | proto.WireFixed64)
+			_ = buf.EncodeFixed64(u)
+		}
+	case descriptor.FieldDescriptorProto_TYPE_SFIXED64:
+		var i int64
+		if i, err = jsonInt(v); err == nil {
+			_ = buf.EncodeVarint(key | proto.WireFixed64)
+			_ = buf.EncodeFixed64(uint64(i))
+		}
+	case descriptor.FieldDescriptorProto_TYPE_FIXED32:
+		var u uint64
+		if u, err = jsonUint(v); err == nil {
+			_ = buf.EncodeVarint(key | proto.WireFixed32)
+			_ = buf.EncodeFixed32(u)
+		}
+	case descriptor.FieldDescriptorProto_TYPE_SFIXED32:
+		var i int64
+		if i, err = jsonInt(v); err == nil {
+			_ = buf.EncodeVarint(key | proto.WireFixed32)
+			_ = buf.EncodeFixed32(uint64(uint32(i)))
+		}
+	case descriptor.FieldDescriptorProto_TYPE_BOOL:
+		b, ok := v.(bool)
+		if !ok {
+			return errors.Errorf("expected a Boolean, got: %v", v)
+		}
+		_ = buf.EncodeVarint(key | proto.WireVarint)
+		if b {
+			_ = buf.EncodeVarint(1)
+		} else {
+			_ = buf.EncodeVarint(0)
+		}
+	case descriptor.FieldDescriptorProto_TYPE_ENUM:
+		var i int64
+		if i, err = pf.enumNumber(fd, v); err == nil {
+			_ = buf.EncodeVarint(key | proto.WireVarint)
+			_ = buf.EncodeVarint(uint64(i))
+		}
+	case descriptor.FieldDescriptorProto_TYPE_STRING:
+		s, ok := v.(string)
+		if !ok {
+			return errors.Errorf("expected a String, got: %v", v)
+		}
+		_ = buf.EncodeVarint(key | proto.WireBytes)
+		_ = buf.EncodeStringBytes(s)
+	case descriptor.FieldDescriptorProto_TYPE_BYTES:
+		s, ok := v.(string)
+		if !ok {
+			return errors.Errorf("expected a base64 encoded String, got: %v", v)
+		}
+		var b []byte
+		if b, err = base64.StdEncoding.DecodeString(s); err == nil {
+			_ = buf.EncodeVarint(key | proto.WireBytes)
+			_ = buf.EncodeRawBytes(b)
+		}
+	case descriptor.FieldDescriptorProto_TYPE_MESSAGE:
+		md, ok := pf.messages[fd.GetTypeName()]
+		if !ok {
+			return errors.Errorf("message %s not found in descriptors", fd.GetTypeName())
+		}
+		var b []byte
+		if b, err = pf.encodeMessage(md, v); err == nil {
+			_ = buf.EncodeVarint(key | proto.WireBytes)
+			_ = buf.EncodeRawBytes(b)
+		}
+	default:
+		return errors.Errorf("unsupported field type %s", fd.GetType())
+	}
+	return err
+}
+
+func (pf *protoFiles) enumNumber(fd *descriptor.FieldDescriptorProto, v interface{}) (int64,
+	error) {
+	s, ok := v.(string)
+	if !ok {
+		return jsonInt(v)
+	}
+	ed, ok := pf.enums[fd.GetTypeName()]
+	if !ok {
+		return 0, errors.Errorf("enum %s not found in descriptors", fd.GetTypeName())
+	}
+	for _, ev := range ed.Value {
+		if ev.GetName() == s {
+			return int64(ev.GetNumber()), nil
+		}
+	}
+	return 0, errors.Errorf("enum %s has no value %s", ed.GetName(), s)
+}
+
+// jsonInt, jsonUint and jsonFloat accept numbers, and numbers quoted as strings which is how the
+// proto3 JSON mapping represents 64 bit integers.
+func jsonInt(v interface{}) (int64, error) {
+	switch n := v.(type) {
+	case json.Number:
+		return strconv.ParseInt(string(n), 10, 64)
+	case string:
+		return strconv.ParseInt(n, 10, 64)
+	}
+	return 0, errors.Errorf("expected an integer, got: %v", v)
+}
+
+func jsonUint(v interface{}) (uint64, error) {
+	switch n := v.(type) {
+	case json.Number:
+		return strconv.ParseUint(string(n), 10, 64)
+	case string:
+		return strconv.ParseUint(n, 10, 64)
+	}
+	return 0, errors.Errorf("expected an unsigned integer, got: %v", v)
+}
+
+func jsonFloat(v interface{}) (float64, error) {
+	switch n := v.(type) {
+	case json.Number:
+		return n.Float64()
+	case string:
+		return strconv.ParseFloat(n, 64)
+	}
+	return 0, errors.Errorf("expected a number, got: %v", v)
+}
+
+// decodeMessage decodes data as the message md into a value that can be marshalled to JSON.
+// Fields that aren't set get their default value, and unknown fields are skipped.
+func (pf *protoFiles) decodeMessage(md *descriptor.DescriptorProto,
+	data []byte) (map[string]interface{}, error) {
+	obj := make(map[string]interface{}, len(md.Field))
+	for len(data) > 0 {
+		key, n := proto.DecodeVarint(data)
+		if n == 0 {
+			return nil, errors.Errorf("invalid field key in message %s", md.GetName())
+		}
+		data = data[n:]
+		num, wireType := int32(key>>3), int(key&7)
+
+		var fd *descriptor.FieldDescriptorProto
+		for _, f := range md.Field {
+			if f.GetNumber() == num {
+				fd = f
+				break
+			}
+		}
+
+		raw, rest, err := splitWireValue(data, wireType)
+		if err != nil {
+			return nil, errors.Wrapf(err, "while decoding message %s", md.GetName())
+		}
+		data = rest
+		if fd == nil {
+			continue
+		}
+
+		name := protoJSONName(fd)
+		switch {
+		case pf.isMap(fd):
+			entry, err := pf.decodeMessage(pf.messages[fd.GetTypeName()], raw)
+			if err != nil {
+				return nil, err
+			}
+			m, _ := obj[name].(map[string]interface{})
+			if m == nil {
+				m = make(map[string]interface{})
+				obj[name] = m
+			}
+			m[fmt.Sprint(entry["key"])] = entry["value"]
+		case fd.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED:
+			list, _ := obj[name].([]interface{})
+			if wireType == proto.WireBytes && isPackable(fd) {
+				// packed repeated scalars are sent as a single length delimited value.
+				for len(raw) > 0 {
+					var elem []byte
+					if elem, raw, err = splitWireValue(raw, packedWireType(fd)); err != nil {
+						return nil, err
+					}
+					v, err := pf.decodeValue(fd, elem)
+					if err != nil {
+						return nil, err
+					}
+					list = append(list, v)
+				}
+			} else {
+				v, err := pf.decodeValue(fd, raw)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, v)
+			}
+			obj[name] = list
+		default:
+			v, err := pf.decodeValue(fd, raw)
+			if err != nil {
+				return nil, err
+			}
+			obj[name] = v
+		}
+	}
+
+	for _, fd := range md.Field {
+		name := protoJSONName(fd)
+		if _, ok := obj[name]; ok {
+			continue
+		}
+		switch {
+		case pf.isMap(fd):
+			obj[name] = map[string]interface{}{}
+		case fd.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED:
+			obj[name] = []interface{}{}
+		case fd.GetType() == descriptor.FieldDescriptorProto_TYPE_MESSAGE:
+			obj[name] = nil
+		default:
+			v, err := pf.decodeValue(fd, nil)
+			if err != nil {
+				return nil, err
+			}
+			obj[name] = v
+		}
+	}
+	return obj, nil
+}
+
+// splitWireValue splits the value of the given wire type from the start of data. For varints and
+// fixed values the bytes of the value are returned, for length delimited values only the content.
+func splitWireValue(data []byte, wireType int) ([]byte, []byte, error) {
+	switch wireType {
+	case proto.WireVarint:
+		_, n := proto.DecodeVarint(data)
+		if n == 0 {
+			return nil, nil, errors.New("invalid varint")
+		}
+		return data[:n], data[n:], nil
+	case proto.WireFixed64:
+		if len(data) < 8 {
+			return nil, nil, errors.New("unexpected end of fixed64 value")
+		}
+		return data[:8], data[8:], nil
+	case proto.WireFixed32:
+		if len(data) < 4 {
+			return nil, nil, errors.New("unexpected end of fixed32 value")
+		}
+		return data[:4], data[4:], nil
+	case proto.WireBytes:
+		l, n := proto.DecodeVarint(data)
+		if n == 0 || uint64(len(data)-n) < l {
+			return nil, nil, errors.New("unexpected end of length delimited value")
+		}
+		return data[n : n+int(l)], data[n+int(l):], nil
+	}
+	return nil, nil, errors.Errorf("unsupported wire type %d", wireType)
+}
+
+func isPackable(fd *descriptor.FieldDescriptorProto) bool {
+	switch fd.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_STRING, descriptor.FieldDescriptorProto_TYPE_BYTES,
+		descriptor.FieldDescriptorProto_TYPE_MESSAGE, descriptor.FieldDescriptorProto_TYPE_GROUP:
+		return false
+	}
+	return true
+}
+
+func packedWireType(fd *descriptor.FieldDescriptorProto) int {
+	switch fd.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_DOUBLE, descriptor.FieldDescriptorProto_TYPE_FIXED64,
+		descriptor.FieldDescriptorProto_TYPE_SFIXED64:
+		return proto.WireFixed64
+	case descriptor.FieldDescriptorProto_TYPE_FLOAT, descriptor.FieldDescriptorProto_TYPE_FIXED32,
+		descriptor.FieldDescriptorProto_TYPE_SFIXED32:
+		return proto.WireFixed32
+	}
+	return proto.WireVarint
+}
+
+// decodeValue decodes a single value of the field fd from raw, which is nil for the default value.
+func (pf *protoFiles) decodeValue(fd *descriptor.FieldDescriptorProto,
+	raw []byte) (interface{}, error) {
+	var u uint64
+	switch {
+	case raw == nil:
+	case packedWireType(fd) == proto.WireFixed64 && len(raw) == 8:
+		u = binary.LittleEndian.Uint64(raw)
+	case packedWireType(fd) == proto.WireFixed32 && len(raw) == 4:
+		u = uint64(binary.LittleEndian.Uint32(raw))
+	case isPackable(fd):
+		var n int
+		if u, n = proto.DecodeVarint(raw); n != len(raw) {
+			return nil, errors.Errorf("invalid value for field %s", fd.GetName())
+		}
+	}
+
+	switch fd.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_DOUBLE:
+		return math.Float64frombits(u), nil
+	case descriptor.FieldDescriptorProto_TYPE_FLOAT:
+		return float64(math.Float32frombits(uint32(u))), nil
+	case descriptor.FieldDescriptorProto_TYPE_INT64, descriptor.FieldDescriptorProto_TYPE_SFIXED64:
+		return int64(u), nil
+	case descriptor.FieldDescriptorProto_TYPE_INT32:
+		return int64(int32(u)), nil
+	case descriptor.FieldDescriptorProto_TYPE_SFIXED32:
+		return int64(int32(uint32(u))), nil
+	case descriptor.FieldDescriptorProto_TYPE_UINT64, descriptor.FieldDescriptorProto_TYPE_FIXED64:
+		return u, nil
+	case descriptor.FieldDescriptorProto_TYPE_UINT32, descriptor.FieldDescriptorProto_TYPE_FIXED32:
+		return uint64(uint32(u)), nil
+	case descriptor.FieldDescriptorProto_TYPE_SINT64:
+		return int64(u>>1) ^ -int64(u&1), nil
+	case descriptor.FieldDescriptorProto_TYPE_SINT32:
+		return int64(int32(uint32(u)>>1) ^ -int32(u&1)), nil
+	case descriptor.FieldDescriptorProto_TYPE_BOOL:
+		return u != 0, nil
+	case descriptor.FieldDescriptorProto_TYPE_ENUM:
+		if ed, ok := pf.enums[fd.GetTypeName()]; ok {
+			for _, ev := range ed.Value {
+				if int64(ev.GetNumber()) == int64(int32(u)) {
+					return ev.GetName(), nil
+				}
+			}
+		}
+		return int64(int32(u)), nil
+	case descriptor.FieldDescriptorProto_TYPE_STRING:
+		return string(raw), nil
+	case descriptor.FieldDescriptorProto_TYPE_BYTES:
+		return base64.StdEncoding.EncodeToString(raw), nil
+	case descriptor.FieldDescriptorProto_TYPE_MESSAGE:
+		md, ok := pf.messages[fd.GetTypeName()]
+		if !ok {
+			return nil, errors.Errorf("message %s not found in descriptors", fd.GetTypeName())
+		}
+		return pf.decodeMessage(md, raw)
+	}
+	return nil, errors.Errorf("unsupported field type %s for field %s", fd.GetType(),
+		fd.GetName())
+}
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/dgraph-io/dgraph/gql"
 	"github.com/dgraph-io/dgraph/graphql/authorization"
@@ -59,7 +60,7 @@ func NewQueryRewriter() QueryRewriter {
 
 func hasAuthRules(field schema.Field, authRw *authRewriter) bool {
 	rn := authRw.selector(field.Type())
-	if rn != nil {
+	if rn != nil || authRw.implsWithAuthRules(field.Type()) != nil {
 		return true
 	}
 
@@ -89,8 +90,11 @@ func (qr *queryRewriter) Rewrite(
 	ctx context.Context,
 	gqlQuery schema.Query) (*gql.GraphQuery, error) {
 
-	if gqlQuery.Type().InterfaceImplHasAuthRules() {
-		return &gql.GraphQuery{Attr: gqlQuery.ResponseName() + "()"}, nil
+	switch gqlQuery.QueryType() {
+	case schema.ConnectionQuery:
+		return qr.rewriteAsConnectionQuery(ctx, gqlQuery)
+	case schema.GroupByQuery:
+		return qr.rewriteAsGroupByQuery(ctx, gqlQuery)
 	}
 
 	authVariables, _ := ctx.Value(authorization.AuthVariables).(map[string]interface{})
@@ -128,14 +132,20 @@ func (qr *queryRewriter) Rewrite(
 		if err != nil {
 			return nil, err
 		}
+		composite, err := compositeIDFuncs(gqlQuery)
+		if err != nil {
+			return nil, err
+		}
 
-		dgQuery := rewriteAsGet(gqlQuery, uid, xid, authRw)
+		dgQuery := rewriteAsGet(gqlQuery, uid, xid, composite, authRw)
 		return dgQuery, nil
 
 	case schema.FilterQuery:
 		return rewriteAsQuery(gqlQuery, authRw), nil
 	case schema.PasswordQuery:
 		return passwordQuery(gqlQuery, authRw)
+	case schema.EntitiesQuery:
+		return rewriteAsEntitiesQuery(gqlQuery, authRw)
 	default:
 		return nil, errors.Errorf("unimplemented query type %s", gqlQuery.QueryType())
 	}
@@ -147,7 +157,7 @@ func passwordQuery(m schema.Query, authRw *authRewriter) (*gql.GraphQuery, error
 		return nil, err
 	}
 
-	dgQuery := rewriteAsGet(m, uid, xid, authRw)
+	dgQuery := rewriteAsGet(m, uid, xid, nil, authRw)
 
 	queriedType := m.Type()
 	name := queriedType.PasswordField().Name()
@@ -263,13 +273,14 @@ func rewriteAsQueryByIds(field schema.Field, uids []uint64, authRw *authRewriter
 		addUIDFunc(dgQuery, intersection(ids, uids))
 	}
 
-	addArgumentsToField(dgQuery, field)
+	filterQueries := addArgumentsToField(dgQuery, field, authRw.varGen)
 	selectionAuth := addSelectionSetFrom(dgQuery, field, authRw)
 	addUID(dgQuery)
 	addCascadeDirective(dgQuery, field)
 
 	dgQuery = authRw.addAuthQueries(field.Type(), dgQuery, rbac)
 
+	selectionAuth = append(selectionAuth, filterQueries...)
 	if len(selectionAuth) > 0 {
 		dgQuery = &gql.GraphQuery{Children: append([]*gql.GraphQuery{dgQuery}, selectionAuth...)}
 	}
@@ -278,17 +289,21 @@ func rewriteAsQueryByIds(field schema.Field, uids []uint64, authRw *authRewriter
 }
 
 // addArgumentsToField adds various different arguments to a field, such as
-// filter, order and pagination.
-func addArgumentsToField(dgQuery *gql.GraphQuery, field schema.Field) {
+// filter, order and pagination.  It returns the var blocks needed by the filter.
+func addArgumentsToField(dgQuery *gql.GraphQuery, field schema.Field,
+	varGen *VariableGenerator) []*gql.GraphQuery {
 	filter, _ := field.ArgValue("filter").(map[string]interface{})
-	_ = addFilter(dgQuery, field.Type(), filter)
+	filterQueries, _ := addFilter(dgQuery, field.Type(), filter, varGen)
 	addOrder(dgQuery, field)
 	addPagination(dgQuery, field)
+	return filterQueries
 }
 
-func addFilterToField(dgQuery *gql.GraphQuery, field schema.Field) {
+func addFilterToField(dgQuery *gql.GraphQuery, field schema.Field,
+	varGen *VariableGenerator) []*gql.GraphQuery {
 	filter, _ := field.ArgValue("filter").(map[string]interface{})
-	_ = addFilter(dgQuery, field.Type(), filter)
+	filterQueries, _ := addFilter(dgQuery, field.Type(), filter, varGen)
+	return filterQueries
 }
 
 func addTopLevelTypeFilter(query *gql.GraphQuery, field schema.Field) {
@@ -320,6 +335,7 @@ func rewriteAsGet(
 	field schema.Field,
 	uid uint64,
 	xid *string,
+	composite []*gql.Function,
 	auth *authRewriter) *gql.GraphQuery {
 
 	var dgQuery *gql.GraphQuery
@@ -328,7 +344,7 @@ func rewriteAsGet(
 		return &gql.GraphQuery{Attr: field.ResponseName() + "()"}
 	}
 
-	if xid == nil {
+	if xid == nil && len(composite) == 0 {
 		dgQuery = rewriteAsQueryByIds(field, []uint64{uid}, auth)
 
 		// Add the type filter to the top level get query. When the auth has been written into the
@@ -337,32 +353,33 @@ func rewriteAsGet(
 		return dgQuery
 	}
 
-	xidArgName := field.XIDArg()
-	eqXidFunc := &gql.Function{
-		Name: "eq",
-		Args: []gql.Arg{
-			{Value: xidArgName},
-			{Value: maybeQuoteArg("eq", *xid)},
-		},
+	var eqFuncs []*gql.Function
+	if xid != nil {
+		xidArgName := field.XIDArg()
+		eqFuncs = append(eqFuncs, &gql.Function{
+			Name: "eq",
+			Args: []gql.Arg{
+				{Value: xidArgName},
+				{Value: maybeQuoteArg("eq", *xid)},
+			},
+		})
 	}
+	eqFuncs = append(eqFuncs, composite...)
 
+	dgQuery = &gql.GraphQuery{
+		Attr: field.Name(),
+	}
 	if uid > 0 {
-		dgQuery = &gql.GraphQuery{
-			Attr: field.Name(),
-			Func: &gql.Function{
-				Name: "uid",
-				UID:  []uint64{uid},
-			},
-		}
-		dgQuery.Filter = &gql.FilterTree{
-			Func: eqXidFunc,
+		dgQuery.Func = &gql.Function{
+			Name: "uid",
+			UID:  []uint64{uid},
 		}
-
 	} else {
-		dgQuery = &gql.GraphQuery{
-			Attr: field.Name(),
-			Func: eqXidFunc,
-		}
+		dgQuery.Func = eqFuncs[0]
+		eqFuncs = eqFuncs[1:]
+	}
+	for _, eqFunc := range eqFuncs {
+		addToFilterTree(dgQuery, &gql.FilterTree{Func: eqFunc})
 	}
 	selectionAuth := addSelectionSetFrom(dgQuery, field, auth)
 	addUID(dgQuery)
@@ -378,6 +395,35 @@ func rewriteAsGet(
 	return dgQuery
 }
 
+// compositeIDFuncs returns the eq() functions that find the object with the composite key given
+// in the arguments of the get query field.  It's an error to give only some of the fields of the
+// key.
+func compositeIDFuncs(field schema.Field) ([]*gql.Function, error) {
+	var funcs []*gql.Function
+	var missing []string
+	for _, fld := range field.Type().CompositeIDFields() {
+		val := field.ArgValue(fld.Name())
+		if val == nil {
+			missing = append(missing, fld.Name())
+			continue
+		}
+		funcs = append(funcs, &gql.Function{
+			Name: "eq",
+			Args: []gql.Arg{
+				{Value: fld.DgraphPredicate()},
+				{Value: maybeQuoteArg("eq", val)},
+			},
+		})
+	}
+
+	if len(funcs) > 0 && len(missing) > 0 {
+		return nil, x.GqlErrorf("%s needs all the fields of the composite key of %s, but "+
+			"%s wasn't given.", field.Name(), field.Type().Name(), strings.Join(missing, ", ")).
+			WithLocations(field.Location())
+	}
+	return funcs, nil
+}
+
 func rewriteAsQuery(field schema.Field, authRw *authRewriter) *gql.GraphQuery {
 	rbac := authRw.evaluateStaticRules(field.Type())
 	dgQuery := &gql.GraphQuery{
@@ -409,13 +455,14 @@ func rewriteAsQuery(field schema.Field, authRw *authRewriter) *gql.GraphQuery {
 		addTypeFunc(dgQuery, field.Type().DgraphName())
 	}
 
-	addArgumentsToField(dgQuery, field)
+	filterQueries := addArgumentsToField(dgQuery, field, authRw.varGen)
 	selectionAuth := addSelectionSetFrom(dgQuery, field, authRw)
 	addUID(dgQuery)
 	addCascadeDirective(dgQuery, field)
 
 	dgQuery = authRw.addAuthQueries(field.Type(), dgQuery, rbac)
 
+	selectionAuth = append(selectionAuth, filterQueries...)
 	if len(selectionAuth) > 0 {
 		dgQuery = &gql.GraphQuery{Children: append([]*gql.GraphQuery{dgQuery}, selectionAuth...)}
 	}
@@ -523,7 +570,7 @@ func (authRw *authRewriter) rewriteAuthQueries(typ schema.Type) ([]*gql.GraphQue
 		return nil, nil
 	}
 
-	return (&authRewriter{
+	rw := &authRewriter{
 		authVariables: authRw.authVariables,
 		varGen:        authRw.varGen,
 		isWritingAuth: true,
@@ -531,7 +578,65 @@ func (authRw *authRewriter) rewriteAuthQueries(typ schema.Type) ([]*gql.GraphQue
 		selector:      authRw.selector,
 		parentVarName: authRw.parentVarName,
 		hasAuthRules:  authRw.hasAuthRules,
-	}).rewriteRuleNode(typ, authRw.selector(typ))
+	}
+	if impls := authRw.implsWithAuthRules(typ); impls != nil {
+		return rw.rewriteInterfaceAuth(impls)
+	}
+	return rw.rewriteRuleNode(typ, authRw.selector(typ))
+}
+
+// implsWithAuthRules returns the implementations of typ, if typ is an interface and any of its
+// implementations has a rule picked by the selector. The nodes found through such an interface
+// are then authorized by the rules of their own type.
+func (authRw *authRewriter) implsWithAuthRules(typ schema.Type) []schema.Type {
+	impls := typ.Implementations()
+	for _, impl := range impls {
+		if authRw.selector(impl) != nil {
+			return impls
+		}
+	}
+	return nil
+}
+
+// rewriteInterfaceAuth builds a filter that lets through a node of an interface only if it
+// satisfies the rules of its own type, like
+//   (type(Question) AND uid(Question2)) OR type(Comment)
+// for an interface implemented by Question, that has rules, and Comment, that doesn't.
+func (authRw *authRewriter) rewriteInterfaceAuth(
+	impls []schema.Type) ([]*gql.GraphQuery, *gql.FilterTree) {
+
+	var qrys []*gql.GraphQuery
+	var filts []*gql.FilterTree
+	for _, impl := range impls {
+		rn := authRw.selector(impl)
+		typeFilter := &gql.FilterTree{Func: buildTypeFunc(impl.DgraphName())}
+		switch rn.EvaluateStatic(authRw.authVariables) {
+		case schema.Negative:
+			continue
+		case schema.Positive:
+			filts = append(filts, typeFilter)
+			continue
+		}
+
+		q, f := authRw.rewriteRuleNode(impl, rn)
+		qrys = append(qrys, q...)
+		if f == nil {
+			filts = append(filts, typeFilter)
+			continue
+		}
+		filts = append(filts, &gql.FilterTree{
+			Op:    "and",
+			Child: []*gql.FilterTree{typeFilter, f},
+		})
+	}
+
+	if len(filts) == 1 {
+		return qrys, filts[0]
+	}
+	return qrys, &gql.FilterTree{
+		Op:    "or",
+		Child: filts,
+	}
 }
 
 func (authRw *authRewriter) evaluateStaticRules(typ schema.Type) schema.RuleResult {
@@ -539,6 +644,18 @@ func (authRw *authRewriter) evaluateStaticRules(typ schema.Type) schema.RuleResu
 		return schema.Uncertain
 	}
 
+	if impls := authRw.implsWithAuthRules(typ); impls != nil {
+		// A query on the interface is denied only if it would be denied for all of the
+		// implementations, and allowed only if it would be allowed for all of them.
+		result := authRw.selector(impls[0]).EvaluateStatic(authRw.authVariables)
+		for _, impl := range impls[1:] {
+			if authRw.selector(impl).EvaluateStatic(authRw.authVariables) != result {
+				return schema.Uncertain
+			}
+		}
+		return result
+	}
+
 	rn := authRw.selector(typ)
 	return rn.EvaluateStatic(authRw.authVariables)
 }
@@ -728,12 +845,19 @@ func addSelectionSetFrom(
 		} else {
 			child.Attr = f.DgraphPredicate()
 		}
+		if f.Type().Name() == schema.Upload {
+			// The content of an Upload field is read through the /blob endpoint, so the query
+			// only needs to know whether there's any content.
+			child.IsCount = true
+		}
 
 		filter, _ := f.ArgValue("filter").(map[string]interface{})
 		// if this field has been filtered out by the filter, then don't add it in DQL query
-		if includeField := addFilter(child, f.Type(), filter); !includeField {
+		filterQueries, includeField := addFilter(child, f.Type(), filter, auth.varGen)
+		if !includeField {
 			continue
 		}
+		authQueries = append(authQueries, filterQueries...)
 		addOrder(child, f)
 		addPagination(child, f)
 		addCascadeDirective(child, f)
@@ -846,7 +970,7 @@ func addSelectionSetFrom(
 				},
 			}
 
-			addFilterToField(selectionQry, f)
+			_ = addFilterToField(selectionQry, f, auth.varGen)
 			selectionQry.Filter = child.Filter
 			authQueries = append(authQueries, parentQry, selectionQry)
 			child.Filter = &gql.FilterTree{
@@ -892,21 +1016,36 @@ func addSelectionSetFrom(
 func addOrder(q *gql.GraphQuery, field schema.Field) {
 	orderArg := field.ArgValue("order")
 	order, ok := orderArg.(map[string]interface{})
-	for ok {
-		ascArg := order["asc"]
-		descArg := order["desc"]
-		thenArg := order["then"]
-
-		if asc, ok := ascArg.(string); ok {
-			q.Order = append(q.Order,
-				&pb.Order{Attr: field.Type().DgraphPredicate(asc)})
-		} else if desc, ok := descArg.(string); ok {
-			q.Order = append(q.Order,
-				&pb.Order{Attr: field.Type().DgraphPredicate(desc), Desc: true})
+	if !ok {
+		return
+	}
+
+	if !field.Type().IsUnion() {
+		q.Order = append(q.Order, buildOrder(field.Type(), order)...)
+		return
+	}
+
+	// For a union, the order of each member type is applied in the order the members are
+	// declared in the union.  Nodes of the other member types don't have the predicates of a
+	// member type, and so Dgraph puts them after the nodes that do.
+	for _, memberType := range field.Type().UnionMembers(nil) {
+		memberOrder, ok := order[schema.CamelCase(memberType.Name())+"Order"].(map[string]interface{})
+		if ok {
+			q.Order = append(q.Order, buildOrder(memberType, memberOrder)...)
 		}
+	}
+}
 
-		order, ok = thenArg.(map[string]interface{})
+func buildOrder(typ schema.Type, order map[string]interface{}) []*pb.Order {
+	var res []*pb.Order
+	for ok := true; ok; order, ok = order["then"].(map[string]interface{}) {
+		if asc, ok := order["asc"].(string); ok {
+			res = append(res, &pb.Order{Attr: typ.DgraphPredicate(asc)})
+		} else if desc, ok := order["desc"].(string); ok {
+			res = append(res, &pb.Order{Attr: typ.DgraphPredicate(desc), Desc: true})
+		}
 	}
+	return res
 }
 
 func addPagination(q *gql.GraphQuery, field schema.Field) {
@@ -925,6 +1064,7 @@ func addPagination(q *gql.GraphQuery, field schema.Field) {
 
 func addCascadeDirective(q *gql.GraphQuery, field schema.Field) {
 	q.Cascade = field.Cascade()
+	q.CascadeDepth = uint64(field.CascadeDepth())
 }
 
 func convertIDs(idsSlice []interface{}) []uint64 {
@@ -958,12 +1098,14 @@ func idFilter(filter map[string]interface{}, idField schema.FieldDefinition) []u
 	return convertIDs(idsSlice)
 }
 
-// addFilter adds a filter to the input DQL query. It returns false if the field for which the
-// filter was specified should not be included in the DQL query.
+// addFilter adds a filter to the input DQL query and returns the var blocks that the filter
+// needs, if it filters on nested fields. It returns false if the field for which the filter was
+// specified should not be included in the DQL query.
 // Currently, it would only be false for a union field when no memberTypes are queried.
-func addFilter(q *gql.GraphQuery, typ schema.Type, filter map[string]interface{}) bool {
+func addFilter(q *gql.GraphQuery, typ schema.Type, filter map[string]interface{},
+	varGen *VariableGenerator) ([]*gql.GraphQuery, bool) {
 	if len(filter) == 0 {
-		return true
+		return nil, true
 	}
 
 	// There are two cases here.
@@ -984,19 +1126,20 @@ func addFilter(q *gql.GraphQuery, typ schema.Type, filter map[string]interface{}
 		delete(filter, idName)
 	}
 
+	var filterQueries []*gql.GraphQuery
 	if typ.IsUnion() {
-		if filter, includeField := buildUnionFilter(typ, filter); includeField {
-			q.Filter = filter
-		} else {
-			return false
+		var includeField bool
+		q.Filter, filterQueries, includeField = buildUnionFilter(typ, filter, varGen)
+		if !includeField {
+			return nil, false
 		}
 	} else {
-		q.Filter = buildFilter(typ, filter)
+		q.Filter, filterQueries = buildFilter(typ, filter, varGen)
 	}
 	if filterAtRoot {
 		addTypeFilter(q, typ)
 	}
-	return true
+	return filterQueries, true
 }
 
 // buildFilter builds a Dgraph gql.FilterTree from a GraphQL 'filter' arg.
@@ -1020,15 +1163,21 @@ func addFilter(q *gql.GraphQuery, typ schema.Type, filter map[string]interface{}
 //
 // Filters with `or:` and `not:` get translated to Dgraph OR and NOT.
 //
+// Filters on nested fields, like posts: { some: { score: { gt: 10 } } }, can't be expressed
+// as a Dgraph filter on their own, so buildFilter also returns the var blocks that find the
+// matching nodes.  The filter then refers to those with uid(...).
+//
 // TODO: There's cases that don't make much sense like
 // filter: { or: { title: { anyofterms: "GraphQL" } } }
 // ATM those will probably generate junk that might cause a Dgraph error.  And
 // bubble back to the user as a GraphQL error when the query fails. Really,
 // they should fail query validation and never get here.
-func buildFilter(typ schema.Type, filter map[string]interface{}) *gql.FilterTree {
+func buildFilter(typ schema.Type, filter map[string]interface{},
+	varGen *VariableGenerator) (*gql.FilterTree, []*gql.GraphQuery) {
 
 	var ands []*gql.FilterTree
 	var or *gql.FilterTree
+	var filterQueries []*gql.GraphQuery
 	// Get a stable ordering so we generate the same thing each time.
 	var keys []string
 	for key := range filter {
@@ -1053,26 +1202,41 @@ func buildFilter(typ schema.Type, filter map[string]interface{}) *gql.FilterTree
 			//                       we are here ^^
 			// ->
 			// @filter(anyofterms(Post.title, "GraphQL") AND ... )
-			ft := buildFilter(typ, filter[field].(map[string]interface{}))
+			ft, qs := buildFilter(typ, filter[field].(map[string]interface{}), varGen)
 			ands = append(ands, ft)
+			filterQueries = append(filterQueries, qs...)
 		case "or":
 			// title: { anyofterms: "GraphQL" }, or: { ... }
 			//                       we are here ^^
 			// ->
 			// @filter(anyofterms(Post.title, "GraphQL") OR ... )
-			or = buildFilter(typ, filter[field].(map[string]interface{}))
+			var qs []*gql.GraphQuery
+			or, qs = buildFilter(typ, filter[field].(map[string]interface{}), varGen)
+			filterQueries = append(filterQueries, qs...)
 		case "not":
 			// title: { anyofterms: "GraphQL" }, not: { isPublished: true}
 			//                       we are here ^^
 			// ->
 			// @filter(anyofterms(Post.title, "GraphQL") AND NOT eq(Post.isPublished, true))
-			not := buildFilter(typ, filter[field].(map[string]interface{}))
+			not, qs := buildFilter(typ, filter[field].(map[string]interface{}), varGen)
+			filterQueries = append(filterQueries, qs...)
 			ands = append(ands,
 				&gql.FilterTree{
 					Op:    "not",
 					Child: []*gql.FilterTree{not},
 				})
 		default:
+			if fld := nestedFilterField(typ, field); fld != nil {
+				// posts: { some: { score: { gt: 10 } } } -> uid(Author1)
+				ft, qs := buildNestedFilter(typ, fld, filter[field].(map[string]interface{}),
+					varGen)
+				if ft != nil {
+					ands = append(ands, ft)
+				}
+				filterQueries = append(filterQueries, qs...)
+				continue
+			}
+
 			//// It's a base case like:
 			//// title: { anyofterms: "GraphQL" } ->  anyofterms(Post.title: "GraphQL")
 			//// numLikes: { between : { min : 10,  max:100 }}
@@ -1135,12 +1299,8 @@ func buildFilter(typ schema.Type, filter map[string]interface{}) *gql.FilterTree
 						buildPoint(point, &buf)
 					}
 					args = append(args, gql.Arg{Value: buf.String()})
-					// TODO: for both contains and intersects, we should use @oneOf in the inbuilt
-					// schema. Once we have variable validation hook available in gqlparser, we can
-					// do this. So, if either both the children are given or none of them is given,
-					// we should get an error at parser level itself. Right now, if both "polygon"
-					// and "point" are given, we only use polygon. If none of them are given,
-					// an incorrect DQL query will be formed and will error out from Dgraph.
+					// Exactly one of "polygon" and "point" is given, as that's checked with the
+					// variables while validating the request.
 				case "intersects":
 					// For Geo type we have `intersects` filter which is either multi-polygon or polygon and is written as follows:
 					// For polygon: { intersect: { polygon: { coordinates: [ { points: [{ latitude: 11.11, longitude: 22.22}, { latitude: 15.15, longitude: 16.16} , { latitude: 20.20, longitude: 21.21} ]}] } } }
@@ -1153,6 +1313,34 @@ func buildFilter(typ schema.Type, filter map[string]interface{}) *gql.FilterTree
 						buildMultiPolygon(multiPolygon, &buf)
 					}
 					args = append(args, gql.Arg{Value: buf.String()})
+				case "similarTo":
+					// embedding: { similarTo: { topK: 3, vector: [0.1, 0.2] } }
+					//   -> similar_to(Product.embedding, 3, [0.1,0.2])
+					similarTo := val.(map[string]interface{})
+					vector, _ := similarTo["vector"].([]interface{})
+					var buf bytes.Buffer
+					x.Check2(buf.WriteString("["))
+					for i, v := range vector {
+						if i > 0 {
+							x.Check2(buf.WriteString(","))
+						}
+						x.Check2(buf.WriteString(fmt.Sprintf("%v", v)))
+					}
+					x.Check2(buf.WriteString("]"))
+					fn = "similar_to"
+					args = append(args, gql.Arg{Value: fmt.Sprintf("%v", similarTo["topK"])},
+						gql.Arg{Value: buf.String()})
+				case "anyofngrams", "allofngrams":
+					// name: { anyofngrams: "graph" } -> anyof(Author.name, ngram, "graph")
+					fn = strings.TrimSuffix(fn, "ngrams")
+					args = append(args, gql.Arg{Value: "ngram"},
+						gql.Arg{Value: maybeQuoteArg(fn, val)})
+				case "anyofsoundex", "allofsoundex", "anyofmetaphone", "allofmetaphone":
+					// name: { anyofsoundex: "Jon" } -> anyof(Author.name, soundex, "Jon")
+					index := strings.TrimPrefix(strings.TrimPrefix(fn, "anyof"), "allof")
+					fn = strings.TrimSuffix(fn, index)
+					args = append(args, gql.Arg{Value: index},
+						gql.Arg{Value: maybeQuoteArg(fn, val)})
 				default:
 					args = append(args, gql.Arg{Value: maybeQuoteArg(fn, val)})
 				}
@@ -1216,13 +1404,105 @@ func buildFilter(typ schema.Type, filter map[string]interface{}) *gql.FilterTree
 	}
 
 	if or == nil {
-		return andFt
+		return andFt, filterQueries
 	}
 
 	return &gql.FilterTree{
 		Op:    "or",
 		Child: []*gql.FilterTree{andFt, or},
+	}, filterQueries
+}
+
+// nestedFilterField returns the definition of field in typ if it's an edge to nodes that can be
+// filtered on, or nil if field is a scalar, geo or union field.
+func nestedFilterField(typ schema.Type, field string) schema.FieldDefinition {
+	if field == "has" {
+		return nil
+	}
+	fld := typ.Field(field)
+	if fld.Type().IsGeo() || fld.Type().IsUnion() || len(fld.Type().Fields()) == 0 {
+		return nil
+	}
+	return fld
+}
+
+// buildNestedFilter builds the filter for a field fld of typ that is an edge to other nodes.
+// Dgraph can't filter a node by the predicates of the nodes it links to, so the nodes of typ
+// that have a child matching the filter are found with a var block like
+//   Author1 as var(func: type(Author)) @cascade {
+//     posts : Author.posts @filter(gt(Post.score, 10))
+//   }
+// and the filter becomes uid(Author1).  For list fields, some uses that var directly, none
+// negates it and every negates the var of the nodes that have a child not matching the filter.
+func buildNestedFilter(typ schema.Type, fld schema.FieldDefinition, filter map[string]interface{},
+	varGen *VariableGenerator) (*gql.FilterTree, []*gql.GraphQuery) {
+	if fld.Type().ListType() == nil {
+		return buildChildFilter(typ, fld, filter, false, varGen)
 	}
+
+	var ands []*gql.FilterTree
+	var filterQueries []*gql.GraphQuery
+	// Go over some, every and none in a fixed order so we generate the same thing each time.
+	for _, quantifier := range []string{"every", "none", "some"} {
+		childFilter, ok := filter[quantifier].(map[string]interface{})
+		if !ok || (quantifier == "every" && len(childFilter) == 0) {
+			// every: {} holds for all the nodes, so there is nothing to filter on.
+			continue
+		}
+
+		ft, qs := buildChildFilter(typ, fld, childFilter, quantifier == "every", varGen)
+		if quantifier != "some" {
+			ft = &gql.FilterTree{
+				Op:    "not",
+				Child: []*gql.FilterTree{ft},
+			}
+		}
+		ands = append(ands, ft)
+		filterQueries = append(filterQueries, qs...)
+	}
+
+	switch len(ands) {
+	case 0:
+		return nil, filterQueries
+	case 1:
+		return ands[0], filterQueries
+	default:
+		return &gql.FilterTree{Op: "and", Child: ands}, filterQueries
+	}
+}
+
+// buildChildFilter returns uid(<var>) along with the var blocks that assign to <var> the nodes of
+// typ that have a child through fld which matches filter, or which doesn't match it if negate
+// is true.  An empty filter matches the nodes that have any child through fld.
+func buildChildFilter(typ schema.Type, fld schema.FieldDefinition, filter map[string]interface{},
+	negate bool, varGen *VariableGenerator) (*gql.FilterTree, []*gql.GraphQuery) {
+	childFt, filterQueries := buildFilter(fld.Type(), filter, varGen)
+	if negate && childFt != nil {
+		childFt = &gql.FilterTree{
+			Op:    "not",
+			Child: []*gql.FilterTree{childFt},
+		}
+	}
+
+	varName := varGen.Next(typ, "", "", false)
+	filterQueries = append(filterQueries, &gql.GraphQuery{
+		Var:     varName,
+		Attr:    "var",
+		Func:    buildTypeFunc(typ.DgraphName()),
+		Cascade: []string{"__all__"},
+		Children: []*gql.GraphQuery{{
+			Alias:  fld.Name(),
+			Attr:   fld.DgraphPredicate(),
+			Filter: childFt,
+		}},
+	})
+
+	return &gql.FilterTree{
+		Func: &gql.Function{
+			Name: "uid",
+			Args: []gql.Arg{{Value: varName}},
+		},
+	}, filterQueries
 }
 
 func buildPoint(point map[string]interface{}, buf *bytes.Buffer) {
@@ -1268,17 +1548,19 @@ func buildMultiPolygon(multipolygon map[string]interface{}, buf *bytes.Buffer) {
 	x.Check2(buf.WriteString("]"))
 }
 
-func buildUnionFilter(typ schema.Type, filter map[string]interface{}) (*gql.FilterTree, bool) {
+func buildUnionFilter(typ schema.Type, filter map[string]interface{},
+	varGen *VariableGenerator) (*gql.FilterTree, []*gql.GraphQuery, bool) {
 	memberTypesList, ok := filter["memberTypes"].([]interface{})
 	// if memberTypes was specified to be an empty list like: { memberTypes: [], ...},
 	// then we don't need to include the field, on which the filter was specified, in the query.
 	if ok && len(memberTypesList) == 0 {
-		return nil, false
+		return nil, nil, false
 	}
 
 	ft := &gql.FilterTree{
 		Op: "or",
 	}
+	var filterQueries []*gql.GraphQuery
 
 	// now iterate over the filtered member types for this union and build FilterTree for them
 	for _, memberType := range typ.UnionMembers(memberTypesList) {
@@ -1291,19 +1573,21 @@ func buildUnionFilter(typ schema.Type, filter map[string]interface{}) (*gql.Filt
 			memberTypeFt = &gql.FilterTree{Func: buildTypeFunc(memberType.DgraphName())}
 		} else {
 			// else we need to query only the nodes which match the filter for that member type
+			memberFt, qs := buildFilter(memberType, memberTypeFilter, varGen)
 			memberTypeFt = &gql.FilterTree{
 				Op: "and",
 				Child: []*gql.FilterTree{
 					{Func: buildTypeFunc(memberType.DgraphName())},
-					buildFilter(memberType, memberTypeFilter),
+					memberFt,
 				},
 			}
+			filterQueries = append(filterQueries, qs...)
 		}
 		ft.Child = append(ft.Child, memberTypeFt)
 	}
 
 	// return true because we want to include the field with filter in query
-	return ft, true
+	return ft, filterQueries, true
 }
 
 func maybeQuoteArg(fn string, arg interface{}) string {
@@ -163,3 +163,18 @@ func TestCustomHTTPQuery(t *testing.T) {
 		})
 	}
 }
+
+func TestGetQueryNeedsWholeCompositeKey(t *testing.T) {
+	gqlSchema := test.LoadSchemaFromFile(t, "schema.graphql")
+
+	op, err := gqlSchema.Operation(&schema.Request{
+		Query: `query { getMember(id: "0x1", tenant: "dgraph") { name } }`,
+	})
+	require.NoError(t, err)
+	gqlQuery := test.GetQuery(t, op)
+
+	_, err = NewQueryRewriter().Rewrite(context.Background(), gqlQuery)
+	require.EqualError(t, err,
+		"getMember needs all the fields of the composite key of Member, but email wasn't given. "+
+			"(Locations: [{Line: 1, Column: 9}])")
+}
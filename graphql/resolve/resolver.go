@@ -25,6 +25,7 @@ import (
 	"io/ioutil"
 	"math"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"sync"
@@ -211,20 +212,22 @@ func NewAdminExecutor() DgraphExecutor {
 func (aex *adminExecutor) Execute(ctx context.Context, req *dgoapi.Request) (
 	*dgoapi.Response, error) {
 	ctx = context.WithValue(ctx, edgraph.Authorize, false)
-	return aex.dg.Execute(ctx, req)
+	resp, err := aex.dg.Execute(ctx, req)
+	return resp, schema.AsExecutionError(err)
 }
 
 func (aex *adminExecutor) CommitOrAbort(ctx context.Context, tc *dgoapi.TxnContext) error {
-	return aex.dg.CommitOrAbort(ctx, tc)
+	return schema.AsExecutionError(aex.dg.CommitOrAbort(ctx, tc))
 }
 
 func (de *dgraphExecutor) Execute(ctx context.Context, req *dgoapi.Request) (
 	*dgoapi.Response, error) {
-	return de.dg.Execute(ctx, req)
+	resp, err := de.dg.Execute(ctx, req)
+	return resp, schema.AsExecutionError(err)
 }
 
 func (de *dgraphExecutor) CommitOrAbort(ctx context.Context, tc *dgoapi.TxnContext) error {
-	return de.dg.CommitOrAbort(ctx, tc)
+	return schema.AsExecutionError(de.dg.CommitOrAbort(ctx, tc))
 }
 
 func (rf *resolverFactory) WithQueryResolver(
@@ -260,12 +263,26 @@ func (rf *resolverFactory) WithConventionResolvers(
 
 	queries := append(s.Queries(schema.GetQuery), s.Queries(schema.FilterQuery)...)
 	queries = append(queries, s.Queries(schema.PasswordQuery)...)
+	queries = append(queries, s.Queries(schema.ConnectionQuery)...)
+	queries = append(queries, s.Queries(schema.GroupByQuery)...)
 	for _, q := range queries {
 		rf.WithQueryResolver(q, func(q schema.Query) QueryResolver {
 			return NewQueryResolver(fns.Qrw, fns.Ex, StdQueryCompletion())
 		})
 	}
 
+	for _, q := range s.Queries(schema.EntitiesQuery) {
+		rf.WithQueryResolver(q, func(q schema.Query) QueryResolver {
+			return NewQueryResolver(fns.Qrw, fns.Ex, entitiesCompletion())
+		})
+	}
+
+	for _, q := range s.Queries(schema.ServiceQuery) {
+		rf.WithQueryResolver(q, func(q schema.Query) QueryResolver {
+			return QueryResolverFunc(resolveApolloService)
+		})
+	}
+
 	for _, q := range s.Queries(schema.HTTPQuery) {
 		rf.WithQueryResolver(q, func(q schema.Query) QueryResolver {
 			return NewHTTPQueryResolver(&http.Client{
@@ -425,7 +442,7 @@ func (r *RequestResolver) Resolve(ctx context.Context, gqlReq *schema.Request) *
 
 	op, err := r.schema.Operation(gqlReq)
 	if err != nil {
-		return schema.ErrorResponse(err)
+		return schema.ErrorResponse(schema.WithErrorCode(err, schema.ErrCodeValidationFailed))
 	}
 
 	if glog.V(3) {
@@ -543,6 +560,20 @@ func (r *RequestResolver) ValidateSubscription(req *schema.Request) error {
 	return nil
 }
 
+// IsSubscription reports whether the given request is a subscription operation.
+func (r *RequestResolver) IsSubscription(req *schema.Request) (bool, error) {
+	if r.schema == nil {
+		glog.Errorf("Call to IsSubscription with no schema")
+		return false, errors.New(errInternal)
+	}
+
+	op, err := r.schema.Operation(req)
+	if err != nil {
+		return false, err
+	}
+	return op.IsSubscription(), nil
+}
+
 // validateCustomFieldsRecursively will return err if the given field is custom or any of its
 // children is type of a custom field.
 func validateCustomFieldsRecursively(field schema.Field) error {
@@ -710,6 +741,18 @@ func completeDgraphResult(
 			schema.GQLWrapLocationf(err, field.Location(), "couldn't unmarshal Dgraph result"))
 	}
 
+	if q, ok := field.(schema.Query); ok {
+		switch q.QueryType() {
+		case schema.ConnectionQuery:
+			// Dgraph returns the nodes of a connection as a list, which needs to be turned into
+			// the edges and page info of the connection.
+			valToComplete[field.DgraphAlias()] = completeConnection(q,
+				valToComplete[field.DgraphAlias()])
+		case schema.GroupByQuery:
+			valToComplete[field.DgraphAlias()] = completeGroupBy(valToComplete[field.DgraphAlias()])
+		}
+	}
+
 	switch val := valToComplete[field.DgraphAlias()].(type) {
 	case []interface{}:
 		if field.Type().ListType() == nil {
@@ -894,7 +937,7 @@ func resolveCustomField(ctx context.Context, f schema.Field, vals []interface{},
 			return
 		}
 
-		b, status, err := makeRequest(nil, fconf.Method, fconf.URL, string(b), fconf.ForwardHeaders)
+		b, status, err := makeCustomRequest(ctx, nil, fconf, fconf.URL, string(b))
 		if err != nil {
 			errCh <- x.GqlErrorList{externalRequestError(err, f)}
 			return
@@ -982,7 +1025,7 @@ func resolveCustomField(ctx context.Context, f schema.Field, vals []interface{},
 			}
 
 			url := fconf.URL
-			if !graphql {
+			if !graphql && fconf.GRPC == nil {
 				// For REST requests, we'll have to substitute the variables used in the URL.
 				mu.RLock()
 				url, err = schema.SubstituteVarsInURL(url,
@@ -999,7 +1042,7 @@ func resolveCustomField(ctx context.Context, f schema.Field, vals []interface{},
 				mu.RUnlock()
 			}
 
-			b, status, err := makeRequest(nil, fconf.Method, url, string(b), fconf.ForwardHeaders)
+			b, status, err := makeCustomRequest(ctx, nil, fconf, url, string(b))
 			if err != nil {
 				errChan <- x.GqlErrorList{externalRequestError(err, f)}
 				return
@@ -1378,6 +1421,9 @@ func completeObject(
 				}}
 			}
 		}
+		if f.Type().Name() == schema.Upload {
+			val = uploadReference(f, fields, res, val)
+		}
 		completed, err := completeValue(append(path, f.ResponseName()), f, val)
 		errs = append(errs, err...)
 		if completed == nil {
@@ -1394,6 +1440,42 @@ func completeObject(
 	return buf.Bytes(), errs
 }
 
+// uploadReference returns the reference to the content of the Upload field f of res.  Queries
+// only fetch the count of values of Upload fields, so count is either 0 or 1.  The reference is
+// the path of the /blob endpoint that reads the content, or nil if the field has no content.
+func uploadReference(
+	f schema.Field,
+	fields []schema.Field,
+	res map[string]interface{},
+	count interface{}) interface{} {
+
+	switch count.(type) {
+	case json.Number, float64, int:
+		if fmt.Sprint(count) == "0" {
+			return nil
+		}
+	default:
+		// not a count, e.g. the value of a field of a remote type
+		return count
+	}
+
+	uid, ok := res["dgraph.uid"].(string)
+	if !ok {
+		for _, fld := range fields {
+			if fld.Type().Name() == schema.IDType {
+				uid, ok = res[fld.DgraphAlias()].(string)
+				break
+			}
+		}
+	}
+	if !ok {
+		return nil
+	}
+
+	pred := strings.TrimSuffix(strings.TrimPrefix(f.DgraphPredicate(), "<"), ">")
+	return fmt.Sprintf("/blob?uid=%s&predicate=%s", url.QueryEscape(uid), url.QueryEscape(pred))
+}
+
 // completeValue applies the value completion algorithm to a single value, which
 // could turn out to be a list or object or scalar value.
 func completeValue(
@@ -1404,7 +1486,8 @@ func completeValue(
 	switch val := val.(type) {
 	case map[string]interface{}:
 		switch field.Type().Name() {
-		case "String", "ID", "Boolean", "Float", "Int", "Int64", "DateTime":
+		case "String", "ID", "Boolean", "Float", "Int", "Int64", "DateTime", schema.BigInt,
+			schema.Decimal, schema.URL, schema.Duration, schema.Upload:
 			return nil, x.GqlErrorList{&x.GqlError{
 				Message:   errExpectedScalar,
 				Locations: []x.Location{field.Location()},
@@ -1780,6 +1863,22 @@ func coerceScalar(val interface{}, field schema.Field, path []interface{}) (inte
 		default:
 			return nil, valueCoercionError(v)
 		}
+	case schema.Upload:
+		// the reference to the content, from uploadReference
+		if _, ok := val.(string); !ok {
+			return nil, valueCoercionError(val)
+		}
+	case schema.BigInt, schema.Decimal, schema.URL, schema.Duration:
+		switch v := val.(type) {
+		case string:
+		case json.Number:
+			val = v.String()
+		default:
+			return nil, valueCoercionError(v)
+		}
+		if _, err := schema.CoerceCustomScalar(field.Type().Name(), val.(string)); err != nil {
+			return nil, valueCoercionError(val)
+		}
 	case "DateTime":
 		switch v := val.(type) {
 		case string:
@@ -1863,8 +1962,14 @@ func completeList(
 
 	x.Check2(buf.WriteRune('['))
 	for i, b := range values {
-		r, err := completeValue(append(path, i), field, b)
-		errs = append(errs, err...)
+		var r []byte
+		if b != nil || !field.Type().ListType().Nullable() {
+			// completeValue() would turn a null item into [] because the field is a list,
+			// but a null item in a list of nullable items is just null.
+			var err x.GqlErrorList
+			r, err = completeValue(append(path, i), field, b)
+			errs = append(errs, err...)
+		}
 		x.Check2(buf.WriteString(comma))
 		if r == nil {
 			if !field.Type().ListType().Nullable() {
@@ -2047,7 +2152,7 @@ func (hr *httpResolver) rewriteAndExecute(ctx context.Context, field schema.Fiel
 		body = string(b)
 	}
 
-	b, status, err := makeRequest(hr.Client, hrc.Method, hrc.URL, body, hrc.ForwardHeaders)
+	b, status, err := makeCustomRequest(ctx, hr.Client, hrc, hrc.URL, body)
 	if err != nil {
 		return emptyResult(externalRequestError(err, field))
 	}
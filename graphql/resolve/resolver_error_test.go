@@ -403,7 +403,8 @@ func TestSubscriptionErrorWhenNoneDefined(t *testing.T) {
 	gqlSchema := test.LoadSchemaFromString(t, testGQLSchema)
 	resp := resolveWithClient(gqlSchema, `subscription { foo }`, nil, nil)
 	test.RequireJSONEq(t, x.GqlErrorList{{Message: "Not resolving subscription because schema" +
-		" doesn't have any fields defined for subscription operation."}}, resp.Errors)
+		" doesn't have any fields defined for subscription operation.",
+		Extensions: map[string]interface{}{"code": schema.ErrCodeValidationFailed}}}, resp.Errors)
 }
 
 func resolve(gqlSchema schema.Schema, gqlQuery string, dgResponse string) *schema.Response {
@@ -350,6 +350,33 @@ func TestQueryAlias(t *testing.T) {
 	}
 }
 
+func TestUploadReference(t *testing.T) {
+	tests := []QueryCase{
+		{Name: "reference to the content of a file",
+			GQLQuery: `query { getAttachment(id: "0x1") { name content preview } }`,
+			Response: `{ "getAttachment": [ { "name": "a", "content": 1, "preview": 0,
+				"dgraph.uid": "0x1" } ] }`,
+			Expected: `{"getAttachment": {"name": "a",
+				"content": "/blob?uid=0x1&predicate=Attachment.content", "preview": null}}`},
+		{Name: "reference with the uid from the id field",
+			GQLQuery: `query { getAttachment(id: "0x1") { id content } }`,
+			Response: `{ "getAttachment": [ { "id": "0x1", "content": 1 } ] }`,
+			Expected: `{"getAttachment": {"id": "0x1",
+				"content": "/blob?uid=0x1&predicate=Attachment.content"}}`},
+	}
+
+	gqlSchema := test.LoadSchemaFromFile(t, "schema.graphql")
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			resp := resolve(gqlSchema, test.GQLQuery, test.Response)
+
+			require.Nil(t, resp.Errors)
+			require.JSONEq(t, test.Expected, resp.Data.String())
+		})
+	}
+}
+
 func TestMutationAlias(t *testing.T) {
 
 	tests := map[string]struct {
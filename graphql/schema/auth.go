@@ -28,6 +28,9 @@ import (
 
 const (
 	RBACQueryPrefix = "{"
+
+	authInheritArg = "inherit"
+	authOverride   = "OVERRIDE"
 )
 
 type RBACQuery struct {
@@ -154,6 +157,10 @@ func authRules(s *ast.Schema) (map[string]*TypeAuth, error) {
 		if auth != nil {
 			authRules[name].Rules, err = parseAuthDirective(s, typ, auth)
 			errResult = AppendGQLErrs(errResult, err)
+			if typ.Kind == ast.Interface && auth.Arguments.ForName(authInheritArg) != nil {
+				errResult = AppendGQLErrs(errResult, gqlerror.Errorf("Type %s: @auth: inherit "+
+					"can only be used on types that implement interfaces.", typ.Name))
+			}
 		}
 
 		for _, field := range typ.Fields {
@@ -165,9 +172,77 @@ func authRules(s *ast.Schema) (map[string]*TypeAuth, error) {
 		}
 	}
 
+	// An object type inherits the rules of the interfaces it implements. For each operation, the
+	// rules of the interfaces and of the type itself all have to be satisfied, unless the type
+	// uses @auth(inherit: OVERRIDE), in which case its own rule replaces the inherited ones.
+	for _, typ := range s.Types {
+		if typ.Kind != ast.Object || len(typ.Interfaces) == 0 {
+			continue
+		}
+
+		var inherited []*AuthContainer
+		for _, intr := range typ.Interfaces {
+			if rules := authRules[typeName(s.Types[intr])].Rules; rules != nil {
+				inherited = append(inherited, rules)
+			}
+		}
+		if len(inherited) == 0 {
+			continue
+		}
+
+		override := false
+		if auth := typ.Directives.ForName(authDirective); auth != nil {
+			if inherit := auth.Arguments.ForName(authInheritArg); inherit != nil &&
+				inherit.Value != nil {
+				override = inherit.Value.Raw == authOverride
+			}
+		}
+		name := typeName(typ)
+		authRules[name].Rules = inheritAuthRules(authRules[name].Rules, inherited, override)
+	}
+
 	return authRules, errResult
 }
 
+func inheritAuthRules(own *AuthContainer, inherited []*AuthContainer,
+	override bool) *AuthContainer {
+	if own == nil {
+		own = &AuthContainer{}
+	}
+
+	inherit := func(ownRule *RuleNode, rule func(*AuthContainer) *RuleNode) *RuleNode {
+		if ownRule != nil && override {
+			return ownRule
+		}
+
+		var rules []*RuleNode
+		for _, container := range inherited {
+			if r := rule(container); r != nil {
+				rules = append(rules, r)
+			}
+		}
+		if ownRule != nil {
+			rules = append(rules, ownRule)
+		}
+
+		switch len(rules) {
+		case 0:
+			return nil
+		case 1:
+			return rules[0]
+		default:
+			return &RuleNode{And: rules}
+		}
+	}
+
+	return &AuthContainer{
+		Query:  inherit(own.Query, func(c *AuthContainer) *RuleNode { return c.Query }),
+		Add:    inherit(own.Add, func(c *AuthContainer) *RuleNode { return c.Add }),
+		Update: inherit(own.Update, func(c *AuthContainer) *RuleNode { return c.Update }),
+		Delete: inherit(own.Delete, func(c *AuthContainer) *RuleNode { return c.Delete }),
+	}
+}
+
 func parseAuthDirective(
 	s *ast.Schema,
 	typ *ast.Definition,
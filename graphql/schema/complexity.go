@@ -0,0 +1,141 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package schema
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/vektah/gqlparser/v2/ast"
+
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// ErrComplexityLimitExceeded is the code, in the extensions of the error, for a request that is
+// more complex than the complexity limit.
+const ErrComplexityLimitExceeded = "COMPLEXITY_LIMIT_EXCEEDED"
+
+// checkComplexity returns an error if the complexity of op is more than the complexity limit of
+// this Alpha. The complexity of a field is its weight, 1 unless set with @complexity, plus the
+// complexity of its selection set. For a list field, the complexity of the selection set is
+// multiplied by its `first` argument or, if that isn't given, by the multiplier from @complexity
+// or by the default list multiplier.
+func checkComplexity(op *ast.OperationDefinition, vars map[string]interface{}) error {
+	limit := x.Config.GraphqlMaxComplexity
+	if limit <= 0 {
+		return nil
+	}
+
+	complexity := selectionSetComplexity(op.SelectionSet, vars)
+	if complexity <= limit {
+		return nil
+	}
+
+	gqlErr := &x.GqlError{
+		Message: fmt.Sprintf("The complexity of the request is %d, which is more than the "+
+			"maximum allowed complexity of %d.", complexity, limit),
+		Extensions: map[string]interface{}{
+			"code":          ErrComplexityLimitExceeded,
+			"complexity":    complexity,
+			"maxComplexity": limit,
+		},
+	}
+	if op.Position != nil {
+		gqlErr.Locations = []x.Location{{Line: op.Position.Line, Column: op.Position.Column}}
+	}
+	return gqlErr
+}
+
+func selectionSetComplexity(selSet ast.SelectionSet, vars map[string]interface{}) int64 {
+	var complexity int64
+	for _, sel := range selSet {
+		switch s := sel.(type) {
+		case *ast.Field:
+			complexity = addComplexity(complexity, fieldComplexity(s, vars))
+		case *ast.InlineFragment:
+			complexity = addComplexity(complexity, selectionSetComplexity(s.SelectionSet, vars))
+		case *ast.FragmentSpread:
+			if s.Definition != nil {
+				complexity = addComplexity(complexity,
+					selectionSetComplexity(s.Definition.SelectionSet, vars))
+			}
+		}
+	}
+	return complexity
+}
+
+func fieldComplexity(f *ast.Field, vars map[string]interface{}) int64 {
+	weight, multiplier := int64(1), int64(1)
+	if f.Definition == nil {
+		return addComplexity(weight, selectionSetComplexity(f.SelectionSet, vars))
+	}
+
+	if f.Definition.Type.Elem != nil {
+		multiplier = x.Config.GraphqlComplexityListMultiplier
+	}
+	if dir := f.Definition.Directives.ForName(complexityDirective); dir != nil {
+		if v, ok := intDirectiveArg(dir, complexityValueArg); ok {
+			weight = v
+		}
+		if v, ok := intDirectiveArg(dir, complexityMultArg); ok {
+			multiplier = v
+		}
+	}
+	if arg := f.Arguments.ForName("first"); arg != nil {
+		if v, err := arg.Value.Value(vars); err == nil && v != nil {
+			if first, err := strconv.ParseInt(fmt.Sprintf("%v", v), 10, 64); err == nil {
+				// A negative first gives the last nodes, so it bounds the list just the same.
+				if first < 0 {
+					first = -first
+				}
+				multiplier = first
+			}
+		}
+	}
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	return addComplexity(weight, mulComplexity(multiplier,
+		selectionSetComplexity(f.SelectionSet, vars)))
+}
+
+func intDirectiveArg(dir *ast.Directive, name string) (int64, bool) {
+	arg := dir.Arguments.ForName(name)
+	if arg == nil || arg.Value == nil || arg.Value.Kind != ast.IntValue {
+		return 0, false
+	}
+	v, err := strconv.ParseInt(arg.Value.Raw, 10, 64)
+	return v, err == nil
+}
+
+// addComplexity and mulComplexity saturate at math.MaxInt64, so that a huge `first` can't wrap
+// the complexity of a request around to a small number.
+func addComplexity(a, b int64) int64 {
+	if a > math.MaxInt64-b {
+		return math.MaxInt64
+	}
+	return a + b
+}
+
+func mulComplexity(a, b int64) int64 {
+	if a != 0 && b > math.MaxInt64/a {
+		return math.MaxInt64
+	}
+	return a * b
+}
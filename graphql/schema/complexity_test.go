@@ -0,0 +1,124 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package schema
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dgraph-io/dgraph/x"
+)
+
+const complexitySchema = `
+type Author {
+	id: ID!
+	name: String!
+	posts: [Post] @complexity(multiplier: 5)
+	bio: String @complexity(value: 20)
+}
+
+type Post {
+	id: ID!
+	title: String!
+	text: String
+}`
+
+func TestComplexityLimit(t *testing.T) {
+	defer func(old x.Options) { x.Config = old }(x.Config)
+	x.Config.GraphqlComplexityListMultiplier = 10
+
+	schHandler, err := NewHandler(complexitySchema, false)
+	require.NoError(t, err)
+	sch, err := FromString(schHandler.GQLSchema())
+	require.NoError(t, err)
+
+	tcases := []struct {
+		name       string
+		query      string
+		vars       map[string]interface{}
+		complexity int64
+	}{
+		{
+			name:       "scalar fields add their weight",
+			query:      `query { getAuthor(id: "0x1") { name bio } }`,
+			complexity: 22,
+		},
+		{
+			name:       "list fields use the default multiplier",
+			query:      `query { queryAuthor { name } }`,
+			complexity: 11,
+		},
+		{
+			name:       "first overrides the multiplier",
+			query:      `query { queryAuthor(first: 3) { name posts(first: 2) { title } } }`,
+			complexity: 1 + 3*(1+1+2*1),
+		},
+		{
+			name:       "first can be a variable",
+			query:      `query q($n: Int) { queryAuthor(first: $n) { name } }`,
+			vars:       map[string]interface{}{"n": 4},
+			complexity: 5,
+		},
+		{
+			name:       "@complexity sets the multiplier",
+			query:      `query { getAuthor(id: "0x1") { posts { title text } } }`,
+			complexity: 1 + 1 + 5*2,
+		},
+		{
+			name: "fragments are counted where they are spread",
+			query: `query { queryAuthor(first: 2) { ...authorFrag } }
+				fragment authorFrag on Author { name bio }`,
+			complexity: 1 + 2*21,
+		},
+	}
+
+	for _, tcase := range tcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			x.Config.GraphqlMaxComplexity = tcase.complexity
+			_, err := sch.Operation(&Request{Query: tcase.query, Variables: tcase.vars})
+			require.NoError(t, err)
+
+			x.Config.GraphqlMaxComplexity = tcase.complexity - 1
+			_, err = sch.Operation(&Request{Query: tcase.query, Variables: tcase.vars})
+			require.Error(t, err)
+			gqlErr, ok := err.(*x.GqlError)
+			require.True(t, ok)
+			require.Equal(t, ErrComplexityLimitExceeded, gqlErr.Extensions["code"])
+			require.Equal(t, tcase.complexity, gqlErr.Extensions["complexity"])
+		})
+	}
+}
+
+func TestComplexityOfHugeLists(t *testing.T) {
+	defer func(old x.Options) { x.Config = old }(x.Config)
+	x.Config.GraphqlMaxComplexity = 1000
+	x.Config.GraphqlComplexityListMultiplier = math.MaxInt64 / 2
+
+	schHandler, err := NewHandler(complexitySchema, false)
+	require.NoError(t, err)
+	sch, err := FromString(schHandler.GQLSchema())
+	require.NoError(t, err)
+
+	// The complexity saturates instead of overflowing back under the limit.
+	_, err = sch.Operation(&Request{Query: `query { queryAuthor { name bio } }`})
+	require.Error(t, err)
+	gqlErr, ok := err.(*x.GqlError)
+	require.True(t, ok)
+	require.Equal(t, int64(math.MaxInt64), gqlErr.Extensions["complexity"])
+}
@@ -0,0 +1,179 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package schema
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+
+	dgtypes "github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// A DefaultGenerator is a value of @default that is generated by the server, rather than given
+// in the schema.
+type DefaultGenerator string
+
+const (
+	// DefaultNow generates the time of the mutation, for DateTime fields.
+	DefaultNow DefaultGenerator = "now()"
+	// DefaultUUID generates a random UUID, for String fields.
+	DefaultUUID DefaultGenerator = "uuid()"
+)
+
+// defaultGeneratorTypes are the types of the fields that each generator can be used on.
+var defaultGeneratorTypes = map[DefaultGenerator]string{
+	DefaultNow:  "DateTime",
+	DefaultUUID: "String",
+}
+
+func defaultValidation(sch *ast.Schema,
+	typ *ast.Definition,
+	field *ast.FieldDefinition,
+	dir *ast.Directive,
+	secrets map[string]x.SensitiveByteSlice) gqlerror.List {
+
+	errf := func(format string, args ...interface{}) gqlerror.List {
+		return []*gqlerror.Error{gqlerror.ErrorPosf(dir.Position,
+			"Type %s; Field %s: "+format, append([]interface{}{typ.Name, field.Name}, args...)...)}
+	}
+
+	if hasCustomOrLambda(field) {
+		return errf("@default can't be used on fields with @custom or @lambda.")
+	}
+	if field.Type.Elem != nil {
+		return errf("@default can't be used on list fields.")
+	}
+	if hasIDDirective(field) && dir.Arguments.ForName(defaultOnUpdateArg) != nil {
+		return errf("@default can't set an @id field on update.")
+	}
+
+	arg := dir.Arguments.ForName(defaultValueArg)
+	if arg == nil || arg.Value == nil || arg.Value.Kind != ast.StringValue {
+		return errf("the value of @default must be a String.")
+	}
+	if _, err := parseDefault(sch, field, arg.Value.Raw); err != nil {
+		return errf("%s", err)
+	}
+	return nil
+}
+
+// parseDefault returns the value of the field that the value of its @default directive stands
+// for: either a DefaultGenerator or a literal of the type of the field.
+func parseDefault(sch *ast.Schema, field *ast.FieldDefinition, value string) (interface{}, error) {
+	typName := field.Type.Name()
+
+	if genType, ok := defaultGeneratorTypes[DefaultGenerator(value)]; ok {
+		if typName != genType {
+			return nil, errors.Errorf("%s can only be the default of %s fields.", value, genType)
+		}
+		return DefaultGenerator(value), nil
+	}
+
+	switch typName {
+	case "String":
+		return value, nil
+	case "Int":
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || v < math.MinInt32 || v > math.MaxInt32 {
+			return nil, errors.Errorf("%q isn't a valid default for an Int.", value)
+		}
+		return v, nil
+	case "Int64":
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, errors.Errorf("%q isn't a valid default for an Int64.", value)
+		}
+		return v, nil
+	case "Float":
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, errors.Errorf("%q isn't a valid default for a Float.", value)
+		}
+		return v, nil
+	case "Boolean":
+		if value != "true" && value != "false" {
+			return nil, errors.Errorf("%q isn't a valid default for a Boolean.", value)
+		}
+		return value == "true", nil
+	case "DateTime":
+		if _, err := dgtypes.ParseTime(value); err != nil {
+			return nil, errors.Errorf("%q isn't a valid default for a DateTime.", value)
+		}
+		return value, nil
+	}
+
+	if IsCustomScalar(typName) {
+		v, err := CoerceCustomScalar(typName, value)
+		if err != nil {
+			return nil, errors.Errorf("%q isn't a valid default for a %s.", value, typName)
+		}
+		return v, nil
+	}
+
+	if def := sch.Types[typName]; def != nil && def.Kind == ast.Enum {
+		if def.EnumValues.ForName(value) == nil {
+			return nil, errors.Errorf("%q isn't a value of the enum %s.", value, typName)
+		}
+		return value, nil
+	}
+
+	return nil, errors.Errorf("@default can't be used on fields of type %s.", typName)
+}
+
+// hasDefault returns true if a value for fld can be left out of an add mutation, because it
+// has a default.
+func hasDefault(fld *ast.FieldDefinition) bool {
+	return fld.Directives.ForName(defaultDirective) != nil
+}
+
+// DefaultValues returns the values that @default gives the fields of t in an add mutation, or,
+// if update is true, in an update mutation.  The map goes from field names to either literal
+// values or DefaultGenerators.
+func (t *astType) DefaultValues(update bool) map[string]interface{} {
+	var defaults map[string]interface{}
+	for _, fld := range t.inSchema.schema.Types[t.Name()].Fields {
+		dir := fld.Directives.ForName(defaultDirective)
+		if dir == nil {
+			continue
+		}
+		if update {
+			onUpdate := dir.Arguments.ForName(defaultOnUpdateArg)
+			if onUpdate == nil || onUpdate.Value == nil || onUpdate.Value.Raw != "true" {
+				continue
+			}
+		}
+		arg := dir.Arguments.ForName(defaultValueArg)
+		if arg == nil || arg.Value == nil {
+			continue
+		}
+		val, err := parseDefault(t.inSchema.schema, fld, arg.Value.Raw)
+		if err != nil {
+			// This can't happen for a schema that passed validation.
+			continue
+		}
+		if defaults == nil {
+			defaults = make(map[string]interface{})
+		}
+		defaults[fld.Name] = val
+	}
+	return defaults
+}
@@ -17,14 +17,162 @@
 package schema
 
 import (
+	"context"
 	"fmt"
+	"sync/atomic"
 
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
 	"github.com/vektah/gqlparser/v2/ast"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
+	"github.com/dgraph-io/dgo/v200"
+	"github.com/dgraph-io/dgraph/graphql/authorization"
 	"github.com/dgraph-io/dgraph/x"
 	"github.com/vektah/gqlparser/v2/gqlerror"
 )
 
+// The codes of the categories of errors.  They are reported in the code extension of GraphQL
+// errors, so that clients can tell errors apart without matching their messages.
+const (
+	// ErrCodeValidationFailed is the code of requests that aren't valid for the schema.
+	ErrCodeValidationFailed = "GRAPHQL_VALIDATION_FAILED"
+	// ErrCodeUnauthenticated is the code of requests with a JWT that can't be verified, or
+	// without the credentials that Dgraph requires.
+	ErrCodeUnauthenticated = "UNAUTHENTICATED"
+	// ErrCodeForbidden is the code of requests that the ACL rules of Dgraph don't allow.
+	ErrCodeForbidden = "FORBIDDEN"
+	// ErrCodeAborted is the code of transactions that were aborted because of a conflict with
+	// another transaction.  They can be retried.
+	ErrCodeAborted = "TRANSACTION_ABORTED"
+	// ErrCodeTimeout is the code of requests that didn't finish in time.
+	ErrCodeTimeout = "TIMEOUT"
+	// ErrCodeInternal is the code of all other errors returned by Dgraph.
+	ErrCodeInternal = "INTERNAL_SERVER_ERROR"
+)
+
+// The error redaction policies.
+const (
+	// RedactNone returns the messages of all errors as they are.
+	RedactNone = "NONE"
+	// RedactInternal replaces the messages of errors with code ErrCodeInternal by a generic
+	// message, and logs them instead.
+	RedactInternal = "INTERNAL"
+)
+
+const redactedMessage = "Internal error - the error has been logged by the alpha."
+
+// errorRedaction holds the error redaction policy.  It starts from the --graphql_error_redaction
+// flag and can be changed with the config mutation of /admin.
+var errorRedaction atomic.Value
+
+func init() {
+	errorRedaction.Store(RedactNone)
+}
+
+// SetErrorRedaction sets the policy for redacting the messages of errors in GraphQL responses.
+func SetErrorRedaction(policy string) {
+	errorRedaction.Store(policy)
+}
+
+// ErrorRedaction returns the policy for redacting the messages of errors in GraphQL responses.
+func ErrorRedaction() string {
+	return errorRedaction.Load().(string)
+}
+
+// errorExtensions returns the extensions that report the category of err, or nil if err isn't in
+// any of the categories.
+func errorExtensions(err error) map[string]interface{} {
+	cause := errors.Cause(err)
+	if lerr, ok := cause.(*x.LimitExceededError); ok {
+		ext := lerr.Extensions()
+		if lerr.Limit == x.LimitTimeout {
+			ext["code"] = ErrCodeTimeout
+		}
+		return ext
+	}
+	if _, ok := cause.(*authorization.InvalidJWTError); ok {
+		return map[string]interface{}{"code": ErrCodeUnauthenticated}
+	}
+
+	var code string
+	switch {
+	case cause == context.DeadlineExceeded:
+		code = ErrCodeTimeout
+	case cause == dgo.ErrAborted:
+		code = ErrCodeAborted
+	default:
+		switch status.Code(cause) {
+		case codes.Unauthenticated:
+			code = ErrCodeUnauthenticated
+		case codes.PermissionDenied:
+			code = ErrCodeForbidden
+		case codes.Aborted:
+			code = ErrCodeAborted
+		case codes.DeadlineExceeded:
+			code = ErrCodeTimeout
+		default:
+			return nil
+		}
+	}
+	return map[string]interface{}{"code": code}
+}
+
+// AsExecutionError formats an error that Dgraph returned while executing a GraphQL request as a
+// GraphQL error, with the code of its category.  Errors that aren't in any of the categories
+// are internal errors.  A nil input results in nil output.
+func AsExecutionError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	ext := errorExtensions(err)
+	if ext == nil {
+		ext = map[string]interface{}{"code": ErrCodeInternal}
+	}
+	return &x.GqlError{Message: err.Error(), Extensions: ext}
+}
+
+// WithErrorCode formats err as a list of GraphQL errors, and sets code as the code of
+// the errors that don't have a code yet.  A nil input results in nil output.
+func WithErrorCode(err error, code string) error {
+	errs := AsGQLErrors(err)
+	if errs == nil {
+		return nil
+	}
+	for _, e := range errs {
+		if _, ok := e.Extensions["code"]; ok {
+			continue
+		}
+		if e.Extensions == nil {
+			e.Extensions = make(map[string]interface{})
+		}
+		e.Extensions["code"] = code
+	}
+	return errs
+}
+
+// redact applies the error redaction policy to errs.
+func redact(errs x.GqlErrorList) x.GqlErrorList {
+	if ErrorRedaction() != RedactInternal {
+		return errs
+	}
+
+	result := make(x.GqlErrorList, 0, len(errs))
+	for _, e := range errs {
+		if e.Extensions["code"] != ErrCodeInternal {
+			result = append(result, e)
+			continue
+		}
+		glog.Errorf("Redacted internal error in GraphQL response: %s", e.Message)
+		redacted := *e
+		redacted.Message = redactedMessage
+		result = append(result, &redacted)
+	}
+	return result
+}
+
 // AsGQLErrors formats an error as a list of GraphQL errors.
 // A []*x.GqlError (x.GqlErrorList) gets returned as is, an x.GqlError gets returned as a one
 // item list, and all other errors get printed into a x.GqlError .  A nil input results
@@ -44,7 +192,7 @@ func AsGQLErrors(err error) x.GqlErrorList {
 	case x.GqlErrorList:
 		return e
 	default:
-		return x.GqlErrorList{&x.GqlError{Message: e.Error()}}
+		return x.GqlErrorList{&x.GqlError{Message: e.Error(), Extensions: errorExtensions(e)}}
 	}
 }
 
@@ -94,9 +242,11 @@ func GQLWrapf(err error, format string, args ...interface{}) error {
 
 	switch err := err.(type) {
 	case *x.GqlError:
-		return x.GqlErrorf("%s because %s", fmt.Sprintf(format, args...), err.Message).
+		wrapped := x.GqlErrorf("%s because %s", fmt.Sprintf(format, args...), err.Message).
 			WithLocations(err.Locations...).
 			WithPath(err.Path)
+		wrapped.Extensions = err.Extensions
+		return wrapped
 	case x.GqlErrorList:
 		var errs x.GqlErrorList
 		for _, e := range err {
@@ -104,7 +254,9 @@ func GQLWrapf(err error, format string, args ...interface{}) error {
 		}
 		return errs
 	default:
-		return x.GqlErrorf("%s because %s", fmt.Sprintf(format, args...), err.Error())
+		wrapped := x.GqlErrorf("%s because %s", fmt.Sprintf(format, args...), err.Error())
+		wrapped.Extensions = errorExtensions(err)
+		return wrapped
 	}
 }
 
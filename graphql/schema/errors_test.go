@@ -17,13 +17,19 @@
 package schema
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"testing"
 
+	"github.com/dgraph-io/dgo/v200"
+	"github.com/dgraph-io/dgraph/graphql/authorization"
 	"github.com/dgraph-io/dgraph/x"
+	pkgerrors "github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 	"github.com/vektah/gqlparser/v2/gqlerror"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -231,3 +237,122 @@ func TestAppendGQLErrs(t *testing.T) {
 		})
 	}
 }
+
+func TestAsExecutionError(t *testing.T) {
+	tests := map[string]struct {
+		err error
+		req string
+	}{
+		"nil": {
+			err: nil,
+			req: "null",
+		},
+		"aborted transaction": {
+			err: dgo.ErrAborted,
+			req: `{"message": "Transaction has been aborted. Please retry",
+				"extensions": {"code": "TRANSACTION_ABORTED"}}`,
+		},
+		"timeout": {
+			err: pkgerrors.Wrap(context.DeadlineExceeded, "while running query"),
+			req: `{"message": "while running query: context deadline exceeded",
+				"extensions": {"code": "TIMEOUT"}}`,
+		},
+		"timeout limit": {
+			err: &x.LimitExceededError{Limit: x.LimitTimeout, Max: 100},
+			req: `{"message": "Query exceeded the timeout limit of 100ms",
+				"extensions": {"code": "TIMEOUT", "limit": "timeout", "max": 100}}`,
+		},
+		"ACL error": {
+			err: status.Error(codes.PermissionDenied, "unauthorized to query the predicate"),
+			req: `{"message": "rpc error: code = PermissionDenied desc = unauthorized to query the predicate",
+				"extensions": {"code": "FORBIDDEN"}}`,
+		},
+		"missing credentials": {
+			err: status.Error(codes.Unauthenticated, "no accessJwt available"),
+			req: `{"message": "rpc error: code = Unauthenticated desc = no accessJwt available",
+				"extensions": {"code": "UNAUTHENTICATED"}}`,
+		},
+		"internal error": {
+			err: errors.New("something went wrong"),
+			req: `{"message": "something went wrong",
+				"extensions": {"code": "INTERNAL_SERVER_ERROR"}}`,
+		},
+	}
+
+	for name, tcase := range tests {
+		t.Run(name, func(t *testing.T) {
+			gqlErr, err := json.Marshal(AsExecutionError(tcase.err))
+			require.NoError(t, err)
+
+			assert.JSONEq(t, tcase.req, string(gqlErr))
+		})
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	jwtErr := &authorization.InvalidJWTError{Err: errors.New("unable to parse jwt token")}
+
+	tests := map[string]struct {
+		err error
+		req string
+	}{
+		"wrapped JWT error": {
+			err: GQLWrapf(jwtErr, "couldn't rewrite query"),
+			req: `[{"message": "couldn't rewrite query because unable to parse jwt token",
+				"extensions": {"code": "UNAUTHENTICATED"}}]`,
+		},
+		"wrapped execution error": {
+			err: GQLWrapf(AsExecutionError(dgo.ErrAborted), "mutation failed"),
+			req: `[{"message": "mutation failed because Transaction has been aborted. Please retry",
+				"extensions": {"code": "TRANSACTION_ABORTED"}}]`,
+		},
+		"error without a category": {
+			err: GQLWrapf(errors.New("bad input"), "couldn't rewrite mutation"),
+			req: `[{"message": "couldn't rewrite mutation because bad input"}]`,
+		},
+		"validation error": {
+			err: WithErrorCode(gqlerror.List{gqlerror.Errorf("Unknown field")},
+				ErrCodeValidationFailed),
+			req: `[{"message": "Unknown field",
+				"extensions": {"code": "GRAPHQL_VALIDATION_FAILED"}}]`,
+		},
+		"validation error with a code": {
+			err: WithErrorCode(&x.GqlError{Message: "too complex",
+				Extensions: map[string]interface{}{"code": ErrComplexityLimitExceeded}},
+				ErrCodeValidationFailed),
+			req: `[{"message": "too complex",
+				"extensions": {"code": "COMPLEXITY_LIMIT_EXCEEDED"}}]`,
+		},
+	}
+
+	for name, tcase := range tests {
+		t.Run(name, func(t *testing.T) {
+			gqlErrs, err := json.Marshal(AsGQLErrors(tcase.err))
+			require.NoError(t, err)
+
+			assert.JSONEq(t, tcase.req, string(gqlErrs))
+		})
+	}
+}
+
+func TestErrorRedaction(t *testing.T) {
+	defer SetErrorRedaction(ErrorRedaction())
+
+	internal := AsExecutionError(errors.New("something went wrong"))
+	aborted := AsExecutionError(dgo.ErrAborted)
+
+	SetErrorRedaction(RedactNone)
+	resp := &Response{}
+	resp.WithError(AppendGQLErrs(internal, aborted))
+	require.Equal(t, "something went wrong", resp.Errors[0].Message)
+	require.Equal(t, "Transaction has been aborted. Please retry", resp.Errors[1].Message)
+
+	SetErrorRedaction(RedactInternal)
+	resp = &Response{}
+	resp.WithError(AppendGQLErrs(internal, aborted))
+	require.Equal(t, redactedMessage, resp.Errors[0].Message)
+	require.Equal(t, ErrCodeInternal, resp.Errors[0].Extensions["code"])
+	require.Equal(t, "Transaction has been aborted. Please retry", resp.Errors[1].Message)
+	// The original error isn't changed.
+	require.Equal(t, "something went wrong", internal.Error())
+}
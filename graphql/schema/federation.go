@@ -0,0 +1,220 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package schema
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// federationLink is the @link that tells an Apollo gateway which version of the Federation spec
+// the subgraph SDL is written against, and which of the spec's directives it uses.
+const federationLink = `extend schema
+	@link(url: "https://specs.apollo.dev/federation/v2.0",
+		import: ["@key", "@shareable", "@override", "@inaccessible"])
+`
+
+// addFederationTypes adds what a subgraph needs to serve an Apollo Federation gateway if any
+// type in the schema is an entity, i.e. has a @key directive:
+//
+//	scalar _Any
+//	union _Entity = T1 | T2 | ...
+//	type _Service { sdl: String }
+//
+// and the queries
+//
+//	_entities(representations: [_Any!]!): [_Entity]!
+//	_service: _Service!
+//
+// If no type is an entity, the schema is left as it is.
+func addFederationTypes(sch *ast.Schema, definitions []string) {
+	var entities []string
+	for _, key := range definitions {
+		defn := sch.Types[key]
+		if defn.Kind == ast.Object && defn.Directives.ForName(keyDirective) != nil {
+			entities = append(entities, defn.Name)
+		}
+	}
+	if len(entities) == 0 {
+		return
+	}
+
+	sch.Types[anyScalar] = &ast.Definition{
+		Kind: ast.Scalar,
+		Name: anyScalar,
+	}
+	sch.Types[entityUnion] = &ast.Definition{
+		Kind:  ast.Union,
+		Name:  entityUnion,
+		Types: entities,
+	}
+	sch.Types[serviceType] = &ast.Definition{
+		Kind: ast.Object,
+		Name: serviceType,
+		Fields: ast.FieldList{{
+			Name: "sdl",
+			Type: ast.NamedType("String", nil),
+		}},
+	}
+
+	sch.Query.Fields = append(sch.Query.Fields,
+		&ast.FieldDefinition{
+			Name: EntitiesQueryName,
+			Arguments: ast.ArgumentDefinitionList{{
+				Name: RepresentationsArg,
+				Type: ast.NonNullListType(ast.NonNullNamedType(anyScalar, nil), nil),
+			}},
+			Type: ast.NonNullListType(ast.NamedType(entityUnion, nil), nil),
+		},
+		&ast.FieldDefinition{
+			Name: ServiceQueryName,
+			Type: ast.NonNullNamedType(serviceType, nil),
+		})
+}
+
+func isFederationType(name string) bool {
+	return name == anyScalar || name == entityUnion || name == serviceType
+}
+
+// ServiceSDL returns the SDL that the _service query answers with.  That's the Federation v2 SDL
+// of the subgraph: every type reachable from the queries and mutations, without Dgraph's own
+// directives, which mean nothing to a gateway, and without the types and queries that only exist
+// to serve the gateway.
+func ServiceSDL(q Query) (string, error) {
+	if q.Name() != ServiceQueryName {
+		return "", errors.New("call to ServiceSDL for field that isn't a _service query " +
+			"this indicates bug. Please let us know by filing an issue.")
+	}
+
+	sch, ok := q.Operation().Schema().(*schema)
+	if !ok {
+		return "", errors.New("couldn't convert schema to internal type " +
+			"this indicates bug. Please let us know by filing an issue.")
+	}
+
+	return federationSDL(sch.schema), nil
+}
+
+func federationSDL(sch *ast.Schema) string {
+	reachable := make(map[string]bool)
+	var visit func(name string)
+	visit = func(name string) {
+		defn := sch.Types[name]
+		if defn == nil || defn.BuiltIn || reachable[name] || isFederationType(name) {
+			return
+		}
+		reachable[name] = true
+
+		for _, fld := range defn.Fields {
+			visit(fld.Type.Name())
+			for _, arg := range fld.Arguments {
+				visit(arg.Type.Name())
+			}
+		}
+		for _, member := range defn.Types {
+			visit(member)
+		}
+		for _, implements := range defn.Interfaces {
+			visit(implements)
+		}
+		for _, impl := range sch.PossibleTypes[name] {
+			visit(impl.Name)
+		}
+	}
+
+	if sch.Query != nil {
+		visit(sch.Query.Name)
+	}
+	if sch.Mutation != nil {
+		visit(sch.Mutation.Name)
+	}
+	if entities := sch.Types[entityUnion]; entities != nil {
+		for _, member := range entities.Types {
+			visit(member)
+		}
+	}
+
+	typeNames := make([]string, 0, len(reachable))
+	for name := range reachable {
+		if !isQueryOrMutation(name) {
+			typeNames = append(typeNames, name)
+		}
+	}
+	sort.Strings(typeNames)
+	if sch.Query != nil && reachable[sch.Query.Name] {
+		typeNames = append(typeNames, sch.Query.Name)
+	}
+	if sch.Mutation != nil && reachable[sch.Mutation.Name] {
+		typeNames = append(typeNames, sch.Mutation.Name)
+	}
+
+	var sdl strings.Builder
+	x.Check2(sdl.WriteString(federationLink))
+	for _, name := range typeNames {
+		typ := federationDefinition(sch.Types[name])
+		x.Check2(sdl.WriteString("\n"))
+		switch typ.Kind {
+		case ast.Scalar:
+			x.Check2(sdl.WriteString(generateScalarString(typ)))
+		case ast.Object:
+			x.Check2(sdl.WriteString(generateObjectString(typ)))
+		case ast.Interface:
+			x.Check2(sdl.WriteString(generateInterfaceString(typ)))
+		case ast.Union:
+			x.Check2(sdl.WriteString(generateUnionString(typ)))
+		case ast.Enum:
+			x.Check2(sdl.WriteString(generateEnumString(typ)))
+		case ast.InputObject:
+			x.Check2(sdl.WriteString(generateInputString(typ)))
+		}
+	}
+
+	return sdl.String()
+}
+
+// federationDefinition returns a copy of defn that keeps only the directives that are meaningful
+// to a gateway, and drops the federation queries if defn is the Query type.
+func federationDefinition(defn *ast.Definition) *ast.Definition {
+	typ := *defn
+	typ.Directives = federationDirectives(defn.Directives)
+	typ.Fields = make(ast.FieldList, 0, len(defn.Fields))
+	for _, fld := range defn.Fields {
+		if isFederationType(fld.Type.Name()) {
+			continue
+		}
+		f := *fld
+		f.Directives = federationDirectives(fld.Directives)
+		typ.Fields = append(typ.Fields, &f)
+	}
+	return &typ
+}
+
+func federationDirectives(dirs ast.DirectiveList) ast.DirectiveList {
+	var result ast.DirectiveList
+	for _, dir := range dirs {
+		switch dir.Name {
+		case keyDirective, shareableDirective, overrideDirective, inaccessibleDirective,
+			deprecatedDirective:
+			result = append(result, dir)
+		}
+	}
+	return result
+}
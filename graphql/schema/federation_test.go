@@ -0,0 +1,64 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package schema
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+func TestServiceSDL(t *testing.T) {
+	input, err := ioutil.ReadFile("testdata/schemagen/input/apollo-federation.graphql")
+	require.NoError(t, err)
+
+	schHandler, errs := NewHandler(string(input), false)
+	require.NoError(t, errs)
+	sch, err := FromString(schHandler.GQLSchema())
+	require.NoError(t, err)
+
+	op, err := sch.Operation(&Request{Query: `query { _service { sdl } }`})
+	require.NoError(t, err)
+	require.Len(t, op.Queries(), 1)
+	require.Equal(t, ServiceQuery, op.Queries()[0].QueryType())
+
+	sdl, err := ServiceSDL(op.Queries()[0])
+	require.NoError(t, err)
+
+	expected, err := ioutil.ReadFile("testdata/federation/apollo-federation.graphql")
+	require.NoError(t, err)
+	if diff := cmp.Diff(string(expected), sdl); diff != "" {
+		t.Errorf("SDL mismatch - diff (-want +got):\n%s", diff)
+	}
+
+	_, gqlErr := parser.ParseSchema(&ast.Source{Input: sdl})
+	require.Nil(t, gqlErr)
+}
+
+func TestNoFederationTypesWithoutEntities(t *testing.T) {
+	schHandler, errs := NewHandler(`type Author { id: ID! name: String! }`, false)
+	require.NoError(t, errs)
+
+	sch, err := FromString(schHandler.GQLSchema())
+	require.NoError(t, err)
+	require.Empty(t, sch.Queries(EntitiesQuery))
+	require.Empty(t, sch.Queries(ServiceQuery))
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package schema
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/vektah/gqlparser/v2/ast"
+
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// oneOfGeoFilters are the geo filters that take exactly one of their fields, as each of them is
+// rewritten to a single DQL function of the geo value that is given.
+var oneOfGeoFilters = map[string]bool{
+	"ContainsFilter":   true,
+	"IntersectsFilter": true,
+}
+
+// checkGeoFilters returns an error if a geo filter in the arguments of the fields in selSet
+// doesn't have exactly one of its fields. GraphQL input objects can't express that, so it's
+// checked after the variables have been coerced.
+func checkGeoFilters(sch *ast.Schema, selSet ast.SelectionSet, vars map[string]interface{}) error {
+	for _, sel := range selSet {
+		switch s := sel.(type) {
+		case *ast.Field:
+			if s.Definition != nil {
+				for _, argDef := range s.Definition.Arguments {
+					arg := s.Arguments.ForName(argDef.Name)
+					if arg == nil {
+						continue
+					}
+					val, err := arg.Value.Value(vars)
+					if err != nil {
+						continue
+					}
+					if err := checkGeoInput(sch, argDef.Type, val); err != nil {
+						return x.GqlErrorf("%s", err).WithLocations(x.Location{
+							Line:   s.Position.Line,
+							Column: s.Position.Column,
+						})
+					}
+				}
+			}
+			if err := checkGeoFilters(sch, s.SelectionSet, vars); err != nil {
+				return err
+			}
+		case *ast.InlineFragment:
+			if err := checkGeoFilters(sch, s.SelectionSet, vars); err != nil {
+				return err
+			}
+		case *ast.FragmentSpread:
+			if s.Definition != nil {
+				if err := checkGeoFilters(sch, s.Definition.SelectionSet, vars); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func checkGeoInput(sch *ast.Schema, typ *ast.Type, val interface{}) error {
+	if val == nil {
+		return nil
+	}
+	if typ.Elem != nil {
+		list, _ := val.([]interface{})
+		for _, v := range list {
+			if err := checkGeoInput(sch, typ.Elem, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	def := sch.Types[typ.Name()]
+	obj, ok := val.(map[string]interface{})
+	if def == nil || def.Kind != ast.InputObject || !ok {
+		return nil
+	}
+	if oneOfGeoFilters[def.Name] {
+		given := 0
+		for _, v := range obj {
+			if v != nil {
+				given++
+			}
+		}
+		if given != 1 {
+			names := make([]string, 0, len(def.Fields))
+			for _, fd := range def.Fields {
+				names = append(names, fd.Name)
+			}
+			return errors.Errorf("%s must have exactly one of %s, found %d.", def.Name,
+				strings.Join(names, ", "), given)
+		}
+	}
+	for _, fd := range def.Fields {
+		if err := checkGeoInput(sch, fd.Type, obj[fd.Name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const geoSchema = `
+type Hotel {
+	id: ID!
+	name: String!
+	location: Point @search
+	area: Polygon @search
+}`
+
+func TestGeoFilters(t *testing.T) {
+	schHandler, err := NewHandler(geoSchema, false)
+	require.NoError(t, err)
+	sch, err := FromString(schHandler.GQLSchema())
+	require.NoError(t, err)
+
+	point := map[string]interface{}{"latitude": 11.11, "longitude": 22.22}
+	polygon := map[string]interface{}{"coordinates": []interface{}{
+		map[string]interface{}{"points": []interface{}{point, point, point}},
+	}}
+
+	tcases := []struct {
+		name  string
+		query string
+		vars  map[string]interface{}
+		err   string
+	}{
+		{
+			name: "near on a point",
+			query: `query { queryHotel(filter: { location: { near: { distance: 10,
+				coordinate: { latitude: 11.11, longitude: 22.22 } } } }) { name } }`,
+		},
+		{
+			name: "contains with a point",
+			query: `query q($p: PointRef) {
+				queryHotel(filter: { area: { contains: { point: $p } } }) { name } }`,
+			vars: map[string]interface{}{"p": point},
+		},
+		{
+			name: "contains with a point and a polygon",
+			query: `query q($p: PointRef, $poly: PolygonRef) {
+				queryHotel(filter: { area: { contains: { point: $p, polygon: $poly } } }) {
+					name } }`,
+			vars: map[string]interface{}{"p": point, "poly": polygon},
+			err: "ContainsFilter must have exactly one of point, polygon, found 2. " +
+				"(Locations: [{Line: 2, Column: 5}])",
+		},
+		{
+			name: "intersects without a polygon",
+			query: `query q($f: HotelFilter) {
+				queryHotel(filter: { not: $f }) { name } }`,
+			vars: map[string]interface{}{"f": map[string]interface{}{
+				"area": map[string]interface{}{"intersects": map[string]interface{}{}},
+			}},
+			err: "IntersectsFilter must have exactly one of polygon, multiPolygon, found 0. " +
+				"(Locations: [{Line: 2, Column: 5}])",
+		},
+		{
+			name: "null fields aren't counted",
+			query: `query q($poly: PolygonRef) {
+				queryHotel(filter: { area: { intersects: { polygon: $poly, multiPolygon: null } } }) {
+					name } }`,
+			vars: map[string]interface{}{"poly": polygon},
+		},
+	}
+
+	for _, tcase := range tcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			_, err := sch.Operation(&Request{Query: tcase.query, Variables: tcase.vars})
+			if tcase.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tcase.err)
+		})
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/dgraph-io/dgraph/tok"
 	"github.com/dgraph-io/dgraph/x"
 	"github.com/vektah/gqlparser/v2/ast"
 	"github.com/vektah/gqlparser/v2/gqlerror"
@@ -33,12 +34,16 @@ const (
 
 	searchDirective = "search"
 	searchArgs      = "by"
+	searchNgramArg  = "ngram"
+	searchTzArg     = "tz"
+	searchHNSWArg   = "hnsw"
 
 	dgraphDirective = "dgraph"
 	dgraphTypeArg   = "type"
 	dgraphPredArg   = "pred"
 
 	idDirective           = "id"
+	idCompositeArg        = "composite"
 	subscriptionDirective = "withSubscription"
 	secretDirective       = "secret"
 	authDirective         = "auth"
@@ -48,17 +53,41 @@ const (
 
 	cascadeDirective = "cascade"
 	cascadeArg       = "fields"
+	cascadeDepthArg  = "depth"
+
+	complexityDirective = "complexity"
+	complexityValueArg  = "value"
+	complexityMultArg   = "multiplier"
+
+	defaultDirective   = "default"
+	defaultValueArg    = "value"
+	defaultOnUpdateArg = "onUpdate"
+
+	// Apollo Federation directives, types and queries
+	keyDirective          = "key"
+	keyArg                = "fields"
+	shareableDirective    = "shareable"
+	overrideDirective     = "override"
+	inaccessibleDirective = "inaccessible"
+	anyScalar             = "_Any"
+	entityUnion           = "_Entity"
+	serviceType           = "_Service"
+	EntitiesQueryName     = "_entities"
+	ServiceQueryName      = "_service"
+	RepresentationsArg    = "representations"
 
 	// custom directive args and fields
-	dqlArg      = "dql"
-	httpArg     = "http"
-	httpUrl     = "url"
-	httpMethod  = "method"
-	httpBody    = "body"
-	httpGraphql = "graphql"
-	mode        = "mode"
-	BATCH       = "BATCH"
-	SINGLE      = "SINGLE"
+	dqlArg         = "dql"
+	httpArg        = "http"
+	grpcArg        = "grpc"
+	httpUrl        = "url"
+	httpMethod     = "method"
+	httpBody       = "body"
+	httpGraphql    = "graphql"
+	descriptorFile = "descriptorFile"
+	mode           = "mode"
+	BATCH          = "BATCH"
+	SINGLE         = "SINGLE"
 
 	// geo type names and fields
 	Point        = "Point"
@@ -92,6 +121,36 @@ For example: "1985-04-12T23:20:50.52Z" represents 20 minutes and 50.52 seconds a
 """
 scalar DateTime
 
+"""
+The BigInt scalar type represents a signed integer of any size, like "123456789012345678901234567890".
+It is stored as a string, so no precision is lost.
+"""
+scalar BigInt
+
+"""
+The Decimal scalar type represents a signed decimal number of any precision, like "1234.5678".
+It is stored as a string, so no precision is lost.
+"""
+scalar Decimal
+
+"""
+The URL scalar type represents an absolute URL, like "https://dgraph.io/docs".
+"""
+scalar URL
+
+"""
+The Duration scalar type represents a length of time, like "1h30m" or "250ms".
+The units are ns, us, ms, s, m and h.
+"""
+scalar Duration
+
+"""
+The Upload scalar type represents a file sent in a multipart request, as described in
+https://github.com/jaydenseric/graphql-multipart-request-spec. Its content is stored in the
+predicate of the field, and queries return a reference to the /blob endpoint that reads it.
+"""
+scalar Upload
+
 input IntRange{
 	min: Int
 	max: Int
@@ -128,11 +187,34 @@ enum DgraphIndex {
 	fulltext
 	trigram
 	regexp
+	ngram
+	soundex
+	metaphone
 	year
 	month
 	day
 	hour
 	geo
+	bigint
+	decimal
+	url
+	duration
+	hnsw
+}
+
+input NgramOptions {
+	min: Int!
+	max: Int!
+}
+
+enum VectorMetric {
+	euclidean
+	cosine
+	dotproduct
+}
+
+input HNSWOptions {
+	metric: VectorMetric!
 }
 
 input AuthRule {
@@ -142,6 +224,11 @@ input AuthRule {
 	rule: String
 }
 
+enum AuthInheritance {
+	MERGE
+	OVERRIDE
+}
+
 enum HTTPMethod {
 	GET
 	POST
@@ -167,6 +254,16 @@ input CustomHTTP {
 	skipIntrospection: Boolean
 }
 
+input CustomGRPC {
+	url: String!
+	method: String!
+	descriptorFile: String
+	body: String
+	mode: Mode
+	forwardHeaders: [String!]
+	secretHeaders: [String!]
+}
+
 type Point {
 	longitude: Float!
 	latitude: Float!
@@ -232,21 +329,35 @@ input PolygonGeoFilter {
 	intersects: IntersectsFilter
 }
 
+type PageInfo {
+	startCursor: String
+	endCursor: String
+	hasNextPage: Boolean!
+	hasPreviousPage: Boolean!
+}
+
 directive @hasInverse(field: String!) on FIELD_DEFINITION
-directive @search(by: [DgraphIndex!]) on FIELD_DEFINITION
+directive @search(by: [DgraphIndex!], ngram: NgramOptions, tz: String, hnsw: HNSWOptions) on FIELD_DEFINITION
 directive @dgraph(type: String, pred: String) on OBJECT | INTERFACE | FIELD_DEFINITION
-directive @id on FIELD_DEFINITION
+directive @id(composite: [String!]) on FIELD_DEFINITION | OBJECT
 directive @withSubscription on OBJECT | INTERFACE
 directive @secret(field: String!, pred: String) on OBJECT | INTERFACE
 directive @auth(
 	query: AuthRule,
 	add: AuthRule,
 	update: AuthRule,
-	delete:AuthRule) on OBJECT
-directive @custom(http: CustomHTTP, dql: String) on FIELD_DEFINITION
+	delete:AuthRule,
+	inherit: AuthInheritance) on OBJECT | INTERFACE
+directive @custom(http: CustomHTTP, grpc: CustomGRPC, dql: String) on FIELD_DEFINITION
 directive @remote on OBJECT | INTERFACE | UNION | INPUT_OBJECT | ENUM
-directive @cascade(fields: [String]) on FIELD
+directive @cascade(fields: [String], depth: Int) on FIELD
 directive @lambda on FIELD_DEFINITION
+directive @complexity(value: Int, multiplier: Int) on FIELD_DEFINITION
+directive @default(value: String!, onUpdate: Boolean) on FIELD_DEFINITION
+directive @key(fields: String!) on OBJECT
+directive @shareable on OBJECT | FIELD_DEFINITION
+directive @override(from: String!) on FIELD_DEFINITION
+directive @inaccessible on OBJECT | INTERFACE | UNION | ENUM | INPUT_OBJECT | FIELD_DEFINITION
 
 input IntFilter {
 	eq: Int
@@ -266,6 +377,26 @@ input Int64Filter {
 	between: Int64Range
 }
 
+input BigIntFilter {
+	eq: BigInt
+	in: [BigInt]
+}
+
+input DecimalFilter {
+	eq: Decimal
+	in: [Decimal]
+}
+
+input URLFilter {
+	eq: URL
+	in: [URL]
+}
+
+input DurationFilter {
+	eq: Duration
+	in: [Duration]
+}
+
 input FloatFilter {
 	eq: Float
 	le: Float
@@ -293,6 +424,30 @@ input StringRegExpFilter {
 	regexp: String
 }
 
+input StringNgramFilter {
+	allofngrams: String
+	anyofngrams: String
+}
+
+input StringSoundexFilter {
+	allofsoundex: String
+	anyofsoundex: String
+}
+
+input StringMetaphoneFilter {
+	allofmetaphone: String
+	anyofmetaphone: String
+}
+
+input SimilarToFilter {
+	topK: Int!
+	vector: [Float!]!
+}
+
+input VectorFilter {
+	similarTo: SimilarToFilter
+}
+
 input StringFullTextFilter {
 	alloftext: String
 	anyoftext: String
@@ -357,6 +512,9 @@ var supportedSearches = map[string]searchTypeIndex{
 	"fulltext":     {"String", "fulltext"},
 	"trigram":      {"String", "trigram"},
 	"regexp":       {"String", "trigram"},
+	"ngram":        {"String", "ngram"},
+	"soundex":      {"String", "soundex"},
+	"metaphone":    {"String", "metaphone"},
 	"year":         {"DateTime", "year"},
 	"month":        {"DateTime", "month"},
 	"day":          {"DateTime", "day"},
@@ -364,6 +522,11 @@ var supportedSearches = map[string]searchTypeIndex{
 	"point":        {"Point", "geo"},
 	"polygon":      {"Polygon", "geo"},
 	"multiPolygon": {"MultiPolygon", "geo"},
+	"bigint":       {"BigInt", "hash"},
+	"decimal":      {"Decimal", "hash"},
+	"url":          {"URL", "hash"},
+	"duration":     {"Duration", "hash"},
+	"hnsw":         {"Float", "hnsw"},
 }
 
 // GraphQL scalar/object type -> default search arg
@@ -378,6 +541,10 @@ var defaultSearches = map[string]string{
 	"Point":        "point",
 	"Polygon":      "polygon",
 	"MultiPolygon": "multiPolygon",
+	"BigInt":       "bigint",
+	"Decimal":      "decimal",
+	"URL":          "url",
+	"Duration":     "duration",
 }
 
 // graphqlSpecScalars holds all the scalar types supported by the graphql spec.
@@ -405,6 +572,15 @@ var orderable = map[string]bool{
 	"DateTime": true,
 }
 
+// GraphQL types that can be aggregated with min and max in the groups of a group by query.  The
+// bool says if an average can also be taken.
+var aggregatable = map[string]bool{
+	"Int":      true,
+	"Int64":    true,
+	"Float":    true,
+	"DateTime": false,
+}
+
 var enumDirectives = map[string]bool{
 	"trigram": true,
 	"hash":    true,
@@ -425,12 +601,20 @@ var builtInFilters = map[string]string{
 	"term":         "StringTermFilter",
 	"trigram":      "StringRegExpFilter",
 	"regexp":       "StringRegExpFilter",
+	"ngram":        "StringNgramFilter",
+	"soundex":      "StringSoundexFilter",
+	"metaphone":    "StringMetaphoneFilter",
 	"fulltext":     "StringFullTextFilter",
 	"exact":        "StringExactFilter",
 	"hash":         "StringHashFilter",
 	"point":        "PointGeoFilter",
 	"polygon":      "PolygonGeoFilter",
 	"multiPolygon": "PolygonGeoFilter",
+	"bigint":       "BigIntFilter",
+	"decimal":      "DecimalFilter",
+	"url":          "URLFilter",
+	"duration":     "DurationFilter",
+	"hnsw":         "VectorFilter",
 }
 
 // GraphQL in-built type -> Dgraph scalar
@@ -446,6 +630,11 @@ var inbuiltTypeToDgraph = map[string]string{
 	"Point":        "geo",
 	"Polygon":      "geo",
 	"MultiPolygon": "geo",
+	"BigInt":       "string",
+	"Decimal":      "string",
+	"URL":          "string",
+	"Duration":     "string",
+	"Upload":       "string",
 }
 
 func ValidatorNoOp(
@@ -469,6 +658,12 @@ var directiveValidators = map[string]directiveValidator{
 	remoteDirective:       ValidatorNoOp,
 	deprecatedDirective:   ValidatorNoOp,
 	lambdaDirective:       lambdaDirectiveValidation,
+	complexityDirective:   complexityValidation,
+	defaultDirective:      defaultValidation,
+	keyDirective:          ValidatorNoOp,
+	shareableDirective:    ValidatorNoOp,
+	overrideDirective:     ValidatorNoOp,
+	inaccessibleDirective: ValidatorNoOp,
 }
 
 // directiveLocationMap stores the directives and their locations for the ones which can be
@@ -477,14 +672,21 @@ var directiveLocationMap = map[string]map[ast.DefinitionKind]bool{
 	inverseDirective:      nil,
 	searchDirective:       nil,
 	dgraphDirective:       {ast.Object: true, ast.Interface: true},
-	idDirective:           nil,
+	idDirective:           {ast.Object: true},
 	subscriptionDirective: {ast.Object: true, ast.Interface: true},
 	secretDirective:       {ast.Object: true, ast.Interface: true},
-	authDirective:         {ast.Object: true},
+	authDirective:         {ast.Object: true, ast.Interface: true},
 	customDirective:       nil,
 	remoteDirective: {ast.Object: true, ast.Interface: true, ast.Union: true,
 		ast.InputObject: true, ast.Enum: true},
-	cascadeDirective: nil,
+	cascadeDirective:    nil,
+	complexityDirective: nil,
+	defaultDirective:    nil,
+	keyDirective:        {ast.Object: true},
+	shareableDirective:  {ast.Object: true},
+	overrideDirective:   nil,
+	inaccessibleDirective: {ast.Object: true, ast.Interface: true, ast.Union: true,
+		ast.InputObject: true, ast.Enum: true},
 }
 
 var schemaDocValidations []func(schema *ast.SchemaDocument) gqlerror.List
@@ -693,6 +895,7 @@ func completeSchema(sch *ast.Schema, definitions []string) {
 			// them from unionRef or unionFilter as required.
 			addUnionReferenceType(sch, defn)
 			addUnionFilterType(sch, defn)
+			addUnionOrderType(sch, defn)
 			addUnionMemberTypeEnum(sch, defn)
 			continue
 		}
@@ -729,6 +932,8 @@ func completeSchema(sch *ast.Schema, definitions []string) {
 		addQueries(sch, defn)
 		addTypeHasFilter(sch, defn)
 	}
+
+	addFederationTypes(sch, definitions)
 }
 
 func cleanupInput(sch *ast.Schema, def *ast.Definition, seen map[string]bool) {
@@ -846,6 +1051,39 @@ func addUnionFilterType(schema *ast.Schema, defn *ast.Definition) {
 	schema.Types[filterName] = filter
 }
 
+// addUnionOrderType adds `input UOrder {...}` with a field for ordering each member type of the
+// union U that has orderable fields.
+func addUnionOrderType(schema *ast.Schema, defn *ast.Definition) {
+	if !hasUnionOrderables(schema, defn) {
+		return
+	}
+
+	order := &ast.Definition{
+		Kind: ast.InputObject,
+		Name: defn.Name + "Order",
+	}
+	for _, typName := range defn.Types {
+		if !hasOrderables(schema.Types[typName]) {
+			continue
+		}
+		order.Fields = append(order.Fields, &ast.FieldDefinition{
+			Name: CamelCase(typName) + "Order",
+			Type: &ast.Type{NamedType: typName + "Order"},
+		})
+	}
+	schema.Types[order.Name] = order
+}
+
+// hasUnionOrderables returns true if any member type of the union defn has orderable fields.
+func hasUnionOrderables(schema *ast.Schema, defn *ast.Definition) bool {
+	for _, typName := range defn.Types {
+		if hasOrderables(schema.Types[typName]) {
+			return true
+		}
+	}
+	return false
+}
+
 func addUnionMemberTypeEnum(schema *ast.Schema, defn *ast.Definition) {
 	enumName := defn.Name + "Type"
 	enum := &ast.Definition{
@@ -1020,7 +1258,7 @@ func addTypeHasFilter(schema *ast.Schema, defn *ast.Definition) {
 			continue
 		}
 		filter.EnumValues = append(filter.EnumValues,
-			&ast.EnumValueDefinition{Name: fld.Name})
+			&ast.EnumValueDefinition{Name: fld.Name, Directives: deprecatedDirectives(fld)})
 	}
 
 	// Interfaces could have just ID field but Types cannot for eg:
@@ -1037,7 +1275,8 @@ func addTypeHasFilter(schema *ast.Schema, defn *ast.Definition) {
 
 func addOrderArgument(schema *ast.Schema, fld *ast.FieldDefinition) {
 	fldType := fld.Type.Name()
-	if hasOrderables(schema.Types[fldType]) {
+	if hasOrderables(schema.Types[fldType]) ||
+		(schema.Types[fldType].Kind == ast.Union && hasUnionOrderables(schema, schema.Types[fldType])) {
 		fld.Arguments = append(fld.Arguments,
 			&ast.ArgumentDefinition{
 				Name: "order",
@@ -1105,6 +1344,43 @@ func mergeAndAddFilters(filterTypes []string, schema *ast.Schema, filterName str
 	}
 }
 
+// addNestedFilterType returns the name of the filter type that can be used to filter on the
+// nodes an edge fld points to, or "" if fld isn't an edge that can be filtered on.  Singular
+// edges use the filter of the type they point to, while list edges get a
+// input TListFilter {
+//   some: TFilter
+//   every: TFilter
+//   none: TFilter
+// }
+// added to the schema, which matches the parent if some, every or none of its children match.
+func addNestedFilterType(schema *ast.Schema, fld *ast.FieldDefinition) string {
+	fldType := schema.Types[fld.Type.Name()]
+	if fldType == nil || (fldType.Kind != ast.Object && fldType.Kind != ast.Interface) ||
+		isGeoTypeName(fldType.Name) || hasCustomOrLambda(fld) ||
+		fldType.Directives.ForName(remoteDirective) != nil {
+		return ""
+	}
+
+	if fld.Type.Elem == nil {
+		return fldType.Name + "Filter"
+	}
+
+	listFilterName := fldType.Name + "ListFilter"
+	if _, ok := schema.Types[listFilterName]; !ok {
+		childFilter := &ast.Type{NamedType: fldType.Name + "Filter"}
+		schema.Types[listFilterName] = &ast.Definition{
+			Kind: ast.InputObject,
+			Name: listFilterName,
+			Fields: ast.FieldList{
+				{Name: "some", Type: childFilter},
+				{Name: "every", Type: childFilter},
+				{Name: "none", Type: childFilter},
+			},
+		}
+	}
+	return listFilterName
+}
+
 // addFilterType add a `input TFilter { ... }` type to the schema, if defn
 // is a type that has fields that can be filtered on.  This type filter is used
 // in constructing the corresponding query
@@ -1134,6 +1410,17 @@ func addFilterType(schema *ast.Schema, defn *ast.Definition) {
 			continue
 		}
 
+		if nestedFilter := addNestedFilterType(schema, fld); nestedFilter != "" {
+			filter.Fields = append(filter.Fields,
+				&ast.FieldDefinition{
+					Name: fld.Name,
+					Type: &ast.Type{
+						NamedType: nestedFilter,
+					},
+				})
+			continue
+		}
+
 		filterTypes := getFilterTypes(schema, fld, filterName)
 		if len(filterTypes) > 0 {
 			filterName := strings.Join(filterTypes, "_")
@@ -1201,6 +1488,36 @@ func hasXID(defn *ast.Definition) bool {
 	return fieldAny(defn.Fields, hasIDDirective)
 }
 
+// compositeIDFields returns the fields that together identify an object of defn, as listed by
+// the @id(composite: [...]) directive of defn, or nil if defn has no composite key.
+func compositeIDFields(defn *ast.Definition) ast.FieldList {
+	id := defn.Directives.ForName(idDirective)
+	if id == nil {
+		return nil
+	}
+	arg := id.Arguments.ForName(idCompositeArg)
+	if arg == nil || arg.Value == nil {
+		return nil
+	}
+
+	var flds ast.FieldList
+	for _, name := range arg.Value.Children {
+		if fld := defn.Fields.ForName(name.Value.Raw); fld != nil {
+			flds = append(flds, fld)
+		}
+	}
+	return flds
+}
+
+func isCompositeIDField(defn *ast.Definition, fld *ast.FieldDefinition) bool {
+	for _, f := range compositeIDFields(defn) {
+		if f.Name == fld.Name {
+			return true
+		}
+	}
+	return false
+}
+
 // fieldAny returns true if any field in fields satisfies pred
 func fieldAny(fields ast.FieldList, pred func(*ast.FieldDefinition) bool) bool {
 	for _, fld := range fields {
@@ -1240,6 +1557,40 @@ func getDefaultSearchIndex(fldName string) string {
 
 // getSearchArgs returns the name of the search applied to fld, or ""
 // if fld doesn't have a search directive.
+// searchIndex returns the Dgraph index for searching by searchArg with the options given to the
+// @search directive, like ngram(min: 2, max: 4) for
+// @search(by: [ngram], ngram: {min: 2, max: 4}).
+func searchIndex(search *ast.Directive, searchArg string) (string, error) {
+	index := supportedSearches[searchArg].dgIndex
+	opts := make(map[string]string)
+	switch index {
+	case "ngram":
+		if arg := search.Arguments.ForName(searchNgramArg); arg != nil {
+			for _, child := range arg.Value.Children {
+				opts[child.Name] = child.Value.Raw
+			}
+		}
+	case "year", "month", "day", "hour":
+		if arg := search.Arguments.ForName(searchTzArg); arg != nil {
+			opts["tz"] = arg.Value.Raw
+		}
+	case "hnsw":
+		if arg := search.Arguments.ForName(searchHNSWArg); arg != nil {
+			for _, child := range arg.Value.Children {
+				opts[child.Name] = child.Value.Raw
+			}
+		}
+	}
+	if len(opts) == 0 {
+		return index, nil
+	}
+	tokenizer, err := tok.GetTokenizerWithOptions(index, opts)
+	if err != nil {
+		return "", err
+	}
+	return tokenizer.Name(), nil
+}
+
 func getSearchArgs(fld *ast.FieldDefinition) []string {
 	search := fld.Directives.ForName(searchDirective)
 	id := fld.Directives.ForName(idDirective)
@@ -1251,11 +1602,11 @@ func getSearchArgs(fld *ast.FieldDefinition) []string {
 		// that we apply.
 		return []string{"hash"}
 	}
-	if len(search.Arguments) == 0 ||
-		len(search.Arguments.ForName(searchArgs).Value.Children) == 0 {
+	arg := search.Arguments.ForName(searchArgs)
+	if arg == nil || len(arg.Value.Children) == 0 {
 		return []string{getDefaultSearchIndex(fld.Type.Name())}
 	}
-	val := search.Arguments.ForName(searchArgs).Value
+	val := arg.Value
 	res := make([]string, len(val.Children))
 
 	for i, child := range val.Children {
@@ -1267,6 +1618,20 @@ func getSearchArgs(fld *ast.FieldDefinition) []string {
 	return res
 }
 
+// isVectorField returns true if fld is searched by hnsw, which means that it's stored as a
+// float32vector in Dgraph rather than as a list of floats.
+func isVectorField(fld *ast.FieldDefinition) bool {
+	if fld.Directives.ForName(searchDirective) == nil {
+		return false
+	}
+	for _, searchArg := range getSearchArgs(fld) {
+		if searchArg == "hnsw" {
+			return true
+		}
+	}
+	return false
+}
+
 // addTypeOrderable adds an input type that allows ordering in query.
 // Two things are added: an enum with the names of all the orderable fields,
 // for a type T that's called TOrderable; and an input type that allows saying
@@ -1313,7 +1678,7 @@ func addTypeOrderable(schema *ast.Schema, defn *ast.Definition) {
 	for _, fld := range defn.Fields {
 		if isOrderable(fld) {
 			order.EnumValues = append(order.EnumValues,
-				&ast.EnumValueDefinition{Name: fld.Name})
+				&ast.EnumValueDefinition{Name: fld.Name, Directives: deprecatedDirectives(fld)})
 		}
 	}
 
@@ -1405,7 +1770,8 @@ func addDeletePayloadType(schema *ast.Schema, defn *ast.Definition) {
 func addGetQuery(schema *ast.Schema, defn *ast.Definition) {
 	hasIDField := hasID(defn)
 	hasXIDField := hasXID(defn)
-	if !hasIDField && !hasXIDField {
+	hasCompositeID := len(compositeIDFields(defn)) > 0
+	if !hasIDField && !hasXIDField && !hasCompositeID {
 		return
 	}
 
@@ -1416,15 +1782,15 @@ func addGetQuery(schema *ast.Schema, defn *ast.Definition) {
 		},
 	}
 
-	// If the defn, only specified one of ID/XID field, they they are mandatory. If it specified
-	// both, then they are optional.
+	// If the defn, only specified one of ID/XID field or a composite key, then they are
+	// mandatory. If it specified more than one, then they are optional.
 	if hasIDField {
 		fields := getIDField(defn)
 		qry.Arguments = append(qry.Arguments, &ast.ArgumentDefinition{
 			Name: fields[0].Name,
 			Type: &ast.Type{
 				NamedType: idTypeFor(defn),
-				NonNull:   !hasXIDField,
+				NonNull:   !hasXIDField && !hasCompositeID,
 			},
 		})
 	}
@@ -1434,7 +1800,16 @@ func addGetQuery(schema *ast.Schema, defn *ast.Definition) {
 			Name: name,
 			Type: &ast.Type{
 				NamedType: "String",
-				NonNull:   !hasIDField,
+				NonNull:   !hasIDField && !hasCompositeID,
+			},
+		})
+	}
+	for _, fld := range compositeIDFields(defn) {
+		qry.Arguments = append(qry.Arguments, &ast.ArgumentDefinition{
+			Name: fld.Name,
+			Type: &ast.Type{
+				NamedType: fld.Type.Name(),
+				NonNull:   !hasIDField && !hasXIDField,
 			},
 		})
 	}
@@ -1504,10 +1879,219 @@ func addPasswordQuery(schema *ast.Schema, defn *ast.Definition) {
 	schema.Query.Fields = append(schema.Query.Fields, qry)
 }
 
+// addConnectionQuery adds a query that pages through the nodes of a type as a Relay style
+// connection.  For a type T, that's
+//
+//	type TConnection {
+//		edges: [TEdge!]!
+//		pageInfo: PageInfo!
+//	}
+//
+//	type TEdge {
+//		cursor: String!
+//		node: T!
+//	}
+//
+// and queryTConnection(filter: TFilter, first: Int, after: String): TConnection.  The nodes are
+// in uid order, so a cursor stays valid however the data changes.
+func addConnectionQuery(schema *ast.Schema, defn *ast.Definition) {
+	edge := &ast.Definition{
+		Kind: ast.Object,
+		Name: defn.Name + "Edge",
+		Fields: []*ast.FieldDefinition{
+			{
+				Name: "cursor",
+				Type: &ast.Type{NamedType: "String", NonNull: true},
+			},
+			{
+				Name: "node",
+				Type: &ast.Type{NamedType: defn.Name, NonNull: true},
+			},
+		},
+	}
+	schema.Types[edge.Name] = edge
+
+	conn := &ast.Definition{
+		Kind: ast.Object,
+		Name: defn.Name + "Connection",
+		Fields: []*ast.FieldDefinition{
+			{
+				Name: "edges",
+				Type: &ast.Type{
+					Elem:    &ast.Type{NamedType: edge.Name, NonNull: true},
+					NonNull: true,
+				},
+			},
+			{
+				Name: "pageInfo",
+				Type: &ast.Type{NamedType: "PageInfo", NonNull: true},
+			},
+		},
+	}
+	schema.Types[conn.Name] = conn
+
+	qry := &ast.FieldDefinition{
+		Name: "query" + conn.Name,
+		Type: &ast.Type{
+			NamedType: conn.Name,
+		},
+	}
+	if hasFilterable(defn) {
+		qry.Arguments = append(qry.Arguments, &ast.ArgumentDefinition{
+			Name: "filter",
+			Type: &ast.Type{NamedType: defn.Name + "Filter"},
+		})
+	}
+	qry.Arguments = append(qry.Arguments,
+		&ast.ArgumentDefinition{Name: "first", Type: &ast.Type{NamedType: "Int"}},
+		&ast.ArgumentDefinition{Name: "after", Type: &ast.Type{NamedType: "String"}},
+	)
+
+	schema.Query.Fields = append(schema.Query.Fields, qry)
+}
+
+// isConnectionType returns true if typName is PageInfo or one of the connection and edge types
+// generated by addConnectionQuery.  None of them are stored in Dgraph.
+func isConnectionType(sch *ast.Schema, typName string) bool {
+	if typName == "PageInfo" {
+		return true
+	}
+	for _, suffix := range []string{"Connection", "Edge"} {
+		if !strings.HasSuffix(typName, suffix) {
+			continue
+		}
+		defn := sch.Types[strings.TrimSuffix(typName, suffix)]
+		if defn != nil && (defn.Kind == ast.Object || defn.Kind == ast.Interface) {
+			return true
+		}
+	}
+	return false
+}
+
+// isGroupable returns true if the nodes of a type can be grouped by the values of fld, which is
+// the case for scalar and enum fields, other than IDs and passwords, that aren't lists.
+func isGroupable(schema *ast.Schema, fld *ast.FieldDefinition) bool {
+	if fld.Type.NamedType == "" || hasCustomOrLambda(fld) {
+		return false
+	}
+	if orderable[fld.Type.NamedType] || fld.Type.NamedType == "Boolean" {
+		return true
+	}
+	typ := schema.Types[fld.Type.NamedType]
+	return typ != nil && typ.Kind == ast.Enum
+}
+
+// deprecatedDirectives returns the @deprecated directive of fld, if it has one, for the fields and
+// enum values that are generated from fld.
+func deprecatedDirectives(fld *ast.FieldDefinition) ast.DirectiveList {
+	if dir := fld.Directives.ForName(deprecatedDirective); dir != nil {
+		return ast.DirectiveList{dir}
+	}
+	return nil
+}
+
+// addGroupByQuery adds a query that groups the nodes of a type by some of its fields, and
+// aggregates each group.  For a type T, that's
+//
+//	enum TGroupable {
+//		... the fields of T that the nodes can be grouped by ...
+//	}
+//
+//	type TGroup {
+//		... the groupable fields of T ...
+//		count: Int
+//		... fMin, fMax and fAvg for the numeric and DateTime fields f of T ...
+//	}
+//
+// and aggregateTGroupBy(filter: TFilter, groupBy: [TGroupable!]!): [TGroup].
+func addGroupByQuery(schema *ast.Schema, defn *ast.Definition) {
+	groupable := &ast.Definition{
+		Kind: ast.Enum,
+		Name: defn.Name + "Groupable",
+	}
+	group := &ast.Definition{
+		Kind: ast.Object,
+		Name: defn.Name + "Group",
+	}
+	for _, fld := range defn.Fields {
+		if !isGroupable(schema, fld) {
+			continue
+		}
+		groupable.EnumValues = append(groupable.EnumValues,
+			&ast.EnumValueDefinition{Name: fld.Name, Directives: deprecatedDirectives(fld)})
+		group.Fields = append(group.Fields, &ast.FieldDefinition{
+			Name:       fld.Name,
+			Type:       &ast.Type{NamedType: fld.Type.NamedType},
+			Directives: deprecatedDirectives(fld),
+		})
+	}
+	if len(groupable.EnumValues) == 0 {
+		return
+	}
+
+	// A field of T that is grouped by wins over an aggregate with the same name.
+	addAggregate := func(name, typ string, dirs ast.DirectiveList) {
+		if group.Fields.ForName(name) == nil {
+			group.Fields = append(group.Fields, &ast.FieldDefinition{
+				Name:       name,
+				Type:       &ast.Type{NamedType: typ},
+				Directives: dirs,
+			})
+		}
+	}
+	addAggregate("count", "Int", nil)
+	for _, fld := range defn.Fields {
+		hasAvg, ok := aggregatable[fld.Type.NamedType]
+		if !ok || hasCustomOrLambda(fld) {
+			continue
+		}
+		addAggregate(fld.Name+"Min", fld.Type.NamedType, deprecatedDirectives(fld))
+		addAggregate(fld.Name+"Max", fld.Type.NamedType, deprecatedDirectives(fld))
+		if hasAvg {
+			addAggregate(fld.Name+"Avg", "Float", deprecatedDirectives(fld))
+		}
+	}
+	schema.Types[groupable.Name] = groupable
+	schema.Types[group.Name] = group
+
+	qry := &ast.FieldDefinition{
+		Name: "aggregate" + group.Name + "By",
+		Type: ast.ListType(&ast.Type{NamedType: group.Name}, nil),
+	}
+	if hasFilterable(defn) {
+		qry.Arguments = append(qry.Arguments, &ast.ArgumentDefinition{
+			Name: "filter",
+			Type: &ast.Type{NamedType: defn.Name + "Filter"},
+		})
+	}
+	qry.Arguments = append(qry.Arguments, &ast.ArgumentDefinition{
+		Name: "groupBy",
+		Type: &ast.Type{
+			Elem:    &ast.Type{NamedType: groupable.Name, NonNull: true},
+			NonNull: true,
+		},
+	})
+
+	schema.Query.Fields = append(schema.Query.Fields, qry)
+}
+
+// isGroupType returns true if typName is one of the group types generated by addGroupByQuery,
+// which aren't stored in Dgraph.
+func isGroupType(sch *ast.Schema, typName string) bool {
+	if !strings.HasSuffix(typName, "Group") {
+		return false
+	}
+	defn := sch.Types[strings.TrimSuffix(typName, "Group")]
+	return defn != nil && (defn.Kind == ast.Object || defn.Kind == ast.Interface) &&
+		sch.Types[typName+"able"] != nil
+}
+
 func addQueries(schema *ast.Schema, defn *ast.Definition) {
 	addGetQuery(schema, defn)
 	addPasswordQuery(schema, defn)
 	addFilterQuery(schema, defn)
+	addConnectionQuery(schema, defn)
+	addGroupByQuery(schema, defn)
 }
 
 func addAddMutation(schema *ast.Schema, defn *ast.Definition) {
@@ -1524,6 +2108,12 @@ func addAddMutation(schema *ast.Schema, defn *ast.Definition) {
 					NonNull:   true,
 				},
 			},
+			{
+				Name: "upsert",
+				Type: &ast.Type{
+					NamedType: "[Boolean!]",
+				},
+			},
 		},
 	}
 	schema.Mutation.Fields = append(schema.Mutation.Fields, add)
@@ -1618,7 +2208,7 @@ func createField(schema *ast.Schema, fld *ast.FieldDefinition) *ast.FieldDefinit
 func getNonIDFields(schema *ast.Schema, defn *ast.Definition) ast.FieldList {
 	fldList := make([]*ast.FieldDefinition, 0)
 	for _, fld := range defn.Fields {
-		if isIDField(defn, fld) || hasIDDirective(fld) {
+		if isIDField(defn, fld) || hasIDDirective(fld) || isCompositeIDField(defn, fld) {
 			continue
 		}
 
@@ -1681,7 +2271,12 @@ func getFieldsWithoutIDType(schema *ast.Schema, defn *ast.Definition) ast.FieldL
 			(!hasID(schema.Types[fld.Type.Name()]) && !hasXID(schema.Types[fld.Type.Name()])) {
 			continue
 		}
-		fldList = append(fldList, createField(schema, fld))
+
+		newFld := createField(schema, fld)
+		if hasDefault(fld) {
+			newFld.Type.NonNull = false
+		}
+		fldList = append(fldList, newFld)
 	}
 
 	pd := getPasswordField(defn)
@@ -1830,7 +2425,8 @@ func generateEnumString(typ *ast.Definition) string {
 			if d := generateDescription(val.Description); d != "" {
 				x.Check2(sch.WriteString(fmt.Sprintf("\t%s", d)))
 			}
-			x.Check2(sch.WriteString(fmt.Sprintf("\t%s\n", val.Name)))
+			x.Check2(sch.WriteString(fmt.Sprintf("\t%s%s\n", val.Name,
+				genDirectivesString(val.Directives))))
 		}
 	}
 	x.Check2(sch.WriteString("}\n"))
@@ -1864,6 +2460,11 @@ func generateObjectString(typ *ast.Definition) string {
 		genFieldsString(typ.Fields))
 }
 
+func generateScalarString(typ *ast.Definition) string {
+	return fmt.Sprintf("%sscalar %s%s\n",
+		generateDescription(typ.Description), typ.Name, genDirectivesString(typ.Directives))
+}
+
 func generateUnionString(typ *ast.Definition) string {
 	return fmt.Sprintf("%sunion %s%s = %s\n",
 		generateDescription(typ.Description), typ.Name, genDirectivesString(typ.Directives),
@@ -1941,7 +2542,7 @@ func Stringify(schema *ast.Schema, originalTypes []string) string {
 	sort.Strings(typeNames)
 
 	// Now consider the types generated by completeSchema, which can only be
-	// types, inputs and enums
+	// types, inputs and enums, plus the scalar and union needed for Apollo Federation
 	for _, typName := range typeNames {
 		typ := schema.Types[typName]
 		switch typ.Kind {
@@ -1951,6 +2552,10 @@ func Stringify(schema *ast.Schema, originalTypes []string) string {
 			x.Check2(input.WriteString(generateInputString(typ) + "\n"))
 		case ast.Enum:
 			x.Check2(enum.WriteString(generateEnumString(typ) + "\n"))
+		case ast.Union:
+			x.Check2(object.WriteString(generateUnionString(typ) + "\n"))
+		case ast.Scalar:
+			x.Check2(object.WriteString(generateScalarString(typ) + "\n"))
 		}
 	}
 
@@ -32,10 +32,23 @@ type Request struct {
 	Query         string                 `json:"query"`
 	OperationName string                 `json:"operationName"`
 	Variables     map[string]interface{} `json:"variables"`
+	Extensions    RequestExtensions      `json:"extensions"`
 
 	Header http.Header
 }
 
+// RequestExtensions are the extensions a client can send along with a request.
+type RequestExtensions struct {
+	PersistedQuery PersistedQuery `json:"persistedQuery"`
+}
+
+// PersistedQuery identifies a query by the hex encoded SHA-256 hash of its text, as in the
+// Automatic Persisted Queries protocol of Apollo.
+type PersistedQuery struct {
+	Version    int    `json:"version"`
+	Sha256Hash string `json:"sha256Hash"`
+}
+
 // Operation finds the operation in req, if it is a valid request for GraphQL
 // schema s. If the request is GraphQL valid, it must contain a single valid
 // Operation.  If either the request is malformed or doesn't contain a valid
@@ -76,6 +89,15 @@ func (s *schema) Operation(req *Request) (Operation, error) {
 	if gqlErr != nil {
 		return nil, gqlErr
 	}
+	if errs := coerceVariables(s.schema, op, vars); errs != nil {
+		return nil, errs
+	}
+	if err := checkComplexity(op, vars); err != nil {
+		return nil, err
+	}
+	if err := checkGeoFilters(s.schema, op.SelectionSet, vars); err != nil {
+		return nil, err
+	}
 
 	operation := &operation{op: op,
 		vars:                    vars,
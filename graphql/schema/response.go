@@ -80,7 +80,7 @@ func (r *Response) WithError(err error) {
 		return
 	}
 
-	r.Errors = append(r.Errors, AsGQLErrors(err)...)
+	r.Errors = append(r.Errors, redact(AsGQLErrors(err))...)
 }
 
 // AddData adds p to r's data buffer.  If p is empty, the call has no effect.
@@ -18,6 +18,7 @@ package schema
 
 import (
 	"fmt"
+	"net"
 	"net/http"
 	"net/url"
 	"sort"
@@ -39,7 +40,7 @@ func init() {
 	schemaValidations = append(schemaValidations, dgraphDirectivePredicateValidation)
 	typeValidations = append(typeValidations, idCountCheck, dgraphDirectiveTypeValidation,
 		passwordDirectiveValidation, conflictingDirectiveValidation, nonIdFieldsCheck,
-		remoteTypeValidation)
+		remoteTypeValidation, keyDirectiveValidation, compositeIDValidation)
 	fieldValidations = append(fieldValidations, listValidityCheck, fieldArgumentCheck,
 		fieldNameCheck, isValidFieldForList, hasAuthDirective)
 
@@ -47,6 +48,8 @@ func init() {
 	validator.AddRule("Check for list type value", listTypeCheck)
 	validator.AddRule("Check arguments of cascade directive", directiveArgumentsCheck)
 	validator.AddRule("Check range for Int type", intRangeCheck)
+	validator.AddRule("Check values of custom scalars", customScalarCheck)
+	validator.AddRule("Check values of uploads", uploadValueCheck)
 
 }
 
@@ -253,25 +256,48 @@ func typeNameValidation(schema *ast.SchemaDocument) gqlerror.List {
 	var errs []*gqlerror.Error
 	forbiddenTypeNames := map[string]bool{
 		// The static types that we define in schemaExtras
-		"Int64":                true,
-		"DateTime":             true,
-		"DgraphIndex":          true,
-		"AuthRule":             true,
-		"HTTPMethod":           true,
-		"Mode":                 true,
-		"CustomHTTP":           true,
-		"IntFilter":            true,
-		"Int64Filter":          true,
-		"FloatFilter":          true,
-		"DateTimeFilter":       true,
-		"StringTermFilter":     true,
-		"StringRegExpFilter":   true,
-		"StringFullTextFilter": true,
-		"StringExactFilter":    true,
-		"StringHashFilter":     true,
-		"PointGeoFilter":       true,
-		"PointRef":             true,
-		"NearFilter":           true,
+		"Int64":                 true,
+		"DateTime":              true,
+		"BigInt":                true,
+		"Decimal":               true,
+		"URL":                   true,
+		"Duration":              true,
+		"Upload":                true,
+		"DgraphIndex":           true,
+		"AuthRule":              true,
+		"AuthInheritance":       true,
+		"HTTPMethod":            true,
+		"Mode":                  true,
+		"CustomHTTP":            true,
+		"CustomGRPC":            true,
+		"NgramOptions":          true,
+		"VectorMetric":          true,
+		"HNSWOptions":           true,
+		"IntFilter":             true,
+		"Int64Filter":           true,
+		"BigIntFilter":          true,
+		"DecimalFilter":         true,
+		"URLFilter":             true,
+		"DurationFilter":        true,
+		"FloatFilter":           true,
+		"DateTimeFilter":        true,
+		"StringTermFilter":      true,
+		"StringRegExpFilter":    true,
+		"StringNgramFilter":     true,
+		"StringSoundexFilter":   true,
+		"StringMetaphoneFilter": true,
+		"SimilarToFilter":       true,
+		"VectorFilter":          true,
+		"StringFullTextFilter":  true,
+		"StringExactFilter":     true,
+		"StringHashFilter":      true,
+		"PointGeoFilter":        true,
+		"PointRef":              true,
+		"NearFilter":            true,
+		// The types generated for Apollo Federation
+		"_Any":     true,
+		"_Entity":  true,
+		"_Service": true,
 	}
 
 	for _, defn := range schema.Definitions {
@@ -295,10 +321,11 @@ func typeNameValidation(schema *ast.SchemaDocument) gqlerror.List {
 		// adding the types that we dynamically generate to forbidden names
 		switch {
 		case defn.Kind == ast.Union:
-			// for unions we generate only `Ref` and `Filter` inputs and a `Type` enum
+			// for unions we generate only `Ref`, `Filter` and `Order` inputs and a `Type` enum
 			forbiddenTypeNames[defName+"Ref"] = true
 			forbiddenTypeNames[defName+"Filter"] = true
 			forbiddenTypeNames[defName+"Type"] = true
+			forbiddenTypeNames[defName+"Order"] = true
 		case defn.Kind == ast.Object || defn.Kind == ast.Interface:
 			// types that are generated by us for objects and interfaces
 			forbiddenTypeNames[defName+"Ref"] = true
@@ -313,8 +340,13 @@ func typeNameValidation(schema *ast.SchemaDocument) gqlerror.List {
 			}
 
 			forbiddenTypeNames[defName+"Filter"] = true
+			forbiddenTypeNames[defName+"ListFilter"] = true
 			forbiddenTypeNames[defName+"Order"] = true
 			forbiddenTypeNames[defName+"Orderable"] = true
+			forbiddenTypeNames[defName+"Connection"] = true
+			forbiddenTypeNames[defName+"Edge"] = true
+			forbiddenTypeNames[defName+"Group"] = true
+			forbiddenTypeNames[defName+"Groupable"] = true
 		}
 	}
 
@@ -355,6 +387,8 @@ func customQueryNameValidation(schema *ast.SchemaDocument) gqlerror.List {
 		forbiddenNames["get"+defName] = true
 		forbiddenNames["check"+defName+"Password"] = true
 		forbiddenNames["query"+defName] = true
+		forbiddenNames["query"+defName+"Connection"] = true
+		forbiddenNames["aggregate"+defName+"GroupBy"] = true
 	}
 
 	for _, qry := range definedQueries {
@@ -589,6 +623,120 @@ func dgraphDirectiveTypeValidation(schema *ast.Schema, typ *ast.Definition) gqle
 	return nil
 }
 
+// keyDirectiveValidation checks that a type with @key, which makes it an Apollo Federation
+// entity, names a single field by which we can look the entity up, i.e. its ID field or a field
+// with the @id directive.
+func keyDirectiveValidation(schema *ast.Schema, typ *ast.Definition) gqlerror.List {
+	keys := typ.Directives.ForNames(keyDirective)
+	if len(keys) == 0 {
+		return nil
+	}
+
+	if len(keys) > 1 {
+		return []*gqlerror.Error{gqlerror.ErrorPosf(keys[1].Position,
+			"Type %s; has more than one @key directive, only a single key is supported.",
+			typ.Name)}
+	}
+
+	if typ.Directives.ForName(remoteDirective) != nil {
+		return []*gqlerror.Error{gqlerror.ErrorPosf(keys[0].Position,
+			"Type %s; cannot have both @%s and @%s directive", typ.Name, keyDirective,
+			remoteDirective)}
+	}
+
+	fields := keys[0].Arguments.ForName(keyArg)
+	if fields == nil || fields.Value.Kind != ast.StringValue {
+		return []*gqlerror.Error{gqlerror.ErrorPosf(keys[0].Position,
+			"Type %s; fields argument for @key directive should be of type String.", typ.Name)}
+	}
+
+	name := strings.TrimSpace(fields.Value.Raw)
+	if strings.ContainsAny(name, " \t\n{}") {
+		return []*gqlerror.Error{gqlerror.ErrorPosf(keys[0].Position,
+			"Type %s; @key directive has fields \"%s\", but compound keys aren't supported. "+
+				"Use a single field of type ID or with the @id directive.", typ.Name, name)}
+	}
+
+	fld := typ.Fields.ForName(name)
+	if fld == nil {
+		return []*gqlerror.Error{gqlerror.ErrorPosf(keys[0].Position,
+			"Type %s; @key directive has fields \"%s\", but there's no such field in the type.",
+			typ.Name, name)}
+	}
+
+	if !isIDField(typ, fld) && !hasIDDirective(fld) {
+		return []*gqlerror.Error{gqlerror.ErrorPosf(keys[0].Position,
+			"Type %s; Field %s: used in @key directive must be of type ID or have the @id "+
+				"directive.", typ.Name, fld.Name)}
+	}
+
+	return nil
+}
+
+// compositeIDValidation checks that the fields named by @id(composite: [...]) on a type can
+// together identify its objects: each must be a non-null String, Int, Int64 or enum that's
+// stored by the type itself.
+func compositeIDValidation(schema *ast.Schema, typ *ast.Definition) gqlerror.List {
+	ids := typ.Directives.ForNames(idDirective)
+	if len(ids) == 0 || typ.Kind != ast.Object {
+		return nil
+	}
+
+	if len(ids) > 1 {
+		return []*gqlerror.Error{gqlerror.ErrorPosf(ids[1].Position,
+			"Type %s; has more than one @id directive, only a single composite key is "+
+				"supported.", typ.Name)}
+	}
+
+	arg := ids[0].Arguments.ForName(idCompositeArg)
+	if arg == nil || arg.Value == nil || arg.Value.Kind != ast.ListValue ||
+		len(arg.Value.Children) < 2 {
+		return []*gqlerror.Error{gqlerror.ErrorPosf(ids[0].Position,
+			"Type %s; @id on a type needs a composite argument that lists at least two "+
+				"fields. Use @id on the field for a key of a single field.", typ.Name)}
+	}
+
+	var errs []*gqlerror.Error
+	seen := make(map[string]bool)
+	for _, name := range arg.Value.Children {
+		fldName := name.Value.Raw
+		fld := typ.Fields.ForName(fldName)
+		switch {
+		case fld == nil:
+			errs = append(errs, gqlerror.ErrorPosf(name.Value.Position,
+				"Type %s; @id has the composite field %s, but there's no such field in the "+
+					"type.", typ.Name, fldName))
+		case seen[fldName]:
+			errs = append(errs, gqlerror.ErrorPosf(name.Value.Position,
+				"Type %s; @id has the composite field %s more than once.", typ.Name, fldName))
+		case isIDField(typ, fld) || fld.Type.Elem != nil || !fld.Type.NonNull ||
+			!isCompositeIDType(schema, fld.Type.Name()):
+			errs = append(errs, gqlerror.ErrorPosf(name.Value.Position,
+				"Type %s; Field %s: used in a composite @id must be of type String!, Int!, "+
+					"Int64! or a non-null enum, not %s.", typ.Name, fldName, fld.Type.String()))
+		case hasCustomOrLambda(fld):
+			errs = append(errs, gqlerror.ErrorPosf(name.Value.Position,
+				"Type %s; Field %s: used in a composite @id can't have @custom or @lambda.",
+				typ.Name, fldName))
+		case parentInterface(schema, typ, fldName) != nil:
+			errs = append(errs, gqlerror.ErrorPosf(name.Value.Position,
+				"Type %s; Field %s: used in a composite @id can't be inherited from an "+
+					"interface.", typ.Name, fldName))
+		}
+		seen[fldName] = true
+	}
+	return errs
+}
+
+func isCompositeIDType(schema *ast.Schema, name string) bool {
+	switch name {
+	case "String", "Int", "Int64":
+		return true
+	}
+	def := schema.Types[name]
+	return def != nil && def.Kind == ast.Enum
+}
+
 // A type should have other fields apart from fields of
 // 1. Type ID!
 // 2. Fields with @custom directive.
@@ -741,12 +889,14 @@ func isValidFieldForList(typ *ast.Definition, field *ast.FieldDefinition) gqlerr
 		return nil
 	}
 
-	// ID and Boolean list are not allowed.
+	// ID, Boolean and Upload lists are not allowed.
 	// [Boolean] is not allowed as dgraph schema doesn't support [bool] yet.
+	// [Upload] is not allowed as queries return a single reference to the content of the field.
 	switch field.Type.Elem.Name() {
 	case
 		"ID",
-		"Boolean":
+		"Boolean",
+		Upload:
 		return []*gqlerror.Error{gqlerror.ErrorPosf(
 			field.Position, "Type %s; Field %s: %s lists are invalid.",
 			typ.Name, field.Name, field.Type.Elem.Name())}
@@ -964,6 +1114,13 @@ func validateSearchArg(searchArg string,
 			typ.Name, field.Name, searchArg, field.Type.Name(),
 			supportedSearches[searchArg].gqlType, searchMessage(sch, field))
 
+	case searchArg == "hnsw" && field.Type.Elem == nil:
+		return gqlerror.ErrorPosf(
+			dir.Position,
+			"Type %s; Field %s: has the @search directive but the argument hnsw applies "+
+				"only to lists of Float, like [Float!], which are stored as vectors.",
+			typ.Name, field.Name)
+
 	case isEnum && !enumDirectives[searchArg]:
 		return gqlerror.ErrorPosf(
 			dir.Position,
@@ -998,7 +1155,7 @@ func searchValidation(
 		// for that type.
 		if sch.Types[field.Type.Name()].Kind == ast.Enum || isGeoType(field.Type) ||
 			(sch.Types[field.Type.Name()].Kind == ast.Scalar && !isIDField(typ, field)) {
-			return nil
+			return searchOptionsValidation(typ, field, dir, getSearchArgs(field))
 		}
 
 		errs = append(errs, gqlerror.ErrorPosf(
@@ -1063,7 +1220,64 @@ func searchValidation(
 		searchIndexes[searchIndex] = searchArg
 	}
 
-	return errs
+	return searchOptionsValidation(typ, field, dir, searchArgs)
+}
+
+// searchOptionsValidation checks the options of the indexes given to @search, like
+// @search(by: [ngram], ngram: {min: 2, max: 4}) or @search(by: [day], tz: "Asia/Kolkata").
+func searchOptionsValidation(
+	typ *ast.Definition,
+	field *ast.FieldDefinition,
+	dir *ast.Directive,
+	searchArgs []string) gqlerror.List {
+
+	searchesBy := func(args ...string) bool {
+		for _, searchArg := range searchArgs {
+			for _, arg := range args {
+				if searchArg == arg {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	if dir.Arguments.ForName(searchNgramArg) != nil && !searchesBy("ngram") {
+		return []*gqlerror.Error{gqlerror.ErrorPosf(
+			dir.Position,
+			"Type %s; Field %s: the ngram argument to @search can only be given when "+
+				"searching by ngram.",
+			typ.Name, field.Name)}
+	}
+	if dir.Arguments.ForName(searchTzArg) != nil && !searchesBy("year", "month", "day", "hour") {
+		return []*gqlerror.Error{gqlerror.ErrorPosf(
+			dir.Position,
+			"Type %s; Field %s: the tz argument to @search can only be given when "+
+				"searching by year, month, day or hour.",
+			typ.Name, field.Name)}
+	}
+	if dir.Arguments.ForName(searchHNSWArg) != nil && !searchesBy("hnsw") {
+		return []*gqlerror.Error{gqlerror.ErrorPosf(
+			dir.Position,
+			"Type %s; Field %s: the hnsw argument to @search can only be given when "+
+				"searching by hnsw.",
+			typ.Name, field.Name)}
+	}
+	if searchesBy("hnsw") && len(searchArgs) > 1 {
+		return []*gqlerror.Error{gqlerror.ErrorPosf(
+			dir.Position,
+			"Type %s; Field %s: hnsw can't be used together with other arguments to "+
+				"@search, as the field is stored as a vector.",
+			typ.Name, field.Name)}
+	}
+	for _, searchArg := range searchArgs {
+		if _, err := searchIndex(dir, searchArg); err != nil {
+			return []*gqlerror.Error{gqlerror.ErrorPosf(
+				dir.Position,
+				"Type %s; Field %s: the options of @search aren't valid for %s: %s.",
+				typ.Name, field.Name, searchArg, err)}
+		}
+	}
+	return nil
 }
 
 func dgraphDirectiveValidation(sch *ast.Schema, typ *ast.Definition, field *ast.FieldDefinition,
@@ -1216,13 +1430,13 @@ func lambdaDirectiveValidation(sch *ast.Schema,
 	field *ast.FieldDefinition,
 	dir *ast.Directive,
 	secrets map[string]x.SensitiveByteSlice) gqlerror.List {
-	// if the lambda url wasn't specified during alpha startup,
+	// if neither the lambda url nor the lambda script was specified during alpha startup,
 	// just return that error. Don't confuse the user with errors from @custom yet.
-	if x.Config.GraphqlLambdaUrl == "" {
+	if x.Config.GraphqlLambdaUrl == "" && x.Config.GraphqlLambdaScript == "" {
 		return []*gqlerror.Error{gqlerror.ErrorPosf(dir.Position,
-			"Type %s; Field %s: has the @lambda directive, but the "+
-				"`--graphql_lambda_url` flag wasn't specified during alpha startup.",
-			typ.Name, field.Name)}
+			"Type %s; Field %s: has the @lambda directive, but neither the "+
+				"`--graphql_lambda_url` nor the `--graphql_lambda_script` flag was specified "+
+				"during alpha startup.", typ.Name, field.Name)}
 	}
 	// reuse @custom directive validation
 	errs := customDirectiveValidation(sch, typ, field, buildCustomDirectiveForLambda(typ, field,
@@ -1276,18 +1490,19 @@ func customDirectiveValidation(sch *ast.Schema,
 		errs = append(errs, gqlerror.ErrorPosf(
 			dir.Position,
 			"Type %s; Field %s: has %d arguments for @custom directive, "+
-				"it should contain exactly one of `http` or `dql` arguments.",
+				"it should contain exactly one of `http`, `grpc` or `dql` arguments.",
 			typ.Name, field.Name, l))
 	}
 
 	httpArg := dir.Arguments.ForName(httpArg)
+	grpcArg := dir.Arguments.ForName(grpcArg)
 	dqlArg := dir.Arguments.ForName(dqlArg)
 
-	if httpArg == nil && dqlArg == nil {
+	if httpArg == nil && grpcArg == nil && dqlArg == nil {
 		errs = append(errs, gqlerror.ErrorPosf(
 			dir.Position,
-			"Type %s; Field %s: one of `http` or `dql` arguments must be present for @custom"+
-				" directive.",
+			"Type %s; Field %s: one of `http`, `grpc` or `dql` arguments must be present for"+
+				" @custom directive.",
 			typ.Name, field.Name))
 		return errs
 	}
@@ -1334,7 +1549,12 @@ func customDirectiveValidation(sch *ast.Schema,
 		return errs
 	}
 
-	// 3.2 Validating http argument
+	// 3.2 Validating grpc argument
+	if grpcArg != nil {
+		return append(errs, customGRPCValidation(typ, field, grpcArg, id, xid)...)
+	}
+
+	// 3.3 Validating http argument
 	// if we reach here, it means that httpArg != nil
 	if httpArg.Value.String() == "" {
 		errs = append(errs, gqlerror.ErrorPosf(
@@ -1717,46 +1937,8 @@ func customDirectiveValidation(sch *ast.Schema,
 				errIn = "@custom"
 			}
 
-			requiresID := false
-			for fname := range requiredFields {
-				if fname == field.Name {
-					errs = append(errs, gqlerror.ErrorPosf(errPos,
-						"Type %s; Field %s; @custom directive, %s can't require itself.",
-						typ.Name, field.Name, errIn))
-				}
-
-				fd := typ.Fields.ForName(fname)
-				if fd == nil {
-					errs = append(errs, gqlerror.ErrorPosf(errPos,
-						"Type %s; Field %s; @custom directive, %s must use fields defined "+
-							"within the type, found `%s`.", typ.Name, field.Name, errIn, fname))
-					continue
-				}
-
-				typName := fd.Type.Name()
-				if !isScalar(typName) {
-					errs = append(errs, gqlerror.ErrorPosf(errPos,
-						"Type %s; Field %s; @custom directive, %s must use scalar fields, "+
-							"found field `%s` of type `%s`.", typ.Name, field.Name, errIn,
-						fname, typName))
-				}
-
-				if hasCustomOrLambda(fd) {
-					errs = append(errs, gqlerror.ErrorPosf(errPos,
-						"Type %s; Field %s; @custom directive, %s can't use another field with "+
-							"@custom/@lambda directive, found field `%s` with @custom/@lambda.",
-						typ.Name, field.Name, errIn, fname))
-				}
-
-				if fname == idField || fname == xidField {
-					requiresID = true
-				}
-			}
-			if !requiresID {
-				errs = append(errs, gqlerror.ErrorPosf(errPos,
-					"Type %s; Field %s: @custom directive, %s must use a field with type "+
-						"ID! or a field with @id directive.", typ.Name, field.Name, errIn))
-			}
+			errs = append(errs, customRequiredFieldsValidation(typ, field, requiredFields, idField,
+				xidField, errPos, errIn)...)
 		}
 	}
 
@@ -1876,11 +2058,215 @@ func customDirectiveValidation(sch *ast.Schema,
 	return errs
 }
 
+// customRequiredFieldsValidation validates the fields of typ that are required by the body or
+// graphql of a @custom field, which must be scalar fields defined within the type that include
+// the ID! field or the field with @id directive.
+func customRequiredFieldsValidation(typ *ast.Definition,
+	field *ast.FieldDefinition,
+	requiredFields map[string]bool,
+	idField, xidField string,
+	errPos *ast.Position,
+	errIn string) gqlerror.List {
+	var errs []*gqlerror.Error
+	requiresID := false
+	for fname := range requiredFields {
+		if fname == field.Name {
+			errs = append(errs, gqlerror.ErrorPosf(errPos,
+				"Type %s; Field %s; @custom directive, %s can't require itself.",
+				typ.Name, field.Name, errIn))
+		}
+
+		fd := typ.Fields.ForName(fname)
+		if fd == nil {
+			errs = append(errs, gqlerror.ErrorPosf(errPos,
+				"Type %s; Field %s; @custom directive, %s must use fields defined "+
+					"within the type, found `%s`.", typ.Name, field.Name, errIn, fname))
+			continue
+		}
+
+		typName := fd.Type.Name()
+		if !isScalar(typName) {
+			errs = append(errs, gqlerror.ErrorPosf(errPos,
+				"Type %s; Field %s; @custom directive, %s must use scalar fields, "+
+					"found field `%s` of type `%s`.", typ.Name, field.Name, errIn,
+				fname, typName))
+		}
+
+		if hasCustomOrLambda(fd) {
+			errs = append(errs, gqlerror.ErrorPosf(errPos,
+				"Type %s; Field %s; @custom directive, %s can't use another field with "+
+					"@custom/@lambda directive, found field `%s` with @custom/@lambda.",
+				typ.Name, field.Name, errIn, fname))
+		}
+
+		if fname == idField || fname == xidField {
+			requiresID = true
+		}
+	}
+	if !requiresID {
+		errs = append(errs, gqlerror.ErrorPosf(errPos,
+			"Type %s; Field %s: @custom directive, %s must use a field with type "+
+				"ID! or a field with @id directive.", typ.Name, field.Name, errIn))
+	}
+	return errs
+}
+
+// customGRPCValidation validates the grpc argument of a @custom directive. A gRPC method is
+// always called with a request message, so unlike http there are no url parameters or graphql.
+func customGRPCValidation(typ *ast.Definition,
+	field *ast.FieldDefinition,
+	grpcArg *ast.Argument,
+	id, xid ast.FieldList) gqlerror.List {
+	var errs []*gqlerror.Error
+	if grpcArg.Value.Kind != ast.ObjectValue || grpcArg.Value.String() == "" {
+		return append(errs, gqlerror.ErrorPosf(
+			grpcArg.Position,
+			"Type %s; Field %s: grpc argument for @custom directive should be a non-empty "+
+				"Object.",
+			typ.Name, field.Name))
+	}
+
+	target := grpcArg.Value.Children.ForName(httpUrl)
+	if target == nil {
+		errs = append(errs, gqlerror.ErrorPosf(
+			grpcArg.Position,
+			"Type %s; Field %s; url field inside @custom directive is mandatory.", typ.Name,
+			field.Name))
+	} else if _, port, err := net.SplitHostPort(target.Raw); err != nil ||
+		strings.Trim(port, "0123456789") != "" {
+		errs = append(errs, gqlerror.ErrorPosf(
+			target.Position,
+			"Type %s; Field %s; url field for grpc inside @custom directive must be of the "+
+				"form host:port, found: `%s`.", typ.Name, field.Name, target.Raw))
+	}
+
+	method := grpcArg.Value.Children.ForName(httpMethod)
+	if method == nil {
+		errs = append(errs, gqlerror.ErrorPosf(
+			grpcArg.Position,
+			"Type %s; Field %s; method field inside @custom directive is mandatory.", typ.Name,
+			field.Name))
+	} else if parts := strings.Split(method.Raw, "/"); len(parts) != 2 ||
+		!strings.Contains(parts[0], ".") || parts[1] == "" {
+		errs = append(errs, gqlerror.ErrorPosf(
+			method.Position,
+			"Type %s; Field %s; method field for grpc inside @custom directive must be of the "+
+				"form package.Service/Method, found: `%s`.", typ.Name, field.Name, method.Raw))
+	}
+
+	if df := grpcArg.Value.Children.ForName(descriptorFile); df != nil &&
+		strings.TrimSpace(df.Raw) == "" {
+		errs = append(errs, gqlerror.ErrorPosf(
+			df.Position,
+			"Type %s; Field %s; descriptorFile field inside @custom directive must not be empty.",
+			typ.Name, field.Name))
+	}
+
+	if mode := grpcArg.Value.Children.ForName(mode); mode != nil {
+		if isQueryOrMutationType(typ) {
+			errs = append(errs, gqlerror.ErrorPosf(
+				mode.Position,
+				"Type %s; Field %s; mode field inside @custom directive can't be "+
+					"present on Query/Mutation.", typ.Name, field.Name))
+		}
+		if mode.Raw != SINGLE && mode.Raw != BATCH {
+			errs = append(errs, gqlerror.ErrorPosf(
+				mode.Position,
+				"Type %s; Field %s; mode field inside @custom directive can only be "+
+					"SINGLE/BATCH.", typ.Name, field.Name))
+		}
+	}
+
+	body := grpcArg.Value.Children.ForName(httpBody)
+	var requiredFields map[string]bool
+	var bodyErr error
+	if body != nil {
+		_, requiredFields, bodyErr = parseBodyTemplate(body.Raw, true)
+		if bodyErr != nil {
+			errs = append(errs, gqlerror.ErrorPosf(body.Position,
+				"Type %s; Field %s; body template inside @custom directive could not be parsed: %s",
+				typ.Name, field.Name, bodyErr.Error()))
+		}
+		if isQueryOrMutationType(typ) {
+			for fname := range requiredFields {
+				if field.Arguments.ForName(fname) == nil {
+					errs = append(errs, gqlerror.ErrorPosf(body.Position,
+						"Type %s; Field %s; body template inside @custom directive uses an"+
+							" argument %s that is not defined.", typ.Name, field.Name, fname))
+				}
+			}
+		}
+	}
+
+	if !isQueryOrMutationType(typ) {
+		var idField, xidField string
+		if len(id) > 0 {
+			idField = id[0].Name
+		}
+		if len(xid) > 0 {
+			xidField = xid[0].Name
+		}
+		if field.Name == idField || field.Name == xidField {
+			errs = append(errs, gqlerror.ErrorPosf(grpcArg.Position,
+				"Type %s; Field %s; custom directive not allowed on field of type ID! or field "+
+					"with @id directive.", typ.Name, field.Name))
+		}
+		if body == nil {
+			errs = append(errs, gqlerror.ErrorPosf(grpcArg.Position,
+				"Type %s; Field %s; body field for grpc inside @custom directive is mandatory "+
+					"on fields of types other than Query/Mutation.", typ.Name, field.Name))
+		} else if bodyErr == nil {
+			errs = append(errs, customRequiredFieldsValidation(typ, field, requiredFields,
+				idField, xidField, body.Position, "body template")...)
+		}
+	}
+
+	fHeaders := make(map[string]bool)
+	if forwardHeaders := grpcArg.Value.Children.ForName("forwardHeaders"); forwardHeaders != nil {
+		for _, h := range forwardHeaders.Children {
+			key := strings.Split(h.Value.Raw, ":")
+			if len(key) > 2 {
+				errs = append(errs, gqlerror.ErrorPosf(grpcArg.Position,
+					"Type %s; Field %s; forwardHeaders in @custom directive should be of the form 'remote_headername:local_headername' or just 'headername'"+
+						", found: `%s`.",
+					typ.Name, field.Name, h.Value.Raw))
+				continue
+			}
+			fHeaders[key[0]] = true
+		}
+	}
+	if secretHeaders := grpcArg.Value.Children.ForName("secretHeaders"); secretHeaders != nil {
+		for _, h := range secretHeaders.Children {
+			key := strings.Split(h.Value.Raw, ":")
+			switch {
+			case len(key) > 2:
+				errs = append(errs, gqlerror.ErrorPosf(grpcArg.Position,
+					"Type %s; Field %s; secretHeaders in @custom directive should be of the form 'remote_headername:local_headername' or just 'headername'"+
+						", found: `%s`.",
+					typ.Name, field.Name, h.Value.Raw))
+			case fHeaders[key[0]]:
+				errs = append(errs, gqlerror.ErrorPosf(grpcArg.Position,
+					"Type %s; Field %s; secretHeaders and forwardHeaders in @custom directive cannot have overlapping headers"+
+						", found: `%s`.",
+					typ.Name, field.Name, h.Value.Raw))
+			}
+		}
+	}
+
+	return errs
+}
+
 func idValidation(sch *ast.Schema,
 	typ *ast.Definition,
 	field *ast.FieldDefinition,
 	dir *ast.Directive,
 	secrets map[string]x.SensitiveByteSlice) gqlerror.List {
+	if dir.Arguments.ForName(idCompositeArg) != nil {
+		return []*gqlerror.Error{gqlerror.ErrorPosf(
+			dir.Position,
+			"Type %s; Field %s: the composite argument of @id can only be used on types.",
+			typ.Name, field.Name)}
+	}
 	if field.Type.String() == "String!" {
 		return nil
 	}
@@ -1890,6 +2276,43 @@ func idValidation(sch *ast.Schema,
 		typ.Name, field.Name, field.Type.String())}
 }
 
+func complexityValidation(sch *ast.Schema,
+	typ *ast.Definition,
+	field *ast.FieldDefinition,
+	dir *ast.Directive,
+	secrets map[string]x.SensitiveByteSlice) gqlerror.List {
+	if len(dir.Arguments) == 0 {
+		return []*gqlerror.Error{gqlerror.ErrorPosf(
+			dir.Position,
+			"Type %s; Field %s: @complexity needs a value or a multiplier.",
+			typ.Name, field.Name)}
+	}
+
+	var errs []*gqlerror.Error
+	if arg := dir.Arguments.ForName(complexityValueArg); arg != nil {
+		if v, ok := intDirectiveArg(dir, complexityValueArg); !ok || v < 0 {
+			errs = append(errs, gqlerror.ErrorPosf(
+				arg.Position,
+				"Type %s; Field %s: the value of @complexity must be an Int of at least 0.",
+				typ.Name, field.Name))
+		}
+	}
+	if arg := dir.Arguments.ForName(complexityMultArg); arg != nil {
+		if field.Type.Elem == nil {
+			errs = append(errs, gqlerror.ErrorPosf(
+				arg.Position,
+				"Type %s; Field %s: @complexity can have a multiplier only on list fields.",
+				typ.Name, field.Name))
+		} else if v, ok := intDirectiveArg(dir, complexityMultArg); !ok || v < 1 {
+			errs = append(errs, gqlerror.ErrorPosf(
+				arg.Position,
+				"Type %s; Field %s: the multiplier of @complexity must be an Int of at least 1.",
+				typ.Name, field.Name))
+		}
+	}
+	return errs
+}
+
 func searchMessage(sch *ast.Schema, field *ast.FieldDefinition) string {
 	var possibleSearchArgs []string
 	for name, typ := range supportedSearches {
@@ -1931,6 +2354,7 @@ func isReservedKeyWord(name string) bool {
 		"uid":          true,
 		"Subscription": true,
 		"Point":        true,
+		"PageInfo":     true,
 	}
 
 	caseInsensitiveKeywords := map[string]bool{
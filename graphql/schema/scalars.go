@@ -0,0 +1,209 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package schema
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+const (
+	BigInt   = "BigInt"
+	Decimal  = "Decimal"
+	URL      = "URL"
+	Duration = "Duration"
+)
+
+var decimalRegexp = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// customScalars are the scalars, besides the ones in the GraphQL spec, Int64 and DateTime, that
+// are stored as strings in Dgraph.  Each one maps to the function that checks a value of the
+// scalar and returns it in canonical form, so that equal values are stored, and hence searched,
+// as the same string.
+var customScalars = map[string]func(string) (string, error){
+	BigInt:   canonicalBigInt,
+	Decimal:  canonicalDecimal,
+	URL:      canonicalURL,
+	Duration: canonicalDuration,
+}
+
+// IsCustomScalar returns true if name is the name of one of the custom scalars.
+func IsCustomScalar(name string) bool {
+	_, ok := customScalars[name]
+	return ok
+}
+
+// CoerceCustomScalar checks that val is a valid value of the custom scalar typ, and returns it
+// in canonical form.
+func CoerceCustomScalar(typ, val string) (string, error) {
+	canonical, ok := customScalars[typ]
+	if !ok {
+		return "", errors.Errorf("%s isn't a custom scalar", typ)
+	}
+	return canonical(val)
+}
+
+func canonicalBigInt(val string) (string, error) {
+	i, ok := new(big.Int).SetString(val, 10)
+	if !ok {
+		return "", errors.Errorf("'%s' isn't a valid BigInt", val)
+	}
+	return i.String(), nil
+}
+
+// canonicalDecimal returns val without leading zeros in the integer part or trailing zeros in
+// the fractional part, so for example "007.50" becomes "7.5".
+func canonicalDecimal(val string) (string, error) {
+	if !decimalRegexp.MatchString(val) {
+		return "", errors.Errorf("'%s' isn't a valid Decimal", val)
+	}
+
+	negative := strings.HasPrefix(val, "-")
+	val = strings.TrimPrefix(val, "-")
+	intPart, fracPart := val, ""
+	if idx := strings.IndexByte(val, '.'); idx >= 0 {
+		intPart, fracPart = val[:idx], val[idx+1:]
+	}
+	intPart = strings.TrimLeft(intPart, "0")
+	if intPart == "" {
+		intPart = "0"
+	}
+	fracPart = strings.TrimRight(fracPart, "0")
+
+	res := intPart
+	if fracPart != "" {
+		res += "." + fracPart
+	}
+	if negative && res != "0" {
+		res = "-" + res
+	}
+	return res, nil
+}
+
+func canonicalURL(val string) (string, error) {
+	u, err := url.Parse(val)
+	if err != nil || u.Scheme == "" || (u.Host == "" && u.Opaque == "") {
+		return "", errors.Errorf("'%s' isn't a valid URL", val)
+	}
+	return val, nil
+}
+
+// canonicalDuration accepts durations like "1h30m" or "250ms", as understood by
+// time.ParseDuration, and returns them in the form that time.Duration prints them in.
+func canonicalDuration(val string) (string, error) {
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		return "", errors.Errorf("'%s' isn't a valid Duration", val)
+	}
+	return d.String(), nil
+}
+
+// coerceVariables checks the values of the variables of op that are, or contain, custom
+// scalars, and replaces them by their canonical form.  It also checks that the values of Upload
+// variables are files.
+func coerceVariables(sch *ast.Schema, op *ast.OperationDefinition,
+	vars map[string]interface{}) gqlerror.List {
+
+	var errs gqlerror.List
+	for _, def := range op.VariableDefinitions {
+		val, ok := vars[def.Variable]
+		if !ok {
+			continue
+		}
+		coerced, err := coerceVariable(sch, def.Type, val)
+		if err != nil {
+			errs = append(errs, gqlerror.ErrorPosf(def.Position, "Variable $%s: %s",
+				def.Variable, err))
+			continue
+		}
+		vars[def.Variable] = coerced
+	}
+	return errs
+}
+
+func coerceVariable(sch *ast.Schema, typ *ast.Type, val interface{}) (interface{}, error) {
+	if val == nil {
+		return nil, nil
+	}
+
+	if typ.Elem != nil {
+		list, ok := val.([]interface{})
+		if !ok {
+			return coerceVariable(sch, typ.Elem, val)
+		}
+		for i, v := range list {
+			coerced, err := coerceVariable(sch, typ.Elem, v)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = coerced
+		}
+		return list, nil
+	}
+
+	if typ.NamedType == Upload {
+		if _, ok := val.(*UploadedFile); !ok {
+			return nil, errors.Errorf("expected a file sent in a multipart request for an Upload, "+
+				"but got %v", val)
+		}
+		return val, nil
+	}
+
+	if IsCustomScalar(typ.NamedType) {
+		var str string
+		switch v := val.(type) {
+		case string:
+			str = v
+		case json.Number:
+			str = v.String()
+		case float64:
+			str = strconv.FormatFloat(v, 'f', -1, 64)
+		case int64:
+			str = strconv.FormatInt(v, 10)
+		default:
+			return nil, errors.Errorf("expected a %s, but got %v", typ.NamedType, val)
+		}
+		return CoerceCustomScalar(typ.NamedType, str)
+	}
+
+	def := sch.Types[typ.NamedType]
+	obj, ok := val.(map[string]interface{})
+	if def == nil || def.Kind != ast.InputObject || !ok {
+		return val, nil
+	}
+	for name, v := range obj {
+		fld := def.Fields.ForName(name)
+		if fld == nil {
+			continue
+		}
+		coerced, err := coerceVariable(sch, fld.Type, v)
+		if err != nil {
+			return nil, err
+		}
+		obj[name] = coerced
+	}
+	return obj, nil
+}
@@ -220,9 +220,9 @@ func NewHandler(input string, validateOnly bool) (Handler, error) {
 		return nil, gqlerror.Errorf("No query or mutation found in the generated schema")
 	}
 
-	// If Dgraph.Authorization header is parsed successfully and JWKUrl is present
-	// then Fetch the JWKs from the JWKUrl
-	if metaInfo != nil && metaInfo.JWKUrl != "" {
+	// If Dgraph.Authorization header is parsed successfully and JWKUrl or Issuers are present
+	// then Fetch the JWKs from the JWKUrl of each
+	if metaInfo != nil && (metaInfo.JWKUrl != "" || len(metaInfo.Issuers) > 0) {
 		fetchErr := metaInfo.FetchJWKs()
 		if fetchErr != nil {
 			return nil, fetchErr
@@ -276,6 +276,9 @@ func getAllowedHeaders(sch *ast.Schema, definitions []string, authHeader string)
 		}
 
 		httpArg := dir.Arguments.ForName("http")
+		if httpArg == nil {
+			httpArg = dir.Arguments.ForName(grpcArg)
+		}
 		if httpArg == nil {
 			return
 		}
@@ -327,11 +330,13 @@ func AllowedHeaders() string {
 	return hc.allowed
 }
 
-func getAllSearchIndexes(val *ast.Value) []string {
+func getAllSearchIndexes(search *ast.Directive) []string {
+	val := search.Arguments.ForName(searchArgs).Value
 	res := make([]string, len(val.Children))
 
 	for i, child := range val.Children {
-		res[i] = supportedSearches[child.Value.Raw].dgIndex
+		// The options of the indexes were checked while validating the schema.
+		res[i], _ = searchIndex(search, child.Value.Raw)
 	}
 
 	return res
@@ -379,6 +384,7 @@ func genDgSchema(gqlSch *ast.Schema, definitions []string) string {
 		indexes map[string]bool
 		upsert  string
 		reverse string
+		enum    string
 	}
 
 	type field struct {
@@ -478,6 +484,9 @@ func genDgSchema(gqlSch *ast.Schema, definitions []string) string {
 						"%s%s%s",
 						prefix, inbuiltTypeToDgraph[f.Type.Name()], suffix,
 					)
+					if isVectorField(f) {
+						typStr = "float32vector"
+					}
 
 					var indexes []string
 					upsertStr := ""
@@ -487,14 +496,24 @@ func genDgSchema(gqlSch *ast.Schema, definitions []string) string {
 						upsertStr = "@upsert "
 						indexes = append(indexes, "hash")
 					}
+					if isCompositeIDField(def, f) {
+						// The parts of a composite key are looked up with eq() when objects
+						// are added, so they need an index that supports it.
+						upsertStr = "@upsert "
+						if f.Type.Name() == "String" {
+							indexes = append(indexes, "hash")
+						} else {
+							indexes = append(indexes, "int")
+						}
+					}
 
 					if search != nil {
 						arg := search.Arguments.ForName(searchArgs)
 						if arg != nil {
-							indexes = append(indexes, getAllSearchIndexes(arg.Value)...)
+							indexes = append(indexes, getAllSearchIndexes(search)...)
 						} else {
-							indexes = append(indexes, supportedSearches[defaultSearches[f.Type.
-								Name()]].dgIndex)
+							index, _ := searchIndex(search, defaultSearches[f.Type.Name()])
+							indexes = append(indexes, index)
 						}
 					}
 
@@ -510,11 +529,23 @@ func genDgSchema(gqlSch *ast.Schema, definitions []string) string {
 					if search != nil {
 						arg := search.Arguments.ForName(searchArgs)
 						if arg != nil {
-							indexes = getAllSearchIndexes(arg.Value)
+							indexes = getAllSearchIndexes(search)
 						}
 					}
+					upsertStr := ""
+					if isCompositeIDField(def, f) {
+						upsertStr = "@upsert "
+						indexes = append(indexes, "hash")
+					}
 					if parentInt == nil {
-						dgPreds[fname] = getUpdatedPred(fname, typStr, "", indexes)
+						pred := getUpdatedPred(fname, typStr, upsertStr, indexes)
+						// The values of the enum are checked by Dgraph as well and stored as ids.
+						var values []string
+						for _, val := range gqlSch.Types[f.Type.Name()].EnumValues {
+							values = append(values, val.Name)
+						}
+						pred.enum = fmt.Sprintf("@enum(%s) ", strings.Join(values, ", "))
+						dgPreds[fname] = pred
 					}
 					typ.fields = append(typ.fields, field{fname, parentInt != nil})
 				}
@@ -556,8 +587,8 @@ func genDgSchema(gqlSch *ast.Schema, definitions []string) string {
 					sort.Strings(indexes)
 					indexStr = fmt.Sprintf(" @index(%s)", strings.Join(indexes, ", "))
 				}
-				fmt.Fprintf(&preds, "%s: %s%s %s%s.\n", fld.name, f.typ, indexStr, f.upsert,
-					f.reverse)
+				fmt.Fprintf(&preds, "%s: %s%s %s%s%s.\n", fld.name, f.typ, indexStr, f.upsert,
+					f.reverse, f.enum)
 				predWritten[fld.name] = true
 			}
 		}
@@ -0,0 +1,85 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package schema
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Upload is the scalar for files sent as part of a multipart request, as described in
+// https://github.com/jaydenseric/graphql-multipart-request-spec
+const Upload = "Upload"
+
+// UploadedFile is the value of an Upload variable.  It holds the whole content of the file.
+type UploadedFile struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"contentType"`
+	Content     []byte `json:"content"`
+}
+
+// SetUpload sets the value at path in the variables of r to file.  The path is an object path
+// as given in the map of a multipart request, like "variables.input.file" or
+// "variables.files.0".  The value at path must be null, as the multipart request spec requires
+// it to be the placeholder for the file.
+func (r *Request) SetUpload(path string, file *UploadedFile) error {
+	parts := strings.Split(path, ".")
+	if len(parts) < 2 || parts[0] != "variables" {
+		return errors.Errorf("can't set a file at %s, only paths into the variables are supported",
+			path)
+	}
+
+	var parent interface{} = r.Variables
+	for i, part := range parts[1:] {
+		last := i == len(parts)-2
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			val, ok := p[part]
+			if !ok {
+				return errors.Errorf("can't set a file at %s, %s isn't in the variables",
+					path, part)
+			}
+			if last {
+				if val != nil {
+					return errors.Errorf("can't set a file at %s, the value there isn't null", path)
+				}
+				p[part] = file
+				return nil
+			}
+			parent = val
+		case []interface{}:
+			idx, err := strconv.Atoi(part)
+			if err != nil || idx < 0 || idx >= len(p) {
+				return errors.Errorf("can't set a file at %s, %s isn't a valid index", path, part)
+			}
+			if last {
+				if p[idx] != nil {
+					return errors.Errorf("can't set a file at %s, the value there isn't null", path)
+				}
+				p[idx] = file
+				return nil
+			}
+			parent = p[idx]
+		default:
+			return errors.Errorf("can't set a file at %s, %s isn't an object or a list",
+				path, strings.Join(parts[:i+1], "."))
+		}
+	}
+	return nil
+}
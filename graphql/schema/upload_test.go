@@ -0,0 +1,81 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetUpload(t *testing.T) {
+	file := &UploadedFile{Filename: "a.txt", Content: []byte("a")}
+
+	tests := map[string]struct {
+		path     string
+		expected map[string]interface{}
+		err      string
+	}{
+		"file in an object": {
+			path: "variables.input.file",
+			expected: map[string]interface{}{
+				"input": map[string]interface{}{"file": file},
+				"files": []interface{}{nil, nil},
+			},
+		},
+		"file in a list": {
+			path: "variables.files.1",
+			expected: map[string]interface{}{
+				"input": map[string]interface{}{"file": nil},
+				"files": []interface{}{nil, file},
+			},
+		},
+		"path outside the variables": {
+			path: "query",
+			err:  "can't set a file at query, only paths into the variables are supported",
+		},
+		"missing variable": {
+			path: "variables.other",
+			err:  "can't set a file at variables.other, other isn't in the variables",
+		},
+		"index out of range": {
+			path: "variables.files.2",
+			err:  "can't set a file at variables.files.2, 2 isn't a valid index",
+		},
+		"value that isn't null": {
+			path: "variables.input",
+			err:  "can't set a file at variables.input, the value there isn't null",
+		},
+	}
+
+	for name, tcase := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := &Request{Variables: map[string]interface{}{
+				"input": map[string]interface{}{"file": nil},
+				"files": []interface{}{nil, nil},
+			}}
+
+			err := req.SetUpload(tcase.path, file)
+			if tcase.err != "" {
+				require.EqualError(t, err, tcase.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tcase.expected, req.Variables)
+		})
+	}
+}
@@ -69,14 +69,22 @@ func variableTypeCheck(observers *validator.Events, addError validator.AddErrFun
 func directiveArgumentsCheck(observers *validator.Events, addError validator.AddErrFunc) {
 	observers.OnDirective(func(walker *validator.Walker, directive *ast.Directive) {
 
-		if directive.Name == cascadeDirective && len(directive.Arguments) == 1 {
-			if directive.ParentDefinition == nil {
-				addError(validator.Message("Schema is not set yet. Please try after sometime."))
-				return
+		if directive.Name == cascadeDirective && len(directive.Arguments) > 0 {
+			if depth := directive.Arguments.ForName(cascadeDepthArg); depth != nil &&
+				depth.Value.Kind == ast.IntValue {
+				if d, err := strconv.Atoi(depth.Value.Raw); err != nil || d <= 0 {
+					addError(validator.Message("Value of `depth` in @cascade must be a positive "+
+						"integer, got %s", depth.Value.Raw), validator.At(depth.Position))
+					return
+				}
 			}
 			if directive.Arguments.ForName(cascadeArg) == nil {
 				return
 			}
+			if directive.ParentDefinition == nil {
+				addError(validator.Message("Schema is not set yet. Please try after sometime."))
+				return
+			}
 			typFields := directive.ParentDefinition.Fields
 			for _, child := range directive.Arguments.ForName(cascadeArg).Value.Children {
 				if typFields.ForName(child.Value.Raw) == nil {
@@ -132,6 +140,41 @@ func intRangeCheck(observers *validator.Events, addError validator.AddErrFunc) {
 	})
 }
 
+// customScalarCheck checks the literal values of the custom scalars, and puts them in canonical
+// form.  BigInt and Decimal values can be given as numbers, but are passed on as strings, so that
+// no precision is lost.
+func customScalarCheck(observers *validator.Events, addError validator.AddErrFunc) {
+	observers.OnValue(func(walker *validator.Walker, value *ast.Value) {
+		if value.Definition == nil || value.ExpectedType == nil || value.Kind == ast.Variable ||
+			value.Kind == ast.NullValue || value.Kind == ast.ListValue ||
+			!IsCustomScalar(value.Definition.Name) {
+			return
+		}
+
+		switch value.Kind {
+		case ast.StringValue, ast.BlockValue:
+		case ast.IntValue, ast.FloatValue:
+			if value.Definition.Name == BigInt || value.Definition.Name == Decimal {
+				break
+			}
+			fallthrough
+		default:
+			addError(validator.Message("Type mismatched for Value `%s`, expected: %s, got: '%s'",
+				value.Raw, value.Definition.Name, valueKindToString(value.Kind)),
+				validator.At(value.Position))
+			return
+		}
+
+		canonical, err := CoerceCustomScalar(value.Definition.Name, value.Raw)
+		if err != nil {
+			addError(validator.Message("%s", err), validator.At(value.Position))
+			return
+		}
+		value.Kind = ast.StringValue
+		value.Raw = canonical
+	})
+}
+
 func valueKindToString(valKind ast.ValueKind) string {
 	switch valKind {
 	case ast.Variable:
@@ -157,3 +200,18 @@ func valueKindToString(valKind ast.ValueKind) string {
 	}
 	return ""
 }
+
+// uploadValueCheck checks that Upload values are given as variables, as files can only be sent as
+// the values of variables in a multipart request.
+func uploadValueCheck(observers *validator.Events, addError validator.AddErrFunc) {
+	observers.OnValue(func(walker *validator.Walker, value *ast.Value) {
+		if value.Definition == nil || value.ExpectedType == nil || value.Kind == ast.Variable ||
+			value.Kind == ast.NullValue || value.Kind == ast.ListValue ||
+			value.Definition.Name != Upload {
+			return
+		}
+
+		addError(validator.Message("Value `%s` can't be used as an Upload, files must be sent as "+
+			"variables of a multipart request", value.Raw), validator.At(value.Position))
+	})
+}
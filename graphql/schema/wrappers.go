@@ -69,8 +69,30 @@ type FieldHTTPConfig struct {
 	// the GraphqlBatchModeArgument would be sinput, we use it to know the GraphQL variable that
 	// we should send the data in.
 	GraphqlBatchModeArgument string
+
+	// would be nil for HTTP requests. For gRPC requests, URL is the host:port of the server and
+	// Method is the fully qualified method name like package.Service/Method.
+	GRPC *GRPCConfig
+
+	// Lambda is true for @lambda fields resolved by the script given with
+	// --graphql_lambda_script, instead of by a lambda server.
+	Lambda bool
+}
+
+// GRPCConfig contains the extra config needed to resolve a field using a remote gRPC method.
+type GRPCConfig struct {
+	// DescriptorFile is the path of a FileDescriptorSet describing the service. If it is empty,
+	// the descriptors are fetched from the server using gRPC server reflection.
+	DescriptorFile string
+	// Unwrap is true if the GraphQL type of the field is a list or a scalar, which a message
+	// can't represent. The only field of the response message is then used as the result.
+	Unwrap bool
 }
 
+// LambdaScriptURL is the URL of the @custom directive of @lambda fields when the lambdas run in
+// the script given with --graphql_lambda_script.
+const LambdaScriptURL = "lambda://script"
+
 // Query/Mutation types and arg names
 const (
 	GetQuery             QueryType    = "get"
@@ -79,6 +101,10 @@ const (
 	PasswordQuery        QueryType    = "checkPassword"
 	HTTPQuery            QueryType    = "http"
 	DQLQuery             QueryType    = "dql"
+	ConnectionQuery      QueryType    = "connection"
+	GroupByQuery         QueryType    = "groupby"
+	EntitiesQuery        QueryType    = "entities"
+	ServiceQuery         QueryType    = "service"
 	NotSupportedQuery    QueryType    = "notsupported"
 	AddMutation          MutationType = "add"
 	UpdateMutation       MutationType = "update"
@@ -88,6 +114,7 @@ const (
 	IDType                            = "ID"
 	InputArgName                      = "input"
 	FilterArgName                     = "filter"
+	UpsertArgName                     = "upsert"
 )
 
 // Schema represents a valid GraphQL schema
@@ -124,6 +151,7 @@ type Field interface {
 	Skip() bool
 	Include() bool
 	Cascade() []string
+	CascadeDepth() int
 	HasCustomDirective() (bool, map[string]bool)
 	HasLambdaDirective() bool
 	Type() Type
@@ -157,6 +185,11 @@ type Query interface {
 	DQLQuery() string
 	Rename(newName string)
 	AuthFor(typ Type, jwtVars map[string]interface{}) Query
+	// ConnectionNodes returns a connection query as a query for the list of nodes that it pages
+	// over, with the selection set of the node fields requested under edges.
+	ConnectionNodes() Query
+	// GroupByNodes returns a group by query as a query for the list of nodes that it groups.
+	GroupByNodes() Query
 }
 
 // A Type is a GraphQL type like: Float, T, T! and [T!]!.  If it's not a list, then
@@ -167,6 +200,12 @@ type Type interface {
 	Fields() []FieldDefinition
 	IDField() FieldDefinition
 	XIDField() FieldDefinition
+	// CompositeIDFields returns the fields that together identify an object of this type, as
+	// listed by @id(composite: [...]), or nil if this type has no composite key.
+	CompositeIDFields() []FieldDefinition
+	// KeyField returns the field named by the @key directive of an Apollo Federation entity, or
+	// nil if this type isn't an entity.
+	KeyField() FieldDefinition
 	InterfaceImplHasAuthRules() bool
 	PasswordField() FieldDefinition
 	Name() string
@@ -177,9 +216,12 @@ type Type interface {
 	IsUnion() bool
 	// returns a list of member types for this union
 	UnionMembers([]interface{}) []Type
+	// returns the object types that implement this interface, sorted by name
+	Implementations() []Type
 	ListType() Type
 	Interfaces() []string
 	EnsureNonNulls(map[string]interface{}, string) error
+	DefaultValues(update bool) map[string]interface{}
 	FieldOriginatedFrom(fieldName string) string
 	AuthRules() *TypeAuth
 	IsGeo() bool
@@ -197,6 +239,7 @@ type FieldDefinition interface {
 	ParentType() Type
 	IsID() bool
 	HasIDDirective() bool
+	IsVector() bool
 	Inverse() FieldDefinition
 	WithMemberType(string) FieldDefinition
 	// TODO - It might be possible to get rid of ForwardEdge and just use Inverse() always.
@@ -279,7 +322,7 @@ func (s *schema) Queries(t QueryType) []string {
 	}
 	var result []string
 	for _, q := range s.schema.Query.Fields {
-		if queryType(q.Name, s.customDirectives["Query"][q.Name]) == t {
+		if queryType(q.Name, q.Type, s.customDirectives["Query"][q.Name]) == t {
 			result = append(result, q.Name)
 		}
 	}
@@ -426,6 +469,11 @@ func convertPasswordDirective(dir *ast.Directive) *ast.FieldDefinition {
 	return fd
 }
 
+// isGeoTypeName returns true if typName is one of the object types used to represent geo data.
+func isGeoTypeName(typName string) bool {
+	return typName == "Point" || typName == "PointList" || typName == "Polygon" || typName == "MultiPolygon"
+}
+
 func dgraphMapping(sch *ast.Schema) map[string]map[string]string {
 	const (
 		add     = "Add"
@@ -437,12 +485,11 @@ func dgraphMapping(sch *ast.Schema) map[string]map[string]string {
 	dgraphPredicate := make(map[string]map[string]string)
 	for _, inputTyp := range sch.Types {
 		// We only want to consider input types (object and interface) defined by the user as part
-		// of the schema hence we ignore BuiltIn, query and mutation types and Geo types.
-		isInputTypeGeo := func(typName string) bool {
-			return typName == "Point" || typName == "PointList" || typName == "Polygon" || typName == "MultiPolygon"
-		}
+		// of the schema hence we ignore BuiltIn, query and mutation types, Geo types, connection
+		// types and group types.
 		if inputTyp.BuiltIn || isQueryOrMutationType(inputTyp) || inputTyp.Name == "Subscription" ||
-			(inputTyp.Kind != ast.Object && inputTyp.Kind != ast.Interface) || isInputTypeGeo(inputTyp.Name) {
+			(inputTyp.Kind != ast.Object && inputTyp.Kind != ast.Interface) || isGeoTypeName(inputTyp.Name) ||
+			isConnectionType(sch, inputTyp.Name) || isGroupType(sch, inputTyp.Name) {
 			continue
 		}
 
@@ -741,7 +788,7 @@ func buildCustomDirectiveForLambda(defn *ast.Definition, field *ast.FieldDefinit
 
 	// build the children for http argument
 	httpArgChildrens := []*ast.ChildValue{
-		getChildValue(httpUrl, x.Config.GraphqlLambdaUrl, ast.StringValue, lambdaDir.Position),
+		getChildValue(httpUrl, lambdaURL(), ast.StringValue, lambdaDir.Position),
 		getChildValue(httpMethod, http.MethodPost, ast.EnumValue, lambdaDir.Position),
 		getChildValue(httpBody, bodyTemplate.String(), ast.StringValue, lambdaDir.Position),
 	}
@@ -766,6 +813,16 @@ func buildCustomDirectiveForLambda(defn *ast.Definition, field *ast.FieldDefinit
 	}
 }
 
+// lambdaURL returns the URL of the @custom directive of @lambda fields. When the lambdas run in
+// the script given with --graphql_lambda_script, the URL only marks the fields for the resolver,
+// which calls the script instead of making a request.
+func lambdaURL() string {
+	if x.Config.GraphqlLambdaScript != "" {
+		return LambdaScriptURL
+	}
+	return x.Config.GraphqlLambdaUrl
+}
+
 func getChildValue(name, raw string, kind ast.ValueKind, position *ast.Position) *ast.ChildValue {
 	return &ast.ChildValue{
 		Name:     name,
@@ -926,6 +983,30 @@ func (f *field) Cascade() []string {
 	return fields
 }
 
+// CascadeDepth returns the number of levels that @cascade applies to, or 0 if it applies to
+// all the levels below the field.
+func (f *field) CascadeDepth() int {
+	dir := f.field.Directives.ForName(cascadeDirective)
+	if dir == nil {
+		return 0
+	}
+	// The @cascade propagated from a mutation to its payload has no definition, so the
+	// argument is read from its value.
+	arg := dir.Arguments.ForName(cascadeDepthArg)
+	if arg == nil || arg.Value == nil {
+		return 0
+	}
+	val, err := arg.Value.Value(f.op.vars)
+	if err != nil || val == nil {
+		return 0
+	}
+	depth, err := strconv.Atoi(fmt.Sprintf("%v", val))
+	if err != nil || depth < 0 {
+		return 0
+	}
+	return depth
+}
+
 func (f *field) HasCustomDirective() (bool, map[string]bool) {
 	custom := f.op.inSchema.customDirectives[f.GetObjectName()][f.Name()]
 	if custom == nil {
@@ -934,6 +1015,17 @@ func (f *field) HasCustomDirective() (bool, map[string]bool) {
 
 	var rf map[string]bool
 	httpArg := custom.Arguments.ForName("http")
+	if httpArg == nil {
+		// gRPC methods only take their arguments from the body.
+		bodyArg := custom.Arguments.ForName(grpcArg).Value.Children.ForName("body")
+		if bodyArg != nil {
+			_, rf, _ = parseBodyTemplate(bodyArg.Raw, true)
+		}
+		if rf == nil {
+			rf = make(map[string]bool)
+		}
+		return true, rf
+	}
 
 	bodyArg := httpArg.Value.Children.ForName("body")
 	graphqlArg := httpArg.Value.Children.ForName("graphql")
@@ -1013,13 +1105,18 @@ func (f *field) XIDArg() string {
 func (f *field) IDArgValue() (xid *string, uid uint64, err error) {
 	idField := f.Type().IDField()
 	passwordField := f.Type().PasswordField()
+	composite := make(map[string]bool)
+	for _, fld := range f.Type().CompositeIDFields() {
+		composite[fld.Name()] = true
+	}
 	xidArgName := ""
-	// This method is only called for Get queries and check. These queries can accept ID, XID
-	// or Password. Therefore the non ID and Password field is an XID.
+	// This method is only called for Get queries and check. These queries can accept ID, XID,
+	// the fields of a composite key or Password. Therefore the non ID, composite key and Password
+	// field is an XID.
 	// TODO maybe there is a better way to do this.
 	for _, arg := range f.field.Arguments {
 		if (idField == nil || arg.Name != idField.Name()) &&
-			(passwordField == nil || arg.Name != passwordField.Name()) {
+			(passwordField == nil || arg.Name != passwordField.Name()) && !composite[arg.Name] {
 			xidArgName = arg.Name
 		}
 	}
@@ -1085,9 +1182,24 @@ func (f *field) GetObjectName() string {
 func getCustomHTTPConfig(f *field, isQueryOrMutation bool) (FieldHTTPConfig, error) {
 	custom := f.op.inSchema.customDirectives[f.GetObjectName()][f.Name()]
 	httpArg := custom.Arguments.ForName("http")
+	isGRPC := httpArg == nil
+	if isGRPC {
+		// grpc shares the url, method, body, mode and headers fields with http.
+		httpArg = custom.Arguments.ForName(grpcArg)
+	}
 	fconf := FieldHTTPConfig{
 		URL:    httpArg.Value.Children.ForName("url").Raw,
 		Method: httpArg.Value.Children.ForName("method").Raw,
+		Lambda: f.HasLambdaDirective() && x.Config.GraphqlLambdaScript != "",
+	}
+	if isGRPC {
+		fconf.GRPC = &GRPCConfig{}
+		if df := httpArg.Value.Children.ForName(descriptorFile); df != nil {
+			fconf.GRPC.DescriptorFile = df.Raw
+		}
+		typ := f.Type()
+		kind := f.op.inSchema.schema.Types[typ.Name()].Kind
+		fconf.GRPC.Unwrap = typ.ListType() != nil || kind == ast.Scalar || kind == ast.Enum
 	}
 
 	fconf.Mode = SINGLE
@@ -1123,8 +1235,10 @@ func getCustomHTTPConfig(f *field, isQueryOrMutation bool) (FieldHTTPConfig, err
 	}
 
 	fconf.ForwardHeaders = http.Header{}
-	// set application/json as the default Content-Type
-	fconf.ForwardHeaders.Set("Content-Type", "application/json")
+	if !isGRPC {
+		// set application/json as the default Content-Type
+		fconf.ForwardHeaders.Set("Content-Type", "application/json")
+	}
 	secretHeaders := httpArg.Value.Children.ForName("secretHeaders")
 	if secretHeaders != nil {
 		hc.RLock()
@@ -1175,8 +1289,10 @@ func getCustomHTTPConfig(f *field, isQueryOrMutation bool) (FieldHTTPConfig, err
 		var err error
 		argMap := f.field.ArgumentMap(f.op.vars)
 		var bodyVars map[string]interface{}
-		// url params can exist only with body, and not with graphql
-		if graphqlArg == nil {
+		// url params can exist only with body, and not with graphql or grpc
+		if isGRPC {
+			bodyVars = argMap
+		} else if graphqlArg == nil {
 			fconf.URL, err = SubstituteVarsInURL(fconf.URL, argMap)
 			if err != nil {
 				return fconf, errors.Wrapf(err, "while substituting vars in URL")
@@ -1305,6 +1421,44 @@ func (q *query) AuthFor(typ Type, jwtVars map[string]interface{}) Query {
 		sel: q.sel}
 }
 
+func (q *query) ConnectionNodes() Query {
+	sch := q.op.inSchema.schema
+	conn := sch.Types[q.field.Definition.Type.Name()]
+	edge := sch.Types[conn.Fields.ForName("edges").Type.Name()]
+	nodeType := edge.Fields.ForName("node").Type.Name()
+
+	var selSet ast.SelectionSet
+	for _, edges := range q.SelectionSet() {
+		if edges.Name() != "edges" || edges.Skip() || !edges.Include() {
+			continue
+		}
+		for _, node := range edges.SelectionSet() {
+			if node.Name() != "node" || node.Skip() || !node.Include() {
+				continue
+			}
+			selSet = append(selSet, node.(*field).field.SelectionSet...)
+		}
+	}
+
+	def := *q.field.Definition
+	def.Type = ast.ListType(&ast.Type{NamedType: nodeType}, nil)
+	fld := *q.field
+	fld.Definition = &def
+	fld.SelectionSet = selSet
+	return &query{field: &fld, op: q.op, sel: q.sel}
+}
+
+func (q *query) GroupByNodes() Query {
+	nodeType := strings.TrimSuffix(q.field.Definition.Type.Name(), "Group")
+
+	def := *q.field.Definition
+	def.Type = ast.ListType(&ast.Type{NamedType: nodeType}, nil)
+	fld := *q.field
+	fld.Definition = &def
+	fld.SelectionSet = nil
+	return &query{field: &fld, op: q.op, sel: q.sel}
+}
+
 func (q *query) Rename(newName string) {
 	q.field.Name = newName
 }
@@ -1349,6 +1503,10 @@ func (q *query) Cascade() []string {
 	return (*field)(q).Cascade()
 }
 
+func (q *query) CascadeDepth() int {
+	return (*field)(q).CascadeDepth()
+}
+
 func (q *query) HasCustomDirective() (bool, map[string]bool) {
 	return (*field)(q).HasCustomDirective()
 }
@@ -1394,7 +1552,11 @@ func (q *query) EnumValues() []string {
 }
 
 func (q *query) QueryType() QueryType {
-	return queryType(q.Name(), q.op.inSchema.customDirectives["Query"][q.Name()])
+	var typ *ast.Type
+	if q.field.Definition != nil {
+		typ = q.field.Definition.Type
+	}
+	return queryType(q.Name(), typ, q.op.inSchema.customDirectives["Query"][q.Name()])
 }
 
 func (q *query) DQLQuery() string {
@@ -1406,7 +1568,7 @@ func (q *query) DQLQuery() string {
 	return ""
 }
 
-func queryType(name string, custom *ast.Directive) QueryType {
+func queryType(name string, typ *ast.Type, custom *ast.Directive) QueryType {
 	switch {
 	case custom != nil:
 		if custom.Arguments.ForName(dqlArg) != nil {
@@ -1417,8 +1579,22 @@ func queryType(name string, custom *ast.Directive) QueryType {
 		return GetQuery
 	case name == "__schema" || name == "__type" || name == "__typename":
 		return SchemaQuery
+	case name == EntitiesQueryName:
+		return EntitiesQuery
+	case name == ServiceQueryName:
+		return ServiceQuery
+	case strings.HasPrefix(name, "query") && typ != nil && typ.Elem == nil:
+		// queryT returns a list of T, while queryTConnection returns a single TConnection.
+		return ConnectionQuery
 	case strings.HasPrefix(name, "query"):
 		return FilterQuery
+	case strings.HasPrefix(name, "aggregate") && strings.HasSuffix(name, "GroupBy"):
+		// aggregateTGroupBy returns a list of TGroup, while the nodes it groups are queried just
+		// like queryT does.
+		if typ != nil && typ.Name() == strings.TrimSuffix(strings.TrimPrefix(name, "aggregate"), "By") {
+			return GroupByQuery
+		}
+		return FilterQuery
 	case strings.HasPrefix(name, "check"):
 		return PasswordQuery
 	default:
@@ -1486,6 +1662,10 @@ func (m *mutation) Cascade() []string {
 	return (*field)(m).Cascade()
 }
 
+func (m *mutation) CascadeDepth() int {
+	return (*field)(m).CascadeDepth()
+}
+
 func (m *mutation) HasCustomDirective() (bool, map[string]bool) {
 	return (*field)(m).HasCustomDirective()
 }
@@ -1521,9 +1701,15 @@ func (m *mutation) QueryField() Field {
 		}
 		// if @cascade was given on mutation itself, then it should get applied for the query which
 		// gets executed to fetch the results of that mutation, so propagating it to the QueryField.
+		// Its depth still applies, but the fields are those of the payload type.
 		if len(m.Cascade()) != 0 && len(f.Cascade()) == 0 {
 			field := f.(*field).field
-			field.Directives = append(field.Directives, &ast.Directive{Name: cascadeDirective})
+			dir := &ast.Directive{Name: cascadeDirective}
+			if depth := m.field.Directives.ForName(cascadeDirective).
+				Arguments.ForName(cascadeDepthArg); depth != nil {
+				dir.Arguments = ast.ArgumentList{depth}
+			}
+			field.Directives = append(field.Directives, dir)
 		}
 		return f
 	}
@@ -1668,6 +1854,13 @@ func (fd *fieldDefinition) HasIDDirective() bool {
 	return hasIDDirective(fd.fieldDef)
 }
 
+func (fd *fieldDefinition) IsVector() bool {
+	if fd.fieldDef == nil {
+		return false
+	}
+	return isVectorField(fd.fieldDef)
+}
+
 func hasIDDirective(fd *ast.FieldDefinition) bool {
 	id := fd.Directives.ForName("id")
 	return id != nil
@@ -1831,6 +2024,30 @@ func (t *astType) UnionMembers(memberTypesList []interface{}) []Type {
 	return memberTypes
 }
 
+func (t *astType) Implementations() []Type {
+	sch := t.inSchema.schema
+	if typ, ok := sch.Types[t.Name()]; !ok || typ.Kind != ast.Interface {
+		return nil
+	}
+
+	names := make([]string, 0, len(sch.PossibleTypes[t.Name()]))
+	for _, impl := range sch.PossibleTypes[t.Name()] {
+		names = append(names, impl.Name)
+	}
+	// PossibleTypes is built from a map, so sort it to rewrite queries the same way every time.
+	sort.Strings(names)
+
+	impls := make([]Type, 0, len(names))
+	for _, name := range names {
+		impls = append(impls, &astType{
+			typ:             &ast.Type{NamedType: name},
+			inSchema:        t.inSchema,
+			dgraphPredicate: t.dgraphPredicate,
+		})
+	}
+	return impls
+}
+
 func (t *astType) ListType() Type {
 	if t.typ == nil || t.typ.Elem == nil {
 		return nil
@@ -1931,6 +2148,51 @@ func (t *astType) XIDField() FieldDefinition {
 	return nil
 }
 
+func (t *astType) CompositeIDFields() []FieldDefinition {
+	def := t.inSchema.schema.Types[t.Name()]
+	if def == nil || def.Kind != ast.Object {
+		return nil
+	}
+
+	var result []FieldDefinition
+	for _, fd := range compositeIDFields(def) {
+		result = append(result, &fieldDefinition{
+			fieldDef:        fd,
+			inSchema:        t.inSchema,
+			parentType:      t,
+			dgraphPredicate: t.dgraphPredicate,
+		})
+	}
+	return result
+}
+
+func (t *astType) KeyField() FieldDefinition {
+	def := t.inSchema.schema.Types[t.Name()]
+	if def == nil || def.Kind != ast.Object {
+		return nil
+	}
+
+	key := def.Directives.ForName(keyDirective)
+	if key == nil {
+		return nil
+	}
+	fields := key.Arguments.ForName(keyArg)
+	if fields == nil {
+		return nil
+	}
+	fd := def.Fields.ForName(strings.TrimSpace(fields.Value.Raw))
+	if fd == nil {
+		return nil
+	}
+
+	return &fieldDefinition{
+		fieldDef:        fd,
+		inSchema:        t.inSchema,
+		parentType:      t,
+		dgraphPredicate: t.dgraphPredicate,
+	}
+}
+
 // InterfaceImplHasAuthRules checks if an interface's implementation has auth rules.
 func (t *astType) InterfaceImplHasAuthRules() bool {
 	schema := t.inSchema.schema
@@ -2009,7 +2271,7 @@ func (t *astType) Interfaces() []string {
 // satisfy a valid post.
 func (t *astType) EnsureNonNulls(obj map[string]interface{}, exclusion string) error {
 	for _, fld := range t.inSchema.schema.Types[t.Name()].Fields {
-		if fld.Type.NonNull && !isID(fld) && fld.Name != exclusion {
+		if fld.Type.NonNull && !isID(fld) && !hasDefault(fld) && fld.Name != exclusion {
 			if val, ok := obj[fld.Name]; !ok || val == nil {
 				return errors.Errorf(
 					"type %s requires a value for field %s, but no value present",
@@ -28,6 +28,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/dgraph-io/dgraph/edgraph"
 	"github.com/dgraph-io/dgraph/graphql/api"
 	"github.com/dgraph-io/dgraph/graphql/authorization"
 	"github.com/dgraph-io/dgraph/graphql/resolve"
@@ -46,6 +47,10 @@ type Headerkey string
 
 const (
 	touchedUidsHeader = "Graphql-TouchedUids"
+
+	// maxUploadMemory is the part of a multipart request that is held in memory while parsing,
+	// the rest is kept in temporary files.
+	maxUploadMemory = 32 << 20
 )
 
 // An IServeGraphQL can serve a GraphQL endpoint (currently only ons http)
@@ -125,9 +130,6 @@ func (gs *graphqlSubscription) Subscribe(
 
 	// library (graphql-transport-ws) passes the headers which are part of the INIT payload to us in the context.
 	// And we are extracting the Auth JWT from those and passing them along.
-	customClaims := &authorization.CustomClaims{
-		StandardClaims: jwt.StandardClaims{},
-	}
 	header, _ := ctx.Value("Header").(json.RawMessage)
 
 	if len(header) > 0 {
@@ -146,26 +148,17 @@ func (gs *graphqlSubscription) Subscribe(
 				"authorizationJwt": val.(string),
 			})
 			ctx = metadata.NewIncomingContext(ctx, md)
-			customClaims, err = authorization.ExtractCustomClaims(ctx)
-			if err != nil {
-				return nil, err
-			}
 			break
 		}
 
 	}
-	// for the cases when no expiry is given in jwt or subscription doesn't have any authorization,
-	// we set their expiry to zero time
-	if customClaims.StandardClaims.ExpiresAt == nil {
-		customClaims.StandardClaims.ExpiresAt = jwt.At(time.Time{})
-	}
 	req := &schema.Request{
 		OperationName: operationName,
 		Query:         document,
 		Variables:     variableValues,
 	}
 
-	res, err := gs.graphqlHandler.poller.AddSubscriber(req, customClaims)
+	res, err := gs.graphqlHandler.addSubscriber(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -179,6 +172,22 @@ func (gs *graphqlSubscription) Subscribe(
 	return res.UpdateCh, ctx.Err()
 }
 
+// addSubscriber registers req with the poller, using the auth JWT (if any) from the incoming
+// metadata of ctx. It is shared by the websocket and the SSE transports.
+func (gh *graphqlHandler) addSubscriber(ctx context.Context,
+	req *schema.Request) (*subscription.SubscriberResponse, error) {
+	customClaims, err := authorization.ExtractCustomClaims(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// for the cases when no expiry is given in jwt or subscription doesn't have any authorization,
+	// we set their expiry to zero time
+	if customClaims.StandardClaims.ExpiresAt == nil {
+		customClaims.StandardClaims.ExpiresAt = jwt.At(time.Time{})
+	}
+	return gh.poller.AddSubscriber(req, customClaims)
+}
+
 func (gh *graphqlHandler) Handler() http.Handler {
 	return graphqlws.NewHandlerFunc(&graphqlSubscription{
 		graphqlHandler: gh,
@@ -209,8 +218,16 @@ func (gh *graphqlHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	var res *schema.Response
 	gqlReq, err := getRequest(ctx, r)
+	if err == nil {
+		err = edgraph.ProcessPersistedQuery(ctx, gqlReq)
+	}
 
-	if err != nil {
+	if err == nil && acceptsEventStream(r) {
+		gqlReq.Header = r.Header
+		if res = gh.serveSSE(ctx, w, gqlReq); res == nil {
+			return
+		}
+	} else if err != nil {
 		res = schema.ErrorResponse(err)
 	} else {
 		gqlReq.Header = r.Header
@@ -262,6 +279,12 @@ func getRequest(ctx context.Context, r *http.Request) (*schema.Request, error) {
 				return nil, errors.Wrap(err, "Not a valid GraphQL request body")
 			}
 		}
+		extensions, ok := query["extensions"]
+		if ok {
+			if err := json.Unmarshal([]byte(extensions[0]), &gqlReq.Extensions); err != nil {
+				return nil, errors.Wrap(err, "Not a valid GraphQL request body")
+			}
+		}
 	case http.MethodPost:
 		mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
 		if err != nil {
@@ -281,12 +304,16 @@ func getRequest(ctx context.Context, r *http.Request) (*schema.Request, error) {
 				return nil, errors.Wrap(err, "Could not read GraphQL request body")
 			}
 			gqlReq.Query = string(bytes)
+		case "multipart/form-data":
+			if err = getMultipartRequest(r, gqlReq); err != nil {
+				return nil, err
+			}
 		default:
 			// https://graphql.org/learn/serving-over-http/#post-request says:
 			// "A standard GraphQL POST request should use the application/json
 			// content type ..."
-			return nil, errors.New(
-				"Unrecognised Content-Type.  Please use application/json or application/graphql for GraphQL requests")
+			return nil, errors.New("Unrecognised Content-Type.  Please use application/json, " +
+				"application/graphql or multipart/form-data for GraphQL requests")
 		}
 	default:
 		return nil,
@@ -296,6 +323,60 @@ func getRequest(ctx context.Context, r *http.Request) (*schema.Request, error) {
 	return gqlReq, nil
 }
 
+// getMultipartRequest reads a request that follows the GraphQL multipart request spec
+// (https://github.com/jaydenseric/graphql-multipart-request-spec) into gqlReq.  The operations
+// field holds the request, and the map field maps each file to the paths of the Upload
+// variables that it is the value of.
+func getMultipartRequest(r *http.Request, gqlReq *schema.Request) error {
+	// A multipart form can be posted cross-origin without a preflight request, so we require a
+	// header that a form can't set, so that browsers check CORS before sending the request.
+	if r.Header.Get("X-Requested-With") == "" {
+		return errors.New("Multipart GraphQL requests must set the X-Requested-With header")
+	}
+
+	if err := r.ParseMultipartForm(maxUploadMemory); err != nil {
+		return errors.Wrap(err, "Not a valid multipart GraphQL request")
+	}
+	defer func() {
+		_ = r.MultipartForm.RemoveAll()
+	}()
+
+	d := json.NewDecoder(strings.NewReader(r.FormValue("operations")))
+	d.UseNumber()
+	if err := d.Decode(&gqlReq); err != nil {
+		return errors.Wrap(err, "Not a valid operations field in multipart GraphQL request")
+	}
+
+	var files map[string][]string
+	if err := json.Unmarshal([]byte(r.FormValue("map")), &files); err != nil {
+		return errors.Wrap(err, "Not a valid map field in multipart GraphQL request")
+	}
+
+	for key, paths := range files {
+		file, header, err := r.FormFile(key)
+		if err != nil {
+			return errors.Wrapf(err, "Could not read file %s of multipart GraphQL request", key)
+		}
+		content, err := ioutil.ReadAll(file)
+		_ = file.Close()
+		if err != nil {
+			return errors.Wrapf(err, "Could not read file %s of multipart GraphQL request", key)
+		}
+
+		upload := &schema.UploadedFile{
+			Filename:    header.Filename,
+			ContentType: header.Header.Get("Content-Type"),
+			Content:     content,
+		}
+		for _, path := range paths {
+			if err := gqlReq.SetUpload(path, upload); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func commonHeaders(admin bool, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if admin {
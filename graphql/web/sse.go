@@ -0,0 +1,133 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/dgraph/graphql/schema"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+const (
+	eventStreamType = "text/event-stream"
+	// sseKeepAlive is how often an idle SSE stream gets a comment line, so that proxies don't
+	// time out a subscription which simply hasn't had an update in a while.
+	sseKeepAlive = 12 * time.Second
+)
+
+// acceptsEventStream returns true if the client asked for the response as Server-Sent Events,
+// following the "distinct connections" mode of the graphql-sse protocol.
+func acceptsEventStream(r *http.Request) bool {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err == nil && mediaType == eventStreamType {
+			return true
+		}
+	}
+	return false
+}
+
+// serveSSE serves gqlReq over a Server-Sent Events stream. Subscriptions are registered with
+// the same poller that serves websocket subscriptions and every update is sent as a "next"
+// event, while queries and mutations are resolved once. The stream always ends with a
+// "complete" event. If the request fails before the stream is started, the error response is
+// returned so that it can be written as a normal JSON response; otherwise nil is returned.
+func (gh *graphqlHandler) serveSSE(ctx context.Context, w http.ResponseWriter,
+	gqlReq *schema.Request) *schema.Response {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return schema.ErrorResponse(errors.New("Server-Sent Events are not supported"))
+	}
+
+	isSubscription, err := gh.resolver.IsSubscription(gqlReq)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+	if !isSubscription {
+		res := gh.resolver.Resolve(ctx, gqlReq)
+		startEventStream(w)
+		if err := writeEvent(w, "next", res.Output()); err == nil {
+			_ = writeEvent(w, "complete", nil)
+		}
+		flusher.Flush()
+		return nil
+	}
+
+	sub, err := gh.addSubscriber(ctx, gqlReq)
+	if err != nil {
+		return schema.ErrorResponse(err)
+	}
+	defer gh.poller.TerminateSubscription(sub.BucketID, sub.SubscriptionID)
+
+	startEventStream(w)
+	flusher.Flush()
+
+	ticker := time.NewTicker(sseKeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			// The client has gone away.
+			return nil
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ":\n\n"); err != nil {
+				return nil
+			}
+		case payload, ok := <-sub.UpdateCh:
+			if !ok {
+				// The poller closed the subscription, either because the JWT expired or the
+				// schema changed. The client can reconnect to listen again.
+				_ = writeEvent(w, "complete", nil)
+				flusher.Flush()
+				return nil
+			}
+			if err := writeEvent(w, "next", payload); err != nil {
+				glog.Errorf("Error while writing subscription update: %v", err)
+				return nil
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+func startEventStream(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", eventStreamType+"; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+}
+
+// writeEvent writes a single SSE event. A nil payload writes an event with empty data.
+func writeEvent(w http.ResponseWriter, event string, payload interface{}) error {
+	data := []byte{}
+	if payload != nil {
+		var err error
+		if data, err = json.Marshal(payload); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	return err
+}
@@ -22,8 +22,20 @@ import (
 
 // IRIRef emits an IRIREF or returns an error if the input is invalid.
 func IRIRef(l *Lexer, styp ItemType) error {
+	return iriRef(l, styp, isIRIRefChar)
+}
+
+// PredicateRef is like IRIRef, but it also accepts the '|' that separates the name of an edge
+// from the name of one of its properties, as in <friend|since>, and the '^' that separates the
+// predicates of a composite index, as in <country^city>. It's only used where a predicate is
+// named.
+func PredicateRef(l *Lexer, styp ItemType) error {
+	return iriRef(l, styp, isPredicateRefChar)
+}
+
+func iriRef(l *Lexer, styp ItemType, accept CheckRuneRec) error {
 	l.Ignore() // ignore '<'
-	l.AcceptRunRec(isIRIRefChar)
+	l.AcceptRunRec(accept)
 	l.Emit(styp) // will emit without '<' and '>'
 	r := l.Next()
 	if r == EOF {
@@ -56,6 +68,10 @@ func isIRIRefChar(r rune, l *Lexer) bool {
 	return true
 }
 
+func isPredicateRefChar(r rune, l *Lexer) bool {
+	return r == '|' || r == '^' || isIRIRefChar(r, l)
+}
+
 // HasUChars returns whether the lexer is at the beginning of a escaped Unicode character.
 // UCHAR ::= '\u' HEX HEX HEX HEX | '\U' HEX HEX HEX HEX HEX HEX HEX HEX
 func HasUChars(r rune, l *Lexer) bool {
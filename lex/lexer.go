@@ -224,6 +224,15 @@ func (l *Lexer) Errorf(format string, args ...interface{}) StateFn {
 	return nil
 }
 
+// LastItem returns the n-th last item emitted, with n = 1 for the last one, or an empty item if
+// fewer items were emitted.
+func (l *Lexer) LastItem(n int) Item {
+	if n < 1 || n > len(l.items) {
+		return Item{}
+	}
+	return l.items[len(l.items)-n]
+}
+
 // Emit emits the item with it's type information.
 func (l *Lexer) Emit(t ItemType) {
 	if t != ItemEOF && l.Pos < l.Start {
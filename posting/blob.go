@@ -0,0 +1,411 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package posting
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"math"
+
+	"github.com/dgraph-io/badger/v2"
+	bpb "github.com/dgraph-io/badger/v2/pb"
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/dgraph/codec"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+var (
+	// blobThreshold is the size above which a value is split into chunks stored under
+	// separate keys when the transaction writing it is committed to disk.
+	blobThreshold = 1 << 20
+	// blobChunkSize is the maximum size of each of the chunks.
+	blobChunkSize = 1 << 20
+)
+
+// BlobChunkSize returns the maximum size of each of the chunks a large value is split into.
+func BlobChunkSize() int {
+	return blobChunkSize
+}
+
+// BlobID returns the id of the chunks storing the value of the posting postingUid in the list
+// of the node uid, as written by the transaction with the given start timestamp. Each write of
+// a value gets its own chunks, so that the chunks of older values can be deleted once nothing
+// refers to them anymore.
+func BlobID(uid, postingUid, startTs uint64) []byte {
+	id := make([]byte, 24)
+	binary.BigEndian.PutUint64(id, uid)
+	binary.BigEndian.PutUint64(id[8:], postingUid)
+	binary.BigEndian.PutUint64(id[16:], startTs)
+	return id
+}
+
+// isBlob returns whether the value of the posting is stored in blob keys.
+func isBlob(p *pb.Posting) bool {
+	return p.BlobSize > 0
+}
+
+// shouldChunk returns whether the value of the posting is big enough to be stored in blob keys.
+func shouldChunk(p *pb.Posting) bool {
+	return p.Op == Set && p.PostingType != pb.Posting_REF && !isBlob(p) &&
+		len(p.Value) > blobThreshold
+}
+
+// encodeBlobChunk returns the value stored under the blob key of a chunk. The chunks are stored
+// as complete posting lists under the predicate, so they are moved, backed up and dropped along
+// with the rest of the data of the predicate.
+func encodeBlobChunk(data []byte) ([]byte, error) {
+	chunk := &pb.PostingList{
+		Pack: codec.Encode([]uint64{math.MaxUint64}, blockSize),
+		Postings: []*pb.Posting{{
+			Uid:         math.MaxUint64,
+			Value:       data,
+			ValType:     pb.Posting_BINARY,
+			PostingType: pb.Posting_VALUE,
+		}},
+	}
+	return chunk.Marshal()
+}
+
+// chunkBlobs replaces the large values in the given delta, written by the transaction with the
+// given start timestamp, with references to their chunks. It returns the updated delta along
+// with the blob keys and values storing the chunks.
+func chunkBlobs(key, delta []byte, startTs uint64) ([]byte, map[string][]byte, error) {
+	pk, err := x.Parse(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !pk.IsData() {
+		return delta, nil, nil
+	}
+
+	var plist pb.PostingList
+	if err := plist.Unmarshal(delta); err != nil {
+		return nil, nil, err
+	}
+	var chunks map[string][]byte
+	for i, p := range plist.Postings {
+		if !shouldChunk(p) {
+			continue
+		}
+		if chunks == nil {
+			chunks = make(map[string][]byte)
+		}
+		id := BlobID(pk.Uid, p.Uid, startTs)
+		for idx := 0; idx*blobChunkSize < len(p.Value); idx++ {
+			end := (idx + 1) * blobChunkSize
+			if end > len(p.Value) {
+				end = len(p.Value)
+			}
+			data, err := encodeBlobChunk(p.Value[idx*blobChunkSize : end])
+			if err != nil {
+				return nil, nil, err
+			}
+			chunks[string(x.BlobKey(pk.Attr, id, uint32(idx)))] = data
+		}
+
+		// The delta shares the postings with the in-memory lists, so modify a copy.
+		ref := *p
+		ref.Value = id
+		ref.BlobSize = uint64(len(p.Value))
+		plist.Postings[i] = &ref
+	}
+	if len(chunks) == 0 {
+		return delta, nil, nil
+	}
+	data, err := plist.Marshal()
+	return data, chunks, err
+}
+
+// WriteBlobChunks stores the given chunks of values being written by the transaction with the
+// given start timestamp. The chunks are written at the start timestamp, so they're in place
+// before the transaction commits the postings referring to them.
+func WriteBlobChunks(startTs uint64, chunks []*pb.BlobChunk) error {
+	writer := NewTxnWriter(pstore)
+	for _, chunk := range chunks {
+		data, err := encodeBlobChunk(chunk.Data)
+		if err != nil {
+			return err
+		}
+		key := x.BlobKey(chunk.Attr, BlobID(chunk.Uid, math.MaxUint64, startTs), chunk.Idx)
+		if err := writer.SetAt(key, data, BitCompletePosting, startTs); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+// blobChunk is the latest version of a blob key.
+type blobChunk struct {
+	key     []byte
+	id      string
+	version uint64
+}
+
+// blobChunks returns the chunks stored under the given prefix, leaving out the ones already
+// deleted and the ones written by transactions that are still pending.
+func blobChunks(prefix []byte) ([]blobChunk, error) {
+	txn := pstore.NewTransactionAt(math.MaxUint64, false)
+	defer txn.Discard()
+	iopt := badger.DefaultIteratorOptions
+	iopt.PrefetchValues = false
+	iopt.Prefix = prefix
+	itr := txn.NewIterator(iopt)
+	defer itr.Close()
+
+	var chunks []blobChunk
+	for itr.Rewind(); itr.Valid(); itr.Next() {
+		item := itr.Item()
+		if item.UserMeta()&BitEmptyPosting > 0 {
+			continue
+		}
+		pk, err := x.Parse(item.Key())
+		if err != nil {
+			return nil, err
+		}
+		if len(pk.Term) != 24 {
+			return nil, errors.Errorf("invalid blob id of length %d", len(pk.Term))
+		}
+		if Oracle().GetTxn(binary.BigEndian.Uint64([]byte(pk.Term[16:]))) != nil {
+			continue
+		}
+		chunks = append(chunks, blobChunk{key: item.KeyCopy(nil), id: pk.Term,
+			version: item.Version()})
+	}
+	return chunks, nil
+}
+
+// deleteBlobChunks returns the deletions of the chunks that don't belong to any of the values in
+// keep. The deletions are written at version ts at the earliest, so that reads at older
+// timestamps can still find the chunks.
+func deleteBlobChunks(chunks []blobChunk, keep map[string]struct{}, ts uint64) []*bpb.KV {
+	var kvs []*bpb.KV
+	for _, chunk := range chunks {
+		if _, ok := keep[chunk.id]; ok {
+			continue
+		}
+		kvs = append(kvs, &bpb.KV{
+			Key:      chunk.key,
+			Version:  x.Max(ts, chunk.version+1),
+			UserMeta: []byte{BitEmptyPosting},
+		})
+	}
+	return kvs
+}
+
+// overwrittenBlobs returns the deletions of the chunks of the values overwritten by the blob
+// values in the given delta, which is committed at commitTs.
+func overwrittenBlobs(key, delta []byte, commitTs uint64) ([]*bpb.KV, error) {
+	pk, err := x.Parse(key)
+	if err != nil {
+		return nil, err
+	}
+	if !pk.IsData() {
+		return nil, nil
+	}
+	var plist pb.PostingList
+	if err := plist.Unmarshal(delta); err != nil {
+		return nil, err
+	}
+	var kvs []*bpb.KV
+	for _, p := range plist.Postings {
+		if !isBlob(p) || len(p.Value) != 24 {
+			continue
+		}
+		chunks, err := blobChunks(x.BlobPrefix(pk.Attr, p.Value[:16]))
+		if err != nil {
+			return nil, err
+		}
+		kvs = append(kvs, deleteBlobChunks(chunks, map[string]struct{}{string(p.Value): {}},
+			commitTs)...)
+	}
+	return kvs, nil
+}
+
+// rollupWithBlobs rolls up the list stored under key, similar to Rollup. For data keys, it also
+// returns the deletions of the chunks of the node that the rolled up list no longer refers to,
+// which are left behind by values that have been overwritten or deleted, or by transactions that
+// were aborted after writing their chunks.
+func rollupWithBlobs(key []byte) (*List, []*bpb.KV, error) {
+	pk, err := x.Parse(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	var chunks []blobChunk
+	if pk.IsData() {
+		// The chunks are found before reading the list. Any chunk not written by a pending
+		// transaction then either belongs to a value in the list, or isn't needed anymore.
+		if chunks, err = blobChunks(x.BlobPrefix(pk.Attr, BlobID(pk.Uid, 0, 0)[:8])); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	l, err := GetNoStore(key, math.MaxUint64)
+	if err != nil {
+		return nil, nil, err
+	}
+	kvs, err := l.Rollup(nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(chunks) == 0 || len(kvs) == 0 {
+		return l, kvs, nil
+	}
+
+	keep := make(map[string]struct{})
+	l.RLock()
+	err = l.iterate(math.MaxUint64, 0, func(p *pb.Posting) error {
+		if isBlob(p) {
+			keep[string(p.Value)] = struct{}{}
+		}
+		return nil
+	})
+	l.RUnlock()
+	if err != nil {
+		return nil, nil, err
+	}
+	return l, append(kvs, deleteBlobChunks(chunks, keep, kvs[0].Version)...), nil
+}
+
+type blobReader struct {
+	txn  *badger.Txn
+	itr  *badger.Iterator
+	size uint64
+	read uint64
+	idx  uint32
+	buf  []byte
+}
+
+// NewBlobReader returns a reader over the value of the given posting, which must belong to a
+// data key of the given attribute, as of readTs. If the value has been split into blob keys,
+// the chunks are read from disk one at a time, as they're consumed.
+func NewBlobReader(attr string, p *pb.Posting, readTs uint64) io.ReadCloser {
+	if !isBlob(p) {
+		return ioutil.NopCloser(bytes.NewReader(p.Value))
+	}
+	txn := pstore.NewTransactionAt(readTs, false)
+	iopt := badger.DefaultIteratorOptions
+	iopt.Prefix = x.BlobPrefix(attr, p.Value)
+	itr := txn.NewIterator(iopt)
+	itr.Rewind()
+	return &blobReader{txn: txn, itr: itr, size: p.BlobSize}
+}
+
+func (r *blobReader) Read(out []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.read == r.size {
+			return 0, io.EOF
+		}
+		if err := r.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(out, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *blobReader) next() error {
+	if !r.itr.Valid() {
+		return errors.Errorf("blob is missing chunk %d, read %d of %d bytes",
+			r.idx, r.read, r.size)
+	}
+	item := r.itr.Item()
+	pk, err := x.Parse(item.Key())
+	if err != nil {
+		return err
+	}
+	if pk.Count != r.idx || item.UserMeta()&BitEmptyPosting > 0 {
+		return errors.Errorf("blob is missing chunk %d, read %d of %d bytes",
+			r.idx, r.read, r.size)
+	}
+
+	var plist pb.PostingList
+	err = item.Value(func(val []byte) error {
+		return plist.Unmarshal(val)
+	})
+	if err != nil {
+		return err
+	}
+	if len(plist.Postings) != 1 {
+		return errors.Errorf("invalid blob chunk %d with %d postings", r.idx, len(plist.Postings))
+	}
+	r.buf = plist.Postings[0].Value
+	r.read += uint64(len(r.buf))
+	if r.read > r.size {
+		return errors.Errorf("blob is larger than the expected %d bytes", r.size)
+	}
+	r.idx++
+	r.itr.Next()
+	return nil
+}
+
+func (r *blobReader) Close() error {
+	r.itr.Close()
+	r.txn.Discard()
+	return nil
+}
+
+// resolveBlob returns a copy of the posting with the value read back from the blob keys if
+// the value has been split into chunks. Otherwise, the posting is returned as is.
+func (l *List) resolveBlob(p *pb.Posting, readTs uint64) (*pb.Posting, error) {
+	if p == nil || !isBlob(p) {
+		return p, nil
+	}
+	pk, err := x.Parse(l.key)
+	if err != nil {
+		return nil, err
+	}
+	r := NewBlobReader(pk.Attr, p, readTs)
+	defer r.Close()
+
+	val := make([]byte, 0, p.BlobSize)
+	buf := bytes.NewBuffer(val)
+	if _, err := io.Copy(buf, r); err != nil {
+		return nil, errors.Wrapf(err, "while reading blob for attr %s", pk.Attr)
+	}
+
+	resolved := *p
+	resolved.Value = buf.Bytes()
+	resolved.BlobSize = 0
+	return &resolved, nil
+}
+
+// ValueReader returns a reader over the untagged value of the list as of readTs, along with the
+// type of the value. Unlike Value, the content of a value split into chunks isn't read into
+// memory at once.
+func (l *List) ValueReader(readTs uint64) (io.ReadCloser, pb.Posting_ValType, error) {
+	l.RLock()
+	p, err := l.postingFor(readTs, nil)
+	l.RUnlock()
+	if err != nil {
+		return nil, 0, err
+	}
+	if p.Encoding != 0 {
+		if p, err = l.resolvePosting(p, readTs); err != nil {
+			return nil, 0, err
+		}
+	}
+	pk, err := x.Parse(l.key)
+	if err != nil {
+		return nil, 0, err
+	}
+	return NewBlobReader(pk.Attr, p, readTs), p.ValType, nil
+}
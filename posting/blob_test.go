@@ -0,0 +1,237 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package posting
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"testing"
+
+	"github.com/dgraph-io/badger/v2"
+	bpb "github.com/dgraph-io/badger/v2/pb"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+func TestBlobValue(t *testing.T) {
+	defer func(threshold, chunkSize int) {
+		blobThreshold, blobChunkSize = threshold, chunkSize
+	}(blobThreshold, blobChunkSize)
+	blobThreshold, blobChunkSize = 32, 16
+
+	value := string(bytes.Repeat([]byte("0123456789"), 10))
+	addEdgeToValue(t, "blob", 1, value, 1, 2)
+	addEdgeToValue(t, "blob", 2, "small value", 3, 4)
+
+	l, err := getNew(x.DataKey("blob", 1), pstore, math.MaxUint64)
+	require.NoError(t, err)
+	val, err := l.Value(5)
+	require.NoError(t, err)
+	require.Equal(t, value, string(val.Value.([]byte)))
+
+	vals, err := l.AllValues(5)
+	require.NoError(t, err)
+	require.Len(t, vals, 1)
+	require.Equal(t, value, string(vals[0].Value.([]byte)))
+
+	// Only a reference to the content is stored in the posting list, even after a rollup.
+	kvs, err := l.Rollup(nil)
+	require.NoError(t, err)
+	require.Len(t, kvs, 1)
+	var plist pb.PostingList
+	require.NoError(t, plist.Unmarshal(kvs[0].Value))
+	require.Len(t, plist.Postings, 1)
+	ref := plist.Postings[0]
+	require.Equal(t, uint64(len(value)), ref.BlobSize)
+	require.Len(t, ref.Value, 24)
+
+	var chunks int
+	txn := pstore.NewTransactionAt(math.MaxUint64, false)
+	defer txn.Discard()
+	iopt := badger.DefaultIteratorOptions
+	iopt.Prefix = x.BlobPrefix("blob", ref.Value)
+	itr := txn.NewIterator(iopt)
+	for itr.Rewind(); itr.Valid(); itr.Next() {
+		chunks++
+	}
+	itr.Close()
+	require.Equal(t, 7, chunks)
+
+	r := NewBlobReader("blob", ref, math.MaxUint64)
+	data, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	require.Equal(t, value, string(data))
+
+	l, err = getNew(x.DataKey("blob", 2), pstore, math.MaxUint64)
+	require.NoError(t, err)
+	kvs, err = l.Rollup(nil)
+	require.NoError(t, err)
+	plist.Reset()
+	require.NoError(t, plist.Unmarshal(kvs[0].Value))
+	require.Len(t, plist.Postings, 1)
+	require.False(t, isBlob(plist.Postings[0]))
+	require.Equal(t, "small value", string(plist.Postings[0].Value))
+}
+
+func TestBlobMissingChunk(t *testing.T) {
+	defer func(threshold, chunkSize int) {
+		blobThreshold, blobChunkSize = threshold, chunkSize
+	}(blobThreshold, blobChunkSize)
+	blobThreshold, blobChunkSize = 32, 16
+
+	value := string(bytes.Repeat([]byte("abcdefghij"), 10))
+	addEdgeToValue(t, "blob_missing", 1, value, 1, 2)
+
+	l, err := getNew(x.DataKey("blob_missing", 1), pstore, math.MaxUint64)
+	require.NoError(t, err)
+	kvs, err := l.Rollup(nil)
+	require.NoError(t, err)
+	var plist pb.PostingList
+	require.NoError(t, plist.Unmarshal(kvs[0].Value))
+
+	writer := NewTxnWriter(pstore)
+	require.NoError(t, writer.update(3, func(txn *badger.Txn) error {
+		return txn.Delete(x.BlobKey("blob_missing", plist.Postings[0].Value, 3))
+	}))
+	require.NoError(t, writer.Flush())
+
+	_, err = l.Value(4)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "blob is missing chunk 3")
+}
+
+// finishTxns removes the transactions with the given start timestamps from the pending ones,
+// as done once their status is known.
+func finishTxns(startTs ...uint64) {
+	delta := &pb.OracleDelta{}
+	for _, ts := range startTs {
+		delta.Txns = append(delta.Txns, &pb.TxnStatus{StartTs: ts})
+	}
+	Oracle().ProcessDelta(delta)
+}
+
+func liveBlobChunks(t *testing.T, attr string, uid uint64) int {
+	chunks, err := blobChunks(x.BlobPrefix(attr, BlobID(uid, 0, 0)[:8]))
+	require.NoError(t, err)
+	return len(chunks)
+}
+
+func rollupBlobKey(t *testing.T, key []byte) {
+	_, kvs, err := rollupWithBlobs(key)
+	require.NoError(t, err)
+	writer := NewTxnWriter(pstore)
+	require.NoError(t, writer.Write(&bpb.KVList{Kv: kvs}))
+	require.NoError(t, writer.Flush())
+}
+
+func TestBlobReclaim(t *testing.T) {
+	defer func(threshold, chunkSize int) {
+		blobThreshold, blobChunkSize = threshold, chunkSize
+	}(blobThreshold, blobChunkSize)
+	blobThreshold, blobChunkSize = 32, 16
+
+	key := x.DataKey("blob_reclaim", 1)
+	first := string(bytes.Repeat([]byte("a"), 100))
+	second := string(bytes.Repeat([]byte("b"), 40))
+	addEdgeToValue(t, "blob_reclaim", 1, first, 1, 2)
+	finishTxns(1)
+	require.Equal(t, 7, liveBlobChunks(t, "blob_reclaim", 1))
+
+	// Overwriting the value with another large value deletes the chunks of the first one, which
+	// can still be read at older timestamps.
+	addEdgeToValue(t, "blob_reclaim", 1, second, 3, 4)
+	finishTxns(3)
+	require.Equal(t, 3, liveBlobChunks(t, "blob_reclaim", 1))
+	l, err := getNew(key, pstore, math.MaxUint64)
+	require.NoError(t, err)
+	val, err := l.Value(2)
+	require.NoError(t, err)
+	require.Equal(t, first, string(val.Value.([]byte)))
+	val, err = l.Value(5)
+	require.NoError(t, err)
+	require.Equal(t, second, string(val.Value.([]byte)))
+
+	// Overwriting the value with a small one leaves the chunks until the list is rolled up.
+	addEdgeToValue(t, "blob_reclaim", 1, "small value", 5, 6)
+	finishTxns(5)
+	require.Equal(t, 3, liveBlobChunks(t, "blob_reclaim", 1))
+	rollupBlobKey(t, key)
+	require.Equal(t, 0, liveBlobChunks(t, "blob_reclaim", 1))
+
+	l, err = getNew(key, pstore, math.MaxUint64)
+	require.NoError(t, err)
+	val, err = l.Value(7)
+	require.NoError(t, err)
+	require.Equal(t, "small value", string(val.Value.([]byte)))
+}
+
+func TestBlobChunksWrittenByTxn(t *testing.T) {
+	key := x.DataKey("blob_streamed", 1)
+	value := bytes.Repeat([]byte("0123456789"), 4)
+	chunksOf := func() []*pb.BlobChunk {
+		var chunks []*pb.BlobChunk
+		for idx := 0; idx*16 < len(value); idx++ {
+			end := x.Min(uint64(idx+1)*16, uint64(len(value)))
+			chunks = append(chunks, &pb.BlobChunk{Attr: "blob_streamed", Uid: 1,
+				Idx: uint32(idx), Data: value[idx*16 : end]})
+		}
+		return chunks
+	}
+
+	// The chunks of a pending transaction are left alone.
+	Oracle().RegisterStartTs(7)
+	require.NoError(t, WriteBlobChunks(7, chunksOf()))
+	require.Equal(t, 0, liveBlobChunks(t, "blob_streamed", 1))
+
+	l, err := GetNoStore(key, 7)
+	require.NoError(t, err)
+	addMutation(t, l, &pb.DirectedEdge{
+		Attr:      "blob_streamed",
+		Entity:    1,
+		Value:     BlobID(1, math.MaxUint64, 7),
+		ValueType: pb.Posting_STRING,
+		BlobSize:  uint64(len(value)),
+	}, Set, 7, 8, false)
+	finishTxns(7)
+	require.Equal(t, 3, liveBlobChunks(t, "blob_streamed", 1))
+
+	l, err = getNew(key, pstore, math.MaxUint64)
+	require.NoError(t, err)
+	r, typ, err := l.ValueReader(9)
+	require.NoError(t, err)
+	require.Equal(t, pb.Posting_STRING, typ)
+	data, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	require.Equal(t, value, data)
+
+	// The chunks left behind by a transaction that didn't commit are deleted by a rollup.
+	require.NoError(t, WriteBlobChunks(10, chunksOf()))
+	require.Equal(t, 6, liveBlobChunks(t, "blob_streamed", 1))
+	rollupBlobKey(t, key)
+	require.Equal(t, 3, liveBlobChunks(t, "blob_streamed", 1))
+
+	l, err = getNew(key, pstore, math.MaxUint64)
+	require.NoError(t, err)
+	val, err := l.Value(11)
+	require.NoError(t, err)
+	require.Equal(t, value, val.Value.([]byte))
+}
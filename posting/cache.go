@@ -0,0 +1,192 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package posting
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+	"sync/atomic"
+
+	"github.com/dgraph-io/ristretto"
+	ostats "go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// cachePolicy holds the predicates whose lists are kept in the cache until they change, and the
+// ones whose lists are never cached.
+type cachePolicy struct {
+	pinned   map[string]struct{}
+	excluded map[string]struct{}
+}
+
+var policy atomic.Value
+
+// pinnedLists holds the lists of the pinned predicates. Unlike lCache, it never evicts them.
+var pinnedLists = struct {
+	sync.RWMutex
+	m map[string]*List
+}{m: make(map[string]*List)}
+
+func init() {
+	policy.Store(&cachePolicy{})
+}
+
+// SetCachePolicy pins the lists of the given predicates in the posting list cache, and keeps the
+// lists of the excluded ones out of it. Scans over excluded predicates don't evict the other lists.
+func SetCachePolicy(pinned, excluded []string) {
+	p := &cachePolicy{
+		pinned:   make(map[string]struct{}),
+		excluded: make(map[string]struct{}),
+	}
+	for _, attr := range pinned {
+		p.pinned[attr] = struct{}{}
+	}
+	for _, attr := range excluded {
+		p.excluded[attr] = struct{}{}
+	}
+	policy.Store(p)
+
+	// The lists of predicates that are no longer pinned are left to lCache.
+	pinnedLists.Lock()
+	defer pinnedLists.Unlock()
+	for key := range pinnedLists.m {
+		if _, ok := p.pinned[string(keyAttr([]byte(key)))]; !ok {
+			delete(pinnedLists.m, key)
+		}
+	}
+}
+
+// keyAttr returns the predicate of the key without parsing the rest of it.
+func keyAttr(key []byte) []byte {
+	if len(key) < 3 {
+		return nil
+	}
+	sz := int(binary.BigEndian.Uint16(key[1:3]))
+	if len(key) < 3+sz {
+		return nil
+	}
+	return key[3 : 3+sz]
+}
+
+// cacheStats counts the accesses of the cached lists of a predicate.
+type cacheStats struct {
+	hits, misses, evictions int64
+}
+
+var predCacheStats = struct {
+	sync.RWMutex
+	m map[string]*cacheStats
+}{m: make(map[string]*cacheStats)}
+
+func statsFor(attr []byte) *cacheStats {
+	predCacheStats.RLock()
+	s, ok := predCacheStats.m[string(attr)]
+	predCacheStats.RUnlock()
+	if ok {
+		return s
+	}
+	predCacheStats.Lock()
+	defer predCacheStats.Unlock()
+	if s, ok = predCacheStats.m[string(attr)]; !ok {
+		s = &cacheStats{}
+		predCacheStats.m[string(attr)] = s
+	}
+	return s
+}
+
+// cacheGet returns the cached list of the key, if it's cached.
+func cacheGet(key []byte) (*List, bool) {
+	attr := keyAttr(key)
+	p := policy.Load().(*cachePolicy)
+	if _, ok := p.excluded[string(attr)]; ok {
+		return nil, false
+	}
+
+	var l *List
+	if _, ok := p.pinned[string(attr)]; ok {
+		pinnedLists.RLock()
+		l = pinnedLists.m[string(key)]
+		pinnedLists.RUnlock()
+	} else if lCache == nil {
+		// The cache is disabled, there's nothing to count.
+		return nil, false
+	} else if val, ok := lCache.Get(key); ok {
+		l, _ = val.(*List)
+	}
+	s := statsFor(attr)
+	if l == nil {
+		atomic.AddInt64(&s.misses, 1)
+		return nil, false
+	}
+	atomic.AddInt64(&s.hits, 1)
+	return l, true
+}
+
+// cacheSet caches the list of the key, unless its predicate is excluded from the cache.
+func cacheSet(key []byte, l *List) {
+	attr := keyAttr(key)
+	p := policy.Load().(*cachePolicy)
+	if _, ok := p.excluded[string(attr)]; ok {
+		return
+	}
+	if _, ok := p.pinned[string(attr)]; ok {
+		pinnedLists.Lock()
+		pinnedLists.m[string(key)] = l
+		pinnedLists.Unlock()
+		return
+	}
+	lCache.Set(key, l, 0)
+}
+
+// cacheDel removes the list of the key from the cache.
+func cacheDel(key []byte) {
+	lCache.Del(key)
+	pinnedLists.Lock()
+	delete(pinnedLists.m, string(key))
+	pinnedLists.Unlock()
+}
+
+// cacheClear removes all the lists from the cache.
+func cacheClear() {
+	lCache.Clear()
+	pinnedLists.Lock()
+	pinnedLists.m = make(map[string]*List)
+	pinnedLists.Unlock()
+}
+
+// onCacheEvict counts the lists evicted from lCache.
+func onCacheEvict(item *ristretto.Item) {
+	if l, ok := item.Value.(*List); ok && l != nil {
+		atomic.AddInt64(&statsFor(keyAttr(l.key)).evictions, 1)
+	}
+}
+
+// recordCacheStats records the hits, misses and evictions of the cached lists of each predicate.
+func recordCacheStats() {
+	predCacheStats.RLock()
+	defer predCacheStats.RUnlock()
+	for attr, s := range predCacheStats.m {
+		_ = ostats.RecordWithTags(context.Background(),
+			[]tag.Mutator{tag.Upsert(x.KeyPredicate, attr)},
+			x.PLCacheHits.M(atomic.LoadInt64(&s.hits)),
+			x.PLCacheMisses.M(atomic.LoadInt64(&s.misses)),
+			x.PLCacheEvictions.M(atomic.LoadInt64(&s.evictions)))
+	}
+}
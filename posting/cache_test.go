@@ -0,0 +1,73 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package posting
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dgraph-io/dgraph/x"
+)
+
+func TestCachePolicy(t *testing.T) {
+	require.Equal(t, "cache.pinned", string(keyAttr(x.DataKey("cache.pinned", 1))))
+	require.Equal(t, "cache.pinned", string(keyAttr(x.IndexKey("cache.pinned", "term"))))
+	require.Nil(t, keyAttr([]byte{0}))
+
+	// The lists of the tests are read without lCache, only the pinned lists are cached.
+	SetCachePolicy([]string{"cache.pinned"}, []string{"cache.excluded"})
+	defer SetCachePolicy(nil, nil)
+
+	pinnedKey := x.DataKey("cache.pinned", 1)
+	_, err := getNew(pinnedKey, ps, math.MaxUint64)
+	require.NoError(t, err)
+	_, err = getNew(pinnedKey, ps, math.MaxUint64)
+	require.NoError(t, err)
+	s := statsFor([]byte("cache.pinned"))
+	require.Equal(t, int64(1), s.misses)
+	require.Equal(t, int64(1), s.hits)
+
+	RemoveCacheFor(pinnedKey)
+	_, ok := cacheGet(pinnedKey)
+	require.False(t, ok)
+	require.Equal(t, int64(2), s.misses)
+
+	// The excluded lists are never cached, nor counted.
+	excludedKey := x.DataKey("cache.excluded", 1)
+	for i := 0; i < 2; i++ {
+		_, err = getNew(excludedKey, ps, math.MaxUint64)
+		require.NoError(t, err)
+	}
+	_, ok = cacheGet(excludedKey)
+	require.False(t, ok)
+	predCacheStats.RLock()
+	_, ok = predCacheStats.m["cache.excluded"]
+	predCacheStats.RUnlock()
+	require.False(t, ok)
+
+	// The lists of predicates that are no longer pinned are dropped.
+	_, err = getNew(pinnedKey, ps, math.MaxUint64)
+	require.NoError(t, err)
+	_, ok = cacheGet(pinnedKey)
+	require.True(t, ok)
+	SetCachePolicy(nil, nil)
+	pinnedLists.RLock()
+	require.Empty(t, pinnedLists.m)
+	pinnedLists.RUnlock()
+}
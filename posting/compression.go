@@ -0,0 +1,217 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package posting
+
+import (
+	"encoding/binary"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/dgraph-io/badger/v2/y"
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/golang/snappy"
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// The complete posting lists of the predicates with @compression are stored with a header of
+// two bytes: compressedMarker, which a marshalled posting list never starts with as there is no
+// field number zero, followed by the algorithm they're compressed with. The lists compressed
+// with a dictionary have the index of the dictionary in the schema of the predicate after the
+// header, as a uvarint.
+const (
+	compressedMarker   byte = 0x00
+	compressedSnappy   byte = 0x01
+	compressedZstd     byte = 0x02
+	compressedZstdDict byte = 0x03
+
+	// zstdLevel is the level the posting lists are compressed at with zstd, the same as the
+	// default level of the tables of Badger.
+	zstdLevel = 3
+)
+
+var (
+	// dictionarySamples is the number of lists sampled to train a compression dictionary.
+	dictionarySamples = 2000
+	// minDictionarySamples is the number of lists a predicate needs for a dictionary to be
+	// trained on them.
+	minDictionarySamples = 100
+	// maxDictionarySampleSize is the size of the largest list sampled. The larger lists compress
+	// well enough without a dictionary.
+	maxDictionarySampleSize = 1024
+	// compressionDictionarySize is the size of the trained dictionaries.
+	compressionDictionarySize = 32 << 10
+	// minDictionaryGain is the fraction of the compressed size of the sampled lists a new
+	// dictionary has to save for it to replace the latest one.
+	minDictionaryGain = 0.1
+)
+
+// compressPostingList compresses the value of the given complete posting list of the predicate
+// with the algorithm set in its schema. The value is left as is if the predicate has no
+// @compression, or if compressing it doesn't make it smaller.
+func compressPostingList(attr string, val []byte, alloc *z.Allocator) []byte {
+	header := make([]byte, 2, 2+binary.MaxVarintLen64)
+	header[0] = compressedMarker
+	var out []byte
+	var err error
+	switch schema.State().Compression(attr) {
+	case schema.CompressionSnappy:
+		header[1], out = compressedSnappy, snappy.Encode(nil, val)
+	case schema.CompressionZstdDict:
+		if dicts := schema.State().CompressionDictionaries(attr); len(dicts) > 0 {
+			// The lists are compressed with the latest dictionary.
+			header[1] = compressedZstdDict
+			header = header[:2+binary.PutUvarint(header[2:cap(header)], uint64(len(dicts)-1))]
+			out, err = zstdCompressDict(val, dicts[len(dicts)-1])
+			break
+		}
+		// The lists are compressed without a dictionary until the first one is trained.
+		fallthrough
+	case schema.CompressionZstd:
+		// Zstd needs cgo, the values are stored uncompressed without it.
+		header[1] = compressedZstd
+		out, err = y.ZSTDCompress(nil, val, zstdLevel)
+	default:
+		return val
+	}
+	if err != nil || len(header)+len(out) >= len(val) {
+		return val
+	}
+	buf := alloc.Allocate(len(header) + len(out))
+	copy(buf, header)
+	copy(buf[len(header):], out)
+	return buf
+}
+
+// DecompressPostingList returns the marshalled posting list stored in the given value of the
+// complete posting list of the key, decompressing it if it was compressed.
+func DecompressPostingList(key, val []byte) ([]byte, error) {
+	if len(val) < 2 || val[0] != compressedMarker {
+		return val, nil
+	}
+	switch val[1] {
+	case compressedSnappy:
+		return snappy.Decode(nil, val[2:])
+	case compressedZstd:
+		return y.ZSTDDecompress(nil, val[2:])
+	case compressedZstdDict:
+		id, n := binary.Uvarint(val[2:])
+		if n <= 0 {
+			return nil, errors.New("invalid dictionary of posting list")
+		}
+		attr := string(keyAttr(key))
+		dicts := schema.State().CompressionDictionaries(attr)
+		if id >= uint64(len(dicts)) {
+			return nil, errors.Errorf("dictionary %d of predicate %s not found", id, attr)
+		}
+		return zstdDecompressDict(val[2+n:], dicts[id])
+	default:
+		return nil, errors.Errorf("unknown compression %d of posting list", val[1])
+	}
+}
+
+// TrainCompressionDictionary trains a dictionary on a sample of the small complete posting lists
+// of the predicate, as of readTs. The dictionary is made of the sampled lists themselves, the
+// content zstd looks for matches in. It's only returned if it compresses the lists noticeably
+// better than the latest dictionary of the predicate, otherwise it's nil. Half of the sampled
+// lists are kept out of the dictionary to compare the two.
+func TrainCompressionDictionary(attr string, readTs uint64) ([]byte, error) {
+	samples, err := sampleLists(attr, readTs)
+	if err != nil || len(samples) < minDictionarySamples {
+		return nil, err
+	}
+
+	var dict, check [][]byte
+	for i, sample := range samples {
+		if i%2 == 0 {
+			dict = append(dict, sample)
+		} else {
+			check = append(check, sample)
+		}
+	}
+	// Zstd finds the matches at the end of the dictionary more cheaply, so that's where the
+	// first samples go.
+	var trained []byte
+	for i := len(dict) - 1; i >= 0 && len(trained) < compressionDictionarySize; i-- {
+		trained = append(trained, dict[i]...)
+	}
+	if len(trained) > compressionDictionarySize {
+		trained = trained[len(trained)-compressionDictionarySize:]
+	}
+
+	var latest []byte
+	if dicts := schema.State().CompressionDictionaries(attr); len(dicts) > 0 {
+		latest = dicts[len(dicts)-1]
+	}
+	var before, after int
+	for _, sample := range check {
+		out, err := compressSample(sample, latest)
+		if err != nil {
+			return nil, err
+		}
+		before += len(out)
+		if out, err = zstdCompressDict(sample, trained); err != nil {
+			return nil, err
+		}
+		after += len(out)
+	}
+	if float64(after) > float64(before)*(1-minDictionaryGain) {
+		return nil, nil
+	}
+	return trained, nil
+}
+
+// compressSample compresses the sampled list with the given dictionary, or without one if it's
+// nil.
+func compressSample(sample, dict []byte) ([]byte, error) {
+	if dict == nil {
+		return y.ZSTDCompress(nil, sample, zstdLevel)
+	}
+	return zstdCompressDict(sample, dict)
+}
+
+// sampleLists returns the marshalled complete posting lists of the first data keys of the
+// predicate that are at most maxDictionarySampleSize long.
+func sampleLists(attr string, readTs uint64) ([][]byte, error) {
+	txn := pstore.NewTransactionAt(readTs, false)
+	defer txn.Discard()
+	iopt := badger.DefaultIteratorOptions
+	iopt.Prefix = x.ParsedKey{Attr: attr}.DataPrefix()
+	itr := txn.NewIterator(iopt)
+	defer itr.Close()
+
+	var samples [][]byte
+	for itr.Rewind(); itr.Valid() && len(samples) < dictionarySamples; itr.Next() {
+		item := itr.Item()
+		if item.UserMeta()&BitCompletePosting == 0 {
+			continue
+		}
+		err := item.Value(func(val []byte) error {
+			val, err := DecompressPostingList(item.Key(), val)
+			if err != nil || len(val) == 0 || len(val) > maxDictionarySampleSize {
+				return err
+			}
+			samples = append(samples, append([]byte{}, val...))
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return samples, nil
+}
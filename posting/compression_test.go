@@ -0,0 +1,128 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package posting
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	bpb "github.com/dgraph-io/badger/v2/pb"
+	"github.com/dgraph-io/badger/v2/y"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+func TestCompressedRollup(t *testing.T) {
+	algos := map[string]byte{schema.CompressionSnappy: compressedSnappy}
+	if y.CgoEnabled {
+		algos[schema.CompressionZstd] = compressedZstd
+	}
+	text := strings.Repeat("the quick brown fox jumps over the lazy dog ", 100)
+	for name, algo := range algos {
+		attr := "text_" + name
+		require.NoError(t, schema.ParseBytes([]byte(attr+": string @compression("+name+") ."), 1))
+		addEdgeToValue(t, attr, 1, text, 1, 2)
+
+		l, err := getNew(x.DataKey(attr, 1), pstore, math.MaxUint64)
+		require.NoError(t, err)
+		kvs, err := l.Rollup(nil)
+		require.NoError(t, err)
+		require.Len(t, kvs, 1)
+		require.Equal(t, []byte{compressedMarker, algo}, kvs[0].Value[:2])
+		require.Less(t, len(kvs[0].Value), len(text))
+
+		var plist pb.PostingList
+		val, err := DecompressPostingList(kvs[0].Key, kvs[0].Value)
+		require.NoError(t, err)
+		require.NoError(t, plist.Unmarshal(val))
+		require.Equal(t, text, string(plist.Postings[0].Value))
+
+		// The list is read back from disk as it was written.
+		require.NoError(t, writePostingListToDisk(kvs))
+		l, err = getNew(x.DataKey(attr, 1), pstore, math.MaxUint64)
+		require.NoError(t, err)
+		v, err := l.Value(3)
+		require.NoError(t, err)
+		require.Equal(t, text, string(v.Value.([]byte)))
+	}
+
+	// The lists of the predicates without @compression are stored as they are.
+	addEdgeToValue(t, "plain_text", 1, text, 1, 2)
+	l, err := getNew(x.DataKey("plain_text", 1), pstore, math.MaxUint64)
+	require.NoError(t, err)
+	kvs, err := l.Rollup(nil)
+	require.NoError(t, err)
+	var plist pb.PostingList
+	require.NoError(t, plist.Unmarshal(kvs[0].Value))
+}
+
+func TestCompressionDictionary(t *testing.T) {
+	if !y.CgoEnabled {
+		t.Skip("Zstd needs cgo")
+	}
+	attr := "dict_email"
+	require.NoError(t, schema.ParseBytes([]byte(attr+": string @compression(zstd_dict) ."), 1))
+	rollup := func(uid uint64) []*bpb.KV {
+		// Each list is committed in its own transaction, as committing one writes all of its
+		// deltas again.
+		addEdgeToValue(t, attr, uid, fmt.Sprintf("user.%d@example.com", uid), 2*uid, 2*uid+1)
+		l, err := getNew(x.DataKey(attr, uid), pstore, math.MaxUint64)
+		require.NoError(t, err)
+		kvs, err := l.Rollup(nil)
+		require.NoError(t, err)
+		require.NoError(t, writePostingListToDisk(kvs))
+		return kvs
+	}
+	for uid := uint64(1); uid <= 200; uid++ {
+		rollup(uid)
+	}
+
+	dict, err := TrainCompressionDictionary(attr, math.MaxUint64)
+	require.NoError(t, err)
+	require.NotEmpty(t, dict)
+	require.LessOrEqual(t, len(dict), compressionDictionarySize)
+	require.NotNil(t, schema.State().AddCompressionDictionary(attr, dict))
+
+	// The lists are compressed with the latest dictionary, whose index follows the header.
+	kvs := rollup(201)
+	require.Equal(t, []byte{compressedMarker, compressedZstdDict, 0}, kvs[0].Value[:3])
+	l, err := getNew(x.DataKey(attr, 201), pstore, math.MaxUint64)
+	require.NoError(t, err)
+	v, err := l.Value(math.MaxUint64)
+	require.NoError(t, err)
+	require.Equal(t, "user.201@example.com", string(v.Value.([]byte)))
+
+	// A dictionary trained on the same lists doesn't do any better.
+	dict, err = TrainCompressionDictionary(attr, math.MaxUint64)
+	require.NoError(t, err)
+	require.Nil(t, dict)
+}
+
+func TestDecompressPostingListErrors(t *testing.T) {
+	_, err := DecompressPostingList(nil, []byte{compressedMarker, 0x7f, 1, 2})
+	require.Error(t, err)
+	_, err = DecompressPostingList(nil, []byte{compressedMarker, compressedSnappy, 0xff})
+	require.Error(t, err)
+	_, err = DecompressPostingList(x.DataKey("no_dict", 1),
+		[]byte{compressedMarker, compressedZstdDict, 0, 1})
+	require.Error(t, err)
+}
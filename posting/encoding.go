@@ -0,0 +1,244 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package posting
+
+import (
+	"context"
+	"encoding/binary"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+const (
+	// encodingDictionary marks the postings whose value is the id of a string in the dictionary
+	// of the predicate.
+	encodingDictionary uint32 = 1
+	// encodingDelta marks the postings whose value is the difference of an int or a datetime to
+	// the delta base of the predicate.
+	encodingDelta uint32 = 2
+
+	// datetimeSize is the length of the binary form of datetimes with a time zone offset in
+	// minutes, the only ones stored as deltas.
+	datetimeSize = 15
+)
+
+// DeltaValue returns the number the deltas of the given value, in its binary form, are taken
+// of. That's the value itself for ints, and the number of seconds since the year 1 for
+// datetimes.
+func DeltaValue(tid types.TypeID, data []byte) (int64, bool) {
+	switch {
+	case tid == types.IntID && len(data) == 8:
+		return int64(binary.LittleEndian.Uint64(data)), true
+	case tid == types.DateTimeID && len(data) == datetimeSize && data[0] == 1:
+		return int64(binary.BigEndian.Uint64(data[1:9])), true
+	}
+	return 0, false
+}
+
+// encodeValues replaces the values in the given delta with their encoded form, as given by the
+// encoding of the predicate. The values without one, like the strings that aren't in the
+// dictionary of the predicate, are kept as they are.
+func encodeValues(key, delta []byte) ([]byte, error) {
+	pk, err := x.Parse(key)
+	if err != nil {
+		return nil, err
+	}
+	if !pk.IsData() {
+		return delta, nil
+	}
+	encoding := schema.State().Encoding(pk.Attr)
+	if encoding == "" {
+		return delta, nil
+	}
+
+	var plist pb.PostingList
+	if err := plist.Unmarshal(delta); err != nil {
+		return nil, err
+	}
+	var changed bool
+	for i, p := range plist.Postings {
+		if p.Op != Set || p.PostingType == pb.Posting_REF || p.Encoding != 0 || isBlob(p) {
+			continue
+		}
+		if enc, ok := encodePosting(pk.Attr, encoding, p); ok {
+			plist.Postings[i] = enc
+			changed = true
+		}
+	}
+	if !changed {
+		return delta, nil
+	}
+	return plist.Marshal()
+}
+
+// SetValues returns the values set by the txn, as the edges setting them, so that the encodings
+// of their predicates can be extended once the txn is committed at commitTs. They're in the order
+// of their keys, for all the members of the group to extend the encodings the same way.
+func (txn *Txn) SetValues(commitTs uint64) ([]*pb.DirectedEdge, error) {
+	cache := txn.cache
+	cache.RLock()
+	defer cache.RUnlock()
+
+	keys := make([]string, 0, len(cache.deltas))
+	for key := range cache.deltas {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var edges []*pb.DirectedEdge
+	for _, key := range keys {
+		if ts := cache.maxVersions[key]; ts >= commitTs {
+			// The delta isn't written, as in CommitToDisk.
+			continue
+		}
+		pk, err := x.Parse([]byte(key))
+		if err != nil {
+			return nil, err
+		}
+		if !pk.IsData() || schema.State().Encoding(pk.Attr) == "" {
+			continue
+		}
+		var plist pb.PostingList
+		if err := plist.Unmarshal(cache.deltas[key]); err != nil {
+			return nil, err
+		}
+		for _, p := range plist.Postings {
+			if p.Op != Set || p.PostingType == pb.Posting_REF || p.Encoding != 0 || isBlob(p) {
+				continue
+			}
+			edges = append(edges, &pb.DirectedEdge{
+				Attr:      pk.Attr,
+				Entity:    pk.Uid,
+				Value:     p.Value,
+				ValueType: p.ValType,
+				Op:        pb.DirectedEdge_SET,
+			})
+		}
+	}
+	return edges, nil
+}
+
+// encodePosting returns a copy of the posting with its value encoded, if it has an encoded form.
+func encodePosting(attr, encoding string, p *pb.Posting) (*pb.Posting, bool) {
+	val := make([]byte, 3*binary.MaxVarintLen64)
+	var n int
+	var marker uint32
+	switch tid := types.TypeID(p.ValType); {
+	case encoding == schema.EncodingDictionary && (tid == types.StringID || tid == types.DefaultID):
+		id, ok := schema.State().DictionaryID(attr, string(p.Value))
+		if !ok {
+			return nil, false
+		}
+		n = binary.PutUvarint(val, id)
+		marker = encodingDictionary
+	case encoding == schema.EncodingDelta:
+		base, ok := schema.State().EncodingBase(attr)
+		if !ok {
+			return nil, false
+		}
+		v, ok := DeltaValue(tid, p.Value)
+		if !ok {
+			return nil, false
+		}
+		n = binary.PutVarint(val, v-base)
+		if tid == types.DateTimeID {
+			n += binary.PutUvarint(val[n:], uint64(binary.BigEndian.Uint32(p.Value[9:13])))
+			n += binary.PutVarint(val[n:], int64(int16(binary.BigEndian.Uint16(p.Value[13:]))))
+		}
+		marker = encodingDelta
+	default:
+		return nil, false
+	}
+
+	// The delta shares the postings with the in-memory lists, so modify a copy.
+	enc := *p
+	enc.Value = val[:n]
+	enc.Encoding = marker
+	return &enc, true
+}
+
+// DecodeValue returns a copy of the posting with its value decoded with the dictionary and the
+// delta base of the given schema, if the value is stored encoded. Otherwise, the posting is
+// returned as is.
+func DecodeValue(su *pb.SchemaUpdate, p *pb.Posting) (*pb.Posting, error) {
+	if p == nil || p.Encoding == 0 {
+		return p, nil
+	}
+	var val []byte
+	switch p.Encoding {
+	case encodingDictionary:
+		id, n := binary.Uvarint(p.Value)
+		if n <= 0 || id >= uint64(len(su.Dictionary)) {
+			return nil, errors.Errorf("Invalid dictionary id for predicate %s", su.Predicate)
+		}
+		val = []byte(su.Dictionary[id])
+	case encodingDelta:
+		d, n := binary.Varint(p.Value)
+		if n <= 0 {
+			return nil, errors.Errorf("Invalid delta for predicate %s", su.Predicate)
+		}
+		v := su.EncodingBase + d
+		switch types.TypeID(p.ValType) {
+		case types.IntID:
+			val = make([]byte, 8)
+			binary.LittleEndian.PutUint64(val, uint64(v))
+		case types.DateTimeID:
+			nsec, m := binary.Uvarint(p.Value[n:])
+			if m <= 0 {
+				return nil, errors.Errorf("Invalid delta for predicate %s", su.Predicate)
+			}
+			offset, k := binary.Varint(p.Value[n+m:])
+			if k <= 0 {
+				return nil, errors.Errorf("Invalid delta for predicate %s", su.Predicate)
+			}
+			val = make([]byte, datetimeSize)
+			val[0] = 1
+			binary.BigEndian.PutUint64(val[1:9], uint64(v))
+			binary.BigEndian.PutUint32(val[9:13], uint32(nsec))
+			binary.BigEndian.PutUint16(val[13:], uint16(int16(offset)))
+		default:
+			return nil, errors.Errorf("Invalid type of delta for predicate %s", su.Predicate)
+		}
+	default:
+		return nil, errors.Errorf("Unknown encoding %d for predicate %s", p.Encoding, su.Predicate)
+	}
+
+	decoded := *p
+	decoded.Value = val
+	decoded.Encoding = 0
+	return &decoded, nil
+}
+
+// resolvePosting returns a copy of the posting with the value it was written with, reading it
+// back from the blob keys or decoding it if needed. Otherwise, the posting is returned as is.
+func (l *List) resolvePosting(p *pb.Posting, readTs uint64) (*pb.Posting, error) {
+	if p == nil || p.Encoding == 0 {
+		return l.resolveBlob(p, readTs)
+	}
+	pk, err := x.Parse(l.key)
+	if err != nil {
+		return nil, err
+	}
+	su, _ := schema.State().Get(context.Background(), pk.Attr)
+	su.Predicate = pk.Attr
+	return DecodeValue(&su, p)
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package posting
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+func addEdgeWithType(t *testing.T, attr string, src uint64, tid pb.Posting_ValType,
+	value []byte, startTs, commitTs uint64) {
+	edge := &pb.DirectedEdge{
+		Value:     value,
+		ValueType: tid,
+		Attr:      attr,
+		Entity:    src,
+		Op:        pb.DirectedEdge_SET,
+	}
+	l, err := GetNoStore(x.DataKey(attr, src), startTs)
+	require.NoError(t, err)
+	addMutation(t, l, edge, Set, startTs, commitTs, false)
+}
+
+// storedPosting returns the posting of the list as it's written to disk.
+func storedPosting(t *testing.T, attr string, uid uint64) *pb.Posting {
+	l, err := getNew(x.DataKey(attr, uid), pstore, math.MaxUint64)
+	require.NoError(t, err)
+	kvs, err := l.Rollup(nil)
+	require.NoError(t, err)
+	require.Len(t, kvs, 1)
+	var plist pb.PostingList
+	require.NoError(t, plist.Unmarshal(kvs[0].Value))
+	require.Len(t, plist.Postings, 1)
+	return plist.Postings[0]
+}
+
+func TestDictionaryEncoding(t *testing.T) {
+	require.NoError(t, schema.ParseBytes([]byte("color: string @encoding(dictionary) ."), 1))
+	require.NotNil(t, schema.State().ExtendEncoding("color", []string{"red", "blue"}, 0, false))
+	addEdgeToValue(t, "color", 1, "blue", 1, 2)
+	addEdgeToValue(t, "color", 2, "green", 3, 4)
+
+	l, err := getNew(x.DataKey("color", 1), pstore, math.MaxUint64)
+	require.NoError(t, err)
+	val, err := l.Value(5)
+	require.NoError(t, err)
+	require.Equal(t, "blue", string(val.Value.([]byte)))
+
+	p := storedPosting(t, "color", 1)
+	require.Equal(t, encodingDictionary, p.Encoding)
+	require.Equal(t, []byte{1}, p.Value)
+
+	// The strings that aren't in the dictionary are stored as they are.
+	p = storedPosting(t, "color", 2)
+	require.Zero(t, p.Encoding)
+	require.Equal(t, "green", string(p.Value))
+
+	_, err = DecodeValue(&pb.SchemaUpdate{Predicate: "color"},
+		&pb.Posting{Value: []byte{5}, Encoding: encodingDictionary})
+	require.Error(t, err)
+}
+
+func TestDeltaEncoding(t *testing.T) {
+	require.NoError(t, schema.ParseBytes([]byte(`
+		seq: int @encoding(delta) .
+		at: datetime @encoding(delta) .
+	`), 1))
+	require.NotNil(t, schema.State().ExtendEncoding("seq", nil, 1000000, true))
+
+	for i, v := range []int64{1000007, 999990} {
+		data := make([]byte, 8)
+		binary.LittleEndian.PutUint64(data, uint64(v))
+		uid := uint64(i + 1)
+		addEdgeWithType(t, "seq", uid, pb.Posting_INT, data, uint64(2*i+1), uint64(2*i+2))
+
+		l, err := getNew(x.DataKey("seq", uid), pstore, math.MaxUint64)
+		require.NoError(t, err)
+		val, err := l.Value(10)
+		require.NoError(t, err)
+		require.Equal(t, data, val.Value)
+
+		p := storedPosting(t, "seq", uid)
+		require.Equal(t, encodingDelta, p.Encoding)
+		require.Len(t, p.Value, 1)
+	}
+
+	at := time.Date(2020, 6, 1, 10, 30, 0, 123, time.FixedZone("", 2*3600))
+	data, err := at.MarshalBinary()
+	require.NoError(t, err)
+	base, ok := DeltaValue(types.DateTimeID, data)
+	require.True(t, ok)
+	require.NotNil(t, schema.State().ExtendEncoding("at", nil, base-60, true))
+	addEdgeWithType(t, "at", 1, pb.Posting_DATETIME, data, 11, 12)
+
+	l, err := getNew(x.DataKey("at", 1), pstore, math.MaxUint64)
+	require.NoError(t, err)
+	val, err := l.Value(13)
+	require.NoError(t, err)
+	require.Equal(t, data, val.Value)
+	p := storedPosting(t, "at", 1)
+	require.Equal(t, encodingDelta, p.Encoding)
+	require.Less(t, len(p.Value), len(data))
+}
@@ -36,10 +36,12 @@ import (
 	"github.com/dgraph-io/badger/v2/options"
 	bpb "github.com/dgraph-io/badger/v2/pb"
 	"github.com/dgraph-io/badger/v2/y"
+	"github.com/dgraph-io/dgo/v200/protos/api"
 	"github.com/dgraph-io/dgraph/protos/pb"
 	"github.com/dgraph-io/dgraph/schema"
 	"github.com/dgraph-io/dgraph/tok"
 	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/types/facets"
 	"github.com/dgraph-io/dgraph/x"
 )
 
@@ -66,10 +68,31 @@ func indexTokens(ctx context.Context, info *indexMutationInfo) ([]string, error)
 	if !schema.State().IsIndexed(ctx, attr) {
 		return nil, errors.Errorf("Attribute %s is not indexed.", attr)
 	}
-	sv, err := types.Convert(info.val, schemaType)
+	// A composite index stores the values of all the predicates it spans. The last one is
+	// tokenized, and the tokens are prefixed by the other ones.
+	var leading []string
+	val := info.val
+	if x.IsCompositeIndex(attr) {
+		str, err := types.Convert(val, types.StringID)
+		if err != nil {
+			return nil, err
+		}
+		values, err := tok.ParseCompositeValue(str.Value.(string),
+			len(x.CompositeIndexPredicates(attr)))
+		if err != nil {
+			return nil, err
+		}
+		leading = values[:len(values)-1]
+		val = types.Val{Tid: types.StringID, Value: []byte(values[len(values)-1])}
+	}
+	sv, err := types.Convert(val, schemaType)
 	if err != nil {
 		return nil, err
 	}
+	// A partial index only has the values satisfying its condition.
+	if cond, ok := schema.State().IndexCondition(ctx, attr); ok && !cond.Holds(sv) {
+		return nil, nil
+	}
 
 	var tokens []string
 	for _, it := range info.tokenizers {
@@ -77,7 +100,19 @@ func indexTokens(ctx context.Context, info *indexMutationInfo) ([]string, error)
 		if err != nil {
 			return tokens, err
 		}
+		if leading != nil {
+			for i := range toks {
+				toks[i] = tok.CompositeToken(leading, toks[i])
+			}
+		}
 		tokens = append(tokens, toks...)
+
+		if it.Identifier() == tok.IdentFullText {
+			// The length of the value is indexed too, for the corpus statistics of score().
+			str, _ := sv.Value.(string)
+			_, n := tok.GetTokenizerForLang(it, lang).(tok.FullTextTokenizer).TermCounts(str)
+			tokens = append(tokens, tok.FullTextLengthToken(n))
+		}
 	}
 	return tokens, nil
 }
@@ -113,6 +148,14 @@ func (txn *Txn) addIndexMutations(ctx context.Context, info *indexMutationInfo)
 			return err
 		}
 	}
+	// The hnsw index has no tokens, its graph is updated on its own.
+	for _, it := range info.tokenizers {
+		if vt, ok := it.(tok.HNSWTokenizer); ok {
+			if err := txn.addVectorIndexMutation(ctx, info, vt); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
@@ -416,6 +459,13 @@ func (txn *Txn) addMutationHelper(ctx context.Context, l *List, doUpdateIndex bo
 			return val, found, emptyCountParams, err
 		}
 	}
+	if found && currPost.Encoding != 0 {
+		// The current value is compared with the new one and removed from the index, so it's
+		// needed as it was written.
+		if currPost, err = l.resolvePosting(currPost, txn.StartTs); err != nil {
+			return val, found, emptyCountParams, err
+		}
+	}
 
 	// If the predicate schema is not a list, ignore delete triples whose object is not a star or
 	// a value that does not match the existing value.
@@ -489,7 +539,11 @@ func (l *List) AddMutationWithIndex(ctx context.Context, edge *pb.DirectedEdge,
 	}
 	if doUpdateIndex {
 		// Exact matches.
-		if found && val.Value != nil {
+		if found && val.Value != nil && x.IsEdgeProperty(edge.Attr) {
+			if err := txn.deleteEdgePropertyIndex(ctx, l, edge, val); err != nil {
+				return err
+			}
+		} else if found && val.Value != nil {
 			if err := txn.addIndexMutations(ctx, &indexMutationInfo{
 				tokenizers: schema.State().Tokenizer(ctx, edge.Attr),
 				edge:       edge,
@@ -517,6 +571,63 @@ func (l *List) AddMutationWithIndex(ctx context.Context, edge *pb.DirectedEdge,
 	return nil
 }
 
+// deleteEdgePropertyIndex removes the node from the index entries of the old value of an edge
+// property. The other edges of the node can share some of these entries, so the tokens of the
+// values still in the list are kept. Those are added as conflict keys instead, so that two
+// transactions removing the last two values with a token can't both keep it.
+func (txn *Txn) deleteEdgePropertyIndex(ctx context.Context, l *List, edge *pb.DirectedEdge,
+	val types.Val) error {
+	info := &indexMutationInfo{
+		tokenizers: schema.State().Tokenizer(ctx, edge.Attr),
+		edge:       edge,
+		val:        val,
+		op:         pb.DirectedEdge_DEL,
+	}
+	tokens, err := indexTokens(ctx, info)
+	if err != nil {
+		return err
+	}
+	vals, err := l.AllValues(txn.StartTs)
+	if err != nil {
+		return err
+	}
+	keep := make(map[string]struct{})
+	for _, v := range vals {
+		info.val = v
+		toks, err := indexTokens(ctx, info)
+		if err != nil {
+			return err
+		}
+		for _, token := range toks {
+			keep[token] = struct{}{}
+		}
+	}
+
+	delEdge := &pb.DirectedEdge{
+		ValueId: edge.Entity,
+		Attr:    edge.Attr,
+		Op:      pb.DirectedEdge_DEL,
+	}
+	for _, token := range tokens {
+		if _, ok := keep[token]; !ok {
+			if err := txn.addIndexMutation(ctx, delEdge, token); err != nil {
+				return err
+			}
+			continue
+		}
+		if x.WorkerConfig.LudicrousMode {
+			continue
+		}
+		key := x.IndexKey(edge.Attr, token)
+		pk, err := x.Parse(key)
+		if err != nil {
+			return err
+		}
+		txn.addConflictKey(GetConflictKey(pk, key, delEdge))
+	}
+	return nil
+}
+
 // deleteTokensFor deletes the index for the given attribute and token.
 func deleteTokensFor(attr, tokenizerName string, hasLang bool) error {
 	pk := x.ParsedKey{Attr: attr}
@@ -590,6 +701,9 @@ type rebuilder struct {
 	// The posting list passed here is the on disk version. It is not coming
 	// from the LRU cache.
 	fn func(uid uint64, pl *List, txn *Txn) error
+	// sequential makes fn run for one key at a time in a single transaction, for the indexes
+	// that are built by reading what was indexed before, like the hnsw graph.
+	sequential bool
 }
 
 func (r *rebuilder) Run(ctx context.Context) error {
@@ -639,6 +753,11 @@ func (r *rebuilder) Run(ctx context.Context) error {
 	stream := pstore.NewStreamAt(r.startTs)
 	stream.LogPrefix = fmt.Sprintf("Rebuilding index for predicate %s (1/2):", r.attr)
 	stream.Prefix = r.prefix
+	var seqTxn *Txn
+	if r.sequential {
+		seqTxn = NewTxn(r.startTs)
+		stream.NumGo = 1
+	}
 	stream.KeyToList = func(key []byte, itr *badger.Iterator) (*bpb.KVList, error) {
 		// We should return quickly if the context is no longer valid.
 		select {
@@ -657,6 +776,11 @@ func (r *rebuilder) Run(ctx context.Context) error {
 			return nil, errors.Wrapf(err, "error reading posting list from disk")
 		}
 
+		if seqTxn != nil {
+			// The deltas are written once all the keys are done.
+			return nil, r.fn(pk.Uid, l, seqTxn)
+		}
+
 		// We are using different transactions in each call to KeyToList function. This could
 		// be a problem for computing reverse count indexes if deltas for same key are added
 		// in different transactions. Such a case doesn't occur for now.
@@ -695,6 +819,22 @@ func (r *rebuilder) Run(ctx context.Context) error {
 	if err := stream.Orchestrate(ctx); err != nil {
 		return err
 	}
+	if seqTxn != nil {
+		seqTxn.Update()
+		kvs := make([]*bpb.KV, 0, len(seqTxn.cache.deltas))
+		for key, data := range seqTxn.cache.deltas {
+			version := atomic.AddUint64(&counter, 1)
+			kvs = append(kvs, &bpb.KV{
+				Key:      []byte(key),
+				Value:    data,
+				UserMeta: []byte{BitDeltaPosting},
+				Version:  version,
+			})
+		}
+		if err := tmpWriter.Write(&bpb.KVList{Kv: kvs}); err != nil {
+			return errors.Wrap(err, "error setting entries in temp badger")
+		}
+	}
 	if err := tmpWriter.Flush(); err != nil {
 		return err
 	}
@@ -817,7 +957,10 @@ func (rb *IndexRebuild) DropIndexes(ctx context.Context) error {
 
 // BuildData updates data.
 func (rb *IndexRebuild) BuildData(ctx context.Context) error {
-	return rebuildListType(ctx, rb)
+	if err := rebuildListType(ctx, rb); err != nil {
+		return err
+	}
+	return rebuildEdgeProperty(ctx, rb)
 }
 
 // NeedIndexRebuild returns true if any of the tokenizer, reverse
@@ -878,8 +1021,9 @@ func (rb *IndexRebuild) needsTokIndexRebuild() indexRebuildInfo {
 	}
 
 	// All tokenizers in the index need to be deleted and rebuilt if the value
-	// types have changed.
-	if currIndex && rb.CurrentSchema.ValueType != old.ValueType {
+	// types or the condition of a partial index have changed.
+	if currIndex && (rb.CurrentSchema.ValueType != old.ValueType ||
+		rb.CurrentSchema.IndexWhere != old.IndexWhere) {
 		return indexRebuildInfo{
 			op:                  indexRebuild,
 			tokenizersToDelete:  old.Tokenizer,
@@ -972,7 +1116,8 @@ func rebuildTokIndex(ctx context.Context, rb *IndexRebuild) error {
 	}
 
 	pk := x.ParsedKey{Attr: rb.Attr}
-	builder := rebuilder{attr: rb.Attr, prefix: pk.DataPrefix(), startTs: rb.StartTs}
+	builder := rebuilder{attr: rb.Attr, prefix: pk.DataPrefix(), startTs: rb.StartTs,
+		sequential: types.TypeID(rb.CurrentSchema.ValueType) == types.VFloatID}
 	builder.fn = func(uid uint64, pl *List, txn *Txn) error {
 		edge := pb.DirectedEdge{Attr: rb.Attr, Entity: uid}
 		return pl.Iterate(txn.StartTs, 0, func(p *pb.Posting) error {
@@ -1233,6 +1378,87 @@ func rebuildListType(ctx context.Context, rb *IndexRebuild) error {
 	return builder.Run(ctx)
 }
 
+// needsEdgePropertyRebuild returns true if the predicate is an edge property that is declared
+// for the first time or whose type changed, so its values have to be built again from the
+// facets of the edges.
+func (rb *IndexRebuild) needsEdgePropertyRebuild() bool {
+	x.AssertTruef(rb.CurrentSchema != nil, "Current schema cannot be nil.")
+
+	if !x.IsEdgeProperty(rb.Attr) {
+		return false
+	}
+	if rb.OldSchema == nil {
+		return true
+	}
+	// Edge properties are always lists, so an old schema that isn't a list is the empty schema
+	// of a new predicate.
+	return !rb.OldSchema.List || rb.OldSchema.ValueType != rb.CurrentSchema.ValueType
+}
+
+// rebuildEdgeProperty stores the values of an edge property from the facets of the existing
+// edges of its predicate. The tablet of an edge property is served by the group serving its
+// predicate, so the edges are read locally.
+func rebuildEdgeProperty(ctx context.Context, rb *IndexRebuild) error {
+	if !rb.needsEdgePropertyRebuild() {
+		return nil
+	}
+
+	glog.Infof("Rebuilding edge property %s", rb.Attr)
+	edgeAttr, key := x.ParseEdgeProperty(rb.Attr)
+	pk := x.ParsedKey{Attr: edgeAttr}
+	builder := rebuilder{attr: rb.Attr, prefix: pk.DataPrefix(), startTs: rb.StartTs}
+	builder.fn = func(uid uint64, pl *List, txn *Txn) error {
+		plist, err := txn.Get(x.DataKey(rb.Attr, uid))
+		if err != nil {
+			return err
+		}
+		return pl.Iterate(txn.StartTs, 0, func(p *pb.Posting) error {
+			for _, f := range p.Facets {
+				if f.Key != key {
+					continue
+				}
+				val, err := edgePropertyValue(f, types.TypeID(rb.CurrentSchema.ValueType))
+				if err != nil {
+					// A mutation setting this facet now would fail, so the edge is skipped.
+					glog.V(2).Infof("Skipping facet %s of edge %#x -> %#x: %v",
+						key, uid, p.Uid, err)
+					return nil
+				}
+				return plist.addMutation(ctx, txn, &pb.DirectedEdge{
+					Entity:    uid,
+					Attr:      rb.Attr,
+					ValueId:   p.Uid,
+					Value:     val.Value.([]byte),
+					ValueType: val.Tid.Enum(),
+					Op:        pb.DirectedEdge_SET,
+				})
+			}
+			return nil
+		})
+	}
+	return builder.Run(ctx)
+}
+
+// edgePropertyValue converts the value of a facet to the binary value of the given type.
+func edgePropertyValue(f *api.Facet, typ types.TypeID) (types.Val, error) {
+	tid, err := facets.TypeIDFor(f)
+	if err != nil {
+		return types.Val{}, err
+	}
+	if typ == types.DefaultID {
+		typ = tid
+	}
+	val, err := types.Convert(types.Val{Tid: tid, Value: f.Value}, typ)
+	if err != nil {
+		return types.Val{}, err
+	}
+	b := types.ValueForType(types.BinaryID)
+	if err := types.Marshal(val, &b); err != nil {
+		return types.Val{}, err
+	}
+	return types.Val{Tid: typ, Value: b.Value}, nil
+}
+
 // DeleteAll deletes all entries in the posting list.
 func DeleteAll() error {
 	return pstore.DropAll()
@@ -1253,3 +1479,10 @@ func DeletePredicate(ctx context.Context, attr string) error {
 
 	return schema.State().Delete(attr)
 }
+
+// DeletePredicateData deletes all entries and indices for a given predicate, but leaves its
+// schema intact.
+func DeletePredicateData(attr string) error {
+	glog.Infof("Dropping data of predicate: [%s]", attr)
+	return pstore.DropPrefix(x.PredicatePrefix(attr))
+}
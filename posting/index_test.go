@@ -24,11 +24,13 @@ import (
 	"time"
 
 	"github.com/dgraph-io/badger/v2"
+	"github.com/dgraph-io/dgo/v200/protos/api"
 	"github.com/stretchr/testify/require"
 
 	"github.com/dgraph-io/dgraph/protos/pb"
 	"github.com/dgraph-io/dgraph/schema"
 	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/types/facets"
 	"github.com/dgraph-io/dgraph/x"
 )
 
@@ -532,3 +534,116 @@ func TestNeedsListTypeRebuild(t *testing.T) {
 	require.False(t, rebuild)
 	require.Error(t, err)
 }
+
+func TestEdgePropertyIndex(t *testing.T) {
+	require.NoError(t, schema.ParseBytes([]byte(`<friend|since>: string @index(exact) .`), 1))
+	attr := x.EdgeProperty("friend", "since")
+	tokens, err := indexTokensForTest(attr, "", types.Val{Tid: types.StringID,
+		Value: []byte("2020")})
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+	indexKey := x.IndexKey(attr, tokens[0])
+
+	setProperty := func(dst uint64, value string, op uint32, ts uint64) {
+		l, err := GetNoStore(x.DataKey(attr, 1), ts)
+		require.NoError(t, err)
+		edge := &pb.DirectedEdge{
+			Attr:      attr,
+			Entity:    1,
+			ValueId:   dst,
+			Value:     []byte(value),
+			ValueType: pb.Posting_STRING,
+		}
+		addMutation(t, l, edge, op, ts, ts+1, true)
+	}
+	indexUids := func(ts uint64) []uint64 {
+		l, err := GetNoStore(indexKey, ts)
+		require.NoError(t, err)
+		return uids(l, ts)
+	}
+
+	// Both edges of node 1 have the same value, and share the index entry.
+	setProperty(2, "2020", Set, 1)
+	setProperty(3, "2020", Set, 3)
+	l, err := GetNoStore(x.DataKey(attr, 1), 5)
+	require.NoError(t, err)
+	vals, err := l.AllValues(5)
+	require.NoError(t, err)
+	require.Len(t, vals, 2)
+	require.Equal(t, []uint64{1}, indexUids(5))
+
+	// Changing the value of one edge keeps the entry for the other one.
+	setProperty(2, "2021", Set, 5)
+	require.Equal(t, []uint64{1}, indexUids(7))
+
+	// Once no edge has the value, the node is removed from the entry.
+	setProperty(3, "", Del, 7)
+	require.Empty(t, indexUids(9))
+	l, err = GetNoStore(x.DataKey(attr, 1), 9)
+	require.NoError(t, err)
+	vals, err = l.AllValues(9)
+	require.NoError(t, err)
+	require.Equal(t, []types.Val{{Tid: types.StringID, Value: []byte("2021")}}, vals)
+}
+
+func TestRebuildEdgeProperty(t *testing.T) {
+	require.NoError(t, schema.ParseBytes([]byte(`
+		follows: [uid] .
+		<follows|since>: int @index(int) .`), 1))
+	attr := x.EdgeProperty("follows", "since")
+
+	addFollow := func(src, dst uint64, since string, ts uint64) {
+		edge := &pb.DirectedEdge{Attr: "follows", Entity: src, ValueId: dst}
+		if since != "" {
+			f, err := facets.FacetFor("since", since)
+			require.NoError(t, err)
+			edge.Facets = []*api.Facet{f}
+		}
+		l, err := GetNoStore(x.DataKey("follows", src), ts)
+		require.NoError(t, err)
+		addMutation(t, l, edge, Set, ts, ts+1, false)
+	}
+	addFollow(1, 2, "2018", 1)
+	addFollow(1, 3, "2020", 3)
+	addFollow(1, 4, "", 5)
+	addFollow(2, 3, "2018", 7)
+
+	currentSchema, _ := schema.State().Get(context.Background(), attr)
+	rb := IndexRebuild{
+		Attr:          attr,
+		StartTs:       9,
+		OldSchema:     &pb.SchemaUpdate{},
+		CurrentSchema: &currentSchema,
+	}
+	require.True(t, rb.needsEdgePropertyRebuild())
+	require.NoError(t, rb.BuildData(context.Background()))
+	require.NoError(t, rebuildTokIndex(context.Background(), &rb))
+
+	// The values are kept under the source of each edge, one per edge with the facet.
+	l, err := GetNoStore(x.DataKey(attr, 1), 10)
+	require.NoError(t, err)
+	var dsts []uint64
+	var vals []int64
+	require.NoError(t, l.Iterate(10, 0, func(p *pb.Posting) error {
+		val, err := types.Convert(types.Val{Tid: types.TypeID(p.ValType), Value: p.Value},
+			types.IntID)
+		require.NoError(t, err)
+		dsts = append(dsts, p.Uid)
+		vals = append(vals, val.Value.(int64))
+		return nil
+	}))
+	require.Equal(t, []uint64{2, 3}, dsts)
+	require.Equal(t, []int64{2018, 2020}, vals)
+
+	tokens, err := indexTokensForTest(attr, "", types.Val{Tid: types.StringID,
+		Value: []byte("2018")})
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+	l, err = GetNoStore(x.IndexKey(attr, tokens[0]), 10)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1, 2}, uids(l, 10))
+
+	// Declaring the property again with the same type doesn't build it again.
+	rb.OldSchema = &currentSchema
+	require.False(t, rb.needsEdgePropertyRebuild())
+}
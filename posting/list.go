@@ -283,6 +283,7 @@ type ListOptions struct {
 	ReadTs    uint64
 	AfterUid  uint64   // Any UIDs returned must be after this value.
 	Intersect *pb.List // Intersect results with this list of UIDs.
+	First     int      // Return at most this many UIDs, if greater than zero.
 }
 
 // NewPosting takes the given edge and returns its equivalent representation as a posting.
@@ -316,6 +317,7 @@ func NewPosting(t *pb.DirectedEdge) *pb.Posting {
 		Label:       t.Label,
 		Op:          op,
 		Facets:      t.Facets,
+		BlobSize:    t.BlobSize,
 	}
 	return p
 }
@@ -412,7 +414,7 @@ func (l *List) updateMutationLayer(mpost *pb.Posting, singleUidUpdate bool) erro
 
 // TypeID returns the typeid of destination vertex
 func TypeID(edge *pb.DirectedEdge) types.TypeID {
-	if edge.ValueId != 0 {
+	if edge.ValueId != 0 && !x.IsEdgeProperty(edge.Attr) {
 		return types.UidID
 	}
 	return types.TypeID(edge.ValueType)
@@ -429,6 +431,9 @@ func fingerprintEdge(t *pb.DirectedEdge) uint64 {
 
 	// Value with a lang type.
 	switch {
+	case x.IsEdgeProperty(t.Attr) && t.ValueId != 0:
+		// The value of an edge property is identified by the destination of its edge.
+		id = t.ValueId
 	case len(t.Lang) > 0:
 		id = farm.Fingerprint64([]byte(t.Lang))
 	case schema.State().IsList(t.Attr):
@@ -458,7 +463,7 @@ func GetConflictKey(pk x.ParsedKey, key []byte, t *pb.DirectedEdge) uint64 {
 	switch {
 	case schema.State().HasNoConflict(t.Attr):
 		break
-	case schema.State().HasUpsert(t.Attr):
+	case schema.State().HasUpsert(t.Attr), schema.State().HasUnique(t.Attr):
 		// Consider checking to see if a email id is unique. A user adds:
 		// <uid> <email> "email@email.org", and there's a string equal tokenizer
 		// and upsert directive on the schema.
@@ -507,19 +512,27 @@ func (l *List) addMutationInternal(ctx context.Context, txn *Txn, t *pb.Directed
 		return zero.ErrConflict
 	}
 
+	pk, err := x.Parse(l.key)
+	if err != nil {
+		return errors.Wrapf(err, "cannot parse key when adding mutation to list with key %s",
+			hex.EncodeToString(l.key))
+	}
+
 	mpost := NewPosting(t)
 	mpost.StartTs = txn.StartTs
+	if isBlob(mpost) {
+		txn.cache.addBlob(l.key)
+	}
+	if pk.IsData() && x.IsEdgeProperty(t.Attr) {
+		// The value of an edge property is kept under the uid of the destination of the edge.
+		mpost.PostingType = pb.Posting_VALUE
+	}
 	if mpost.PostingType != pb.Posting_REF {
 		t.ValueId = fingerprintEdge(t)
 		mpost.Uid = t.ValueId
 	}
 
 	// Check whether this mutation is an update for a predicate of type uid.
-	pk, err := x.Parse(l.key)
-	if err != nil {
-		return errors.Wrapf(err, "cannot parse key when adding mutation to list with key %s",
-			hex.EncodeToString(l.key))
-	}
 	pred, ok := schema.State().Get(ctx, t.Attr)
 	isSingleUidUpdate := ok && !pred.GetList() && pred.GetValueType() == pb.Posting_UID &&
 		pk.IsData() && mpost.Op == Set && mpost.PostingType == pb.Posting_REF
@@ -582,7 +595,13 @@ func (l *List) setMutation(startTs uint64, data []byte) {
 func (l *List) Iterate(readTs uint64, afterUid uint64, f func(obj *pb.Posting) error) error {
 	l.RLock()
 	defer l.RUnlock()
-	return l.iterate(readTs, afterUid, f)
+	return l.iterate(readTs, afterUid, func(p *pb.Posting) error {
+		p, err := l.resolvePosting(p, readTs)
+		if err != nil {
+			return err
+		}
+		return f(p)
+	})
 }
 
 // pickPostings goes through the mutable layer and returns the appropriate postings,
@@ -853,6 +872,17 @@ func (l *List) Rollup(alloc *z.Allocator) ([]*bpb.KV, error) {
 	})
 
 	x.VerifyPostingSplits(kvs, out.plist, out.parts, l.key)
+
+	// The complete lists of the predicates with @compression are compressed as they're written.
+	pk, err := x.Parse(l.key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "while parsing key %s", hex.EncodeToString(l.key))
+	}
+	for _, kv := range kvs {
+		if kv.UserMeta[0] == BitCompletePosting {
+			kv.Value = compressPostingList(pk.Attr, kv.Value, alloc)
+		}
+	}
 	return kvs, nil
 }
 
@@ -1114,53 +1144,137 @@ func (l *List) ApproxLen() int {
 
 // Uids returns the UIDs given some query params.
 // We have to apply the filtering before applying (offset, count).
+// The parts of a multi-part list are read one at a time, and only as far as the intersection
+// and opt.First need them, so the UIDs of a large list are never all read to return a few.
 // WARNING: Calling this function just to get UIDs is expensive
 func (l *List) Uids(opt ListOptions) (*pb.List, error) {
-	// Pre-assign length to make it faster.
 	l.RLock()
-	// Use approximate length for initial capacity.
-	res := make([]uint64, 0, len(l.mutationMap)+codec.ApproxLen(l.plist.Pack))
+	defer l.RUnlock()
 	out := &pb.List{}
-	if len(l.mutationMap) == 0 && opt.Intersect != nil && len(l.plist.Splits) == 0 {
+	if len(l.mutationMap) == 0 && opt.Intersect != nil {
 		if opt.ReadTs < l.minTs {
-			l.RUnlock()
 			return out, ErrTsTooOld
 		}
+		if len(l.plist.Splits) > 0 {
+			err := l.intersectParts(opt, out)
+			return out, errors.Wrapf(err, "cannot intersect UIDs of list with key %s",
+				hex.EncodeToString(l.key))
+		}
 		algo.IntersectCompressedWith(l.plist.Pack, opt.AfterUid, opt.Intersect, out)
-		l.RUnlock()
+		if opt.First > 0 && len(out.Uids) > opt.First {
+			out.Uids = out.Uids[:opt.First]
+		}
 		return out, nil
 	}
 
-	err := l.iterate(opt.ReadTs, opt.AfterUid, func(p *pb.Posting) error {
-		if p.PostingType == pb.Posting_REF {
-			res = append(res, p.Uid)
+	// Use approximate length for initial capacity, unless fewer UIDs can be returned.
+	size := len(l.mutationMap) + codec.ApproxLen(l.plist.Pack)
+	if opt.Intersect != nil && len(opt.Intersect.Uids) < size {
+		size = len(opt.Intersect.Uids)
+	}
+	if opt.First > 0 && opt.First < size {
+		size = opt.First
+	}
+	res := make([]uint64, 0, size)
+
+	// The intersection is merged with the postings as they're iterated, starting at the first
+	// UID to intersect with, and stops once there's nothing left to intersect with.
+	afterUid := opt.AfterUid
+	var intersect []uint64
+	if opt.Intersect != nil {
+		intersect = opt.Intersect.Uids
+		if len(intersect) == 0 {
+			return out, nil
+		}
+		if intersect[0] > afterUid+1 {
+			afterUid = intersect[0] - 1
+		}
+	}
+	err := l.iterate(opt.ReadTs, afterUid, func(p *pb.Posting) error {
+		if p.PostingType != pb.Posting_REF {
+			return nil
+		}
+		if opt.Intersect != nil {
+			for len(intersect) > 0 && intersect[0] < p.Uid {
+				intersect = intersect[1:]
+			}
+			if len(intersect) == 0 {
+				return ErrStopIteration
+			}
+			if intersect[0] != p.Uid {
+				return nil
+			}
+		}
+		res = append(res, p.Uid)
+		if opt.First > 0 && len(res) >= opt.First {
+			return ErrStopIteration
 		}
 		return nil
 	})
-	l.RUnlock()
 	if err != nil {
 		return out, errors.Wrapf(err, "cannot retrieve UIDs from list with key %s",
 			hex.EncodeToString(l.key))
 	}
-
-	// Do The intersection here as it's optimized.
 	out.Uids = res
-	if opt.Intersect != nil {
-		algo.IntersectWith(out, opt.Intersect, out)
-	}
 	return out, nil
 }
 
+// intersectParts intersects the immutable layer of a multi-part list with opt.Intersect one part
+// at a time. The parts that hold none of the UIDs to intersect with aren't read.
+func (l *List) intersectParts(opt ListOptions, out *pb.List) error {
+	l.AssertRLock()
+	uids := opt.Intersect.Uids
+	splits := l.plist.Splits
+	for i, startUid := range splits {
+		// Each part holds the UIDs from its start UID up to the start UID of the next one.
+		endUid := uint64(math.MaxUint64)
+		if i+1 < len(splits) {
+			endUid = splits[i+1] - 1
+		}
+		if endUid <= opt.AfterUid {
+			continue
+		}
+		from := sort.Search(len(uids), func(j int) bool {
+			return uids[j] >= startUid && uids[j] > opt.AfterUid
+		})
+		if from == len(uids) {
+			break
+		}
+		to := sort.Search(len(uids), func(j int) bool { return uids[j] > endUid })
+		if from >= to {
+			continue
+		}
+
+		part, err := l.readListPart(startUid)
+		if err != nil {
+			return err
+		}
+		var res pb.List
+		algo.IntersectCompressedWith(part.Pack, opt.AfterUid, &pb.List{Uids: uids[from:to]}, &res)
+		out.Uids = append(out.Uids, res.Uids...)
+		if opt.First > 0 && len(out.Uids) >= opt.First {
+			out.Uids = out.Uids[:opt.First]
+			break
+		}
+	}
+	return nil
+}
+
 // Postings calls postFn with the postings that are common with
 // UIDs in the opt ListOptions.
 func (l *List) Postings(opt ListOptions, postFn func(*pb.Posting) error) error {
 	l.RLock()
 	defer l.RUnlock()
 
+	var count int
 	err := l.iterate(opt.ReadTs, opt.AfterUid, func(p *pb.Posting) error {
 		if p.PostingType != pb.Posting_REF {
 			return nil
 		}
+		if opt.First > 0 && count >= opt.First {
+			return ErrStopIteration
+		}
+		count++
 		return postFn(p)
 	})
 	return errors.Wrapf(err, "cannot retrieve postings from list with key %s",
@@ -1175,6 +1289,10 @@ func (l *List) AllUntaggedValues(readTs uint64) ([]types.Val, error) {
 	var vals []types.Val
 	err := l.iterate(readTs, 0, func(p *pb.Posting) error {
 		if len(p.LangTag) == 0 {
+			p, err := l.resolvePosting(p, readTs)
+			if err != nil {
+				return err
+			}
 			vals = append(vals, types.Val{
 				Tid:   types.TypeID(p.ValType),
 				Value: p.Value,
@@ -1209,6 +1327,10 @@ func (l *List) AllValues(readTs uint64) ([]types.Val, error) {
 
 	var vals []types.Val
 	err := l.iterate(readTs, 0, func(p *pb.Posting) error {
+		p, err := l.resolvePosting(p, readTs)
+		if err != nil {
+			return err
+		}
 		vals = append(vals, types.Val{
 			Tid:   types.TypeID(p.ValType),
 			Value: p.Value,
@@ -1265,6 +1387,9 @@ func (l *List) ValueFor(readTs uint64, langs []string) (rval types.Val, rerr err
 		return rval, errors.Wrapf(err, "cannot retrieve value with langs %v from list with key %s",
 			langs, hex.EncodeToString(l.key))
 	}
+	if p, err = l.resolvePosting(p, readTs); err != nil {
+		return rval, err
+	}
 	return valueToTypesVal(p), nil
 }
 
@@ -1272,7 +1397,11 @@ func (l *List) ValueFor(readTs uint64, langs []string) (rval types.Val, rerr err
 func (l *List) PostingFor(readTs uint64, langs []string) (p *pb.Posting, rerr error) {
 	l.RLock()
 	defer l.RUnlock()
-	return l.postingFor(readTs, langs)
+	p, err := l.postingFor(readTs, langs)
+	if err != nil {
+		return nil, err
+	}
+	return l.resolvePosting(p, readTs)
 }
 
 func (l *List) postingFor(readTs uint64, langs []string) (p *pb.Posting, rerr error) {
@@ -1288,6 +1417,9 @@ func (l *List) ValueForTag(readTs uint64, tag string) (rval types.Val, rerr erro
 	if err != nil {
 		return rval, err
 	}
+	if p, err = l.resolvePosting(p, readTs); err != nil {
+		return rval, err
+	}
 	return valueToTypesVal(p), nil
 }
 
@@ -1374,7 +1506,9 @@ func (l *List) findValue(readTs, uid uint64) (rval types.Val, found bool, err er
 	if !found {
 		return rval, found, err
 	}
-
+	if p, err = l.resolvePosting(p, readTs); err != nil {
+		return rval, false, err
+	}
 	return valueToTypesVal(p), true, nil
 }
 
@@ -1030,6 +1030,44 @@ func TestMultiPartListBasic(t *testing.T) {
 	}
 }
 
+// Verify that the intersections and the pages of multi-part lists are read part by part.
+func TestMultiPartListIntersectAndFirst(t *testing.T) {
+	size := int(1e5)
+	ol, _ := createMultiPartList(t, size, false)
+	readTs := uint64(size) + 1
+	intersect := &pb.List{Uids: []uint64{5, 20000, 50001, 99999, 200000}}
+
+	check := func(opt ListOptions, expected []uint64) {
+		opt.ReadTs = readTs
+		l, err := ol.Uids(opt)
+		require.NoError(t, err)
+		require.Equal(t, expected, l.Uids)
+	}
+	check(ListOptions{Intersect: intersect}, []uint64{5, 20000, 50001, 99999})
+	check(ListOptions{Intersect: intersect, AfterUid: 20000}, []uint64{50001, 99999})
+	check(ListOptions{Intersect: intersect, First: 2}, []uint64{5, 20000})
+	check(ListOptions{Intersect: &pb.List{}}, nil)
+	check(ListOptions{First: 3}, []uint64{1, 2, 3})
+	check(ListOptions{First: 2, AfterUid: 70000}, []uint64{70001, 70002})
+
+	// The mutable layer is merged with the parts as they're iterated.
+	txn := Txn{StartTs: readTs}
+	addMutationHelper(t, ol, &pb.DirectedEdge{ValueId: 200000}, Set, &txn)
+	require.NoError(t, ol.commitMutation(readTs, readTs+1))
+	readTs += 2
+	check(ListOptions{Intersect: intersect}, []uint64{5, 20000, 50001, 99999, 200000})
+	check(ListOptions{Intersect: intersect, AfterUid: 50001, First: 1}, []uint64{99999})
+	check(ListOptions{First: 2, AfterUid: 99999}, []uint64{100000, 200000})
+
+	var visited []uint64
+	require.NoError(t, ol.Postings(ListOptions{ReadTs: readTs, First: 2, AfterUid: 10},
+		func(p *pb.Posting) error {
+			visited = append(visited, p.Uid)
+			return nil
+		}))
+	require.Equal(t, []uint64{11, 12}, visited)
+}
+
 // Verify that iteration works with an afterUid value greater than zero.
 func TestMultiPartListIterAfterUid(t *testing.T) {
 	size := int(1e5)
@@ -66,6 +66,7 @@ func Init(ps *badger.DB, cacheSize int64) {
 			}
 			return int64(l.DeepSize())
 		},
+		OnEvict: onCacheEvict,
 	})
 	x.Check(err)
 	go func() {
@@ -75,10 +76,19 @@ func Init(ps *badger.DB, cacheSize int64) {
 		for range ticker.C {
 			// Record the posting list cache hit ratio
 			ostats.Record(context.Background(), x.PLCacheHitRatio.M(m.Ratio()))
+			recordCacheStats()
 		}
 	}()
 }
 
+// MaxCost returns the max cost of the posting list cache, and false if the cache is disabled.
+func MaxCost() (int64, bool) {
+	if lCache == nil {
+		return 0, false
+	}
+	return lCache.MaxCost(), true
+}
+
 func UpdateMaxCost(maxCost int64) {
 	lCache.UpdateMaxCost(maxCost)
 }
@@ -112,6 +122,10 @@ type LocalCache struct {
 
 	// plists are posting lists in memory. They can be discarded to reclaim space.
 	plists map[string]*List
+
+	// blobs keeps track of the keys of the posting lists to which txn added values already
+	// stored as blob chunks.
+	blobs map[string]struct{}
 }
 
 // NewLocalCache returns a new LocalCache instance.
@@ -219,6 +233,46 @@ func (lc *LocalCache) UpdateDeltasAndDiscardLists() {
 	lc.plists = make(map[string]*List)
 }
 
+func (lc *LocalCache) addBlob(key []byte) {
+	lc.Lock()
+	defer lc.Unlock()
+	if lc.blobs == nil {
+		lc.blobs = make(map[string]struct{})
+	}
+	lc.blobs[string(key)] = struct{}{}
+}
+
+// copyDeltas returns a copy of the deltas and the max versions held by the cache.
+func (lc *LocalCache) copyDeltas() (map[string][]byte, map[string]uint64) {
+	lc.RLock()
+	defer lc.RUnlock()
+	return copyDeltas(lc.deltas, lc.maxVersions)
+}
+
+// resetDeltas replaces the deltas and the max versions held by the cache, discarding the
+// posting lists in memory built on top of the previous deltas.
+func (lc *LocalCache) resetDeltas(deltas map[string][]byte, maxVersions map[string]uint64) {
+	lc.Lock()
+	defer lc.Unlock()
+	lc.deltas = deltas
+	lc.maxVersions = maxVersions
+	lc.plists = make(map[string]*List)
+}
+
+func copyDeltas(deltas map[string][]byte,
+	maxVersions map[string]uint64) (map[string][]byte, map[string]uint64) {
+	// The deltas are never modified in place, they're only replaced. So, the values can be shared.
+	d := make(map[string][]byte, len(deltas))
+	for key, data := range deltas {
+		d[key] = data
+	}
+	mv := make(map[string]uint64, len(maxVersions))
+	for key, version := range maxVersions {
+		mv[key] = version
+	}
+	return d, mv
+}
+
 func (lc *LocalCache) fillPreds(ctx *api.TxnContext, gid uint32) {
 	lc.RLock()
 	defer lc.RUnlock()
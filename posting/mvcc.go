@@ -19,7 +19,6 @@ package posting
 import (
 	"bytes"
 	"encoding/hex"
-	"math"
 	"strconv"
 	"sync"
 	"sync/atomic"
@@ -65,12 +64,7 @@ var (
 
 // rollUpKey takes the given key's posting lists, rolls it up and writes back to badger
 func (ir *incrRollupi) rollUpKey(writer *TxnWriter, key []byte) error {
-	l, err := GetNoStore(key, math.MaxUint64)
-	if err != nil {
-		return err
-	}
-
-	kvs, err := l.Rollup(nil)
+	_, kvs, err := rollupWithBlobs(key)
 	if err != nil {
 		return err
 	}
@@ -221,6 +215,42 @@ func (txn *Txn) CommitToDisk(writer *TxnWriter, commitTs uint64) error {
 		keys = append(keys, key)
 	}
 
+	// Write the chunks of large values first, one chunk per transaction, so that the deltas
+	// referring to them are written only after their content. The chunks of the values they
+	// overwrite are deleted along the way.
+	blobDeltas := make(map[string][]byte)
+	for _, key := range keys {
+		if ts := cache.maxVersions[key]; ts >= commitTs {
+			continue
+		}
+		data := cache.deltas[key]
+		if _, ok := cache.blobs[key]; !ok && len(data) <= blobThreshold {
+			// A delta this small can't hold a value above the threshold.
+			continue
+		}
+		data, chunks, err := chunkBlobs([]byte(key), data, txn.StartTs)
+		if err != nil {
+			return err
+		}
+		for ckey, chunk := range chunks {
+			if err := writer.SetAt([]byte(ckey), chunk, BitCompletePosting, commitTs); err != nil {
+				return err
+			}
+		}
+		stale, err := overwrittenBlobs([]byte(key), data, commitTs)
+		if err != nil {
+			return err
+		}
+		for _, kv := range stale {
+			if err := writer.SetAt(kv.Key, nil, BitEmptyPosting, kv.Version); err != nil {
+				return err
+			}
+		}
+		if len(chunks) > 0 {
+			blobDeltas[key] = data
+		}
+	}
+
 	var idx int
 	for idx < len(keys) {
 		// writer.update can return early from the loop in case we encounter badger.ErrTxnTooBig. On
@@ -239,7 +269,14 @@ func (txn *Txn) CommitToDisk(writer *TxnWriter, commitTs uint64) error {
 					// not output anything here.
 					continue
 				}
-				err := btxn.SetEntry(&badger.Entry{
+				if blob, ok := blobDeltas[key]; ok {
+					data = blob
+				}
+				data, err := encodeValues([]byte(key), data)
+				if err != nil {
+					return err
+				}
+				err = btxn.SetEntry(&badger.Entry{
 					Key:      []byte(key),
 					Value:    data,
 					UserMeta: BitDeltaPosting,
@@ -259,13 +296,13 @@ func (txn *Txn) CommitToDisk(writer *TxnWriter, commitTs uint64) error {
 
 // ResetCache will clear all the cached list.
 func ResetCache() {
-	lCache.Clear()
+	cacheClear()
 }
 
 // RemoveCacheFor will delete the list corresponding to the given key.
 func RemoveCacheFor(key []byte) {
 	// TODO: investigate if this can be done by calling Set with a nil value.
-	lCache.Del(key)
+	cacheDel(key)
 }
 
 // RemoveCachedKeys will delete the cached list by this txn.
@@ -274,7 +311,7 @@ func (txn *Txn) RemoveCachedKeys() {
 		return
 	}
 	for key := range txn.cache.deltas {
-		lCache.Del(key)
+		cacheDel([]byte(key))
 	}
 }
 
@@ -294,6 +331,11 @@ func unmarshalOrCopy(plist *pb.PostingList, item *badger.Item) error {
 			// empty pl
 			return nil
 		}
+		val, err := DecompressPostingList(item.Key(), val)
+		if err != nil {
+			return errors.Wrapf(err, "while decompressing posting list of key %s",
+				hex.Dump(item.Key()))
+		}
 		return plist.Unmarshal(val)
 	})
 }
@@ -395,25 +437,22 @@ func ReadPostingList(key []byte, it *badger.Iterator) (*List, error) {
 }
 
 func getNew(key []byte, pstore *badger.DB, readTs uint64) (*List, error) {
-	cachedVal, ok := lCache.Get(key)
-	if ok {
-		l, ok := cachedVal.(*List)
-		if ok && l != nil {
-			// No need to clone the immutable layer or the key since mutations will not modify it.
-			lCopy := &List{
-				minTs: l.minTs,
-				maxTs: l.maxTs,
-				key:   key,
-				plist: l.plist,
-			}
-			if l.mutationMap != nil {
-				lCopy.mutationMap = make(map[uint64]*pb.PostingList, len(l.mutationMap))
-				for ts, pl := range l.mutationMap {
-					lCopy.mutationMap[ts] = proto.Clone(pl).(*pb.PostingList)
-				}
+	// A list rolled up after readTs can't be read at readTs, so read it from disk instead.
+	if l, ok := cacheGet(key); ok && l.minTs <= readTs {
+		// No need to clone the immutable layer or the key since mutations will not modify it.
+		lCopy := &List{
+			minTs: l.minTs,
+			maxTs: l.maxTs,
+			key:   key,
+			plist: l.plist,
+		}
+		if l.mutationMap != nil {
+			lCopy.mutationMap = make(map[uint64]*pb.PostingList, len(l.mutationMap))
+			for ts, pl := range l.mutationMap {
+				lCopy.mutationMap[ts] = proto.Clone(pl).(*pb.PostingList)
 			}
-			return lCopy, nil
 		}
+		return lCopy, nil
 	}
 
 	if pstore.IsClosed() {
@@ -434,6 +473,9 @@ func getNew(key []byte, pstore *badger.DB, readTs uint64) (*List, error) {
 	if err != nil {
 		return l, err
 	}
-	lCache.Set(key, l, 0)
+	// A list read at a past timestamp might miss the commits made since, so it's not cached.
+	if readTs >= Oracle().MaxAssigned() {
+		cacheSet(key, l)
+	}
 	return l, nil
 }
@@ -97,3 +97,49 @@ func TestPostingListRead(t *testing.T) {
 	addEdgeToUID(t, "emptypl", 1, 7, 15, 16)
 	assertLength(17, 3)
 }
+
+func TestTxnSavepoint(t *testing.T) {
+	key := x.DataKey("savepoint", 1)
+	txn := Oracle().RegisterStartTs(13)
+	addUid := func(uid uint64) {
+		l, err := txn.Get(key)
+		require.NoError(t, err)
+		addMutationHelper(t, l, &pb.DirectedEdge{ValueId: uid, Attr: "savepoint", Entity: 1}, Set,
+			txn)
+		txn.Update()
+	}
+	readUids := func() []uint64 {
+		l, err := txn.Get(key)
+		require.NoError(t, err)
+		uids, err := l.Uids(ListOptions{ReadTs: txn.StartTs})
+		require.NoError(t, err)
+		return uids.Uids
+	}
+
+	addUid(2)
+	txn.Savepoint("first")
+	addUid(3)
+	txn.Savepoint("second")
+	addUid(4)
+	require.Equal(t, []uint64{2, 3, 4}, readUids())
+
+	require.NoError(t, txn.RollbackTo("second"))
+	require.Equal(t, []uint64{2, 3}, readUids())
+	require.NoError(t, txn.RollbackTo("first"))
+	require.Equal(t, []uint64{2}, readUids())
+
+	// The savepoints created after the one rolled back to are removed.
+	require.Error(t, txn.RollbackTo("second"))
+
+	addUid(5)
+	txn.Update()
+	writer := NewTxnWriter(pstore)
+	require.NoError(t, txn.CommitToDisk(writer, 14))
+	require.NoError(t, writer.Flush())
+
+	l, err := GetNoStore(key, 15)
+	require.NoError(t, err)
+	uids, err := l.Uids(ListOptions{ReadTs: 15})
+	require.NoError(t, err)
+	require.Equal(t, []uint64{2, 5}, uids.Uids)
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/dgraph-io/dgraph/protos/pb"
 	"github.com/dgraph-io/dgraph/x"
 	"github.com/golang/glog"
+	"github.com/pkg/errors"
 	ostats "go.opencensus.io/stats"
 )
 
@@ -61,6 +62,19 @@ type Txn struct {
 	lastUpdate time.Time
 
 	cache *LocalCache // This pointer does not get modified.
+
+	// savepoints keeps the pending changes recorded by each savepoint, in creation order.
+	savepoints []*txnSavepoint
+}
+
+// txnSavepoint holds the pending changes of a transaction as of the time the savepoint was
+// created.
+type txnSavepoint struct {
+	name      string
+	deltas    map[string][]byte
+	conflicts map[uint64]struct{}
+	// maxVersions of the read posting lists.
+	maxVersions map[string]uint64
 }
 
 // NewTxn returns a new Txn instance.
@@ -87,6 +101,60 @@ func (txn *Txn) Update() {
 	txn.cache.UpdateDeltasAndDiscardLists()
 }
 
+// Savepoint records the pending changes of the transaction under the given name. A savepoint
+// created earlier with the same name is replaced.
+func (txn *Txn) Savepoint(name string) {
+	// Move the changes of the previous mutations to the deltas, so they're included.
+	txn.Update()
+
+	txn.Lock()
+	defer txn.Unlock()
+	sp := &txnSavepoint{
+		name:      name,
+		conflicts: make(map[uint64]struct{}, len(txn.conflicts)),
+	}
+	for key := range txn.conflicts {
+		sp.conflicts[key] = struct{}{}
+	}
+	sp.deltas, sp.maxVersions = txn.cache.copyDeltas()
+
+	for i, prev := range txn.savepoints {
+		if prev.name == name {
+			txn.savepoints = append(txn.savepoints[:i], txn.savepoints[i+1:]...)
+			break
+		}
+	}
+	txn.savepoints = append(txn.savepoints, sp)
+}
+
+// RollbackTo reverts the pending changes of the transaction to the ones recorded by the
+// savepoint with the given name. The savepoint is kept, so the transaction can be rolled back to
+// it again, but the savepoints created after it are removed.
+func (txn *Txn) RollbackTo(name string) error {
+	txn.Lock()
+	defer txn.Unlock()
+	idx := -1
+	for i, sp := range txn.savepoints {
+		if sp.name == name {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		return errors.Errorf("savepoint %q doesn't exist in transaction with startTs: %d",
+			name, txn.StartTs)
+	}
+	sp := txn.savepoints[idx]
+	txn.savepoints = txn.savepoints[:idx+1]
+
+	txn.conflicts = make(map[uint64]struct{}, len(sp.conflicts))
+	for key := range sp.conflicts {
+		txn.conflicts[key] = struct{}{}
+	}
+	deltas, maxVersions := copyDeltas(sp.deltas, sp.maxVersions)
+	txn.cache.resetDeltas(deltas, maxVersions)
+	return nil
+}
+
 // Store is used by tests.
 func (txn *Txn) Store(pl *List) *List {
 	return txn.cache.SetIfAbsent(string(pl.key), pl)
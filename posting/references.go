@@ -0,0 +1,53 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package posting
+
+import (
+	"encoding/binary"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgryski/go-farm"
+)
+
+// referencesConflictPrefix prefixes the uid of a node in the conflict key shared by the
+// transactions deleting the node and the ones pointing to it with @references predicates.
+const referencesConflictPrefix = "references-"
+
+// AddReferenceConflicts adds the conflict keys that make the transaction conflict with the
+// concurrent ones deleting the nodes its edges point to with predicates that have @references,
+// or pointing to the nodes its edges delete the types of. The existence of the nodes is checked
+// as of the start of the transactions, so otherwise a transaction could point to a node deleted
+// by another one committed in the meantime.
+func (txn *Txn) AddReferenceConflicts(edges []*pb.DirectedEdge) {
+	for _, edge := range edges {
+		switch {
+		case edge.Op == pb.DirectedEdge_SET && edge.ValueId != 0 &&
+			schema.State().References(edge.Attr) != "":
+			txn.addConflictKey(referencesConflictKey(edge.ValueId))
+		case edge.Op == pb.DirectedEdge_DEL && edge.Attr == "dgraph.type" && edge.Entity != 0:
+			txn.addConflictKey(referencesConflictKey(edge.Entity))
+		}
+	}
+}
+
+func referencesConflictKey(uid uint64) uint64 {
+	key := make([]byte, len(referencesConflictPrefix)+8)
+	copy(key, referencesConflictPrefix)
+	binary.BigEndian.PutUint64(key[len(referencesConflictPrefix):], uid)
+	return farm.Fingerprint64(key)
+}
@@ -0,0 +1,57 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package posting
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+func TestAddReferenceConflicts(t *testing.T) {
+	require.NoError(t, schema.ParseBytes([]byte(`
+		owner: uid @reverse @references .
+		friend: uid .
+	`), 1))
+
+	startTs := uint64(4000)
+	keys := func(edges ...*pb.DirectedEdge) []string {
+		startTs++
+		txn := Oracle().RegisterStartTs(startTs)
+		txn.AddReferenceConflicts(edges)
+		var ctx api.TxnContext
+		txn.FillContext(&ctx, 1)
+		return ctx.Keys
+	}
+	referencing := keys(&pb.DirectedEdge{Entity: 1, Attr: "owner", ValueId: 2,
+		Op: pb.DirectedEdge_SET})
+	require.Len(t, referencing, 1)
+
+	// Deleting the types of the node conflicts with referencing it.
+	require.Equal(t, referencing, keys(&pb.DirectedEdge{Entity: 2, Attr: "dgraph.type",
+		Value: []byte(x.Star), Op: pb.DirectedEdge_DEL}))
+	// Edges of predicates without @references don't add any conflict key.
+	require.Empty(t, keys(&pb.DirectedEdge{Entity: 1, Attr: "friend", ValueId: 2,
+		Op: pb.DirectedEdge_SET}))
+	require.NotEqual(t, referencing, keys(&pb.DirectedEdge{Entity: 1, Attr: "owner",
+		ValueId: 3, Op: pb.DirectedEdge_SET}))
+}
@@ -189,14 +189,17 @@ func calculateUIDBlock(block *pb.UidBlock) uint64 {
 	var size uint64 = 1*8 + // Base consists of 1 word.
 		3*8 + // Delta array consists of 3 words.
 		1*8 + // NumUids consists of 1 word.
+		3*8 + // Bitmap array consists of 3 words.
 		0*8 + // XXX_NoUnkeyedLiteral consists of 0 word. Because, It is empty struct.
 		3*8 + // XXX_unrecognized array consists of 3 words.
-		1*8 + // XXX_sizecache consistss of 1 word.
-		1*8 // Rounding it to 10 words by adding 1.
+		1*8 // XXX_sizecache consistss of 1 word.
 
 	// Adding the size of each entry in Deltas array.
 	size += uint64(cap(block.Deltas))
 
+	// Adding the size of each entry in Bitmap array.
+	size += uint64(cap(block.Bitmap))
+
 	// Adding the size of each entry in XXX_unrecognized array.
 	size += uint64(cap(block.XXX_unrecognized))
 
@@ -96,8 +96,8 @@ func TestUidPackCalculation(t *testing.T) {
 
 func TestUidBlockCalculation(t *testing.T) {
 	block = &pb.UidBlock{}
-	// 80 is obtained from BenchmarkUidBlock
-	require.Equal(t, uint64(80), calculateUIDBlock(block))
+	// 96 is obtained from BenchmarkUidBlock
+	require.Equal(t, uint64(96), calculateUIDBlock(block))
 }
 
 func TestPostingCalculation(t *testing.T) {
@@ -0,0 +1,153 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package posting
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/tok"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// CheckUnique returns an error if a value set by the edges for a predicate with the @unique
+// directive is held by more than one node once the edges are applied. The values are looked up in
+// the index, so it must be called after all the edges of the mutation are applied. That way, a
+// value moved from one node to another in the same mutation isn't reported as a duplicate.
+// Concurrent transactions can't both pass the check, as the index keys of @unique predicates are
+// conflict keys.
+func (txn *Txn) CheckUnique(ctx context.Context, edges []*pb.DirectedEdge) error {
+	checked := make(map[string]bool)
+	for _, edge := range edges {
+		if edge.Op != pb.DirectedEdge_SET || !schema.State().HasUnique(edge.Attr) {
+			continue
+		}
+		tokenizer, ok := uniqueTokenizer(ctx, edge.Attr)
+		if !ok {
+			// The index was checked with the schema, but it could be rebuilding.
+			continue
+		}
+		val := types.Val{Tid: types.TypeID(edge.ValueType), Value: edge.Value}
+		tokens, err := indexTokens(ctx, &indexMutationInfo{
+			tokenizers: []tok.Tokenizer{tokenizer},
+			edge:       edge,
+			val:        val,
+			op:         pb.DirectedEdge_SET,
+		})
+		if err != nil {
+			return err
+		}
+		for _, token := range tokens {
+			key := x.IndexKey(edge.Attr, token)
+			if checked[string(key)] {
+				continue
+			}
+			checked[string(key)] = true
+			if err := txn.checkUniqueToken(key, edge); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkUniqueToken checks that only one node with the index token given by key holds the value of
+// the edge. Tokens like the ones of hash can be shared by different values, so the values of the
+// nodes are compared too.
+func (txn *Txn) checkUniqueToken(key []byte, edge *pb.DirectedEdge) error {
+	pl, err := txn.cache.readWithOwnDeltas(key)
+	if err != nil {
+		return err
+	}
+	uids, err := pl.Uids(ListOptions{ReadTs: txn.StartTs})
+	if err != nil {
+		return err
+	}
+	var holders []uint64
+	for _, uid := range uids.Uids {
+		data, err := txn.cache.readWithOwnDeltas(x.DataKey(edge.Attr, uid))
+		if err != nil {
+			return err
+		}
+		val, err := data.Value(txn.StartTs)
+		switch {
+		case err == ErrNoValue:
+			continue
+		case err != nil:
+			return err
+		}
+		if b, ok := val.Value.([]byte); ok && val.Tid == types.TypeID(edge.ValueType) &&
+			bytes.Equal(b, edge.Value) {
+			holders = append(holders, uid)
+		}
+	}
+	if len(holders) > 1 {
+		other := holders[0]
+		if other == edge.Entity {
+			other = holders[1]
+		}
+		return errors.Errorf("Could not set the value of predicate [%s] with the @unique "+
+			"directive for uid [%#x], as uid [%#x] already has the same value", edge.Attr,
+			edge.Entity, other)
+	}
+	return nil
+}
+
+// uniqueTokenizer returns the tokenizer used to look up the nodes with a given value of the
+// predicate, one that isn't lossy.
+func uniqueTokenizer(ctx context.Context, attr string) (tok.Tokenizer, bool) {
+	for _, tokenizer := range schema.State().Tokenizer(ctx, attr) {
+		if !tokenizer.IsLossy() {
+			return tokenizer, true
+		}
+	}
+	return nil, false
+}
+
+// readWithOwnDeltas reads the posting list for key as of the start of the transaction and
+// applies the mutations of the transaction to it. Unlike Get, it reads from disk even if the list
+// in the cache was only built from the deltas, like the index lists are.
+func (lc *LocalCache) readWithOwnDeltas(key []byte) (*List, error) {
+	pl, err := getNew(key, pstore, lc.startTs)
+	if err != nil {
+		return nil, err
+	}
+	var data []byte
+	if l := lc.getNoStore(string(key)); l != nil {
+		l.RLock()
+		if mpl, ok := l.mutationMap[lc.startTs]; ok {
+			data, err = mpl.Marshal()
+		}
+		l.RUnlock()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		lc.RLock()
+		data = lc.deltas[string(key)]
+		lc.RUnlock()
+	}
+	if len(data) > 0 {
+		pl.setMutation(lc.startTs, data)
+	}
+	return pl, nil
+}
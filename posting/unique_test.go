@@ -0,0 +1,88 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package posting
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+func uniqueEdgeForTest(uid uint64, value string, op pb.DirectedEdge_Op) *pb.DirectedEdge {
+	return &pb.DirectedEdge{
+		Value:     []byte(value),
+		ValueType: pb.Posting_STRING,
+		Attr:      "email",
+		Entity:    uid,
+		Op:        op,
+	}
+}
+
+// applyUniqueForTest applies the edges in a transaction at startTs, which is committed at
+// commitTs if the values of the edges are unique.
+func applyUniqueForTest(t *testing.T, startTs, commitTs uint64, edges ...*pb.DirectedEdge) error {
+	ctx := context.Background()
+	txn := Oracle().RegisterStartTs(startTs)
+	for _, edge := range edges {
+		l, err := txn.Get(x.DataKey(edge.Attr, edge.Entity))
+		require.NoError(t, err)
+		require.NoError(t, l.AddMutationWithIndex(ctx, edge, txn))
+	}
+	if err := txn.CheckUnique(ctx, edges); err != nil {
+		return err
+	}
+	txn.Update()
+	writer := NewTxnWriter(pstore)
+	require.NoError(t, txn.CommitToDisk(writer, commitTs))
+	require.NoError(t, writer.Flush())
+	return nil
+}
+
+func TestCheckUnique(t *testing.T) {
+	require.NoError(t, schema.ParseBytes([]byte("email: string @index(hash) @unique ."), 1))
+
+	require.NoError(t, applyUniqueForTest(t, 3001, 3002,
+		uniqueEdgeForTest(1, "a@dgraph.io", pb.DirectedEdge_SET),
+		uniqueEdgeForTest(2, "b@dgraph.io", pb.DirectedEdge_SET)))
+
+	// A value held by another node.
+	err := applyUniqueForTest(t, 3003, 0, uniqueEdgeForTest(3, "a@dgraph.io", pb.DirectedEdge_SET))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "uid [0x1] already has the same value")
+
+	// The same value given to two nodes in one transaction.
+	err = applyUniqueForTest(t, 3005, 0,
+		uniqueEdgeForTest(3, "c@dgraph.io", pb.DirectedEdge_SET),
+		uniqueEdgeForTest(4, "c@dgraph.io", pb.DirectedEdge_SET))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "already has the same value")
+
+	// Setting a node's own value again, and moving a value from one node to another.
+	require.NoError(t, applyUniqueForTest(t, 3007, 3008,
+		uniqueEdgeForTest(1, "a@dgraph.io", pb.DirectedEdge_SET)))
+	require.NoError(t, applyUniqueForTest(t, 3009, 3010,
+		uniqueEdgeForTest(3, "b@dgraph.io", pb.DirectedEdge_SET),
+		uniqueEdgeForTest(2, "b@dgraph.io", pb.DirectedEdge_DEL)))
+	err = applyUniqueForTest(t, 3011, 0, uniqueEdgeForTest(2, "b@dgraph.io", pb.DirectedEdge_SET))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "uid [0x3] already has the same value")
+}
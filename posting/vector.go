@@ -0,0 +1,415 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package posting
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"sort"
+
+	"github.com/dgryski/go-farm"
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/tok"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// Parameters of the hierarchical navigable small world graph of the hnsw index, as described in
+// https://arxiv.org/abs/1603.09320. A node is linked to the hnswM closest nodes found in every
+// level it belongs to, and keeps up to hnswM neighbors in the upper levels and 2*hnswM in the
+// first one. The ef parameters are the number of closest nodes kept while searching a level,
+// which trade speed for recall.
+const (
+	hnswM              = 16
+	hnswMaxLevel       = 16
+	hnswEfConstruction = 64
+	hnswEfSearch       = 64
+)
+
+// hnswLevel returns the highest level of the graph that the node with the given uid belongs to.
+// The levels follow the exponentially decaying distribution of the paper. They're drawn from the
+// fingerprint of the uid instead of a random number, so that every replica agrees on them and
+// they don't need to be stored.
+func hnswLevel(uid uint64) int {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uid)
+	// A uniform number in (0, 1].
+	u := (float64(farm.Fingerprint64(buf[:])>>11) + 1) / (1 << 53)
+	level := int(-math.Log(u) / math.Log(hnswM))
+	if level > hnswMaxLevel {
+		level = hnswMaxLevel
+	}
+	return level
+}
+
+func hnswMaxNeighbors(level int) int {
+	if level == 0 {
+		return 2 * hnswM
+	}
+	return hnswM
+}
+
+type hnswCandidate struct {
+	uid  uint64
+	dist float64
+}
+
+// insertCandidate inserts c into cands, which is ordered by distance.
+func insertCandidate(cands []hnswCandidate, c hnswCandidate) []hnswCandidate {
+	i := sort.Search(len(cands), func(i int) bool { return cands[i].dist > c.dist })
+	cands = append(cands, hnswCandidate{})
+	copy(cands[i+1:], cands[i:])
+	cands[i] = c
+	return cands
+}
+
+// hnswGraph reads the graph of the hnsw index of a predicate from the index posting lists. Each
+// node has a list of its neighbors in every level it belongs to, and every level has the list
+// of the nodes for which it's the highest level. If txn is set, the graph is updated by
+// the mutations of the transaction.
+type hnswGraph struct {
+	attr    string
+	readTs  uint64
+	cache   *LocalCache
+	txn     *Txn
+	metric  types.VectorMetric
+	vectors map[uint64][]float32
+}
+
+func newHNSWGraph(attr string, cache *LocalCache, readTs uint64,
+	tokenizer tok.HNSWTokenizer) *hnswGraph {
+	return &hnswGraph{
+		attr:    attr,
+		readTs:  readTs,
+		cache:   cache,
+		metric:  tokenizer.Metric(),
+		vectors: make(map[uint64][]float32),
+	}
+}
+
+// vector returns the vector of the node with the given uid, or nil if it doesn't have one. Nodes
+// can still be linked to after their vector is deleted, the searches skip them.
+func (g *hnswGraph) vector(uid uint64) ([]float32, error) {
+	if vec, ok := g.vectors[uid]; ok {
+		return vec, nil
+	}
+	pl, err := g.cache.Get(x.DataKey(g.attr, uid))
+	if err != nil {
+		return nil, err
+	}
+	var vec []float32
+	val, err := pl.Value(g.readTs)
+	switch {
+	case err == ErrNoValue:
+	case err != nil:
+		return nil, err
+	default:
+		v, err := types.Convert(val, types.VFloatID)
+		if err != nil {
+			return nil, err
+		}
+		vec = v.Value.([]float32)
+	}
+	g.vectors[uid] = vec
+	return vec, nil
+}
+
+// distance returns the distance between two vectors. The vectors of different lengths are never
+// considered close.
+func (g *hnswGraph) distance(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return math.Inf(1)
+	}
+	return g.metric(a, b)
+}
+
+func (g *hnswGraph) uids(token string) ([]uint64, error) {
+	pl, err := g.cache.Get(x.IndexKey(g.attr, token))
+	if err != nil {
+		return nil, err
+	}
+	list, err := pl.Uids(ListOptions{ReadTs: g.readTs})
+	if err != nil {
+		return nil, err
+	}
+	return list.Uids, nil
+}
+
+func (g *hnswGraph) neighbors(uid uint64, level int) ([]uint64, error) {
+	return g.uids(tok.HNSWNeighborsToken(uid, level))
+}
+
+func (g *hnswGraph) addEdge(ctx context.Context, token string, uid uint64,
+	op pb.DirectedEdge_Op) error {
+	pl, err := g.txn.cache.Get(x.IndexKey(g.attr, token))
+	if err != nil {
+		return err
+	}
+	return pl.addMutation(ctx, g.txn, &pb.DirectedEdge{ValueId: uid, Attr: g.attr, Op: op})
+}
+
+// link adds or removes the node to in the neighbors of the node from in the given level.
+func (g *hnswGraph) link(ctx context.Context, from, to uint64, level int,
+	op pb.DirectedEdge_Op) error {
+	return g.addEdge(ctx, tok.HNSWNeighborsToken(from, level), to, op)
+}
+
+// entryPoint returns a node of the highest level of the graph and that level, or a zero uid if
+// the graph is empty.
+func (g *hnswGraph) entryPoint() (uint64, int, error) {
+	for level := hnswMaxLevel; level >= 0; level-- {
+		uids, err := g.uids(tok.HNSWLevelToken(level))
+		if err != nil {
+			return 0, 0, err
+		}
+		if len(uids) > 0 {
+			return uids[0], level, nil
+		}
+	}
+	return 0, 0, nil
+}
+
+// searchLevel returns the ef closest nodes to query that it finds in the given level of the
+// graph by walking from the entry points, ordered by distance.
+func (g *hnswGraph) searchLevel(ctx context.Context, query []float32,
+	entries []hnswCandidate, ef, level int) ([]hnswCandidate, error) {
+	visited := make(map[uint64]bool)
+	var cands, results []hnswCandidate
+	for _, e := range entries {
+		visited[e.uid] = true
+		cands = insertCandidate(cands, e)
+		results = insertCandidate(results, e)
+	}
+	if len(results) > ef {
+		results = results[:ef]
+	}
+
+	for len(cands) > 0 {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		c := cands[0]
+		cands = cands[1:]
+		if len(results) >= ef && c.dist > results[len(results)-1].dist {
+			break
+		}
+		nbrs, err := g.neighbors(c.uid, level)
+		if err != nil {
+			return nil, err
+		}
+		for _, uid := range nbrs {
+			if visited[uid] {
+				continue
+			}
+			visited[uid] = true
+			vec, err := g.vector(uid)
+			if err != nil {
+				return nil, err
+			}
+			if vec == nil {
+				continue
+			}
+			n := hnswCandidate{uid: uid, dist: g.distance(query, vec)}
+			if len(results) < ef || n.dist < results[len(results)-1].dist {
+				cands = insertCandidate(cands, n)
+				results = insertCandidate(results, n)
+				if len(results) > ef {
+					results = results[:ef]
+				}
+			}
+		}
+	}
+	return results, nil
+}
+
+// search returns the ef closest nodes to query down to the given level of the graph, ordered by
+// distance. It greedily goes down the levels above, from the entry point of the graph.
+func (g *hnswGraph) search(ctx context.Context, query []float32, ef,
+	level int) ([]hnswCandidate, int, error) {
+	ep, epLevel, err := g.entryPoint()
+	if err != nil || ep == 0 {
+		return nil, 0, err
+	}
+	epVec, err := g.vector(ep)
+	if err != nil || epVec == nil {
+		return nil, 0, err
+	}
+	entries := []hnswCandidate{{uid: ep, dist: g.distance(query, epVec)}}
+	for l := epLevel; l > level; l-- {
+		if entries, err = g.searchLevel(ctx, query, entries, 1, l); err != nil {
+			return nil, 0, err
+		}
+	}
+	if epLevel < level {
+		level = epLevel
+	}
+	results, err := g.searchLevel(ctx, query, entries, ef, level)
+	return results, level, err
+}
+
+// insert adds the node with the given uid and vector to the graph. In every level the node
+// belongs to, it is linked both ways to the closest nodes.
+func (g *hnswGraph) insert(ctx context.Context, uid uint64, vec []float32) error {
+	g.vectors[uid] = vec
+	level := hnswLevel(uid)
+	cands, top, err := g.search(ctx, vec, hnswEfConstruction, level)
+	if err != nil {
+		return err
+	}
+	for l := top; len(cands) > 0 && l >= 0; l-- {
+		if l < top {
+			if cands, err = g.searchLevel(ctx, vec, cands, hnswEfConstruction, l); err != nil {
+				return err
+			}
+		}
+		var linked int
+		for _, c := range cands {
+			if linked == hnswM {
+				break
+			}
+			if c.uid == uid {
+				continue
+			}
+			if err := g.link(ctx, uid, c.uid, l, pb.DirectedEdge_SET); err != nil {
+				return err
+			}
+			if err := g.link(ctx, c.uid, uid, l, pb.DirectedEdge_SET); err != nil {
+				return err
+			}
+			if err := g.prune(ctx, c.uid, l); err != nil {
+				return err
+			}
+			linked++
+		}
+	}
+	return g.addEdge(ctx, tok.HNSWLevelToken(level), uid, pb.DirectedEdge_SET)
+}
+
+// prune unlinks the node with the given uid from its farthest neighbors in the level, if it has
+// more than the maximum number.
+func (g *hnswGraph) prune(ctx context.Context, uid uint64, level int) error {
+	nbrs, err := g.neighbors(uid, level)
+	if err != nil || len(nbrs) <= hnswMaxNeighbors(level) {
+		return err
+	}
+	vec, err := g.vector(uid)
+	if err != nil || vec == nil {
+		return err
+	}
+	var cands []hnswCandidate
+	for _, n := range nbrs {
+		nvec, err := g.vector(n)
+		if err != nil {
+			return err
+		}
+		dist := math.Inf(1)
+		if nvec != nil {
+			dist = g.distance(vec, nvec)
+		}
+		cands = insertCandidate(cands, hnswCandidate{uid: n, dist: dist})
+	}
+	for _, c := range cands[hnswMaxNeighbors(level):] {
+		if err := g.link(ctx, uid, c.uid, level, pb.DirectedEdge_DEL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// remove takes the node with the given uid out of the graph. Its neighbors are linked to each
+// other instead, so that the graph stays connected.
+func (g *hnswGraph) remove(ctx context.Context, uid uint64) error {
+	g.vectors[uid] = nil
+	level := hnswLevel(uid)
+	for l := 0; l <= level; l++ {
+		nbrs, err := g.neighbors(uid, l)
+		if err != nil {
+			return err
+		}
+		for _, n := range nbrs {
+			if err := g.link(ctx, uid, n, l, pb.DirectedEdge_DEL); err != nil {
+				return err
+			}
+			if err := g.link(ctx, n, uid, l, pb.DirectedEdge_DEL); err != nil {
+				return err
+			}
+		}
+		for _, n := range nbrs {
+			for _, other := range nbrs {
+				if other == n {
+					continue
+				}
+				if err := g.link(ctx, n, other, l, pb.DirectedEdge_SET); err != nil {
+					return err
+				}
+			}
+			if err := g.prune(ctx, n, l); err != nil {
+				return err
+			}
+		}
+	}
+	return g.addEdge(ctx, tok.HNSWLevelToken(level), uid, pb.DirectedEdge_DEL)
+}
+
+// addVectorIndexMutation updates the hnsw index of the predicate for the vector of a node.
+func (txn *Txn) addVectorIndexMutation(ctx context.Context, info *indexMutationInfo,
+	tokenizer tok.HNSWTokenizer) error {
+	g := newHNSWGraph(info.edge.Attr, txn.cache, txn.StartTs, tokenizer)
+	g.txn = txn
+	if info.op == pb.DirectedEdge_DEL {
+		return g.remove(ctx, info.edge.Entity)
+	}
+	val, err := types.Convert(info.val, types.VFloatID)
+	if err != nil {
+		return err
+	}
+	return g.insert(ctx, info.edge.Entity, val.Value.([]float32))
+}
+
+// SimilarTo returns the uids of the k nodes whose vectors of the predicate attr are the closest to
+// the query vector, found with the hnsw index of the predicate. The search is approximate, so the
+// closest nodes can be missed, although rarely. The uids are sorted.
+func SimilarTo(ctx context.Context, cache *LocalCache, attr string, readTs uint64,
+	tokenizer tok.HNSWTokenizer, query []float32, k int) ([]uint64, error) {
+	if k <= 0 {
+		return nil, errors.Errorf("The number of similar nodes must be positive, got %d", k)
+	}
+	ef := hnswEfSearch
+	if k > ef {
+		ef = k
+	}
+	g := newHNSWGraph(attr, cache, readTs, tokenizer)
+	results, _, err := g.search(ctx, query, ef, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) > k {
+		results = results[:k]
+	}
+	uids := make([]uint64, 0, len(results))
+	for _, c := range results {
+		uids = append(uids, c.uid)
+	}
+	sort.Slice(uids, func(i, j int) bool { return uids[i] < uids[j] })
+	return uids, nil
+}
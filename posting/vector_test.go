@@ -0,0 +1,130 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package posting
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/tok"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+func randomVectors(n, dim int) map[uint64][]float32 {
+	r := rand.New(rand.NewSource(1))
+	vectors := make(map[uint64][]float32)
+	for uid := uint64(1); uid <= uint64(n); uid++ {
+		vec := make([]float32, dim)
+		for i := range vec {
+			vec[i] = r.Float32()
+		}
+		vectors[uid] = vec
+	}
+	return vectors
+}
+
+// closestForTest returns the uids of the k vectors closest to query, sorted.
+func closestForTest(vectors map[uint64][]float32, query []float32, k int) []uint64 {
+	var all []uint64
+	for uid := range vectors {
+		all = append(all, uid)
+	}
+	metric, _ := types.GetVectorMetric("euclidean")
+	sort.Slice(all, func(i, j int) bool {
+		return metric(vectors[all[i]], query) < metric(vectors[all[j]], query)
+	})
+	all = all[:k]
+	sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+	return all
+}
+
+func setVectorForTest(t *testing.T, attr string, uid uint64, vec []float32, op uint32,
+	startTs, commitTs uint64) {
+	b := types.ValueForType(types.BinaryID)
+	require.NoError(t, types.Marshal(types.Val{Tid: types.VFloatID, Value: vec}, &b))
+	edge := &pb.DirectedEdge{
+		Value:     b.Value.([]byte),
+		ValueType: pb.Posting_VFLOAT,
+		Attr:      attr,
+		Entity:    uid,
+	}
+	l, err := GetNoStore(x.DataKey(attr, uid), startTs)
+	require.NoError(t, err)
+	addMutation(t, l, edge, op, startTs, commitTs, true)
+}
+
+func similarToForTest(t *testing.T, attr string, query []float32, k int, readTs uint64) []uint64 {
+	uids, err := SimilarTo(context.Background(), NewLocalCache(readTs), attr, readTs,
+		tok.HNSWTokenizer{}, query, k)
+	require.NoError(t, err)
+	return uids
+}
+
+func TestVectorIndex(t *testing.T) {
+	require.NoError(t, schema.ParseBytes([]byte("vec: float32vector @index(hnsw) ."), 1))
+
+	vectors := randomVectors(200, 8)
+	ts := uint64(100)
+	for uid := uint64(1); uid <= 200; uid++ {
+		setVectorForTest(t, "vec", uid, vectors[uid], Set, ts, ts+1)
+		ts += 2
+	}
+
+	query := []float32{0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5}
+	require.Equal(t, closestForTest(vectors, query, 5), similarToForTest(t, "vec", query, 5, ts))
+	require.Equal(t, []uint64{42}, similarToForTest(t, "vec", vectors[42], 1, ts))
+
+	// The deleted and updated vectors are taken out of the graph.
+	setVectorForTest(t, "vec", 42, vectors[42], Del, ts, ts+1)
+	delete(vectors, 42)
+	vectors[7] = []float32{0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.4}
+	setVectorForTest(t, "vec", 7, vectors[7], Set, ts+2, ts+3)
+	ts += 4
+	require.Equal(t, closestForTest(vectors, query, 5), similarToForTest(t, "vec", query, 5, ts))
+	require.NotContains(t, similarToForTest(t, "vec", vectors[41], 3, ts), uint64(42))
+}
+
+func TestRebuildVectorIndex(t *testing.T) {
+	vectors := randomVectors(50, 4)
+	ts := uint64(1000)
+	for uid := uint64(1); uid <= 50; uid++ {
+		addEdgeToValue(t, "vec2", uid, types.FormatVector(vectors[uid]), ts, ts+1)
+		ts += 2
+	}
+
+	require.NoError(t, schema.ParseBytes([]byte("vec2: float32vector @index(hnsw) ."), 1))
+	currentSchema, _ := schema.State().Get(context.Background(), "vec2")
+	rb := IndexRebuild{
+		Attr:          "vec2",
+		StartTs:       ts,
+		OldSchema:     nil,
+		CurrentSchema: &currentSchema,
+	}
+	require.NoError(t, dropTokIndexes(context.Background(), &rb))
+	require.NoError(t, rebuildTokIndex(context.Background(), &rb))
+
+	query := []float32{0.1, 0.9, 0.1, 0.9}
+	require.Equal(t, closestForTest(vectors, query, 3),
+		similarToForTest(t, "vec2", query, 3, ts+1))
+}
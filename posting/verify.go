@@ -0,0 +1,271 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package posting
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/dgraph-io/badger/v2"
+
+	"github.com/dgraph-io/dgraph/algo"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/tok"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+const (
+	// maxIntegrityProblems is the most problems described in the report of a predicate. All of
+	// them are counted regardless.
+	maxIntegrityProblems = 10
+	// maxVerifyCached is the most uids kept in the caches of the index and data lists read
+	// during a verification, after which the caches are emptied.
+	maxVerifyCached = 1 << 20
+)
+
+// PredicateIntegrity is the outcome of the verification of the lists of a predicate.
+type PredicateIntegrity struct {
+	Predicate  string `json:"predicate"`
+	DataLists  int64  `json:"dataLists"`
+	IndexLists int64  `json:"indexLists"`
+	// CorruptLists are the lists that couldn't be read or decoded.
+	CorruptLists int64 `json:"corruptLists"`
+	// MissingIndexEntries are the tokens of indexed values that don't have the uid of the
+	// value in their index list.
+	MissingIndexEntries int64 `json:"missingIndexEntries"`
+	// DanglingIndexEntries are the uids of index lists that don't have a value producing the
+	// token of the list.
+	DanglingIndexEntries int64    `json:"danglingIndexEntries"`
+	Problems             []string `json:"problems,omitempty"`
+}
+
+// Healthy tells whether no problem was found.
+func (pi *PredicateIntegrity) Healthy() bool {
+	return pi.CorruptLists == 0 && pi.MissingIndexEntries == 0 && pi.DanglingIndexEntries == 0
+}
+
+func (pi *PredicateIntegrity) problem(format string, args ...interface{}) {
+	if len(pi.Problems) < maxIntegrityProblems {
+		pi.Problems = append(pi.Problems, fmt.Sprintf(format, args...))
+	}
+}
+
+type verifier struct {
+	ctx        context.Context
+	attr       string
+	readTs     uint64
+	tokenizers []tok.Tokenizer
+	wait       func() error
+	res        *PredicateIntegrity
+
+	// index caches the uids of the index lists, by token.
+	index       map[string]*pb.List
+	indexCached int
+	// tokens caches the tokens of the values of the data lists, by uid.
+	tokens map[uint64]map[string]struct{}
+}
+
+// VerifyPredicate reads all the data and index lists of attr at readTs, and checks that every
+// token of the indexed values has the uid of the value in its index list, and that every uid of
+// the index lists has a value producing the token of the list. wait is called before each list
+// is read, so that the verification can be throttled, and it's stopped if wait returns an error.
+func VerifyPredicate(ctx context.Context, attr string, readTs uint64,
+	wait func() error) (*PredicateIntegrity, error) {
+	v := &verifier{
+		ctx:    ctx,
+		attr:   attr,
+		readTs: readTs,
+		wait:   wait,
+		res:    &PredicateIntegrity{Predicate: attr},
+	}
+	// The hnsw index has no tokens, its graph isn't verified.
+	for _, it := range schema.State().Tokenizer(ctx, attr) {
+		if _, ok := it.(tok.HNSWTokenizer); !ok {
+			v.tokenizers = append(v.tokenizers, it)
+		}
+	}
+
+	pk := x.ParsedKey{Attr: attr}
+	n, err := v.lists(pk.DataPrefix(), v.verifyData)
+	v.res.DataLists = n
+	if err != nil {
+		return v.res, err
+	}
+	if len(v.tokenizers) == 0 {
+		return v.res, nil
+	}
+	v.index = nil
+	n, err = v.lists(pk.IndexPrefix(), v.verifyIndex)
+	v.res.IndexLists = n
+	return v.res, err
+}
+
+// lists reads each list of the prefix, and passes it to fn. The lists that can't be read, or
+// for which fn fails, are counted as corrupt. It returns the number of lists read.
+func (v *verifier) lists(prefix []byte, fn func(pk x.ParsedKey, l *List) error) (int64, error) {
+	txn := pstore.NewTransactionAt(v.readTs, false)
+	defer txn.Discard()
+	iopt := badger.DefaultIteratorOptions
+	iopt.AllVersions = true
+	iopt.PrefetchValues = false
+	iopt.Prefix = prefix
+	itr := txn.NewIterator(iopt)
+	defer itr.Close()
+
+	var n int64
+	for itr.Rewind(); itr.Valid(); {
+		key := itr.Item().KeyCopy(nil)
+		pk, err := x.Parse(key)
+		switch {
+		case err != nil:
+			v.corrupt(key, err)
+		case pk.HasStartUid:
+			// The parts of a split list are read through its main key.
+		default:
+			if err := v.wait(); err != nil {
+				return n, err
+			}
+			n++
+			l, err := ReadPostingList(key, itr)
+			if err == nil {
+				err = fn(pk, l)
+			}
+			if err != nil {
+				v.corrupt(key, err)
+			}
+		}
+		// Skip the versions of the key that weren't read.
+		for itr.Valid() && bytes.Equal(itr.Item().Key(), key) {
+			itr.Next()
+		}
+	}
+	return n, nil
+}
+
+func (v *verifier) corrupt(key []byte, err error) {
+	v.res.CorruptLists++
+	v.res.problem("Corrupt list with key %s: %v", hex.EncodeToString(key), err)
+}
+
+// verifyData checks that all the tokens of the values of the data list are in the index.
+func (v *verifier) verifyData(pk x.ParsedKey, l *List) error {
+	tokens, err := v.valueTokens(pk.Uid, l)
+	if err != nil {
+		return err
+	}
+	for token := range tokens {
+		uids, err := v.indexUids(token)
+		if err != nil {
+			// The corrupt index lists are reported when the index is read.
+			continue
+		}
+		if algo.IndexOf(uids, pk.Uid) < 0 {
+			v.res.MissingIndexEntries++
+			v.res.problem("Uid %#x has no entry in the index for token %q", pk.Uid, token)
+		}
+	}
+	return nil
+}
+
+// verifyIndex checks that every uid of the index list has a value producing its token.
+func (v *verifier) verifyIndex(pk x.ParsedKey, l *List) error {
+	uids, err := l.Uids(ListOptions{ReadTs: v.readTs})
+	if err != nil {
+		return err
+	}
+	for _, uid := range uids.Uids {
+		tokens, ok := v.tokens[uid]
+		if !ok {
+			dl, err := GetNoStore(x.DataKey(v.attr, uid), v.readTs)
+			if err != nil {
+				continue
+			}
+			if tokens, err = v.valueTokens(uid, dl); err != nil {
+				// The corrupt data lists are reported when the data is read.
+				continue
+			}
+		}
+		if _, ok := tokens[pk.Term]; !ok {
+			v.res.DanglingIndexEntries++
+			v.res.problem("Uid %#x in the index for token %q has no value with the token",
+				uid, pk.Term)
+		}
+	}
+	return nil
+}
+
+// valueTokens returns the index tokens of the values of the data list of uid.
+func (v *verifier) valueTokens(uid uint64, l *List) (map[string]struct{}, error) {
+	tokens := make(map[string]struct{})
+	err := l.Iterate(v.readTs, 0, func(p *pb.Posting) error {
+		if len(v.tokenizers) == 0 {
+			return nil
+		}
+		toks, err := indexTokens(v.ctx, &indexMutationInfo{
+			tokenizers: v.tokenizers,
+			edge:       &pb.DirectedEdge{Attr: v.attr, Entity: uid, Lang: string(p.LangTag)},
+			val:        types.Val{Tid: types.TypeID(p.ValType), Value: p.Value},
+		})
+		if err != nil {
+			// The value can't be indexed, so it isn't in the index either.
+			return nil
+		}
+		for _, t := range toks {
+			tokens[t] = struct{}{}
+		}
+		return nil
+	})
+	if err != nil || len(v.tokenizers) == 0 {
+		return nil, err
+	}
+	if len(v.tokens) >= maxVerifyCached {
+		v.tokens = nil
+	}
+	if v.tokens == nil {
+		v.tokens = make(map[uint64]map[string]struct{})
+	}
+	v.tokens[uid] = tokens
+	return tokens, nil
+}
+
+// indexUids returns the uids of the index list of token.
+func (v *verifier) indexUids(token string) (*pb.List, error) {
+	if uids, ok := v.index[token]; ok {
+		return uids, nil
+	}
+	l, err := GetNoStore(x.IndexKey(v.attr, token), v.readTs)
+	if err != nil {
+		return nil, err
+	}
+	uids, err := l.Uids(ListOptions{ReadTs: v.readTs})
+	if err != nil {
+		return nil, err
+	}
+	if v.indexCached+len(uids.Uids) > maxVerifyCached {
+		v.index, v.indexCached = nil, 0
+	}
+	if v.index == nil {
+		v.index = make(map[string]*pb.List)
+	}
+	v.index[token] = uids
+	v.indexCached += len(uids.Uids)
+	return uids, nil
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package posting
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+func TestVerifyPredicate(t *testing.T) {
+	require.NoError(t, schema.ParseBytes([]byte("verify.name: string @index(exact) ."), 1))
+	noWait := func() error { return nil }
+	verify := func(readTs uint64) *PredicateIntegrity {
+		res, err := VerifyPredicate(context.Background(), "verify.name", readTs, noWait)
+		require.NoError(t, err)
+		return res
+	}
+
+	for uid, name := range map[uint64]string{1: "alice", 2: "bob"} {
+		l, err := GetNoStore(x.DataKey("verify.name", uid), 1)
+		require.NoError(t, err)
+		edge := &pb.DirectedEdge{Value: []byte(name), Attr: "verify.name", Entity: uid}
+		addMutation(t, l, edge, Set, 1, 2, true)
+	}
+	res := verify(3)
+	require.True(t, res.Healthy(), "%+v", res)
+	require.Equal(t, int64(2), res.DataLists)
+	require.Equal(t, int64(2), res.IndexLists)
+
+	// A value without its index entry.
+	addEdgeToValue(t, "verify.name", 3, "carol", 3, 4)
+	// An index entry without its value.
+	l, err := GetNoStore(x.IndexKey("verify.name", "\x02dave"), 5)
+	require.NoError(t, err)
+	addMutation(t, l, &pb.DirectedEdge{ValueId: 4, Attr: "verify.name"}, Set, 5, 6, false)
+	res = verify(7)
+	require.Equal(t, int64(1), res.MissingIndexEntries)
+	require.Equal(t, int64(1), res.DanglingIndexEntries)
+	require.Len(t, res.Problems, 2)
+
+	// A list that can't be decoded.
+	txn := ps.NewTransactionAt(8, true)
+	require.NoError(t, txn.SetEntry(badger.NewEntry(x.DataKey("verify.name", 5),
+		[]byte{0xff, 0xff, 0xff}).WithMeta(BitCompletePosting)))
+	require.NoError(t, txn.CommitAt(9, nil))
+	res = verify(10)
+	require.Equal(t, int64(1), res.CorruptLists)
+	require.Equal(t, int64(4), res.DataLists)
+	require.False(t, res.Healthy())
+
+	// The lists read before the verification is stopped are counted.
+	var n int
+	res, err = VerifyPredicate(context.Background(), "verify.name", 10, func() error {
+		if n++; n > 2 {
+			return context.Canceled
+		}
+		return nil
+	})
+	require.Equal(t, context.Canceled, err)
+	require.Equal(t, int64(2), res.DataLists)
+}
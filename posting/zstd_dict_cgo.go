@@ -0,0 +1,46 @@
+// +build cgo
+
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package posting
+
+import (
+	"bytes"
+	"io/ioutil"
+
+	"github.com/DataDog/zstd"
+)
+
+// zstdCompressDict compresses src with zstd, using the given dictionary.
+func zstdCompressDict(src, dict []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := zstd.NewWriterLevelDict(&buf, zstdLevel, dict)
+	if _, err := w.Write(src); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// zstdDecompressDict decompresses src, which was compressed with the given dictionary.
+func zstdDecompressDict(src, dict []byte) ([]byte, error) {
+	r := zstd.NewReaderDict(bytes.NewReader(src), dict)
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
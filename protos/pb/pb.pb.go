@@ -133,6 +133,8 @@ const (
 	Posting_PASSWORD Posting_ValType = 8
 	Posting_STRING   Posting_ValType = 9
 	Posting_OBJECT   Posting_ValType = 10
+	Posting_VFLOAT   Posting_ValType = 11
+	Posting_DECIMAL  Posting_ValType = 12
 )
 
 var Posting_ValType_name = map[int32]string{
@@ -147,6 +149,8 @@ var Posting_ValType_name = map[int32]string{
 	8:  "PASSWORD",
 	9:  "STRING",
 	10: "OBJECT",
+	11: "VFLOAT",
+	12: "DECIMAL",
 }
 
 var Posting_ValType_value = map[string]int32{
@@ -161,6 +165,8 @@ var Posting_ValType_value = map[string]int32{
 	"PASSWORD": 8,
 	"STRING":   9,
 	"OBJECT":   10,
+	"VFLOAT":   11,
+	"DECIMAL":  12,
 }
 
 func (x Posting_ValType) String() string {
@@ -241,6 +247,7 @@ const (
 	BackupKey_COUNT_REV BackupKey_KeyType = 5
 	BackupKey_SCHEMA    BackupKey_KeyType = 6
 	BackupKey_TYPE      BackupKey_KeyType = 7
+	BackupKey_BLOB      BackupKey_KeyType = 8
 )
 
 var BackupKey_KeyType_name = map[int32]string{
@@ -252,6 +259,7 @@ var BackupKey_KeyType_name = map[int32]string{
 	5: "COUNT_REV",
 	6: "SCHEMA",
 	7: "TYPE",
+	8: "BLOB",
 }
 
 var BackupKey_KeyType_value = map[string]int32{
@@ -263,6 +271,7 @@ var BackupKey_KeyType_value = map[string]int32{
 	"COUNT_REV": 5,
 	"SCHEMA":    6,
 	"TYPE":      7,
+	"BLOB":      8,
 }
 
 func (x BackupKey_KeyType) String() string {
@@ -454,6 +463,9 @@ type Query struct {
 	ReadTs               uint64       `protobuf:"varint,13,opt,name=read_ts,json=readTs,proto3" json:"read_ts,omitempty"`
 	Cache                int32        `protobuf:"varint,14,opt,name=cache,proto3" json:"cache,omitempty"`
 	First                int32        `protobuf:"varint,15,opt,name=first,proto3" json:"first,omitempty"`
+	DistinctCount        bool         `protobuf:"varint,16,opt,name=distinct_count,json=distinctCount,proto3" json:"distinct_count,omitempty"`
+	EdgeFilter           *FilterTree  `protobuf:"bytes,17,opt,name=edge_filter,json=edgeFilter,proto3" json:"edge_filter,omitempty"`
+	Sample               int32        `protobuf:"varint,18,opt,name=sample,proto3" json:"sample,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
 	XXX_unrecognized     []byte       `json:"-"`
 	XXX_sizecache        int32        `json:"-"`
@@ -583,6 +595,27 @@ func (m *Query) GetFirst() int32 {
 	return 0
 }
 
+func (m *Query) GetDistinctCount() bool {
+	if m != nil {
+		return m.DistinctCount
+	}
+	return false
+}
+
+func (m *Query) GetEdgeFilter() *FilterTree {
+	if m != nil {
+		return m.EdgeFilter
+	}
+	return nil
+}
+
+func (m *Query) GetSample() int32 {
+	if m != nil {
+		return m.Sample
+	}
+	return 0
+}
+
 type ValueList struct {
 	Values               []*TaskValue `protobuf:"bytes,1,rep,name=values,proto3" json:"values,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
@@ -678,16 +711,20 @@ func (m *LangList) GetLang() []string {
 }
 
 type Result struct {
-	UidMatrix            []*List       `protobuf:"bytes,1,rep,name=uid_matrix,json=uidMatrix,proto3" json:"uid_matrix,omitempty"`
-	ValueMatrix          []*ValueList  `protobuf:"bytes,2,rep,name=value_matrix,json=valueMatrix,proto3" json:"value_matrix,omitempty"`
-	Counts               []uint32      `protobuf:"varint,3,rep,packed,name=counts,proto3" json:"counts,omitempty"`
-	IntersectDest        bool          `protobuf:"varint,4,opt,name=intersect_dest,json=intersectDest,proto3" json:"intersect_dest,omitempty"`
-	FacetMatrix          []*FacetsList `protobuf:"bytes,5,rep,name=facet_matrix,json=facetMatrix,proto3" json:"facet_matrix,omitempty"`
-	LangMatrix           []*LangList   `protobuf:"bytes,6,rep,name=lang_matrix,json=langMatrix,proto3" json:"lang_matrix,omitempty"`
-	List                 bool          `protobuf:"varint,7,opt,name=list,proto3" json:"list,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
-	XXX_unrecognized     []byte        `json:"-"`
-	XXX_sizecache        int32         `json:"-"`
+	UidMatrix     []*List       `protobuf:"bytes,1,rep,name=uid_matrix,json=uidMatrix,proto3" json:"uid_matrix,omitempty"`
+	ValueMatrix   []*ValueList  `protobuf:"bytes,2,rep,name=value_matrix,json=valueMatrix,proto3" json:"value_matrix,omitempty"`
+	Counts        []uint32      `protobuf:"varint,3,rep,packed,name=counts,proto3" json:"counts,omitempty"`
+	IntersectDest bool          `protobuf:"varint,4,opt,name=intersect_dest,json=intersectDest,proto3" json:"intersect_dest,omitempty"`
+	FacetMatrix   []*FacetsList `protobuf:"bytes,5,rep,name=facet_matrix,json=facetMatrix,proto3" json:"facet_matrix,omitempty"`
+	LangMatrix    []*LangList   `protobuf:"bytes,6,rep,name=lang_matrix,json=langMatrix,proto3" json:"lang_matrix,omitempty"`
+	List          bool          `protobuf:"varint,7,opt,name=list,proto3" json:"list,omitempty"`
+	// distinct_buckets has the uids of every index token of the predicate that has any of the
+	// uids of the query. It's only set for count(distinct) when the predicate has an exact or hash
+	// index.
+	DistinctBuckets      []*List  `protobuf:"bytes,8,rep,name=distinct_buckets,json=distinctBuckets,proto3" json:"distinct_buckets,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
 func (m *Result) Reset()         { *m = Result{} }
@@ -772,6 +809,13 @@ func (m *Result) GetList() bool {
 	return false
 }
 
+func (m *Result) GetDistinctBuckets() []*List {
+	if m != nil {
+		return m.DistinctBuckets
+	}
+	return nil
+}
+
 type Order struct {
 	Attr                 string   `protobuf:"bytes,1,opt,name=attr,proto3" json:"attr,omitempty"`
 	Desc                 bool     `protobuf:"varint,2,opt,name=desc,proto3" json:"desc,omitempty"`
@@ -966,6 +1010,7 @@ type RaftContext struct {
 	Group                uint32   `protobuf:"varint,2,opt,name=group,proto3" json:"group,omitempty"`
 	Addr                 string   `protobuf:"bytes,3,opt,name=addr,proto3" json:"addr,omitempty"`
 	SnapshotTs           uint64   `protobuf:"varint,4,opt,name=snapshot_ts,json=snapshotTs,proto3" json:"snapshot_ts,omitempty"`
+	IsLearner            bool     `protobuf:"varint,5,opt,name=is_learner,json=isLearner,proto3" json:"is_learner,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -1032,18 +1077,30 @@ func (m *RaftContext) GetSnapshotTs() uint64 {
 	return 0
 }
 
+func (m *RaftContext) GetIsLearner() bool {
+	if m != nil {
+		return m.IsLearner
+	}
+	return false
+}
+
 // Member stores information about RAFT group member for a single RAFT node.
 // Note that each server can be serving multiple RAFT groups. Each group would have
 // one RAFT node per server serving that group.
 type Member struct {
-	Id                   uint64   `protobuf:"fixed64,1,opt,name=id,proto3" json:"id,omitempty"`
-	GroupId              uint32   `protobuf:"varint,2,opt,name=group_id,json=groupId,proto3" json:"groupId,omitempty"`
-	Addr                 string   `protobuf:"bytes,3,opt,name=addr,proto3" json:"addr,omitempty"`
-	Leader               bool     `protobuf:"varint,4,opt,name=leader,proto3" json:"leader,omitempty"`
-	AmDead               bool     `protobuf:"varint,5,opt,name=am_dead,json=amDead,proto3" json:"amDead,omitempty"`
-	LastUpdate           uint64   `protobuf:"varint,6,opt,name=last_update,json=lastUpdate,proto3" json:"lastUpdate,omitempty"`
-	ClusterInfoOnly      bool     `protobuf:"varint,13,opt,name=cluster_info_only,json=clusterInfoOnly,proto3" json:"clusterInfoOnly,omitempty"`
-	ForceGroupId         bool     `protobuf:"varint,14,opt,name=force_group_id,json=forceGroupId,proto3" json:"forceGroupId,omitempty"`
+	Id              uint64 `protobuf:"fixed64,1,opt,name=id,proto3" json:"id,omitempty"`
+	GroupId         uint32 `protobuf:"varint,2,opt,name=group_id,json=groupId,proto3" json:"groupId,omitempty"`
+	Addr            string `protobuf:"bytes,3,opt,name=addr,proto3" json:"addr,omitempty"`
+	Leader          bool   `protobuf:"varint,4,opt,name=leader,proto3" json:"leader,omitempty"`
+	AmDead          bool   `protobuf:"varint,5,opt,name=am_dead,json=amDead,proto3" json:"amDead,omitempty"`
+	LastUpdate      uint64 `protobuf:"varint,6,opt,name=last_update,json=lastUpdate,proto3" json:"lastUpdate,omitempty"`
+	ClusterInfoOnly bool   `protobuf:"varint,13,opt,name=cluster_info_only,json=clusterInfoOnly,proto3" json:"clusterInfoOnly,omitempty"`
+	ForceGroupId    bool   `protobuf:"varint,14,opt,name=force_group_id,json=forceGroupId,proto3" json:"forceGroupId,omitempty"`
+	// A learner replicates the Raft log of its group, but doesn't vote.
+	Learner bool `protobuf:"varint,15,opt,name=learner,proto3" json:"learner,omitempty"`
+	// A decommissioned member is removed once the tablets of its group are moved away, if it's
+	// the last voter of the group.
+	Decommissioning      bool     `protobuf:"varint,16,opt,name=decommissioning,proto3" json:"decommissioning,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -1138,14 +1195,30 @@ func (m *Member) GetForceGroupId() bool {
 	return false
 }
 
+func (m *Member) GetLearner() bool {
+	if m != nil {
+		return m.Learner
+	}
+	return false
+}
+
+func (m *Member) GetDecommissioning() bool {
+	if m != nil {
+		return m.Decommissioning
+	}
+	return false
+}
+
 type Group struct {
-	Members              map[uint64]*Member `protobuf:"bytes,1,rep,name=members,proto3" json:"members,omitempty" protobuf_key:"varint,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
-	Tablets              map[string]*Tablet `protobuf:"bytes,2,rep,name=tablets,proto3" json:"tablets,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
-	SnapshotTs           uint64             `protobuf:"varint,3,opt,name=snapshot_ts,json=snapshotTs,proto3" json:"snapshot_ts,omitempty"`
-	Checksum             uint64             `protobuf:"varint,4,opt,name=checksum,proto3" json:"checksum,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
-	XXX_unrecognized     []byte             `json:"-"`
-	XXX_sizecache        int32              `json:"-"`
+	Members    map[uint64]*Member `protobuf:"bytes,1,rep,name=members,proto3" json:"members,omitempty" protobuf_key:"varint,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Tablets    map[string]*Tablet `protobuf:"bytes,2,rep,name=tablets,proto3" json:"tablets,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	SnapshotTs uint64             `protobuf:"varint,3,opt,name=snapshot_ts,json=snapshotTs,proto3" json:"snapshot_ts,omitempty"`
+	Checksum   uint64             `protobuf:"varint,4,opt,name=checksum,proto3" json:"checksum,omitempty"`
+	// The query and mutation rates of the tablets served by the member sending the group.
+	Loads                []*Tablet `protobuf:"bytes,5,rep,name=loads,proto3" json:"loads,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
 }
 
 func (m *Group) Reset()         { *m = Group{} }
@@ -1209,6 +1282,13 @@ func (m *Group) GetChecksum() uint64 {
 	return 0
 }
 
+func (m *Group) GetLoads() []*Tablet {
+	if m != nil {
+		return m.Loads
+	}
+	return nil
+}
+
 type License struct {
 	User                 string   `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
 	MaxNodes             uint64   `protobuf:"varint,2,opt,name=maxNodes,proto3" json:"maxNodes,omitempty"`
@@ -1281,19 +1361,25 @@ func (m *License) GetEnabled() bool {
 }
 
 type ZeroProposal struct {
-	SnapshotTs           map[uint32]uint64 `protobuf:"bytes,1,rep,name=snapshot_ts,json=snapshotTs,proto3" json:"snapshot_ts,omitempty" protobuf_key:"varint,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
-	Member               *Member           `protobuf:"bytes,2,opt,name=member,proto3" json:"member,omitempty"`
-	Tablet               *Tablet           `protobuf:"bytes,3,opt,name=tablet,proto3" json:"tablet,omitempty"`
-	MaxLeaseId           uint64            `protobuf:"varint,4,opt,name=maxLeaseId,proto3" json:"maxLeaseId,omitempty"`
-	MaxTxnTs             uint64            `protobuf:"varint,5,opt,name=maxTxnTs,proto3" json:"maxTxnTs,omitempty"`
-	MaxRaftId            uint64            `protobuf:"varint,6,opt,name=maxRaftId,proto3" json:"maxRaftId,omitempty"`
-	Txn                  *api.TxnContext   `protobuf:"bytes,7,opt,name=txn,proto3" json:"txn,omitempty"`
-	Key                  string            `protobuf:"bytes,8,opt,name=key,proto3" json:"key,omitempty"`
-	Cid                  string            `protobuf:"bytes,9,opt,name=cid,proto3" json:"cid,omitempty"`
-	License              *License          `protobuf:"bytes,10,opt,name=license,proto3" json:"license,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
-	XXX_unrecognized     []byte            `json:"-"`
-	XXX_sizecache        int32             `json:"-"`
+	SnapshotTs           map[uint32]uint64  `protobuf:"bytes,1,rep,name=snapshot_ts,json=snapshotTs,proto3" json:"snapshot_ts,omitempty" protobuf_key:"varint,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	Member               *Member            `protobuf:"bytes,2,opt,name=member,proto3" json:"member,omitempty"`
+	Tablet               *Tablet            `protobuf:"bytes,3,opt,name=tablet,proto3" json:"tablet,omitempty"`
+	MaxLeaseId           uint64             `protobuf:"varint,4,opt,name=maxLeaseId,proto3" json:"maxLeaseId,omitempty"`
+	MaxTxnTs             uint64             `protobuf:"varint,5,opt,name=maxTxnTs,proto3" json:"maxTxnTs,omitempty"`
+	MaxRaftId            uint64             `protobuf:"varint,6,opt,name=maxRaftId,proto3" json:"maxRaftId,omitempty"`
+	Txn                  *api.TxnContext    `protobuf:"bytes,7,opt,name=txn,proto3" json:"txn,omitempty"`
+	Key                  string             `protobuf:"bytes,8,opt,name=key,proto3" json:"key,omitempty"`
+	Cid                  string             `protobuf:"bytes,9,opt,name=cid,proto3" json:"cid,omitempty"`
+	License              *License           `protobuf:"bytes,10,opt,name=license,proto3" json:"license,omitempty"`
+	Idempotency          *IdempotencyRecord `protobuf:"bytes,11,opt,name=idempotency,proto3" json:"idempotency,omitempty"`
+	StrictSchema         *StrictSchema      `protobuf:"bytes,12,opt,name=strict_schema,json=strictSchema,proto3" json:"strict_schema,omitempty"`
+	Replication          *ReplicationState  `protobuf:"bytes,13,opt,name=replication,proto3" json:"replication,omitempty"`
+	Freeze               *FreezePoint       `protobuf:"bytes,14,opt,name=freeze,proto3" json:"freeze,omitempty"`
+	ReleaseFreeze        string             `protobuf:"bytes,15,opt,name=release_freeze,json=releaseFreeze,proto3" json:"release_freeze,omitempty"`
+	IdempotencyCutoff    int64              `protobuf:"varint,16,opt,name=idempotency_cutoff,json=idempotencyCutoff,proto3" json:"idempotency_cutoff,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
 }
 
 func (m *ZeroProposal) Reset()         { *m = ZeroProposal{} }
@@ -1399,22 +1485,68 @@ func (m *ZeroProposal) GetLicense() *License {
 	return nil
 }
 
+func (m *ZeroProposal) GetIdempotency() *IdempotencyRecord {
+	if m != nil {
+		return m.Idempotency
+	}
+	return nil
+}
+
+func (m *ZeroProposal) GetStrictSchema() *StrictSchema {
+	if m != nil {
+		return m.StrictSchema
+	}
+	return nil
+}
+
+func (m *ZeroProposal) GetReplication() *ReplicationState {
+	if m != nil {
+		return m.Replication
+	}
+	return nil
+}
+
+func (m *ZeroProposal) GetFreeze() *FreezePoint {
+	if m != nil {
+		return m.Freeze
+	}
+	return nil
+}
+
+func (m *ZeroProposal) GetReleaseFreeze() string {
+	if m != nil {
+		return m.ReleaseFreeze
+	}
+	return ""
+}
+
+func (m *ZeroProposal) GetIdempotencyCutoff() int64 {
+	if m != nil {
+		return m.IdempotencyCutoff
+	}
+	return 0
+}
+
 // MembershipState is used to pack together the current membership state of all the nodes
 // in the caller server; and the membership updates recorded by the callee server since
 // the provided lastUpdate.
 type MembershipState struct {
-	Counter              uint64             `protobuf:"varint,1,opt,name=counter,proto3" json:"counter,omitempty"`
-	Groups               map[uint32]*Group  `protobuf:"bytes,2,rep,name=groups,proto3" json:"groups,omitempty" protobuf_key:"varint,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
-	Zeros                map[uint64]*Member `protobuf:"bytes,3,rep,name=zeros,proto3" json:"zeros,omitempty" protobuf_key:"varint,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
-	MaxLeaseId           uint64             `protobuf:"varint,4,opt,name=maxLeaseId,proto3" json:"maxLeaseId,omitempty"`
-	MaxTxnTs             uint64             `protobuf:"varint,5,opt,name=maxTxnTs,proto3" json:"maxTxnTs,omitempty"`
-	MaxRaftId            uint64             `protobuf:"varint,6,opt,name=maxRaftId,proto3" json:"maxRaftId,omitempty"`
-	Removed              []*Member          `protobuf:"bytes,7,rep,name=removed,proto3" json:"removed,omitempty"`
-	Cid                  string             `protobuf:"bytes,8,opt,name=cid,proto3" json:"cid,omitempty"`
-	License              *License           `protobuf:"bytes,9,opt,name=license,proto3" json:"license,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
-	XXX_unrecognized     []byte             `json:"-"`
-	XXX_sizecache        int32              `json:"-"`
+	Counter              uint64               `protobuf:"varint,1,opt,name=counter,proto3" json:"counter,omitempty"`
+	Groups               map[uint32]*Group    `protobuf:"bytes,2,rep,name=groups,proto3" json:"groups,omitempty" protobuf_key:"varint,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Zeros                map[uint64]*Member   `protobuf:"bytes,3,rep,name=zeros,proto3" json:"zeros,omitempty" protobuf_key:"varint,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	MaxLeaseId           uint64               `protobuf:"varint,4,opt,name=maxLeaseId,proto3" json:"maxLeaseId,omitempty"`
+	MaxTxnTs             uint64               `protobuf:"varint,5,opt,name=maxTxnTs,proto3" json:"maxTxnTs,omitempty"`
+	MaxRaftId            uint64               `protobuf:"varint,6,opt,name=maxRaftId,proto3" json:"maxRaftId,omitempty"`
+	Removed              []*Member            `protobuf:"bytes,7,rep,name=removed,proto3" json:"removed,omitempty"`
+	Cid                  string               `protobuf:"bytes,8,opt,name=cid,proto3" json:"cid,omitempty"`
+	License              *License             `protobuf:"bytes,9,opt,name=license,proto3" json:"license,omitempty"`
+	Idempotency          []*IdempotencyRecord `protobuf:"bytes,10,rep,name=idempotency,proto3" json:"idempotency,omitempty"`
+	StrictSchema         *StrictSchema        `protobuf:"bytes,11,opt,name=strict_schema,json=strictSchema,proto3" json:"strict_schema,omitempty"`
+	Replication          *ReplicationState    `protobuf:"bytes,12,opt,name=replication,proto3" json:"replication,omitempty"`
+	FreezePoints         []*FreezePoint       `protobuf:"bytes,13,rep,name=freeze_points,json=freezePoints,proto3" json:"freeze_points,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
 }
 
 func (m *MembershipState) Reset()         { *m = MembershipState{} }
@@ -1513,6 +1645,34 @@ func (m *MembershipState) GetLicense() *License {
 	return nil
 }
 
+func (m *MembershipState) GetIdempotency() []*IdempotencyRecord {
+	if m != nil {
+		return m.Idempotency
+	}
+	return nil
+}
+
+func (m *MembershipState) GetStrictSchema() *StrictSchema {
+	if m != nil {
+		return m.StrictSchema
+	}
+	return nil
+}
+
+func (m *MembershipState) GetReplication() *ReplicationState {
+	if m != nil {
+		return m.Replication
+	}
+	return nil
+}
+
+func (m *MembershipState) GetFreezePoints() []*FreezePoint {
+	if m != nil {
+		return m.FreezePoints
+	}
+	return nil
+}
+
 type ConnectionState struct {
 	Member               *Member          `protobuf:"bytes,1,opt,name=member,proto3" json:"member,omitempty"`
 	State                *MembershipState `protobuf:"bytes,2,opt,name=state,proto3" json:"state,omitempty"`
@@ -1703,6 +1863,9 @@ type Tablet struct {
 	Remove               bool     `protobuf:"varint,8,opt,name=remove,proto3" json:"remove,omitempty"`
 	ReadOnly             bool     `protobuf:"varint,9,opt,name=read_only,json=readOnly,proto3" json:"readOnly,omitempty"`
 	MoveTs               uint64   `protobuf:"varint,10,opt,name=move_ts,json=moveTs,proto3" json:"moveTs,omitempty"`
+	QueriesPerMin        uint64   `protobuf:"varint,11,opt,name=queries_per_min,json=queriesPerMin,proto3" json:"queriesPerMin,omitempty"`
+	MutationsPerMin      uint64   `protobuf:"varint,12,opt,name=mutations_per_min,json=mutationsPerMin,proto3" json:"mutationsPerMin,omitempty"`
+	NumKeys              uint64   `protobuf:"varint,13,opt,name=num_keys,json=numKeys,proto3" json:"numKeys,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -1790,6 +1953,27 @@ func (m *Tablet) GetMoveTs() uint64 {
 	return 0
 }
 
+func (m *Tablet) GetQueriesPerMin() uint64 {
+	if m != nil {
+		return m.QueriesPerMin
+	}
+	return 0
+}
+
+func (m *Tablet) GetMutationsPerMin() uint64 {
+	if m != nil {
+		return m.MutationsPerMin
+	}
+	return 0
+}
+
+func (m *Tablet) GetNumKeys() uint64 {
+	if m != nil {
+		return m.NumKeys
+	}
+	return 0
+}
+
 type DirectedEdge struct {
 	Entity               uint64          `protobuf:"fixed64,1,opt,name=entity,proto3" json:"entity,omitempty"`
 	Attr                 string          `protobuf:"bytes,2,opt,name=attr,proto3" json:"attr,omitempty"`
@@ -1801,6 +1985,7 @@ type DirectedEdge struct {
 	Op                   DirectedEdge_Op `protobuf:"varint,8,opt,name=op,proto3,enum=pb.DirectedEdge_Op" json:"op,omitempty"`
 	Facets               []*api.Facet    `protobuf:"bytes,9,rep,name=facets,proto3" json:"facets,omitempty"`
 	AllowedPreds         []string        `protobuf:"bytes,10,rep,name=allowedPreds,proto3" json:"allowedPreds,omitempty"`
+	BlobSize             uint64          `protobuf:"varint,11,opt,name=blob_size,json=blobSize,proto3" json:"blob_size,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
 	XXX_unrecognized     []byte          `json:"-"`
 	XXX_sizecache        int32           `json:"-"`
@@ -1909,18 +2094,39 @@ func (m *DirectedEdge) GetAllowedPreds() []string {
 	return nil
 }
 
+func (m *DirectedEdge) GetBlobSize() uint64 {
+	if m != nil {
+		return m.BlobSize
+	}
+	return 0
+}
+
 type Mutations struct {
-	GroupId              uint32           `protobuf:"varint,1,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
-	StartTs              uint64           `protobuf:"varint,2,opt,name=start_ts,json=startTs,proto3" json:"start_ts,omitempty"`
-	Edges                []*DirectedEdge  `protobuf:"bytes,3,rep,name=edges,proto3" json:"edges,omitempty"`
-	Schema               []*SchemaUpdate  `protobuf:"bytes,4,rep,name=schema,proto3" json:"schema,omitempty"`
-	Types                []*TypeUpdate    `protobuf:"bytes,6,rep,name=types,proto3" json:"types,omitempty"`
-	DropOp               Mutations_DropOp `protobuf:"varint,7,opt,name=drop_op,json=dropOp,proto3,enum=pb.Mutations_DropOp" json:"drop_op,omitempty"`
-	DropValue            string           `protobuf:"bytes,8,opt,name=drop_value,json=dropValue,proto3" json:"drop_value,omitempty"`
-	Metadata             *Metadata        `protobuf:"bytes,9,opt,name=metadata,proto3" json:"metadata,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
-	XXX_unrecognized     []byte           `json:"-"`
-	XXX_sizecache        int32            `json:"-"`
+	GroupId   uint32           `protobuf:"varint,1,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	StartTs   uint64           `protobuf:"varint,2,opt,name=start_ts,json=startTs,proto3" json:"start_ts,omitempty"`
+	Edges     []*DirectedEdge  `protobuf:"bytes,3,rep,name=edges,proto3" json:"edges,omitempty"`
+	Schema    []*SchemaUpdate  `protobuf:"bytes,4,rep,name=schema,proto3" json:"schema,omitempty"`
+	Types     []*TypeUpdate    `protobuf:"bytes,6,rep,name=types,proto3" json:"types,omitempty"`
+	DropOp    Mutations_DropOp `protobuf:"varint,7,opt,name=drop_op,json=dropOp,proto3,enum=pb.Mutations_DropOp" json:"drop_op,omitempty"`
+	DropValue string           `protobuf:"bytes,8,opt,name=drop_value,json=dropValue,proto3" json:"drop_value,omitempty"`
+	Metadata  *Metadata        `protobuf:"bytes,9,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	// savepoint, if set, records the pending changes of the transaction under this name.
+	Savepoint string `protobuf:"bytes,10,opt,name=savepoint,proto3" json:"savepoint,omitempty"`
+	// rollback_to, if set, reverts the pending changes of the transaction to the ones recorded
+	// by the savepoint with this name.
+	RollbackTo string `protobuf:"bytes,11,opt,name=rollback_to,json=rollbackTo,proto3" json:"rollback_to,omitempty"`
+	// blob_chunks, if set, stores the chunks of a large value written by the transaction.
+	BlobChunks           []*BlobChunk `protobuf:"bytes,12,rep,name=blob_chunks,json=blobChunks,proto3" json:"blob_chunks,omitempty"`
+	RenameFrom           string       `protobuf:"bytes,13,opt,name=rename_from,json=renameFrom,proto3" json:"rename_from,omitempty"`
+	RenameTo             string       `protobuf:"bytes,14,opt,name=rename_to,json=renameTo,proto3" json:"rename_to,omitempty"`
+	TierPredicate        string       `protobuf:"bytes,15,opt,name=tier_predicate,json=tierPredicate,proto3" json:"tier_predicate,omitempty"`
+	Tier                 string       `protobuf:"bytes,16,opt,name=tier,proto3" json:"tier,omitempty"`
+	TierObject           string       `protobuf:"bytes,17,opt,name=tier_object,json=tierObject,proto3" json:"tier_object,omitempty"`
+	DictionaryPredicate  string       `protobuf:"bytes,18,opt,name=dictionary_predicate,json=dictionaryPredicate,proto3" json:"dictionary_predicate,omitempty"`
+	Dictionary           []byte       `protobuf:"bytes,19,opt,name=dictionary,proto3" json:"dictionary,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
 }
 
 func (m *Mutations) Reset()         { *m = Mutations{} }
@@ -2012,6 +2218,76 @@ func (m *Mutations) GetMetadata() *Metadata {
 	return nil
 }
 
+func (m *Mutations) GetSavepoint() string {
+	if m != nil {
+		return m.Savepoint
+	}
+	return ""
+}
+
+func (m *Mutations) GetRollbackTo() string {
+	if m != nil {
+		return m.RollbackTo
+	}
+	return ""
+}
+
+func (m *Mutations) GetBlobChunks() []*BlobChunk {
+	if m != nil {
+		return m.BlobChunks
+	}
+	return nil
+}
+
+func (m *Mutations) GetRenameFrom() string {
+	if m != nil {
+		return m.RenameFrom
+	}
+	return ""
+}
+
+func (m *Mutations) GetRenameTo() string {
+	if m != nil {
+		return m.RenameTo
+	}
+	return ""
+}
+
+func (m *Mutations) GetTierPredicate() string {
+	if m != nil {
+		return m.TierPredicate
+	}
+	return ""
+}
+
+func (m *Mutations) GetTier() string {
+	if m != nil {
+		return m.Tier
+	}
+	return ""
+}
+
+func (m *Mutations) GetTierObject() string {
+	if m != nil {
+		return m.TierObject
+	}
+	return ""
+}
+
+func (m *Mutations) GetDictionaryPredicate() string {
+	if m != nil {
+		return m.DictionaryPredicate
+	}
+	return ""
+}
+
+func (m *Mutations) GetDictionary() []byte {
+	if m != nil {
+		return m.Dictionary
+	}
+	return nil
+}
+
 type Metadata struct {
 	// Map of predicates to their hints.
 	PredHints            map[string]Metadata_HintType `protobuf:"bytes,1,rep,name=pred_hints,json=predHints,proto3" json:"pred_hints,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3,enum=pb.Metadata_HintType"`
@@ -2067,10 +2343,13 @@ type Snapshot struct {
 	// done is used to indicate that snapshot stream was a success.
 	Done bool `protobuf:"varint,4,opt,name=done,proto3" json:"done,omitempty"`
 	// since_ts stores the ts of the last snapshot to support diff snap updates.
-	SinceTs              uint64   `protobuf:"varint,5,opt,name=since_ts,json=sinceTs,proto3" json:"since_ts,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	SinceTs uint64 `protobuf:"varint,5,opt,name=since_ts,json=sinceTs,proto3" json:"since_ts,omitempty"`
+	// received holds the key ranges a follower already wrote, when it resumes the stream of the
+	// snapshot after an error. They aren't sent again.
+	Received             []*KeyRange `protobuf:"bytes,6,rep,name=received,proto3" json:"received,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
 }
 
 func (m *Snapshot) Reset()         { *m = Snapshot{} }
@@ -2141,6 +2420,13 @@ func (m *Snapshot) GetSinceTs() uint64 {
 	return 0
 }
 
+func (m *Snapshot) GetReceived() []*KeyRange {
+	if m != nil {
+		return m.Received
+	}
+	return nil
+}
+
 type RestoreRequest struct {
 	GroupId   uint32 `protobuf:"varint,1,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
 	RestoreTs uint64 `protobuf:"varint,2,opt,name=restore_ts,json=restoreTs,proto3" json:"restore_ts,omitempty"`
@@ -2322,6 +2608,7 @@ type Proposal struct {
 	Index                uint64           `protobuf:"varint,10,opt,name=index,proto3" json:"index,omitempty"`
 	ExpectedChecksum     uint64           `protobuf:"varint,11,opt,name=expected_checksum,json=expectedChecksum,proto3" json:"expected_checksum,omitempty"`
 	Restore              *RestoreRequest  `protobuf:"bytes,12,opt,name=restore,proto3" json:"restore,omitempty"`
+	TierAccessed         []string         `protobuf:"bytes,13,rep,name=tier_accessed,json=tierAccessed,proto3" json:"tier_accessed,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
 	XXX_unrecognized     []byte           `json:"-"`
 	XXX_sizecache        int32            `json:"-"`
@@ -2430,6 +2717,13 @@ func (m *Proposal) GetRestore() *RestoreRequest {
 	return nil
 }
 
+func (m *Proposal) GetTierAccessed() []string {
+	if m != nil {
+		return m.TierAccessed
+	}
+	return nil
+}
+
 type KVS struct {
 	Kv []*pb.KV `protobuf:"bytes,1,rep,name=kv,proto3" json:"kv,omitempty"`
 	// done used to indicate if the stream of KVS is over.
@@ -2514,9 +2808,13 @@ type Posting struct {
 	Label       string              `protobuf:"bytes,6,opt,name=label,proto3" json:"label,omitempty"`
 	Facets      []*api.Facet        `protobuf:"bytes,9,rep,name=facets,proto3" json:"facets,omitempty"`
 	// TODO: op is only used temporarily. See if we can remove it from here.
-	Op                   uint32   `protobuf:"varint,12,opt,name=op,proto3" json:"op,omitempty"`
-	StartTs              uint64   `protobuf:"varint,13,opt,name=start_ts,json=startTs,proto3" json:"start_ts,omitempty"`
-	CommitTs             uint64   `protobuf:"varint,14,opt,name=commit_ts,json=commitTs,proto3" json:"commit_ts,omitempty"`
+	Op       uint32 `protobuf:"varint,12,opt,name=op,proto3" json:"op,omitempty"`
+	StartTs  uint64 `protobuf:"varint,13,opt,name=start_ts,json=startTs,proto3" json:"start_ts,omitempty"`
+	CommitTs uint64 `protobuf:"varint,14,opt,name=commit_ts,json=commitTs,proto3" json:"commit_ts,omitempty"`
+	// blob_size is set when the value has been chunked into blob keys. In that case, value
+	// holds the hash of the content and blob_size is the length of the original value.
+	BlobSize             uint64   `protobuf:"varint,15,opt,name=blob_size,json=blobSize,proto3" json:"blob_size,omitempty"`
+	Encoding             uint32   `protobuf:"varint,16,opt,name=encoding,proto3" json:"encoding,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -2625,6 +2923,20 @@ func (m *Posting) GetCommitTs() uint64 {
 	return 0
 }
 
+func (m *Posting) GetBlobSize() uint64 {
+	if m != nil {
+		return m.BlobSize
+	}
+	return 0
+}
+
+func (m *Posting) GetEncoding() uint32 {
+	if m != nil {
+		return m.Encoding
+	}
+	return 0
+}
+
 type UidBlock struct {
 	Base uint64 `protobuf:"varint,1,opt,name=base,proto3" json:"base,omitempty"`
 	// deltas contains the deltas encoded with Varints. We don't store deltas as a list of integers,
@@ -2637,7 +2949,10 @@ type UidBlock struct {
 	// MSB base uids. That is, if the 32 MSBs are different, we will create a new block irrespective
 	// of whether the block is filled with the block_size or not.
 	// Default Blocksize is 256 so uint32 would be sufficient.
-	NumUids              uint32   `protobuf:"varint,3,opt,name=num_uids,json=numUids,proto3" json:"num_uids,omitempty"`
+	NumUids uint32 `protobuf:"varint,3,opt,name=num_uids,json=numUids,proto3" json:"num_uids,omitempty"`
+	// bitmap replaces deltas in the dense blocks, for which it's smaller, like the bitmap
+	// containers of roaring bitmaps. The i-th bit is set if base + i is in the block.
+	Bitmap               []byte   `protobuf:"bytes,4,opt,name=bitmap,proto3" json:"bitmap,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -2697,6 +3012,13 @@ func (m *UidBlock) GetNumUids() uint32 {
 	return 0
 }
 
+func (m *UidBlock) GetBitmap() []byte {
+	if m != nil {
+		return m.Bitmap
+	}
+	return nil
+}
+
 type UidPack struct {
 	BlockSize            uint32      `protobuf:"varint,1,opt,name=block_size,json=blockSize,proto3" json:"block_size,omitempty"`
 	Blocks               []*UidBlock `protobuf:"bytes,2,rep,name=blocks,proto3" json:"blocks,omitempty"`
@@ -3246,6 +3568,22 @@ type SchemaNode struct {
 	Upsert               bool     `protobuf:"varint,8,opt,name=upsert,proto3" json:"upsert,omitempty"`
 	Lang                 bool     `protobuf:"varint,9,opt,name=lang,proto3" json:"lang,omitempty"`
 	NoConflict           bool     `protobuf:"varint,10,opt,name=no_conflict,json=noConflict,proto3" json:"no_conflict,omitempty"`
+	Unique               bool     `protobuf:"varint,11,opt,name=unique,proto3" json:"unique,omitempty"`
+	Ttl                  string   `protobuf:"bytes,12,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	TtlFrom              string   `protobuf:"bytes,13,opt,name=ttl_from,json=ttlFrom,proto3" json:"ttl_from,omitempty"`
+	RenamedFrom          string   `protobuf:"bytes,14,opt,name=renamed_from,json=renamedFrom,proto3" json:"renamed_from,omitempty"`
+	DefaultValue         string   `protobuf:"bytes,15,opt,name=default_value,json=defaultValue,proto3" json:"default_value,omitempty"`
+	DefaultVirtual       bool     `protobuf:"varint,16,opt,name=default_virtual,json=defaultVirtual,proto3" json:"default_virtual,omitempty"`
+	IndexWhere           string   `protobuf:"bytes,17,opt,name=index_where,json=indexWhere,proto3" json:"index_where,omitempty"`
+	Encoding             string   `protobuf:"bytes,18,opt,name=encoding,proto3" json:"encoding,omitempty"`
+	EnumValues           []string `protobuf:"bytes,19,rep,name=enum_values,json=enumValues,proto3" json:"enum_values,omitempty"`
+	CheckMin             string   `protobuf:"bytes,20,opt,name=check_min,json=checkMin,proto3" json:"check_min,omitempty"`
+	CheckMax             string   `protobuf:"bytes,21,opt,name=check_max,json=checkMax,proto3" json:"check_max,omitempty"`
+	CheckMaxLength       uint32   `protobuf:"varint,22,opt,name=check_max_length,json=checkMaxLength,proto3" json:"check_max_length,omitempty"`
+	CheckPattern         string   `protobuf:"bytes,23,opt,name=check_pattern,json=checkPattern,proto3" json:"check_pattern,omitempty"`
+	References           string   `protobuf:"bytes,24,opt,name=references,proto3" json:"references,omitempty"`
+	Tier                 string   `protobuf:"bytes,25,opt,name=tier,proto3" json:"tier,omitempty"`
+	Compression          string   `protobuf:"bytes,26,opt,name=compression,proto3" json:"compression,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -3354,35 +3692,147 @@ func (m *SchemaNode) GetNoConflict() bool {
 	return false
 }
 
-type SchemaResult struct {
-	Schema               []*SchemaNode `protobuf:"bytes,1,rep,name=schema,proto3" json:"schema,omitempty"` // Deprecated: Do not use.
-	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
-	XXX_unrecognized     []byte        `json:"-"`
-	XXX_sizecache        int32         `json:"-"`
+func (m *SchemaNode) GetUnique() bool {
+	if m != nil {
+		return m.Unique
+	}
+	return false
 }
 
-func (m *SchemaResult) Reset()         { *m = SchemaResult{} }
-func (m *SchemaResult) String() string { return proto.CompactTextString(m) }
-func (*SchemaResult) ProtoMessage()    {}
-func (*SchemaResult) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f80abaa17e25ccc8, []int{38}
-}
-func (m *SchemaResult) XXX_Unmarshal(b []byte) error {
-	return m.Unmarshal(b)
-}
-func (m *SchemaResult) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	if deterministic {
-		return xxx_messageInfo_SchemaResult.Marshal(b, m, deterministic)
-	} else {
-		b = b[:cap(b)]
-		n, err := m.MarshalToSizedBuffer(b)
-		if err != nil {
-			return nil, err
-		}
-		return b[:n], nil
+func (m *SchemaNode) GetTtl() string {
+	if m != nil {
+		return m.Ttl
 	}
+	return ""
 }
-func (m *SchemaResult) XXX_Merge(src proto.Message) {
+
+func (m *SchemaNode) GetTtlFrom() string {
+	if m != nil {
+		return m.TtlFrom
+	}
+	return ""
+}
+
+func (m *SchemaNode) GetRenamedFrom() string {
+	if m != nil {
+		return m.RenamedFrom
+	}
+	return ""
+}
+
+func (m *SchemaNode) GetDefaultValue() string {
+	if m != nil {
+		return m.DefaultValue
+	}
+	return ""
+}
+
+func (m *SchemaNode) GetDefaultVirtual() bool {
+	if m != nil {
+		return m.DefaultVirtual
+	}
+	return false
+}
+
+func (m *SchemaNode) GetIndexWhere() string {
+	if m != nil {
+		return m.IndexWhere
+	}
+	return ""
+}
+
+func (m *SchemaNode) GetEncoding() string {
+	if m != nil {
+		return m.Encoding
+	}
+	return ""
+}
+
+func (m *SchemaNode) GetEnumValues() []string {
+	if m != nil {
+		return m.EnumValues
+	}
+	return nil
+}
+
+func (m *SchemaNode) GetCheckMin() string {
+	if m != nil {
+		return m.CheckMin
+	}
+	return ""
+}
+
+func (m *SchemaNode) GetCheckMax() string {
+	if m != nil {
+		return m.CheckMax
+	}
+	return ""
+}
+
+func (m *SchemaNode) GetCheckMaxLength() uint32 {
+	if m != nil {
+		return m.CheckMaxLength
+	}
+	return 0
+}
+
+func (m *SchemaNode) GetCheckPattern() string {
+	if m != nil {
+		return m.CheckPattern
+	}
+	return ""
+}
+
+func (m *SchemaNode) GetReferences() string {
+	if m != nil {
+		return m.References
+	}
+	return ""
+}
+
+func (m *SchemaNode) GetTier() string {
+	if m != nil {
+		return m.Tier
+	}
+	return ""
+}
+
+func (m *SchemaNode) GetCompression() string {
+	if m != nil {
+		return m.Compression
+	}
+	return ""
+}
+
+type SchemaResult struct {
+	Schema               []*SchemaNode `protobuf:"bytes,1,rep,name=schema,proto3" json:"schema,omitempty"` // Deprecated: Do not use.
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *SchemaResult) Reset()         { *m = SchemaResult{} }
+func (m *SchemaResult) String() string { return proto.CompactTextString(m) }
+func (*SchemaResult) ProtoMessage()    {}
+func (*SchemaResult) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f80abaa17e25ccc8, []int{38}
+}
+func (m *SchemaResult) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *SchemaResult) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_SchemaResult.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *SchemaResult) XXX_Merge(src proto.Message) {
 	xxx_messageInfo_SchemaResult.Merge(m, src)
 }
 func (m *SchemaResult) XXX_Size() int {
@@ -3416,11 +3866,32 @@ type SchemaUpdate struct {
 	NonNullableList bool `protobuf:"varint,11,opt,name=non_nullable_list,json=nonNullableList,proto3" json:"non_nullable_list,omitempty"`
 	// If value_type is OBJECT, then this represents an object type with a
 	// custom name. This field stores said name.
-	ObjectTypeName       string   `protobuf:"bytes,12,opt,name=object_type_name,json=objectTypeName,proto3" json:"object_type_name,omitempty"`
-	NoConflict           bool     `protobuf:"varint,13,opt,name=no_conflict,json=noConflict,proto3" json:"no_conflict,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	ObjectTypeName          string   `protobuf:"bytes,12,opt,name=object_type_name,json=objectTypeName,proto3" json:"object_type_name,omitempty"`
+	NoConflict              bool     `protobuf:"varint,13,opt,name=no_conflict,json=noConflict,proto3" json:"no_conflict,omitempty"`
+	Unique                  bool     `protobuf:"varint,14,opt,name=unique,proto3" json:"unique,omitempty"`
+	Ttl                     string   `protobuf:"bytes,15,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	TtlFrom                 string   `protobuf:"bytes,16,opt,name=ttl_from,json=ttlFrom,proto3" json:"ttl_from,omitempty"`
+	RenamedFrom             string   `protobuf:"bytes,17,opt,name=renamed_from,json=renamedFrom,proto3" json:"renamed_from,omitempty"`
+	DefaultValue            string   `protobuf:"bytes,18,opt,name=default_value,json=defaultValue,proto3" json:"default_value,omitempty"`
+	DefaultVirtual          bool     `protobuf:"varint,19,opt,name=default_virtual,json=defaultVirtual,proto3" json:"default_virtual,omitempty"`
+	IndexWhere              string   `protobuf:"bytes,20,opt,name=index_where,json=indexWhere,proto3" json:"index_where,omitempty"`
+	Encoding                string   `protobuf:"bytes,21,opt,name=encoding,proto3" json:"encoding,omitempty"`
+	Dictionary              []string `protobuf:"bytes,22,rep,name=dictionary,proto3" json:"dictionary,omitempty"`
+	EncodingBase            int64    `protobuf:"varint,23,opt,name=encoding_base,json=encodingBase,proto3" json:"encoding_base,omitempty"`
+	EncodingBaseSet         bool     `protobuf:"varint,24,opt,name=encoding_base_set,json=encodingBaseSet,proto3" json:"encoding_base_set,omitempty"`
+	EnumValues              []string `protobuf:"bytes,25,rep,name=enum_values,json=enumValues,proto3" json:"enum_values,omitempty"`
+	CheckMin                string   `protobuf:"bytes,26,opt,name=check_min,json=checkMin,proto3" json:"check_min,omitempty"`
+	CheckMax                string   `protobuf:"bytes,27,opt,name=check_max,json=checkMax,proto3" json:"check_max,omitempty"`
+	CheckMaxLength          uint32   `protobuf:"varint,28,opt,name=check_max_length,json=checkMaxLength,proto3" json:"check_max_length,omitempty"`
+	CheckPattern            string   `protobuf:"bytes,29,opt,name=check_pattern,json=checkPattern,proto3" json:"check_pattern,omitempty"`
+	References              string   `protobuf:"bytes,30,opt,name=references,proto3" json:"references,omitempty"`
+	Tier                    string   `protobuf:"bytes,31,opt,name=tier,proto3" json:"tier,omitempty"`
+	TierObject              string   `protobuf:"bytes,32,opt,name=tier_object,json=tierObject,proto3" json:"tier_object,omitempty"`
+	Compression             string   `protobuf:"bytes,33,opt,name=compression,proto3" json:"compression,omitempty"`
+	CompressionDictionaries [][]byte `protobuf:"bytes,34,rep,name=compression_dictionaries,json=compressionDictionaries,proto3" json:"compression_dictionaries,omitempty"`
+	XXX_NoUnkeyedLiteral    struct{} `json:"-"`
+	XXX_unrecognized        []byte   `json:"-"`
+	XXX_sizecache           int32    `json:"-"`
 }
 
 func (m *SchemaUpdate) Reset()         { *m = SchemaUpdate{} }
@@ -3540,9 +4011,158 @@ func (m *SchemaUpdate) GetNoConflict() bool {
 	return false
 }
 
+func (m *SchemaUpdate) GetUnique() bool {
+	if m != nil {
+		return m.Unique
+	}
+	return false
+}
+
+func (m *SchemaUpdate) GetTtl() string {
+	if m != nil {
+		return m.Ttl
+	}
+	return ""
+}
+
+func (m *SchemaUpdate) GetTtlFrom() string {
+	if m != nil {
+		return m.TtlFrom
+	}
+	return ""
+}
+
+func (m *SchemaUpdate) GetRenamedFrom() string {
+	if m != nil {
+		return m.RenamedFrom
+	}
+	return ""
+}
+
+func (m *SchemaUpdate) GetDefaultValue() string {
+	if m != nil {
+		return m.DefaultValue
+	}
+	return ""
+}
+
+func (m *SchemaUpdate) GetDefaultVirtual() bool {
+	if m != nil {
+		return m.DefaultVirtual
+	}
+	return false
+}
+
+func (m *SchemaUpdate) GetIndexWhere() string {
+	if m != nil {
+		return m.IndexWhere
+	}
+	return ""
+}
+
+func (m *SchemaUpdate) GetEncoding() string {
+	if m != nil {
+		return m.Encoding
+	}
+	return ""
+}
+
+func (m *SchemaUpdate) GetDictionary() []string {
+	if m != nil {
+		return m.Dictionary
+	}
+	return nil
+}
+
+func (m *SchemaUpdate) GetEncodingBase() int64 {
+	if m != nil {
+		return m.EncodingBase
+	}
+	return 0
+}
+
+func (m *SchemaUpdate) GetEncodingBaseSet() bool {
+	if m != nil {
+		return m.EncodingBaseSet
+	}
+	return false
+}
+
+func (m *SchemaUpdate) GetEnumValues() []string {
+	if m != nil {
+		return m.EnumValues
+	}
+	return nil
+}
+
+func (m *SchemaUpdate) GetCheckMin() string {
+	if m != nil {
+		return m.CheckMin
+	}
+	return ""
+}
+
+func (m *SchemaUpdate) GetCheckMax() string {
+	if m != nil {
+		return m.CheckMax
+	}
+	return ""
+}
+
+func (m *SchemaUpdate) GetCheckMaxLength() uint32 {
+	if m != nil {
+		return m.CheckMaxLength
+	}
+	return 0
+}
+
+func (m *SchemaUpdate) GetCheckPattern() string {
+	if m != nil {
+		return m.CheckPattern
+	}
+	return ""
+}
+
+func (m *SchemaUpdate) GetReferences() string {
+	if m != nil {
+		return m.References
+	}
+	return ""
+}
+
+func (m *SchemaUpdate) GetTier() string {
+	if m != nil {
+		return m.Tier
+	}
+	return ""
+}
+
+func (m *SchemaUpdate) GetTierObject() string {
+	if m != nil {
+		return m.TierObject
+	}
+	return ""
+}
+
+func (m *SchemaUpdate) GetCompression() string {
+	if m != nil {
+		return m.Compression
+	}
+	return ""
+}
+
+func (m *SchemaUpdate) GetCompressionDictionaries() [][]byte {
+	if m != nil {
+		return m.CompressionDictionaries
+	}
+	return nil
+}
+
 type TypeUpdate struct {
 	TypeName             string          `protobuf:"bytes,1,opt,name=type_name,json=typeName,proto3" json:"type_name,omitempty"`
 	Fields               []*SchemaUpdate `protobuf:"bytes,2,rep,name=fields,proto3" json:"fields,omitempty"`
+	Ttl                  string          `protobuf:"bytes,3,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	TtlFrom              string          `protobuf:"bytes,4,opt,name=ttl_from,json=ttlFrom,proto3" json:"ttl_from,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
 	XXX_unrecognized     []byte          `json:"-"`
 	XXX_sizecache        int32           `json:"-"`
@@ -3595,6 +4215,20 @@ func (m *TypeUpdate) GetFields() []*SchemaUpdate {
 	return nil
 }
 
+func (m *TypeUpdate) GetTtl() string {
+	if m != nil {
+		return m.Ttl
+	}
+	return ""
+}
+
+func (m *TypeUpdate) GetTtlFrom() string {
+	if m != nil {
+		return m.TtlFrom
+	}
+	return ""
+}
+
 type MapHeader struct {
 	PartitionKeys        [][]byte `protobuf:"bytes,1,rep,name=partition_keys,json=partitionKeys,proto3" json:"partition_keys,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
@@ -4906,1195 +5540,1289 @@ func (m *UpdateGraphQLSchemaResponse) GetUid() uint64 {
 	return 0
 }
 
-func init() {
-	proto.RegisterEnum("pb.DirectedEdge_Op", DirectedEdge_Op_name, DirectedEdge_Op_value)
-	proto.RegisterEnum("pb.Mutations_DropOp", Mutations_DropOp_name, Mutations_DropOp_value)
-	proto.RegisterEnum("pb.Metadata_HintType", Metadata_HintType_name, Metadata_HintType_value)
-	proto.RegisterEnum("pb.Posting_ValType", Posting_ValType_name, Posting_ValType_value)
-	proto.RegisterEnum("pb.Posting_PostingType", Posting_PostingType_name, Posting_PostingType_value)
-	proto.RegisterEnum("pb.SchemaUpdate_Directive", SchemaUpdate_Directive_name, SchemaUpdate_Directive_value)
-	proto.RegisterEnum("pb.BackupKey_KeyType", BackupKey_KeyType_name, BackupKey_KeyType_value)
-	proto.RegisterType((*List)(nil), "pb.List")
-	proto.RegisterType((*TaskValue)(nil), "pb.TaskValue")
-	proto.RegisterType((*SrcFunction)(nil), "pb.SrcFunction")
-	proto.RegisterType((*Query)(nil), "pb.Query")
-	proto.RegisterType((*ValueList)(nil), "pb.ValueList")
-	proto.RegisterType((*LangList)(nil), "pb.LangList")
-	proto.RegisterType((*Result)(nil), "pb.Result")
-	proto.RegisterType((*Order)(nil), "pb.Order")
-	proto.RegisterType((*SortMessage)(nil), "pb.SortMessage")
-	proto.RegisterType((*SortResult)(nil), "pb.SortResult")
-	proto.RegisterType((*RaftContext)(nil), "pb.RaftContext")
-	proto.RegisterType((*Member)(nil), "pb.Member")
-	proto.RegisterType((*Group)(nil), "pb.Group")
-	proto.RegisterMapType((map[uint64]*Member)(nil), "pb.Group.MembersEntry")
-	proto.RegisterMapType((map[string]*Tablet)(nil), "pb.Group.TabletsEntry")
-	proto.RegisterType((*License)(nil), "pb.License")
-	proto.RegisterType((*ZeroProposal)(nil), "pb.ZeroProposal")
-	proto.RegisterMapType((map[uint32]uint64)(nil), "pb.ZeroProposal.SnapshotTsEntry")
-	proto.RegisterType((*MembershipState)(nil), "pb.MembershipState")
-	proto.RegisterMapType((map[uint32]*Group)(nil), "pb.MembershipState.GroupsEntry")
-	proto.RegisterMapType((map[uint64]*Member)(nil), "pb.MembershipState.ZerosEntry")
-	proto.RegisterType((*ConnectionState)(nil), "pb.ConnectionState")
-	proto.RegisterType((*HealthInfo)(nil), "pb.HealthInfo")
-	proto.RegisterType((*Tablet)(nil), "pb.Tablet")
-	proto.RegisterType((*DirectedEdge)(nil), "pb.DirectedEdge")
-	proto.RegisterType((*Mutations)(nil), "pb.Mutations")
-	proto.RegisterType((*Metadata)(nil), "pb.Metadata")
-	proto.RegisterMapType((map[string]Metadata_HintType)(nil), "pb.Metadata.PredHintsEntry")
-	proto.RegisterType((*Snapshot)(nil), "pb.Snapshot")
-	proto.RegisterType((*RestoreRequest)(nil), "pb.RestoreRequest")
-	proto.RegisterType((*Proposal)(nil), "pb.Proposal")
-	proto.RegisterType((*KVS)(nil), "pb.KVS")
-	proto.RegisterType((*Posting)(nil), "pb.Posting")
-	proto.RegisterType((*UidBlock)(nil), "pb.UidBlock")
-	proto.RegisterType((*UidPack)(nil), "pb.UidPack")
-	proto.RegisterType((*PostingList)(nil), "pb.PostingList")
-	proto.RegisterType((*FacetParam)(nil), "pb.FacetParam")
-	proto.RegisterType((*FacetParams)(nil), "pb.FacetParams")
-	proto.RegisterType((*Facets)(nil), "pb.Facets")
-	proto.RegisterType((*FacetsList)(nil), "pb.FacetsList")
-	proto.RegisterType((*Function)(nil), "pb.Function")
-	proto.RegisterType((*FilterTree)(nil), "pb.FilterTree")
-	proto.RegisterType((*SchemaRequest)(nil), "pb.SchemaRequest")
-	proto.RegisterType((*SchemaNode)(nil), "pb.SchemaNode")
-	proto.RegisterType((*SchemaResult)(nil), "pb.SchemaResult")
-	proto.RegisterType((*SchemaUpdate)(nil), "pb.SchemaUpdate")
-	proto.RegisterType((*TypeUpdate)(nil), "pb.TypeUpdate")
-	proto.RegisterType((*MapHeader)(nil), "pb.MapHeader")
-	proto.RegisterType((*MovePredicatePayload)(nil), "pb.MovePredicatePayload")
-	proto.RegisterType((*TxnStatus)(nil), "pb.TxnStatus")
-	proto.RegisterType((*OracleDelta)(nil), "pb.OracleDelta")
-	proto.RegisterMapType((map[uint32]uint64)(nil), "pb.OracleDelta.GroupChecksumsEntry")
-	proto.RegisterType((*TxnTimestamps)(nil), "pb.TxnTimestamps")
-	proto.RegisterType((*PeerResponse)(nil), "pb.PeerResponse")
-	proto.RegisterType((*RaftBatch)(nil), "pb.RaftBatch")
-	proto.RegisterType((*SubscriptionRequest)(nil), "pb.SubscriptionRequest")
-	proto.RegisterType((*SubscriptionResponse)(nil), "pb.SubscriptionResponse")
-	proto.RegisterType((*Num)(nil), "pb.Num")
-	proto.RegisterType((*AssignedIds)(nil), "pb.AssignedIds")
-	proto.RegisterType((*SnapshotMeta)(nil), "pb.SnapshotMeta")
-	proto.RegisterType((*Status)(nil), "pb.Status")
-	proto.RegisterType((*BackupRequest)(nil), "pb.BackupRequest")
-	proto.RegisterType((*ExportRequest)(nil), "pb.ExportRequest")
-	proto.RegisterType((*ExportResponse)(nil), "pb.ExportResponse")
-	proto.RegisterType((*BackupKey)(nil), "pb.BackupKey")
-	proto.RegisterType((*BackupPostingList)(nil), "pb.BackupPostingList")
-	proto.RegisterType((*UpdateGraphQLSchemaRequest)(nil), "pb.UpdateGraphQLSchemaRequest")
-	proto.RegisterType((*UpdateGraphQLSchemaResponse)(nil), "pb.UpdateGraphQLSchemaResponse")
+// IdempotencyRecord is the outcome of a commit made with an idempotency key. Zero keeps it until
+// it expires, so that retries of the commit get the same outcome whichever Alpha they're sent to.
+type IdempotencyRecord struct {
+	Key                  string   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Fingerprint          uint64   `protobuf:"varint,2,opt,name=fingerprint,proto3" json:"fingerprint,omitempty"`
+	StartTs              uint64   `protobuf:"varint,3,opt,name=start_ts,json=startTs,proto3" json:"start_ts,omitempty"`
+	CommitTs             uint64   `protobuf:"varint,4,opt,name=commit_ts,json=commitTs,proto3" json:"commit_ts,omitempty"`
+	ExpiresAt            int64    `protobuf:"varint,5,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	Response             []byte   `protobuf:"bytes,6,opt,name=response,proto3" json:"response,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-func init() { proto.RegisterFile("pb.proto", fileDescriptor_f80abaa17e25ccc8) }
-
-var fileDescriptor_f80abaa17e25ccc8 = []byte{
-	// 4685 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xd4, 0x3a, 0x4d, 0x6f, 0x1c, 0x57,
-	0x72, 0x9a, 0xef, 0xe9, 0x9a, 0x19, 0x6a, 0xf4, 0xa4, 0x95, 0x67, 0x47, 0xb6, 0x48, 0xb7, 0xad,
-	0x35, 0x6d, 0x59, 0x94, 0x4c, 0x6f, 0x90, 0xb5, 0x17, 0x01, 0x42, 0x8a, 0x43, 0x99, 0x16, 0x45,
-	0xd2, 0xcd, 0xa1, 0xbc, 0xeb, 0x43, 0x06, 0x3d, 0xdd, 0x8f, 0x64, 0x2f, 0x7b, 0xba, 0x7b, 0xbb,
-	0x7b, 0xb8, 0x43, 0x9f, 0x12, 0x04, 0xc8, 0x29, 0x39, 0x05, 0x41, 0xf6, 0x94, 0x04, 0xf9, 0x03,
-	0x41, 0x72, 0x0a, 0x72, 0x0e, 0x82, 0x20, 0x87, 0x20, 0xbf, 0x40, 0x09, 0x9c, 0x9c, 0x04, 0xe4,
-	0x10, 0x04, 0xc8, 0x31, 0x08, 0xaa, 0xea, 0xf5, 0xd7, 0x70, 0x28, 0xd9, 0x0b, 0xec, 0x21, 0xa7,
-	0x7e, 0x55, 0xf5, 0x3e, 0xeb, 0xd5, 0xf7, 0x6b, 0x68, 0x06, 0xe3, 0xb5, 0x20, 0xf4, 0x63, 0x5f,
-	0x94, 0x83, 0x71, 0x5f, 0x33, 0x03, 0x87, 0xc1, 0xfe, 0x07, 0x27, 0x4e, 0x7c, 0x3a, 0x1d, 0xaf,
-	0x59, 0xfe, 0xe4, 0xa1, 0x7d, 0x12, 0x9a, 0xc1, 0xe9, 0x03, 0xc7, 0x7f, 0x38, 0x36, 0xed, 0x13,
-	0x19, 0x3e, 0x3c, 0x5f, 0x7f, 0x18, 0x8c, 0x1f, 0x26, 0x43, 0xfb, 0x0f, 0x72, 0x7d, 0x4f, 0xfc,
-	0x13, 0xff, 0x21, 0xa1, 0xc7, 0xd3, 0x63, 0x82, 0x08, 0xa0, 0x16, 0x77, 0xd7, 0xfb, 0x50, 0xdd,
-	0x75, 0xa2, 0x58, 0x08, 0xa8, 0x4e, 0x1d, 0x3b, 0xea, 0x95, 0x56, 0x2a, 0xab, 0x75, 0x83, 0xda,
-	0xfa, 0x33, 0xd0, 0x86, 0x66, 0x74, 0xf6, 0xdc, 0x74, 0xa7, 0x52, 0x74, 0xa1, 0x72, 0x6e, 0xba,
-	0xbd, 0xd2, 0x4a, 0x69, 0xb5, 0x6d, 0x60, 0x53, 0xac, 0x41, 0xf3, 0xdc, 0x74, 0x47, 0xf1, 0x45,
-	0x20, 0x7b, 0xe5, 0x95, 0xd2, 0xea, 0xd2, 0xfa, 0xcd, 0xb5, 0x60, 0xbc, 0x76, 0xe0, 0x47, 0xb1,
-	0xe3, 0x9d, 0xac, 0x3d, 0x37, 0xdd, 0xe1, 0x45, 0x20, 0x8d, 0xc6, 0x39, 0x37, 0xf4, 0x7d, 0x68,
-	0x1d, 0x86, 0xd6, 0xf6, 0xd4, 0xb3, 0x62, 0xc7, 0xf7, 0x70, 0x45, 0xcf, 0x9c, 0x48, 0x9a, 0x51,
-	0x33, 0xa8, 0x8d, 0x38, 0x33, 0x3c, 0x89, 0x7a, 0x95, 0x95, 0x0a, 0xe2, 0xb0, 0x2d, 0x7a, 0xd0,
-	0x70, 0xa2, 0xc7, 0xfe, 0xd4, 0x8b, 0x7b, 0xd5, 0x95, 0xd2, 0x6a, 0xd3, 0x48, 0x40, 0xfd, 0xcf,
-	0x2b, 0x50, 0xfb, 0x62, 0x2a, 0xc3, 0x0b, 0x1a, 0x17, 0xc7, 0x61, 0x32, 0x17, 0xb6, 0xc5, 0x2d,
-	0xa8, 0xb9, 0xa6, 0x77, 0x12, 0xf5, 0xca, 0x34, 0x19, 0x03, 0xe2, 0x0e, 0x68, 0xe6, 0x71, 0x2c,
-	0xc3, 0xd1, 0xd4, 0xb1, 0x7b, 0x95, 0x95, 0xd2, 0x6a, 0xdd, 0x68, 0x12, 0xe2, 0xc8, 0xb1, 0xc5,
-	0xf7, 0xa1, 0x69, 0xfb, 0x23, 0x2b, 0xbf, 0x96, 0xed, 0xd3, 0x5a, 0xe2, 0x1d, 0x68, 0x4e, 0x1d,
-	0x7b, 0xe4, 0x3a, 0x51, 0xdc, 0xab, 0xad, 0x94, 0x56, 0x5b, 0xeb, 0x4d, 0x3c, 0x2c, 0xf2, 0xce,
-	0x68, 0x4c, 0x1d, 0x9b, 0x98, 0xf8, 0x01, 0x34, 0xa3, 0xd0, 0x1a, 0x1d, 0x4f, 0x3d, 0xab, 0x57,
-	0xa7, 0x4e, 0xd7, 0xb1, 0x53, 0xee, 0xd4, 0x46, 0x23, 0x62, 0x00, 0x8f, 0x15, 0xca, 0x73, 0x19,
-	0x46, 0xb2, 0xd7, 0xe0, 0xa5, 0x14, 0x28, 0x1e, 0x41, 0xeb, 0xd8, 0xb4, 0x64, 0x3c, 0x0a, 0xcc,
-	0xd0, 0x9c, 0xf4, 0x9a, 0xd9, 0x44, 0xdb, 0x88, 0x3e, 0x40, 0x6c, 0x64, 0xc0, 0x71, 0x0a, 0x88,
-	0x8f, 0xa1, 0x43, 0x50, 0x34, 0x3a, 0x76, 0xdc, 0x58, 0x86, 0x3d, 0x8d, 0xc6, 0x2c, 0xd1, 0x18,
-	0xc2, 0x0c, 0x43, 0x29, 0x8d, 0x36, 0x77, 0x62, 0x8c, 0x78, 0x0b, 0x40, 0xce, 0x02, 0xd3, 0xb3,
-	0x47, 0xa6, 0xeb, 0xf6, 0x80, 0xf6, 0xa0, 0x31, 0x66, 0xc3, 0x75, 0xc5, 0x1b, 0xb8, 0x3f, 0xd3,
-	0x1e, 0xc5, 0x51, 0xaf, 0xb3, 0x52, 0x5a, 0xad, 0x1a, 0x75, 0x04, 0x87, 0x11, 0xf2, 0xd5, 0x32,
-	0xad, 0x53, 0xd9, 0x5b, 0x5a, 0x29, 0xad, 0xd6, 0x0c, 0x06, 0x10, 0x7b, 0xec, 0x84, 0x51, 0xdc,
-	0xbb, 0xce, 0x58, 0x02, 0xf4, 0x75, 0xd0, 0x48, 0x7a, 0x88, 0x3b, 0xf7, 0xa0, 0x7e, 0x8e, 0x00,
-	0x0b, 0x59, 0x6b, 0xbd, 0x83, 0xdb, 0x4b, 0x05, 0xcc, 0x50, 0x44, 0xfd, 0x2e, 0x34, 0x77, 0x4d,
-	0xef, 0x24, 0x91, 0x4a, 0xbc, 0x36, 0x1a, 0xa0, 0x19, 0xd4, 0xd6, 0x7f, 0x59, 0x86, 0xba, 0x21,
-	0xa3, 0xa9, 0x1b, 0x8b, 0xf7, 0x00, 0xf0, 0x52, 0x26, 0x66, 0x1c, 0x3a, 0x33, 0x35, 0x6b, 0x76,
-	0x2d, 0xda, 0xd4, 0xb1, 0x9f, 0x11, 0x49, 0x3c, 0x82, 0x36, 0xcd, 0x9e, 0x74, 0x2d, 0x67, 0x1b,
-	0x48, 0xf7, 0x67, 0xb4, 0xa8, 0x8b, 0x1a, 0x71, 0x1b, 0xea, 0x24, 0x07, 0x2c, 0x8b, 0x1d, 0x43,
-	0x41, 0xe2, 0x1e, 0x2c, 0x39, 0x5e, 0x8c, 0xf7, 0x64, 0xc5, 0x23, 0x5b, 0x46, 0x89, 0xa0, 0x74,
-	0x52, 0xec, 0x96, 0x8c, 0x62, 0xf1, 0x11, 0x30, 0xb3, 0x93, 0x05, 0x6b, 0xb4, 0xe0, 0x52, 0x7a,
-	0x89, 0x11, 0xaf, 0x48, 0x7d, 0xd4, 0x8a, 0x0f, 0xa0, 0x85, 0xe7, 0x4b, 0x46, 0xd4, 0x69, 0x44,
-	0x9b, 0x4e, 0xa3, 0xd8, 0x61, 0x00, 0x76, 0x50, 0xdd, 0x91, 0x35, 0x28, 0x8c, 0x2c, 0x3c, 0xd4,
-	0xd6, 0x07, 0x50, 0xdb, 0x0f, 0x6d, 0x19, 0x2e, 0xd4, 0x07, 0x01, 0x55, 0x5b, 0x46, 0x16, 0xa9,
-	0x6a, 0xd3, 0xa0, 0x76, 0xa6, 0x23, 0x95, 0x9c, 0x8e, 0xe8, 0x7f, 0x56, 0x82, 0xd6, 0xa1, 0x1f,
-	0xc6, 0xcf, 0x64, 0x14, 0x99, 0x27, 0x52, 0x2c, 0x43, 0xcd, 0xc7, 0x69, 0x15, 0x87, 0x35, 0xdc,
-	0x13, 0xad, 0x63, 0x30, 0x7e, 0xee, 0x1e, 0xca, 0x57, 0xdf, 0x03, 0xca, 0x0e, 0x69, 0x57, 0x45,
-	0xc9, 0x0e, 0xe9, 0xd6, 0x6d, 0xa8, 0xfb, 0xc7, 0xc7, 0x91, 0x64, 0x5e, 0xd6, 0x0c, 0x05, 0x5d,
-	0x29, 0x82, 0xfa, 0x6f, 0x00, 0xe0, 0xfe, 0xbe, 0xa3, 0x14, 0xe8, 0xa7, 0xd0, 0x32, 0xcc, 0xe3,
-	0xf8, 0xb1, 0xef, 0xc5, 0x72, 0x16, 0x8b, 0x25, 0x28, 0x3b, 0x36, 0xb1, 0xa8, 0x6e, 0x94, 0x1d,
-	0x1b, 0x37, 0x77, 0x12, 0xfa, 0xd3, 0x80, 0x38, 0xd4, 0x31, 0x18, 0x20, 0x56, 0xda, 0x76, 0x48,
-	0x3b, 0x46, 0x56, 0xda, 0x76, 0x28, 0x96, 0xa1, 0x15, 0x79, 0x66, 0x10, 0x9d, 0xfa, 0x31, 0x6e,
-	0xae, 0x4a, 0x9b, 0x83, 0x04, 0x35, 0x8c, 0xf4, 0xff, 0x2c, 0x43, 0xfd, 0x99, 0x9c, 0x8c, 0x65,
-	0x78, 0x69, 0x95, 0x47, 0xd0, 0xa4, 0x89, 0x47, 0x8e, 0xcd, 0x0b, 0x6d, 0x7e, 0xef, 0xe5, 0x8b,
-	0xe5, 0x1b, 0x84, 0xdb, 0xb1, 0x3f, 0xf4, 0x27, 0x4e, 0x2c, 0x27, 0x41, 0x7c, 0x61, 0x34, 0x14,
-	0x6a, 0xe1, 0x0e, 0x6e, 0x43, 0xdd, 0x95, 0x26, 0xde, 0x09, 0x8b, 0x9f, 0x82, 0xc4, 0x03, 0x68,
-	0x98, 0x93, 0x91, 0x2d, 0x4d, 0x9b, 0xac, 0x54, 0x73, 0xf3, 0xd6, 0xcb, 0x17, 0xcb, 0x5d, 0x73,
-	0xb2, 0x25, 0xcd, 0xfc, 0xdc, 0x75, 0xc6, 0x88, 0x4f, 0x50, 0xe6, 0xa2, 0x78, 0x34, 0x0d, 0x6c,
-	0x33, 0x96, 0x64, 0xb3, 0xaa, 0x9b, 0xbd, 0x97, 0x2f, 0x96, 0x6f, 0x21, 0xfa, 0x88, 0xb0, 0xb9,
-	0x61, 0x90, 0x61, 0xc5, 0x0e, 0xdc, 0xb0, 0xdc, 0x69, 0x84, 0xa6, 0xd4, 0xf1, 0x8e, 0xfd, 0x91,
-	0xef, 0xb9, 0x17, 0x74, 0x4d, 0xcd, 0xcd, 0xb7, 0x5e, 0xbe, 0x58, 0xfe, 0xbe, 0x22, 0xee, 0x78,
-	0xc7, 0xfe, 0xbe, 0xe7, 0x5e, 0xe4, 0x66, 0xb9, 0x3e, 0x47, 0x12, 0xbf, 0x0d, 0x4b, 0xc7, 0x7e,
-	0x68, 0xc9, 0x51, 0xca, 0x98, 0x25, 0x9a, 0xa7, 0xff, 0xf2, 0xc5, 0xf2, 0x6d, 0xa2, 0x3c, 0xb9,
-	0xc4, 0x9d, 0x76, 0x1e, 0xaf, 0xff, 0x6d, 0x19, 0x6a, 0xd4, 0x16, 0x8f, 0xa0, 0x31, 0x21, 0xc6,
-	0x27, 0x56, 0xe6, 0x36, 0x4a, 0x02, 0xd1, 0xd6, 0xf8, 0x46, 0xa2, 0x81, 0x17, 0x87, 0x17, 0x46,
-	0xd2, 0x0d, 0x47, 0xc4, 0xe6, 0xd8, 0x95, 0x71, 0xa4, 0x24, 0x37, 0x37, 0x62, 0xc8, 0x04, 0x35,
-	0x42, 0x75, 0x9b, 0xbf, 0xfe, 0xca, 0xfc, 0xf5, 0x8b, 0x3e, 0x34, 0xad, 0x53, 0x69, 0x9d, 0x45,
-	0xd3, 0x89, 0x12, 0x8e, 0x14, 0xee, 0x6f, 0x43, 0x3b, 0xbf, 0x0f, 0xf4, 0xab, 0x67, 0xf2, 0x82,
-	0x04, 0xa4, 0x6a, 0x60, 0x53, 0xac, 0x40, 0x8d, 0x2c, 0x11, 0x89, 0x47, 0x6b, 0x1d, 0x70, 0x3b,
-	0x3c, 0xc4, 0x60, 0xc2, 0xa7, 0xe5, 0x1f, 0x95, 0x70, 0x9e, 0xfc, 0xee, 0xf2, 0xf3, 0x68, 0x57,
-	0xcf, 0xc3, 0x43, 0x72, 0xf3, 0xe8, 0x3e, 0x34, 0x76, 0x1d, 0x4b, 0x7a, 0x11, 0x79, 0xdf, 0x69,
-	0x24, 0x53, 0xab, 0x81, 0x6d, 0x3c, 0xca, 0xc4, 0x9c, 0xed, 0xf9, 0xb6, 0x8c, 0x68, 0x9e, 0xaa,
-	0x91, 0xc2, 0x48, 0x93, 0xb3, 0xc0, 0x09, 0x2f, 0x86, 0xcc, 0x84, 0x8a, 0x91, 0xc2, 0xe8, 0xde,
-	0xa4, 0x87, 0x8b, 0xd9, 0x89, 0x27, 0x55, 0xa0, 0xfe, 0x17, 0x15, 0x68, 0x7f, 0x25, 0x43, 0xff,
-	0x20, 0xf4, 0x03, 0x3f, 0x32, 0x5d, 0xb1, 0x51, 0x64, 0x27, 0x5f, 0xdb, 0x0a, 0xee, 0x36, 0xdf,
-	0x6d, 0xed, 0x30, 0xe5, 0x2f, 0x5f, 0x47, 0x9e, 0xe1, 0x3a, 0xd4, 0xf9, 0x3a, 0x17, 0xf0, 0x4c,
-	0x51, 0xb0, 0x0f, 0x5f, 0x20, 0xed, 0xb5, 0xc8, 0x0f, 0x45, 0x11, 0x77, 0x01, 0x26, 0xe6, 0x6c,
-	0x57, 0x9a, 0x91, 0xdc, 0xb1, 0x13, 0xbd, 0xce, 0x30, 0x8a, 0x1b, 0xc3, 0x99, 0x37, 0x8c, 0x48,
-	0xbf, 0x98, 0x1b, 0x04, 0x8b, 0x37, 0x41, 0x9b, 0x98, 0x33, 0x34, 0x30, 0x3b, 0x36, 0x6b, 0x92,
-	0x91, 0x21, 0xc4, 0xdb, 0x50, 0x89, 0x67, 0x1e, 0x59, 0x6b, 0x74, 0xe6, 0x18, 0xdb, 0x0d, 0x67,
-	0x9e, 0x32, 0x45, 0x06, 0xd2, 0x92, 0x1b, 0x6c, 0x66, 0x37, 0xd8, 0x85, 0x8a, 0xe5, 0xd8, 0xe4,
-	0xcd, 0x35, 0x03, 0x9b, 0xe2, 0x1e, 0x34, 0x5c, 0xbe, 0x2d, 0xf2, 0xd8, 0xad, 0xf5, 0x16, 0x1b,
-	0x3a, 0x42, 0x19, 0x09, 0xad, 0xff, 0x5b, 0x70, 0x7d, 0x8e, 0x5d, 0x79, 0xf9, 0xe8, 0xf0, 0xec,
-	0xb7, 0xf2, 0xf2, 0x51, 0xcd, 0xcb, 0xc4, 0xbf, 0x56, 0xe0, 0xba, 0x12, 0xd2, 0x53, 0x27, 0x38,
-	0x8c, 0x51, 0xdf, 0x7b, 0xd0, 0x20, 0x6b, 0xad, 0xe4, 0xa3, 0x6a, 0x24, 0xa0, 0xf8, 0x4d, 0xa8,
-	0x93, 0xe2, 0x26, 0xfa, 0xb3, 0x9c, 0x31, 0x3f, 0x1d, 0xce, 0xfa, 0xa4, 0x6e, 0x4e, 0x75, 0x17,
-	0x3f, 0x84, 0xda, 0xd7, 0x32, 0xf4, 0xd9, 0xfb, 0xb4, 0xd6, 0xef, 0x2e, 0x1a, 0x87, 0x22, 0xa0,
-	0x86, 0x71, 0xe7, 0x5f, 0xe3, 0x1d, 0xbd, 0x8b, 0xfe, 0x66, 0xe2, 0x9f, 0x4b, 0xbb, 0xd7, 0xa0,
-	0x1d, 0xe5, 0xc5, 0x28, 0x21, 0x25, 0x97, 0xd2, 0x5c, 0x78, 0x29, 0xda, 0x2b, 0x2e, 0x65, 0x0b,
-	0x5a, 0x39, 0x2e, 0x2c, 0xb8, 0x90, 0xe5, 0xa2, 0xc2, 0x6a, 0xa9, 0x1d, 0xca, 0xeb, 0xfd, 0x16,
-	0x40, 0xc6, 0x93, 0x5f, 0xd5, 0x7a, 0xe8, 0xbf, 0x57, 0x82, 0xeb, 0x8f, 0x7d, 0xcf, 0x93, 0x14,
-	0x95, 0xf2, 0x0d, 0x67, 0x4a, 0x54, 0xba, 0x52, 0x89, 0xde, 0x87, 0x5a, 0x84, 0x9d, 0xd5, 0xec,
-	0x37, 0x17, 0x5c, 0x99, 0xc1, 0x3d, 0xd0, 0x4a, 0x4e, 0xcc, 0xd9, 0x28, 0x90, 0x9e, 0xed, 0x78,
-	0x27, 0x89, 0x95, 0x9c, 0x98, 0xb3, 0x03, 0xc6, 0xe8, 0x7f, 0x52, 0x06, 0xf8, 0x4c, 0x9a, 0x6e,
-	0x7c, 0x8a, 0x9e, 0x00, 0xef, 0xcd, 0xf1, 0xa2, 0xd8, 0xf4, 0xac, 0x24, 0x27, 0x48, 0x61, 0x14,
-	0x3e, 0x74, 0x7b, 0x32, 0x62, 0x23, 0xa4, 0x19, 0x09, 0x88, 0x8e, 0x10, 0x97, 0x9b, 0x46, 0xca,
-	0x3d, 0x2a, 0x28, 0x73, 0xe6, 0x55, 0x42, 0x2b, 0x67, 0xde, 0x83, 0x06, 0xc6, 0xd8, 0x8e, 0xef,
-	0x91, 0x68, 0x68, 0x46, 0x02, 0xe2, 0x3c, 0xd3, 0x20, 0x76, 0x26, 0xec, 0x04, 0x2b, 0x86, 0x82,
-	0x70, 0x57, 0xe8, 0xf4, 0x06, 0xd6, 0xa9, 0x4f, 0xca, 0x5b, 0x31, 0x52, 0x18, 0x67, 0xf3, 0xbd,
-	0x13, 0x1f, 0x4f, 0xd7, 0xa4, 0xf8, 0x29, 0x01, 0xf9, 0x2c, 0xb6, 0x9c, 0x21, 0x49, 0x23, 0x52,
-	0x0a, 0x23, 0x5f, 0xa4, 0x1c, 0x1d, 0x4b, 0x33, 0x9e, 0x86, 0x32, 0xea, 0x01, 0x91, 0x41, 0xca,
-	0x6d, 0x85, 0xd1, 0x7f, 0xb7, 0x0c, 0x75, 0xb6, 0x4b, 0x85, 0x60, 0xa1, 0xf4, 0xad, 0x82, 0x85,
-	0x37, 0x41, 0x0b, 0x42, 0x69, 0x3b, 0x56, 0x72, 0x49, 0x9a, 0x91, 0x21, 0x28, 0x4a, 0x47, 0xbf,
-	0x49, 0xcc, 0x6a, 0x1a, 0x0c, 0x20, 0x36, 0x0a, 0x4c, 0x4b, 0xaa, 0x03, 0x32, 0x80, 0x1c, 0x61,
-	0x91, 0x27, 0x51, 0x6f, 0x1a, 0x0a, 0x12, 0x1f, 0x83, 0x46, 0x51, 0x19, 0x39, 0x7c, 0x8d, 0x1c,
-	0xf5, 0xed, 0x97, 0x2f, 0x96, 0x05, 0x22, 0xe7, 0x3c, 0x7d, 0x33, 0xc1, 0x61, 0x5c, 0x82, 0x83,
-	0xd1, 0xbe, 0x03, 0x05, 0x19, 0x14, 0x97, 0x20, 0x6a, 0x18, 0xe5, 0xe3, 0x12, 0xc6, 0xe8, 0xff,
-	0x5c, 0x86, 0xf6, 0x96, 0x13, 0x4a, 0x2b, 0x96, 0xf6, 0xc0, 0x3e, 0xa1, 0xcd, 0x48, 0x2f, 0x76,
-	0xe2, 0x0b, 0x15, 0x49, 0x29, 0x28, 0x0d, 0x74, 0xcb, 0xc5, 0xc4, 0x8f, 0x35, 0xa0, 0x42, 0xb9,
-	0x2a, 0x03, 0x62, 0x1d, 0x80, 0x53, 0x00, 0xca, 0x57, 0xab, 0x57, 0xe7, 0xab, 0x1a, 0x75, 0xc3,
-	0x26, 0xe6, 0x83, 0x3c, 0xc6, 0xe1, 0x70, 0xaa, 0x4e, 0xc9, 0xec, 0x14, 0xad, 0x0c, 0x45, 0xce,
-	0x63, 0xe9, 0x92, 0xb8, 0x50, 0xe4, 0x3c, 0x96, 0x6e, 0x9a, 0xaf, 0x34, 0x78, 0x3b, 0xd8, 0x16,
-	0xef, 0x40, 0xd9, 0x0f, 0x88, 0x87, 0x6a, 0xc1, 0xfc, 0xc1, 0xd6, 0xf6, 0x03, 0xa3, 0xec, 0x07,
-	0xa8, 0x7b, 0x9c, 0x9c, 0x91, 0xb8, 0xa0, 0xee, 0xa1, 0x87, 0xa0, 0x54, 0xc1, 0x50, 0x14, 0xa1,
-	0x43, 0xdb, 0x74, 0x5d, 0xff, 0x17, 0xd2, 0x3e, 0x08, 0xa5, 0x9d, 0x48, 0x4e, 0x01, 0xa7, 0xdf,
-	0x86, 0xf2, 0x7e, 0x20, 0x1a, 0x50, 0x39, 0x1c, 0x0c, 0xbb, 0xd7, 0xb0, 0xb1, 0x35, 0xd8, 0xed,
-	0x96, 0xf4, 0x6f, 0xca, 0xa0, 0x3d, 0x9b, 0xc6, 0x26, 0x6a, 0x7b, 0x84, 0xe7, 0x2a, 0x8a, 0x55,
-	0x26, 0x3f, 0xdf, 0x87, 0x66, 0x14, 0x9b, 0x21, 0x79, 0x62, 0xf6, 0x0b, 0x0d, 0x82, 0x87, 0x91,
-	0xf8, 0x01, 0xd4, 0xa4, 0x7d, 0x22, 0x13, 0x73, 0xdd, 0x9d, 0x3f, 0x8b, 0xc1, 0x64, 0xb1, 0x0a,
-	0xf5, 0xc8, 0x3a, 0x95, 0x13, 0xb3, 0x57, 0xcd, 0x3a, 0x1e, 0x12, 0x86, 0x63, 0x47, 0x43, 0xd1,
-	0xc5, 0xbb, 0x50, 0xc3, 0xdb, 0x88, 0x54, 0xb2, 0x43, 0xe9, 0x11, 0x32, 0x5e, 0x75, 0x63, 0x22,
-	0xca, 0x8e, 0x1d, 0xfa, 0xc1, 0xc8, 0x0f, 0x88, 0xaf, 0x4b, 0xeb, 0xb7, 0xc8, 0xea, 0x24, 0xa7,
-	0x59, 0xdb, 0x0a, 0xfd, 0x60, 0x3f, 0x30, 0xea, 0x36, 0x7d, 0x31, 0xaf, 0xa5, 0xee, 0x2c, 0x03,
-	0x6c, 0xa6, 0x35, 0xc4, 0x70, 0x1d, 0x63, 0x15, 0x9a, 0x13, 0x19, 0x9b, 0xb6, 0x19, 0x9b, 0xca,
-	0x5a, 0xb7, 0xd9, 0x88, 0x31, 0xce, 0x48, 0xa9, 0xfa, 0x43, 0xa8, 0xf3, 0xd4, 0xa2, 0x09, 0xd5,
-	0xbd, 0xfd, 0xbd, 0x01, 0x33, 0x74, 0x63, 0x77, 0xb7, 0x5b, 0x42, 0xd4, 0xd6, 0xc6, 0x70, 0xa3,
-	0x5b, 0xc6, 0xd6, 0xf0, 0xa7, 0x07, 0x83, 0x6e, 0x45, 0xff, 0xa7, 0x12, 0x34, 0x93, 0x79, 0xc4,
-	0xa7, 0x00, 0xa8, 0x77, 0xa3, 0x53, 0xc7, 0x4b, 0x83, 0x9a, 0x3b, 0xf9, 0x95, 0xd6, 0xf0, 0xc6,
-	0x3e, 0x43, 0x2a, 0xbb, 0x37, 0x52, 0x53, 0x82, 0xfb, 0x87, 0xb0, 0x54, 0x24, 0x2e, 0x88, 0xee,
-	0xee, 0xe7, 0xed, 0xfc, 0xd2, 0xfa, 0xf7, 0x0a, 0x53, 0xe3, 0x48, 0x12, 0xe6, 0x9c, 0xc9, 0x7f,
-	0x00, 0xcd, 0x04, 0x2d, 0x5a, 0xd0, 0xd8, 0x1a, 0x6c, 0x6f, 0x1c, 0xed, 0xa2, 0x90, 0x00, 0xd4,
-	0x0f, 0x77, 0xf6, 0x9e, 0xec, 0x0e, 0xf8, 0x58, 0xbb, 0x3b, 0x87, 0xc3, 0x6e, 0x59, 0xff, 0xe3,
-	0x12, 0x34, 0x93, 0x18, 0x42, 0xbc, 0x8f, 0xce, 0x9f, 0x42, 0x15, 0xe5, 0x1b, 0xa8, 0x1c, 0x91,
-	0x4b, 0xa6, 0x8c, 0x84, 0x8e, 0x8a, 0x41, 0xa6, 0x2e, 0x89, 0x2a, 0x08, 0xc8, 0xa7, 0x72, 0x95,
-	0x42, 0x35, 0x01, 0xb3, 0x52, 0xdf, 0x93, 0x2a, 0x48, 0xa4, 0x36, 0xc9, 0xa0, 0xe3, 0x59, 0x64,
-	0x2d, 0x6a, 0x4a, 0x06, 0x11, 0x1e, 0x46, 0xfa, 0x5f, 0x57, 0x61, 0xc9, 0x90, 0x51, 0xec, 0x87,
-	0xd2, 0x90, 0x3f, 0x9f, 0x62, 0xaa, 0xfd, 0x0a, 0x61, 0x7e, 0x0b, 0x20, 0xe4, 0xce, 0x99, 0x38,
-	0x6b, 0x0a, 0xc3, 0x61, 0xba, 0xeb, 0x5b, 0x24, 0x45, 0xca, 0x7b, 0xa4, 0xb0, 0xb8, 0x03, 0xda,
-	0xd8, 0xb4, 0xce, 0x78, 0x5a, 0xf6, 0x21, 0x4d, 0x46, 0xf0, 0xbc, 0xa6, 0x65, 0xc9, 0x28, 0x1a,
-	0xe1, 0xa5, 0xb0, 0x27, 0xd1, 0x18, 0xf3, 0x54, 0x5e, 0x20, 0x39, 0x92, 0x56, 0x28, 0x63, 0x22,
-	0xb3, 0x81, 0xd0, 0x18, 0x83, 0xe4, 0x77, 0xa0, 0x13, 0xc9, 0x08, 0xbd, 0xce, 0x28, 0xf6, 0xcf,
-	0xa4, 0xa7, 0xac, 0x45, 0x5b, 0x21, 0x87, 0x88, 0x43, 0x3b, 0x6e, 0x7a, 0xbe, 0x77, 0x31, 0xf1,
-	0xa7, 0x91, 0x32, 0xc0, 0x19, 0x42, 0xac, 0xc1, 0x4d, 0xe9, 0x59, 0xe1, 0x45, 0x80, 0x7b, 0xc5,
-	0x55, 0x46, 0xc7, 0x8e, 0x2b, 0x55, 0xa0, 0x78, 0x23, 0x23, 0x3d, 0x95, 0x17, 0xdb, 0x8e, 0x2b,
-	0x71, 0x47, 0xe7, 0xe6, 0xd4, 0x8d, 0x47, 0x94, 0x48, 0x02, 0xef, 0x88, 0x30, 0x1b, 0x98, 0x4d,
-	0x7e, 0x00, 0x37, 0x98, 0x1c, 0xfa, 0xae, 0x74, 0x6c, 0x9e, 0xac, 0x45, 0xbd, 0xae, 0x13, 0xc1,
-	0x20, 0x3c, 0x4d, 0xb5, 0x06, 0x37, 0xb9, 0x2f, 0x1f, 0x28, 0xe9, 0xdd, 0xe6, 0xa5, 0x89, 0x74,
-	0xa8, 0x28, 0xc5, 0xa5, 0x03, 0x33, 0x3e, 0xa5, 0x04, 0x31, 0x59, 0xfa, 0xc0, 0x8c, 0x4f, 0xd1,
-	0x1b, 0x32, 0xf9, 0xd8, 0x91, 0x2e, 0x27, 0x7e, 0x9a, 0xc1, 0x23, 0xb6, 0x11, 0x23, 0xde, 0x86,
-	0xb6, 0xea, 0xe0, 0x87, 0x13, 0x93, 0xeb, 0x4b, 0x9a, 0xc1, 0x83, 0xb6, 0x09, 0x85, 0x4b, 0xa8,
-	0xbb, 0xf2, 0xa6, 0x93, 0x5e, 0x97, 0xaf, 0x99, 0x31, 0x7b, 0xd3, 0x89, 0xfe, 0xbf, 0x65, 0x68,
-	0xa6, 0xc9, 0xc6, 0x7d, 0xd0, 0x26, 0x89, 0xe5, 0x50, 0x41, 0x4c, 0xa7, 0x60, 0x4e, 0x8c, 0x8c,
-	0x2e, 0xde, 0x82, 0xf2, 0xd9, 0xb9, 0xb2, 0x62, 0x9d, 0x35, 0xae, 0xb7, 0x06, 0xe3, 0xf5, 0xb5,
-	0xa7, 0xcf, 0x8d, 0xf2, 0xd9, 0x79, 0x16, 0x0c, 0xd5, 0x5e, 0x1b, 0x0c, 0xbd, 0x07, 0xd7, 0x2d,
-	0x57, 0x9a, 0xde, 0x28, 0x73, 0xce, 0x2c, 0x17, 0x4b, 0x84, 0x3e, 0x48, 0x3d, 0xb4, 0x52, 0xf4,
-	0x46, 0xa6, 0xe8, 0xf7, 0xa0, 0x66, 0x4b, 0x37, 0x36, 0xf3, 0x85, 0xc0, 0xfd, 0xd0, 0xb4, 0x5c,
-	0xb9, 0x85, 0x68, 0x83, 0xa9, 0x68, 0xd7, 0x92, 0x84, 0x28, 0x6f, 0xd7, 0x12, 0x15, 0x36, 0x52,
-	0x6a, 0xa6, 0xa1, 0x90, 0xd7, 0xd0, 0xfb, 0x70, 0x43, 0xce, 0x02, 0x32, 0xe6, 0xa3, 0x34, 0x79,
-	0x6d, 0x51, 0x8f, 0x6e, 0x42, 0x78, 0xac, 0xf0, 0xe2, 0x43, 0x54, 0x67, 0x52, 0x23, 0xba, 0xf8,
-	0xd6, 0xba, 0x20, 0x7b, 0x50, 0x50, 0x4c, 0x23, 0xe9, 0xa2, 0x7b, 0x50, 0x79, 0xfa, 0xfc, 0x50,
-	0x71, 0xb3, 0x74, 0x15, 0x37, 0x13, 0x4b, 0x50, 0xce, 0x59, 0x82, 0xbb, 0x6c, 0x44, 0x89, 0x35,
-	0x49, 0x91, 0x2a, 0x87, 0xc1, 0xa3, 0xb0, 0x03, 0xa9, 0x72, 0xfd, 0x8a, 0x00, 0xfd, 0x7f, 0x2a,
-	0xd0, 0x50, 0x5e, 0x1d, 0xf9, 0x39, 0x4d, 0xeb, 0x2f, 0xd8, 0x2c, 0xa6, 0x3d, 0x69, 0x78, 0x90,
-	0x2f, 0x66, 0x57, 0x5e, 0x5f, 0xcc, 0x16, 0x9f, 0x42, 0x3b, 0x60, 0x5a, 0x3e, 0xa0, 0x78, 0x23,
-	0x3f, 0x46, 0x7d, 0x69, 0x5c, 0x2b, 0xc8, 0x00, 0xb4, 0x58, 0x54, 0xe9, 0x8b, 0xcd, 0x13, 0x12,
-	0x9d, 0xb6, 0xd1, 0x40, 0x78, 0x68, 0x9e, 0x5c, 0x11, 0x56, 0x7c, 0x9b, 0xe8, 0x60, 0x89, 0xc2,
-	0x8c, 0x36, 0x19, 0x40, 0x8c, 0x28, 0xf2, 0x8e, 0xbc, 0x53, 0x74, 0xe4, 0x77, 0x40, 0xb3, 0xfc,
-	0xc9, 0xc4, 0x21, 0xda, 0x92, 0xaa, 0x4f, 0x10, 0x62, 0x18, 0xe9, 0x7f, 0x50, 0x82, 0x86, 0x3a,
-	0xed, 0x25, 0x37, 0xb1, 0xb9, 0xb3, 0xb7, 0x61, 0xfc, 0xb4, 0x5b, 0x42, 0x37, 0xb8, 0xb3, 0x37,
-	0xec, 0x96, 0x85, 0x06, 0xb5, 0xed, 0xdd, 0xfd, 0x8d, 0x61, 0xb7, 0x82, 0xae, 0x63, 0x73, 0x7f,
-	0x7f, 0xb7, 0x5b, 0x15, 0x6d, 0x68, 0x6e, 0x6d, 0x0c, 0x07, 0xc3, 0x9d, 0x67, 0x83, 0x6e, 0x0d,
-	0xfb, 0x3e, 0x19, 0xec, 0x77, 0xeb, 0xd8, 0x38, 0xda, 0xd9, 0xea, 0x36, 0x90, 0x7e, 0xb0, 0x71,
-	0x78, 0xf8, 0xe5, 0xbe, 0xb1, 0xd5, 0x6d, 0x92, 0xfb, 0x19, 0x1a, 0x3b, 0x7b, 0x4f, 0xba, 0x1a,
-	0xb6, 0xf7, 0x37, 0x3f, 0x1f, 0x3c, 0x1e, 0x76, 0x41, 0xff, 0x08, 0x5a, 0x39, 0x0e, 0xe2, 0x68,
-	0x63, 0xb0, 0xdd, 0xbd, 0x86, 0x4b, 0x3e, 0xdf, 0xd8, 0x3d, 0x42, 0x6f, 0xb5, 0x04, 0x40, 0xcd,
-	0xd1, 0xee, 0xc6, 0xde, 0x93, 0x6e, 0x59, 0xff, 0x02, 0x9a, 0x47, 0x8e, 0xbd, 0xe9, 0xfa, 0xd6,
-	0x19, 0x8a, 0xd3, 0xd8, 0x8c, 0xa4, 0x4a, 0x8d, 0xa8, 0x8d, 0x51, 0x24, 0x29, 0x4b, 0xa4, 0xee,
-	0x5e, 0x41, 0xc8, 0x2b, 0x6f, 0x3a, 0x19, 0xd1, 0x03, 0x48, 0x85, 0x5d, 0x88, 0x37, 0x9d, 0x1c,
-	0x39, 0x76, 0xa4, 0x9f, 0x41, 0xe3, 0xc8, 0xb1, 0x0f, 0x4c, 0xeb, 0x8c, 0xcc, 0x0c, 0x4e, 0x3d,
-	0x8a, 0x9c, 0xaf, 0xa5, 0x72, 0x35, 0x1a, 0x61, 0x0e, 0x9d, 0xaf, 0xa5, 0x78, 0x17, 0xea, 0x04,
-	0x24, 0x69, 0x30, 0xa9, 0x5f, 0xb2, 0x1d, 0x43, 0xd1, 0xe8, 0xfd, 0xc1, 0x75, 0x7d, 0x6b, 0x14,
-	0xca, 0xe3, 0xde, 0x1b, 0xcc, 0x7b, 0x42, 0x18, 0xf2, 0x58, 0xff, 0xc3, 0x52, 0x7a, 0x66, 0x2a,
-	0x7f, 0x2f, 0x43, 0x35, 0x30, 0xad, 0x33, 0xe5, 0x73, 0x5b, 0x6a, 0x42, 0xdc, 0x8c, 0x41, 0x04,
-	0xf1, 0x1e, 0x34, 0x95, 0x60, 0x25, 0xab, 0xb6, 0x72, 0x12, 0x68, 0xa4, 0xc4, 0xe2, 0x95, 0x57,
-	0x8a, 0x57, 0x4e, 0x39, 0x54, 0xe0, 0x3a, 0x31, 0xab, 0x51, 0xd5, 0x50, 0x90, 0xfe, 0x43, 0x80,
-	0xec, 0xc5, 0x61, 0x41, 0x08, 0x72, 0x0b, 0x6a, 0xa6, 0xeb, 0x98, 0x49, 0x4e, 0xc6, 0x80, 0xbe,
-	0x07, 0xad, 0xdc, 0x3b, 0x05, 0xf2, 0xd6, 0x74, 0x5d, 0xf4, 0x51, 0x11, 0x8d, 0x6d, 0x1a, 0x0d,
-	0xd3, 0x75, 0x9f, 0xca, 0x8b, 0x08, 0xc3, 0x3f, 0x7e, 0xe2, 0x28, 0xcf, 0x55, 0xc7, 0x69, 0xa8,
-	0xc1, 0x44, 0xfd, 0x43, 0xa8, 0x6f, 0x27, 0x01, 0x70, 0xa2, 0x06, 0xa5, 0xab, 0xd4, 0x40, 0xff,
-	0x44, 0xed, 0x99, 0x0a, 0xec, 0xe2, 0xbe, 0x7a, 0x4a, 0x89, 0xf8, 0xe1, 0xa6, 0x94, 0x65, 0xf5,
-	0xdc, 0x49, 0xbd, 0xa2, 0x50, 0x67, 0x7d, 0x0b, 0x9a, 0xaf, 0x7c, 0x9c, 0x52, 0x0c, 0x28, 0x67,
-	0x0c, 0x58, 0xf0, 0x5c, 0xa5, 0xff, 0x0c, 0x20, 0x7b, 0x72, 0x51, 0x5a, 0xc9, 0xb3, 0xa0, 0x56,
-	0x7e, 0x00, 0x4d, 0xeb, 0xd4, 0x71, 0xed, 0x50, 0x7a, 0x85, 0x53, 0x67, 0x8f, 0x34, 0x29, 0x5d,
-	0xac, 0x40, 0x95, 0x5e, 0x92, 0x2a, 0x99, 0x35, 0x4f, 0x9f, 0x91, 0x88, 0xa2, 0xcf, 0xa0, 0xc3,
-	0x71, 0xf5, 0xb7, 0x88, 0x85, 0x8a, 0xa6, 0xb4, 0x7c, 0xc9, 0x94, 0xde, 0x86, 0x3a, 0xb9, 0xe0,
-	0xe4, 0x34, 0x0a, 0xba, 0xc2, 0xc4, 0xfe, 0x7e, 0x19, 0x80, 0x97, 0xde, 0xf3, 0x6d, 0x59, 0xcc,
-	0x3a, 0x4b, 0xf3, 0x59, 0xa7, 0x80, 0x6a, 0xfa, 0x48, 0xa8, 0x19, 0xd4, 0xce, 0x9c, 0x90, 0xca,
-	0x44, 0xd9, 0x09, 0xbd, 0x09, 0x1a, 0x85, 0x44, 0xce, 0xd7, 0x54, 0xd9, 0xc6, 0x05, 0x33, 0x44,
-	0xfe, 0xc9, 0xac, 0x56, 0x7c, 0x32, 0x4b, 0xdf, 0x15, 0xea, 0x3c, 0x1b, 0xbf, 0x2b, 0x2c, 0x78,
-	0x22, 0xe1, 0x3c, 0x3f, 0x92, 0x61, 0x9c, 0x64, 0xb5, 0x0c, 0xa5, 0x99, 0x9b, 0xa6, 0xfa, 0x9a,
-	0x9c, 0xa9, 0x7b, 0xfe, 0xc8, 0xf2, 0xbd, 0x63, 0xd7, 0xb1, 0x62, 0xf5, 0x44, 0x06, 0x9e, 0xff,
-	0x58, 0x61, 0xf4, 0x4f, 0xa1, 0x9d, 0xf0, 0x9f, 0x5e, 0x22, 0x3e, 0x48, 0x33, 0x9f, 0x52, 0x76,
-	0xb7, 0x19, 0x9b, 0x36, 0xcb, 0xbd, 0x52, 0x92, 0xfb, 0xe8, 0xff, 0x5d, 0x49, 0x06, 0xab, 0x82,
-	0xfa, 0xab, 0x79, 0x58, 0x4c, 0x5f, 0xcb, 0xdf, 0x2a, 0x7d, 0xfd, 0x11, 0x68, 0x36, 0xe5, 0x67,
-	0xce, 0x79, 0xe2, 0xd4, 0xfa, 0xf3, 0xb9, 0x98, 0xca, 0xe0, 0x9c, 0x73, 0x69, 0x64, 0x9d, 0x5f,
-	0x73, 0x0f, 0x29, 0xb7, 0x6b, 0x8b, 0xb8, 0x5d, 0xff, 0x15, 0xb9, 0xfd, 0x36, 0xb4, 0x3d, 0xdf,
-	0x1b, 0x79, 0x53, 0xd7, 0x35, 0xc7, 0xae, 0x54, 0xec, 0x6e, 0x79, 0xbe, 0xb7, 0xa7, 0x50, 0x18,
-	0xa7, 0xe6, 0xbb, 0xb0, 0x52, 0xb7, 0xa8, 0xdf, 0xf5, 0x5c, 0x3f, 0x52, 0xfd, 0x55, 0xe8, 0xfa,
-	0xe3, 0x9f, 0x49, 0x2b, 0x26, 0x8e, 0x8d, 0x48, 0x9b, 0x39, 0x48, 0x5d, 0x62, 0x3c, 0xb2, 0x68,
-	0x0f, 0xf5, 0x7a, 0xee, 0x9a, 0x3b, 0x97, 0xae, 0xf9, 0x13, 0xd0, 0x52, 0x2e, 0xe5, 0x72, 0x41,
-	0x0d, 0x6a, 0x3b, 0x7b, 0x5b, 0x83, 0x9f, 0x74, 0x4b, 0xe8, 0x28, 0x8d, 0xc1, 0xf3, 0x81, 0x71,
-	0x38, 0xe8, 0x96, 0xd1, 0x89, 0x6d, 0x0d, 0x76, 0x07, 0xc3, 0x41, 0xb7, 0xf2, 0x79, 0xb5, 0xd9,
-	0xe8, 0x36, 0xa9, 0x2c, 0xee, 0x3a, 0x96, 0x13, 0xeb, 0x87, 0x00, 0x59, 0x82, 0x8b, 0x56, 0x39,
-	0xdb, 0x9c, 0xaa, 0x79, 0xc5, 0xc9, 0xb6, 0x56, 0x53, 0x85, 0x2c, 0x5f, 0x95, 0x46, 0x33, 0x5d,
-	0x5f, 0x07, 0xed, 0x99, 0x19, 0x7c, 0xc6, 0x2f, 0x40, 0xf7, 0x60, 0x29, 0x30, 0xc3, 0xd8, 0x49,
-	0x32, 0x03, 0x36, 0x96, 0x6d, 0xa3, 0x93, 0x62, 0xd1, 0xf6, 0xea, 0x7f, 0x53, 0x82, 0x5b, 0xcf,
-	0xfc, 0x73, 0x99, 0x46, 0x9e, 0x07, 0xe6, 0x85, 0xeb, 0x9b, 0xf6, 0x6b, 0xc4, 0x10, 0x53, 0x1b,
-	0x7f, 0x4a, 0x6f, 0x35, 0xc9, 0xfb, 0x95, 0xa1, 0x31, 0xe6, 0x89, 0x7a, 0x40, 0x97, 0x51, 0x4c,
-	0x44, 0xe5, 0x48, 0x11, 0x46, 0xd2, 0xf7, 0xa0, 0x1e, 0xcf, 0xbc, 0xec, 0xb9, 0xac, 0x16, 0x53,
-	0x45, 0x76, 0x61, 0xd8, 0x59, 0x5b, 0x1c, 0x76, 0xea, 0x8f, 0x41, 0x1b, 0xce, 0xa8, 0x5a, 0x39,
-	0x8d, 0x0a, 0x01, 0x4e, 0xe9, 0x15, 0x01, 0x4e, 0x79, 0x2e, 0xc0, 0xf9, 0x8f, 0x12, 0xb4, 0x72,
-	0xf1, 0xb3, 0x78, 0x1b, 0xaa, 0xf1, 0xcc, 0x2b, 0x3e, 0x4a, 0x27, 0x8b, 0x18, 0x44, 0x42, 0xd1,
-	0x9c, 0x98, 0xb3, 0x91, 0x19, 0x45, 0xce, 0x89, 0x27, 0x6d, 0x35, 0x65, 0x6b, 0x62, 0xce, 0x36,
-	0x14, 0x4a, 0xec, 0xc2, 0x75, 0xb6, 0xbc, 0xc9, 0x21, 0x92, 0x32, 0xc9, 0x3b, 0x73, 0xf1, 0x3a,
-	0x57, 0x74, 0x93, 0x23, 0xa9, 0xdc, 0x7f, 0xe9, 0xa4, 0x80, 0xec, 0x6f, 0xc0, 0xcd, 0x05, 0xdd,
-	0xbe, 0x53, 0x0d, 0x7f, 0x19, 0x3a, 0xc3, 0x99, 0x37, 0x74, 0x26, 0x32, 0x8a, 0xcd, 0x49, 0x40,
-	0x01, 0xa2, 0xf2, 0x9c, 0x55, 0xa3, 0x1c, 0x47, 0xfa, 0x0f, 0xa0, 0x7d, 0x20, 0x65, 0x68, 0xc8,
-	0x28, 0xf0, 0x3d, 0x0e, 0x8e, 0x54, 0x25, 0x95, 0xdd, 0xb4, 0x82, 0xf4, 0xdf, 0x01, 0x0d, 0x13,
-	0xfd, 0x4d, 0x33, 0xb6, 0x4e, 0xbf, 0x4b, 0x21, 0xe0, 0x07, 0xd0, 0x08, 0x58, 0xa6, 0x54, 0x9e,
-	0xd5, 0x26, 0x77, 0xad, 0xe4, 0xcc, 0x48, 0x88, 0xfa, 0x47, 0x70, 0xf3, 0x70, 0x3a, 0x8e, 0xac,
-	0xd0, 0xa1, 0x94, 0x35, 0x71, 0x65, 0x7d, 0x68, 0x06, 0xa1, 0x3c, 0x76, 0x66, 0x32, 0x91, 0xe0,
-	0x14, 0xd6, 0x7f, 0x0c, 0xb7, 0x8a, 0x43, 0xd4, 0x11, 0xde, 0x81, 0xca, 0xd9, 0x79, 0xa4, 0x76,
-	0x76, 0xa3, 0x90, 0x62, 0xd0, 0x5b, 0x30, 0x52, 0x75, 0x03, 0x2a, 0x7b, 0xd3, 0x49, 0xfe, 0x7f,
-	0x96, 0x2a, 0xff, 0xcf, 0x72, 0x27, 0x5f, 0xd8, 0xe4, 0x2c, 0x24, 0x2b, 0x60, 0xbe, 0x09, 0xda,
-	0xb1, 0x1f, 0xfe, 0xc2, 0x0c, 0x6d, 0x69, 0x2b, 0x9f, 0x95, 0x21, 0xf4, 0xaf, 0xa0, 0x95, 0x48,
-	0xc2, 0x8e, 0x4d, 0x8f, 0x5f, 0x24, 0x8a, 0x3b, 0x76, 0x41, 0x32, 0xb9, 0x6c, 0x28, 0x3d, 0x7b,
-	0x27, 0x11, 0x21, 0x06, 0x8a, 0x2b, 0xab, 0x37, 0x8b, 0x64, 0x65, 0x7d, 0x1b, 0xda, 0x49, 0x12,
-	0xf7, 0x4c, 0xc6, 0x26, 0x09, 0xb7, 0xeb, 0x48, 0x2f, 0x27, 0xf8, 0x4d, 0x46, 0x0c, 0x8b, 0x95,
-	0xbd, 0x72, 0x21, 0x00, 0xd0, 0xd7, 0xa0, 0xae, 0x34, 0x47, 0x40, 0xd5, 0xf2, 0x6d, 0xd6, 0xee,
-	0x9a, 0x41, 0x6d, 0x64, 0xc7, 0x24, 0x3a, 0x49, 0x82, 0x9b, 0x49, 0x74, 0xa2, 0xff, 0x5d, 0x19,
-	0x3a, 0x9b, 0x94, 0x44, 0x27, 0x57, 0x92, 0x2b, 0xe2, 0x94, 0x0a, 0x45, 0x9c, 0x7c, 0xc1, 0xa6,
-	0x5c, 0x28, 0xd8, 0x14, 0x36, 0x54, 0x29, 0x46, 0x24, 0x6f, 0x40, 0x63, 0xea, 0x39, 0xb3, 0xc4,
-	0x24, 0x68, 0x46, 0x1d, 0xc1, 0x61, 0x24, 0x56, 0xa0, 0x85, 0x56, 0xc3, 0xf1, 0xb8, 0x34, 0xc3,
-	0xf5, 0x95, 0x3c, 0x6a, 0xae, 0x00, 0x53, 0x7f, 0x75, 0x01, 0xa6, 0xf1, 0xda, 0x02, 0x4c, 0xf3,
-	0x75, 0x05, 0x18, 0x6d, 0xbe, 0x00, 0x53, 0x8c, 0xa6, 0x60, 0x3e, 0x9a, 0xd2, 0xff, 0xb4, 0x0c,
-	0x9d, 0xc1, 0x2c, 0xa0, 0x9f, 0x14, 0x5e, 0x1b, 0x9a, 0xe5, 0xf8, 0x5a, 0x2e, 0xf0, 0x35, 0xc7,
-	0xa1, 0x8a, 0x7a, 0x95, 0x60, 0x0e, 0x61, 0xb0, 0xc6, 0xe5, 0x10, 0xc5, 0x39, 0x86, 0xfe, 0x1f,
-	0x70, 0x4e, 0xdf, 0x85, 0xa5, 0x84, 0x31, 0x4a, 0x6b, 0xbf, 0x95, 0x38, 0xf2, 0x0f, 0x46, 0x6e,
-	0x5a, 0x05, 0x60, 0x40, 0xff, 0xa3, 0x32, 0x68, 0x2c, 0xa4, 0xb8, 0xbd, 0xf7, 0x55, 0xa0, 0x59,
-	0xca, 0x4a, 0xa2, 0x29, 0x71, 0xed, 0xa9, 0xbc, 0xa0, 0x00, 0x89, 0xe3, 0xcf, 0x45, 0x0f, 0x07,
-	0xaa, 0x56, 0xc0, 0xe9, 0x11, 0xd5, 0x0a, 0xee, 0x80, 0xc6, 0x3e, 0x66, 0xea, 0x24, 0x4f, 0x8d,
-	0xec, 0x74, 0x8e, 0x1c, 0xfa, 0x2f, 0x23, 0x96, 0xe1, 0x44, 0x71, 0x99, 0xda, 0xc5, 0x40, 0xb4,
-	0xa3, 0x42, 0x23, 0xfd, 0x14, 0x1a, 0x6a, 0x75, 0x8c, 0x14, 0x8e, 0xf6, 0x9e, 0xee, 0xed, 0x7f,
-	0xb9, 0xd7, 0xbd, 0x96, 0x16, 0x91, 0x4b, 0x59, 0x2c, 0x51, 0xce, 0xc7, 0x12, 0x15, 0xc4, 0x3f,
-	0xde, 0x3f, 0xda, 0x1b, 0x76, 0xab, 0xa2, 0x03, 0x1a, 0x35, 0x47, 0xc6, 0xe0, 0x79, 0xb7, 0x46,
-	0x69, 0xf3, 0xe3, 0xcf, 0x06, 0xcf, 0x36, 0xba, 0xf5, 0xb4, 0x04, 0xdd, 0xd0, 0xff, 0xb2, 0x04,
-	0x37, 0xf8, 0xc8, 0xf9, 0x3c, 0x32, 0xff, 0x73, 0x5f, 0x95, 0x7f, 0xee, 0xfb, 0xf5, 0xa6, 0x8e,
-	0x38, 0x68, 0xea, 0xd8, 0xa3, 0xf1, 0x05, 0xaa, 0x07, 0xd7, 0x38, 0x9a, 0x53, 0xc7, 0xde, 0x44,
-	0x58, 0xff, 0x87, 0x12, 0xf4, 0x39, 0x84, 0x79, 0x12, 0x9a, 0xc1, 0xe9, 0x17, 0xbb, 0x97, 0x92,
-	0x98, 0xab, 0x1c, 0xfb, 0x3d, 0x58, 0xa2, 0xdf, 0x1f, 0x7f, 0xee, 0x8e, 0x54, 0xa0, 0xcd, 0xf7,
-	0xd7, 0x51, 0x58, 0x9e, 0x48, 0x7c, 0x0c, 0x6d, 0xfe, 0x4d, 0x92, 0xca, 0x6d, 0x85, 0x07, 0x8b,
-	0x42, 0x00, 0xd5, 0xe2, 0x5e, 0xf4, 0x74, 0x22, 0x3e, 0x4a, 0x07, 0x65, 0xf9, 0xce, 0xe5, 0x37,
-	0x09, 0x35, 0x64, 0x48, 0x59, 0xd0, 0x43, 0xb8, 0xb3, 0xf0, 0x1c, 0x4a, 0xb0, 0x73, 0xb5, 0x27,
-	0x96, 0xa7, 0xf5, 0xbf, 0x2f, 0x41, 0x15, 0x9d, 0xa5, 0x78, 0x00, 0xda, 0x67, 0xd2, 0x0c, 0xe3,
-	0xb1, 0x34, 0x63, 0x51, 0x70, 0x8c, 0x7d, 0x5a, 0x31, 0x7b, 0x17, 0xd5, 0xaf, 0x3d, 0x2a, 0x89,
-	0x35, 0xfe, 0x73, 0x29, 0xf9, 0x21, 0xab, 0x93, 0x38, 0x5d, 0x72, 0xca, 0xfd, 0xc2, 0x78, 0xfd,
-	0xda, 0x2a, 0xf5, 0xff, 0xdc, 0x77, 0xbc, 0xc7, 0xfc, 0xa3, 0x8d, 0x98, 0x77, 0xd2, 0xf3, 0x23,
-	0xc4, 0x03, 0xa8, 0xef, 0x44, 0x18, 0x0d, 0x5c, 0xee, 0x4a, 0x5c, 0xcb, 0x07, 0x0a, 0xfa, 0xb5,
-	0xf5, 0xbf, 0xaa, 0x40, 0xf5, 0x2b, 0x19, 0xfa, 0xe2, 0x43, 0x68, 0xa8, 0x57, 0x64, 0x91, 0x7b,
-	0x2d, 0xee, 0x53, 0x62, 0x32, 0xf7, 0xbc, 0x4c, 0xab, 0x74, 0x99, 0x5d, 0x59, 0x91, 0x54, 0x64,
-	0x8f, 0xdc, 0x97, 0x36, 0xf5, 0x09, 0x74, 0x0f, 0xe3, 0x50, 0x9a, 0x93, 0x5c, 0xf7, 0x22, 0xab,
-	0x16, 0x55, 0x5c, 0x89, 0x5f, 0xf7, 0xa1, 0xce, 0x21, 0xd7, 0xdc, 0x80, 0xf9, 0xe2, 0x29, 0x75,
-	0x7e, 0x0f, 0x5a, 0x87, 0xa7, 0xfe, 0xd4, 0xb5, 0x0f, 0x65, 0x78, 0x2e, 0x45, 0xee, 0xbf, 0x90,
-	0x7e, 0xae, 0xad, 0x5f, 0x13, 0xab, 0x00, 0xec, 0xe5, 0x8f, 0x50, 0x81, 0x1a, 0x48, 0xdb, 0x9b,
-	0x4e, 0x78, 0xd2, 0x9c, 0xfb, 0xe7, 0x9e, 0xb9, 0xc8, 0xeb, 0x55, 0x3d, 0x3f, 0x86, 0xce, 0x63,
-	0x52, 0xa6, 0xfd, 0x70, 0x63, 0xec, 0x87, 0xb1, 0x98, 0xff, 0x37, 0xa4, 0x3f, 0x8f, 0xd0, 0xaf,
-	0x89, 0x47, 0xd0, 0x1c, 0x86, 0x17, 0xdc, 0xff, 0x86, 0x0a, 0x58, 0xb3, 0xf5, 0x16, 0x9c, 0x72,
-	0xfd, 0xbf, 0xaa, 0x50, 0xff, 0xd2, 0x0f, 0xcf, 0x64, 0x88, 0x49, 0x2a, 0x15, 0xbb, 0x95, 0x18,
-	0xa5, 0x85, 0xef, 0x45, 0x0b, 0xbd, 0x0b, 0x1a, 0x31, 0x65, 0x68, 0x46, 0x67, 0x7c, 0x55, 0xf4,
-	0xbf, 0x2d, 0xf3, 0x85, 0x93, 0x5e, 0xba, 0xd7, 0x25, 0xbe, 0xa8, 0xf4, 0xbd, 0xa8, 0x50, 0x7a,
-	0xee, 0xd3, 0xf9, 0x9f, 0x3e, 0x3f, 0x44, 0xd1, 0x7c, 0x54, 0x42, 0x2b, 0x7d, 0xc8, 0x27, 0xc5,
-	0x4e, 0xd9, 0x7f, 0x86, 0x2c, 0xf9, 0xd9, 0x8f, 0x7d, 0xfa, 0x35, 0xf1, 0x10, 0xea, 0x4a, 0xa5,
-	0x6f, 0x64, 0xca, 0xab, 0xec, 0x44, 0xbf, 0x9b, 0x47, 0xa9, 0x01, 0xef, 0x43, 0x9d, 0xcd, 0x1f,
-	0x0f, 0x28, 0xc4, 0x2f, 0xbc, 0x6b, 0x8e, 0x81, 0xf4, 0x6b, 0xe2, 0x3e, 0x34, 0x54, 0xc1, 0x5a,
-	0x2c, 0xa8, 0x5e, 0xcf, 0x75, 0xfe, 0x08, 0xea, 0xec, 0xb5, 0x78, 0xde, 0x82, 0x6b, 0xef, 0x8b,
-	0x3c, 0x2a, 0x51, 0x12, 0x94, 0x76, 0x43, 0x5a, 0xd2, 0xc9, 0xe5, 0x58, 0x22, 0xe1, 0xc4, 0x02,
-	0x95, 0xfd, 0x04, 0x3a, 0x85, 0x7c, 0x4c, 0xf4, 0xe8, 0x76, 0x16, 0xa4, 0x68, 0x97, 0x14, 0xe5,
-	0xc7, 0xa0, 0xa9, 0x70, 0x78, 0x2c, 0x05, 0x95, 0xa0, 0x17, 0x04, 0xd4, 0xfd, 0xcb, 0xf1, 0x30,
-	0x49, 0xff, 0x4f, 0xe0, 0xe6, 0x02, 0x1b, 0x26, 0xe8, 0x67, 0x9c, 0xab, 0x8d, 0x74, 0x7f, 0xf9,
-	0x4a, 0x7a, 0xc2, 0x80, 0xcd, 0xee, 0x3f, 0x7e, 0x73, 0xb7, 0xf4, 0x2f, 0xdf, 0xdc, 0x2d, 0xfd,
-	0xdb, 0x37, 0x77, 0x4b, 0xbf, 0xfc, 0xf7, 0xbb, 0xd7, 0xc6, 0x75, 0xfa, 0xe7, 0xfc, 0xe3, 0xff,
-	0x0b, 0x00, 0x00, 0xff, 0xff, 0x1c, 0x42, 0x30, 0xc0, 0xe9, 0x2e, 0x00, 0x00,
+func (m *IdempotencyRecord) Reset()         { *m = IdempotencyRecord{} }
+func (m *IdempotencyRecord) String() string { return proto.CompactTextString(m) }
+func (*IdempotencyRecord) ProtoMessage()    {}
+func (*IdempotencyRecord) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f80abaa17e25ccc8, []int{61}
 }
-
-// Reference imports to suppress errors if they are not otherwise used.
-var _ context.Context
-var _ grpc.ClientConn
-
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the grpc package it is being compiled against.
-const _ = grpc.SupportPackageIsVersion4
-
-// RaftClient is the client API for Raft service.
-//
-// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
-type RaftClient interface {
-	Heartbeat(ctx context.Context, in *api.Payload, opts ...grpc.CallOption) (Raft_HeartbeatClient, error)
-	RaftMessage(ctx context.Context, opts ...grpc.CallOption) (Raft_RaftMessageClient, error)
-	JoinCluster(ctx context.Context, in *RaftContext, opts ...grpc.CallOption) (*api.Payload, error)
-	IsPeer(ctx context.Context, in *RaftContext, opts ...grpc.CallOption) (*PeerResponse, error)
+func (m *IdempotencyRecord) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
 }
-
-type raftClient struct {
-	cc *grpc.ClientConn
+func (m *IdempotencyRecord) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_IdempotencyRecord.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
 }
-
-func NewRaftClient(cc *grpc.ClientConn) RaftClient {
-	return &raftClient{cc}
+func (m *IdempotencyRecord) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_IdempotencyRecord.Merge(m, src)
+}
+func (m *IdempotencyRecord) XXX_Size() int {
+	return m.Size()
+}
+func (m *IdempotencyRecord) XXX_DiscardUnknown() {
+	xxx_messageInfo_IdempotencyRecord.DiscardUnknown(m)
 }
 
-func (c *raftClient) Heartbeat(ctx context.Context, in *api.Payload, opts ...grpc.CallOption) (Raft_HeartbeatClient, error) {
-	stream, err := c.cc.NewStream(ctx, &_Raft_serviceDesc.Streams[0], "/pb.Raft/Heartbeat", opts...)
-	if err != nil {
-		return nil, err
-	}
-	x := &raftHeartbeatClient{stream}
-	if err := x.ClientStream.SendMsg(in); err != nil {
-		return nil, err
-	}
-	if err := x.ClientStream.CloseSend(); err != nil {
-		return nil, err
+var xxx_messageInfo_IdempotencyRecord proto.InternalMessageInfo
+
+func (m *IdempotencyRecord) GetKey() string {
+	if m != nil {
+		return m.Key
 	}
-	return x, nil
+	return ""
 }
 
-type Raft_HeartbeatClient interface {
-	Recv() (*HealthInfo, error)
-	grpc.ClientStream
+func (m *IdempotencyRecord) GetFingerprint() uint64 {
+	if m != nil {
+		return m.Fingerprint
+	}
+	return 0
 }
 
-type raftHeartbeatClient struct {
-	grpc.ClientStream
+func (m *IdempotencyRecord) GetStartTs() uint64 {
+	if m != nil {
+		return m.StartTs
+	}
+	return 0
 }
 
-func (x *raftHeartbeatClient) Recv() (*HealthInfo, error) {
-	m := new(HealthInfo)
-	if err := x.ClientStream.RecvMsg(m); err != nil {
-		return nil, err
+func (m *IdempotencyRecord) GetCommitTs() uint64 {
+	if m != nil {
+		return m.CommitTs
 	}
-	return m, nil
+	return 0
 }
 
-func (c *raftClient) RaftMessage(ctx context.Context, opts ...grpc.CallOption) (Raft_RaftMessageClient, error) {
-	stream, err := c.cc.NewStream(ctx, &_Raft_serviceDesc.Streams[1], "/pb.Raft/RaftMessage", opts...)
-	if err != nil {
-		return nil, err
+func (m *IdempotencyRecord) GetExpiresAt() int64 {
+	if m != nil {
+		return m.ExpiresAt
 	}
-	x := &raftRaftMessageClient{stream}
-	return x, nil
+	return 0
 }
 
-type Raft_RaftMessageClient interface {
-	Send(*RaftBatch) error
-	CloseAndRecv() (*api.Payload, error)
-	grpc.ClientStream
+func (m *IdempotencyRecord) GetResponse() []byte {
+	if m != nil {
+		return m.Response
+	}
+	return nil
 }
 
-type raftRaftMessageClient struct {
-	grpc.ClientStream
+type StrictSchema struct {
+	Enabled              bool     `protobuf:"varint,1,opt,name=enabled,proto3" json:"enabled,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-func (x *raftRaftMessageClient) Send(m *RaftBatch) error {
-	return x.ClientStream.SendMsg(m)
+func (m *StrictSchema) Reset()         { *m = StrictSchema{} }
+func (m *StrictSchema) String() string { return proto.CompactTextString(m) }
+func (*StrictSchema) ProtoMessage()    {}
+func (*StrictSchema) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f80abaa17e25ccc8, []int{62}
 }
-
-func (x *raftRaftMessageClient) CloseAndRecv() (*api.Payload, error) {
-	if err := x.ClientStream.CloseSend(); err != nil {
-		return nil, err
-	}
-	m := new(api.Payload)
-	if err := x.ClientStream.RecvMsg(m); err != nil {
-		return nil, err
-	}
-	return m, nil
+func (m *StrictSchema) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
 }
-
-func (c *raftClient) JoinCluster(ctx context.Context, in *RaftContext, opts ...grpc.CallOption) (*api.Payload, error) {
-	out := new(api.Payload)
-	err := c.cc.Invoke(ctx, "/pb.Raft/JoinCluster", in, out, opts...)
-	if err != nil {
-		return nil, err
+func (m *StrictSchema) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_StrictSchema.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
 	}
-	return out, nil
+}
+func (m *StrictSchema) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StrictSchema.Merge(m, src)
+}
+func (m *StrictSchema) XXX_Size() int {
+	return m.Size()
+}
+func (m *StrictSchema) XXX_DiscardUnknown() {
+	xxx_messageInfo_StrictSchema.DiscardUnknown(m)
 }
 
-func (c *raftClient) IsPeer(ctx context.Context, in *RaftContext, opts ...grpc.CallOption) (*PeerResponse, error) {
-	out := new(PeerResponse)
-	err := c.cc.Invoke(ctx, "/pb.Raft/IsPeer", in, out, opts...)
-	if err != nil {
-		return nil, err
+var xxx_messageInfo_StrictSchema proto.InternalMessageInfo
+
+func (m *StrictSchema) GetEnabled() bool {
+	if m != nil {
+		return m.Enabled
 	}
-	return out, nil
+	return false
 }
 
-// RaftServer is the server API for Raft service.
-type RaftServer interface {
-	Heartbeat(*api.Payload, Raft_HeartbeatServer) error
-	RaftMessage(Raft_RaftMessageServer) error
-	JoinCluster(context.Context, *RaftContext) (*api.Payload, error)
-	IsPeer(context.Context, *RaftContext) (*PeerResponse, error)
+// BlobChunk is one chunk of a large value, either written as part of a transaction or
+// streamed back when the value is read.
+type BlobChunk struct {
+	Attr                 string   `protobuf:"bytes,1,opt,name=attr,proto3" json:"attr,omitempty"`
+	Uid                  uint64   `protobuf:"fixed64,2,opt,name=uid,proto3" json:"uid,omitempty"`
+	Idx                  uint32   `protobuf:"varint,3,opt,name=idx,proto3" json:"idx,omitempty"`
+	Data                 []byte   `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-// UnimplementedRaftServer can be embedded to have forward compatible implementations.
-type UnimplementedRaftServer struct {
+func (m *BlobChunk) Reset()         { *m = BlobChunk{} }
+func (m *BlobChunk) String() string { return proto.CompactTextString(m) }
+func (*BlobChunk) ProtoMessage()    {}
+func (*BlobChunk) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f80abaa17e25ccc8, []int{63}
 }
-
-func (*UnimplementedRaftServer) Heartbeat(req *api.Payload, srv Raft_HeartbeatServer) error {
-	return status.Errorf(codes.Unimplemented, "method Heartbeat not implemented")
+func (m *BlobChunk) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
 }
-func (*UnimplementedRaftServer) RaftMessage(srv Raft_RaftMessageServer) error {
-	return status.Errorf(codes.Unimplemented, "method RaftMessage not implemented")
+func (m *BlobChunk) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_BlobChunk.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
 }
-func (*UnimplementedRaftServer) JoinCluster(ctx context.Context, req *RaftContext) (*api.Payload, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method JoinCluster not implemented")
+func (m *BlobChunk) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BlobChunk.Merge(m, src)
 }
-func (*UnimplementedRaftServer) IsPeer(ctx context.Context, req *RaftContext) (*PeerResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method IsPeer not implemented")
+func (m *BlobChunk) XXX_Size() int {
+	return m.Size()
 }
-
-func RegisterRaftServer(s *grpc.Server, srv RaftServer) {
-	s.RegisterService(&_Raft_serviceDesc, srv)
+func (m *BlobChunk) XXX_DiscardUnknown() {
+	xxx_messageInfo_BlobChunk.DiscardUnknown(m)
 }
 
-func _Raft_Heartbeat_Handler(srv interface{}, stream grpc.ServerStream) error {
-	m := new(api.Payload)
-	if err := stream.RecvMsg(m); err != nil {
-		return err
+var xxx_messageInfo_BlobChunk proto.InternalMessageInfo
+
+func (m *BlobChunk) GetAttr() string {
+	if m != nil {
+		return m.Attr
 	}
-	return srv.(RaftServer).Heartbeat(m, &raftHeartbeatServer{stream})
+	return ""
 }
 
-type Raft_HeartbeatServer interface {
-	Send(*HealthInfo) error
-	grpc.ServerStream
+func (m *BlobChunk) GetUid() uint64 {
+	if m != nil {
+		return m.Uid
+	}
+	return 0
 }
 
-type raftHeartbeatServer struct {
-	grpc.ServerStream
+func (m *BlobChunk) GetIdx() uint32 {
+	if m != nil {
+		return m.Idx
+	}
+	return 0
 }
 
-func (x *raftHeartbeatServer) Send(m *HealthInfo) error {
-	return x.ServerStream.SendMsg(m)
+func (m *BlobChunk) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
 }
 
-func _Raft_RaftMessage_Handler(srv interface{}, stream grpc.ServerStream) error {
-	return srv.(RaftServer).RaftMessage(&raftRaftMessageServer{stream})
+type BlobRequest struct {
+	Attr                 string   `protobuf:"bytes,1,opt,name=attr,proto3" json:"attr,omitempty"`
+	Uid                  uint64   `protobuf:"fixed64,2,opt,name=uid,proto3" json:"uid,omitempty"`
+	ReadTs               uint64   `protobuf:"varint,3,opt,name=read_ts,json=readTs,proto3" json:"read_ts,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-type Raft_RaftMessageServer interface {
-	SendAndClose(*api.Payload) error
-	Recv() (*RaftBatch, error)
-	grpc.ServerStream
+func (m *BlobRequest) Reset()         { *m = BlobRequest{} }
+func (m *BlobRequest) String() string { return proto.CompactTextString(m) }
+func (*BlobRequest) ProtoMessage()    {}
+func (*BlobRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f80abaa17e25ccc8, []int{64}
 }
-
-type raftRaftMessageServer struct {
-	grpc.ServerStream
+func (m *BlobRequest) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
 }
-
-func (x *raftRaftMessageServer) SendAndClose(m *api.Payload) error {
-	return x.ServerStream.SendMsg(m)
+func (m *BlobRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_BlobRequest.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *BlobRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BlobRequest.Merge(m, src)
+}
+func (m *BlobRequest) XXX_Size() int {
+	return m.Size()
+}
+func (m *BlobRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_BlobRequest.DiscardUnknown(m)
 }
 
-func (x *raftRaftMessageServer) Recv() (*RaftBatch, error) {
-	m := new(RaftBatch)
-	if err := x.ServerStream.RecvMsg(m); err != nil {
-		return nil, err
+var xxx_messageInfo_BlobRequest proto.InternalMessageInfo
+
+func (m *BlobRequest) GetAttr() string {
+	if m != nil {
+		return m.Attr
 	}
-	return m, nil
+	return ""
 }
 
-func _Raft_JoinCluster_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(RaftContext)
-	if err := dec(in); err != nil {
-		return nil, err
-	}
-	if interceptor == nil {
-		return srv.(RaftServer).JoinCluster(ctx, in)
-	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/pb.Raft/JoinCluster",
-	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(RaftServer).JoinCluster(ctx, req.(*RaftContext))
+func (m *BlobRequest) GetUid() uint64 {
+	if m != nil {
+		return m.Uid
 	}
-	return interceptor(ctx, in, info, handler)
+	return 0
 }
 
-func _Raft_IsPeer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(RaftContext)
-	if err := dec(in); err != nil {
-		return nil, err
-	}
-	if interceptor == nil {
-		return srv.(RaftServer).IsPeer(ctx, in)
-	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/pb.Raft/IsPeer",
-	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(RaftServer).IsPeer(ctx, req.(*RaftContext))
+func (m *BlobRequest) GetReadTs() uint64 {
+	if m != nil {
+		return m.ReadTs
 	}
-	return interceptor(ctx, in, info, handler)
+	return 0
 }
 
-var _Raft_serviceDesc = grpc.ServiceDesc{
-	ServiceName: "pb.Raft",
-	HandlerType: (*RaftServer)(nil),
-	Methods: []grpc.MethodDesc{
-		{
-			MethodName: "JoinCluster",
-			Handler:    _Raft_JoinCluster_Handler,
-		},
-		{
-			MethodName: "IsPeer",
-			Handler:    _Raft_IsPeer_Handler,
-		},
-	},
-	Streams: []grpc.StreamDesc{
-		{
-			StreamName:    "Heartbeat",
-			Handler:       _Raft_Heartbeat_Handler,
-			ServerStreams: true,
-		},
-		{
-			StreamName:    "RaftMessage",
-			Handler:       _Raft_RaftMessage_Handler,
-			ClientStreams: true,
-		},
-	},
-	Metadata: "pb.proto",
+type ReplicationState struct {
+	Primary              string   `protobuf:"bytes,1,opt,name=primary,proto3" json:"primary,omitempty"`
+	PrimaryTs            uint64   `protobuf:"varint,2,opt,name=primary_ts,json=primaryTs,proto3" json:"primary_ts,omitempty"`
+	ReadTs               uint64   `protobuf:"varint,3,opt,name=read_ts,json=readTs,proto3" json:"read_ts,omitempty"`
+	SnapshotAt           int64    `protobuf:"varint,4,opt,name=snapshot_at,json=snapshotAt,proto3" json:"snapshot_at,omitempty"`
+	Promote              bool     `protobuf:"varint,5,opt,name=promote,proto3" json:"promote,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-// ZeroClient is the client API for Zero service.
-//
-// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
-type ZeroClient interface {
-	// These 3 endpoints are for handling membership.
-	Connect(ctx context.Context, in *Member, opts ...grpc.CallOption) (*ConnectionState, error)
-	UpdateMembership(ctx context.Context, in *Group, opts ...grpc.CallOption) (*api.Payload, error)
-	StreamMembership(ctx context.Context, in *api.Payload, opts ...grpc.CallOption) (Zero_StreamMembershipClient, error)
-	Oracle(ctx context.Context, in *api.Payload, opts ...grpc.CallOption) (Zero_OracleClient, error)
-	ShouldServe(ctx context.Context, in *Tablet, opts ...grpc.CallOption) (*Tablet, error)
-	AssignUids(ctx context.Context, in *Num, opts ...grpc.CallOption) (*AssignedIds, error)
-	Timestamps(ctx context.Context, in *Num, opts ...grpc.CallOption) (*AssignedIds, error)
-	CommitOrAbort(ctx context.Context, in *api.TxnContext, opts ...grpc.CallOption) (*api.TxnContext, error)
-	TryAbort(ctx context.Context, in *TxnTimestamps, opts ...grpc.CallOption) (*OracleDelta, error)
-}
+func (m *ReplicationState) Reset() { *m = ReplicationState{} }
 
-type zeroClient struct {
-	cc *grpc.ClientConn
+func (m *ReplicationState) String() string { return proto.CompactTextString(m) }
+
+func (*ReplicationState) ProtoMessage() {}
+
+func (*ReplicationState) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f80abaa17e25ccc8, []int{65}
 }
 
-func NewZeroClient(cc *grpc.ClientConn) ZeroClient {
-	return &zeroClient{cc}
+func (m *ReplicationState) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
 }
 
-func (c *zeroClient) Connect(ctx context.Context, in *Member, opts ...grpc.CallOption) (*ConnectionState, error) {
-	out := new(ConnectionState)
-	err := c.cc.Invoke(ctx, "/pb.Zero/Connect", in, out, opts...)
-	if err != nil {
-		return nil, err
+func (m *ReplicationState) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_ReplicationState.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
 	}
-	return out, nil
 }
 
-func (c *zeroClient) UpdateMembership(ctx context.Context, in *Group, opts ...grpc.CallOption) (*api.Payload, error) {
-	out := new(api.Payload)
-	err := c.cc.Invoke(ctx, "/pb.Zero/UpdateMembership", in, out, opts...)
-	if err != nil {
-		return nil, err
-	}
-	return out, nil
+func (m *ReplicationState) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ReplicationState.Merge(m, src)
 }
 
-func (c *zeroClient) StreamMembership(ctx context.Context, in *api.Payload, opts ...grpc.CallOption) (Zero_StreamMembershipClient, error) {
-	stream, err := c.cc.NewStream(ctx, &_Zero_serviceDesc.Streams[0], "/pb.Zero/StreamMembership", opts...)
-	if err != nil {
-		return nil, err
-	}
-	x := &zeroStreamMembershipClient{stream}
-	if err := x.ClientStream.SendMsg(in); err != nil {
-		return nil, err
-	}
-	if err := x.ClientStream.CloseSend(); err != nil {
-		return nil, err
-	}
-	return x, nil
+func (m *ReplicationState) XXX_Size() int {
+	return m.Size()
 }
 
-type Zero_StreamMembershipClient interface {
-	Recv() (*MembershipState, error)
-	grpc.ClientStream
+func (m *ReplicationState) XXX_DiscardUnknown() {
+	xxx_messageInfo_ReplicationState.DiscardUnknown(m)
 }
 
-type zeroStreamMembershipClient struct {
-	grpc.ClientStream
+var xxx_messageInfo_ReplicationState proto.InternalMessageInfo
+
+func (m *ReplicationState) GetPrimary() string {
+	if m != nil {
+		return m.Primary
+	}
+	return ""
 }
 
-func (x *zeroStreamMembershipClient) Recv() (*MembershipState, error) {
-	m := new(MembershipState)
-	if err := x.ClientStream.RecvMsg(m); err != nil {
-		return nil, err
+func (m *ReplicationState) GetPrimaryTs() uint64 {
+	if m != nil {
+		return m.PrimaryTs
 	}
-	return m, nil
+	return 0
 }
 
-func (c *zeroClient) Oracle(ctx context.Context, in *api.Payload, opts ...grpc.CallOption) (Zero_OracleClient, error) {
-	stream, err := c.cc.NewStream(ctx, &_Zero_serviceDesc.Streams[1], "/pb.Zero/Oracle", opts...)
-	if err != nil {
-		return nil, err
+func (m *ReplicationState) GetReadTs() uint64 {
+	if m != nil {
+		return m.ReadTs
 	}
-	x := &zeroOracleClient{stream}
-	if err := x.ClientStream.SendMsg(in); err != nil {
-		return nil, err
+	return 0
+}
+
+func (m *ReplicationState) GetSnapshotAt() int64 {
+	if m != nil {
+		return m.SnapshotAt
 	}
-	if err := x.ClientStream.CloseSend(); err != nil {
-		return nil, err
+	return 0
+}
+
+func (m *ReplicationState) GetPromote() bool {
+	if m != nil {
+		return m.Promote
 	}
-	return x, nil
+	return false
 }
 
-type Zero_OracleClient interface {
-	Recv() (*OracleDelta, error)
-	grpc.ClientStream
+type ReplicationRequest struct {
+	GroupId              uint32   `protobuf:"varint,1,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	SinceTs              uint64   `protobuf:"varint,2,opt,name=since_ts,json=sinceTs,proto3" json:"since_ts,omitempty"`
+	ReadTs               uint64   `protobuf:"varint,3,opt,name=read_ts,json=readTs,proto3" json:"read_ts,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-type zeroOracleClient struct {
-	grpc.ClientStream
+func (m *ReplicationRequest) Reset() { *m = ReplicationRequest{} }
+
+func (m *ReplicationRequest) String() string { return proto.CompactTextString(m) }
+
+func (*ReplicationRequest) ProtoMessage() {}
+
+func (*ReplicationRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f80abaa17e25ccc8, []int{66}
 }
 
-func (x *zeroOracleClient) Recv() (*OracleDelta, error) {
-	m := new(OracleDelta)
-	if err := x.ClientStream.RecvMsg(m); err != nil {
-		return nil, err
-	}
-	return m, nil
+func (m *ReplicationRequest) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
 }
 
-func (c *zeroClient) ShouldServe(ctx context.Context, in *Tablet, opts ...grpc.CallOption) (*Tablet, error) {
-	out := new(Tablet)
-	err := c.cc.Invoke(ctx, "/pb.Zero/ShouldServe", in, out, opts...)
-	if err != nil {
-		return nil, err
+func (m *ReplicationRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_ReplicationRequest.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
 	}
-	return out, nil
 }
 
-func (c *zeroClient) AssignUids(ctx context.Context, in *Num, opts ...grpc.CallOption) (*AssignedIds, error) {
-	out := new(AssignedIds)
-	err := c.cc.Invoke(ctx, "/pb.Zero/AssignUids", in, out, opts...)
-	if err != nil {
-		return nil, err
-	}
-	return out, nil
+func (m *ReplicationRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ReplicationRequest.Merge(m, src)
 }
 
-func (c *zeroClient) Timestamps(ctx context.Context, in *Num, opts ...grpc.CallOption) (*AssignedIds, error) {
-	out := new(AssignedIds)
-	err := c.cc.Invoke(ctx, "/pb.Zero/Timestamps", in, out, opts...)
-	if err != nil {
-		return nil, err
+func (m *ReplicationRequest) XXX_Size() int {
+	return m.Size()
+}
+
+func (m *ReplicationRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ReplicationRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ReplicationRequest proto.InternalMessageInfo
+
+func (m *ReplicationRequest) GetGroupId() uint32 {
+	if m != nil {
+		return m.GroupId
 	}
-	return out, nil
+	return 0
 }
 
-func (c *zeroClient) CommitOrAbort(ctx context.Context, in *api.TxnContext, opts ...grpc.CallOption) (*api.TxnContext, error) {
-	out := new(api.TxnContext)
-	err := c.cc.Invoke(ctx, "/pb.Zero/CommitOrAbort", in, out, opts...)
-	if err != nil {
-		return nil, err
+func (m *ReplicationRequest) GetSinceTs() uint64 {
+	if m != nil {
+		return m.SinceTs
 	}
-	return out, nil
+	return 0
 }
 
-func (c *zeroClient) TryAbort(ctx context.Context, in *TxnTimestamps, opts ...grpc.CallOption) (*OracleDelta, error) {
-	out := new(OracleDelta)
-	err := c.cc.Invoke(ctx, "/pb.Zero/TryAbort", in, out, opts...)
-	if err != nil {
-		return nil, err
+func (m *ReplicationRequest) GetReadTs() uint64 {
+	if m != nil {
+		return m.ReadTs
 	}
-	return out, nil
+	return 0
 }
 
-// ZeroServer is the server API for Zero service.
-type ZeroServer interface {
-	// These 3 endpoints are for handling membership.
-	Connect(context.Context, *Member) (*ConnectionState, error)
-	UpdateMembership(context.Context, *Group) (*api.Payload, error)
-	StreamMembership(*api.Payload, Zero_StreamMembershipServer) error
-	Oracle(*api.Payload, Zero_OracleServer) error
-	ShouldServe(context.Context, *Tablet) (*Tablet, error)
-	AssignUids(context.Context, *Num) (*AssignedIds, error)
-	Timestamps(context.Context, *Num) (*AssignedIds, error)
-	CommitOrAbort(context.Context, *api.TxnContext) (*api.TxnContext, error)
-	TryAbort(context.Context, *TxnTimestamps) (*OracleDelta, error)
+type KeyRange struct {
+	Start                []byte   `protobuf:"bytes,1,opt,name=start,proto3" json:"start,omitempty"`
+	End                  []byte   `protobuf:"bytes,2,opt,name=end,proto3" json:"end,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-// UnimplementedZeroServer can be embedded to have forward compatible implementations.
-type UnimplementedZeroServer struct {
+func (m *KeyRange) Reset() { *m = KeyRange{} }
+
+func (m *KeyRange) String() string { return proto.CompactTextString(m) }
+
+func (*KeyRange) ProtoMessage() {}
+
+func (*KeyRange) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f80abaa17e25ccc8, []int{67}
 }
 
-func (*UnimplementedZeroServer) Connect(ctx context.Context, req *Member) (*ConnectionState, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method Connect not implemented")
+func (m *KeyRange) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
 }
-func (*UnimplementedZeroServer) UpdateMembership(ctx context.Context, req *Group) (*api.Payload, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method UpdateMembership not implemented")
+
+func (m *KeyRange) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_KeyRange.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
 }
-func (*UnimplementedZeroServer) StreamMembership(req *api.Payload, srv Zero_StreamMembershipServer) error {
-	return status.Errorf(codes.Unimplemented, "method StreamMembership not implemented")
+
+func (m *KeyRange) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_KeyRange.Merge(m, src)
 }
-func (*UnimplementedZeroServer) Oracle(req *api.Payload, srv Zero_OracleServer) error {
-	return status.Errorf(codes.Unimplemented, "method Oracle not implemented")
+
+func (m *KeyRange) XXX_Size() int {
+	return m.Size()
 }
-func (*UnimplementedZeroServer) ShouldServe(ctx context.Context, req *Tablet) (*Tablet, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method ShouldServe not implemented")
+
+func (m *KeyRange) XXX_DiscardUnknown() {
+	xxx_messageInfo_KeyRange.DiscardUnknown(m)
 }
-func (*UnimplementedZeroServer) AssignUids(ctx context.Context, req *Num) (*AssignedIds, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method AssignUids not implemented")
+
+var xxx_messageInfo_KeyRange proto.InternalMessageInfo
+
+func (m *KeyRange) GetStart() []byte {
+	if m != nil {
+		return m.Start
+	}
+	return nil
 }
-func (*UnimplementedZeroServer) Timestamps(ctx context.Context, req *Num) (*AssignedIds, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method Timestamps not implemented")
+
+func (m *KeyRange) GetEnd() []byte {
+	if m != nil {
+		return m.End
+	}
+	return nil
 }
-func (*UnimplementedZeroServer) CommitOrAbort(ctx context.Context, req *api.TxnContext) (*api.TxnContext, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method CommitOrAbort not implemented")
+
+type FreezePoint struct {
+	Tag                  string         `protobuf:"bytes,1,opt,name=tag,proto3" json:"tag,omitempty"`
+	ReadTs               uint64         `protobuf:"varint,2,opt,name=read_ts,json=readTs,proto3" json:"read_ts,omitempty"`
+	CreatedAt            int64          `protobuf:"varint,3,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	Groups               []*FrozenGroup `protobuf:"bytes,4,rep,name=groups,proto3" json:"groups,omitempty"`
+	Ready                bool           `protobuf:"varint,5,opt,name=ready,proto3" json:"ready,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
 }
-func (*UnimplementedZeroServer) TryAbort(ctx context.Context, req *TxnTimestamps) (*OracleDelta, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method TryAbort not implemented")
+
+func (m *FreezePoint) Reset() { *m = FreezePoint{} }
+
+func (m *FreezePoint) String() string { return proto.CompactTextString(m) }
+
+func (*FreezePoint) ProtoMessage() {}
+
+func (*FreezePoint) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f80abaa17e25ccc8, []int{68}
 }
 
-func RegisterZeroServer(s *grpc.Server, srv ZeroServer) {
-	s.RegisterService(&_Zero_serviceDesc, srv)
+func (m *FreezePoint) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
 }
 
-func _Zero_Connect_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(Member)
-	if err := dec(in); err != nil {
-		return nil, err
-	}
-	if interceptor == nil {
-		return srv.(ZeroServer).Connect(ctx, in)
-	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/pb.Zero/Connect",
-	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(ZeroServer).Connect(ctx, req.(*Member))
+func (m *FreezePoint) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_FreezePoint.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
 	}
-	return interceptor(ctx, in, info, handler)
 }
 
-func _Zero_UpdateMembership_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(Group)
-	if err := dec(in); err != nil {
-		return nil, err
+func (m *FreezePoint) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FreezePoint.Merge(m, src)
+}
+
+func (m *FreezePoint) XXX_Size() int {
+	return m.Size()
+}
+
+func (m *FreezePoint) XXX_DiscardUnknown() {
+	xxx_messageInfo_FreezePoint.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FreezePoint proto.InternalMessageInfo
+
+func (m *FreezePoint) GetTag() string {
+	if m != nil {
+		return m.Tag
 	}
-	if interceptor == nil {
-		return srv.(ZeroServer).UpdateMembership(ctx, in)
+	return ""
+}
+
+func (m *FreezePoint) GetReadTs() uint64 {
+	if m != nil {
+		return m.ReadTs
 	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/pb.Zero/UpdateMembership",
+	return 0
+}
+
+func (m *FreezePoint) GetCreatedAt() int64 {
+	if m != nil {
+		return m.CreatedAt
 	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(ZeroServer).UpdateMembership(ctx, req.(*Group))
+	return 0
+}
+
+func (m *FreezePoint) GetGroups() []*FrozenGroup {
+	if m != nil {
+		return m.Groups
 	}
-	return interceptor(ctx, in, info, handler)
+	return nil
 }
 
-func _Zero_StreamMembership_Handler(srv interface{}, stream grpc.ServerStream) error {
-	m := new(api.Payload)
-	if err := stream.RecvMsg(m); err != nil {
-		return err
+func (m *FreezePoint) GetReady() bool {
+	if m != nil {
+		return m.Ready
 	}
-	return srv.(ZeroServer).StreamMembership(m, &zeroStreamMembershipServer{stream})
+	return false
 }
 
-type Zero_StreamMembershipServer interface {
-	Send(*MembershipState) error
-	grpc.ServerStream
+type FrozenGroup struct {
+	GroupId              uint32   `protobuf:"varint,1,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	ReadTs               uint64   `protobuf:"varint,2,opt,name=read_ts,json=readTs,proto3" json:"read_ts,omitempty"`
+	NodeId               uint64   `protobuf:"varint,3,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	Index                uint64   `protobuf:"varint,4,opt,name=index,proto3" json:"index,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-type zeroStreamMembershipServer struct {
-	grpc.ServerStream
+func (m *FrozenGroup) Reset() { *m = FrozenGroup{} }
+
+func (m *FrozenGroup) String() string { return proto.CompactTextString(m) }
+
+func (*FrozenGroup) ProtoMessage() {}
+
+func (*FrozenGroup) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f80abaa17e25ccc8, []int{69}
 }
 
-func (x *zeroStreamMembershipServer) Send(m *MembershipState) error {
-	return x.ServerStream.SendMsg(m)
+func (m *FrozenGroup) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
 }
 
-func _Zero_Oracle_Handler(srv interface{}, stream grpc.ServerStream) error {
-	m := new(api.Payload)
-	if err := stream.RecvMsg(m); err != nil {
-		return err
+func (m *FrozenGroup) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_FrozenGroup.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
 	}
-	return srv.(ZeroServer).Oracle(m, &zeroOracleServer{stream})
 }
 
-type Zero_OracleServer interface {
-	Send(*OracleDelta) error
-	grpc.ServerStream
+func (m *FrozenGroup) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FrozenGroup.Merge(m, src)
 }
 
-type zeroOracleServer struct {
-	grpc.ServerStream
+func (m *FrozenGroup) XXX_Size() int {
+	return m.Size()
 }
 
-func (x *zeroOracleServer) Send(m *OracleDelta) error {
-	return x.ServerStream.SendMsg(m)
+func (m *FrozenGroup) XXX_DiscardUnknown() {
+	xxx_messageInfo_FrozenGroup.DiscardUnknown(m)
 }
 
-func _Zero_ShouldServe_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(Tablet)
-	if err := dec(in); err != nil {
-		return nil, err
-	}
-	if interceptor == nil {
-		return srv.(ZeroServer).ShouldServe(ctx, in)
-	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/pb.Zero/ShouldServe",
-	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(ZeroServer).ShouldServe(ctx, req.(*Tablet))
+var xxx_messageInfo_FrozenGroup proto.InternalMessageInfo
+
+func (m *FrozenGroup) GetGroupId() uint32 {
+	if m != nil {
+		return m.GroupId
 	}
-	return interceptor(ctx, in, info, handler)
+	return 0
 }
 
-func _Zero_AssignUids_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(Num)
-	if err := dec(in); err != nil {
-		return nil, err
-	}
-	if interceptor == nil {
-		return srv.(ZeroServer).AssignUids(ctx, in)
-	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/pb.Zero/AssignUids",
-	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(ZeroServer).AssignUids(ctx, req.(*Num))
+func (m *FrozenGroup) GetReadTs() uint64 {
+	if m != nil {
+		return m.ReadTs
 	}
-	return interceptor(ctx, in, info, handler)
+	return 0
 }
 
-func _Zero_Timestamps_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(Num)
-	if err := dec(in); err != nil {
-		return nil, err
-	}
-	if interceptor == nil {
-		return srv.(ZeroServer).Timestamps(ctx, in)
-	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/pb.Zero/Timestamps",
-	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(ZeroServer).Timestamps(ctx, req.(*Num))
+func (m *FrozenGroup) GetNodeId() uint64 {
+	if m != nil {
+		return m.NodeId
 	}
-	return interceptor(ctx, in, info, handler)
+	return 0
 }
 
-func _Zero_CommitOrAbort_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(api.TxnContext)
-	if err := dec(in); err != nil {
-		return nil, err
-	}
-	if interceptor == nil {
-		return srv.(ZeroServer).CommitOrAbort(ctx, in)
-	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/pb.Zero/CommitOrAbort",
-	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(ZeroServer).CommitOrAbort(ctx, req.(*api.TxnContext))
+func (m *FrozenGroup) GetIndex() uint64 {
+	if m != nil {
+		return m.Index
 	}
-	return interceptor(ctx, in, info, handler)
+	return 0
 }
 
-func _Zero_TryAbort_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(TxnTimestamps)
-	if err := dec(in); err != nil {
-		return nil, err
-	}
-	if interceptor == nil {
-		return srv.(ZeroServer).TryAbort(ctx, in)
-	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/pb.Zero/TryAbort",
-	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(ZeroServer).TryAbort(ctx, req.(*TxnTimestamps))
-	}
-	return interceptor(ctx, in, info, handler)
+type MoveProgress struct {
+	Predicate            string   `protobuf:"bytes,1,opt,name=predicate,proto3" json:"predicate,omitempty"`
+	Keys                 uint64   `protobuf:"varint,2,opt,name=keys,proto3" json:"keys,omitempty"`
+	Bytes                uint64   `protobuf:"varint,3,opt,name=bytes,proto3" json:"bytes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-var _Zero_serviceDesc = grpc.ServiceDesc{
-	ServiceName: "pb.Zero",
-	HandlerType: (*ZeroServer)(nil),
-	Methods: []grpc.MethodDesc{
-		{
-			MethodName: "Connect",
-			Handler:    _Zero_Connect_Handler,
-		},
-		{
-			MethodName: "UpdateMembership",
-			Handler:    _Zero_UpdateMembership_Handler,
-		},
-		{
-			MethodName: "ShouldServe",
-			Handler:    _Zero_ShouldServe_Handler,
-		},
-		{
-			MethodName: "AssignUids",
-			Handler:    _Zero_AssignUids_Handler,
-		},
-		{
-			MethodName: "Timestamps",
-			Handler:    _Zero_Timestamps_Handler,
-		},
-		{
-			MethodName: "CommitOrAbort",
-			Handler:    _Zero_CommitOrAbort_Handler,
-		},
-		{
-			MethodName: "TryAbort",
-			Handler:    _Zero_TryAbort_Handler,
-		},
-	},
-	Streams: []grpc.StreamDesc{
-		{
-			StreamName:    "StreamMembership",
-			Handler:       _Zero_StreamMembership_Handler,
-			ServerStreams: true,
-		},
-		{
-			StreamName:    "Oracle",
-			Handler:       _Zero_Oracle_Handler,
-			ServerStreams: true,
-		},
-	},
-	Metadata: "pb.proto",
+func (m *MoveProgress) Reset() { *m = MoveProgress{} }
+
+func (m *MoveProgress) String() string { return proto.CompactTextString(m) }
+
+func (*MoveProgress) ProtoMessage() {}
+
+func (*MoveProgress) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f80abaa17e25ccc8, []int{70}
 }
 
-// WorkerClient is the client API for Worker service.
+func (m *MoveProgress) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+
+func (m *MoveProgress) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_MoveProgress.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+
+func (m *MoveProgress) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MoveProgress.Merge(m, src)
+}
+
+func (m *MoveProgress) XXX_Size() int {
+	return m.Size()
+}
+
+func (m *MoveProgress) XXX_DiscardUnknown() {
+	xxx_messageInfo_MoveProgress.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MoveProgress proto.InternalMessageInfo
+
+func (m *MoveProgress) GetPredicate() string {
+	if m != nil {
+		return m.Predicate
+	}
+	return ""
+}
+
+func (m *MoveProgress) GetKeys() uint64 {
+	if m != nil {
+		return m.Keys
+	}
+	return 0
+}
+
+func (m *MoveProgress) GetBytes() uint64 {
+	if m != nil {
+		return m.Bytes
+	}
+	return 0
+}
+
+func init() {
+	proto.RegisterEnum("pb.DirectedEdge_Op", DirectedEdge_Op_name, DirectedEdge_Op_value)
+	proto.RegisterEnum("pb.Mutations_DropOp", Mutations_DropOp_name, Mutations_DropOp_value)
+	proto.RegisterEnum("pb.Metadata_HintType", Metadata_HintType_name, Metadata_HintType_value)
+	proto.RegisterEnum("pb.Posting_ValType", Posting_ValType_name, Posting_ValType_value)
+	proto.RegisterEnum("pb.Posting_PostingType", Posting_PostingType_name, Posting_PostingType_value)
+	proto.RegisterEnum("pb.SchemaUpdate_Directive", SchemaUpdate_Directive_name, SchemaUpdate_Directive_value)
+	proto.RegisterEnum("pb.BackupKey_KeyType", BackupKey_KeyType_name, BackupKey_KeyType_value)
+	proto.RegisterType((*List)(nil), "pb.List")
+	proto.RegisterType((*TaskValue)(nil), "pb.TaskValue")
+	proto.RegisterType((*SrcFunction)(nil), "pb.SrcFunction")
+	proto.RegisterType((*Query)(nil), "pb.Query")
+	proto.RegisterType((*ValueList)(nil), "pb.ValueList")
+	proto.RegisterType((*LangList)(nil), "pb.LangList")
+	proto.RegisterType((*Result)(nil), "pb.Result")
+	proto.RegisterType((*Order)(nil), "pb.Order")
+	proto.RegisterType((*SortMessage)(nil), "pb.SortMessage")
+	proto.RegisterType((*SortResult)(nil), "pb.SortResult")
+	proto.RegisterType((*RaftContext)(nil), "pb.RaftContext")
+	proto.RegisterType((*Member)(nil), "pb.Member")
+	proto.RegisterType((*Group)(nil), "pb.Group")
+	proto.RegisterMapType((map[uint64]*Member)(nil), "pb.Group.MembersEntry")
+	proto.RegisterMapType((map[string]*Tablet)(nil), "pb.Group.TabletsEntry")
+	proto.RegisterType((*License)(nil), "pb.License")
+	proto.RegisterType((*ZeroProposal)(nil), "pb.ZeroProposal")
+	proto.RegisterMapType((map[uint32]uint64)(nil), "pb.ZeroProposal.SnapshotTsEntry")
+	proto.RegisterType((*MembershipState)(nil), "pb.MembershipState")
+	proto.RegisterMapType((map[uint32]*Group)(nil), "pb.MembershipState.GroupsEntry")
+	proto.RegisterMapType((map[uint64]*Member)(nil), "pb.MembershipState.ZerosEntry")
+	proto.RegisterType((*ConnectionState)(nil), "pb.ConnectionState")
+	proto.RegisterType((*HealthInfo)(nil), "pb.HealthInfo")
+	proto.RegisterType((*Tablet)(nil), "pb.Tablet")
+	proto.RegisterType((*DirectedEdge)(nil), "pb.DirectedEdge")
+	proto.RegisterType((*Mutations)(nil), "pb.Mutations")
+	proto.RegisterType((*Metadata)(nil), "pb.Metadata")
+	proto.RegisterMapType((map[string]Metadata_HintType)(nil), "pb.Metadata.PredHintsEntry")
+	proto.RegisterType((*Snapshot)(nil), "pb.Snapshot")
+	proto.RegisterType((*RestoreRequest)(nil), "pb.RestoreRequest")
+	proto.RegisterType((*Proposal)(nil), "pb.Proposal")
+	proto.RegisterType((*KVS)(nil), "pb.KVS")
+	proto.RegisterType((*Posting)(nil), "pb.Posting")
+	proto.RegisterType((*UidBlock)(nil), "pb.UidBlock")
+	proto.RegisterType((*UidPack)(nil), "pb.UidPack")
+	proto.RegisterType((*PostingList)(nil), "pb.PostingList")
+	proto.RegisterType((*FacetParam)(nil), "pb.FacetParam")
+	proto.RegisterType((*FacetParams)(nil), "pb.FacetParams")
+	proto.RegisterType((*Facets)(nil), "pb.Facets")
+	proto.RegisterType((*FacetsList)(nil), "pb.FacetsList")
+	proto.RegisterType((*Function)(nil), "pb.Function")
+	proto.RegisterType((*FilterTree)(nil), "pb.FilterTree")
+	proto.RegisterType((*SchemaRequest)(nil), "pb.SchemaRequest")
+	proto.RegisterType((*SchemaNode)(nil), "pb.SchemaNode")
+	proto.RegisterType((*SchemaResult)(nil), "pb.SchemaResult")
+	proto.RegisterType((*SchemaUpdate)(nil), "pb.SchemaUpdate")
+	proto.RegisterType((*TypeUpdate)(nil), "pb.TypeUpdate")
+	proto.RegisterType((*MapHeader)(nil), "pb.MapHeader")
+	proto.RegisterType((*MovePredicatePayload)(nil), "pb.MovePredicatePayload")
+	proto.RegisterType((*TxnStatus)(nil), "pb.TxnStatus")
+	proto.RegisterType((*OracleDelta)(nil), "pb.OracleDelta")
+	proto.RegisterMapType((map[uint32]uint64)(nil), "pb.OracleDelta.GroupChecksumsEntry")
+	proto.RegisterType((*TxnTimestamps)(nil), "pb.TxnTimestamps")
+	proto.RegisterType((*PeerResponse)(nil), "pb.PeerResponse")
+	proto.RegisterType((*RaftBatch)(nil), "pb.RaftBatch")
+	proto.RegisterType((*SubscriptionRequest)(nil), "pb.SubscriptionRequest")
+	proto.RegisterType((*SubscriptionResponse)(nil), "pb.SubscriptionResponse")
+	proto.RegisterType((*Num)(nil), "pb.Num")
+	proto.RegisterType((*AssignedIds)(nil), "pb.AssignedIds")
+	proto.RegisterType((*SnapshotMeta)(nil), "pb.SnapshotMeta")
+	proto.RegisterType((*Status)(nil), "pb.Status")
+	proto.RegisterType((*BackupRequest)(nil), "pb.BackupRequest")
+	proto.RegisterType((*ExportRequest)(nil), "pb.ExportRequest")
+	proto.RegisterType((*ExportResponse)(nil), "pb.ExportResponse")
+	proto.RegisterType((*BackupKey)(nil), "pb.BackupKey")
+	proto.RegisterType((*BackupPostingList)(nil), "pb.BackupPostingList")
+	proto.RegisterType((*UpdateGraphQLSchemaRequest)(nil), "pb.UpdateGraphQLSchemaRequest")
+	proto.RegisterType((*UpdateGraphQLSchemaResponse)(nil), "pb.UpdateGraphQLSchemaResponse")
+	proto.RegisterType((*IdempotencyRecord)(nil), "pb.IdempotencyRecord")
+	proto.RegisterType((*StrictSchema)(nil), "pb.StrictSchema")
+	proto.RegisterType((*BlobChunk)(nil), "pb.BlobChunk")
+	proto.RegisterType((*BlobRequest)(nil), "pb.BlobRequest")
+	proto.RegisterType((*ReplicationState)(nil), "pb.ReplicationState")
+	proto.RegisterType((*ReplicationRequest)(nil), "pb.ReplicationRequest")
+	proto.RegisterType((*KeyRange)(nil), "pb.KeyRange")
+	proto.RegisterType((*FreezePoint)(nil), "pb.FreezePoint")
+	proto.RegisterType((*FrozenGroup)(nil), "pb.FrozenGroup")
+	proto.RegisterType((*MoveProgress)(nil), "pb.MoveProgress")
+}
+
+func init() { proto.RegisterFile("pb.proto", fileDescriptor_f80abaa17e25ccc8) }
+
+var fileDescriptor_f80abaa17e25ccc8 = []byte{
+	// 6031 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xd4, 0x3b, 0x4d, 0x6f, 0x24, 0xd7,
+	0x71, 0x3b, 0xdf, 0xd3, 0x35, 0x33, 0xe4, 0xb0, 0x77, 0xb5, 0x1a, 0x8d, 0xa4, 0x25, 0xd5, 0x92,
+	0x2c, 0x6a, 0xe5, 0xe5, 0xee, 0x52, 0xfe, 0x92, 0x1c, 0x03, 0xe1, 0xd7, 0xae, 0xe8, 0xe5, 0x92,
+	0xf4, 0xe3, 0xec, 0xca, 0xf6, 0x21, 0x83, 0x9e, 0xee, 0x37, 0x64, 0x9b, 0x3d, 0xdd, 0xad, 0xee,
+	0x1e, 0x9a, 0xd4, 0x29, 0x39, 0xe5, 0x12, 0x20, 0xa7, 0x7c, 0x9c, 0x12, 0x20, 0xf9, 0x05, 0x4e,
+	0x6e, 0x06, 0x9c, 0x53, 0x90, 0x18, 0x01, 0x02, 0xe4, 0x9c, 0x83, 0x90, 0x38, 0x39, 0x29, 0x87,
+	0x1c, 0x02, 0xe4, 0x1c, 0x54, 0xd5, 0xeb, 0xaf, 0xe1, 0x70, 0x97, 0x12, 0xe0, 0x43, 0x4e, 0xf3,
+	0xea, 0xe3, 0x7d, 0xf4, 0x7b, 0x55, 0xf5, 0xaa, 0xea, 0xd5, 0x40, 0x33, 0x18, 0xad, 0x05, 0xa1,
+	0x1f, 0xfb, 0x7a, 0x39, 0x18, 0xf5, 0x35, 0x33, 0x70, 0x18, 0xec, 0xdf, 0x3d, 0x76, 0xe2, 0x93,
+	0xe9, 0x68, 0xcd, 0xf2, 0x27, 0xf7, 0xed, 0xe3, 0xd0, 0x0c, 0x4e, 0xee, 0x39, 0xfe, 0xfd, 0x91,
+	0x69, 0x1f, 0xcb, 0xf0, 0xfe, 0xd9, 0xfa, 0xfd, 0x60, 0x74, 0x3f, 0xe9, 0xda, 0xbf, 0x97, 0xe3,
+	0x3d, 0xf6, 0x8f, 0xfd, 0xfb, 0x84, 0x1e, 0x4d, 0xc7, 0x04, 0x11, 0x40, 0x2d, 0x66, 0x37, 0xfa,
+	0x50, 0xdd, 0x73, 0xa2, 0x58, 0xd7, 0xa1, 0x3a, 0x75, 0xec, 0xa8, 0x57, 0x5a, 0xa9, 0xac, 0xd6,
+	0x05, 0xb5, 0x8d, 0xa7, 0xa0, 0x0d, 0xcc, 0xe8, 0xf4, 0xb9, 0xe9, 0x4e, 0xa5, 0xde, 0x85, 0xca,
+	0x99, 0xe9, 0xf6, 0x4a, 0x2b, 0xa5, 0xd5, 0xb6, 0xc0, 0xa6, 0xbe, 0x06, 0xcd, 0x33, 0xd3, 0x1d,
+	0xc6, 0x17, 0x81, 0xec, 0x95, 0x57, 0x4a, 0xab, 0x0b, 0xeb, 0x37, 0xd7, 0x82, 0xd1, 0xda, 0xa1,
+	0x1f, 0xc5, 0x8e, 0x77, 0xbc, 0xf6, 0xdc, 0x74, 0x07, 0x17, 0x81, 0x14, 0x8d, 0x33, 0x6e, 0x18,
+	0x07, 0xd0, 0x3a, 0x0a, 0xad, 0x47, 0x53, 0xcf, 0x8a, 0x1d, 0xdf, 0xc3, 0x19, 0x3d, 0x73, 0x22,
+	0x69, 0x44, 0x4d, 0x50, 0x1b, 0x71, 0x66, 0x78, 0x1c, 0xf5, 0x2a, 0x2b, 0x15, 0xc4, 0x61, 0x5b,
+	0xef, 0x41, 0xc3, 0x89, 0xb6, 0xfc, 0xa9, 0x17, 0xf7, 0xaa, 0x2b, 0xa5, 0xd5, 0xa6, 0x48, 0x40,
+	0xe3, 0x8f, 0xab, 0x50, 0xfb, 0xd1, 0x54, 0x86, 0x17, 0xd4, 0x2f, 0x8e, 0xc3, 0x64, 0x2c, 0x6c,
+	0xeb, 0xb7, 0xa0, 0xe6, 0x9a, 0xde, 0x71, 0xd4, 0x2b, 0xd3, 0x60, 0x0c, 0xe8, 0xaf, 0x83, 0x66,
+	0x8e, 0x63, 0x19, 0x0e, 0xa7, 0x8e, 0xdd, 0xab, 0xac, 0x94, 0x56, 0xeb, 0xa2, 0x49, 0x88, 0x67,
+	0x8e, 0xad, 0xbf, 0x06, 0x4d, 0xdb, 0x1f, 0x5a, 0xf9, 0xb9, 0x6c, 0x9f, 0xe6, 0xd2, 0xdf, 0x86,
+	0xe6, 0xd4, 0xb1, 0x87, 0xae, 0x13, 0xc5, 0xbd, 0xda, 0x4a, 0x69, 0xb5, 0xb5, 0xde, 0xc4, 0x8f,
+	0xc5, 0xbd, 0x13, 0x8d, 0xa9, 0x63, 0x63, 0x43, 0xbf, 0x0b, 0xcd, 0x28, 0xb4, 0x86, 0xe3, 0xa9,
+	0x67, 0xf5, 0xea, 0xc4, 0xb4, 0x88, 0x4c, 0xb9, 0xaf, 0x16, 0x8d, 0x88, 0x01, 0xfc, 0xac, 0x50,
+	0x9e, 0xc9, 0x30, 0x92, 0xbd, 0x06, 0x4f, 0xa5, 0x40, 0xfd, 0x01, 0xb4, 0xc6, 0xa6, 0x25, 0xe3,
+	0x61, 0x60, 0x86, 0xe6, 0xa4, 0xd7, 0xcc, 0x06, 0x7a, 0x84, 0xe8, 0x43, 0xc4, 0x46, 0x02, 0xc6,
+	0x29, 0xa0, 0x7f, 0x08, 0x1d, 0x82, 0xa2, 0xe1, 0xd8, 0x71, 0x63, 0x19, 0xf6, 0x34, 0xea, 0xb3,
+	0x40, 0x7d, 0x08, 0x33, 0x08, 0xa5, 0x14, 0x6d, 0x66, 0x62, 0x8c, 0xfe, 0x26, 0x80, 0x3c, 0x0f,
+	0x4c, 0xcf, 0x1e, 0x9a, 0xae, 0xdb, 0x03, 0x5a, 0x83, 0xc6, 0x98, 0x0d, 0xd7, 0xd5, 0x5f, 0xc5,
+	0xf5, 0x99, 0xf6, 0x30, 0x8e, 0x7a, 0x9d, 0x95, 0xd2, 0x6a, 0x55, 0xd4, 0x11, 0x1c, 0x44, 0xb8,
+	0xaf, 0x96, 0x69, 0x9d, 0xc8, 0xde, 0xc2, 0x4a, 0x69, 0xb5, 0x26, 0x18, 0x40, 0xec, 0xd8, 0x09,
+	0xa3, 0xb8, 0xb7, 0xc8, 0x58, 0x02, 0xf4, 0x77, 0x61, 0xc1, 0x76, 0x50, 0x1c, 0xac, 0x58, 0x6d,
+	0x6b, 0x97, 0xe6, 0xe9, 0x24, 0x58, 0xde, 0xdc, 0xfb, 0xd0, 0x92, 0xf6, 0xb1, 0x4c, 0x56, 0xbf,
+	0x34, 0x77, 0xf5, 0x80, 0x2c, 0x6a, 0xed, 0xb7, 0xa1, 0x1e, 0x99, 0x93, 0xc0, 0x95, 0x3d, 0x9d,
+	0xa6, 0x53, 0x90, 0xb1, 0x0e, 0x1a, 0x49, 0x2b, 0x9d, 0xc6, 0xbb, 0x50, 0x3f, 0x43, 0x80, 0x85,
+	0xba, 0xb5, 0xde, 0xc1, 0x01, 0x53, 0x81, 0x16, 0x8a, 0x68, 0xdc, 0x81, 0xe6, 0x9e, 0xe9, 0x1d,
+	0x27, 0x5a, 0x80, 0x62, 0x42, 0x1d, 0x34, 0x41, 0x6d, 0xe3, 0x9f, 0xcb, 0x50, 0x17, 0x32, 0x9a,
+	0xba, 0xb1, 0xfe, 0x1e, 0x00, 0x0a, 0xc1, 0xc4, 0x8c, 0x43, 0xe7, 0x5c, 0x8d, 0x9a, 0x89, 0x81,
+	0x36, 0x75, 0xec, 0xa7, 0x44, 0xd2, 0x1f, 0x40, 0x9b, 0x46, 0x4f, 0x58, 0xcb, 0xd9, 0x02, 0xd2,
+	0xf5, 0x89, 0x16, 0xb1, 0xa8, 0x1e, 0xb7, 0xa1, 0x4e, 0x1b, 0xc4, 0xb2, 0xdf, 0x11, 0x0a, 0xc2,
+	0x1d, 0x74, 0xbc, 0x18, 0xe5, 0xc2, 0x8a, 0x87, 0xb6, 0x8c, 0x12, 0xc1, 0xec, 0xa4, 0xd8, 0x6d,
+	0x19, 0xc5, 0xfa, 0x43, 0xe0, 0xc3, 0x4d, 0x26, 0xac, 0xad, 0x54, 0xd2, 0x2d, 0xa4, 0x43, 0xe7,
+	0x19, 0x89, 0x47, 0xcd, 0x78, 0x0f, 0x5a, 0xf8, 0x7d, 0x49, 0x8f, 0x3a, 0xf5, 0x68, 0xd3, 0xd7,
+	0xa8, 0xed, 0x10, 0x80, 0x0c, 0x8a, 0x1d, 0xb7, 0x06, 0x85, 0x9f, 0x85, 0x95, 0xda, 0xfa, 0x87,
+	0xd0, 0x4d, 0x8f, 0x77, 0x34, 0xb5, 0x4e, 0x65, 0x1c, 0xf5, 0x9a, 0x33, 0xbb, 0xb2, 0x98, 0x70,
+	0x6c, 0x32, 0x83, 0xb1, 0x03, 0xb5, 0x83, 0xd0, 0x96, 0xe1, 0x5c, 0xa5, 0xd5, 0xa1, 0x6a, 0xcb,
+	0xc8, 0x22, 0x7b, 0xd2, 0x14, 0xd4, 0xce, 0x14, 0xb9, 0x92, 0x53, 0x64, 0xe3, 0x2f, 0x4a, 0xd0,
+	0x3a, 0xf2, 0xc3, 0xf8, 0xa9, 0x8c, 0x22, 0xf3, 0x58, 0xea, 0xcb, 0x50, 0xf3, 0x71, 0x58, 0x75,
+	0x2c, 0x1a, 0x2e, 0x80, 0xe6, 0x11, 0x8c, 0x9f, 0x39, 0xbc, 0xf2, 0xd5, 0x87, 0x87, 0x02, 0x4e,
+	0xb2, 0x5a, 0x51, 0x02, 0x8e, 0x00, 0x1e, 0x90, 0x3f, 0x1e, 0x47, 0x92, 0x0f, 0xa0, 0x26, 0x14,
+	0x74, 0xa5, 0x9e, 0x18, 0xdf, 0x06, 0xc0, 0xf5, 0x7d, 0x45, 0xd1, 0x31, 0xfe, 0xb0, 0x04, 0x2d,
+	0x61, 0x8e, 0xe3, 0x2d, 0xdf, 0x8b, 0xe5, 0x79, 0xac, 0x2f, 0x40, 0xd9, 0xb1, 0x69, 0x8f, 0xea,
+	0xa2, 0xec, 0xd8, 0xb8, 0xba, 0xe3, 0xd0, 0x9f, 0x06, 0xb4, 0x45, 0x1d, 0xc1, 0x00, 0xed, 0xa5,
+	0x6d, 0x87, 0xbd, 0x8a, 0xda, 0x4b, 0xdb, 0x0e, 0xf5, 0x65, 0x68, 0x45, 0x9e, 0x19, 0x44, 0x27,
+	0x7e, 0x8c, 0xab, 0xab, 0xd2, 0xea, 0x20, 0x41, 0x0d, 0x22, 0xb4, 0x00, 0x4e, 0x34, 0x74, 0xa5,
+	0x19, 0x7a, 0x32, 0x24, 0xab, 0xd6, 0x14, 0x9a, 0x13, 0xed, 0x31, 0xc2, 0xf8, 0x45, 0x05, 0xea,
+	0x4f, 0xe5, 0x64, 0x24, 0xc3, 0x4b, 0x8b, 0x78, 0x00, 0x4d, 0x9a, 0x77, 0xe8, 0xd8, 0xbc, 0x8e,
+	0xcd, 0x57, 0xbe, 0xfc, 0x62, 0x79, 0x89, 0x70, 0xbb, 0xf6, 0x37, 0xfd, 0x89, 0x13, 0xcb, 0x49,
+	0x10, 0x5f, 0x88, 0x86, 0x42, 0xcd, 0x5d, 0xe0, 0x6d, 0xa8, 0xbb, 0xd2, 0xc4, 0x33, 0x63, 0x99,
+	0x56, 0x90, 0x7e, 0x0f, 0x1a, 0xe6, 0x64, 0x68, 0x4b, 0xd3, 0xe6, 0x45, 0x6d, 0xde, 0xfa, 0xf2,
+	0x8b, 0xe5, 0xae, 0x39, 0xd9, 0x96, 0x66, 0x7e, 0xec, 0x3a, 0x63, 0xf4, 0x8f, 0x50, 0x90, 0xa3,
+	0x78, 0x38, 0x0d, 0x6c, 0x33, 0x96, 0x64, 0x78, 0xab, 0x9b, 0xbd, 0x2f, 0xbf, 0x58, 0xbe, 0x85,
+	0xe8, 0x67, 0x84, 0xcd, 0x75, 0x83, 0x0c, 0xab, 0xef, 0xc2, 0x92, 0xe5, 0x4e, 0x23, 0xbc, 0x0f,
+	0x1c, 0x6f, 0xec, 0x0f, 0x7d, 0xcf, 0xbd, 0xa0, 0x63, 0x6c, 0x6e, 0xbe, 0xf9, 0xe5, 0x17, 0xcb,
+	0xaf, 0x29, 0xe2, 0xae, 0x37, 0xf6, 0x0f, 0x3c, 0xf7, 0x22, 0x37, 0xca, 0xe2, 0x0c, 0x49, 0xff,
+	0x5d, 0x58, 0x18, 0xfb, 0xa1, 0x25, 0x87, 0xe9, 0xc6, 0x2c, 0xd0, 0x38, 0xfd, 0x2f, 0xbf, 0x58,
+	0xbe, 0x4d, 0x94, 0xc7, 0x97, 0x76, 0xa7, 0x9d, 0xc7, 0xe3, 0x8d, 0x90, 0x9c, 0xc5, 0x22, 0xdf,
+	0x08, 0x0a, 0xd4, 0x57, 0x61, 0xd1, 0x96, 0x96, 0x3f, 0x99, 0x38, 0x51, 0xe4, 0xf8, 0x9e, 0xe3,
+	0x1d, 0x2b, 0x3b, 0x3a, 0x8b, 0x36, 0xfe, 0xb5, 0x0c, 0x35, 0x1a, 0x4f, 0x7f, 0x00, 0x8d, 0x09,
+	0x1d, 0x5e, 0x62, 0xfe, 0x6e, 0xa3, 0xb4, 0x11, 0x6d, 0x8d, 0x4f, 0x35, 0xda, 0xf1, 0xe2, 0xf0,
+	0x42, 0x24, 0x6c, 0xd8, 0x23, 0x36, 0x47, 0x2e, 0x2a, 0x71, 0x79, 0xb6, 0xc7, 0x80, 0x09, 0xaa,
+	0x87, 0x62, 0x9b, 0x95, 0xb0, 0xca, 0x25, 0x09, 0xeb, 0x43, 0xd3, 0x3a, 0x91, 0xd6, 0x69, 0x34,
+	0x9d, 0x28, 0xf9, 0x4b, 0x61, 0x7d, 0x05, 0x6a, 0xae, 0x6f, 0xda, 0x91, 0xb2, 0x55, 0xc0, 0xd6,
+	0x19, 0x07, 0x16, 0x4c, 0xe8, 0x3f, 0x82, 0x76, 0x7e, 0xa5, 0xe8, 0x82, 0x9c, 0xca, 0x0b, 0x12,
+	0xc3, 0xaa, 0xc0, 0x26, 0x8e, 0x41, 0x46, 0x94, 0x84, 0x50, 0x8d, 0xc1, 0x5d, 0x04, 0x13, 0x3e,
+	0x2e, 0x7f, 0xaf, 0x84, 0xe3, 0xe4, 0xd7, 0x9f, 0x1f, 0x47, 0xbb, 0x7a, 0x9c, 0x64, 0x2d, 0xe9,
+	0x38, 0x86, 0x0f, 0x8d, 0x3d, 0xc7, 0x92, 0x5e, 0x44, 0x8e, 0xca, 0x34, 0x92, 0xa9, 0xed, 0xc2,
+	0x36, 0x7e, 0xec, 0xc4, 0x3c, 0xdf, 0xf7, 0x6d, 0x19, 0xd1, 0x38, 0x55, 0x91, 0xc2, 0x48, 0x93,
+	0xe7, 0x81, 0x13, 0x5e, 0x0c, 0x78, 0x9b, 0x2a, 0x22, 0x85, 0xf1, 0xdc, 0xa5, 0x87, 0x93, 0xd9,
+	0x89, 0xd3, 0xa1, 0x40, 0xe3, 0x1f, 0x6b, 0xd0, 0xfe, 0xa9, 0x0c, 0xfd, 0xc3, 0xd0, 0x0f, 0xfc,
+	0xc8, 0x74, 0xf5, 0x8d, 0xe2, 0x86, 0xf3, 0xc1, 0xae, 0xe0, 0x6a, 0xf3, 0x6c, 0x6b, 0x47, 0xe9,
+	0x09, 0xf0, 0x81, 0xe5, 0x8f, 0xc4, 0x80, 0x3a, 0x1f, 0xf8, 0x9c, 0x3d, 0x53, 0x14, 0xe4, 0xe1,
+	0x23, 0xee, 0x55, 0x32, 0x1e, 0xb5, 0x1f, 0x8a, 0xa2, 0xdf, 0x01, 0x98, 0x98, 0xe7, 0x7b, 0xd2,
+	0x8c, 0xe4, 0xae, 0x9d, 0x18, 0x97, 0x0c, 0xa3, 0x76, 0x63, 0x70, 0xee, 0x0d, 0xa2, 0x5e, 0x2d,
+	0xdd, 0x0d, 0x82, 0xf5, 0x37, 0x40, 0x9b, 0x98, 0xe7, 0x68, 0xe5, 0x76, 0x6d, 0xd6, 0x57, 0x91,
+	0x21, 0xf4, 0xb7, 0xa0, 0x12, 0x9f, 0x7b, 0xbd, 0x86, 0xf2, 0x7b, 0xd0, 0x0d, 0x1e, 0x9c, 0x7b,
+	0xca, 0x1e, 0x0a, 0xa4, 0x25, 0x27, 0xd8, 0xcc, 0x4e, 0xb0, 0x0b, 0x15, 0xcb, 0xb1, 0xc9, 0xf1,
+	0xd1, 0x04, 0x36, 0xf5, 0x77, 0xa1, 0xe1, 0xf2, 0x69, 0x91, 0x73, 0xd3, 0x5a, 0x6f, 0xb1, 0xb9,
+	0x25, 0x94, 0x48, 0x68, 0xfa, 0x77, 0xa1, 0xe5, 0xd8, 0x72, 0x12, 0xf8, 0xb1, 0xf4, 0xac, 0x8b,
+	0x5e, 0x8b, 0x58, 0x5f, 0x41, 0xd6, 0xdd, 0x0c, 0x2d, 0xa4, 0xe5, 0x87, 0xb6, 0xc8, 0x73, 0xea,
+	0xdf, 0x86, 0x4e, 0x14, 0x87, 0x8e, 0x15, 0x0f, 0x23, 0xeb, 0x44, 0x4e, 0xcc, 0x5e, 0x9b, 0xba,
+	0x76, 0xc9, 0xe3, 0x23, 0xc2, 0x11, 0xe1, 0x45, 0x3b, 0xca, 0x41, 0xfa, 0x77, 0xa0, 0x15, 0xca,
+	0xc0, 0x75, 0x2c, 0x13, 0xfd, 0x41, 0x32, 0x36, 0xad, 0xf5, 0x5b, 0xd8, 0x49, 0x64, 0xe8, 0xa3,
+	0xd8, 0x8c, 0xa5, 0xc8, 0x33, 0xea, 0xef, 0x41, 0x7d, 0x1c, 0x4a, 0xf9, 0x39, 0xfb, 0x5d, 0x89,
+	0x43, 0x48, 0x98, 0x43, 0xdf, 0xf1, 0x62, 0xa1, 0xc8, 0xe8, 0x31, 0x84, 0xd2, 0xc5, 0x53, 0x18,
+	0xaa, 0x0e, 0x8b, 0xb4, 0x29, 0x1d, 0x85, 0xe5, 0x3e, 0xfa, 0x3d, 0xd0, 0x73, 0x5f, 0x33, 0xb4,
+	0xa6, 0xb1, 0x3f, 0x1e, 0x93, 0x59, 0xa9, 0x88, 0xa5, 0x1c, 0x65, 0x8b, 0x08, 0xfd, 0x1f, 0xc0,
+	0xe2, 0x8c, 0x54, 0xe5, 0xd5, 0xa8, 0xc3, 0x87, 0x70, 0x2b, 0xaf, 0x46, 0xd5, 0xbc, 0xea, 0xfc,
+	0xaa, 0x06, 0x8b, 0x4a, 0x97, 0x4f, 0x9c, 0x80, 0x3e, 0x0f, 0xe5, 0x9e, 0xae, 0x56, 0xa5, 0x46,
+	0x55, 0x91, 0x80, 0xfa, 0x77, 0xa1, 0x4e, 0x56, 0x34, 0x31, 0x44, 0xcb, 0x99, 0x8c, 0xa6, 0xdd,
+	0xd9, 0x30, 0x29, 0x01, 0x57, 0xec, 0xfa, 0xb7, 0xa0, 0xf6, 0xb9, 0x0c, 0x7d, 0x76, 0x15, 0x5a,
+	0xeb, 0x77, 0xe6, 0xf5, 0x43, 0x4d, 0x51, 0xdd, 0x98, 0xf9, 0xb7, 0x28, 0xca, 0xef, 0xa0, 0x73,
+	0x30, 0xf1, 0xcf, 0xa4, 0xdd, 0x6b, 0x64, 0x56, 0x4e, 0x69, 0x5b, 0x42, 0x4a, 0x64, 0xb7, 0x39,
+	0x57, 0x76, 0xb5, 0xeb, 0xcb, 0x2e, 0xac, 0x54, 0xbe, 0xae, 0xec, 0xb6, 0xbe, 0x8e, 0xec, 0xb6,
+	0xaf, 0x2b, 0xbb, 0xdf, 0x82, 0x0e, 0x8b, 0xe2, 0x30, 0x40, 0x51, 0x45, 0x4f, 0xa9, 0x32, 0x4f,
+	0x84, 0xdb, 0xe3, 0x0c, 0x88, 0xfa, 0xdb, 0xd0, 0xca, 0x9d, 0xf1, 0x1c, 0x71, 0x5b, 0x2e, 0x5a,
+	0x6d, 0x2d, 0xbd, 0xae, 0xf2, 0xc6, 0x7f, 0x1b, 0x20, 0x3b, 0xf1, 0xaf, 0x7b, 0x85, 0x18, 0x7f,
+	0x50, 0x82, 0xc5, 0x2d, 0xdf, 0xf3, 0xa4, 0x95, 0x7e, 0x62, 0xce, 0x92, 0x96, 0xae, 0xb4, 0xa4,
+	0xef, 0x43, 0x2d, 0x42, 0x66, 0x35, 0xfa, 0xcd, 0x39, 0x02, 0x29, 0x98, 0x03, 0x2f, 0xd3, 0x89,
+	0x79, 0x3e, 0x0c, 0xa4, 0x67, 0xe3, 0x05, 0x5f, 0x49, 0xc5, 0xf0, 0x90, 0x31, 0xc6, 0x9f, 0x94,
+	0x01, 0x3e, 0x91, 0xa6, 0x1b, 0x9f, 0xa0, 0xd3, 0x81, 0x52, 0xe9, 0x78, 0x51, 0x6c, 0x7a, 0x56,
+	0x12, 0x43, 0xa7, 0x30, 0xaa, 0x16, 0x7a, 0x58, 0x32, 0xe2, 0x9b, 0x48, 0x13, 0x09, 0x48, 0x91,
+	0x53, 0x6c, 0xc6, 0xd3, 0x48, 0x79, 0x62, 0x0a, 0xca, 0xdc, 0xca, 0x2a, 0xa1, 0x19, 0xc0, 0x71,
+	0x30, 0x26, 0xc5, 0xc3, 0xae, 0xf1, 0x38, 0x0a, 0xc4, 0x71, 0xa6, 0x41, 0xec, 0x4c, 0xd8, 0xdf,
+	0xaa, 0x08, 0x05, 0xe1, 0xaa, 0xd0, 0xbf, 0xda, 0xb1, 0x4e, 0x7c, 0xb2, 0xe0, 0x15, 0x91, 0xc2,
+	0x38, 0x9a, 0xef, 0x1d, 0xfb, 0xf8, 0x75, 0x4d, 0x72, 0xe5, 0x13, 0x90, 0xbf, 0xc5, 0x96, 0xe7,
+	0x48, 0xd2, 0x88, 0x94, 0xc2, 0xb8, 0x2f, 0x52, 0x0e, 0xc7, 0xd2, 0x8c, 0xa7, 0xa1, 0x8c, 0x48,
+	0xc8, 0x35, 0x01, 0x52, 0x3e, 0x52, 0x18, 0xe3, 0xbf, 0xcb, 0x50, 0xe7, 0xcb, 0xa9, 0xe0, 0x97,
+	0x96, 0xae, 0xe5, 0x97, 0xbe, 0x01, 0x5a, 0x10, 0x4a, 0xdb, 0xb1, 0x92, 0x43, 0xd2, 0x44, 0x86,
+	0xa0, 0xa8, 0x16, 0x5d, 0x34, 0xda, 0xac, 0xa6, 0x60, 0x00, 0xb1, 0x51, 0x60, 0x5a, 0x52, 0x7d,
+	0x20, 0x03, 0xb8, 0x23, 0xac, 0xd0, 0xa4, 0xc8, 0x4d, 0xa1, 0x20, 0xfd, 0x43, 0xd0, 0x28, 0x40,
+	0x20, 0xdf, 0x52, 0x23, 0x9f, 0xf0, 0xf6, 0x97, 0x5f, 0x2c, 0xeb, 0x88, 0x9c, 0x71, 0x2a, 0x9b,
+	0x09, 0x0e, 0x5d, 0x60, 0xec, 0x8c, 0x97, 0x3c, 0x90, 0x3f, 0x4b, 0x2e, 0x30, 0xa2, 0x06, 0x51,
+	0xde, 0x05, 0x66, 0x8c, 0xfe, 0x0d, 0x58, 0xfc, 0x6c, 0x2a, 0x43, 0x47, 0x46, 0xc3, 0x40, 0x86,
+	0xc3, 0x89, 0xe3, 0x91, 0x46, 0x57, 0x45, 0x47, 0xa1, 0x0f, 0x65, 0xf8, 0xd4, 0xf1, 0xf4, 0xbb,
+	0xb0, 0x34, 0x99, 0xc6, 0xa4, 0x94, 0x19, 0x67, 0x9b, 0x38, 0x17, 0x53, 0x82, 0xe2, 0x7d, 0x0d,
+	0x9a, 0xde, 0x74, 0x32, 0x3c, 0x95, 0x17, 0x49, 0x64, 0xd3, 0xf0, 0xa6, 0x93, 0x27, 0xf2, 0x22,
+	0x32, 0xfe, 0xab, 0x0c, 0xed, 0x6d, 0x27, 0x94, 0x56, 0x2c, 0xed, 0x1d, 0xfb, 0x98, 0xbe, 0x5d,
+	0x7a, 0xb1, 0x13, 0x5f, 0xa8, 0x18, 0x41, 0x41, 0x69, 0x88, 0x57, 0x2e, 0xe6, 0x65, 0x58, 0xe1,
+	0x2a, 0x94, 0x4a, 0x62, 0x40, 0x5f, 0x07, 0xa0, 0x06, 0xa7, 0x93, 0xaa, 0x57, 0xa7, 0x93, 0x34,
+	0x62, 0xc3, 0x26, 0xae, 0x90, 0xfb, 0x38, 0x1c, 0x28, 0xd4, 0x29, 0xd7, 0x34, 0x45, 0x93, 0x4d,
+	0x31, 0xe3, 0x48, 0xba, 0x24, 0x9d, 0x14, 0x33, 0x8e, 0xa4, 0x9b, 0x86, 0xf7, 0x0d, 0x5e, 0x0e,
+	0xb6, 0xf5, 0xb7, 0xa1, 0xec, 0x07, 0xbd, 0x66, 0x36, 0x61, 0xfe, 0xc3, 0xd6, 0x0e, 0x02, 0x51,
+	0xf6, 0x03, 0x54, 0x75, 0xce, 0x9d, 0x90, 0x74, 0xa2, 0xaa, 0xa3, 0x57, 0x42, 0x91, 0xb5, 0x50,
+	0x14, 0xdd, 0x80, 0xb6, 0xe9, 0xba, 0xfe, 0xcf, 0xa5, 0x7d, 0x18, 0x4a, 0x3b, 0x11, 0xd4, 0x02,
+	0x0e, 0xb3, 0x4f, 0x23, 0xd7, 0x1f, 0x0d, 0x23, 0xe7, 0x73, 0xa9, 0x4e, 0xa8, 0x89, 0x88, 0x23,
+	0xe7, 0x73, 0x69, 0xdc, 0x86, 0xf2, 0x41, 0xa0, 0x37, 0xa0, 0x72, 0xb4, 0x33, 0xe8, 0xde, 0xc0,
+	0xc6, 0xf6, 0xce, 0x5e, 0xb7, 0x64, 0xfc, 0x5d, 0x0d, 0xb4, 0xa7, 0xc9, 0xe1, 0xe0, 0x47, 0x17,
+	0x45, 0x3c, 0x93, 0xe5, 0xd7, 0xa0, 0x19, 0xc5, 0x66, 0x48, 0xae, 0x21, 0xdf, 0xc0, 0x0d, 0x82,
+	0x49, 0x40, 0x6a, 0x98, 0x3e, 0x49, 0x2e, 0xc6, 0xee, 0xec, 0x87, 0x0a, 0x26, 0xeb, 0xab, 0x50,
+	0x57, 0x37, 0x42, 0x35, 0x63, 0x64, 0xeb, 0xcf, 0x21, 0x93, 0x50, 0x74, 0xfd, 0x1d, 0xa8, 0xe1,
+	0x51, 0x45, 0xbd, 0x7a, 0x96, 0x6a, 0xc0, 0x53, 0x51, 0x6c, 0x4c, 0x44, 0x39, 0xb6, 0x43, 0x3f,
+	0x18, 0xfa, 0x01, 0x6d, 0xfa, 0x02, 0xdf, 0x16, 0xe9, 0xd7, 0xac, 0x6d, 0x87, 0x7e, 0x70, 0x10,
+	0x88, 0xba, 0x4d, 0xbf, 0x18, 0x91, 0x12, 0x3b, 0x0b, 0x08, 0x5f, 0x88, 0x1a, 0x62, 0x38, 0x07,
+	0xb9, 0x0a, 0xcd, 0x89, 0x8c, 0x4d, 0xdb, 0x8c, 0x4d, 0x75, 0x2f, 0x52, 0xbe, 0xe2, 0xa9, 0xc2,
+	0x89, 0x94, 0x8a, 0x6a, 0x1d, 0x99, 0x67, 0x92, 0xae, 0x1b, 0xd2, 0x20, 0x4d, 0x64, 0x08, 0x34,
+	0x29, 0xa1, 0xef, 0xba, 0x23, 0xd3, 0x3a, 0x1d, 0xc6, 0x3e, 0x1d, 0x84, 0x26, 0x20, 0x41, 0x0d,
+	0x7c, 0x7d, 0x0d, 0x5a, 0x74, 0x4e, 0xd6, 0xc9, 0xd4, 0x3b, 0x8d, 0x7a, 0xed, 0x2c, 0x7d, 0xb3,
+	0xe9, 0xfa, 0xa3, 0x2d, 0xc4, 0x0a, 0x18, 0x25, 0x4d, 0x0a, 0x84, 0x42, 0x89, 0x19, 0xcc, 0xe1,
+	0x38, 0xf4, 0x27, 0xbd, 0x8e, 0x1a, 0x90, 0x50, 0x8f, 0x42, 0x7f, 0x82, 0x07, 0xaf, 0x18, 0x62,
+	0x9f, 0x1c, 0x38, 0x4d, 0x34, 0x19, 0x31, 0xf0, 0xd1, 0x63, 0x8b, 0x1d, 0x19, 0x0e, 0x33, 0x43,
+	0xa4, 0x3c, 0x36, 0xc4, 0x1e, 0x26, 0x48, 0x94, 0x5e, 0x44, 0x90, 0x8f, 0xa6, 0x09, 0x6a, 0xe3,
+	0xc4, 0xd4, 0xd5, 0x1f, 0xfd, 0x4c, 0x5a, 0x31, 0x65, 0xce, 0x34, 0x01, 0x88, 0x3a, 0x20, 0x8c,
+	0xfe, 0x10, 0x6e, 0xd9, 0x0e, 0x5d, 0x5a, 0x66, 0x78, 0x91, 0x9b, 0x41, 0x27, 0xce, 0x9b, 0x19,
+	0x2d, 0x9b, 0xe7, 0x0e, 0x40, 0x86, 0xee, 0xdd, 0x24, 0x2d, 0xcd, 0x61, 0x8c, 0xfb, 0x50, 0xe7,
+	0x63, 0xd3, 0x9b, 0x50, 0xdd, 0x3f, 0xd8, 0xdf, 0x61, 0x61, 0xdd, 0xd8, 0xdb, 0xeb, 0x96, 0x10,
+	0xb5, 0xbd, 0x31, 0xd8, 0xe8, 0x96, 0xb1, 0x35, 0xf8, 0xc9, 0xe1, 0x4e, 0xb7, 0x62, 0xfc, 0x53,
+	0x09, 0x9a, 0xc9, 0x19, 0xe9, 0x1f, 0x03, 0xe0, 0x2a, 0x86, 0x27, 0x8e, 0x97, 0x46, 0x30, 0xaf,
+	0xe7, 0x4f, 0x71, 0x0d, 0x57, 0xf2, 0x09, 0x52, 0xd9, 0x49, 0xd3, 0x82, 0x04, 0xee, 0x1f, 0xc1,
+	0x42, 0x91, 0x38, 0x27, 0x94, 0xfb, 0x20, 0x7f, 0x9f, 0x2f, 0xac, 0xbf, 0x52, 0x18, 0x1a, 0x7b,
+	0x92, 0x15, 0xc9, 0x5d, 0xed, 0xf7, 0xa0, 0x99, 0xa0, 0xf5, 0x16, 0x34, 0xb6, 0x77, 0x1e, 0x6d,
+	0x3c, 0xdb, 0x43, 0x05, 0x04, 0xa8, 0x1f, 0xed, 0xee, 0x3f, 0xde, 0xdb, 0xe1, 0xcf, 0xda, 0xdb,
+	0x3d, 0x1a, 0x74, 0xcb, 0xc6, 0xaf, 0x4a, 0xd0, 0x4c, 0x3c, 0x61, 0xfd, 0x7d, 0x74, 0x61, 0x29,
+	0x2e, 0x51, 0x3e, 0x00, 0xb9, 0x34, 0xb9, 0xf4, 0x8d, 0x48, 0xe8, 0x68, 0x91, 0xe8, 0x4a, 0x4b,
+	0x7c, 0x63, 0x02, 0xf2, 0xd9, 0xa3, 0x4a, 0x21, 0xcb, 0x8a, 0x89, 0x30, 0xdf, 0x93, 0x2a, 0x22,
+	0xa4, 0x36, 0xe9, 0xb7, 0xe3, 0x59, 0x74, 0x2b, 0xd4, 0x94, 0x7e, 0x23, 0x3c, 0x40, 0xbd, 0x6d,
+	0x86, 0xd2, 0x92, 0x0e, 0x7a, 0x9a, 0xb9, 0x4c, 0xde, 0x13, 0x79, 0x21, 0x4c, 0xef, 0x58, 0x8a,
+	0x94, 0x6a, 0xfc, 0xa2, 0x0a, 0x0b, 0x42, 0x46, 0xb1, 0x1f, 0x4a, 0x21, 0x3f, 0x9b, 0xca, 0x28,
+	0x7e, 0x91, 0x49, 0x79, 0x13, 0x20, 0x64, 0xe6, 0xcc, 0xa8, 0x68, 0x0a, 0xc3, 0xf1, 0xbd, 0xeb,
+	0x2b, 0x6f, 0x90, 0xfd, 0x89, 0x14, 0x26, 0x5b, 0x67, 0x5a, 0xa7, 0x3c, 0x2c, 0x7b, 0x15, 0x4d,
+	0x46, 0xf0, 0xb8, 0xa6, 0x65, 0xc9, 0x28, 0xc2, 0xfb, 0x45, 0xf9, 0x16, 0x1a, 0x63, 0x9e, 0xc8,
+	0x0b, 0x24, 0x47, 0xd2, 0x0a, 0x65, 0x4c, 0x64, 0xb6, 0xe1, 0x1a, 0x63, 0x90, 0xfc, 0x36, 0x74,
+	0x22, 0x49, 0x39, 0x8f, 0x61, 0xec, 0x9f, 0x4a, 0x4f, 0x19, 0xf4, 0xb6, 0x42, 0x0e, 0x10, 0x87,
+	0x26, 0xc0, 0xf4, 0x7c, 0xef, 0x62, 0xe2, 0x4f, 0x23, 0x75, 0x25, 0x67, 0x08, 0x7d, 0x0d, 0x6e,
+	0x4a, 0xcf, 0x0a, 0x2f, 0x02, 0x5c, 0x2b, 0xce, 0x82, 0xc9, 0x67, 0xa9, 0xe2, 0xc7, 0xa5, 0x8c,
+	0xf4, 0x44, 0x5e, 0x3c, 0x72, 0x5c, 0x89, 0x2b, 0x3a, 0x33, 0xa7, 0x6e, 0x3c, 0xa4, 0x2c, 0x96,
+	0xb2, 0x28, 0x84, 0xd9, 0xc0, 0x54, 0xd6, 0x5d, 0x58, 0x62, 0x72, 0xe8, 0xbb, 0xd2, 0xb1, 0x79,
+	0x30, 0xb6, 0x2b, 0x8b, 0x44, 0x10, 0x84, 0xa7, 0xa1, 0xd6, 0xe0, 0x26, 0xf3, 0xf2, 0x07, 0x25,
+	0xdc, 0x6d, 0x9e, 0x9a, 0x48, 0x47, 0x8a, 0x52, 0x9c, 0x3a, 0x30, 0xe3, 0x93, 0x5e, 0x27, 0x37,
+	0xf5, 0xa1, 0x19, 0x9f, 0xa0, 0x09, 0x60, 0xf2, 0xd8, 0x91, 0xae, 0xad, 0x8c, 0x0b, 0xf7, 0x78,
+	0x84, 0x18, 0xfd, 0x2d, 0x68, 0x2b, 0x06, 0x3f, 0x9c, 0x98, 0xb1, 0x32, 0x2e, 0xdc, 0xe9, 0x11,
+	0xa1, 0x70, 0x0a, 0x75, 0x56, 0xde, 0x74, 0x42, 0x06, 0xa6, 0x2a, 0xd4, 0xe9, 0xed, 0x4f, 0x27,
+	0xc6, 0x5f, 0x57, 0xa0, 0x99, 0xe6, 0x20, 0x3e, 0x00, 0x2d, 0x75, 0x15, 0x94, 0x5b, 0xdb, 0x29,
+	0x18, 0x75, 0x91, 0xd1, 0xf5, 0x37, 0xa1, 0x7c, 0x7a, 0xa6, 0xee, 0x92, 0xce, 0x1a, 0xbf, 0x58,
+	0x05, 0xa3, 0xf5, 0xb5, 0x27, 0xcf, 0x45, 0xf9, 0xf4, 0x2c, 0x73, 0x8f, 0x6b, 0x2f, 0x75, 0x8f,
+	0xdf, 0x83, 0x45, 0xcb, 0x95, 0xa6, 0x97, 0xb3, 0x61, 0x2c, 0x17, 0x0b, 0x84, 0xce, 0xcc, 0x97,
+	0x32, 0x09, 0x8d, 0xcc, 0x24, 0xbc, 0x0b, 0x35, 0x5b, 0xba, 0xb1, 0x99, 0x7f, 0x4a, 0x39, 0x08,
+	0x4d, 0xcb, 0x95, 0xdb, 0x88, 0x16, 0x4c, 0x45, 0x1d, 0x4a, 0xf2, 0x24, 0xf9, 0xdb, 0x25, 0x51,
+	0x76, 0x91, 0x52, 0x33, 0x5d, 0x86, 0xbc, 0x2e, 0x7f, 0x00, 0x4b, 0xf2, 0x3c, 0xa0, 0x2b, 0x75,
+	0x98, 0x66, 0xbd, 0xf8, 0x92, 0xef, 0x26, 0x84, 0x2d, 0x85, 0xd7, 0xbf, 0x09, 0x0d, 0xa5, 0x46,
+	0x2a, 0x8c, 0xd2, 0x39, 0x8c, 0xca, 0x2b, 0xa6, 0x48, 0x58, 0x50, 0xe0, 0xc9, 0xcc, 0xb3, 0x86,
+	0x48, 0x9b, 0x02, 0x28, 0x4d, 0xb4, 0x11, 0xb9, 0xa1, 0x70, 0x86, 0x07, 0x95, 0x27, 0xcf, 0x8f,
+	0xd4, 0x96, 0x97, 0xae, 0xda, 0xf2, 0xc4, 0xb0, 0x94, 0x73, 0x86, 0xe5, 0x0e, 0xdb, 0x64, 0xda,
+	0xbf, 0x24, 0xcd, 0x9e, 0xc3, 0xe0, 0xf7, 0xf2, 0x5d, 0x5f, 0x25, 0x12, 0x03, 0xc6, 0xaf, 0xab,
+	0xd0, 0x50, 0xde, 0x19, 0x6e, 0xfa, 0x34, 0xcd, 0x10, 0x63, 0xb3, 0x98, 0x0b, 0x48, 0xdd, 0xbc,
+	0xfc, 0x9b, 0x61, 0xe5, 0xe5, 0x6f, 0x86, 0xfa, 0xc7, 0xd0, 0x0e, 0x98, 0x96, 0x77, 0x0c, 0x5f,
+	0xcd, 0xf7, 0x51, 0xbf, 0xd4, 0xaf, 0x15, 0x64, 0x00, 0x9a, 0x35, 0x7a, 0xe0, 0x88, 0xcd, 0x63,
+	0x92, 0xaf, 0xb6, 0x68, 0x20, 0x3c, 0x30, 0x8f, 0xaf, 0x70, 0x0f, 0xaf, 0xe3, 0xe5, 0x2d, 0x90,
+	0xbb, 0xd8, 0x26, 0x2b, 0x89, 0x9e, 0x61, 0xde, 0xe7, 0xea, 0x14, 0x7d, 0xae, 0xd7, 0x41, 0xa3,
+	0xe4, 0x2c, 0xd1, 0x16, 0x54, 0xf6, 0x93, 0x10, 0x83, 0x19, 0x4f, 0x70, 0xb1, 0xe8, 0x09, 0x52,
+	0xb6, 0xd0, 0xb3, 0x7c, 0x3b, 0x49, 0xf4, 0x76, 0x44, 0x0a, 0x1b, 0x7f, 0x59, 0x82, 0x86, 0xda,
+	0xa6, 0x4b, 0xd7, 0xd5, 0xe6, 0xee, 0xfe, 0x86, 0xf8, 0x49, 0xb7, 0x84, 0xd7, 0xf1, 0xee, 0xfe,
+	0xa0, 0x5b, 0xd6, 0x35, 0xa8, 0x3d, 0xda, 0x3b, 0xd8, 0x18, 0x74, 0x2b, 0x78, 0x85, 0x6d, 0x1e,
+	0x1c, 0xec, 0x75, 0xab, 0x7a, 0x1b, 0x9a, 0xdb, 0x1b, 0x83, 0x9d, 0xc1, 0xee, 0xd3, 0x9d, 0x6e,
+	0x0d, 0x79, 0x1f, 0xef, 0x1c, 0x74, 0xeb, 0xd8, 0x78, 0xb6, 0xbb, 0xdd, 0x6d, 0x20, 0xfd, 0x70,
+	0xe3, 0xe8, 0xe8, 0xd3, 0x03, 0xb1, 0xdd, 0x6d, 0xd2, 0x35, 0x38, 0x10, 0xbb, 0xfb, 0x8f, 0xbb,
+	0x1a, 0xb6, 0x0f, 0x36, 0x7f, 0xb8, 0xb3, 0x35, 0xe8, 0x02, 0xb6, 0x9f, 0xf3, 0xd8, 0x2d, 0x5e,
+	0xc8, 0xd6, 0xee, 0xd3, 0x8d, 0xbd, 0x6e, 0xdb, 0x78, 0x08, 0xad, 0xdc, 0x99, 0xe0, 0xb0, 0x62,
+	0xe7, 0x51, 0xf7, 0x06, 0xae, 0xe5, 0xf9, 0xc6, 0xde, 0x33, 0xbc, 0x4e, 0x17, 0x00, 0xa8, 0x39,
+	0xdc, 0xdb, 0xd8, 0x7f, 0xdc, 0x2d, 0x1b, 0x0e, 0x34, 0x9f, 0x39, 0xf6, 0xa6, 0xeb, 0x5b, 0xa7,
+	0x28, 0xa0, 0x23, 0x33, 0x92, 0x2a, 0x46, 0xa7, 0x36, 0xc6, 0x17, 0xa4, 0xa3, 0x91, 0x92, 0x26,
+	0x05, 0x25, 0x31, 0x0a, 0xbd, 0x5c, 0x57, 0xf8, 0xe6, 0xf2, 0xa6, 0x93, 0x67, 0x8e, 0x4d, 0x81,
+	0xee, 0xc8, 0x89, 0x27, 0x26, 0x47, 0xb4, 0x6d, 0xa1, 0x20, 0xe3, 0x14, 0x1a, 0xcf, 0x1c, 0xfb,
+	0xd0, 0xb4, 0x4e, 0xc9, 0xea, 0xe1, 0x94, 0x7c, 0x08, 0x7c, 0xf3, 0x69, 0x84, 0xa1, 0x53, 0x78,
+	0x07, 0xea, 0x04, 0x24, 0x59, 0x28, 0xb2, 0x06, 0xc9, 0x32, 0x85, 0xa2, 0xd1, 0x83, 0xb2, 0xeb,
+	0xfa, 0xd6, 0x30, 0x94, 0xe3, 0xde, 0xab, 0x7c, 0x90, 0x84, 0x10, 0x72, 0x6c, 0xfc, 0x51, 0x29,
+	0xdd, 0x0b, 0x7a, 0x5f, 0x5c, 0x86, 0x6a, 0x60, 0x5a, 0xa7, 0xbd, 0x52, 0x96, 0xd4, 0x51, 0x8b,
+	0x11, 0x44, 0xd0, 0xdf, 0x83, 0xa6, 0x12, 0xe1, 0x64, 0xd6, 0x56, 0x4e, 0xd6, 0x45, 0x4a, 0x2c,
+	0x0a, 0x57, 0x65, 0x46, 0xb8, 0x30, 0xc8, 0x0f, 0x5c, 0x27, 0x66, 0x85, 0xad, 0x0a, 0x05, 0x19,
+	0xdf, 0x02, 0xc8, 0x9e, 0x90, 0xe7, 0xf8, 0x4e, 0xb7, 0xa0, 0x66, 0xba, 0x8e, 0x99, 0x24, 0x0d,
+	0x18, 0x30, 0xf6, 0xa1, 0x95, 0xf5, 0xa2, 0x3d, 0x37, 0x5d, 0x97, 0xe3, 0xc2, 0x12, 0xe7, 0xab,
+	0x4d, 0xd7, 0xc5, 0xb8, 0x10, 0x63, 0x02, 0x7e, 0xb3, 0x2e, 0xcf, 0x3c, 0x3f, 0x52, 0x57, 0xc1,
+	0x44, 0xe3, 0x9b, 0x50, 0x7f, 0x94, 0x84, 0x4c, 0x89, 0xc2, 0x95, 0xae, 0x52, 0x38, 0xe3, 0x23,
+	0x80, 0xec, 0x05, 0x53, 0xff, 0x40, 0xbd, 0x8d, 0x47, 0xfc, 0x12, 0x5f, 0xca, 0x92, 0x6a, 0xcc,
+	0xa4, 0x9e, 0xc5, 0x89, 0xd9, 0xd8, 0x86, 0xe6, 0x0b, 0xab, 0x0d, 0xd4, 0x06, 0x94, 0xb3, 0x0d,
+	0x98, 0x53, 0x7f, 0x60, 0xfc, 0x0c, 0x20, 0x7b, 0x85, 0x56, 0xfa, 0xcf, 0xa3, 0xa0, 0xfe, 0xdf,
+	0xc5, 0x17, 0x0e, 0xc7, 0xb5, 0x43, 0xe9, 0x15, 0xbe, 0x3a, 0xed, 0x21, 0x52, 0xba, 0xbe, 0x02,
+	0x55, 0x2a, 0x0d, 0xa8, 0x64, 0x97, 0x4b, 0xb2, 0x3e, 0x41, 0x14, 0xe3, 0x1c, 0x3a, 0x2a, 0xf1,
+	0xf6, 0x72, 0xd7, 0xac, 0x68, 0xb4, 0xcb, 0x97, 0x8c, 0xf6, 0x6d, 0xa8, 0x93, 0x47, 0x90, 0x7c,
+	0x8d, 0x82, 0xae, 0x30, 0xe6, 0xff, 0x5b, 0x03, 0xe0, 0xa9, 0xf1, 0xc1, 0xa2, 0x98, 0x16, 0x29,
+	0xcd, 0xa6, 0x45, 0x30, 0x12, 0x49, 0xaa, 0x3e, 0x30, 0x12, 0x41, 0x35, 0x4f, 0xef, 0x44, 0x95,
+	0x2a, 0x21, 0x00, 0xc7, 0x21, 0x0f, 0xcd, 0xf9, 0x5c, 0x86, 0x6a, 0xc2, 0x0c, 0x91, 0xaf, 0x81,
+	0xa8, 0x15, 0x6b, 0x20, 0xd2, 0x37, 0xd8, 0x3a, 0x8f, 0x46, 0xc0, 0xdc, 0x37, 0x68, 0x4a, 0x44,
+	0x45, 0x32, 0x8c, 0x93, 0xb4, 0x0b, 0x43, 0x69, 0xac, 0xaf, 0x29, 0x5e, 0x93, 0x53, 0x49, 0x1e,
+	0xd6, 0x77, 0x78, 0x63, 0xd7, 0xb1, 0x62, 0x55, 0xf3, 0x00, 0x9e, 0xbf, 0xa5, 0x30, 0x34, 0x98,
+	0xe7, 0x7c, 0x36, 0x65, 0xdf, 0xad, 0x29, 0x14, 0x84, 0x92, 0x12, 0xc7, 0xae, 0x72, 0xd1, 0xb0,
+	0x89, 0x07, 0x13, 0xc7, 0x6e, 0x3e, 0xdc, 0x6b, 0xc4, 0xb1, 0x4b, 0xb1, 0xde, 0x5b, 0xd0, 0xe6,
+	0xd0, 0xce, 0x66, 0x32, 0x7b, 0x64, 0x2a, 0x40, 0xb4, 0x89, 0xe5, 0x6d, 0xe8, 0xd8, 0x72, 0x4c,
+	0x4e, 0x19, 0x5f, 0x92, 0xec, 0x93, 0xb5, 0x15, 0x92, 0xa3, 0xdd, 0xf7, 0x60, 0x51, 0xc1, 0xc3,
+	0x33, 0x27, 0x8c, 0xa7, 0xa6, 0xab, 0x5e, 0xfd, 0x16, 0x12, 0x36, 0xc6, 0xe2, 0x67, 0xd1, 0x6e,
+	0x0f, 0x7f, 0x7e, 0x22, 0x43, 0x99, 0x04, 0x81, 0x84, 0xfa, 0x14, 0x31, 0x85, 0xfb, 0x84, 0x03,
+	0xbf, 0x14, 0xc6, 0xce, 0x12, 0x6d, 0xa8, 0x2a, 0x95, 0xb8, 0xa9, 0xd2, 0x6b, 0xde, 0x74, 0x42,
+	0xab, 0x60, 0x4b, 0x83, 0x5e, 0x0b, 0xe5, 0x8a, 0x6e, 0x71, 0x6f, 0x42, 0x60, 0x92, 0x28, 0x23,
+	0x9a, 0xe7, 0xbd, 0x57, 0xf2, 0x44, 0xf3, 0x5c, 0x5f, 0x85, 0x6e, 0x4a, 0x1c, 0xba, 0xd2, 0x3b,
+	0x8e, 0x4f, 0x7a, 0xb7, 0x49, 0x88, 0x17, 0x12, 0x9e, 0x3d, 0xc2, 0xe2, 0x7e, 0x30, 0x67, 0x60,
+	0xc6, 0xb1, 0x0c, 0x3d, 0x32, 0xa4, 0x9a, 0x68, 0x13, 0xf2, 0x90, 0x71, 0x28, 0xf0, 0xa1, 0x1c,
+	0xcb, 0x50, 0x7a, 0x96, 0x8c, 0x7a, 0xbd, 0x24, 0xc6, 0x4e, 0x30, 0x69, 0x7c, 0xfc, 0x5a, 0x2e,
+	0x3e, 0x5e, 0x81, 0x96, 0xe5, 0x4f, 0x82, 0x90, 0x03, 0x83, 0x5e, 0x9f, 0x8f, 0x22, 0x87, 0x32,
+	0x3e, 0x86, 0x76, 0xa2, 0x72, 0xf4, 0x50, 0x7f, 0x37, 0xcd, 0x80, 0x94, 0x32, 0x75, 0xce, 0x34,
+	0x63, 0xb3, 0xdc, 0x2b, 0x25, 0x39, 0x10, 0xe3, 0x6f, 0xb5, 0xa4, 0xb3, 0x7a, 0x4f, 0x7e, 0xb1,
+	0xda, 0x14, 0x73, 0x5c, 0xe5, 0x6b, 0xe5, 0xb8, 0xbe, 0x07, 0x9a, 0x4d, 0x79, 0x1a, 0xe7, 0x2c,
+	0xf1, 0x98, 0xfa, 0xb3, 0x39, 0x19, 0x95, 0xc9, 0x71, 0xce, 0xa4, 0xc8, 0x98, 0x5f, 0xa2, 0x7a,
+	0xa9, 0x82, 0xd5, 0xe6, 0x29, 0x58, 0xfd, 0x6b, 0x2a, 0xd8, 0x5b, 0xd0, 0xf6, 0x7c, 0x6f, 0xe8,
+	0x4d, 0x5d, 0x17, 0x13, 0xb2, 0x4a, 0xc3, 0x5a, 0x9e, 0xef, 0xed, 0x2b, 0x14, 0x46, 0x4a, 0x79,
+	0x16, 0xb6, 0xe3, 0xac, 0x6d, 0x8b, 0x39, 0x3e, 0xb2, 0xf6, 0xab, 0xd0, 0xe5, 0xc4, 0x06, 0xed,
+	0xd8, 0x90, 0x0c, 0x38, 0xeb, 0xe0, 0x02, 0xe3, 0x71, 0x8b, 0xf6, 0xd1, 0x94, 0xcf, 0x68, 0x76,
+	0xe7, 0x05, 0x9a, 0xbd, 0x30, 0x4f, 0xb3, 0x17, 0xe7, 0x6b, 0x76, 0xf7, 0xc5, 0x9a, 0xbd, 0x74,
+	0x0d, 0xcd, 0xd6, 0xaf, 0xa7, 0xd9, 0x37, 0xaf, 0xa3, 0xd9, 0xb7, 0x5e, 0xa8, 0xd9, 0xaf, 0xcc,
+	0x68, 0x76, 0x31, 0x8f, 0x73, 0x9b, 0x15, 0x3b, 0xc3, 0xe0, 0x52, 0x13, 0xde, 0x21, 0x79, 0x5c,
+	0xaf, 0x52, 0x3a, 0xbb, 0x9d, 0x20, 0x37, 0xd1, 0xf3, 0xba, 0x0b, 0x4b, 0x05, 0xa6, 0x61, 0x24,
+	0x63, 0xd2, 0xbd, 0xa6, 0x58, 0xcc, 0x33, 0x1e, 0xc9, 0x78, 0xd6, 0x94, 0xbc, 0xf6, 0x62, 0x53,
+	0xd2, 0x7f, 0x91, 0x29, 0x79, 0xfd, 0x1a, 0xa6, 0xe4, 0x8d, 0xeb, 0x99, 0x92, 0x37, 0x5f, 0x6a,
+	0x4a, 0xee, 0x5c, 0x69, 0x4a, 0x96, 0xaf, 0x4e, 0xb5, 0xad, 0x5c, 0x4a, 0xb5, 0xcd, 0xd8, 0x9a,
+	0xb7, 0x2e, 0xd9, 0x1a, 0xfd, 0x23, 0xe8, 0xe5, 0xc0, 0x61, 0x7a, 0x16, 0x8e, 0x8c, 0x7a, 0xc6,
+	0x4a, 0x65, 0xb5, 0x2d, 0x5e, 0xcd, 0xd1, 0xb7, 0x73, 0x64, 0xe3, 0x23, 0xd0, 0x52, 0x2d, 0xcf,
+	0xe5, 0xdd, 0x34, 0xa8, 0xed, 0xee, 0x6f, 0xef, 0xfc, 0xb8, 0x5b, 0x42, 0x1f, 0x5c, 0xec, 0x3c,
+	0xdf, 0x11, 0x47, 0x3b, 0xdd, 0x32, 0x3a, 0xe7, 0xdb, 0x3b, 0x7b, 0x3b, 0x83, 0x9d, 0x6e, 0xe5,
+	0x87, 0xd5, 0x66, 0xa3, 0xdb, 0xa4, 0x7a, 0x03, 0xd7, 0xb1, 0x9c, 0xd8, 0xf8, 0xfd, 0x12, 0x40,
+	0x96, 0xa9, 0xc5, 0x7d, 0xcf, 0xb4, 0x4b, 0x3d, 0x24, 0xc5, 0x89, 0x5e, 0xad, 0xa6, 0x4e, 0x44,
+	0xf9, 0xaa, 0x7c, 0x30, 0xd3, 0x13, 0x45, 0xaa, 0xcc, 0x57, 0xa4, 0x6a, 0x41, 0x91, 0xb0, 0x4e,
+	0xef, 0xa9, 0x19, 0x7c, 0xc2, 0xe5, 0x3e, 0xef, 0xc2, 0x42, 0x60, 0x86, 0xb1, 0x93, 0x64, 0x62,
+	0xd8, 0x1b, 0x6c, 0x8b, 0x4e, 0x8a, 0xa5, 0x47, 0x87, 0xbf, 0x29, 0xc1, 0xad, 0xa7, 0xfe, 0x99,
+	0x4c, 0x23, 0xfd, 0x43, 0xf3, 0x02, 0xeb, 0x44, 0x5e, 0x62, 0x74, 0x31, 0x95, 0xe4, 0x4f, 0xa9,
+	0x30, 0x27, 0x29, 0x56, 0x12, 0x1a, 0x63, 0x1e, 0xab, 0x92, 0x4f, 0x19, 0xc5, 0x44, 0x54, 0x11,
+	0x04, 0xc2, 0x48, 0x7a, 0x05, 0xea, 0xf1, 0xb9, 0x97, 0x95, 0x4e, 0xd5, 0x62, 0x7a, 0xf1, 0x9d,
+	0x1b, 0xe6, 0xd7, 0xe6, 0x87, 0xf9, 0xc6, 0x16, 0x68, 0x83, 0x73, 0x7a, 0x2f, 0x9c, 0x46, 0x85,
+	0x58, 0xb1, 0xf4, 0x82, 0x58, 0xb1, 0x5c, 0x74, 0xe7, 0x8d, 0xff, 0x2c, 0x41, 0x2b, 0x97, 0xaf,
+	0xd0, 0xdf, 0x82, 0x6a, 0x7c, 0xee, 0x15, 0xcb, 0x1a, 0x93, 0x49, 0x04, 0x91, 0xd0, 0x52, 0xa1,
+	0xa6, 0x98, 0x51, 0xe4, 0x1c, 0x7b, 0xd2, 0x56, 0x43, 0xe2, 0x03, 0xe3, 0x86, 0x42, 0xe9, 0x7b,
+	0xb0, 0xc8, 0xae, 0x65, 0xf2, 0x11, 0xc9, 0xe3, 0xc0, 0xdb, 0x33, 0xf9, 0x11, 0x7e, 0x53, 0x4d,
+	0x3e, 0x49, 0x65, 0x65, 0x17, 0x8e, 0x0b, 0xc8, 0xfe, 0x06, 0xdc, 0x9c, 0xc3, 0xf6, 0x95, 0x6a,
+	0x04, 0x96, 0xa1, 0x83, 0x6f, 0xea, 0xce, 0x44, 0x46, 0xb1, 0x39, 0x09, 0x28, 0xd6, 0x56, 0xa1,
+	0x41, 0x55, 0x94, 0xe3, 0xc8, 0xf8, 0x06, 0xb4, 0x0f, 0xa5, 0x0c, 0x85, 0x8c, 0x02, 0xdf, 0xe3,
+	0xa8, 0x50, 0xbd, 0x65, 0x72, 0x1c, 0xa2, 0x20, 0xe3, 0xf7, 0x40, 0xc3, 0x14, 0xec, 0xa6, 0x19,
+	0x5b, 0x27, 0x5f, 0x25, 0x45, 0xfb, 0x0d, 0x68, 0x04, 0x2c, 0x53, 0x2a, 0xaf, 0xd5, 0xa6, 0x78,
+	0x44, 0xc9, 0x99, 0x48, 0x88, 0xc6, 0x43, 0xb8, 0x79, 0x34, 0x1d, 0x45, 0x56, 0xe8, 0x50, 0x8a,
+	0x30, 0xf1, 0xd5, 0xfb, 0xd0, 0x0c, 0x42, 0x39, 0x76, 0xce, 0x65, 0x22, 0xc1, 0x29, 0x6c, 0x7c,
+	0x1f, 0x6e, 0x15, 0xbb, 0xa8, 0x4f, 0x78, 0x1b, 0x2a, 0xa7, 0x67, 0x91, 0x5a, 0xd9, 0x52, 0x21,
+	0x5b, 0x43, 0x85, 0x81, 0x48, 0x35, 0x04, 0x54, 0xf6, 0xa7, 0x93, 0x7c, 0x05, 0x76, 0x95, 0x2b,
+	0xb0, 0x5f, 0xcf, 0x3f, 0x2d, 0x72, 0x42, 0x27, 0x7b, 0x42, 0x7c, 0x03, 0xb4, 0xb1, 0x1f, 0xfe,
+	0xdc, 0x0c, 0x6d, 0x69, 0x2b, 0xa7, 0x3c, 0x43, 0x18, 0x3f, 0x85, 0x56, 0x22, 0x09, 0xbb, 0x36,
+	0xd5, 0x20, 0x91, 0x28, 0xee, 0xda, 0x05, 0xc9, 0xe4, 0x97, 0x34, 0xe9, 0xd9, 0xbb, 0x89, 0x08,
+	0x31, 0x50, 0x9c, 0x59, 0xd5, 0x44, 0x24, 0x33, 0x1b, 0x8f, 0xa0, 0x9d, 0x24, 0xcd, 0x30, 0xf3,
+	0x4e, 0xc2, 0xed, 0x3a, 0xd2, 0xcb, 0x09, 0x7e, 0x93, 0x11, 0x83, 0xe2, 0x7b, 0x56, 0xb9, 0x10,
+	0xe1, 0x18, 0x6b, 0x50, 0x57, 0x9a, 0xa3, 0x43, 0xd5, 0xf2, 0x6d, 0xd6, 0xee, 0x9a, 0xa0, 0x36,
+	0x6e, 0xc7, 0x24, 0x3a, 0x4e, 0xa2, 0xb7, 0x49, 0x74, 0x6c, 0xfc, 0xb2, 0x0c, 0x9d, 0x4d, 0x4a,
+	0x5a, 0x26, 0x47, 0x92, 0x4b, 0xaf, 0x97, 0x0a, 0xe9, 0xf5, 0x7c, 0x2a, 0xbd, 0x5c, 0x4c, 0xa5,
+	0xe7, 0x17, 0x54, 0x29, 0x86, 0x5c, 0xaf, 0x42, 0x63, 0xea, 0x39, 0xe7, 0x89, 0x49, 0xd0, 0xc8,
+	0x8b, 0x38, 0x1f, 0x44, 0x68, 0xfa, 0xd1, 0x6a, 0x38, 0x1e, 0xa7, 0xc2, 0x39, 0x9f, 0x9d, 0x47,
+	0xcd, 0x24, 0xbc, 0xeb, 0x2f, 0x4e, 0x78, 0x37, 0x5e, 0x9a, 0xf0, 0x6e, 0xbe, 0x2c, 0xe1, 0xad,
+	0xcd, 0x26, 0xbc, 0x8b, 0xe1, 0x22, 0xcc, 0x86, 0x8b, 0xc6, 0x9f, 0x95, 0xa1, 0xb3, 0x73, 0x1e,
+	0x50, 0xc5, 0xea, 0x4b, 0x63, 0xcf, 0xdc, 0xbe, 0x96, 0x0b, 0xfb, 0x9a, 0xdb, 0xa1, 0x8a, 0xaa,
+	0x0b, 0xe0, 0x1d, 0xc2, 0x68, 0x94, 0xd3, 0xcf, 0x6a, 0xe7, 0x18, 0xfa, 0x7f, 0xb0, 0x73, 0xc6,
+	0x1e, 0x2c, 0x24, 0x1b, 0xa3, 0xb4, 0xf6, 0x5a, 0xe2, 0xc8, 0x25, 0xf1, 0x6e, 0x9a, 0x50, 0x65,
+	0x00, 0xf7, 0x59, 0x63, 0x21, 0xc5, 0xe5, 0xbd, 0xaf, 0x22, 0xe9, 0x52, 0xf6, 0x58, 0x95, 0x12,
+	0xf1, 0xf5, 0x86, 0xc2, 0x01, 0x62, 0x99, 0xfb, 0x96, 0xae, 0xd2, 0xae, 0x9c, 0xff, 0xc1, 0x26,
+	0xea, 0x1a, 0xdf, 0x31, 0x53, 0x27, 0x29, 0x65, 0xe2, 0x4b, 0x07, 0xff, 0xdf, 0x80, 0x6e, 0x8d,
+	0x0c, 0x27, 0x6a, 0x97, 0xa9, 0x5d, 0x8c, 0xb4, 0x3b, 0x2a, 0x10, 0x30, 0x42, 0x68, 0xa8, 0xd9,
+	0xd1, 0xaf, 0x78, 0xb6, 0xff, 0x64, 0xff, 0xe0, 0xd3, 0xfd, 0xee, 0x8d, 0xf4, 0x79, 0xaf, 0x94,
+	0x79, 0x1e, 0xe5, 0xbc, 0xe7, 0x51, 0x41, 0xfc, 0xd6, 0xc1, 0xb3, 0xfd, 0x41, 0xb7, 0xaa, 0x77,
+	0x40, 0xa3, 0xe6, 0x50, 0xec, 0x3c, 0xef, 0xd6, 0x28, 0x91, 0xb8, 0xf5, 0xc9, 0xce, 0xd3, 0x8d,
+	0x6e, 0x3d, 0x7d, 0x1c, 0x6c, 0x60, 0x6b, 0x73, 0xef, 0x60, 0xb3, 0xdb, 0x34, 0xfe, 0xaa, 0x04,
+	0x4b, 0xfc, 0xf1, 0xf9, 0x94, 0x59, 0xfe, 0x8f, 0x29, 0x55, 0xfe, 0x63, 0xca, 0x6f, 0x37, 0x4b,
+	0x86, 0x9d, 0xb0, 0x54, 0x7b, 0x74, 0x81, 0x8a, 0xc2, 0x89, 0x63, 0xfc, 0xef, 0xc7, 0x26, 0xc2,
+	0xc6, 0x3f, 0x94, 0xa0, 0xcf, 0x9e, 0xcf, 0x63, 0xfc, 0x1f, 0xce, 0x8f, 0xf6, 0x2e, 0xe5, 0x6b,
+	0xae, 0xba, 0xe2, 0xdf, 0x85, 0x05, 0xfa, 0xeb, 0xce, 0x67, 0x6e, 0x52, 0x74, 0xc5, 0x27, 0xd9,
+	0x51, 0x58, 0x1e, 0x48, 0xff, 0x10, 0xda, 0xfc, 0x17, 0x1f, 0x7a, 0xe8, 0x28, 0x3c, 0xd8, 0x17,
+	0xfc, 0xae, 0x16, 0x73, 0x71, 0x5d, 0xc1, 0xc3, 0xb4, 0x53, 0x96, 0xda, 0xb9, 0xfc, 0x26, 0xaf,
+	0xba, 0x20, 0x26, 0x32, 0xee, 0xc3, 0xeb, 0x73, 0xbf, 0x43, 0x89, 0x78, 0x2e, 0xa1, 0xcf, 0x92,
+	0x65, 0xfc, 0xb2, 0x04, 0x4b, 0x97, 0xca, 0xca, 0xe6, 0xd6, 0xd2, 0xb6, 0xc6, 0x8e, 0x87, 0xd7,
+	0x58, 0x88, 0x8f, 0xef, 0xca, 0xf3, 0xc8, 0xa1, 0x0a, 0x9b, 0x54, 0x79, 0x81, 0x1f, 0x54, 0x9d,
+	0x39, 0x30, 0xfe, 0xc7, 0x8a, 0x13, 0xca, 0x68, 0x68, 0x72, 0xe0, 0x5a, 0x11, 0x9a, 0xc2, 0x6c,
+	0xd0, 0xfd, 0x1b, 0xaa, 0xe5, 0x93, 0x30, 0xb7, 0x45, 0x0a, 0x1b, 0xab, 0xd0, 0xce, 0xd7, 0xb5,
+	0xe5, 0x6b, 0x6e, 0x4b, 0xc5, 0x9a, 0xdb, 0x4f, 0x41, 0x4b, 0xdf, 0xf8, 0xe7, 0xfe, 0x45, 0x41,
+	0xed, 0x4c, 0x39, 0x7b, 0xea, 0xe8, 0x42, 0xc5, 0xb1, 0xcf, 0xd5, 0x65, 0x81, 0x4d, 0xec, 0x47,
+	0x45, 0x0a, 0x9c, 0x7a, 0xa6, 0xb6, 0xb1, 0x07, 0x2d, 0x1c, 0x38, 0x91, 0x94, 0xeb, 0x0d, 0x7d,
+	0xd5, 0xfb, 0x30, 0x3e, 0x03, 0x74, 0x67, 0x8b, 0xee, 0xf0, 0xab, 0x82, 0xd0, 0x99, 0x60, 0xb8,
+	0xc7, 0xc3, 0x26, 0x20, 0x6e, 0x9d, 0x6a, 0xe6, 0xde, 0x71, 0x15, 0x86, 0xcd, 0xf6, 0xdc, 0x69,
+	0x0a, 0x15, 0xde, 0xca, 0x76, 0x57, 0xb2, 0x72, 0xe2, 0x8d, 0x98, 0xa7, 0xf4, 0x27, 0x7e, 0x9c,
+	0xa6, 0xf0, 0x14, 0x68, 0x58, 0xa0, 0xe7, 0x16, 0x78, 0x8d, 0x4b, 0xe5, 0x05, 0x77, 0xf2, 0x95,
+	0xdb, 0xb0, 0x0e, 0xcd, 0xe4, 0x8d, 0x9b, 0xca, 0xb2, 0x50, 0x8c, 0xd4, 0x7f, 0xd4, 0x18, 0xc0,
+	0x3d, 0x95, 0x9e, 0xad, 0xde, 0x0d, 0xb0, 0x69, 0xfc, 0x69, 0x09, 0x5a, 0xb9, 0xaa, 0x43, 0xe4,
+	0xc0, 0x27, 0x22, 0x25, 0xc2, 0xb1, 0x79, 0x7c, 0xf5, 0xf5, 0xf6, 0x26, 0x80, 0x15, 0x4a, 0x13,
+	0x5d, 0x7f, 0x33, 0x56, 0x37, 0x9c, 0xa6, 0x30, 0x1b, 0xf8, 0x27, 0x8f, 0xa4, 0x6e, 0xb5, 0x9a,
+	0x2f, 0x70, 0xf4, 0x3f, 0x97, 0x1e, 0xd7, 0x25, 0x2a, 0x32, 0x2e, 0x15, 0x47, 0xbc, 0x48, 0xb2,
+	0x2f, 0x04, 0x18, 0x01, 0xb4, 0x72, 0xcc, 0x5f, 0xf7, 0xfe, 0xf5, 0x7c, 0x5b, 0x0e, 0xd3, 0x4b,
+	0xa1, 0x8e, 0x20, 0xbb, 0x71, 0x9c, 0x9e, 0xad, 0xe6, 0x9e, 0x2c, 0x8d, 0xe7, 0xd0, 0xe6, 0x90,
+	0xca, 0x3f, 0xa6, 0xea, 0xc0, 0x97, 0xa6, 0x7d, 0x29, 0x3c, 0xe3, 0x29, 0xa9, 0x8d, 0xe3, 0xb2,
+	0xa1, 0xe4, 0xe9, 0x18, 0x58, 0xff, 0xfb, 0x12, 0x54, 0xd1, 0xc5, 0xd6, 0xef, 0x81, 0xf6, 0x89,
+	0x34, 0xc3, 0x78, 0x24, 0xcd, 0x58, 0x2f, 0xb8, 0xd3, 0x7d, 0xb2, 0x4e, 0x59, 0x3d, 0xa3, 0x71,
+	0xe3, 0x41, 0x09, 0xeb, 0x6e, 0xb0, 0x5b, 0xf2, 0x9f, 0x9e, 0x4e, 0xe2, 0xaa, 0x93, 0x2b, 0xdf,
+	0x2f, 0xf4, 0x37, 0x6e, 0xac, 0x12, 0xff, 0x0f, 0x7d, 0xc7, 0xdb, 0xe2, 0xff, 0x62, 0xe8, 0xb3,
+	0xae, 0xfd, 0x6c, 0x0f, 0xfd, 0x1e, 0xd4, 0x77, 0xa3, 0x43, 0x39, 0x8f, 0x95, 0x2c, 0x6c, 0x3e,
+	0xbc, 0x30, 0x6e, 0xac, 0xff, 0x7b, 0x15, 0xaa, 0x58, 0x3c, 0x8a, 0xaf, 0xb5, 0xaa, 0xfa, 0x53,
+	0xcf, 0x55, 0x79, 0xf6, 0x29, 0x79, 0x37, 0x53, 0x16, 0x4a, 0xb3, 0x74, 0xd9, 0xb4, 0x66, 0x4f,
+	0xd9, 0x7a, 0x56, 0x9c, 0x7a, 0x69, 0x51, 0x1f, 0x41, 0xf7, 0x28, 0x0e, 0xa5, 0x39, 0xc9, 0xb1,
+	0x17, 0xb7, 0x6a, 0xde, 0xbb, 0x38, 0xed, 0xd7, 0x07, 0x50, 0xe7, 0x40, 0x6d, 0xa6, 0xc3, 0xec,
+	0x13, 0x37, 0x31, 0xbf, 0x07, 0xad, 0xa3, 0x13, 0x7f, 0xea, 0xda, 0x47, 0x32, 0x3c, 0x93, 0x7a,
+	0xae, 0xa8, 0xbf, 0x9f, 0x6b, 0x1b, 0x37, 0xf4, 0x55, 0x00, 0x8e, 0x0d, 0xe8, 0x21, 0xad, 0x81,
+	0xb4, 0xfd, 0xe9, 0x84, 0x07, 0xcd, 0x05, 0x0d, 0xcc, 0x99, 0x8b, 0xd7, 0x5e, 0xc4, 0xf9, 0x21,
+	0x74, 0xb6, 0xc8, 0x8e, 0x1f, 0x84, 0x1b, 0x23, 0x3f, 0x8c, 0xf5, 0xd9, 0xc2, 0xfe, 0xfe, 0x2c,
+	0xc2, 0xb8, 0x81, 0xe5, 0x9c, 0x83, 0xf0, 0x82, 0xf9, 0x97, 0x54, 0x98, 0x9b, 0xcd, 0x37, 0xe7,
+	0x2b, 0xf5, 0x1f, 0x40, 0x2b, 0x77, 0x47, 0xe9, 0xf3, 0x6b, 0xa1, 0xfb, 0xf3, 0xd1, 0xc6, 0x0d,
+	0xfd, 0x3b, 0xa0, 0xf3, 0xc9, 0x15, 0x2e, 0x8b, 0x4b, 0x65, 0xd1, 0x73, 0x8e, 0x70, 0x89, 0xfb,
+	0xe5, 0x2c, 0x9e, 0x3e, 0xb7, 0x30, 0x7a, 0xb6, 0xeb, 0xfa, 0xff, 0xd4, 0xa1, 0xfe, 0xa9, 0x1f,
+	0x9e, 0x4a, 0x2c, 0x22, 0xa9, 0x53, 0x11, 0x85, 0x12, 0xfc, 0xb4, 0xa0, 0x62, 0xde, 0xd6, 0xbc,
+	0x03, 0x1a, 0x1d, 0x23, 0xfe, 0x9f, 0x91, 0x85, 0x8b, 0xfe, 0x09, 0xcb, 0x27, 0xc9, 0xa9, 0x6c,
+	0x92, 0xc4, 0x05, 0x16, 0xad, 0xb4, 0x62, 0xa9, 0x50, 0xd2, 0xd0, 0xa7, 0x13, 0x7b, 0xf2, 0xfc,
+	0x08, 0x95, 0xe9, 0x41, 0x09, 0xbd, 0xd1, 0x23, 0x3e, 0x1b, 0x64, 0xca, 0xfe, 0x5c, 0xd7, 0x5f,
+	0x48, 0x10, 0xe9, 0xc8, 0xf7, 0xa1, 0xae, 0x76, 0x67, 0x29, 0x73, 0x4d, 0x94, 0x91, 0xef, 0x77,
+	0xf3, 0x28, 0xd5, 0xe1, 0x7d, 0xa8, 0xb3, 0x73, 0xc7, 0x1d, 0x0a, 0x71, 0x1a, 0xaf, 0x9a, 0x63,
+	0x3d, 0xe3, 0x86, 0xfe, 0x01, 0x34, 0x54, 0x21, 0x84, 0x3e, 0xa7, 0x2a, 0x62, 0x86, 0xf9, 0x21,
+	0xd4, 0xd9, 0x3b, 0xe7, 0x71, 0x0b, 0x21, 0x4c, 0x5f, 0xcf, 0xa3, 0x12, 0xb5, 0x46, 0xfd, 0x14,
+	0x5c, 0x0e, 0x95, 0x55, 0x8d, 0x24, 0x3b, 0x31, 0xc7, 0xc8, 0x7c, 0x04, 0x9d, 0x42, 0xde, 0x49,
+	0xef, 0xd1, 0xe9, 0xcc, 0x49, 0x45, 0x5d, 0x92, 0x8b, 0xef, 0x83, 0xa6, 0xc2, 0xfe, 0x91, 0xd4,
+	0xa9, 0x6a, 0x61, 0x4e, 0xe2, 0xa0, 0x7f, 0x39, 0xee, 0x27, 0x7d, 0xfd, 0x31, 0xdc, 0x9c, 0xe3,
+	0xa1, 0xe9, 0xf4, 0xa7, 0x86, 0xab, 0x5d, 0xd0, 0xfe, 0xf2, 0x95, 0xf4, 0x74, 0x03, 0xd6, 0xa0,
+	0x29, 0xa4, 0x89, 0x0f, 0xd9, 0x23, 0x3e, 0xeb, 0x9c, 0x63, 0xd2, 0x2f, 0x96, 0x39, 0xd2, 0x4a,
+	0xbe, 0x0d, 0x0b, 0x89, 0x1c, 0xf3, 0xbf, 0xd5, 0xf4, 0xdb, 0x33, 0xb2, 0x9d, 0x74, 0xce, 0x04,
+	0xea, 0x41, 0x49, 0x5f, 0x85, 0x4e, 0xda, 0x8d, 0xde, 0x87, 0xaf, 0xda, 0x64, 0xfd, 0x61, 0x72,
+	0x23, 0xf3, 0xe8, 0xb3, 0xf7, 0x66, 0x7f, 0x16, 0x61, 0xdc, 0xd0, 0x7f, 0x67, 0xe6, 0xea, 0xba,
+	0xfa, 0x50, 0xba, 0x19, 0x85, 0x79, 0x8d, 0x1b, 0x9b, 0xdd, 0x5f, 0xff, 0xe6, 0x4e, 0xe9, 0x5f,
+	0x7e, 0x73, 0xa7, 0xf4, 0x6f, 0xbf, 0xb9, 0x53, 0xfa, 0xf3, 0xff, 0xb8, 0x73, 0x63, 0x54, 0xa7,
+	0xff, 0xc3, 0x7f, 0xf8, 0x7f, 0x03, 0x00, 0xfb, 0x94, 0x6a, 0x1b, 0x85, 0x3f, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// RaftClient is the client API for Raft service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
-type WorkerClient interface {
-	// Data serving RPCs.
-	Mutate(ctx context.Context, in *Mutations, opts ...grpc.CallOption) (*api.TxnContext, error)
-	ServeTask(ctx context.Context, in *Query, opts ...grpc.CallOption) (*Result, error)
-	StreamSnapshot(ctx context.Context, opts ...grpc.CallOption) (Worker_StreamSnapshotClient, error)
-	Sort(ctx context.Context, in *SortMessage, opts ...grpc.CallOption) (*SortResult, error)
-	Schema(ctx context.Context, in *SchemaRequest, opts ...grpc.CallOption) (*SchemaResult, error)
-	Backup(ctx context.Context, in *BackupRequest, opts ...grpc.CallOption) (*Status, error)
-	Restore(ctx context.Context, in *RestoreRequest, opts ...grpc.CallOption) (*Status, error)
-	Export(ctx context.Context, in *ExportRequest, opts ...grpc.CallOption) (*ExportResponse, error)
-	ReceivePredicate(ctx context.Context, opts ...grpc.CallOption) (Worker_ReceivePredicateClient, error)
-	MovePredicate(ctx context.Context, in *MovePredicatePayload, opts ...grpc.CallOption) (*api.Payload, error)
-	Subscribe(ctx context.Context, in *SubscriptionRequest, opts ...grpc.CallOption) (Worker_SubscribeClient, error)
-	UpdateGraphQLSchema(ctx context.Context, in *UpdateGraphQLSchemaRequest, opts ...grpc.CallOption) (*UpdateGraphQLSchemaResponse, error)
+type RaftClient interface {
+	Heartbeat(ctx context.Context, in *api.Payload, opts ...grpc.CallOption) (Raft_HeartbeatClient, error)
+	RaftMessage(ctx context.Context, opts ...grpc.CallOption) (Raft_RaftMessageClient, error)
+	JoinCluster(ctx context.Context, in *RaftContext, opts ...grpc.CallOption) (*api.Payload, error)
+	IsPeer(ctx context.Context, in *RaftContext, opts ...grpc.CallOption) (*PeerResponse, error)
 }
 
-type workerClient struct {
+type raftClient struct {
 	cc *grpc.ClientConn
 }
 
-func NewWorkerClient(cc *grpc.ClientConn) WorkerClient {
-	return &workerClient{cc}
+func NewRaftClient(cc *grpc.ClientConn) RaftClient {
+	return &raftClient{cc}
 }
 
-func (c *workerClient) Mutate(ctx context.Context, in *Mutations, opts ...grpc.CallOption) (*api.TxnContext, error) {
-	out := new(api.TxnContext)
-	err := c.cc.Invoke(ctx, "/pb.Worker/Mutate", in, out, opts...)
+func (c *raftClient) Heartbeat(ctx context.Context, in *api.Payload, opts ...grpc.CallOption) (Raft_HeartbeatClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Raft_serviceDesc.Streams[0], "/pb.Raft/Heartbeat", opts...)
 	if err != nil {
 		return nil, err
 	}
-	return out, nil
-}
-
-func (c *workerClient) ServeTask(ctx context.Context, in *Query, opts ...grpc.CallOption) (*Result, error) {
-	out := new(Result)
-	err := c.cc.Invoke(ctx, "/pb.Worker/ServeTask", in, out, opts...)
-	if err != nil {
+	x := &raftHeartbeatClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
 		return nil, err
 	}
-	return out, nil
-}
-
-func (c *workerClient) StreamSnapshot(ctx context.Context, opts ...grpc.CallOption) (Worker_StreamSnapshotClient, error) {
-	stream, err := c.cc.NewStream(ctx, &_Worker_serviceDesc.Streams[0], "/pb.Worker/StreamSnapshot", opts...)
-	if err != nil {
+	if err := x.ClientStream.CloseSend(); err != nil {
 		return nil, err
 	}
-	x := &workerStreamSnapshotClient{stream}
 	return x, nil
 }
 
-type Worker_StreamSnapshotClient interface {
-	Send(*Snapshot) error
-	Recv() (*KVS, error)
+type Raft_HeartbeatClient interface {
+	Recv() (*HealthInfo, error)
 	grpc.ClientStream
 }
 
-type workerStreamSnapshotClient struct {
+type raftHeartbeatClient struct {
 	grpc.ClientStream
 }
 
-func (x *workerStreamSnapshotClient) Send(m *Snapshot) error {
-	return x.ClientStream.SendMsg(m)
-}
-
-func (x *workerStreamSnapshotClient) Recv() (*KVS, error) {
-	m := new(KVS)
+func (x *raftHeartbeatClient) Recv() (*HealthInfo, error) {
+	m := new(HealthInfo)
 	if err := x.ClientStream.RecvMsg(m); err != nil {
 		return nil, err
 	}
 	return m, nil
 }
 
-func (c *workerClient) Sort(ctx context.Context, in *SortMessage, opts ...grpc.CallOption) (*SortResult, error) {
-	out := new(SortResult)
-	err := c.cc.Invoke(ctx, "/pb.Worker/Sort", in, out, opts...)
+func (c *raftClient) RaftMessage(ctx context.Context, opts ...grpc.CallOption) (Raft_RaftMessageClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Raft_serviceDesc.Streams[1], "/pb.Raft/RaftMessage", opts...)
 	if err != nil {
 		return nil, err
 	}
-	return out, nil
+	x := &raftRaftMessageClient{stream}
+	return x, nil
 }
 
-func (c *workerClient) Schema(ctx context.Context, in *SchemaRequest, opts ...grpc.CallOption) (*SchemaResult, error) {
-	out := new(SchemaResult)
-	err := c.cc.Invoke(ctx, "/pb.Worker/Schema", in, out, opts...)
-	if err != nil {
-		return nil, err
-	}
-	return out, nil
-}
-
-func (c *workerClient) Backup(ctx context.Context, in *BackupRequest, opts ...grpc.CallOption) (*Status, error) {
-	out := new(Status)
-	err := c.cc.Invoke(ctx, "/pb.Worker/Backup", in, out, opts...)
-	if err != nil {
-		return nil, err
-	}
-	return out, nil
-}
-
-func (c *workerClient) Restore(ctx context.Context, in *RestoreRequest, opts ...grpc.CallOption) (*Status, error) {
-	out := new(Status)
-	err := c.cc.Invoke(ctx, "/pb.Worker/Restore", in, out, opts...)
-	if err != nil {
-		return nil, err
-	}
-	return out, nil
-}
-
-func (c *workerClient) Export(ctx context.Context, in *ExportRequest, opts ...grpc.CallOption) (*ExportResponse, error) {
-	out := new(ExportResponse)
-	err := c.cc.Invoke(ctx, "/pb.Worker/Export", in, out, opts...)
-	if err != nil {
-		return nil, err
-	}
-	return out, nil
-}
-
-func (c *workerClient) ReceivePredicate(ctx context.Context, opts ...grpc.CallOption) (Worker_ReceivePredicateClient, error) {
-	stream, err := c.cc.NewStream(ctx, &_Worker_serviceDesc.Streams[1], "/pb.Worker/ReceivePredicate", opts...)
-	if err != nil {
-		return nil, err
-	}
-	x := &workerReceivePredicateClient{stream}
-	return x, nil
-}
-
-type Worker_ReceivePredicateClient interface {
-	Send(*KVS) error
+type Raft_RaftMessageClient interface {
+	Send(*RaftBatch) error
 	CloseAndRecv() (*api.Payload, error)
 	grpc.ClientStream
 }
 
-type workerReceivePredicateClient struct {
+type raftRaftMessageClient struct {
 	grpc.ClientStream
 }
 
-func (x *workerReceivePredicateClient) Send(m *KVS) error {
+func (x *raftRaftMessageClient) Send(m *RaftBatch) error {
 	return x.ClientStream.SendMsg(m)
 }
 
-func (x *workerReceivePredicateClient) CloseAndRecv() (*api.Payload, error) {
+func (x *raftRaftMessageClient) CloseAndRecv() (*api.Payload, error) {
 	if err := x.ClientStream.CloseSend(); err != nil {
 		return nil, err
 	}
@@ -6105,1731 +6833,1507 @@ func (x *workerReceivePredicateClient) CloseAndRecv() (*api.Payload, error) {
 	return m, nil
 }
 
-func (c *workerClient) MovePredicate(ctx context.Context, in *MovePredicatePayload, opts ...grpc.CallOption) (*api.Payload, error) {
+func (c *raftClient) JoinCluster(ctx context.Context, in *RaftContext, opts ...grpc.CallOption) (*api.Payload, error) {
 	out := new(api.Payload)
-	err := c.cc.Invoke(ctx, "/pb.Worker/MovePredicate", in, out, opts...)
+	err := c.cc.Invoke(ctx, "/pb.Raft/JoinCluster", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *workerClient) Subscribe(ctx context.Context, in *SubscriptionRequest, opts ...grpc.CallOption) (Worker_SubscribeClient, error) {
-	stream, err := c.cc.NewStream(ctx, &_Worker_serviceDesc.Streams[2], "/pb.Worker/Subscribe", opts...)
-	if err != nil {
-		return nil, err
-	}
-	x := &workerSubscribeClient{stream}
-	if err := x.ClientStream.SendMsg(in); err != nil {
-		return nil, err
-	}
-	if err := x.ClientStream.CloseSend(); err != nil {
-		return nil, err
-	}
-	return x, nil
-}
-
-type Worker_SubscribeClient interface {
-	Recv() (*pb.KVList, error)
-	grpc.ClientStream
-}
-
-type workerSubscribeClient struct {
-	grpc.ClientStream
-}
-
-func (x *workerSubscribeClient) Recv() (*pb.KVList, error) {
-	m := new(pb.KVList)
-	if err := x.ClientStream.RecvMsg(m); err != nil {
-		return nil, err
-	}
-	return m, nil
-}
-
-func (c *workerClient) UpdateGraphQLSchema(ctx context.Context, in *UpdateGraphQLSchemaRequest, opts ...grpc.CallOption) (*UpdateGraphQLSchemaResponse, error) {
-	out := new(UpdateGraphQLSchemaResponse)
-	err := c.cc.Invoke(ctx, "/pb.Worker/UpdateGraphQLSchema", in, out, opts...)
+func (c *raftClient) IsPeer(ctx context.Context, in *RaftContext, opts ...grpc.CallOption) (*PeerResponse, error) {
+	out := new(PeerResponse)
+	err := c.cc.Invoke(ctx, "/pb.Raft/IsPeer", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-// WorkerServer is the server API for Worker service.
-type WorkerServer interface {
-	// Data serving RPCs.
-	Mutate(context.Context, *Mutations) (*api.TxnContext, error)
-	ServeTask(context.Context, *Query) (*Result, error)
-	StreamSnapshot(Worker_StreamSnapshotServer) error
-	Sort(context.Context, *SortMessage) (*SortResult, error)
-	Schema(context.Context, *SchemaRequest) (*SchemaResult, error)
-	Backup(context.Context, *BackupRequest) (*Status, error)
-	Restore(context.Context, *RestoreRequest) (*Status, error)
-	Export(context.Context, *ExportRequest) (*ExportResponse, error)
-	ReceivePredicate(Worker_ReceivePredicateServer) error
-	MovePredicate(context.Context, *MovePredicatePayload) (*api.Payload, error)
-	Subscribe(*SubscriptionRequest, Worker_SubscribeServer) error
-	UpdateGraphQLSchema(context.Context, *UpdateGraphQLSchemaRequest) (*UpdateGraphQLSchemaResponse, error)
+// RaftServer is the server API for Raft service.
+type RaftServer interface {
+	Heartbeat(*api.Payload, Raft_HeartbeatServer) error
+	RaftMessage(Raft_RaftMessageServer) error
+	JoinCluster(context.Context, *RaftContext) (*api.Payload, error)
+	IsPeer(context.Context, *RaftContext) (*PeerResponse, error)
 }
 
-// UnimplementedWorkerServer can be embedded to have forward compatible implementations.
-type UnimplementedWorkerServer struct {
+// UnimplementedRaftServer can be embedded to have forward compatible implementations.
+type UnimplementedRaftServer struct {
 }
 
-func (*UnimplementedWorkerServer) Mutate(ctx context.Context, req *Mutations) (*api.TxnContext, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method Mutate not implemented")
-}
-func (*UnimplementedWorkerServer) ServeTask(ctx context.Context, req *Query) (*Result, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method ServeTask not implemented")
-}
-func (*UnimplementedWorkerServer) StreamSnapshot(srv Worker_StreamSnapshotServer) error {
-	return status.Errorf(codes.Unimplemented, "method StreamSnapshot not implemented")
-}
-func (*UnimplementedWorkerServer) Sort(ctx context.Context, req *SortMessage) (*SortResult, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method Sort not implemented")
-}
-func (*UnimplementedWorkerServer) Schema(ctx context.Context, req *SchemaRequest) (*SchemaResult, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method Schema not implemented")
-}
-func (*UnimplementedWorkerServer) Backup(ctx context.Context, req *BackupRequest) (*Status, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method Backup not implemented")
-}
-func (*UnimplementedWorkerServer) Restore(ctx context.Context, req *RestoreRequest) (*Status, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method Restore not implemented")
+func (*UnimplementedRaftServer) Heartbeat(req *api.Payload, srv Raft_HeartbeatServer) error {
+	return status.Errorf(codes.Unimplemented, "method Heartbeat not implemented")
 }
-func (*UnimplementedWorkerServer) Export(ctx context.Context, req *ExportRequest) (*ExportResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method Export not implemented")
+func (*UnimplementedRaftServer) RaftMessage(srv Raft_RaftMessageServer) error {
+	return status.Errorf(codes.Unimplemented, "method RaftMessage not implemented")
 }
-func (*UnimplementedWorkerServer) ReceivePredicate(srv Worker_ReceivePredicateServer) error {
-	return status.Errorf(codes.Unimplemented, "method ReceivePredicate not implemented")
+func (*UnimplementedRaftServer) JoinCluster(ctx context.Context, req *RaftContext) (*api.Payload, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method JoinCluster not implemented")
 }
-func (*UnimplementedWorkerServer) MovePredicate(ctx context.Context, req *MovePredicatePayload) (*api.Payload, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method MovePredicate not implemented")
+func (*UnimplementedRaftServer) IsPeer(ctx context.Context, req *RaftContext) (*PeerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method IsPeer not implemented")
 }
-func (*UnimplementedWorkerServer) Subscribe(req *SubscriptionRequest, srv Worker_SubscribeServer) error {
-	return status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
+
+func RegisterRaftServer(s *grpc.Server, srv RaftServer) {
+	s.RegisterService(&_Raft_serviceDesc, srv)
 }
-func (*UnimplementedWorkerServer) UpdateGraphQLSchema(ctx context.Context, req *UpdateGraphQLSchemaRequest) (*UpdateGraphQLSchemaResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method UpdateGraphQLSchema not implemented")
+
+func _Raft_Heartbeat_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(api.Payload)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RaftServer).Heartbeat(m, &raftHeartbeatServer{stream})
 }
 
-func RegisterWorkerServer(s *grpc.Server, srv WorkerServer) {
-	s.RegisterService(&_Worker_serviceDesc, srv)
+type Raft_HeartbeatServer interface {
+	Send(*HealthInfo) error
+	grpc.ServerStream
 }
 
-func _Worker_Mutate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(Mutations)
-	if err := dec(in); err != nil {
-		return nil, err
-	}
-	if interceptor == nil {
-		return srv.(WorkerServer).Mutate(ctx, in)
-	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/pb.Worker/Mutate",
-	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(WorkerServer).Mutate(ctx, req.(*Mutations))
-	}
-	return interceptor(ctx, in, info, handler)
+type raftHeartbeatServer struct {
+	grpc.ServerStream
 }
 
-func _Worker_ServeTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(Query)
-	if err := dec(in); err != nil {
-		return nil, err
-	}
-	if interceptor == nil {
-		return srv.(WorkerServer).ServeTask(ctx, in)
-	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/pb.Worker/ServeTask",
-	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(WorkerServer).ServeTask(ctx, req.(*Query))
-	}
-	return interceptor(ctx, in, info, handler)
+func (x *raftHeartbeatServer) Send(m *HealthInfo) error {
+	return x.ServerStream.SendMsg(m)
 }
 
-func _Worker_StreamSnapshot_Handler(srv interface{}, stream grpc.ServerStream) error {
-	return srv.(WorkerServer).StreamSnapshot(&workerStreamSnapshotServer{stream})
+func _Raft_RaftMessage_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(RaftServer).RaftMessage(&raftRaftMessageServer{stream})
 }
 
-type Worker_StreamSnapshotServer interface {
-	Send(*KVS) error
-	Recv() (*Snapshot, error)
+type Raft_RaftMessageServer interface {
+	SendAndClose(*api.Payload) error
+	Recv() (*RaftBatch, error)
 	grpc.ServerStream
 }
 
-type workerStreamSnapshotServer struct {
+type raftRaftMessageServer struct {
 	grpc.ServerStream
 }
 
-func (x *workerStreamSnapshotServer) Send(m *KVS) error {
+func (x *raftRaftMessageServer) SendAndClose(m *api.Payload) error {
 	return x.ServerStream.SendMsg(m)
 }
 
-func (x *workerStreamSnapshotServer) Recv() (*Snapshot, error) {
-	m := new(Snapshot)
+func (x *raftRaftMessageServer) Recv() (*RaftBatch, error) {
+	m := new(RaftBatch)
 	if err := x.ServerStream.RecvMsg(m); err != nil {
 		return nil, err
 	}
 	return m, nil
 }
 
-func _Worker_Sort_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(SortMessage)
+func _Raft_JoinCluster_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RaftContext)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(WorkerServer).Sort(ctx, in)
+		return srv.(RaftServer).JoinCluster(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/pb.Worker/Sort",
+		FullMethod: "/pb.Raft/JoinCluster",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(WorkerServer).Sort(ctx, req.(*SortMessage))
+		return srv.(RaftServer).JoinCluster(ctx, req.(*RaftContext))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Worker_Schema_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(SchemaRequest)
+func _Raft_IsPeer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RaftContext)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(WorkerServer).Schema(ctx, in)
+		return srv.(RaftServer).IsPeer(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/pb.Worker/Schema",
+		FullMethod: "/pb.Raft/IsPeer",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(WorkerServer).Schema(ctx, req.(*SchemaRequest))
+		return srv.(RaftServer).IsPeer(ctx, req.(*RaftContext))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Worker_Backup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(BackupRequest)
-	if err := dec(in); err != nil {
-		return nil, err
-	}
-	if interceptor == nil {
-		return srv.(WorkerServer).Backup(ctx, in)
-	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/pb.Worker/Backup",
-	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(WorkerServer).Backup(ctx, req.(*BackupRequest))
+var _Raft_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "pb.Raft",
+	HandlerType: (*RaftServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "JoinCluster",
+			Handler:    _Raft_JoinCluster_Handler,
+		},
+		{
+			MethodName: "IsPeer",
+			Handler:    _Raft_IsPeer_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Heartbeat",
+			Handler:       _Raft_Heartbeat_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "RaftMessage",
+			Handler:       _Raft_RaftMessage_Handler,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "pb.proto",
+}
+
+// ZeroClient is the client API for Zero service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type ZeroClient interface {
+	// These 3 endpoints are for handling membership.
+	Connect(ctx context.Context, in *Member, opts ...grpc.CallOption) (*ConnectionState, error)
+	UpdateMembership(ctx context.Context, in *Group, opts ...grpc.CallOption) (*api.Payload, error)
+	StreamMembership(ctx context.Context, in *api.Payload, opts ...grpc.CallOption) (Zero_StreamMembershipClient, error)
+	Oracle(ctx context.Context, in *api.Payload, opts ...grpc.CallOption) (Zero_OracleClient, error)
+	ShouldServe(ctx context.Context, in *Tablet, opts ...grpc.CallOption) (*Tablet, error)
+	AssignUids(ctx context.Context, in *Num, opts ...grpc.CallOption) (*AssignedIds, error)
+	Timestamps(ctx context.Context, in *Num, opts ...grpc.CallOption) (*AssignedIds, error)
+	CommitOrAbort(ctx context.Context, in *api.TxnContext, opts ...grpc.CallOption) (*api.TxnContext, error)
+	TryAbort(ctx context.Context, in *TxnTimestamps, opts ...grpc.CallOption) (*OracleDelta, error)
+	// Returns the commit made with the idempotency key of the given record, if any.
+	Idempotency(ctx context.Context, in *IdempotencyRecord, opts ...grpc.CallOption) (*IdempotencyRecord, error)
+	// Enables or disables the strict schema mode of the cluster.
+	UpdateStrictSchema(ctx context.Context, in *StrictSchema, opts ...grpc.CallOption) (*api.Payload, error)
+	// Records the progress of the replication of a replica cluster, or requests its promotion.
+	UpdateReplication(ctx context.Context, in *ReplicationState, opts ...grpc.CallOption) (*api.Payload, error)
+}
+
+type zeroClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewZeroClient(cc *grpc.ClientConn) ZeroClient {
+	return &zeroClient{cc}
+}
+
+func (c *zeroClient) Connect(ctx context.Context, in *Member, opts ...grpc.CallOption) (*ConnectionState, error) {
+	out := new(ConnectionState)
+	err := c.cc.Invoke(ctx, "/pb.Zero/Connect", in, out, opts...)
+	if err != nil {
+		return nil, err
 	}
-	return interceptor(ctx, in, info, handler)
+	return out, nil
 }
 
-func _Worker_Restore_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(RestoreRequest)
-	if err := dec(in); err != nil {
+func (c *zeroClient) UpdateMembership(ctx context.Context, in *Group, opts ...grpc.CallOption) (*api.Payload, error) {
+	out := new(api.Payload)
+	err := c.cc.Invoke(ctx, "/pb.Zero/UpdateMembership", in, out, opts...)
+	if err != nil {
 		return nil, err
 	}
-	if interceptor == nil {
-		return srv.(WorkerServer).Restore(ctx, in)
+	return out, nil
+}
+
+func (c *zeroClient) StreamMembership(ctx context.Context, in *api.Payload, opts ...grpc.CallOption) (Zero_StreamMembershipClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Zero_serviceDesc.Streams[0], "/pb.Zero/StreamMembership", opts...)
+	if err != nil {
+		return nil, err
 	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/pb.Worker/Restore",
+	x := &zeroStreamMembershipClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
 	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(WorkerServer).Restore(ctx, req.(*RestoreRequest))
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
 	}
-	return interceptor(ctx, in, info, handler)
+	return x, nil
 }
 
-func _Worker_Export_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(ExportRequest)
-	if err := dec(in); err != nil {
+type Zero_StreamMembershipClient interface {
+	Recv() (*MembershipState, error)
+	grpc.ClientStream
+}
+
+type zeroStreamMembershipClient struct {
+	grpc.ClientStream
+}
+
+func (x *zeroStreamMembershipClient) Recv() (*MembershipState, error) {
+	m := new(MembershipState)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
 		return nil, err
 	}
-	if interceptor == nil {
-		return srv.(WorkerServer).Export(ctx, in)
+	return m, nil
+}
+
+func (c *zeroClient) Oracle(ctx context.Context, in *api.Payload, opts ...grpc.CallOption) (Zero_OracleClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Zero_serviceDesc.Streams[1], "/pb.Zero/Oracle", opts...)
+	if err != nil {
+		return nil, err
 	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/pb.Worker/Export",
+	x := &zeroOracleClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
 	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(WorkerServer).Export(ctx, req.(*ExportRequest))
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
 	}
-	return interceptor(ctx, in, info, handler)
+	return x, nil
 }
 
-func _Worker_ReceivePredicate_Handler(srv interface{}, stream grpc.ServerStream) error {
-	return srv.(WorkerServer).ReceivePredicate(&workerReceivePredicateServer{stream})
+type Zero_OracleClient interface {
+	Recv() (*OracleDelta, error)
+	grpc.ClientStream
 }
 
-type Worker_ReceivePredicateServer interface {
-	SendAndClose(*api.Payload) error
-	Recv() (*KVS, error)
-	grpc.ServerStream
+type zeroOracleClient struct {
+	grpc.ClientStream
 }
 
-type workerReceivePredicateServer struct {
-	grpc.ServerStream
+func (x *zeroOracleClient) Recv() (*OracleDelta, error) {
+	m := new(OracleDelta)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
 }
 
-func (x *workerReceivePredicateServer) SendAndClose(m *api.Payload) error {
-	return x.ServerStream.SendMsg(m)
+func (c *zeroClient) ShouldServe(ctx context.Context, in *Tablet, opts ...grpc.CallOption) (*Tablet, error) {
+	out := new(Tablet)
+	err := c.cc.Invoke(ctx, "/pb.Zero/ShouldServe", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
 
-func (x *workerReceivePredicateServer) Recv() (*KVS, error) {
-	m := new(KVS)
-	if err := x.ServerStream.RecvMsg(m); err != nil {
+func (c *zeroClient) AssignUids(ctx context.Context, in *Num, opts ...grpc.CallOption) (*AssignedIds, error) {
+	out := new(AssignedIds)
+	err := c.cc.Invoke(ctx, "/pb.Zero/AssignUids", in, out, opts...)
+	if err != nil {
 		return nil, err
 	}
-	return m, nil
+	return out, nil
 }
 
-func _Worker_MovePredicate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(MovePredicatePayload)
-	if err := dec(in); err != nil {
+func (c *zeroClient) Timestamps(ctx context.Context, in *Num, opts ...grpc.CallOption) (*AssignedIds, error) {
+	out := new(AssignedIds)
+	err := c.cc.Invoke(ctx, "/pb.Zero/Timestamps", in, out, opts...)
+	if err != nil {
 		return nil, err
 	}
-	if interceptor == nil {
-		return srv.(WorkerServer).MovePredicate(ctx, in)
-	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/pb.Worker/MovePredicate",
-	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(WorkerServer).MovePredicate(ctx, req.(*MovePredicatePayload))
-	}
-	return interceptor(ctx, in, info, handler)
+	return out, nil
 }
 
-func _Worker_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
-	m := new(SubscriptionRequest)
-	if err := stream.RecvMsg(m); err != nil {
-		return err
+func (c *zeroClient) CommitOrAbort(ctx context.Context, in *api.TxnContext, opts ...grpc.CallOption) (*api.TxnContext, error) {
+	out := new(api.TxnContext)
+	err := c.cc.Invoke(ctx, "/pb.Zero/CommitOrAbort", in, out, opts...)
+	if err != nil {
+		return nil, err
 	}
-	return srv.(WorkerServer).Subscribe(m, &workerSubscribeServer{stream})
+	return out, nil
 }
 
-type Worker_SubscribeServer interface {
-	Send(*pb.KVList) error
-	grpc.ServerStream
+func (c *zeroClient) TryAbort(ctx context.Context, in *TxnTimestamps, opts ...grpc.CallOption) (*OracleDelta, error) {
+	out := new(OracleDelta)
+	err := c.cc.Invoke(ctx, "/pb.Zero/TryAbort", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
 
-type workerSubscribeServer struct {
-	grpc.ServerStream
+func (c *zeroClient) Idempotency(ctx context.Context, in *IdempotencyRecord, opts ...grpc.CallOption) (*IdempotencyRecord, error) {
+	out := new(IdempotencyRecord)
+	err := c.cc.Invoke(ctx, "/pb.Zero/Idempotency", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
 
-func (x *workerSubscribeServer) Send(m *pb.KVList) error {
-	return x.ServerStream.SendMsg(m)
+func (c *zeroClient) UpdateStrictSchema(ctx context.Context, in *StrictSchema, opts ...grpc.CallOption) (*api.Payload, error) {
+	out := new(api.Payload)
+	err := c.cc.Invoke(ctx, "/pb.Zero/UpdateStrictSchema", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
 
-func _Worker_UpdateGraphQLSchema_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(UpdateGraphQLSchemaRequest)
-	if err := dec(in); err != nil {
+func (c *zeroClient) UpdateReplication(ctx context.Context, in *ReplicationState, opts ...grpc.CallOption) (*api.Payload, error) {
+	out := new(api.Payload)
+	err := c.cc.Invoke(ctx, "/pb.Zero/UpdateReplication", in, out, opts...)
+	if err != nil {
 		return nil, err
 	}
-	if interceptor == nil {
-		return srv.(WorkerServer).UpdateGraphQLSchema(ctx, in)
-	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/pb.Worker/UpdateGraphQLSchema",
-	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(WorkerServer).UpdateGraphQLSchema(ctx, req.(*UpdateGraphQLSchemaRequest))
-	}
-	return interceptor(ctx, in, info, handler)
+	return out, nil
 }
 
-var _Worker_serviceDesc = grpc.ServiceDesc{
-	ServiceName: "pb.Worker",
-	HandlerType: (*WorkerServer)(nil),
-	Methods: []grpc.MethodDesc{
-		{
-			MethodName: "Mutate",
-			Handler:    _Worker_Mutate_Handler,
-		},
-		{
-			MethodName: "ServeTask",
-			Handler:    _Worker_ServeTask_Handler,
-		},
-		{
-			MethodName: "Sort",
-			Handler:    _Worker_Sort_Handler,
-		},
-		{
-			MethodName: "Schema",
-			Handler:    _Worker_Schema_Handler,
-		},
-		{
-			MethodName: "Backup",
-			Handler:    _Worker_Backup_Handler,
-		},
-		{
-			MethodName: "Restore",
-			Handler:    _Worker_Restore_Handler,
-		},
-		{
-			MethodName: "Export",
-			Handler:    _Worker_Export_Handler,
-		},
-		{
-			MethodName: "MovePredicate",
-			Handler:    _Worker_MovePredicate_Handler,
-		},
-		{
-			MethodName: "UpdateGraphQLSchema",
-			Handler:    _Worker_UpdateGraphQLSchema_Handler,
-		},
-	},
-	Streams: []grpc.StreamDesc{
-		{
-			StreamName:    "StreamSnapshot",
-			Handler:       _Worker_StreamSnapshot_Handler,
-			ServerStreams: true,
-			ClientStreams: true,
-		},
-		{
-			StreamName:    "ReceivePredicate",
-			Handler:       _Worker_ReceivePredicate_Handler,
-			ClientStreams: true,
-		},
-		{
-			StreamName:    "Subscribe",
-			Handler:       _Worker_Subscribe_Handler,
-			ServerStreams: true,
-		},
-	},
-	Metadata: "pb.proto",
+// ZeroServer is the server API for Zero service.
+type ZeroServer interface {
+	// These 3 endpoints are for handling membership.
+	Connect(context.Context, *Member) (*ConnectionState, error)
+	UpdateMembership(context.Context, *Group) (*api.Payload, error)
+	StreamMembership(*api.Payload, Zero_StreamMembershipServer) error
+	Oracle(*api.Payload, Zero_OracleServer) error
+	ShouldServe(context.Context, *Tablet) (*Tablet, error)
+	AssignUids(context.Context, *Num) (*AssignedIds, error)
+	Timestamps(context.Context, *Num) (*AssignedIds, error)
+	CommitOrAbort(context.Context, *api.TxnContext) (*api.TxnContext, error)
+	TryAbort(context.Context, *TxnTimestamps) (*OracleDelta, error)
+	// Returns the commit made with the idempotency key of the given record, if any.
+	Idempotency(context.Context, *IdempotencyRecord) (*IdempotencyRecord, error)
+	// Enables or disables the strict schema mode of the cluster.
+	UpdateStrictSchema(context.Context, *StrictSchema) (*api.Payload, error)
+	// Records the progress of the replication of a replica cluster, or requests its promotion.
+	UpdateReplication(context.Context, *ReplicationState) (*api.Payload, error)
 }
 
-func (m *List) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
-	if err != nil {
-		return nil, err
-	}
-	return dAtA[:n], nil
+// UnimplementedZeroServer can be embedded to have forward compatible implementations.
+type UnimplementedZeroServer struct {
 }
 
-func (m *List) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
+func (*UnimplementedZeroServer) Connect(ctx context.Context, req *Member) (*ConnectionState, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Connect not implemented")
 }
-
-func (m *List) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if m.XXX_unrecognized != nil {
-		i -= len(m.XXX_unrecognized)
-		copy(dAtA[i:], m.XXX_unrecognized)
-	}
-	if len(m.Uids) > 0 {
-		for iNdEx := len(m.Uids) - 1; iNdEx >= 0; iNdEx-- {
-			i -= 8
-			encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(m.Uids[iNdEx]))
-		}
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Uids)*8))
-		i--
-		dAtA[i] = 0xa
-	}
-	return len(dAtA) - i, nil
+func (*UnimplementedZeroServer) UpdateMembership(ctx context.Context, req *Group) (*api.Payload, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateMembership not implemented")
+}
+func (*UnimplementedZeroServer) StreamMembership(req *api.Payload, srv Zero_StreamMembershipServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamMembership not implemented")
+}
+func (*UnimplementedZeroServer) Oracle(req *api.Payload, srv Zero_OracleServer) error {
+	return status.Errorf(codes.Unimplemented, "method Oracle not implemented")
+}
+func (*UnimplementedZeroServer) ShouldServe(ctx context.Context, req *Tablet) (*Tablet, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ShouldServe not implemented")
+}
+func (*UnimplementedZeroServer) AssignUids(ctx context.Context, req *Num) (*AssignedIds, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AssignUids not implemented")
+}
+func (*UnimplementedZeroServer) Timestamps(ctx context.Context, req *Num) (*AssignedIds, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Timestamps not implemented")
+}
+func (*UnimplementedZeroServer) CommitOrAbort(ctx context.Context, req *api.TxnContext) (*api.TxnContext, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CommitOrAbort not implemented")
+}
+func (*UnimplementedZeroServer) TryAbort(ctx context.Context, req *TxnTimestamps) (*OracleDelta, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TryAbort not implemented")
+}
+func (*UnimplementedZeroServer) Idempotency(ctx context.Context, req *IdempotencyRecord) (*IdempotencyRecord, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Idempotency not implemented")
+}
+func (*UnimplementedZeroServer) UpdateStrictSchema(ctx context.Context, req *StrictSchema) (*api.Payload, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateStrictSchema not implemented")
 }
 
-func (m *TaskValue) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
-	if err != nil {
-		return nil, err
-	}
-	return dAtA[:n], nil
+func (*UnimplementedZeroServer) UpdateReplication(ctx context.Context, req *ReplicationState) (*api.Payload, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateReplication not implemented")
 }
 
-func (m *TaskValue) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
+func RegisterZeroServer(s *grpc.Server, srv ZeroServer) {
+	s.RegisterService(&_Zero_serviceDesc, srv)
 }
 
-func (m *TaskValue) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if m.XXX_unrecognized != nil {
-		i -= len(m.XXX_unrecognized)
-		copy(dAtA[i:], m.XXX_unrecognized)
+func _Zero_Connect_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Member)
+	if err := dec(in); err != nil {
+		return nil, err
 	}
-	if m.ValType != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.ValType))
-		i--
-		dAtA[i] = 0x10
+	if interceptor == nil {
+		return srv.(ZeroServer).Connect(ctx, in)
 	}
-	if len(m.Val) > 0 {
-		i -= len(m.Val)
-		copy(dAtA[i:], m.Val)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Val)))
-		i--
-		dAtA[i] = 0xa
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Zero/Connect",
 	}
-	return len(dAtA) - i, nil
-}
-
-func (m *SrcFunction) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
-	if err != nil {
-		return nil, err
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ZeroServer).Connect(ctx, req.(*Member))
 	}
-	return dAtA[:n], nil
-}
-
-func (m *SrcFunction) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
+	return interceptor(ctx, in, info, handler)
 }
 
-func (m *SrcFunction) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if m.XXX_unrecognized != nil {
-		i -= len(m.XXX_unrecognized)
-		copy(dAtA[i:], m.XXX_unrecognized)
+func _Zero_UpdateMembership_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Group)
+	if err := dec(in); err != nil {
+		return nil, err
 	}
-	if m.IsCount {
-		i--
-		if m.IsCount {
-			dAtA[i] = 1
-		} else {
-			dAtA[i] = 0
-		}
-		i--
-		dAtA[i] = 0x20
+	if interceptor == nil {
+		return srv.(ZeroServer).UpdateMembership(ctx, in)
 	}
-	if len(m.Args) > 0 {
-		for iNdEx := len(m.Args) - 1; iNdEx >= 0; iNdEx-- {
-			i -= len(m.Args[iNdEx])
-			copy(dAtA[i:], m.Args[iNdEx])
-			i = encodeVarintPb(dAtA, i, uint64(len(m.Args[iNdEx])))
-			i--
-			dAtA[i] = 0x1a
-		}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Zero/UpdateMembership",
 	}
-	if len(m.Name) > 0 {
-		i -= len(m.Name)
-		copy(dAtA[i:], m.Name)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Name)))
-		i--
-		dAtA[i] = 0xa
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ZeroServer).UpdateMembership(ctx, req.(*Group))
 	}
-	return len(dAtA) - i, nil
+	return interceptor(ctx, in, info, handler)
 }
 
-func (m *Query) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
-	if err != nil {
-		return nil, err
+func _Zero_StreamMembership_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(api.Payload)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
 	}
-	return dAtA[:n], nil
+	return srv.(ZeroServer).StreamMembership(m, &zeroStreamMembershipServer{stream})
 }
 
-func (m *Query) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
+type Zero_StreamMembershipServer interface {
+	Send(*MembershipState) error
+	grpc.ServerStream
 }
 
-func (m *Query) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if m.XXX_unrecognized != nil {
-		i -= len(m.XXX_unrecognized)
-		copy(dAtA[i:], m.XXX_unrecognized)
+type zeroStreamMembershipServer struct {
+	grpc.ServerStream
+}
+
+func (x *zeroStreamMembershipServer) Send(m *MembershipState) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Zero_Oracle_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(api.Payload)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
 	}
-	if m.First != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.First))
-		i--
-		dAtA[i] = 0x78
-	}
-	if m.Cache != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.Cache))
-		i--
-		dAtA[i] = 0x70
+	return srv.(ZeroServer).Oracle(m, &zeroOracleServer{stream})
+}
+
+type Zero_OracleServer interface {
+	Send(*OracleDelta) error
+	grpc.ServerStream
+}
+
+type zeroOracleServer struct {
+	grpc.ServerStream
+}
+
+func (x *zeroOracleServer) Send(m *OracleDelta) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Zero_ShouldServe_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Tablet)
+	if err := dec(in); err != nil {
+		return nil, err
 	}
-	if m.ReadTs != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.ReadTs))
-		i--
-		dAtA[i] = 0x68
+	if interceptor == nil {
+		return srv.(ZeroServer).ShouldServe(ctx, in)
 	}
-	if m.ExpandAll {
-		i--
-		if m.ExpandAll {
-			dAtA[i] = 1
-		} else {
-			dAtA[i] = 0
-		}
-		i--
-		dAtA[i] = 0x50
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Zero/ShouldServe",
 	}
-	if m.FacetsFilter != nil {
-		{
-			size, err := m.FacetsFilter.MarshalToSizedBuffer(dAtA[:i])
-			if err != nil {
-				return 0, err
-			}
-			i -= size
-			i = encodeVarintPb(dAtA, i, uint64(size))
-		}
-		i--
-		dAtA[i] = 0x4a
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ZeroServer).ShouldServe(ctx, req.(*Tablet))
 	}
-	if m.FacetParam != nil {
-		{
-			size, err := m.FacetParam.MarshalToSizedBuffer(dAtA[:i])
-			if err != nil {
-				return 0, err
-			}
-			i -= size
-			i = encodeVarintPb(dAtA, i, uint64(size))
-		}
-		i--
-		dAtA[i] = 0x42
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Zero_AssignUids_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Num)
+	if err := dec(in); err != nil {
+		return nil, err
 	}
-	if m.Reverse {
-		i--
-		if m.Reverse {
-			dAtA[i] = 1
-		} else {
-			dAtA[i] = 0
-		}
-		i--
-		dAtA[i] = 0x38
+	if interceptor == nil {
+		return srv.(ZeroServer).AssignUids(ctx, in)
 	}
-	if m.SrcFunc != nil {
-		{
-			size, err := m.SrcFunc.MarshalToSizedBuffer(dAtA[:i])
-			if err != nil {
-				return 0, err
-			}
-			i -= size
-			i = encodeVarintPb(dAtA, i, uint64(size))
-		}
-		i--
-		dAtA[i] = 0x32
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Zero/AssignUids",
 	}
-	if m.UidList != nil {
-		{
-			size, err := m.UidList.MarshalToSizedBuffer(dAtA[:i])
-			if err != nil {
-				return 0, err
-			}
-			i -= size
-			i = encodeVarintPb(dAtA, i, uint64(size))
-		}
-		i--
-		dAtA[i] = 0x2a
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ZeroServer).AssignUids(ctx, req.(*Num))
 	}
-	if m.DoCount {
-		i--
-		if m.DoCount {
-			dAtA[i] = 1
-		} else {
-			dAtA[i] = 0
-		}
-		i--
-		dAtA[i] = 0x20
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Zero_Timestamps_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Num)
+	if err := dec(in); err != nil {
+		return nil, err
 	}
-	if m.AfterUid != 0 {
-		i -= 8
-		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(m.AfterUid))
-		i--
-		dAtA[i] = 0x19
+	if interceptor == nil {
+		return srv.(ZeroServer).Timestamps(ctx, in)
 	}
-	if len(m.Langs) > 0 {
-		for iNdEx := len(m.Langs) - 1; iNdEx >= 0; iNdEx-- {
-			i -= len(m.Langs[iNdEx])
-			copy(dAtA[i:], m.Langs[iNdEx])
-			i = encodeVarintPb(dAtA, i, uint64(len(m.Langs[iNdEx])))
-			i--
-			dAtA[i] = 0x12
-		}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Zero/Timestamps",
 	}
-	if len(m.Attr) > 0 {
-		i -= len(m.Attr)
-		copy(dAtA[i:], m.Attr)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Attr)))
-		i--
-		dAtA[i] = 0xa
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ZeroServer).Timestamps(ctx, req.(*Num))
 	}
-	return len(dAtA) - i, nil
+	return interceptor(ctx, in, info, handler)
 }
 
-func (m *ValueList) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
-	if err != nil {
+func _Zero_CommitOrAbort_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(api.TxnContext)
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	return dAtA[:n], nil
+	if interceptor == nil {
+		return srv.(ZeroServer).CommitOrAbort(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Zero/CommitOrAbort",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ZeroServer).CommitOrAbort(ctx, req.(*api.TxnContext))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (m *ValueList) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
+func _Zero_TryAbort_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TxnTimestamps)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ZeroServer).TryAbort(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Zero/TryAbort",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ZeroServer).TryAbort(ctx, req.(*TxnTimestamps))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (m *ValueList) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if m.XXX_unrecognized != nil {
-		i -= len(m.XXX_unrecognized)
-		copy(dAtA[i:], m.XXX_unrecognized)
+func _Zero_Idempotency_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IdempotencyRecord)
+	if err := dec(in); err != nil {
+		return nil, err
 	}
-	if len(m.Values) > 0 {
-		for iNdEx := len(m.Values) - 1; iNdEx >= 0; iNdEx-- {
-			{
-				size, err := m.Values[iNdEx].MarshalToSizedBuffer(dAtA[:i])
-				if err != nil {
-					return 0, err
-				}
-				i -= size
-				i = encodeVarintPb(dAtA, i, uint64(size))
-			}
-			i--
-			dAtA[i] = 0xa
-		}
+	if interceptor == nil {
+		return srv.(ZeroServer).Idempotency(ctx, in)
 	}
-	return len(dAtA) - i, nil
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Zero/Idempotency",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ZeroServer).Idempotency(ctx, req.(*IdempotencyRecord))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (m *LangList) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
-	if err != nil {
+func _Zero_UpdateStrictSchema_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StrictSchema)
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	return dAtA[:n], nil
+	if interceptor == nil {
+		return srv.(ZeroServer).UpdateStrictSchema(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Zero/UpdateStrictSchema",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ZeroServer).UpdateStrictSchema(ctx, req.(*StrictSchema))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (m *LangList) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
+func _Zero_UpdateReplication_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReplicationState)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ZeroServer).UpdateReplication(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Zero/UpdateReplication",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ZeroServer).UpdateReplication(ctx, req.(*ReplicationState))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (m *LangList) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if m.XXX_unrecognized != nil {
-		i -= len(m.XXX_unrecognized)
-		copy(dAtA[i:], m.XXX_unrecognized)
+var _Zero_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "pb.Zero",
+	HandlerType: (*ZeroServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Connect",
+			Handler:    _Zero_Connect_Handler,
+		},
+		{
+			MethodName: "UpdateMembership",
+			Handler:    _Zero_UpdateMembership_Handler,
+		},
+		{
+			MethodName: "ShouldServe",
+			Handler:    _Zero_ShouldServe_Handler,
+		},
+		{
+			MethodName: "AssignUids",
+			Handler:    _Zero_AssignUids_Handler,
+		},
+		{
+			MethodName: "Timestamps",
+			Handler:    _Zero_Timestamps_Handler,
+		},
+		{
+			MethodName: "CommitOrAbort",
+			Handler:    _Zero_CommitOrAbort_Handler,
+		},
+		{
+			MethodName: "TryAbort",
+			Handler:    _Zero_TryAbort_Handler,
+		},
+		{
+			MethodName: "Idempotency",
+			Handler:    _Zero_Idempotency_Handler,
+		},
+		{
+			MethodName: "UpdateStrictSchema",
+			Handler:    _Zero_UpdateStrictSchema_Handler,
+		},
+		{
+			MethodName: "UpdateReplication",
+			Handler:    _Zero_UpdateReplication_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamMembership",
+			Handler:       _Zero_StreamMembership_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Oracle",
+			Handler:       _Zero_Oracle_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "pb.proto",
+}
+
+// WorkerClient is the client API for Worker service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type WorkerClient interface {
+	// Data serving RPCs.
+	Mutate(ctx context.Context, in *Mutations, opts ...grpc.CallOption) (*api.TxnContext, error)
+	ServeTask(ctx context.Context, in *Query, opts ...grpc.CallOption) (*Result, error)
+	StreamSnapshot(ctx context.Context, opts ...grpc.CallOption) (Worker_StreamSnapshotClient, error)
+	Sort(ctx context.Context, in *SortMessage, opts ...grpc.CallOption) (*SortResult, error)
+	Schema(ctx context.Context, in *SchemaRequest, opts ...grpc.CallOption) (*SchemaResult, error)
+	Backup(ctx context.Context, in *BackupRequest, opts ...grpc.CallOption) (*Status, error)
+	Restore(ctx context.Context, in *RestoreRequest, opts ...grpc.CallOption) (*Status, error)
+	Export(ctx context.Context, in *ExportRequest, opts ...grpc.CallOption) (*ExportResponse, error)
+	ReceivePredicate(ctx context.Context, opts ...grpc.CallOption) (Worker_ReceivePredicateClient, error)
+	MovePredicate(ctx context.Context, in *MovePredicatePayload, opts ...grpc.CallOption) (*api.Payload, error)
+	Subscribe(ctx context.Context, in *SubscriptionRequest, opts ...grpc.CallOption) (Worker_SubscribeClient, error)
+	UpdateGraphQLSchema(ctx context.Context, in *UpdateGraphQLSchemaRequest, opts ...grpc.CallOption) (*UpdateGraphQLSchemaResponse, error)
+	ReadBlob(ctx context.Context, in *BlobRequest, opts ...grpc.CallOption) (Worker_ReadBlobClient, error)
+	// Streams the data of a group of the primary cluster to a replica cluster.
+	ReplicateGroup(ctx context.Context, in *ReplicationRequest, opts ...grpc.CallOption) (Worker_ReplicateGroupClient, error)
+	// Writes the key-values replicated from the primary cluster in the group.
+	ReplicateKeys(ctx context.Context, in *KVS, opts ...grpc.CallOption) (*api.Payload, error)
+	// Waits for the group to apply the read timestamp of a freeze point.
+	FreezeGroup(ctx context.Context, in *FrozenGroup, opts ...grpc.CallOption) (*FrozenGroup, error)
+	// Returns the progress of the predicate being moved by the group to another group.
+	MoveProgress(ctx context.Context, in *MovePredicatePayload, opts ...grpc.CallOption) (*MoveProgress, error)
+}
+
+type workerClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewWorkerClient(cc *grpc.ClientConn) WorkerClient {
+	return &workerClient{cc}
+}
+
+func (c *workerClient) Mutate(ctx context.Context, in *Mutations, opts ...grpc.CallOption) (*api.TxnContext, error) {
+	out := new(api.TxnContext)
+	err := c.cc.Invoke(ctx, "/pb.Worker/Mutate", in, out, opts...)
+	if err != nil {
+		return nil, err
 	}
-	if len(m.Lang) > 0 {
-		for iNdEx := len(m.Lang) - 1; iNdEx >= 0; iNdEx-- {
-			i -= len(m.Lang[iNdEx])
-			copy(dAtA[i:], m.Lang[iNdEx])
-			i = encodeVarintPb(dAtA, i, uint64(len(m.Lang[iNdEx])))
-			i--
-			dAtA[i] = 0xa
-		}
+	return out, nil
+}
+
+func (c *workerClient) ServeTask(ctx context.Context, in *Query, opts ...grpc.CallOption) (*Result, error) {
+	out := new(Result)
+	err := c.cc.Invoke(ctx, "/pb.Worker/ServeTask", in, out, opts...)
+	if err != nil {
+		return nil, err
 	}
-	return len(dAtA) - i, nil
+	return out, nil
 }
 
-func (m *Result) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+func (c *workerClient) StreamSnapshot(ctx context.Context, opts ...grpc.CallOption) (Worker_StreamSnapshotClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Worker_serviceDesc.Streams[0], "/pb.Worker/StreamSnapshot", opts...)
 	if err != nil {
 		return nil, err
 	}
-	return dAtA[:n], nil
+	x := &workerStreamSnapshotClient{stream}
+	return x, nil
 }
 
-func (m *Result) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
+type Worker_StreamSnapshotClient interface {
+	Send(*Snapshot) error
+	Recv() (*KVS, error)
+	grpc.ClientStream
 }
 
-func (m *Result) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if m.XXX_unrecognized != nil {
-		i -= len(m.XXX_unrecognized)
-		copy(dAtA[i:], m.XXX_unrecognized)
-	}
-	if m.List {
-		i--
-		if m.List {
-			dAtA[i] = 1
-		} else {
-			dAtA[i] = 0
-		}
-		i--
-		dAtA[i] = 0x38
-	}
-	if len(m.LangMatrix) > 0 {
-		for iNdEx := len(m.LangMatrix) - 1; iNdEx >= 0; iNdEx-- {
-			{
-				size, err := m.LangMatrix[iNdEx].MarshalToSizedBuffer(dAtA[:i])
-				if err != nil {
-					return 0, err
-				}
-				i -= size
-				i = encodeVarintPb(dAtA, i, uint64(size))
-			}
-			i--
-			dAtA[i] = 0x32
-		}
-	}
-	if len(m.FacetMatrix) > 0 {
-		for iNdEx := len(m.FacetMatrix) - 1; iNdEx >= 0; iNdEx-- {
-			{
-				size, err := m.FacetMatrix[iNdEx].MarshalToSizedBuffer(dAtA[:i])
-				if err != nil {
-					return 0, err
-				}
-				i -= size
-				i = encodeVarintPb(dAtA, i, uint64(size))
-			}
-			i--
-			dAtA[i] = 0x2a
-		}
-	}
-	if m.IntersectDest {
-		i--
-		if m.IntersectDest {
-			dAtA[i] = 1
-		} else {
-			dAtA[i] = 0
-		}
-		i--
-		dAtA[i] = 0x20
-	}
-	if len(m.Counts) > 0 {
-		dAtA6 := make([]byte, len(m.Counts)*10)
-		var j5 int
-		for _, num := range m.Counts {
-			for num >= 1<<7 {
-				dAtA6[j5] = uint8(uint64(num)&0x7f | 0x80)
-				num >>= 7
-				j5++
-			}
-			dAtA6[j5] = uint8(num)
-			j5++
-		}
-		i -= j5
-		copy(dAtA[i:], dAtA6[:j5])
-		i = encodeVarintPb(dAtA, i, uint64(j5))
-		i--
-		dAtA[i] = 0x1a
-	}
-	if len(m.ValueMatrix) > 0 {
-		for iNdEx := len(m.ValueMatrix) - 1; iNdEx >= 0; iNdEx-- {
-			{
-				size, err := m.ValueMatrix[iNdEx].MarshalToSizedBuffer(dAtA[:i])
-				if err != nil {
-					return 0, err
-				}
-				i -= size
-				i = encodeVarintPb(dAtA, i, uint64(size))
-			}
-			i--
-			dAtA[i] = 0x12
-		}
-	}
-	if len(m.UidMatrix) > 0 {
-		for iNdEx := len(m.UidMatrix) - 1; iNdEx >= 0; iNdEx-- {
-			{
-				size, err := m.UidMatrix[iNdEx].MarshalToSizedBuffer(dAtA[:i])
-				if err != nil {
-					return 0, err
-				}
-				i -= size
-				i = encodeVarintPb(dAtA, i, uint64(size))
-			}
-			i--
-			dAtA[i] = 0xa
-		}
+type workerStreamSnapshotClient struct {
+	grpc.ClientStream
+}
+
+func (x *workerStreamSnapshotClient) Send(m *Snapshot) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *workerStreamSnapshotClient) Recv() (*KVS, error) {
+	m := new(KVS)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
 	}
-	return len(dAtA) - i, nil
+	return m, nil
 }
 
-func (m *Order) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+func (c *workerClient) Sort(ctx context.Context, in *SortMessage, opts ...grpc.CallOption) (*SortResult, error) {
+	out := new(SortResult)
+	err := c.cc.Invoke(ctx, "/pb.Worker/Sort", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
-	return dAtA[:n], nil
+	return out, nil
 }
 
-func (m *Order) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
-}
-
-func (m *Order) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if m.XXX_unrecognized != nil {
-		i -= len(m.XXX_unrecognized)
-		copy(dAtA[i:], m.XXX_unrecognized)
-	}
-	if len(m.Langs) > 0 {
-		for iNdEx := len(m.Langs) - 1; iNdEx >= 0; iNdEx-- {
-			i -= len(m.Langs[iNdEx])
-			copy(dAtA[i:], m.Langs[iNdEx])
-			i = encodeVarintPb(dAtA, i, uint64(len(m.Langs[iNdEx])))
-			i--
-			dAtA[i] = 0x1a
-		}
-	}
-	if m.Desc {
-		i--
-		if m.Desc {
-			dAtA[i] = 1
-		} else {
-			dAtA[i] = 0
-		}
-		i--
-		dAtA[i] = 0x10
-	}
-	if len(m.Attr) > 0 {
-		i -= len(m.Attr)
-		copy(dAtA[i:], m.Attr)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Attr)))
-		i--
-		dAtA[i] = 0xa
+func (c *workerClient) Schema(ctx context.Context, in *SchemaRequest, opts ...grpc.CallOption) (*SchemaResult, error) {
+	out := new(SchemaResult)
+	err := c.cc.Invoke(ctx, "/pb.Worker/Schema", in, out, opts...)
+	if err != nil {
+		return nil, err
 	}
-	return len(dAtA) - i, nil
+	return out, nil
 }
 
-func (m *SortMessage) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+func (c *workerClient) Backup(ctx context.Context, in *BackupRequest, opts ...grpc.CallOption) (*Status, error) {
+	out := new(Status)
+	err := c.cc.Invoke(ctx, "/pb.Worker/Backup", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
-	return dAtA[:n], nil
+	return out, nil
 }
 
-func (m *SortMessage) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
+func (c *workerClient) Restore(ctx context.Context, in *RestoreRequest, opts ...grpc.CallOption) (*Status, error) {
+	out := new(Status)
+	err := c.cc.Invoke(ctx, "/pb.Worker/Restore", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
 
-func (m *SortMessage) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if m.XXX_unrecognized != nil {
-		i -= len(m.XXX_unrecognized)
-		copy(dAtA[i:], m.XXX_unrecognized)
-	}
-	if m.ReadTs != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.ReadTs))
-		i--
-		dAtA[i] = 0x68
-	}
-	if m.Offset != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.Offset))
-		i--
-		dAtA[i] = 0x20
-	}
-	if m.Count != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.Count))
-		i--
-		dAtA[i] = 0x18
-	}
-	if len(m.UidMatrix) > 0 {
-		for iNdEx := len(m.UidMatrix) - 1; iNdEx >= 0; iNdEx-- {
-			{
-				size, err := m.UidMatrix[iNdEx].MarshalToSizedBuffer(dAtA[:i])
-				if err != nil {
-					return 0, err
-				}
-				i -= size
-				i = encodeVarintPb(dAtA, i, uint64(size))
-			}
-			i--
-			dAtA[i] = 0x12
-		}
-	}
-	if len(m.Order) > 0 {
-		for iNdEx := len(m.Order) - 1; iNdEx >= 0; iNdEx-- {
-			{
-				size, err := m.Order[iNdEx].MarshalToSizedBuffer(dAtA[:i])
-				if err != nil {
-					return 0, err
-				}
-				i -= size
-				i = encodeVarintPb(dAtA, i, uint64(size))
-			}
-			i--
-			dAtA[i] = 0xa
-		}
+func (c *workerClient) Export(ctx context.Context, in *ExportRequest, opts ...grpc.CallOption) (*ExportResponse, error) {
+	out := new(ExportResponse)
+	err := c.cc.Invoke(ctx, "/pb.Worker/Export", in, out, opts...)
+	if err != nil {
+		return nil, err
 	}
-	return len(dAtA) - i, nil
+	return out, nil
 }
 
-func (m *SortResult) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+func (c *workerClient) ReceivePredicate(ctx context.Context, opts ...grpc.CallOption) (Worker_ReceivePredicateClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Worker_serviceDesc.Streams[1], "/pb.Worker/ReceivePredicate", opts...)
 	if err != nil {
 		return nil, err
 	}
-	return dAtA[:n], nil
+	x := &workerReceivePredicateClient{stream}
+	return x, nil
 }
 
-func (m *SortResult) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
+type Worker_ReceivePredicateClient interface {
+	Send(*KVS) error
+	CloseAndRecv() (*api.Payload, error)
+	grpc.ClientStream
 }
 
-func (m *SortResult) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if m.XXX_unrecognized != nil {
-		i -= len(m.XXX_unrecognized)
-		copy(dAtA[i:], m.XXX_unrecognized)
+type workerReceivePredicateClient struct {
+	grpc.ClientStream
+}
+
+func (x *workerReceivePredicateClient) Send(m *KVS) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *workerReceivePredicateClient) CloseAndRecv() (*api.Payload, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
 	}
-	if len(m.UidMatrix) > 0 {
-		for iNdEx := len(m.UidMatrix) - 1; iNdEx >= 0; iNdEx-- {
-			{
-				size, err := m.UidMatrix[iNdEx].MarshalToSizedBuffer(dAtA[:i])
-				if err != nil {
-					return 0, err
-				}
-				i -= size
-				i = encodeVarintPb(dAtA, i, uint64(size))
-			}
-			i--
-			dAtA[i] = 0xa
-		}
+	m := new(api.Payload)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
 	}
-	return len(dAtA) - i, nil
+	return m, nil
 }
 
-func (m *RaftContext) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+func (c *workerClient) MovePredicate(ctx context.Context, in *MovePredicatePayload, opts ...grpc.CallOption) (*api.Payload, error) {
+	out := new(api.Payload)
+	err := c.cc.Invoke(ctx, "/pb.Worker/MovePredicate", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
-	return dAtA[:n], nil
-}
-
-func (m *RaftContext) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
+	return out, nil
 }
 
-func (m *RaftContext) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if m.XXX_unrecognized != nil {
-		i -= len(m.XXX_unrecognized)
-		copy(dAtA[i:], m.XXX_unrecognized)
-	}
-	if m.SnapshotTs != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.SnapshotTs))
-		i--
-		dAtA[i] = 0x20
-	}
-	if len(m.Addr) > 0 {
-		i -= len(m.Addr)
-		copy(dAtA[i:], m.Addr)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Addr)))
-		i--
-		dAtA[i] = 0x1a
+func (c *workerClient) Subscribe(ctx context.Context, in *SubscriptionRequest, opts ...grpc.CallOption) (Worker_SubscribeClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Worker_serviceDesc.Streams[2], "/pb.Worker/Subscribe", opts...)
+	if err != nil {
+		return nil, err
 	}
-	if m.Group != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.Group))
-		i--
-		dAtA[i] = 0x10
+	x := &workerSubscribeClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
 	}
-	if m.Id != 0 {
-		i -= 8
-		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(m.Id))
-		i--
-		dAtA[i] = 0x9
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
 	}
-	return len(dAtA) - i, nil
+	return x, nil
 }
 
-func (m *Member) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
-	if err != nil {
+type Worker_SubscribeClient interface {
+	Recv() (*pb.KVList, error)
+	grpc.ClientStream
+}
+
+type workerSubscribeClient struct {
+	grpc.ClientStream
+}
+
+func (x *workerSubscribeClient) Recv() (*pb.KVList, error) {
+	m := new(pb.KVList)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
 		return nil, err
 	}
-	return dAtA[:n], nil
+	return m, nil
 }
 
-func (m *Member) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
+func (c *workerClient) UpdateGraphQLSchema(ctx context.Context, in *UpdateGraphQLSchemaRequest, opts ...grpc.CallOption) (*UpdateGraphQLSchemaResponse, error) {
+	out := new(UpdateGraphQLSchemaResponse)
+	err := c.cc.Invoke(ctx, "/pb.Worker/UpdateGraphQLSchema", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
 
-func (m *Member) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if m.XXX_unrecognized != nil {
-		i -= len(m.XXX_unrecognized)
-		copy(dAtA[i:], m.XXX_unrecognized)
-	}
-	if m.ForceGroupId {
-		i--
-		if m.ForceGroupId {
-			dAtA[i] = 1
-		} else {
-			dAtA[i] = 0
-		}
-		i--
-		dAtA[i] = 0x70
-	}
-	if m.ClusterInfoOnly {
-		i--
-		if m.ClusterInfoOnly {
-			dAtA[i] = 1
-		} else {
-			dAtA[i] = 0
-		}
-		i--
-		dAtA[i] = 0x68
-	}
-	if m.LastUpdate != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.LastUpdate))
-		i--
-		dAtA[i] = 0x30
-	}
-	if m.AmDead {
-		i--
-		if m.AmDead {
-			dAtA[i] = 1
-		} else {
-			dAtA[i] = 0
-		}
-		i--
-		dAtA[i] = 0x28
-	}
-	if m.Leader {
-		i--
-		if m.Leader {
-			dAtA[i] = 1
-		} else {
-			dAtA[i] = 0
-		}
-		i--
-		dAtA[i] = 0x20
-	}
-	if len(m.Addr) > 0 {
-		i -= len(m.Addr)
-		copy(dAtA[i:], m.Addr)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Addr)))
-		i--
-		dAtA[i] = 0x1a
+func (c *workerClient) ReadBlob(ctx context.Context, in *BlobRequest, opts ...grpc.CallOption) (Worker_ReadBlobClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Worker_serviceDesc.Streams[3], "/pb.Worker/ReadBlob", opts...)
+	if err != nil {
+		return nil, err
 	}
-	if m.GroupId != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.GroupId))
-		i--
-		dAtA[i] = 0x10
+	x := &workerReadBlobClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
 	}
-	if m.Id != 0 {
-		i -= 8
-		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(m.Id))
-		i--
-		dAtA[i] = 0x9
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
 	}
-	return len(dAtA) - i, nil
+	return x, nil
 }
 
-func (m *Group) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
-	if err != nil {
-		return nil, err
-	}
-	return dAtA[:n], nil
+type Worker_ReadBlobClient interface {
+	Recv() (*BlobChunk, error)
+	grpc.ClientStream
 }
 
-func (m *Group) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
+type workerReadBlobClient struct {
+	grpc.ClientStream
 }
 
-func (m *Group) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if m.XXX_unrecognized != nil {
-		i -= len(m.XXX_unrecognized)
-		copy(dAtA[i:], m.XXX_unrecognized)
-	}
-	if m.Checksum != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.Checksum))
-		i--
-		dAtA[i] = 0x20
-	}
-	if m.SnapshotTs != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.SnapshotTs))
-		i--
-		dAtA[i] = 0x18
-	}
-	if len(m.Tablets) > 0 {
-		for k := range m.Tablets {
-			v := m.Tablets[k]
-			baseI := i
-			if v != nil {
-				{
-					size, err := v.MarshalToSizedBuffer(dAtA[:i])
-					if err != nil {
-						return 0, err
-					}
-					i -= size
-					i = encodeVarintPb(dAtA, i, uint64(size))
-				}
-				i--
-				dAtA[i] = 0x12
-			}
-			i -= len(k)
-			copy(dAtA[i:], k)
-			i = encodeVarintPb(dAtA, i, uint64(len(k)))
-			i--
-			dAtA[i] = 0xa
-			i = encodeVarintPb(dAtA, i, uint64(baseI-i))
-			i--
-			dAtA[i] = 0x12
-		}
-	}
-	if len(m.Members) > 0 {
-		for k := range m.Members {
-			v := m.Members[k]
-			baseI := i
-			if v != nil {
-				{
-					size, err := v.MarshalToSizedBuffer(dAtA[:i])
-					if err != nil {
-						return 0, err
-					}
-					i -= size
-					i = encodeVarintPb(dAtA, i, uint64(size))
-				}
-				i--
-				dAtA[i] = 0x12
-			}
-			i = encodeVarintPb(dAtA, i, uint64(k))
-			i--
-			dAtA[i] = 0x8
-			i = encodeVarintPb(dAtA, i, uint64(baseI-i))
-			i--
-			dAtA[i] = 0xa
-		}
+func (x *workerReadBlobClient) Recv() (*BlobChunk, error) {
+	m := new(BlobChunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
 	}
-	return len(dAtA) - i, nil
+	return m, nil
 }
 
-func (m *License) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+func (c *workerClient) ReplicateGroup(ctx context.Context, in *ReplicationRequest, opts ...grpc.CallOption) (Worker_ReplicateGroupClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Worker_serviceDesc.Streams[4], "/pb.Worker/ReplicateGroup", opts...)
 	if err != nil {
 		return nil, err
 	}
-	return dAtA[:n], nil
+	x := &workerReplicateGroupClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
 }
 
-func (m *License) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
+type Worker_ReplicateGroupClient interface {
+	Recv() (*KVS, error)
+	grpc.ClientStream
 }
 
-func (m *License) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if m.XXX_unrecognized != nil {
-		i -= len(m.XXX_unrecognized)
-		copy(dAtA[i:], m.XXX_unrecognized)
-	}
-	if m.Enabled {
-		i--
-		if m.Enabled {
-			dAtA[i] = 1
-		} else {
-			dAtA[i] = 0
-		}
-		i--
-		dAtA[i] = 0x20
-	}
-	if m.ExpiryTs != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.ExpiryTs))
-		i--
-		dAtA[i] = 0x18
+type workerReplicateGroupClient struct {
+	grpc.ClientStream
+}
+
+func (x *workerReplicateGroupClient) Recv() (*KVS, error) {
+	m := new(KVS)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
 	}
-	if m.MaxNodes != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.MaxNodes))
-		i--
-		dAtA[i] = 0x10
+	return m, nil
+}
+
+func (c *workerClient) ReplicateKeys(ctx context.Context, in *KVS, opts ...grpc.CallOption) (*api.Payload, error) {
+	out := new(api.Payload)
+	err := c.cc.Invoke(ctx, "/pb.Worker/ReplicateKeys", in, out, opts...)
+	if err != nil {
+		return nil, err
 	}
-	if len(m.User) > 0 {
-		i -= len(m.User)
-		copy(dAtA[i:], m.User)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.User)))
-		i--
-		dAtA[i] = 0xa
+	return out, nil
+}
+
+func (c *workerClient) FreezeGroup(ctx context.Context, in *FrozenGroup, opts ...grpc.CallOption) (*FrozenGroup, error) {
+	out := new(FrozenGroup)
+	err := c.cc.Invoke(ctx, "/pb.Worker/FreezeGroup", in, out, opts...)
+	if err != nil {
+		return nil, err
 	}
-	return len(dAtA) - i, nil
+	return out, nil
 }
 
-func (m *ZeroProposal) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+func (c *workerClient) MoveProgress(ctx context.Context, in *MovePredicatePayload, opts ...grpc.CallOption) (*MoveProgress, error) {
+	out := new(MoveProgress)
+	err := c.cc.Invoke(ctx, "/pb.Worker/MoveProgress", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
-	return dAtA[:n], nil
+	return out, nil
 }
 
-func (m *ZeroProposal) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
+// WorkerServer is the server API for Worker service.
+type WorkerServer interface {
+	// Data serving RPCs.
+	Mutate(context.Context, *Mutations) (*api.TxnContext, error)
+	ServeTask(context.Context, *Query) (*Result, error)
+	StreamSnapshot(Worker_StreamSnapshotServer) error
+	Sort(context.Context, *SortMessage) (*SortResult, error)
+	Schema(context.Context, *SchemaRequest) (*SchemaResult, error)
+	Backup(context.Context, *BackupRequest) (*Status, error)
+	Restore(context.Context, *RestoreRequest) (*Status, error)
+	Export(context.Context, *ExportRequest) (*ExportResponse, error)
+	ReceivePredicate(Worker_ReceivePredicateServer) error
+	MovePredicate(context.Context, *MovePredicatePayload) (*api.Payload, error)
+	Subscribe(*SubscriptionRequest, Worker_SubscribeServer) error
+	UpdateGraphQLSchema(context.Context, *UpdateGraphQLSchemaRequest) (*UpdateGraphQLSchemaResponse, error)
+	ReadBlob(*BlobRequest, Worker_ReadBlobServer) error
+	// Streams the data of a group of the primary cluster to a replica cluster.
+	ReplicateGroup(*ReplicationRequest, Worker_ReplicateGroupServer) error
+	// Writes the key-values replicated from the primary cluster in the group.
+	ReplicateKeys(context.Context, *KVS) (*api.Payload, error)
+	// Waits for the group to apply the read timestamp of a freeze point.
+	FreezeGroup(context.Context, *FrozenGroup) (*FrozenGroup, error)
+	// Returns the progress of the predicate being moved by the group to another group.
+	MoveProgress(context.Context, *MovePredicatePayload) (*MoveProgress, error)
 }
 
-func (m *ZeroProposal) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if m.XXX_unrecognized != nil {
-		i -= len(m.XXX_unrecognized)
-		copy(dAtA[i:], m.XXX_unrecognized)
-	}
-	if m.License != nil {
-		{
-			size, err := m.License.MarshalToSizedBuffer(dAtA[:i])
-			if err != nil {
-				return 0, err
-			}
-			i -= size
-			i = encodeVarintPb(dAtA, i, uint64(size))
-		}
-		i--
-		dAtA[i] = 0x52
-	}
-	if len(m.Cid) > 0 {
-		i -= len(m.Cid)
-		copy(dAtA[i:], m.Cid)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Cid)))
-		i--
-		dAtA[i] = 0x4a
-	}
-	if len(m.Key) > 0 {
-		i -= len(m.Key)
-		copy(dAtA[i:], m.Key)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Key)))
-		i--
-		dAtA[i] = 0x42
+// UnimplementedWorkerServer can be embedded to have forward compatible implementations.
+type UnimplementedWorkerServer struct {
+}
+
+func (*UnimplementedWorkerServer) Mutate(ctx context.Context, req *Mutations) (*api.TxnContext, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Mutate not implemented")
+}
+func (*UnimplementedWorkerServer) ServeTask(ctx context.Context, req *Query) (*Result, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ServeTask not implemented")
+}
+func (*UnimplementedWorkerServer) StreamSnapshot(srv Worker_StreamSnapshotServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamSnapshot not implemented")
+}
+func (*UnimplementedWorkerServer) Sort(ctx context.Context, req *SortMessage) (*SortResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Sort not implemented")
+}
+func (*UnimplementedWorkerServer) Schema(ctx context.Context, req *SchemaRequest) (*SchemaResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Schema not implemented")
+}
+func (*UnimplementedWorkerServer) Backup(ctx context.Context, req *BackupRequest) (*Status, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Backup not implemented")
+}
+func (*UnimplementedWorkerServer) Restore(ctx context.Context, req *RestoreRequest) (*Status, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Restore not implemented")
+}
+func (*UnimplementedWorkerServer) Export(ctx context.Context, req *ExportRequest) (*ExportResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Export not implemented")
+}
+func (*UnimplementedWorkerServer) ReceivePredicate(srv Worker_ReceivePredicateServer) error {
+	return status.Errorf(codes.Unimplemented, "method ReceivePredicate not implemented")
+}
+func (*UnimplementedWorkerServer) MovePredicate(ctx context.Context, req *MovePredicatePayload) (*api.Payload, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MovePredicate not implemented")
+}
+func (*UnimplementedWorkerServer) Subscribe(req *SubscriptionRequest, srv Worker_SubscribeServer) error {
+	return status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
+}
+func (*UnimplementedWorkerServer) UpdateGraphQLSchema(ctx context.Context, req *UpdateGraphQLSchemaRequest) (*UpdateGraphQLSchemaResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateGraphQLSchema not implemented")
+}
+func (*UnimplementedWorkerServer) ReadBlob(req *BlobRequest, srv Worker_ReadBlobServer) error {
+	return status.Errorf(codes.Unimplemented, "method ReadBlob not implemented")
+}
+
+func (*UnimplementedWorkerServer) ReplicateGroup(req *ReplicationRequest, srv Worker_ReplicateGroupServer) error {
+	return status.Errorf(codes.Unimplemented, "method ReplicateGroup not implemented")
+}
+
+func (*UnimplementedWorkerServer) ReplicateKeys(ctx context.Context, req *KVS) (*api.Payload, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReplicateKeys not implemented")
+}
+
+func (*UnimplementedWorkerServer) FreezeGroup(ctx context.Context, req *FrozenGroup) (*FrozenGroup, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FreezeGroup not implemented")
+}
+
+func (*UnimplementedWorkerServer) MoveProgress(ctx context.Context, req *MovePredicatePayload) (*MoveProgress, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MoveProgress not implemented")
+}
+
+func RegisterWorkerServer(s *grpc.Server, srv WorkerServer) {
+	s.RegisterService(&_Worker_serviceDesc, srv)
+}
+
+func _Worker_Mutate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Mutations)
+	if err := dec(in); err != nil {
+		return nil, err
 	}
-	if m.Txn != nil {
-		{
-			size, err := m.Txn.MarshalToSizedBuffer(dAtA[:i])
-			if err != nil {
-				return 0, err
-			}
-			i -= size
-			i = encodeVarintPb(dAtA, i, uint64(size))
-		}
-		i--
-		dAtA[i] = 0x3a
+	if interceptor == nil {
+		return srv.(WorkerServer).Mutate(ctx, in)
 	}
-	if m.MaxRaftId != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.MaxRaftId))
-		i--
-		dAtA[i] = 0x30
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Worker/Mutate",
 	}
-	if m.MaxTxnTs != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.MaxTxnTs))
-		i--
-		dAtA[i] = 0x28
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WorkerServer).Mutate(ctx, req.(*Mutations))
 	}
-	if m.MaxLeaseId != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.MaxLeaseId))
-		i--
-		dAtA[i] = 0x20
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Worker_ServeTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Query)
+	if err := dec(in); err != nil {
+		return nil, err
 	}
-	if m.Tablet != nil {
-		{
-			size, err := m.Tablet.MarshalToSizedBuffer(dAtA[:i])
-			if err != nil {
-				return 0, err
-			}
-			i -= size
-			i = encodeVarintPb(dAtA, i, uint64(size))
-		}
-		i--
-		dAtA[i] = 0x1a
+	if interceptor == nil {
+		return srv.(WorkerServer).ServeTask(ctx, in)
 	}
-	if m.Member != nil {
-		{
-			size, err := m.Member.MarshalToSizedBuffer(dAtA[:i])
-			if err != nil {
-				return 0, err
-			}
-			i -= size
-			i = encodeVarintPb(dAtA, i, uint64(size))
-		}
-		i--
-		dAtA[i] = 0x12
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Worker/ServeTask",
 	}
-	if len(m.SnapshotTs) > 0 {
-		for k := range m.SnapshotTs {
-			v := m.SnapshotTs[k]
-			baseI := i
-			i = encodeVarintPb(dAtA, i, uint64(v))
-			i--
-			dAtA[i] = 0x10
-			i = encodeVarintPb(dAtA, i, uint64(k))
-			i--
-			dAtA[i] = 0x8
-			i = encodeVarintPb(dAtA, i, uint64(baseI-i))
-			i--
-			dAtA[i] = 0xa
-		}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WorkerServer).ServeTask(ctx, req.(*Query))
 	}
-	return len(dAtA) - i, nil
+	return interceptor(ctx, in, info, handler)
 }
 
-func (m *MembershipState) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
-	if err != nil {
+func _Worker_StreamSnapshot_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(WorkerServer).StreamSnapshot(&workerStreamSnapshotServer{stream})
+}
+
+type Worker_StreamSnapshotServer interface {
+	Send(*KVS) error
+	Recv() (*Snapshot, error)
+	grpc.ServerStream
+}
+
+type workerStreamSnapshotServer struct {
+	grpc.ServerStream
+}
+
+func (x *workerStreamSnapshotServer) Send(m *KVS) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *workerStreamSnapshotServer) Recv() (*Snapshot, error) {
+	m := new(Snapshot)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
 		return nil, err
 	}
-	return dAtA[:n], nil
+	return m, nil
 }
 
-func (m *MembershipState) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
+func _Worker_Sort_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SortMessage)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WorkerServer).Sort(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Worker/Sort",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WorkerServer).Sort(ctx, req.(*SortMessage))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (m *MembershipState) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if m.XXX_unrecognized != nil {
-		i -= len(m.XXX_unrecognized)
-		copy(dAtA[i:], m.XXX_unrecognized)
+func _Worker_Schema_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SchemaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
 	}
-	if m.License != nil {
-		{
-			size, err := m.License.MarshalToSizedBuffer(dAtA[:i])
-			if err != nil {
-				return 0, err
-			}
-			i -= size
-			i = encodeVarintPb(dAtA, i, uint64(size))
-		}
-		i--
-		dAtA[i] = 0x4a
+	if interceptor == nil {
+		return srv.(WorkerServer).Schema(ctx, in)
 	}
-	if len(m.Cid) > 0 {
-		i -= len(m.Cid)
-		copy(dAtA[i:], m.Cid)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Cid)))
-		i--
-		dAtA[i] = 0x42
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Worker/Schema",
 	}
-	if len(m.Removed) > 0 {
-		for iNdEx := len(m.Removed) - 1; iNdEx >= 0; iNdEx-- {
-			{
-				size, err := m.Removed[iNdEx].MarshalToSizedBuffer(dAtA[:i])
-				if err != nil {
-					return 0, err
-				}
-				i -= size
-				i = encodeVarintPb(dAtA, i, uint64(size))
-			}
-			i--
-			dAtA[i] = 0x3a
-		}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WorkerServer).Schema(ctx, req.(*SchemaRequest))
 	}
-	if m.MaxRaftId != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.MaxRaftId))
-		i--
-		dAtA[i] = 0x30
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Worker_Backup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BackupRequest)
+	if err := dec(in); err != nil {
+		return nil, err
 	}
-	if m.MaxTxnTs != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.MaxTxnTs))
-		i--
-		dAtA[i] = 0x28
+	if interceptor == nil {
+		return srv.(WorkerServer).Backup(ctx, in)
 	}
-	if m.MaxLeaseId != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.MaxLeaseId))
-		i--
-		dAtA[i] = 0x20
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Worker/Backup",
 	}
-	if len(m.Zeros) > 0 {
-		for k := range m.Zeros {
-			v := m.Zeros[k]
-			baseI := i
-			if v != nil {
-				{
-					size, err := v.MarshalToSizedBuffer(dAtA[:i])
-					if err != nil {
-						return 0, err
-					}
-					i -= size
-					i = encodeVarintPb(dAtA, i, uint64(size))
-				}
-				i--
-				dAtA[i] = 0x12
-			}
-			i = encodeVarintPb(dAtA, i, uint64(k))
-			i--
-			dAtA[i] = 0x8
-			i = encodeVarintPb(dAtA, i, uint64(baseI-i))
-			i--
-			dAtA[i] = 0x1a
-		}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WorkerServer).Backup(ctx, req.(*BackupRequest))
 	}
-	if len(m.Groups) > 0 {
-		for k := range m.Groups {
-			v := m.Groups[k]
-			baseI := i
-			if v != nil {
-				{
-					size, err := v.MarshalToSizedBuffer(dAtA[:i])
-					if err != nil {
-						return 0, err
-					}
-					i -= size
-					i = encodeVarintPb(dAtA, i, uint64(size))
-				}
-				i--
-				dAtA[i] = 0x12
-			}
-			i = encodeVarintPb(dAtA, i, uint64(k))
-			i--
-			dAtA[i] = 0x8
-			i = encodeVarintPb(dAtA, i, uint64(baseI-i))
-			i--
-			dAtA[i] = 0x12
-		}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Worker_Restore_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreRequest)
+	if err := dec(in); err != nil {
+		return nil, err
 	}
-	if m.Counter != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.Counter))
-		i--
-		dAtA[i] = 0x8
+	if interceptor == nil {
+		return srv.(WorkerServer).Restore(ctx, in)
 	}
-	return len(dAtA) - i, nil
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Worker/Restore",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WorkerServer).Restore(ctx, req.(*RestoreRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (m *ConnectionState) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
-	if err != nil {
+func _Worker_Export_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExportRequest)
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	return dAtA[:n], nil
+	if interceptor == nil {
+		return srv.(WorkerServer).Export(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Worker/Export",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WorkerServer).Export(ctx, req.(*ExportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (m *ConnectionState) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
+func _Worker_ReceivePredicate_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(WorkerServer).ReceivePredicate(&workerReceivePredicateServer{stream})
 }
 
-func (m *ConnectionState) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if m.XXX_unrecognized != nil {
-		i -= len(m.XXX_unrecognized)
-		copy(dAtA[i:], m.XXX_unrecognized)
+type Worker_ReceivePredicateServer interface {
+	SendAndClose(*api.Payload) error
+	Recv() (*KVS, error)
+	grpc.ServerStream
+}
+
+type workerReceivePredicateServer struct {
+	grpc.ServerStream
+}
+
+func (x *workerReceivePredicateServer) SendAndClose(m *api.Payload) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *workerReceivePredicateServer) Recv() (*KVS, error) {
+	m := new(KVS)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
 	}
-	if m.MaxPending != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.MaxPending))
-		i--
-		dAtA[i] = 0x18
+	return m, nil
+}
+
+func _Worker_MovePredicate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MovePredicatePayload)
+	if err := dec(in); err != nil {
+		return nil, err
 	}
-	if m.State != nil {
-		{
-			size, err := m.State.MarshalToSizedBuffer(dAtA[:i])
-			if err != nil {
-				return 0, err
-			}
-			i -= size
-			i = encodeVarintPb(dAtA, i, uint64(size))
-		}
-		i--
-		dAtA[i] = 0x12
+	if interceptor == nil {
+		return srv.(WorkerServer).MovePredicate(ctx, in)
 	}
-	if m.Member != nil {
-		{
-			size, err := m.Member.MarshalToSizedBuffer(dAtA[:i])
-			if err != nil {
-				return 0, err
-			}
-			i -= size
-			i = encodeVarintPb(dAtA, i, uint64(size))
-		}
-		i--
-		dAtA[i] = 0xa
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Worker/MovePredicate",
 	}
-	return len(dAtA) - i, nil
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WorkerServer).MovePredicate(ctx, req.(*MovePredicatePayload))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (m *HealthInfo) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
-	if err != nil {
-		return nil, err
+func _Worker_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscriptionRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
 	}
-	return dAtA[:n], nil
+	return srv.(WorkerServer).Subscribe(m, &workerSubscribeServer{stream})
 }
 
-func (m *HealthInfo) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
+type Worker_SubscribeServer interface {
+	Send(*pb.KVList) error
+	grpc.ServerStream
 }
 
-func (m *HealthInfo) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if m.XXX_unrecognized != nil {
-		i -= len(m.XXX_unrecognized)
-		copy(dAtA[i:], m.XXX_unrecognized)
+type workerSubscribeServer struct {
+	grpc.ServerStream
+}
+
+func (x *workerSubscribeServer) Send(m *pb.KVList) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Worker_UpdateGraphQLSchema_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateGraphQLSchemaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
 	}
-	if len(m.EeFeatures) > 0 {
-		for iNdEx := len(m.EeFeatures) - 1; iNdEx >= 0; iNdEx-- {
-			i -= len(m.EeFeatures[iNdEx])
-			copy(dAtA[i:], m.EeFeatures[iNdEx])
-			i = encodeVarintPb(dAtA, i, uint64(len(m.EeFeatures[iNdEx])))
-			i--
-			dAtA[i] = 0x52
-		}
+	if interceptor == nil {
+		return srv.(WorkerServer).UpdateGraphQLSchema(ctx, in)
 	}
-	if len(m.Indexing) > 0 {
-		for iNdEx := len(m.Indexing) - 1; iNdEx >= 0; iNdEx-- {
-			i -= len(m.Indexing[iNdEx])
-			copy(dAtA[i:], m.Indexing[iNdEx])
-			i = encodeVarintPb(dAtA, i, uint64(len(m.Indexing[iNdEx])))
-			i--
-			dAtA[i] = 0x4a
-		}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Worker/UpdateGraphQLSchema",
 	}
-	if len(m.Ongoing) > 0 {
-		for iNdEx := len(m.Ongoing) - 1; iNdEx >= 0; iNdEx-- {
-			i -= len(m.Ongoing[iNdEx])
-			copy(dAtA[i:], m.Ongoing[iNdEx])
-			i = encodeVarintPb(dAtA, i, uint64(len(m.Ongoing[iNdEx])))
-			i--
-			dAtA[i] = 0x42
-		}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WorkerServer).UpdateGraphQLSchema(ctx, req.(*UpdateGraphQLSchemaRequest))
 	}
-	if m.LastEcho != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.LastEcho))
-		i--
-		dAtA[i] = 0x38
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Worker_ReadBlob_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(BlobRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
 	}
-	if m.Uptime != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.Uptime))
-		i--
-		dAtA[i] = 0x30
+	return srv.(WorkerServer).ReadBlob(m, &workerReadBlobServer{stream})
+}
+
+type Worker_ReadBlobServer interface {
+	Send(*BlobChunk) error
+	grpc.ServerStream
+}
+
+type workerReadBlobServer struct {
+	grpc.ServerStream
+}
+
+func (x *workerReadBlobServer) Send(m *BlobChunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Worker_ReplicateGroup_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ReplicationRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
 	}
-	if len(m.Version) > 0 {
-		i -= len(m.Version)
-		copy(dAtA[i:], m.Version)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Version)))
-		i--
-		dAtA[i] = 0x2a
+	return srv.(WorkerServer).ReplicateGroup(m, &workerReplicateGroupServer{stream})
+}
+
+type Worker_ReplicateGroupServer interface {
+	Send(*KVS) error
+	grpc.ServerStream
+}
+
+type workerReplicateGroupServer struct {
+	grpc.ServerStream
+}
+
+func (x *workerReplicateGroupServer) Send(m *KVS) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Worker_ReplicateKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KVS)
+	if err := dec(in); err != nil {
+		return nil, err
 	}
-	if len(m.Group) > 0 {
-		i -= len(m.Group)
-		copy(dAtA[i:], m.Group)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Group)))
-		i--
-		dAtA[i] = 0x22
+	if interceptor == nil {
+		return srv.(WorkerServer).ReplicateKeys(ctx, in)
 	}
-	if len(m.Status) > 0 {
-		i -= len(m.Status)
-		copy(dAtA[i:], m.Status)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Status)))
-		i--
-		dAtA[i] = 0x1a
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Worker/ReplicateKeys",
 	}
-	if len(m.Address) > 0 {
-		i -= len(m.Address)
-		copy(dAtA[i:], m.Address)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Address)))
-		i--
-		dAtA[i] = 0x12
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WorkerServer).ReplicateKeys(ctx, req.(*KVS))
 	}
-	if len(m.Instance) > 0 {
-		i -= len(m.Instance)
-		copy(dAtA[i:], m.Instance)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Instance)))
-		i--
-		dAtA[i] = 0xa
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Worker_FreezeGroup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FrozenGroup)
+	if err := dec(in); err != nil {
+		return nil, err
 	}
-	return len(dAtA) - i, nil
+	if interceptor == nil {
+		return srv.(WorkerServer).FreezeGroup(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Worker/FreezeGroup",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WorkerServer).FreezeGroup(ctx, req.(*FrozenGroup))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (m *Tablet) Marshal() (dAtA []byte, err error) {
+func _Worker_MoveProgress_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MovePredicatePayload)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WorkerServer).MoveProgress(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.Worker/MoveProgress",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WorkerServer).MoveProgress(ctx, req.(*MovePredicatePayload))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Worker_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "pb.Worker",
+	HandlerType: (*WorkerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Mutate",
+			Handler:    _Worker_Mutate_Handler,
+		},
+		{
+			MethodName: "ServeTask",
+			Handler:    _Worker_ServeTask_Handler,
+		},
+		{
+			MethodName: "Sort",
+			Handler:    _Worker_Sort_Handler,
+		},
+		{
+			MethodName: "Schema",
+			Handler:    _Worker_Schema_Handler,
+		},
+		{
+			MethodName: "Backup",
+			Handler:    _Worker_Backup_Handler,
+		},
+		{
+			MethodName: "Restore",
+			Handler:    _Worker_Restore_Handler,
+		},
+		{
+			MethodName: "Export",
+			Handler:    _Worker_Export_Handler,
+		},
+		{
+			MethodName: "MovePredicate",
+			Handler:    _Worker_MovePredicate_Handler,
+		},
+		{
+			MethodName: "UpdateGraphQLSchema",
+			Handler:    _Worker_UpdateGraphQLSchema_Handler,
+		},
+		{
+			MethodName: "ReplicateKeys",
+			Handler:    _Worker_ReplicateKeys_Handler,
+		},
+		{
+			MethodName: "FreezeGroup",
+			Handler:    _Worker_FreezeGroup_Handler,
+		},
+		{
+			MethodName: "MoveProgress",
+			Handler:    _Worker_MoveProgress_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamSnapshot",
+			Handler:       _Worker_StreamSnapshot_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "ReceivePredicate",
+			Handler:       _Worker_ReceivePredicate_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Subscribe",
+			Handler:       _Worker_Subscribe_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ReadBlob",
+			Handler:       _Worker_ReadBlob_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ReplicateGroup",
+			Handler:       _Worker_ReplicateGroup_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "pb.proto",
+}
+
+func (m *List) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -7839,12 +8343,12 @@ func (m *Tablet) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *Tablet) MarshalTo(dAtA []byte) (int, error) {
+func (m *List) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *Tablet) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *List) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
@@ -7853,62 +8357,19 @@ func (m *Tablet) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if m.MoveTs != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.MoveTs))
-		i--
-		dAtA[i] = 0x50
-	}
-	if m.ReadOnly {
-		i--
-		if m.ReadOnly {
-			dAtA[i] = 1
-		} else {
-			dAtA[i] = 0
-		}
-		i--
-		dAtA[i] = 0x48
-	}
-	if m.Remove {
-		i--
-		if m.Remove {
-			dAtA[i] = 1
-		} else {
-			dAtA[i] = 0
-		}
-		i--
-		dAtA[i] = 0x40
-	}
-	if m.Space != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.Space))
-		i--
-		dAtA[i] = 0x38
-	}
-	if m.Force {
-		i--
-		if m.Force {
-			dAtA[i] = 1
-		} else {
-			dAtA[i] = 0
+	if len(m.Uids) > 0 {
+		for iNdEx := len(m.Uids) - 1; iNdEx >= 0; iNdEx-- {
+			i -= 8
+			encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(m.Uids[iNdEx]))
 		}
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Uids)*8))
 		i--
-		dAtA[i] = 0x18
-	}
-	if len(m.Predicate) > 0 {
-		i -= len(m.Predicate)
-		copy(dAtA[i:], m.Predicate)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Predicate)))
-		i--
-		dAtA[i] = 0x12
-	}
-	if m.GroupId != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.GroupId))
-		i--
-		dAtA[i] = 0x8
+		dAtA[i] = 0xa
 	}
 	return len(dAtA) - i, nil
 }
 
-func (m *DirectedEdge) Marshal() (dAtA []byte, err error) {
+func (m *TaskValue) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -7918,12 +8379,12 @@ func (m *DirectedEdge) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *DirectedEdge) MarshalTo(dAtA []byte) (int, error) {
+func (m *TaskValue) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *DirectedEdge) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *TaskValue) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
@@ -7932,83 +8393,22 @@ func (m *DirectedEdge) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if len(m.AllowedPreds) > 0 {
-		for iNdEx := len(m.AllowedPreds) - 1; iNdEx >= 0; iNdEx-- {
-			i -= len(m.AllowedPreds[iNdEx])
-			copy(dAtA[i:], m.AllowedPreds[iNdEx])
-			i = encodeVarintPb(dAtA, i, uint64(len(m.AllowedPreds[iNdEx])))
-			i--
-			dAtA[i] = 0x52
-		}
-	}
-	if len(m.Facets) > 0 {
-		for iNdEx := len(m.Facets) - 1; iNdEx >= 0; iNdEx-- {
-			{
-				size, err := m.Facets[iNdEx].MarshalToSizedBuffer(dAtA[:i])
-				if err != nil {
-					return 0, err
-				}
-				i -= size
-				i = encodeVarintPb(dAtA, i, uint64(size))
-			}
-			i--
-			dAtA[i] = 0x4a
-		}
-	}
-	if m.Op != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.Op))
-		i--
-		dAtA[i] = 0x40
-	}
-	if len(m.Lang) > 0 {
-		i -= len(m.Lang)
-		copy(dAtA[i:], m.Lang)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Lang)))
-		i--
-		dAtA[i] = 0x3a
-	}
-	if len(m.Label) > 0 {
-		i -= len(m.Label)
-		copy(dAtA[i:], m.Label)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Label)))
-		i--
-		dAtA[i] = 0x32
-	}
-	if m.ValueId != 0 {
-		i -= 8
-		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(m.ValueId))
-		i--
-		dAtA[i] = 0x29
-	}
-	if m.ValueType != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.ValueType))
-		i--
-		dAtA[i] = 0x20
-	}
-	if len(m.Value) > 0 {
-		i -= len(m.Value)
-		copy(dAtA[i:], m.Value)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Value)))
-		i--
-		dAtA[i] = 0x1a
-	}
-	if len(m.Attr) > 0 {
-		i -= len(m.Attr)
-		copy(dAtA[i:], m.Attr)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Attr)))
+	if m.ValType != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.ValType))
 		i--
-		dAtA[i] = 0x12
+		dAtA[i] = 0x10
 	}
-	if m.Entity != 0 {
-		i -= 8
-		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(m.Entity))
+	if len(m.Val) > 0 {
+		i -= len(m.Val)
+		copy(dAtA[i:], m.Val)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Val)))
 		i--
-		dAtA[i] = 0x9
+		dAtA[i] = 0xa
 	}
 	return len(dAtA) - i, nil
 }
 
-func (m *Mutations) Marshal() (dAtA []byte, err error) {
+func (m *SrcFunction) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -8018,12 +8418,12 @@ func (m *Mutations) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *Mutations) MarshalTo(dAtA []byte) (int, error) {
+func (m *SrcFunction) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *Mutations) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *SrcFunction) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
@@ -8032,86 +8432,36 @@ func (m *Mutations) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if m.Metadata != nil {
-		{
-			size, err := m.Metadata.MarshalToSizedBuffer(dAtA[:i])
-			if err != nil {
-				return 0, err
-			}
-			i -= size
-			i = encodeVarintPb(dAtA, i, uint64(size))
-		}
-		i--
-		dAtA[i] = 0x4a
-	}
-	if len(m.DropValue) > 0 {
-		i -= len(m.DropValue)
-		copy(dAtA[i:], m.DropValue)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.DropValue)))
-		i--
-		dAtA[i] = 0x42
-	}
-	if m.DropOp != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.DropOp))
+	if m.IsCount {
 		i--
-		dAtA[i] = 0x38
-	}
-	if len(m.Types) > 0 {
-		for iNdEx := len(m.Types) - 1; iNdEx >= 0; iNdEx-- {
-			{
-				size, err := m.Types[iNdEx].MarshalToSizedBuffer(dAtA[:i])
-				if err != nil {
-					return 0, err
-				}
-				i -= size
-				i = encodeVarintPb(dAtA, i, uint64(size))
-			}
-			i--
-			dAtA[i] = 0x32
-		}
-	}
-	if len(m.Schema) > 0 {
-		for iNdEx := len(m.Schema) - 1; iNdEx >= 0; iNdEx-- {
-			{
-				size, err := m.Schema[iNdEx].MarshalToSizedBuffer(dAtA[:i])
-				if err != nil {
-					return 0, err
-				}
-				i -= size
-				i = encodeVarintPb(dAtA, i, uint64(size))
-			}
-			i--
-			dAtA[i] = 0x22
+		if m.IsCount {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
 		}
+		i--
+		dAtA[i] = 0x20
 	}
-	if len(m.Edges) > 0 {
-		for iNdEx := len(m.Edges) - 1; iNdEx >= 0; iNdEx-- {
-			{
-				size, err := m.Edges[iNdEx].MarshalToSizedBuffer(dAtA[:i])
-				if err != nil {
-					return 0, err
-				}
-				i -= size
-				i = encodeVarintPb(dAtA, i, uint64(size))
-			}
+	if len(m.Args) > 0 {
+		for iNdEx := len(m.Args) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Args[iNdEx])
+			copy(dAtA[i:], m.Args[iNdEx])
+			i = encodeVarintPb(dAtA, i, uint64(len(m.Args[iNdEx])))
 			i--
 			dAtA[i] = 0x1a
 		}
 	}
-	if m.StartTs != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.StartTs))
-		i--
-		dAtA[i] = 0x10
-	}
-	if m.GroupId != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.GroupId))
+	if len(m.Name) > 0 {
+		i -= len(m.Name)
+		copy(dAtA[i:], m.Name)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Name)))
 		i--
-		dAtA[i] = 0x8
+		dAtA[i] = 0xa
 	}
 	return len(dAtA) - i, nil
 }
 
-func (m *Metadata) Marshal() (dAtA []byte, err error) {
+func (m *Query) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -8121,12 +8471,12 @@ func (m *Metadata) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *Metadata) MarshalTo(dAtA []byte) (int, error) {
+func (m *Query) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *Metadata) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *Query) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
@@ -8135,78 +8485,113 @@ func (m *Metadata) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if len(m.PredHints) > 0 {
-		for k := range m.PredHints {
-			v := m.PredHints[k]
-			baseI := i
-			i = encodeVarintPb(dAtA, i, uint64(v))
-			i--
-			dAtA[i] = 0x10
-			i -= len(k)
-			copy(dAtA[i:], k)
-			i = encodeVarintPb(dAtA, i, uint64(len(k)))
-			i--
-			dAtA[i] = 0xa
-			i = encodeVarintPb(dAtA, i, uint64(baseI-i))
-			i--
-			dAtA[i] = 0xa
-		}
-	}
-	return len(dAtA) - i, nil
-}
-
-func (m *Snapshot) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
-	if err != nil {
-		return nil, err
-	}
-	return dAtA[:n], nil
-}
-
-func (m *Snapshot) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
-}
-
-func (m *Snapshot) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if m.XXX_unrecognized != nil {
-		i -= len(m.XXX_unrecognized)
-		copy(dAtA[i:], m.XXX_unrecognized)
+	if m.Sample != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.Sample))
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0x90
 	}
-	if m.SinceTs != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.SinceTs))
+	if m.EdgeFilter != nil {
+		{
+			size, err := m.EdgeFilter.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintPb(dAtA, i, uint64(size))
+		}
 		i--
-		dAtA[i] = 0x28
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0x8a
 	}
-	if m.Done {
+	if m.DistinctCount {
 		i--
-		if m.Done {
+		if m.DistinctCount {
 			dAtA[i] = 1
 		} else {
 			dAtA[i] = 0
 		}
 		i--
-		dAtA[i] = 0x20
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0x80
+	}
+	if m.First != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.First))
+		i--
+		dAtA[i] = 0x78
+	}
+	if m.Cache != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.Cache))
+		i--
+		dAtA[i] = 0x70
 	}
 	if m.ReadTs != 0 {
 		i = encodeVarintPb(dAtA, i, uint64(m.ReadTs))
 		i--
-		dAtA[i] = 0x18
+		dAtA[i] = 0x68
 	}
-	if m.Index != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.Index))
+	if m.ExpandAll {
 		i--
-		dAtA[i] = 0x10
-	}
-	if m.Context != nil {
-		{
-			size, err := m.Context.MarshalToSizedBuffer(dAtA[:i])
+		if m.ExpandAll {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x50
+	}
+	if m.FacetsFilter != nil {
+		{
+			size, err := m.FacetsFilter.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintPb(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x4a
+	}
+	if m.FacetParam != nil {
+		{
+			size, err := m.FacetParam.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintPb(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x42
+	}
+	if m.Reverse {
+		i--
+		if m.Reverse {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x38
+	}
+	if m.SrcFunc != nil {
+		{
+			size, err := m.SrcFunc.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintPb(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x32
+	}
+	if m.UidList != nil {
+		{
+			size, err := m.UidList.MarshalToSizedBuffer(dAtA[:i])
 			if err != nil {
 				return 0, err
 			}
@@ -8214,12 +8599,44 @@ func (m *Snapshot) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 			i = encodeVarintPb(dAtA, i, uint64(size))
 		}
 		i--
+		dAtA[i] = 0x2a
+	}
+	if m.DoCount {
+		i--
+		if m.DoCount {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x20
+	}
+	if m.AfterUid != 0 {
+		i -= 8
+		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(m.AfterUid))
+		i--
+		dAtA[i] = 0x19
+	}
+	if len(m.Langs) > 0 {
+		for iNdEx := len(m.Langs) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Langs[iNdEx])
+			copy(dAtA[i:], m.Langs[iNdEx])
+			i = encodeVarintPb(dAtA, i, uint64(len(m.Langs[iNdEx])))
+			i--
+			dAtA[i] = 0x12
+		}
+	}
+	if len(m.Attr) > 0 {
+		i -= len(m.Attr)
+		copy(dAtA[i:], m.Attr)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Attr)))
+		i--
 		dAtA[i] = 0xa
 	}
 	return len(dAtA) - i, nil
 }
 
-func (m *RestoreRequest) Marshal() (dAtA []byte, err error) {
+func (m *ValueList) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -8229,12 +8646,12 @@ func (m *RestoreRequest) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *RestoreRequest) MarshalTo(dAtA []byte) (int, error) {
+func (m *ValueList) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *RestoreRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *ValueList) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
@@ -8243,121 +8660,24 @@ func (m *RestoreRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if m.BackupNum != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.BackupNum))
-		i--
-		dAtA[i] = 0x1
-		i--
-		dAtA[i] = 0x80
-	}
-	if len(m.VaultFormat) > 0 {
-		i -= len(m.VaultFormat)
-		copy(dAtA[i:], m.VaultFormat)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.VaultFormat)))
-		i--
-		dAtA[i] = 0x7a
-	}
-	if len(m.VaultField) > 0 {
-		i -= len(m.VaultField)
-		copy(dAtA[i:], m.VaultField)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.VaultField)))
-		i--
-		dAtA[i] = 0x72
-	}
-	if len(m.VaultPath) > 0 {
-		i -= len(m.VaultPath)
-		copy(dAtA[i:], m.VaultPath)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.VaultPath)))
-		i--
-		dAtA[i] = 0x6a
-	}
-	if len(m.VaultSecretidFile) > 0 {
-		i -= len(m.VaultSecretidFile)
-		copy(dAtA[i:], m.VaultSecretidFile)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.VaultSecretidFile)))
-		i--
-		dAtA[i] = 0x62
-	}
-	if len(m.VaultRoleidFile) > 0 {
-		i -= len(m.VaultRoleidFile)
-		copy(dAtA[i:], m.VaultRoleidFile)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.VaultRoleidFile)))
-		i--
-		dAtA[i] = 0x5a
-	}
-	if len(m.VaultAddr) > 0 {
-		i -= len(m.VaultAddr)
-		copy(dAtA[i:], m.VaultAddr)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.VaultAddr)))
-		i--
-		dAtA[i] = 0x52
-	}
-	if len(m.EncryptionKeyFile) > 0 {
-		i -= len(m.EncryptionKeyFile)
-		copy(dAtA[i:], m.EncryptionKeyFile)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.EncryptionKeyFile)))
-		i--
-		dAtA[i] = 0x4a
-	}
-	if m.Anonymous {
-		i--
-		if m.Anonymous {
-			dAtA[i] = 1
-		} else {
-			dAtA[i] = 0
+	if len(m.Values) > 0 {
+		for iNdEx := len(m.Values) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Values[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintPb(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0xa
 		}
-		i--
-		dAtA[i] = 0x40
-	}
-	if len(m.SessionToken) > 0 {
-		i -= len(m.SessionToken)
-		copy(dAtA[i:], m.SessionToken)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.SessionToken)))
-		i--
-		dAtA[i] = 0x3a
-	}
-	if len(m.SecretKey) > 0 {
-		i -= len(m.SecretKey)
-		copy(dAtA[i:], m.SecretKey)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.SecretKey)))
-		i--
-		dAtA[i] = 0x32
-	}
-	if len(m.AccessKey) > 0 {
-		i -= len(m.AccessKey)
-		copy(dAtA[i:], m.AccessKey)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.AccessKey)))
-		i--
-		dAtA[i] = 0x2a
-	}
-	if len(m.BackupId) > 0 {
-		i -= len(m.BackupId)
-		copy(dAtA[i:], m.BackupId)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.BackupId)))
-		i--
-		dAtA[i] = 0x22
-	}
-	if len(m.Location) > 0 {
-		i -= len(m.Location)
-		copy(dAtA[i:], m.Location)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Location)))
-		i--
-		dAtA[i] = 0x1a
-	}
-	if m.RestoreTs != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.RestoreTs))
-		i--
-		dAtA[i] = 0x10
-	}
-	if m.GroupId != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.GroupId))
-		i--
-		dAtA[i] = 0x8
 	}
 	return len(dAtA) - i, nil
 }
 
-func (m *Proposal) Marshal() (dAtA []byte, err error) {
+func (m *LangList) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -8367,12 +8687,12 @@ func (m *Proposal) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *Proposal) MarshalTo(dAtA []byte) (int, error) {
+func (m *LangList) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *Proposal) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *LangList) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
@@ -8381,82 +8701,126 @@ func (m *Proposal) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if m.Restore != nil {
-		{
-			size, err := m.Restore.MarshalToSizedBuffer(dAtA[:i])
-			if err != nil {
-				return 0, err
-			}
-			i -= size
-			i = encodeVarintPb(dAtA, i, uint64(size))
+	if len(m.Lang) > 0 {
+		for iNdEx := len(m.Lang) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Lang[iNdEx])
+			copy(dAtA[i:], m.Lang[iNdEx])
+			i = encodeVarintPb(dAtA, i, uint64(len(m.Lang[iNdEx])))
+			i--
+			dAtA[i] = 0xa
 		}
-		i--
-		dAtA[i] = 0x62
 	}
-	if m.ExpectedChecksum != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.ExpectedChecksum))
-		i--
-		dAtA[i] = 0x58
+	return len(dAtA) - i, nil
+}
+
+func (m *Result) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
-	if m.Index != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.Index))
-		i--
-		dAtA[i] = 0x50
+	return dAtA[:n], nil
+}
+
+func (m *Result) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Result) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if m.Snapshot != nil {
-		{
-			size, err := m.Snapshot.MarshalToSizedBuffer(dAtA[:i])
-			if err != nil {
-				return 0, err
+	if len(m.DistinctBuckets) > 0 {
+		for iNdEx := len(m.DistinctBuckets) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.DistinctBuckets[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintPb(dAtA, i, uint64(size))
 			}
-			i -= size
-			i = encodeVarintPb(dAtA, i, uint64(size))
+			i--
+			dAtA[i] = 0x42
 		}
+	}
+	if m.List {
 		i--
-		dAtA[i] = 0x4a
+		if m.List {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x38
 	}
-	if m.Delta != nil {
-		{
-			size, err := m.Delta.MarshalToSizedBuffer(dAtA[:i])
-			if err != nil {
-				return 0, err
+	if len(m.LangMatrix) > 0 {
+		for iNdEx := len(m.LangMatrix) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.LangMatrix[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintPb(dAtA, i, uint64(size))
 			}
-			i -= size
-			i = encodeVarintPb(dAtA, i, uint64(size))
+			i--
+			dAtA[i] = 0x32
 		}
-		i--
-		dAtA[i] = 0x42
 	}
-	if len(m.Key) > 0 {
-		i -= len(m.Key)
-		copy(dAtA[i:], m.Key)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Key)))
-		i--
-		dAtA[i] = 0x3a
+	if len(m.FacetMatrix) > 0 {
+		for iNdEx := len(m.FacetMatrix) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.FacetMatrix[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintPb(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x2a
+		}
 	}
-	if len(m.CleanPredicate) > 0 {
-		i -= len(m.CleanPredicate)
-		copy(dAtA[i:], m.CleanPredicate)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.CleanPredicate)))
+	if m.IntersectDest {
 		i--
-		dAtA[i] = 0x32
+		if m.IntersectDest {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x20
 	}
-	if m.State != nil {
-		{
-			size, err := m.State.MarshalToSizedBuffer(dAtA[:i])
-			if err != nil {
-				return 0, err
+	if len(m.Counts) > 0 {
+		dAtA6 := make([]byte, len(m.Counts)*10)
+		var j5 int
+		for _, num := range m.Counts {
+			for num >= 1<<7 {
+				dAtA6[j5] = uint8(uint64(num)&0x7f | 0x80)
+				num >>= 7
+				j5++
 			}
-			i -= size
-			i = encodeVarintPb(dAtA, i, uint64(size))
+			dAtA6[j5] = uint8(num)
+			j5++
 		}
+		i -= j5
+		copy(dAtA[i:], dAtA6[:j5])
+		i = encodeVarintPb(dAtA, i, uint64(j5))
 		i--
-		dAtA[i] = 0x2a
+		dAtA[i] = 0x1a
 	}
-	if len(m.Kv) > 0 {
-		for iNdEx := len(m.Kv) - 1; iNdEx >= 0; iNdEx-- {
+	if len(m.ValueMatrix) > 0 {
+		for iNdEx := len(m.ValueMatrix) - 1; iNdEx >= 0; iNdEx-- {
 			{
-				size, err := m.Kv[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				size, err := m.ValueMatrix[iNdEx].MarshalToSizedBuffer(dAtA[:i])
 				if err != nil {
 					return 0, err
 				}
@@ -8464,25 +8828,27 @@ func (m *Proposal) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 				i = encodeVarintPb(dAtA, i, uint64(size))
 			}
 			i--
-			dAtA[i] = 0x22
+			dAtA[i] = 0x12
 		}
 	}
-	if m.Mutations != nil {
-		{
-			size, err := m.Mutations.MarshalToSizedBuffer(dAtA[:i])
-			if err != nil {
-				return 0, err
+	if len(m.UidMatrix) > 0 {
+		for iNdEx := len(m.UidMatrix) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.UidMatrix[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintPb(dAtA, i, uint64(size))
 			}
-			i -= size
-			i = encodeVarintPb(dAtA, i, uint64(size))
+			i--
+			dAtA[i] = 0xa
 		}
-		i--
-		dAtA[i] = 0x12
 	}
 	return len(dAtA) - i, nil
 }
 
-func (m *KVS) Marshal() (dAtA []byte, err error) {
+func (m *Order) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -8492,12 +8858,12 @@ func (m *KVS) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *KVS) MarshalTo(dAtA []byte) (int, error) {
+func (m *Order) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *KVS) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *Order) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
@@ -8506,27 +8872,18 @@ func (m *KVS) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if len(m.Types) > 0 {
-		for iNdEx := len(m.Types) - 1; iNdEx >= 0; iNdEx-- {
-			i -= len(m.Types[iNdEx])
-			copy(dAtA[i:], m.Types[iNdEx])
-			i = encodeVarintPb(dAtA, i, uint64(len(m.Types[iNdEx])))
-			i--
-			dAtA[i] = 0x22
-		}
-	}
-	if len(m.Predicates) > 0 {
-		for iNdEx := len(m.Predicates) - 1; iNdEx >= 0; iNdEx-- {
-			i -= len(m.Predicates[iNdEx])
-			copy(dAtA[i:], m.Predicates[iNdEx])
-			i = encodeVarintPb(dAtA, i, uint64(len(m.Predicates[iNdEx])))
+	if len(m.Langs) > 0 {
+		for iNdEx := len(m.Langs) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Langs[iNdEx])
+			copy(dAtA[i:], m.Langs[iNdEx])
+			i = encodeVarintPb(dAtA, i, uint64(len(m.Langs[iNdEx])))
 			i--
 			dAtA[i] = 0x1a
 		}
 	}
-	if m.Done {
+	if m.Desc {
 		i--
-		if m.Done {
+		if m.Desc {
 			dAtA[i] = 1
 		} else {
 			dAtA[i] = 0
@@ -8534,24 +8891,17 @@ func (m *KVS) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i--
 		dAtA[i] = 0x10
 	}
-	if len(m.Kv) > 0 {
-		for iNdEx := len(m.Kv) - 1; iNdEx >= 0; iNdEx-- {
-			{
-				size, err := m.Kv[iNdEx].MarshalToSizedBuffer(dAtA[:i])
-				if err != nil {
-					return 0, err
-				}
-				i -= size
-				i = encodeVarintPb(dAtA, i, uint64(size))
-			}
-			i--
-			dAtA[i] = 0xa
-		}
+	if len(m.Attr) > 0 {
+		i -= len(m.Attr)
+		copy(dAtA[i:], m.Attr)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Attr)))
+		i--
+		dAtA[i] = 0xa
 	}
 	return len(dAtA) - i, nil
 }
 
-func (m *Posting) Marshal() (dAtA []byte, err error) {
+func (m *SortMessage) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -8561,12 +8911,12 @@ func (m *Posting) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *Posting) MarshalTo(dAtA []byte) (int, error) {
+func (m *SortMessage) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *Posting) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *SortMessage) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
@@ -8575,25 +8925,25 @@ func (m *Posting) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if m.CommitTs != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.CommitTs))
+	if m.ReadTs != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.ReadTs))
 		i--
-		dAtA[i] = 0x70
+		dAtA[i] = 0x68
 	}
-	if m.StartTs != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.StartTs))
+	if m.Offset != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.Offset))
 		i--
-		dAtA[i] = 0x68
+		dAtA[i] = 0x20
 	}
-	if m.Op != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.Op))
+	if m.Count != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.Count))
 		i--
-		dAtA[i] = 0x60
+		dAtA[i] = 0x18
 	}
-	if len(m.Facets) > 0 {
-		for iNdEx := len(m.Facets) - 1; iNdEx >= 0; iNdEx-- {
+	if len(m.UidMatrix) > 0 {
+		for iNdEx := len(m.UidMatrix) - 1; iNdEx >= 0; iNdEx-- {
 			{
-				size, err := m.Facets[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				size, err := m.UidMatrix[iNdEx].MarshalToSizedBuffer(dAtA[:i])
 				if err != nil {
 					return 0, err
 				}
@@ -8601,50 +8951,27 @@ func (m *Posting) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 				i = encodeVarintPb(dAtA, i, uint64(size))
 			}
 			i--
-			dAtA[i] = 0x4a
+			dAtA[i] = 0x12
 		}
 	}
-	if len(m.Label) > 0 {
-		i -= len(m.Label)
-		copy(dAtA[i:], m.Label)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Label)))
-		i--
-		dAtA[i] = 0x32
-	}
-	if len(m.LangTag) > 0 {
-		i -= len(m.LangTag)
-		copy(dAtA[i:], m.LangTag)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.LangTag)))
-		i--
-		dAtA[i] = 0x2a
-	}
-	if m.PostingType != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.PostingType))
-		i--
-		dAtA[i] = 0x20
-	}
-	if m.ValType != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.ValType))
-		i--
-		dAtA[i] = 0x18
-	}
-	if len(m.Value) > 0 {
-		i -= len(m.Value)
-		copy(dAtA[i:], m.Value)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Value)))
-		i--
-		dAtA[i] = 0x12
-	}
-	if m.Uid != 0 {
-		i -= 8
-		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(m.Uid))
-		i--
-		dAtA[i] = 0x9
+	if len(m.Order) > 0 {
+		for iNdEx := len(m.Order) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Order[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintPb(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0xa
+		}
 	}
 	return len(dAtA) - i, nil
 }
 
-func (m *UidBlock) Marshal() (dAtA []byte, err error) {
+func (m *SortResult) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -8654,12 +8981,12 @@ func (m *UidBlock) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *UidBlock) MarshalTo(dAtA []byte) (int, error) {
+func (m *SortResult) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *UidBlock) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *SortResult) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
@@ -8668,27 +8995,24 @@ func (m *UidBlock) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if m.NumUids != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.NumUids))
-		i--
-		dAtA[i] = 0x18
-	}
-	if len(m.Deltas) > 0 {
-		i -= len(m.Deltas)
-		copy(dAtA[i:], m.Deltas)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Deltas)))
-		i--
-		dAtA[i] = 0x12
-	}
-	if m.Base != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.Base))
-		i--
-		dAtA[i] = 0x8
+	if len(m.UidMatrix) > 0 {
+		for iNdEx := len(m.UidMatrix) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.UidMatrix[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintPb(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0xa
+		}
 	}
 	return len(dAtA) - i, nil
 }
 
-func (m *UidPack) Marshal() (dAtA []byte, err error) {
+func (m *RaftContext) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -8698,12 +9022,12 @@ func (m *UidPack) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *UidPack) MarshalTo(dAtA []byte) (int, error) {
+func (m *RaftContext) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *UidPack) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *RaftContext) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
@@ -8712,36 +9036,43 @@ func (m *UidPack) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if m.AllocRef != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.AllocRef))
+	if m.IsLearner {
 		i--
-		dAtA[i] = 0x1
+		if m.IsLearner {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
 		i--
-		dAtA[i] = 0xb8
+		dAtA[i] = 0x28
 	}
-	if len(m.Blocks) > 0 {
-		for iNdEx := len(m.Blocks) - 1; iNdEx >= 0; iNdEx-- {
-			{
-				size, err := m.Blocks[iNdEx].MarshalToSizedBuffer(dAtA[:i])
-				if err != nil {
-					return 0, err
-				}
-				i -= size
-				i = encodeVarintPb(dAtA, i, uint64(size))
-			}
-			i--
-			dAtA[i] = 0x12
-		}
+	if m.SnapshotTs != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.SnapshotTs))
+		i--
+		dAtA[i] = 0x20
 	}
-	if m.BlockSize != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.BlockSize))
+	if len(m.Addr) > 0 {
+		i -= len(m.Addr)
+		copy(dAtA[i:], m.Addr)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Addr)))
 		i--
-		dAtA[i] = 0x8
+		dAtA[i] = 0x1a
+	}
+	if m.Group != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.Group))
+		i--
+		dAtA[i] = 0x10
+	}
+	if m.Id != 0 {
+		i -= 8
+		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(m.Id))
+		i--
+		dAtA[i] = 0x9
 	}
 	return len(dAtA) - i, nil
 }
 
-func (m *PostingList) Marshal() (dAtA []byte, err error) {
+func (m *Member) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -8751,12 +9082,12 @@ func (m *PostingList) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *PostingList) MarshalTo(dAtA []byte) (int, error) {
+func (m *Member) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *PostingList) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *Member) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
@@ -8765,100 +9096,95 @@ func (m *PostingList) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if len(m.Splits) > 0 {
-		dAtA26 := make([]byte, len(m.Splits)*10)
-		var j25 int
-		for _, num := range m.Splits {
-			for num >= 1<<7 {
-				dAtA26[j25] = uint8(uint64(num)&0x7f | 0x80)
-				num >>= 7
-				j25++
-			}
-			dAtA26[j25] = uint8(num)
-			j25++
+	if m.Decommissioning {
+		i--
+		if m.Decommissioning {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
 		}
-		i -= j25
-		copy(dAtA[i:], dAtA26[:j25])
-		i = encodeVarintPb(dAtA, i, uint64(j25))
 		i--
-		dAtA[i] = 0x22
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0x80
 	}
-	if m.CommitTs != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.CommitTs))
+	if m.Learner {
 		i--
-		dAtA[i] = 0x18
+		if m.Learner {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x78
 	}
-	if len(m.Postings) > 0 {
-		for iNdEx := len(m.Postings) - 1; iNdEx >= 0; iNdEx-- {
-			{
-				size, err := m.Postings[iNdEx].MarshalToSizedBuffer(dAtA[:i])
-				if err != nil {
-					return 0, err
-				}
-				i -= size
-				i = encodeVarintPb(dAtA, i, uint64(size))
-			}
-			i--
-			dAtA[i] = 0x12
+	if m.ForceGroupId {
+		i--
+		if m.ForceGroupId {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
 		}
+		i--
+		dAtA[i] = 0x70
 	}
-	if m.Pack != nil {
-		{
-			size, err := m.Pack.MarshalToSizedBuffer(dAtA[:i])
-			if err != nil {
-				return 0, err
-			}
-			i -= size
-			i = encodeVarintPb(dAtA, i, uint64(size))
+	if m.ClusterInfoOnly {
+		i--
+		if m.ClusterInfoOnly {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
 		}
 		i--
-		dAtA[i] = 0xa
+		dAtA[i] = 0x68
 	}
-	return len(dAtA) - i, nil
-}
-
-func (m *FacetParam) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
-	if err != nil {
-		return nil, err
+	if m.LastUpdate != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.LastUpdate))
+		i--
+		dAtA[i] = 0x30
 	}
-	return dAtA[:n], nil
-}
-
-func (m *FacetParam) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
-}
-
-func (m *FacetParam) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if m.XXX_unrecognized != nil {
-		i -= len(m.XXX_unrecognized)
-		copy(dAtA[i:], m.XXX_unrecognized)
+	if m.AmDead {
+		i--
+		if m.AmDead {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x28
 	}
-	if len(m.Alias) > 0 {
-		i -= len(m.Alias)
-		copy(dAtA[i:], m.Alias)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Alias)))
+	if m.Leader {
 		i--
-		dAtA[i] = 0x12
+		if m.Leader {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x20
 	}
-	if len(m.Key) > 0 {
-		i -= len(m.Key)
-		copy(dAtA[i:], m.Key)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Key)))
+	if len(m.Addr) > 0 {
+		i -= len(m.Addr)
+		copy(dAtA[i:], m.Addr)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Addr)))
 		i--
-		dAtA[i] = 0xa
+		dAtA[i] = 0x1a
+	}
+	if m.GroupId != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.GroupId))
+		i--
+		dAtA[i] = 0x10
+	}
+	if m.Id != 0 {
+		i -= 8
+		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(m.Id))
+		i--
+		dAtA[i] = 0x9
 	}
 	return len(dAtA) - i, nil
 }
 
-func (m *FacetParams) Marshal() (dAtA []byte, err error) {
+func (m *Group) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -8868,12 +9194,12 @@ func (m *FacetParams) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *FacetParams) MarshalTo(dAtA []byte) (int, error) {
+func (m *Group) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *FacetParams) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *Group) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
@@ -8882,10 +9208,10 @@ func (m *FacetParams) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if len(m.Param) > 0 {
-		for iNdEx := len(m.Param) - 1; iNdEx >= 0; iNdEx-- {
+	if len(m.Loads) > 0 {
+		for iNdEx := len(m.Loads) - 1; iNdEx >= 0; iNdEx-- {
 			{
-				size, err := m.Param[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				size, err := m.Loads[iNdEx].MarshalToSizedBuffer(dAtA[:i])
 				if err != nil {
 					return 0, err
 				}
@@ -8893,97 +9219,65 @@ func (m *FacetParams) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 				i = encodeVarintPb(dAtA, i, uint64(size))
 			}
 			i--
-			dAtA[i] = 0x12
+			dAtA[i] = 0x2a
 		}
 	}
-	if m.AllKeys {
-		i--
-		if m.AllKeys {
-			dAtA[i] = 1
-		} else {
-			dAtA[i] = 0
-		}
+	if m.Checksum != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.Checksum))
 		i--
-		dAtA[i] = 0x8
-	}
-	return len(dAtA) - i, nil
-}
-
-func (m *Facets) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
-	if err != nil {
-		return nil, err
+		dAtA[i] = 0x20
 	}
-	return dAtA[:n], nil
-}
-
-func (m *Facets) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
-}
-
-func (m *Facets) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if m.XXX_unrecognized != nil {
-		i -= len(m.XXX_unrecognized)
-		copy(dAtA[i:], m.XXX_unrecognized)
+	if m.SnapshotTs != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.SnapshotTs))
+		i--
+		dAtA[i] = 0x18
 	}
-	if len(m.Facets) > 0 {
-		for iNdEx := len(m.Facets) - 1; iNdEx >= 0; iNdEx-- {
-			{
-				size, err := m.Facets[iNdEx].MarshalToSizedBuffer(dAtA[:i])
-				if err != nil {
-					return 0, err
+	if len(m.Tablets) > 0 {
+		for k := range m.Tablets {
+			v := m.Tablets[k]
+			baseI := i
+			if v != nil {
+				{
+					size, err := v.MarshalToSizedBuffer(dAtA[:i])
+					if err != nil {
+						return 0, err
+					}
+					i -= size
+					i = encodeVarintPb(dAtA, i, uint64(size))
 				}
-				i -= size
-				i = encodeVarintPb(dAtA, i, uint64(size))
+				i--
+				dAtA[i] = 0x12
 			}
+			i -= len(k)
+			copy(dAtA[i:], k)
+			i = encodeVarintPb(dAtA, i, uint64(len(k)))
 			i--
 			dAtA[i] = 0xa
+			i = encodeVarintPb(dAtA, i, uint64(baseI-i))
+			i--
+			dAtA[i] = 0x12
 		}
 	}
-	return len(dAtA) - i, nil
-}
-
-func (m *FacetsList) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
-	if err != nil {
-		return nil, err
-	}
-	return dAtA[:n], nil
-}
-
-func (m *FacetsList) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
-}
-
-func (m *FacetsList) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if m.XXX_unrecognized != nil {
-		i -= len(m.XXX_unrecognized)
-		copy(dAtA[i:], m.XXX_unrecognized)
-	}
-	if len(m.FacetsList) > 0 {
-		for iNdEx := len(m.FacetsList) - 1; iNdEx >= 0; iNdEx-- {
-			{
-				size, err := m.FacetsList[iNdEx].MarshalToSizedBuffer(dAtA[:i])
-				if err != nil {
-					return 0, err
+	if len(m.Members) > 0 {
+		for k := range m.Members {
+			v := m.Members[k]
+			baseI := i
+			if v != nil {
+				{
+					size, err := v.MarshalToSizedBuffer(dAtA[:i])
+					if err != nil {
+						return 0, err
+					}
+					i -= size
+					i = encodeVarintPb(dAtA, i, uint64(size))
 				}
-				i -= size
-				i = encodeVarintPb(dAtA, i, uint64(size))
+				i--
+				dAtA[i] = 0x12
 			}
+			i = encodeVarintPb(dAtA, i, uint64(k))
+			i--
+			dAtA[i] = 0x8
+			i = encodeVarintPb(dAtA, i, uint64(baseI-i))
 			i--
 			dAtA[i] = 0xa
 		}
@@ -8991,7 +9285,7 @@ func (m *FacetsList) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	return len(dAtA) - i, nil
 }
 
-func (m *Function) Marshal() (dAtA []byte, err error) {
+func (m *License) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -9001,12 +9295,12 @@ func (m *Function) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *Function) MarshalTo(dAtA []byte) (int, error) {
+func (m *License) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *Function) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *License) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
@@ -9015,33 +9309,37 @@ func (m *Function) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if len(m.Args) > 0 {
-		for iNdEx := len(m.Args) - 1; iNdEx >= 0; iNdEx-- {
-			i -= len(m.Args[iNdEx])
-			copy(dAtA[i:], m.Args[iNdEx])
-			i = encodeVarintPb(dAtA, i, uint64(len(m.Args[iNdEx])))
-			i--
-			dAtA[i] = 0x1a
+	if m.Enabled {
+		i--
+		if m.Enabled {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
 		}
+		i--
+		dAtA[i] = 0x20
 	}
-	if len(m.Key) > 0 {
-		i -= len(m.Key)
-		copy(dAtA[i:], m.Key)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Key)))
+	if m.ExpiryTs != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.ExpiryTs))
 		i--
-		dAtA[i] = 0x12
+		dAtA[i] = 0x18
 	}
-	if len(m.Name) > 0 {
-		i -= len(m.Name)
-		copy(dAtA[i:], m.Name)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Name)))
+	if m.MaxNodes != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.MaxNodes))
+		i--
+		dAtA[i] = 0x10
+	}
+	if len(m.User) > 0 {
+		i -= len(m.User)
+		copy(dAtA[i:], m.User)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.User)))
 		i--
 		dAtA[i] = 0xa
 	}
 	return len(dAtA) - i, nil
 }
 
-func (m *FilterTree) Marshal() (dAtA []byte, err error) {
+func (m *ZeroProposal) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -9051,12 +9349,12 @@ func (m *FilterTree) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *FilterTree) MarshalTo(dAtA []byte) (int, error) {
+func (m *ZeroProposal) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *FilterTree) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *ZeroProposal) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
@@ -9065,9 +9363,23 @@ func (m *FilterTree) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if m.Func != nil {
+	if m.IdempotencyCutoff != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.IdempotencyCutoff))
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0x80
+	}
+	if len(m.ReleaseFreeze) > 0 {
+		i -= len(m.ReleaseFreeze)
+		copy(dAtA[i:], m.ReleaseFreeze)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.ReleaseFreeze)))
+		i--
+		dAtA[i] = 0x7a
+	}
+	if m.Freeze != nil {
 		{
-			size, err := m.Func.MarshalToSizedBuffer(dAtA[:i])
+			size, err := m.Freeze.MarshalToSizedBuffer(dAtA[:i])
 			if err != nil {
 				return 0, err
 			}
@@ -9075,212 +9387,140 @@ func (m *FilterTree) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 			i = encodeVarintPb(dAtA, i, uint64(size))
 		}
 		i--
-		dAtA[i] = 0x1a
+		dAtA[i] = 0x72
 	}
-	if len(m.Children) > 0 {
-		for iNdEx := len(m.Children) - 1; iNdEx >= 0; iNdEx-- {
-			{
-				size, err := m.Children[iNdEx].MarshalToSizedBuffer(dAtA[:i])
-				if err != nil {
-					return 0, err
-				}
-				i -= size
-				i = encodeVarintPb(dAtA, i, uint64(size))
+	if m.Replication != nil {
+		{
+			size, err := m.Replication.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
 			}
-			i--
-			dAtA[i] = 0x12
+			i -= size
+			i = encodeVarintPb(dAtA, i, uint64(size))
 		}
-	}
-	if len(m.Op) > 0 {
-		i -= len(m.Op)
-		copy(dAtA[i:], m.Op)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Op)))
 		i--
-		dAtA[i] = 0xa
-	}
-	return len(dAtA) - i, nil
-}
-
-func (m *SchemaRequest) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
-	if err != nil {
-		return nil, err
-	}
-	return dAtA[:n], nil
-}
-
-func (m *SchemaRequest) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
-}
-
-func (m *SchemaRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if m.XXX_unrecognized != nil {
-		i -= len(m.XXX_unrecognized)
-		copy(dAtA[i:], m.XXX_unrecognized)
+		dAtA[i] = 0x6a
 	}
-	if len(m.Types) > 0 {
-		for iNdEx := len(m.Types) - 1; iNdEx >= 0; iNdEx-- {
-			i -= len(m.Types[iNdEx])
-			copy(dAtA[i:], m.Types[iNdEx])
-			i = encodeVarintPb(dAtA, i, uint64(len(m.Types[iNdEx])))
-			i--
-			dAtA[i] = 0x22
+	if m.StrictSchema != nil {
+		{
+			size, err := m.StrictSchema.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintPb(dAtA, i, uint64(size))
 		}
+		i--
+		dAtA[i] = 0x62
 	}
-	if len(m.Fields) > 0 {
-		for iNdEx := len(m.Fields) - 1; iNdEx >= 0; iNdEx-- {
-			i -= len(m.Fields[iNdEx])
-			copy(dAtA[i:], m.Fields[iNdEx])
-			i = encodeVarintPb(dAtA, i, uint64(len(m.Fields[iNdEx])))
-			i--
-			dAtA[i] = 0x1a
-		}
-	}
-	if len(m.Predicates) > 0 {
-		for iNdEx := len(m.Predicates) - 1; iNdEx >= 0; iNdEx-- {
-			i -= len(m.Predicates[iNdEx])
-			copy(dAtA[i:], m.Predicates[iNdEx])
-			i = encodeVarintPb(dAtA, i, uint64(len(m.Predicates[iNdEx])))
-			i--
-			dAtA[i] = 0x12
+	if m.Idempotency != nil {
+		{
+			size, err := m.Idempotency.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintPb(dAtA, i, uint64(size))
 		}
-	}
-	if m.GroupId != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.GroupId))
 		i--
-		dAtA[i] = 0x8
-	}
-	return len(dAtA) - i, nil
-}
-
-func (m *SchemaNode) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
-	if err != nil {
-		return nil, err
-	}
-	return dAtA[:n], nil
-}
-
-func (m *SchemaNode) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
-}
-
-func (m *SchemaNode) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if m.XXX_unrecognized != nil {
-		i -= len(m.XXX_unrecognized)
-		copy(dAtA[i:], m.XXX_unrecognized)
+		dAtA[i] = 0x5a
 	}
-	if m.NoConflict {
-		i--
-		if m.NoConflict {
-			dAtA[i] = 1
-		} else {
-			dAtA[i] = 0
+	if m.License != nil {
+		{
+			size, err := m.License.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintPb(dAtA, i, uint64(size))
 		}
 		i--
-		dAtA[i] = 0x50
+		dAtA[i] = 0x52
 	}
-	if m.Lang {
-		i--
-		if m.Lang {
-			dAtA[i] = 1
-		} else {
-			dAtA[i] = 0
-		}
+	if len(m.Cid) > 0 {
+		i -= len(m.Cid)
+		copy(dAtA[i:], m.Cid)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Cid)))
 		i--
-		dAtA[i] = 0x48
+		dAtA[i] = 0x4a
 	}
-	if m.Upsert {
-		i--
-		if m.Upsert {
-			dAtA[i] = 1
-		} else {
-			dAtA[i] = 0
-		}
+	if len(m.Key) > 0 {
+		i -= len(m.Key)
+		copy(dAtA[i:], m.Key)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Key)))
 		i--
-		dAtA[i] = 0x40
+		dAtA[i] = 0x42
 	}
-	if m.List {
-		i--
-		if m.List {
-			dAtA[i] = 1
-		} else {
-			dAtA[i] = 0
+	if m.Txn != nil {
+		{
+			size, err := m.Txn.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintPb(dAtA, i, uint64(size))
 		}
 		i--
-		dAtA[i] = 0x38
+		dAtA[i] = 0x3a
 	}
-	if m.Count {
-		i--
-		if m.Count {
-			dAtA[i] = 1
-		} else {
-			dAtA[i] = 0
-		}
+	if m.MaxRaftId != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.MaxRaftId))
 		i--
 		dAtA[i] = 0x30
 	}
-	if m.Reverse {
-		i--
-		if m.Reverse {
-			dAtA[i] = 1
-		} else {
-			dAtA[i] = 0
-		}
+	if m.MaxTxnTs != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.MaxTxnTs))
 		i--
 		dAtA[i] = 0x28
 	}
-	if len(m.Tokenizer) > 0 {
-		for iNdEx := len(m.Tokenizer) - 1; iNdEx >= 0; iNdEx-- {
-			i -= len(m.Tokenizer[iNdEx])
-			copy(dAtA[i:], m.Tokenizer[iNdEx])
-			i = encodeVarintPb(dAtA, i, uint64(len(m.Tokenizer[iNdEx])))
-			i--
-			dAtA[i] = 0x22
-		}
-	}
-	if m.Index {
+	if m.MaxLeaseId != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.MaxLeaseId))
 		i--
-		if m.Index {
-			dAtA[i] = 1
-		} else {
-			dAtA[i] = 0
+		dAtA[i] = 0x20
+	}
+	if m.Tablet != nil {
+		{
+			size, err := m.Tablet.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintPb(dAtA, i, uint64(size))
 		}
 		i--
-		dAtA[i] = 0x18
+		dAtA[i] = 0x1a
 	}
-	if len(m.Type) > 0 {
-		i -= len(m.Type)
-		copy(dAtA[i:], m.Type)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Type)))
+	if m.Member != nil {
+		{
+			size, err := m.Member.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintPb(dAtA, i, uint64(size))
+		}
 		i--
 		dAtA[i] = 0x12
 	}
-	if len(m.Predicate) > 0 {
-		i -= len(m.Predicate)
-		copy(dAtA[i:], m.Predicate)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Predicate)))
-		i--
-		dAtA[i] = 0xa
+	if len(m.SnapshotTs) > 0 {
+		for k := range m.SnapshotTs {
+			v := m.SnapshotTs[k]
+			baseI := i
+			i = encodeVarintPb(dAtA, i, uint64(v))
+			i--
+			dAtA[i] = 0x10
+			i = encodeVarintPb(dAtA, i, uint64(k))
+			i--
+			dAtA[i] = 0x8
+			i = encodeVarintPb(dAtA, i, uint64(baseI-i))
+			i--
+			dAtA[i] = 0xa
+		}
 	}
 	return len(dAtA) - i, nil
 }
 
-func (m *SchemaResult) Marshal() (dAtA []byte, err error) {
+func (m *MembershipState) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -9290,12 +9530,12 @@ func (m *SchemaResult) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *SchemaResult) MarshalTo(dAtA []byte) (int, error) {
+func (m *MembershipState) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *SchemaResult) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *MembershipState) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
@@ -9304,10 +9544,10 @@ func (m *SchemaResult) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if len(m.Schema) > 0 {
-		for iNdEx := len(m.Schema) - 1; iNdEx >= 0; iNdEx-- {
+	if len(m.FreezePoints) > 0 {
+		for iNdEx := len(m.FreezePoints) - 1; iNdEx >= 0; iNdEx-- {
 			{
-				size, err := m.Schema[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				size, err := m.FreezePoints[iNdEx].MarshalToSizedBuffer(dAtA[:i])
 				if err != nil {
 					return 0, err
 				}
@@ -9315,143 +9555,152 @@ func (m *SchemaResult) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 				i = encodeVarintPb(dAtA, i, uint64(size))
 			}
 			i--
-			dAtA[i] = 0xa
+			dAtA[i] = 0x6a
 		}
 	}
-	return len(dAtA) - i, nil
-}
-
-func (m *SchemaUpdate) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
-	if err != nil {
-		return nil, err
-	}
-	return dAtA[:n], nil
-}
-
-func (m *SchemaUpdate) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
-}
-
-func (m *SchemaUpdate) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if m.XXX_unrecognized != nil {
-		i -= len(m.XXX_unrecognized)
-		copy(dAtA[i:], m.XXX_unrecognized)
-	}
-	if m.NoConflict {
-		i--
-		if m.NoConflict {
-			dAtA[i] = 1
-		} else {
-			dAtA[i] = 0
+	if m.Replication != nil {
+		{
+			size, err := m.Replication.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintPb(dAtA, i, uint64(size))
 		}
 		i--
-		dAtA[i] = 0x68
-	}
-	if len(m.ObjectTypeName) > 0 {
-		i -= len(m.ObjectTypeName)
-		copy(dAtA[i:], m.ObjectTypeName)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.ObjectTypeName)))
-		i--
 		dAtA[i] = 0x62
 	}
-	if m.NonNullableList {
-		i--
-		if m.NonNullableList {
-			dAtA[i] = 1
-		} else {
-			dAtA[i] = 0
+	if m.StrictSchema != nil {
+		{
+			size, err := m.StrictSchema.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintPb(dAtA, i, uint64(size))
 		}
 		i--
-		dAtA[i] = 0x58
+		dAtA[i] = 0x5a
 	}
-	if m.NonNullable {
-		i--
-		if m.NonNullable {
-			dAtA[i] = 1
-		} else {
-			dAtA[i] = 0
+	if len(m.Idempotency) > 0 {
+		for iNdEx := len(m.Idempotency) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Idempotency[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintPb(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x52
 		}
-		i--
-		dAtA[i] = 0x50
 	}
-	if m.Lang {
-		i--
-		if m.Lang {
-			dAtA[i] = 1
-		} else {
-			dAtA[i] = 0
+	if m.License != nil {
+		{
+			size, err := m.License.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintPb(dAtA, i, uint64(size))
 		}
 		i--
-		dAtA[i] = 0x48
+		dAtA[i] = 0x4a
 	}
-	if m.Upsert {
-		i--
-		if m.Upsert {
-			dAtA[i] = 1
-		} else {
-			dAtA[i] = 0
-		}
+	if len(m.Cid) > 0 {
+		i -= len(m.Cid)
+		copy(dAtA[i:], m.Cid)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Cid)))
 		i--
-		dAtA[i] = 0x40
+		dAtA[i] = 0x42
 	}
-	if m.List {
-		i--
-		if m.List {
-			dAtA[i] = 1
-		} else {
-			dAtA[i] = 0
+	if len(m.Removed) > 0 {
+		for iNdEx := len(m.Removed) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Removed[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintPb(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x3a
 		}
+	}
+	if m.MaxRaftId != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.MaxRaftId))
 		i--
 		dAtA[i] = 0x30
 	}
-	if m.Count {
-		i--
-		if m.Count {
-			dAtA[i] = 1
-		} else {
-			dAtA[i] = 0
-		}
+	if m.MaxTxnTs != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.MaxTxnTs))
 		i--
 		dAtA[i] = 0x28
 	}
-	if len(m.Tokenizer) > 0 {
-		for iNdEx := len(m.Tokenizer) - 1; iNdEx >= 0; iNdEx-- {
-			i -= len(m.Tokenizer[iNdEx])
-			copy(dAtA[i:], m.Tokenizer[iNdEx])
-			i = encodeVarintPb(dAtA, i, uint64(len(m.Tokenizer[iNdEx])))
+	if m.MaxLeaseId != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.MaxLeaseId))
+		i--
+		dAtA[i] = 0x20
+	}
+	if len(m.Zeros) > 0 {
+		for k := range m.Zeros {
+			v := m.Zeros[k]
+			baseI := i
+			if v != nil {
+				{
+					size, err := v.MarshalToSizedBuffer(dAtA[:i])
+					if err != nil {
+						return 0, err
+					}
+					i -= size
+					i = encodeVarintPb(dAtA, i, uint64(size))
+				}
+				i--
+				dAtA[i] = 0x12
+			}
+			i = encodeVarintPb(dAtA, i, uint64(k))
 			i--
-			dAtA[i] = 0x22
+			dAtA[i] = 0x8
+			i = encodeVarintPb(dAtA, i, uint64(baseI-i))
+			i--
+			dAtA[i] = 0x1a
 		}
 	}
-	if m.Directive != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.Directive))
-		i--
-		dAtA[i] = 0x18
-	}
-	if m.ValueType != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.ValueType))
-		i--
-		dAtA[i] = 0x10
+	if len(m.Groups) > 0 {
+		for k := range m.Groups {
+			v := m.Groups[k]
+			baseI := i
+			if v != nil {
+				{
+					size, err := v.MarshalToSizedBuffer(dAtA[:i])
+					if err != nil {
+						return 0, err
+					}
+					i -= size
+					i = encodeVarintPb(dAtA, i, uint64(size))
+				}
+				i--
+				dAtA[i] = 0x12
+			}
+			i = encodeVarintPb(dAtA, i, uint64(k))
+			i--
+			dAtA[i] = 0x8
+			i = encodeVarintPb(dAtA, i, uint64(baseI-i))
+			i--
+			dAtA[i] = 0x12
+		}
 	}
-	if len(m.Predicate) > 0 {
-		i -= len(m.Predicate)
-		copy(dAtA[i:], m.Predicate)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Predicate)))
+	if m.Counter != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.Counter))
 		i--
-		dAtA[i] = 0xa
+		dAtA[i] = 0x8
 	}
 	return len(dAtA) - i, nil
 }
 
-func (m *TypeUpdate) Marshal() (dAtA []byte, err error) {
+func (m *ConnectionState) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -9461,12 +9710,12 @@ func (m *TypeUpdate) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *TypeUpdate) MarshalTo(dAtA []byte) (int, error) {
+func (m *ConnectionState) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *TypeUpdate) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *ConnectionState) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
@@ -9475,31 +9724,39 @@ func (m *TypeUpdate) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if len(m.Fields) > 0 {
-		for iNdEx := len(m.Fields) - 1; iNdEx >= 0; iNdEx-- {
-			{
-				size, err := m.Fields[iNdEx].MarshalToSizedBuffer(dAtA[:i])
-				if err != nil {
-					return 0, err
-				}
-				i -= size
-				i = encodeVarintPb(dAtA, i, uint64(size))
+	if m.MaxPending != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.MaxPending))
+		i--
+		dAtA[i] = 0x18
+	}
+	if m.State != nil {
+		{
+			size, err := m.State.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
 			}
-			i--
-			dAtA[i] = 0x12
+			i -= size
+			i = encodeVarintPb(dAtA, i, uint64(size))
 		}
+		i--
+		dAtA[i] = 0x12
 	}
-	if len(m.TypeName) > 0 {
-		i -= len(m.TypeName)
-		copy(dAtA[i:], m.TypeName)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.TypeName)))
+	if m.Member != nil {
+		{
+			size, err := m.Member.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintPb(dAtA, i, uint64(size))
+		}
 		i--
 		dAtA[i] = 0xa
 	}
 	return len(dAtA) - i, nil
 }
 
-func (m *MapHeader) Marshal() (dAtA []byte, err error) {
+func (m *HealthInfo) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -9509,12 +9766,12 @@ func (m *MapHeader) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *MapHeader) MarshalTo(dAtA []byte) (int, error) {
+func (m *HealthInfo) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *MapHeader) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *HealthInfo) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
@@ -9523,73 +9780,82 @@ func (m *MapHeader) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if len(m.PartitionKeys) > 0 {
-		for iNdEx := len(m.PartitionKeys) - 1; iNdEx >= 0; iNdEx-- {
-			i -= len(m.PartitionKeys[iNdEx])
-			copy(dAtA[i:], m.PartitionKeys[iNdEx])
-			i = encodeVarintPb(dAtA, i, uint64(len(m.PartitionKeys[iNdEx])))
+	if len(m.EeFeatures) > 0 {
+		for iNdEx := len(m.EeFeatures) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.EeFeatures[iNdEx])
+			copy(dAtA[i:], m.EeFeatures[iNdEx])
+			i = encodeVarintPb(dAtA, i, uint64(len(m.EeFeatures[iNdEx])))
 			i--
-			dAtA[i] = 0xa
+			dAtA[i] = 0x52
 		}
 	}
-	return len(dAtA) - i, nil
-}
-
-func (m *MovePredicatePayload) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
-	if err != nil {
-		return nil, err
+	if len(m.Indexing) > 0 {
+		for iNdEx := len(m.Indexing) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Indexing[iNdEx])
+			copy(dAtA[i:], m.Indexing[iNdEx])
+			i = encodeVarintPb(dAtA, i, uint64(len(m.Indexing[iNdEx])))
+			i--
+			dAtA[i] = 0x4a
+		}
 	}
-	return dAtA[:n], nil
-}
-
-func (m *MovePredicatePayload) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
-}
-
-func (m *MovePredicatePayload) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if m.XXX_unrecognized != nil {
-		i -= len(m.XXX_unrecognized)
-		copy(dAtA[i:], m.XXX_unrecognized)
+	if len(m.Ongoing) > 0 {
+		for iNdEx := len(m.Ongoing) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Ongoing[iNdEx])
+			copy(dAtA[i:], m.Ongoing[iNdEx])
+			i = encodeVarintPb(dAtA, i, uint64(len(m.Ongoing[iNdEx])))
+			i--
+			dAtA[i] = 0x42
+		}
 	}
-	if m.ExpectedChecksum != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.ExpectedChecksum))
+	if m.LastEcho != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.LastEcho))
 		i--
-		dAtA[i] = 0x28
+		dAtA[i] = 0x38
 	}
-	if m.TxnTs != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.TxnTs))
+	if m.Uptime != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.Uptime))
 		i--
-		dAtA[i] = 0x20
+		dAtA[i] = 0x30
 	}
-	if m.DestGid != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.DestGid))
+	if len(m.Version) > 0 {
+		i -= len(m.Version)
+		copy(dAtA[i:], m.Version)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Version)))
 		i--
-		dAtA[i] = 0x18
+		dAtA[i] = 0x2a
 	}
-	if m.SourceGid != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.SourceGid))
+	if len(m.Group) > 0 {
+		i -= len(m.Group)
+		copy(dAtA[i:], m.Group)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Group)))
 		i--
-		dAtA[i] = 0x10
+		dAtA[i] = 0x22
 	}
-	if len(m.Predicate) > 0 {
-		i -= len(m.Predicate)
-		copy(dAtA[i:], m.Predicate)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Predicate)))
+	if len(m.Status) > 0 {
+		i -= len(m.Status)
+		copy(dAtA[i:], m.Status)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Status)))
+		i--
+		dAtA[i] = 0x1a
+	}
+	if len(m.Address) > 0 {
+		i -= len(m.Address)
+		copy(dAtA[i:], m.Address)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Address)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if len(m.Instance) > 0 {
+		i -= len(m.Instance)
+		copy(dAtA[i:], m.Instance)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Instance)))
 		i--
 		dAtA[i] = 0xa
 	}
 	return len(dAtA) - i, nil
 }
 
-func (m *TxnStatus) Marshal() (dAtA []byte, err error) {
+func (m *Tablet) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -9599,12 +9865,12 @@ func (m *TxnStatus) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *TxnStatus) MarshalTo(dAtA []byte) (int, error) {
+func (m *Tablet) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *TxnStatus) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *Tablet) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
@@ -9613,20 +9879,77 @@ func (m *TxnStatus) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if m.CommitTs != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.CommitTs))
+	if m.NumKeys != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.NumKeys))
 		i--
-		dAtA[i] = 0x10
+		dAtA[i] = 0x68
 	}
-	if m.StartTs != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.StartTs))
+	if m.MutationsPerMin != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.MutationsPerMin))
+		i--
+		dAtA[i] = 0x60
+	}
+	if m.QueriesPerMin != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.QueriesPerMin))
+		i--
+		dAtA[i] = 0x58
+	}
+	if m.MoveTs != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.MoveTs))
+		i--
+		dAtA[i] = 0x50
+	}
+	if m.ReadOnly {
+		i--
+		if m.ReadOnly {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x48
+	}
+	if m.Remove {
+		i--
+		if m.Remove {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x40
+	}
+	if m.Space != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.Space))
+		i--
+		dAtA[i] = 0x38
+	}
+	if m.Force {
+		i--
+		if m.Force {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x18
+	}
+	if len(m.Predicate) > 0 {
+		i -= len(m.Predicate)
+		copy(dAtA[i:], m.Predicate)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Predicate)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if m.GroupId != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.GroupId))
 		i--
 		dAtA[i] = 0x8
 	}
 	return len(dAtA) - i, nil
 }
 
-func (m *OracleDelta) Marshal() (dAtA []byte, err error) {
+func (m *DirectedEdge) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -9636,12 +9959,12 @@ func (m *OracleDelta) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *OracleDelta) MarshalTo(dAtA []byte) (int, error) {
+func (m *DirectedEdge) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *OracleDelta) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *DirectedEdge) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
@@ -9650,30 +9973,24 @@ func (m *OracleDelta) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if len(m.GroupChecksums) > 0 {
-		for k := range m.GroupChecksums {
-			v := m.GroupChecksums[k]
-			baseI := i
-			i = encodeVarintPb(dAtA, i, uint64(v))
-			i--
-			dAtA[i] = 0x10
-			i = encodeVarintPb(dAtA, i, uint64(k))
-			i--
-			dAtA[i] = 0x8
-			i = encodeVarintPb(dAtA, i, uint64(baseI-i))
+	if m.BlobSize != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.BlobSize))
+		i--
+		dAtA[i] = 0x58
+	}
+	if len(m.AllowedPreds) > 0 {
+		for iNdEx := len(m.AllowedPreds) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.AllowedPreds[iNdEx])
+			copy(dAtA[i:], m.AllowedPreds[iNdEx])
+			i = encodeVarintPb(dAtA, i, uint64(len(m.AllowedPreds[iNdEx])))
 			i--
-			dAtA[i] = 0x1a
+			dAtA[i] = 0x52
 		}
 	}
-	if m.MaxAssigned != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.MaxAssigned))
-		i--
-		dAtA[i] = 0x10
-	}
-	if len(m.Txns) > 0 {
-		for iNdEx := len(m.Txns) - 1; iNdEx >= 0; iNdEx-- {
+	if len(m.Facets) > 0 {
+		for iNdEx := len(m.Facets) - 1; iNdEx >= 0; iNdEx-- {
 			{
-				size, err := m.Txns[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				size, err := m.Facets[iNdEx].MarshalToSizedBuffer(dAtA[:i])
 				if err != nil {
 					return 0, err
 				}
@@ -9681,58 +9998,63 @@ func (m *OracleDelta) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 				i = encodeVarintPb(dAtA, i, uint64(size))
 			}
 			i--
-			dAtA[i] = 0xa
+			dAtA[i] = 0x4a
 		}
 	}
-	return len(dAtA) - i, nil
-}
-
-func (m *TxnTimestamps) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
-	if err != nil {
-		return nil, err
+	if m.Op != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.Op))
+		i--
+		dAtA[i] = 0x40
 	}
-	return dAtA[:n], nil
-}
-
-func (m *TxnTimestamps) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
-}
-
-func (m *TxnTimestamps) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if m.XXX_unrecognized != nil {
-		i -= len(m.XXX_unrecognized)
-		copy(dAtA[i:], m.XXX_unrecognized)
+	if len(m.Lang) > 0 {
+		i -= len(m.Lang)
+		copy(dAtA[i:], m.Lang)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Lang)))
+		i--
+		dAtA[i] = 0x3a
 	}
-	if len(m.Ts) > 0 {
-		dAtA30 := make([]byte, len(m.Ts)*10)
-		var j29 int
-		for _, num := range m.Ts {
-			for num >= 1<<7 {
-				dAtA30[j29] = uint8(uint64(num)&0x7f | 0x80)
-				num >>= 7
-				j29++
-			}
-			dAtA30[j29] = uint8(num)
-			j29++
-		}
-		i -= j29
-		copy(dAtA[i:], dAtA30[:j29])
-		i = encodeVarintPb(dAtA, i, uint64(j29))
+	if len(m.Label) > 0 {
+		i -= len(m.Label)
+		copy(dAtA[i:], m.Label)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Label)))
 		i--
-		dAtA[i] = 0xa
+		dAtA[i] = 0x32
+	}
+	if m.ValueId != 0 {
+		i -= 8
+		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(m.ValueId))
+		i--
+		dAtA[i] = 0x29
+	}
+	if m.ValueType != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.ValueType))
+		i--
+		dAtA[i] = 0x20
+	}
+	if len(m.Value) > 0 {
+		i -= len(m.Value)
+		copy(dAtA[i:], m.Value)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Value)))
+		i--
+		dAtA[i] = 0x1a
+	}
+	if len(m.Attr) > 0 {
+		i -= len(m.Attr)
+		copy(dAtA[i:], m.Attr)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Attr)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if m.Entity != 0 {
+		i -= 8
+		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(m.Entity))
+		i--
+		dAtA[i] = 0x9
 	}
 	return len(dAtA) - i, nil
 }
 
-func (m *PeerResponse) Marshal() (dAtA []byte, err error) {
+func (m *Mutations) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -9742,12 +10064,12 @@ func (m *PeerResponse) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *PeerResponse) MarshalTo(dAtA []byte) (int, error) {
+func (m *Mutations) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *PeerResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *Mutations) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
@@ -9756,58 +10078,94 @@ func (m *PeerResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if m.Status {
+	if len(m.Dictionary) > 0 {
+		i -= len(m.Dictionary)
+		copy(dAtA[i:], m.Dictionary)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Dictionary)))
 		i--
-		if m.Status {
-			dAtA[i] = 1
-		} else {
-			dAtA[i] = 0
-		}
+		dAtA[i] = 0x1
 		i--
-		dAtA[i] = 0x8
+		dAtA[i] = 0x9a
 	}
-	return len(dAtA) - i, nil
-}
-
-func (m *RaftBatch) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
-	if err != nil {
-		return nil, err
+	if len(m.DictionaryPredicate) > 0 {
+		i -= len(m.DictionaryPredicate)
+		copy(dAtA[i:], m.DictionaryPredicate)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.DictionaryPredicate)))
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0x92
 	}
-	return dAtA[:n], nil
-}
-
-func (m *RaftBatch) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
-}
-
-func (m *RaftBatch) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if m.XXX_unrecognized != nil {
-		i -= len(m.XXX_unrecognized)
-		copy(dAtA[i:], m.XXX_unrecognized)
+	if len(m.TierObject) > 0 {
+		i -= len(m.TierObject)
+		copy(dAtA[i:], m.TierObject)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.TierObject)))
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0x8a
 	}
-	if m.Payload != nil {
-		{
-			size, err := m.Payload.MarshalToSizedBuffer(dAtA[:i])
-			if err != nil {
-				return 0, err
+	if len(m.Tier) > 0 {
+		i -= len(m.Tier)
+		copy(dAtA[i:], m.Tier)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Tier)))
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0x82
+	}
+	if len(m.TierPredicate) > 0 {
+		i -= len(m.TierPredicate)
+		copy(dAtA[i:], m.TierPredicate)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.TierPredicate)))
+		i--
+		dAtA[i] = 0x7a
+	}
+	if len(m.RenameTo) > 0 {
+		i -= len(m.RenameTo)
+		copy(dAtA[i:], m.RenameTo)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.RenameTo)))
+		i--
+		dAtA[i] = 0x72
+	}
+	if len(m.RenameFrom) > 0 {
+		i -= len(m.RenameFrom)
+		copy(dAtA[i:], m.RenameFrom)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.RenameFrom)))
+		i--
+		dAtA[i] = 0x6a
+	}
+	if len(m.BlobChunks) > 0 {
+		for iNdEx := len(m.BlobChunks) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.BlobChunks[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintPb(dAtA, i, uint64(size))
 			}
-			i -= size
-			i = encodeVarintPb(dAtA, i, uint64(size))
+			i--
+			dAtA[i] = 0x62
 		}
+	}
+	if len(m.RollbackTo) > 0 {
+		i -= len(m.RollbackTo)
+		copy(dAtA[i:], m.RollbackTo)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.RollbackTo)))
 		i--
-		dAtA[i] = 0x12
+		dAtA[i] = 0x5a
 	}
-	if m.Context != nil {
+	if len(m.Savepoint) > 0 {
+		i -= len(m.Savepoint)
+		copy(dAtA[i:], m.Savepoint)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Savepoint)))
+		i--
+		dAtA[i] = 0x52
+	}
+	if m.Metadata != nil {
 		{
-			size, err := m.Context.MarshalToSizedBuffer(dAtA[:i])
+			size, err := m.Metadata.MarshalToSizedBuffer(dAtA[:i])
 			if err != nil {
 				return 0, err
 			}
@@ -9815,12 +10173,76 @@ func (m *RaftBatch) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 			i = encodeVarintPb(dAtA, i, uint64(size))
 		}
 		i--
-		dAtA[i] = 0xa
+		dAtA[i] = 0x4a
+	}
+	if len(m.DropValue) > 0 {
+		i -= len(m.DropValue)
+		copy(dAtA[i:], m.DropValue)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.DropValue)))
+		i--
+		dAtA[i] = 0x42
+	}
+	if m.DropOp != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.DropOp))
+		i--
+		dAtA[i] = 0x38
+	}
+	if len(m.Types) > 0 {
+		for iNdEx := len(m.Types) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Types[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintPb(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x32
+		}
+	}
+	if len(m.Schema) > 0 {
+		for iNdEx := len(m.Schema) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Schema[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintPb(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x22
+		}
+	}
+	if len(m.Edges) > 0 {
+		for iNdEx := len(m.Edges) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Edges[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintPb(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x1a
+		}
+	}
+	if m.StartTs != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.StartTs))
+		i--
+		dAtA[i] = 0x10
+	}
+	if m.GroupId != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.GroupId))
+		i--
+		dAtA[i] = 0x8
 	}
 	return len(dAtA) - i, nil
 }
 
-func (m *SubscriptionRequest) Marshal() (dAtA []byte, err error) {
+func (m *Metadata) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -9830,12 +10252,12 @@ func (m *SubscriptionRequest) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *SubscriptionRequest) MarshalTo(dAtA []byte) (int, error) {
+func (m *Metadata) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *SubscriptionRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *Metadata) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
@@ -9844,11 +10266,19 @@ func (m *SubscriptionRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if len(m.Prefixes) > 0 {
-		for iNdEx := len(m.Prefixes) - 1; iNdEx >= 0; iNdEx-- {
-			i -= len(m.Prefixes[iNdEx])
-			copy(dAtA[i:], m.Prefixes[iNdEx])
-			i = encodeVarintPb(dAtA, i, uint64(len(m.Prefixes[iNdEx])))
+	if len(m.PredHints) > 0 {
+		for k := range m.PredHints {
+			v := m.PredHints[k]
+			baseI := i
+			i = encodeVarintPb(dAtA, i, uint64(v))
+			i--
+			dAtA[i] = 0x10
+			i -= len(k)
+			copy(dAtA[i:], k)
+			i = encodeVarintPb(dAtA, i, uint64(len(k)))
+			i--
+			dAtA[i] = 0xa
+			i = encodeVarintPb(dAtA, i, uint64(baseI-i))
 			i--
 			dAtA[i] = 0xa
 		}
@@ -9856,7 +10286,7 @@ func (m *SubscriptionRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	return len(dAtA) - i, nil
 }
 
-func (m *SubscriptionResponse) Marshal() (dAtA []byte, err error) {
+func (m *Snapshot) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -9866,12 +10296,12 @@ func (m *SubscriptionResponse) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *SubscriptionResponse) MarshalTo(dAtA []byte) (int, error) {
+func (m *Snapshot) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *SubscriptionResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *Snapshot) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
@@ -9880,74 +10310,61 @@ func (m *SubscriptionResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if m.Kvs != nil {
-		{
-			size, err := m.Kvs.MarshalToSizedBuffer(dAtA[:i])
-			if err != nil {
-				return 0, err
+	if len(m.Received) > 0 {
+		for iNdEx := len(m.Received) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Received[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintPb(dAtA, i, uint64(size))
 			}
-			i -= size
-			i = encodeVarintPb(dAtA, i, uint64(size))
+			i--
+			dAtA[i] = 0x32
 		}
-		i--
-		dAtA[i] = 0xa
-	}
-	return len(dAtA) - i, nil
-}
-
-func (m *Num) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
-	if err != nil {
-		return nil, err
 	}
-	return dAtA[:n], nil
-}
-
-func (m *Num) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
-}
-
-func (m *Num) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if m.XXX_unrecognized != nil {
-		i -= len(m.XXX_unrecognized)
-		copy(dAtA[i:], m.XXX_unrecognized)
+	if m.SinceTs != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.SinceTs))
+		i--
+		dAtA[i] = 0x28
 	}
-	if m.Forwarded {
+	if m.Done {
 		i--
-		if m.Forwarded {
+		if m.Done {
 			dAtA[i] = 1
 		} else {
 			dAtA[i] = 0
 		}
 		i--
-		dAtA[i] = 0x18
+		dAtA[i] = 0x20
 	}
-	if m.ReadOnly {
+	if m.ReadTs != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.ReadTs))
 		i--
-		if m.ReadOnly {
-			dAtA[i] = 1
-		} else {
-			dAtA[i] = 0
-		}
+		dAtA[i] = 0x18
+	}
+	if m.Index != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.Index))
 		i--
 		dAtA[i] = 0x10
 	}
-	if m.Val != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.Val))
+	if m.Context != nil {
+		{
+			size, err := m.Context.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintPb(dAtA, i, uint64(size))
+		}
 		i--
-		dAtA[i] = 0x8
+		dAtA[i] = 0xa
 	}
 	return len(dAtA) - i, nil
 }
 
-func (m *AssignedIds) Marshal() (dAtA []byte, err error) {
+func (m *RestoreRequest) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -9957,12 +10374,12 @@ func (m *AssignedIds) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *AssignedIds) MarshalTo(dAtA []byte) (int, error) {
+func (m *RestoreRequest) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *AssignedIds) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *RestoreRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
@@ -9971,132 +10388,61 @@ func (m *AssignedIds) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if m.ReadOnly != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.ReadOnly))
+	if m.BackupNum != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.BackupNum))
 		i--
-		dAtA[i] = 0x28
-	}
-	if m.EndId != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.EndId))
+		dAtA[i] = 0x1
 		i--
-		dAtA[i] = 0x10
+		dAtA[i] = 0x80
 	}
-	if m.StartId != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.StartId))
+	if len(m.VaultFormat) > 0 {
+		i -= len(m.VaultFormat)
+		copy(dAtA[i:], m.VaultFormat)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.VaultFormat)))
 		i--
-		dAtA[i] = 0x8
-	}
-	return len(dAtA) - i, nil
-}
-
-func (m *SnapshotMeta) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
-	if err != nil {
-		return nil, err
-	}
-	return dAtA[:n], nil
-}
-
-func (m *SnapshotMeta) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
-}
-
-func (m *SnapshotMeta) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if m.XXX_unrecognized != nil {
-		i -= len(m.XXX_unrecognized)
-		copy(dAtA[i:], m.XXX_unrecognized)
+		dAtA[i] = 0x7a
 	}
-	if m.GroupId != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.GroupId))
+	if len(m.VaultField) > 0 {
+		i -= len(m.VaultField)
+		copy(dAtA[i:], m.VaultField)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.VaultField)))
 		i--
-		dAtA[i] = 0x10
+		dAtA[i] = 0x72
 	}
-	if m.ClientTs != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.ClientTs))
+	if len(m.VaultPath) > 0 {
+		i -= len(m.VaultPath)
+		copy(dAtA[i:], m.VaultPath)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.VaultPath)))
 		i--
-		dAtA[i] = 0x8
-	}
-	return len(dAtA) - i, nil
-}
-
-func (m *Status) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
-	if err != nil {
-		return nil, err
-	}
-	return dAtA[:n], nil
-}
-
-func (m *Status) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
-}
-
-func (m *Status) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if m.XXX_unrecognized != nil {
-		i -= len(m.XXX_unrecognized)
-		copy(dAtA[i:], m.XXX_unrecognized)
+		dAtA[i] = 0x6a
 	}
-	if len(m.Msg) > 0 {
-		i -= len(m.Msg)
-		copy(dAtA[i:], m.Msg)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Msg)))
+	if len(m.VaultSecretidFile) > 0 {
+		i -= len(m.VaultSecretidFile)
+		copy(dAtA[i:], m.VaultSecretidFile)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.VaultSecretidFile)))
 		i--
-		dAtA[i] = 0x12
+		dAtA[i] = 0x62
 	}
-	if m.Code != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.Code))
+	if len(m.VaultRoleidFile) > 0 {
+		i -= len(m.VaultRoleidFile)
+		copy(dAtA[i:], m.VaultRoleidFile)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.VaultRoleidFile)))
 		i--
-		dAtA[i] = 0x8
-	}
-	return len(dAtA) - i, nil
-}
-
-func (m *BackupRequest) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
-	if err != nil {
-		return nil, err
+		dAtA[i] = 0x5a
 	}
-	return dAtA[:n], nil
-}
-
-func (m *BackupRequest) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
-}
-
-func (m *BackupRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if m.XXX_unrecognized != nil {
-		i -= len(m.XXX_unrecognized)
-		copy(dAtA[i:], m.XXX_unrecognized)
+	if len(m.VaultAddr) > 0 {
+		i -= len(m.VaultAddr)
+		copy(dAtA[i:], m.VaultAddr)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.VaultAddr)))
+		i--
+		dAtA[i] = 0x52
 	}
-	if len(m.Predicates) > 0 {
-		for iNdEx := len(m.Predicates) - 1; iNdEx >= 0; iNdEx-- {
-			i -= len(m.Predicates[iNdEx])
-			copy(dAtA[i:], m.Predicates[iNdEx])
-			i = encodeVarintPb(dAtA, i, uint64(len(m.Predicates[iNdEx])))
-			i--
-			dAtA[i] = 0x52
-		}
+	if len(m.EncryptionKeyFile) > 0 {
+		i -= len(m.EncryptionKeyFile)
+		copy(dAtA[i:], m.EncryptionKeyFile)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.EncryptionKeyFile)))
+		i--
+		dAtA[i] = 0x4a
 	}
 	if m.Anonymous {
 		i--
@@ -10106,62 +10452,57 @@ func (m *BackupRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 			dAtA[i] = 0
 		}
 		i--
-		dAtA[i] = 0x48
+		dAtA[i] = 0x40
 	}
 	if len(m.SessionToken) > 0 {
 		i -= len(m.SessionToken)
 		copy(dAtA[i:], m.SessionToken)
 		i = encodeVarintPb(dAtA, i, uint64(len(m.SessionToken)))
 		i--
-		dAtA[i] = 0x42
+		dAtA[i] = 0x3a
 	}
 	if len(m.SecretKey) > 0 {
 		i -= len(m.SecretKey)
 		copy(dAtA[i:], m.SecretKey)
 		i = encodeVarintPb(dAtA, i, uint64(len(m.SecretKey)))
 		i--
-		dAtA[i] = 0x3a
+		dAtA[i] = 0x32
 	}
 	if len(m.AccessKey) > 0 {
 		i -= len(m.AccessKey)
 		copy(dAtA[i:], m.AccessKey)
 		i = encodeVarintPb(dAtA, i, uint64(len(m.AccessKey)))
 		i--
-		dAtA[i] = 0x32
-	}
-	if len(m.Destination) > 0 {
-		i -= len(m.Destination)
-		copy(dAtA[i:], m.Destination)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Destination)))
-		i--
 		dAtA[i] = 0x2a
 	}
-	if len(m.UnixTs) > 0 {
-		i -= len(m.UnixTs)
-		copy(dAtA[i:], m.UnixTs)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.UnixTs)))
+	if len(m.BackupId) > 0 {
+		i -= len(m.BackupId)
+		copy(dAtA[i:], m.BackupId)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.BackupId)))
 		i--
 		dAtA[i] = 0x22
 	}
-	if m.GroupId != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.GroupId))
+	if len(m.Location) > 0 {
+		i -= len(m.Location)
+		copy(dAtA[i:], m.Location)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Location)))
 		i--
-		dAtA[i] = 0x18
+		dAtA[i] = 0x1a
 	}
-	if m.SinceTs != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.SinceTs))
+	if m.RestoreTs != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.RestoreTs))
 		i--
 		dAtA[i] = 0x10
 	}
-	if m.ReadTs != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.ReadTs))
+	if m.GroupId != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.GroupId))
 		i--
 		dAtA[i] = 0x8
 	}
 	return len(dAtA) - i, nil
 }
 
-func (m *ExportRequest) Marshal() (dAtA []byte, err error) {
+func (m *Proposal) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -10171,12 +10512,12 @@ func (m *ExportRequest) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *ExportRequest) MarshalTo(dAtA []byte) (int, error) {
+func (m *Proposal) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *ExportRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *Proposal) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
@@ -10185,70 +10526,117 @@ func (m *ExportRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if m.Anonymous {
+	if len(m.TierAccessed) > 0 {
+		for iNdEx := len(m.TierAccessed) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.TierAccessed[iNdEx])
+			copy(dAtA[i:], m.TierAccessed[iNdEx])
+			i = encodeVarintPb(dAtA, i, uint64(len(m.TierAccessed[iNdEx])))
+			i--
+			dAtA[i] = 0x6a
+		}
+	}
+	if m.Restore != nil {
+		{
+			size, err := m.Restore.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintPb(dAtA, i, uint64(size))
+		}
 		i--
-		if m.Anonymous {
-			dAtA[i] = 1
-		} else {
-			dAtA[i] = 0
+		dAtA[i] = 0x62
+	}
+	if m.ExpectedChecksum != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.ExpectedChecksum))
+		i--
+		dAtA[i] = 0x58
+	}
+	if m.Index != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.Index))
+		i--
+		dAtA[i] = 0x50
+	}
+	if m.Snapshot != nil {
+		{
+			size, err := m.Snapshot.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintPb(dAtA, i, uint64(size))
 		}
 		i--
-		dAtA[i] = 0x48
+		dAtA[i] = 0x4a
 	}
-	if len(m.SessionToken) > 0 {
-		i -= len(m.SessionToken)
-		copy(dAtA[i:], m.SessionToken)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.SessionToken)))
+	if m.Delta != nil {
+		{
+			size, err := m.Delta.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintPb(dAtA, i, uint64(size))
+		}
 		i--
 		dAtA[i] = 0x42
 	}
-	if len(m.SecretKey) > 0 {
-		i -= len(m.SecretKey)
-		copy(dAtA[i:], m.SecretKey)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.SecretKey)))
+	if len(m.Key) > 0 {
+		i -= len(m.Key)
+		copy(dAtA[i:], m.Key)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Key)))
 		i--
 		dAtA[i] = 0x3a
 	}
-	if len(m.AccessKey) > 0 {
-		i -= len(m.AccessKey)
-		copy(dAtA[i:], m.AccessKey)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.AccessKey)))
+	if len(m.CleanPredicate) > 0 {
+		i -= len(m.CleanPredicate)
+		copy(dAtA[i:], m.CleanPredicate)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.CleanPredicate)))
 		i--
 		dAtA[i] = 0x32
 	}
-	if len(m.Destination) > 0 {
-		i -= len(m.Destination)
-		copy(dAtA[i:], m.Destination)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Destination)))
+	if m.State != nil {
+		{
+			size, err := m.State.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintPb(dAtA, i, uint64(size))
+		}
 		i--
 		dAtA[i] = 0x2a
 	}
-	if len(m.Format) > 0 {
-		i -= len(m.Format)
-		copy(dAtA[i:], m.Format)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Format)))
-		i--
-		dAtA[i] = 0x22
-	}
-	if m.UnixTs != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.UnixTs))
-		i--
-		dAtA[i] = 0x18
-	}
-	if m.ReadTs != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.ReadTs))
-		i--
-		dAtA[i] = 0x10
+	if len(m.Kv) > 0 {
+		for iNdEx := len(m.Kv) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Kv[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintPb(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x22
+		}
 	}
-	if m.GroupId != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.GroupId))
+	if m.Mutations != nil {
+		{
+			size, err := m.Mutations.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintPb(dAtA, i, uint64(size))
+		}
 		i--
-		dAtA[i] = 0x8
+		dAtA[i] = 0x12
 	}
 	return len(dAtA) - i, nil
 }
 
-func (m *ExportResponse) Marshal() (dAtA []byte, err error) {
+func (m *KVS) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -10258,12 +10646,12 @@ func (m *ExportResponse) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *ExportResponse) MarshalTo(dAtA []byte) (int, error) {
+func (m *KVS) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *ExportResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *KVS) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
@@ -10272,31 +10660,52 @@ func (m *ExportResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if len(m.Files) > 0 {
-		for iNdEx := len(m.Files) - 1; iNdEx >= 0; iNdEx-- {
-			i -= len(m.Files[iNdEx])
-			copy(dAtA[i:], m.Files[iNdEx])
-			i = encodeVarintPb(dAtA, i, uint64(len(m.Files[iNdEx])))
+	if len(m.Types) > 0 {
+		for iNdEx := len(m.Types) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Types[iNdEx])
+			copy(dAtA[i:], m.Types[iNdEx])
+			i = encodeVarintPb(dAtA, i, uint64(len(m.Types[iNdEx])))
+			i--
+			dAtA[i] = 0x22
+		}
+	}
+	if len(m.Predicates) > 0 {
+		for iNdEx := len(m.Predicates) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Predicates[iNdEx])
+			copy(dAtA[i:], m.Predicates[iNdEx])
+			i = encodeVarintPb(dAtA, i, uint64(len(m.Predicates[iNdEx])))
 			i--
 			dAtA[i] = 0x1a
 		}
 	}
-	if len(m.Msg) > 0 {
-		i -= len(m.Msg)
-		copy(dAtA[i:], m.Msg)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Msg)))
+	if m.Done {
 		i--
-		dAtA[i] = 0x12
-	}
-	if m.Code != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.Code))
+		if m.Done {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
 		i--
-		dAtA[i] = 0x8
+		dAtA[i] = 0x10
+	}
+	if len(m.Kv) > 0 {
+		for iNdEx := len(m.Kv) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Kv[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintPb(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0xa
+		}
 	}
 	return len(dAtA) - i, nil
 }
 
-func (m *BackupKey) Marshal() (dAtA []byte, err error) {
+func (m *Posting) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -10306,12 +10715,12 @@ func (m *BackupKey) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *BackupKey) MarshalTo(dAtA []byte) (int, error) {
+func (m *Posting) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *BackupKey) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *Posting) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
@@ -10320,44 +10729,88 @@ func (m *BackupKey) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if m.Count != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.Count))
+	if m.Encoding != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.Encoding))
 		i--
-		dAtA[i] = 0x30
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0x80
 	}
-	if len(m.Term) > 0 {
-		i -= len(m.Term)
-		copy(dAtA[i:], m.Term)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Term)))
+	if m.BlobSize != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.BlobSize))
+		i--
+		dAtA[i] = 0x78
+	}
+	if m.CommitTs != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.CommitTs))
+		i--
+		dAtA[i] = 0x70
+	}
+	if m.StartTs != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.StartTs))
+		i--
+		dAtA[i] = 0x68
+	}
+	if m.Op != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.Op))
+		i--
+		dAtA[i] = 0x60
+	}
+	if len(m.Facets) > 0 {
+		for iNdEx := len(m.Facets) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Facets[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintPb(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x4a
+		}
+	}
+	if len(m.Label) > 0 {
+		i -= len(m.Label)
+		copy(dAtA[i:], m.Label)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Label)))
+		i--
+		dAtA[i] = 0x32
+	}
+	if len(m.LangTag) > 0 {
+		i -= len(m.LangTag)
+		copy(dAtA[i:], m.LangTag)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.LangTag)))
 		i--
 		dAtA[i] = 0x2a
 	}
-	if m.StartUid != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.StartUid))
+	if m.PostingType != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.PostingType))
 		i--
 		dAtA[i] = 0x20
 	}
-	if m.Uid != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.Uid))
+	if m.ValType != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.ValType))
 		i--
 		dAtA[i] = 0x18
 	}
-	if len(m.Attr) > 0 {
-		i -= len(m.Attr)
-		copy(dAtA[i:], m.Attr)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.Attr)))
+	if len(m.Value) > 0 {
+		i -= len(m.Value)
+		copy(dAtA[i:], m.Value)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Value)))
 		i--
 		dAtA[i] = 0x12
 	}
-	if m.Type != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.Type))
+	if m.Uid != 0 {
+		i -= 8
+		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(m.Uid))
 		i--
-		dAtA[i] = 0x8
+		dAtA[i] = 0x9
 	}
 	return len(dAtA) - i, nil
 }
 
-func (m *BackupPostingList) Marshal() (dAtA []byte, err error) {
+func (m *UidBlock) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -10367,12 +10820,12 @@ func (m *BackupPostingList) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *BackupPostingList) MarshalTo(dAtA []byte) (int, error) {
+func (m *UidBlock) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *BackupPostingList) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *UidBlock) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
@@ -10381,72 +10834,34 @@ func (m *BackupPostingList) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if len(m.UidBytes) > 0 {
-		i -= len(m.UidBytes)
-		copy(dAtA[i:], m.UidBytes)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.UidBytes)))
-		i--
-		dAtA[i] = 0x2a
-	}
-	if len(m.Splits) > 0 {
-		dAtA35 := make([]byte, len(m.Splits)*10)
-		var j34 int
-		for _, num := range m.Splits {
-			for num >= 1<<7 {
-				dAtA35[j34] = uint8(uint64(num)&0x7f | 0x80)
-				num >>= 7
-				j34++
-			}
-			dAtA35[j34] = uint8(num)
-			j34++
-		}
-		i -= j34
-		copy(dAtA[i:], dAtA35[:j34])
-		i = encodeVarintPb(dAtA, i, uint64(j34))
+	if len(m.Bitmap) > 0 {
+		i -= len(m.Bitmap)
+		copy(dAtA[i:], m.Bitmap)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Bitmap)))
 		i--
 		dAtA[i] = 0x22
 	}
-	if m.CommitTs != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.CommitTs))
+	if m.NumUids != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.NumUids))
 		i--
 		dAtA[i] = 0x18
 	}
-	if len(m.Postings) > 0 {
-		for iNdEx := len(m.Postings) - 1; iNdEx >= 0; iNdEx-- {
-			{
-				size, err := m.Postings[iNdEx].MarshalToSizedBuffer(dAtA[:i])
-				if err != nil {
-					return 0, err
-				}
-				i -= size
-				i = encodeVarintPb(dAtA, i, uint64(size))
-			}
-			i--
-			dAtA[i] = 0x12
-		}
-	}
-	if len(m.Uids) > 0 {
-		dAtA37 := make([]byte, len(m.Uids)*10)
-		var j36 int
-		for _, num := range m.Uids {
-			for num >= 1<<7 {
-				dAtA37[j36] = uint8(uint64(num)&0x7f | 0x80)
-				num >>= 7
-				j36++
-			}
-			dAtA37[j36] = uint8(num)
-			j36++
-		}
-		i -= j36
-		copy(dAtA[i:], dAtA37[:j36])
-		i = encodeVarintPb(dAtA, i, uint64(j36))
+	if len(m.Deltas) > 0 {
+		i -= len(m.Deltas)
+		copy(dAtA[i:], m.Deltas)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Deltas)))
 		i--
-		dAtA[i] = 0xa
+		dAtA[i] = 0x12
 	}
-	return len(dAtA) - i, nil
+	if m.Base != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.Base))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
 }
 
-func (m *UpdateGraphQLSchemaRequest) Marshal() (dAtA []byte, err error) {
+func (m *UidPack) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -10456,12 +10871,12 @@ func (m *UpdateGraphQLSchemaRequest) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *UpdateGraphQLSchemaRequest) MarshalTo(dAtA []byte) (int, error) {
+func (m *UidPack) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *UpdateGraphQLSchemaRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *UidPack) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
@@ -10470,10 +10885,17 @@ func (m *UpdateGraphQLSchemaRequest) MarshalToSizedBuffer(dAtA []byte) (int, err
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if len(m.DgraphTypes) > 0 {
-		for iNdEx := len(m.DgraphTypes) - 1; iNdEx >= 0; iNdEx-- {
+	if m.AllocRef != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.AllocRef))
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0xb8
+	}
+	if len(m.Blocks) > 0 {
+		for iNdEx := len(m.Blocks) - 1; iNdEx >= 0; iNdEx-- {
 			{
-				size, err := m.DgraphTypes[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				size, err := m.Blocks[iNdEx].MarshalToSizedBuffer(dAtA[:i])
 				if err != nil {
 					return 0, err
 				}
@@ -10481,13 +10903,68 @@ func (m *UpdateGraphQLSchemaRequest) MarshalToSizedBuffer(dAtA []byte) (int, err
 				i = encodeVarintPb(dAtA, i, uint64(size))
 			}
 			i--
-			dAtA[i] = 0x22
+			dAtA[i] = 0x12
 		}
 	}
-	if len(m.DgraphPreds) > 0 {
-		for iNdEx := len(m.DgraphPreds) - 1; iNdEx >= 0; iNdEx-- {
+	if m.BlockSize != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.BlockSize))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *PostingList) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *PostingList) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *PostingList) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if len(m.Splits) > 0 {
+		dAtA26 := make([]byte, len(m.Splits)*10)
+		var j25 int
+		for _, num := range m.Splits {
+			for num >= 1<<7 {
+				dAtA26[j25] = uint8(uint64(num)&0x7f | 0x80)
+				num >>= 7
+				j25++
+			}
+			dAtA26[j25] = uint8(num)
+			j25++
+		}
+		i -= j25
+		copy(dAtA[i:], dAtA26[:j25])
+		i = encodeVarintPb(dAtA, i, uint64(j25))
+		i--
+		dAtA[i] = 0x22
+	}
+	if m.CommitTs != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.CommitTs))
+		i--
+		dAtA[i] = 0x18
+	}
+	if len(m.Postings) > 0 {
+		for iNdEx := len(m.Postings) - 1; iNdEx >= 0; iNdEx-- {
 			{
-				size, err := m.DgraphPreds[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				size, err := m.Postings[iNdEx].MarshalToSizedBuffer(dAtA[:i])
 				if err != nil {
 					return 0, err
 				}
@@ -10495,25 +10972,25 @@ func (m *UpdateGraphQLSchemaRequest) MarshalToSizedBuffer(dAtA []byte) (int, err
 				i = encodeVarintPb(dAtA, i, uint64(size))
 			}
 			i--
-			dAtA[i] = 0x1a
+			dAtA[i] = 0x12
 		}
 	}
-	if len(m.GraphqlSchema) > 0 {
-		i -= len(m.GraphqlSchema)
-		copy(dAtA[i:], m.GraphqlSchema)
-		i = encodeVarintPb(dAtA, i, uint64(len(m.GraphqlSchema)))
-		i--
-		dAtA[i] = 0x12
-	}
-	if m.StartTs != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.StartTs))
+	if m.Pack != nil {
+		{
+			size, err := m.Pack.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintPb(dAtA, i, uint64(size))
+		}
 		i--
-		dAtA[i] = 0x8
+		dAtA[i] = 0xa
 	}
 	return len(dAtA) - i, nil
 }
 
-func (m *UpdateGraphQLSchemaResponse) Marshal() (dAtA []byte, err error) {
+func (m *FacetParam) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -10523,12 +11000,12 @@ func (m *UpdateGraphQLSchemaResponse) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *UpdateGraphQLSchemaResponse) MarshalTo(dAtA []byte) (int, error) {
+func (m *FacetParam) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *UpdateGraphQLSchemaResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *FacetParam) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
@@ -10537,1744 +11014,2594 @@ func (m *UpdateGraphQLSchemaResponse) MarshalToSizedBuffer(dAtA []byte) (int, er
 		i -= len(m.XXX_unrecognized)
 		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if m.Uid != 0 {
-		i = encodeVarintPb(dAtA, i, uint64(m.Uid))
+	if len(m.Alias) > 0 {
+		i -= len(m.Alias)
+		copy(dAtA[i:], m.Alias)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Alias)))
 		i--
-		dAtA[i] = 0x8
+		dAtA[i] = 0x12
+	}
+	if len(m.Key) > 0 {
+		i -= len(m.Key)
+		copy(dAtA[i:], m.Key)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Key)))
+		i--
+		dAtA[i] = 0xa
 	}
 	return len(dAtA) - i, nil
 }
 
-func encodeVarintPb(dAtA []byte, offset int, v uint64) int {
-	offset -= sovPb(v)
-	base := offset
-	for v >= 1<<7 {
-		dAtA[offset] = uint8(v&0x7f | 0x80)
-		v >>= 7
-		offset++
+func (m *FacetParams) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
-	dAtA[offset] = uint8(v)
-	return base
+	return dAtA[:n], nil
 }
-func (m *List) Size() (n int) {
-	if m == nil {
-		return 0
-	}
-	var l int
-	_ = l
-	if len(m.Uids) > 0 {
-		n += 1 + sovPb(uint64(len(m.Uids)*8)) + len(m.Uids)*8
-	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
-	}
-	return n
+
+func (m *FacetParams) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *TaskValue) Size() (n int) {
-	if m == nil {
-		return 0
-	}
+func (m *FacetParams) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	l = len(m.Val)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if m.ValType != 0 {
-		n += 1 + sovPb(uint64(m.ValType))
+	if len(m.Param) > 0 {
+		for iNdEx := len(m.Param) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Param[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintPb(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x12
+		}
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if m.AllKeys {
+		i--
+		if m.AllKeys {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x8
 	}
-	return n
+	return len(dAtA) - i, nil
 }
 
-func (m *SrcFunction) Size() (n int) {
-	if m == nil {
-		return 0
+func (m *Facets) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
+	return dAtA[:n], nil
+}
+
+func (m *Facets) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Facets) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	l = len(m.Name)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if len(m.Args) > 0 {
-		for _, s := range m.Args {
-			l = len(s)
-			n += 1 + l + sovPb(uint64(l))
+	if len(m.Facets) > 0 {
+		for iNdEx := len(m.Facets) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Facets[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintPb(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0xa
 		}
 	}
-	if m.IsCount {
-		n += 2
-	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
-	}
-	return n
+	return len(dAtA) - i, nil
 }
 
-func (m *Query) Size() (n int) {
-	if m == nil {
-		return 0
+func (m *FacetsList) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
+	return dAtA[:n], nil
+}
+
+func (m *FacetsList) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *FacetsList) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	l = len(m.Attr)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if len(m.Langs) > 0 {
-		for _, s := range m.Langs {
-			l = len(s)
-			n += 1 + l + sovPb(uint64(l))
+	if len(m.FacetsList) > 0 {
+		for iNdEx := len(m.FacetsList) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.FacetsList[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintPb(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0xa
 		}
 	}
-	if m.AfterUid != 0 {
-		n += 9
-	}
-	if m.DoCount {
-		n += 2
-	}
-	if m.UidList != nil {
-		l = m.UidList.Size()
-		n += 1 + l + sovPb(uint64(l))
-	}
-	if m.SrcFunc != nil {
-		l = m.SrcFunc.Size()
-		n += 1 + l + sovPb(uint64(l))
-	}
-	if m.Reverse {
-		n += 2
-	}
-	if m.FacetParam != nil {
-		l = m.FacetParam.Size()
-		n += 1 + l + sovPb(uint64(l))
-	}
-	if m.FacetsFilter != nil {
-		l = m.FacetsFilter.Size()
-		n += 1 + l + sovPb(uint64(l))
-	}
-	if m.ExpandAll {
-		n += 2
-	}
-	if m.ReadTs != 0 {
-		n += 1 + sovPb(uint64(m.ReadTs))
-	}
-	if m.Cache != 0 {
-		n += 1 + sovPb(uint64(m.Cache))
-	}
-	if m.First != 0 {
-		n += 1 + sovPb(uint64(m.First))
-	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
-	}
-	return n
+	return len(dAtA) - i, nil
 }
 
-func (m *ValueList) Size() (n int) {
-	if m == nil {
-		return 0
+func (m *Function) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
+	return dAtA[:n], nil
+}
+
+func (m *Function) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Function) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	if len(m.Values) > 0 {
-		for _, e := range m.Values {
-			l = e.Size()
-			n += 1 + l + sovPb(uint64(l))
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if len(m.Args) > 0 {
+		for iNdEx := len(m.Args) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Args[iNdEx])
+			copy(dAtA[i:], m.Args[iNdEx])
+			i = encodeVarintPb(dAtA, i, uint64(len(m.Args[iNdEx])))
+			i--
+			dAtA[i] = 0x1a
 		}
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if len(m.Key) > 0 {
+		i -= len(m.Key)
+		copy(dAtA[i:], m.Key)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Key)))
+		i--
+		dAtA[i] = 0x12
 	}
-	return n
+	if len(m.Name) > 0 {
+		i -= len(m.Name)
+		copy(dAtA[i:], m.Name)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Name)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
 }
 
-func (m *LangList) Size() (n int) {
-	if m == nil {
-		return 0
+func (m *FilterTree) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
+	return dAtA[:n], nil
+}
+
+func (m *FilterTree) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *FilterTree) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	if len(m.Lang) > 0 {
-		for _, s := range m.Lang {
-			l = len(s)
-			n += 1 + l + sovPb(uint64(l))
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if m.Func != nil {
+		{
+			size, err := m.Func.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintPb(dAtA, i, uint64(size))
 		}
+		i--
+		dAtA[i] = 0x1a
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if len(m.Children) > 0 {
+		for iNdEx := len(m.Children) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Children[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintPb(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x12
+		}
 	}
-	return n
+	if len(m.Op) > 0 {
+		i -= len(m.Op)
+		copy(dAtA[i:], m.Op)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Op)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
 }
 
-func (m *Result) Size() (n int) {
-	if m == nil {
-		return 0
+func (m *SchemaRequest) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
+	return dAtA[:n], nil
+}
+
+func (m *SchemaRequest) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *SchemaRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	if len(m.UidMatrix) > 0 {
-		for _, e := range m.UidMatrix {
-			l = e.Size()
-			n += 1 + l + sovPb(uint64(l))
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if len(m.Types) > 0 {
+		for iNdEx := len(m.Types) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Types[iNdEx])
+			copy(dAtA[i:], m.Types[iNdEx])
+			i = encodeVarintPb(dAtA, i, uint64(len(m.Types[iNdEx])))
+			i--
+			dAtA[i] = 0x22
 		}
 	}
-	if len(m.ValueMatrix) > 0 {
-		for _, e := range m.ValueMatrix {
-			l = e.Size()
-			n += 1 + l + sovPb(uint64(l))
+	if len(m.Fields) > 0 {
+		for iNdEx := len(m.Fields) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Fields[iNdEx])
+			copy(dAtA[i:], m.Fields[iNdEx])
+			i = encodeVarintPb(dAtA, i, uint64(len(m.Fields[iNdEx])))
+			i--
+			dAtA[i] = 0x1a
 		}
 	}
-	if len(m.Counts) > 0 {
-		l = 0
-		for _, e := range m.Counts {
-			l += sovPb(uint64(e))
+	if len(m.Predicates) > 0 {
+		for iNdEx := len(m.Predicates) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Predicates[iNdEx])
+			copy(dAtA[i:], m.Predicates[iNdEx])
+			i = encodeVarintPb(dAtA, i, uint64(len(m.Predicates[iNdEx])))
+			i--
+			dAtA[i] = 0x12
 		}
-		n += 1 + sovPb(uint64(l)) + l
 	}
-	if m.IntersectDest {
-		n += 2
-	}
-	if len(m.FacetMatrix) > 0 {
-		for _, e := range m.FacetMatrix {
-			l = e.Size()
-			n += 1 + l + sovPb(uint64(l))
-		}
-	}
-	if len(m.LangMatrix) > 0 {
-		for _, e := range m.LangMatrix {
-			l = e.Size()
-			n += 1 + l + sovPb(uint64(l))
-		}
-	}
-	if m.List {
-		n += 2
-	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if m.GroupId != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.GroupId))
+		i--
+		dAtA[i] = 0x8
 	}
-	return n
+	return len(dAtA) - i, nil
 }
 
-func (m *Order) Size() (n int) {
-	if m == nil {
-		return 0
-	}
-	var l int
-	_ = l
-	l = len(m.Attr)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
-	}
-	if m.Desc {
-		n += 2
-	}
-	if len(m.Langs) > 0 {
-		for _, s := range m.Langs {
-			l = len(s)
-			n += 1 + l + sovPb(uint64(l))
-		}
-	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+func (m *SchemaNode) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
-	return n
+	return dAtA[:n], nil
 }
 
-func (m *SortMessage) Size() (n int) {
-	if m == nil {
-		return 0
-	}
-	var l int
-	_ = l
-	if len(m.Order) > 0 {
-		for _, e := range m.Order {
-			l = e.Size()
-			n += 1 + l + sovPb(uint64(l))
-		}
-	}
-	if len(m.UidMatrix) > 0 {
-		for _, e := range m.UidMatrix {
-			l = e.Size()
-			n += 1 + l + sovPb(uint64(l))
-		}
-	}
-	if m.Count != 0 {
-		n += 1 + sovPb(uint64(m.Count))
-	}
-	if m.Offset != 0 {
-		n += 1 + sovPb(uint64(m.Offset))
-	}
-	if m.ReadTs != 0 {
-		n += 1 + sovPb(uint64(m.ReadTs))
-	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
-	}
-	return n
+func (m *SchemaNode) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *SortResult) Size() (n int) {
-	if m == nil {
-		return 0
-	}
+func (m *SchemaNode) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	if len(m.UidMatrix) > 0 {
-		for _, e := range m.UidMatrix {
-			l = e.Size()
-			n += 1 + l + sovPb(uint64(l))
-		}
-	}
 	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
-	}
-	return n
-}
-
-func (m *RaftContext) Size() (n int) {
-	if m == nil {
-		return 0
-	}
-	var l int
-	_ = l
-	if m.Id != 0 {
-		n += 9
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if m.Group != 0 {
-		n += 1 + sovPb(uint64(m.Group))
+	if len(m.Compression) > 0 {
+		i -= len(m.Compression)
+		copy(dAtA[i:], m.Compression)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Compression)))
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0xd2
 	}
-	l = len(m.Addr)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
+	if len(m.Tier) > 0 {
+		i -= len(m.Tier)
+		copy(dAtA[i:], m.Tier)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Tier)))
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0xca
 	}
-	if m.SnapshotTs != 0 {
-		n += 1 + sovPb(uint64(m.SnapshotTs))
+	if len(m.References) > 0 {
+		i -= len(m.References)
+		copy(dAtA[i:], m.References)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.References)))
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0xc2
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if len(m.CheckPattern) > 0 {
+		i -= len(m.CheckPattern)
+		copy(dAtA[i:], m.CheckPattern)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.CheckPattern)))
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0xba
 	}
-	return n
-}
-
-func (m *Member) Size() (n int) {
-	if m == nil {
-		return 0
+	if m.CheckMaxLength != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.CheckMaxLength))
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0xb0
 	}
-	var l int
-	_ = l
-	if m.Id != 0 {
-		n += 9
+	if len(m.CheckMax) > 0 {
+		i -= len(m.CheckMax)
+		copy(dAtA[i:], m.CheckMax)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.CheckMax)))
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0xaa
 	}
-	if m.GroupId != 0 {
-		n += 1 + sovPb(uint64(m.GroupId))
+	if len(m.CheckMin) > 0 {
+		i -= len(m.CheckMin)
+		copy(dAtA[i:], m.CheckMin)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.CheckMin)))
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0xa2
 	}
-	l = len(m.Addr)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
+	if len(m.EnumValues) > 0 {
+		for iNdEx := len(m.EnumValues) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.EnumValues[iNdEx])
+			copy(dAtA[i:], m.EnumValues[iNdEx])
+			i = encodeVarintPb(dAtA, i, uint64(len(m.EnumValues[iNdEx])))
+			i--
+			dAtA[i] = 0x1
+			i--
+			dAtA[i] = 0x9a
+		}
 	}
-	if m.Leader {
-		n += 2
+	if len(m.Encoding) > 0 {
+		i -= len(m.Encoding)
+		copy(dAtA[i:], m.Encoding)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Encoding)))
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0x92
 	}
-	if m.AmDead {
-		n += 2
+	if len(m.IndexWhere) > 0 {
+		i -= len(m.IndexWhere)
+		copy(dAtA[i:], m.IndexWhere)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.IndexWhere)))
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0x8a
 	}
-	if m.LastUpdate != 0 {
-		n += 1 + sovPb(uint64(m.LastUpdate))
+	if m.DefaultVirtual {
+		i--
+		if m.DefaultVirtual {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0x80
 	}
-	if m.ClusterInfoOnly {
-		n += 2
+	if len(m.DefaultValue) > 0 {
+		i -= len(m.DefaultValue)
+		copy(dAtA[i:], m.DefaultValue)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.DefaultValue)))
+		i--
+		dAtA[i] = 0x7a
 	}
-	if m.ForceGroupId {
-		n += 2
+	if len(m.RenamedFrom) > 0 {
+		i -= len(m.RenamedFrom)
+		copy(dAtA[i:], m.RenamedFrom)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.RenamedFrom)))
+		i--
+		dAtA[i] = 0x72
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if len(m.TtlFrom) > 0 {
+		i -= len(m.TtlFrom)
+		copy(dAtA[i:], m.TtlFrom)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.TtlFrom)))
+		i--
+		dAtA[i] = 0x6a
 	}
-	return n
-}
-
-func (m *Group) Size() (n int) {
-	if m == nil {
-		return 0
+	if len(m.Ttl) > 0 {
+		i -= len(m.Ttl)
+		copy(dAtA[i:], m.Ttl)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Ttl)))
+		i--
+		dAtA[i] = 0x62
 	}
-	var l int
-	_ = l
-	if len(m.Members) > 0 {
-		for k, v := range m.Members {
-			_ = k
-			_ = v
-			l = 0
-			if v != nil {
-				l = v.Size()
-				l += 1 + sovPb(uint64(l))
-			}
-			mapEntrySize := 1 + sovPb(uint64(k)) + l
-			n += mapEntrySize + 1 + sovPb(uint64(mapEntrySize))
+	if m.Unique {
+		i--
+		if m.Unique {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
 		}
+		i--
+		dAtA[i] = 0x58
 	}
-	if len(m.Tablets) > 0 {
-		for k, v := range m.Tablets {
-			_ = k
-			_ = v
-			l = 0
-			if v != nil {
-				l = v.Size()
-				l += 1 + sovPb(uint64(l))
-			}
-			mapEntrySize := 1 + len(k) + sovPb(uint64(len(k))) + l
-			n += mapEntrySize + 1 + sovPb(uint64(mapEntrySize))
+	if m.NoConflict {
+		i--
+		if m.NoConflict {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
 		}
+		i--
+		dAtA[i] = 0x50
 	}
-	if m.SnapshotTs != 0 {
-		n += 1 + sovPb(uint64(m.SnapshotTs))
+	if m.Lang {
+		i--
+		if m.Lang {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x48
 	}
-	if m.Checksum != 0 {
-		n += 1 + sovPb(uint64(m.Checksum))
+	if m.Upsert {
+		i--
+		if m.Upsert {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x40
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if m.List {
+		i--
+		if m.List {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x38
 	}
-	return n
-}
-
-func (m *License) Size() (n int) {
-	if m == nil {
-		return 0
+	if m.Count {
+		i--
+		if m.Count {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x30
 	}
-	var l int
-	_ = l
-	l = len(m.User)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
+	if m.Reverse {
+		i--
+		if m.Reverse {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x28
 	}
-	if m.MaxNodes != 0 {
-		n += 1 + sovPb(uint64(m.MaxNodes))
+	if len(m.Tokenizer) > 0 {
+		for iNdEx := len(m.Tokenizer) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Tokenizer[iNdEx])
+			copy(dAtA[i:], m.Tokenizer[iNdEx])
+			i = encodeVarintPb(dAtA, i, uint64(len(m.Tokenizer[iNdEx])))
+			i--
+			dAtA[i] = 0x22
+		}
 	}
-	if m.ExpiryTs != 0 {
-		n += 1 + sovPb(uint64(m.ExpiryTs))
+	if m.Index {
+		i--
+		if m.Index {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x18
 	}
-	if m.Enabled {
-		n += 2
+	if len(m.Type) > 0 {
+		i -= len(m.Type)
+		copy(dAtA[i:], m.Type)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Type)))
+		i--
+		dAtA[i] = 0x12
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if len(m.Predicate) > 0 {
+		i -= len(m.Predicate)
+		copy(dAtA[i:], m.Predicate)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Predicate)))
+		i--
+		dAtA[i] = 0xa
 	}
-	return n
+	return len(dAtA) - i, nil
 }
 
-func (m *ZeroProposal) Size() (n int) {
-	if m == nil {
-		return 0
+func (m *SchemaResult) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
+	return dAtA[:n], nil
+}
+
+func (m *SchemaResult) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *SchemaResult) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	if len(m.SnapshotTs) > 0 {
-		for k, v := range m.SnapshotTs {
-			_ = k
-			_ = v
-			mapEntrySize := 1 + sovPb(uint64(k)) + 1 + sovPb(uint64(v))
-			n += mapEntrySize + 1 + sovPb(uint64(mapEntrySize))
-		}
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if m.Member != nil {
-		l = m.Member.Size()
-		n += 1 + l + sovPb(uint64(l))
+	if len(m.Schema) > 0 {
+		for iNdEx := len(m.Schema) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Schema[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintPb(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0xa
+		}
 	}
-	if m.Tablet != nil {
-		l = m.Tablet.Size()
-		n += 1 + l + sovPb(uint64(l))
+	return len(dAtA) - i, nil
+}
+
+func (m *SchemaUpdate) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
-	if m.MaxLeaseId != 0 {
-		n += 1 + sovPb(uint64(m.MaxLeaseId))
+	return dAtA[:n], nil
+}
+
+func (m *SchemaUpdate) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *SchemaUpdate) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if m.MaxTxnTs != 0 {
-		n += 1 + sovPb(uint64(m.MaxTxnTs))
+	if len(m.CompressionDictionaries) > 0 {
+		for iNdEx := len(m.CompressionDictionaries) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.CompressionDictionaries[iNdEx])
+			copy(dAtA[i:], m.CompressionDictionaries[iNdEx])
+			i = encodeVarintPb(dAtA, i, uint64(len(m.CompressionDictionaries[iNdEx])))
+			i--
+			dAtA[i] = 0x2
+			i--
+			dAtA[i] = 0x92
+		}
 	}
-	if m.MaxRaftId != 0 {
-		n += 1 + sovPb(uint64(m.MaxRaftId))
+	if len(m.Compression) > 0 {
+		i -= len(m.Compression)
+		copy(dAtA[i:], m.Compression)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Compression)))
+		i--
+		dAtA[i] = 0x2
+		i--
+		dAtA[i] = 0x8a
 	}
-	if m.Txn != nil {
-		l = m.Txn.Size()
-		n += 1 + l + sovPb(uint64(l))
+	if len(m.TierObject) > 0 {
+		i -= len(m.TierObject)
+		copy(dAtA[i:], m.TierObject)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.TierObject)))
+		i--
+		dAtA[i] = 0x2
+		i--
+		dAtA[i] = 0x82
 	}
-	l = len(m.Key)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
+	if len(m.Tier) > 0 {
+		i -= len(m.Tier)
+		copy(dAtA[i:], m.Tier)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Tier)))
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0xfa
 	}
-	l = len(m.Cid)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
+	if len(m.References) > 0 {
+		i -= len(m.References)
+		copy(dAtA[i:], m.References)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.References)))
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0xf2
 	}
-	if m.License != nil {
-		l = m.License.Size()
-		n += 1 + l + sovPb(uint64(l))
+	if len(m.CheckPattern) > 0 {
+		i -= len(m.CheckPattern)
+		copy(dAtA[i:], m.CheckPattern)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.CheckPattern)))
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0xea
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if m.CheckMaxLength != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.CheckMaxLength))
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0xe0
 	}
-	return n
-}
-
-func (m *MembershipState) Size() (n int) {
-	if m == nil {
-		return 0
+	if len(m.CheckMax) > 0 {
+		i -= len(m.CheckMax)
+		copy(dAtA[i:], m.CheckMax)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.CheckMax)))
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0xda
 	}
-	var l int
-	_ = l
-	if m.Counter != 0 {
-		n += 1 + sovPb(uint64(m.Counter))
+	if len(m.CheckMin) > 0 {
+		i -= len(m.CheckMin)
+		copy(dAtA[i:], m.CheckMin)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.CheckMin)))
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0xd2
 	}
-	if len(m.Groups) > 0 {
-		for k, v := range m.Groups {
-			_ = k
-			_ = v
-			l = 0
-			if v != nil {
-				l = v.Size()
-				l += 1 + sovPb(uint64(l))
-			}
-			mapEntrySize := 1 + sovPb(uint64(k)) + l
-			n += mapEntrySize + 1 + sovPb(uint64(mapEntrySize))
+	if len(m.EnumValues) > 0 {
+		for iNdEx := len(m.EnumValues) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.EnumValues[iNdEx])
+			copy(dAtA[i:], m.EnumValues[iNdEx])
+			i = encodeVarintPb(dAtA, i, uint64(len(m.EnumValues[iNdEx])))
+			i--
+			dAtA[i] = 0x1
+			i--
+			dAtA[i] = 0xca
 		}
 	}
-	if len(m.Zeros) > 0 {
-		for k, v := range m.Zeros {
-			_ = k
-			_ = v
-			l = 0
-			if v != nil {
-				l = v.Size()
-				l += 1 + sovPb(uint64(l))
-			}
-			mapEntrySize := 1 + sovPb(uint64(k)) + l
-			n += mapEntrySize + 1 + sovPb(uint64(mapEntrySize))
+	if m.EncodingBaseSet {
+		i--
+		if m.EncodingBaseSet {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
 		}
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0xc0
 	}
-	if m.MaxLeaseId != 0 {
-		n += 1 + sovPb(uint64(m.MaxLeaseId))
+	if m.EncodingBase != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.EncodingBase))
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0xb8
 	}
-	if m.MaxTxnTs != 0 {
-		n += 1 + sovPb(uint64(m.MaxTxnTs))
-	}
-	if m.MaxRaftId != 0 {
-		n += 1 + sovPb(uint64(m.MaxRaftId))
-	}
-	if len(m.Removed) > 0 {
-		for _, e := range m.Removed {
-			l = e.Size()
-			n += 1 + l + sovPb(uint64(l))
+	if len(m.Dictionary) > 0 {
+		for iNdEx := len(m.Dictionary) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Dictionary[iNdEx])
+			copy(dAtA[i:], m.Dictionary[iNdEx])
+			i = encodeVarintPb(dAtA, i, uint64(len(m.Dictionary[iNdEx])))
+			i--
+			dAtA[i] = 0x1
+			i--
+			dAtA[i] = 0xb2
 		}
 	}
-	l = len(m.Cid)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
-	}
-	if m.License != nil {
-		l = m.License.Size()
-		n += 1 + l + sovPb(uint64(l))
-	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if len(m.Encoding) > 0 {
+		i -= len(m.Encoding)
+		copy(dAtA[i:], m.Encoding)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Encoding)))
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0xaa
 	}
-	return n
-}
-
-func (m *ConnectionState) Size() (n int) {
-	if m == nil {
-		return 0
+	if len(m.IndexWhere) > 0 {
+		i -= len(m.IndexWhere)
+		copy(dAtA[i:], m.IndexWhere)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.IndexWhere)))
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0xa2
 	}
-	var l int
-	_ = l
-	if m.Member != nil {
-		l = m.Member.Size()
-		n += 1 + l + sovPb(uint64(l))
+	if m.DefaultVirtual {
+		i--
+		if m.DefaultVirtual {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0x98
 	}
-	if m.State != nil {
-		l = m.State.Size()
-		n += 1 + l + sovPb(uint64(l))
+	if len(m.DefaultValue) > 0 {
+		i -= len(m.DefaultValue)
+		copy(dAtA[i:], m.DefaultValue)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.DefaultValue)))
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0x92
 	}
-	if m.MaxPending != 0 {
-		n += 1 + sovPb(uint64(m.MaxPending))
+	if len(m.RenamedFrom) > 0 {
+		i -= len(m.RenamedFrom)
+		copy(dAtA[i:], m.RenamedFrom)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.RenamedFrom)))
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0x8a
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if len(m.TtlFrom) > 0 {
+		i -= len(m.TtlFrom)
+		copy(dAtA[i:], m.TtlFrom)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.TtlFrom)))
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0x82
 	}
-	return n
-}
-
-func (m *HealthInfo) Size() (n int) {
-	if m == nil {
-		return 0
+	if len(m.Ttl) > 0 {
+		i -= len(m.Ttl)
+		copy(dAtA[i:], m.Ttl)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Ttl)))
+		i--
+		dAtA[i] = 0x7a
 	}
-	var l int
-	_ = l
-	l = len(m.Instance)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
+	if m.Unique {
+		i--
+		if m.Unique {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x70
 	}
-	l = len(m.Address)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
+	if m.NoConflict {
+		i--
+		if m.NoConflict {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x68
 	}
-	l = len(m.Status)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
+	if len(m.ObjectTypeName) > 0 {
+		i -= len(m.ObjectTypeName)
+		copy(dAtA[i:], m.ObjectTypeName)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.ObjectTypeName)))
+		i--
+		dAtA[i] = 0x62
 	}
-	l = len(m.Group)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
+	if m.NonNullableList {
+		i--
+		if m.NonNullableList {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x58
 	}
-	l = len(m.Version)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
+	if m.NonNullable {
+		i--
+		if m.NonNullable {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x50
 	}
-	if m.Uptime != 0 {
-		n += 1 + sovPb(uint64(m.Uptime))
+	if m.Lang {
+		i--
+		if m.Lang {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x48
 	}
-	if m.LastEcho != 0 {
-		n += 1 + sovPb(uint64(m.LastEcho))
+	if m.Upsert {
+		i--
+		if m.Upsert {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x40
 	}
-	if len(m.Ongoing) > 0 {
-		for _, s := range m.Ongoing {
-			l = len(s)
-			n += 1 + l + sovPb(uint64(l))
+	if m.List {
+		i--
+		if m.List {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
 		}
+		i--
+		dAtA[i] = 0x30
 	}
-	if len(m.Indexing) > 0 {
-		for _, s := range m.Indexing {
-			l = len(s)
-			n += 1 + l + sovPb(uint64(l))
+	if m.Count {
+		i--
+		if m.Count {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
 		}
+		i--
+		dAtA[i] = 0x28
 	}
-	if len(m.EeFeatures) > 0 {
-		for _, s := range m.EeFeatures {
-			l = len(s)
-			n += 1 + l + sovPb(uint64(l))
+	if len(m.Tokenizer) > 0 {
+		for iNdEx := len(m.Tokenizer) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Tokenizer[iNdEx])
+			copy(dAtA[i:], m.Tokenizer[iNdEx])
+			i = encodeVarintPb(dAtA, i, uint64(len(m.Tokenizer[iNdEx])))
+			i--
+			dAtA[i] = 0x22
 		}
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if m.Directive != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.Directive))
+		i--
+		dAtA[i] = 0x18
 	}
-	return n
+	if m.ValueType != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.ValueType))
+		i--
+		dAtA[i] = 0x10
+	}
+	if len(m.Predicate) > 0 {
+		i -= len(m.Predicate)
+		copy(dAtA[i:], m.Predicate)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Predicate)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
 }
 
-func (m *Tablet) Size() (n int) {
-	if m == nil {
-		return 0
+func (m *TypeUpdate) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
+	return dAtA[:n], nil
+}
+
+func (m *TypeUpdate) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *TypeUpdate) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	if m.GroupId != 0 {
-		n += 1 + sovPb(uint64(m.GroupId))
-	}
-	l = len(m.Predicate)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if m.Force {
-		n += 2
+	if len(m.TtlFrom) > 0 {
+		i -= len(m.TtlFrom)
+		copy(dAtA[i:], m.TtlFrom)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.TtlFrom)))
+		i--
+		dAtA[i] = 0x22
 	}
-	if m.Space != 0 {
-		n += 1 + sovPb(uint64(m.Space))
+	if len(m.Ttl) > 0 {
+		i -= len(m.Ttl)
+		copy(dAtA[i:], m.Ttl)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Ttl)))
+		i--
+		dAtA[i] = 0x1a
 	}
-	if m.Remove {
-		n += 2
+	if len(m.Fields) > 0 {
+		for iNdEx := len(m.Fields) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Fields[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintPb(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x12
+		}
 	}
-	if m.ReadOnly {
-		n += 2
-	}
-	if m.MoveTs != 0 {
-		n += 1 + sovPb(uint64(m.MoveTs))
-	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if len(m.TypeName) > 0 {
+		i -= len(m.TypeName)
+		copy(dAtA[i:], m.TypeName)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.TypeName)))
+		i--
+		dAtA[i] = 0xa
 	}
-	return n
+	return len(dAtA) - i, nil
 }
 
-func (m *DirectedEdge) Size() (n int) {
-	if m == nil {
-		return 0
+func (m *MapHeader) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
+	return dAtA[:n], nil
+}
+
+func (m *MapHeader) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *MapHeader) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	if m.Entity != 0 {
-		n += 9
-	}
-	l = len(m.Attr)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
-	}
-	l = len(m.Value)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
-	}
-	if m.ValueType != 0 {
-		n += 1 + sovPb(uint64(m.ValueType))
-	}
-	if m.ValueId != 0 {
-		n += 9
-	}
-	l = len(m.Label)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
-	}
-	l = len(m.Lang)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
-	}
-	if m.Op != 0 {
-		n += 1 + sovPb(uint64(m.Op))
-	}
-	if len(m.Facets) > 0 {
-		for _, e := range m.Facets {
-			l = e.Size()
-			n += 1 + l + sovPb(uint64(l))
-		}
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if len(m.AllowedPreds) > 0 {
-		for _, s := range m.AllowedPreds {
-			l = len(s)
-			n += 1 + l + sovPb(uint64(l))
+	if len(m.PartitionKeys) > 0 {
+		for iNdEx := len(m.PartitionKeys) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.PartitionKeys[iNdEx])
+			copy(dAtA[i:], m.PartitionKeys[iNdEx])
+			i = encodeVarintPb(dAtA, i, uint64(len(m.PartitionKeys[iNdEx])))
+			i--
+			dAtA[i] = 0xa
 		}
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
-	}
-	return n
+	return len(dAtA) - i, nil
 }
 
-func (m *Mutations) Size() (n int) {
-	if m == nil {
-		return 0
+func (m *MovePredicatePayload) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
+	return dAtA[:n], nil
+}
+
+func (m *MovePredicatePayload) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *MovePredicatePayload) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	if m.GroupId != 0 {
-		n += 1 + sovPb(uint64(m.GroupId))
-	}
-	if m.StartTs != 0 {
-		n += 1 + sovPb(uint64(m.StartTs))
-	}
-	if len(m.Edges) > 0 {
-		for _, e := range m.Edges {
-			l = e.Size()
-			n += 1 + l + sovPb(uint64(l))
-		}
-	}
-	if len(m.Schema) > 0 {
-		for _, e := range m.Schema {
-			l = e.Size()
-			n += 1 + l + sovPb(uint64(l))
-		}
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if len(m.Types) > 0 {
-		for _, e := range m.Types {
-			l = e.Size()
-			n += 1 + l + sovPb(uint64(l))
-		}
+	if m.ExpectedChecksum != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.ExpectedChecksum))
+		i--
+		dAtA[i] = 0x28
 	}
-	if m.DropOp != 0 {
-		n += 1 + sovPb(uint64(m.DropOp))
+	if m.TxnTs != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.TxnTs))
+		i--
+		dAtA[i] = 0x20
 	}
-	l = len(m.DropValue)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
+	if m.DestGid != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.DestGid))
+		i--
+		dAtA[i] = 0x18
 	}
-	if m.Metadata != nil {
-		l = m.Metadata.Size()
-		n += 1 + l + sovPb(uint64(l))
+	if m.SourceGid != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.SourceGid))
+		i--
+		dAtA[i] = 0x10
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if len(m.Predicate) > 0 {
+		i -= len(m.Predicate)
+		copy(dAtA[i:], m.Predicate)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Predicate)))
+		i--
+		dAtA[i] = 0xa
 	}
-	return n
+	return len(dAtA) - i, nil
 }
 
-func (m *Metadata) Size() (n int) {
-	if m == nil {
-		return 0
-	}
-	var l int
-	_ = l
-	if len(m.PredHints) > 0 {
-		for k, v := range m.PredHints {
-			_ = k
-			_ = v
-			mapEntrySize := 1 + len(k) + sovPb(uint64(len(k))) + 1 + sovPb(uint64(v))
-			n += mapEntrySize + 1 + sovPb(uint64(mapEntrySize))
-		}
-	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+func (m *TxnStatus) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
-	return n
+	return dAtA[:n], nil
 }
 
-func (m *Snapshot) Size() (n int) {
-	if m == nil {
-		return 0
-	}
+func (m *TxnStatus) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *TxnStatus) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	if m.Context != nil {
-		l = m.Context.Size()
-		n += 1 + l + sovPb(uint64(l))
-	}
-	if m.Index != 0 {
-		n += 1 + sovPb(uint64(m.Index))
-	}
-	if m.ReadTs != 0 {
-		n += 1 + sovPb(uint64(m.ReadTs))
-	}
-	if m.Done {
-		n += 2
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if m.SinceTs != 0 {
-		n += 1 + sovPb(uint64(m.SinceTs))
+	if m.CommitTs != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.CommitTs))
+		i--
+		dAtA[i] = 0x10
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if m.StartTs != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.StartTs))
+		i--
+		dAtA[i] = 0x8
 	}
-	return n
+	return len(dAtA) - i, nil
 }
 
-func (m *RestoreRequest) Size() (n int) {
-	if m == nil {
-		return 0
+func (m *OracleDelta) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
+	return dAtA[:n], nil
+}
+
+func (m *OracleDelta) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *OracleDelta) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	if m.GroupId != 0 {
-		n += 1 + sovPb(uint64(m.GroupId))
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if m.RestoreTs != 0 {
-		n += 1 + sovPb(uint64(m.RestoreTs))
-	}
-	l = len(m.Location)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
-	}
-	l = len(m.BackupId)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
-	}
-	l = len(m.AccessKey)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
-	}
-	l = len(m.SecretKey)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
-	}
-	l = len(m.SessionToken)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
-	}
-	if m.Anonymous {
-		n += 2
-	}
-	l = len(m.EncryptionKeyFile)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
-	}
-	l = len(m.VaultAddr)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
-	}
-	l = len(m.VaultRoleidFile)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
-	}
-	l = len(m.VaultSecretidFile)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
-	}
-	l = len(m.VaultPath)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
-	}
-	l = len(m.VaultField)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
-	}
-	l = len(m.VaultFormat)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
+	if len(m.GroupChecksums) > 0 {
+		for k := range m.GroupChecksums {
+			v := m.GroupChecksums[k]
+			baseI := i
+			i = encodeVarintPb(dAtA, i, uint64(v))
+			i--
+			dAtA[i] = 0x10
+			i = encodeVarintPb(dAtA, i, uint64(k))
+			i--
+			dAtA[i] = 0x8
+			i = encodeVarintPb(dAtA, i, uint64(baseI-i))
+			i--
+			dAtA[i] = 0x1a
+		}
 	}
-	if m.BackupNum != 0 {
-		n += 2 + sovPb(uint64(m.BackupNum))
+	if m.MaxAssigned != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.MaxAssigned))
+		i--
+		dAtA[i] = 0x10
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if len(m.Txns) > 0 {
+		for iNdEx := len(m.Txns) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Txns[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintPb(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0xa
+		}
 	}
-	return n
+	return len(dAtA) - i, nil
 }
 
-func (m *Proposal) Size() (n int) {
-	if m == nil {
-		return 0
+func (m *TxnTimestamps) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
+	return dAtA[:n], nil
+}
+
+func (m *TxnTimestamps) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *TxnTimestamps) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	if m.Mutations != nil {
-		l = m.Mutations.Size()
-		n += 1 + l + sovPb(uint64(l))
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if len(m.Kv) > 0 {
-		for _, e := range m.Kv {
-			l = e.Size()
-			n += 1 + l + sovPb(uint64(l))
+	if len(m.Ts) > 0 {
+		dAtA30 := make([]byte, len(m.Ts)*10)
+		var j29 int
+		for _, num := range m.Ts {
+			for num >= 1<<7 {
+				dAtA30[j29] = uint8(uint64(num)&0x7f | 0x80)
+				num >>= 7
+				j29++
+			}
+			dAtA30[j29] = uint8(num)
+			j29++
 		}
+		i -= j29
+		copy(dAtA[i:], dAtA30[:j29])
+		i = encodeVarintPb(dAtA, i, uint64(j29))
+		i--
+		dAtA[i] = 0xa
 	}
-	if m.State != nil {
-		l = m.State.Size()
-		n += 1 + l + sovPb(uint64(l))
-	}
-	l = len(m.CleanPredicate)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
-	}
-	l = len(m.Key)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
-	}
-	if m.Delta != nil {
-		l = m.Delta.Size()
-		n += 1 + l + sovPb(uint64(l))
-	}
-	if m.Snapshot != nil {
-		l = m.Snapshot.Size()
-		n += 1 + l + sovPb(uint64(l))
-	}
-	if m.Index != 0 {
-		n += 1 + sovPb(uint64(m.Index))
-	}
-	if m.ExpectedChecksum != 0 {
-		n += 1 + sovPb(uint64(m.ExpectedChecksum))
-	}
-	if m.Restore != nil {
-		l = m.Restore.Size()
-		n += 1 + l + sovPb(uint64(l))
-	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
-	}
-	return n
+	return len(dAtA) - i, nil
 }
 
-func (m *KVS) Size() (n int) {
-	if m == nil {
-		return 0
+func (m *PeerResponse) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
+	return dAtA[:n], nil
+}
+
+func (m *PeerResponse) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *PeerResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	if len(m.Kv) > 0 {
-		for _, e := range m.Kv {
-			l = e.Size()
-			n += 1 + l + sovPb(uint64(l))
-		}
-	}
-	if m.Done {
-		n += 2
-	}
-	if len(m.Predicates) > 0 {
-		for _, s := range m.Predicates {
-			l = len(s)
-			n += 1 + l + sovPb(uint64(l))
-		}
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if len(m.Types) > 0 {
-		for _, s := range m.Types {
-			l = len(s)
-			n += 1 + l + sovPb(uint64(l))
+	if m.Status {
+		i--
+		if m.Status {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
 		}
+		i--
+		dAtA[i] = 0x8
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
-	}
-	return n
+	return len(dAtA) - i, nil
 }
 
-func (m *Posting) Size() (n int) {
-	if m == nil {
-		return 0
+func (m *RaftBatch) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
+	return dAtA[:n], nil
+}
+
+func (m *RaftBatch) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *RaftBatch) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	if m.Uid != 0 {
-		n += 9
-	}
-	l = len(m.Value)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if m.ValType != 0 {
-		n += 1 + sovPb(uint64(m.ValType))
-	}
-	if m.PostingType != 0 {
-		n += 1 + sovPb(uint64(m.PostingType))
-	}
-	l = len(m.LangTag)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
-	}
-	l = len(m.Label)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
-	}
-	if len(m.Facets) > 0 {
-		for _, e := range m.Facets {
-			l = e.Size()
-			n += 1 + l + sovPb(uint64(l))
+	if m.Payload != nil {
+		{
+			size, err := m.Payload.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintPb(dAtA, i, uint64(size))
 		}
+		i--
+		dAtA[i] = 0x12
 	}
-	if m.Op != 0 {
-		n += 1 + sovPb(uint64(m.Op))
-	}
-	if m.StartTs != 0 {
-		n += 1 + sovPb(uint64(m.StartTs))
-	}
-	if m.CommitTs != 0 {
-		n += 1 + sovPb(uint64(m.CommitTs))
-	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if m.Context != nil {
+		{
+			size, err := m.Context.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintPb(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0xa
 	}
-	return n
+	return len(dAtA) - i, nil
 }
 
-func (m *UidBlock) Size() (n int) {
-	if m == nil {
-		return 0
+func (m *SubscriptionRequest) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
+	return dAtA[:n], nil
+}
+
+func (m *SubscriptionRequest) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *SubscriptionRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	if m.Base != 0 {
-		n += 1 + sovPb(uint64(m.Base))
-	}
-	l = len(m.Deltas)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
-	}
-	if m.NumUids != 0 {
-		n += 1 + sovPb(uint64(m.NumUids))
-	}
 	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	return n
+	if len(m.Prefixes) > 0 {
+		for iNdEx := len(m.Prefixes) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Prefixes[iNdEx])
+			copy(dAtA[i:], m.Prefixes[iNdEx])
+			i = encodeVarintPb(dAtA, i, uint64(len(m.Prefixes[iNdEx])))
+			i--
+			dAtA[i] = 0xa
+		}
+	}
+	return len(dAtA) - i, nil
 }
 
-func (m *UidPack) Size() (n int) {
-	if m == nil {
-		return 0
+func (m *SubscriptionResponse) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
+	return dAtA[:n], nil
+}
+
+func (m *SubscriptionResponse) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *SubscriptionResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	if m.BlockSize != 0 {
-		n += 1 + sovPb(uint64(m.BlockSize))
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if len(m.Blocks) > 0 {
-		for _, e := range m.Blocks {
-			l = e.Size()
-			n += 1 + l + sovPb(uint64(l))
+	if m.Kvs != nil {
+		{
+			size, err := m.Kvs.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintPb(dAtA, i, uint64(size))
 		}
+		i--
+		dAtA[i] = 0xa
 	}
-	if m.AllocRef != 0 {
-		n += 2 + sovPb(uint64(m.AllocRef))
-	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
-	}
-	return n
+	return len(dAtA) - i, nil
 }
 
-func (m *PostingList) Size() (n int) {
-	if m == nil {
-		return 0
+func (m *Num) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
+	return dAtA[:n], nil
+}
+
+func (m *Num) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Num) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	if m.Pack != nil {
-		l = m.Pack.Size()
-		n += 1 + l + sovPb(uint64(l))
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if len(m.Postings) > 0 {
-		for _, e := range m.Postings {
-			l = e.Size()
-			n += 1 + l + sovPb(uint64(l))
+	if m.Forwarded {
+		i--
+		if m.Forwarded {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
 		}
+		i--
+		dAtA[i] = 0x18
 	}
-	if m.CommitTs != 0 {
-		n += 1 + sovPb(uint64(m.CommitTs))
-	}
-	if len(m.Splits) > 0 {
-		l = 0
-		for _, e := range m.Splits {
-			l += sovPb(uint64(e))
+	if m.ReadOnly {
+		i--
+		if m.ReadOnly {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
 		}
-		n += 1 + sovPb(uint64(l)) + l
+		i--
+		dAtA[i] = 0x10
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if m.Val != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.Val))
+		i--
+		dAtA[i] = 0x8
 	}
-	return n
+	return len(dAtA) - i, nil
 }
 
-func (m *FacetParam) Size() (n int) {
-	if m == nil {
-		return 0
+func (m *AssignedIds) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
+	return dAtA[:n], nil
+}
+
+func (m *AssignedIds) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *AssignedIds) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	l = len(m.Key)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	l = len(m.Alias)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
+	if m.ReadOnly != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.ReadOnly))
+		i--
+		dAtA[i] = 0x28
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if m.EndId != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.EndId))
+		i--
+		dAtA[i] = 0x10
 	}
-	return n
+	if m.StartId != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.StartId))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
 }
 
-func (m *FacetParams) Size() (n int) {
-	if m == nil {
-		return 0
-	}
-	var l int
-	_ = l
-	if m.AllKeys {
-		n += 2
-	}
-	if len(m.Param) > 0 {
-		for _, e := range m.Param {
-			l = e.Size()
-			n += 1 + l + sovPb(uint64(l))
-		}
-	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+func (m *SnapshotMeta) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
-	return n
+	return dAtA[:n], nil
 }
 
-func (m *Facets) Size() (n int) {
-	if m == nil {
-		return 0
-	}
-	var l int
-	_ = l
-	if len(m.Facets) > 0 {
-		for _, e := range m.Facets {
-			l = e.Size()
-			n += 1 + l + sovPb(uint64(l))
-		}
-	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
-	}
-	return n
+func (m *SnapshotMeta) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *FacetsList) Size() (n int) {
-	if m == nil {
-		return 0
-	}
+func (m *SnapshotMeta) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	if len(m.FacetsList) > 0 {
-		for _, e := range m.FacetsList {
-			l = e.Size()
-			n += 1 + l + sovPb(uint64(l))
-		}
-	}
 	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
-	}
-	return n
-}
-
-func (m *Function) Size() (n int) {
-	if m == nil {
-		return 0
-	}
-	var l int
-	_ = l
-	l = len(m.Name)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
-	}
-	l = len(m.Key)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if len(m.Args) > 0 {
-		for _, s := range m.Args {
-			l = len(s)
-			n += 1 + l + sovPb(uint64(l))
-		}
+	if m.GroupId != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.GroupId))
+		i--
+		dAtA[i] = 0x10
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if m.ClientTs != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.ClientTs))
+		i--
+		dAtA[i] = 0x8
 	}
-	return n
+	return len(dAtA) - i, nil
 }
 
-func (m *FilterTree) Size() (n int) {
-	if m == nil {
-		return 0
+func (m *Status) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
+	return dAtA[:n], nil
+}
+
+func (m *Status) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Status) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	l = len(m.Op)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
-	}
-	if len(m.Children) > 0 {
-		for _, e := range m.Children {
-			l = e.Size()
-			n += 1 + l + sovPb(uint64(l))
-		}
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if m.Func != nil {
-		l = m.Func.Size()
-		n += 1 + l + sovPb(uint64(l))
+	if len(m.Msg) > 0 {
+		i -= len(m.Msg)
+		copy(dAtA[i:], m.Msg)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Msg)))
+		i--
+		dAtA[i] = 0x12
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if m.Code != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.Code))
+		i--
+		dAtA[i] = 0x8
 	}
-	return n
+	return len(dAtA) - i, nil
 }
 
-func (m *SchemaRequest) Size() (n int) {
-	if m == nil {
-		return 0
+func (m *BackupRequest) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
+	return dAtA[:n], nil
+}
+
+func (m *BackupRequest) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *BackupRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	if m.GroupId != 0 {
-		n += 1 + sovPb(uint64(m.GroupId))
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
 	}
 	if len(m.Predicates) > 0 {
-		for _, s := range m.Predicates {
-			l = len(s)
-			n += 1 + l + sovPb(uint64(l))
+		for iNdEx := len(m.Predicates) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Predicates[iNdEx])
+			copy(dAtA[i:], m.Predicates[iNdEx])
+			i = encodeVarintPb(dAtA, i, uint64(len(m.Predicates[iNdEx])))
+			i--
+			dAtA[i] = 0x52
 		}
 	}
-	if len(m.Fields) > 0 {
-		for _, s := range m.Fields {
-			l = len(s)
-			n += 1 + l + sovPb(uint64(l))
+	if m.Anonymous {
+		i--
+		if m.Anonymous {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
 		}
+		i--
+		dAtA[i] = 0x48
 	}
-	if len(m.Types) > 0 {
-		for _, s := range m.Types {
-			l = len(s)
-			n += 1 + l + sovPb(uint64(l))
-		}
+	if len(m.SessionToken) > 0 {
+		i -= len(m.SessionToken)
+		copy(dAtA[i:], m.SessionToken)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.SessionToken)))
+		i--
+		dAtA[i] = 0x42
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if len(m.SecretKey) > 0 {
+		i -= len(m.SecretKey)
+		copy(dAtA[i:], m.SecretKey)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.SecretKey)))
+		i--
+		dAtA[i] = 0x3a
 	}
-	return n
-}
-
-func (m *SchemaNode) Size() (n int) {
-	if m == nil {
-		return 0
+	if len(m.AccessKey) > 0 {
+		i -= len(m.AccessKey)
+		copy(dAtA[i:], m.AccessKey)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.AccessKey)))
+		i--
+		dAtA[i] = 0x32
 	}
-	var l int
-	_ = l
-	l = len(m.Predicate)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
+	if len(m.Destination) > 0 {
+		i -= len(m.Destination)
+		copy(dAtA[i:], m.Destination)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Destination)))
+		i--
+		dAtA[i] = 0x2a
 	}
-	l = len(m.Type)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
+	if len(m.UnixTs) > 0 {
+		i -= len(m.UnixTs)
+		copy(dAtA[i:], m.UnixTs)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.UnixTs)))
+		i--
+		dAtA[i] = 0x22
 	}
-	if m.Index {
-		n += 2
+	if m.GroupId != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.GroupId))
+		i--
+		dAtA[i] = 0x18
 	}
-	if len(m.Tokenizer) > 0 {
-		for _, s := range m.Tokenizer {
-			l = len(s)
-			n += 1 + l + sovPb(uint64(l))
-		}
+	if m.SinceTs != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.SinceTs))
+		i--
+		dAtA[i] = 0x10
 	}
-	if m.Reverse {
-		n += 2
+	if m.ReadTs != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.ReadTs))
+		i--
+		dAtA[i] = 0x8
 	}
-	if m.Count {
-		n += 2
-	}
-	if m.List {
-		n += 2
-	}
-	if m.Upsert {
-		n += 2
-	}
-	if m.Lang {
-		n += 2
-	}
-	if m.NoConflict {
-		n += 2
-	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
-	}
-	return n
+	return len(dAtA) - i, nil
 }
 
-func (m *SchemaResult) Size() (n int) {
-	if m == nil {
-		return 0
-	}
-	var l int
-	_ = l
-	if len(m.Schema) > 0 {
-		for _, e := range m.Schema {
-			l = e.Size()
-			n += 1 + l + sovPb(uint64(l))
-		}
-	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+func (m *ExportRequest) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
-	return n
+	return dAtA[:n], nil
 }
 
-func (m *SchemaUpdate) Size() (n int) {
-	if m == nil {
-		return 0
-	}
+func (m *ExportRequest) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *ExportRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	l = len(m.Predicate)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
-	}
-	if m.ValueType != 0 {
-		n += 1 + sovPb(uint64(m.ValueType))
-	}
-	if m.Directive != 0 {
-		n += 1 + sovPb(uint64(m.Directive))
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if len(m.Tokenizer) > 0 {
-		for _, s := range m.Tokenizer {
-			l = len(s)
-			n += 1 + l + sovPb(uint64(l))
+	if m.Anonymous {
+		i--
+		if m.Anonymous {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
 		}
+		i--
+		dAtA[i] = 0x48
 	}
-	if m.Count {
-		n += 2
-	}
-	if m.List {
-		n += 2
+	if len(m.SessionToken) > 0 {
+		i -= len(m.SessionToken)
+		copy(dAtA[i:], m.SessionToken)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.SessionToken)))
+		i--
+		dAtA[i] = 0x42
 	}
-	if m.Upsert {
-		n += 2
+	if len(m.SecretKey) > 0 {
+		i -= len(m.SecretKey)
+		copy(dAtA[i:], m.SecretKey)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.SecretKey)))
+		i--
+		dAtA[i] = 0x3a
 	}
-	if m.Lang {
-		n += 2
+	if len(m.AccessKey) > 0 {
+		i -= len(m.AccessKey)
+		copy(dAtA[i:], m.AccessKey)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.AccessKey)))
+		i--
+		dAtA[i] = 0x32
 	}
-	if m.NonNullable {
-		n += 2
+	if len(m.Destination) > 0 {
+		i -= len(m.Destination)
+		copy(dAtA[i:], m.Destination)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Destination)))
+		i--
+		dAtA[i] = 0x2a
 	}
-	if m.NonNullableList {
-		n += 2
+	if len(m.Format) > 0 {
+		i -= len(m.Format)
+		copy(dAtA[i:], m.Format)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Format)))
+		i--
+		dAtA[i] = 0x22
 	}
-	l = len(m.ObjectTypeName)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
+	if m.UnixTs != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.UnixTs))
+		i--
+		dAtA[i] = 0x18
 	}
-	if m.NoConflict {
-		n += 2
+	if m.ReadTs != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.ReadTs))
+		i--
+		dAtA[i] = 0x10
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if m.GroupId != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.GroupId))
+		i--
+		dAtA[i] = 0x8
 	}
-	return n
+	return len(dAtA) - i, nil
 }
 
-func (m *TypeUpdate) Size() (n int) {
-	if m == nil {
-		return 0
+func (m *ExportResponse) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
+	return dAtA[:n], nil
+}
+
+func (m *ExportResponse) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *ExportResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	l = len(m.TypeName)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if len(m.Fields) > 0 {
-		for _, e := range m.Fields {
-			l = e.Size()
-			n += 1 + l + sovPb(uint64(l))
+	if len(m.Files) > 0 {
+		for iNdEx := len(m.Files) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Files[iNdEx])
+			copy(dAtA[i:], m.Files[iNdEx])
+			i = encodeVarintPb(dAtA, i, uint64(len(m.Files[iNdEx])))
+			i--
+			dAtA[i] = 0x1a
 		}
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if len(m.Msg) > 0 {
+		i -= len(m.Msg)
+		copy(dAtA[i:], m.Msg)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Msg)))
+		i--
+		dAtA[i] = 0x12
 	}
-	return n
+	if m.Code != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.Code))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
 }
 
-func (m *MapHeader) Size() (n int) {
-	if m == nil {
-		return 0
-	}
-	var l int
-	_ = l
-	if len(m.PartitionKeys) > 0 {
-		for _, b := range m.PartitionKeys {
-			l = len(b)
-			n += 1 + l + sovPb(uint64(l))
-		}
-	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+func (m *BackupKey) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
-	return n
+	return dAtA[:n], nil
 }
 
-func (m *MovePredicatePayload) Size() (n int) {
-	if m == nil {
-		return 0
-	}
+func (m *BackupKey) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *BackupKey) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	l = len(m.Predicate)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
-	}
-	if m.SourceGid != 0 {
-		n += 1 + sovPb(uint64(m.SourceGid))
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if m.DestGid != 0 {
-		n += 1 + sovPb(uint64(m.DestGid))
+	if m.Count != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.Count))
+		i--
+		dAtA[i] = 0x30
 	}
-	if m.TxnTs != 0 {
-		n += 1 + sovPb(uint64(m.TxnTs))
+	if len(m.Term) > 0 {
+		i -= len(m.Term)
+		copy(dAtA[i:], m.Term)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Term)))
+		i--
+		dAtA[i] = 0x2a
 	}
-	if m.ExpectedChecksum != 0 {
-		n += 1 + sovPb(uint64(m.ExpectedChecksum))
+	if m.StartUid != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.StartUid))
+		i--
+		dAtA[i] = 0x20
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if m.Uid != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.Uid))
+		i--
+		dAtA[i] = 0x18
 	}
-	return n
+	if len(m.Attr) > 0 {
+		i -= len(m.Attr)
+		copy(dAtA[i:], m.Attr)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Attr)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if m.Type != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.Type))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
 }
 
-func (m *TxnStatus) Size() (n int) {
-	if m == nil {
-		return 0
+func (m *BackupPostingList) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
+	return dAtA[:n], nil
+}
+
+func (m *BackupPostingList) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *BackupPostingList) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	if m.StartTs != 0 {
-		n += 1 + sovPb(uint64(m.StartTs))
-	}
-	if m.CommitTs != 0 {
-		n += 1 + sovPb(uint64(m.CommitTs))
-	}
 	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	return n
-}
-
-func (m *OracleDelta) Size() (n int) {
-	if m == nil {
-		return 0
+	if len(m.UidBytes) > 0 {
+		i -= len(m.UidBytes)
+		copy(dAtA[i:], m.UidBytes)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.UidBytes)))
+		i--
+		dAtA[i] = 0x2a
 	}
-	var l int
-	_ = l
-	if len(m.Txns) > 0 {
-		for _, e := range m.Txns {
-			l = e.Size()
-			n += 1 + l + sovPb(uint64(l))
+	if len(m.Splits) > 0 {
+		dAtA35 := make([]byte, len(m.Splits)*10)
+		var j34 int
+		for _, num := range m.Splits {
+			for num >= 1<<7 {
+				dAtA35[j34] = uint8(uint64(num)&0x7f | 0x80)
+				num >>= 7
+				j34++
+			}
+			dAtA35[j34] = uint8(num)
+			j34++
 		}
+		i -= j34
+		copy(dAtA[i:], dAtA35[:j34])
+		i = encodeVarintPb(dAtA, i, uint64(j34))
+		i--
+		dAtA[i] = 0x22
 	}
-	if m.MaxAssigned != 0 {
-		n += 1 + sovPb(uint64(m.MaxAssigned))
+	if m.CommitTs != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.CommitTs))
+		i--
+		dAtA[i] = 0x18
 	}
-	if len(m.GroupChecksums) > 0 {
-		for k, v := range m.GroupChecksums {
-			_ = k
-			_ = v
-			mapEntrySize := 1 + sovPb(uint64(k)) + 1 + sovPb(uint64(v))
-			n += mapEntrySize + 1 + sovPb(uint64(mapEntrySize))
+	if len(m.Postings) > 0 {
+		for iNdEx := len(m.Postings) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Postings[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintPb(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x12
 		}
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if len(m.Uids) > 0 {
+		dAtA37 := make([]byte, len(m.Uids)*10)
+		var j36 int
+		for _, num := range m.Uids {
+			for num >= 1<<7 {
+				dAtA37[j36] = uint8(uint64(num)&0x7f | 0x80)
+				num >>= 7
+				j36++
+			}
+			dAtA37[j36] = uint8(num)
+			j36++
+		}
+		i -= j36
+		copy(dAtA[i:], dAtA37[:j36])
+		i = encodeVarintPb(dAtA, i, uint64(j36))
+		i--
+		dAtA[i] = 0xa
 	}
-	return n
+	return len(dAtA) - i, nil
 }
 
-func (m *TxnTimestamps) Size() (n int) {
-	if m == nil {
-		return 0
+func (m *UpdateGraphQLSchemaRequest) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
+	return dAtA[:n], nil
+}
+
+func (m *UpdateGraphQLSchemaRequest) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *UpdateGraphQLSchemaRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	if len(m.Ts) > 0 {
-		l = 0
-		for _, e := range m.Ts {
-			l += sovPb(uint64(e))
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if len(m.DgraphTypes) > 0 {
+		for iNdEx := len(m.DgraphTypes) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.DgraphTypes[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintPb(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x22
 		}
-		n += 1 + sovPb(uint64(l)) + l
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if len(m.DgraphPreds) > 0 {
+		for iNdEx := len(m.DgraphPreds) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.DgraphPreds[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintPb(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x1a
+		}
 	}
-	return n
+	if len(m.GraphqlSchema) > 0 {
+		i -= len(m.GraphqlSchema)
+		copy(dAtA[i:], m.GraphqlSchema)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.GraphqlSchema)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if m.StartTs != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.StartTs))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
 }
 
-func (m *PeerResponse) Size() (n int) {
-	if m == nil {
-		return 0
+func (m *UpdateGraphQLSchemaResponse) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
+	return dAtA[:n], nil
+}
+
+func (m *UpdateGraphQLSchemaResponse) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *UpdateGraphQLSchemaResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	if m.Status {
-		n += 2
-	}
 	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	return n
+	if m.Uid != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.Uid))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
 }
 
-func (m *RaftBatch) Size() (n int) {
-	if m == nil {
-		return 0
+func (m *IdempotencyRecord) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
+	return dAtA[:n], nil
+}
+
+func (m *IdempotencyRecord) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *IdempotencyRecord) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	if m.Context != nil {
-		l = m.Context.Size()
-		n += 1 + l + sovPb(uint64(l))
-	}
-	if m.Payload != nil {
-		l = m.Payload.Size()
-		n += 1 + l + sovPb(uint64(l))
-	}
 	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	return n
-}
-
-func (m *SubscriptionRequest) Size() (n int) {
-	if m == nil {
-		return 0
+	if len(m.Response) > 0 {
+		i -= len(m.Response)
+		copy(dAtA[i:], m.Response)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Response)))
+		i--
+		dAtA[i] = 0x32
 	}
-	var l int
-	_ = l
-	if len(m.Prefixes) > 0 {
-		for _, b := range m.Prefixes {
-			l = len(b)
-			n += 1 + l + sovPb(uint64(l))
-		}
+	if m.ExpiresAt != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.ExpiresAt))
+		i--
+		dAtA[i] = 0x28
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if m.CommitTs != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.CommitTs))
+		i--
+		dAtA[i] = 0x20
 	}
-	return n
+	if m.StartTs != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.StartTs))
+		i--
+		dAtA[i] = 0x18
+	}
+	if m.Fingerprint != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.Fingerprint))
+		i--
+		dAtA[i] = 0x10
+	}
+	if len(m.Key) > 0 {
+		i -= len(m.Key)
+		copy(dAtA[i:], m.Key)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Key)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
 }
 
-func (m *SubscriptionResponse) Size() (n int) {
-	if m == nil {
-		return 0
+func (m *StrictSchema) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
+	return dAtA[:n], nil
+}
+
+func (m *StrictSchema) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *StrictSchema) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	if m.Kvs != nil {
-		l = m.Kvs.Size()
-		n += 1 + l + sovPb(uint64(l))
-	}
 	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	return n
+	if m.Enabled {
+		i--
+		if m.Enabled {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
 }
 
-func (m *Num) Size() (n int) {
-	if m == nil {
-		return 0
+func (m *BlobChunk) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
+	return dAtA[:n], nil
+}
+
+func (m *BlobChunk) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *BlobChunk) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	if m.Val != 0 {
-		n += 1 + sovPb(uint64(m.Val))
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if m.ReadOnly {
-		n += 2
+	if len(m.Data) > 0 {
+		i -= len(m.Data)
+		copy(dAtA[i:], m.Data)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Data)))
+		i--
+		dAtA[i] = 0x22
 	}
-	if m.Forwarded {
-		n += 2
+	if m.Idx != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.Idx))
+		i--
+		dAtA[i] = 0x18
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if m.Uid != 0 {
+		i -= 8
+		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(m.Uid))
+		i--
+		dAtA[i] = 0x11
 	}
-	return n
+	if len(m.Attr) > 0 {
+		i -= len(m.Attr)
+		copy(dAtA[i:], m.Attr)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Attr)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
 }
 
-func (m *AssignedIds) Size() (n int) {
-	if m == nil {
-		return 0
+func (m *BlobRequest) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
+	return dAtA[:n], nil
+}
+
+func (m *BlobRequest) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *BlobRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	if m.StartId != 0 {
-		n += 1 + sovPb(uint64(m.StartId))
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	if m.EndId != 0 {
-		n += 1 + sovPb(uint64(m.EndId))
+	if m.ReadTs != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.ReadTs))
+		i--
+		dAtA[i] = 0x18
 	}
-	if m.ReadOnly != 0 {
-		n += 1 + sovPb(uint64(m.ReadOnly))
+	if m.Uid != 0 {
+		i -= 8
+		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(m.Uid))
+		i--
+		dAtA[i] = 0x11
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if len(m.Attr) > 0 {
+		i -= len(m.Attr)
+		copy(dAtA[i:], m.Attr)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Attr)))
+		i--
+		dAtA[i] = 0xa
 	}
-	return n
+	return len(dAtA) - i, nil
 }
 
-func (m *SnapshotMeta) Size() (n int) {
-	if m == nil {
-		return 0
+func (m *ReplicationState) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
+	return dAtA[:n], nil
+}
+
+func (m *ReplicationState) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *ReplicationState) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	if m.ClientTs != 0 {
-		n += 1 + sovPb(uint64(m.ClientTs))
-	}
-	if m.GroupId != 0 {
-		n += 1 + sovPb(uint64(m.GroupId))
-	}
 	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	return n
-}
-
-func (m *Status) Size() (n int) {
-	if m == nil {
-		return 0
+	if m.Promote {
+		i--
+		if m.Promote {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x28
 	}
-	var l int
-	_ = l
-	if m.Code != 0 {
-		n += 1 + sovPb(uint64(m.Code))
+	if m.SnapshotAt != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.SnapshotAt))
+		i--
+		dAtA[i] = 0x20
 	}
-	l = len(m.Msg)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
+	if m.ReadTs != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.ReadTs))
+		i--
+		dAtA[i] = 0x18
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if m.PrimaryTs != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.PrimaryTs))
+		i--
+		dAtA[i] = 0x10
 	}
-	return n
+	if len(m.Primary) > 0 {
+		i -= len(m.Primary)
+		copy(dAtA[i:], m.Primary)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Primary)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
 }
 
-func (m *BackupRequest) Size() (n int) {
-	if m == nil {
-		return 0
+func (m *ReplicationRequest) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
+	return dAtA[:n], nil
+}
+
+func (m *ReplicationRequest) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *ReplicationRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
 	if m.ReadTs != 0 {
-		n += 1 + sovPb(uint64(m.ReadTs))
+		i = encodeVarintPb(dAtA, i, uint64(m.ReadTs))
+		i--
+		dAtA[i] = 0x18
 	}
 	if m.SinceTs != 0 {
-		n += 1 + sovPb(uint64(m.SinceTs))
-	}
-	if m.GroupId != 0 {
-		n += 1 + sovPb(uint64(m.GroupId))
+		i = encodeVarintPb(dAtA, i, uint64(m.SinceTs))
+		i--
+		dAtA[i] = 0x10
 	}
-	l = len(m.UnixTs)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
+	if m.GroupId != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.GroupId))
+		i--
+		dAtA[i] = 0x8
 	}
-	l = len(m.Destination)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
+	return len(dAtA) - i, nil
+}
+
+func (m *KeyRange) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
-	l = len(m.AccessKey)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
+	return dAtA[:n], nil
+}
+
+func (m *KeyRange) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *KeyRange) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	l = len(m.SecretKey)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
+	if len(m.End) > 0 {
+		i -= len(m.End)
+		copy(dAtA[i:], m.End)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.End)))
+		i--
+		dAtA[i] = 0x12
 	}
-	l = len(m.SessionToken)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
+	if len(m.Start) > 0 {
+		i -= len(m.Start)
+		copy(dAtA[i:], m.Start)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Start)))
+		i--
+		dAtA[i] = 0xa
 	}
-	if m.Anonymous {
-		n += 2
+	return len(dAtA) - i, nil
+}
+
+func (m *FreezePoint) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
-	if len(m.Predicates) > 0 {
-		for _, s := range m.Predicates {
-			l = len(s)
-			n += 1 + l + sovPb(uint64(l))
+	return dAtA[:n], nil
+}
+
+func (m *FreezePoint) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *FreezePoint) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if m.Ready {
+		i--
+		if m.Ready {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
 		}
+		i--
+		dAtA[i] = 0x28
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if len(m.Groups) > 0 {
+		for iNdEx := len(m.Groups) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Groups[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintPb(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x22
+		}
 	}
-	return n
+	if m.CreatedAt != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.CreatedAt))
+		i--
+		dAtA[i] = 0x18
+	}
+	if m.ReadTs != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.ReadTs))
+		i--
+		dAtA[i] = 0x10
+	}
+	if len(m.Tag) > 0 {
+		i -= len(m.Tag)
+		copy(dAtA[i:], m.Tag)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Tag)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
 }
 
-func (m *ExportRequest) Size() (n int) {
-	if m == nil {
-		return 0
+func (m *FrozenGroup) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
+	return dAtA[:n], nil
+}
+
+func (m *FrozenGroup) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *FrozenGroup) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
 	var l int
 	_ = l
-	if m.GroupId != 0 {
-		n += 1 + sovPb(uint64(m.GroupId))
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if m.Index != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.Index))
+		i--
+		dAtA[i] = 0x20
+	}
+	if m.NodeId != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.NodeId))
+		i--
+		dAtA[i] = 0x18
 	}
 	if m.ReadTs != 0 {
-		n += 1 + sovPb(uint64(m.ReadTs))
+		i = encodeVarintPb(dAtA, i, uint64(m.ReadTs))
+		i--
+		dAtA[i] = 0x10
 	}
-	if m.UnixTs != 0 {
-		n += 1 + sovPb(uint64(m.UnixTs))
+	if m.GroupId != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.GroupId))
+		i--
+		dAtA[i] = 0x8
 	}
-	l = len(m.Format)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
+	return len(dAtA) - i, nil
+}
+
+func (m *MoveProgress) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
-	l = len(m.Destination)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
+	return dAtA[:n], nil
+}
+
+func (m *MoveProgress) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *MoveProgress) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
 	}
-	l = len(m.AccessKey)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
+	if m.Bytes != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.Bytes))
+		i--
+		dAtA[i] = 0x18
 	}
-	l = len(m.SecretKey)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
+	if m.Keys != 0 {
+		i = encodeVarintPb(dAtA, i, uint64(m.Keys))
+		i--
+		dAtA[i] = 0x10
 	}
-	l = len(m.SessionToken)
+	if len(m.Predicate) > 0 {
+		i -= len(m.Predicate)
+		copy(dAtA[i:], m.Predicate)
+		i = encodeVarintPb(dAtA, i, uint64(len(m.Predicate)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func encodeVarintPb(dAtA []byte, offset int, v uint64) int {
+	offset -= sovPb(v)
+	base := offset
+	for v >= 1<<7 {
+		dAtA[offset] = uint8(v&0x7f | 0x80)
+		v >>= 7
+		offset++
+	}
+	dAtA[offset] = uint8(v)
+	return base
+}
+func (m *List) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Uids) > 0 {
+		n += 1 + sovPb(uint64(len(m.Uids)*8)) + len(m.Uids)*8
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *TaskValue) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Val)
 	if l > 0 {
 		n += 1 + l + sovPb(uint64(l))
 	}
-	if m.Anonymous {
-		n += 2
+	if m.ValType != 0 {
+		n += 1 + sovPb(uint64(m.ValType))
 	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
@@ -12282,56 +13609,93 @@ func (m *ExportRequest) Size() (n int) {
 	return n
 }
 
-func (m *ExportResponse) Size() (n int) {
+func (m *SrcFunction) Size() (n int) {
 	if m == nil {
 		return 0
 	}
 	var l int
 	_ = l
-	if m.Code != 0 {
-		n += 1 + sovPb(uint64(m.Code))
-	}
-	l = len(m.Msg)
+	l = len(m.Name)
 	if l > 0 {
 		n += 1 + l + sovPb(uint64(l))
 	}
-	if len(m.Files) > 0 {
-		for _, s := range m.Files {
+	if len(m.Args) > 0 {
+		for _, s := range m.Args {
 			l = len(s)
 			n += 1 + l + sovPb(uint64(l))
 		}
 	}
+	if m.IsCount {
+		n += 2
+	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
 	return n
 }
 
-func (m *BackupKey) Size() (n int) {
+func (m *Query) Size() (n int) {
 	if m == nil {
 		return 0
 	}
 	var l int
 	_ = l
-	if m.Type != 0 {
-		n += 1 + sovPb(uint64(m.Type))
-	}
 	l = len(m.Attr)
 	if l > 0 {
 		n += 1 + l + sovPb(uint64(l))
 	}
-	if m.Uid != 0 {
-		n += 1 + sovPb(uint64(m.Uid))
+	if len(m.Langs) > 0 {
+		for _, s := range m.Langs {
+			l = len(s)
+			n += 1 + l + sovPb(uint64(l))
+		}
 	}
-	if m.StartUid != 0 {
-		n += 1 + sovPb(uint64(m.StartUid))
+	if m.AfterUid != 0 {
+		n += 9
 	}
-	l = len(m.Term)
-	if l > 0 {
+	if m.DoCount {
+		n += 2
+	}
+	if m.UidList != nil {
+		l = m.UidList.Size()
 		n += 1 + l + sovPb(uint64(l))
 	}
-	if m.Count != 0 {
-		n += 1 + sovPb(uint64(m.Count))
+	if m.SrcFunc != nil {
+		l = m.SrcFunc.Size()
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.Reverse {
+		n += 2
+	}
+	if m.FacetParam != nil {
+		l = m.FacetParam.Size()
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.FacetsFilter != nil {
+		l = m.FacetsFilter.Size()
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.ExpandAll {
+		n += 2
+	}
+	if m.ReadTs != 0 {
+		n += 1 + sovPb(uint64(m.ReadTs))
+	}
+	if m.Cache != 0 {
+		n += 1 + sovPb(uint64(m.Cache))
+	}
+	if m.First != 0 {
+		n += 1 + sovPb(uint64(m.First))
+	}
+	if m.DistinctCount {
+		n += 3
+	}
+	if m.EdgeFilter != nil {
+		l = m.EdgeFilter.Size()
+		n += 2 + l + sovPb(uint64(l))
+	}
+	if m.Sample != 0 {
+		n += 2 + sovPb(uint64(m.Sample))
 	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
@@ -12339,38 +13703,90 @@ func (m *BackupKey) Size() (n int) {
 	return n
 }
 
-func (m *BackupPostingList) Size() (n int) {
+func (m *ValueList) Size() (n int) {
 	if m == nil {
 		return 0
 	}
 	var l int
 	_ = l
-	if len(m.Uids) > 0 {
-		l = 0
-		for _, e := range m.Uids {
-			l += sovPb(uint64(e))
+	if len(m.Values) > 0 {
+		for _, e := range m.Values {
+			l = e.Size()
+			n += 1 + l + sovPb(uint64(l))
 		}
-		n += 1 + sovPb(uint64(l)) + l
 	}
-	if len(m.Postings) > 0 {
-		for _, e := range m.Postings {
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *LangList) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Lang) > 0 {
+		for _, s := range m.Lang {
+			l = len(s)
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Result) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.UidMatrix) > 0 {
+		for _, e := range m.UidMatrix {
 			l = e.Size()
 			n += 1 + l + sovPb(uint64(l))
 		}
 	}
-	if m.CommitTs != 0 {
-		n += 1 + sovPb(uint64(m.CommitTs))
+	if len(m.ValueMatrix) > 0 {
+		for _, e := range m.ValueMatrix {
+			l = e.Size()
+			n += 1 + l + sovPb(uint64(l))
+		}
 	}
-	if len(m.Splits) > 0 {
+	if len(m.Counts) > 0 {
 		l = 0
-		for _, e := range m.Splits {
+		for _, e := range m.Counts {
 			l += sovPb(uint64(e))
 		}
 		n += 1 + sovPb(uint64(l)) + l
 	}
-	l = len(m.UidBytes)
-	if l > 0 {
-		n += 1 + l + sovPb(uint64(l))
+	if m.IntersectDest {
+		n += 2
+	}
+	if len(m.FacetMatrix) > 0 {
+		for _, e := range m.FacetMatrix {
+			l = e.Size()
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if len(m.LangMatrix) > 0 {
+		for _, e := range m.LangMatrix {
+			l = e.Size()
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if m.List {
+		n += 2
+	}
+	if len(m.DistinctBuckets) > 0 {
+		for _, e := range m.DistinctBuckets {
+			l = e.Size()
+			n += 1 + l + sovPb(uint64(l))
+		}
 	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
@@ -12378,59 +13794,6080 @@ func (m *BackupPostingList) Size() (n int) {
 	return n
 }
 
-func (m *UpdateGraphQLSchemaRequest) Size() (n int) {
+func (m *Order) Size() (n int) {
 	if m == nil {
 		return 0
 	}
 	var l int
 	_ = l
-	if m.StartTs != 0 {
-		n += 1 + sovPb(uint64(m.StartTs))
-	}
-	l = len(m.GraphqlSchema)
+	l = len(m.Attr)
 	if l > 0 {
 		n += 1 + l + sovPb(uint64(l))
 	}
-	if len(m.DgraphPreds) > 0 {
-		for _, e := range m.DgraphPreds {
+	if m.Desc {
+		n += 2
+	}
+	if len(m.Langs) > 0 {
+		for _, s := range m.Langs {
+			l = len(s)
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *SortMessage) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Order) > 0 {
+		for _, e := range m.Order {
 			l = e.Size()
 			n += 1 + l + sovPb(uint64(l))
 		}
 	}
-	if len(m.DgraphTypes) > 0 {
-		for _, e := range m.DgraphTypes {
+	if len(m.UidMatrix) > 0 {
+		for _, e := range m.UidMatrix {
+			l = e.Size()
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if m.Count != 0 {
+		n += 1 + sovPb(uint64(m.Count))
+	}
+	if m.Offset != 0 {
+		n += 1 + sovPb(uint64(m.Offset))
+	}
+	if m.ReadTs != 0 {
+		n += 1 + sovPb(uint64(m.ReadTs))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *SortResult) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.UidMatrix) > 0 {
+		for _, e := range m.UidMatrix {
 			l = e.Size()
 			n += 1 + l + sovPb(uint64(l))
 		}
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
-	}
-	return n
-}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *RaftContext) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Id != 0 {
+		n += 9
+	}
+	if m.Group != 0 {
+		n += 1 + sovPb(uint64(m.Group))
+	}
+	l = len(m.Addr)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.SnapshotTs != 0 {
+		n += 1 + sovPb(uint64(m.SnapshotTs))
+	}
+	if m.IsLearner {
+		n += 2
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Member) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Id != 0 {
+		n += 9
+	}
+	if m.GroupId != 0 {
+		n += 1 + sovPb(uint64(m.GroupId))
+	}
+	l = len(m.Addr)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.Leader {
+		n += 2
+	}
+	if m.AmDead {
+		n += 2
+	}
+	if m.LastUpdate != 0 {
+		n += 1 + sovPb(uint64(m.LastUpdate))
+	}
+	if m.ClusterInfoOnly {
+		n += 2
+	}
+	if m.ForceGroupId {
+		n += 2
+	}
+	if m.Learner {
+		n += 2
+	}
+	if m.Decommissioning {
+		n += 3
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Group) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Members) > 0 {
+		for k, v := range m.Members {
+			_ = k
+			_ = v
+			l = 0
+			if v != nil {
+				l = v.Size()
+				l += 1 + sovPb(uint64(l))
+			}
+			mapEntrySize := 1 + sovPb(uint64(k)) + l
+			n += mapEntrySize + 1 + sovPb(uint64(mapEntrySize))
+		}
+	}
+	if len(m.Tablets) > 0 {
+		for k, v := range m.Tablets {
+			_ = k
+			_ = v
+			l = 0
+			if v != nil {
+				l = v.Size()
+				l += 1 + sovPb(uint64(l))
+			}
+			mapEntrySize := 1 + len(k) + sovPb(uint64(len(k))) + l
+			n += mapEntrySize + 1 + sovPb(uint64(mapEntrySize))
+		}
+	}
+	if m.SnapshotTs != 0 {
+		n += 1 + sovPb(uint64(m.SnapshotTs))
+	}
+	if m.Checksum != 0 {
+		n += 1 + sovPb(uint64(m.Checksum))
+	}
+	if len(m.Loads) > 0 {
+		for _, e := range m.Loads {
+			l = e.Size()
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *License) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.User)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.MaxNodes != 0 {
+		n += 1 + sovPb(uint64(m.MaxNodes))
+	}
+	if m.ExpiryTs != 0 {
+		n += 1 + sovPb(uint64(m.ExpiryTs))
+	}
+	if m.Enabled {
+		n += 2
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *ZeroProposal) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.SnapshotTs) > 0 {
+		for k, v := range m.SnapshotTs {
+			_ = k
+			_ = v
+			mapEntrySize := 1 + sovPb(uint64(k)) + 1 + sovPb(uint64(v))
+			n += mapEntrySize + 1 + sovPb(uint64(mapEntrySize))
+		}
+	}
+	if m.Member != nil {
+		l = m.Member.Size()
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.Tablet != nil {
+		l = m.Tablet.Size()
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.MaxLeaseId != 0 {
+		n += 1 + sovPb(uint64(m.MaxLeaseId))
+	}
+	if m.MaxTxnTs != 0 {
+		n += 1 + sovPb(uint64(m.MaxTxnTs))
+	}
+	if m.MaxRaftId != 0 {
+		n += 1 + sovPb(uint64(m.MaxRaftId))
+	}
+	if m.Txn != nil {
+		l = m.Txn.Size()
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.Key)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.Cid)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.License != nil {
+		l = m.License.Size()
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.Idempotency != nil {
+		l = m.Idempotency.Size()
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.StrictSchema != nil {
+		l = m.StrictSchema.Size()
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.Replication != nil {
+		l = m.Replication.Size()
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.Freeze != nil {
+		l = m.Freeze.Size()
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.ReleaseFreeze)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.IdempotencyCutoff != 0 {
+		n += 2 + sovPb(uint64(m.IdempotencyCutoff))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *MembershipState) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Counter != 0 {
+		n += 1 + sovPb(uint64(m.Counter))
+	}
+	if len(m.Groups) > 0 {
+		for k, v := range m.Groups {
+			_ = k
+			_ = v
+			l = 0
+			if v != nil {
+				l = v.Size()
+				l += 1 + sovPb(uint64(l))
+			}
+			mapEntrySize := 1 + sovPb(uint64(k)) + l
+			n += mapEntrySize + 1 + sovPb(uint64(mapEntrySize))
+		}
+	}
+	if len(m.Zeros) > 0 {
+		for k, v := range m.Zeros {
+			_ = k
+			_ = v
+			l = 0
+			if v != nil {
+				l = v.Size()
+				l += 1 + sovPb(uint64(l))
+			}
+			mapEntrySize := 1 + sovPb(uint64(k)) + l
+			n += mapEntrySize + 1 + sovPb(uint64(mapEntrySize))
+		}
+	}
+	if m.MaxLeaseId != 0 {
+		n += 1 + sovPb(uint64(m.MaxLeaseId))
+	}
+	if m.MaxTxnTs != 0 {
+		n += 1 + sovPb(uint64(m.MaxTxnTs))
+	}
+	if m.MaxRaftId != 0 {
+		n += 1 + sovPb(uint64(m.MaxRaftId))
+	}
+	if len(m.Removed) > 0 {
+		for _, e := range m.Removed {
+			l = e.Size()
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	l = len(m.Cid)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.License != nil {
+		l = m.License.Size()
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if len(m.Idempotency) > 0 {
+		for _, e := range m.Idempotency {
+			l = e.Size()
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if m.StrictSchema != nil {
+		l = m.StrictSchema.Size()
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.Replication != nil {
+		l = m.Replication.Size()
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if len(m.FreezePoints) > 0 {
+		for _, e := range m.FreezePoints {
+			l = e.Size()
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *ConnectionState) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Member != nil {
+		l = m.Member.Size()
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.State != nil {
+		l = m.State.Size()
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.MaxPending != 0 {
+		n += 1 + sovPb(uint64(m.MaxPending))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *HealthInfo) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Instance)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.Address)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.Status)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.Group)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.Version)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.Uptime != 0 {
+		n += 1 + sovPb(uint64(m.Uptime))
+	}
+	if m.LastEcho != 0 {
+		n += 1 + sovPb(uint64(m.LastEcho))
+	}
+	if len(m.Ongoing) > 0 {
+		for _, s := range m.Ongoing {
+			l = len(s)
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if len(m.Indexing) > 0 {
+		for _, s := range m.Indexing {
+			l = len(s)
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if len(m.EeFeatures) > 0 {
+		for _, s := range m.EeFeatures {
+			l = len(s)
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Tablet) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.GroupId != 0 {
+		n += 1 + sovPb(uint64(m.GroupId))
+	}
+	l = len(m.Predicate)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.Force {
+		n += 2
+	}
+	if m.Space != 0 {
+		n += 1 + sovPb(uint64(m.Space))
+	}
+	if m.Remove {
+		n += 2
+	}
+	if m.ReadOnly {
+		n += 2
+	}
+	if m.MoveTs != 0 {
+		n += 1 + sovPb(uint64(m.MoveTs))
+	}
+	if m.QueriesPerMin != 0 {
+		n += 1 + sovPb(uint64(m.QueriesPerMin))
+	}
+	if m.MutationsPerMin != 0 {
+		n += 1 + sovPb(uint64(m.MutationsPerMin))
+	}
+	if m.NumKeys != 0 {
+		n += 1 + sovPb(uint64(m.NumKeys))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *DirectedEdge) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Entity != 0 {
+		n += 9
+	}
+	l = len(m.Attr)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.Value)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.ValueType != 0 {
+		n += 1 + sovPb(uint64(m.ValueType))
+	}
+	if m.ValueId != 0 {
+		n += 9
+	}
+	l = len(m.Label)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.Lang)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.Op != 0 {
+		n += 1 + sovPb(uint64(m.Op))
+	}
+	if len(m.Facets) > 0 {
+		for _, e := range m.Facets {
+			l = e.Size()
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if len(m.AllowedPreds) > 0 {
+		for _, s := range m.AllowedPreds {
+			l = len(s)
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if m.BlobSize != 0 {
+		n += 1 + sovPb(uint64(m.BlobSize))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Mutations) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.GroupId != 0 {
+		n += 1 + sovPb(uint64(m.GroupId))
+	}
+	if m.StartTs != 0 {
+		n += 1 + sovPb(uint64(m.StartTs))
+	}
+	if len(m.Edges) > 0 {
+		for _, e := range m.Edges {
+			l = e.Size()
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if len(m.Schema) > 0 {
+		for _, e := range m.Schema {
+			l = e.Size()
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if len(m.Types) > 0 {
+		for _, e := range m.Types {
+			l = e.Size()
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if m.DropOp != 0 {
+		n += 1 + sovPb(uint64(m.DropOp))
+	}
+	l = len(m.DropValue)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.Metadata != nil {
+		l = m.Metadata.Size()
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.Savepoint)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.RollbackTo)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if len(m.BlobChunks) > 0 {
+		for _, e := range m.BlobChunks {
+			l = e.Size()
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	l = len(m.RenameFrom)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.RenameTo)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.TierPredicate)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.Tier)
+	if l > 0 {
+		n += 2 + l + sovPb(uint64(l))
+	}
+	l = len(m.TierObject)
+	if l > 0 {
+		n += 2 + l + sovPb(uint64(l))
+	}
+	l = len(m.DictionaryPredicate)
+	if l > 0 {
+		n += 2 + l + sovPb(uint64(l))
+	}
+	l = len(m.Dictionary)
+	if l > 0 {
+		n += 2 + l + sovPb(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Metadata) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.PredHints) > 0 {
+		for k, v := range m.PredHints {
+			_ = k
+			_ = v
+			mapEntrySize := 1 + len(k) + sovPb(uint64(len(k))) + 1 + sovPb(uint64(v))
+			n += mapEntrySize + 1 + sovPb(uint64(mapEntrySize))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Snapshot) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Context != nil {
+		l = m.Context.Size()
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.Index != 0 {
+		n += 1 + sovPb(uint64(m.Index))
+	}
+	if m.ReadTs != 0 {
+		n += 1 + sovPb(uint64(m.ReadTs))
+	}
+	if m.Done {
+		n += 2
+	}
+	if m.SinceTs != 0 {
+		n += 1 + sovPb(uint64(m.SinceTs))
+	}
+	if len(m.Received) > 0 {
+		for _, e := range m.Received {
+			l = e.Size()
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *RestoreRequest) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.GroupId != 0 {
+		n += 1 + sovPb(uint64(m.GroupId))
+	}
+	if m.RestoreTs != 0 {
+		n += 1 + sovPb(uint64(m.RestoreTs))
+	}
+	l = len(m.Location)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.BackupId)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.AccessKey)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.SecretKey)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.SessionToken)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.Anonymous {
+		n += 2
+	}
+	l = len(m.EncryptionKeyFile)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.VaultAddr)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.VaultRoleidFile)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.VaultSecretidFile)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.VaultPath)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.VaultField)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.VaultFormat)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.BackupNum != 0 {
+		n += 2 + sovPb(uint64(m.BackupNum))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Proposal) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Mutations != nil {
+		l = m.Mutations.Size()
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if len(m.Kv) > 0 {
+		for _, e := range m.Kv {
+			l = e.Size()
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if m.State != nil {
+		l = m.State.Size()
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.CleanPredicate)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.Key)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.Delta != nil {
+		l = m.Delta.Size()
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.Snapshot != nil {
+		l = m.Snapshot.Size()
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.Index != 0 {
+		n += 1 + sovPb(uint64(m.Index))
+	}
+	if m.ExpectedChecksum != 0 {
+		n += 1 + sovPb(uint64(m.ExpectedChecksum))
+	}
+	if m.Restore != nil {
+		l = m.Restore.Size()
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if len(m.TierAccessed) > 0 {
+		for _, s := range m.TierAccessed {
+			l = len(s)
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *KVS) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Kv) > 0 {
+		for _, e := range m.Kv {
+			l = e.Size()
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if m.Done {
+		n += 2
+	}
+	if len(m.Predicates) > 0 {
+		for _, s := range m.Predicates {
+			l = len(s)
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if len(m.Types) > 0 {
+		for _, s := range m.Types {
+			l = len(s)
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Posting) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Uid != 0 {
+		n += 9
+	}
+	l = len(m.Value)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.ValType != 0 {
+		n += 1 + sovPb(uint64(m.ValType))
+	}
+	if m.PostingType != 0 {
+		n += 1 + sovPb(uint64(m.PostingType))
+	}
+	l = len(m.LangTag)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.Label)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if len(m.Facets) > 0 {
+		for _, e := range m.Facets {
+			l = e.Size()
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if m.Op != 0 {
+		n += 1 + sovPb(uint64(m.Op))
+	}
+	if m.StartTs != 0 {
+		n += 1 + sovPb(uint64(m.StartTs))
+	}
+	if m.CommitTs != 0 {
+		n += 1 + sovPb(uint64(m.CommitTs))
+	}
+	if m.BlobSize != 0 {
+		n += 1 + sovPb(uint64(m.BlobSize))
+	}
+	if m.Encoding != 0 {
+		n += 2 + sovPb(uint64(m.Encoding))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *UidBlock) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Base != 0 {
+		n += 1 + sovPb(uint64(m.Base))
+	}
+	l = len(m.Deltas)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.NumUids != 0 {
+		n += 1 + sovPb(uint64(m.NumUids))
+	}
+	l = len(m.Bitmap)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *UidPack) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.BlockSize != 0 {
+		n += 1 + sovPb(uint64(m.BlockSize))
+	}
+	if len(m.Blocks) > 0 {
+		for _, e := range m.Blocks {
+			l = e.Size()
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if m.AllocRef != 0 {
+		n += 2 + sovPb(uint64(m.AllocRef))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *PostingList) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Pack != nil {
+		l = m.Pack.Size()
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if len(m.Postings) > 0 {
+		for _, e := range m.Postings {
+			l = e.Size()
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if m.CommitTs != 0 {
+		n += 1 + sovPb(uint64(m.CommitTs))
+	}
+	if len(m.Splits) > 0 {
+		l = 0
+		for _, e := range m.Splits {
+			l += sovPb(uint64(e))
+		}
+		n += 1 + sovPb(uint64(l)) + l
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *FacetParam) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Key)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.Alias)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *FacetParams) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.AllKeys {
+		n += 2
+	}
+	if len(m.Param) > 0 {
+		for _, e := range m.Param {
+			l = e.Size()
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Facets) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Facets) > 0 {
+		for _, e := range m.Facets {
+			l = e.Size()
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *FacetsList) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.FacetsList) > 0 {
+		for _, e := range m.FacetsList {
+			l = e.Size()
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Function) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Name)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.Key)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if len(m.Args) > 0 {
+		for _, s := range m.Args {
+			l = len(s)
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *FilterTree) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Op)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if len(m.Children) > 0 {
+		for _, e := range m.Children {
+			l = e.Size()
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if m.Func != nil {
+		l = m.Func.Size()
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *SchemaRequest) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.GroupId != 0 {
+		n += 1 + sovPb(uint64(m.GroupId))
+	}
+	if len(m.Predicates) > 0 {
+		for _, s := range m.Predicates {
+			l = len(s)
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if len(m.Fields) > 0 {
+		for _, s := range m.Fields {
+			l = len(s)
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if len(m.Types) > 0 {
+		for _, s := range m.Types {
+			l = len(s)
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *SchemaNode) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Predicate)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.Type)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.Index {
+		n += 2
+	}
+	if len(m.Tokenizer) > 0 {
+		for _, s := range m.Tokenizer {
+			l = len(s)
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if m.Reverse {
+		n += 2
+	}
+	if m.Count {
+		n += 2
+	}
+	if m.List {
+		n += 2
+	}
+	if m.Upsert {
+		n += 2
+	}
+	if m.Lang {
+		n += 2
+	}
+	if m.NoConflict {
+		n += 2
+	}
+	if m.Unique {
+		n += 2
+	}
+	l = len(m.Ttl)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.TtlFrom)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.RenamedFrom)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.DefaultValue)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.DefaultVirtual {
+		n += 3
+	}
+	l = len(m.IndexWhere)
+	if l > 0 {
+		n += 2 + l + sovPb(uint64(l))
+	}
+	l = len(m.Encoding)
+	if l > 0 {
+		n += 2 + l + sovPb(uint64(l))
+	}
+	if len(m.EnumValues) > 0 {
+		for _, s := range m.EnumValues {
+			l = len(s)
+			n += 2 + l + sovPb(uint64(l))
+		}
+	}
+	l = len(m.CheckMin)
+	if l > 0 {
+		n += 2 + l + sovPb(uint64(l))
+	}
+	l = len(m.CheckMax)
+	if l > 0 {
+		n += 2 + l + sovPb(uint64(l))
+	}
+	if m.CheckMaxLength != 0 {
+		n += 2 + sovPb(uint64(m.CheckMaxLength))
+	}
+	l = len(m.CheckPattern)
+	if l > 0 {
+		n += 2 + l + sovPb(uint64(l))
+	}
+	l = len(m.References)
+	if l > 0 {
+		n += 2 + l + sovPb(uint64(l))
+	}
+	l = len(m.Tier)
+	if l > 0 {
+		n += 2 + l + sovPb(uint64(l))
+	}
+	l = len(m.Compression)
+	if l > 0 {
+		n += 2 + l + sovPb(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *SchemaResult) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Schema) > 0 {
+		for _, e := range m.Schema {
+			l = e.Size()
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *SchemaUpdate) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Predicate)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.ValueType != 0 {
+		n += 1 + sovPb(uint64(m.ValueType))
+	}
+	if m.Directive != 0 {
+		n += 1 + sovPb(uint64(m.Directive))
+	}
+	if len(m.Tokenizer) > 0 {
+		for _, s := range m.Tokenizer {
+			l = len(s)
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if m.Count {
+		n += 2
+	}
+	if m.List {
+		n += 2
+	}
+	if m.Upsert {
+		n += 2
+	}
+	if m.Lang {
+		n += 2
+	}
+	if m.NonNullable {
+		n += 2
+	}
+	if m.NonNullableList {
+		n += 2
+	}
+	l = len(m.ObjectTypeName)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.NoConflict {
+		n += 2
+	}
+	if m.Unique {
+		n += 2
+	}
+	l = len(m.Ttl)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.TtlFrom)
+	if l > 0 {
+		n += 2 + l + sovPb(uint64(l))
+	}
+	l = len(m.RenamedFrom)
+	if l > 0 {
+		n += 2 + l + sovPb(uint64(l))
+	}
+	l = len(m.DefaultValue)
+	if l > 0 {
+		n += 2 + l + sovPb(uint64(l))
+	}
+	if m.DefaultVirtual {
+		n += 3
+	}
+	l = len(m.IndexWhere)
+	if l > 0 {
+		n += 2 + l + sovPb(uint64(l))
+	}
+	l = len(m.Encoding)
+	if l > 0 {
+		n += 2 + l + sovPb(uint64(l))
+	}
+	if len(m.Dictionary) > 0 {
+		for _, s := range m.Dictionary {
+			l = len(s)
+			n += 2 + l + sovPb(uint64(l))
+		}
+	}
+	if m.EncodingBase != 0 {
+		n += 2 + sovPb(uint64(m.EncodingBase))
+	}
+	if m.EncodingBaseSet {
+		n += 3
+	}
+	if len(m.EnumValues) > 0 {
+		for _, s := range m.EnumValues {
+			l = len(s)
+			n += 2 + l + sovPb(uint64(l))
+		}
+	}
+	l = len(m.CheckMin)
+	if l > 0 {
+		n += 2 + l + sovPb(uint64(l))
+	}
+	l = len(m.CheckMax)
+	if l > 0 {
+		n += 2 + l + sovPb(uint64(l))
+	}
+	if m.CheckMaxLength != 0 {
+		n += 2 + sovPb(uint64(m.CheckMaxLength))
+	}
+	l = len(m.CheckPattern)
+	if l > 0 {
+		n += 2 + l + sovPb(uint64(l))
+	}
+	l = len(m.References)
+	if l > 0 {
+		n += 2 + l + sovPb(uint64(l))
+	}
+	l = len(m.Tier)
+	if l > 0 {
+		n += 2 + l + sovPb(uint64(l))
+	}
+	l = len(m.TierObject)
+	if l > 0 {
+		n += 2 + l + sovPb(uint64(l))
+	}
+	l = len(m.Compression)
+	if l > 0 {
+		n += 2 + l + sovPb(uint64(l))
+	}
+	if len(m.CompressionDictionaries) > 0 {
+		for _, s := range m.CompressionDictionaries {
+			l = len(s)
+			n += 2 + l + sovPb(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *TypeUpdate) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.TypeName)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if len(m.Fields) > 0 {
+		for _, e := range m.Fields {
+			l = e.Size()
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	l = len(m.Ttl)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.TtlFrom)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *MapHeader) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.PartitionKeys) > 0 {
+		for _, b := range m.PartitionKeys {
+			l = len(b)
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *MovePredicatePayload) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Predicate)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.SourceGid != 0 {
+		n += 1 + sovPb(uint64(m.SourceGid))
+	}
+	if m.DestGid != 0 {
+		n += 1 + sovPb(uint64(m.DestGid))
+	}
+	if m.TxnTs != 0 {
+		n += 1 + sovPb(uint64(m.TxnTs))
+	}
+	if m.ExpectedChecksum != 0 {
+		n += 1 + sovPb(uint64(m.ExpectedChecksum))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *TxnStatus) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.StartTs != 0 {
+		n += 1 + sovPb(uint64(m.StartTs))
+	}
+	if m.CommitTs != 0 {
+		n += 1 + sovPb(uint64(m.CommitTs))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *OracleDelta) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Txns) > 0 {
+		for _, e := range m.Txns {
+			l = e.Size()
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if m.MaxAssigned != 0 {
+		n += 1 + sovPb(uint64(m.MaxAssigned))
+	}
+	if len(m.GroupChecksums) > 0 {
+		for k, v := range m.GroupChecksums {
+			_ = k
+			_ = v
+			mapEntrySize := 1 + sovPb(uint64(k)) + 1 + sovPb(uint64(v))
+			n += mapEntrySize + 1 + sovPb(uint64(mapEntrySize))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *TxnTimestamps) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Ts) > 0 {
+		l = 0
+		for _, e := range m.Ts {
+			l += sovPb(uint64(e))
+		}
+		n += 1 + sovPb(uint64(l)) + l
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *PeerResponse) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Status {
+		n += 2
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *RaftBatch) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Context != nil {
+		l = m.Context.Size()
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.Payload != nil {
+		l = m.Payload.Size()
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *SubscriptionRequest) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Prefixes) > 0 {
+		for _, b := range m.Prefixes {
+			l = len(b)
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *SubscriptionResponse) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Kvs != nil {
+		l = m.Kvs.Size()
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Num) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Val != 0 {
+		n += 1 + sovPb(uint64(m.Val))
+	}
+	if m.ReadOnly {
+		n += 2
+	}
+	if m.Forwarded {
+		n += 2
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *AssignedIds) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.StartId != 0 {
+		n += 1 + sovPb(uint64(m.StartId))
+	}
+	if m.EndId != 0 {
+		n += 1 + sovPb(uint64(m.EndId))
+	}
+	if m.ReadOnly != 0 {
+		n += 1 + sovPb(uint64(m.ReadOnly))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *SnapshotMeta) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.ClientTs != 0 {
+		n += 1 + sovPb(uint64(m.ClientTs))
+	}
+	if m.GroupId != 0 {
+		n += 1 + sovPb(uint64(m.GroupId))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Status) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Code != 0 {
+		n += 1 + sovPb(uint64(m.Code))
+	}
+	l = len(m.Msg)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *BackupRequest) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.ReadTs != 0 {
+		n += 1 + sovPb(uint64(m.ReadTs))
+	}
+	if m.SinceTs != 0 {
+		n += 1 + sovPb(uint64(m.SinceTs))
+	}
+	if m.GroupId != 0 {
+		n += 1 + sovPb(uint64(m.GroupId))
+	}
+	l = len(m.UnixTs)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.Destination)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.AccessKey)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.SecretKey)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.SessionToken)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.Anonymous {
+		n += 2
+	}
+	if len(m.Predicates) > 0 {
+		for _, s := range m.Predicates {
+			l = len(s)
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *ExportRequest) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.GroupId != 0 {
+		n += 1 + sovPb(uint64(m.GroupId))
+	}
+	if m.ReadTs != 0 {
+		n += 1 + sovPb(uint64(m.ReadTs))
+	}
+	if m.UnixTs != 0 {
+		n += 1 + sovPb(uint64(m.UnixTs))
+	}
+	l = len(m.Format)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.Destination)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.AccessKey)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.SecretKey)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.SessionToken)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.Anonymous {
+		n += 2
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *ExportResponse) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Code != 0 {
+		n += 1 + sovPb(uint64(m.Code))
+	}
+	l = len(m.Msg)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if len(m.Files) > 0 {
+		for _, s := range m.Files {
+			l = len(s)
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *BackupKey) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Type != 0 {
+		n += 1 + sovPb(uint64(m.Type))
+	}
+	l = len(m.Attr)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.Uid != 0 {
+		n += 1 + sovPb(uint64(m.Uid))
+	}
+	if m.StartUid != 0 {
+		n += 1 + sovPb(uint64(m.StartUid))
+	}
+	l = len(m.Term)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.Count != 0 {
+		n += 1 + sovPb(uint64(m.Count))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *BackupPostingList) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Uids) > 0 {
+		l = 0
+		for _, e := range m.Uids {
+			l += sovPb(uint64(e))
+		}
+		n += 1 + sovPb(uint64(l)) + l
+	}
+	if len(m.Postings) > 0 {
+		for _, e := range m.Postings {
+			l = e.Size()
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if m.CommitTs != 0 {
+		n += 1 + sovPb(uint64(m.CommitTs))
+	}
+	if len(m.Splits) > 0 {
+		l = 0
+		for _, e := range m.Splits {
+			l += sovPb(uint64(e))
+		}
+		n += 1 + sovPb(uint64(l)) + l
+	}
+	l = len(m.UidBytes)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *UpdateGraphQLSchemaRequest) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.StartTs != 0 {
+		n += 1 + sovPb(uint64(m.StartTs))
+	}
+	l = len(m.GraphqlSchema)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if len(m.DgraphPreds) > 0 {
+		for _, e := range m.DgraphPreds {
+			l = e.Size()
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if len(m.DgraphTypes) > 0 {
+		for _, e := range m.DgraphTypes {
+			l = e.Size()
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *UpdateGraphQLSchemaResponse) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Uid != 0 {
+		n += 1 + sovPb(uint64(m.Uid))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *IdempotencyRecord) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Key)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.Fingerprint != 0 {
+		n += 1 + sovPb(uint64(m.Fingerprint))
+	}
+	if m.StartTs != 0 {
+		n += 1 + sovPb(uint64(m.StartTs))
+	}
+	if m.CommitTs != 0 {
+		n += 1 + sovPb(uint64(m.CommitTs))
+	}
+	if m.ExpiresAt != 0 {
+		n += 1 + sovPb(uint64(m.ExpiresAt))
+	}
+	l = len(m.Response)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *StrictSchema) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Enabled {
+		n += 2
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *BlobChunk) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Attr)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.Uid != 0 {
+		n += 9
+	}
+	if m.Idx != 0 {
+		n += 1 + sovPb(uint64(m.Idx))
+	}
+	l = len(m.Data)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *BlobRequest) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Attr)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.Uid != 0 {
+		n += 9
+	}
+	if m.ReadTs != 0 {
+		n += 1 + sovPb(uint64(m.ReadTs))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *ReplicationState) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Primary)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.PrimaryTs != 0 {
+		n += 1 + sovPb(uint64(m.PrimaryTs))
+	}
+	if m.ReadTs != 0 {
+		n += 1 + sovPb(uint64(m.ReadTs))
+	}
+	if m.SnapshotAt != 0 {
+		n += 1 + sovPb(uint64(m.SnapshotAt))
+	}
+	if m.Promote {
+		n += 2
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *ReplicationRequest) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.GroupId != 0 {
+		n += 1 + sovPb(uint64(m.GroupId))
+	}
+	if m.SinceTs != 0 {
+		n += 1 + sovPb(uint64(m.SinceTs))
+	}
+	if m.ReadTs != 0 {
+		n += 1 + sovPb(uint64(m.ReadTs))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *KeyRange) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Start)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	l = len(m.End)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *FreezePoint) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Tag)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.ReadTs != 0 {
+		n += 1 + sovPb(uint64(m.ReadTs))
+	}
+	if m.CreatedAt != 0 {
+		n += 1 + sovPb(uint64(m.CreatedAt))
+	}
+	if len(m.Groups) > 0 {
+		for _, e := range m.Groups {
+			l = e.Size()
+			n += 1 + l + sovPb(uint64(l))
+		}
+	}
+	if m.Ready {
+		n += 2
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *FrozenGroup) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.GroupId != 0 {
+		n += 1 + sovPb(uint64(m.GroupId))
+	}
+	if m.ReadTs != 0 {
+		n += 1 + sovPb(uint64(m.ReadTs))
+	}
+	if m.NodeId != 0 {
+		n += 1 + sovPb(uint64(m.NodeId))
+	}
+	if m.Index != 0 {
+		n += 1 + sovPb(uint64(m.Index))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *MoveProgress) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Predicate)
+	if l > 0 {
+		n += 1 + l + sovPb(uint64(l))
+	}
+	if m.Keys != 0 {
+		n += 1 + sovPb(uint64(m.Keys))
+	}
+	if m.Bytes != 0 {
+		n += 1 + sovPb(uint64(m.Bytes))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func sovPb(x uint64) (n int) {
+	return (math_bits.Len64(x|1) + 6) / 7
+}
+func sozPb(x uint64) (n int) {
+	return sovPb(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+}
+func (m *List) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: List: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: List: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType == 1 {
+				var v uint64
+				if (iNdEx + 8) > l {
+					return io.ErrUnexpectedEOF
+				}
+				v = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
+				iNdEx += 8
+				m.Uids = append(m.Uids, v)
+			} else if wireType == 2 {
+				var packedLen int
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowPb
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					packedLen |= int(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				if packedLen < 0 {
+					return ErrInvalidLengthPb
+				}
+				postIndex := iNdEx + packedLen
+				if postIndex < 0 {
+					return ErrInvalidLengthPb
+				}
+				if postIndex > l {
+					return io.ErrUnexpectedEOF
+				}
+				var elementCount int
+				elementCount = packedLen / 8
+				if elementCount != 0 && len(m.Uids) == 0 {
+					m.Uids = make([]uint64, 0, elementCount)
+				}
+				for iNdEx < postIndex {
+					var v uint64
+					if (iNdEx + 8) > l {
+						return io.ErrUnexpectedEOF
+					}
+					v = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
+					iNdEx += 8
+					m.Uids = append(m.Uids, v)
+				}
+			} else {
+				return fmt.Errorf("proto: wrong wireType = %d for field Uids", wireType)
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *TaskValue) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: TaskValue: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: TaskValue: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Val", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Val = append(m.Val[:0], dAtA[iNdEx:postIndex]...)
+			if m.Val == nil {
+				m.Val = []byte{}
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ValType", wireType)
+			}
+			m.ValType = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ValType |= Posting_ValType(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *SrcFunction) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: SrcFunction: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: SrcFunction: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Name = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Args", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Args = append(m.Args, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field IsCount", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.IsCount = bool(v != 0)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Query) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Query: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Query: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Attr", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Attr = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Langs", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Langs = append(m.Langs, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		case 3:
+			if wireType != 1 {
+				return fmt.Errorf("proto: wrong wireType = %d for field AfterUid", wireType)
+			}
+			m.AfterUid = 0
+			if (iNdEx + 8) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.AfterUid = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
+			iNdEx += 8
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field DoCount", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.DoCount = bool(v != 0)
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field UidList", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.UidList == nil {
+				m.UidList = &List{}
+			}
+			if err := m.UidList.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 6:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SrcFunc", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.SrcFunc == nil {
+				m.SrcFunc = &SrcFunction{}
+			}
+			if err := m.SrcFunc.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Reverse", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Reverse = bool(v != 0)
+		case 8:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field FacetParam", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.FacetParam == nil {
+				m.FacetParam = &FacetParams{}
+			}
+			if err := m.FacetParam.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 9:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field FacetsFilter", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.FacetsFilter == nil {
+				m.FacetsFilter = &FilterTree{}
+			}
+			if err := m.FacetsFilter.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 10:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ExpandAll", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.ExpandAll = bool(v != 0)
+		case 13:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ReadTs", wireType)
+			}
+			m.ReadTs = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ReadTs |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 14:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Cache", wireType)
+			}
+			m.Cache = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Cache |= int32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 15:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field First", wireType)
+			}
+			m.First = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.First |= int32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 16:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field DistinctCount", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.DistinctCount = bool(v != 0)
+		case 17:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field EdgeFilter", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.EdgeFilter == nil {
+				m.EdgeFilter = &FilterTree{}
+			}
+			if err := m.EdgeFilter.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 18:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Sample", wireType)
+			}
+			m.Sample = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Sample |= int32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *ValueList) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: ValueList: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: ValueList: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Values", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Values = append(m.Values, &TaskValue{})
+			if err := m.Values[len(m.Values)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *LangList) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: LangList: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: LangList: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Lang", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Lang = append(m.Lang, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Result) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Result: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Result: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field UidMatrix", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.UidMatrix = append(m.UidMatrix, &List{})
+			if err := m.UidMatrix[len(m.UidMatrix)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ValueMatrix", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.ValueMatrix = append(m.ValueMatrix, &ValueList{})
+			if err := m.ValueMatrix[len(m.ValueMatrix)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 3:
+			if wireType == 0 {
+				var v uint32
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowPb
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					v |= uint32(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				m.Counts = append(m.Counts, v)
+			} else if wireType == 2 {
+				var packedLen int
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowPb
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					packedLen |= int(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				if packedLen < 0 {
+					return ErrInvalidLengthPb
+				}
+				postIndex := iNdEx + packedLen
+				if postIndex < 0 {
+					return ErrInvalidLengthPb
+				}
+				if postIndex > l {
+					return io.ErrUnexpectedEOF
+				}
+				var elementCount int
+				var count int
+				for _, integer := range dAtA[iNdEx:postIndex] {
+					if integer < 128 {
+						count++
+					}
+				}
+				elementCount = count
+				if elementCount != 0 && len(m.Counts) == 0 {
+					m.Counts = make([]uint32, 0, elementCount)
+				}
+				for iNdEx < postIndex {
+					var v uint32
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowPb
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						v |= uint32(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					m.Counts = append(m.Counts, v)
+				}
+			} else {
+				return fmt.Errorf("proto: wrong wireType = %d for field Counts", wireType)
+			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field IntersectDest", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.IntersectDest = bool(v != 0)
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field FacetMatrix", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.FacetMatrix = append(m.FacetMatrix, &FacetsList{})
+			if err := m.FacetMatrix[len(m.FacetMatrix)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 6:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LangMatrix", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.LangMatrix = append(m.LangMatrix, &LangList{})
+			if err := m.LangMatrix[len(m.LangMatrix)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field List", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.List = bool(v != 0)
+		case 8:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field DistinctBuckets", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.DistinctBuckets = append(m.DistinctBuckets, &List{})
+			if err := m.DistinctBuckets[len(m.DistinctBuckets)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Order) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Order: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Order: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Attr", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Attr = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Desc", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Desc = bool(v != 0)
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Langs", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Langs = append(m.Langs, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *SortMessage) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: SortMessage: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: SortMessage: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Order", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Order = append(m.Order, &Order{})
+			if err := m.Order[len(m.Order)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field UidMatrix", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.UidMatrix = append(m.UidMatrix, &List{})
+			if err := m.UidMatrix[len(m.UidMatrix)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Count", wireType)
+			}
+			m.Count = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Count |= int32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Offset", wireType)
+			}
+			m.Offset = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Offset |= int32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 13:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ReadTs", wireType)
+			}
+			m.ReadTs = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ReadTs |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *SortResult) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: SortResult: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: SortResult: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field UidMatrix", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.UidMatrix = append(m.UidMatrix, &List{})
+			if err := m.UidMatrix[len(m.UidMatrix)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *RaftContext) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: RaftContext: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: RaftContext: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 1 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Id", wireType)
+			}
+			m.Id = 0
+			if (iNdEx + 8) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Id = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
+			iNdEx += 8
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Group", wireType)
+			}
+			m.Group = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Group |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Addr", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Addr = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SnapshotTs", wireType)
+			}
+			m.SnapshotTs = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.SnapshotTs |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field IsLearner", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.IsLearner = bool(v != 0)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Member) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Member: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Member: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 1 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Id", wireType)
+			}
+			m.Id = 0
+			if (iNdEx + 8) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Id = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
+			iNdEx += 8
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field GroupId", wireType)
+			}
+			m.GroupId = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.GroupId |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Addr", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Addr = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Leader", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Leader = bool(v != 0)
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field AmDead", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.AmDead = bool(v != 0)
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LastUpdate", wireType)
+			}
+			m.LastUpdate = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.LastUpdate |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 13:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ClusterInfoOnly", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.ClusterInfoOnly = bool(v != 0)
+		case 14:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ForceGroupId", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.ForceGroupId = bool(v != 0)
+		case 15:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Learner", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Learner = bool(v != 0)
+		case 16:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Decommissioning", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Decommissioning = bool(v != 0)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Group) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Group: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Group: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Members", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Members == nil {
+				m.Members = make(map[uint64]*Member)
+			}
+			var mapkey uint64
+			var mapvalue *Member
+			for iNdEx < postIndex {
+				entryPreIndex := iNdEx
+				var wire uint64
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowPb
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					wire |= uint64(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				fieldNum := int32(wire >> 3)
+				if fieldNum == 1 {
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowPb
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						mapkey |= uint64(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+				} else if fieldNum == 2 {
+					var mapmsglen int
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowPb
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						mapmsglen |= int(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					if mapmsglen < 0 {
+						return ErrInvalidLengthPb
+					}
+					postmsgIndex := iNdEx + mapmsglen
+					if postmsgIndex < 0 {
+						return ErrInvalidLengthPb
+					}
+					if postmsgIndex > l {
+						return io.ErrUnexpectedEOF
+					}
+					mapvalue = &Member{}
+					if err := mapvalue.Unmarshal(dAtA[iNdEx:postmsgIndex]); err != nil {
+						return err
+					}
+					iNdEx = postmsgIndex
+				} else {
+					iNdEx = entryPreIndex
+					skippy, err := skipPb(dAtA[iNdEx:])
+					if err != nil {
+						return err
+					}
+					if skippy < 0 {
+						return ErrInvalidLengthPb
+					}
+					if (iNdEx + skippy) > postIndex {
+						return io.ErrUnexpectedEOF
+					}
+					iNdEx += skippy
+				}
+			}
+			m.Members[mapkey] = mapvalue
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Tablets", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Tablets == nil {
+				m.Tablets = make(map[string]*Tablet)
+			}
+			var mapkey string
+			var mapvalue *Tablet
+			for iNdEx < postIndex {
+				entryPreIndex := iNdEx
+				var wire uint64
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowPb
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					wire |= uint64(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				fieldNum := int32(wire >> 3)
+				if fieldNum == 1 {
+					var stringLenmapkey uint64
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowPb
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						stringLenmapkey |= uint64(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					intStringLenmapkey := int(stringLenmapkey)
+					if intStringLenmapkey < 0 {
+						return ErrInvalidLengthPb
+					}
+					postStringIndexmapkey := iNdEx + intStringLenmapkey
+					if postStringIndexmapkey < 0 {
+						return ErrInvalidLengthPb
+					}
+					if postStringIndexmapkey > l {
+						return io.ErrUnexpectedEOF
+					}
+					mapkey = string(dAtA[iNdEx:postStringIndexmapkey])
+					iNdEx = postStringIndexmapkey
+				} else if fieldNum == 2 {
+					var mapmsglen int
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowPb
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						mapmsglen |= int(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					if mapmsglen < 0 {
+						return ErrInvalidLengthPb
+					}
+					postmsgIndex := iNdEx + mapmsglen
+					if postmsgIndex < 0 {
+						return ErrInvalidLengthPb
+					}
+					if postmsgIndex > l {
+						return io.ErrUnexpectedEOF
+					}
+					mapvalue = &Tablet{}
+					if err := mapvalue.Unmarshal(dAtA[iNdEx:postmsgIndex]); err != nil {
+						return err
+					}
+					iNdEx = postmsgIndex
+				} else {
+					iNdEx = entryPreIndex
+					skippy, err := skipPb(dAtA[iNdEx:])
+					if err != nil {
+						return err
+					}
+					if skippy < 0 {
+						return ErrInvalidLengthPb
+					}
+					if (iNdEx + skippy) > postIndex {
+						return io.ErrUnexpectedEOF
+					}
+					iNdEx += skippy
+				}
+			}
+			m.Tablets[mapkey] = mapvalue
+			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SnapshotTs", wireType)
+			}
+			m.SnapshotTs = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.SnapshotTs |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Checksum", wireType)
+			}
+			m.Checksum = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Checksum |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Loads", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Loads = append(m.Loads, &Tablet{})
+			if err := m.Loads[len(m.Loads)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *License) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: License: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: License: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field User", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.User = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxNodes", wireType)
+			}
+			m.MaxNodes = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MaxNodes |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ExpiryTs", wireType)
+			}
+			m.ExpiryTs = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ExpiryTs |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Enabled", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Enabled = bool(v != 0)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *ZeroProposal) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: ZeroProposal: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: ZeroProposal: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SnapshotTs", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.SnapshotTs == nil {
+				m.SnapshotTs = make(map[uint32]uint64)
+			}
+			var mapkey uint32
+			var mapvalue uint64
+			for iNdEx < postIndex {
+				entryPreIndex := iNdEx
+				var wire uint64
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowPb
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					wire |= uint64(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				fieldNum := int32(wire >> 3)
+				if fieldNum == 1 {
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowPb
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						mapkey |= uint32(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+				} else if fieldNum == 2 {
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowPb
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						mapvalue |= uint64(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+				} else {
+					iNdEx = entryPreIndex
+					skippy, err := skipPb(dAtA[iNdEx:])
+					if err != nil {
+						return err
+					}
+					if skippy < 0 {
+						return ErrInvalidLengthPb
+					}
+					if (iNdEx + skippy) > postIndex {
+						return io.ErrUnexpectedEOF
+					}
+					iNdEx += skippy
+				}
+			}
+			m.SnapshotTs[mapkey] = mapvalue
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Member", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Member == nil {
+				m.Member = &Member{}
+			}
+			if err := m.Member.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Tablet", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Tablet == nil {
+				m.Tablet = &Tablet{}
+			}
+			if err := m.Tablet.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxLeaseId", wireType)
+			}
+			m.MaxLeaseId = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MaxLeaseId |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxTxnTs", wireType)
+			}
+			m.MaxTxnTs = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MaxTxnTs |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxRaftId", wireType)
+			}
+			m.MaxRaftId = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MaxRaftId |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 7:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Txn", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Txn == nil {
+				m.Txn = &api.TxnContext{}
+			}
+			if err := m.Txn.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 8:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Key = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 9:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Cid", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Cid = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 10:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field License", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.License == nil {
+				m.License = &License{}
+			}
+			if err := m.License.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 11:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Idempotency", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Idempotency == nil {
+				m.Idempotency = &IdempotencyRecord{}
+			}
+			if err := m.Idempotency.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 12:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field StrictSchema", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.StrictSchema == nil {
+				m.StrictSchema = &StrictSchema{}
+			}
+			if err := m.StrictSchema.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 13:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Replication", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Replication == nil {
+				m.Replication = &ReplicationState{}
+			}
+			if err := m.Replication.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 14:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Freeze", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Freeze == nil {
+				m.Freeze = &FreezePoint{}
+			}
+			if err := m.Freeze.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 15:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ReleaseFreeze", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.ReleaseFreeze = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 16:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field IdempotencyCutoff", wireType)
+			}
+			m.IdempotencyCutoff = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.IdempotencyCutoff |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *MembershipState) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: MembershipState: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: MembershipState: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Counter", wireType)
+			}
+			m.Counter = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Counter |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Groups", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Groups == nil {
+				m.Groups = make(map[uint32]*Group)
+			}
+			var mapkey uint32
+			var mapvalue *Group
+			for iNdEx < postIndex {
+				entryPreIndex := iNdEx
+				var wire uint64
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowPb
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					wire |= uint64(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				fieldNum := int32(wire >> 3)
+				if fieldNum == 1 {
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowPb
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						mapkey |= uint32(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+				} else if fieldNum == 2 {
+					var mapmsglen int
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowPb
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						mapmsglen |= int(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					if mapmsglen < 0 {
+						return ErrInvalidLengthPb
+					}
+					postmsgIndex := iNdEx + mapmsglen
+					if postmsgIndex < 0 {
+						return ErrInvalidLengthPb
+					}
+					if postmsgIndex > l {
+						return io.ErrUnexpectedEOF
+					}
+					mapvalue = &Group{}
+					if err := mapvalue.Unmarshal(dAtA[iNdEx:postmsgIndex]); err != nil {
+						return err
+					}
+					iNdEx = postmsgIndex
+				} else {
+					iNdEx = entryPreIndex
+					skippy, err := skipPb(dAtA[iNdEx:])
+					if err != nil {
+						return err
+					}
+					if skippy < 0 {
+						return ErrInvalidLengthPb
+					}
+					if (iNdEx + skippy) > postIndex {
+						return io.ErrUnexpectedEOF
+					}
+					iNdEx += skippy
+				}
+			}
+			m.Groups[mapkey] = mapvalue
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Zeros", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Zeros == nil {
+				m.Zeros = make(map[uint64]*Member)
+			}
+			var mapkey uint64
+			var mapvalue *Member
+			for iNdEx < postIndex {
+				entryPreIndex := iNdEx
+				var wire uint64
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowPb
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					wire |= uint64(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				fieldNum := int32(wire >> 3)
+				if fieldNum == 1 {
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowPb
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						mapkey |= uint64(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+				} else if fieldNum == 2 {
+					var mapmsglen int
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowPb
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						mapmsglen |= int(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					if mapmsglen < 0 {
+						return ErrInvalidLengthPb
+					}
+					postmsgIndex := iNdEx + mapmsglen
+					if postmsgIndex < 0 {
+						return ErrInvalidLengthPb
+					}
+					if postmsgIndex > l {
+						return io.ErrUnexpectedEOF
+					}
+					mapvalue = &Member{}
+					if err := mapvalue.Unmarshal(dAtA[iNdEx:postmsgIndex]); err != nil {
+						return err
+					}
+					iNdEx = postmsgIndex
+				} else {
+					iNdEx = entryPreIndex
+					skippy, err := skipPb(dAtA[iNdEx:])
+					if err != nil {
+						return err
+					}
+					if skippy < 0 {
+						return ErrInvalidLengthPb
+					}
+					if (iNdEx + skippy) > postIndex {
+						return io.ErrUnexpectedEOF
+					}
+					iNdEx += skippy
+				}
+			}
+			m.Zeros[mapkey] = mapvalue
+			iNdEx = postIndex
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxLeaseId", wireType)
+			}
+			m.MaxLeaseId = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MaxLeaseId |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxTxnTs", wireType)
+			}
+			m.MaxTxnTs = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MaxTxnTs |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxRaftId", wireType)
+			}
+			m.MaxRaftId = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MaxRaftId |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 7:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Removed", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Removed = append(m.Removed, &Member{})
+			if err := m.Removed[len(m.Removed)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 8:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Cid", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Cid = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 9:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field License", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.License == nil {
+				m.License = &License{}
+			}
+			if err := m.License.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 10:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Idempotency", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Idempotency = append(m.Idempotency, &IdempotencyRecord{})
+			if err := m.Idempotency[len(m.Idempotency)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 11:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field StrictSchema", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.StrictSchema == nil {
+				m.StrictSchema = &StrictSchema{}
+			}
+			if err := m.StrictSchema.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 12:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Replication", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Replication == nil {
+				m.Replication = &ReplicationState{}
+			}
+			if err := m.Replication.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 13:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field FreezePoints", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.FreezePoints = append(m.FreezePoints, &FreezePoint{})
+			if err := m.FreezePoints[len(m.FreezePoints)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
 
-func (m *UpdateGraphQLSchemaResponse) Size() (n int) {
-	if m == nil {
-		return 0
-	}
-	var l int
-	_ = l
-	if m.Uid != 0 {
-		n += 1 + sovPb(uint64(m.Uid))
-	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
 	}
-	return n
-}
-
-func sovPb(x uint64) (n int) {
-	return (math_bits.Len64(x|1) + 6) / 7
-}
-func sozPb(x uint64) (n int) {
-	return sovPb(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+	return nil
 }
-func (m *List) Unmarshal(dAtA []byte) error {
+func (m *ConnectionState) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -12453,63 +19890,102 @@ func (m *List) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: List: wiretype end group for non-group")
+			return fmt.Errorf("proto: ConnectionState: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: List: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: ConnectionState: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType == 1 {
-				var v uint64
-				if (iNdEx + 8) > l {
-					return io.ErrUnexpectedEOF
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Member", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
 				}
-				v = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
-				iNdEx += 8
-				m.Uids = append(m.Uids, v)
-			} else if wireType == 2 {
-				var packedLen int
-				for shift := uint(0); ; shift += 7 {
-					if shift >= 64 {
-						return ErrIntOverflowPb
-					}
-					if iNdEx >= l {
-						return io.ErrUnexpectedEOF
-					}
-					b := dAtA[iNdEx]
-					iNdEx++
-					packedLen |= int(b&0x7F) << shift
-					if b < 0x80 {
-						break
-					}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
 				}
-				if packedLen < 0 {
-					return ErrInvalidLengthPb
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
 				}
-				postIndex := iNdEx + packedLen
-				if postIndex < 0 {
-					return ErrInvalidLengthPb
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Member == nil {
+				m.Member = &Member{}
+			}
+			if err := m.Member.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field State", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
 				}
-				if postIndex > l {
+				if iNdEx >= l {
 					return io.ErrUnexpectedEOF
 				}
-				var elementCount int
-				elementCount = packedLen / 8
-				if elementCount != 0 && len(m.Uids) == 0 {
-					m.Uids = make([]uint64, 0, elementCount)
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
 				}
-				for iNdEx < postIndex {
-					var v uint64
-					if (iNdEx + 8) > l {
-						return io.ErrUnexpectedEOF
-					}
-					v = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
-					iNdEx += 8
-					m.Uids = append(m.Uids, v)
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.State == nil {
+				m.State = &MembershipState{}
+			}
+			if err := m.State.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxPending", wireType)
+			}
+			m.MaxPending = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MaxPending |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
 				}
-			} else {
-				return fmt.Errorf("proto: wrong wireType = %d for field Uids", wireType)
 			}
 		default:
 			iNdEx = preIndex
@@ -12536,7 +20012,7 @@ func (m *List) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *TaskValue) Unmarshal(dAtA []byte) error {
+func (m *HealthInfo) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -12559,17 +20035,113 @@ func (m *TaskValue) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: TaskValue: wiretype end group for non-group")
+			return fmt.Errorf("proto: HealthInfo: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: TaskValue: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: HealthInfo: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Val", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Instance", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Instance = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Address", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Address = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Status", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Status = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Group", wireType)
 			}
-			var byteLen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -12579,31 +20151,29 @@ func (m *TaskValue) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if byteLen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + byteLen
+			postIndex := iNdEx + intStringLen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Val = append(m.Val[:0], dAtA[iNdEx:postIndex]...)
-			if m.Val == nil {
-				m.Val = []byte{}
-			}
+			m.Group = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 2:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ValType", wireType)
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Version", wireType)
 			}
-			m.ValType = 0
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -12613,68 +20183,65 @@ func (m *TaskValue) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.ValType |= Posting_ValType(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		default:
-			iNdEx = preIndex
-			skippy, err := skipPb(dAtA[iNdEx:])
-			if err != nil {
-				return err
-			}
-			if skippy < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			if (iNdEx + skippy) < 0 {
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
-			if (iNdEx + skippy) > l {
+			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *SrcFunction) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowPb
+			m.Version = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Uptime", wireType)
 			}
-			if iNdEx >= l {
-				return io.ErrUnexpectedEOF
+			m.Uptime = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Uptime |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
 			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= uint64(b&0x7F) << shift
-			if b < 0x80 {
-				break
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LastEcho", wireType)
 			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: SrcFunction: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: SrcFunction: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
+			m.LastEcho = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.LastEcho |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 8:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Ongoing", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -12702,11 +20269,11 @@ func (m *SrcFunction) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Name = string(dAtA[iNdEx:postIndex])
+			m.Ongoing = append(m.Ongoing, string(dAtA[iNdEx:postIndex]))
 			iNdEx = postIndex
-		case 3:
+		case 9:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Args", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Indexing", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -12734,13 +20301,13 @@ func (m *SrcFunction) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Args = append(m.Args, string(dAtA[iNdEx:postIndex]))
+			m.Indexing = append(m.Indexing, string(dAtA[iNdEx:postIndex]))
 			iNdEx = postIndex
-		case 4:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field IsCount", wireType)
+		case 10:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field EeFeatures", wireType)
 			}
-			var v int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -12750,12 +20317,24 @@ func (m *SrcFunction) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			m.IsCount = bool(v != 0)
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.EeFeatures = append(m.EeFeatures, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipPb(dAtA[iNdEx:])
@@ -12781,7 +20360,7 @@ func (m *SrcFunction) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *Query) Unmarshal(dAtA []byte) error {
+func (m *Tablet) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -12804,17 +20383,17 @@ func (m *Query) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Query: wiretype end group for non-group")
+			return fmt.Errorf("proto: Tablet: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Query: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: Tablet: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Attr", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field GroupId", wireType)
 			}
-			var stringLen uint64
+			m.GroupId = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -12824,27 +20403,14 @@ func (m *Query) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				m.GroupId |= uint32(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthPb
-			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
-				return ErrInvalidLengthPb
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Attr = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
 		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Langs", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Predicate", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -12872,21 +20438,50 @@ func (m *Query) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Langs = append(m.Langs, string(dAtA[iNdEx:postIndex]))
+			m.Predicate = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
 		case 3:
-			if wireType != 1 {
-				return fmt.Errorf("proto: wrong wireType = %d for field AfterUid", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Force", wireType)
 			}
-			m.AfterUid = 0
-			if (iNdEx + 8) > l {
-				return io.ErrUnexpectedEOF
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
 			}
-			m.AfterUid = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
-			iNdEx += 8
-		case 4:
+			m.Force = bool(v != 0)
+		case 7:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field DoCount", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Space", wireType)
+			}
+			m.Space = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Space |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 8:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Remove", wireType)
 			}
 			var v int
 			for shift := uint(0); ; shift += 7 {
@@ -12903,12 +20498,32 @@ func (m *Query) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
-			m.DoCount = bool(v != 0)
-		case 5:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field UidList", wireType)
+			m.Remove = bool(v != 0)
+		case 9:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ReadOnly", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.ReadOnly = bool(v != 0)
+		case 10:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MoveTs", wireType)
 			}
-			var msglen int
+			m.MoveTs = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -12918,33 +20533,16 @@ func (m *Query) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				m.MoveTs |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
-				return ErrInvalidLengthPb
-			}
-			postIndex := iNdEx + msglen
-			if postIndex < 0 {
-				return ErrInvalidLengthPb
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			if m.UidList == nil {
-				m.UidList = &List{}
-			}
-			if err := m.UidList.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
-			iNdEx = postIndex
-		case 6:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field SrcFunc", wireType)
+		case 11:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field QueriesPerMin", wireType)
 			}
-			var msglen int
+			m.QueriesPerMin = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -12954,33 +20552,16 @@ func (m *Query) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				m.QueriesPerMin |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
-				return ErrInvalidLengthPb
-			}
-			postIndex := iNdEx + msglen
-			if postIndex < 0 {
-				return ErrInvalidLengthPb
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			if m.SrcFunc == nil {
-				m.SrcFunc = &SrcFunction{}
-			}
-			if err := m.SrcFunc.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
-			iNdEx = postIndex
-		case 7:
+		case 12:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Reverse", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field MutationsPerMin", wireType)
 			}
-			var v int
+			m.MutationsPerMin = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -12990,17 +20571,16 @@ func (m *Query) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= int(b&0x7F) << shift
+				m.MutationsPerMin |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			m.Reverse = bool(v != 0)
-		case 8:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field FacetParam", wireType)
+		case 13:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field NumKeys", wireType)
 			}
-			var msglen int
+			m.NumKeys = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -13010,33 +20590,80 @@ func (m *Query) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				m.NumKeys |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + msglen
-			if postIndex < 0 {
+			if (iNdEx + skippy) < 0 {
 				return ErrInvalidLengthPb
 			}
-			if postIndex > l {
+			if (iNdEx + skippy) > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.FacetParam == nil {
-				m.FacetParam = &FacetParams{}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *DirectedEdge) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
 			}
-			if err := m.FacetParam.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
 			}
-			iNdEx = postIndex
-		case 9:
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: DirectedEdge: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: DirectedEdge: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 1 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Entity", wireType)
+			}
+			m.Entity = 0
+			if (iNdEx + 8) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Entity = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
+			iNdEx += 8
+		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field FacetsFilter", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Attr", wireType)
 			}
-			var msglen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -13046,33 +20673,29 @@ func (m *Query) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + intStringLen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.FacetsFilter == nil {
-				m.FacetsFilter = &FilterTree{}
-			}
-			if err := m.FacetsFilter.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
+			m.Attr = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 10:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ExpandAll", wireType)
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Value", wireType)
 			}
-			var v int
+			var byteLen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -13082,17 +20705,31 @@ func (m *Query) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= int(b&0x7F) << shift
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			m.ExpandAll = bool(v != 0)
-		case 13:
+			if byteLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Value = append(m.Value[:0], dAtA[iNdEx:postIndex]...)
+			if m.Value == nil {
+				m.Value = []byte{}
+			}
+			iNdEx = postIndex
+		case 4:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ReadTs", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field ValueType", wireType)
 			}
-			m.ReadTs = 0
+			m.ValueType = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -13102,16 +20739,26 @@ func (m *Query) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.ReadTs |= uint64(b&0x7F) << shift
+				m.ValueType |= Posting_ValType(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 14:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Cache", wireType)
+		case 5:
+			if wireType != 1 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ValueId", wireType)
 			}
-			m.Cache = 0
+			m.ValueId = 0
+			if (iNdEx + 8) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.ValueId = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
+			iNdEx += 8
+		case 6:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Label", wireType)
+			}
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -13121,16 +20768,29 @@ func (m *Query) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Cache |= int32(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 15:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field First", wireType)
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
 			}
-			m.First = 0
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Label = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 7:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Lang", wireType)
+			}
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -13140,68 +20800,46 @@ func (m *Query) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.First |= int32(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		default:
-			iNdEx = preIndex
-			skippy, err := skipPb(dAtA[iNdEx:])
-			if err != nil {
-				return err
-			}
-			if skippy < 0 {
-				return ErrInvalidLengthPb
-			}
-			if (iNdEx + skippy) < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *ValueList) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowPb
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
 			}
-			if iNdEx >= l {
+			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= uint64(b&0x7F) << shift
-			if b < 0x80 {
-				break
+			m.Lang = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 8:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Op", wireType)
 			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: ValueList: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: ValueList: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
+			m.Op = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Op |= DirectedEdge_Op(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 9:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Values", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Facets", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -13228,68 +20866,14 @@ func (m *ValueList) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Values = append(m.Values, &TaskValue{})
-			if err := m.Values[len(m.Values)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+			m.Facets = append(m.Facets, &api.Facet{})
+			if err := m.Facets[len(m.Facets)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
 			iNdEx = postIndex
-		default:
-			iNdEx = preIndex
-			skippy, err := skipPb(dAtA[iNdEx:])
-			if err != nil {
-				return err
-			}
-			if skippy < 0 {
-				return ErrInvalidLengthPb
-			}
-			if (iNdEx + skippy) < 0 {
-				return ErrInvalidLengthPb
-			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *LangList) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowPb
-			}
-			if iNdEx >= l {
-				return io.ErrUnexpectedEOF
-			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= uint64(b&0x7F) << shift
-			if b < 0x80 {
-				break
-			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: LangList: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: LangList: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
+		case 10:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Lang", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field AllowedPreds", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -13317,8 +20901,27 @@ func (m *LangList) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Lang = append(m.Lang, string(dAtA[iNdEx:postIndex]))
+			m.AllowedPreds = append(m.AllowedPreds, string(dAtA[iNdEx:postIndex]))
 			iNdEx = postIndex
+		case 11:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field BlobSize", wireType)
+			}
+			m.BlobSize = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.BlobSize |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipPb(dAtA[iNdEx:])
@@ -13344,7 +20947,7 @@ func (m *LangList) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *Result) Unmarshal(dAtA []byte) error {
+func (m *Mutations) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -13367,17 +20970,17 @@ func (m *Result) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Result: wiretype end group for non-group")
+			return fmt.Errorf("proto: Mutations: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Result: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: Mutations: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field UidMatrix", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field GroupId", wireType)
 			}
-			var msglen int
+			m.GroupId = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -13387,29 +20990,33 @@ func (m *Result) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				m.GroupId |= uint32(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
-				return ErrInvalidLengthPb
-			}
-			postIndex := iNdEx + msglen
-			if postIndex < 0 {
-				return ErrInvalidLengthPb
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field StartTs", wireType)
 			}
-			m.UidMatrix = append(m.UidMatrix, &List{})
-			if err := m.UidMatrix[len(m.UidMatrix)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
+			m.StartTs = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.StartTs |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
 			}
-			iNdEx = postIndex
-		case 2:
+		case 3:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ValueMatrix", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Edges", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -13436,92 +21043,16 @@ func (m *Result) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.ValueMatrix = append(m.ValueMatrix, &ValueList{})
-			if err := m.ValueMatrix[len(m.ValueMatrix)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+			m.Edges = append(m.Edges, &DirectedEdge{})
+			if err := m.Edges[len(m.Edges)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
 			iNdEx = postIndex
-		case 3:
-			if wireType == 0 {
-				var v uint32
-				for shift := uint(0); ; shift += 7 {
-					if shift >= 64 {
-						return ErrIntOverflowPb
-					}
-					if iNdEx >= l {
-						return io.ErrUnexpectedEOF
-					}
-					b := dAtA[iNdEx]
-					iNdEx++
-					v |= uint32(b&0x7F) << shift
-					if b < 0x80 {
-						break
-					}
-				}
-				m.Counts = append(m.Counts, v)
-			} else if wireType == 2 {
-				var packedLen int
-				for shift := uint(0); ; shift += 7 {
-					if shift >= 64 {
-						return ErrIntOverflowPb
-					}
-					if iNdEx >= l {
-						return io.ErrUnexpectedEOF
-					}
-					b := dAtA[iNdEx]
-					iNdEx++
-					packedLen |= int(b&0x7F) << shift
-					if b < 0x80 {
-						break
-					}
-				}
-				if packedLen < 0 {
-					return ErrInvalidLengthPb
-				}
-				postIndex := iNdEx + packedLen
-				if postIndex < 0 {
-					return ErrInvalidLengthPb
-				}
-				if postIndex > l {
-					return io.ErrUnexpectedEOF
-				}
-				var elementCount int
-				var count int
-				for _, integer := range dAtA[iNdEx:postIndex] {
-					if integer < 128 {
-						count++
-					}
-				}
-				elementCount = count
-				if elementCount != 0 && len(m.Counts) == 0 {
-					m.Counts = make([]uint32, 0, elementCount)
-				}
-				for iNdEx < postIndex {
-					var v uint32
-					for shift := uint(0); ; shift += 7 {
-						if shift >= 64 {
-							return ErrIntOverflowPb
-						}
-						if iNdEx >= l {
-							return io.ErrUnexpectedEOF
-						}
-						b := dAtA[iNdEx]
-						iNdEx++
-						v |= uint32(b&0x7F) << shift
-						if b < 0x80 {
-							break
-						}
-					}
-					m.Counts = append(m.Counts, v)
-				}
-			} else {
-				return fmt.Errorf("proto: wrong wireType = %d for field Counts", wireType)
-			}
 		case 4:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field IntersectDest", wireType)
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Schema", wireType)
 			}
-			var v int
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -13531,15 +21062,29 @@ func (m *Result) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= int(b&0x7F) << shift
+				msglen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			m.IntersectDest = bool(v != 0)
-		case 5:
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Schema = append(m.Schema, &SchemaUpdate{})
+			if err := m.Schema[len(m.Schema)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 6:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field FacetMatrix", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Types", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -13566,14 +21111,65 @@ func (m *Result) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.FacetMatrix = append(m.FacetMatrix, &FacetsList{})
-			if err := m.FacetMatrix[len(m.FacetMatrix)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+			m.Types = append(m.Types, &TypeUpdate{})
+			if err := m.Types[len(m.Types)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
 			iNdEx = postIndex
-		case 6:
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field DropOp", wireType)
+			}
+			m.DropOp = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.DropOp |= Mutations_DropOp(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 8:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field LangMatrix", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field DropValue", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.DropValue = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 9:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Metadata", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -13600,16 +21196,18 @@ func (m *Result) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.LangMatrix = append(m.LangMatrix, &LangList{})
-			if err := m.LangMatrix[len(m.LangMatrix)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+			if m.Metadata == nil {
+				m.Metadata = &Metadata{}
+			}
+			if err := m.Metadata.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
 			iNdEx = postIndex
-		case 7:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field List", wireType)
+		case 10:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Savepoint", wireType)
 			}
-			var v int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -13619,69 +21217,27 @@ func (m *Result) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			m.List = bool(v != 0)
-		default:
-			iNdEx = preIndex
-			skippy, err := skipPb(dAtA[iNdEx:])
-			if err != nil {
-				return err
-			}
-			if skippy < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			if (iNdEx + skippy) < 0 {
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *Order) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowPb
-			}
-			if iNdEx >= l {
+			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= uint64(b&0x7F) << shift
-			if b < 0x80 {
-				break
-			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: Order: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Order: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
+			m.Savepoint = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 11:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Attr", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field RollbackTo", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -13709,13 +21265,13 @@ func (m *Order) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Attr = string(dAtA[iNdEx:postIndex])
+			m.RollbackTo = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 2:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Desc", wireType)
+		case 12:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field BlobChunks", wireType)
 			}
-			var v int
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -13725,15 +21281,29 @@ func (m *Order) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= int(b&0x7F) << shift
+				msglen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			m.Desc = bool(v != 0)
-		case 3:
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.BlobChunks = append(m.BlobChunks, &BlobChunk{})
+			if err := m.BlobChunks[len(m.BlobChunks)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 13:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Langs", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field RenameFrom", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -13761,67 +21331,45 @@ func (m *Order) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Langs = append(m.Langs, string(dAtA[iNdEx:postIndex]))
+			m.RenameFrom = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		default:
-			iNdEx = preIndex
-			skippy, err := skipPb(dAtA[iNdEx:])
-			if err != nil {
-				return err
+		case 14:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RenameTo", wireType)
 			}
-			if skippy < 0 {
-				return ErrInvalidLengthPb
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
 			}
-			if (iNdEx + skippy) < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *SortMessage) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowPb
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
 			}
-			if iNdEx >= l {
+			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= uint64(b&0x7F) << shift
-			if b < 0x80 {
-				break
-			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: SortMessage: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: SortMessage: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
+			m.RenameTo = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 15:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Order", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field TierPredicate", wireType)
 			}
-			var msglen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -13831,31 +21379,29 @@ func (m *SortMessage) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + intStringLen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Order = append(m.Order, &Order{})
-			if err := m.Order[len(m.Order)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
+			m.TierPredicate = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 2:
+		case 16:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field UidMatrix", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Tier", wireType)
 			}
-			var msglen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -13865,31 +21411,29 @@ func (m *SortMessage) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + intStringLen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.UidMatrix = append(m.UidMatrix, &List{})
-			if err := m.UidMatrix[len(m.UidMatrix)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
+			m.Tier = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 3:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Count", wireType)
+		case 17:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TierObject", wireType)
 			}
-			m.Count = 0
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -13899,16 +21443,29 @@ func (m *SortMessage) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Count |= int32(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 4:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Offset", wireType)
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
 			}
-			m.Offset = 0
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.TierObject = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 18:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field DictionaryPredicate", wireType)
+			}
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -13918,16 +21475,29 @@ func (m *SortMessage) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Offset |= int32(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 13:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ReadTs", wireType)
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
 			}
-			m.ReadTs = 0
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.DictionaryPredicate = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 19:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Dictionary", wireType)
+			}
+			var byteLen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -13937,11 +21507,26 @@ func (m *SortMessage) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.ReadTs |= uint64(b&0x7F) << shift
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
+			if byteLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Dictionary = append(m.Dictionary[:0], dAtA[iNdEx:postIndex]...)
+			if m.Dictionary == nil {
+				m.Dictionary = []byte{}
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipPb(dAtA[iNdEx:])
@@ -13967,7 +21552,7 @@ func (m *SortMessage) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *SortResult) Unmarshal(dAtA []byte) error {
+func (m *Metadata) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -13990,15 +21575,15 @@ func (m *SortResult) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: SortResult: wiretype end group for non-group")
+			return fmt.Errorf("proto: Metadata: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: SortResult: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: Metadata: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field UidMatrix", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field PredHints", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -14025,10 +21610,89 @@ func (m *SortResult) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.UidMatrix = append(m.UidMatrix, &List{})
-			if err := m.UidMatrix[len(m.UidMatrix)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
+			if m.PredHints == nil {
+				m.PredHints = make(map[string]Metadata_HintType)
+			}
+			var mapkey string
+			var mapvalue Metadata_HintType
+			for iNdEx < postIndex {
+				entryPreIndex := iNdEx
+				var wire uint64
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowPb
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					wire |= uint64(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				fieldNum := int32(wire >> 3)
+				if fieldNum == 1 {
+					var stringLenmapkey uint64
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowPb
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						stringLenmapkey |= uint64(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					intStringLenmapkey := int(stringLenmapkey)
+					if intStringLenmapkey < 0 {
+						return ErrInvalidLengthPb
+					}
+					postStringIndexmapkey := iNdEx + intStringLenmapkey
+					if postStringIndexmapkey < 0 {
+						return ErrInvalidLengthPb
+					}
+					if postStringIndexmapkey > l {
+						return io.ErrUnexpectedEOF
+					}
+					mapkey = string(dAtA[iNdEx:postStringIndexmapkey])
+					iNdEx = postStringIndexmapkey
+				} else if fieldNum == 2 {
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowPb
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						mapvalue |= Metadata_HintType(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+				} else {
+					iNdEx = entryPreIndex
+					skippy, err := skipPb(dAtA[iNdEx:])
+					if err != nil {
+						return err
+					}
+					if skippy < 0 {
+						return ErrInvalidLengthPb
+					}
+					if (iNdEx + skippy) > postIndex {
+						return io.ErrUnexpectedEOF
+					}
+					iNdEx += skippy
+				}
 			}
+			m.PredHints[mapkey] = mapvalue
 			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
@@ -14055,7 +21719,7 @@ func (m *SortResult) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *RaftContext) Unmarshal(dAtA []byte) error {
+func (m *Snapshot) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -14078,27 +21742,91 @@ func (m *RaftContext) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: RaftContext: wiretype end group for non-group")
+			return fmt.Errorf("proto: Snapshot: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: RaftContext: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: Snapshot: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 1 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Id", wireType)
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Context", wireType)
 			}
-			m.Id = 0
-			if (iNdEx + 8) > l {
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Id = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
-			iNdEx += 8
-		case 2:
+			if m.Context == nil {
+				m.Context = &RaftContext{}
+			}
+			if err := m.Context.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Index", wireType)
+			}
+			m.Index = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Index |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ReadTs", wireType)
+			}
+			m.ReadTs = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ReadTs |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Group", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Done", wireType)
 			}
-			m.Group = 0
+			var v int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -14108,16 +21836,17 @@ func (m *RaftContext) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Group |= uint32(b&0x7F) << shift
+				v |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 3:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Addr", wireType)
+			m.Done = bool(v != 0)
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SinceTs", wireType)
 			}
-			var stringLen uint64
+			m.SinceTs = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -14127,29 +21856,16 @@ func (m *RaftContext) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				m.SinceTs |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthPb
-			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
-				return ErrInvalidLengthPb
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Addr = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
-		case 4:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field SnapshotTs", wireType)
+		case 6:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Received", wireType)
 			}
-			m.SnapshotTs = 0
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -14159,11 +21875,26 @@ func (m *RaftContext) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.SnapshotTs |= uint64(b&0x7F) << shift
+				msglen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Received = append(m.Received, &KeyRange{})
+			if err := m.Received[len(m.Received)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipPb(dAtA[iNdEx:])
@@ -14189,7 +21920,7 @@ func (m *RaftContext) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *Member) Unmarshal(dAtA []byte) error {
+func (m *RestoreRequest) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -14212,23 +21943,13 @@ func (m *Member) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Member: wiretype end group for non-group")
+			return fmt.Errorf("proto: RestoreRequest: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Member: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: RestoreRequest: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 1 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Id", wireType)
-			}
-			m.Id = 0
-			if (iNdEx + 8) > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Id = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
-			iNdEx += 8
-		case 2:
 			if wireType != 0 {
 				return fmt.Errorf("proto: wrong wireType = %d for field GroupId", wireType)
 			}
@@ -14247,9 +21968,28 @@ func (m *Member) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RestoreTs", wireType)
+			}
+			m.RestoreTs = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.RestoreTs |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		case 3:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Addr", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Location", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -14277,13 +22017,13 @@ func (m *Member) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Addr = string(dAtA[iNdEx:postIndex])
+			m.Location = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
 		case 4:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Leader", wireType)
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field BackupId", wireType)
 			}
-			var v int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -14293,17 +22033,29 @@ func (m *Member) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			m.Leader = bool(v != 0)
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.BackupId = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
 		case 5:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field AmDead", wireType)
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field AccessKey", wireType)
 			}
-			var v int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -14313,17 +22065,29 @@ func (m *Member) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			m.AmDead = bool(v != 0)
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.AccessKey = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
 		case 6:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field LastUpdate", wireType)
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SecretKey", wireType)
 			}
-			m.LastUpdate = 0
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -14333,16 +22097,29 @@ func (m *Member) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.LastUpdate |= uint64(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 13:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ClusterInfoOnly", wireType)
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
 			}
-			var v int
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.SecretKey = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 7:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SessionToken", wireType)
+			}
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -14352,15 +22129,27 @@ func (m *Member) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			m.ClusterInfoOnly = bool(v != 0)
-		case 14:
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.SessionToken = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 8:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ForceGroupId", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Anonymous", wireType)
 			}
 			var v int
 			for shift := uint(0); ; shift += 7 {
@@ -14377,66 +22166,44 @@ func (m *Member) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
-			m.ForceGroupId = bool(v != 0)
-		default:
-			iNdEx = preIndex
-			skippy, err := skipPb(dAtA[iNdEx:])
-			if err != nil {
-				return err
+			m.Anonymous = bool(v != 0)
+		case 9:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field EncryptionKeyFile", wireType)
 			}
-			if skippy < 0 {
-				return ErrInvalidLengthPb
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
 			}
-			if (iNdEx + skippy) < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *Group) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowPb
-			}
-			if iNdEx >= l {
-				return io.ErrUnexpectedEOF
-			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= uint64(b&0x7F) << shift
-			if b < 0x80 {
-				break
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
 			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: Group: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Group: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.EncryptionKeyFile = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 10:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Members", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field VaultAddr", wireType)
 			}
-			var msglen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -14446,112 +22213,29 @@ func (m *Group) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + intStringLen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.Members == nil {
-				m.Members = make(map[uint64]*Member)
-			}
-			var mapkey uint64
-			var mapvalue *Member
-			for iNdEx < postIndex {
-				entryPreIndex := iNdEx
-				var wire uint64
-				for shift := uint(0); ; shift += 7 {
-					if shift >= 64 {
-						return ErrIntOverflowPb
-					}
-					if iNdEx >= l {
-						return io.ErrUnexpectedEOF
-					}
-					b := dAtA[iNdEx]
-					iNdEx++
-					wire |= uint64(b&0x7F) << shift
-					if b < 0x80 {
-						break
-					}
-				}
-				fieldNum := int32(wire >> 3)
-				if fieldNum == 1 {
-					for shift := uint(0); ; shift += 7 {
-						if shift >= 64 {
-							return ErrIntOverflowPb
-						}
-						if iNdEx >= l {
-							return io.ErrUnexpectedEOF
-						}
-						b := dAtA[iNdEx]
-						iNdEx++
-						mapkey |= uint64(b&0x7F) << shift
-						if b < 0x80 {
-							break
-						}
-					}
-				} else if fieldNum == 2 {
-					var mapmsglen int
-					for shift := uint(0); ; shift += 7 {
-						if shift >= 64 {
-							return ErrIntOverflowPb
-						}
-						if iNdEx >= l {
-							return io.ErrUnexpectedEOF
-						}
-						b := dAtA[iNdEx]
-						iNdEx++
-						mapmsglen |= int(b&0x7F) << shift
-						if b < 0x80 {
-							break
-						}
-					}
-					if mapmsglen < 0 {
-						return ErrInvalidLengthPb
-					}
-					postmsgIndex := iNdEx + mapmsglen
-					if postmsgIndex < 0 {
-						return ErrInvalidLengthPb
-					}
-					if postmsgIndex > l {
-						return io.ErrUnexpectedEOF
-					}
-					mapvalue = &Member{}
-					if err := mapvalue.Unmarshal(dAtA[iNdEx:postmsgIndex]); err != nil {
-						return err
-					}
-					iNdEx = postmsgIndex
-				} else {
-					iNdEx = entryPreIndex
-					skippy, err := skipPb(dAtA[iNdEx:])
-					if err != nil {
-						return err
-					}
-					if skippy < 0 {
-						return ErrInvalidLengthPb
-					}
-					if (iNdEx + skippy) > postIndex {
-						return io.ErrUnexpectedEOF
-					}
-					iNdEx += skippy
-				}
-			}
-			m.Members[mapkey] = mapvalue
+			m.VaultAddr = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 2:
+		case 11:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Tablets", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field VaultRoleidFile", wireType)
 			}
-			var msglen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -14561,126 +22245,29 @@ func (m *Group) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + intStringLen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.Tablets == nil {
-				m.Tablets = make(map[string]*Tablet)
-			}
-			var mapkey string
-			var mapvalue *Tablet
-			for iNdEx < postIndex {
-				entryPreIndex := iNdEx
-				var wire uint64
-				for shift := uint(0); ; shift += 7 {
-					if shift >= 64 {
-						return ErrIntOverflowPb
-					}
-					if iNdEx >= l {
-						return io.ErrUnexpectedEOF
-					}
-					b := dAtA[iNdEx]
-					iNdEx++
-					wire |= uint64(b&0x7F) << shift
-					if b < 0x80 {
-						break
-					}
-				}
-				fieldNum := int32(wire >> 3)
-				if fieldNum == 1 {
-					var stringLenmapkey uint64
-					for shift := uint(0); ; shift += 7 {
-						if shift >= 64 {
-							return ErrIntOverflowPb
-						}
-						if iNdEx >= l {
-							return io.ErrUnexpectedEOF
-						}
-						b := dAtA[iNdEx]
-						iNdEx++
-						stringLenmapkey |= uint64(b&0x7F) << shift
-						if b < 0x80 {
-							break
-						}
-					}
-					intStringLenmapkey := int(stringLenmapkey)
-					if intStringLenmapkey < 0 {
-						return ErrInvalidLengthPb
-					}
-					postStringIndexmapkey := iNdEx + intStringLenmapkey
-					if postStringIndexmapkey < 0 {
-						return ErrInvalidLengthPb
-					}
-					if postStringIndexmapkey > l {
-						return io.ErrUnexpectedEOF
-					}
-					mapkey = string(dAtA[iNdEx:postStringIndexmapkey])
-					iNdEx = postStringIndexmapkey
-				} else if fieldNum == 2 {
-					var mapmsglen int
-					for shift := uint(0); ; shift += 7 {
-						if shift >= 64 {
-							return ErrIntOverflowPb
-						}
-						if iNdEx >= l {
-							return io.ErrUnexpectedEOF
-						}
-						b := dAtA[iNdEx]
-						iNdEx++
-						mapmsglen |= int(b&0x7F) << shift
-						if b < 0x80 {
-							break
-						}
-					}
-					if mapmsglen < 0 {
-						return ErrInvalidLengthPb
-					}
-					postmsgIndex := iNdEx + mapmsglen
-					if postmsgIndex < 0 {
-						return ErrInvalidLengthPb
-					}
-					if postmsgIndex > l {
-						return io.ErrUnexpectedEOF
-					}
-					mapvalue = &Tablet{}
-					if err := mapvalue.Unmarshal(dAtA[iNdEx:postmsgIndex]); err != nil {
-						return err
-					}
-					iNdEx = postmsgIndex
-				} else {
-					iNdEx = entryPreIndex
-					skippy, err := skipPb(dAtA[iNdEx:])
-					if err != nil {
-						return err
-					}
-					if skippy < 0 {
-						return ErrInvalidLengthPb
-					}
-					if (iNdEx + skippy) > postIndex {
-						return io.ErrUnexpectedEOF
-					}
-					iNdEx += skippy
-				}
-			}
-			m.Tablets[mapkey] = mapvalue
+			m.VaultRoleidFile = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 3:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field SnapshotTs", wireType)
+		case 12:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field VaultSecretidFile", wireType)
 			}
-			m.SnapshotTs = 0
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -14690,16 +22277,29 @@ func (m *Group) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.SnapshotTs |= uint64(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 4:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Checksum", wireType)
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.VaultSecretidFile = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 13:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field VaultPath", wireType)
 			}
-			m.Checksum = 0
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -14709,68 +22309,27 @@ func (m *Group) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Checksum |= uint64(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		default:
-			iNdEx = preIndex
-			skippy, err := skipPb(dAtA[iNdEx:])
-			if err != nil {
-				return err
-			}
-			if skippy < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			if (iNdEx + skippy) < 0 {
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *License) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowPb
-			}
-			if iNdEx >= l {
+			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= uint64(b&0x7F) << shift
-			if b < 0x80 {
-				break
-			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: License: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: License: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
+			m.VaultPath = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 14:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field User", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field VaultField", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -14798,13 +22357,13 @@ func (m *License) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.User = string(dAtA[iNdEx:postIndex])
+			m.VaultField = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 2:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field MaxNodes", wireType)
+		case 15:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field VaultFormat", wireType)
 			}
-			m.MaxNodes = 0
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -14814,35 +22373,29 @@ func (m *License) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.MaxNodes |= uint64(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 3:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ExpiryTs", wireType)
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
 			}
-			m.ExpiryTs = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.ExpiryTs |= int64(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
 			}
-		case 4:
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.VaultFormat = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 16:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Enabled", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field BackupNum", wireType)
 			}
-			var v int
+			m.BackupNum = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -14852,12 +22405,11 @@ func (m *License) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= int(b&0x7F) << shift
+				m.BackupNum |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			m.Enabled = bool(v != 0)
 		default:
 			iNdEx = preIndex
 			skippy, err := skipPb(dAtA[iNdEx:])
@@ -14883,7 +22435,7 @@ func (m *License) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *ZeroProposal) Unmarshal(dAtA []byte) error {
+func (m *Proposal) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -14906,15 +22458,15 @@ func (m *ZeroProposal) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: ZeroProposal: wiretype end group for non-group")
+			return fmt.Errorf("proto: Proposal: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: ZeroProposal: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: Proposal: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
-		case 1:
+		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field SnapshotTs", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Mutations", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -14941,81 +22493,88 @@ func (m *ZeroProposal) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.SnapshotTs == nil {
-				m.SnapshotTs = make(map[uint32]uint64)
+			if m.Mutations == nil {
+				m.Mutations = &Mutations{}
 			}
-			var mapkey uint32
-			var mapvalue uint64
-			for iNdEx < postIndex {
-				entryPreIndex := iNdEx
-				var wire uint64
-				for shift := uint(0); ; shift += 7 {
-					if shift >= 64 {
-						return ErrIntOverflowPb
-					}
-					if iNdEx >= l {
-						return io.ErrUnexpectedEOF
-					}
-					b := dAtA[iNdEx]
-					iNdEx++
-					wire |= uint64(b&0x7F) << shift
-					if b < 0x80 {
-						break
-					}
+			if err := m.Mutations.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Kv", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
 				}
-				fieldNum := int32(wire >> 3)
-				if fieldNum == 1 {
-					for shift := uint(0); ; shift += 7 {
-						if shift >= 64 {
-							return ErrIntOverflowPb
-						}
-						if iNdEx >= l {
-							return io.ErrUnexpectedEOF
-						}
-						b := dAtA[iNdEx]
-						iNdEx++
-						mapkey |= uint32(b&0x7F) << shift
-						if b < 0x80 {
-							break
-						}
-					}
-				} else if fieldNum == 2 {
-					for shift := uint(0); ; shift += 7 {
-						if shift >= 64 {
-							return ErrIntOverflowPb
-						}
-						if iNdEx >= l {
-							return io.ErrUnexpectedEOF
-						}
-						b := dAtA[iNdEx]
-						iNdEx++
-						mapvalue |= uint64(b&0x7F) << shift
-						if b < 0x80 {
-							break
-						}
-					}
-				} else {
-					iNdEx = entryPreIndex
-					skippy, err := skipPb(dAtA[iNdEx:])
-					if err != nil {
-						return err
-					}
-					if skippy < 0 {
-						return ErrInvalidLengthPb
-					}
-					if (iNdEx + skippy) > postIndex {
-						return io.ErrUnexpectedEOF
-					}
-					iNdEx += skippy
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Kv = append(m.Kv, &pb.KV{})
+			if err := m.Kv[len(m.Kv)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field State", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
 				}
 			}
-			m.SnapshotTs[mapkey] = mapvalue
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.State == nil {
+				m.State = &MembershipState{}
+			}
+			if err := m.State.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
 			iNdEx = postIndex
-		case 2:
+		case 6:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Member", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field CleanPredicate", wireType)
 			}
-			var msglen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -15025,31 +22584,59 @@ func (m *ZeroProposal) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + intStringLen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.Member == nil {
-				m.Member = &Member{}
+			m.CleanPredicate = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 7:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
 			}
-			if err := m.Member.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
 			}
+			m.Key = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 3:
+		case 8:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Tablet", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Delta", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -15076,18 +22663,18 @@ func (m *ZeroProposal) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.Tablet == nil {
-				m.Tablet = &Tablet{}
+			if m.Delta == nil {
+				m.Delta = &OracleDelta{}
 			}
-			if err := m.Tablet.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+			if err := m.Delta.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
 			iNdEx = postIndex
-		case 4:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field MaxLeaseId", wireType)
+		case 9:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Snapshot", wireType)
 			}
-			m.MaxLeaseId = 0
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -15097,16 +22684,33 @@ func (m *ZeroProposal) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.MaxLeaseId |= uint64(b&0x7F) << shift
+				msglen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 5:
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Snapshot == nil {
+				m.Snapshot = &Snapshot{}
+			}
+			if err := m.Snapshot.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 10:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field MaxTxnTs", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Index", wireType)
 			}
-			m.MaxTxnTs = 0
+			m.Index = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -15116,16 +22720,16 @@ func (m *ZeroProposal) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.MaxTxnTs |= uint64(b&0x7F) << shift
+				m.Index |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 6:
+		case 11:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field MaxRaftId", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field ExpectedChecksum", wireType)
 			}
-			m.MaxRaftId = 0
+			m.ExpectedChecksum = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -15135,14 +22739,14 @@ func (m *ZeroProposal) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.MaxRaftId |= uint64(b&0x7F) << shift
+				m.ExpectedChecksum |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 7:
+		case 12:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Txn", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Restore", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -15169,16 +22773,16 @@ func (m *ZeroProposal) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.Txn == nil {
-				m.Txn = &api.TxnContext{}
+			if m.Restore == nil {
+				m.Restore = &RestoreRequest{}
 			}
-			if err := m.Txn.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+			if err := m.Restore.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
 			iNdEx = postIndex
-		case 8:
+		case 13:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field TierAccessed", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -15206,11 +22810,119 @@ func (m *ZeroProposal) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Key = string(dAtA[iNdEx:postIndex])
+			m.TierAccessed = append(m.TierAccessed, string(dAtA[iNdEx:postIndex]))
 			iNdEx = postIndex
-		case 9:
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *KVS) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: KVS: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: KVS: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Cid", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Kv", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Kv = append(m.Kv, &pb.KV{})
+			if err := m.Kv[len(m.Kv)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Done", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Done = bool(v != 0)
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Predicates", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -15238,13 +22950,13 @@ func (m *ZeroProposal) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Cid = string(dAtA[iNdEx:postIndex])
+			m.Predicates = append(m.Predicates, string(dAtA[iNdEx:postIndex]))
 			iNdEx = postIndex
-		case 10:
+		case 4:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field License", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Types", wireType)
 			}
-			var msglen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -15254,27 +22966,23 @@ func (m *ZeroProposal) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + intStringLen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.License == nil {
-				m.License = &License{}
-			}
-			if err := m.License.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
+			m.Types = append(m.Types, string(dAtA[iNdEx:postIndex]))
 			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
@@ -15301,7 +23009,7 @@ func (m *ZeroProposal) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *MembershipState) Unmarshal(dAtA []byte) error {
+func (m *Posting) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -15324,151 +23032,27 @@ func (m *MembershipState) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: MembershipState: wiretype end group for non-group")
+			return fmt.Errorf("proto: Posting: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: MembershipState: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: Posting: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Counter", wireType)
-			}
-			m.Counter = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.Counter |= uint64(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-		case 2:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Groups", wireType)
-			}
-			var msglen int
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				msglen |= int(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-			if msglen < 0 {
-				return ErrInvalidLengthPb
-			}
-			postIndex := iNdEx + msglen
-			if postIndex < 0 {
-				return ErrInvalidLengthPb
+			if wireType != 1 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Uid", wireType)
 			}
-			if postIndex > l {
+			m.Uid = 0
+			if (iNdEx + 8) > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.Groups == nil {
-				m.Groups = make(map[uint32]*Group)
-			}
-			var mapkey uint32
-			var mapvalue *Group
-			for iNdEx < postIndex {
-				entryPreIndex := iNdEx
-				var wire uint64
-				for shift := uint(0); ; shift += 7 {
-					if shift >= 64 {
-						return ErrIntOverflowPb
-					}
-					if iNdEx >= l {
-						return io.ErrUnexpectedEOF
-					}
-					b := dAtA[iNdEx]
-					iNdEx++
-					wire |= uint64(b&0x7F) << shift
-					if b < 0x80 {
-						break
-					}
-				}
-				fieldNum := int32(wire >> 3)
-				if fieldNum == 1 {
-					for shift := uint(0); ; shift += 7 {
-						if shift >= 64 {
-							return ErrIntOverflowPb
-						}
-						if iNdEx >= l {
-							return io.ErrUnexpectedEOF
-						}
-						b := dAtA[iNdEx]
-						iNdEx++
-						mapkey |= uint32(b&0x7F) << shift
-						if b < 0x80 {
-							break
-						}
-					}
-				} else if fieldNum == 2 {
-					var mapmsglen int
-					for shift := uint(0); ; shift += 7 {
-						if shift >= 64 {
-							return ErrIntOverflowPb
-						}
-						if iNdEx >= l {
-							return io.ErrUnexpectedEOF
-						}
-						b := dAtA[iNdEx]
-						iNdEx++
-						mapmsglen |= int(b&0x7F) << shift
-						if b < 0x80 {
-							break
-						}
-					}
-					if mapmsglen < 0 {
-						return ErrInvalidLengthPb
-					}
-					postmsgIndex := iNdEx + mapmsglen
-					if postmsgIndex < 0 {
-						return ErrInvalidLengthPb
-					}
-					if postmsgIndex > l {
-						return io.ErrUnexpectedEOF
-					}
-					mapvalue = &Group{}
-					if err := mapvalue.Unmarshal(dAtA[iNdEx:postmsgIndex]); err != nil {
-						return err
-					}
-					iNdEx = postmsgIndex
-				} else {
-					iNdEx = entryPreIndex
-					skippy, err := skipPb(dAtA[iNdEx:])
-					if err != nil {
-						return err
-					}
-					if skippy < 0 {
-						return ErrInvalidLengthPb
-					}
-					if (iNdEx + skippy) > postIndex {
-						return io.ErrUnexpectedEOF
-					}
-					iNdEx += skippy
-				}
-			}
-			m.Groups[mapkey] = mapvalue
-			iNdEx = postIndex
-		case 3:
+			m.Uid = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
+			iNdEx += 8
+		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Zeros", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Value", wireType)
 			}
-			var msglen int
+			var byteLen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -15478,131 +23062,31 @@ func (m *MembershipState) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			if byteLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + byteLen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.Zeros == nil {
-				m.Zeros = make(map[uint64]*Member)
-			}
-			var mapkey uint64
-			var mapvalue *Member
-			for iNdEx < postIndex {
-				entryPreIndex := iNdEx
-				var wire uint64
-				for shift := uint(0); ; shift += 7 {
-					if shift >= 64 {
-						return ErrIntOverflowPb
-					}
-					if iNdEx >= l {
-						return io.ErrUnexpectedEOF
-					}
-					b := dAtA[iNdEx]
-					iNdEx++
-					wire |= uint64(b&0x7F) << shift
-					if b < 0x80 {
-						break
-					}
-				}
-				fieldNum := int32(wire >> 3)
-				if fieldNum == 1 {
-					for shift := uint(0); ; shift += 7 {
-						if shift >= 64 {
-							return ErrIntOverflowPb
-						}
-						if iNdEx >= l {
-							return io.ErrUnexpectedEOF
-						}
-						b := dAtA[iNdEx]
-						iNdEx++
-						mapkey |= uint64(b&0x7F) << shift
-						if b < 0x80 {
-							break
-						}
-					}
-				} else if fieldNum == 2 {
-					var mapmsglen int
-					for shift := uint(0); ; shift += 7 {
-						if shift >= 64 {
-							return ErrIntOverflowPb
-						}
-						if iNdEx >= l {
-							return io.ErrUnexpectedEOF
-						}
-						b := dAtA[iNdEx]
-						iNdEx++
-						mapmsglen |= int(b&0x7F) << shift
-						if b < 0x80 {
-							break
-						}
-					}
-					if mapmsglen < 0 {
-						return ErrInvalidLengthPb
-					}
-					postmsgIndex := iNdEx + mapmsglen
-					if postmsgIndex < 0 {
-						return ErrInvalidLengthPb
-					}
-					if postmsgIndex > l {
-						return io.ErrUnexpectedEOF
-					}
-					mapvalue = &Member{}
-					if err := mapvalue.Unmarshal(dAtA[iNdEx:postmsgIndex]); err != nil {
-						return err
-					}
-					iNdEx = postmsgIndex
-				} else {
-					iNdEx = entryPreIndex
-					skippy, err := skipPb(dAtA[iNdEx:])
-					if err != nil {
-						return err
-					}
-					if skippy < 0 {
-						return ErrInvalidLengthPb
-					}
-					if (iNdEx + skippy) > postIndex {
-						return io.ErrUnexpectedEOF
-					}
-					iNdEx += skippy
-				}
-			}
-			m.Zeros[mapkey] = mapvalue
-			iNdEx = postIndex
-		case 4:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field MaxLeaseId", wireType)
-			}
-			m.MaxLeaseId = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.MaxLeaseId |= uint64(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
+			m.Value = append(m.Value[:0], dAtA[iNdEx:postIndex]...)
+			if m.Value == nil {
+				m.Value = []byte{}
 			}
-		case 5:
+			iNdEx = postIndex
+		case 3:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field MaxTxnTs", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field ValType", wireType)
 			}
-			m.MaxTxnTs = 0
+			m.ValType = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -15612,16 +23096,16 @@ func (m *MembershipState) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.MaxTxnTs |= uint64(b&0x7F) << shift
+				m.ValType |= Posting_ValType(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 6:
+		case 4:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field MaxRaftId", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field PostingType", wireType)
 			}
-			m.MaxRaftId = 0
+			m.PostingType = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -15631,16 +23115,16 @@ func (m *MembershipState) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.MaxRaftId |= uint64(b&0x7F) << shift
+				m.PostingType |= Posting_PostingType(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 7:
+		case 5:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Removed", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field LangTag", wireType)
 			}
-			var msglen int
+			var byteLen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -15650,29 +23134,29 @@ func (m *MembershipState) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			if byteLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + byteLen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Removed = append(m.Removed, &Member{})
-			if err := m.Removed[len(m.Removed)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
+			m.LangTag = append(m.LangTag[:0], dAtA[iNdEx:postIndex]...)
+			if m.LangTag == nil {
+				m.LangTag = []byte{}
 			}
 			iNdEx = postIndex
-		case 8:
+		case 6:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Cid", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Label", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -15700,11 +23184,11 @@ func (m *MembershipState) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Cid = string(dAtA[iNdEx:postIndex])
+			m.Label = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
 		case 9:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field License", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Facets", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -15731,13 +23215,106 @@ func (m *MembershipState) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.License == nil {
-				m.License = &License{}
-			}
-			if err := m.License.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+			m.Facets = append(m.Facets, &api.Facet{})
+			if err := m.Facets[len(m.Facets)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
 			iNdEx = postIndex
+		case 12:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Op", wireType)
+			}
+			m.Op = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Op |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 13:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field StartTs", wireType)
+			}
+			m.StartTs = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.StartTs |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 14:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field CommitTs", wireType)
+			}
+			m.CommitTs = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.CommitTs |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 15:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field BlobSize", wireType)
+			}
+			m.BlobSize = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.BlobSize |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 16:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Encoding", wireType)
+			}
+			m.Encoding = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Encoding |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipPb(dAtA[iNdEx:])
@@ -15763,7 +23340,7 @@ func (m *MembershipState) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *ConnectionState) Unmarshal(dAtA []byte) error {
+func (m *UidBlock) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -15786,17 +23363,17 @@ func (m *ConnectionState) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: ConnectionState: wiretype end group for non-group")
+			return fmt.Errorf("proto: UidBlock: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: ConnectionState: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: UidBlock: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Member", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Base", wireType)
 			}
-			var msglen int
+			m.Base = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -15806,33 +23383,16 @@ func (m *ConnectionState) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				m.Base |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
-				return ErrInvalidLengthPb
-			}
-			postIndex := iNdEx + msglen
-			if postIndex < 0 {
-				return ErrInvalidLengthPb
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			if m.Member == nil {
-				m.Member = &Member{}
-			}
-			if err := m.Member.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
-			iNdEx = postIndex
 		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field State", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Deltas", wireType)
 			}
-			var msglen int
+			var byteLen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -15842,33 +23402,31 @@ func (m *ConnectionState) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			if byteLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + byteLen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.State == nil {
-				m.State = &MembershipState{}
-			}
-			if err := m.State.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
+			m.Deltas = append(m.Deltas[:0], dAtA[iNdEx:postIndex]...)
+			if m.Deltas == nil {
+				m.Deltas = []byte{}
 			}
 			iNdEx = postIndex
 		case 3:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field MaxPending", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field NumUids", wireType)
 			}
-			m.MaxPending = 0
+			m.NumUids = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -15878,11 +23436,45 @@ func (m *ConnectionState) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.MaxPending |= uint64(b&0x7F) << shift
+				m.NumUids |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Bitmap", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
+			if byteLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Bitmap = append(m.Bitmap[:0], dAtA[iNdEx:postIndex]...)
+			if m.Bitmap == nil {
+				m.Bitmap = []byte{}
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipPb(dAtA[iNdEx:])
@@ -15908,7 +23500,7 @@ func (m *ConnectionState) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *HealthInfo) Unmarshal(dAtA []byte) error {
+func (m *UidPack) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -15931,17 +23523,17 @@ func (m *HealthInfo) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: HealthInfo: wiretype end group for non-group")
+			return fmt.Errorf("proto: UidPack: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: HealthInfo: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: UidPack: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Instance", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field BlockSize", wireType)
 			}
-			var stringLen uint64
+			m.BlockSize = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -15951,29 +23543,16 @@ func (m *HealthInfo) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				m.BlockSize |= uint32(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthPb
-			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
-				return ErrInvalidLengthPb
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Instance = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
 		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Address", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Blocks", wireType)
 			}
-			var stringLen uint64
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -15983,29 +23562,31 @@ func (m *HealthInfo) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				msglen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
+			if msglen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + intStringLen
+			postIndex := iNdEx + msglen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Address = string(dAtA[iNdEx:postIndex])
+			m.Blocks = append(m.Blocks, &UidBlock{})
+			if err := m.Blocks[len(m.Blocks)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
 			iNdEx = postIndex
-		case 3:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Status", wireType)
+		case 23:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field AllocRef", wireType)
 			}
-			var stringLen uint64
+			m.AllocRef = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -16015,29 +23596,70 @@ func (m *HealthInfo) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				m.AllocRef |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
+			if (iNdEx + skippy) < 0 {
 				return ErrInvalidLengthPb
 			}
-			if postIndex > l {
+			if (iNdEx + skippy) > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Status = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
-		case 4:
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *PostingList) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: PostingList: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: PostingList: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Group", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Pack", wireType)
 			}
-			var stringLen uint64
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -16047,29 +23669,33 @@ func (m *HealthInfo) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				msglen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
+			if msglen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + intStringLen
+			postIndex := iNdEx + msglen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Group = string(dAtA[iNdEx:postIndex])
+			if m.Pack == nil {
+				m.Pack = &UidPack{}
+			}
+			if err := m.Pack.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
 			iNdEx = postIndex
-		case 5:
+		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Version", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Postings", wireType)
 			}
-			var stringLen uint64
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -16079,29 +23705,31 @@ func (m *HealthInfo) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				msglen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
+			if msglen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + intStringLen
+			postIndex := iNdEx + msglen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Version = string(dAtA[iNdEx:postIndex])
+			m.Postings = append(m.Postings, &Posting{})
+			if err := m.Postings[len(m.Postings)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
 			iNdEx = postIndex
-		case 6:
+		case 3:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Uptime", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field CommitTs", wireType)
 			}
-			m.Uptime = 0
+			m.CommitTs = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -16111,65 +23739,144 @@ func (m *HealthInfo) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Uptime |= int64(b&0x7F) << shift
+				m.CommitTs |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 7:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field LastEcho", wireType)
-			}
-			m.LastEcho = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
+		case 4:
+			if wireType == 0 {
+				var v uint64
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowPb
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					v |= uint64(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
 				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
+				m.Splits = append(m.Splits, v)
+			} else if wireType == 2 {
+				var packedLen int
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowPb
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					packedLen |= int(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
 				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.LastEcho |= int64(b&0x7F) << shift
-				if b < 0x80 {
-					break
+				if packedLen < 0 {
+					return ErrInvalidLengthPb
 				}
-			}
-		case 8:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Ongoing", wireType)
-			}
-			var stringLen uint64
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
+				postIndex := iNdEx + packedLen
+				if postIndex < 0 {
+					return ErrInvalidLengthPb
 				}
-				if iNdEx >= l {
+				if postIndex > l {
 					return io.ErrUnexpectedEOF
 				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
-				if b < 0x80 {
-					break
+				var elementCount int
+				var count int
+				for _, integer := range dAtA[iNdEx:postIndex] {
+					if integer < 128 {
+						count++
+					}
+				}
+				elementCount = count
+				if elementCount != 0 && len(m.Splits) == 0 {
+					m.Splits = make([]uint64, 0, elementCount)
+				}
+				for iNdEx < postIndex {
+					var v uint64
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowPb
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						v |= uint64(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					m.Splits = append(m.Splits, v)
 				}
+			} else {
+				return fmt.Errorf("proto: wrong wireType = %d for field Splits", wireType)
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
+			if (iNdEx + skippy) < 0 {
 				return ErrInvalidLengthPb
 			}
-			if postIndex > l {
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *FacetParam) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
+			}
+			if iNdEx >= l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Ongoing = append(m.Ongoing, string(dAtA[iNdEx:postIndex]))
-			iNdEx = postIndex
-		case 9:
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: FacetParam: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: FacetParam: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Indexing", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -16197,11 +23904,11 @@ func (m *HealthInfo) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Indexing = append(m.Indexing, string(dAtA[iNdEx:postIndex]))
+			m.Key = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 10:
+		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field EeFeatures", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Alias", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -16229,7 +23936,7 @@ func (m *HealthInfo) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.EeFeatures = append(m.EeFeatures, string(dAtA[iNdEx:postIndex]))
+			m.Alias = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
@@ -16256,7 +23963,7 @@ func (m *HealthInfo) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *Tablet) Unmarshal(dAtA []byte) error {
+func (m *FacetParams) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -16279,17 +23986,17 @@ func (m *Tablet) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Tablet: wiretype end group for non-group")
+			return fmt.Errorf("proto: FacetParams: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Tablet: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: FacetParams: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field GroupId", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field AllKeys", wireType)
 			}
-			m.GroupId = 0
+			var v int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -16299,16 +24006,17 @@ func (m *Tablet) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.GroupId |= uint32(b&0x7F) << shift
+				v |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
+			m.AllKeys = bool(v != 0)
 		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Predicate", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Param", wireType)
 			}
-			var stringLen uint64
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -16318,122 +24026,26 @@ func (m *Tablet) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				msglen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
+			if msglen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + intStringLen
+			postIndex := iNdEx + msglen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Predicate = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
-		case 3:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Force", wireType)
-			}
-			var v int
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				v |= int(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-			m.Force = bool(v != 0)
-		case 7:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Space", wireType)
-			}
-			m.Space = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.Space |= int64(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-		case 8:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Remove", wireType)
-			}
-			var v int
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				v |= int(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-			m.Remove = bool(v != 0)
-		case 9:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ReadOnly", wireType)
-			}
-			var v int
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				v |= int(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-			m.ReadOnly = bool(v != 0)
-		case 10:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field MoveTs", wireType)
-			}
-			m.MoveTs = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.MoveTs |= uint64(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
+			m.Param = append(m.Param, &FacetParam{})
+			if err := m.Param[len(m.Param)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
 			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipPb(dAtA[iNdEx:])
@@ -16459,7 +24071,7 @@ func (m *Tablet) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *DirectedEdge) Unmarshal(dAtA []byte) error {
+func (m *Facets) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -16482,27 +24094,17 @@ func (m *DirectedEdge) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: DirectedEdge: wiretype end group for non-group")
+			return fmt.Errorf("proto: Facets: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: DirectedEdge: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: Facets: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 1 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Entity", wireType)
-			}
-			m.Entity = 0
-			if (iNdEx + 8) > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Entity = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
-			iNdEx += 8
-		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Attr", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Facets", wireType)
 			}
-			var stringLen uint64
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -16512,92 +24114,85 @@ func (m *DirectedEdge) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				msglen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
+			if msglen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + intStringLen
+			postIndex := iNdEx + msglen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Attr = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
-		case 3:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Value", wireType)
+			m.Facets = append(m.Facets, &api.Facet{})
+			if err := m.Facets[len(m.Facets)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
 			}
-			var byteLen int
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				byteLen |= int(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
 			}
-			if byteLen < 0 {
+			if skippy < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + byteLen
-			if postIndex < 0 {
+			if (iNdEx + skippy) < 0 {
 				return ErrInvalidLengthPb
 			}
-			if postIndex > l {
+			if (iNdEx + skippy) > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Value = append(m.Value[:0], dAtA[iNdEx:postIndex]...)
-			if m.Value == nil {
-				m.Value = []byte{}
-			}
-			iNdEx = postIndex
-		case 4:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ValueType", wireType)
-			}
-			m.ValueType = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.ValueType |= Posting_ValType(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-		case 5:
-			if wireType != 1 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ValueId", wireType)
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *FacetsList) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
 			}
-			m.ValueId = 0
-			if (iNdEx + 8) > l {
+			if iNdEx >= l {
 				return io.ErrUnexpectedEOF
 			}
-			m.ValueId = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
-			iNdEx += 8
-		case 6:
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: FacetsList: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: FacetsList: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Label", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field FacetsList", wireType)
 			}
-			var stringLen uint64
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -16607,27 +24202,83 @@ func (m *DirectedEdge) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				msglen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
+			if msglen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + intStringLen
+			postIndex := iNdEx + msglen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Label = string(dAtA[iNdEx:postIndex])
+			m.FacetsList = append(m.FacetsList, &Facets{})
+			if err := m.FacetsList[len(m.FacetsList)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
 			iNdEx = postIndex
-		case 7:
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Function) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Function: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Function: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Lang", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -16655,32 +24306,13 @@ func (m *DirectedEdge) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Lang = string(dAtA[iNdEx:postIndex])
+			m.Name = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 8:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Op", wireType)
-			}
-			m.Op = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.Op |= DirectedEdge_Op(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-		case 9:
+		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Facets", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
 			}
-			var msglen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -16690,29 +24322,27 @@ func (m *DirectedEdge) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + intStringLen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Facets = append(m.Facets, &api.Facet{})
-			if err := m.Facets[len(m.Facets)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
+			m.Key = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 10:
+		case 3:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field AllowedPreds", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Args", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -16740,7 +24370,7 @@ func (m *DirectedEdge) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.AllowedPreds = append(m.AllowedPreds, string(dAtA[iNdEx:postIndex]))
+			m.Args = append(m.Args, string(dAtA[iNdEx:postIndex]))
 			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
@@ -16767,7 +24397,7 @@ func (m *DirectedEdge) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *Mutations) Unmarshal(dAtA []byte) error {
+func (m *FilterTree) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -16790,17 +24420,17 @@ func (m *Mutations) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Mutations: wiretype end group for non-group")
+			return fmt.Errorf("proto: FilterTree: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Mutations: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: FilterTree: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field GroupId", wireType)
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Op", wireType)
 			}
-			m.GroupId = 0
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -16810,16 +24440,29 @@ func (m *Mutations) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.GroupId |= uint32(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Op = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
 		case 2:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field StartTs", wireType)
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Children", wireType)
 			}
-			m.StartTs = 0
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -16829,14 +24472,29 @@ func (m *Mutations) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.StartTs |= uint64(b&0x7F) << shift
+				msglen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Children = append(m.Children, &FilterTree{})
+			if err := m.Children[len(m.Children)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		case 3:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Edges", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Func", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -16863,16 +24521,72 @@ func (m *Mutations) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Edges = append(m.Edges, &DirectedEdge{})
-			if err := m.Edges[len(m.Edges)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+			if m.Func == nil {
+				m.Func = &Function{}
+			}
+			if err := m.Func.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
 			iNdEx = postIndex
-		case 4:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Schema", wireType)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *SchemaRequest) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
 			}
-			var msglen int
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: SchemaRequest: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: SchemaRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field GroupId", wireType)
+			}
+			m.GroupId = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -16882,31 +24596,16 @@ func (m *Mutations) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				m.GroupId |= uint32(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
-				return ErrInvalidLengthPb
-			}
-			postIndex := iNdEx + msglen
-			if postIndex < 0 {
-				return ErrInvalidLengthPb
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Schema = append(m.Schema, &SchemaUpdate{})
-			if err := m.Schema[len(m.Schema)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
-			iNdEx = postIndex
-		case 6:
+		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Types", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Predicates", wireType)
 			}
-			var msglen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -16916,48 +24615,27 @@ func (m *Mutations) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + intStringLen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Types = append(m.Types, &TypeUpdate{})
-			if err := m.Types[len(m.Types)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
+			m.Predicates = append(m.Predicates, string(dAtA[iNdEx:postIndex]))
 			iNdEx = postIndex
-		case 7:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field DropOp", wireType)
-			}
-			m.DropOp = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.DropOp |= Mutations_DropOp(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-		case 8:
+		case 3:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field DropValue", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Fields", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -16985,13 +24663,13 @@ func (m *Mutations) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.DropValue = string(dAtA[iNdEx:postIndex])
+			m.Fields = append(m.Fields, string(dAtA[iNdEx:postIndex]))
 			iNdEx = postIndex
-		case 9:
+		case 4:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Metadata", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Types", wireType)
 			}
-			var msglen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -17001,27 +24679,23 @@ func (m *Mutations) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + intStringLen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.Metadata == nil {
-				m.Metadata = &Metadata{}
-			}
-			if err := m.Metadata.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
+			m.Types = append(m.Types, string(dAtA[iNdEx:postIndex]))
 			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
@@ -17048,7 +24722,7 @@ func (m *Mutations) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *Metadata) Unmarshal(dAtA []byte) error {
+func (m *SchemaNode) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -17071,17 +24745,17 @@ func (m *Metadata) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Metadata: wiretype end group for non-group")
+			return fmt.Errorf("proto: SchemaNode: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Metadata: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: SchemaNode: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field PredHints", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Predicate", wireType)
 			}
-			var msglen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -17091,164 +24765,81 @@ func (m *Metadata) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + intStringLen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.PredHints == nil {
-				m.PredHints = make(map[string]Metadata_HintType)
+			m.Predicate = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Type", wireType)
 			}
-			var mapkey string
-			var mapvalue Metadata_HintType
-			for iNdEx < postIndex {
-				entryPreIndex := iNdEx
-				var wire uint64
-				for shift := uint(0); ; shift += 7 {
-					if shift >= 64 {
-						return ErrIntOverflowPb
-					}
-					if iNdEx >= l {
-						return io.ErrUnexpectedEOF
-					}
-					b := dAtA[iNdEx]
-					iNdEx++
-					wire |= uint64(b&0x7F) << shift
-					if b < 0x80 {
-						break
-					}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
 				}
-				fieldNum := int32(wire >> 3)
-				if fieldNum == 1 {
-					var stringLenmapkey uint64
-					for shift := uint(0); ; shift += 7 {
-						if shift >= 64 {
-							return ErrIntOverflowPb
-						}
-						if iNdEx >= l {
-							return io.ErrUnexpectedEOF
-						}
-						b := dAtA[iNdEx]
-						iNdEx++
-						stringLenmapkey |= uint64(b&0x7F) << shift
-						if b < 0x80 {
-							break
-						}
-					}
-					intStringLenmapkey := int(stringLenmapkey)
-					if intStringLenmapkey < 0 {
-						return ErrInvalidLengthPb
-					}
-					postStringIndexmapkey := iNdEx + intStringLenmapkey
-					if postStringIndexmapkey < 0 {
-						return ErrInvalidLengthPb
-					}
-					if postStringIndexmapkey > l {
-						return io.ErrUnexpectedEOF
-					}
-					mapkey = string(dAtA[iNdEx:postStringIndexmapkey])
-					iNdEx = postStringIndexmapkey
-				} else if fieldNum == 2 {
-					for shift := uint(0); ; shift += 7 {
-						if shift >= 64 {
-							return ErrIntOverflowPb
-						}
-						if iNdEx >= l {
-							return io.ErrUnexpectedEOF
-						}
-						b := dAtA[iNdEx]
-						iNdEx++
-						mapvalue |= Metadata_HintType(b&0x7F) << shift
-						if b < 0x80 {
-							break
-						}
-					}
-				} else {
-					iNdEx = entryPreIndex
-					skippy, err := skipPb(dAtA[iNdEx:])
-					if err != nil {
-						return err
-					}
-					if skippy < 0 {
-						return ErrInvalidLengthPb
-					}
-					if (iNdEx + skippy) > postIndex {
-						return io.ErrUnexpectedEOF
-					}
-					iNdEx += skippy
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
 				}
 			}
-			m.PredHints[mapkey] = mapvalue
-			iNdEx = postIndex
-		default:
-			iNdEx = preIndex
-			skippy, err := skipPb(dAtA[iNdEx:])
-			if err != nil {
-				return err
-			}
-			if skippy < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			if (iNdEx + skippy) < 0 {
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *Snapshot) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowPb
-			}
-			if iNdEx >= l {
+			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= uint64(b&0x7F) << shift
-			if b < 0x80 {
-				break
+			m.Type = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Index", wireType)
 			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: Snapshot: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Snapshot: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Index = bool(v != 0)
+		case 4:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Context", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Tokenizer", wireType)
 			}
-			var msglen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -17258,33 +24849,29 @@ func (m *Snapshot) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + intStringLen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.Context == nil {
-				m.Context = &RaftContext{}
-			}
-			if err := m.Context.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
+			m.Tokenizer = append(m.Tokenizer, string(dAtA[iNdEx:postIndex]))
 			iNdEx = postIndex
-		case 2:
+		case 5:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Index", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Reverse", wireType)
 			}
-			m.Index = 0
+			var v int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -17294,16 +24881,17 @@ func (m *Snapshot) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Index |= uint64(b&0x7F) << shift
+				v |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 3:
+			m.Reverse = bool(v != 0)
+		case 6:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ReadTs", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Count", wireType)
 			}
-			m.ReadTs = 0
+			var v int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -17313,14 +24901,15 @@ func (m *Snapshot) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.ReadTs |= uint64(b&0x7F) << shift
+				v |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 4:
+			m.Count = bool(v != 0)
+		case 7:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Done", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field List", wireType)
 			}
 			var v int
 			for shift := uint(0); ; shift += 7 {
@@ -17337,12 +24926,12 @@ func (m *Snapshot) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
-			m.Done = bool(v != 0)
-		case 5:
+			m.List = bool(v != 0)
+		case 8:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field SinceTs", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Upsert", wireType)
 			}
-			m.SinceTs = 0
+			var v int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -17352,70 +24941,37 @@ func (m *Snapshot) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.SinceTs |= uint64(b&0x7F) << shift
+				v |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		default:
-			iNdEx = preIndex
-			skippy, err := skipPb(dAtA[iNdEx:])
-			if err != nil {
-				return err
-			}
-			if skippy < 0 {
-				return ErrInvalidLengthPb
-			}
-			if (iNdEx + skippy) < 0 {
-				return ErrInvalidLengthPb
-			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *RestoreRequest) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowPb
-			}
-			if iNdEx >= l {
-				return io.ErrUnexpectedEOF
+			m.Upsert = bool(v != 0)
+		case 9:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Lang", wireType)
 			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= uint64(b&0x7F) << shift
-			if b < 0x80 {
-				break
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
 			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: RestoreRequest: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: RestoreRequest: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
+			m.Lang = bool(v != 0)
+		case 10:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field GroupId", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field NoConflict", wireType)
 			}
-			m.GroupId = 0
+			var v int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -17425,16 +24981,17 @@ func (m *RestoreRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.GroupId |= uint32(b&0x7F) << shift
+				v |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 2:
+			m.NoConflict = bool(v != 0)
+		case 11:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field RestoreTs", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Unique", wireType)
 			}
-			m.RestoreTs = 0
+			var v int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -17444,14 +25001,15 @@ func (m *RestoreRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.RestoreTs |= uint64(b&0x7F) << shift
+				v |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 3:
+			m.Unique = bool(v != 0)
+		case 12:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Location", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Ttl", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -17479,11 +25037,11 @@ func (m *RestoreRequest) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Location = string(dAtA[iNdEx:postIndex])
+			m.Ttl = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 4:
+		case 13:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field BackupId", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field TtlFrom", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -17511,11 +25069,11 @@ func (m *RestoreRequest) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.BackupId = string(dAtA[iNdEx:postIndex])
+			m.TtlFrom = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 5:
+		case 14:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field AccessKey", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field RenamedFrom", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -17543,11 +25101,11 @@ func (m *RestoreRequest) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.AccessKey = string(dAtA[iNdEx:postIndex])
+			m.RenamedFrom = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 6:
+		case 15:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field SecretKey", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field DefaultValue", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -17575,11 +25133,31 @@ func (m *RestoreRequest) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.SecretKey = string(dAtA[iNdEx:postIndex])
+			m.DefaultValue = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 7:
+		case 16:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field DefaultVirtual", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.DefaultVirtual = bool(v != 0)
+		case 17:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field SessionToken", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field IndexWhere", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -17607,13 +25185,13 @@ func (m *RestoreRequest) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.SessionToken = string(dAtA[iNdEx:postIndex])
+			m.IndexWhere = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 8:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Anonymous", wireType)
+		case 18:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Encoding", wireType)
 			}
-			var v int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -17623,15 +25201,27 @@ func (m *RestoreRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			m.Anonymous = bool(v != 0)
-		case 9:
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Encoding = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 19:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field EncryptionKeyFile", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field EnumValues", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -17659,11 +25249,11 @@ func (m *RestoreRequest) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.EncryptionKeyFile = string(dAtA[iNdEx:postIndex])
+			m.EnumValues = append(m.EnumValues, string(dAtA[iNdEx:postIndex]))
 			iNdEx = postIndex
-		case 10:
+		case 20:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field VaultAddr", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field CheckMin", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -17691,11 +25281,11 @@ func (m *RestoreRequest) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.VaultAddr = string(dAtA[iNdEx:postIndex])
+			m.CheckMin = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 11:
+		case 21:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field VaultRoleidFile", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field CheckMax", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -17723,11 +25313,30 @@ func (m *RestoreRequest) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.VaultRoleidFile = string(dAtA[iNdEx:postIndex])
+			m.CheckMax = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 12:
+		case 22:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field CheckMaxLength", wireType)
+			}
+			m.CheckMaxLength = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.CheckMaxLength |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 23:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field VaultSecretidFile", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field CheckPattern", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -17755,11 +25364,11 @@ func (m *RestoreRequest) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.VaultSecretidFile = string(dAtA[iNdEx:postIndex])
+			m.CheckPattern = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 13:
+		case 24:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field VaultPath", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field References", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -17787,11 +25396,11 @@ func (m *RestoreRequest) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.VaultPath = string(dAtA[iNdEx:postIndex])
+			m.References = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 14:
+		case 25:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field VaultField", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Tier", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -17819,11 +25428,11 @@ func (m *RestoreRequest) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.VaultField = string(dAtA[iNdEx:postIndex])
+			m.Tier = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 15:
+		case 26:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field VaultFormat", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Compression", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -17851,27 +25460,8 @@ func (m *RestoreRequest) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.VaultFormat = string(dAtA[iNdEx:postIndex])
+			m.Compression = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 16:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field BackupNum", wireType)
-			}
-			m.BackupNum = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.BackupNum |= uint64(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipPb(dAtA[iNdEx:])
@@ -17897,7 +25487,7 @@ func (m *RestoreRequest) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *Proposal) Unmarshal(dAtA []byte) error {
+func (m *SchemaResult) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -17920,15 +25510,15 @@ func (m *Proposal) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Proposal: wiretype end group for non-group")
+			return fmt.Errorf("proto: SchemaResult: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Proposal: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: SchemaResult: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
-		case 2:
+		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Mutations", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Schema", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -17955,18 +25545,70 @@ func (m *Proposal) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.Mutations == nil {
-				m.Mutations = &Mutations{}
-			}
-			if err := m.Mutations.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+			m.Schema = append(m.Schema, &SchemaNode{})
+			if err := m.Schema[len(m.Schema)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
 			iNdEx = postIndex
-		case 4:
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *SchemaUpdate) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: SchemaUpdate: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: SchemaUpdate: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Kv", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Predicate", wireType)
 			}
-			var msglen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -17976,31 +25618,29 @@ func (m *Proposal) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + intStringLen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Kv = append(m.Kv, &pb.KV{})
-			if err := m.Kv[len(m.Kv)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
+			m.Predicate = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 5:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field State", wireType)
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ValueType", wireType)
 			}
-			var msglen int
+			m.ValueType = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -18010,31 +25650,33 @@ func (m *Proposal) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				m.ValueType |= Posting_ValType(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
-				return ErrInvalidLengthPb
-			}
-			postIndex := iNdEx + msglen
-			if postIndex < 0 {
-				return ErrInvalidLengthPb
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			if m.State == nil {
-				m.State = &MembershipState{}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Directive", wireType)
 			}
-			if err := m.State.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
+			m.Directive = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Directive |= SchemaUpdate_Directive(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
 			}
-			iNdEx = postIndex
-		case 6:
+		case 4:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field CleanPredicate", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Tokenizer", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -18062,13 +25704,13 @@ func (m *Proposal) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.CleanPredicate = string(dAtA[iNdEx:postIndex])
+			m.Tokenizer = append(m.Tokenizer, string(dAtA[iNdEx:postIndex]))
 			iNdEx = postIndex
-		case 7:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Count", wireType)
 			}
-			var stringLen uint64
+			var v int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -18078,29 +25720,37 @@ func (m *Proposal) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				v |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthPb
-			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
-				return ErrInvalidLengthPb
+			m.Count = bool(v != 0)
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field List", wireType)
 			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
 			}
-			m.Key = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
+			m.List = bool(v != 0)
 		case 8:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Delta", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Upsert", wireType)
 			}
-			var msglen int
+			var v int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -18110,33 +25760,17 @@ func (m *Proposal) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				v |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
-				return ErrInvalidLengthPb
-			}
-			postIndex := iNdEx + msglen
-			if postIndex < 0 {
-				return ErrInvalidLengthPb
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			if m.Delta == nil {
-				m.Delta = &OracleDelta{}
-			}
-			if err := m.Delta.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
-			iNdEx = postIndex
+			m.Upsert = bool(v != 0)
 		case 9:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Snapshot", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Lang", wireType)
 			}
-			var msglen int
+			var v int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -18146,33 +25780,17 @@ func (m *Proposal) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				v |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
-				return ErrInvalidLengthPb
-			}
-			postIndex := iNdEx + msglen
-			if postIndex < 0 {
-				return ErrInvalidLengthPb
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			if m.Snapshot == nil {
-				m.Snapshot = &Snapshot{}
-			}
-			if err := m.Snapshot.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
-			iNdEx = postIndex
+			m.Lang = bool(v != 0)
 		case 10:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Index", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field NonNullable", wireType)
 			}
-			m.Index = 0
+			var v int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -18182,16 +25800,17 @@ func (m *Proposal) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Index |= uint64(b&0x7F) << shift
+				v |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
+			m.NonNullable = bool(v != 0)
 		case 11:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ExpectedChecksum", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field NonNullableList", wireType)
 			}
-			m.ExpectedChecksum = 0
+			var v int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -18201,16 +25820,17 @@ func (m *Proposal) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.ExpectedChecksum |= uint64(b&0x7F) << shift
+				v |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
+			m.NonNullableList = bool(v != 0)
 		case 12:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Restore", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field ObjectTypeName", wireType)
 			}
-			var msglen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -18220,87 +25840,29 @@ func (m *Proposal) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + intStringLen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.Restore == nil {
-				m.Restore = &RestoreRequest{}
-			}
-			if err := m.Restore.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
+			m.ObjectTypeName = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		default:
-			iNdEx = preIndex
-			skippy, err := skipPb(dAtA[iNdEx:])
-			if err != nil {
-				return err
-			}
-			if skippy < 0 {
-				return ErrInvalidLengthPb
-			}
-			if (iNdEx + skippy) < 0 {
-				return ErrInvalidLengthPb
-			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *KVS) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowPb
-			}
-			if iNdEx >= l {
-				return io.ErrUnexpectedEOF
-			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= uint64(b&0x7F) << shift
-			if b < 0x80 {
-				break
-			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: KVS: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: KVS: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Kv", wireType)
+		case 13:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field NoConflict", wireType)
 			}
-			var msglen int
+			var v int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -18310,29 +25872,15 @@ func (m *KVS) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				v |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
-				return ErrInvalidLengthPb
-			}
-			postIndex := iNdEx + msglen
-			if postIndex < 0 {
-				return ErrInvalidLengthPb
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Kv = append(m.Kv, &pb.KV{})
-			if err := m.Kv[len(m.Kv)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
-			iNdEx = postIndex
-		case 2:
+			m.NoConflict = bool(v != 0)
+		case 14:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Done", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Unique", wireType)
 			}
 			var v int
 			for shift := uint(0); ; shift += 7 {
@@ -18349,10 +25897,10 @@ func (m *KVS) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
-			m.Done = bool(v != 0)
-		case 3:
+			m.Unique = bool(v != 0)
+		case 15:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Predicates", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Ttl", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -18380,11 +25928,11 @@ func (m *KVS) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Predicates = append(m.Predicates, string(dAtA[iNdEx:postIndex]))
+			m.Ttl = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 4:
+		case 16:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Types", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field TtlFrom", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -18412,77 +25960,13 @@ func (m *KVS) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Types = append(m.Types, string(dAtA[iNdEx:postIndex]))
+			m.TtlFrom = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		default:
-			iNdEx = preIndex
-			skippy, err := skipPb(dAtA[iNdEx:])
-			if err != nil {
-				return err
-			}
-			if skippy < 0 {
-				return ErrInvalidLengthPb
-			}
-			if (iNdEx + skippy) < 0 {
-				return ErrInvalidLengthPb
-			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *Posting) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowPb
-			}
-			if iNdEx >= l {
-				return io.ErrUnexpectedEOF
-			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= uint64(b&0x7F) << shift
-			if b < 0x80 {
-				break
-			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: Posting: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Posting: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
-			if wireType != 1 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Uid", wireType)
-			}
-			m.Uid = 0
-			if (iNdEx + 8) > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Uid = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
-			iNdEx += 8
-		case 2:
+		case 17:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Value", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field RenamedFrom", wireType)
 			}
-			var byteLen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -18492,31 +25976,29 @@ func (m *Posting) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if byteLen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + byteLen
+			postIndex := iNdEx + intStringLen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Value = append(m.Value[:0], dAtA[iNdEx:postIndex]...)
-			if m.Value == nil {
-				m.Value = []byte{}
-			}
+			m.RenamedFrom = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 3:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ValType", wireType)
+		case 18:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field DefaultValue", wireType)
 			}
-			m.ValType = 0
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -18526,16 +26008,29 @@ func (m *Posting) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.ValType |= Posting_ValType(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 4:
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.DefaultValue = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 19:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field PostingType", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field DefaultVirtual", wireType)
 			}
-			m.PostingType = 0
+			var v int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -18545,16 +26040,17 @@ func (m *Posting) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.PostingType |= Posting_PostingType(b&0x7F) << shift
+				v |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 5:
+			m.DefaultVirtual = bool(v != 0)
+		case 20:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field LangTag", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field IndexWhere", wireType)
 			}
-			var byteLen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -18564,29 +26060,27 @@ func (m *Posting) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if byteLen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + byteLen
+			postIndex := iNdEx + intStringLen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.LangTag = append(m.LangTag[:0], dAtA[iNdEx:postIndex]...)
-			if m.LangTag == nil {
-				m.LangTag = []byte{}
-			}
+			m.IndexWhere = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 6:
+		case 21:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Label", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Encoding", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -18614,13 +26108,13 @@ func (m *Posting) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Label = string(dAtA[iNdEx:postIndex])
+			m.Encoding = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 9:
+		case 22:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Facets", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Dictionary", wireType)
 			}
-			var msglen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -18630,31 +26124,29 @@ func (m *Posting) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + intStringLen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Facets = append(m.Facets, &api.Facet{})
-			if err := m.Facets[len(m.Facets)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
+			m.Dictionary = append(m.Dictionary, string(dAtA[iNdEx:postIndex]))
 			iNdEx = postIndex
-		case 12:
+		case 23:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Op", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field EncodingBase", wireType)
 			}
-			m.Op = 0
+			m.EncodingBase = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -18664,16 +26156,16 @@ func (m *Posting) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Op |= uint32(b&0x7F) << shift
+				m.EncodingBase |= int64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 13:
+		case 24:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field StartTs", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field EncodingBaseSet", wireType)
 			}
-			m.StartTs = 0
+			var v int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -18683,16 +26175,17 @@ func (m *Posting) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.StartTs |= uint64(b&0x7F) << shift
+				v |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 14:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field CommitTs", wireType)
+			m.EncodingBaseSet = bool(v != 0)
+		case 25:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field EnumValues", wireType)
 			}
-			m.CommitTs = 0
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -18702,89 +26195,29 @@ func (m *Posting) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.CommitTs |= uint64(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		default:
-			iNdEx = preIndex
-			skippy, err := skipPb(dAtA[iNdEx:])
-			if err != nil {
-				return err
-			}
-			if skippy < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			if (iNdEx + skippy) < 0 {
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *UidBlock) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowPb
-			}
-			if iNdEx >= l {
+			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= uint64(b&0x7F) << shift
-			if b < 0x80 {
-				break
-			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: UidBlock: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: UidBlock: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Base", wireType)
-			}
-			m.Base = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.Base |= uint64(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-		case 2:
+			m.EnumValues = append(m.EnumValues, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		case 26:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Deltas", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field CheckMin", wireType)
 			}
-			var byteLen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -18794,31 +26227,29 @@ func (m *UidBlock) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if byteLen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + byteLen
+			postIndex := iNdEx + intStringLen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Deltas = append(m.Deltas[:0], dAtA[iNdEx:postIndex]...)
-			if m.Deltas == nil {
-				m.Deltas = []byte{}
-			}
+			m.CheckMin = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 3:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field NumUids", wireType)
+		case 27:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field CheckMax", wireType)
 			}
-			m.NumUids = 0
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -18828,70 +26259,29 @@ func (m *UidBlock) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.NumUids |= uint32(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		default:
-			iNdEx = preIndex
-			skippy, err := skipPb(dAtA[iNdEx:])
-			if err != nil {
-				return err
-			}
-			if skippy < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			if (iNdEx + skippy) < 0 {
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *UidPack) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowPb
-			}
-			if iNdEx >= l {
+			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= uint64(b&0x7F) << shift
-			if b < 0x80 {
-				break
-			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: UidPack: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: UidPack: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
+			m.CheckMax = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 28:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field BlockSize", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field CheckMaxLength", wireType)
 			}
-			m.BlockSize = 0
+			m.CheckMaxLength = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -18901,16 +26291,16 @@ func (m *UidPack) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.BlockSize |= uint32(b&0x7F) << shift
+				m.CheckMaxLength |= uint32(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 2:
+		case 29:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Blocks", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field CheckPattern", wireType)
 			}
-			var msglen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -18920,31 +26310,29 @@ func (m *UidPack) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + intStringLen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Blocks = append(m.Blocks, &UidBlock{})
-			if err := m.Blocks[len(m.Blocks)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
+			m.CheckPattern = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 23:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field AllocRef", wireType)
+		case 30:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field References", wireType)
 			}
-			m.AllocRef = 0
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -18954,70 +26342,29 @@ func (m *UidPack) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.AllocRef |= uint64(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		default:
-			iNdEx = preIndex
-			skippy, err := skipPb(dAtA[iNdEx:])
-			if err != nil {
-				return err
-			}
-			if skippy < 0 {
-				return ErrInvalidLengthPb
-			}
-			if (iNdEx + skippy) < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
-			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *PostingList) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowPb
-			}
-			if iNdEx >= l {
-				return io.ErrUnexpectedEOF
-			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= uint64(b&0x7F) << shift
-			if b < 0x80 {
-				break
-			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: PostingList: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: PostingList: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.References = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 31:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Pack", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Tier", wireType)
 			}
-			var msglen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -19027,33 +26374,29 @@ func (m *PostingList) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + intStringLen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.Pack == nil {
-				m.Pack = &UidPack{}
-			}
-			if err := m.Pack.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
+			m.Tier = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 2:
+		case 32:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Postings", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field TierObject", wireType)
 			}
-			var msglen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -19063,31 +26406,29 @@ func (m *PostingList) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + intStringLen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Postings = append(m.Postings, &Posting{})
-			if err := m.Postings[len(m.Postings)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
+			m.TierObject = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 3:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field CommitTs", wireType)
+		case 33:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Compression", wireType)
 			}
-			m.CommitTs = 0
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -19097,87 +26438,56 @@ func (m *PostingList) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.CommitTs |= uint64(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 4:
-			if wireType == 0 {
-				var v uint64
-				for shift := uint(0); ; shift += 7 {
-					if shift >= 64 {
-						return ErrIntOverflowPb
-					}
-					if iNdEx >= l {
-						return io.ErrUnexpectedEOF
-					}
-					b := dAtA[iNdEx]
-					iNdEx++
-					v |= uint64(b&0x7F) << shift
-					if b < 0x80 {
-						break
-					}
-				}
-				m.Splits = append(m.Splits, v)
-			} else if wireType == 2 {
-				var packedLen int
-				for shift := uint(0); ; shift += 7 {
-					if shift >= 64 {
-						return ErrIntOverflowPb
-					}
-					if iNdEx >= l {
-						return io.ErrUnexpectedEOF
-					}
-					b := dAtA[iNdEx]
-					iNdEx++
-					packedLen |= int(b&0x7F) << shift
-					if b < 0x80 {
-						break
-					}
-				}
-				if packedLen < 0 {
-					return ErrInvalidLengthPb
-				}
-				postIndex := iNdEx + packedLen
-				if postIndex < 0 {
-					return ErrInvalidLengthPb
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Compression = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 34:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field CompressionDictionaries", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
 				}
-				if postIndex > l {
+				if iNdEx >= l {
 					return io.ErrUnexpectedEOF
 				}
-				var elementCount int
-				var count int
-				for _, integer := range dAtA[iNdEx:postIndex] {
-					if integer < 128 {
-						count++
-					}
-				}
-				elementCount = count
-				if elementCount != 0 && len(m.Splits) == 0 {
-					m.Splits = make([]uint64, 0, elementCount)
-				}
-				for iNdEx < postIndex {
-					var v uint64
-					for shift := uint(0); ; shift += 7 {
-						if shift >= 64 {
-							return ErrIntOverflowPb
-						}
-						if iNdEx >= l {
-							return io.ErrUnexpectedEOF
-						}
-						b := dAtA[iNdEx]
-						iNdEx++
-						v |= uint64(b&0x7F) << shift
-						if b < 0x80 {
-							break
-						}
-					}
-					m.Splits = append(m.Splits, v)
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
 				}
-			} else {
-				return fmt.Errorf("proto: wrong wireType = %d for field Splits", wireType)
 			}
+			if byteLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.CompressionDictionaries = append(m.CompressionDictionaries, make([]byte, postIndex-iNdEx))
+			copy(m.CompressionDictionaries[len(m.CompressionDictionaries)-1], dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipPb(dAtA[iNdEx:])
@@ -19203,7 +26513,7 @@ func (m *PostingList) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *FacetParam) Unmarshal(dAtA []byte) error {
+func (m *TypeUpdate) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -19226,15 +26536,15 @@ func (m *FacetParam) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: FacetParam: wiretype end group for non-group")
+			return fmt.Errorf("proto: TypeUpdate: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: FacetParam: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: TypeUpdate: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field TypeName", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -19262,13 +26572,13 @@ func (m *FacetParam) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Key = string(dAtA[iNdEx:postIndex])
+			m.TypeName = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
 		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Alias", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Fields", wireType)
 			}
-			var stringLen uint64
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -19278,83 +26588,31 @@ func (m *FacetParam) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				msglen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
+			if msglen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + intStringLen
+			postIndex := iNdEx + msglen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Alias = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
-		default:
-			iNdEx = preIndex
-			skippy, err := skipPb(dAtA[iNdEx:])
-			if err != nil {
+			m.Fields = append(m.Fields, &SchemaUpdate{})
+			if err := m.Fields[len(m.Fields)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
-			if skippy < 0 {
-				return ErrInvalidLengthPb
-			}
-			if (iNdEx + skippy) < 0 {
-				return ErrInvalidLengthPb
-			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *FacetParams) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowPb
-			}
-			if iNdEx >= l {
-				return io.ErrUnexpectedEOF
-			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= uint64(b&0x7F) << shift
-			if b < 0x80 {
-				break
-			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: FacetParams: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: FacetParams: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field AllKeys", wireType)
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Ttl", wireType)
 			}
-			var v int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -19364,17 +26622,29 @@ func (m *FacetParams) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			m.AllKeys = bool(v != 0)
-		case 2:
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Ttl = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 4:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Param", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field TtlFrom", wireType)
 			}
-			var msglen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -19384,25 +26654,23 @@ func (m *FacetParams) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + intStringLen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Param = append(m.Param, &FacetParam{})
-			if err := m.Param[len(m.Param)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
+			m.TtlFrom = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
@@ -19429,7 +26697,7 @@ func (m *FacetParams) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *Facets) Unmarshal(dAtA []byte) error {
+func (m *MapHeader) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -19452,17 +26720,17 @@ func (m *Facets) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Facets: wiretype end group for non-group")
+			return fmt.Errorf("proto: MapHeader: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Facets: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: MapHeader: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Facets", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field PartitionKeys", wireType)
 			}
-			var msglen int
+			var byteLen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -19472,25 +26740,23 @@ func (m *Facets) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			if byteLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + byteLen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Facets = append(m.Facets, &api.Facet{})
-			if err := m.Facets[len(m.Facets)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
+			m.PartitionKeys = append(m.PartitionKeys, make([]byte, postIndex-iNdEx))
+			copy(m.PartitionKeys[len(m.PartitionKeys)-1], dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
@@ -19517,7 +26783,7 @@ func (m *Facets) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *FacetsList) Unmarshal(dAtA []byte) error {
+func (m *MovePredicatePayload) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -19540,17 +26806,17 @@ func (m *FacetsList) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: FacetsList: wiretype end group for non-group")
+			return fmt.Errorf("proto: MovePredicatePayload: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: FacetsList: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: MovePredicatePayload: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field FacetsList", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Predicate", wireType)
 			}
-			var msglen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -19560,26 +26826,100 @@ func (m *FacetsList) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + intStringLen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.FacetsList = append(m.FacetsList, &Facets{})
-			if err := m.FacetsList[len(m.FacetsList)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
+			m.Predicate = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SourceGid", wireType)
+			}
+			m.SourceGid = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.SourceGid |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field DestGid", wireType)
+			}
+			m.DestGid = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.DestGid |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TxnTs", wireType)
+			}
+			m.TxnTs = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.TxnTs |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ExpectedChecksum", wireType)
+			}
+			m.ExpectedChecksum = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ExpectedChecksum |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipPb(dAtA[iNdEx:])
@@ -19605,7 +26945,7 @@ func (m *FacetsList) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *Function) Unmarshal(dAtA []byte) error {
+func (m *TxnStatus) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -19628,17 +26968,17 @@ func (m *Function) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Function: wiretype end group for non-group")
+			return fmt.Errorf("proto: TxnStatus: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Function: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: TxnStatus: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field StartTs", wireType)
 			}
-			var stringLen uint64
+			m.StartTs = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -19648,61 +26988,16 @@ func (m *Function) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				m.StartTs |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthPb
-			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
-				return ErrInvalidLengthPb
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Name = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
 		case 2:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
-			}
-			var stringLen uint64
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthPb
-			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
-				return ErrInvalidLengthPb
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Key = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
-		case 3:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Args", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field CommitTs", wireType)
 			}
-			var stringLen uint64
+			m.CommitTs = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -19712,24 +27007,11 @@ func (m *Function) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				m.CommitTs |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthPb
-			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
-				return ErrInvalidLengthPb
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Args = append(m.Args, string(dAtA[iNdEx:postIndex]))
-			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipPb(dAtA[iNdEx:])
@@ -19755,7 +27037,7 @@ func (m *Function) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *FilterTree) Unmarshal(dAtA []byte) error {
+func (m *OracleDelta) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -19778,47 +27060,15 @@ func (m *FilterTree) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: FilterTree: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: FilterTree: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Op", wireType)
-			}
-			var stringLen uint64
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthPb
-			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
-				return ErrInvalidLengthPb
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Op = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
-		case 2:
+			return fmt.Errorf("proto: OracleDelta: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: OracleDelta: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Children", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Txns", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -19845,14 +27095,33 @@ func (m *FilterTree) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Children = append(m.Children, &FilterTree{})
-			if err := m.Children[len(m.Children)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+			m.Txns = append(m.Txns, &TxnStatus{})
+			if err := m.Txns[len(m.Txns)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
 			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxAssigned", wireType)
+			}
+			m.MaxAssigned = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MaxAssigned |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		case 3:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Func", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field GroupChecksums", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -19879,12 +27148,75 @@ func (m *FilterTree) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.Func == nil {
-				m.Func = &Function{}
+			if m.GroupChecksums == nil {
+				m.GroupChecksums = make(map[uint32]uint64)
 			}
-			if err := m.Func.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
+			var mapkey uint32
+			var mapvalue uint64
+			for iNdEx < postIndex {
+				entryPreIndex := iNdEx
+				var wire uint64
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowPb
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					wire |= uint64(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				fieldNum := int32(wire >> 3)
+				if fieldNum == 1 {
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowPb
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						mapkey |= uint32(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+				} else if fieldNum == 2 {
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowPb
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						mapvalue |= uint64(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+				} else {
+					iNdEx = entryPreIndex
+					skippy, err := skipPb(dAtA[iNdEx:])
+					if err != nil {
+						return err
+					}
+					if skippy < 0 {
+						return ErrInvalidLengthPb
+					}
+					if (iNdEx + skippy) > postIndex {
+						return io.ErrUnexpectedEOF
+					}
+					iNdEx += skippy
+				}
 			}
+			m.GroupChecksums[mapkey] = mapvalue
 			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
@@ -19911,7 +27243,7 @@ func (m *FilterTree) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *SchemaRequest) Unmarshal(dAtA []byte) error {
+func (m *TxnTimestamps) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -19934,127 +27266,88 @@ func (m *SchemaRequest) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: SchemaRequest: wiretype end group for non-group")
+			return fmt.Errorf("proto: TxnTimestamps: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: SchemaRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: TxnTimestamps: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field GroupId", wireType)
-			}
-			m.GroupId = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.GroupId |= uint32(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-		case 2:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Predicates", wireType)
-			}
-			var stringLen uint64
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
+			if wireType == 0 {
+				var v uint64
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowPb
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					v |= uint64(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
 				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
+				m.Ts = append(m.Ts, v)
+			} else if wireType == 2 {
+				var packedLen int
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowPb
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					packedLen |= int(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
 				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
-				if b < 0x80 {
-					break
+				if packedLen < 0 {
+					return ErrInvalidLengthPb
 				}
-			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthPb
-			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
-				return ErrInvalidLengthPb
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Predicates = append(m.Predicates, string(dAtA[iNdEx:postIndex]))
-			iNdEx = postIndex
-		case 3:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Fields", wireType)
-			}
-			var stringLen uint64
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
+				postIndex := iNdEx + packedLen
+				if postIndex < 0 {
+					return ErrInvalidLengthPb
 				}
-				if iNdEx >= l {
+				if postIndex > l {
 					return io.ErrUnexpectedEOF
 				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthPb
-			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
-				return ErrInvalidLengthPb
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Fields = append(m.Fields, string(dAtA[iNdEx:postIndex]))
-			iNdEx = postIndex
-		case 4:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Types", wireType)
-			}
-			var stringLen uint64
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
+				var elementCount int
+				var count int
+				for _, integer := range dAtA[iNdEx:postIndex] {
+					if integer < 128 {
+						count++
+					}
 				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
+				elementCount = count
+				if elementCount != 0 && len(m.Ts) == 0 {
+					m.Ts = make([]uint64, 0, elementCount)
 				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
-				if b < 0x80 {
-					break
+				for iNdEx < postIndex {
+					var v uint64
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowPb
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						v |= uint64(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					m.Ts = append(m.Ts, v)
 				}
+			} else {
+				return fmt.Errorf("proto: wrong wireType = %d for field Ts", wireType)
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthPb
-			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
-				return ErrInvalidLengthPb
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Types = append(m.Types, string(dAtA[iNdEx:postIndex]))
-			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipPb(dAtA[iNdEx:])
@@ -20080,7 +27373,7 @@ func (m *SchemaRequest) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *SchemaNode) Unmarshal(dAtA []byte) error {
+func (m *PeerResponse) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -20103,17 +27396,17 @@ func (m *SchemaNode) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: SchemaNode: wiretype end group for non-group")
+			return fmt.Errorf("proto: PeerResponse: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: SchemaNode: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: PeerResponse: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Predicate", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Status", wireType)
 			}
-			var stringLen uint64
+			var v int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -20123,29 +27416,71 @@ func (m *SchemaNode) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				v |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
+			m.Status = bool(v != 0)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
+			if (iNdEx + skippy) < 0 {
 				return ErrInvalidLengthPb
 			}
-			if postIndex > l {
+			if (iNdEx + skippy) > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Predicate = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
-		case 2:
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *RaftBatch) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: RaftBatch: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: RaftBatch: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Type", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Context", wireType)
 			}
-			var stringLen uint64
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -20155,49 +27490,33 @@ func (m *SchemaNode) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				msglen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
+			if msglen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + intStringLen
+			postIndex := iNdEx + msglen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Type = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
-		case 3:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Index", wireType)
+			if m.Context == nil {
+				m.Context = &RaftContext{}
 			}
-			var v int
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				v |= int(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
+			if err := m.Context.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
 			}
-			m.Index = bool(v != 0)
-		case 4:
+			iNdEx = postIndex
+		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Tokenizer", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Payload", wireType)
 			}
-			var stringLen uint64
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -20207,109 +27526,87 @@ func (m *SchemaNode) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				msglen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
+			if msglen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + intStringLen
+			postIndex := iNdEx + msglen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Tokenizer = append(m.Tokenizer, string(dAtA[iNdEx:postIndex]))
-			iNdEx = postIndex
-		case 5:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Reverse", wireType)
+			if m.Payload == nil {
+				m.Payload = &api.Payload{}
 			}
-			var v int
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				v |= int(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
+			if err := m.Payload.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
 			}
-			m.Reverse = bool(v != 0)
-		case 6:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Count", wireType)
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
 			}
-			var v int
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				v |= int(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
+			if skippy < 0 {
+				return ErrInvalidLengthPb
 			}
-			m.Count = bool(v != 0)
-		case 7:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field List", wireType)
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthPb
 			}
-			var v int
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				v |= int(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
 			}
-			m.List = bool(v != 0)
-		case 8:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Upsert", wireType)
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *SubscriptionRequest) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
 			}
-			var v int
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				v |= int(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
 			}
-			m.Upsert = bool(v != 0)
-		case 9:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Lang", wireType)
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: SubscriptionRequest: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: SubscriptionRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Prefixes", wireType)
 			}
-			var v int
+			var byteLen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -20319,32 +27616,24 @@ func (m *SchemaNode) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= int(b&0x7F) << shift
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			m.Lang = bool(v != 0)
-		case 10:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field NoConflict", wireType)
+			if byteLen < 0 {
+				return ErrInvalidLengthPb
 			}
-			var v int
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				v |= int(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
 			}
-			m.NoConflict = bool(v != 0)
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Prefixes = append(m.Prefixes, make([]byte, postIndex-iNdEx))
+			copy(m.Prefixes[len(m.Prefixes)-1], dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipPb(dAtA[iNdEx:])
@@ -20370,7 +27659,7 @@ func (m *SchemaNode) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *SchemaResult) Unmarshal(dAtA []byte) error {
+func (m *SubscriptionResponse) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -20393,15 +27682,15 @@ func (m *SchemaResult) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: SchemaResult: wiretype end group for non-group")
+			return fmt.Errorf("proto: SubscriptionResponse: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: SchemaResult: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: SubscriptionResponse: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Schema", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Kvs", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -20428,8 +27717,10 @@ func (m *SchemaResult) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Schema = append(m.Schema, &SchemaNode{})
-			if err := m.Schema[len(m.Schema)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+			if m.Kvs == nil {
+				m.Kvs = &pb.KVList{}
+			}
+			if err := m.Kvs.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
 			iNdEx = postIndex
@@ -20458,7 +27749,7 @@ func (m *SchemaResult) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *SchemaUpdate) Unmarshal(dAtA []byte) error {
+func (m *Num) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -20481,17 +27772,17 @@ func (m *SchemaUpdate) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: SchemaUpdate: wiretype end group for non-group")
+			return fmt.Errorf("proto: Num: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: SchemaUpdate: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: Num: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Predicate", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Val", wireType)
 			}
-			var stringLen uint64
+			m.Val = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -20501,29 +27792,16 @@ func (m *SchemaUpdate) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				m.Val |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthPb
-			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
-				return ErrInvalidLengthPb
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Predicate = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
 		case 2:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ValueType", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field ReadOnly", wireType)
 			}
-			m.ValueType = 0
+			var v int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -20533,16 +27811,17 @@ func (m *SchemaUpdate) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.ValueType |= Posting_ValType(b&0x7F) << shift
+				v |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
+			m.ReadOnly = bool(v != 0)
 		case 3:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Directive", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Forwarded", wireType)
 			}
-			m.Directive = 0
+			var v int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -20552,48 +27831,71 @@ func (m *SchemaUpdate) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Directive |= SchemaUpdate_Directive(b&0x7F) << shift
+				v |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 4:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Tokenizer", wireType)
-			}
-			var stringLen uint64
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
+			m.Forwarded = bool(v != 0)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
+			if skippy < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
+			if (iNdEx + skippy) < 0 {
 				return ErrInvalidLengthPb
 			}
-			if postIndex > l {
+			if (iNdEx + skippy) > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Tokenizer = append(m.Tokenizer, string(dAtA[iNdEx:postIndex]))
-			iNdEx = postIndex
-		case 5:
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *AssignedIds) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: AssignedIds: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: AssignedIds: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Count", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field StartId", wireType)
 			}
-			var v int
+			m.StartId = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -20603,17 +27905,16 @@ func (m *SchemaUpdate) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= int(b&0x7F) << shift
+				m.StartId |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			m.Count = bool(v != 0)
-		case 6:
+		case 2:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field List", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field EndId", wireType)
 			}
-			var v int
+			m.EndId = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -20623,17 +27924,16 @@ func (m *SchemaUpdate) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= int(b&0x7F) << shift
+				m.EndId |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			m.List = bool(v != 0)
-		case 8:
+		case 5:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Upsert", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field ReadOnly", wireType)
 			}
-			var v int
+			m.ReadOnly = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -20643,17 +27943,70 @@ func (m *SchemaUpdate) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= int(b&0x7F) << shift
+				m.ReadOnly |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			m.Upsert = bool(v != 0)
-		case 9:
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *SnapshotMeta) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: SnapshotMeta: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: SnapshotMeta: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Lang", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field ClientTs", wireType)
 			}
-			var v int
+			m.ClientTs = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -20663,17 +28016,16 @@ func (m *SchemaUpdate) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= int(b&0x7F) << shift
+				m.ClientTs |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			m.Lang = bool(v != 0)
-		case 10:
+		case 2:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field NonNullable", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field GroupId", wireType)
 			}
-			var v int
+			m.GroupId = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -20683,17 +28035,70 @@ func (m *SchemaUpdate) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= int(b&0x7F) << shift
+				m.GroupId |= uint32(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			m.NonNullable = bool(v != 0)
-		case 11:
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthPb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Status) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Status: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Status: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field NonNullableList", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Code", wireType)
 			}
-			var v int
+			m.Code = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -20703,15 +28108,14 @@ func (m *SchemaUpdate) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= int(b&0x7F) << shift
+				m.Code |= int32(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			m.NonNullableList = bool(v != 0)
-		case 12:
+		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ObjectTypeName", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Msg", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -20739,28 +28143,8 @@ func (m *SchemaUpdate) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.ObjectTypeName = string(dAtA[iNdEx:postIndex])
+			m.Msg = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 13:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field NoConflict", wireType)
-			}
-			var v int
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				v |= int(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-			m.NoConflict = bool(v != 0)
 		default:
 			iNdEx = preIndex
 			skippy, err := skipPb(dAtA[iNdEx:])
@@ -20786,7 +28170,7 @@ func (m *SchemaUpdate) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *TypeUpdate) Unmarshal(dAtA []byte) error {
+func (m *BackupRequest) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -20809,15 +28193,72 @@ func (m *TypeUpdate) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: TypeUpdate: wiretype end group for non-group")
+			return fmt.Errorf("proto: BackupRequest: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: TypeUpdate: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: BackupRequest: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ReadTs", wireType)
+			}
+			m.ReadTs = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ReadTs |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SinceTs", wireType)
+			}
+			m.SinceTs = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.SinceTs |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field GroupId", wireType)
+			}
+			m.GroupId = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.GroupId |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field TypeName", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field UnixTs", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -20845,13 +28286,13 @@ func (m *TypeUpdate) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.TypeName = string(dAtA[iNdEx:postIndex])
+			m.UnixTs = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 2:
+		case 5:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Fields", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Destination", wireType)
 			}
-			var msglen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -20861,85 +28302,29 @@ func (m *TypeUpdate) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + intStringLen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Fields = append(m.Fields, &SchemaUpdate{})
-			if err := m.Fields[len(m.Fields)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
+			m.Destination = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		default:
-			iNdEx = preIndex
-			skippy, err := skipPb(dAtA[iNdEx:])
-			if err != nil {
-				return err
-			}
-			if skippy < 0 {
-				return ErrInvalidLengthPb
-			}
-			if (iNdEx + skippy) < 0 {
-				return ErrInvalidLengthPb
-			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *MapHeader) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowPb
-			}
-			if iNdEx >= l {
-				return io.ErrUnexpectedEOF
-			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= uint64(b&0x7F) << shift
-			if b < 0x80 {
-				break
-			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: MapHeader: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: MapHeader: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
+		case 6:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field PartitionKeys", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field AccessKey", wireType)
 			}
-			var byteLen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -20949,81 +28334,27 @@ func (m *MapHeader) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if byteLen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + byteLen
+			postIndex := iNdEx + intStringLen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.PartitionKeys = append(m.PartitionKeys, make([]byte, postIndex-iNdEx))
-			copy(m.PartitionKeys[len(m.PartitionKeys)-1], dAtA[iNdEx:postIndex])
+			m.AccessKey = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		default:
-			iNdEx = preIndex
-			skippy, err := skipPb(dAtA[iNdEx:])
-			if err != nil {
-				return err
-			}
-			if skippy < 0 {
-				return ErrInvalidLengthPb
-			}
-			if (iNdEx + skippy) < 0 {
-				return ErrInvalidLengthPb
-			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *MovePredicatePayload) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowPb
-			}
-			if iNdEx >= l {
-				return io.ErrUnexpectedEOF
-			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= uint64(b&0x7F) << shift
-			if b < 0x80 {
-				break
-			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: MovePredicatePayload: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: MovePredicatePayload: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
+		case 7:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Predicate", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field SecretKey", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -21051,13 +28382,13 @@ func (m *MovePredicatePayload) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Predicate = string(dAtA[iNdEx:postIndex])
+			m.SecretKey = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 2:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field SourceGid", wireType)
+		case 8:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SessionToken", wireType)
 			}
-			m.SourceGid = 0
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -21067,35 +28398,29 @@ func (m *MovePredicatePayload) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.SourceGid |= uint32(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 3:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field DestGid", wireType)
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
 			}
-			m.DestGid = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.DestGid |= uint32(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
 			}
-		case 4:
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.SessionToken = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 9:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field TxnTs", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Anonymous", wireType)
 			}
-			m.TxnTs = 0
+			var v int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -21105,16 +28430,17 @@ func (m *MovePredicatePayload) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.TxnTs |= uint64(b&0x7F) << shift
+				v |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 5:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ExpectedChecksum", wireType)
+			m.Anonymous = bool(v != 0)
+		case 10:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Predicates", wireType)
 			}
-			m.ExpectedChecksum = 0
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -21124,11 +28450,24 @@ func (m *MovePredicatePayload) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.ExpectedChecksum |= uint64(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Predicates = append(m.Predicates, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipPb(dAtA[iNdEx:])
@@ -21154,7 +28493,7 @@ func (m *MovePredicatePayload) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *TxnStatus) Unmarshal(dAtA []byte) error {
+func (m *ExportRequest) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -21177,17 +28516,17 @@ func (m *TxnStatus) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: TxnStatus: wiretype end group for non-group")
+			return fmt.Errorf("proto: ExportRequest: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: TxnStatus: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: ExportRequest: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field StartTs", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field GroupId", wireType)
 			}
-			m.StartTs = 0
+			m.GroupId = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -21197,16 +28536,16 @@ func (m *TxnStatus) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.StartTs |= uint64(b&0x7F) << shift
+				m.GroupId |= uint32(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
 		case 2:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field CommitTs", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field ReadTs", wireType)
 			}
-			m.CommitTs = 0
+			m.ReadTs = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -21216,70 +28555,35 @@ func (m *TxnStatus) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.CommitTs |= uint64(b&0x7F) << shift
+				m.ReadTs |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		default:
-			iNdEx = preIndex
-			skippy, err := skipPb(dAtA[iNdEx:])
-			if err != nil {
-				return err
-			}
-			if skippy < 0 {
-				return ErrInvalidLengthPb
-			}
-			if (iNdEx + skippy) < 0 {
-				return ErrInvalidLengthPb
-			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *OracleDelta) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowPb
-			}
-			if iNdEx >= l {
-				return io.ErrUnexpectedEOF
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field UnixTs", wireType)
 			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= uint64(b&0x7F) << shift
-			if b < 0x80 {
-				break
+			m.UnixTs = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.UnixTs |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
 			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: OracleDelta: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: OracleDelta: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
+		case 4:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Txns", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Format", wireType)
 			}
-			var msglen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -21289,31 +28593,29 @@ func (m *OracleDelta) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + intStringLen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Txns = append(m.Txns, &TxnStatus{})
-			if err := m.Txns[len(m.Txns)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
+			m.Format = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 2:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field MaxAssigned", wireType)
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Destination", wireType)
 			}
-			m.MaxAssigned = 0
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -21323,16 +28625,29 @@ func (m *OracleDelta) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.MaxAssigned |= uint64(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 3:
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Destination = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 6:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field GroupChecksums", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field AccessKey", wireType)
 			}
-			var msglen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -21342,278 +28657,91 @@ func (m *OracleDelta) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + intStringLen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.GroupChecksums == nil {
-				m.GroupChecksums = make(map[uint32]uint64)
+			m.AccessKey = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 7:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SecretKey", wireType)
 			}
-			var mapkey uint32
-			var mapvalue uint64
-			for iNdEx < postIndex {
-				entryPreIndex := iNdEx
-				var wire uint64
-				for shift := uint(0); ; shift += 7 {
-					if shift >= 64 {
-						return ErrIntOverflowPb
-					}
-					if iNdEx >= l {
-						return io.ErrUnexpectedEOF
-					}
-					b := dAtA[iNdEx]
-					iNdEx++
-					wire |= uint64(b&0x7F) << shift
-					if b < 0x80 {
-						break
-					}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
 				}
-				fieldNum := int32(wire >> 3)
-				if fieldNum == 1 {
-					for shift := uint(0); ; shift += 7 {
-						if shift >= 64 {
-							return ErrIntOverflowPb
-						}
-						if iNdEx >= l {
-							return io.ErrUnexpectedEOF
-						}
-						b := dAtA[iNdEx]
-						iNdEx++
-						mapkey |= uint32(b&0x7F) << shift
-						if b < 0x80 {
-							break
-						}
-					}
-				} else if fieldNum == 2 {
-					for shift := uint(0); ; shift += 7 {
-						if shift >= 64 {
-							return ErrIntOverflowPb
-						}
-						if iNdEx >= l {
-							return io.ErrUnexpectedEOF
-						}
-						b := dAtA[iNdEx]
-						iNdEx++
-						mapvalue |= uint64(b&0x7F) << shift
-						if b < 0x80 {
-							break
-						}
-					}
-				} else {
-					iNdEx = entryPreIndex
-					skippy, err := skipPb(dAtA[iNdEx:])
-					if err != nil {
-						return err
-					}
-					if skippy < 0 {
-						return ErrInvalidLengthPb
-					}
-					if (iNdEx + skippy) > postIndex {
-						return io.ErrUnexpectedEOF
-					}
-					iNdEx += skippy
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
 				}
 			}
-			m.GroupChecksums[mapkey] = mapvalue
-			iNdEx = postIndex
-		default:
-			iNdEx = preIndex
-			skippy, err := skipPb(dAtA[iNdEx:])
-			if err != nil {
-				return err
-			}
-			if skippy < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			if (iNdEx + skippy) < 0 {
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *TxnTimestamps) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowPb
-			}
-			if iNdEx >= l {
+			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= uint64(b&0x7F) << shift
-			if b < 0x80 {
-				break
+			m.SecretKey = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 8:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SessionToken", wireType)
 			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: TxnTimestamps: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: TxnTimestamps: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
-			if wireType == 0 {
-				var v uint64
-				for shift := uint(0); ; shift += 7 {
-					if shift >= 64 {
-						return ErrIntOverflowPb
-					}
-					if iNdEx >= l {
-						return io.ErrUnexpectedEOF
-					}
-					b := dAtA[iNdEx]
-					iNdEx++
-					v |= uint64(b&0x7F) << shift
-					if b < 0x80 {
-						break
-					}
-				}
-				m.Ts = append(m.Ts, v)
-			} else if wireType == 2 {
-				var packedLen int
-				for shift := uint(0); ; shift += 7 {
-					if shift >= 64 {
-						return ErrIntOverflowPb
-					}
-					if iNdEx >= l {
-						return io.ErrUnexpectedEOF
-					}
-					b := dAtA[iNdEx]
-					iNdEx++
-					packedLen |= int(b&0x7F) << shift
-					if b < 0x80 {
-						break
-					}
-				}
-				if packedLen < 0 {
-					return ErrInvalidLengthPb
-				}
-				postIndex := iNdEx + packedLen
-				if postIndex < 0 {
-					return ErrInvalidLengthPb
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
 				}
-				if postIndex > l {
+				if iNdEx >= l {
 					return io.ErrUnexpectedEOF
 				}
-				var elementCount int
-				var count int
-				for _, integer := range dAtA[iNdEx:postIndex] {
-					if integer < 128 {
-						count++
-					}
-				}
-				elementCount = count
-				if elementCount != 0 && len(m.Ts) == 0 {
-					m.Ts = make([]uint64, 0, elementCount)
-				}
-				for iNdEx < postIndex {
-					var v uint64
-					for shift := uint(0); ; shift += 7 {
-						if shift >= 64 {
-							return ErrIntOverflowPb
-						}
-						if iNdEx >= l {
-							return io.ErrUnexpectedEOF
-						}
-						b := dAtA[iNdEx]
-						iNdEx++
-						v |= uint64(b&0x7F) << shift
-						if b < 0x80 {
-							break
-						}
-					}
-					m.Ts = append(m.Ts, v)
-				}
-			} else {
-				return fmt.Errorf("proto: wrong wireType = %d for field Ts", wireType)
-			}
-		default:
-			iNdEx = preIndex
-			skippy, err := skipPb(dAtA[iNdEx:])
-			if err != nil {
-				return err
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
 			}
-			if skippy < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			if (iNdEx + skippy) < 0 {
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *PeerResponse) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowPb
-			}
-			if iNdEx >= l {
+			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= uint64(b&0x7F) << shift
-			if b < 0x80 {
-				break
-			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: PeerResponse: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: PeerResponse: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
+			m.SessionToken = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 9:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Status", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Anonymous", wireType)
 			}
 			var v int
 			for shift := uint(0); ; shift += 7 {
@@ -21630,7 +28758,7 @@ func (m *PeerResponse) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
-			m.Status = bool(v != 0)
+			m.Anonymous = bool(v != 0)
 		default:
 			iNdEx = preIndex
 			skippy, err := skipPb(dAtA[iNdEx:])
@@ -21656,7 +28784,7 @@ func (m *PeerResponse) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *RaftBatch) Unmarshal(dAtA []byte) error {
+func (m *ExportResponse) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -21679,17 +28807,17 @@ func (m *RaftBatch) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: RaftBatch: wiretype end group for non-group")
+			return fmt.Errorf("proto: ExportResponse: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: RaftBatch: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: ExportResponse: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Context", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Code", wireType)
 			}
-			var msglen int
+			m.Code = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -21699,33 +28827,16 @@ func (m *RaftBatch) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				m.Code |= int32(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
-				return ErrInvalidLengthPb
-			}
-			postIndex := iNdEx + msglen
-			if postIndex < 0 {
-				return ErrInvalidLengthPb
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			if m.Context == nil {
-				m.Context = &RaftContext{}
-			}
-			if err := m.Context.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
-			iNdEx = postIndex
 		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Payload", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Msg", wireType)
 			}
-			var msglen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -21735,87 +28846,29 @@ func (m *RaftBatch) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + intStringLen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.Payload == nil {
-				m.Payload = &api.Payload{}
-			}
-			if err := m.Payload.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
+			m.Msg = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		default:
-			iNdEx = preIndex
-			skippy, err := skipPb(dAtA[iNdEx:])
-			if err != nil {
-				return err
-			}
-			if skippy < 0 {
-				return ErrInvalidLengthPb
-			}
-			if (iNdEx + skippy) < 0 {
-				return ErrInvalidLengthPb
-			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *SubscriptionRequest) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowPb
-			}
-			if iNdEx >= l {
-				return io.ErrUnexpectedEOF
-			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= uint64(b&0x7F) << shift
-			if b < 0x80 {
-				break
-			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: SubscriptionRequest: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: SubscriptionRequest: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
+		case 3:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Prefixes", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Files", wireType)
 			}
-			var byteLen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -21825,23 +28878,23 @@ func (m *SubscriptionRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if byteLen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + byteLen
+			postIndex := iNdEx + intStringLen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Prefixes = append(m.Prefixes, make([]byte, postIndex-iNdEx))
-			copy(m.Prefixes[len(m.Prefixes)-1], dAtA[iNdEx:postIndex])
+			m.Files = append(m.Files, string(dAtA[iNdEx:postIndex]))
 			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
@@ -21868,7 +28921,7 @@ func (m *SubscriptionRequest) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *SubscriptionResponse) Unmarshal(dAtA []byte) error {
+func (m *BackupKey) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -21891,17 +28944,17 @@ func (m *SubscriptionResponse) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: SubscriptionResponse: wiretype end group for non-group")
+			return fmt.Errorf("proto: BackupKey: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: SubscriptionResponse: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: BackupKey: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Kvs", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Type", wireType)
 			}
-			var msglen int
+			m.Type = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -21911,87 +28964,48 @@ func (m *SubscriptionResponse) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				m.Type |= BackupKey_KeyType(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
-				return ErrInvalidLengthPb
-			}
-			postIndex := iNdEx + msglen
-			if postIndex < 0 {
-				return ErrInvalidLengthPb
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			if m.Kvs == nil {
-				m.Kvs = &pb.KVList{}
-			}
-			if err := m.Kvs.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
-			iNdEx = postIndex
-		default:
-			iNdEx = preIndex
-			skippy, err := skipPb(dAtA[iNdEx:])
-			if err != nil {
-				return err
-			}
-			if skippy < 0 {
-				return ErrInvalidLengthPb
-			}
-			if (iNdEx + skippy) < 0 {
-				return ErrInvalidLengthPb
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Attr", wireType)
 			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
 			}
-			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *Num) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowPb
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
 			}
-			if iNdEx >= l {
-				return io.ErrUnexpectedEOF
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
 			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= uint64(b&0x7F) << shift
-			if b < 0x80 {
-				break
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
 			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: Num: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Num: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
+			m.Attr = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 3:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Val", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Uid", wireType)
 			}
-			m.Val = 0
+			m.Uid = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -22001,16 +29015,16 @@ func (m *Num) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Val |= uint64(b&0x7F) << shift
+				m.Uid |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 2:
+		case 4:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ReadOnly", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field StartUid", wireType)
 			}
-			var v int
+			m.StartUid = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -22020,17 +29034,48 @@ func (m *Num) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= int(b&0x7F) << shift
+				m.StartUid |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			m.ReadOnly = bool(v != 0)
-		case 3:
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Term", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Term = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 6:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Forwarded", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Count", wireType)
 			}
-			var v int
+			m.Count = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -22040,12 +29085,11 @@ func (m *Num) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= int(b&0x7F) << shift
+				m.Count |= uint32(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			m.Forwarded = bool(v != 0)
 		default:
 			iNdEx = preIndex
 			skippy, err := skipPb(dAtA[iNdEx:])
@@ -22071,7 +29115,7 @@ func (m *Num) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *AssignedIds) Unmarshal(dAtA []byte) error {
+func (m *BackupPostingList) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -22094,17 +29138,93 @@ func (m *AssignedIds) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: AssignedIds: wiretype end group for non-group")
+			return fmt.Errorf("proto: BackupPostingList: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: AssignedIds: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: BackupPostingList: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field StartId", wireType)
+			if wireType == 0 {
+				var v uint64
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowPb
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					v |= uint64(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				m.Uids = append(m.Uids, v)
+			} else if wireType == 2 {
+				var packedLen int
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowPb
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					packedLen |= int(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				if packedLen < 0 {
+					return ErrInvalidLengthPb
+				}
+				postIndex := iNdEx + packedLen
+				if postIndex < 0 {
+					return ErrInvalidLengthPb
+				}
+				if postIndex > l {
+					return io.ErrUnexpectedEOF
+				}
+				var elementCount int
+				var count int
+				for _, integer := range dAtA[iNdEx:postIndex] {
+					if integer < 128 {
+						count++
+					}
+				}
+				elementCount = count
+				if elementCount != 0 && len(m.Uids) == 0 {
+					m.Uids = make([]uint64, 0, elementCount)
+				}
+				for iNdEx < postIndex {
+					var v uint64
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowPb
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						v |= uint64(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					m.Uids = append(m.Uids, v)
+				}
+			} else {
+				return fmt.Errorf("proto: wrong wireType = %d for field Uids", wireType)
 			}
-			m.StartId = 0
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Postings", wireType)
+			}
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -22114,16 +29234,31 @@ func (m *AssignedIds) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.StartId |= uint64(b&0x7F) << shift
+				msglen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 2:
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Postings = append(m.Postings, &Posting{})
+			if err := m.Postings[len(m.Postings)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 3:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field EndId", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field CommitTs", wireType)
 			}
-			m.EndId = 0
+			m.CommitTs = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -22131,18 +29266,94 @@ func (m *AssignedIds) Unmarshal(dAtA []byte) error {
 				if iNdEx >= l {
 					return io.ErrUnexpectedEOF
 				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.EndId |= uint64(b&0x7F) << shift
-				if b < 0x80 {
-					break
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.CommitTs |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType == 0 {
+				var v uint64
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowPb
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					v |= uint64(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				m.Splits = append(m.Splits, v)
+			} else if wireType == 2 {
+				var packedLen int
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowPb
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					packedLen |= int(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				if packedLen < 0 {
+					return ErrInvalidLengthPb
+				}
+				postIndex := iNdEx + packedLen
+				if postIndex < 0 {
+					return ErrInvalidLengthPb
+				}
+				if postIndex > l {
+					return io.ErrUnexpectedEOF
+				}
+				var elementCount int
+				var count int
+				for _, integer := range dAtA[iNdEx:postIndex] {
+					if integer < 128 {
+						count++
+					}
+				}
+				elementCount = count
+				if elementCount != 0 && len(m.Splits) == 0 {
+					m.Splits = make([]uint64, 0, elementCount)
+				}
+				for iNdEx < postIndex {
+					var v uint64
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowPb
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						v |= uint64(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					m.Splits = append(m.Splits, v)
 				}
+			} else {
+				return fmt.Errorf("proto: wrong wireType = %d for field Splits", wireType)
 			}
 		case 5:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ReadOnly", wireType)
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field UidBytes", wireType)
 			}
-			m.ReadOnly = 0
+			var byteLen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -22152,11 +29363,26 @@ func (m *AssignedIds) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.ReadOnly |= uint64(b&0x7F) << shift
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
+			if byteLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.UidBytes = append(m.UidBytes[:0], dAtA[iNdEx:postIndex]...)
+			if m.UidBytes == nil {
+				m.UidBytes = []byte{}
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipPb(dAtA[iNdEx:])
@@ -22182,7 +29408,7 @@ func (m *AssignedIds) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *SnapshotMeta) Unmarshal(dAtA []byte) error {
+func (m *UpdateGraphQLSchemaRequest) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -22205,17 +29431,17 @@ func (m *SnapshotMeta) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: SnapshotMeta: wiretype end group for non-group")
+			return fmt.Errorf("proto: UpdateGraphQLSchemaRequest: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: SnapshotMeta: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: UpdateGraphQLSchemaRequest: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ClientTs", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field StartTs", wireType)
 			}
-			m.ClientTs = 0
+			m.StartTs = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -22225,16 +29451,16 @@ func (m *SnapshotMeta) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.ClientTs |= uint64(b&0x7F) << shift
+				m.StartTs |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
 		case 2:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field GroupId", wireType)
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field GraphqlSchema", wireType)
 			}
-			m.GroupId = 0
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -22244,70 +29470,29 @@ func (m *SnapshotMeta) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.GroupId |= uint32(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		default:
-			iNdEx = preIndex
-			skippy, err := skipPb(dAtA[iNdEx:])
-			if err != nil {
-				return err
-			}
-			if skippy < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			if (iNdEx + skippy) < 0 {
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *Status) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowPb
-			}
-			if iNdEx >= l {
+			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= uint64(b&0x7F) << shift
-			if b < 0x80 {
-				break
-			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: Status: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Status: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Code", wireType)
+			m.GraphqlSchema = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field DgraphPreds", wireType)
 			}
-			m.Code = 0
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -22317,16 +29502,31 @@ func (m *Status) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Code |= int32(b&0x7F) << shift
+				msglen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 2:
+			if msglen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.DgraphPreds = append(m.DgraphPreds, &SchemaUpdate{})
+			if err := m.DgraphPreds[len(m.DgraphPreds)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 4:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Msg", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field DgraphTypes", wireType)
 			}
-			var stringLen uint64
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -22336,23 +29536,25 @@ func (m *Status) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				msglen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
+			if msglen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + intStringLen
+			postIndex := iNdEx + msglen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Msg = string(dAtA[iNdEx:postIndex])
+			m.DgraphTypes = append(m.DgraphTypes, &TypeUpdate{})
+			if err := m.DgraphTypes[len(m.DgraphTypes)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
 			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
@@ -22379,7 +29581,7 @@ func (m *Status) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *BackupRequest) Unmarshal(dAtA []byte) error {
+func (m *UpdateGraphQLSchemaResponse) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -22402,55 +29604,17 @@ func (m *BackupRequest) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: BackupRequest: wiretype end group for non-group")
+			return fmt.Errorf("proto: UpdateGraphQLSchemaResponse: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: BackupRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: UpdateGraphQLSchemaResponse: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ReadTs", wireType)
-			}
-			m.ReadTs = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.ReadTs |= uint64(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-		case 2:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field SinceTs", wireType)
-			}
-			m.SinceTs = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.SinceTs |= uint64(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-		case 3:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field GroupId", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Uid", wireType)
 			}
-			m.GroupId = 0
+			m.Uid = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -22460,46 +29624,68 @@ func (m *BackupRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.GroupId |= uint32(b&0x7F) << shift
+				m.Uid |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 4:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field UnixTs", wireType)
-			}
-			var stringLen uint64
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
+			if skippy < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
+			if (iNdEx + skippy) < 0 {
 				return ErrInvalidLengthPb
 			}
-			if postIndex > l {
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *IdempotencyRecord) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
+			}
+			if iNdEx >= l {
 				return io.ErrUnexpectedEOF
 			}
-			m.UnixTs = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
-		case 5:
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: IdempotencyRecord: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: IdempotencyRecord: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Destination", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -22527,13 +29713,13 @@ func (m *BackupRequest) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Destination = string(dAtA[iNdEx:postIndex])
+			m.Key = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 6:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field AccessKey", wireType)
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Fingerprint", wireType)
 			}
-			var stringLen uint64
+			m.Fingerprint = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -22543,29 +29729,16 @@ func (m *BackupRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				m.Fingerprint |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthPb
-			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
-				return ErrInvalidLengthPb
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.AccessKey = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
-		case 7:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field SecretKey", wireType)
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field StartTs", wireType)
 			}
-			var stringLen uint64
+			m.StartTs = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -22575,29 +29748,16 @@ func (m *BackupRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				m.StartTs |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthPb
-			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
-				return ErrInvalidLengthPb
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.SecretKey = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
-		case 8:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field SessionToken", wireType)
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field CommitTs", wireType)
 			}
-			var stringLen uint64
+			m.CommitTs = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -22607,29 +29767,16 @@ func (m *BackupRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				m.CommitTs |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthPb
-			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
-				return ErrInvalidLengthPb
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.SessionToken = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
-		case 9:
+		case 5:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Anonymous", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field ExpiresAt", wireType)
 			}
-			var v int
+			m.ExpiresAt = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -22639,17 +29786,16 @@ func (m *BackupRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= int(b&0x7F) << shift
+				m.ExpiresAt |= int64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			m.Anonymous = bool(v != 0)
-		case 10:
+		case 6:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Predicates", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Response", wireType)
 			}
-			var stringLen uint64
+			var byteLen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -22659,23 +29805,25 @@ func (m *BackupRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
+			if byteLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + intStringLen
+			postIndex := iNdEx + byteLen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Predicates = append(m.Predicates, string(dAtA[iNdEx:postIndex]))
+			m.Response = append(m.Response[:0], dAtA[iNdEx:postIndex]...)
+			if m.Response == nil {
+				m.Response = []byte{}
+			}
 			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
@@ -22702,7 +29850,7 @@ func (m *BackupRequest) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *ExportRequest) Unmarshal(dAtA []byte) error {
+func (m *StrictSchema) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -22725,55 +29873,17 @@ func (m *ExportRequest) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: ExportRequest: wiretype end group for non-group")
+			return fmt.Errorf("proto: StrictSchema: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: ExportRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: StrictSchema: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field GroupId", wireType)
-			}
-			m.GroupId = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.GroupId |= uint32(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-		case 2:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ReadTs", wireType)
-			}
-			m.ReadTs = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.ReadTs |= uint64(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-		case 3:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field UnixTs", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Enabled", wireType)
 			}
-			m.UnixTs = 0
+			var v int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -22783,78 +29893,69 @@ func (m *ExportRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.UnixTs |= int64(b&0x7F) << shift
+				v |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 4:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Format", wireType)
-			}
-			var stringLen uint64
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
+			m.Enabled = bool(v != 0)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
+			if skippy < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
+			if (iNdEx + skippy) < 0 {
 				return ErrInvalidLengthPb
 			}
-			if postIndex > l {
+			if (iNdEx + skippy) > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Format = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
-		case 5:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Destination", wireType)
-			}
-			var stringLen uint64
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthPb
-			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
-				return ErrInvalidLengthPb
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *BlobChunk) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
 			}
-			if postIndex > l {
+			if iNdEx >= l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Destination = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
-		case 6:
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: BlobChunk: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: BlobChunk: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field AccessKey", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Attr", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -22882,13 +29983,23 @@ func (m *ExportRequest) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.AccessKey = string(dAtA[iNdEx:postIndex])
+			m.Attr = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 7:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field SecretKey", wireType)
+		case 2:
+			if wireType != 1 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Uid", wireType)
 			}
-			var stringLen uint64
+			m.Uid = 0
+			if (iNdEx + 8) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Uid = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
+			iNdEx += 8
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Idx", wireType)
+			}
+			m.Idx = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -22898,29 +30009,16 @@ func (m *ExportRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				m.Idx |= uint32(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthPb
-			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
-				return ErrInvalidLengthPb
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.SecretKey = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
-		case 8:
+		case 4:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field SessionToken", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Data", wireType)
 			}
-			var stringLen uint64
+			var byteLen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -22930,44 +30028,26 @@ func (m *ExportRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
+			if byteLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + intStringLen
+			postIndex := iNdEx + byteLen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.SessionToken = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
-		case 9:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Anonymous", wireType)
-			}
-			var v int
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				v |= int(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
+			m.Data = append(m.Data[:0], dAtA[iNdEx:postIndex]...)
+			if m.Data == nil {
+				m.Data = []byte{}
 			}
-			m.Anonymous = bool(v != 0)
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipPb(dAtA[iNdEx:])
@@ -22993,7 +30073,7 @@ func (m *ExportRequest) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *ExportResponse) Unmarshal(dAtA []byte) error {
+func (m *BlobRequest) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -23016,34 +30096,15 @@ func (m *ExportResponse) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: ExportResponse: wiretype end group for non-group")
+			return fmt.Errorf("proto: BlobRequest: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: ExportResponse: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: BlobRequest: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Code", wireType)
-			}
-			m.Code = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.Code |= int32(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Msg", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Attr", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -23071,13 +30132,23 @@ func (m *ExportResponse) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Msg = string(dAtA[iNdEx:postIndex])
+			m.Attr = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
+		case 2:
+			if wireType != 1 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Uid", wireType)
+			}
+			m.Uid = 0
+			if (iNdEx + 8) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Uid = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
+			iNdEx += 8
 		case 3:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Files", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ReadTs", wireType)
 			}
-			var stringLen uint64
+			m.ReadTs = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -23087,24 +30158,11 @@ func (m *ExportResponse) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				m.ReadTs |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthPb
-			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
-				return ErrInvalidLengthPb
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Files = append(m.Files, string(dAtA[iNdEx:postIndex]))
-			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipPb(dAtA[iNdEx:])
@@ -23130,7 +30188,8 @@ func (m *ExportResponse) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *BackupKey) Unmarshal(dAtA []byte) error {
+
+func (m *ReplicationState) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -23153,17 +30212,17 @@ func (m *BackupKey) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: BackupKey: wiretype end group for non-group")
+			return fmt.Errorf("proto: ReplicationState: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: BackupKey: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: ReplicationState: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Type", wireType)
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Primary", wireType)
 			}
-			m.Type = 0
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -23173,16 +30232,29 @@ func (m *BackupKey) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Type |= BackupKey_KeyType(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Primary = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
 		case 2:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Attr", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PrimaryTs", wireType)
 			}
-			var stringLen uint64
+			m.PrimaryTs = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -23192,48 +30264,129 @@ func (m *BackupKey) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				m.PrimaryTs |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ReadTs", wireType)
+			}
+			m.ReadTs = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ReadTs |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SnapshotAt", wireType)
+			}
+			m.SnapshotAt = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.SnapshotAt |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Promote", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Promote = bool(v != 0)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
+			if (iNdEx + skippy) < 0 {
 				return ErrInvalidLengthPb
 			}
-			if postIndex > l {
+			if (iNdEx + skippy) > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Attr = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
-		case 3:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Uid", wireType)
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+func (m *ReplicationRequest) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
 			}
-			m.Uid = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.Uid |= uint64(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
 			}
-		case 4:
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: ReplicationRequest: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: ReplicationRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field StartUid", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field GroupId", wireType)
 			}
-			m.StartUid = 0
+			m.GroupId = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -23243,16 +30396,16 @@ func (m *BackupKey) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.StartUid |= uint64(b&0x7F) << shift
+				m.GroupId |= uint32(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 5:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Term", wireType)
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SinceTs", wireType)
 			}
-			var stringLen uint64
+			m.SinceTs = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -23262,29 +30415,16 @@ func (m *BackupKey) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				m.SinceTs |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthPb
-			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
-				return ErrInvalidLengthPb
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Term = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
-		case 6:
+		case 3:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Count", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field ReadTs", wireType)
 			}
-			m.Count = 0
+			m.ReadTs = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -23294,7 +30434,7 @@ func (m *BackupKey) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Count |= uint32(b&0x7F) << shift
+				m.ReadTs |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -23324,7 +30464,8 @@ func (m *BackupKey) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *BackupPostingList) Unmarshal(dAtA []byte) error {
+
+func (m *KeyRange) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -23347,93 +30488,17 @@ func (m *BackupPostingList) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: BackupPostingList: wiretype end group for non-group")
+			return fmt.Errorf("proto: KeyRange: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: BackupPostingList: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: KeyRange: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType == 0 {
-				var v uint64
-				for shift := uint(0); ; shift += 7 {
-					if shift >= 64 {
-						return ErrIntOverflowPb
-					}
-					if iNdEx >= l {
-						return io.ErrUnexpectedEOF
-					}
-					b := dAtA[iNdEx]
-					iNdEx++
-					v |= uint64(b&0x7F) << shift
-					if b < 0x80 {
-						break
-					}
-				}
-				m.Uids = append(m.Uids, v)
-			} else if wireType == 2 {
-				var packedLen int
-				for shift := uint(0); ; shift += 7 {
-					if shift >= 64 {
-						return ErrIntOverflowPb
-					}
-					if iNdEx >= l {
-						return io.ErrUnexpectedEOF
-					}
-					b := dAtA[iNdEx]
-					iNdEx++
-					packedLen |= int(b&0x7F) << shift
-					if b < 0x80 {
-						break
-					}
-				}
-				if packedLen < 0 {
-					return ErrInvalidLengthPb
-				}
-				postIndex := iNdEx + packedLen
-				if postIndex < 0 {
-					return ErrInvalidLengthPb
-				}
-				if postIndex > l {
-					return io.ErrUnexpectedEOF
-				}
-				var elementCount int
-				var count int
-				for _, integer := range dAtA[iNdEx:postIndex] {
-					if integer < 128 {
-						count++
-					}
-				}
-				elementCount = count
-				if elementCount != 0 && len(m.Uids) == 0 {
-					m.Uids = make([]uint64, 0, elementCount)
-				}
-				for iNdEx < postIndex {
-					var v uint64
-					for shift := uint(0); ; shift += 7 {
-						if shift >= 64 {
-							return ErrIntOverflowPb
-						}
-						if iNdEx >= l {
-							return io.ErrUnexpectedEOF
-						}
-						b := dAtA[iNdEx]
-						iNdEx++
-						v |= uint64(b&0x7F) << shift
-						if b < 0x80 {
-							break
-						}
-					}
-					m.Uids = append(m.Uids, v)
-				}
-			} else {
-				return fmt.Errorf("proto: wrong wireType = %d for field Uids", wireType)
-			}
-		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Postings", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Start", wireType)
 			}
-			var msglen int
+			var byteLen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -23443,124 +30508,29 @@ func (m *BackupPostingList) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			if byteLen < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + byteLen
 			if postIndex < 0 {
 				return ErrInvalidLengthPb
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Postings = append(m.Postings, &Posting{})
-			if err := m.Postings[len(m.Postings)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
+			m.Start = append(m.Start[:0], dAtA[iNdEx:postIndex]...)
+			if m.Start == nil {
+				m.Start = []byte{}
 			}
 			iNdEx = postIndex
-		case 3:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field CommitTs", wireType)
-			}
-			m.CommitTs = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowPb
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.CommitTs |= uint64(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-		case 4:
-			if wireType == 0 {
-				var v uint64
-				for shift := uint(0); ; shift += 7 {
-					if shift >= 64 {
-						return ErrIntOverflowPb
-					}
-					if iNdEx >= l {
-						return io.ErrUnexpectedEOF
-					}
-					b := dAtA[iNdEx]
-					iNdEx++
-					v |= uint64(b&0x7F) << shift
-					if b < 0x80 {
-						break
-					}
-				}
-				m.Splits = append(m.Splits, v)
-			} else if wireType == 2 {
-				var packedLen int
-				for shift := uint(0); ; shift += 7 {
-					if shift >= 64 {
-						return ErrIntOverflowPb
-					}
-					if iNdEx >= l {
-						return io.ErrUnexpectedEOF
-					}
-					b := dAtA[iNdEx]
-					iNdEx++
-					packedLen |= int(b&0x7F) << shift
-					if b < 0x80 {
-						break
-					}
-				}
-				if packedLen < 0 {
-					return ErrInvalidLengthPb
-				}
-				postIndex := iNdEx + packedLen
-				if postIndex < 0 {
-					return ErrInvalidLengthPb
-				}
-				if postIndex > l {
-					return io.ErrUnexpectedEOF
-				}
-				var elementCount int
-				var count int
-				for _, integer := range dAtA[iNdEx:postIndex] {
-					if integer < 128 {
-						count++
-					}
-				}
-				elementCount = count
-				if elementCount != 0 && len(m.Splits) == 0 {
-					m.Splits = make([]uint64, 0, elementCount)
-				}
-				for iNdEx < postIndex {
-					var v uint64
-					for shift := uint(0); ; shift += 7 {
-						if shift >= 64 {
-							return ErrIntOverflowPb
-						}
-						if iNdEx >= l {
-							return io.ErrUnexpectedEOF
-						}
-						b := dAtA[iNdEx]
-						iNdEx++
-						v |= uint64(b&0x7F) << shift
-						if b < 0x80 {
-							break
-						}
-					}
-					m.Splits = append(m.Splits, v)
-				}
-			} else {
-				return fmt.Errorf("proto: wrong wireType = %d for field Splits", wireType)
-			}
-		case 5:
+		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field UidBytes", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field End", wireType)
 			}
 			var byteLen int
 			for shift := uint(0); ; shift += 7 {
@@ -23587,9 +30557,9 @@ func (m *BackupPostingList) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.UidBytes = append(m.UidBytes[:0], dAtA[iNdEx:postIndex]...)
-			if m.UidBytes == nil {
-				m.UidBytes = []byte{}
+			m.End = append(m.End[:0], dAtA[iNdEx:postIndex]...)
+			if m.End == nil {
+				m.End = []byte{}
 			}
 			iNdEx = postIndex
 		default:
@@ -23617,7 +30587,8 @@ func (m *BackupPostingList) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *UpdateGraphQLSchemaRequest) Unmarshal(dAtA []byte) error {
+
+func (m *FreezePoint) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -23640,17 +30611,17 @@ func (m *UpdateGraphQLSchemaRequest) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: UpdateGraphQLSchemaRequest: wiretype end group for non-group")
+			return fmt.Errorf("proto: FreezePoint: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: UpdateGraphQLSchemaRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: FreezePoint: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field StartTs", wireType)
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Tag", wireType)
 			}
-			m.StartTs = 0
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -23660,16 +30631,29 @@ func (m *UpdateGraphQLSchemaRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.StartTs |= uint64(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Tag = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
 		case 2:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field GraphqlSchema", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ReadTs", wireType)
 			}
-			var stringLen uint64
+			m.ReadTs = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -23679,27 +30663,33 @@ func (m *UpdateGraphQLSchemaRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				m.ReadTs |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthPb
-			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
-				return ErrInvalidLengthPb
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field CreatedAt", wireType)
 			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
+			m.CreatedAt = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.CreatedAt |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
 			}
-			m.GraphqlSchema = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
-		case 3:
+		case 4:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field DgraphPreds", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Groups", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -23726,16 +30716,16 @@ func (m *UpdateGraphQLSchemaRequest) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.DgraphPreds = append(m.DgraphPreds, &SchemaUpdate{})
-			if err := m.DgraphPreds[len(m.DgraphPreds)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+			m.Groups = append(m.Groups, &FrozenGroup{})
+			if err := m.Groups[len(m.Groups)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
 			iNdEx = postIndex
-		case 4:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field DgraphTypes", wireType)
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Ready", wireType)
 			}
-			var msglen int
+			var v int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -23745,26 +30735,143 @@ func (m *UpdateGraphQLSchemaRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				v |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			m.Ready = bool(v != 0)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipPb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
 				return ErrInvalidLengthPb
 			}
-			postIndex := iNdEx + msglen
-			if postIndex < 0 {
+			if (iNdEx + skippy) < 0 {
 				return ErrInvalidLengthPb
 			}
-			if postIndex > l {
+			if (iNdEx + skippy) > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.DgraphTypes = append(m.DgraphTypes, &TypeUpdate{})
-			if err := m.DgraphTypes[len(m.DgraphTypes)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+func (m *FrozenGroup) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowPb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: FrozenGroup: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: FrozenGroup: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field GroupId", wireType)
+			}
+			m.GroupId = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.GroupId |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ReadTs", wireType)
+			}
+			m.ReadTs = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ReadTs |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field NodeId", wireType)
+			}
+			m.NodeId = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.NodeId |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Index", wireType)
+			}
+			m.Index = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Index |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
 			}
-			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipPb(dAtA[iNdEx:])
@@ -23790,7 +30897,8 @@ func (m *UpdateGraphQLSchemaRequest) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *UpdateGraphQLSchemaResponse) Unmarshal(dAtA []byte) error {
+
+func (m *MoveProgress) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -23813,17 +30921,49 @@ func (m *UpdateGraphQLSchemaResponse) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: UpdateGraphQLSchemaResponse: wiretype end group for non-group")
+			return fmt.Errorf("proto: MoveProgress: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: UpdateGraphQLSchemaResponse: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: MoveProgress: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Predicate", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthPb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthPb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Predicate = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Uid", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Keys", wireType)
 			}
-			m.Uid = 0
+			m.Keys = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowPb
@@ -23833,7 +30973,26 @@ func (m *UpdateGraphQLSchemaResponse) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Uid |= uint64(b&0x7F) << shift
+				m.Keys |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Bytes", wireType)
+			}
+			m.Bytes = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowPb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Bytes |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
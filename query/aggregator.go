@@ -19,6 +19,7 @@ package query
 import (
 	"bytes"
 	"math"
+	"math/big"
 	"time"
 
 	"github.com/dgraph-io/dgraph/protos/pb"
@@ -73,6 +74,14 @@ func compareValues(ag string, va, vb types.Val) (bool, error) {
 	if err != nil {
 		//Try to convert values.
 		switch {
+		case va.Tid == types.DecimalID:
+			if err := toDecimal(&vb); err != nil {
+				return false, err
+			}
+		case vb.Tid == types.DecimalID:
+			if err := toDecimal(&va); err != nil {
+				return false, err
+			}
 		case va.Tid == types.IntID:
 			va.Tid = types.FloatID
 			va.Value = float64(va.Value.(int64))
@@ -121,6 +130,9 @@ func applyAdd(a, b, c *types.Val) error {
 	case FLOAT:
 		c.Value = a.Value.(float64) + b.Value.(float64)
 
+	case DECIMAL:
+		c.Value = new(big.Rat).Add(a.Value.(*big.Rat), b.Value.(*big.Rat))
+
 	case DEFAULT:
 		return errors.Errorf("Wrong type %v encountered for func +", a.Tid)
 	}
@@ -136,6 +148,9 @@ func applySub(a, b, c *types.Val) error {
 	case FLOAT:
 		c.Value = a.Value.(float64) - b.Value.(float64)
 
+	case DECIMAL:
+		c.Value = new(big.Rat).Sub(a.Value.(*big.Rat), b.Value.(*big.Rat))
+
 	case DEFAULT:
 		return errors.Errorf("Wrong type %v encountered for func -", a.Tid)
 	}
@@ -151,6 +166,9 @@ func applyMul(a, b, c *types.Val) error {
 	case FLOAT:
 		c.Value = a.Value.(float64) * b.Value.(float64)
 
+	case DECIMAL:
+		c.Value = new(big.Rat).Mul(a.Value.(*big.Rat), b.Value.(*big.Rat))
+
 	case DEFAULT:
 		return errors.Errorf("Wrong type %v encountered for func *", a.Tid)
 	}
@@ -172,6 +190,12 @@ func applyDiv(a, b, c *types.Val) error {
 		}
 		c.Value = a.Value.(float64) / b.Value.(float64)
 
+	case DECIMAL:
+		if b.Value.(*big.Rat).Sign() == 0 {
+			return errors.Errorf("Division by zero")
+		}
+		c.Value = new(big.Rat).Quo(a.Value.(*big.Rat), b.Value.(*big.Rat))
+
 	case DEFAULT:
 		return errors.Errorf("Wrong type %v encountered for func /", a.Tid)
 	}
@@ -193,6 +217,12 @@ func applyMod(a, b, c *types.Val) error {
 		}
 		c.Value = math.Mod(a.Value.(float64), b.Value.(float64))
 
+	case DECIMAL:
+		if b.Value.(*big.Rat).Sign() == 0 {
+			return errors.Errorf("Module by zero")
+		}
+		c.Value = types.DecimalMod(a.Value.(*big.Rat), b.Value.(*big.Rat))
+
 	case DEFAULT:
 		return errors.Errorf("Wrong type %v encountered for func %%", a.Tid)
 	}
@@ -209,6 +239,10 @@ func applyPow(a, b, c *types.Val) error {
 	case FLOAT:
 		c.Value = math.Pow(a.Value.(float64), b.Value.(float64))
 
+	case DECIMAL:
+		c.Value = math.Pow(decimalToFloat(a), decimalToFloat(b))
+		c.Tid = types.FloatID
+
 	case DEFAULT:
 		return errors.Errorf("Wrong type %v encountered for func ^", a.Tid)
 	}
@@ -225,6 +259,10 @@ func applyLog(a, b, c *types.Val) error {
 	case FLOAT:
 		c.Value = math.Log(a.Value.(float64)) / math.Log(b.Value.(float64))
 
+	case DECIMAL:
+		c.Value = math.Log(decimalToFloat(a)) / math.Log(decimalToFloat(b))
+		c.Tid = types.FloatID
+
 	case DEFAULT:
 		return errors.Errorf("Wrong type %v encountered for func log", a.Tid)
 	}
@@ -267,6 +305,10 @@ func applyLn(a, res *types.Val) error {
 	case FLOAT:
 		res.Value = math.Log(a.Value.(float64))
 
+	case DECIMAL:
+		res.Value = math.Log(decimalToFloat(a))
+		res.Tid = types.FloatID
+
 	case DEFAULT:
 		return errors.Errorf("Wrong type %v encountered for func ln", a.Tid)
 	}
@@ -283,6 +325,10 @@ func applyExp(a, res *types.Val) error {
 	case FLOAT:
 		res.Value = math.Exp(a.Value.(float64))
 
+	case DECIMAL:
+		res.Value = math.Exp(decimalToFloat(a))
+		res.Tid = types.FloatID
+
 	case DEFAULT:
 		return errors.Errorf("Wrong type %v encountered for func exp", a.Tid)
 	}
@@ -298,6 +344,9 @@ func applyNeg(a, res *types.Val) error {
 	case FLOAT:
 		res.Value = -a.Value.(float64)
 
+	case DECIMAL:
+		res.Value = new(big.Rat).Neg(a.Value.(*big.Rat))
+
 	case DEFAULT:
 		return errors.Errorf("Wrong type %v encountered for func u-", a.Tid)
 	}
@@ -314,6 +363,10 @@ func applySqrt(a, res *types.Val) error {
 	case FLOAT:
 		res.Value = math.Sqrt(a.Value.(float64))
 
+	case DECIMAL:
+		res.Value = math.Sqrt(decimalToFloat(a))
+		res.Tid = types.FloatID
+
 	case DEFAULT:
 		return errors.Errorf("Wrong type %v encountered for func sqrt", a.Tid)
 	}
@@ -329,6 +382,9 @@ func applyFloor(a, res *types.Val) error {
 	case FLOAT:
 		res.Value = math.Floor(a.Value.(float64))
 
+	case DECIMAL:
+		res.Value = types.DecimalFloor(a.Value.(*big.Rat))
+
 	case DEFAULT:
 		return errors.Errorf("Wrong type %v encountered for func floor", a.Tid)
 	}
@@ -344,6 +400,9 @@ func applyCeil(a, res *types.Val) error {
 	case FLOAT:
 		res.Value = math.Ceil(a.Value.(float64))
 
+	case DECIMAL:
+		res.Value = types.DecimalCeil(a.Value.(*big.Rat))
+
 	case DEFAULT:
 		return errors.Errorf("Wrong type %v encountered for fun ceil", a.Tid)
 	}
@@ -360,6 +419,31 @@ func applySince(a, res *types.Val) error {
 	return errors.Errorf("Wrong type %v encountered for func since", a.Tid)
 }
 
+// toDecimal converts an int or float value to decimal.
+func toDecimal(v *types.Val) error {
+	switch v.Tid {
+	case types.IntID:
+		v.Value = new(big.Rat).SetInt64(v.Value.(int64))
+	case types.FloatID:
+		r, err := types.DecimalFromFloat(v.Value.(float64))
+		if err != nil {
+			return err
+		}
+		v.Value = r
+	default:
+		return errors.Errorf("Wrong type %v encountered for decimal", v.Tid)
+	}
+	v.Tid = types.DecimalID
+	return nil
+}
+
+// decimalToFloat returns the float closest to the decimal value, for the functions that can't be
+// computed exactly.
+func decimalToFloat(v *types.Val) float64 {
+	f, _ := v.Value.(*big.Rat).Float64()
+	return f
+}
+
 type unaryFunc func(a, res *types.Val) error
 type binaryFunc func(a, b, res *types.Val) error
 
@@ -390,6 +474,7 @@ type valType int
 const (
 	INT valType = iota
 	FLOAT
+	DECIMAL
 	DEFAULT
 )
 
@@ -400,6 +485,8 @@ func getValType(v *types.Val) valType {
 		vBase = INT
 	case types.FloatID:
 		vBase = FLOAT
+	case types.DecimalID:
+		vBase = DECIMAL
 	default:
 		vBase = DEFAULT
 	}
@@ -418,6 +505,15 @@ func (ag *aggregator) matchType(v, va *types.Val) error {
 			va.Tid, ag.name)
 	}
 
+	// If one of them is decimal, the other one is converted to decimal so that the result is
+	// exact.
+	if vBase == DECIMAL {
+		return toDecimal(va)
+	}
+	if vaBase == DECIMAL {
+		return toDecimal(v)
+	}
+
 	// One of them is int and one is float
 	if vBase == INT {
 		v.Tid = types.FloatID
@@ -505,6 +601,8 @@ func (ag *aggregator) Apply(val types.Val) {
 			va.Value = va.Value.(int64) + vb.Value.(int64)
 		case va.Tid == types.FloatID && vb.Tid == types.FloatID:
 			va.Value = va.Value.(float64) + vb.Value.(float64)
+		case va.Tid == types.DecimalID && vb.Tid == types.DecimalID:
+			va.Value = new(big.Rat).Add(va.Value.(*big.Rat), vb.Value.(*big.Rat))
 		}
 		// Skipping the else case since that means the pair cannot be summed.
 		res = va
@@ -537,6 +635,10 @@ func (ag *aggregator) divideByCount() {
 	}
 	var v float64
 	switch ag.result.Tid {
+	case types.DecimalID:
+		ag.result.Value = new(big.Rat).Quo(ag.result.Value.(*big.Rat),
+			big.NewRat(int64(ag.count), 1))
+		return
 	case types.IntID:
 		v = float64(ag.result.Value.(int64))
 	case types.FloatID:
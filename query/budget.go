@@ -0,0 +1,79 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package query
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// nodeBudget counts the nodes and edges expanded by a query, so that a query going over its
+// max_nodes limit is stopped while it runs.
+type nodeBudget struct {
+	max  uint64
+	used uint64 // Accessed atomically.
+}
+
+func withNodeBudget(ctx context.Context, max uint64) context.Context {
+	return context.WithValue(ctx, budgetKey, &nodeBudget{max: max})
+}
+
+// chargeNodeBudget adds the nodes and edges found by sg to the budget of the request and
+// returns an error if the budget has been exceeded. The root contributes its destination
+// uids, whereas the children contribute their outgoing edges and values. Filters don't
+// contribute anything, because they only restrict the results of their parent.
+func (sg *SubGraph) chargeNodeBudget(ctx context.Context, parent *SubGraph) error {
+	b, _ := ctx.Value(budgetKey).(*nodeBudget)
+	if b == nil || parent.isFilter(sg) {
+		return nil
+	}
+
+	var num uint64
+	if parent == nil {
+		num = uint64(len(sg.DestUIDs.GetUids()))
+	} else {
+		if len(sg.Filters) > 0 {
+			sg.updateUidMatrix()
+		}
+		for _, ul := range sg.uidMatrix {
+			num += uint64(len(ul.GetUids()))
+		}
+		for _, vl := range sg.valueMatrix {
+			num += uint64(len(vl.GetValues()))
+		}
+	}
+
+	if atomic.AddUint64(&b.used, num) > b.max {
+		return &x.LimitExceededError{Limit: x.LimitMaxNodes, Max: b.max}
+	}
+	return nil
+}
+
+// isFilter returns whether child is one of the filters of sg.
+func (sg *SubGraph) isFilter(child *SubGraph) bool {
+	if sg == nil {
+		return false
+	}
+	for _, f := range sg.Filters {
+		if f == child {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,193 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package query
+
+import (
+	"bytes"
+	"context"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/tok"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/worker"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// expandCompositeIndexes adds the edges that keep the composite indexes spanning the predicates
+// of the edges in sync. Each node the edges change gets the values of all the predicates of the
+// index stored in it, or loses its value if one of them has none.
+func expandCompositeIndexes(ctx context.Context, edges []*pb.DirectedEdge,
+	startTs uint64) ([]*pb.DirectedEdge, error) {
+
+	indexes := make(map[string][]string)
+	nodes := make(map[string]map[uint64]struct{})
+	for _, edge := range edges {
+		if x.IsCompositeIndex(edge.Attr) {
+			if edge.Op == pb.DirectedEdge_DEL && bytes.Equal(edge.Value, []byte(x.Star)) {
+				continue
+			}
+			return nil, errors.Errorf("Composite index %s can only be set through the"+
+				" predicates it spans", edge.Attr)
+		}
+		// Dropping a predicate doesn't change the indexes here, they are dropped with separate
+		// mutations.
+		if edge.Entity == 0 || edge.Lang != "" {
+			continue
+		}
+		if _, ok := indexes[edge.Attr]; !ok {
+			indexes[edge.Attr] = worker.CompositeIndexes(edge.Attr)
+		}
+		for _, index := range indexes[edge.Attr] {
+			if _, ok := nodes[index]; !ok {
+				nodes[index] = make(map[uint64]struct{})
+			}
+			nodes[index][edge.Entity] = struct{}{}
+		}
+	}
+
+	names := make([]string, 0, len(nodes))
+	for index := range nodes {
+		names = append(names, index)
+	}
+	sort.Strings(names)
+	out := edges
+	for _, index := range names {
+		uids := make([]uint64, 0, len(nodes[index]))
+		for uid := range nodes[index] {
+			uids = append(uids, uid)
+		}
+		sort.Slice(uids, func(i, j int) bool { return uids[i] < uids[j] })
+		indexEdges, err := compositeIndexEdges(ctx, index, uids, edges, startTs)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, indexEdges...)
+	}
+	return out, nil
+}
+
+// CompositeIndexEdges returns the edges storing the values of the given sorted nodes in a
+// composite index, from the values the predicates it spans have at startTs.
+func CompositeIndexEdges(ctx context.Context, index string, uids []uint64,
+	startTs uint64) ([]*pb.DirectedEdge, error) {
+	return compositeIndexEdges(ctx, index, uids, nil, startTs)
+}
+
+// compositeIndexEdges returns the edges storing the values of the given sorted nodes in a
+// composite index, from the values the predicates it spans have at startTs with the given edges
+// applied on top.
+func compositeIndexEdges(ctx context.Context, index string, uids []uint64,
+	edges []*pb.DirectedEdge, startTs uint64) ([]*pb.DirectedEdge, error) {
+
+	preds := x.CompositeIndexPredicates(index)
+	tids, err := worker.CompositeIndexTypes(ctx, preds)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]map[uint64]string, len(preds))
+	for _, pred := range preds {
+		if values[pred], err = compositeStoredValues(ctx, pred, tids[pred], uids,
+			startTs); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, edge := range edges {
+		vals, ok := values[edge.Attr]
+		if !ok || edge.Lang != "" {
+			continue
+		}
+		idx := sort.Search(len(uids), func(i int) bool { return uids[i] >= edge.Entity })
+		if idx == len(uids) || uids[idx] != edge.Entity {
+			continue
+		}
+		if edge.Op == pb.DirectedEdge_DEL && bytes.Equal(edge.Value, []byte(x.Star)) {
+			delete(vals, edge.Entity)
+			continue
+		}
+		val, err := worker.CompositeIndexValue(
+			types.Val{Tid: types.TypeID(edge.ValueType), Value: edge.Value}, tids[edge.Attr])
+		if err != nil {
+			return nil, errors.Wrapf(err, "Input for predicate %q of composite index %s has an"+
+				" invalid value", edge.Attr, index)
+		}
+		switch {
+		case edge.Op == pb.DirectedEdge_SET:
+			vals[edge.Entity] = val
+		case vals[edge.Entity] == val:
+			// Deleting a value other than the one of the node changes nothing.
+			delete(vals, edge.Entity)
+		}
+	}
+
+	out := make([]*pb.DirectedEdge, 0, len(uids))
+	for _, uid := range uids {
+		row := make([]string, 0, len(preds))
+		for _, pred := range preds {
+			val, ok := values[pred][uid]
+			if !ok {
+				break
+			}
+			row = append(row, val)
+		}
+		edge := &pb.DirectedEdge{
+			Entity: uid,
+			Attr:   index,
+			Value:  []byte(x.Star),
+			Op:     pb.DirectedEdge_DEL,
+		}
+		if len(row) == len(preds) {
+			edge.Value = []byte(tok.CompositeValue(row))
+			edge.ValueType = pb.Posting_STRING
+			edge.Op = pb.DirectedEdge_SET
+		}
+		out = append(out, edge)
+	}
+	return out, nil
+}
+
+// compositeStoredValues returns the values of a predicate spanned by a composite index that the
+// given sorted nodes have at readTs, as stored by the index.
+func compositeStoredValues(ctx context.Context, pred string, tid types.TypeID, uids []uint64,
+	readTs uint64) (map[uint64]string, error) {
+
+	res, err := worker.ProcessTaskOverNetwork(ctx, &pb.Query{
+		Attr:    pred,
+		UidList: &pb.List{Uids: uids},
+		ReadTs:  readTs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[uint64]string, len(uids))
+	for i, list := range res.ValueMatrix {
+		if i >= len(uids) || len(list.Values) == 0 || len(list.Values[0].Val) == 0 {
+			continue
+		}
+		tv := list.Values[0]
+		val, err := worker.CompositeIndexValue(
+			types.Val{Tid: types.TypeID(tv.ValType), Value: tv.Val}, tid)
+		if err != nil {
+			return nil, err
+		}
+		values[uids[i]] = val
+	}
+	return values, nil
+}
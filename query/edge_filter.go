@@ -0,0 +1,166 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+
+package query
+
+import (
+	"context"
+	"strings"
+
+	"github.com/dgraph-io/dgraph/algo"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/worker"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/pkg/errors"
+)
+
+// isEdgeFilter returns whether the filters of the SubGraph are on the properties of its edges,
+// as in friend @filter(ge(<friend|since>, "2018")). Such filters are applied to each edge
+// instead of to the nodes it points to, so they can't be combined with filters on the nodes.
+func (sg *SubGraph) isEdgeFilter() (bool, error) {
+	if len(sg.Filters) == 0 || sg.Attr == "" || sg.SrcUIDs == nil {
+		return false, nil
+	}
+	prefix := x.EdgeProperty(sg.Attr, "")
+	var edges, nodes int
+	var walk func(f *SubGraph) error
+	walk = func(f *SubGraph) error {
+		if f.FilterOp != "" {
+			for _, child := range f.Filters {
+				if err := walk(child); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if !strings.HasPrefix(f.Attr, prefix) {
+			nodes++
+			return nil
+		}
+		edges++
+		if f.SrcFunc == nil || len(f.Params.NeedsVar) > 0 || f.SrcFunc.IsValueVar {
+			return errors.Errorf("Filter on edge property %s requires a function with"+
+				" constant arguments", f.Attr)
+		}
+		switch f.SrcFunc.Name {
+		case "eq", "le", "lt", "ge", "gt", "allofterms", "anyofterms":
+		default:
+			return errors.Errorf("Function %s isn't supported in a filter on edge property %s",
+				f.SrcFunc.Name, f.Attr)
+		}
+		return nil
+	}
+	for _, f := range sg.Filters {
+		if err := walk(f); err != nil {
+			return false, err
+		}
+	}
+	if edges > 0 && nodes > 0 {
+		return false, errors.Errorf("Filters on the edge properties of %s can't be combined with"+
+			" other filters", sg.Attr)
+	}
+	return edges > 0, nil
+}
+
+// applyEdgeFilter keeps the edges in the uidMatrix matched by the filters on the properties of
+// the edges, along with their facets.
+func (sg *SubGraph) applyEdgeFilter(ctx context.Context) error {
+	rows, err := sg.edgeFilterRows(ctx, sg)
+	if err != nil {
+		return err
+	}
+
+	hasFacets := len(sg.facetsMatrix) == len(sg.uidMatrix)
+	for i, l := range sg.uidMatrix {
+		uids := l.Uids[:0]
+		var fcs []*pb.Facets
+		for idx, uid := range l.Uids {
+			if algo.IndexOf(rows[i], uid) < 0 {
+				continue
+			}
+			uids = append(uids, uid)
+			if hasFacets {
+				fcs = append(fcs, sg.facetsMatrix[i].FacetsList[idx])
+			}
+		}
+		l.Uids = uids
+		if hasFacets {
+			sg.facetsMatrix[i].FacetsList = fcs
+		}
+	}
+	sg.DestUIDs = algo.MergeSorted(sg.uidMatrix)
+	return nil
+}
+
+// edgeFilterRows returns, for each of the SrcUIDs of sg, the destinations of the edges matched
+// by the filter f or by the filters of it, combined with its operator.
+func (sg *SubGraph) edgeFilterRows(ctx context.Context, f *SubGraph) ([]*pb.List, error) {
+	if f != sg && f.FilterOp == "" {
+		_, key := x.ParseEdgeProperty(f.Attr)
+		ftree := &pb.FilterTree{Func: &pb.Function{Name: f.SrcFunc.Name, Key: key}}
+		for _, arg := range f.SrcFunc.Args {
+			ftree.Func.Args = append(ftree.Func.Args, arg.Value)
+		}
+		result, err := worker.ProcessTaskOverNetwork(ctx, &pb.Query{
+			Attr:       f.Attr,
+			UidList:    sg.SrcUIDs,
+			ReadTs:     sg.ReadTs,
+			EdgeFilter: ftree,
+		})
+		switch {
+		case err != nil && strings.Contains(err.Error(), worker.ErrNonExistentTabletMessage):
+			// There are no values for the property, so no edge matches.
+			result = &pb.Result{}
+			for range sg.SrcUIDs.Uids {
+				result.UidMatrix = append(result.UidMatrix, &pb.List{})
+			}
+		case err != nil:
+			return nil, err
+		}
+		if len(result.UidMatrix) != len(sg.uidMatrix) {
+			return nil, errors.Errorf("Edge filter on %s returned %d lists, expected %d",
+				f.Attr, len(result.UidMatrix), len(sg.uidMatrix))
+		}
+		return result.UidMatrix, nil
+	}
+
+	children := make([][]*pb.List, 0, len(f.Filters))
+	for _, child := range f.Filters {
+		rows, err := sg.edgeFilterRows(ctx, child)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, rows)
+	}
+	rows := make([]*pb.List, len(sg.uidMatrix))
+	for i := range rows {
+		lists := make([]*pb.List, 0, len(children))
+		for _, child := range children {
+			lists = append(lists, child[i])
+		}
+		switch f.FilterOp {
+		case "or":
+			rows[i] = algo.MergeSorted(lists)
+		case "not":
+			x.AssertTrue(len(lists) == 1)
+			rows[i] = algo.Difference(sg.uidMatrix[i], lists[0])
+		default:
+			rows[i] = algo.IntersectSorted(lists)
+		}
+	}
+	return rows, nil
+}
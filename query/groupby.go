@@ -76,6 +76,18 @@ type groupResults struct {
 	group []*groupResult
 }
 
+// groupCost is roughly the memory taken by a group besides its uids.
+const groupCost = 128
+
+// size returns roughly the memory taken by the groups, charged to the query memory account.
+func (res *groupResults) size() int64 {
+	var n int64
+	for _, grp := range res.group {
+		n += groupCost + int64(len(grp.uids))*8
+	}
+	return n
+}
+
 type groupElements struct {
 	entities *pb.List
 	key      types.Val
@@ -377,6 +389,9 @@ func (sg *SubGraph) processGroupBy(doneVars map[string]varValue, path []*SubGrap
 		if err != nil {
 			return err
 		}
+		if err := sg.memory.Charge(r.size()); err != nil {
+			return err
+		}
 		sg.GroupbyRes = append(sg.GroupbyRes, r)
 	}
 
@@ -17,6 +17,7 @@
 package query
 
 import (
+	"math/big"
 	"testing"
 
 	"github.com/dgraph-io/dgraph/types"
@@ -285,6 +286,56 @@ func TestProcessUnary(t *testing.T) {
 	}
 }
 
+func decimalVal(t *testing.T, s string) types.Val {
+	r, err := types.ParseDecimal(s)
+	require.NoError(t, err)
+	return types.Val{Tid: types.DecimalID, Value: r}
+}
+
+func TestProcessDecimal(t *testing.T) {
+	tests := []struct {
+		fn   string
+		args []types.Val
+		out  string
+	}{
+		{fn: "+", args: []types.Val{decimalVal(t, "0.1"), decimalVal(t, "0.2")}, out: "0.3"},
+		{fn: "-", args: []types.Val{decimalVal(t, "1"), decimalVal(t, "0.9")}, out: "0.1"},
+		{fn: "*", args: []types.Val{decimalVal(t, "1.1"), decimalVal(t, "1.1")}, out: "1.21"},
+		{fn: "/", args: []types.Val{decimalVal(t, "1"), decimalVal(t, "3")},
+			out: "0.3333333333333333333333333333333333"},
+		{fn: "%", args: []types.Val{decimalVal(t, "7.5"), decimalVal(t, "2")}, out: "1.5"},
+		{fn: "+", args: []types.Val{decimalVal(t, "0.1"),
+			{Tid: types.IntID, Value: int64(2)}}, out: "2.1"},
+		{fn: "*", args: []types.Val{{Tid: types.FloatID, Value: 0.1},
+			decimalVal(t, "3")}, out: "0.3"},
+		{fn: "max", args: []types.Val{decimalVal(t, "2.5"),
+			{Tid: types.IntID, Value: int64(2)}}, out: "2.5"},
+		{fn: "u-", args: []types.Val{decimalVal(t, "2.5")}, out: "-2.5"},
+		{fn: "floor", args: []types.Val{decimalVal(t, "-2.5")}, out: "-3"},
+		{fn: "ceil", args: []types.Val{decimalVal(t, "-2.5")}, out: "-2"},
+	}
+	for _, tc := range tests {
+		t.Logf("Test %s", tc.fn)
+		tree := &mathTree{Fn: tc.fn}
+		for _, arg := range tc.args {
+			tree.Child = append(tree.Child, &mathTree{Const: arg})
+		}
+		var err error
+		if len(tc.args) == 1 {
+			err = processUnary(tree)
+		} else {
+			err = processBinary(tree)
+		}
+		require.NoError(t, err)
+		require.Equal(t, types.DecimalID, tree.Const.Tid)
+		require.Equal(t, tc.out, types.FormatDecimal(tree.Const.Value.(*big.Rat)))
+	}
+
+	err := processBinary(&mathTree{Fn: "/", Child: []*mathTree{
+		{Const: decimalVal(t, "1")}, {Const: decimalVal(t, "0")}}})
+	require.Error(t, err)
+}
+
 func TestProcessBinaryBoolean(t *testing.T) {
 	tests := []struct {
 		in  *mathTree
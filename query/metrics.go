@@ -0,0 +1,140 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package query
+
+import (
+	"context"
+	"sort"
+
+	"github.com/dgraph-io/dgraph/worker"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// MetricsBasic is the default metrics level, which only reports the number of uids
+	// touched per predicate.
+	MetricsBasic = "basic"
+	// MetricsDetailed additionally reports the metrics of every task run by the request.
+	MetricsDetailed = "detailed"
+
+	// CacheModeTxn is the cache mode of the tasks reading through the transaction cache.
+	CacheModeTxn = "txn"
+	// CacheModeNone is the cache mode of the tasks reading the posting lists from disk.
+	CacheModeNone = "none"
+)
+
+// TaskMetrics holds the metrics of a single task run while processing a query.
+type TaskMetrics struct {
+	Attr         string `json:"attr"`
+	ProcessingNs uint64 `json:"processing_ns"`
+	UidsIn       uint64 `json:"uids_in"`
+	UidsOut      uint64 `json:"uids_out"`
+	Group        uint32 `json:"group,omitempty"`
+	// Local is true if the task was served by this instance without a network call.
+	Local bool `json:"local"`
+	// CacheMode is the cache the task was asked to read the posting lists through, either
+	// CacheModeTxn or CacheModeNone. It doesn't tell whether the lists were found in it.
+	CacheMode string `json:"cache_mode"`
+}
+
+// DetailedMetrics collects the metrics of the tasks run by a request that asked for the
+// detailed metrics level. It's passed around in the context using MetricsKey.
+type DetailedMetrics struct {
+	Tasks           []*TaskMetrics `json:"tasks"`
+	GroupsContacted []uint32       `json:"groups_contacted"`
+}
+
+// ParseMetricsLevel validates the metrics level requested by the client and returns whether
+// detailed metrics were asked for.
+func ParseMetricsLevel(level string) (bool, error) {
+	switch level {
+	case "", MetricsBasic:
+		return false, nil
+	case MetricsDetailed:
+		return true, nil
+	}
+	return false, errors.Errorf("Invalid metrics level: %q. Valid levels are %q and %q",
+		level, MetricsBasic, MetricsDetailed)
+}
+
+// WithDetailedMetrics returns a context carrying a new DetailedMetrics collector.
+func WithDetailedMetrics(ctx context.Context) (context.Context, *DetailedMetrics) {
+	dm := &DetailedMetrics{}
+	return context.WithValue(ctx, MetricsKey, dm), dm
+}
+
+// DetailedMetricsFromContext returns the DetailedMetrics collector attached to the context,
+// or nil if the request didn't ask for detailed metrics.
+func DetailedMetricsFromContext(ctx context.Context) *DetailedMetrics {
+	dm, _ := ctx.Value(MetricsKey).(*DetailedMetrics)
+	return dm
+}
+
+// IsDetailedMetricsRequested returns whether a gRPC client asked for detailed metrics, which
+// it does by passing the metrics level as metadata.
+func IsDetailedMetricsRequested(ctx context.Context) (bool, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok || len(md["metrics"]) == 0 {
+		return false, nil
+	}
+	return ParseMetricsLevel(md["metrics"][0])
+}
+
+// collect records the metrics of the tasks run for the given SubGraph and its filters and
+// children.
+func (dm *DetailedMetrics) collect(sg *SubGraph) {
+	if sg.Attr != "" && sg.Attr != "uid" && !sg.IsInternal() {
+		tm := &TaskMetrics{
+			Attr:         sg.Attr,
+			ProcessingNs: uint64(sg.taskLatency.Nanoseconds()),
+			UidsIn:       uint64(len(sg.SrcUIDs.GetUids())),
+			UidsOut:      uint64(len(sg.DestUIDs.GetUids())),
+			CacheMode:    CacheModeNone,
+		}
+		if sg.Cache == worker.UseTxnCache {
+			tm.CacheMode = CacheModeTxn
+		}
+		if gid, local, err := worker.ServingGroup(sg.Attr, sg.ReadTs); err == nil {
+			tm.Group = gid
+			tm.Local = local
+			dm.addGroup(gid)
+		}
+		dm.Tasks = append(dm.Tasks, tm)
+	}
+	for _, filter := range sg.Filters {
+		dm.collect(filter)
+	}
+	for _, child := range sg.Children {
+		dm.collect(child)
+	}
+}
+
+func (dm *DetailedMetrics) addGroup(gid uint32) {
+	if gid == 0 {
+		return
+	}
+	idx := sort.Search(len(dm.GroupsContacted), func(i int) bool {
+		return dm.GroupsContacted[i] >= gid
+	})
+	if idx < len(dm.GroupsContacted) && dm.GroupsContacted[idx] == gid {
+		return
+	}
+	dm.GroupsContacted = append(dm.GroupsContacted, 0)
+	copy(dm.GroupsContacted[idx+1:], dm.GroupsContacted[idx:])
+	dm.GroupsContacted[idx] = gid
+}
@@ -0,0 +1,49 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package query
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMetricsLevel(t *testing.T) {
+	detailed, err := ParseMetricsLevel("")
+	require.NoError(t, err)
+	require.False(t, detailed)
+
+	detailed, err = ParseMetricsLevel(MetricsBasic)
+	require.NoError(t, err)
+	require.False(t, detailed)
+
+	detailed, err = ParseMetricsLevel(MetricsDetailed)
+	require.NoError(t, err)
+	require.True(t, detailed)
+
+	_, err = ParseMetricsLevel("verbose")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Invalid metrics level")
+}
+
+func TestDetailedMetricsAddGroup(t *testing.T) {
+	dm := &DetailedMetrics{}
+	for _, gid := range []uint32{3, 1, 0, 3, 2, 1} {
+		dm.addGroup(gid)
+	}
+	require.Equal(t, []uint32{1, 2, 3}, dm.GroupsContacted)
+}
@@ -17,6 +17,7 @@
 package query
 
 import (
+	"bytes"
 	"context"
 	"strings"
 	"sync/atomic"
@@ -41,7 +42,14 @@ func ApplyMutations(ctx context.Context, m *pb.Mutations) (*api.TxnContext, erro
 	if err != nil {
 		return nil, errors.Wrapf(err, "While adding pb.edges")
 	}
-	m.Edges = edges
+	m.Edges, err = expandEdgeProperties(edges)
+	if err != nil {
+		return nil, err
+	}
+	m.Edges, err = expandCompositeIndexes(ctx, m.Edges, m.StartTs)
+	if err != nil {
+		return nil, err
+	}
 
 	err = checkIfDeletingAclOperation(m.Edges)
 	if err != nil {
@@ -104,6 +112,64 @@ func expandEdges(ctx context.Context, m *pb.Mutations) ([]*pb.DirectedEdge, erro
 	return edges, nil
 }
 
+// expandEdgeProperties adds the edges that keep the properties of uid edges in sync with their
+// facets. Setting an edge stores the facets of the edge declared as properties, and removes the
+// properties the edge no longer has. Deleting an edge deletes all of its properties.
+func expandEdgeProperties(edges []*pb.DirectedEdge) ([]*pb.DirectedEdge, error) {
+	props := make(map[string][]string)
+	out := edges
+	for _, edge := range edges {
+		if x.IsEdgeProperty(edge.Attr) {
+			if edge.Op == pb.DirectedEdge_DEL && bytes.Equal(edge.Value, []byte(x.Star)) {
+				continue
+			}
+			edgeAttr, _ := x.ParseEdgeProperty(edge.Attr)
+			return nil, errors.Errorf("Predicate %s can only be set through the facets of %s",
+				edge.Attr, edgeAttr)
+		}
+		// Dropping a predicate doesn't drop its properties here, they are dropped with
+		// separate mutations.
+		if edge.Entity == 0 {
+			continue
+		}
+		if _, ok := props[edge.Attr]; !ok {
+			props[edge.Attr] = worker.EdgeProperties(edge.Attr)
+		}
+		for _, prop := range props[edge.Attr] {
+			propEdge := &pb.DirectedEdge{
+				Entity:  edge.Entity,
+				Attr:    prop,
+				ValueId: edge.ValueId,
+				Op:      pb.DirectedEdge_DEL,
+			}
+			switch {
+			case edge.Op == pb.DirectedEdge_DEL && bytes.Equal(edge.Value, []byte(x.Star)):
+				propEdge.ValueId = 0
+				propEdge.Value = []byte(x.Star)
+			case edge.ValueId == 0:
+				// Only uid edges have properties.
+				continue
+			case edge.Op == pb.DirectedEdge_SET:
+				_, key := x.ParseEdgeProperty(prop)
+				for _, f := range edge.Facets {
+					if f.Key != key {
+						continue
+					}
+					tid, err := facets.TypeIDFor(f)
+					if err != nil {
+						return nil, err
+					}
+					propEdge.Value = f.Value
+					propEdge.ValueType = tid.Enum()
+					propEdge.Op = pb.DirectedEdge_SET
+				}
+			}
+			out = append(out, propEdge)
+		}
+	}
+	return out, nil
+}
+
 func verifyUid(ctx context.Context, uid uint64) error {
 	if uid <= worker.MaxLeaseId() {
 		return nil
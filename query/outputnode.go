@@ -22,6 +22,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"math/big"
 	"strconv"
 	"strings"
 	"sync"
@@ -190,6 +191,8 @@ const (
 	uidNodeBit = 1 << 61
 	// Node has been visited for fixing the children order.
 	visitedBit = 1 << 60
+	// Node is part of a list of nodes that @normalize(lists) keeps as an array.
+	preservedBit = 1 << 59
 
 	// Value with all bits set to 1 for bytes 7 and 6.
 	setBytes76 = uint64(0x00FFFF0000000000)
@@ -276,6 +279,10 @@ func (enc *encoder) setFacetsParent(fj fastJsonNode) {
 	fj.meta |= facetsBit
 }
 
+func (enc *encoder) setPreserved(fj fastJsonNode) {
+	fj.meta |= preservedBit
+}
+
 func (enc *encoder) appendAttrs(fj, child fastJsonNode) {
 	enc.addChildren(fj, child)
 }
@@ -374,8 +381,10 @@ func (enc *encoder) getList(fj fastJsonNode) bool {
 	return (fj.meta & listBit) > 0
 }
 
-func (enc *encoder) getFacetsParent(fj fastJsonNode) bool {
-	return (fj.meta & facetsBit) > 0
+// keepsStructure returns true if normalize shouldn't flatten fj even if it has children, which is
+// the case for facets parents and the lists of nodes kept by @normalize(lists).
+func (enc *encoder) keepsStructure(fj fastJsonNode) bool {
+	return (fj.meta & (facetsBit | preservedBit)) > 0
 }
 
 func (enc *encoder) children(fj fastJsonNode) fastJsonNode {
@@ -576,6 +585,8 @@ func valToBytes(v types.Val) ([]byte, error) {
 		}
 
 		return []byte(fmt.Sprintf("%f", f)), nil
+	case types.DecimalID:
+		return []byte(types.FormatDecimal(v.Value.(*big.Rat))), nil
 	case types.BoolID:
 		if v.Value.(bool) {
 			return boolTrue, nil
@@ -590,6 +601,8 @@ func valToBytes(v types.Val) ([]byte, error) {
 		return []byte(fmt.Sprintf("\"%#x\"", v.Value)), nil
 	case types.PasswordID:
 		return []byte(fmt.Sprintf("%q", v.Value.(string))), nil
+	case types.VFloatID:
+		return json.Marshal(v.Value.([]float32))
 	default:
 		return nil, errors.New("Unsupported types.Val.Tid")
 	}
@@ -795,8 +808,8 @@ func (enc *encoder) normalize(fj fastJsonNode) ([]fastJsonNode, error) {
 		// Here we are counting all non-scalar children of fj. If there are any such
 		// children, we will flatten them, otherwise we will return all children.
 		// We should only consider those children(of fj) for flattening which have
-		// children and are not facetsParent.
-		if enc.children(chead) != nil && !enc.getFacetsParent(chead) {
+		// children and are not facetsParent or preserved lists.
+		if enc.children(chead) != nil && !enc.keepsStructure(chead) {
 			cnt++
 		}
 		chead = chead.next
@@ -814,12 +827,13 @@ func (enc *encoder) normalize(fj fastJsonNode) ([]fastJsonNode, error) {
 	var shead, curScalar fastJsonNode
 	chead = enc.children(fj)
 	for chead != nil {
-		if enc.children(chead) != nil && enc.getFacetsParent(chead) == false {
+		if enc.children(chead) != nil && !enc.keepsStructure(chead) {
 			chead = chead.next
 			continue
 		}
 
-		// Here, add all nodes which have either no children or they are facetsParent.
+		// Here, add all nodes which have either no children or they are facetsParent or
+		// preserved lists.
 		copyNode := enc.copySingleNode(chead)
 		if curScalar == nil {
 			shead, curScalar = copyNode, copyNode
@@ -835,8 +849,9 @@ func (enc *encoder) normalize(fj fastJsonNode) ([]fastJsonNode, error) {
 	chead = enc.children(fj)
 	for chead != nil {
 		childNode := chead
-		// Here, exclude all nodes which have either no children or they are facetsParent.
-		if enc.children(childNode) == nil || enc.getFacetsParent(childNode) {
+		// Here, exclude all nodes which have either no children or they are facetsParent or
+		// preserved lists.
+		if enc.children(childNode) == nil || enc.keepsStructure(childNode) {
 			chead = chead.next
 			continue
 		}
@@ -907,6 +922,17 @@ func (sg *SubGraph) addGroupby(enc *encoder, fj fastJsonNode,
 
 func (sg *SubGraph) addAggregations(enc *encoder, fj fastJsonNode) error {
 	for _, child := range sg.Children {
+		if child.Params.DistinctCount {
+			// In an empty block, count(distinct val(x)) counts all the values of x.
+			uids := make([]uint64, 0, len(child.Params.UidToVal))
+			for uid := range child.Params.UidToVal {
+				uids = append(uids, uid)
+			}
+			if err := child.addDistinctCount(enc, fj, sg.Params.Alias, uids); err != nil {
+				return err
+			}
+			continue
+		}
 		aggVal, ok := child.Params.UidToVal[0]
 		if !ok {
 			if len(child.Params.NeedsVar) == 0 {
@@ -932,18 +958,26 @@ func (sg *SubGraph) addAggregations(enc *encoder, fj fastJsonNode) error {
 	return nil
 }
 
-func (sg *SubGraph) handleCountUIDNodes(enc *encoder, n fastJsonNode, count int) (bool, error) {
+func (sg *SubGraph) handleCountUIDNodes(enc *encoder, n fastJsonNode,
+	uids []uint64) (bool, error) {
 	addedNewChild := false
 	fieldName := sg.fieldName()
 	sgFieldID := enc.idForAttr(fieldName)
 	for _, child := range sg.Children {
 		uidCount := child.Attr == "uid" && child.Params.DoCount && child.IsInternal()
 		normWithoutAlias := child.Params.Alias == "" && child.Params.Normalize
+		if child.Params.DistinctCount && !normWithoutAlias {
+			addedNewChild = true
+			if err := child.addDistinctCount(enc, n, fieldName, uids); err != nil {
+				return false, err
+			}
+			continue
+		}
 		if uidCount && !normWithoutAlias {
 			addedNewChild = true
 
 			c := types.ValueForType(types.IntID)
-			c.Value = int64(count)
+			c.Value = int64(len(uids))
 
 			field := child.Params.Alias
 			if field == "" {
@@ -961,6 +995,94 @@ func (sg *SubGraph) handleCountUIDNodes(enc *encoder, n fastJsonNode, count int)
 	return addedNewChild, nil
 }
 
+// distinctCount returns the number of distinct uids or values of this SubGraph, which was
+// requested using count(distinct), across the given uids of the parent.
+func (sg *SubGraph) distinctCount(uids []uint64) (int64, error) {
+	seen := make(map[string]struct{})
+	if sg.IsInternal() {
+		// count(distinct val(x)) counts the distinct values of the variable.
+		for _, uid := range uids {
+			val, ok := sg.Params.UidToVal[uid]
+			if !ok || val.Value == nil {
+				continue
+			}
+			data := types.ValueForType(types.StringID)
+			if err := types.Marshal(val, &data); err != nil {
+				return 0, err
+			}
+			seen[data.Value.(string)] = struct{}{}
+		}
+		return int64(len(seen)), nil
+	}
+
+	if len(sg.distinctBuckets) > 0 {
+		// The worker has grouped the uids by the index tokens of the values.
+		if len(uids) == len(sg.SrcUIDs.GetUids()) {
+			return int64(len(sg.distinctBuckets)), nil
+		}
+		set := make(map[uint64]struct{}, len(uids))
+		for _, uid := range uids {
+			set[uid] = struct{}{}
+		}
+		var count int64
+		for _, bucket := range sg.distinctBuckets {
+			for _, uid := range bucket.Uids {
+				if _, ok := set[uid]; ok {
+					count++
+					break
+				}
+			}
+		}
+		return count, nil
+	}
+
+	// Uid lists are merged, which takes care of the duplicates without looking at the values.
+	var lists []*pb.List
+	for _, uid := range uids {
+		idx := algo.IndexOf(sg.SrcUIDs, uid)
+		if idx < 0 {
+			continue
+		}
+		if idx < len(sg.uidMatrix) && len(sg.uidMatrix[idx].Uids) > 0 {
+			lists = append(lists, sg.uidMatrix[idx])
+		}
+		if idx < len(sg.valueMatrix) {
+			for _, tv := range sg.valueMatrix[idx].Values {
+				seen[string(tv.Val)] = struct{}{}
+			}
+		}
+	}
+	return int64(len(algo.MergeSorted(lists).GetUids()) + len(seen)), nil
+}
+
+// addDistinctCount adds the distinct count across the given uids of the parent as a new
+// child of n, in the same way as count(uid).
+func (sg *SubGraph) addDistinctCount(enc *encoder, n fastJsonNode, parentField string,
+	uids []uint64) error {
+	count, err := sg.distinctCount(uids)
+	if err != nil {
+		return err
+	}
+	c := types.ValueForType(types.IntID)
+	c.Value = count
+
+	field := sg.Params.Alias
+	switch {
+	case field != "":
+	case sg.IsInternal() && len(sg.Params.NeedsVar) > 0:
+		field = fmt.Sprintf("count(distinct val(%s))", sg.Params.NeedsVar[0].Name)
+	default:
+		field = fmt.Sprintf("count(distinct %s)", sg.Attr)
+	}
+
+	fjChild := enc.newNode(enc.idForAttr(parentField))
+	if err := enc.AddValue(fjChild, enc.idForAttr(field), c); err != nil {
+		return err
+	}
+	enc.AddListChild(n, fjChild)
+	return nil
+}
+
 func processNodeUids(fj fastJsonNode, enc *encoder, sg *SubGraph) error {
 	if sg.Params.IsEmpty {
 		return sg.addAggregations(enc, fj)
@@ -974,7 +1096,7 @@ func processNodeUids(fj fastJsonNode, enc *encoder, sg *SubGraph) error {
 		return nil
 	}
 
-	hasChild, err := sg.handleCountUIDNodes(enc, fj, len(sg.DestUIDs.Uids))
+	hasChild, err := sg.handleCountUIDNodes(enc, fj, sg.DestUIDs.Uids)
 	if err != nil {
 		return err
 	}
@@ -1043,6 +1165,8 @@ type Extensions struct {
 	Latency *api.Latency    `json:"server_latency,omitempty"`
 	Txn     *api.TxnContext `json:"txn,omitempty"`
 	Metrics *api.Metrics    `json:"metrics,omitempty"`
+	// Detailed is only set if the request asked for the detailed metrics level.
+	Detailed *DetailedMetrics `json:"detailed_metrics,omitempty"`
 }
 
 func (sg *SubGraph) toFastJSON(l *Latency) ([]byte, error) {
@@ -1151,6 +1275,22 @@ func (sg *SubGraph) addCheckPwd(enc *encoder, vals []*pb.TaskValue, dst fastJson
 	return enc.AddValue(dst, enc.idForAttr(fieldName), c)
 }
 
+func (sg *SubGraph) addRelevanceScore(enc *encoder, vals []*pb.TaskValue,
+	dst fastJsonNode) error {
+	// Nodes without a value for the predicate don't get a score.
+	if len(vals) == 0 {
+		return nil
+	}
+	c := types.ValueForType(types.FloatID)
+	c.Value = task.ToFloat(vals[0])
+
+	fieldName := sg.Params.Alias
+	if fieldName == "" {
+		fieldName = fmt.Sprintf("bm25(%s)", sg.Attr)
+	}
+	return enc.AddValue(dst, enc.idForAttr(fieldName), c)
+}
+
 func alreadySeen(parentIds []uint64, uid uint64) bool {
 	for _, id := range parentIds {
 		if id == uid {
@@ -1181,7 +1321,8 @@ func (sg *SubGraph) preTraverse(enc *encoder, uid uint64, dst fastJsonNode) erro
 	var invalidUids map[uint64]bool
 	// We go through all predicate children of the subprotos.
 	for _, pc := range sg.Children {
-		if pc.Params.IgnoreResult {
+		if pc.Params.IgnoreResult || pc.Params.DistinctCount {
+			// Distinct counts are added once for all the nodes at this level.
 			continue
 		}
 		if pc.IsInternal() {
@@ -1233,6 +1374,11 @@ func (sg *SubGraph) preTraverse(enc *encoder, uid uint64, dst fastJsonNode) erro
 				return err
 			}
 
+		case pc.SrcFunc != nil && pc.SrcFunc.Name == "bm25":
+			if err := pc.addRelevanceScore(enc, pc.valueMatrix[idx].Values, dst); err != nil {
+				return err
+			}
+
 		case idx < len(pc.uidMatrix) && len(pc.uidMatrix[idx].Uids) > 0:
 			var fcsList []*pb.Facets
 			if pc.Params.Facet != nil {
@@ -1298,6 +1444,22 @@ func (sg *SubGraph) preTraverse(enc *encoder, uid uint64, dst fastJsonNode) erro
 							return err
 						}
 
+						if pc.Params.NormalizeLists && pc.List {
+							// @normalize(lists) keeps the list of nodes as an array of the
+							// normalized nodes, instead of flattening it into the parent.
+							for _, c := range normAttrs {
+								if c == nil {
+									continue
+								}
+								node := enc.newNode(fieldID)
+								enc.setVisited(node, true)
+								enc.setPreserved(node)
+								enc.addChildren(node, c)
+								enc.AddListChild(dst, node)
+							}
+							continue
+						}
+
 						for _, c := range normAttrs {
 							// Adding as list child irrespective of the type of pc
 							// (list or non-list), otherwise result might be inconsistent or might
@@ -1341,7 +1503,7 @@ func (sg *SubGraph) preTraverse(enc *encoder, uid uint64, dst fastJsonNode) erro
 			}
 
 			// add value for count(uid) nodes if any.
-			if _, err := pc.handleCountUIDNodes(enc, dst, len(ul.Uids)); err != nil {
+			if _, err := pc.handleCountUIDNodes(enc, dst, ul.Uids); err != nil {
 				return err
 			}
 		default:
@@ -182,7 +182,7 @@ func getObjectVal(v types.Val) ([]byte, error) {
 		return buildTriple(outputval), nil
 	case types.IntID:
 		return quotedNumber(outputval), nil
-	case types.FloatID:
+	case types.FloatID, types.DecimalID:
 		return quotedNumber(outputval), nil
 	case types.GeoID:
 		return nil, errors.New("Geo id is not supported in rdf output")
@@ -216,6 +216,9 @@ func validateSubGraphForRDF(sg *SubGraph) error {
 	if sg.SrcFunc != nil && sg.SrcFunc.Name == "checkpwd" {
 		return errors.New("chkpwd function is not supported in the rdf output format")
 	}
+	if sg.SrcFunc != nil && sg.SrcFunc.Name == "bm25" {
+		return errors.New("bm25 function is not supported in the rdf output format")
+	}
 	if sg.Params.Facet != nil && !sg.Params.ExpandAll {
 		return errors.New("facets are not supported in the rdf output format")
 	}
@@ -20,6 +20,8 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"math/rand"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -114,8 +116,14 @@ type params struct {
 	Offset int
 	// AfterUID is the value of the "after" parameter.
 	AfterUID uint64
+	// Sample is the value of the "sample" parameter, which is the number of uids to pick at
+	// random out of the ones matched at root.
+	Sample int
 	// DoCount is true if the count of the predicate is requested instead of its value.
 	DoCount bool
+	// DistinctCount is true if the number of distinct uids or values across all the nodes at
+	// this level is requested using count(distinct).
+	DistinctCount bool
 	// GetUid is true if the uid should be returned. Used for debug requests.
 	GetUid bool
 	// Order is the list of predicates to sort by and their sort order.
@@ -152,6 +160,9 @@ type params struct {
 
 	// Normalize is true if the @normalize directive is specified.
 	Normalize bool
+	// NormalizeLists is true if the @normalize(lists) directive is specified, in which case the
+	// lists of nodes are kept as arrays instead of being flattened.
+	NormalizeLists bool
 	// Recurse is true if the @recurse directive is specified.
 	Recurse bool
 	// RecurseArgs stores the arguments passed to the @recurse directive.
@@ -160,6 +171,9 @@ type params struct {
 	// __all__ is special to mean @cascade i.e. all the children of this subgraph are mandatory
 	// and should have values otherwise the node will be excluded.
 	Cascade []string
+	// CascadeDepth is the number of levels, starting from this one, that Cascade applies to.
+	// 0 means that it applies to all of them.
+	CascadeDepth uint64
 	// IgnoreReflex is true if the @ignorereflex directive is specified.
 	IgnoreReflex bool
 
@@ -175,10 +189,16 @@ type params struct {
 	MaxWeight float64
 	// MinWeight is the min weight allowed in a path returned by the shortest path algorithm.
 	MinWeight float64
+	// Undirected is true if a shortest path or recurse query follows the edges in both
+	// directions.
+	Undirected bool
 
 	// ExploreDepth is used by recurse and shortest path queries to specify the maximum graph
 	// depth to explore.
 	ExploreDepth *uint64
+	// RecurseDepth is the depth of the nodes that a filter inside a recurse query is applied
+	// to. It's nil outside of recurse queries.
+	RecurseDepth *uint64
 
 	// IsInternal determines if processTask has to be called or not.
 	IsInternal bool
@@ -217,6 +237,12 @@ type Function struct {
 	IsCount    bool      // gt(count(friends),0)
 	IsValueVar bool      // eq(val(s), 10)
 	IsLenVar   bool      // eq(len(s), 10)
+	IsDepth    bool      // le(depth(), 2)
+	// CountGraph counts the filtered edges for ge(count(post @filter(...)), 3).
+	CountGraph *SubGraph
+	// Union holds the root SubGraphs whose results are merged by union(f1, f2, ...) or
+	// has(p1, p2, ...), or intersected by hasall(p1, p2, ...).
+	Union []*SubGraph
 }
 
 // SubGraph is the way to represent data. It contains both the request parameters and the response.
@@ -244,6 +270,8 @@ type Function struct {
 type SubGraph struct {
 	ReadTs      uint64
 	Cache       int
+	// memory is the account the groups formed by the query are charged to.
+	memory      *x.MemoryAccount
 	Attr        string
 	UnknownAttr bool
 	// read only parameters which are populated before the execution of the query and are used to
@@ -262,6 +290,9 @@ type SubGraph struct {
 	// uidMatrix is a slice of List. There would be one List corresponding to each uid in SrcUIDs.
 	// In graph terms, a list is a slice of outgoing edges from a node.
 	uidMatrix []*pb.List
+	// distinctBuckets has a List for every index token of the predicate that has any of the
+	// SrcUIDs. It's only returned for count(distinct) when the predicate has an exact or hash index.
+	distinctBuckets []*pb.List
 
 	// facetsMatrix contains the facet values. There would a list corresponding to each uid in
 	// uidMatrix.
@@ -288,6 +319,12 @@ type SubGraph struct {
 	List     bool // whether predicate is of list type
 
 	pathMeta *pathMetadata
+
+	// taskLatency is the time spent waiting for the task of this SubGraph to be processed.
+	taskLatency time.Duration
+	// recurseDepths maps the nodes reached by a recurse query to the depth at which they were
+	// first reached. It's only populated if the depth was asked to be stored in a variable.
+	recurseDepths map[uint64]uint64
 }
 
 func (sg *SubGraph) recurse(set func(sg *SubGraph)) {
@@ -298,6 +335,14 @@ func (sg *SubGraph) recurse(set func(sg *SubGraph)) {
 	for _, filter := range sg.Filters {
 		filter.recurse(set)
 	}
+	if sg.SrcFunc != nil && sg.SrcFunc.CountGraph != nil {
+		sg.SrcFunc.CountGraph.recurse(set)
+	}
+	if sg.SrcFunc != nil {
+		for _, member := range sg.SrcFunc.Union {
+			member.recurse(set)
+		}
+	}
 }
 
 // IsGroupBy returns whether this subgraph is part of a groupBy query.
@@ -321,6 +366,7 @@ func (sg *SubGraph) createSrcFunction(gf *gql.Function) {
 		IsCount:    gf.IsCount,
 		IsValueVar: gf.IsValueVar,
 		IsLenVar:   gf.IsLenVar,
+		IsDepth:    gf.IsDepth,
 	}
 
 	// type function is just an alias for eq(type, "dgraph.type").
@@ -408,9 +454,13 @@ func (sg *SubGraph) isSimilar(ssg *SubGraph) bool {
 	return true
 }
 
-func isEmptyIneqFnWithVar(sg *SubGraph) bool {
-	return sg.SrcFunc != nil && isInequalityFn(sg.SrcFunc.Name) && len(sg.SrcFunc.Args) == 0 &&
-		len(sg.Params.NeedsVar) > 0
+func isEmptyFnWithVar(sg *SubGraph) bool {
+	return sg.SrcFunc != nil && (isInequalityFn(sg.SrcFunc.Name) || sg.SrcFunc.Name == "regexp") &&
+		len(sg.SrcFunc.Args) == 0 && len(sg.Params.NeedsVar) > 0
+}
+
+func isEmptyRegexpWithVar(sg *SubGraph) bool {
+	return isEmptyFnWithVar(sg) && sg.SrcFunc.Name == "regexp"
 }
 
 // convert from task.Val to types.Value, based on schema appropriate type
@@ -450,9 +500,25 @@ func mathCopy(dst *mathTree, src *gql.MathTree) error {
 
 func filterCopy(sg *SubGraph, ft *gql.FilterTree) error {
 	// Either we'll have an operation specified, or the function specified.
-	if len(ft.Op) > 0 {
+	switch {
+	case len(ft.Op) > 0:
 		sg.FilterOp = ft.Op
-	} else {
+	case isMultiHasFn(ft.Func):
+		// has(p1, p2, ...) is the same as has(p1) or has(p2) or ..., while hasall uses and.
+		sg.FilterOp = "or"
+		if ft.Func.Name == "hasall" {
+			sg.FilterOp = "and"
+		}
+		preds := []string{ft.Func.Attr}
+		for _, arg := range ft.Func.Args {
+			preds = append(preds, arg.Value)
+		}
+		for _, pred := range preds {
+			child := &SubGraph{Attr: pred}
+			child.SrcFunc = &Function{Name: "has"}
+			sg.Filters = append(sg.Filters, child)
+		}
+	default:
 		sg.Attr = ft.Func.Attr
 		if !isValidFuncName(ft.Func.Name) {
 			return errors.Errorf("Invalid function name: %s", ft.Func.Name)
@@ -469,6 +535,11 @@ func filterCopy(sg *SubGraph, ft *gql.FilterTree) error {
 			}
 			sg.createSrcFunction(ft.Func)
 			sg.Params.NeedsVar = append(sg.Params.NeedsVar, ft.Func.NeedsVar...)
+			if ft.Func.CountFilter != nil {
+				if err := sg.createCountGraph(ft.Func); err != nil {
+					return err
+				}
+			}
 		}
 	}
 	for _, ftc := range ft.Child {
@@ -481,6 +552,29 @@ func filterCopy(sg *SubGraph, ft *gql.FilterTree) error {
 	return nil
 }
 
+// createCountGraph prepares the SubGraph that counts the edges matching the filter given to
+// count inside a filter function, e.g. @filter(ge(count(post @filter(has(title))), 3)).
+func (sg *SubGraph) createCountGraph(gf *gql.Function) error {
+	if !isInequalityFn(gf.Name) {
+		return errors.Errorf("Filtered count is only supported with inequality functions, "+
+			"got: %s", gf.Name)
+	}
+	if len(gf.Args) == 0 {
+		return errors.Errorf("Function %s expects an argument to compare the count with", gf.Name)
+	}
+	cg := &SubGraph{
+		Attr:   gf.Attr,
+		Params: params{DoCount: true},
+	}
+	sgf := &SubGraph{}
+	if err := filterCopy(sgf, gf.CountFilter); err != nil {
+		return err
+	}
+	cg.Filters = append(cg.Filters, sgf)
+	sg.SrcFunc.CountGraph = cg
+	return nil
+}
+
 func uniqueKey(gchild *gql.GraphQuery) string {
 	key := gchild.Attr
 	if gchild.Func != nil {
@@ -505,6 +599,9 @@ func uniqueKey(gchild *gql.GraphQuery) string {
 	if gchild.IsCount { // ignore count subgraphs..
 		key += "count"
 	}
+	if gchild.IsDistinct {
+		key += "distinct"
+	}
 	if len(gchild.Langs) > 0 {
 		key += fmt.Sprintf("%v", gchild.Langs)
 	}
@@ -561,20 +658,33 @@ func treeCopy(gq *gql.GraphQuery, sg *SubGraph) error {
 			IsInternal:   gchild.IsInternal,
 		}
 
-		// Inherit from the parent.
-		if len(sg.Params.Cascade) > 0 {
+		// Inherit from the parent, unless the child has its own @normalize.
+		if gchild.Normalize {
+			args.NormalizeLists = gchild.NormalizeLists
+		} else {
+			args.NormalizeLists = sg.Params.NormalizeLists
+		}
+
+		// Inherit from the parent, unless its depth stops at the parent.
+		if len(sg.Params.Cascade) > 0 && sg.Params.CascadeDepth != 1 {
 			args.Cascade = append(args.Cascade, sg.Params.Cascade...)
+			if sg.Params.CascadeDepth > 1 {
+				args.CascadeDepth = sg.Params.CascadeDepth - 1
+			}
 		}
 		// Allow over-riding at this level.
 		if len(gchild.Cascade) > 0 {
 			args.Cascade = gchild.Cascade
+			args.CascadeDepth = gchild.CascadeDepth
 		}
 
 		if gchild.IsCount {
 			if len(gchild.Children) != 0 {
 				return errors.New("Node with count cannot have child attributes")
 			}
-			args.DoCount = true
+			// The distinct count needs the uids and values to be fetched.
+			args.DoCount = !gchild.IsDistinct
+			args.DistinctCount = gchild.IsDistinct
 		}
 
 		for argk := range gchild.Args {
@@ -603,7 +713,8 @@ func treeCopy(gq *gql.GraphQuery, sg *SubGraph) error {
 		}
 
 		if gchild.Func != nil &&
-			(gchild.Func.IsAggregator() || gchild.Func.IsPasswordVerifier()) {
+			(gchild.Func.IsAggregator() || gchild.Func.IsPasswordVerifier() ||
+				gchild.Func.IsRelevanceScore()) {
 			if len(gchild.Children) != 0 {
 				return errors.Errorf("Node with %q cant have child attr", gchild.Func.Name)
 			}
@@ -661,6 +772,20 @@ func (args *params) fill(gq *gql.GraphQuery) error {
 		}
 		args.AfterUID = after
 	}
+	if v, ok := gq.Args["sample"]; ok {
+		sample, err := strconv.ParseInt(v, 0, 32)
+		if err != nil {
+			return err
+		}
+		if sample <= 0 {
+			return errors.Errorf("sample should be a positive number, got: %d", sample)
+		}
+		if gq.Filter != nil {
+			// The sample is taken while the root function is evaluated, before any filter.
+			return errors.Errorf("sample can't be used along with a filter at root")
+		}
+		args.Sample = int(sample)
+	}
 
 	if args.Alias == "shortest" {
 		if v, ok := gq.Args["depth"]; ok {
@@ -699,6 +824,14 @@ func (args *params) fill(gq *gql.GraphQuery) error {
 			args.MinWeight = -math.MaxFloat64
 		}
 
+		if v, ok := gq.Args["undirected"]; ok {
+			undirected, err := strconv.ParseBool(v)
+			if err != nil {
+				return errors.Errorf("undirected should be a boolean, got: %s", v)
+			}
+			args.Undirected = undirected
+		}
+
 		if gq.ShortestPathArgs.From == nil || gq.ShortestPathArgs.To == nil {
 			return errors.Errorf("from/to can't be nil for shortest path")
 		}
@@ -739,6 +872,10 @@ type ContextKey int
 const (
 	// DebugKey is the key used to toggle debug mode.
 	DebugKey ContextKey = iota
+	// MetricsKey is the key used to pass the DetailedMetrics collector of a request.
+	MetricsKey
+	// budgetKey is the key used to pass the nodeBudget of a request.
+	budgetKey
 )
 
 func isDebug(ctx context.Context) bool {
@@ -778,16 +915,19 @@ func newGraph(ctx context.Context, gq *gql.GraphQuery) (*SubGraph, error) {
 	args := params{
 		Alias:            gq.Alias,
 		Cascade:          gq.Cascade,
+		CascadeDepth:     gq.CascadeDepth,
 		GetUid:           isDebug(ctx),
 		IgnoreReflex:     gq.IgnoreReflex,
 		IsEmpty:          gq.IsEmpty,
 		Langs:            gq.Langs,
 		NeedsVar:         append(gq.NeedsVar[:0:0], gq.NeedsVar...),
 		Normalize:        gq.Normalize,
+		NormalizeLists:   gq.NormalizeLists,
 		Order:            gq.Order,
 		ParentVars:       make(map[string]varValue),
 		Recurse:          gq.Recurse,
 		RecurseArgs:      gq.RecurseArgs,
+		Undirected:       gq.RecurseArgs.Undirected,
 		ShortestPathArgs: gq.ShortestPathArgs,
 		Var:              gq.Var,
 		GroupbyAttrs:     gq.GroupbyAttrs,
@@ -796,7 +936,8 @@ func newGraph(ctx context.Context, gq *gql.GraphQuery) (*SubGraph, error) {
 	}
 
 	for argk := range gq.Args {
-		if !isValidArg(argk) {
+		// Sampling is only supported at root.
+		if !isValidArg(argk) && argk != "sample" {
 			return nil, errors.Errorf("Invalid argument: %s", argk)
 		}
 	}
@@ -819,8 +960,26 @@ func newGraph(ctx context.Context, gq *gql.GraphQuery) (*SubGraph, error) {
 		if !isValidFuncName(gq.Func.Name) {
 			return nil, errors.Errorf("Invalid function name: %s", gq.Func.Name)
 		}
+		if gq.Func.CountFilter != nil {
+			return nil, errors.Errorf("Filtered count is only supported inside @filter, "+
+				"got it in root function: %s", gq.Func.Name)
+		}
 
 		sg.createSrcFunction(gq.Func)
+		for _, gf := range gq.Func.Union {
+			// Each function in the union is evaluated like the function of a root block.
+			member, err := newGraph(ctx, &gql.GraphQuery{Func: gf, UID: gf.UID,
+				NeedsVar: gf.NeedsVar})
+			if err != nil {
+				return nil, errors.Wrapf(err, "while creating union")
+			}
+			sg.SrcFunc.Union = append(sg.SrcFunc.Union, member)
+		}
+		if isMultiHasFn(gq.Func) {
+			if err := sg.createHasMembers(ctx, gq); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	if isUidFnWithoutVar(gq.Func) && len(gq.UID) > 0 {
@@ -917,6 +1076,9 @@ func createTaskQuery(sg *SubGraph) (*pb.Query, error) {
 		FacetsFilter: sg.facetsFilter,
 		ExpandAll:    sg.Params.ExpandAll,
 		First:        first,
+		Sample:       int32(sg.Params.Sample),
+
+		DistinctCount: len(sg.Filters) == 0 && sg.Params.DistinctCount,
 	}
 
 	if sg.SrcUIDs != nil {
@@ -943,15 +1105,25 @@ func calculateFirstN(sg *SubGraph) int32 {
 	//     name
 	//   }
 	// }
-	// - should be has function (Right now, I'm doing it for has, later it can be extended)
+	// - should be has function, or an edge whose uids are used as they're read
 	// {
 	//   q(func: has(name), first:1) {
 	//     name
+	//     friend(first: 10) {
+	//       name
+	//     }
 	//   }
 	// }
-	isSupportedFunction := sg.SrcFunc != nil && sg.SrcFunc.Name == "has"
+	isSupportedFunction := sg.SrcFunc != nil && sg.SrcFunc.Name == "has" && sg.Params.Sample == 0
+	isPlainEdge := sg.SrcFunc == nil && sg.facetsFilter == nil && !sg.Params.DoCount &&
+		sg.Params.Sample == 0 && len(sg.Params.FacetsOrder) == 0 &&
+		len(sg.Params.Cascade) == 0 && !sg.Params.IgnoreReflex && !sg.Params.Recurse
+	if isPlainEdge && sg.Params.Count < 0 {
+		// The last uids of the lists are returned, which needs all of them.
+		isPlainEdge = false
+	}
 	if len(sg.Filters) == 0 && len(sg.Params.Order) == 0 &&
-		isSupportedFunction {
+		(isSupportedFunction || isPlainEdge) {
 		// Offset also added because, we need n results to trim the offset.
 		if sg.Params.Count != 0 {
 			count = sg.Params.Count + sg.Params.Offset
@@ -1098,7 +1270,8 @@ func (fromNode *varValue) transformTo(toPath []*SubGraph) (map[uint64]types.Val,
 			if !ok || curVal.Value == nil {
 				continue
 			}
-			if curVal.Tid != types.IntID && curVal.Tid != types.FloatID {
+			if curVal.Tid != types.IntID && curVal.Tid != types.FloatID &&
+				curVal.Tid != types.DecimalID {
 				return nil, errors.Errorf("Encountered non int/float type for summing")
 			}
 			for j := 0; j < len(ul.Uids); j++ {
@@ -1396,7 +1569,8 @@ AssignStep:
 func (sg *SubGraph) updateVars(doneVars map[string]varValue, sgPath []*SubGraph) error {
 	// NOTE: although we initialize doneVars (req.Vars) in ProcessQuery, this nil check is for
 	// non-root lookups that happen to other nodes. Don't use len(doneVars) == 0 !
-	if doneVars == nil || (sg.Params.Var == "" && sg.Params.FacetVar == nil) {
+	if doneVars == nil || (sg.Params.Var == "" && sg.Params.FacetVar == nil &&
+		sg.Params.RecurseArgs.DepthVar == "") {
 		return nil
 	}
 
@@ -1404,9 +1578,26 @@ func (sg *SubGraph) updateVars(doneVars map[string]varValue, sgPath []*SubGraph)
 	if err := sg.populateUidValVar(doneVars, sgPathCopy); err != nil {
 		return err
 	}
+	sg.populateRecurseDepthVar(doneVars, sgPathCopy)
 	return sg.populateFacetVars(doneVars, sgPathCopy)
 }
 
+// populateRecurseDepthVar stores the depth at which each node was reached by a recurse query
+// in the value variable passed as depth_var to @recurse.
+func (sg *SubGraph) populateRecurseDepthVar(doneVars map[string]varValue, sgPath []*SubGraph) {
+	if sg.Params.RecurseArgs.DepthVar == "" {
+		return
+	}
+	v := varValue{
+		Vals: make(map[uint64]types.Val, len(sg.recurseDepths)),
+		path: sgPath,
+	}
+	for uid, depth := range sg.recurseDepths {
+		v.Vals[uid] = types.Val{Tid: types.IntID, Value: int64(depth)}
+	}
+	doneVars[sg.Params.RecurseArgs.DepthVar] = v
+}
+
 // populateUidValVar populates the value of the variable into doneVars.
 func (sg *SubGraph) populateUidValVar(doneVars map[string]varValue, sgPath []*SubGraph) error {
 	if sg.Params.Var == "" {
@@ -1616,6 +1807,16 @@ func (sg *SubGraph) recursiveFillVars(doneVars map[string]varValue) error {
 			return err
 		}
 	}
+	if sg.SrcFunc != nil && sg.SrcFunc.CountGraph != nil {
+		return sg.SrcFunc.CountGraph.recursiveFillVars(doneVars)
+	}
+	if sg.SrcFunc != nil {
+		for _, member := range sg.SrcFunc.Union {
+			if err = member.recursiveFillVars(doneVars); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
@@ -1727,19 +1928,21 @@ func (sg *SubGraph) fillVars(mp map[string]varValue) error {
 
 // replaceVarInFunc gets values stored inside UidToVal(coming from a value variable defined in some
 // other query) and adds them as arguments to the SrcFunc in SubGraph.
-// E.g. - func: eq(score, val(myscore))
+// E.g. - func: eq(score, val(myscore)) or func: regexp(email, val(pattern))
 // NOTE - We disallow vars in facets filter so we don't need to worry about that as of now.
 func (sg *SubGraph) replaceVarInFunc() error {
 	if sg.SrcFunc == nil {
 		return nil
 	}
 	var args []gql.Arg
+	var hasValueVar bool
 	// Iterate over the args and replace value args with their values
 	for _, arg := range sg.SrcFunc.Args {
 		if !arg.IsValueVar {
 			args = append(args, arg)
 			continue
 		}
+		hasValueVar = true
 		if len(sg.Params.UidToVal) == 0 {
 			// This means that the variable didn't have any values and hence there is nothing to add
 			// to args.
@@ -1761,10 +1964,32 @@ func (sg *SubGraph) replaceVarInFunc() error {
 			args = append(args, gql.Arg{Value: val})
 		}
 	}
+	if sg.SrcFunc.Name == "regexp" && hasValueVar && len(args) > 0 {
+		args = regexpArgsFromValues(args)
+	}
 	sg.SrcFunc.Args = args
 	return nil
 }
 
+// regexpArgsFromValues turns the values of the variable given to regexp into the expression and
+// the (empty) flags expected by the function. The values are matched literally, and a node
+// matches if its value contains any of them.
+func regexpArgsFromValues(vals []gql.Arg) []gql.Arg {
+	exprs := make([]string, 0, len(vals))
+	for _, v := range vals {
+		// The values are data, not patterns, so they can't inject an expression.
+		exprs = append(exprs, regexp.QuoteMeta(v.Value))
+	}
+	// Sort the expressions so that the alternation doesn't depend on the order of the map.
+	sort.Strings(exprs)
+
+	expr := exprs[0]
+	if len(exprs) > 1 {
+		expr = "(?:" + strings.Join(exprs, ")|(?:") + ")"
+	}
+	return []gql.Arg{{Value: expr}, {Value: ""}}
+}
+
 // Used to evaluate an inequality function which uses a value variable instead of a predicate.
 // E.g.
 // 1. func: eq(val(x), 35) or @filter(eq(val(x), 35)
@@ -1817,6 +2042,173 @@ func (sg *SubGraph) applyIneqFunc() error {
 	return nil
 }
 
+// isMultiHasFn returns whether f is a has function over more than one predicate, or a hasall
+// function.
+func isMultiHasFn(f *gql.Function) bool {
+	return f != nil && ((f.Name == "has" && len(f.Args) > 0) || f.Name == "hasall")
+}
+
+// createHasMembers creates a has function at root for each of the predicates given to
+// has(p1, p2, ...) or hasall(p1, p2, ...), whose results are then merged or intersected.
+func (sg *SubGraph) createHasMembers(ctx context.Context, gq *gql.GraphQuery) error {
+	var memberArgs map[string]string
+	// The first n uids of has(p1, p2, ...) are among the first n uids of each predicate, so
+	// there is no need to fetch more of them unless something else happens before pagination.
+	if sg.SrcFunc.Name == "has" && sg.Params.Count > 0 && sg.Params.AfterUID == 0 &&
+		sg.Params.Sample == 0 && gq.Filter == nil && len(gq.Order) == 0 {
+		memberArgs = map[string]string{"first": strconv.Itoa(sg.Params.Count + sg.Params.Offset)}
+	}
+
+	preds := []string{gq.Func.Attr}
+	for _, arg := range gq.Func.Args {
+		preds = append(preds, arg.Value)
+	}
+	for _, pred := range preds {
+		member, err := newGraph(ctx, &gql.GraphQuery{Func: &gql.Function{Name: "has",
+			Attr: pred}, Args: memberArgs})
+		if err != nil {
+			return errors.Wrapf(err, "while creating %s", gq.Func.Name)
+		}
+		sg.SrcFunc.Union = append(sg.SrcFunc.Union, member)
+	}
+	return nil
+}
+
+// applyUnion runs each of the functions given to union and merges the uids they return, so that
+// every uid appears only once in the result of the block. The same is done for the has function
+// over several predicates, while the results of hasall are intersected instead.
+func (sg *SubGraph) applyUnion(ctx context.Context) error {
+	// The members only select the uids of the block, which are charged to the node budget
+	// once they're merged.
+	mctx := context.WithValue(ctx, budgetKey, (*nodeBudget)(nil))
+	errChan := make(chan error, len(sg.SrcFunc.Union))
+	for _, member := range sg.SrcFunc.Union {
+		go ProcessGraph(mctx, member, nil, errChan)
+	}
+
+	var err error
+	for range sg.SrcFunc.Union {
+		if e := <-errChan; e != nil && err == nil {
+			err = e
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	lists := make([]*pb.List, 0, len(sg.SrcFunc.Union))
+	for _, member := range sg.SrcFunc.Union {
+		lists = append(lists, member.DestUIDs)
+	}
+	if sg.SrcFunc.Name == "hasall" {
+		sg.DestUIDs = algo.IntersectSorted(lists)
+	} else {
+		sg.DestUIDs = algo.MergeSorted(lists)
+	}
+	sg.uidMatrix = []*pb.List{sg.DestUIDs}
+	return nil
+}
+
+// applyCountGraph evaluates an inequality function over the number of edges that match the
+// filter given to count, e.g. @filter(ge(count(post @filter(has(title))), 3)). The edges are
+// counted for each of the SrcUIDs and the uids whose count satisfies the inequality are stored
+// in DestUIDs.
+func (sg *SubGraph) applyCountGraph(ctx context.Context) error {
+	if sg.SrcUIDs == nil {
+		return errors.Errorf("Filtered count is only supported inside @filter")
+	}
+	args, err := sg.SrcFunc.intArgs()
+	if err != nil {
+		return err
+	}
+
+	// Each evaluation works on its own copy, so that the template isn't modified.
+	cg := new(SubGraph)
+	cg.copyFiltersRecurse(sg.SrcFunc.CountGraph)
+	cg.SrcUIDs = sg.SrcUIDs
+	cg.Params.ParentVars = sg.Params.ParentVars
+	cg.recurse(func(s *SubGraph) {
+		s.ReadTs = sg.ReadTs
+		s.Cache = sg.Cache
+		s.memory = sg.memory
+	})
+
+	errCh := make(chan error, 1)
+	ProcessGraph(ctx, cg, sg, errCh)
+	if err := <-errCh; err != nil {
+		return err
+	}
+
+	sg.DestUIDs = &pb.List{}
+	for i, uid := range sg.SrcUIDs.Uids {
+		var count int64
+		if i < len(cg.counts) {
+			count = int64(cg.counts[i])
+		}
+		if sg.SrcFunc.compareInt(count, args) {
+			sg.DestUIDs.Uids = append(sg.DestUIDs.Uids, uid)
+		}
+	}
+	return nil
+}
+
+// applyDepthFunc evaluates an inequality function over the depth of the nodes a filter inside
+// a recurse query is applied to, e.g. @filter(lt(depth(), 2)). All the nodes of a level have
+// the same depth, so either all or none of the SrcUIDs are kept.
+func (sg *SubGraph) applyDepthFunc() error {
+	if sg.Params.RecurseDepth == nil {
+		return errors.Errorf("depth() can only be used in filters inside @recurse")
+	}
+	args, err := sg.SrcFunc.intArgs()
+	if err != nil {
+		return err
+	}
+	if sg.SrcFunc.compareInt(int64(*sg.Params.RecurseDepth), args) {
+		sg.DestUIDs = &pb.List{Uids: sg.SrcUIDs.GetUids()}
+	} else {
+		sg.DestUIDs = &pb.List{}
+	}
+	return nil
+}
+
+// intArgs converts the arguments of an inequality function that compares integers computed
+// by the query, like a count or the recurse depth.
+func (fn *Function) intArgs() ([]types.Val, error) {
+	if fn.Name == "between" && len(fn.Args) != 2 {
+		return nil, errors.Errorf("between function expects 2 arguments, got: %d", len(fn.Args))
+	}
+	if fn.Name != "eq" && fn.Name != "between" && len(fn.Args) != 1 {
+		return nil, errors.Errorf("Function %s expects a single argument to compare with",
+			fn.Name)
+	}
+	var args []types.Val
+	for _, arg := range fn.Args {
+		src := types.Val{Tid: types.StringID, Value: []byte(arg.Value)}
+		dst, err := types.Convert(src, types.IntID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid argument %v. Comparing with different type",
+				arg.Value)
+		}
+		args = append(args, dst)
+	}
+	return args, nil
+}
+
+// compareInt returns whether val satisfies the inequality function against the given args.
+// eq is satisfied if val is equal to any of the args.
+func (fn *Function) compareInt(val int64, args []types.Val) bool {
+	curVal := types.Val{Tid: types.IntID, Value: val}
+	if fn.Name == "between" {
+		return types.CompareBetween(curVal, args[0], args[1])
+	}
+	for _, arg := range args {
+		if types.CompareVals(fn.Name, curVal, arg) {
+			return true
+		}
+	}
+	return false
+}
+
 func (sg *SubGraph) appendDummyValues() {
 	if sg.SrcUIDs == nil || len(sg.SrcUIDs.Uids) == 0 {
 		return
@@ -2023,6 +2415,21 @@ func ProcessGraph(ctx context.Context, sg, parent *SubGraph, rch chan error) {
 			sg.DestUIDs.Uids = sg.DestUIDs.Uids[i:]
 		}
 
+	case parent == nil && sg.SrcFunc != nil && len(sg.SrcFunc.Union) > 0:
+		if err = sg.applyUnion(ctx); err != nil {
+			rch <- err
+			return
+		}
+
+	case sg.SrcFunc != nil && sg.SrcFunc.IsDepth:
+		rch <- sg.applyDepthFunc()
+		return
+	case parent != nil && isEmptyRegexpWithVar(sg):
+		// The value variable used as argument of the regexp filter has no values, so nothing
+		// matches. Inequality filters are left to the worker, which rejects the missing argument.
+		sg.DestUIDs = &pb.List{}
+		rch <- nil
+		return
 	case sg.Attr == "":
 		// This is when we have uid function in children.
 		if sg.SrcFunc != nil && sg.SrcFunc.Name == "uid" {
@@ -2051,6 +2458,10 @@ func ProcessGraph(ctx context.Context, sg, parent *SubGraph, rch chan error) {
 	default:
 		isInequalityFn := sg.SrcFunc != nil && isInequalityFn(sg.SrcFunc.Name)
 		switch {
+		case isInequalityFn && sg.SrcFunc.CountGraph != nil:
+			// This is a ineq function over a filtered count, evaluated for each of the SrcUIDs.
+			rch <- sg.applyCountGraph(ctx)
+			return
 		case isInequalityFn && sg.SrcFunc.IsValueVar:
 			// This is a ineq function which uses a value variable.
 			err = sg.applyIneqFunc()
@@ -2082,7 +2493,9 @@ func ProcessGraph(ctx context.Context, sg, parent *SubGraph, rch chan error) {
 				rch <- err
 				return
 			}
+			taskStart := time.Now()
 			result, err := worker.ProcessTaskOverNetwork(ctx, taskQuery)
+			sg.taskLatency = time.Since(taskStart)
 			switch {
 			case err != nil && strings.Contains(err.Error(), worker.ErrNonExistentTabletMessage):
 				sg.UnknownAttr = true
@@ -2099,6 +2512,7 @@ func ProcessGraph(ctx context.Context, sg, parent *SubGraph, rch chan error) {
 			sg.counts = result.Counts
 			sg.LangTags = result.LangMatrix
 			sg.List = result.List
+			sg.distinctBuckets = result.DistinctBuckets
 
 			if sg.Params.DoCount {
 				if len(sg.Filters) == 0 {
@@ -2122,8 +2536,20 @@ func ProcessGraph(ctx context.Context, sg, parent *SubGraph, rch chan error) {
 		}
 	}
 
+	isEdgeFilter, err := sg.isEdgeFilter()
+	if err != nil {
+		rch <- err
+		return
+	}
+	if isEdgeFilter {
+		if err = sg.applyEdgeFilter(ctx); err != nil {
+			rch <- err
+			return
+		}
+	}
+
 	// Run filters if any.
-	if len(sg.Filters) > 0 {
+	if len(sg.Filters) > 0 && !isEdgeFilter {
 		// Run all filters in parallel.
 		filterChan := make(chan error, len(sg.Filters))
 		for _, filter := range sg.Filters {
@@ -2183,6 +2609,11 @@ func ProcessGraph(ctx context.Context, sg, parent *SubGraph, rch chan error) {
 		}
 	}
 
+	if sg.Params.Sample > 0 {
+		// Sampling is done before ordering and pagination, so that they apply to the sample.
+		sg.applySample()
+	}
+
 	if len(sg.Params.Order) == 0 && len(sg.Params.FacetsOrder) == 0 {
 		// There is no ordering. Just apply pagination and return.
 		if err = sg.applyPagination(ctx); err != nil {
@@ -2219,6 +2650,13 @@ func ProcessGraph(ctx context.Context, sg, parent *SubGraph, rch chan error) {
 		return
 	}
 
+	// Charge the budget before expanding, so that a query going over it stops at this level
+	// instead of after doing all of its work.
+	if err = sg.chargeNodeBudget(ctx, parent); err != nil {
+		rch <- err
+		return
+	}
+
 	if sg.Children, err = expandSubgraph(ctx, sg); err != nil {
 		rch <- err
 		return
@@ -2297,6 +2735,18 @@ func ProcessGraph(ctx context.Context, sg, parent *SubGraph, rch chan error) {
 	rch <- childErr
 }
 
+// applySample keeps a uniformly random subset of Params.Sample uids out of DestUIDs. The root
+// functions evaluated by the worker already return their sample, so this only picks the sample of
+// the roots evaluated here: uid(), union() and has() over several predicates.
+func (sg *SubGraph) applySample() {
+	if len(sg.DestUIDs.GetUids()) <= sg.Params.Sample {
+		return
+	}
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	sg.DestUIDs = algo.Sample(sg.DestUIDs, sg.Params.Sample, rnd)
+	sg.updateUidMatrix()
+}
+
 // applyPagination applies count and offset to lists inside uidMatrix.
 func (sg *SubGraph) applyPagination(ctx context.Context) error {
 	if sg.Params.Count == 0 && sg.Params.Offset == 0 { // No pagination.
@@ -2529,7 +2979,7 @@ func (sg *SubGraph) sortAndPaginateUsingVar(ctx context.Context) error {
 func isValidArg(a string) bool {
 	switch a {
 	case "numpaths", "from", "to", "orderasc", "orderdesc", "first", "offset", "after", "depth",
-		"minweight", "maxweight":
+		"minweight", "maxweight", "undirected":
 		return true
 	}
 	return false
@@ -2539,7 +2989,8 @@ func isValidArg(a string) bool {
 func isValidFuncName(f string) bool {
 	switch f {
 	case "anyofterms", "allofterms", "val", "regexp", "anyoftext", "alloftext",
-		"has", "uid", "uid_in", "anyof", "allof", "type", "match":
+		"has", "hasall", "uid", "uid_in", "anyof", "allof", "type", "match", "union",
+		"similar_to":
 		return true
 	}
 	return isInequalityFn(f) || types.IsGeoFunc(f)
@@ -2617,6 +3068,56 @@ func filterUidPredicates(ctx context.Context, preds []string) ([]string, error)
 	return filteredPreds, nil
 }
 
+// addReverseChildren adds the predicates in the opposite direction of the children of sg, so that
+// the edges are followed in both directions. The reverse of a predicate is only added if it has a
+// @reverse index.
+func (sg *SubGraph) addReverseChildren(ctx context.Context) error {
+	present := make(map[string]struct{}, len(sg.Children))
+	var preds []string
+	for _, child := range sg.Children {
+		present[child.Attr] = struct{}{}
+		if !strings.HasPrefix(child.Attr, "~") {
+			preds = append(preds, child.Attr)
+		}
+	}
+
+	reversed := make(map[string]struct{})
+	if len(preds) > 0 {
+		schs, err := worker.GetSchemaOverNetwork(ctx, &pb.SchemaRequest{Predicates: preds,
+			Fields: []string{"reverse"}})
+		if err != nil {
+			return err
+		}
+		for _, sch := range schs {
+			if sch.GetReverse() {
+				reversed[sch.GetPredicate()] = struct{}{}
+			}
+		}
+	}
+
+	for _, child := range sg.Children {
+		attr := "~" + child.Attr
+		if strings.HasPrefix(child.Attr, "~") {
+			attr = strings.TrimPrefix(child.Attr, "~")
+		} else if _, ok := reversed[child.Attr]; !ok {
+			continue
+		}
+		if _, ok := present[attr]; ok {
+			continue
+		}
+		present[attr] = struct{}{}
+
+		rev := new(SubGraph)
+		rev.copyFiltersRecurse(child)
+		rev.Attr = attr
+		// The alias and variable given to the predicate only apply to its own direction.
+		rev.Params.Alias = ""
+		rev.Params.Var = ""
+		sg.Children = append(sg.Children, rev)
+	}
+	return nil
+}
+
 // UidsToHex converts the new UIDs to hex string.
 func UidsToHex(m map[string]uint64) map[string]string {
 	res := make(map[string]string)
@@ -2642,6 +3143,10 @@ type Request struct {
 	Subgraphs []*SubGraph
 
 	Vars map[string]varValue
+
+	// MaxNodes is the maximum number of nodes and edges that the query is allowed to
+	// expand. Zero means that there is no limit.
+	MaxNodes uint64
 }
 
 // ProcessQuery processes query part of the request (without mutations).
@@ -2652,6 +3157,12 @@ func (req *Request) ProcessQuery(ctx context.Context) (err error) {
 	stop := x.SpanTimer(span, "query.ProcessQuery")
 	defer stop()
 
+	if req.MaxNodes > 0 {
+		ctx = withNodeBudget(ctx, req.MaxNodes)
+	}
+	ctx, memory := x.WithMemoryAccount(ctx)
+	defer memory.Close()
+
 	// Vars stores the processed variables.
 	req.Vars = make(map[string]varValue)
 	loopStart := time.Now()
@@ -2672,6 +3183,7 @@ func (req *Request) ProcessQuery(ctx context.Context) (err error) {
 		sg.recurse(func(sg *SubGraph) {
 			sg.ReadTs = req.ReadTs
 			sg.Cache = req.Cache
+			sg.memory = memory
 		})
 		span.Annotate(nil, "Query parsed")
 		req.Subgraphs = append(req.Subgraphs, sg)
@@ -2730,9 +3242,9 @@ func (req *Request) ProcessQuery(ctx context.Context) (err error) {
 			idxList = append(idxList, idx)
 			// A query doesn't need to be executed if
 			// 1. It just does aggregation and math functions which is when sg.Params.IsEmpty is true.
-			// 2. Its has an inequality fn at root without any args which can happen when it uses
-			// value variables for args which don't expand to any value.
-			if sg.Params.IsEmpty || isEmptyIneqFnWithVar(sg) {
+			// 2. Its has an inequality or regexp fn at root without any args which can happen when
+			// it uses value variables for args which don't expand to any value.
+			if sg.Params.IsEmpty || isEmptyFnWithVar(sg) {
 				errChan <- nil
 				continue
 			}
@@ -2815,6 +3327,11 @@ func (req *Request) Process(ctx context.Context) (er ExecutionResult, err error)
 		calculateMetrics(sg, metrics)
 	}
 	er.Metrics = metrics
+	if dm := DetailedMetricsFromContext(ctx); dm != nil {
+		for _, sg := range er.Subgraphs {
+			dm.collect(sg)
+		}
+	}
 
 	schemaProcessingStart := time.Now()
 	if req.GqlQuery.Schema != nil {
@@ -2123,6 +2123,31 @@ func TestNestedFuncRoot2(t *testing.T) {
 	require.JSONEq(t, `{"data": {"me":[{"name":"Michonne"},{"name":"Rick Grimes"},{"name":"Andrea"}]}}`, js)
 }
 
+func TestFilterOnFilteredCount(t *testing.T) {
+	query := `
+		{
+			me(func: uid(1, 23, 31)) @filter(ge(count(friend @filter(anyofterms(name, "Glenn Rick"))), 1)) {
+				name
+			}
+		}
+  `
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t, `{"data": {"me":[{"name":"Michonne"},{"name":"Andrea"}]}}`, js)
+}
+
+func TestFilterOnFilteredCountAtRoot(t *testing.T) {
+	query := `
+		{
+			me(func: ge(count(friend @filter(has(name))), 1)) {
+				name
+			}
+		}
+  `
+	_, err := processQuery(context.Background(), t, query)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Filtered count is only supported inside @filter")
+}
+
 func TestNestedFuncRoot4(t *testing.T) {
 
 	query := `
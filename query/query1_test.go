@@ -556,6 +556,96 @@ func TestUidAlias(t *testing.T) {
 		js)
 }
 
+func TestSampleAtRoot(t *testing.T) {
+	query := `
+	{
+		me(func: uid(1, 23, 24, 25, 31), sample: 2) {
+			uid
+		}
+	}
+	`
+	js := processQueryNoErr(t, query)
+	var res struct {
+		Data struct {
+			Me []struct {
+				Uid string `json:"uid"`
+			} `json:"me"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(js), &res))
+	require.Len(t, res.Data.Me, 2)
+	require.NotEqual(t, res.Data.Me[0].Uid, res.Data.Me[1].Uid)
+	for _, node := range res.Data.Me {
+		require.Contains(t, []string{"0x1", "0x17", "0x18", "0x19", "0x1f"}, node.Uid)
+	}
+}
+
+func TestSampleWithOrder(t *testing.T) {
+	query := `
+	{
+		me(func: uid(1, 23, 24, 25, 31), sample: 10, orderasc: age) {
+			age
+		}
+	}
+	`
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t,
+		`{"data":{"me":[{"age":15},{"age":15},{"age":17},{"age":19},{"age":38}]}}`, js)
+}
+
+func TestSampleHas(t *testing.T) {
+	query := `
+	{
+		me(func: has(name), sample: 3, first: 2) {
+			uid
+			name
+		}
+	}
+	`
+	js := processQueryNoErr(t, query)
+	var res struct {
+		Data struct {
+			Me []struct {
+				Uid  string `json:"uid"`
+				Name string `json:"name"`
+			} `json:"me"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(js), &res))
+	// The sample is taken out of all the nodes with a name, then paginated.
+	require.Len(t, res.Data.Me, 2)
+	require.NotEqual(t, res.Data.Me[0].Uid, res.Data.Me[1].Uid)
+	for _, node := range res.Data.Me {
+		require.NotEmpty(t, node.Name)
+	}
+}
+
+func TestSampleWithFilter(t *testing.T) {
+	query := `
+	{
+		me(func: uid(1, 23, 24, 25, 31), sample: 2) @filter(lt(age, 18)) {
+			age
+		}
+	}
+	`
+	_, err := processQuery(context.Background(), t, query)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "sample can't be used along with a filter at root")
+}
+
+func TestSampleInvalid(t *testing.T) {
+	query := `
+	{
+		me(func: uid(1, 23, 24), sample: 0) {
+			uid
+		}
+	}
+	`
+	_, err := processQuery(context.Background(), t, query)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "sample should be a positive number, got: 0")
+}
+
 func TestCountAtRoot(t *testing.T) {
 
 	query := `
@@ -582,6 +672,70 @@ func TestCountAtRoot2(t *testing.T) {
 	require.JSONEq(t, `{"data": {"me":[{"count": 4}]}}`, js)
 }
 
+func TestCountDistinctAtRoot(t *testing.T) {
+
+	query := `
+        {
+            me(func: uid(1, 31)) {
+				count(distinct friend)
+			}
+        }
+        `
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t, `{"data": {"me":[{"count(distinct friend)": 5}]}}`, js)
+}
+
+func TestCountDistinctValues(t *testing.T) {
+
+	query := `
+        {
+            me(func: uid(1)) {
+				friend {
+					count(uid)
+					count(distinct age)
+				}
+			}
+        }
+        `
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t,
+		`{"data": {"me":[{"friend":[{"count": 5},{"count(distinct age)": 3}]}]}}`, js)
+}
+
+func TestCountDistinctIndexedValues(t *testing.T) {
+
+	// pet_name and lang_type have an exact index, so the count is taken from the index.
+	query := `
+        {
+            me(func: uid(10101, 10102, 20000, 20001)) {
+				count(distinct lang_type)
+				pets: count(distinct pet_name)
+			}
+        }
+        `
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t,
+		`{"data": {"me":[{"count(distinct lang_type)": 1},{"pets": 4}]}}`, js)
+}
+
+func TestCountDistinctValueVar(t *testing.T) {
+
+	query := `
+        {
+            var(func: uid(1)) {
+				friend {
+					a as age
+				}
+			}
+            me() {
+				ages: count(distinct val(a))
+			}
+        }
+        `
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t, `{"data": {"me":[{"ages": 3}]}}`, js)
+}
+
 func TestCountAtRoot3(t *testing.T) {
 
 	query := `
@@ -651,6 +805,60 @@ func TestHasFuncAtRoot(t *testing.T) {
 	require.JSONEq(t, `{"data": {"me":[{"friend":[{"count":5}],"name":"Michonne"},{"friend":[{"count":1}],"name":"Rick Grimes"},{"friend":[{"count":1}],"name":"Andrea"}]}}`, js)
 }
 
+func TestHasMultiplePredicatesAtRoot(t *testing.T) {
+	query := `
+	{
+		me(func: has(friend, best_friend)) {
+			uid
+		}
+	}
+	`
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t, `{"data": {"me":[{"uid":"0x1"},{"uid":"0x2"},{"uid":"0x3"},{"uid":"0x4"},
+		{"uid":"0x17"},{"uid":"0x1f"}]}}`, js)
+}
+
+func TestHasMultiplePredicatesAtRootWithFirst(t *testing.T) {
+	query := `
+	{
+		me(func: has(friend, alias), first: 3) {
+			name
+		}
+	}
+	`
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t, `{"data": {"me":[{"name":"Michonne"},{"name":"Rick Grimes"},
+		{"name":"Glenn Rhee"}]}}`, js)
+}
+
+func TestHasAllAtRoot(t *testing.T) {
+	query := `
+	{
+		me(func: hasall(friend, alias)) {
+			name
+		}
+	}
+	`
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t, `{"data": {"me":[{"name":"Rick Grimes"},{"name":"Andrea"}]}}`, js)
+}
+
+func TestHasMultiplePredicatesInFilter(t *testing.T) {
+	query := `
+	{
+		any(func: uid(1, 2, 23, 24, 25)) @filter(has(best_friend, alias)) {
+			uid
+		}
+		all(func: uid(1, 2, 23, 24, 25)) @filter(hasall(friend, alias)) {
+			uid
+		}
+	}
+	`
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t, `{"data": {"any":[{"uid":"0x2"},{"uid":"0x17"},{"uid":"0x18"},{"uid":"0x19"}],
+		"all":[{"uid":"0x17"}]}}`, js)
+}
+
 func TestHasFuncAtRootWithAfter(t *testing.T) {
 
 	query := `
@@ -203,6 +203,55 @@ func TestToFastJSONFilterge2(t *testing.T) {
 		js)
 }
 
+func TestFilterDateTimeInLocation(t *testing.T) {
+
+	query := `
+		{
+			me(func: uid(0x01)) {
+				name
+				friend @filter(lt(dob_day, "1909-05-04T20:00:00", "America/New_York")) {
+					name
+				}
+			}
+		}
+	`
+
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t,
+		`{"data": {"me":[{"friend":[{"name":"Glenn Rhee"},{"name":"Daryl Dixon"},{"name":"Andrea"}],"name":"Michonne"}]}}`,
+		js)
+}
+
+func TestFilterBetweenDateTimeInLocation(t *testing.T) {
+
+	query := `
+		{
+			me(func: uid(1, 23, 24, 25, 31)) @filter(between(dob, "1909-12-31T19:00:00",
+				"1910-01-01T18:00:00", "America/New_York")) {
+				name
+			}
+		}
+	`
+
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t, `{"data": {"me":[{"name":"Michonne"}]}}`, js)
+}
+
+func TestFilterDateTimeInvalidLocation(t *testing.T) {
+
+	query := `
+		{
+			me(func: ge(dob, "1910-01-01", "Mars/Olympus_Mons")) {
+				name
+			}
+		}
+	`
+
+	_, err := processQuery(context.Background(), t, query)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "while parsing the time zone argument of ge")
+}
+
 func TestToFastJSONFilterGt(t *testing.T) {
 
 	query := `
@@ -2330,6 +2379,118 @@ func TestNormalizeDirectiveMultipleQuery(t *testing.T) {
 		}`, js)
 }
 
+func TestNormalizeDirectiveLists(t *testing.T) {
+	query := `
+		{
+			me(func: uid(0x01)) @normalize(lists) {
+				mn: name
+				gender
+				friends: friend {
+					n: name
+					d: dob
+					friend {
+						fn : name
+					}
+				}
+			}
+		}`
+
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t, `
+		{
+			"data": {
+			  "me": [
+				{
+				  "mn": "Michonne",
+				  "friends": [
+					{
+					  "d": "1910-01-02T00:00:00Z",
+					  "n": "Rick Grimes",
+					  "friend": [{"fn": "Michonne"}]
+					},
+					{
+					  "d": "1909-05-05T00:00:00Z",
+					  "n": "Glenn Rhee"
+					},
+					{
+					  "d": "1909-01-10T00:00:00Z",
+					  "n": "Daryl Dixon"
+					},
+					{
+					  "d": "1901-01-15T00:00:00Z",
+					  "n": "Andrea",
+					  "friend": [{"fn": "Glenn Rhee"}]
+					}
+				  ]
+				}
+			  ]
+			}
+		}`, js)
+}
+
+func TestNormalizeDirectiveListsNonListChild(t *testing.T) {
+	query := `
+		{
+			me(func: uid(501, 502)) @normalize(lists) {
+				mn: newname
+				boss {
+					bn: newname
+					newfriend {
+						bfn: newname
+					}
+				}
+			}
+		}
+	`
+
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t, `
+		{
+			"data": {
+				"me": [
+					{
+						"mn": "P1",
+						"bn": "P4",
+						"newfriend": [{"bfn": "P9"}, {"bfn": "P10"}]
+					},
+					{
+						"mn": "P2",
+						"bn": "P10",
+						"newfriend": [{"bfn": "P11"}, {"bfn": "P12"}]
+					}
+				]
+			}
+		}`, js)
+}
+
+func TestNormalizeDirectiveListsOverride(t *testing.T) {
+	query := `
+		{
+			me(func: uid(0x01)) @normalize(lists) {
+				mn: name
+				friend @normalize {
+					n: name
+					friend {
+						fn : name
+					}
+				}
+			}
+		}`
+
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t, `
+		{
+			"data": {
+			  "me": [
+				{"mn": "Michonne", "n": "Rick Grimes", "fn": "Michonne"},
+				{"mn": "Michonne", "n": "Glenn Rhee"},
+				{"mn": "Michonne", "n": "Daryl Dixon"},
+				{"mn": "Michonne", "n": "Andrea", "fn": "Glenn Rhee"}
+			  ]
+			}
+		}`, js)
+}
+
 func TestNormalizeDirectiveListAndNonListChild1(t *testing.T) {
 	query := `
 		{
@@ -178,6 +178,95 @@ func TestRecurseQueryLimitDepth1(t *testing.T) {
 		`{"data": {"me":[{"name":"Michonne", "friend":[{"name":"Rick Grimes"},{"name":"Glenn Rhee"},{"name":"Daryl Dixon"},{"name":"Andrea"}]}]}}`, js)
 }
 
+func TestRecurseQueryDepthFilter(t *testing.T) {
+
+	query := `
+		{
+			me(func: uid(0x01)) @recurse {
+				friend @filter(lt(depth(), 2))
+				name
+			}
+		}`
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t,
+		`{"data": {"me":[{"name":"Michonne", "friend":[{"name":"Rick Grimes"},{"name":"Glenn Rhee"},{"name":"Daryl Dixon"},{"name":"Andrea"}]}]}}`, js)
+}
+
+func TestRecurseQueryPerLevelFilter(t *testing.T) {
+
+	query := `
+		{
+			me(func: uid(0x01)) @recurse {
+				friend @filter(eq(depth(), 1) and anyofterms(name, "Rick Andrea") or eq(depth(), 2))
+				name
+			}
+		}`
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t,
+		`{"data": {"me":[{"name":"Michonne", "friend":[{"name":"Rick Grimes", "friend":[{"name":"Michonne"}]},{"name":"Andrea", "friend":[{"name":"Glenn Rhee"}]}]}]}}`, js)
+}
+
+func TestRecurseQueryDepthVar(t *testing.T) {
+
+	query := `
+		{
+			var(func: uid(0x01)) @recurse(depth_var: lvl) {
+				friend
+				name
+			}
+			levels(func: uid(lvl)) {
+				name
+				level: val(lvl)
+			}
+		}`
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t,
+		`{"data": {"levels":[{"name":"Michonne","level":0},{"name":"Rick Grimes","level":1},{"name":"Glenn Rhee","level":1},{"name":"Daryl Dixon","level":1},{"name":"Andrea","level":1},{"level":1}]}}`, js)
+}
+
+func TestRecurseUndirected(t *testing.T) {
+	query := `
+		{
+			var(func: uid(24)) @recurse(depth_var: lvl, undirected: true) {
+				friend
+			}
+			levels(func: uid(lvl)) {
+				name
+				level: val(lvl)
+			}
+		}`
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t,
+		`{"data": {"levels":[{"name":"Michonne","level":1},{"name":"Rick Grimes","level":2},{"name":"Glenn Rhee","level":0},{"name":"Daryl Dixon","level":2},{"name":"Andrea","level":1},{"level":2}]}}`, js)
+}
+
+func TestRecurseDirected(t *testing.T) {
+	query := `
+		{
+			me(func: uid(24)) @recurse {
+				name
+				friend
+			}
+		}`
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t, `{"data": {"me":[{"name":"Glenn Rhee"}]}}`, js)
+}
+
+func TestDepthFuncOutsideRecurse(t *testing.T) {
+
+	query := `
+		{
+			me(func: uid(0x01)) {
+				friend @filter(lt(depth(), 2)) {
+					name
+				}
+			}
+		}`
+	_, err := processQuery(context.Background(), t, query)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "depth() can only be used in filters inside @recurse")
+}
+
 func TestRecurseQueryLimitDepth2(t *testing.T) {
 
 	query := `
@@ -308,6 +397,49 @@ func TestShortestPath_NoPath(t *testing.T) {
 	require.JSONEq(t, `{"data": {"me": []}}`, js)
 }
 
+func TestShortestPathUndirected(t *testing.T) {
+	query := `
+		{
+			A as shortest(from: 24, to: 31, undirected: true) {
+				friend
+			}
+
+			me(func: uid(A)) {
+				name
+			}
+		}`
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t, `{"data": {"_path_":[{"uid":"0x18","~friend":{"uid":"0x1f"},"_weight_":1}],
+		"me":[{"name":"Glenn Rhee"},{"name":"Andrea"}]}}`, js)
+}
+
+func TestShortestPathDirectedNoPath(t *testing.T) {
+	query := `
+		{
+			A as shortest(from: 24, to: 31) {
+				friend
+			}
+
+			me(func: uid(A)) {
+				name
+			}
+		}`
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t, `{"data": {"me": []}}`, js)
+}
+
+func TestShortestPathUndirectedInvalidValue(t *testing.T) {
+	query := `
+		{
+			shortest(from: 24, to: 31, undirected: yes) {
+				friend
+			}
+		}`
+	_, err := processQuery(context.Background(), t, query)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "undirected should be a boolean, got: yes")
+}
+
 func TestKShortestPath_NoPath(t *testing.T) {
 
 	query := `
@@ -2751,6 +2883,208 @@ func TestValidFullTextIndex(t *testing.T) {
 		`{"data": {"me":[{"name":"Michonne", "friend":[{"alias":"Bob Joe"}]}]}}`, js)
 }
 
+func TestBm25Score(t *testing.T) {
+	query := `
+		{
+			me(func: uid(23, 24, 25, 101)) {
+				alias
+				bm25(alias, "john alice")
+			}
+		}
+	`
+
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t, `{"data": {"me":[
+		{"alias":"Zambo Alice", "bm25(alias)":0.8754687373538999},
+		{"alias":"John Alice", "bm25(alias)":1.7509374747077997},
+		{"alias":"Bob Joe", "bm25(alias)":0},
+		{"alias":"John Oliver", "bm25(alias)":0.8754687373538999}
+	]}}`, js)
+}
+
+func TestBm25ScoreOrder(t *testing.T) {
+	query := `
+		{
+			var(func: anyoftext(alias, "john oliver")) {
+				score as bm25(alias, "john oliver")
+			}
+
+			me(func: uid(score), orderdesc: val(score)) {
+				alias
+			}
+		}
+	`
+
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t, `{"data": {"me":[{"alias":"John Oliver"}, {"alias":"John Alice"}]}}`, js)
+}
+
+func TestBm25ScoreThreshold(t *testing.T) {
+	query := `
+		{
+			var(func: anyoftext(alias, "john oliver")) {
+				score as bm25(alias, "john oliver")
+			}
+
+			me(func: uid(score)) @filter(gt(val(score), 1.0)) {
+				alias
+				relevance: val(score)
+			}
+		}
+	`
+
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t, `{"data": {"me":[{"alias":"John Oliver", "relevance":2.26176309847379}]}}`,
+		js)
+}
+
+func TestBm25ScoreWithoutFullTextIndex(t *testing.T) {
+	query := `
+		{
+			me(func: uid(0x01)) {
+				bm25(name, "Michonne")
+			}
+		}
+	`
+
+	_, err := processQuery(context.Background(), t, query)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Attribute name is not indexed with type fulltext")
+}
+
+func TestMatchExactIndex(t *testing.T) {
+	query := `
+		{
+			me(func: match(alias, "Jon Alice", 1)) {
+				alias
+			}
+		}
+	`
+
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t, `{"data": {"me":[{"alias":"John Alice"}]}}`, js)
+}
+
+func TestMatchPrefixLength(t *testing.T) {
+	query := `
+		{
+			me(func: match(alias, "Bohn Alice", 1, 1)) {
+				alias
+			}
+		}
+	`
+
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t, `{"data": {"me":[]}}`, js)
+
+	query = `
+		{
+			me(func: uid(23, 24, 25, 31, 101)) @filter(match(alias, "John Alicia", 2, 4)) {
+				alias
+			}
+		}
+	`
+
+	js = processQueryNoErr(t, query)
+	require.JSONEq(t, `{"data": {"me":[{"alias":"John Alice"}]}}`, js)
+}
+
+func TestMatchTermIndex(t *testing.T) {
+	query := `
+		{
+			me(func: match(nick_name, "trms", 1)) {
+				nick_name
+			}
+		}
+	`
+
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t, `{"data": {"me":[{"nick_name":"Two Terms"}]}}`, js)
+
+	query = `
+		{
+			me(func: match(nick_name, "two trms", 1)) {
+				nick_name
+			}
+		}
+	`
+
+	_, err := processQuery(context.Background(), t, query)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Fuzzy match over a term index requires a single term")
+}
+
+func TestMatchInvalidPrefixLength(t *testing.T) {
+	query := `
+		{
+			me(func: match(alias, "John", 1, -1)) {
+				alias
+			}
+		}
+	`
+
+	_, err := processQuery(context.Background(), t, query)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Prefix length value can't be negative")
+}
+
+func TestUnion(t *testing.T) {
+	query := `
+		{
+			me(func: union(anyofterms(alias, "Alice"), uid(25, 23))) {
+				uid
+				alias
+			}
+		}
+	`
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t, `{"data": {"me":[
+		{"uid":"0x17","alias":"Zambo Alice"},
+		{"uid":"0x18","alias":"John Alice"},
+		{"uid":"0x19","alias":"Bob Joe"}]}}`, js)
+}
+
+func TestUnionWithFilterAndOrder(t *testing.T) {
+	query := `
+		{
+			me(func: union(eq(alias, "Bob Joe"), anyofterms(alias, "Alice"), uid(24)),
+				orderasc: alias, first: 2) @filter(not uid(23)) {
+				alias
+			}
+		}
+	`
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t, `{"data": {"me":[{"alias":"Bob Joe"},{"alias":"John Alice"}]}}`, js)
+}
+
+func TestUnionWithVars(t *testing.T) {
+	query := `
+		{
+			a as var(func: eq(alias, "Allan Matt"))
+			b as var(func: eq(alias, "John Oliver"))
+
+			me(func: union(uid(a), uid(b), uid(a))) {
+				alias
+			}
+		}
+	`
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t, `{"data": {"me":[{"alias":"Allan Matt"},{"alias":"John Oliver"}]}}`, js)
+}
+
+func TestUnionInvalidFunction(t *testing.T) {
+	query := `
+		{
+			me(func: union(eq(alias, "Bob Joe"), uid(23))) @filter(union(uid(1), uid(2))) {
+				alias
+			}
+		}
+	`
+	_, err := processQuery(context.Background(), t, query)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "union is only supported as the root function")
+}
+
 // dob (date of birth) is not a string
 func TestFilterRegexError(t *testing.T) {
 
@@ -3060,6 +3394,110 @@ func TestFilterRegex17(t *testing.T) {
 	require.Contains(t, err.Error(), "Function 'regexp' requires 2 arguments,")
 }
 
+func TestFilterRegexValueVar(t *testing.T) {
+	query := `
+		{
+			var(func: uid(0x1f)) {
+				p as name
+			}
+
+			me(func: uid(0x01)) {
+				name
+				friend @filter(regexp(name, val(p))) {
+					name
+				}
+			}
+		}
+	`
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t,
+		`{"data": {"me":[{"name":"Michonne", "friend":[{"name":"Andrea"}]}]}}`, js)
+}
+
+func TestFilterRegexMultipleValuesInVar(t *testing.T) {
+	query := `
+		{
+			var(func: uid(0x17, 0x1f)) {
+				p as name
+			}
+
+			me(func: uid(0x01)) {
+				name
+				friend @filter(regexp(name, val(p))) {
+					name
+				}
+			}
+		}
+	`
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t, `{"data": {"me":[{"name":"Michonne",
+		"friend":[{"name":"Rick Grimes"}, {"name":"Andrea"}]}]}}`, js)
+}
+
+func TestFilterRegexEmptyValueVar(t *testing.T) {
+	query := `
+		{
+			var(func: uid(0xfffffff)) {
+				p as name
+			}
+
+			me(func: uid(0x01)) {
+				name
+				friend @filter(regexp(name, val(p))) {
+					name
+				}
+			}
+
+			other(func: regexp(name, val(p))) {
+				name
+			}
+		}
+	`
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t, `{"data": {"me":[{"name":"Michonne"}], "other": []}}`, js)
+}
+
+func TestFilterEqEmptyValueVar(t *testing.T) {
+	// An inequality filter with a variable without values is still rejected, as it was before
+	// value variables could be used in regexp.
+	query := `
+		{
+			var(func: uid(0xfffffff)) {
+				a as name
+			}
+
+			me(func: uid(0x01)) {
+				name
+				friend @filter(eq(name, val(a))) {
+					name
+				}
+			}
+		}
+	`
+	_, err := processQuery(context.Background(), t, query)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "eq expects atleast 1 argument")
+}
+
+func TestRegexValueVarIsLiteral(t *testing.T) {
+	// The parentheses would make a group if the value were used as an expression, which
+	// wouldn't match the value itself.
+	query := `
+		{
+			var(func: uid(11002)) {
+				p as name@en
+			}
+
+			me(func: regexp(name@en, val(p))) {
+				name@en
+			}
+		}
+	`
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t,
+		`{"data": {"me":[{"name@en":"Puccini: La boheme (Sydney Opera)"}]}}`, js)
+}
+
 func TestTypeFunction(t *testing.T) {
 	query := `
 		{
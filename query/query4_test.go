@@ -26,6 +26,7 @@ import (
 	"github.com/dgraph-io/dgraph/x"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
 )
 
 func TestBigMathValue(t *testing.T) {
@@ -1561,3 +1562,30 @@ func TestNumUids(t *testing.T) {
 	require.Equal(t, metrics.NumUids["name"], uint64(16))
 	require.Equal(t, metrics.NumUids["_total"], uint64(26))
 }
+
+func TestQueryMaxNodesDuringExpansion(t *testing.T) {
+	query := `{
+		me(func: uid(0x01)) {
+			name
+			friend {
+				name
+				friend {
+					name
+				}
+			}
+		}
+	}`
+
+	// The root and its friends (1 + 5 edges + 6 names) fit in the budget, but the budget is
+	// exceeded while expanding the friends of friends, before the rest of the query runs.
+	ctx := metadata.NewOutgoingContext(context.Background(),
+		metadata.Pairs(x.QueryMaxNodesKey, "12"))
+	_, err := processQuery(ctx, t, query)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Query exceeded the max_nodes limit of 12")
+
+	ctx = metadata.NewOutgoingContext(context.Background(),
+		metadata.Pairs(x.QueryMaxNodesKey, "1000"))
+	_, err = processQuery(ctx, t, query)
+	require.NoError(t, err)
+}
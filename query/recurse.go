@@ -41,6 +41,7 @@ func (start *SubGraph) expandRecurse(ctx context.Context, maxDepth uint64) error
 	start.Children = start.Children[:0]
 
 	// Process the root first.
+	setRecurseDepth(start.Filters, 0)
 	go ProcessGraph(ctx, start, nil, rrch)
 	select {
 	case err = <-rrch:
@@ -55,6 +56,16 @@ func (start *SubGraph) expandRecurse(ctx context.Context, maxDepth uint64) error
 		return nil
 	}
 
+	// depths stores the depth at which each node was first reached, if it was asked for.
+	var depths map[uint64]uint64
+	if start.Params.RecurseArgs.DepthVar != "" {
+		depths = make(map[uint64]uint64)
+		start.recurseDepths = depths
+		for _, uid := range start.DestUIDs.GetUids() {
+			depths[uid] = 0
+		}
+	}
+
 	// Add children back and expand if necessary
 	if exec, err = expandChildren(ctx, start, startChildren); err != nil {
 		return err
@@ -80,6 +91,7 @@ func (start *SubGraph) expandRecurse(ctx context.Context, maxDepth uint64) error
 
 		rrch := make(chan error, len(exec))
 		for _, sg := range exec {
+			setRecurseDepth(sg.Filters, depth)
 			go ProcessGraph(ctx, sg, dummy, rrch)
 		}
 
@@ -143,6 +155,14 @@ func (start *SubGraph) expandRecurse(ctx context.Context, maxDepth uint64) error
 			} else {
 				sg.DestUIDs = algo.MergeSorted(sg.uidMatrix)
 			}
+
+			if depths != nil {
+				for _, uid := range sg.DestUIDs.Uids {
+					if _, ok := depths[uid]; !ok {
+						depths[uid] = depth
+					}
+				}
+			}
 		}
 
 		// modify the exec and attach child nodes.
@@ -174,6 +194,16 @@ func (start *SubGraph) expandRecurse(ctx context.Context, maxDepth uint64) error
 	}
 }
 
+// setRecurseDepth sets the depth of the nodes that the given filters are applied to, so that
+// they can be compared against using the depth() function.
+func setRecurseDepth(filters []*SubGraph, depth uint64) {
+	for _, filter := range filters {
+		d := depth
+		filter.Params.RecurseDepth = &d
+		setRecurseDepth(filter.Filters, depth)
+	}
+}
+
 // expandChildren adds child nodes to a SubGraph with no children, expanding them if necessary.
 func expandChildren(ctx context.Context, sg *SubGraph, children []*SubGraph) ([]*SubGraph, error) {
 	if len(sg.Children) > 0 {
@@ -221,5 +251,11 @@ func recurse(ctx context.Context, sg *SubGraph) error {
 		}
 	}
 
+	if sg.Params.Undirected {
+		if err := sg.addReverseChildren(ctx); err != nil {
+			return err
+		}
+	}
+
 	return sg.expandRecurse(ctx, depth)
 }
@@ -470,6 +470,11 @@ func shortestPath(ctx context.Context, sg *SubGraph) ([]*SubGraph, error) {
 	if sg.Params.From == 0 || sg.Params.To == 0 {
 		return nil, nil
 	}
+	if sg.Params.Undirected {
+		if err := sg.addReverseChildren(ctx); err != nil {
+			return nil, err
+		}
+	}
 	numPaths := sg.Params.NumPaths
 	if numPaths == 0 {
 		// Return 1 path by default.
@@ -17,6 +17,7 @@
 package raftwal
 
 import (
+	"bytes"
 	"io/ioutil"
 	"math/rand"
 	"os"
@@ -65,3 +66,39 @@ func TestEntryReadWrite(t *testing.T) {
 	_, err = openWal(dir)
 	require.EqualError(t, err, "Logfile is encrypted but encryption key is nil")
 }
+
+func TestSnapshotEncryption(t *testing.T) {
+	defer func() { x.WorkerConfig.EncryptionKey = nil }()
+	dir, err := ioutil.TempDir("", "raftwal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// A snapshot stored before encryption was enabled is still readable.
+	ds := Init(dir)
+	data := []byte("cluster state")
+	require.NoError(t, ds.meta.StoreSnapshot(&raftpb.Snapshot{
+		Data: data, Metadata: raftpb.SnapshotMetadata{Index: 1, Term: 1}}))
+	require.True(t, bytes.Contains(ds.meta.Data[snapshotOffset:snapshotOffset+1024], data))
+
+	x.WorkerConfig.EncryptionKey = []byte("badger16byteskey")
+	ds = Init(dir)
+	snap, err := ds.Snapshot()
+	require.NoError(t, err)
+	require.Equal(t, data, snap.Data)
+
+	// From then on, it's stored encrypted.
+	require.NoError(t, ds.meta.StoreSnapshot(&raftpb.Snapshot{
+		Data: data, Metadata: raftpb.SnapshotMetadata{Index: 2, Term: 1}}))
+	require.False(t, bytes.Contains(ds.meta.Data[snapshotOffset:snapshotOffset+1024], data))
+	ds = Init(dir)
+	snap, err = ds.Snapshot()
+	require.NoError(t, err)
+	require.Equal(t, data, snap.Data)
+	require.Equal(t, uint64(2), snap.Metadata.Index)
+
+	x.WorkerConfig.EncryptionKey = nil
+	mf, err := newMetaFile(dir, nil)
+	require.NoError(t, err)
+	_, err = mf.snapshot()
+	require.EqualError(t, err, "Snapshot is encrypted but encryption key is nil")
+}
@@ -21,6 +21,7 @@ import (
 	cryptorand "crypto/rand"
 	"encoding/binary"
 	"fmt"
+	"hash/crc32"
 	"os"
 	"path"
 	"sort"
@@ -52,16 +53,25 @@ const (
 	// encOffset is offset in the log file where keyID (first 8 bytes)
 	// and baseIV (remaining 8 bytes) are stored.
 	encOffset = logFileOffset - 16 // 1MB - 16B
+	// createdOffset is offset in the log file where the time it was created at is stored.
+	createdOffset = encOffset - 8
+	// entriesSize is the size of the part of the log file storing the entries.
+	entriesSize = maxNumEntries * entrySize
 	// logFileSize is the initial size of the log file.
 	logFileSize = 16 << 30
 	// entrySize is the size in bytes of a single entry.
 	entrySize = 32
 	// logSuffix is the suffix for log files.
 	logSuffix = ".wal"
+	// checksumFlag is set in the type of the entries written along with the checksum of their
+	// data. The entries written before checksums were added don't have it.
+	checksumFlag = 1 << 31
 )
 
 var (
 	emptyEntry = entry(make([]byte, entrySize))
+
+	castagnoli = crc32.MakeTable(crc32.Castagnoli)
 )
 
 type entry []byte
@@ -69,15 +79,22 @@ type entry []byte
 func (e entry) Term() uint64       { return binary.BigEndian.Uint64(e) }
 func (e entry) Index() uint64      { return binary.BigEndian.Uint64(e[8:]) }
 func (e entry) DataOffset() uint64 { return binary.BigEndian.Uint64(e[16:]) }
-func (e entry) Type() uint64       { return binary.BigEndian.Uint64(e[24:]) }
+func (e entry) Type() uint64       { return uint64(binary.BigEndian.Uint32(e[28:]) &^ checksumFlag) }
+
+// Checksum returns the CRC32 checksum of the data of the entry, as stored in the file, and whether
+// the entry has one.
+func (e entry) Checksum() (uint32, bool) {
+	return binary.BigEndian.Uint32(e[24:]), binary.BigEndian.Uint32(e[28:])&checksumFlag != 0
+}
 
-func marshalEntry(b []byte, term, index, do, typ uint64) {
+func marshalEntry(b []byte, term, index, do, typ uint64, checksum uint32) {
 	x.AssertTrue(len(b) == entrySize)
 
 	binary.BigEndian.PutUint64(b, term)
 	binary.BigEndian.PutUint64(b[8:], index)
 	binary.BigEndian.PutUint64(b[16:], do)
-	binary.BigEndian.PutUint64(b[24:], typ)
+	binary.BigEndian.PutUint32(b[24:], checksum)
+	binary.BigEndian.PutUint32(b[28:], uint32(typ)|checksumFlag)
 }
 
 // logFile represents a single log file.
@@ -88,6 +105,9 @@ type logFile struct {
 	registry *badger.KeyRegistry
 	dataKey  *pb.DataKey
 	baseIV   []byte
+	// created is the time the file was created at, or first opened at if it was created before
+	// the time was stored.
+	created time.Time
 }
 
 func logFname(dir string, id int64) string {
@@ -95,37 +115,24 @@ func logFname(dir string, id int64) string {
 }
 
 // openLogFile opens a logFile in the given directory. The filename is
-// constructed based on the value of fid.
-func openLogFile(dir string, fid int64) (*logFile, error) {
+// constructed based on the value of fid. The registry is nil if encryption isn't enabled.
+// NOTE: If encryption is enabled then there is no going back because if we disable it
+// later then the older log files which were previously encrypted can't be opened.
+func openLogFile(dir string, fid int64, registry *badger.KeyRegistry) (*logFile, error) {
 	glog.V(3).Infof("opening log file: %d\n", fid)
 	fpath := logFname(dir, fid)
 	lf := &logFile{
-		fid: fid,
+		fid:      fid,
+		registry: registry,
 	}
 	var err error
-	encKey := x.WorkerConfig.EncryptionKey
-	// Initialize the registry for logFile if encryption in enabled.
-	// NOTE: If encryption is enabled then there is no going back because if we disable it
-	// later then the older log files which were previously encrypted can't be opened.
-	if len(encKey) > 0 {
-		krOpt := badger.KeyRegistryOptions{
-			ReadOnly:                      false,
-			Dir:                           dir,
-			EncryptionKey:                 encKey,
-			EncryptionKeyRotationDuration: 10 * 24 * time.Hour,
-			InMemory:                      false,
-		}
-		// This won't open Badger. It would only use its key registry.
-		if lf.registry, err = badger.OpenKeyRegistry(krOpt); err != nil {
-			return nil, err
-		}
-	}
 	// Open the file in read-write mode and create it if it doesn't exist yet.
 	lf.MmapFile, err = z.OpenMmapFile(fpath, os.O_RDWR|os.O_CREATE, logFileSize)
 
 	if err == z.NewFile {
 		glog.V(3).Infof("New file: %d\n", fid)
 		z.ZeroOut(lf.Data, 0, logFileOffset)
+		lf.setCreated(time.Now())
 		if err = lf.bootstrap(); err != nil {
 			return nil, err
 		}
@@ -138,7 +145,7 @@ func openLogFile(dir string, fid int64) (*logFile, error) {
 		// If keyID is non-zero, then the opened file is encrypted.
 		if keyID != 0 {
 			// Logfile is encrypted but encryption key is not provided.
-			if encKey == nil {
+			if lf.registry == nil {
 				return nil, errors.New("Logfile is encrypted but encryption key is nil")
 			}
 			// retrieve datakey from the keyID of the logfile.
@@ -148,10 +155,41 @@ func openLogFile(dir string, fid int64) (*logFile, error) {
 			lf.baseIV = buf[8:]
 			y.AssertTrue(len(lf.baseIV) == 8)
 		}
+
+		if ns := binary.BigEndian.Uint64(lf.Data[createdOffset:]); ns > 0 {
+			lf.created = time.Unix(0, int64(ns))
+		} else {
+			lf.setCreated(time.Now())
+		}
 	}
 	return lf, nil
 }
 
+// openKeyRegistry opens the registry of the data keys used to encrypt the files in the given
+// directory. It returns nil if encryption isn't enabled. There must be a single registry open for
+// a directory, so that the keys it creates don't clash.
+func openKeyRegistry(dir string) (*badger.KeyRegistry, error) {
+	encKey := x.WorkerConfig.EncryptionKey
+	if len(encKey) == 0 {
+		return nil, nil
+	}
+	krOpt := badger.KeyRegistryOptions{
+		ReadOnly:                      false,
+		Dir:                           dir,
+		EncryptionKey:                 encKey,
+		EncryptionKeyRotationDuration: 10 * 24 * time.Hour,
+		InMemory:                      false,
+	}
+	// This won't open Badger. It would only use its key registry.
+	return badger.OpenKeyRegistry(krOpt)
+}
+
+// setCreated stores the time the file was created at.
+func (lf *logFile) setCreated(t time.Time) {
+	lf.created = t
+	binary.BigEndian.PutUint64(lf.Data[createdOffset:], uint64(t.UnixNano()))
+}
+
 // getEntry gets the entry at the slot idx.
 func (lf *logFile) getEntry(idx int) entry {
 	if lf == nil {
@@ -173,6 +211,8 @@ func (lf *logFile) GetRaftEntry(idx int) raftpb.Entry {
 	}
 	if entry.DataOffset() > 0 && entry.DataOffset() < logFileSize {
 		data := lf.Slice(int(entry.DataOffset()))
+		// The WAL can't be trusted past a corrupted entry.
+		x.Check(lf.verify(entry, data))
 		if len(data) > 0 {
 			// Copy the data over to allow the mmaped file to be deleted later.
 			re.Data = append(re.Data, data...)
@@ -191,6 +231,33 @@ func (lf *logFile) GetRaftEntry(idx int) raftpb.Entry {
 	return re
 }
 
+// verify checks the data of the entry, as stored in the file, against the checksum of the entry.
+func (lf *logFile) verify(e entry, data []byte) error {
+	sum, ok := e.Checksum()
+	if !ok || crc32.Checksum(data, castagnoli) == sum {
+		return nil
+	}
+	return errors.Errorf("Checksum mismatch for the entry at index %d of WAL file %s",
+		e.Index(), lf.Fd.Name())
+}
+
+// verifyAll checks the data of all the entries of the file against their checksums.
+func (lf *logFile) verifyAll() error {
+	for i := 0; i < maxNumEntries; i++ {
+		e := lf.getEntry(i)
+		if e.Index() == 0 {
+			return nil
+		}
+		if e.DataOffset() == 0 || e.DataOffset() >= logFileSize {
+			continue
+		}
+		if err := lf.verify(e, lf.Slice(int(e.DataOffset()))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // firstIndex returns the first index in the file.
 func (lf *logFile) firstIndex() uint64 {
 	return lf.getEntry(0).Index()
@@ -259,7 +326,7 @@ func (lf *logFile) delete() error {
 
 // getLogFiles returns all the log files in the directory sorted by the first
 // index in each file.
-func getLogFiles(dir string) ([]*logFile, error) {
+func getLogFiles(dir string, registry *badger.KeyRegistry) ([]*logFile, error) {
 	entryFiles := x.WalkPathFunc(dir, func(path string, isDir bool) bool {
 		if isDir {
 			return false
@@ -287,7 +354,7 @@ func getLogFiles(dir string) ([]*logFile, error) {
 		}
 		seen[fid] = struct{}{}
 
-		f, err := openLogFile(dir, fid)
+		f, err := openLogFile(dir, fid, registry)
 		if err != nil {
 			return nil, err
 		}
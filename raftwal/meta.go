@@ -17,11 +17,15 @@
 package raftwal
 
 import (
+	"crypto/aes"
+	cryptorand "crypto/rand"
 	"encoding/binary"
 	"fmt"
 	"os"
 	"path/filepath"
 
+	"github.com/dgraph-io/badger/v2"
+	"github.com/dgraph-io/badger/v2/y"
 	"github.com/dgraph-io/dgraph/x"
 	"github.com/dgraph-io/ristretto/z"
 	"github.com/golang/glog"
@@ -64,6 +68,9 @@ const (
 	metaName = "wal.meta"
 	// metaFileSize is the size of the wal.meta file.
 	metaFileSize = 4 << 30
+	// snapshotEncOffset is the offset of the keyID (first 8 bytes) and the baseIV (remaining 8
+	// bytes) the snapshot is encrypted with. The keyID is zero if it isn't encrypted.
+	snapshotEncOffset = 24
 	//hardStateOffset is the offset of the hard sate within the wal.meta file.
 	hardStateOffset = 512
 	// snapshotIndex stores the index and term corresponding to the snapshot.
@@ -93,10 +100,15 @@ func sliceSize(dst []byte, offset int) int {
 // metaFile stores the RAFT metadata (e.g RAFT ID, snapshot, hard state).
 type metaFile struct {
 	*z.MmapFile
+
+	// registry is nil if encryption isn't enabled. The snapshot is encrypted with a data key
+	// from it, like the log files.
+	registry *badger.KeyRegistry
 }
 
-// newMetaFile opens the meta file in the given directory.
-func newMetaFile(dir string) (*metaFile, error) {
+// newMetaFile opens the meta file in the given directory. The registry is the one of the log files,
+// it's nil if encryption isn't enabled.
+func newMetaFile(dir string, registry *badger.KeyRegistry) (*metaFile, error) {
 	fname := filepath.Join(dir, metaName)
 	// Open the file in read-write mode and creates it if it doesn't exist.
 	mf, err := z.OpenMmapFile(fname, os.O_RDWR|os.O_CREATE, metaFileSize)
@@ -105,7 +117,7 @@ func newMetaFile(dir string) (*metaFile, error) {
 	} else if err != nil {
 		return nil, errors.Wrapf(err, "unable to open meta file")
 	}
-	return &metaFile{MmapFile: mf}, nil
+	return &metaFile{MmapFile: mf, registry: registry}, nil
 }
 
 func (m *metaFile) bufAt(info MetaInfo) []byte {
@@ -161,7 +173,28 @@ func (m *metaFile) StoreSnapshot(snap *raftpb.Snapshot) error {
 	if len(m.Data)-snapshotOffset < len(buf) {
 		return errors.Errorf("Unable to store snapshot of size: %d\n", len(buf))
 	}
+
+	enc := m.Data[snapshotEncOffset : snapshotEncOffset+16]
+	if m.registry == nil {
+		z.ZeroOut(enc, 0, len(enc))
+		writeSlice(m.Data[snapshotOffset:], buf)
+		return nil
+	}
+	dk, err := m.registry.LatestDataKey()
+	if err != nil {
+		return errors.Wrapf(err, "while retrieving datakey to encrypt snapshot")
+	}
+	// Every snapshot is encrypted with a new baseIV.
+	var baseIV [8]byte
+	if _, err := cryptorand.Read(baseIV[:]); err != nil {
+		return errors.Wrapf(err, "while creating base IV to encrypt snapshot")
+	}
+	if buf, err = y.XORBlockAllocate(buf, dk.Data, snapshotIV(baseIV[:])); err != nil {
+		return errors.Wrapf(err, "while encrypting snapshot")
+	}
 	writeSlice(m.Data[snapshotOffset:], buf)
+	binary.BigEndian.PutUint64(enc[:8], dk.KeyId)
+	copy(enc[8:], baseIV[:])
 	return nil
 }
 
@@ -173,8 +206,30 @@ func (m *metaFile) snapshot() (raftpb.Snapshot, error) {
 		return snap, nil
 	}
 
+	enc := m.Data[snapshotEncOffset : snapshotEncOffset+16]
+	// If keyID is non-zero, then the snapshot is encrypted.
+	if keyID := binary.BigEndian.Uint64(enc[:8]); keyID != 0 {
+		if m.registry == nil {
+			return snap, errors.New("Snapshot is encrypted but encryption key is nil")
+		}
+		dk, err := m.registry.DataKey(keyID)
+		if err != nil {
+			return snap, err
+		}
+		if val, err = y.XORBlockAllocate(val, dk.Data, snapshotIV(enc[8:])); err != nil {
+			return snap, errors.Wrapf(err, "while decrypting snapshot")
+		}
+	}
+
 	if err := snap.Unmarshal(val); err != nil {
 		return snap, errors.Wrapf(err, "cannot parse snapshot")
 	}
 	return snap, nil
 }
+
+// snapshotIV returns the IV the snapshot is encrypted with, the baseIV followed by zeros.
+func snapshotIV(baseIV []byte) []byte {
+	iv := make([]byte, aes.BlockSize)
+	y.AssertTrue(8 == copy(iv, baseIV))
+	return iv
+}
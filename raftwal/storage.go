@@ -41,6 +41,7 @@ const versionKey = 1
 // 00-08 Bytes: Raft ID
 // 08-16 Bytes: Group ID
 // 16-24 Bytes: Checkpoint Index
+// 24-40 Bytes: Key ID and base IV of the snapshot, if it's encrypted
 // 512 Bytes: Hard State (Marshalled)
 // 1024-1032 Bytes: Snapshot Index
 // 1032-1040 Bytes: Snapshot Term
@@ -49,8 +50,10 @@ const versionKey = 1
 // --- <0000i>.wal files ---
 // These files contain raftpb.Entry protos. Each entry is composed of term, index, type and data.
 //
-// Term takes 8 bytes. Index takes 8 bytes. Type takes 8 bytes. And for data, we store an offset to
-// the actual slice, which is 8 bytes. Size of entry = 32 bytes.
+// Term takes 8 bytes. Index takes 8 bytes. The CRC32 checksum of the data takes 4 bytes, and type
+// the next 4 bytes. And for data, we store an offset to the actual slice, which is 8 bytes. Size of
+// entry = 32 bytes. The checksums are verified when the files are opened, and when the entries are
+// read.
 // First 30K entries would consume 960KB, hence fitting on the first MB of the file (logFileOffset).
 //
 // Pre-allocate 1MB in each file just for these entries, and zero them out explicitly. Zeroing them
@@ -58,7 +61,8 @@ const versionKey = 1
 //
 // And the data for these entries are laid out starting logFileOffset. Those are the offsets you
 // store in the Entry for Data field.
-// After 30K entries, we rotate the file.
+// After 30K entries, or once the data or the age of the file reach x.WorkerConfig.WALSegmentSize
+// or x.WorkerConfig.WALSegmentAge, we rotate the file.
 //
 // --- clean up ---
 // If snapshot idx = Idx_s. We find the first log file whose first entry is
@@ -93,13 +97,13 @@ func Init(dir string) *DiskStorage {
 	}
 
 	var err error
-	w.meta, err = newMetaFile(dir)
+	w.wal, err = openWal(dir)
 	x.Check(err)
-	// fmt.Printf("meta: %s\n", hex.Dump(w.meta.data[1024:2048]))
-	// fmt.Printf("found snapshot of size: %d\n", sliceSize(w.meta.data, snapshotOffset))
 
-	w.wal, err = openWal(dir)
+	w.meta, err = newMetaFile(dir, w.wal.registry)
 	x.Check(err)
+	// fmt.Printf("meta: %s\n", hex.Dump(w.meta.data[1024:2048]))
+	// fmt.Printf("found snapshot of size: %d\n", sliceSize(w.meta.data, snapshotOffset))
 
 	w.elog = trace.NewEventLog("Badger", "RaftStorage")
 
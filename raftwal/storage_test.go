@@ -34,11 +34,13 @@ package raftwal
 
 import (
 	"crypto/rand"
+	"encoding/binary"
 	"io/ioutil"
 	"math"
 	"os"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/dgraph-io/dgraph/x"
 	"github.com/stretchr/testify/require"
@@ -285,7 +287,7 @@ func TestMetaFile(t *testing.T) {
 	dir, err := ioutil.TempDir("", "badger-test")
 	require.NoError(t, err)
 
-	mf, err := newMetaFile(dir)
+	mf, err := newMetaFile(dir, nil)
 	require.NoError(t, err)
 	id := mf.Uint(RaftId)
 	require.Zero(t, id)
@@ -466,7 +468,7 @@ func TestStorageBig(t *testing.T) {
 
 		require.Equal(t, 0, len(ds.wal.files))
 
-		files, err := getLogFiles(dir)
+		files, err := getLogFiles(dir, ds.wal.registry)
 		require.NoError(t, err)
 		require.Equal(t, 1, len(files))
 
@@ -508,3 +510,67 @@ func TestStorageBig(t *testing.T) {
 	t.Run("without encryption", func(t *testing.T) { test(t, nil) })
 	t.Run("with encryption", func(t *testing.T) { test(t, []byte("badger16byteskey")) })
 }
+
+func TestEntryChecksum(t *testing.T) {
+	dir, err := ioutil.TempDir("", "raftwal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	el, err := openWal(dir)
+	require.NoError(t, err)
+	require.NoError(t, el.AddEntries([]raftpb.Entry{
+		{Index: 1, Term: 1, Type: raftpb.EntryConfChange, Data: []byte("first")},
+		{Index: 2, Term: 1, Data: []byte("second")},
+	}))
+	entries := el.allEntries(0, 100, math.MaxUint64)
+	require.Equal(t, 2, len(entries))
+	require.Equal(t, raftpb.EntryConfChange, entries[0].Type)
+	require.Equal(t, []byte("second"), entries[1].Data)
+
+	// Entries written without a checksum aren't verified.
+	e := el.current.getEntry(0)
+	binary.BigEndian.PutUint32(e[24:], 0)
+	binary.BigEndian.PutUint32(e[28:], uint32(raftpb.EntryConfChange))
+	require.NoError(t, el.current.verifyAll())
+
+	// Corrupting the data of an entry is caught when the WAL is opened.
+	data := el.current.Slice(int(el.current.getEntry(1).DataOffset()))
+	data[0] ^= 0xff
+	require.NoError(t, el.current.Sync())
+	_, err = openWal(dir)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Checksum mismatch for the entry at index 2")
+}
+
+func TestSegmentRotation(t *testing.T) {
+	defer func(size int64) { x.WorkerConfig.WALSegmentSize = size }(x.WorkerConfig.WALSegmentSize)
+	defer func(age time.Duration) { x.WorkerConfig.WALSegmentAge = age }(x.WorkerConfig.WALSegmentAge)
+	dir, err := ioutil.TempDir("", "raftwal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	el, err := openWal(dir)
+	require.NoError(t, err)
+	data := make([]byte, 100)
+	x.WorkerConfig.WALSegmentSize = 250
+	for i := uint64(1); i <= 6; i++ {
+		require.NoError(t, el.AddEntries([]raftpb.Entry{{Index: i, Term: 1, Data: data}}))
+	}
+	// A file is rotated once it holds 250 bytes of data, each entry taking 104 bytes.
+	require.Equal(t, 1, len(el.files))
+	require.Equal(t, uint64(4), el.current.firstIndex())
+
+	x.WorkerConfig.WALSegmentSize = 0
+	x.WorkerConfig.WALSegmentAge = time.Hour
+	require.NoError(t, el.AddEntries([]raftpb.Entry{{Index: 7, Term: 1, Data: data}}))
+	require.Equal(t, 1, len(el.files))
+
+	// The age of the files survives restarts.
+	el.current.setCreated(time.Now().Add(-2 * time.Hour))
+	el, err = openWal(dir)
+	require.NoError(t, err)
+	require.NoError(t, el.AddEntries([]raftpb.Entry{{Index: 8, Term: 1, Data: data}}))
+	require.Equal(t, 2, len(el.files))
+	require.Equal(t, uint64(8), el.current.firstIndex())
+	require.Equal(t, 8, len(el.allEntries(0, 100, math.MaxUint64)))
+}
@@ -18,8 +18,11 @@ package raftwal
 
 import (
 	"bytes"
+	"hash/crc32"
 	"sort"
+	"time"
 
+	"github.com/dgraph-io/badger/v2"
 	"github.com/dgraph-io/badger/v2/y"
 	"github.com/dgraph-io/dgraph/x"
 	"github.com/dgraph-io/ristretto/z"
@@ -45,6 +48,9 @@ type wal struct {
 	nextEntryIdx int
 	// dir is the directory to use to store files.
 	dir string
+	// registry holds the data keys the files are encrypted with. It's nil if encryption isn't
+	// enabled.
+	registry *badger.KeyRegistry
 }
 
 // allEntries returns all the entries in the range [lo, hi).
@@ -140,7 +146,7 @@ func (l *wal) AddEntries(entries []raftpb.Entry) error {
 					glog.Errorf("deleting file: %s. error: %v\n", ef.Fd.Name(), err)
 				}
 			}
-			z.ZeroOut(l.current.Data, entrySize*eidx, logFileOffset)
+			z.ZeroOut(l.current.Data, entrySize*eidx, entriesSize)
 			l.files = l.files[:fidx]
 		}
 		l.nextEntryIdx = eidx
@@ -162,7 +168,7 @@ func (l *wal) AddEntries(entries []raftpb.Entry) error {
 	}
 
 	for _, re := range entries {
-		if l.nextEntryIdx >= maxNumEntries {
+		if l.nextEntryIdx >= maxNumEntries || l.segmentFull(offset) {
 			if err := l.rotate(re.Index); err != nil {
 				return err
 			}
@@ -185,7 +191,8 @@ func (l *wal) AddEntries(entries []raftpb.Entry) error {
 
 		// Write the entry at the given slot.
 		buf := l.current.getEntry(l.nextEntryIdx)
-		marshalEntry(buf, re.Term, re.Index, uint64(offset), uint64(re.Type))
+		marshalEntry(buf, re.Term, re.Index, uint64(offset), uint64(re.Type),
+			crc32.Checksum(destBuf, castagnoli))
 
 		// Update values for the next entry.
 		offset = next
@@ -194,6 +201,23 @@ func (l *wal) AddEntries(entries []raftpb.Entry) error {
 	return nil
 }
 
+// segmentFull returns true if the current file has to be rotated before writing an entry at the
+// given offset, because it's over the size or the age a file can reach, as set by
+// x.WorkerConfig.WALSegmentSize and x.WorkerConfig.WALSegmentAge.
+func (l *wal) segmentFull(offset int) bool {
+	if l.nextEntryIdx == 0 {
+		// Every file holds at least one entry.
+		return false
+	}
+	if max := x.WorkerConfig.WALSegmentSize; max > 0 && int64(offset-logFileOffset) >= max {
+		return true
+	}
+	if max := x.WorkerConfig.WALSegmentAge; max > 0 && time.Since(l.current.created) >= max {
+		return true
+	}
+	return false
+}
+
 // firstIndex returns the first index available in the entry log.
 func (l *wal) firstIndex() uint64 {
 	if l == nil {
@@ -346,7 +370,9 @@ func (l *wal) reset() error {
 		}
 	}
 	l.files = l.files[:0]
-	z.ZeroOut(l.current.Data, 0, logFileOffset)
+	// Keep the encryption header, the data key of the file is still used to write to it.
+	z.ZeroOut(l.current.Data, 0, entriesSize)
+	l.current.setCreated(time.Now())
 	l.nextEntryIdx = 0
 	return nil
 }
@@ -364,7 +390,7 @@ func (l *wal) rotate(firstIndex uint64) error {
 	nextFid += 1
 	go l.current.Sync() // Trigger a sync in the background.
 
-	ef, err := openLogFile(l.dir, nextFid)
+	ef, err := openLogFile(l.dir, nextFid, l.registry)
 	if err != nil {
 		return errors.Wrapf(err, "while creating a new entry file")
 	}
@@ -377,10 +403,15 @@ func (l *wal) rotate(firstIndex uint64) error {
 }
 
 func openWal(dir string) (*wal, error) {
+	registry, err := openKeyRegistry(dir)
+	if err != nil {
+		return nil, err
+	}
 	e := &wal{
-		dir: dir,
+		dir:      dir,
+		registry: registry,
 	}
-	files, err := getLogFiles(dir)
+	files, err := getLogFiles(dir, registry)
 	if err != nil {
 		return nil, err
 	}
@@ -394,9 +425,12 @@ func openWal(dir string) (*wal, error) {
 			if err := ef.delete(); err != nil {
 				return nil, err
 			}
-		} else {
-			out = append(out, ef)
+			continue
+		}
+		if err := ef.verifyAll(); err != nil {
+			return nil, err
 		}
+		out = append(out, ef)
 	}
 	e.files = out
 	if sz := len(e.files); sz > 0 {
@@ -409,7 +443,7 @@ func openWal(dir string) (*wal, error) {
 
 	// No files found. Create a new file.
 	nextFid += 1
-	ef, err := openLogFile(dir, nextFid)
+	ef, err := openLogFile(dir, nextFid, registry)
 	e.current = ef
 	return e, err
 }
@@ -0,0 +1,188 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package schema
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/pkg/errors"
+)
+
+// patterns holds the compiled patterns of the @check directives, by their source.
+var patterns sync.Map
+
+// HasValueCheck returns whether the values of the predicate are constrained with @check.
+func HasValueCheck(su *pb.SchemaUpdate) bool {
+	return su.GetCheckMin() != "" || su.GetCheckMax() != "" || su.GetCheckMaxLength() > 0 ||
+		su.GetCheckPattern() != ""
+}
+
+// FormatValueCheck returns the arguments of the @check directive of the predicate as written in
+// schema files, like min: 0, max: 150.
+func FormatValueCheck(su *pb.SchemaUpdate) string {
+	var args []string
+	if su.GetCheckMin() != "" {
+		args = append(args, "min: "+formatBound(su.CheckMin))
+	}
+	if su.GetCheckMax() != "" {
+		args = append(args, "max: "+formatBound(su.CheckMax))
+	}
+	if su.GetCheckMaxLength() > 0 {
+		args = append(args, fmt.Sprintf("maxlength: %d", su.CheckMaxLength))
+	}
+	if su.GetCheckPattern() != "" {
+		args = append(args, "pattern: "+strconv.Quote(su.CheckPattern))
+	}
+	return strings.Join(args, ", ")
+}
+
+// formatBound returns a bound of @check as written in schema files. Bounds that aren't unsigned
+// integers, like negative numbers, are quoted.
+func formatBound(bound string) string {
+	if _, err := strconv.ParseUint(bound, 10, 64); err != nil {
+		return strconv.Quote(bound)
+	}
+	return bound
+}
+
+// ValueCheckViolation returns the constraint of the @check directive of the predicate that the
+// value doesn't satisfy, formatted like in the directive, or "" if it satisfies all of them. The
+// value is converted to the type of the predicate first.
+func ValueCheckViolation(su *pb.SchemaUpdate, val types.Val) (string, error) {
+	val, err := types.Convert(val, types.TypeID(su.ValueType))
+	if err != nil {
+		return "", err
+	}
+	if su.CheckMin != "" {
+		min, err := checkBound(su, su.CheckMin)
+		if err != nil {
+			return "", err
+		}
+		if types.CompareVals("lt", val, min) {
+			return "min: " + formatBound(su.CheckMin), nil
+		}
+	}
+	if su.CheckMax != "" {
+		max, err := checkBound(su, su.CheckMax)
+		if err != nil {
+			return "", err
+		}
+		if types.CompareVals("gt", val, max) {
+			return "max: " + formatBound(su.CheckMax), nil
+		}
+	}
+	if su.CheckMaxLength == 0 && su.CheckPattern == "" {
+		return "", nil
+	}
+	str, ok := val.Value.(string)
+	if !ok {
+		return "", errors.Errorf("Expected a string value for predicate [%s]", su.Predicate)
+	}
+	if su.CheckMaxLength > 0 && utf8.RuneCountInString(str) > int(su.CheckMaxLength) {
+		return fmt.Sprintf("maxlength: %d", su.CheckMaxLength), nil
+	}
+	if su.CheckPattern != "" {
+		re, err := checkPattern(su.CheckPattern)
+		if err != nil {
+			return "", err
+		}
+		if !re.MatchString(str) {
+			return "pattern: " + strconv.Quote(su.CheckPattern), nil
+		}
+	}
+	return "", nil
+}
+
+// checkBound converts a bound of the @check directive of the predicate to its type.
+func checkBound(su *pb.SchemaUpdate, bound string) (types.Val, error) {
+	src := types.Val{Tid: types.StringID, Value: []byte(bound)}
+	return types.Convert(src, types.TypeID(su.ValueType))
+}
+
+// checkPattern returns the compiled form of a pattern of the @check directive.
+func checkPattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	patterns.Store(pattern, re)
+	return re, nil
+}
+
+// checkValueCheck checks that the constraints of the @check directive of a predicate can be
+// applied to its values: ranges to numbers, and lengths and patterns to strings. The default
+// value of the predicate, if any, must satisfy them.
+func checkValueCheck(schema *pb.SchemaUpdate, t types.TypeID) error {
+	if x.IsEdgeProperty(schema.Predicate) || x.IsCompositeIndex(schema.Predicate) {
+		return errors.Errorf("@check isn't supported for edge properties or composite"+
+			" indexes, got predicate [%s]", schema.Predicate)
+	}
+	if schema.CheckMin != "" || schema.CheckMax != "" {
+		switch t {
+		case types.IntID, types.FloatID, types.DecimalID:
+		default:
+			return errors.Errorf("min and max of @check aren't supported for predicate [%s]"+
+				" of type [%s]", schema.Predicate, t.Name())
+		}
+		var bounds []types.Val
+		for _, bound := range []string{schema.CheckMin, schema.CheckMax} {
+			if bound == "" {
+				continue
+			}
+			val, err := checkBound(schema, bound)
+			if err != nil {
+				return errors.Errorf("Invalid bound %q of @check on predicate [%s] of type"+
+					" [%s]: %v", bound, schema.Predicate, t.Name(), err)
+			}
+			bounds = append(bounds, val)
+		}
+		if len(bounds) == 2 && types.CompareVals("gt", bounds[0], bounds[1]) {
+			return errors.Errorf("The min of @check on predicate [%s] is greater than its max",
+				schema.Predicate)
+		}
+	}
+	if (schema.CheckMaxLength > 0 || schema.CheckPattern != "") && t != types.StringID {
+		return errors.Errorf("maxlength and pattern of @check aren't supported for predicate"+
+			" [%s] of type [%s]", schema.Predicate, t.Name())
+	}
+	if schema.CheckPattern != "" {
+		if _, err := checkPattern(schema.CheckPattern); err != nil {
+			return errors.Errorf("Invalid pattern of @check on predicate [%s]: %v",
+				schema.Predicate, err)
+		}
+	}
+	if schema.DefaultValue != "" {
+		src := types.Val{Tid: types.StringID, Value: []byte(schema.DefaultValue)}
+		violation, err := ValueCheckViolation(schema, src)
+		if err == nil && violation != "" {
+			return errors.Errorf("The default value %q of predicate [%s] doesn't satisfy the"+
+				" %s constraint of @check", schema.DefaultValue, schema.Predicate, violation)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package schema
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/pkg/errors"
+)
+
+// IndexCondition is the condition of a partial index. It's a conjunction of comparisons of the
+// values of the predicate with constants, and only the values satisfying all of them are indexed.
+type IndexCondition []IndexComparison
+
+// IndexComparison compares the values of a predicate with a constant.
+type IndexComparison struct {
+	Op    string
+	Value types.Val
+}
+
+// isIndexConditionOp returns whether op can be used in the condition of a partial index.
+func isIndexConditionOp(op string) bool {
+	switch op {
+	case "eq", "ne", "le", "lt", "ge", "gt":
+		return true
+	}
+	return false
+}
+
+// formatIndexCondition returns the canonical form of a condition, in which it's stored in the
+// schema, like ne: "archived", lt: "100".
+func formatIndexCondition(ops, values []string) string {
+	cmps := make([]string, 0, len(ops))
+	for i := range ops {
+		cmps = append(cmps, fmt.Sprintf("%s: %q", ops[i], values[i]))
+	}
+	return strings.Join(cmps, ", ")
+}
+
+// ParseIndexCondition parses the canonical form of the condition of a partial index, converting
+// its constants to the type of the predicate.
+func ParseIndexCondition(where string, t types.TypeID) (IndexCondition, error) {
+	var cond IndexCondition
+	for rest := where; len(rest) > 0; {
+		i := strings.Index(rest, ": ")
+		if i < 0 || !isIndexConditionOp(rest[:i]) {
+			return nil, errors.Errorf("Invalid comparison in condition %s", where)
+		}
+		op := rest[:i]
+		rest = rest[i+2:]
+
+		// The constant is a quoted string, which ends at the first quote that isn't escaped.
+		end := 1
+		for ; end < len(rest) && rest[end] != '"'; end++ {
+			if rest[end] == '\\' {
+				end++
+			}
+		}
+		if len(rest) == 0 || rest[0] != '"' || end >= len(rest) {
+			return nil, errors.Errorf("Invalid constant in condition %s", where)
+		}
+		s, err := strconv.Unquote(rest[:end+1])
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid constant in condition %s", where)
+		}
+		rest = strings.TrimPrefix(rest[end+1:], ", ")
+
+		val, err := types.Convert(types.Val{Tid: types.StringID, Value: []byte(s)}, t)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid constant %q in condition %s", s, where)
+		}
+		if op != "eq" && op != "ne" {
+			if _, err := types.Less(val, val); err != nil {
+				return nil, errors.Errorf("Comparison %s isn't supported for type [%s]",
+					op, t.Name())
+			}
+		}
+		cond = append(cond, IndexComparison{Op: op, Value: val})
+	}
+	return cond, nil
+}
+
+// Holds returns whether the value satisfies the condition, and so is indexed.
+func (c IndexCondition) Holds(val types.Val) bool {
+	for _, cmp := range c {
+		if cmp.Op == "ne" {
+			if eq, err := types.Equal(val, cmp.Value); err != nil || eq {
+				return false
+			}
+			continue
+		}
+		if !types.CompareVals(cmp.Op, val, cmp.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// bound is a bound of a range of values. It's included in the range if inclusive is set.
+type bound struct {
+	val       types.Val
+	inclusive bool
+}
+
+// Covers returns whether all the values matched by the compare function satisfy the condition,
+// so that the function can be answered from the partial index. The arguments are the values of
+// eq, the bounds of between, or the single value of the other functions.
+func (c IndexCondition) Covers(fname string, args []types.Val) bool {
+	var lo, hi *bound
+	switch {
+	case fname == "eq":
+		for _, arg := range args {
+			if !c.Holds(arg) {
+				return false
+			}
+		}
+		return true
+	case fname == "between" && len(args) == 2:
+		lo, hi = &bound{args[0], true}, &bound{args[1], true}
+	case len(args) != 1:
+		return false
+	case fname == "ge" || fname == "gt":
+		lo = &bound{args[0], fname == "ge"}
+	case fname == "le" || fname == "lt":
+		hi = &bound{args[0], fname == "le"}
+	default:
+		return false
+	}
+	for _, cmp := range c {
+		if !cmp.coversRange(lo, hi) {
+			return false
+		}
+	}
+	return true
+}
+
+// coversRange returns whether all the values between lo and hi satisfy the comparison. A nil
+// bound leaves the range open on that side.
+func (cmp IndexComparison) coversRange(lo, hi *bound) bool {
+	v := cmp.Value
+	// above returns whether all the values of the range are above v, or also equal to it if
+	// orEqual is set.
+	above := func(orEqual bool) bool {
+		return lo != nil && (types.CompareVals("gt", lo.val, v) ||
+			((orEqual || !lo.inclusive) && types.CompareVals("eq", lo.val, v)))
+	}
+	below := func(orEqual bool) bool {
+		return hi != nil && (types.CompareVals("lt", hi.val, v) ||
+			((orEqual || !hi.inclusive) && types.CompareVals("eq", hi.val, v)))
+	}
+	switch cmp.Op {
+	case "ge":
+		return above(true)
+	case "gt":
+		return above(false)
+	case "le":
+		return below(true)
+	case "lt":
+		return below(false)
+	case "ne":
+		return above(false) || below(false)
+	case "eq":
+		return lo != nil && hi != nil && lo.inclusive && hi.inclusive &&
+			types.CompareVals("eq", lo.val, v) && types.CompareVals("eq", hi.val, v)
+	}
+	return false
+}
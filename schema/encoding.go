@@ -0,0 +1,218 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package schema
+
+import (
+	"bytes"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+)
+
+const (
+	// EncodingDictionary stores each string value as the id of the string in a dictionary kept
+	// in the schema of the predicate.
+	EncodingDictionary = "dictionary"
+	// EncodingDelta stores each int or datetime value as its difference to a base kept in the
+	// schema of the predicate.
+	EncodingDelta = "delta"
+
+	// CompressionSnappy compresses the posting lists of a predicate with Snappy, which is fast
+	// but compresses less.
+	CompressionSnappy = "snappy"
+	// CompressionZstd compresses the posting lists of a predicate with Zstandard, which
+	// compresses more at the cost of more CPU.
+	CompressionZstd = "zstd"
+	// CompressionZstdDict compresses the posting lists of a predicate with Zstandard, using a
+	// dictionary trained on the lists of the predicate. It suits predicates with many small
+	// values, which compress poorly on their own.
+	CompressionZstdDict = "zstd_dict"
+)
+
+var (
+	// maxDictionarySize is the number of strings the dictionary of a predicate holds at most.
+	// The values that don't fit in it are stored as they are.
+	maxDictionarySize = 4096
+	// maxDictionaryValueSize is the length of the longest string added to a dictionary.
+	maxDictionaryValueSize = 1024
+	// maxCompressionDictionaries is the number of compression dictionaries a predicate has at
+	// most. Once it has that many, it isn't trained anymore.
+	maxCompressionDictionaries = 16
+)
+
+// ValueEncoding returns the encoding of the values of the predicate with the given schema, if it
+// has one. The values of enums are stored in a dictionary.
+func ValueEncoding(su *pb.SchemaUpdate) string {
+	if len(su.GetEnumValues()) > 0 {
+		return EncodingDictionary
+	}
+	return su.GetEncoding()
+}
+
+// Encoding returns the encoding of the values of the given predicate, if it has one.
+func (s *state) Encoding(pred string) string {
+	s.RLock()
+	defer s.RUnlock()
+	return ValueEncoding(s.predicate[pred])
+}
+
+// Compression returns the algorithm the posting lists of the given predicate are compressed
+// with, if it has one.
+func (s *state) Compression(pred string) string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.predicate[pred].GetCompression()
+}
+
+// CompressionDictionaries returns the dictionaries trained for the compression of the posting
+// lists of the given predicate, the latest one last. The lists refer to them by their index.
+func (s *state) CompressionDictionaries(pred string) [][]byte {
+	s.RLock()
+	defer s.RUnlock()
+	return s.predicate[pred].GetCompressionDictionaries()
+}
+
+// DictionaryID returns the id of the given string in the dictionary of the predicate.
+func (s *state) DictionaryID(pred, value string) (uint64, bool) {
+	s.RLock()
+	defer s.RUnlock()
+	id, ok := s.dictionaries[pred][value]
+	return id, ok
+}
+
+// EncodingBase returns the base of the deltas the values of the predicate are stored as, if
+// it has been set.
+func (s *state) EncodingBase(pred string) (int64, bool) {
+	s.RLock()
+	defer s.RUnlock()
+	su, ok := s.predicate[pred]
+	if !ok || !su.EncodingBaseSet {
+		return 0, false
+	}
+	return su.EncodingBase, true
+}
+
+// ExtendEncoding adds the given strings to the dictionary of the predicate, as long as it has
+// room for them, and sets the base of its deltas if it isn't set yet. Both the schema of the
+// predicate and the one being applied in the background are changed. It returns the updated
+// schema to be written to disk, or nil if nothing changed.
+func (s *state) ExtendEncoding(pred string, values []string, base int64,
+	setBase bool) *pb.SchemaUpdate {
+	s.Lock()
+	defer s.Unlock()
+	cur, ok := s.predicate[pred]
+	if !ok {
+		return nil
+	}
+	// The schemas are replaced rather than modified, as they're read without the lock once
+	// they've been returned.
+	su := *cur
+	su.Dictionary = su.Dictionary[:len(su.Dictionary):len(su.Dictionary)]
+	var changed bool
+	for _, val := range values {
+		if len(su.Dictionary) >= maxDictionarySize {
+			break
+		}
+		if _, ok := s.dictionaries[pred][val]; ok || len(val) > maxDictionaryValueSize {
+			continue
+		}
+		su.Dictionary = append(su.Dictionary, val)
+		s.indexDictionary(pred, &su)
+		changed = true
+	}
+	if setBase && !su.EncodingBaseSet {
+		su.EncodingBase, su.EncodingBaseSet = base, true
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	s.predicate[pred] = &su
+	if mut, ok := s.mutSchema[pred]; ok {
+		updated := *mut
+		updated.Dictionary = su.Dictionary
+		updated.EncodingBase, updated.EncodingBaseSet = su.EncodingBase, su.EncodingBaseSet
+		s.mutSchema[pred] = &updated
+	}
+	return &su
+}
+
+// AddCompressionDictionary adds the given dictionary to the compression dictionaries of the
+// predicate, unless it already has it or it has as many as it can. Like ExtendEncoding, it
+// changes both the schema of the predicate and the one being applied in the background, and
+// returns the updated schema to be written to disk, or nil if nothing changed.
+func (s *state) AddCompressionDictionary(pred string, dict []byte) *pb.SchemaUpdate {
+	s.Lock()
+	defer s.Unlock()
+	cur, ok := s.predicate[pred]
+	if !ok || len(cur.CompressionDictionaries) >= maxCompressionDictionaries {
+		return nil
+	}
+	for _, d := range cur.CompressionDictionaries {
+		// The proposal adding it has been applied already.
+		if bytes.Equal(d, dict) {
+			return nil
+		}
+	}
+	su := *cur
+	n := len(su.CompressionDictionaries)
+	su.CompressionDictionaries = append(su.CompressionDictionaries[:n:n], dict)
+	s.predicate[pred] = &su
+	if mut, ok := s.mutSchema[pred]; ok {
+		updated := *mut
+		updated.CompressionDictionaries = su.CompressionDictionaries
+		s.mutSchema[pred] = &updated
+	}
+	return &su
+}
+
+// keepEncoding copies the dictionaries and the delta base of the predicate over to the given
+// schema of it. They only ever grow until the predicate is dropped, as the values stored with
+// them need them to be read back, even if the encoding of the predicate is changed. Must be
+// called with the lock held.
+func (s *state) keepEncoding(pred string, su *pb.SchemaUpdate) {
+	for _, cur := range []*pb.SchemaUpdate{s.predicate[pred], s.mutSchema[pred]} {
+		if cur == nil || cur == su {
+			continue
+		}
+		if len(cur.Dictionary) > len(su.Dictionary) {
+			su.Dictionary = cur.Dictionary
+		}
+		if len(cur.CompressionDictionaries) > len(su.CompressionDictionaries) {
+			su.CompressionDictionaries = cur.CompressionDictionaries
+		}
+		if cur.EncodingBaseSet && !su.EncodingBaseSet {
+			su.EncodingBase, su.EncodingBaseSet = cur.EncodingBase, true
+		}
+	}
+	s.indexDictionary(pred, su)
+}
+
+// indexDictionary adds the strings of the dictionary of the given schema that aren't indexed yet
+// to the ids of the strings of the predicate. Must be called with the lock held.
+func (s *state) indexDictionary(pred string, su *pb.SchemaUpdate) {
+	ids := s.dictionaries[pred]
+	if len(ids) >= len(su.Dictionary) {
+		return
+	}
+	if ids == nil {
+		ids = make(map[string]uint64, len(su.Dictionary))
+		s.dictionaries[pred] = ids
+	}
+	for id := len(ids); id < len(su.Dictionary); id++ {
+		ids[su.Dictionary[id]] = uint64(id)
+	}
+}
@@ -17,7 +17,9 @@
 package schema
 
 import (
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/dgraph-io/dgraph/lex"
 	"github.com/dgraph-io/dgraph/protos/pb"
@@ -73,6 +75,62 @@ func parseDirective(it *lex.ItemIterator, schema *pb.SchemaUpdate, t types.TypeI
 		schema.Upsert = true
 	case "noconflict":
 		schema.NoConflict = true
+	case "unique":
+		schema.Unique = true
+	case "ttl":
+		ttl, from, err := parseTTLDirective(it, "predicate", schema.Predicate)
+		if err != nil {
+			return err
+		}
+		if from == "" {
+			if t != types.DateTimeID {
+				return next.Errorf("@ttl on predicate [%s] of type [%s] must name the datetime"+
+					" predicate it expires from, like @ttl(\"24h\", from: createdAt)",
+					schema.Predicate, t.Name())
+			}
+			from = schema.Predicate
+		}
+		schema.Ttl, schema.TtlFrom = ttl, from
+	case "default":
+		value, virtual, err := parseDefaultDirective(it, schema.Predicate)
+		if err != nil {
+			return err
+		}
+		schema.DefaultValue, schema.DefaultVirtual = value, virtual
+	case "where":
+		where, err := parseWhereDirective(it, schema.Predicate)
+		if err != nil {
+			return err
+		}
+		schema.IndexWhere = where
+	case "enum":
+		values, err := parseEnumDirective(it, schema.Predicate)
+		if err != nil {
+			return err
+		}
+		schema.EnumValues = values
+	case "references":
+		onDelete, err := parseReferencesDirective(it, schema.Predicate)
+		if err != nil {
+			return err
+		}
+		schema.References = onDelete
+	case "check":
+		if err := parseCheckDirective(it, schema); err != nil {
+			return err
+		}
+	case "encoding":
+		encoding, err := parseEncodingDirective(it, schema.Predicate)
+		if err != nil {
+			return err
+		}
+		schema.Encoding = encoding
+	case "compression":
+		compression, err := parseCompressionDirective(it, schema.Predicate)
+		if err != nil {
+			return err
+		}
+		schema.Compression = compression
 	case "lang":
 		if t != types.StringID || schema.List {
 			return next.Errorf("@lang directive can only be specified for string type."+
@@ -126,8 +184,11 @@ func parseScalarPair(it *lex.ItemIterator, predicate string) (*pb.SchemaUpdate,
 	if !ok {
 		return nil, next.Errorf("Undefined Type")
 	}
-	if schema.List {
-		if uint32(t) == uint32(types.PasswordID) || uint32(t) == uint32(types.BoolID) {
+	// Edge properties are stored once per edge, so they don't share the restrictions that lists
+	// of values have.
+	if schema.List && !x.IsEdgeProperty(predicate) {
+		if uint32(t) == uint32(types.PasswordID) || uint32(t) == uint32(types.BoolID) ||
+			uint32(t) == uint32(types.VFloatID) {
 			return nil, next.Errorf("Unsupported type for list: [%s].", types.TypeID(t).Name())
 		}
 	}
@@ -159,6 +220,59 @@ func parseScalarPair(it *lex.ItemIterator, predicate string) (*pb.SchemaUpdate,
 	if next.Typ != itemDot {
 		return nil, next.Errorf("Invalid ending")
 	}
+	if x.IsEdgeProperty(predicate) {
+		if err := checkEdgeProperty(schema, t); err != nil {
+			return nil, next.Errorf("%v", err)
+		}
+	}
+	if x.IsCompositeIndex(predicate) {
+		if err := checkCompositeIndex(schema, t); err != nil {
+			return nil, next.Errorf("%v", err)
+		}
+	}
+	if schema.Unique {
+		if err := checkUnique(schema, t); err != nil {
+			return nil, next.Errorf("%v", err)
+		}
+	}
+	if schema.Ttl != "" && x.IsEdgeProperty(predicate) {
+		return nil, next.Errorf("@ttl isn't supported for edge property [%s]", predicate)
+	}
+	if len(schema.EnumValues) > 0 {
+		if err := checkEnum(schema, t); err != nil {
+			return nil, next.Errorf("%v", err)
+		}
+	}
+	if schema.DefaultValue != "" {
+		if err := checkDefault(schema, t); err != nil {
+			return nil, next.Errorf("%v", err)
+		}
+	}
+	if schema.References != "" {
+		switch {
+		case t != types.UidID:
+			return nil, next.Errorf("@references isn't supported for predicate [%s] of type"+
+				" [%s]", predicate, t.Name())
+		case schema.Directive != pb.SchemaUpdate_REVERSE:
+			return nil, next.Errorf("@references on predicate [%s] requires @reverse, to find"+
+				" the edges pointing to a node", predicate)
+		}
+	}
+	if HasValueCheck(schema) {
+		if err := checkValueCheck(schema, t); err != nil {
+			return nil, next.Errorf("%v", err)
+		}
+	}
+	if schema.IndexWhere != "" {
+		if err := checkIndexCondition(schema, t); err != nil {
+			return nil, next.Errorf("%v", err)
+		}
+	}
+	if schema.Encoding != "" {
+		if err := checkEncoding(schema, t); err != nil {
+			return nil, next.Errorf("%v", err)
+		}
+	}
 	it.Next()
 	next = it.Item()
 	if next.Typ == lex.ItemEOF {
@@ -171,11 +285,563 @@ func parseScalarPair(it *lex.ItemIterator, predicate string) (*pb.SchemaUpdate,
 	return schema, nil
 }
 
+// checkEdgeProperty validates the schema of a predicate storing a property of the edges of
+// another predicate. Its values come from the facets of the edges, so only the types facets
+// can take are allowed. The predicate holds one value per edge of a node, so it's always a list.
+func checkEdgeProperty(schema *pb.SchemaUpdate, t types.TypeID) error {
+	edge, property := x.ParseEdgeProperty(schema.Predicate)
+	if edge == "" || property == "" || x.IsEdgeProperty(property) {
+		return errors.Errorf("Invalid name for edge property: [%s]", schema.Predicate)
+	}
+	switch t {
+	case types.DefaultID, types.StringID, types.IntID, types.FloatID, types.BoolID,
+		types.DateTimeID:
+	default:
+		return errors.Errorf("Type [%s] isn't supported for edge property [%s]",
+			t.Name(), schema.Predicate)
+	}
+	if schema.Count || schema.Lang {
+		return errors.Errorf("@count and @lang aren't supported for edge property [%s]",
+			schema.Predicate)
+	}
+	schema.List = true
+	return nil
+}
+
+// checkCompositeIndex validates the schema of a composite index. The type and the index of the
+// composite index are the ones of the last predicate it spans. The index must give a single
+// token to each value, as the index keys are made of the values of the other predicates followed
+// by the token of the value of the last one.
+func checkCompositeIndex(schema *pb.SchemaUpdate, t types.TypeID) error {
+	preds := x.CompositeIndexPredicates(schema.Predicate)
+	seen := make(map[string]bool, len(preds))
+	for _, pred := range preds {
+		if pred == "" || seen[pred] || x.IsEdgeProperty(pred) || x.IsReservedPredicate(pred) {
+			return errors.Errorf("Invalid name for composite index: [%s]", schema.Predicate)
+		}
+		seen[pred] = true
+	}
+	switch {
+	case t == types.UidID || t == types.PasswordID || t == types.GeoID || t == types.VFloatID:
+		return errors.Errorf("Type [%s] isn't supported for composite index [%s]",
+			t.Name(), schema.Predicate)
+	case schema.List || schema.Count || schema.Lang:
+		return errors.Errorf("Lists, @count and @lang aren't supported for composite index [%s]",
+			schema.Predicate)
+	case schema.Unique || schema.DefaultValue != "" || schema.Ttl != "":
+		return errors.Errorf("@unique, @default and @ttl aren't supported for composite index"+
+			" [%s]", schema.Predicate)
+	case len(schema.Tokenizer) == 0:
+		return errors.Errorf("Composite index [%s] requires an index", schema.Predicate)
+	}
+	for _, name := range schema.Tokenizer {
+		tokenizer, ok := tok.GetTokenizer(name)
+		if !ok || (!tokenizer.IsSortable() && tokenizer.IsLossy()) {
+			return errors.Errorf("Tokenizer [%s] isn't supported for composite index [%s]",
+				name, schema.Predicate)
+		}
+	}
+	return nil
+}
+
+// checkUnique checks that the values of a predicate with the @unique directive can be looked up
+// in its index, which is how they're checked when they're set.
+func checkUnique(schema *pb.SchemaUpdate, t types.TypeID) error {
+	switch {
+	case t == types.UidID || t == types.PasswordID:
+		return errors.Errorf("@unique isn't supported for predicate [%s] of type [%s]",
+			schema.Predicate, t.Name())
+	case schema.List || schema.Lang || x.IsEdgeProperty(schema.Predicate):
+		return errors.Errorf("@unique isn't supported for lists, @lang or edge properties,"+
+			" got predicate [%s]", schema.Predicate)
+	case schema.NoConflict:
+		return errors.Errorf("@unique and @noconflict can't be used together for predicate [%s]",
+			schema.Predicate)
+	}
+	for _, name := range schema.Tokenizer {
+		if tokenizer, ok := tok.GetTokenizer(name); ok && !tokenizer.IsLossy() {
+			return nil
+		}
+	}
+	return errors.Errorf("@unique on predicate [%s] requires an exact, hash, int or bool index",
+		schema.Predicate)
+}
+
+// checkDefault checks that a predicate with the @default directive can hold its default value.
+func checkDefault(schema *pb.SchemaUpdate, t types.TypeID) error {
+	switch {
+	case t == types.UidID || t == types.PasswordID:
+		return errors.Errorf("@default isn't supported for predicate [%s] of type [%s]",
+			schema.Predicate, t.Name())
+	case schema.Lang || schema.Unique || x.IsEdgeProperty(schema.Predicate):
+		return errors.Errorf("@default isn't supported with @lang, @unique or for edge properties,"+
+			" got predicate [%s]", schema.Predicate)
+	}
+	if _, err := DefaultValue(schema); err != nil {
+		return errors.Errorf("Invalid default value %q for predicate [%s] of type [%s]: %v",
+			schema.DefaultValue, schema.Predicate, t.Name(), err)
+	}
+	return nil
+}
+
+// DefaultValue returns the default value of a predicate, converted to the type of the predicate
+// and marshalled to binary like the values of the edges of a mutation.
+func DefaultValue(schema *pb.SchemaUpdate) (types.Val, error) {
+	src := types.Val{Tid: types.StringID, Value: []byte(schema.DefaultValue)}
+	val, err := types.Convert(src, types.TypeID(schema.ValueType))
+	if err != nil {
+		return val, err
+	}
+	out := types.ValueForType(types.BinaryID)
+	if err := types.Marshal(val, &out); err != nil {
+		return out, err
+	}
+	return out, nil
+}
+
+// checkIndexCondition checks that a predicate with the @where directive has an index, and that
+// its values can be compared with the constants of the condition.
+func checkIndexCondition(schema *pb.SchemaUpdate, t types.TypeID) error {
+	switch {
+	case schema.Directive != pb.SchemaUpdate_INDEX:
+		return errors.Errorf("@where on predicate [%s] requires an index", schema.Predicate)
+	case schema.Unique || x.IsCompositeIndex(schema.Predicate):
+		return errors.Errorf("@where isn't supported with @unique or for composite indexes,"+
+			" got predicate [%s]", schema.Predicate)
+	}
+	switch t {
+	case types.IntID, types.FloatID, types.StringID, types.BoolID, types.DateTimeID,
+		types.DecimalID:
+	default:
+		return errors.Errorf("@where isn't supported for predicate [%s] of type [%s]",
+			schema.Predicate, t.Name())
+	}
+	if _, err := ParseIndexCondition(schema.IndexWhere, t); err != nil {
+		return errors.Errorf("Invalid condition for @where on predicate [%s]: %v",
+			schema.Predicate, err)
+	}
+	return nil
+}
+
+// checkEnum checks that a predicate with the @enum directive is a string predicate whose default
+// value, if it has one, is one of the values of the enum.
+func checkEnum(schema *pb.SchemaUpdate, t types.TypeID) error {
+	switch {
+	case t != types.StringID:
+		return errors.Errorf("@enum isn't supported for predicate [%s] of type [%s]",
+			schema.Predicate, t.Name())
+	case schema.Lang || x.IsEdgeProperty(schema.Predicate) ||
+		x.IsCompositeIndex(schema.Predicate):
+		return errors.Errorf("@enum isn't supported with @lang, for edge properties or"+
+			" composite indexes, got predicate [%s]", schema.Predicate)
+	case schema.DefaultValue != "" && !IsEnumValue(schema, schema.DefaultValue):
+		return errors.Errorf("The default value %q of predicate [%s] isn't one of the values"+
+			" of its enum", schema.DefaultValue, schema.Predicate)
+	}
+	return nil
+}
+
+// IsEnumValue returns whether the value is one of the values of the enum of the predicate.
+func IsEnumValue(schema *pb.SchemaUpdate, value string) bool {
+	for _, v := range schema.EnumValues {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// checkEncoding checks that the values of a predicate can be stored in the encoding given by
+// the @encoding directive. Dictionary encoding is for strings and delta encoding for ints and
+// datetimes.
+func checkEncoding(schema *pb.SchemaUpdate, t types.TypeID) error {
+	if x.IsEdgeProperty(schema.Predicate) || x.IsCompositeIndex(schema.Predicate) {
+		return errors.Errorf("@encoding isn't supported for edge properties or composite"+
+			" indexes, got predicate [%s]", schema.Predicate)
+	}
+	switch {
+	case schema.Encoding == EncodingDictionary && t == types.StringID:
+	case schema.Encoding == EncodingDelta && (t == types.IntID || t == types.DateTimeID):
+	default:
+		return errors.Errorf("@encoding(%s) isn't supported for predicate [%s] of type [%s]",
+			schema.Encoding, schema.Predicate, t.Name())
+	}
+	return nil
+}
+
+// checkTTLs checks that the predicates that @ttl expires from, if they're defined in the same
+// schema, are datetime predicates with an index that can find the expired values. A partial index
+// can't, as it doesn't have all the values.
+func checkTTLs(result *ParsedSchema) error {
+	preds := make(map[string]*pb.SchemaUpdate, len(result.Preds))
+	for _, update := range result.Preds {
+		preds[update.Predicate] = update
+	}
+	check := func(kind, name, from string) error {
+		update, ok := preds[from]
+		if from == "" || !ok {
+			return nil
+		}
+		if types.TypeID(update.ValueType) != types.DateTimeID ||
+			update.Directive != pb.SchemaUpdate_INDEX || update.IndexWhere != "" {
+			return errors.Errorf("@ttl on %s [%s] expires from [%s], which must be a datetime"+
+				" predicate with an index without @where", kind, name, from)
+		}
+		return nil
+	}
+	for _, update := range result.Preds {
+		if err := check("predicate", update.Predicate, update.TtlFrom); err != nil {
+			return err
+		}
+	}
+	for _, typ := range result.Types {
+		if err := check("type", typ.TypeName, typ.TtlFrom); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseTTLDirective parses the arguments of @ttl, which are a quoted duration and optionally the
+// datetime predicate the duration is counted from, like @ttl("24h", from: createdAt). The
+// duration is returned in its canonical form, like 24h0m0s.
+func parseTTLDirective(it *lex.ItemIterator, kind, name string) (string, string, error) {
+	if !it.Next() || it.Item().Typ != itemLeftRound || !it.Next() ||
+		it.Item().Typ != itemQuotedText {
+		return "", "", it.Item().Errorf("Expected a quoted duration for @ttl on %s [%s],"+
+			" like @ttl(\"24h\")", kind, name)
+	}
+	item := it.Item()
+	s, err := strconv.Unquote(item.Val)
+	if err != nil {
+		return "", "", item.Errorf("Invalid duration %s for @ttl on %s [%s]: %v",
+			item.Val, kind, name, err)
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < time.Second {
+		return "", "", item.Errorf("Invalid duration %s for @ttl on %s [%s], expected a"+
+			" duration of at least 1s, like \"24h\"", item.Val, kind, name)
+	}
+
+	var from string
+	it.Next()
+	if it.Item().Typ == itemComma {
+		if !it.Next() || it.Item().Typ != itemText || it.Item().Val != "from" ||
+			!it.Next() || it.Item().Typ != itemColon || !it.Next() ||
+			it.Item().Typ != itemText {
+			return "", "", it.Item().Errorf("Expected from: <predicate> after the duration of"+
+				" @ttl on %s [%s]", kind, name)
+		}
+		from = it.Item().Val
+		it.Next()
+	}
+	if it.Item().Typ != itemRightRound {
+		return "", "", it.Item().Errorf("Expected ) after the arguments of @ttl on %s [%s]."+
+			" Got %v", kind, name, it.Item().Val)
+	}
+	return d.String(), from, nil
+}
+
+// parseDefaultDirective parses the arguments of @default, which are a quoted value and optionally
+// the mode of the default, like @default("0", mode: virtual). The value is set on the nodes
+// created with a type that has the predicate in the default mode, materialize. In the virtual
+// mode, it's returned by queries of the nodes without a value instead.
+func parseDefaultDirective(it *lex.ItemIterator, predicate string) (string, bool, error) {
+	if !it.Next() || it.Item().Typ != itemLeftRound || !it.Next() ||
+		it.Item().Typ != itemQuotedText {
+		return "", false, it.Item().Errorf("Expected a quoted value for @default on predicate"+
+			" [%s], like @default(\"0\")", predicate)
+	}
+	item := it.Item()
+	value, err := strconv.Unquote(item.Val)
+	if err != nil {
+		return "", false, item.Errorf("Invalid value %s for @default on predicate [%s]: %v",
+			item.Val, predicate, err)
+	}
+	if value == "" {
+		return "", false, item.Errorf("The value of @default on predicate [%s] can't be empty",
+			predicate)
+	}
+
+	var virtual bool
+	it.Next()
+	if it.Item().Typ == itemComma {
+		if !it.Next() || it.Item().Typ != itemText || it.Item().Val != "mode" ||
+			!it.Next() || it.Item().Typ != itemColon || !it.Next() ||
+			it.Item().Typ != itemText {
+			return "", false, it.Item().Errorf("Expected mode: materialize or mode: virtual"+
+				" after the value of @default on predicate [%s]", predicate)
+		}
+		switch it.Item().Val {
+		case "materialize":
+		case "virtual":
+			virtual = true
+		default:
+			return "", false, it.Item().Errorf("Invalid mode %s for @default on predicate"+
+				" [%s], expected materialize or virtual", it.Item().Val, predicate)
+		}
+		it.Next()
+	}
+	if it.Item().Typ != itemRightRound {
+		return "", false, it.Item().Errorf("Expected ) after the arguments of @default on"+
+			" predicate [%s]. Got %v", predicate, it.Item().Val)
+	}
+	return value, virtual, nil
+}
+
+// parseEnumDirective parses the values of @enum, which are names like the ones of GraphQL enum
+// values, like @enum(ACTIVE, PENDING, ARCHIVED).
+func parseEnumDirective(it *lex.ItemIterator, predicate string) ([]string, error) {
+	if !it.Next() || it.Item().Typ != itemLeftRound {
+		return nil, it.Item().Errorf("Expected the values of @enum on predicate [%s],"+
+			" like @enum(ACTIVE, PENDING)", predicate)
+	}
+	var values []string
+	seen := make(map[string]bool)
+	for it.Next() {
+		item := it.Item()
+		if item.Typ != itemText || !isEnumName(item.Val) {
+			return nil, item.Errorf("Invalid value %s for @enum on predicate [%s], expected"+
+				" a name made of letters, digits and underscores", item.Val, predicate)
+		}
+		if seen[item.Val] {
+			return nil, item.Errorf("Duplicate value %s for @enum on predicate [%s]",
+				item.Val, predicate)
+		}
+		seen[item.Val] = true
+		values = append(values, item.Val)
+		if !it.Next() {
+			break
+		}
+		switch sep := it.Item(); sep.Typ {
+		case itemRightRound:
+			return values, nil
+		case itemComma:
+		default:
+			return nil, sep.Errorf("Expected a comma or a right round bracket in @enum on"+
+				" predicate [%s] but got: %v", predicate, sep.Val)
+		}
+	}
+	return nil, it.Item().Errorf("Unexpected end of @enum on predicate [%s]", predicate)
+}
+
+// isEnumName returns whether the value is a valid name for a value of an enum, which is the same
+// as for GraphQL enum values.
+func isEnumName(val string) bool {
+	for i, r := range val {
+		switch {
+		case r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z'):
+		case i > 0 && r >= '0' && r <= '9':
+		default:
+			return false
+		}
+	}
+	return len(val) > 0 && val != "true" && val != "false" && val != "null"
+}
+
+// parseReferencesDirective parses the optional argument of @references, which is what's done to
+// the edges pointing to a node when it's deleted, like @references(ondelete: cascade). It
+// defaults to restrict.
+func parseReferencesDirective(it *lex.ItemIterator, predicate string) (string, error) {
+	it.Next()
+	if it.Item().Typ != itemLeftRound {
+		it.Prev()
+		return ReferencesRestrict, nil
+	}
+	if !it.Next() || it.Item().Typ != itemText || it.Item().Val != "ondelete" ||
+		!it.Next() || it.Item().Typ != itemColon || !it.Next() || it.Item().Typ != itemText {
+		return "", it.Item().Errorf("Expected ondelete: restrict, cascade or setnull in"+
+			" @references on predicate [%s]", predicate)
+	}
+	onDelete := it.Item().Val
+	switch onDelete {
+	case ReferencesRestrict, ReferencesCascade, ReferencesSetNull:
+	default:
+		return "", it.Item().Errorf("Invalid ondelete %s for @references on predicate [%s],"+
+			" expected restrict, cascade or setnull", onDelete, predicate)
+	}
+	if !it.Next() || it.Item().Typ != itemRightRound {
+		return "", it.Item().Errorf("Expected ) after the arguments of @references on"+
+			" predicate [%s]. Got %v", predicate, it.Item().Val)
+	}
+	return onDelete, nil
+}
+
+// parseCheckDirective parses the constraints of @check on the values of the predicate, like
+// @check(min: 0, max: 150) or @check(maxlength: 64, pattern: "^[a-z]+$"). Bounds that aren't
+// unsigned integers are quoted, like "-1.5".
+func parseCheckDirective(it *lex.ItemIterator, schema *pb.SchemaUpdate) error {
+	predicate := schema.Predicate
+	if !it.Next() || it.Item().Typ != itemLeftRound {
+		return it.Item().Errorf("Expected the constraints of @check on predicate [%s],"+
+			" like @check(min: 0, max: 150)", predicate)
+	}
+	seen := make(map[string]bool)
+	for it.Next() {
+		item := it.Item()
+		name := item.Val
+		switch {
+		case item.Typ != itemText:
+			return item.Errorf("Expected a constraint in @check on predicate [%s] but got: %v",
+				predicate, item.Val)
+		case name != "min" && name != "max" && name != "maxlength" && name != "pattern":
+			return item.Errorf("Invalid constraint %s for @check on predicate [%s], expected"+
+				" min, max, maxlength or pattern", name, predicate)
+		case seen[name]:
+			return item.Errorf("Duplicate constraint %s for @check on predicate [%s]",
+				name, predicate)
+		}
+		seen[name] = true
+		if !it.Next() || it.Item().Typ != itemColon || !it.Next() {
+			return it.Item().Errorf("Expected a colon after %s in @check on predicate [%s]",
+				name, predicate)
+		}
+		val := it.Item()
+		var value string
+		switch val.Typ {
+		case itemQuotedText:
+			s, err := strconv.Unquote(val.Val)
+			if err != nil || s == "" {
+				return val.Errorf("Invalid value %s for %s in @check on predicate [%s]",
+					val.Val, name, predicate)
+			}
+			value = s
+		case itemNumber:
+			value = val.Val
+		default:
+			return val.Errorf("Expected a quoted string or a number after %s in @check on"+
+				" predicate [%s] but got: %v", name, predicate, val.Val)
+		}
+		switch name {
+		case "min":
+			schema.CheckMin = value
+		case "max":
+			schema.CheckMax = value
+		case "maxlength":
+			n, err := strconv.ParseUint(value, 10, 32)
+			if err != nil || n == 0 {
+				return val.Errorf("Invalid maxlength %s in @check on predicate [%s], expected"+
+					" a positive integer", val.Val, predicate)
+			}
+			schema.CheckMaxLength = uint32(n)
+		case "pattern":
+			schema.CheckPattern = value
+		}
+		if !it.Next() {
+			break
+		}
+		switch sep := it.Item(); sep.Typ {
+		case itemRightRound:
+			return nil
+		case itemComma:
+		default:
+			return sep.Errorf("Expected a comma or a right round bracket in @check on"+
+				" predicate [%s] but got: %v", predicate, sep.Val)
+		}
+	}
+	return it.Item().Errorf("Unexpected end of @check on predicate [%s]", predicate)
+}
+
+// parseEncodingDirective parses the argument of @encoding, which is the name of the encoding of
+// the values, like @encoding(dictionary).
+func parseEncodingDirective(it *lex.ItemIterator, predicate string) (string, error) {
+	if !it.Next() || it.Item().Typ != itemLeftRound || !it.Next() ||
+		it.Item().Typ != itemText {
+		return "", it.Item().Errorf("Expected an encoding for @encoding on predicate [%s],"+
+			" like @encoding(dictionary)", predicate)
+	}
+	encoding := it.Item().Val
+	if encoding != EncodingDictionary && encoding != EncodingDelta {
+		return "", it.Item().Errorf("Invalid encoding %s for @encoding on predicate [%s],"+
+			" expected dictionary or delta", encoding, predicate)
+	}
+	if !it.Next() || it.Item().Typ != itemRightRound {
+		return "", it.Item().Errorf("Expected ) after the encoding of @encoding on predicate"+
+			" [%s]. Got %v", predicate, it.Item().Val)
+	}
+	return encoding, nil
+}
+
+// parseCompressionDirective parses the argument of @compression, which is the name of the
+// algorithm the posting lists are compressed with, like @compression(zstd).
+func parseCompressionDirective(it *lex.ItemIterator, predicate string) (string, error) {
+	if !it.Next() || it.Item().Typ != itemLeftRound || !it.Next() ||
+		it.Item().Typ != itemText {
+		return "", it.Item().Errorf("Expected an algorithm for @compression on predicate [%s],"+
+			" like @compression(zstd)", predicate)
+	}
+	compression := it.Item().Val
+	switch compression {
+	case CompressionSnappy, CompressionZstd, CompressionZstdDict:
+	default:
+		return "", it.Item().Errorf("Invalid algorithm %s for @compression on predicate [%s],"+
+			" expected snappy, zstd or zstd_dict", compression, predicate)
+	}
+	if !it.Next() || it.Item().Typ != itemRightRound {
+		return "", it.Item().Errorf("Expected ) after the algorithm of @compression on"+
+			" predicate [%s]. Got %v", predicate, it.Item().Val)
+	}
+	return compression, nil
+}
+
+// parseWhereDirective parses the condition of a partial index, which is a list of comparisons
+// of the values with constants, like @where(ne: "archived") or @where(ge: 10, lt: 100). The
+// condition is returned in its canonical form, with all the constants quoted.
+func parseWhereDirective(it *lex.ItemIterator, predicate string) (string, error) {
+	if !it.Next() || it.Item().Typ != itemLeftRound {
+		return "", it.Item().Errorf("Expected a condition for @where on predicate [%s],"+
+			" like @where(ne: \"archived\")", predicate)
+	}
+	var ops, values []string
+	for it.Next() {
+		item := it.Item()
+		if item.Typ != itemText || !isIndexConditionOp(item.Val) {
+			return "", item.Errorf("Invalid comparison %s for @where on predicate [%s],"+
+				" expected eq, ne, le, lt, ge or gt", item.Val, predicate)
+		}
+		op := item.Val
+		if !it.Next() || it.Item().Typ != itemColon || !it.Next() {
+			return "", it.Item().Errorf("Expected a colon after %s in @where on predicate [%s]",
+				op, predicate)
+		}
+		val := it.Item()
+		switch val.Typ {
+		case itemQuotedText:
+			s, err := strconv.Unquote(val.Val)
+			if err != nil {
+				return "", val.Errorf("Invalid value %s in @where on predicate [%s]: %v",
+					val.Val, predicate, err)
+			}
+			values = append(values, s)
+		case itemNumber:
+			values = append(values, val.Val)
+		default:
+			return "", val.Errorf("Expected a quoted string or a number after %s in @where on"+
+				" predicate [%s] but got: %v", op, predicate, val.Val)
+		}
+		ops = append(ops, op)
+		if !it.Next() {
+			break
+		}
+		switch sep := it.Item(); sep.Typ {
+		case itemRightRound:
+			return formatIndexCondition(ops, values), nil
+		case itemComma:
+		default:
+			return "", sep.Errorf("Expected a comma or a right round bracket in @where on"+
+				" predicate [%s] but got: %v", predicate, sep.Val)
+		}
+	}
+	return "", it.Item().Errorf("Unexpected end of @where on predicate [%s]", predicate)
+}
+
 // parseIndexDirective works on "@index" or "@index(customtokenizer)".
 func parseIndexDirective(it *lex.ItemIterator, predicate string,
 	typ types.TypeID) ([]string, error) {
 	var tokenizers []string
-	var seen = make(map[string]bool)
+	// Tokenizers are seen by identifier, as the ones that only differ by their options, like
+	// ngram and ngram(min: 1, max: 3), would write to the same index keys.
+	var seen = make(map[byte]bool)
 	var seenSortableTok bool
 
 	if typ == types.UidID || typ == types.DefaultID || typ == types.PasswordID {
@@ -219,6 +885,16 @@ func parseIndexDirective(it *lex.ItemIterator, predicate string,
 		if !has {
 			return tokenizers, next.Errorf("Invalid tokenizer %s", next.Val)
 		}
+		if peek, ok := it.PeekOne(); ok && peek.Typ == itemLeftRound {
+			// Tokenizer options, like day(tz: "Asia/Kolkata") or ngram(min: 2, max: 4).
+			opts, err := parseTokenizerOptions(it)
+			if err != nil {
+				return tokenizers, err
+			}
+			if tokenizer, err = tok.GetTokenizerWithOptions(tokenizer.Name(), opts); err != nil {
+				return tokenizers, next.Errorf("%v", err)
+			}
+		}
 		tokenizerType, ok := types.TypeForName(tokenizer.Type())
 		x.AssertTrue(ok) // Type is validated during tokenizer loading.
 		if tokenizerType != typ {
@@ -226,7 +902,7 @@ func parseIndexDirective(it *lex.ItemIterator, predicate string,
 				next.Errorf("Tokenizer: %s isn't valid for predicate: %s of type: %s",
 					tokenizer.Name(), predicate, typ.Name())
 		}
-		if _, found := seen[tokenizer.Name()]; found {
+		if _, found := seen[tokenizer.Identifier()]; found {
 			return tokenizers, next.Errorf("Duplicate tokenizers defined for pred %v",
 				predicate)
 		}
@@ -238,12 +914,62 @@ func parseIndexDirective(it *lex.ItemIterator, predicate string,
 			seenSortableTok = true
 		}
 		tokenizers = append(tokenizers, tokenizer.Name())
-		seen[tokenizer.Name()] = true
+		seen[tokenizer.Identifier()] = true
 		expectArg = false
 	}
 	return tokenizers, nil
 }
 
+// parseTokenizerOptions parses the options of a tokenizer, which are of the form
+// (name: value, ...) where the values are quoted strings or numbers, like (tz: "Asia/Kolkata").
+func parseTokenizerOptions(it *lex.ItemIterator) (map[string]string, error) {
+	it.Next() // Consume the left round bracket.
+	opts := make(map[string]string)
+	for it.Next() {
+		item := it.Item()
+		if item.Typ != itemText {
+			return nil, item.Errorf("Expected a tokenizer option but got: %v", item.Val)
+		}
+		name := item.Val
+		if _, ok := opts[name]; ok {
+			return nil, item.Errorf("Duplicate tokenizer option %s", name)
+		}
+		if !it.Next() || it.Item().Typ != itemColon {
+			return nil, it.Item().Errorf("Expected a colon after tokenizer option %s", name)
+		}
+		if !it.Next() {
+			break
+		}
+		val := it.Item()
+		switch val.Typ {
+		case itemQuotedText:
+			s, err := strconv.Unquote(val.Val)
+			if err != nil {
+				return nil, val.Errorf("Invalid value %s of tokenizer option %s: %v",
+					val.Val, name, err)
+			}
+			opts[name] = s
+		case itemNumber:
+			opts[name] = val.Val
+		default:
+			return nil, val.Errorf("Expected a quoted string or a number as the value of "+
+				"tokenizer option %s but got: %v", name, val.Val)
+		}
+		if !it.Next() {
+			break
+		}
+		switch sep := it.Item(); sep.Typ {
+		case itemRightRound:
+			return opts, nil
+		case itemComma:
+		default:
+			return nil, sep.Errorf("Expected a comma or a right round bracket but got: %v",
+				sep.Val)
+		}
+	}
+	return nil, it.Item().Errorf("Unexpected end of tokenizer options")
+}
+
 // resolveTokenizers resolves default tokenizers and verifies tokenizers definitions.
 func resolveTokenizers(updates []*pb.SchemaUpdate) error {
 	for _, schema := range updates {
@@ -266,7 +992,7 @@ func resolveTokenizers(updates []*pb.SchemaUpdate) error {
 			return errors.Errorf("Tokenizers present without indexing on attr %s", schema.Predicate)
 		}
 		// check for valid tokeniser types and duplicates
-		var seen = make(map[string]bool)
+		var seen = make(map[byte]bool)
 		var seenSortableTok bool
 		for _, t := range schema.Tokenizer {
 			tokenizer, has := tok.GetTokenizer(t)
@@ -279,8 +1005,8 @@ func resolveTokenizers(updates []*pb.SchemaUpdate) error {
 				return errors.Errorf("Tokenizer: %s isn't valid for predicate: %s of type: %s",
 					tokenizer.Name(), schema.Predicate, typ.Name())
 			}
-			if _, ok := seen[tokenizer.Name()]; !ok {
-				seen[tokenizer.Name()] = true
+			if _, ok := seen[tokenizer.Identifier()]; !ok {
+				seen[tokenizer.Identifier()] = true
 			} else {
 				return errors.Errorf("Duplicate tokenizers present for attr %s", schema.Predicate)
 			}
@@ -309,6 +1035,22 @@ func parseTypeDeclaration(it *lex.ItemIterator) (*pb.TypeUpdate, error) {
 	typeUpdate := &pb.TypeUpdate{TypeName: it.Item().Val}
 
 	it.Next()
+	for it.Item().Typ == itemAt {
+		if !it.Next() || it.Item().Typ != itemText || it.Item().Val != "ttl" {
+			return nil, it.Item().Errorf("Invalid directive %v for type %s, only @ttl is"+
+				" supported", it.Item().Val, typeUpdate.TypeName)
+		}
+		ttl, from, err := parseTTLDirective(it, "type", typeUpdate.TypeName)
+		if err != nil {
+			return nil, err
+		}
+		if from == "" {
+			return nil, it.Item().Errorf("@ttl on type [%s] must name the datetime predicate"+
+				" it expires from, like @ttl(\"24h\", from: createdAt)", typeUpdate.TypeName)
+		}
+		typeUpdate.Ttl, typeUpdate.TtlFrom = ttl, from
+		it.Next()
+	}
 	if it.Item().Typ != itemLeftCurl {
 		return nil, it.Item().Errorf("Expected {. Got %v", it.Item().Val)
 	}
@@ -335,6 +1077,10 @@ func parseTypeDeclaration(it *lex.ItemIterator) (*pb.TypeUpdate, error) {
 
 				fieldSet[field.GetPredicate()] = struct{}{}
 			}
+			if _, ok := fieldSet[typeUpdate.TtlFrom]; typeUpdate.TtlFrom != "" && !ok {
+				return nil, it.Item().Errorf("@ttl on type [%s] expires from [%s], which isn't"+
+					" a field of the type", typeUpdate.TypeName, typeUpdate.TtlFrom)
+			}
 
 			typeUpdate.Fields = fields
 			return typeUpdate, nil
@@ -357,6 +1103,9 @@ func parseTypeField(it *lex.ItemIterator, typeName string) (*pb.SchemaUpdate, er
 	field := &pb.SchemaUpdate{Predicate: it.Item().Val}
 	var list bool
 	it.Next()
+	if err := parseFieldDirectives(it, field, typeName); err != nil {
+		return nil, err
+	}
 
 	// Simplified type definitions only require the field name. If a new line is found,
 	// proceed to the next field in the type.
@@ -396,6 +1145,9 @@ func parseTypeField(it *lex.ItemIterator, typeName string) (*pb.SchemaUpdate, er
 			it.Next()
 		}
 	}
+	if err := parseFieldDirectives(it, field, typeName); err != nil {
+		return nil, err
+	}
 
 	if it.Item().Typ != itemNewLine {
 		return nil, it.Item().Errorf("Expected new line after field declaration. Got %v",
@@ -407,6 +1159,24 @@ func parseTypeField(it *lex.ItemIterator, typeName string) (*pb.SchemaUpdate, er
 	return field, nil
 }
 
+// parseFieldDirectives parses the directives of a field of a type. Only @required is supported,
+// which makes the mutations giving the type to a node without a value for the field fail.
+func parseFieldDirectives(it *lex.ItemIterator, field *pb.SchemaUpdate, typeName string) error {
+	for it.Item().Typ == itemAt {
+		if !it.Next() || it.Item().Typ != itemText || it.Item().Val != "required" {
+			return it.Item().Errorf("Invalid directive %v for field %s of type %s, only"+
+				" @required is supported", it.Item().Val, field.Predicate, typeName)
+		}
+		if strings.HasPrefix(field.Predicate, "~") {
+			return it.Item().Errorf("@required isn't supported for the reverse field %s of"+
+				" type %s", field.Predicate, typeName)
+		}
+		field.NonNullable = true
+		it.Next()
+	}
+	return nil
+}
+
 // ParsedSchema represents the parsed schema and type updates.
 type ParsedSchema struct {
 	Preds []*pb.SchemaUpdate
@@ -426,7 +1196,7 @@ func isTypeDeclaration(item lex.Item, it *lex.ItemIterator) bool {
 	case nextItems[0].Typ != itemText:
 		return false
 
-	case nextItems[1].Typ != itemLeftCurl:
+	case nextItems[1].Typ != itemLeftCurl && nextItems[1].Typ != itemAt:
 		return false
 	}
 
@@ -451,6 +1221,9 @@ func Parse(s string) (*ParsedSchema, error) {
 			if err := resolveTokenizers(result.Preds); err != nil {
 				return nil, errors.Wrapf(err, "failed to enrich schema")
 			}
+			if err := checkTTLs(&result); err != nil {
+				return nil, err
+			}
 			return &result, nil
 
 		case itemText:
@@ -18,6 +18,7 @@ package schema
 
 import (
 	"context"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"testing"
@@ -197,6 +198,138 @@ func TestSchemaIndexCustom(t *testing.T) {
 	require.Equal(t, "int", State().Tokenizer(context.Background(), "age")[0].Name())
 }
 
+func TestParseIndexTimeZone(t *testing.T) {
+	reset()
+	result, err := Parse(`created: datetime @index(hour(tz: "Asia/Kolkata")) .`)
+	require.NoError(t, err)
+	require.Equal(t, []string{`hour(tz: "Asia/Kolkata")`}, result.Preds[0].Tokenizer)
+
+	// The name of the tokenizer can be parsed back.
+	result, err = Parse(fmt.Sprintf("created: datetime @index(%s) .",
+		result.Preds[0].Tokenizer[0]))
+	require.NoError(t, err)
+	require.Equal(t, []string{`hour(tz: "Asia/Kolkata")`}, result.Preds[0].Tokenizer)
+	require.NoError(t, resolveTokenizers(result.Preds))
+}
+
+func TestParseIndexTimeZoneError(t *testing.T) {
+	reset()
+	_, err := Parse(`name: string @index(exact(tz: "Asia/Kolkata")) .`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Tokenizer exact doesn't support the tz option")
+
+	_, err = Parse(`created: datetime @index(day(tz: "Mars/Olympus_Mons")) .`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "while loading time zone")
+
+	_, err = Parse(`created: datetime @index(day(zone: "Asia/Kolkata")) .`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Expected tokenizer options of the form")
+
+	_, err = Parse(`created: datetime @index(day(tz: "Asia/Kolkata)) .`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Unterminated quoted string")
+}
+
+func TestParseIndexNgram(t *testing.T) {
+	reset()
+	result, err := Parse(`name: string @index(term, ngram(min: 2, max: 10)) .`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"term", "ngram(min: 2, max: 10)"}, result.Preds[0].Tokenizer)
+	require.NoError(t, resolveTokenizers(result.Preds))
+
+	_, err = Parse(`name: string @index(ngram(min: 2 max: 10)) .`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Expected a comma or a right round bracket")
+
+	_, err = Parse(`name: string @index(ngram(min: 2, min: 3)) .`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Duplicate tokenizer option min")
+
+	_, err = Parse(`name: string @index(ngram, ngram(min: 1, max: 3)) .`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Duplicate tokenizers defined for pred name")
+
+	reset()
+	result, err = Parse(`name: string @index(ngram, edgengram(min: 1, max: 8)) .`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"ngram", "edgengram(min: 1, max: 8)"}, result.Preds[0].Tokenizer)
+}
+
+func TestParseIndexPhonetic(t *testing.T) {
+	reset()
+	result, err := Parse(`name: string @index(soundex, metaphone) .`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"soundex", "metaphone"}, result.Preds[0].Tokenizer)
+
+	_, err = Parse(`age: int @index(soundex) .`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Tokenizer: soundex isn't valid for predicate: age of type: int")
+}
+
+func TestParseIndexCollation(t *testing.T) {
+	reset()
+	result, err := Parse(`name: string @index(exact(collation: "sv"), term) .`)
+	require.NoError(t, err)
+	require.Equal(t, []string{`exact(collation: "sv")`, "term"}, result.Preds[0].Tokenizer)
+	require.NoError(t, resolveTokenizers(result.Preds))
+
+	_, err = Parse(`name: string @index(exact, exact(collation: "sv")) .`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "More than one sortable index encountered for: name")
+
+	_, err = Parse(`name: string @index(exact(collation: "no locale")) .`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `Invalid collation "no locale" for tokenizer exact`)
+}
+
+func TestParseIndexGeoLevels(t *testing.T) {
+	reset()
+	result, err := Parse(`loc: geo @index(geo(minlevel: 8, maxlevel: 20, maxcells: 30)) .`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"geo(minlevel: 8, maxlevel: 20, maxcells: 30)"},
+		result.Preds[0].Tokenizer)
+	require.NoError(t, resolveTokenizers(result.Preds))
+
+	_, err = Parse(`loc: geo @index(geo, geo(minlevel: 8, maxlevel: 20, maxcells: 30)) .`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Duplicate tokenizers defined for pred loc")
+
+	_, err = Parse(`loc: geo @index(geo(minlevel: 20, maxlevel: 8, maxcells: 30)) .`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "0 <= minlevel <= maxlevel <= 30")
+}
+
+func TestParseIndexCasefold(t *testing.T) {
+	reset()
+	result, err := Parse(`name: string @index(term(normalize: "nfkc_casefold"), ` +
+		`fulltext(normalize: "nfkc_casefold"), exact) .`)
+	require.NoError(t, err)
+	require.Equal(t, []string{`term(normalize: "nfkc_casefold")`,
+		`fulltext(normalize: "nfkc_casefold")`, "exact"}, result.Preds[0].Tokenizer)
+	require.NoError(t, resolveTokenizers(result.Preds))
+
+	_, err = Parse(`name: string @index(term, term(normalize: "nfkc_casefold")) .`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Duplicate tokenizers defined for pred name")
+
+	_, err = Parse(`name: string @index(fulltext(normalize: "nfd")) .`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `Invalid normalization "nfd" for tokenizer fulltext`)
+}
+
+func TestParseDecimal(t *testing.T) {
+	reset()
+	result, err := Parse(`price: decimal @index(decimal) .`)
+	require.NoError(t, err)
+	require.Equal(t, pb.Posting_DECIMAL, result.Preds[0].ValueType)
+	require.Equal(t, []string{"decimal"}, result.Preds[0].Tokenizer)
+	require.NoError(t, resolveTokenizers(result.Preds))
+
+	_, err = Parse(`price: decimal @index(float) .`)
+	require.Error(t, err)
+}
+
 func TestParse(t *testing.T) {
 	reset()
 	_, err := Parse("age:int @index . name:string")
@@ -368,6 +501,281 @@ func TestParseUpsert(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestParseUnique(t *testing.T) {
+	reset()
+	result, err := Parse(`
+		email    : string @index(hash) @unique .
+		username : string @index(exact, term) @unique .
+		ssn      : int @index(int) @unique .
+	`)
+	require.NoError(t, err)
+	for _, update := range result.Preds {
+		require.True(t, update.Unique, update.Predicate)
+	}
+}
+
+func TestParseUniqueErrors(t *testing.T) {
+	tests := []struct {
+		schema string
+		err    string
+	}{
+		{"email: string @unique .", "requires an exact, hash, int or bool index"},
+		{"email: string @index(term) @unique .", "requires an exact, hash, int or bool index"},
+		{"emails: [string] @index(exact) @unique .", "@unique isn't supported for lists"},
+		{"name: string @index(exact) @lang @unique .", "@unique isn't supported for lists"},
+		{"friend: uid @unique .", "isn't supported for predicate [friend] of type [uid]"},
+		{"email: string @index(exact) @unique @noconflict .", "can't be used together"},
+	}
+	for _, test := range tests {
+		reset()
+		_, err := Parse(test.schema)
+		require.Error(t, err, test.schema)
+		require.Contains(t, err.Error(), test.err, test.schema)
+	}
+}
+
+func TestParseTTL(t *testing.T) {
+	reset()
+	result, err := Parse(`
+		createdAt : datetime @index(hour) .
+		lastSeen  : datetime @index(day) @ttl("720h") .
+		token     : string @ttl("90m", from: createdAt) .
+		type Session @ttl("24h", from: createdAt) {
+			token
+			createdAt
+		}
+	`)
+	require.NoError(t, err)
+	require.Equal(t, "", result.Preds[0].Ttl)
+	require.Equal(t, "720h0m0s", result.Preds[1].Ttl)
+	require.Equal(t, "lastSeen", result.Preds[1].TtlFrom)
+	require.Equal(t, "1h30m0s", result.Preds[2].Ttl)
+	require.Equal(t, "createdAt", result.Preds[2].TtlFrom)
+	require.Equal(t, "Session", result.Types[0].TypeName)
+	require.Equal(t, "24h0m0s", result.Types[0].Ttl)
+	require.Equal(t, "createdAt", result.Types[0].TtlFrom)
+	require.Len(t, result.Types[0].Fields, 2)
+
+	// The form written by exports.
+	reset()
+	result, err = Parse("<token>:string @ttl(\"1h0m0s\", from: <createdAt>) . \n" +
+		"type <Session> @ttl(\"24h0m0s\", from: <createdAt>) {\n\tcreatedAt\n}\n")
+	require.NoError(t, err)
+	require.Equal(t, "createdAt", result.Preds[0].TtlFrom)
+	require.Equal(t, "24h0m0s", result.Types[0].Ttl)
+}
+
+func TestParseTTLErrors(t *testing.T) {
+	tests := []struct {
+		schema string
+		err    string
+	}{
+		{`token: string @ttl("1h") .`, "must name the datetime predicate it expires from"},
+		{`token: string @ttl(1h) .`, "Expected a quoted duration for @ttl"},
+		{`token: string @ttl .`, "Expected a quoted duration for @ttl"},
+		{`token: string @ttl("1 hour", from: createdAt) .`, "Invalid duration"},
+		{`token: string @ttl("10ms", from: createdAt) .`, "Invalid duration"},
+		{`token: string @ttl("1h", since: createdAt) .`, "Expected from: <predicate>"},
+		{`token: string @ttl("1h", from: createdAt, x) .`, "Expected ) after the arguments"},
+		{`lastSeen: datetime @ttl("1h") .`, "must be a datetime predicate with an index"},
+		{"createdAt: string @index(exact) .\ntoken: string @ttl(\"1h\", from: createdAt) .",
+			"must be a datetime predicate with an index"},
+		{"type Session @ttl(\"1h\") {\n createdAt\n}", "must name the datetime predicate"},
+		{"type Session @ttl(\"1h\", from: expiresAt) {\n createdAt\n}",
+			"which isn't a field of the type"},
+		{"type Session @upsert {\n createdAt\n}", "only @ttl is supported"},
+	}
+	for _, test := range tests {
+		reset()
+		_, err := Parse(test.schema)
+		require.Error(t, err, test.schema)
+		require.Contains(t, err.Error(), test.err, test.schema)
+	}
+}
+
+func TestParseDefault(t *testing.T) {
+	reset()
+	result, err := Parse(`
+		status  : string @index(exact) @default("active") .
+		score   : int @default("0", mode: virtual) .
+		verified: bool @default("false", mode: materialize) .
+		joined  : datetime @default("2020-01-01T00:00:00Z") .
+	`)
+	require.NoError(t, err)
+	require.Equal(t, "active", result.Preds[0].DefaultValue)
+	require.False(t, result.Preds[0].DefaultVirtual)
+	require.Equal(t, []string{"exact"}, result.Preds[0].Tokenizer)
+	require.Equal(t, "0", result.Preds[1].DefaultValue)
+	require.True(t, result.Preds[1].DefaultVirtual)
+	require.Equal(t, "false", result.Preds[2].DefaultValue)
+	require.False(t, result.Preds[2].DefaultVirtual)
+
+	val, err := DefaultValue(result.Preds[1])
+	require.NoError(t, err)
+	require.Equal(t, types.BinaryID, val.Tid)
+	require.Equal(t, []byte{0, 0, 0, 0, 0, 0, 0, 0}, val.Value)
+
+	// The form written by exports.
+	reset()
+	result, err = Parse("<note>:string @default(\"say \\\"hi\\\"\", mode: virtual) . \n")
+	require.NoError(t, err)
+	require.Equal(t, `say "hi"`, result.Preds[0].DefaultValue)
+	require.True(t, result.Preds[0].DefaultVirtual)
+}
+
+func TestParseDefaultErrors(t *testing.T) {
+	tests := []struct {
+		schema string
+		err    string
+	}{
+		{`score: int @default(0) .`, "Expected a quoted value for @default"},
+		{`score: int @default .`, "Expected a quoted value for @default"},
+		{`score: int @default("") .`, "can't be empty"},
+		{`score: int @default("ten") .`, "Invalid default value"},
+		{`score: int @default("0", mode: lazy) .`, "Invalid mode lazy"},
+		{`score: int @default("0", virtual) .`, "Expected mode: materialize or mode: virtual"},
+		{`score: int @default("0", mode: virtual, x) .`, "Expected ) after the arguments"},
+		{`friend: uid @default("0x1") .`, "isn't supported for predicate [friend] of type [uid]"},
+		{`name: string @lang @default("x") .`, "@default isn't supported with @lang"},
+		{`email: string @index(exact) @unique @default("x") .`, "@default isn't supported"},
+	}
+	for _, test := range tests {
+		reset()
+		_, err := Parse(test.schema)
+		require.Error(t, err, test.schema)
+		require.Contains(t, err.Error(), test.err, test.schema)
+	}
+}
+
+func TestParseCompositeIndex(t *testing.T) {
+	reset()
+	result, err := Parse(`
+		country: string @index(exact) .
+		city: string @index(exact) .
+		<country^city>: string @index(exact, hash) .
+		<country^born>: datetime @index(year) @upsert .
+	`)
+	require.NoError(t, err)
+	require.Equal(t, "country^city", result.Preds[2].Predicate)
+	require.Equal(t, []string{"exact", "hash"}, result.Preds[2].Tokenizer)
+	require.Equal(t, []string{"country", "city"},
+		x.CompositeIndexPredicates(result.Preds[2].Predicate))
+	require.Equal(t, "country^born", result.Preds[3].Predicate)
+}
+
+func TestParseCompositeIndexErrors(t *testing.T) {
+	tests := []struct {
+		schema string
+		err    string
+	}{
+		{`<country^>: string @index(exact) .`, "Invalid name for composite index"},
+		{`<country^country>: string @index(exact) .`, "Invalid name for composite index"},
+		{`<country^friend|since>: string @index(exact) .`, "Invalid name for composite index"},
+		{`<country^dgraph.type>: string @index(exact) .`, "Invalid name for composite index"},
+		{`<country^city>: string .`, "requires an index"},
+		{`<country^city>: string @index(term) .`, "Tokenizer [term] isn't supported"},
+		{`<country^friend>: uid .`, "Type [uid] isn't supported"},
+		{`<country^city>: [string] @index(exact) .`, "Lists, @count and @lang aren't supported"},
+		{`<country^city>: string @index(exact) @lang .`, "Lists, @count and @lang"},
+		{`<country^city>: string @index(exact) @unique .`, "@unique, @default and @ttl"},
+	}
+	for _, test := range tests {
+		reset()
+		_, err := Parse(test.schema)
+		require.Error(t, err, test.schema)
+		require.Contains(t, err.Error(), test.err, test.schema)
+	}
+}
+
+func TestParseIndexWhere(t *testing.T) {
+	reset()
+	result, err := Parse(`
+		status: string @index(exact) @where(ne: "archived") .
+		score: int @where(ge: 10, lt: "100") @index(int) .
+	`)
+	require.NoError(t, err)
+	require.Equal(t, `ne: "archived"`, result.Preds[0].IndexWhere)
+	require.Equal(t, `ge: "10", lt: "100"`, result.Preds[1].IndexWhere)
+}
+
+func TestParseIndexWhereErrors(t *testing.T) {
+	tests := []struct {
+		schema string
+		err    string
+	}{
+		{`status: string @where(ne: "archived") .`, "requires an index"},
+		{`status: string @index(exact) @where .`, "Expected a condition for @where"},
+		{`status: string @index(exact) @where(is: "archived") .`, "Invalid comparison is"},
+		{`status: string @index(exact) @where(ne "archived") .`, "Expected a colon after ne"},
+		{`status: string @index(exact) @where(ne: archived) .`, "Expected a quoted string"},
+		{`status: string @index(exact) @where(ne: "archived" .`, "Expected a comma"},
+		{`score: int @index(int) @where(ge: "ten") .`, "Invalid constant"},
+		{`done: bool @index(bool) @where(gt: "false") .`, "Comparison gt isn't supported"},
+		{`loc: geo @index(geo) @where(ne: "x") .`, "@where isn't supported for predicate [loc]"},
+		{`email: string @index(exact) @unique @where(ne: "") .`, "isn't supported with @unique"},
+		{`<a^b>: string @index(exact) @where(ne: "") .`, "isn't supported with @unique or for"},
+		{`expires: datetime @index(hour) @where(gt: "2020-01-01")  .
+		  token: string @ttl("1h", from: expires) .`, "index without @where"},
+	}
+	for _, test := range tests {
+		reset()
+		_, err := Parse(test.schema)
+		require.Error(t, err, test.schema)
+		require.Contains(t, err.Error(), test.err, test.schema)
+	}
+}
+
+func TestIndexCondition(t *testing.T) {
+	cond, err := ParseIndexCondition(`ge: "10", lt: "100", ne: "50"`, types.IntID)
+	require.NoError(t, err)
+	val := func(v int64) types.Val {
+		return types.Val{Tid: types.IntID, Value: v}
+	}
+	require.True(t, cond.Holds(val(10)))
+	require.True(t, cond.Holds(val(99)))
+	require.False(t, cond.Holds(val(9)))
+	require.False(t, cond.Holds(val(50)))
+	require.False(t, cond.Holds(val(100)))
+
+	tests := []struct {
+		fname   string
+		args    []types.Val
+		covered bool
+	}{
+		{"eq", []types.Val{val(10), val(20)}, true},
+		{"eq", []types.Val{val(10), val(50)}, false},
+		{"between", []types.Val{val(10), val(49)}, true},
+		{"between", []types.Val{val(51), val(99)}, true},
+		{"between", []types.Val{val(40), val(60)}, false},
+		{"between", []types.Val{val(5), val(20)}, false},
+		{"ge", []types.Val{val(60)}, false},
+		{"le", []types.Val{val(40)}, false},
+	}
+	for _, test := range tests {
+		require.Equal(t, test.covered, cond.Covers(test.fname, test.args), "%s%v",
+			test.fname, test.args)
+	}
+
+	cond, err = ParseIndexCondition(`gt: "10"`, types.IntID)
+	require.NoError(t, err)
+	require.True(t, cond.Covers("gt", []types.Val{val(10)}))
+	require.True(t, cond.Covers("ge", []types.Val{val(11)}))
+	require.False(t, cond.Covers("ge", []types.Val{val(10)}))
+	require.False(t, cond.Covers("lt", []types.Val{val(20)}))
+
+	cond, err = ParseIndexCondition(`ne: "archived"`, types.StringID)
+	require.NoError(t, err)
+	str := func(s string) types.Val {
+		return types.Val{Tid: types.StringID, Value: s}
+	}
+	require.True(t, cond.Covers("eq", []types.Val{str("active")}))
+	require.False(t, cond.Covers("eq", []types.Val{str("archived")}))
+	require.True(t, cond.Covers("gt", []types.Val{str("archived")}))
+	require.True(t, cond.Covers("lt", []types.Val{str("archived")}))
+	require.False(t, cond.Covers("le", []types.Val{str("archived")}))
+	require.True(t, cond.Covers("between", []types.Val{str("b"), str("z")}))
+}
+
 func TestParseEmptyType(t *testing.T) {
 	reset()
 	result, err := Parse(`
@@ -482,6 +890,40 @@ func TestParseTypeDuplicateFields(t *testing.T) {
 	require.Contains(t, err.Error(), "Duplicate fields with name: name")
 }
 
+func TestParseTypeRequiredFields(t *testing.T) {
+	reset()
+	result, err := Parse(`
+		type Person {
+			name @required
+			email: string! @required
+			<~friend>
+			age
+		}
+	`)
+	require.NoError(t, err)
+	require.Equal(t, []*pb.SchemaUpdate{
+		{Predicate: "name", NonNullable: true},
+		{Predicate: "email", NonNullable: true},
+		{Predicate: "~friend"},
+		{Predicate: "age"},
+	}, result.Types[0].Fields)
+
+	tests := []struct {
+		schema string
+		err    string
+	}{
+		{"type Person {\n name @index\n}", "only @required is supported"},
+		{"type Person {\n <~friend> @required\n}", "isn't supported for the reverse field"},
+		{"type Person {\n name @required age\n}", "Missing colon in type declaration"},
+	}
+	for _, test := range tests {
+		reset()
+		_, err := Parse(test.schema)
+		require.Error(t, err, test.schema)
+		require.Contains(t, err.Error(), test.err, test.schema)
+	}
+}
+
 func TestOldTypeFormat(t *testing.T) {
 	reset()
 	result, err := Parse(`
@@ -601,8 +1043,262 @@ func TestParseTypeComments(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestParseEdgeProperty(t *testing.T) {
+	reset()
+	result, err := Parse(`
+		friend: [uid] .
+		<friend|since>: datetime @index(year) .
+		<friend|close>: [bool] .
+	`)
+	require.NoError(t, err)
+	require.Len(t, result.Preds, 3)
+	require.Equal(t, &pb.SchemaUpdate{
+		Predicate: "friend|since",
+		ValueType: pb.Posting_DATETIME,
+		Directive: pb.SchemaUpdate_INDEX,
+		Tokenizer: []string{"year"},
+		List:      true,
+	}, result.Preds[1])
+	require.True(t, result.Preds[2].List)
+}
+
+func TestParseEdgePropertyErrors(t *testing.T) {
+	tests := []struct {
+		schema string
+		err    string
+	}{
+		{"<friend|since>: [uid] .", "Type [uid] isn't supported for edge property [friend|since]"},
+		{"<friend|loc>: geo .", "Type [geo] isn't supported for edge property [friend|loc]"},
+		{"<friend|note>: string @lang .", "@count and @lang aren't supported"},
+		{"<friend|>: string .", "Invalid name for edge property: [friend|]"},
+		{"<friend|a|b>: string .", "Invalid name for edge property: [friend|a|b]"},
+	}
+	for _, test := range tests {
+		reset()
+		_, err := Parse(test.schema)
+		require.Error(t, err, test.schema)
+		require.Contains(t, err.Error(), test.err, test.schema)
+	}
+}
+
 var ps *badger.DB
 
+func TestParseEncoding(t *testing.T) {
+	reset()
+	result, err := Parse(`
+		color: string @index(exact) @encoding(dictionary) .
+		seq: int @encoding(delta) .
+		at: [datetime] @encoding(delta) .
+	`)
+	require.NoError(t, err)
+	require.Equal(t, EncodingDictionary, result.Preds[0].Encoding)
+	require.Equal(t, []string{"exact"}, result.Preds[0].Tokenizer)
+	require.Equal(t, EncodingDelta, result.Preds[1].Encoding)
+	require.Equal(t, EncodingDelta, result.Preds[2].Encoding)
+}
+
+func TestParseEncodingErrors(t *testing.T) {
+	tests := []struct {
+		schema string
+		err    string
+	}{
+		{`color: string @encoding .`, "Expected an encoding for @encoding"},
+		{`color: string @encoding(zstd) .`, "Invalid encoding zstd"},
+		{`color: string @encoding(dictionary, delta) .`, "Expected ) after the encoding"},
+		{`color: string @encoding(delta) .`,
+			"@encoding(delta) isn't supported for predicate [color] of type [string]"},
+		{`seq: int @encoding(dictionary) .`,
+			"@encoding(dictionary) isn't supported for predicate [seq] of type [int]"},
+		{`<a^b>: string @index(exact) @encoding(dictionary) .`,
+			"@encoding isn't supported for edge properties or composite indexes"},
+	}
+	for _, test := range tests {
+		reset()
+		_, err := Parse(test.schema)
+		require.Error(t, err, test.schema)
+		require.Contains(t, err.Error(), test.err, test.schema)
+	}
+}
+
+func TestEncodingKeptAcrossChanges(t *testing.T) {
+	reset()
+	State().Set("color", &pb.SchemaUpdate{Predicate: "color", Encoding: EncodingDictionary})
+	su := State().ExtendEncoding("color", []string{"red", "blue", "red"}, 0, false)
+	require.Equal(t, []string{"red", "blue"}, su.Dictionary)
+	require.Nil(t, State().ExtendEncoding("color", []string{"blue"}, 0, false))
+
+	// A change of the schema keeps the dictionary, even without the encoding.
+	State().Set("color", &pb.SchemaUpdate{Predicate: "color"})
+	id, ok := State().DictionaryID("color", "blue")
+	require.True(t, ok)
+	require.Equal(t, uint64(1), id)
+	cur, _ := State().Get(context.Background(), "color")
+	require.Equal(t, []string{"red", "blue"}, cur.Dictionary)
+
+	defer func(size int) { maxDictionarySize = size }(maxDictionarySize)
+	maxDictionarySize = 3
+	su = State().ExtendEncoding("color", []string{"green", "white"}, 0, false)
+	require.Equal(t, []string{"red", "blue", "green"}, su.Dictionary)
+	_, ok = State().DictionaryID("color", "white")
+	require.False(t, ok)
+}
+
+func TestCompressionDictionariesKept(t *testing.T) {
+	reset()
+	State().Set("name", &pb.SchemaUpdate{Predicate: "name", Compression: CompressionZstdDict})
+	su := State().AddCompressionDictionary("name", []byte("alice bob"))
+	require.Equal(t, [][]byte{[]byte("alice bob")}, su.CompressionDictionaries)
+	require.Nil(t, State().AddCompressionDictionary("name", []byte("alice bob")))
+	require.Nil(t, State().AddCompressionDictionary("missing", []byte("alice bob")))
+
+	// The lists compressed with them are still read after the compression is changed.
+	State().Set("name", &pb.SchemaUpdate{Predicate: "name"})
+	require.Equal(t, [][]byte{[]byte("alice bob")}, State().CompressionDictionaries("name"))
+
+	defer func(n int) { maxCompressionDictionaries = n }(maxCompressionDictionaries)
+	maxCompressionDictionaries = 1
+	require.Nil(t, State().AddCompressionDictionary("name", []byte("carol")))
+}
+
+func TestParseEnum(t *testing.T) {
+	reset()
+	result, err := Parse(`
+		state: string @index(hash) @enum(ACTIVE, PENDING, on_hold) @default("PENDING") .
+		tags: [string] @enum(A) .
+	`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"ACTIVE", "PENDING", "on_hold"}, result.Preds[0].EnumValues)
+	require.Equal(t, "PENDING", result.Preds[0].DefaultValue)
+	require.Equal(t, EncodingDictionary, ValueEncoding(result.Preds[0]))
+	require.True(t, IsEnumValue(result.Preds[0], "ACTIVE"))
+	require.False(t, IsEnumValue(result.Preds[0], "active"))
+	require.Equal(t, []string{"A"}, result.Preds[1].EnumValues)
+	require.True(t, result.Preds[1].List)
+}
+
+func TestParseEnumErrors(t *testing.T) {
+	tests := []struct {
+		schema string
+		err    string
+	}{
+		{`state: string @enum .`, "Expected the values of @enum"},
+		{`state: string @enum() .`, "Invalid value ) for @enum"},
+		{`state: string @enum(true) .`, "Invalid value true for @enum"},
+		{`state: string @enum(A, A) .`, "Duplicate value A for @enum"},
+		{`state: string @enum(A B) .`, "Expected a comma or a right round bracket in @enum"},
+		{`state: int @enum(A) .`, "@enum isn't supported for predicate [state] of type [int]"},
+		{`state: string @lang @enum(A) .`, "@enum isn't supported with @lang"},
+		{`state: string @enum(A) @default("B") .`,
+			`The default value "B" of predicate [state] isn't one of the values of its enum`},
+	}
+	for _, test := range tests {
+		reset()
+		_, err := Parse(test.schema)
+		require.Error(t, err, test.schema)
+		require.Contains(t, err.Error(), test.err, test.schema)
+	}
+}
+
+func TestParseCheck(t *testing.T) {
+	reset()
+	result, err := Parse(`
+		age: int @index(int) @check(min: 0, max: 150) .
+		temp: float @check(min: "-273.15") .
+		handle: string @check(maxlength: 15, pattern: "^[a-z0-9_]+$") @default("anon") .
+	`)
+	require.NoError(t, err)
+	require.Equal(t, "0", result.Preds[0].CheckMin)
+	require.Equal(t, "150", result.Preds[0].CheckMax)
+	require.Equal(t, `min: "-273.15"`, FormatValueCheck(result.Preds[1]))
+	require.Equal(t, uint32(15), result.Preds[2].CheckMaxLength)
+	require.Equal(t, `maxlength: 15, pattern: "^[a-z0-9_]+$"`, FormatValueCheck(result.Preds[2]))
+
+	tests := []struct {
+		su         *pb.SchemaUpdate
+		value      string
+		constraint string
+	}{
+		{result.Preds[0], "150", ""},
+		{result.Preds[0], "-1", "min: 0"},
+		{result.Preds[0], "151", "max: 150"},
+		{result.Preds[1], "-300", `min: "-273.15"`},
+		{result.Preds[2], "héllo", "pattern: \"^[a-z0-9_]+$\""},
+		{result.Preds[2], "abcdefghijklmnop", "maxlength: 15"},
+		{result.Preds[2], "alice_99", ""},
+	}
+	for _, test := range tests {
+		src := types.Val{Tid: types.StringID, Value: []byte(test.value)}
+		constraint, err := ValueCheckViolation(test.su, src)
+		require.NoError(t, err)
+		require.Equal(t, test.constraint, constraint, test.value)
+	}
+}
+
+func TestParseCheckErrors(t *testing.T) {
+	tests := []struct {
+		schema string
+		err    string
+	}{
+		{`age: int @check .`, "Expected the constraints of @check"},
+		{`age: int @check(least: 0) .`, "Invalid constraint least for @check"},
+		{`age: int @check(min: 0, min: 1) .`, "Duplicate constraint min for @check"},
+		{`age: int @check(min 0) .`, "Expected a colon after min in @check"},
+		{`age: int @check(min: 0 max: 1) .`, "Expected a comma or a right round bracket"},
+		{`age: int @check(min: "x") .`, `Invalid bound "x" of @check on predicate [age]`},
+		{`age: int @check(min: 5, max: 1) .`, "The min of @check on predicate [age] is greater"},
+		{`name: string @check(min: 0) .`,
+			"min and max of @check aren't supported for predicate [name] of type [string]"},
+		{`age: int @check(maxlength: 3) .`,
+			"maxlength and pattern of @check aren't supported for predicate [age] of type [int]"},
+		{`name: string @check(maxlength: 0) .`, "Invalid maxlength 0 in @check"},
+		{`name: string @check(pattern: "[a-") .`, "Invalid pattern of @check on predicate"},
+		{`age: int @check(max: 10) @default("11") .`,
+			`The default value "11" of predicate [age] doesn't satisfy the max: 10 constraint`},
+	}
+	for _, test := range tests {
+		reset()
+		_, err := Parse(test.schema)
+		require.Error(t, err, test.schema)
+		require.Contains(t, err.Error(), test.err, test.schema)
+	}
+}
+
+func TestParseReferences(t *testing.T) {
+	reset()
+	result, err := Parse(`
+		owner: uid @reverse @references .
+		items: [uid] @reverse @count @references(ondelete: cascade) .
+		manager: uid @references(ondelete: setnull) @reverse .
+	`)
+	require.NoError(t, err)
+	require.Equal(t, ReferencesRestrict, result.Preds[0].References)
+	require.Equal(t, ReferencesCascade, result.Preds[1].References)
+	require.True(t, result.Preds[1].Count)
+	require.Equal(t, ReferencesSetNull, result.Preds[2].References)
+	require.Equal(t, pb.SchemaUpdate_REVERSE, result.Preds[2].Directive)
+}
+
+func TestParseReferencesErrors(t *testing.T) {
+	tests := []struct {
+		schema string
+		err    string
+	}{
+		{`owner: uid @reverse @references(cascade) .`, "Expected ondelete: restrict, cascade"},
+		{`owner: uid @reverse @references(ondelete: drop) .`,
+			"Invalid ondelete drop for @references"},
+		{`owner: uid @reverse @references(ondelete: cascade .`, "Expected ) after the arguments"},
+		{`owner: string @references .`,
+			"@references isn't supported for predicate [owner] of type [string]"},
+		{`owner: uid @references .`, "@references on predicate [owner] requires @reverse"},
+	}
+	for _, test := range tests {
+		reset()
+		_, err := Parse(test.schema)
+		require.Error(t, err, test.schema)
+		require.Contains(t, err.Error(), test.err, test.schema)
+	}
+}
+
 func TestMain(m *testing.M) {
 	x.Init()
 
@@ -619,3 +1315,29 @@ func TestMain(m *testing.M) {
 	os.RemoveAll(dir)
 	os.Exit(r)
 }
+
+func TestParseCompression(t *testing.T) {
+	reset()
+	result, err := Parse(`
+		body: string @compression(zstd) .
+		flag: bool @compression(snappy) .
+		title: string @compression(zstd_dict) .
+		name: string .
+	`)
+	require.NoError(t, err)
+	require.Equal(t, CompressionZstd, result.Preds[0].Compression)
+	require.Equal(t, CompressionSnappy, result.Preds[1].Compression)
+	require.Equal(t, CompressionZstdDict, result.Preds[2].Compression)
+	require.Empty(t, result.Preds[3].Compression)
+
+	for schema, msg := range map[string]string{
+		`body: string @compression .`:            "Expected an algorithm for @compression",
+		`body: string @compression(lz4) .`:       "Invalid algorithm lz4",
+		`body: string @compression(zstd, lz4) .`: "Expected ) after the algorithm",
+	} {
+		reset()
+		_, err := Parse(schema)
+		require.Error(t, err, schema)
+		require.Contains(t, err.Error(), msg, schema)
+	}
+}
@@ -0,0 +1,34 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package schema
+
+const (
+	// ReferencesRestrict rejects the deletion of a node while edges of the predicate point to it.
+	ReferencesRestrict = "restrict"
+	// ReferencesCascade deletes the nodes whose edges of the predicate point to a deleted node.
+	ReferencesCascade = "cascade"
+	// ReferencesSetNull deletes the edges of the predicate pointing to a deleted node.
+	ReferencesSetNull = "setnull"
+)
+
+// References returns what's done to the edges of the predicate pointing to a deleted node, if
+// the predicate is marked with @references.
+func (s *state) References(pred string) string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.predicate[pred].GetReferences()
+}
@@ -61,6 +61,7 @@ func (s *state) init() {
 	s.types = make(map[string]*pb.TypeUpdate)
 	s.elog = trace.NewEventLog("Dgraph", "Schema")
 	s.mutSchema = make(map[string]*pb.SchemaUpdate)
+	s.dictionaries = make(map[string]map[string]uint64)
 }
 
 type state struct {
@@ -71,6 +72,8 @@ type state struct {
 	elog      trace.EventLog
 	// mutSchema holds the schema update that is being applied in the background.
 	mutSchema map[string]*pb.SchemaUpdate
+	// dictionaries maps the strings in the dictionary of each predicate to their ids.
+	dictionaries map[string]map[string]uint64
 }
 
 // State returns the struct holding the current schema.
@@ -93,6 +96,10 @@ func (s *state) DeleteAll() {
 	for pred := range s.mutSchema {
 		delete(s.mutSchema, pred)
 	}
+
+	for pred := range s.dictionaries {
+		delete(s.dictionaries, pred)
+	}
 }
 
 // Delete updates the schema in memory and disk
@@ -112,6 +119,7 @@ func (s *state) Delete(attr string) error {
 
 	delete(s.predicate, attr)
 	delete(s.mutSchema, attr)
+	delete(s.dictionaries, attr)
 	return nil
 }
 
@@ -164,6 +172,7 @@ func (s *state) Set(pred string, schema *pb.SchemaUpdate) {
 
 	s.Lock()
 	defer s.Unlock()
+	s.keepEncoding(pred, schema)
 	s.predicate[pred] = schema
 	s.elog.Printf(logUpdate(schema, pred))
 }
@@ -172,6 +181,7 @@ func (s *state) Set(pred string, schema *pb.SchemaUpdate) {
 func (s *state) SetMutSchema(pred string, schema *pb.SchemaUpdate) {
 	s.Lock()
 	defer s.Unlock()
+	s.keepEncoding(pred, schema)
 	s.mutSchema[pred] = schema
 }
 
@@ -410,6 +420,104 @@ func (s *state) HasNoConflict(pred string) bool {
 	return s.predicate[pred].GetNoConflict()
 }
 
+// HasUnique returns whether the values of the predicate must be unique across nodes.
+func (s *state) HasUnique(pred string) bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.predicate[pred].GetUnique()
+}
+
+// TTL returns the duration after which the values of the predicate expire, and the datetime
+// predicate it's counted from. The duration is empty if the values don't expire.
+func (s *state) TTL(pred string) (string, string) {
+	s.RLock()
+	defer s.RUnlock()
+	return s.predicate[pred].GetTtl(), s.predicate[pred].GetTtlFrom()
+}
+
+// RenamedFrom returns the predicate that is being renamed to pred, or an empty string if pred
+// isn't the target of an ongoing rename.
+func (s *state) RenamedFrom(pred string) string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.predicate[pred].GetRenamedFrom()
+}
+
+// Renames returns the ongoing renames, as a map from the new name of each predicate to the
+// old one.
+func (s *state) Renames() map[string]string {
+	s.RLock()
+	defer s.RUnlock()
+	renames := make(map[string]string)
+	for pred, su := range s.predicate {
+		if len(su.GetRenamedFrom()) > 0 {
+			renames[pred] = su.RenamedFrom
+		}
+	}
+	return renames
+}
+
+const (
+	// TierOffloading is the tier of a predicate whose data is being uploaded to the object
+	// storage. It's still read from the local keys, but its mutations are rejected.
+	TierOffloading = "offloading"
+	// TierCold is the tier of a predicate whose data is only stored in the object storage.
+	TierCold = "cold"
+	// TierFetching is the tier of a predicate whose data is being fetched back from the object
+	// storage.
+	TierFetching = "fetching"
+)
+
+// Tier returns the storage tier of pred, or an empty string if its data is stored locally.
+func (s *state) Tier(pred string) string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.predicate[pred].GetTier()
+}
+
+// Tiers returns the predicates whose data isn't stored locally, or is being moved, as a map
+// from each predicate to its tier.
+func (s *state) Tiers() map[string]string {
+	s.RLock()
+	defer s.RUnlock()
+	tiers := make(map[string]string)
+	for pred, su := range s.predicate {
+		if len(su.GetTier()) > 0 {
+			tiers[pred] = su.Tier
+		}
+	}
+	return tiers
+}
+
+// Default returns the default value of pred, if it has one, and whether the default is virtual.
+func (s *state) Default(pred string) (types.Val, bool, bool) {
+	s.RLock()
+	defer s.RUnlock()
+	su, ok := s.predicate[pred]
+	if !ok || su.DefaultValue == "" {
+		return types.Val{}, false, false
+	}
+	val, err := DefaultValue(su)
+	if err != nil {
+		return types.Val{}, false, false
+	}
+	return val, su.DefaultVirtual, true
+}
+
+// IndexCondition returns the condition of the index of pred, if only the values satisfying it
+// are indexed.
+func (s *state) IndexCondition(ctx context.Context, pred string) (IndexCondition, bool) {
+	su, ok := s.Get(ctx, pred)
+	if !ok || su.IndexWhere == "" {
+		return nil, false
+	}
+	cond, err := ParseIndexCondition(su.IndexWhere, types.TypeID(su.ValueType))
+	if err != nil {
+		return nil, false
+	}
+	return cond, true
+}
+
 // IndexingInProgress checks whether indexing is going on for a given predicate.
 func (s *state) IndexingInProgress() bool {
 	s.RLock()
@@ -577,6 +685,31 @@ func initialTypesInternal(all bool) []*pb.TypeUpdate {
 					ValueType: pb.Posting_DATETIME,
 				},
 			},
+		}, &pb.TypeUpdate{
+			TypeName: "dgraph.graphql.persisted_query",
+			Fields: []*pb.SchemaUpdate{
+				{
+					Predicate: "dgraph.graphql.p_query",
+					ValueType: pb.Posting_STRING,
+				}, {
+					Predicate: "dgraph.graphql.p_sha256hash",
+					ValueType: pb.Posting_STRING,
+				},
+			},
+		}, &pb.TypeUpdate{
+			TypeName: "dgraph.schema.history",
+			Fields: []*pb.SchemaUpdate{
+				{
+					Predicate: "dgraph.schema.change",
+					ValueType: pb.Posting_STRING,
+				}, {
+					Predicate: "dgraph.schema.changed_by",
+					ValueType: pb.Posting_STRING,
+				}, {
+					Predicate: "dgraph.schema.changed_at",
+					ValueType: pb.Posting_DATETIME,
+				},
+			},
 		})
 
 	if all || x.WorkerConfig.AclEnabled {
@@ -678,6 +811,24 @@ func initialSchemaInternal(all bool) []*pb.SchemaUpdate {
 		}, &pb.SchemaUpdate{
 			Predicate: "dgraph.graphql.schema_created_at",
 			ValueType: pb.Posting_DATETIME,
+		}, &pb.SchemaUpdate{
+			Predicate: "dgraph.graphql.p_query",
+			ValueType: pb.Posting_STRING,
+		}, &pb.SchemaUpdate{
+			Predicate: "dgraph.graphql.p_sha256hash",
+			ValueType: pb.Posting_STRING,
+			Directive: pb.SchemaUpdate_INDEX,
+			Tokenizer: []string{"exact"},
+			Upsert:    true,
+		}, &pb.SchemaUpdate{
+			Predicate: "dgraph.schema.change",
+			ValueType: pb.Posting_STRING,
+		}, &pb.SchemaUpdate{
+			Predicate: "dgraph.schema.changed_by",
+			ValueType: pb.Posting_STRING,
+		}, &pb.SchemaUpdate{
+			Predicate: "dgraph.schema.changed_at",
+			ValueType: pb.Posting_DATETIME,
 		})
 
 	if all || x.WorkerConfig.AclEnabled {
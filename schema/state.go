@@ -35,6 +35,8 @@ const (
 	itemLeftSquare
 	itemRightSquare
 	itemExclamationMark
+	itemQuotedText // double quoted string
+	itemNumber     // unsigned integer, like the arguments of a tokenizer
 )
 
 func lexText(l *lex.Lexer) lex.StateFn {
@@ -57,7 +59,7 @@ Loop:
 		case r == ',':
 			l.Emit(itemComma)
 		case r == '<':
-			if err := lex.IRIRef(l, itemText); err != nil {
+			if err := lex.PredicateRef(l, itemText); err != nil {
 				return l.Errorf("Invalid schema: %v", err)
 			}
 		case r == '{':
@@ -78,6 +80,10 @@ Loop:
 			l.Emit(itemRightSquare)
 		case r == '!':
 			l.Emit(itemExclamationMark)
+		case r == '"':
+			return lexQuotedText
+		case isDigit(r):
+			return lexNumber
 		case r == '_':
 			// Predicates can start with _.
 			return lexWord
@@ -106,6 +112,31 @@ func lexWord(l *lex.Lexer) lex.StateFn {
 	return lexText
 }
 
+// lexQuotedText lexes a double quoted string, like the arguments of a tokenizer.
+func lexQuotedText(l *lex.Lexer) lex.StateFn {
+	for {
+		r := l.Next()
+		switch {
+		case r == lex.EOF || lex.IsEndOfLine(r):
+			return l.Errorf("Unterminated quoted string in schema")
+		case r == '\\':
+			l.Next()
+		case r == '"':
+			l.Emit(itemQuotedText)
+			return lexText
+		}
+	}
+}
+
+// lexNumber lexes an unsigned integer, like the arguments of a tokenizer.
+func lexNumber(l *lex.Lexer) lex.StateFn {
+	for isDigit(l.Next()) {
+	}
+	l.Backup()
+	l.Emit(itemNumber)
+	return lexText
+}
+
 // lexTextComment lexes a comment text inside a schema.
 func lexTextComment(l *lex.Lexer) lex.StateFn {
 	for {
@@ -137,11 +168,16 @@ func isNameBegin(r rune) bool {
 	}
 }
 
+// isDigit returns true if the rune is a decimal digit.
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
 func isNameSuffix(r rune) bool {
 	if isNameBegin(r) {
 		return true
 	}
-	if r >= '0' && r <= '9' {
+	if isDigit(r) {
 		return true
 	}
 	if r == '_' || r == '.' || r == '-' { // Use by freebase.
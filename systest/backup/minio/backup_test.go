@@ -115,8 +115,11 @@ func TestBackupMinio(t *testing.T) {
 	// Check the predicates and types in the schema are as expected.
 	// TODO: refactor tests so that minio and filesystem tests share most of their logic.
 	preds := []string{"dgraph.graphql.schema", "dgraph.cors", "dgraph.graphql.xid", "dgraph.type", "movie",
-		"dgraph.graphql.schema_history", "dgraph.graphql.schema_created_at"}
-	types := []string{"Node", "dgraph.graphql", "dgraph.graphql.history"}
+		"dgraph.graphql.schema_history", "dgraph.graphql.schema_created_at",
+		"dgraph.graphql.p_query", "dgraph.graphql.p_sha256hash", "dgraph.schema.change",
+		"dgraph.schema.changed_by", "dgraph.schema.changed_at"}
+	types := []string{"Node", "dgraph.graphql", "dgraph.graphql.history",
+		"dgraph.graphql.persisted_query", "dgraph.schema.history"}
 	testutil.CheckSchema(t, preds, types)
 
 	checks := []struct {
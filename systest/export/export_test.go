@@ -83,6 +83,11 @@ var expectedSchema = `<movie>:string .` + " " + `
 <dgraph.graphql.schema>:string .` + " " + `
 <dgraph.graphql.schema_history>:string .` + " " + `
 <dgraph.graphql.schema_created_at>:datetime .` + " " + `
+<dgraph.graphql.p_query>:string .` + " " + `
+<dgraph.graphql.p_sha256hash>:string @index(exact) @upsert .` + " " + `
+<dgraph.schema.change>:string .` + " " + `
+<dgraph.schema.changed_by>:string .` + " " + `
+<dgraph.schema.changed_at>:datetime .` + " " + `
 type <Node> {
 	movie
 }
@@ -94,6 +99,15 @@ type <dgraph.graphql.history> {
 	dgraph.graphql.schema_history
 	dgraph.graphql.schema_created_at
 }
+type <dgraph.graphql.persisted_query> {
+	dgraph.graphql.p_query
+	dgraph.graphql.p_sha256hash
+}
+type <dgraph.schema.history> {
+	dgraph.schema.change
+	dgraph.schema.changed_by
+	dgraph.schema.changed_at
+}
 `
 
 func setupDgraph(t *testing.T) {
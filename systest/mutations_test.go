@@ -637,7 +637,7 @@ func SchemaAfterDeleteNode(t *testing.T, c *dgo.Dgraph) {
 	resp, err := c.NewTxn().Query(ctx, `schema{}`)
 	require.NoError(t, err)
 	testutil.CompareJSON(t, asJson(`[`+x.CorsPredicate+","+
-		x.AclPredicates+","+x.GraphqlPredicates+","+
+		x.AclPredicates+","+x.GraphqlPredicates+","+x.SchemaHistoryPredicates+","+
 		`{"predicate":"friend","type":"uid","list":true},`+
 		`{"predicate":"married","type":"bool"},`+
 		`{"predicate":"name","type":"default"},`+
@@ -660,7 +660,7 @@ func SchemaAfterDeleteNode(t *testing.T, c *dgo.Dgraph) {
 	require.NoError(t, err)
 	testutil.CompareJSON(t, asJson(`[`+
 		x.AclPredicates+","+x.CorsPredicate+","+
-		x.GraphqlPredicates+","+
+		x.GraphqlPredicates+","+x.SchemaHistoryPredicates+","+
 		`{"predicate":"friend","type":"uid","list":true},`+
 		`{"predicate":"name","type":"default"},`+
 		`{"predicate":"dgraph.type","type":"string","index":true, "tokenizer":["exact"],
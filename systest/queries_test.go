@@ -336,7 +336,8 @@ func SchemaQueryTest(t *testing.T, c *dgo.Dgraph) {
 	require.NoError(t, err)
 	js := `
   {
-    "schema": [` + x.CorsPredicate + "," + x.AclPredicates + `,` + x.GraphqlPredicates + `,
+    "schema": [` + x.CorsPredicate + "," + x.AclPredicates + `,` + x.GraphqlPredicates + "," +
+		x.SchemaHistoryPredicates + `,
       {
         "predicate": "dgraph.type",
         "type": "string",
@@ -398,6 +399,21 @@ func SchemaQueryTestPredicate1(t *testing.T, c *dgo.Dgraph) {
 	  {
 	    "predicate": "dgraph.graphql.schema_created_at"
 	  },
+	  {
+	    "predicate": "dgraph.graphql.p_query"
+	  },
+	  {
+	    "predicate": "dgraph.graphql.p_sha256hash"
+	  },
+	  {
+	    "predicate": "dgraph.schema.change"
+	  },
+	  {
+	    "predicate": "dgraph.schema.changed_by"
+	  },
+	  {
+	    "predicate": "dgraph.schema.changed_at"
+	  },
       {
         "predicate": "dgraph.xid"
       },
@@ -560,7 +576,8 @@ func SchemaQueryTestHTTP(t *testing.T, c *dgo.Dgraph) {
 
 	js := `
   {
-    "schema": [` + x.CorsPredicate + `,` + x.AclPredicates + `,` + x.GraphqlPredicates + `,
+    "schema": [` + x.CorsPredicate + `,` + x.AclPredicates + `,` + x.GraphqlPredicates + "," +
+		x.SchemaHistoryPredicates + `,
       {
         "index": true,
         "predicate": "dgraph.type",
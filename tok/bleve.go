@@ -25,9 +25,15 @@ import (
 	"github.com/blevesearch/bleve/analysis/token/unicodenorm"
 	"github.com/blevesearch/bleve/analysis/tokenizer/unicode"
 	"github.com/blevesearch/bleve/registry"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
 )
 
-const unicodenormName = "unicodenorm_nfkc"
+const (
+	unicodenormName = "unicodenorm_nfkc"
+	// normalizeCasefold is the normalization option of the term and fulltext tokenizers.
+	normalizeCasefold = "nfkc_casefold"
+)
 
 var (
 	bleveCache                     = registry.NewCache()
@@ -79,3 +85,17 @@ func uniqueTerms(tokens analysis.TokenStream) []string {
 	terms = x.RemoveDuplicates(terms)
 	return terms
 }
+
+// casefold normalizes str with NFKC_Casefold: full case folding, which maps ß to ss, between
+// Normalization Form KC on both sides, as folding may change the normalized form.
+func casefold(str string) string {
+	return norm.NFKC.String(cases.Fold().String(norm.NFKC.String(str)))
+}
+
+// casefoldTokens normalizes the terms of the tokens with NFKC_Casefold.
+func casefoldTokens(tokens analysis.TokenStream) analysis.TokenStream {
+	for _, token := range tokens {
+		token.Term = []byte(casefold(string(token.Term)))
+	}
+	return tokens
+}
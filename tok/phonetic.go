@@ -0,0 +1,210 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tok
+
+import "strings"
+
+// asciiUpper returns the ASCII letters of term in upper case, leaving out the other characters.
+// The phonetic codes are only defined for the letters of the English alphabet.
+func asciiUpper(term string) []byte {
+	var letters []byte
+	for _, r := range term {
+		switch {
+		case r >= 'a' && r <= 'z':
+			letters = append(letters, byte(r-'a'+'A'))
+		case r >= 'A' && r <= 'Z':
+			letters = append(letters, byte(r))
+		}
+	}
+	return letters
+}
+
+// soundexCodes maps each letter to its Soundex digit. The vowels and Y are 0, as they separate
+// consonants with the same digit, and H and W are left out, as they don't.
+var soundexCodes = map[byte]byte{
+	'A': '0', 'E': '0', 'I': '0', 'O': '0', 'U': '0', 'Y': '0',
+	'B': '1', 'F': '1', 'P': '1', 'V': '1',
+	'C': '2', 'G': '2', 'J': '2', 'K': '2', 'Q': '2', 'S': '2', 'X': '2', 'Z': '2',
+	'D': '3', 'T': '3',
+	'L': '4',
+	'M': '5', 'N': '5',
+	'R': '6',
+}
+
+// soundex returns the American Soundex code of term, like R163 for Robert and Rupert, or "" if
+// the term has no letters.
+func soundex(term string) string {
+	letters := asciiUpper(term)
+	if len(letters) == 0 {
+		return ""
+	}
+	code := []byte{letters[0]}
+	prev := soundexCodes[letters[0]]
+	for _, c := range letters[1:] {
+		digit, ok := soundexCodes[c]
+		switch {
+		case !ok:
+			// H and W don't separate the consonants around them.
+			continue
+		case digit != '0' && digit != prev:
+			code = append(code, digit)
+		}
+		prev = digit
+		if len(code) == 4 {
+			break
+		}
+	}
+	for len(code) < 4 {
+		code = append(code, '0')
+	}
+	return string(code)
+}
+
+func isVowel(c byte) bool {
+	return c == 'A' || c == 'E' || c == 'I' || c == 'O' || c == 'U'
+}
+
+// metaphone returns the code of term given by the original Metaphone algorithm of Lawrence
+// Philips, like JN for John and Jahn, or "" if the term has no letters. The digit 0 stands for
+// the sound of TH.
+func metaphone(term string) string {
+	w := asciiUpper(term)
+	if len(w) == 0 {
+		return ""
+	}
+	s := string(w)
+	switch {
+	case strings.HasPrefix(s, "AE"), strings.HasPrefix(s, "GN"), strings.HasPrefix(s, "KN"),
+		strings.HasPrefix(s, "PN"), strings.HasPrefix(s, "WR"):
+		w = w[1:]
+	case strings.HasPrefix(s, "WH"):
+		w = w[1:]
+		w[0] = 'W'
+	case w[0] == 'X':
+		w[0] = 'S'
+	}
+
+	at := func(i int) byte {
+		if i < 0 || i >= len(w) {
+			return 0
+		}
+		return w[i]
+	}
+	in := func(c byte, set string) bool {
+		return c != 0 && strings.IndexByte(set, c) >= 0
+	}
+
+	var code []byte
+	for i := 0; i < len(w); i++ {
+		c := w[i]
+		// Double letters sound like a single one, but for CC as in accident.
+		if c == at(i-1) && c != 'C' {
+			continue
+		}
+		switch c {
+		case 'A', 'E', 'I', 'O', 'U':
+			if i == 0 {
+				code = append(code, c)
+			}
+		case 'B':
+			// The B is silent after M at the end, as in dumb.
+			if !(i == len(w)-1 && at(i-1) == 'M') {
+				code = append(code, 'B')
+			}
+		case 'C':
+			switch {
+			case at(i+1) == 'I' && at(i+2) == 'A':
+				code = append(code, 'X')
+			case at(i+1) == 'H' && at(i-1) == 'S':
+				code = append(code, 'K')
+			case at(i+1) == 'H':
+				code = append(code, 'X')
+			case in(at(i+1), "IEY"):
+				if at(i-1) != 'S' {
+					code = append(code, 'S')
+				}
+			default:
+				code = append(code, 'K')
+			}
+		case 'D':
+			if at(i+1) == 'G' && in(at(i+2), "IEY") {
+				code = append(code, 'J')
+				i++
+			} else {
+				code = append(code, 'T')
+			}
+		case 'G':
+			switch {
+			case at(i+1) == 'H' && i+2 < len(w) && !isVowel(at(i+2)):
+				// Silent as in night.
+			case at(i+1) == 'N' && (i+2 == len(w) || string(w[i+1:]) == "NED"):
+				// Silent as in sign and signed.
+			case in(at(i+1), "IEY"):
+				code = append(code, 'J')
+			default:
+				code = append(code, 'K')
+			}
+		case 'H':
+			if !(isVowel(at(i-1)) && !isVowel(at(i+1))) && !in(at(i-1), "CSPTG") {
+				code = append(code, 'H')
+			}
+		case 'K':
+			if at(i-1) != 'C' {
+				code = append(code, 'K')
+			}
+		case 'P':
+			if at(i+1) == 'H' {
+				code = append(code, 'F')
+			} else {
+				code = append(code, 'P')
+			}
+		case 'Q':
+			code = append(code, 'K')
+		case 'S':
+			switch {
+			case at(i+1) == 'H', at(i+1) == 'I' && in(at(i+2), "OA"):
+				code = append(code, 'X')
+			default:
+				code = append(code, 'S')
+			}
+		case 'T':
+			switch {
+			case at(i+1) == 'I' && in(at(i+2), "OA"):
+				code = append(code, 'X')
+			case at(i+1) == 'H':
+				code = append(code, '0')
+			case at(i+1) == 'C' && at(i+2) == 'H':
+				// Silent as in watch.
+			default:
+				code = append(code, 'T')
+			}
+		case 'V':
+			code = append(code, 'F')
+		case 'W', 'Y':
+			if isVowel(at(i + 1)) {
+				code = append(code, c)
+			}
+		case 'X':
+			code = append(code, 'K', 'S')
+		case 'Z':
+			code = append(code, 'S')
+		default:
+			code = append(code, c)
+		}
+	}
+	return string(code)
+}
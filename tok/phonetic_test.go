@@ -0,0 +1,84 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tok
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSoundex(t *testing.T) {
+	tests := map[string]string{
+		"Robert":   "R163",
+		"Rupert":   "R163",
+		"Rubin":    "R150",
+		"Ashcraft": "A261",
+		"Tymczak":  "T522",
+		"Pfister":  "P236",
+		"Honeyman": "H555",
+		"John":     "J500",
+		"Jon":      "J500",
+		"Jahn":     "J500",
+		"Lee":      "L000",
+		"1984":     "",
+	}
+	for in, out := range tests {
+		require.Equal(t, out, soundex(in), in)
+	}
+}
+
+func TestMetaphone(t *testing.T) {
+	tests := map[string]string{
+		"John":    "JN",
+		"Jon":     "JN",
+		"Jahn":    "JN",
+		"Knight":  "NT",
+		"Night":   "NT",
+		"Smith":   "SM0",
+		"Phil":    "FL",
+		"Fill":    "FL",
+		"Bach":    "BX",
+		"School":  "SKL",
+		"Dodge":   "TJ",
+		"Thumb":   "0M",
+		"Xavier":  "SFR",
+		"Whitney": "WTN",
+		"Science": "SNS",
+		"Nation":  "NXN",
+		"Sign":    "SN",
+		"Wright":  "RT",
+		"1984":    "",
+	}
+	for in, out := range tests {
+		require.Equal(t, out, metaphone(in), in)
+	}
+}
+
+func TestPhoneticTokenizers(t *testing.T) {
+	tokenizer, has := GetTokenizer("soundex")
+	require.True(t, has)
+	tokens, err := tokenizer.Tokens("John Smith and Jon Smyth")
+	require.NoError(t, err)
+	require.Equal(t, []string{"A530", "J500", "S530"}, tokens)
+
+	tokenizer, has = GetTokenizer("metaphone")
+	require.True(t, has)
+	tokens, err = tokenizer.Tokens("John Smith and Jahn Smyth")
+	require.NoError(t, err)
+	require.Equal(t, []string{"ANT", "JN", "SM0"}, tokens)
+}
@@ -18,14 +18,23 @@ package tok
 
 import (
 	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
 	"plugin"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/blevesearch/bleve/analysis"
 	"github.com/golang/glog"
 	geom "github.com/twpayne/go-geom"
 	"golang.org/x/crypto/blake2b"
 	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
 
 	"github.com/dgraph-io/dgraph/types"
 	"github.com/dgraph-io/dgraph/x"
@@ -52,6 +61,13 @@ const (
 	IdentBool      = 0x9
 	IdentTrigram   = 0xA
 	IdentHash      = 0xB
+	IdentNgram     = 0xC
+	IdentHNSW      = 0xD
+	IdentEdgeNgram = 0xE
+	IdentSoundex   = 0xF
+	IdentMetaphone = 0x10
+	IdentCollation = 0x11
+	IdentDecimal   = 0x12
 	IdentCustom    = 0x80
 	IdentDelimiter = 0x1f // ASCII 31 - Unit seperator
 )
@@ -89,6 +105,7 @@ func init() {
 	registerTokenizer(GeoTokenizer{})
 	registerTokenizer(IntTokenizer{})
 	registerTokenizer(FloatTokenizer{})
+	registerTokenizer(DecimalTokenizer{})
 	registerTokenizer(YearTokenizer{})
 	registerTokenizer(HourTokenizer{})
 	registerTokenizer(MonthTokenizer{})
@@ -99,6 +116,11 @@ func init() {
 	registerTokenizer(HashTokenizer{})
 	registerTokenizer(TermTokenizer{})
 	registerTokenizer(FullTextTokenizer{})
+	registerTokenizer(NgramTokenizer{})
+	registerTokenizer(EdgeNgramTokenizer{})
+	registerTokenizer(SoundexTokenizer{})
+	registerTokenizer(MetaphoneTokenizer{})
+	registerTokenizer(HNSWTokenizer{})
 	setupBleve()
 }
 
@@ -148,8 +170,192 @@ func GetTokenizerByID(id byte) (Tokenizer, bool) {
 
 // GetTokenizer returns tokenizer given unique name.
 func GetTokenizer(name string) (Tokenizer, bool) {
-	t, found := tokenizers[name]
-	return t, found
+	if t, found := tokenizers[name]; found {
+		return t, true
+	}
+	return getTokenizerWithOptions(name)
+}
+
+// optionsTokenizerName matches the names of the tokenizers configured with options, as returned
+// by their Name method. For example: day(tz: "Asia/Kolkata") or ngram(min: 2, max: 4).
+var optionsTokenizerName = regexp.MustCompile(`^(\w+)\((.+)\)$`)
+
+// optionsTokenizers caches the tokenizers configured with options, by name.
+var optionsTokenizers sync.Map
+
+func getTokenizerWithOptions(name string) (Tokenizer, bool) {
+	if t, ok := optionsTokenizers.Load(name); ok {
+		return t.(Tokenizer), true
+	}
+	m := optionsTokenizerName.FindStringSubmatch(name)
+	if m == nil {
+		return nil, false
+	}
+	opts := make(map[string]string)
+	for _, opt := range strings.Split(m[2], ", ") {
+		kv := strings.SplitN(opt, ": ", 2)
+		if len(kv) != 2 {
+			return nil, false
+		}
+		if val, err := strconv.Unquote(kv[1]); err == nil {
+			kv[1] = val
+		}
+		opts[kv[0]] = kv[1]
+	}
+	t, err := GetTokenizerWithOptions(m[1], opts)
+	if err != nil || t.Name() != name {
+		return nil, false
+	}
+	optionsTokenizers.Store(name, t)
+	return t, true
+}
+
+// GetTokenizerInLocation returns the datetime tokenizer with the given name that buckets the
+// values in the time zone tz instead of UTC.
+func GetTokenizerInLocation(name, tz string) (Tokenizer, error) {
+	return GetTokenizerWithOptions(name, map[string]string{"tz": tz})
+}
+
+// GetTokenizerWithOptions returns the tokenizer with the given name configured with the given
+// options. The datetime tokenizers take the time zone to bucket the values in, like
+// (tz: "Asia/Kolkata"), the ngram and edgengram tokenizers take the lengths of the n-grams, like
+// (min: 2, max: 4), the hnsw tokenizer takes the metric, like (metric: "cosine"), the exact
+// tokenizer takes the locale to sort the values in, like (collation: "de"), the term and
+// fulltext tokenizers take the normalization of their terms, (normalize: "nfkc_casefold"), and
+// the geo tokenizer takes the levels of its cells, like (minlevel: 5, maxlevel: 16, maxcells: 18).
+func GetTokenizerWithOptions(name string, opts map[string]string) (Tokenizer, error) {
+	switch name {
+	case "exact":
+		for _, opt := range sortedOptions(opts) {
+			if opt != "collation" {
+				return nil, errors.Errorf("Tokenizer %s doesn't support the %s option", name, opt)
+			}
+		}
+		if err := checkTokenizerOptions(name, opts, `(collation: "de")`, "collation"); err != nil {
+			return nil, err
+		}
+		tag, err := language.Parse(opts["collation"])
+		if err != nil {
+			return nil, errors.Errorf("Invalid collation %q for tokenizer exact: %v",
+				opts["collation"], err)
+		}
+		return ExactTokenizer{collation: tag.String()}, nil
+	case "year", "month", "day", "hour":
+		if err := checkTokenizerOptions(name, opts, `(tz: "Zone/Name")`, "tz"); err != nil {
+			return nil, err
+		}
+		loc, err := types.LoadLocation(opts["tz"])
+		if err != nil {
+			return nil, err
+		}
+		switch name {
+		case "year":
+			return YearTokenizer{loc: loc}, nil
+		case "month":
+			return MonthTokenizer{loc: loc}, nil
+		case "day":
+			return DayTokenizer{loc: loc}, nil
+		default:
+			return HourTokenizer{loc: loc}, nil
+		}
+	case "ngram", "edgengram":
+		if err := checkTokenizerOptions(name, opts, "(min: 2, max: 4)", "min", "max"); err != nil {
+			return nil, err
+		}
+		min, err := strconv.Atoi(opts["min"])
+		if err != nil {
+			return nil, errors.Errorf("Invalid min length %q for tokenizer %s", opts["min"], name)
+		}
+		max, err := strconv.Atoi(opts["max"])
+		if err != nil {
+			return nil, errors.Errorf("Invalid max length %q for tokenizer %s", opts["max"], name)
+		}
+		if min < 1 || max < min {
+			return nil, errors.Errorf("The lengths of tokenizer %s must satisfy "+
+				"1 <= min <= max, got min: %d, max: %d", name, min, max)
+		}
+		if name == "edgengram" {
+			return EdgeNgramTokenizer{min: min, max: max}, nil
+		}
+		return NgramTokenizer{min: min, max: max}, nil
+	case "term", "fulltext":
+		form := fmt.Sprintf("(normalize: %q)", normalizeCasefold)
+		if err := checkTokenizerOptions(name, opts, form, "normalize"); err != nil {
+			return nil, err
+		}
+		if opts["normalize"] != normalizeCasefold {
+			return nil, errors.Errorf("Invalid normalization %q for tokenizer %s, expected %q",
+				opts["normalize"], name, normalizeCasefold)
+		}
+		if name == "fulltext" {
+			return FullTextTokenizer{fold: true}, nil
+		}
+		return TermTokenizer{fold: true}, nil
+	case "geo":
+		form := "(minlevel: 5, maxlevel: 16, maxcells: 18)"
+		if err := checkTokenizerOptions(name, opts, form, "minlevel", "maxlevel",
+			"maxcells"); err != nil {
+			return nil, err
+		}
+		var levels [3]int
+		for i, opt := range []string{"minlevel", "maxlevel", "maxcells"} {
+			n, err := strconv.Atoi(opts[opt])
+			if err != nil {
+				return nil, errors.Errorf("Invalid %s %q for tokenizer geo", opt, opts[opt])
+			}
+			levels[i] = n
+		}
+		geoOpts := types.GeoIndexOptions{MinLevel: levels[0], MaxLevel: levels[1],
+			MaxCells: levels[2]}
+		if err := geoOpts.Validate(); err != nil {
+			return nil, err
+		}
+		if geoOpts == types.DefaultGeoIndexOptions {
+			return GeoTokenizer{}, nil
+		}
+		return GeoTokenizer{opts: geoOpts}, nil
+	case "hnsw":
+		if err := checkTokenizerOptions(name, opts, `(metric: "cosine")`, "metric"); err != nil {
+			return nil, err
+		}
+		if _, ok := types.GetVectorMetric(opts["metric"]); !ok {
+			return nil, errors.Errorf("Invalid metric %q for tokenizer hnsw, expected one of "+
+				"euclidean, cosine and dotproduct", opts["metric"])
+		}
+		if opts["metric"] == defaultHNSWMetric {
+			return HNSWTokenizer{}, nil
+		}
+		return HNSWTokenizer{metric: opts["metric"]}, nil
+	}
+	for _, opt := range sortedOptions(opts) {
+		return nil, errors.Errorf("Tokenizer %s doesn't support the %s option", name, opt)
+	}
+	return nil, errors.Errorf("Tokenizer %s doesn't support options", name)
+}
+
+// checkTokenizerOptions checks that opts are exactly the required options of the tokenizer
+// name, which are written as form in the schema.
+func checkTokenizerOptions(name string, opts map[string]string, form string,
+	required ...string) error {
+	valid := len(opts) == len(required)
+	for _, opt := range required {
+		if _, ok := opts[opt]; !ok {
+			valid = false
+		}
+	}
+	if !valid {
+		return errors.Errorf("Expected tokenizer options of the form %s for %s", form, name)
+	}
+	return nil
+}
+
+func sortedOptions(opts map[string]string) []string {
+	names := make([]string, 0, len(opts))
+	for opt := range opts {
+		names = append(names, opt)
+	}
+	sort.Strings(names)
+	return names
 }
 
 // GetTokenizers returns a list of tokenizer given a list of unique names.
@@ -173,13 +379,30 @@ func registerTokenizer(t Tokenizer) {
 	tokenizers[t.Name()] = t
 }
 
-// GeoTokenizer generates tokens from geo data.
-type GeoTokenizer struct{}
+// GeoTokenizer generates tokens from geo data. If opts is set, the geometries are covered with
+// the cells of its levels instead of the default ones.
+type GeoTokenizer struct {
+	opts types.GeoIndexOptions
+}
 
-func (t GeoTokenizer) Name() string { return "geo" }
+func (t GeoTokenizer) Name() string {
+	if t.opts == (types.GeoIndexOptions{}) {
+		return "geo"
+	}
+	return fmt.Sprintf("geo(minlevel: %d, maxlevel: %d, maxcells: %d)",
+		t.opts.MinLevel, t.opts.MaxLevel, t.opts.MaxCells)
+}
 func (t GeoTokenizer) Type() string { return "geo" }
 func (t GeoTokenizer) Tokens(v interface{}) ([]string, error) {
-	return types.IndexGeoTokens(v.(geom.T))
+	return types.IndexGeoTokens(v.(geom.T), t.Options())
+}
+
+// Options returns the options the geometries are indexed with.
+func (t GeoTokenizer) Options() types.GeoIndexOptions {
+	if t.opts == (types.GeoIndexOptions{}) {
+		return types.DefaultGeoIndexOptions
+	}
+	return t.opts
 }
 func (t GeoTokenizer) Identifier() byte { return IdentGeo }
 func (t GeoTokenizer) IsSortable() bool { return false }
@@ -209,15 +432,83 @@ func (t FloatTokenizer) Identifier() byte { return IdentFloat }
 func (t FloatTokenizer) IsSortable() bool { return true }
 func (t FloatTokenizer) IsLossy() bool    { return true }
 
+// DecimalTokenizer generates a token for each decimal value, such that the tokens sort in the
+// order of the values.
+type DecimalTokenizer struct{}
+
+func (t DecimalTokenizer) Name() string { return "decimal" }
+func (t DecimalTokenizer) Type() string { return "decimal" }
+func (t DecimalTokenizer) Tokens(v interface{}) ([]string, error) {
+	return []string{encodeDecimal(v.(*big.Rat))}, nil
+}
+func (t DecimalTokenizer) Identifier() byte { return IdentDecimal }
+func (t DecimalTokenizer) IsSortable() bool { return true }
+func (t DecimalTokenizer) IsLossy() bool    { return false }
+
+// encodeDecimal writes the value as 0.d1d2...dn * 10^exp, where d1 isn't 0, and encodes it as a
+// sign byte, the exponent and the digits. The bytes of negative values are complemented, with a
+// terminator so that longer digits of the same prefix sort first.
+func encodeDecimal(r *big.Rat) string {
+	s := types.FormatDecimal(r)
+	if s == "0" {
+		return "\x01"
+	}
+	neg := s[0] == '-'
+	s = strings.TrimPrefix(s, "-")
+	intPart, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, frac = s[:i], s[i+1:]
+	}
+	digits := intPart + frac
+	exp := len(intPart)
+	for digits[0] == '0' {
+		digits = digits[1:]
+		exp--
+	}
+	digits = strings.TrimRight(digits, "0")
+
+	buf := make([]byte, 5, 6+len(digits))
+	binary.BigEndian.PutUint32(buf[1:5], uint32(int64(exp)+math.MaxInt32+1))
+	buf = append(buf, digits...)
+	if !neg {
+		buf[0] = 2
+		return string(buf)
+	}
+	buf = append(buf, 0)
+	for i := 1; i < len(buf); i++ {
+		buf[i] = ^buf[i]
+	}
+	return string(buf)
+}
+
+// inLocation returns tval in the given location, which defaults to UTC.
+func inLocation(tval time.Time, loc *time.Location) time.Time {
+	if loc == nil {
+		return tval.UTC()
+	}
+	return tval.In(loc)
+}
+
+// nameInLocation returns the name of a datetime tokenizer that buckets in the given location.
+// It's the same syntax as the one used to define the index in the schema.
+func nameInLocation(name string, loc *time.Location) string {
+	if loc == nil {
+		return name
+	}
+	return fmt.Sprintf("%s(tz: %q)", name, loc.String())
+}
+
 // YearTokenizer generates year tokens from datetime data.
-type YearTokenizer struct{}
+type YearTokenizer struct {
+	loc *time.Location
+}
 
-func (t YearTokenizer) Name() string { return "year" }
+func (t YearTokenizer) Name() string { return nameInLocation("year", t.loc) }
 func (t YearTokenizer) Type() string { return "datetime" }
 func (t YearTokenizer) Tokens(v interface{}) ([]string, error) {
-	tval := v.(time.Time)
+	tval := inLocation(v.(time.Time), t.loc)
 	buf := make([]byte, 2)
-	binary.BigEndian.PutUint16(buf[0:2], uint16(tval.UTC().Year()))
+	binary.BigEndian.PutUint16(buf[0:2], uint16(tval.Year()))
 	return []string{string(buf)}, nil
 }
 func (t YearTokenizer) Identifier() byte { return IdentYear }
@@ -225,15 +516,17 @@ func (t YearTokenizer) IsSortable() bool { return true }
 func (t YearTokenizer) IsLossy() bool    { return true }
 
 // MonthTokenizer generates month tokens from datetime data.
-type MonthTokenizer struct{}
+type MonthTokenizer struct {
+	loc *time.Location
+}
 
-func (t MonthTokenizer) Name() string { return "month" }
+func (t MonthTokenizer) Name() string { return nameInLocation("month", t.loc) }
 func (t MonthTokenizer) Type() string { return "datetime" }
 func (t MonthTokenizer) Tokens(v interface{}) ([]string, error) {
-	tval := v.(time.Time)
+	tval := inLocation(v.(time.Time), t.loc)
 	buf := make([]byte, 4)
-	binary.BigEndian.PutUint16(buf[0:2], uint16(tval.UTC().Year()))
-	binary.BigEndian.PutUint16(buf[2:4], uint16(tval.UTC().Month()))
+	binary.BigEndian.PutUint16(buf[0:2], uint16(tval.Year()))
+	binary.BigEndian.PutUint16(buf[2:4], uint16(tval.Month()))
 	return []string{string(buf)}, nil
 }
 func (t MonthTokenizer) Identifier() byte { return IdentMonth }
@@ -241,16 +534,18 @@ func (t MonthTokenizer) IsSortable() bool { return true }
 func (t MonthTokenizer) IsLossy() bool    { return true }
 
 // DayTokenizer generates day tokens from datetime data.
-type DayTokenizer struct{}
+type DayTokenizer struct {
+	loc *time.Location
+}
 
-func (t DayTokenizer) Name() string { return "day" }
+func (t DayTokenizer) Name() string { return nameInLocation("day", t.loc) }
 func (t DayTokenizer) Type() string { return "datetime" }
 func (t DayTokenizer) Tokens(v interface{}) ([]string, error) {
-	tval := v.(time.Time)
+	tval := inLocation(v.(time.Time), t.loc)
 	buf := make([]byte, 6)
-	binary.BigEndian.PutUint16(buf[0:2], uint16(tval.UTC().Year()))
-	binary.BigEndian.PutUint16(buf[2:4], uint16(tval.UTC().Month()))
-	binary.BigEndian.PutUint16(buf[4:6], uint16(tval.UTC().Day()))
+	binary.BigEndian.PutUint16(buf[0:2], uint16(tval.Year()))
+	binary.BigEndian.PutUint16(buf[2:4], uint16(tval.Month()))
+	binary.BigEndian.PutUint16(buf[4:6], uint16(tval.Day()))
 	return []string{string(buf)}, nil
 }
 func (t DayTokenizer) Identifier() byte { return IdentDay }
@@ -258,29 +553,39 @@ func (t DayTokenizer) IsSortable() bool { return true }
 func (t DayTokenizer) IsLossy() bool    { return true }
 
 // HourTokenizer generates hour tokens from datetime data.
-type HourTokenizer struct{}
+type HourTokenizer struct {
+	loc *time.Location
+}
 
-func (t HourTokenizer) Name() string { return "hour" }
+func (t HourTokenizer) Name() string { return nameInLocation("hour", t.loc) }
 func (t HourTokenizer) Type() string { return "datetime" }
 func (t HourTokenizer) Tokens(v interface{}) ([]string, error) {
-	tval := v.(time.Time)
+	tval := inLocation(v.(time.Time), t.loc)
 	buf := make([]byte, 8)
-	binary.BigEndian.PutUint16(buf[0:2], uint16(tval.UTC().Year()))
-	binary.BigEndian.PutUint16(buf[2:4], uint16(tval.UTC().Month()))
-	binary.BigEndian.PutUint16(buf[4:6], uint16(tval.UTC().Day()))
-	binary.BigEndian.PutUint16(buf[6:8], uint16(tval.UTC().Hour()))
+	binary.BigEndian.PutUint16(buf[0:2], uint16(tval.Year()))
+	binary.BigEndian.PutUint16(buf[2:4], uint16(tval.Month()))
+	binary.BigEndian.PutUint16(buf[4:6], uint16(tval.Day()))
+	binary.BigEndian.PutUint16(buf[6:8], uint16(tval.Hour()))
 	return []string{string(buf)}, nil
 }
 func (t HourTokenizer) Identifier() byte { return IdentHour }
 func (t HourTokenizer) IsSortable() bool { return true }
 func (t HourTokenizer) IsLossy() bool    { return true }
 
-// TermTokenizer generates term tokens from string data.
+// TermTokenizer generates term tokens from string data. If fold is set, the terms are also
+// normalized with NFKC_Casefold, so that they match regardless of their case and of the
+// equivalent forms of their characters.
 type TermTokenizer struct {
 	lang string
+	fold bool
 }
 
-func (t TermTokenizer) Name() string { return "term" }
+func (t TermTokenizer) Name() string {
+	if !t.fold {
+		return "term"
+	}
+	return fmt.Sprintf("term(normalize: %q)", normalizeCasefold)
+}
 func (t TermTokenizer) Type() string { return "string" }
 func (t TermTokenizer) Tokens(v interface{}) ([]string, error) {
 	str, ok := v.(string)
@@ -291,10 +596,16 @@ func (t TermTokenizer) Tokens(v interface{}) ([]string, error) {
 	switch lang {
 	case "zh", "ja", "th", "lo", "my", "bo", "km", "kxm":
 		// Chinese, Japanese, Thai, Lao, Burmese, Tibetan and Khmer (km, kxm) do not use spaces as delimiters. We simply split by space.
+		if t.fold {
+			str = casefold(str)
+		}
 		tokens := strings.Split(str, " ")
 		return x.RemoveDuplicates(tokens), nil
 	default:
 		tokens := termAnalyzer.Analyze([]byte(str))
+		if t.fold {
+			tokens = casefoldTokens(tokens)
+		}
 		return uniqueTerms(tokens), nil
 	}
 
@@ -304,14 +615,22 @@ func (t TermTokenizer) IsSortable() bool { return false }
 func (t TermTokenizer) IsLossy() bool    { return true }
 
 // ExactTokenizer returns the exact string as a token. If collator is provided for
-// any language then it also adds the language in the prefix .
+// any language then it also adds the language in the prefix. If the tokenizer has a
+// collation, the token is the collation key of the string in that locale instead, so that
+// the tokens of all the values of the predicate sort in the order of the locale.
 type ExactTokenizer struct {
-	langBase string
-	cl       *collate.Collator
-	buffer   *collate.Buffer
+	langBase  string
+	cl        *collate.Collator
+	buffer    *collate.Buffer
+	collation string
 }
 
-func (t ExactTokenizer) Name() string { return "exact" }
+func (t ExactTokenizer) Name() string {
+	if t.collation == "" {
+		return "exact"
+	}
+	return fmt.Sprintf("exact(collation: %q)", t.collation)
+}
 func (t ExactTokenizer) Type() string { return "string" }
 func (t ExactTokenizer) Tokens(v interface{}) ([]string, error) {
 	val, ok := v.(string)
@@ -319,6 +638,11 @@ func (t ExactTokenizer) Tokens(v interface{}) ([]string, error) {
 		return nil, errors.Errorf("Exact indices only supported for string types")
 	}
 
+	if t.collation != "" {
+		// Collators aren't safe for concurrent use, so each call gets its own.
+		cl := collate.New(language.Make(t.collation))
+		return []string{string(cl.KeyFromString(&collate.Buffer{}, val))}, nil
+	}
 	if t.cl == nil {
 		return []string{val}, nil
 	}
@@ -335,16 +659,19 @@ func (t ExactTokenizer) Tokens(v interface{}) ([]string, error) {
 }
 
 func (t ExactTokenizer) Identifier() byte {
-	if t.cl == nil {
-		return IdentExact
+	switch {
+	case t.cl != nil:
+		return IdentExactLang
+	case t.collation != "":
+		return IdentCollation
 	}
-	return IdentExactLang
+	return IdentExact
 }
 func (t ExactTokenizer) IsSortable() bool { return true }
 func (t ExactTokenizer) IsLossy() bool    { return false }
 func (t ExactTokenizer) Prefix() []byte {
 	if t.cl == nil {
-		return []byte{IdentExact}
+		return []byte{t.Identifier()}
 	}
 	prefix := []byte{IdentExactLang}
 	prefix = append(prefix, []byte(t.langBase)...)
@@ -352,25 +679,45 @@ func (t ExactTokenizer) Prefix() []byte {
 	return prefix
 }
 
-// FullTextTokenizer generates full-text tokens from string data.
-type FullTextTokenizer struct{ lang string }
+// Collation returns the locale that the tokens of the untagged values are sorted in, or an
+// empty string if they are sorted bytewise.
+func (t ExactTokenizer) Collation() string { return t.collation }
+
+// FullTextTokenizer generates full-text tokens from string data. If fold is set, the terms are
+// normalized with NFKC_Casefold before the stop words and stems are looked up.
+type FullTextTokenizer struct {
+	lang string
+	fold bool
+}
 
-func (t FullTextTokenizer) Name() string { return "fulltext" }
+func (t FullTextTokenizer) Name() string {
+	if !t.fold {
+		return "fulltext"
+	}
+	return fmt.Sprintf("fulltext(normalize: %q)", normalizeCasefold)
+}
 func (t FullTextTokenizer) Type() string { return "string" }
 func (t FullTextTokenizer) Tokens(v interface{}) ([]string, error) {
 	str, ok := v.(string)
 	if !ok || str == "" {
 		return []string{}, nil
 	}
+	// finally, return the terms.
+	return uniqueTerms(t.analyze(str)), nil
+}
+
+// analyze runs the fulltext analysis pipeline on the given string. Repeated terms are kept.
+func (t FullTextTokenizer) analyze(str string) analysis.TokenStream {
 	lang := LangBase(t.lang)
 	// pass 1 - lowercase and normalize input
 	tokens := fulltextAnalyzer.Analyze([]byte(str))
+	if t.fold {
+		tokens = casefoldTokens(tokens)
+	}
 	// pass 2 - filter stop words
 	tokens = filterStopwords(lang, tokens)
 	// pass 3 - filter stems
-	tokens = filterStemmers(lang, tokens)
-	// finally, return the terms.
-	return uniqueTerms(tokens), nil
+	return filterStemmers(lang, tokens)
 }
 func (t FullTextTokenizer) Identifier() byte { return IdentFullText }
 func (t FullTextTokenizer) IsSortable() bool { return false }
@@ -417,6 +764,179 @@ func (t TrigramTokenizer) Identifier() byte { return IdentTrigram }
 func (t TrigramTokenizer) IsSortable() bool { return false }
 func (t TrigramTokenizer) IsLossy() bool    { return true }
 
+// NgramTokenizer returns the n-grams of the terms in string data, for autocomplete and substring
+// searches. The n-grams are between min and max characters long, and the terms shorter than min
+// are kept whole. The zero value uses the default lengths of 2 to 4 characters.
+type NgramTokenizer struct {
+	min, max int
+}
+
+const (
+	defaultNgramMin = 2
+	defaultNgramMax = 4
+)
+
+func (t NgramTokenizer) Name() string {
+	if t.min == 0 {
+		return "ngram"
+	}
+	return fmt.Sprintf("ngram(min: %d, max: %d)", t.min, t.max)
+}
+func (t NgramTokenizer) Type() string { return "string" }
+func (t NgramTokenizer) Tokens(v interface{}) ([]string, error) {
+	str, ok := v.(string)
+	if !ok {
+		return nil, errors.Errorf("Ngram indices only supported for string types")
+	}
+	min, max := t.min, t.max
+	if min == 0 {
+		min, max = defaultNgramMin, defaultNgramMax
+	}
+	var tokens []string
+	for _, term := range uniqueTerms(termAnalyzer.Analyze([]byte(str))) {
+		runes := []rune(term)
+		if len(runes) < min {
+			tokens = append(tokens, term)
+			continue
+		}
+		for n := min; n <= max && n <= len(runes); n++ {
+			for i := 0; i+n <= len(runes); i++ {
+				tokens = append(tokens, string(runes[i:i+n]))
+			}
+		}
+	}
+	return x.RemoveDuplicates(tokens), nil
+}
+func (t NgramTokenizer) Identifier() byte { return IdentNgram }
+func (t NgramTokenizer) IsSortable() bool { return false }
+func (t NgramTokenizer) IsLossy() bool    { return true }
+
+// EdgeNgramTokenizer returns the prefixes of the terms in string data, for prefix autocomplete.
+// The prefixes are between min and max characters long, and the terms shorter than min are kept
+// whole. The zero value uses the default lengths of 2 to 10 characters. As the prefixes of the
+// terms in the text of a query are looked up with allof, a term matches the terms that start with
+// it, up to max characters.
+type EdgeNgramTokenizer struct {
+	min, max int
+}
+
+const (
+	defaultEdgeNgramMin = 2
+	defaultEdgeNgramMax = 10
+)
+
+func (t EdgeNgramTokenizer) Name() string {
+	if t.min == 0 {
+		return "edgengram"
+	}
+	return fmt.Sprintf("edgengram(min: %d, max: %d)", t.min, t.max)
+}
+func (t EdgeNgramTokenizer) Type() string { return "string" }
+func (t EdgeNgramTokenizer) Tokens(v interface{}) ([]string, error) {
+	str, ok := v.(string)
+	if !ok {
+		return nil, errors.Errorf("Edge ngram indices only supported for string types")
+	}
+	min, max := t.min, t.max
+	if min == 0 {
+		min, max = defaultEdgeNgramMin, defaultEdgeNgramMax
+	}
+	var tokens []string
+	for _, term := range uniqueTerms(termAnalyzer.Analyze([]byte(str))) {
+		runes := []rune(term)
+		if len(runes) < min {
+			tokens = append(tokens, term)
+			continue
+		}
+		for n := min; n <= max && n <= len(runes); n++ {
+			tokens = append(tokens, string(runes[:n]))
+		}
+	}
+	return x.RemoveDuplicates(tokens), nil
+}
+func (t EdgeNgramTokenizer) Identifier() byte { return IdentEdgeNgram }
+func (t EdgeNgramTokenizer) IsSortable() bool { return false }
+func (t EdgeNgramTokenizer) IsLossy() bool    { return true }
+
+// SoundexTokenizer returns the Soundex codes of the terms in string data, so that names that
+// sound alike, like Robert and Rupert, share a token.
+type SoundexTokenizer struct{}
+
+func (t SoundexTokenizer) Name() string { return "soundex" }
+func (t SoundexTokenizer) Type() string { return "string" }
+func (t SoundexTokenizer) Tokens(v interface{}) ([]string, error) {
+	return phoneticTokens(v, soundex)
+}
+func (t SoundexTokenizer) Identifier() byte { return IdentSoundex }
+func (t SoundexTokenizer) IsSortable() bool { return false }
+func (t SoundexTokenizer) IsLossy() bool    { return true }
+
+// MetaphoneTokenizer returns the Metaphone codes of the terms in string data. Metaphone knows
+// more of the rules of English spelling than Soundex, so it tells apart more of the names.
+type MetaphoneTokenizer struct{}
+
+func (t MetaphoneTokenizer) Name() string { return "metaphone" }
+func (t MetaphoneTokenizer) Type() string { return "string" }
+func (t MetaphoneTokenizer) Tokens(v interface{}) ([]string, error) {
+	return phoneticTokens(v, metaphone)
+}
+func (t MetaphoneTokenizer) Identifier() byte { return IdentMetaphone }
+func (t MetaphoneTokenizer) IsSortable() bool { return false }
+func (t MetaphoneTokenizer) IsLossy() bool    { return true }
+
+// phoneticTokens returns the codes given by encode for the terms of string data, leaving out the
+// terms without letters.
+func phoneticTokens(v interface{}, encode func(string) string) ([]string, error) {
+	str, ok := v.(string)
+	if !ok {
+		return nil, errors.Errorf("Phonetic indices only supported for string types")
+	}
+	var tokens []string
+	for _, term := range uniqueTerms(termAnalyzer.Analyze([]byte(str))) {
+		if code := encode(term); code != "" {
+			tokens = append(tokens, code)
+		}
+	}
+	return x.RemoveDuplicates(tokens), nil
+}
+
+// HNSWTokenizer marks the predicates of type float32vector that are indexed with a hierarchical
+// navigable small world graph, for the approximate nearest neighbor searches of similar_to. The
+// graph doesn't come from the tokens of each value, so it has no tokens. The posting package
+// keeps it in the index instead, under the tokens returned by HNSWNeighborsToken and
+// HNSWLevelToken. The zero value uses the euclidean distance.
+type HNSWTokenizer struct {
+	metric string
+}
+
+const defaultHNSWMetric = "euclidean"
+
+func (t HNSWTokenizer) Name() string {
+	if t.metric == "" {
+		return "hnsw"
+	}
+	return fmt.Sprintf("hnsw(metric: %q)", t.metric)
+}
+func (t HNSWTokenizer) Type() string { return "float32vector" }
+func (t HNSWTokenizer) Tokens(v interface{}) ([]string, error) {
+	if _, ok := v.([]float32); !ok {
+		return nil, errors.Errorf("HNSW indices only supported for float32vector types")
+	}
+	return nil, nil
+}
+func (t HNSWTokenizer) Identifier() byte { return IdentHNSW }
+func (t HNSWTokenizer) IsSortable() bool { return false }
+func (t HNSWTokenizer) IsLossy() bool    { return true }
+
+// Metric returns the metric used to compare the vectors in the graph.
+func (t HNSWTokenizer) Metric() types.VectorMetric {
+	metric, ok := types.GetVectorMetric(t.metric)
+	if !ok {
+		metric, _ = types.GetVectorMetric(defaultHNSWMetric)
+	}
+	return metric
+}
+
 // HashTokenizer returns hash tokens from string data.
 type HashTokenizer struct{}
 
@@ -19,10 +19,13 @@ package tok
 import (
 	"math"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/dgraph-io/dgraph/types"
 	"github.com/stretchr/testify/require"
+	geom "github.com/twpayne/go-geom"
 )
 
 type encL struct {
@@ -61,6 +64,30 @@ func TestIntEncoding(t *testing.T) {
 	}
 }
 
+func TestDecimalEncoding(t *testing.T) {
+	// The values are in increasing order.
+	vals := []string{"-1e20", "-123.45", "-12.3", "-12.25", "-12", "-1", "-0.5", "-0.05", "-0.045",
+		"0", "0.00001", "0.1", "0.12", "0.123", "1", "1.5", "9.99", "10", "12.3", "100", "1e20",
+		"123456789012345678901234567890.5"}
+	var tokens []string
+	for _, v := range vals {
+		r, err := types.ParseDecimal(v)
+		require.NoError(t, err)
+		tokens = append(tokens, encodeDecimal(r))
+	}
+	for i := 1; i < len(tokens); i++ {
+		require.True(t, tokens[i-1] < tokens[i], "%s %v vs %s %v",
+			vals[i-1], []byte(tokens[i-1]), vals[i], []byte(tokens[i]))
+	}
+
+	// Equal values of different forms have the same token.
+	a, err := types.ParseDecimal("12.50")
+	require.NoError(t, err)
+	b, err := types.ParseDecimal("1.25e1")
+	require.NoError(t, err)
+	require.Equal(t, encodeDecimal(a), encodeDecimal(b))
+}
+
 func TestFullTextTokenizer(t *testing.T) {
 	tokenizer, has := GetTokenizer("fulltext")
 	require.True(t, has)
@@ -115,6 +142,260 @@ func TestMonthTokenizer(t *testing.T) {
 	require.Equal(t, 1+2*2, len(tokens[0]))
 }
 
+func TestDayTokenizerInLocation(t *testing.T) {
+	tokenizer, err := GetTokenizerInLocation("day", "America/New_York")
+	require.NoError(t, err)
+	require.Equal(t, `day(tz: "America/New_York")`, tokenizer.Name())
+	require.Equal(t, byte(IdentDay), tokenizer.Identifier())
+
+	byName, has := GetTokenizer(tokenizer.Name())
+	require.True(t, has)
+	require.Equal(t, tokenizer, byName)
+
+	// 02:00 UTC is still the previous day in New York.
+	dt, err := time.Parse(time.RFC3339, "2017-01-02T02:00:00Z")
+	require.NoError(t, err)
+	tokens, err := BuildTokens(dt, tokenizer)
+	require.NoError(t, err)
+	prev, err := time.Parse(time.RFC3339, "2017-01-01T12:00:00Z")
+	require.NoError(t, err)
+	utcTokens, err := BuildTokens(prev, DayTokenizer{})
+	require.NoError(t, err)
+	require.Equal(t, utcTokens, tokens)
+}
+
+func TestTokenizerInLocationErrors(t *testing.T) {
+	_, err := GetTokenizerInLocation("exact", "America/New_York")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Tokenizer exact doesn't support the tz option")
+
+	_, err = GetTokenizerInLocation("day", "Mars/Olympus_Mons")
+	require.Error(t, err)
+
+	_, has := GetTokenizer(`day(tz: "Mars/Olympus_Mons")`)
+	require.False(t, has)
+	_, has = GetTokenizer(`exact(tz: "America/New_York")`)
+	require.False(t, has)
+}
+
+func TestExactTokenizerCollation(t *testing.T) {
+	tokenizer, err := GetTokenizerWithOptions("exact", map[string]string{"collation": "de"})
+	require.NoError(t, err)
+	require.Equal(t, `exact(collation: "de")`, tokenizer.Name())
+	require.Equal(t, byte(IdentCollation), tokenizer.Identifier())
+	require.True(t, tokenizer.IsSortable())
+	require.False(t, tokenizer.IsLossy())
+
+	// The tokens sort in the German order, which is not the bytewise order of the values.
+	words := []string{"Zebra", "ost", "Äpfel", "öl", "apfel"}
+	tokens := make(map[string]string)
+	for _, w := range words {
+		toks, err := tokenizer.Tokens(w)
+		require.NoError(t, err)
+		require.Len(t, toks, 1)
+		tokens[w] = toks[0]
+	}
+	sort.Slice(words, func(i, j int) bool { return tokens[words[i]] < tokens[words[j]] })
+	require.Equal(t, []string{"apfel", "Äpfel", "öl", "ost", "Zebra"}, words)
+
+	byName, has := GetTokenizer(tokenizer.Name())
+	require.True(t, has)
+	require.Equal(t, tokenizer, byName)
+
+	_, err = GetTokenizerWithOptions("exact", map[string]string{"collation": "not a locale"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `Invalid collation "not a locale" for tokenizer exact`)
+}
+
+func TestTermTokenizerCasefold(t *testing.T) {
+	tokenizer, err := GetTokenizerWithOptions("term", map[string]string{"normalize": "nfkc_casefold"})
+	require.NoError(t, err)
+	require.Equal(t, `term(normalize: "nfkc_casefold")`, tokenizer.Name())
+	require.Equal(t, byte(IdentTerm), tokenizer.Identifier())
+
+	// Full case folding maps ß to ss and the final sigma to sigma. The equivalent forms of
+	// a character, as the composed and decomposed é, get the same token.
+	tokens, err := tokenizer.Tokens("STRASSE straße Straße")
+	require.NoError(t, err)
+	require.Equal(t, []string{"strasse"}, tokens)
+	tokens, err = tokenizer.Tokens("ΣΊΣΥΦΟΣ σίσυφος café cafe\u0301")
+	require.NoError(t, err)
+	require.Equal(t, []string{"café", "σίσυφοσ"}, tokens)
+	// The compatibility characters are folded after they are normalized.
+	tokens, err = tokenizer.Tokens("\u210cello hello")
+	require.NoError(t, err)
+	require.Equal(t, []string{"hello"}, tokens)
+
+	// Without the option, the terms are only lowercased.
+	tokens, err = TermTokenizer{}.Tokens("STRASSE straße")
+	require.NoError(t, err)
+	require.Equal(t, []string{"strasse", "straße"}, tokens)
+
+	byName, has := GetTokenizer(tokenizer.Name())
+	require.True(t, has)
+	require.Equal(t, tokenizer, byName)
+	require.Equal(t, TermTokenizer{lang: "de", fold: true}, GetTokenizerForLang(tokenizer, "de"))
+
+	_, err = GetTokenizerWithOptions("term", map[string]string{"normalize": "nfc"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(),
+		`Invalid normalization "nfc" for tokenizer term, expected "nfkc_casefold"`)
+	_, err = GetTokenizerWithOptions("term", map[string]string{"fold": "true"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Expected tokenizer options of the form")
+}
+
+func TestFullTextTokenizerCasefold(t *testing.T) {
+	tokenizer, has := GetTokenizer(`fulltext(normalize: "nfkc_casefold")`)
+	require.True(t, has)
+	require.Equal(t, byte(IdentFullText), tokenizer.Identifier())
+
+	// The stop words and the stems are looked up in the folded terms.
+	tokens, err := BuildTokens("Die STRASSEN und die Straßen", GetTokenizerForLang(tokenizer, "de"))
+	require.NoError(t, err)
+	require.Equal(t, []string{encodeToken("strass", IdentFullText)}, tokens)
+
+	counts, n := GetTokenizerForLang(tokenizer, "de").(FullTextTokenizer).TermCounts(
+		"Die STRASSEN und die Straßen")
+	require.Equal(t, map[string]int{encodeToken("strass", IdentFullText): 2}, counts)
+	require.Equal(t, 2, n)
+}
+
+func TestGeoTokenizerOptions(t *testing.T) {
+	tokenizer, err := GetTokenizerWithOptions("geo",
+		map[string]string{"minlevel": "10", "maxlevel": "20", "maxcells": "4"})
+	require.NoError(t, err)
+	require.Equal(t, "geo(minlevel: 10, maxlevel: 20, maxcells: 4)", tokenizer.Name())
+	require.Equal(t, byte(IdentGeo), tokenizer.Identifier())
+	require.Equal(t, types.GeoIndexOptions{MinLevel: 10, MaxLevel: 20, MaxCells: 4},
+		tokenizer.(GeoTokenizer).Options())
+
+	// A point has a token for the cell of each level, and one for the smallest cell.
+	point := geom.NewPoint(geom.XY).MustSetCoords(geom.Coord{-122.082506, 37.4249518})
+	tokens, err := tokenizer.Tokens(point)
+	require.NoError(t, err)
+	require.Len(t, tokens, 20-10+1+1)
+
+	byName, has := GetTokenizer(tokenizer.Name())
+	require.True(t, has)
+	require.Equal(t, tokenizer, byName)
+
+	// The default levels give the tokenizer without options.
+	tokenizer, err = GetTokenizerWithOptions("geo",
+		map[string]string{"minlevel": "5", "maxlevel": "16", "maxcells": "18"})
+	require.NoError(t, err)
+	require.Equal(t, "geo", tokenizer.Name())
+	require.Equal(t, types.DefaultGeoIndexOptions, tokenizer.(GeoTokenizer).Options())
+
+	_, err = GetTokenizerWithOptions("geo", map[string]string{"minlevel": "10"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(),
+		"Expected tokenizer options of the form (minlevel: 5, maxlevel: 16, maxcells: 18)")
+	_, err = GetTokenizerWithOptions("geo",
+		map[string]string{"minlevel": "10", "maxlevel": "x", "maxcells": "4"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `Invalid maxlevel "x" for tokenizer geo`)
+	_, err = GetTokenizerWithOptions("geo",
+		map[string]string{"minlevel": "10", "maxlevel": "40", "maxcells": "4"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "0 <= minlevel <= maxlevel <= 30")
+}
+
+func TestNgramTokenizer(t *testing.T) {
+	tokenizer, has := GetTokenizer("ngram")
+	require.True(t, has)
+	tokens, err := tokenizer.Tokens("Dgraph is")
+	require.NoError(t, err)
+	require.Equal(t, []string{"ap", "aph", "dg", "dgr", "dgra", "gr", "gra", "grap", "is", "ph",
+		"ra", "rap", "raph"}, tokens)
+
+	tokenizer, err = GetTokenizerWithOptions("ngram", map[string]string{"min": "3", "max": "3"})
+	require.NoError(t, err)
+	require.Equal(t, "ngram(min: 3, max: 3)", tokenizer.Name())
+	require.Equal(t, byte(IdentNgram), tokenizer.Identifier())
+	tokens, err = tokenizer.Tokens("Dgraph is")
+	require.NoError(t, err)
+	require.Equal(t, []string{"aph", "dgr", "gra", "is", "rap"}, tokens)
+
+	byName, has := GetTokenizer(tokenizer.Name())
+	require.True(t, has)
+	require.Equal(t, tokenizer, byName)
+}
+
+func TestNgramTokenizerErrors(t *testing.T) {
+	_, err := GetTokenizerWithOptions("ngram", map[string]string{"min": "3"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Expected tokenizer options of the form (min: 2, max: 4)")
+
+	_, err = GetTokenizerWithOptions("ngram", map[string]string{"min": "3", "max": "2"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "must satisfy 1 <= min <= max")
+
+	_, err = GetTokenizerWithOptions("ngram", map[string]string{"min": "a", "max": "2"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `Invalid min length "a"`)
+
+	_, has := GetTokenizer("ngram(min: 0, max: 2)")
+	require.False(t, has)
+	_, has = GetTokenizer("ngram(max: 4, min: 2)")
+	require.False(t, has)
+}
+
+func TestEdgeNgramTokenizer(t *testing.T) {
+	tokenizer, has := GetTokenizer("edgengram")
+	require.True(t, has)
+	tokens, err := tokenizer.Tokens("Dgraph is a graph database")
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "da", "dat", "data", "datab", "databa", "databas", "database",
+		"dg", "dgr", "dgra", "dgrap", "dgraph", "gr", "gra", "grap", "graph", "is"}, tokens)
+
+	tokenizer, err = GetTokenizerWithOptions("edgengram", map[string]string{"min": "3", "max": "4"})
+	require.NoError(t, err)
+	require.Equal(t, "edgengram(min: 3, max: 4)", tokenizer.Name())
+	require.Equal(t, byte(IdentEdgeNgram), tokenizer.Identifier())
+	tokens, err = tokenizer.Tokens("Dgraph is")
+	require.NoError(t, err)
+	require.Equal(t, []string{"dgr", "dgra", "is"}, tokens)
+
+	byName, has := GetTokenizer(tokenizer.Name())
+	require.True(t, has)
+	require.Equal(t, tokenizer, byName)
+
+	_, err = GetTokenizerWithOptions("edgengram", map[string]string{"min": "3", "max": "2"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "The lengths of tokenizer edgengram must satisfy")
+}
+
+func TestHNSWTokenizer(t *testing.T) {
+	tokenizer, has := GetTokenizer("hnsw")
+	require.True(t, has)
+	require.Equal(t, byte(IdentHNSW), tokenizer.Identifier())
+	require.Equal(t, "float32vector", tokenizer.Type())
+	tokens, err := tokenizer.Tokens([]float32{0.1, 0.2})
+	require.NoError(t, err)
+	require.Empty(t, tokens)
+	// The euclidean distance is squared.
+	require.Equal(t, float64(4), tokenizer.(HNSWTokenizer).Metric()([]float32{1, 0},
+		[]float32{-1, 0}))
+
+	tokenizer, err = GetTokenizerWithOptions("hnsw", map[string]string{"metric": "cosine"})
+	require.NoError(t, err)
+	require.Equal(t, `hnsw(metric: "cosine")`, tokenizer.Name())
+	require.Equal(t, float64(2), tokenizer.(HNSWTokenizer).Metric()([]float32{1, 0},
+		[]float32{-1, 0}))
+	byName, has := GetTokenizer(tokenizer.Name())
+	require.True(t, has)
+	require.Equal(t, tokenizer, byName)
+
+	tokenizer, err = GetTokenizerWithOptions("hnsw", map[string]string{"metric": "euclidean"})
+	require.NoError(t, err)
+	require.Equal(t, "hnsw", tokenizer.Name())
+
+	_, err = GetTokenizerWithOptions("hnsw", map[string]string{"metric": "manhattan"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `Invalid metric "manhattan" for tokenizer hnsw`)
+}
+
 func TestDateTimeTokenizer(t *testing.T) {
 	var err error
 	tokenizer, has := GetTokenizer("year")
@@ -142,6 +423,21 @@ func TestFullTextTokenizerLang(t *testing.T) {
 	require.Equal(t, []string{encodeToken("auffassung", id), encodeToken("katz", id)}, tokens)
 }
 
+func TestFullTextTermCounts(t *testing.T) {
+	counts, total := GetFullTextTermCounts("Katzen und Auffassung und Auffassung", "de")
+	// "und" is a stop word, so it is not counted.
+	require.Equal(t, 3, total)
+	id := FullTextTokenizer{}.Identifier()
+	require.Equal(t, map[string]int{
+		encodeToken("auffassung", id): 2,
+		encodeToken("katz", id):       1,
+	}, counts)
+
+	counts, total = GetFullTextTermCounts("", "en")
+	require.Equal(t, 0, total)
+	require.Empty(t, counts)
+}
+
 func TestTermTokenizer(t *testing.T) {
 	tokenizer, has := GetTokenizer("term")
 	require.True(t, has)
@@ -333,3 +629,56 @@ func checkSortedAndUnique(t *testing.T, tokens []string) {
 func BenchmarkTermTokenizer(b *testing.B) {
 	b.Skip() // tmp
 }
+
+func TestFullTextLengthToken(t *testing.T) {
+	for _, n := range []int{0, 7, 300} {
+		token := FullTextLengthToken(n)
+		require.True(t, strings.HasPrefix(token, FullTextLengthPrefix()))
+		got, err := ParseFullTextLengthToken(token)
+		require.NoError(t, err)
+		require.Equal(t, n, got)
+	}
+
+	// Fulltext terms don't share the prefix of the length tokens.
+	tokens, err := BuildTokens("the quick brown fox", FullTextTokenizer{})
+	require.NoError(t, err)
+	for _, token := range tokens {
+		require.False(t, strings.HasPrefix(token, FullTextLengthPrefix()))
+		_, err := ParseFullTextLengthToken(token)
+		require.Error(t, err)
+	}
+}
+
+func TestCompositeToken(t *testing.T) {
+	build := func(leading []string, val int64) string {
+		tokens, err := BuildTokens(val, IntTokenizer{})
+		require.NoError(t, err)
+		require.Len(t, tokens, 1)
+		return CompositeToken(leading, tokens[0])
+	}
+
+	// The tokens of the same leading values keep the order of the last value.
+	us := []string{"US"}
+	require.True(t, build(us, -3) < build(us, 2))
+	require.True(t, build(us, 2) < build(us, 40))
+
+	// The tokens start with the identifier of the tokenizer, followed by the leading values.
+	prefix := CompositeToken(us, string(IntTokenizer{}.Identifier()))
+	for _, val := range []int64{-3, 2, 40} {
+		require.True(t, strings.HasPrefix(build(us, val), prefix))
+	}
+	require.False(t, strings.HasPrefix(build([]string{"USA"}, 2), prefix))
+	require.False(t, strings.HasPrefix(build([]string{"U", "S"}, 2), prefix))
+}
+
+func TestCompositeValue(t *testing.T) {
+	value := CompositeValue([]string{"US", "New \"York\""})
+	values, err := ParseCompositeValue(value, 2)
+	require.NoError(t, err)
+	require.Equal(t, []string{"US", "New \"York\""}, values)
+
+	_, err = ParseCompositeValue(value, 3)
+	require.Error(t, err)
+	_, err = ParseCompositeValue("US", 1)
+	require.Error(t, err)
+}
@@ -17,6 +17,9 @@
 package tok
 
 import (
+	"encoding/binary"
+	"encoding/json"
+
 	"github.com/pkg/errors"
 	"golang.org/x/text/collate"
 	"golang.org/x/text/language"
@@ -31,13 +34,13 @@ func GetTokenizerForLang(t Tokenizer, lang string) Tokenizer {
 	if lang == "" {
 		return t
 	}
-	switch t.(type) {
+	switch t := t.(type) {
 	case FullTextTokenizer:
 		// We must return a new instance because another goroutine might be calling this
 		// with a different lang.
-		return FullTextTokenizer{lang: lang}
+		return FullTextTokenizer{lang: lang, fold: t.fold}
 	case TermTokenizer:
-		return TermTokenizer{lang: lang}
+		return TermTokenizer{lang: lang, fold: t.fold}
 	case ExactTokenizer:
 		langTag, err := language.Parse(lang)
 		// We default to english if the language is not supported.
@@ -77,3 +80,95 @@ func GetFullTextTokens(funcArgs []string, lang string) ([]string, error) {
 	}
 	return BuildTokens(funcArgs[0], FullTextTokenizer{lang: lang})
 }
+
+// GetFullTextTermCounts returns the number of occurrences of each fulltext token in the given
+// text, along with the total number of tokens in it. The tokens are encoded in the same way as
+// the ones returned by GetFullTextTokens.
+func GetFullTextTermCounts(text string, lang string) (map[string]int, int) {
+	return FullTextTokenizer{lang: lang}.TermCounts(text)
+}
+
+// TermCounts returns the number of occurrences of each token of the tokenizer in the given text,
+// along with the total number of tokens in it.
+func (t FullTextTokenizer) TermCounts(text string) (map[string]int, int) {
+	tokens := t.analyze(text)
+	counts := make(map[string]int, len(tokens))
+	for i := range tokens {
+		counts[encodeToken(string(tokens[i].Term), t.Identifier())]++
+	}
+	return counts, len(tokens)
+}
+
+// FullTextLengthPrefix is the prefix of the tokens returned by FullTextLengthToken. Fulltext
+// terms never start with a zero byte, so these tokens can't be mistaken for one of them.
+func FullTextLengthPrefix() string {
+	return encodeToken("\x00", IdentFullText)
+}
+
+// FullTextLengthToken returns the token under which the fulltext index keeps the nodes whose
+// value has the given number of fulltext tokens. Reading these tokens gives the number of values
+// of a predicate and their total length without reading the values.
+func FullTextLengthToken(n int) string {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], uint32(n))
+	return FullTextLengthPrefix() + string(buf[:])
+}
+
+// HNSWNeighborsToken returns the token under which the hnsw index keeps the neighbors of the node
+// with the given uid in the given level of the graph.
+func HNSWNeighborsToken(uid uint64, level int) string {
+	var buf [9]byte
+	buf[0] = byte(level)
+	binary.BigEndian.PutUint64(buf[1:], uid)
+	return encodeToken("n"+string(buf[:]), IdentHNSW)
+}
+
+// HNSWLevelToken returns the token under which the hnsw index keeps the nodes whose highest level
+// in the graph is the given level. The searches start from a node of the highest level.
+func HNSWLevelToken(level int) string {
+	return encodeToken("l"+string([]byte{byte(level)}), IdentHNSW)
+}
+
+// ParseFullTextLengthToken returns the length encoded in a token returned by FullTextLengthToken.
+func ParseFullTextLengthToken(token string) (int, error) {
+	prefix := FullTextLengthPrefix()
+	if len(token) != len(prefix)+4 || token[:len(prefix)] != prefix {
+		return 0, errors.Errorf("Invalid fulltext length token: %q", token)
+	}
+	return int(binary.BigEndian.Uint32([]byte(token[len(prefix):]))), nil
+}
+
+// CompositeToken returns the token of a composite index for the values of all the predicates it
+// spans but the last one, and a token of the value of the last predicate. The values come right
+// after the identifier of the tokenizer, each prefixed by its length, so the tokens sharing the
+// same values are contiguous and sorted like the tokens of the last predicate.
+func CompositeToken(values []string, token string) string {
+	if len(token) == 0 {
+		return token
+	}
+	buf := []byte{token[0]}
+	var length [binary.MaxVarintLen64]byte
+	for _, val := range values {
+		n := binary.PutUvarint(length[:], uint64(len(val)))
+		buf = append(buf, length[:n]...)
+		buf = append(buf, val...)
+	}
+	return string(append(buf, token[1:]...))
+}
+
+// CompositeValue returns the value stored by a composite index for a node, made of the values of
+// the predicates it spans converted to strings.
+func CompositeValue(values []string) string {
+	data, _ := json.Marshal(values)
+	return string(data)
+}
+
+// ParseCompositeValue returns the values of the n predicates of a composite index stored in a
+// value returned by CompositeValue.
+func ParseCompositeValue(value string, n int) ([]string, error) {
+	var values []string
+	if err := json.Unmarshal([]byte(value), &values); err != nil || len(values) != n {
+		return nil, errors.Errorf("Invalid value of composite index: %q", value)
+	}
+	return values, nil
+}
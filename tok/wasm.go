@@ -0,0 +1,305 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tok
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/sys"
+
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// A WASM tokenizer is a WebAssembly module that exports its memory as "memory" and the
+// following functions, where a packed i64 holds a pointer in its upper 32 bits and a length in
+// its lower 32 bits:
+//
+//	dgraph_name() -> i64                  the packed name of the tokenizer
+//	dgraph_type() -> i64                  the packed type of the values, like "string"
+//	dgraph_identifier() -> i32            the identifier byte, in the range 0x80 - 0xff
+//	dgraph_alloc(size: i32) -> i32        a pointer to size bytes for the value to tokenize
+//	dgraph_tokens(ptr: i32, len: i32) -> i64
+//	dgraph_free(ptr: i32, len: i32)       optional, frees a buffer
+//
+// dgraph_tokens returns the packed output, which starts with a status byte. If the status is 0,
+// it's followed by the tokens, each as a little-endian u32 length and the bytes of the token.
+// Otherwise, it's followed by an error message. Once the output is copied, the buffer returned by
+// dgraph_alloc and then the output are passed to dgraph_free, if the module exports it. Since
+// the memory of an instance never shrinks, an instance whose memory grew past
+// wasmMemoryHighWater is closed after its call instead of being reused, so that a module that
+// doesn't free its buffers can't run out of memory. The module is instantiated without any
+// imports, so it can't reach the file system, the network or the clock, and each call is
+// stopped after the time limit given when loading it.
+const (
+	wasmMemoryLimitPages = 1024     // 64 MB.
+	wasmMemoryHighWater  = 48 << 20 // Bytes.
+	wasmStatusOK         = 0
+)
+
+// LoadWasmTokenizer reads and loads a custom tokenizer from the given WebAssembly file. Each
+// call to the tokenizer is stopped after timeout.
+func LoadWasmTokenizer(wasmFile string, timeout time.Duration) {
+	glog.Infof("Loading WASM tokenizer from %q", wasmFile)
+	code, err := ioutil.ReadFile(wasmFile)
+	x.Checkf(err, "could not read WASM tokenizer plugin file")
+	tokenizer, err := newWasmTokenizer(code, timeout)
+	x.Checkf(err, "could not load WASM tokenizer plugin file %q", wasmFile)
+
+	id := tokenizer.Identifier()
+	x.AssertTruef(id >= IdentCustom,
+		"custom tokenizer identifier byte must be >= 0x80, but was %#x", id)
+	registerTokenizer(CustomTokenizer{PluginTokenizer: tokenizer})
+}
+
+// wasmTokenizer is a PluginTokenizer that runs the functions of a WebAssembly module.
+type wasmTokenizer struct {
+	name string
+	typ  string
+	id   byte
+	pool *wasmPool
+}
+
+func newWasmTokenizer(code []byte, timeout time.Duration) (*wasmTokenizer, error) {
+	ctx := context.Background()
+	rt := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithCloseOnContextDone(true).
+		WithMemoryLimitPages(wasmMemoryLimitPages))
+	compiled, err := rt.CompileModule(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	pool := &wasmPool{
+		runtime:   rt,
+		compiled:  compiled,
+		timeout:   timeout,
+		instances: make(chan api.Module, runtime.NumCPU()),
+	}
+
+	t := &wasmTokenizer{pool: pool}
+	name, err := pool.call("dgraph_name", nil)
+	if err != nil {
+		return nil, err
+	}
+	typ, err := pool.call("dgraph_type", nil)
+	if err != nil {
+		return nil, err
+	}
+	t.name, t.typ = string(name), string(typ)
+	switch t.typ {
+	case "string", "int", "float", "bool", "datetime":
+	default:
+		return nil, errors.Errorf("Invalid type %q for WASM tokenizer %s", t.typ, t.name)
+	}
+	err = pool.with(func(ctx context.Context, m api.Module) error {
+		res, err := callExport(ctx, m, "dgraph_identifier")
+		t.id = byte(res)
+		return err
+	})
+	return t, err
+}
+
+func (t *wasmTokenizer) Name() string     { return t.name }
+func (t *wasmTokenizer) Type() string     { return t.typ }
+func (t *wasmTokenizer) Identifier() byte { return t.id }
+func (t *wasmTokenizer) Tokens(v interface{}) ([]string, error) {
+	val, err := encodeWasmValue(t.typ, v)
+	if err != nil {
+		return nil, err
+	}
+	out, err := t.pool.call("dgraph_tokens", val)
+	if err != nil {
+		return nil, errors.Wrapf(err, "while running WASM tokenizer %s", t.name)
+	}
+	if len(out) == 0 {
+		return nil, errors.Errorf("WASM tokenizer %s returned no status", t.name)
+	}
+	if out[0] != wasmStatusOK {
+		return nil, errors.Errorf("WASM tokenizer %s: %s", t.name, out[1:])
+	}
+
+	var tokens []string
+	for out = out[1:]; len(out) > 0; {
+		if len(out) < 4 {
+			return nil, errors.Errorf("WASM tokenizer %s returned a truncated token", t.name)
+		}
+		n := binary.LittleEndian.Uint32(out)
+		if uint64(len(out)-4) < uint64(n) {
+			return nil, errors.Errorf("WASM tokenizer %s returned a truncated token", t.name)
+		}
+		tokens = append(tokens, string(out[4:4+n]))
+		out = out[4+n:]
+	}
+	return tokens, nil
+}
+
+// encodeWasmValue encodes the value to tokenize for a WASM tokenizer of type typ. Strings are
+// passed as UTF-8, ints and floats as little-endian 64-bit values, bools as a single byte and
+// datetimes in the RFC 3339 format.
+func encodeWasmValue(typ string, v interface{}) ([]byte, error) {
+	switch val := v.(type) {
+	case string:
+		if typ == "string" {
+			return []byte(val), nil
+		}
+	case int64:
+		if typ == "int" {
+			buf := make([]byte, 8)
+			binary.LittleEndian.PutUint64(buf, uint64(val))
+			return buf, nil
+		}
+	case float64:
+		if typ == "float" {
+			buf := make([]byte, 8)
+			binary.LittleEndian.PutUint64(buf, math.Float64bits(val))
+			return buf, nil
+		}
+	case bool:
+		if typ == "bool" {
+			if val {
+				return []byte{1}, nil
+			}
+			return []byte{0}, nil
+		}
+	case time.Time:
+		if typ == "datetime" {
+			return []byte(val.Format(time.RFC3339Nano)), nil
+		}
+	}
+	return nil, errors.Errorf("Value of type %T can't be tokenized by a tokenizer of type %s",
+		v, typ)
+}
+
+// wasmPool keeps the instances of a WebAssembly module, since an instance can only run one call
+// at a time. The instances that fail or time out, or whose memory grew past
+// wasmMemoryHighWater, are closed instead of being put back.
+type wasmPool struct {
+	runtime   wazero.Runtime
+	compiled  wazero.CompiledModule
+	timeout   time.Duration
+	instances chan api.Module
+	count     uint64
+}
+
+func (p *wasmPool) with(f func(ctx context.Context, m api.Module) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	var m api.Module
+	select {
+	case m = <-p.instances:
+	default:
+		// Module names must be unique within the runtime.
+		name := fmt.Sprintf("tokenizer-%d", atomic.AddUint64(&p.count, 1))
+		var err error
+		m, err = p.runtime.InstantiateModule(ctx, p.compiled,
+			wazero.NewModuleConfig().WithName(name).WithStartFunctions("_initialize"))
+		if err != nil {
+			return wasmError(err, p.timeout)
+		}
+	}
+
+	if err := f(ctx, m); err != nil {
+		_ = m.Close(context.Background())
+		return wasmError(err, p.timeout)
+	}
+	if m.Memory().Size() > wasmMemoryHighWater {
+		_ = m.Close(context.Background())
+		return nil
+	}
+	select {
+	case p.instances <- m:
+	default:
+		_ = m.Close(context.Background())
+	}
+	return nil
+}
+
+// call calls the export fn with the value in input, or without arguments if input is nil, and
+// returns a copy of the packed output. If there's an input, its buffer and the output are
+// freed afterwards.
+func (p *wasmPool) call(fn string, input []byte) ([]byte, error) {
+	var out []byte
+	err := p.with(func(ctx context.Context, m api.Module) error {
+		var params []uint64
+		if input != nil {
+			ptr, err := callExport(ctx, m, "dgraph_alloc", uint64(len(input)))
+			if err != nil {
+				return err
+			}
+			if !m.Memory().Write(uint32(ptr), input) {
+				return errors.Errorf("dgraph_alloc returned memory out of range")
+			}
+			params = []uint64{uint64(uint32(ptr)), uint64(len(input))}
+		}
+		packed, err := callExport(ctx, m, fn, params...)
+		if err != nil {
+			return err
+		}
+		buf, ok := m.Memory().Read(uint32(packed>>32), uint32(packed))
+		if !ok {
+			return errors.Errorf("%s returned memory out of range", fn)
+		}
+		out = append([]byte{}, buf...)
+
+		free := m.ExportedFunction("dgraph_free")
+		if input == nil || free == nil {
+			return nil
+		}
+		if _, err := free.Call(ctx, params...); err != nil {
+			return err
+		}
+		_, err = free.Call(ctx, packed>>32, uint64(uint32(packed)))
+		return err
+	})
+	return out, err
+}
+
+func callExport(ctx context.Context, m api.Module, fn string, params ...uint64) (uint64, error) {
+	f := m.ExportedFunction(fn)
+	if f == nil {
+		return 0, errors.Errorf("function %s isn't exported", fn)
+	}
+	if m.Memory() == nil {
+		return 0, errors.Errorf("memory isn't exported")
+	}
+	res, err := f.Call(ctx, params...)
+	if err != nil {
+		return 0, err
+	}
+	if len(res) != 1 {
+		return 0, errors.Errorf("function %s must return a single value", fn)
+	}
+	return res[0], nil
+}
+
+func wasmError(err error, timeout time.Duration) error {
+	if exit, ok := err.(*sys.ExitError); ok && exit.ExitCode() == sys.ExitCodeDeadlineExceeded {
+		return errors.Errorf("call timed out after %s", timeout)
+	}
+	return err
+}
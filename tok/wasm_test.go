@@ -0,0 +1,264 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tok
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testWasmModule assembles a WebAssembly module with the exports of a WASM tokenizer named
+// wasmexact of type string, whose dgraph_tokens function has the given body. The name is stored
+// at offset 16, the type at offset 64 and the other data segments at their offsets.
+func testWasmModule(tokensBody []byte, data map[int]string) []byte {
+	return testWasmAllocModule([]byte{0x41, 0x80, 0x20}, nil, tokensBody, data) // i32.const 4096
+}
+
+// testWasmAllocModule is like testWasmModule, but with the given bodies for dgraph_alloc and
+// dgraph_free, which isn't exported if freeBody is nil. The module has a mutable i32 global,
+// which starts at 4096.
+func testWasmAllocModule(allocBody, freeBody, tokensBody []byte, data map[int]string) []byte {
+	data[16] = "wasmexact"
+	data[64] = "string"
+
+	types := [][]byte{{0x60, 0x00, 0x01, 0x7e}, // () -> i64
+		{0x60, 0x00, 0x01, 0x7f},             // () -> i32
+		{0x60, 0x01, 0x7f, 0x01, 0x7f},       // (i32) -> i32
+		{0x60, 0x02, 0x7f, 0x7f, 0x01, 0x7e}, // (i32, i32) -> i64
+		{0x60, 0x02, 0x7f, 0x7f, 0x00}}       // (i32, i32) -> ()
+	funcs := [][]byte{{0}, {0}, {1}, {2}, {3}}
+	memory := vec([]byte{0x00, 0x01})                          // At least one page.
+	globals := vec([]byte{0x7f, 0x01, 0x41, 0x80, 0x20, 0x0b}) // mut i32 = 4096
+	exports := [][]byte{export("memory", 2, 0), export("dgraph_name", 0, 0),
+		export("dgraph_type", 0, 1), export("dgraph_identifier", 0, 2),
+		export("dgraph_alloc", 0, 3), export("dgraph_tokens", 0, 4)}
+	code := [][]byte{funcBody(packed(16, len(data[16]))),
+		funcBody(packed(64, len(data[64]))),
+		funcBody([]byte{0x41, 0x90, 0x01}), // i32.const 0x90
+		funcBody(allocBody),
+		funcBody(tokensBody)}
+	if freeBody != nil {
+		funcs = append(funcs, []byte{4})
+		exports = append(exports, export("dgraph_free", 0, 5))
+		code = append(code, funcBody(freeBody))
+	}
+	var segments [][]byte
+	for offset, bytes := range data {
+		seg := append([]byte{0x00, 0x41}, sleb(int64(offset))...)
+		seg = append(seg, 0x0b)
+		seg = append(seg, uleb(uint64(len(bytes)))...)
+		segments = append(segments, append(seg, bytes...))
+	}
+
+	module := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	module = append(module, section(1, vec(types...))...)
+	module = append(module, section(3, vec(funcs...))...)
+	module = append(module, section(5, memory)...)
+	module = append(module, section(6, globals)...)
+	module = append(module, section(7, vec(exports...))...)
+	module = append(module, section(10, vec(code...))...)
+	return append(module, section(11, vec(segments...))...)
+}
+
+// packed returns the instruction that pushes the packed pointer ptr and length n.
+func packed(ptr, n int) []byte {
+	return append([]byte{0x42}, sleb(int64(ptr)<<32|int64(n))...) // i64.const
+}
+
+func uleb(v uint64) []byte {
+	var out []byte
+	for {
+		b := byte(v & 0x7f)
+		v >>= 7
+		if v == 0 {
+			return append(out, b)
+		}
+		out = append(out, b|0x80)
+	}
+}
+
+func sleb(v int64) []byte {
+	var out []byte
+	for {
+		b := byte(v & 0x7f)
+		v >>= 7
+		if (v == 0 && b&0x40 == 0) || (v == -1 && b&0x40 != 0) {
+			return append(out, b)
+		}
+		out = append(out, b|0x80)
+	}
+}
+
+func vec(items ...[]byte) []byte {
+	out := uleb(uint64(len(items)))
+	for _, item := range items {
+		out = append(out, item...)
+	}
+	return out
+}
+
+func section(id byte, contents []byte) []byte {
+	return append(append([]byte{id}, uleb(uint64(len(contents)))...), contents...)
+}
+
+func export(name string, kind, index byte) []byte {
+	return append(append(uleb(uint64(len(name))), name...), kind, index)
+}
+
+func funcBody(instrs []byte) []byte {
+	body := append([]byte{0x00}, instrs...) // No locals.
+	body = append(body, 0x0b)
+	return append(uleb(uint64(len(body))), body...)
+}
+
+// identityTokens returns the value as the only token, by writing the status at 1024, the length
+// at 1025 and the value at 1029.
+var identityTokens = []byte{
+	0x41, 0x80, 0x08, 0x41, 0x00, 0x3a, 0x00, 0x00, // i32.store8(1024, 0)
+	0x41, 0x81, 0x08, 0x20, 0x01, 0x36, 0x00, 0x00, // i32.store(1025, len)
+	0x41, 0x85, 0x08, 0x20, 0x00, 0x20, 0x01, 0xfc, 0x0a, 0x00, 0x00, // memory.copy(1029, ptr, len)
+	0x42, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x01, // i64.const 1024 << 32
+	0x20, 0x01, 0xad, 0x42, 0x05, 0x7c, 0x84, // | (i64(len) + 5)
+}
+
+func TestWasmTokenizer(t *testing.T) {
+	tokenizer, err := newWasmTokenizer(testWasmModule(identityTokens, map[int]string{}),
+		time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "wasmexact", tokenizer.Name())
+	require.Equal(t, "string", tokenizer.Type())
+	require.Equal(t, byte(0x90), tokenizer.Identifier())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				tokens, err := tokenizer.Tokens("Jon Smith")
+				require.NoError(t, err)
+				require.Equal(t, []string{"Jon Smith"}, tokens)
+			}
+		}()
+	}
+	wg.Wait()
+
+	_, err = tokenizer.Tokens(int64(1))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Value of type int64 can't be tokenized")
+}
+
+// bumpAlloc allocates from the global, which is the end of the allocated memory, and grows the
+// memory when it's full. It never reuses memory on its own.
+var bumpAlloc = []byte{
+	0x23, 0x00, // global.get 0, the result
+	0x23, 0x00, 0x20, 0x00, 0x6a, 0x24, 0x00, // global.set 0 (global.get 0 + size)
+	0x02, 0x40, // block
+	0x23, 0x00, 0x3f, 0x00, 0x41, 0x10, 0x74, 0x4d, 0x0d, 0x00, // br_if 0 (end <= pages << 16)
+	0x23, 0x00, 0x3f, 0x00, 0x41, 0x10, 0x74, 0x6b, // end - pages << 16
+	0x41, 0x10, 0x76, 0x41, 0x01, 0x6a, 0x40, 0x00, // memory.grow ((... >> 16) + 1)
+	0x41, 0x7f, 0x46, 0x04, 0x40, 0x00, 0x0b, // if (... == -1) unreachable
+	0x0b, // end
+}
+
+// bumpFree frees all the memory allocated by bumpAlloc, by resetting the global to 4096.
+var bumpFree = []byte{0x41, 0x80, 0x20, 0x24, 0x00}
+
+func TestWasmTokenizerAlloc(t *testing.T) {
+	value := strings.Repeat("x", 1<<20)
+	output := "\x00\x01\x00\x00\x00a"
+	tokensBody := packed(2048, len(output))
+
+	// Without dgraph_free, the memory of an instance grows with every call, so the instances
+	// are replaced once they pass the high-water mark, before they reach the limit.
+	module := testWasmAllocModule(bumpAlloc, nil, tokensBody, map[int]string{2048: output})
+	tokenizer, err := newWasmTokenizer(module, time.Second)
+	require.NoError(t, err)
+	for i := 0; i < 200; i++ {
+		tokens, err := tokenizer.Tokens(value)
+		require.NoError(t, err)
+		require.Equal(t, []string{"a"}, tokens)
+	}
+	require.True(t, tokenizer.pool.count > 1)
+
+	// With dgraph_free, the same instance is used for every call.
+	module = testWasmAllocModule(bumpAlloc, bumpFree, tokensBody, map[int]string{2048: output})
+	tokenizer, err = newWasmTokenizer(module, time.Second)
+	require.NoError(t, err)
+	for i := 0; i < 200; i++ {
+		tokens, err := tokenizer.Tokens(value)
+		require.NoError(t, err)
+		require.Equal(t, []string{"a"}, tokens)
+	}
+	require.Equal(t, uint64(1), tokenizer.pool.count)
+}
+
+func TestWasmTokenizerOutput(t *testing.T) {
+	tests := []struct {
+		output string
+		tokens []string
+		err    string
+	}{
+		{output: "\x00\x02\x00\x00\x00ab\x01\x00\x00\x00c", tokens: []string{"ab", "c"}},
+		{output: "\x00"},
+		{output: "\x01bad value", err: "WASM tokenizer wasmexact: bad value"},
+		{output: "\x00\x09\x00", err: "returned a truncated token"},
+		{output: "", err: "returned no status"},
+	}
+	for _, tc := range tests {
+		module := testWasmModule(packed(2048, len(tc.output)), map[int]string{2048: tc.output})
+		tokenizer, err := newWasmTokenizer(module, time.Second)
+		require.NoError(t, err)
+		tokens, err := tokenizer.Tokens("anything")
+		if tc.err != "" {
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.err)
+			continue
+		}
+		require.NoError(t, err)
+		require.Equal(t, tc.tokens, tokens)
+	}
+}
+
+func TestWasmTokenizerTimeout(t *testing.T) {
+	module := testWasmModule([]byte{
+		0x03, 0x40, 0x0c, 0x00, 0x0b, // loop br 0 end
+		0x00, // unreachable
+	}, map[int]string{})
+	tokenizer, err := newWasmTokenizer(module, 100*time.Millisecond)
+	require.NoError(t, err)
+
+	start := time.Now()
+	_, err = tokenizer.Tokens("anything")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "call timed out after 100ms")
+	require.True(t, time.Since(start) < 5*time.Second)
+
+	// The timed out instance is closed, and the next call gets a new one.
+	_, err = tokenizer.Tokens("anything")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "call timed out after 100ms")
+}
+
+func TestWasmTokenizerInvalid(t *testing.T) {
+	_, err := newWasmTokenizer([]byte("not wasm"), time.Second)
+	require.Error(t, err)
+}
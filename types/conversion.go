@@ -21,6 +21,7 @@ import (
 	"encoding/binary"
 	"encoding/json"
 	"math"
+	"math/big"
 	"strconv"
 	"time"
 	"unsafe"
@@ -91,6 +92,18 @@ func Convert(from Val, toID TypeID) (Val, error) {
 				*res = w
 			case PasswordID:
 				*res = string(data)
+			case VFloatID:
+				vec, err := bytesToVector(data)
+				if err != nil {
+					return to, err
+				}
+				*res = vec
+			case DecimalID:
+				d, err := ParseDecimal(string(data))
+				if err != nil {
+					return to, err
+				}
+				*res = d
 			default:
 				return to, cantConvert(fromID, toID)
 			}
@@ -144,6 +157,18 @@ func Convert(from Val, toID TypeID) (Val, error) {
 					return to, err
 				}
 				*res = p
+			case VFloatID:
+				vec, err := ParseVector(vc)
+				if err != nil {
+					return to, err
+				}
+				*res = vec
+			case DecimalID:
+				d, err := ParseDecimal(vc)
+				if err != nil {
+					return to, err
+				}
+				*res = d
 			default:
 				return to, cantConvert(fromID, toID)
 			}
@@ -169,6 +194,8 @@ func Convert(from Val, toID TypeID) (Val, error) {
 				*res = strconv.FormatInt(vc, 10)
 			case DateTimeID:
 				*res = time.Unix(vc, 0).UTC()
+			case DecimalID:
+				*res = new(big.Rat).SetInt64(vc)
 			default:
 				return to, cantConvert(fromID, toID)
 			}
@@ -202,6 +229,12 @@ func Convert(from Val, toID TypeID) (Val, error) {
 				fracSecs := vc - float64(secs)
 				nsecs := int64(fracSecs * nanoSecondsInSec)
 				*res = time.Unix(secs, nsecs).UTC()
+			case DecimalID:
+				d, err := DecimalFromFloat(vc)
+				if err != nil {
+					return to, err
+				}
+				*res = d
 			default:
 				return to, cantConvert(fromID, toID)
 			}
@@ -304,6 +337,52 @@ func Convert(from Val, toID TypeID) (Val, error) {
 				return to, cantConvert(fromID, toID)
 			}
 		}
+	case VFloatID:
+		{
+			vc, err := bytesToVector(data)
+			if err != nil {
+				return to, err
+			}
+			switch toID {
+			case VFloatID:
+				*res = vc
+			case BinaryID:
+				*res = vectorToBytes(vc)
+			case StringID, DefaultID:
+				*res = FormatVector(vc)
+			default:
+				return to, cantConvert(fromID, toID)
+			}
+		}
+	case DecimalID:
+		{
+			vc, err := ParseDecimal(string(data))
+			if err != nil {
+				return to, err
+			}
+			switch toID {
+			case DecimalID:
+				*res = vc
+			case BinaryID:
+				*res = []byte(FormatDecimal(vc))
+			case StringID, DefaultID:
+				*res = FormatDecimal(vc)
+			case IntID:
+				// Like floats, decimals are truncated towards zero.
+				i := new(big.Int).Quo(vc.Num(), vc.Denom())
+				if !i.IsInt64() {
+					return to, errors.Errorf("Decimal out of int64 range")
+				}
+				*res = i.Int64()
+			case FloatID:
+				f, _ := vc.Float64()
+				*res = f
+			case BoolID:
+				*res = vc.Sign() != 0
+			default:
+				return to, cantConvert(fromID, toID)
+			}
+		}
 	default:
 		return to, cantConvert(fromID, toID)
 	}
@@ -435,6 +514,26 @@ func Marshal(from Val, to *Val) error {
 		default:
 			return cantConvert(fromID, toID)
 		}
+	case VFloatID:
+		vc := val.([]float32)
+		switch toID {
+		case StringID, DefaultID:
+			*res = FormatVector(vc)
+		case BinaryID:
+			*res = vectorToBytes(vc)
+		default:
+			return cantConvert(fromID, toID)
+		}
+	case DecimalID:
+		vc := val.(*big.Rat)
+		switch toID {
+		case StringID, DefaultID:
+			*res = FormatDecimal(vc)
+		case BinaryID:
+			*res = []byte(FormatDecimal(vc))
+		default:
+			return cantConvert(fromID, toID)
+		}
 	default:
 		return cantConvert(fromID, toID)
 	}
@@ -503,6 +602,21 @@ func ObjectValue(id TypeID, value interface{}) (*api.Value, error) {
 			return def, errors.Errorf("Expected value of type password. Got : %v", value)
 		}
 		return &api.Value{Val: &api.Value_PasswordVal{PasswordVal: v}}, nil
+	case VFloatID:
+		// There is no vector value in the N-Quad, the vector is converted from its string form
+		// to the type in the schema instead.
+		var v []float32
+		if v, ok = value.([]float32); !ok {
+			return def, errors.Errorf("Expected value of type float32vector. Got : %v", value)
+		}
+		return &api.Value{Val: &api.Value_DefaultVal{DefaultVal: FormatVector(v)}}, nil
+	case DecimalID:
+		// Like vectors, decimals are converted from their string form to the type in the schema.
+		var v *big.Rat
+		if v, ok = value.(*big.Rat); !ok {
+			return def, errors.Errorf("Expected value of type decimal. Got : %v", value)
+		}
+		return &api.Value{Val: &api.Value_DefaultVal{DefaultVal: FormatDecimal(v)}}, nil
 	default:
 		return def, errors.Errorf("ObjectValue not available for: %v", id)
 	}
@@ -537,6 +651,10 @@ func (v Val) MarshalJSON() ([]byte, error) {
 		return json.Marshal(v.Safe().(string))
 	case PasswordID:
 		return json.Marshal(v.Value.(string))
+	case VFloatID:
+		return json.Marshal(v.Value.([]float32))
+	case DecimalID:
+		return []byte(FormatDecimal(v.Value.(*big.Rat))), nil
 	}
 	return nil, errors.Errorf("Invalid type for MarshalJSON: %v", v.Tid)
 }
@@ -0,0 +1,127 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// MaxDecimalScale is the number of fractional digits that the decimals which don't have a finite
+// decimal form, like the result of 1 / 3, are rounded to.
+const MaxDecimalScale = 34
+
+// maxDecimalExponent bounds the exponent of a parsed decimal, so that a short input like 1e999999
+// can't make the value take a lot of memory.
+const maxDecimalExponent = 1000
+
+// decimalRegexp matches the decimals like "12", "-12.50", ".5" and "1.5e-3".
+var decimalRegexp = regexp.MustCompile(`^[+-]?(\d+(\.\d*)?|\.\d+)([eE]([+-]?\d+))?$`)
+
+var (
+	bigTwo  = big.NewInt(2)
+	bigFive = big.NewInt(5)
+)
+
+// ParseDecimal parses a decimal number of any precision, like "1234.5678" or "1.5e-3".
+func ParseDecimal(s string) (*big.Rat, error) {
+	s = strings.TrimSpace(s)
+	m := decimalRegexp.FindStringSubmatch(s)
+	if m == nil {
+		return nil, errors.Errorf("Invalid decimal %q", s)
+	}
+	if m[4] != "" {
+		if exp, err := strconv.Atoi(m[4]); err != nil || exp > maxDecimalExponent ||
+			exp < -maxDecimalExponent {
+			return nil, errors.Errorf("Invalid decimal %q, the exponent must be between -%d and %d",
+				s, maxDecimalExponent, maxDecimalExponent)
+		}
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, errors.Errorf("Invalid decimal %q", s)
+	}
+	return r, nil
+}
+
+// FormatDecimal returns the shortest decimal form of r, like "-12.5". The decimals that don't
+// have a finite decimal form are rounded to MaxDecimalScale fractional digits.
+func FormatDecimal(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+
+	// The decimal form is finite iff the denominator only has the factors 2 and 5, and then it
+	// has as many fractional digits as the larger of their powers.
+	den := new(big.Int).Set(r.Denom())
+	var twos, fives int
+	rem := new(big.Int)
+	for rem.Mod(den, bigTwo).Sign() == 0 {
+		den.Quo(den, bigTwo)
+		twos++
+	}
+	for rem.Mod(den, bigFive).Sign() == 0 {
+		den.Quo(den, bigFive)
+		fives++
+	}
+	scale := twos
+	if fives > scale {
+		scale = fives
+	}
+	if den.Cmp(big.NewInt(1)) != 0 {
+		scale = MaxDecimalScale
+	}
+
+	s := strings.TrimRight(strings.TrimRight(r.FloatString(scale), "0"), ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+// DecimalFromFloat returns the decimal with the shortest decimal form that converts back to f,
+// so that 0.1 becomes 0.1 and not the exact value of the float.
+func DecimalFromFloat(f float64) (*big.Rat, error) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil, errors.Errorf("Invalid decimal %v", f)
+	}
+	return ParseDecimal(strconv.FormatFloat(f, 'g', -1, 64))
+}
+
+// DecimalFloor returns the largest integer that is less than or equal to r.
+func DecimalFloor(r *big.Rat) *big.Rat {
+	// Rat denominators are positive, so the Euclidean division rounds down.
+	return new(big.Rat).SetInt(new(big.Int).Div(r.Num(), r.Denom()))
+}
+
+// DecimalCeil returns the smallest integer that is greater than or equal to r.
+func DecimalCeil(r *big.Rat) *big.Rat {
+	neg := DecimalFloor(new(big.Rat).Neg(r))
+	return neg.Neg(neg)
+}
+
+// DecimalMod returns the remainder of a / b, which has the sign of a like math.Mod.
+func DecimalMod(a, b *big.Rat) *big.Rat {
+	q := new(big.Rat).Quo(a, b)
+	trunc := new(big.Rat).SetInt(new(big.Int).Quo(q.Num(), q.Denom()))
+	return trunc.Sub(a, trunc.Mul(trunc, b))
+}
@@ -0,0 +1,146 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{"0", "0"},
+		{"-0.0", "0"},
+		{"12.50", "12.5"},
+		{"+.5", "0.5"},
+		{"1.5e-3", "0.0015"},
+		{"1.5E3", "1500"},
+		{"-123456789012345678901234567890.123456789", "-123456789012345678901234567890.123456789"},
+		{"0.1234567890123456789012345678901234567890",
+			"0.123456789012345678901234567890123456789"},
+	}
+	for _, tc := range tests {
+		r, err := ParseDecimal(tc.in)
+		require.NoError(t, err, tc.in)
+		require.Equal(t, tc.out, FormatDecimal(r), tc.in)
+	}
+
+	for _, in := range []string{"", "abc", "1/3", "1.2.3", "0x10", "Inf", "NaN", "1e1001"} {
+		_, err := ParseDecimal(in)
+		require.Error(t, err, in)
+	}
+}
+
+func TestFormatDecimalRounding(t *testing.T) {
+	require.Equal(t, "0.3333333333333333333333333333333333", FormatDecimal(big.NewRat(1, 3)))
+	require.Equal(t, "-0.6666666666666666666666666666666667", FormatDecimal(big.NewRat(-2, 3)))
+	require.Equal(t, "0.0625", FormatDecimal(big.NewRat(1, 16)))
+}
+
+func TestDecimalFromFloat(t *testing.T) {
+	r, err := DecimalFromFloat(0.1)
+	require.NoError(t, err)
+	require.Equal(t, "0.1", FormatDecimal(r))
+
+	r, err = DecimalFromFloat(-1e21)
+	require.NoError(t, err)
+	require.Equal(t, "-1000000000000000000000", FormatDecimal(r))
+}
+
+func TestDecimalRounding(t *testing.T) {
+	tests := []struct {
+		in    string
+		floor string
+		ceil  string
+	}{
+		{"2.5", "2", "3"},
+		{"-2.5", "-3", "-2"},
+		{"3", "3", "3"},
+		{"-0.1", "-1", "0"},
+	}
+	for _, tc := range tests {
+		r, err := ParseDecimal(tc.in)
+		require.NoError(t, err)
+		require.Equal(t, tc.floor, FormatDecimal(DecimalFloor(r)), tc.in)
+		require.Equal(t, tc.ceil, FormatDecimal(DecimalCeil(r)), tc.in)
+	}
+}
+
+func TestDecimalMod(t *testing.T) {
+	tests := []struct {
+		a, b, out string
+	}{
+		{"7.5", "2", "1.5"},
+		{"-7.5", "2", "-1.5"},
+		{"7.5", "-2", "1.5"},
+		{"0.3", "0.1", "0"},
+	}
+	for _, tc := range tests {
+		a, err := ParseDecimal(tc.a)
+		require.NoError(t, err)
+		b, err := ParseDecimal(tc.b)
+		require.NoError(t, err)
+		require.Equal(t, tc.out, FormatDecimal(DecimalMod(a, b)), "%s %% %s", tc.a, tc.b)
+	}
+}
+
+func TestConvertDecimal(t *testing.T) {
+	v, err := Convert(Val{Tid: StringID, Value: []byte("0.1")}, DecimalID)
+	require.NoError(t, err)
+	sum := new(big.Rat)
+	for i := 0; i < 3; i++ {
+		sum.Add(sum, v.Value.(*big.Rat))
+	}
+	require.Equal(t, "0.3", FormatDecimal(sum))
+
+	// The value round trips through its binary form.
+	b := ValueForType(BinaryID)
+	require.NoError(t, Marshal(Val{Tid: DecimalID, Value: sum}, &b))
+	v, err = Convert(b, DecimalID)
+	require.NoError(t, err)
+	require.Equal(t, 0, sum.Cmp(v.Value.(*big.Rat)))
+
+	v, err = Convert(Val{Tid: DecimalID, Value: []byte("-12.75")}, IntID)
+	require.NoError(t, err)
+	require.Equal(t, int64(-12), v.Value)
+	v, err = Convert(Val{Tid: DecimalID, Value: []byte("-12.75")}, FloatID)
+	require.NoError(t, err)
+	require.Equal(t, -12.75, v.Value)
+
+	b = ValueForType(BinaryID)
+	require.NoError(t, Marshal(Val{Tid: IntID, Value: int64(42)}, &b))
+	v, err = Convert(Val{Tid: IntID, Value: b.Value}, DecimalID)
+	require.NoError(t, err)
+	require.Equal(t, "42", FormatDecimal(v.Value.(*big.Rat)))
+
+	_, err = Convert(Val{Tid: StringID, Value: []byte("twelve")}, DecimalID)
+	require.Error(t, err)
+}
+
+func TestSortDecimals(t *testing.T) {
+	list := getInput(t, DecimalID, []string{"10.5", "-3", "0.25", "10.25", "1e2"})
+	ul := getUIDList(5)
+	require.NoError(t, Sort(list, &ul.Uids, []bool{false}, ""))
+	require.EqualValues(t, []uint64{200, 300, 400, 100, 500}, ul.Uids)
+	require.EqualValues(t, []string{"-3", "0.25", "10.25", "10.5", "100"},
+		toString(t, list, DecimalID))
+}
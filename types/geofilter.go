@@ -62,8 +62,9 @@ func IsGeoFunc(str string) bool {
 }
 
 // GetGeoTokens returns the corresponding index keys based on the type
-// of function.
-func GetGeoTokens(srcFunc *pb.SrcFunction) ([]string, *GeoQueryData, error) {
+// of function, in a geo index with the given options.
+func GetGeoTokens(srcFunc *pb.SrcFunction, opts GeoIndexOptions) ([]string, *GeoQueryData,
+	error) {
 	x.AssertTruef(len(srcFunc.Name) > 0, "Invalid function")
 	funcName := strings.ToLower(srcFunc.Name)
 	switch funcName {
@@ -83,7 +84,7 @@ func GetGeoTokens(srcFunc *pb.SrcFunction) ([]string, *GeoQueryData, error) {
 		if err != nil {
 			return nil, nil, err
 		}
-		return queryTokensGeo(QueryTypeNear, g, maxDist)
+		return queryTokensGeo(QueryTypeNear, g, maxDist, opts)
 	case "within":
 		if len(srcFunc.Args) != 1 {
 			return nil, nil, errors.Errorf("within function requires 1 arguments, but got %d",
@@ -93,7 +94,7 @@ func GetGeoTokens(srcFunc *pb.SrcFunction) ([]string, *GeoQueryData, error) {
 		if err != nil {
 			return nil, nil, err
 		}
-		return queryTokensGeo(QueryTypeWithin, g, 0.0)
+		return queryTokensGeo(QueryTypeWithin, g, 0.0, opts)
 	case "contains":
 		if len(srcFunc.Args) != 1 {
 			return nil, nil, errors.Errorf("contains function requires 1 arguments, but got %d",
@@ -103,7 +104,7 @@ func GetGeoTokens(srcFunc *pb.SrcFunction) ([]string, *GeoQueryData, error) {
 		if err != nil {
 			return nil, nil, err
 		}
-		return queryTokensGeo(QueryTypeContains, g, 0.0)
+		return queryTokensGeo(QueryTypeContains, g, 0.0, opts)
 	case "intersects":
 		if len(srcFunc.Args) != 1 {
 			return nil, nil, errors.Errorf("intersects function requires 1 arguments, but got %d",
@@ -113,7 +114,7 @@ func GetGeoTokens(srcFunc *pb.SrcFunction) ([]string, *GeoQueryData, error) {
 		if err != nil {
 			return nil, nil, err
 		}
-		return queryTokensGeo(QueryTypeIntersects, g, 0.0)
+		return queryTokensGeo(QueryTypeIntersects, g, 0.0, opts)
 	default:
 		return nil, nil, errors.Errorf("Invalid geo function")
 	}
@@ -123,7 +124,9 @@ func GetGeoTokens(srcFunc *pb.SrcFunction) ([]string, *GeoQueryData, error) {
 // qt is the type of Geo query - near/intersects/contains/within
 // g is the geom.T representation of the input. It could be a point/polygon/multipolygon.
 // maxDistance is distance in metres, only used for near query.
-func queryTokensGeo(qt QueryType, g geom.T, maxDistance float64) ([]string, *GeoQueryData, error) {
+// opts are the options of the geo index that is looked up.
+func queryTokensGeo(qt QueryType, g geom.T, maxDistance float64,
+	opts GeoIndexOptions) ([]string, *GeoQueryData, error) {
 	var loops []*s2.Loop
 	var pt *s2.Point
 	var err error
@@ -171,10 +174,10 @@ func queryTokensGeo(qt QueryType, g geom.T, maxDistance float64) ([]string, *Geo
 		if len(loops) == 0 {
 			return nil, nil, errors.Errorf("Internal error while processing near query.")
 		}
-		cover = coverLoop(loops[0], MinCellLevel, MaxCellLevel, MaxCells)
-		parents = getParentCells(cover, MinCellLevel)
+		cover = coverLoop(loops[0], opts.MinLevel, opts.MaxLevel, opts.MaxCells)
+		parents = getParentCells(cover, opts.MinLevel)
 	} else {
-		parents, cover, err = indexCells(g)
+		parents, cover, err = indexCells(g, opts)
 		if err != nil {
 			return nil, nil, err
 		}
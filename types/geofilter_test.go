@@ -39,7 +39,7 @@ func queryTokens(qt QueryType, data string, maxDistance float64) ([]string, *Geo
 	}
 	g := gc.Value.(geom.T)
 
-	return queryTokensGeo(qt, g, maxDistance)
+	return queryTokensGeo(qt, g, maxDistance, DefaultGeoIndexOptions)
 }
 
 func formData(t *testing.T, str string) string {
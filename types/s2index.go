@@ -38,10 +38,10 @@ func parentCoverTokens(parents s2.CellUnion, cover s2.CellUnion) []string {
 	return tokens
 }
 
-// IndexGeoTokens returns the tokens to be used in a geospatial index for the
-// given geometry. If the geometry is not supported it returns an error.
-func IndexGeoTokens(g geom.T) ([]string, error) {
-	parents, cover, err := indexCells(g)
+// IndexGeoTokens returns the tokens to be used in a geospatial index with the given options for
+// the given geometry. If the geometry is not supported it returns an error.
+func IndexGeoTokens(g geom.T, opts GeoIndexOptions) ([]string, error) {
+	parents, cover, err := indexCells(g, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -71,21 +71,21 @@ const (
 // possible cells required to cover the region. This makes it easier at query time to query only the
 // parents or only the cover or both depending on whether it is a within, contains or intersects
 // query.
-func indexCells(g geom.T) (parents, cover s2.CellUnion, err error) {
+func indexCells(g geom.T, opts GeoIndexOptions) (parents, cover s2.CellUnion, err error) {
 	if g.Stride() != 2 {
 		return nil, nil, errors.Errorf("Covering only available for 2D co-ordinates.")
 	}
 	switch v := g.(type) {
 	case *geom.Point:
-		p, c := indexCellsForPoint(v, MinCellLevel, MaxCellLevel)
+		p, c := indexCellsForPoint(v, opts.MinLevel, opts.MaxLevel)
 		return p, c, nil
 	case *geom.Polygon:
 		l, err := loopFromPolygon(v)
 		if err != nil {
 			return nil, nil, err
 		}
-		cover := coverLoop(l, MinCellLevel, MaxCellLevel, MaxCells)
-		parents := getParentCells(cover, MinCellLevel)
+		cover := coverLoop(l, opts.MinLevel, opts.MaxLevel, opts.MaxCells)
+		parents := getParentCells(cover, opts.MinLevel)
 		return parents, cover, nil
 	case *geom.MultiPolygon:
 		var cover s2.CellUnion
@@ -96,10 +96,10 @@ func indexCells(g geom.T) (parents, cover s2.CellUnion, err error) {
 			if err != nil {
 				return nil, nil, err
 			}
-			cover = append(cover, coverLoop(l, MinCellLevel, MaxCellLevel, MaxCells)...)
+			cover = append(cover, coverLoop(l, opts.MinLevel, opts.MaxLevel, opts.MaxCells)...)
 		}
 		// Get parents for all cells in cover.
-		parents := getParentCells(cover, MinCellLevel)
+		parents := getParentCells(cover, opts.MinLevel)
 		return parents, cover, nil
 	default:
 		return nil, nil, errors.Errorf("Cannot index geometry of type %T", v)
@@ -113,8 +113,41 @@ const (
 	MaxCellLevel = 16 // Approx 120m x 180m
 	// MaxCells is the maximum number of cells to use when indexing regions.
 	MaxCells = 18
+	// maxS2Level is the level of the smallest cells of S2.
+	maxS2Level = 30
 )
 
+// GeoIndexOptions are the levels of the cells a geo index covers the geometries with, from
+// MinLevel for the largest cells to MaxLevel for the smallest ones, and the maximum number of cells
+// in the cover of a region. Higher levels index the geometries more precisely, with more tokens.
+type GeoIndexOptions struct {
+	MinLevel int
+	MaxLevel int
+	MaxCells int
+}
+
+// DefaultGeoIndexOptions are the options of a geo index that isn't given any.
+var DefaultGeoIndexOptions = GeoIndexOptions{
+	MinLevel: MinCellLevel,
+	MaxLevel: MaxCellLevel,
+	MaxCells: MaxCells,
+}
+
+// Validate returns an error if the levels aren't valid S2 cell levels in increasing order, or
+// if there isn't any cell to cover a region with.
+func (opts GeoIndexOptions) Validate() error {
+	if opts.MinLevel < 0 || opts.MaxLevel > maxS2Level || opts.MaxLevel < opts.MinLevel {
+		return errors.Errorf("The cell levels of a geo index must satisfy "+
+			"0 <= minlevel <= maxlevel <= %d, got minlevel: %d, maxlevel: %d",
+			maxS2Level, opts.MinLevel, opts.MaxLevel)
+	}
+	if opts.MaxCells < 1 {
+		return errors.Errorf("The maxcells of a geo index must be at least 1, got %d",
+			opts.MaxCells)
+	}
+	return nil
+}
+
 func pointFromCoord(r geom.Coord) s2.Point {
 	// The geojson spec says that coordinates are specified as [long, lat]
 	// We assume that any data encoded in the database follows that format.
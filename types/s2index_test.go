@@ -52,7 +52,7 @@ func loadPolygon(name string) (geom.T, error) {
 
 func TestIndexCellsPoint(t *testing.T) {
 	p := geom.NewPoint(geom.XY).MustSetCoords(geom.Coord{-122.082506, 37.4249518})
-	parents, cover, err := indexCells(p)
+	parents, cover, err := indexCells(p, DefaultGeoIndexOptions)
 	require.NoError(t, err)
 	require.Len(t, parents, MaxCellLevel-MinCellLevel+1)
 	c := parents[0]
@@ -88,7 +88,7 @@ func TestIndexCellsPoint(t *testing.T) {
 func TestIndexCellsPolygon(t *testing.T) {
 	p, err := loadPolygon("testdata/zip.json")
 	require.NoError(t, err)
-	parents, cover, err := indexCells(p)
+	parents, cover, err := indexCells(p, DefaultGeoIndexOptions)
 	require.NoError(t, err)
 	if len(cover) > MaxCells {
 		t.Errorf("Expected less than %d cells. Got %d instead.", MaxCells, len(cover))
@@ -102,10 +102,42 @@ func TestIndexCellsPolygon(t *testing.T) {
 	require.True(t, len(parents) > len(cover))
 }
 
+func TestIndexCellsOptions(t *testing.T) {
+	opts := GeoIndexOptions{MinLevel: 10, MaxLevel: 20, MaxCells: 4}
+	p := geom.NewPoint(geom.XY).MustSetCoords(geom.Coord{-122.082506, 37.4249518})
+	parents, cover, err := indexCells(p, opts)
+	require.NoError(t, err)
+	require.Len(t, parents, 11)
+	require.Equal(t, 10, parents[0].Level())
+	require.Len(t, cover, 1)
+	require.Equal(t, 20, cover[0].Level())
+
+	poly, err := loadPolygon("testdata/zip.json")
+	require.NoError(t, err)
+	_, defaultCover, err := indexCells(poly, DefaultGeoIndexOptions)
+	require.NoError(t, err)
+	parents, cover, err = indexCells(poly, opts)
+	require.NoError(t, err)
+	require.True(t, len(cover) < len(defaultCover))
+	for _, c := range parents {
+		require.True(t, c.Level() >= 10 && c.Level() <= 20)
+	}
+
+	require.NoError(t, opts.Validate())
+	err = GeoIndexOptions{MinLevel: 12, MaxLevel: 8, MaxCells: 4}.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "0 <= minlevel <= maxlevel <= 30")
+	err = GeoIndexOptions{MinLevel: 5, MaxLevel: 31, MaxCells: 4}.Validate()
+	require.Error(t, err)
+	err = GeoIndexOptions{MinLevel: 5, MaxLevel: 16}.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "maxcells of a geo index must be at least 1")
+}
+
 func TestIndexCellsPolygonError(t *testing.T) {
 	poly := geom.NewPolygon(geom.XY).MustSetCoords([][]geom.Coord{
 		{{-122, 37}, {-123, 37}, {-123, 38}, {-122, 38}, {-122, 38}}})
-	_, _, err := indexCells(poly)
+	_, _, err := indexCells(poly, DefaultGeoIndexOptions)
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "Last coordinate not same as first")
 }
@@ -121,7 +153,7 @@ func TestKeyGeneratorPoint(t *testing.T) {
 	require.NoError(t, err)
 	g := gc.Value.(geom.T)
 
-	keys, err := IndexGeoTokens(g)
+	keys, err := IndexGeoTokens(g, DefaultGeoIndexOptions)
 	require.NoError(t, err)
 	require.Len(t, keys, MaxCellLevel-MinCellLevel+1+1) // +1 for the cover
 }
@@ -138,7 +170,7 @@ func TestKeyGeneratorPolygon(t *testing.T) {
 	require.NoError(t, err)
 	g := gc.Value.(geom.T)
 
-	keys, err := IndexGeoTokens(g)
+	keys, err := IndexGeoTokens(g, DefaultGeoIndexOptions)
 	require.NoError(t, err)
 	require.Len(t, keys, 67)
 }
@@ -213,7 +245,7 @@ func BenchmarkKeyGeneratorPoint(b *testing.B) {
 
 	b.ResetTimer()
 	for n := 0; n < b.N; n++ {
-		IndexGeoTokens(g)
+		IndexGeoTokens(g, DefaultGeoIndexOptions)
 	}
 }
 
@@ -235,7 +267,7 @@ func BenchmarkKeyGeneratorPolygon(b *testing.B) {
 
 	b.ResetTimer()
 	for n := 0; n < b.N; n++ {
-		IndexGeoTokens(g)
+		IndexGeoTokens(g, DefaultGeoIndexOptions)
 	}
 }
 
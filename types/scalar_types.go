@@ -17,9 +17,12 @@
 package types
 
 import (
+	"math/big"
+	"sync"
 	"time"
 
 	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/pkg/errors"
 	geom "github.com/twpayne/go-geom"
 )
 
@@ -55,21 +58,27 @@ const (
 	PasswordID = TypeID(pb.Posting_PASSWORD)
 	// StringID represents the string type.
 	StringID = TypeID(pb.Posting_STRING)
+	// VFloatID represents the vector of float32 values type.
+	VFloatID = TypeID(pb.Posting_VFLOAT)
+	// DecimalID represents the decimal number of any precision type.
+	DecimalID = TypeID(pb.Posting_DECIMAL)
 	// UndefinedID represents the undefined type.
 	UndefinedID = TypeID(100)
 )
 
 var typeNameMap = map[string]TypeID{
-	"default":  DefaultID,
-	"binary":   BinaryID,
-	"int":      IntID,
-	"float":    FloatID,
-	"bool":     BoolID,
-	"datetime": DateTimeID,
-	"geo":      GeoID,
-	"uid":      UidID,
-	"string":   StringID,
-	"password": PasswordID,
+	"default":       DefaultID,
+	"binary":        BinaryID,
+	"int":           IntID,
+	"float":         FloatID,
+	"bool":          BoolID,
+	"datetime":      DateTimeID,
+	"geo":           GeoID,
+	"uid":           UidID,
+	"string":        StringID,
+	"password":      PasswordID,
+	"float32vector": VFloatID,
+	"decimal":       DecimalID,
 }
 
 // TypeID represents the type of the data.
@@ -103,6 +112,10 @@ func (t TypeID) Name() string {
 		return "string"
 	case PasswordID:
 		return "password"
+	case VFloatID:
+		return "float32vector"
+	case DecimalID:
+		return "decimal"
 	}
 	return ""
 }
@@ -187,6 +200,13 @@ func ValueForType(id TypeID) Val {
 		var p string
 		return Val{PasswordID, p}
 
+	case VFloatID:
+		var v []float32
+		return Val{VFloatID, &v}
+
+	case DecimalID:
+		return Val{DecimalID, new(big.Rat)}
+
 	default:
 		return Val{}
 	}
@@ -195,23 +215,55 @@ func ValueForType(id TypeID) Val {
 // ParseTime parses the time from string trying various datetime formats.
 // By default, Go parses time in UTC unless specified in the data itself.
 func ParseTime(val string) (time.Time, error) {
+	return ParseTimeInLocation(val, time.UTC)
+}
+
+// ParseTimeInLocation parses the time from string like ParseTime, but interprets the values
+// that don't specify a time zone as being in the given location.
+func ParseTimeInLocation(val string, loc *time.Location) (time.Time, error) {
 	if len(val) == len(dateFormatY) {
-		return time.Parse(dateFormatY, val)
+		return time.ParseInLocation(dateFormatY, val, loc)
 	}
 	if len(val) == len(dateFormatYM) {
-		return time.Parse(dateFormatYM, val)
+		return time.ParseInLocation(dateFormatYM, val, loc)
 	}
 	if len(val) == len(dateFormatYMD) {
-		return time.Parse(dateFormatYMD, val)
+		return time.ParseInLocation(dateFormatYMD, val, loc)
 	}
 	if len(val) > len(dateTimeFormat) && val[len(dateFormatYMD)] == 'T' &&
 		(val[len(val)-1] == 'Z' || val[len(val)-3] == ':') {
 		// https://tools.ietf.org/html/rfc3339#section-5.6
-		return time.Parse(time.RFC3339, val)
+		return time.ParseInLocation(time.RFC3339, val, loc)
 	}
-	if t, err := time.Parse(dateFormatYMDZone, val); err == nil {
+	if t, err := time.ParseInLocation(dateFormatYMDZone, val, loc); err == nil {
 		return t, err
 	}
 	// Try without timezone.
-	return time.Parse(dateTimeFormat, val)
+	return time.ParseInLocation(dateTimeFormat, val, loc)
+}
+
+var (
+	locationsMu sync.Mutex
+	// locations caches the loaded locations, as loading them reads the time zone database.
+	locations = make(map[string]*time.Location)
+)
+
+// LoadLocation returns the location with the given IANA time zone name, like
+// "America/New_York". Unlike time.LoadLocation, it doesn't accept "Local" or an empty name, so
+// that the location doesn't depend on the configuration of the server.
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, errors.Errorf("Invalid time zone %q, expected an IANA time zone name", name)
+	}
+	locationsMu.Lock()
+	defer locationsMu.Unlock()
+	if loc, ok := locations[name]; ok {
+		return loc, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "while loading time zone %q", name)
+	}
+	locations[name] = loc
+	return loc, nil
 }
@@ -126,6 +126,35 @@ func TestParseTimeWithTZ(t *testing.T) {
 	}
 }
 
+func TestParseTimeInLocation(t *testing.T) {
+	loc, err := LoadLocation("Asia/Kolkata")
+	require.NoError(t, err)
+
+	out, err := ParseTimeInLocation("2018-05-30", loc)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2018, 5, 29, 18, 30, 0, 0, time.UTC), out.UTC())
+
+	out, err = ParseTimeInLocation("2018-05-30T09:30:00", loc)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2018, 5, 30, 4, 0, 0, 0, time.UTC), out.UTC())
+
+	// The time zone in the value takes precedence over the location.
+	out, err = ParseTimeInLocation("2018-05-30T09:30:00Z", loc)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2018, 5, 30, 9, 30, 0, 0, time.UTC), out.UTC())
+}
+
+func TestLoadLocation(t *testing.T) {
+	loc, err := LoadLocation("America/New_York")
+	require.NoError(t, err)
+	require.Equal(t, "America/New_York", loc.String())
+
+	for _, name := range []string{"", "Local", "Mars/Olympus_Mons"} {
+		_, err := LoadLocation(name)
+		require.Error(t, err)
+	}
+}
+
 func TestParseTimeRejection(t *testing.T) {
 	var err error
 
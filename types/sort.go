@@ -17,6 +17,7 @@
 package types
 
 import (
+	"math/big"
 	"sort"
 	"time"
 
@@ -54,7 +55,11 @@ type byValue struct{ sortBase }
 // Less compares two elements
 // skipcq: CRT-P0003
 func (s byValue) Less(i, j int) bool {
-	first, second := s.values[i], s.values[j]
+	return lessValues(s.values[i], s.values[j], s.desc, s.cl)
+}
+
+// lessValues returns true if the values of a uid, first, sort before the values of another, second.
+func lessValues(first, second []Val, desc []bool, cl *collate.Collator) bool {
 	if len(first) == 0 || len(second) == 0 {
 		return false
 	}
@@ -62,11 +67,11 @@ func (s byValue) Less(i, j int) bool {
 		// Null value is considered greatest hence comes at first place while doing descending sort
 		// and at last place while doing ascending sort.
 		if first[vidx].Value == nil {
-			return s.desc[vidx]
+			return desc[vidx]
 		}
 
 		if second[vidx].Value == nil {
-			return !s.desc[vidx]
+			return !desc[vidx]
 		}
 
 		// We have to look at next value to decide.
@@ -75,8 +80,8 @@ func (s byValue) Less(i, j int) bool {
 		}
 
 		// Its either less or greater.
-		less := less(first[vidx], second[vidx], s.cl)
-		if s.desc[vidx] {
+		less := less(first[vidx], second[vidx], cl)
+		if desc[vidx] {
 			return !less
 		}
 		return less
@@ -87,7 +92,7 @@ func (s byValue) Less(i, j int) bool {
 // IsSortable returns true, if tid is sortable. Otherwise it returns false.
 func IsSortable(tid TypeID) bool {
 	switch tid {
-	case DateTimeID, IntID, FloatID, StringID, DefaultID:
+	case DateTimeID, IntID, FloatID, StringID, DefaultID, DecimalID:
 		return true
 	default:
 		return false
@@ -107,21 +112,43 @@ func SortWithFacet(v [][]Val, ul *[]uint64, l []*pb.Facets, desc []bool, lang st
 		}
 	}
 
-	var cl *collate.Collator
-	if lang != "" {
-		// Collator is nil if we are unable to parse the language.
-		// We default to bytewise comparison in that case.
-		if langTag, err := language.Parse(lang); err == nil {
-			cl = collate.New(langTag)
-		}
-	}
-
-	b := sortBase{v, desc, ul, l, cl}
+	b := sortBase{v, desc, ul, l, newCollator(lang)}
 	toBeSorted := byValue{b}
 	sort.Sort(toBeSorted)
 	return nil
 }
 
+// newCollator returns the collator for the given language.
+func newCollator(lang string) *collate.Collator {
+	if lang == "" {
+		return nil
+	}
+	// Collator is nil if we are unable to parse the language.
+	// We default to bytewise comparison in that case.
+	langTag, err := language.Parse(lang)
+	if err != nil {
+		return nil
+	}
+	return collate.New(langTag)
+}
+
+// Comparator compares the values of uids in the order Sort sorts them in. It's used to merge
+// values sorted separately. It isn't safe for concurrent use.
+type Comparator struct {
+	desc []bool
+	cl   *collate.Collator
+}
+
+// NewComparator returns a Comparator for the given sort orders and language.
+func NewComparator(desc []bool, lang string) *Comparator {
+	return &Comparator{desc: desc, cl: newCollator(lang)}
+}
+
+// Less returns true if the values of a uid, first, sort before the values of another, second.
+func (c *Comparator) Less(first, second []Val) bool {
+	return lessValues(first, second, c.desc, c.cl)
+}
+
 // Sort sorts the given array in-place.
 func Sort(v [][]Val, ul *[]uint64, desc []bool, lang string) error {
 	return SortWithFacet(v, ul, nil, desc, lang)
@@ -134,7 +161,7 @@ func Less(a, b Val) (bool, error) {
 	}
 	typ := a.Tid
 	switch typ {
-	case DateTimeID, UidID, IntID, FloatID, StringID, DefaultID:
+	case DateTimeID, UidID, IntID, FloatID, StringID, DefaultID, DecimalID:
 		// Don't do anything, we can sort values of this type.
 	default:
 		return false, errors.Errorf("Compare not supported for type: %v", a.Tid)
@@ -155,6 +182,8 @@ func less(a, b Val, cl *collate.Collator) bool {
 		return (a.Value.(float64)) < (b.Value.(float64))
 	case UidID:
 		return (a.Value.(uint64) < b.Value.(uint64))
+	case DecimalID:
+		return a.Value.(*big.Rat).Cmp(b.Value.(*big.Rat)) < 0
 	case StringID, DefaultID:
 		// Use language comparator.
 		if cl != nil {
@@ -190,7 +219,7 @@ func Equal(a, b Val) (bool, error) {
 	}
 	typ := a.Tid
 	switch typ {
-	case DateTimeID, IntID, FloatID, StringID, DefaultID, BoolID:
+	case DateTimeID, IntID, FloatID, StringID, DefaultID, BoolID, DecimalID:
 		// Don't do anything, we can sort values of this type.
 	default:
 		return false, errors.Errorf("Equal not supported for type: %v", a.Tid)
@@ -223,6 +252,10 @@ func equal(a, b Val) bool {
 		aVal, aOk := a.Value.(bool)
 		bVal, bOk := b.Value.(bool)
 		return aOk && bOk && aVal == bVal
+	case DecimalID:
+		aVal, aOk := a.Value.(*big.Rat)
+		bVal, bOk := b.Value.(*big.Rat)
+		return aOk && bOk && aVal.Cmp(bVal) == 0
 	}
 	return false
 }
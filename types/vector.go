@@ -0,0 +1,130 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ParseVector parses a vector written as a JSON array of numbers, like "[0.1, 0.5, -1]".
+func ParseVector(s string) ([]float32, error) {
+	var vals []float64
+	if err := json.Unmarshal([]byte(strings.TrimSpace(s)), &vals); err != nil {
+		return nil, errors.Errorf("Invalid vector %q, expected a list of numbers like [0.1, 0.5]",
+			s)
+	}
+	if len(vals) == 0 {
+		return nil, errors.Errorf("Invalid vector %q, a vector can't be empty", s)
+	}
+	vec := make([]float32, len(vals))
+	for i, v := range vals {
+		if math.Abs(v) > math.MaxFloat32 {
+			return nil, errors.Errorf("Invalid vector %q, %v is out of the float32 range", s, v)
+		}
+		vec[i] = float32(v)
+	}
+	return vec, nil
+}
+
+// FormatVector is the inverse of ParseVector.
+func FormatVector(vec []float32) string {
+	var sb strings.Builder
+	sb.WriteByte('[')
+	for i, v := range vec {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(strconv.FormatFloat(float64(v), 'g', -1, 32))
+	}
+	sb.WriteByte(']')
+	return sb.String()
+}
+
+// vectorToBytes encodes the vector as its float32 values in little endian order.
+func vectorToBytes(vec []float32) []byte {
+	b := make([]byte, 4*len(vec))
+	for i, v := range vec {
+		binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(v))
+	}
+	return b
+}
+
+func bytesToVector(b []byte) ([]float32, error) {
+	if len(b) == 0 || len(b)%4 != 0 {
+		return nil, errors.Errorf("Invalid data for float32vector of length %d", len(b))
+	}
+	vec := make([]float32, len(b)/4)
+	for i := range vec {
+		vec[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[4*i:]))
+	}
+	return vec, nil
+}
+
+// VectorMetric returns the distance between two vectors of the same length. The distance is
+// smaller for the vectors that are more similar.
+type VectorMetric func(a, b []float32) float64
+
+var vectorMetrics = map[string]VectorMetric{
+	"euclidean":  euclideanDistance,
+	"cosine":     cosineDistance,
+	"dotproduct": dotProductDistance,
+}
+
+// GetVectorMetric returns the vector metric with the given name, which is one of euclidean,
+// cosine and dotproduct.
+func GetVectorMetric(name string) (VectorMetric, bool) {
+	m, ok := vectorMetrics[name]
+	return m, ok
+}
+
+// euclideanDistance returns the square of the euclidean distance, which orders the vectors the
+// same way without computing the square root.
+func euclideanDistance(a, b []float32) float64 {
+	var sum float64
+	for i := range a {
+		d := float64(a[i]) - float64(b[i])
+		sum += d * d
+	}
+	return sum
+}
+
+func cosineDistance(a, b []float32) float64 {
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 1
+	}
+	return 1 - dot/math.Sqrt(normA*normB)
+}
+
+func dotProductDistance(a, b []float32) float64 {
+	var dot float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	return -dot
+}
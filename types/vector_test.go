@@ -0,0 +1,68 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVector(t *testing.T) {
+	vec, err := ParseVector(" [0.5, -1, 2e3] ")
+	require.NoError(t, err)
+	require.Equal(t, []float32{0.5, -1, 2000}, vec)
+	require.Equal(t, "[0.5, -1, 2000]", FormatVector(vec))
+
+	for _, s := range []string{"", "[]", "0.5", `["a"]`, "[1e40]"} {
+		_, err := ParseVector(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestConvertVector(t *testing.T) {
+	src := Val{Tid: StringID, Value: []byte("[0.25, 1.5]")}
+	vec, err := Convert(src, VFloatID)
+	require.NoError(t, err)
+	require.Equal(t, []float32{0.25, 1.5}, vec.Value)
+
+	b := ValueForType(BinaryID)
+	require.NoError(t, Marshal(vec, &b))
+	require.Len(t, b.Value, 8)
+
+	stored := Val{Tid: VFloatID, Value: b.Value}
+	for _, tc := range []struct {
+		to   TypeID
+		want interface{}
+	}{
+		{VFloatID, []float32{0.25, 1.5}},
+		{StringID, "[0.25, 1.5]"},
+		{BinaryID, b.Value},
+	} {
+		out, err := Convert(stored, tc.to)
+		require.NoError(t, err)
+		require.Equal(t, tc.want, out.Value)
+	}
+	_, err = Convert(stored, IntID)
+	require.Error(t, err)
+	_, err = Convert(Val{Tid: VFloatID, Value: []byte{1, 2, 3}}, VFloatID)
+	require.Error(t, err)
+
+	js, err := vec.MarshalJSON()
+	require.NoError(t, err)
+	require.Equal(t, "[0.25,1.5]", string(js))
+}
@@ -0,0 +1,151 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+	otrace "go.opencensus.io/trace"
+
+	"github.com/dgraph-io/dgraph/conn"
+	"github.com/dgraph-io/dgraph/posting"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// ReadBlobOverNetwork writes the untagged value of the predicate req.Attr of the node req.Uid as
+// of req.ReadTs to w. The value is streamed from the group serving the predicate one chunk at a
+// time, so large values are never held in memory at once. Values of types other than string and
+// binary are written in their string representation.
+func ReadBlobOverNetwork(ctx context.Context, req *pb.BlobRequest, w io.Writer) error {
+	ctx, span := otrace.StartSpan(ctx, "worker.ReadBlobOverNetwork")
+	defer span.End()
+
+	gid, err := groups().BelongsToReadOnly(req.Attr, req.ReadTs)
+	switch {
+	case err != nil:
+		return err
+	case gid == 0:
+		return errNonExistentTablet
+	}
+	if groups().ServesGroup(gid) {
+		return readBlob(ctx, req, func(data []byte) error {
+			_, err := w.Write(data)
+			return err
+		})
+	}
+
+	pl := groups().AnyServer(gid)
+	if pl == nil {
+		return conn.ErrNoConnection
+	}
+	stream, err := pb.NewWorkerClient(pl.Get()).ReadBlob(ctx, req)
+	if err != nil {
+		return err
+	}
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(chunk.Data); err != nil {
+			return err
+		}
+	}
+}
+
+// ReadBlob streams the value requested by another instance.
+func (w *grpcWorker) ReadBlob(req *pb.BlobRequest, stream pb.Worker_ReadBlobServer) error {
+	ctx, span := otrace.StartSpan(stream.Context(), "worker.ReadBlob")
+	defer span.End()
+
+	var idx uint32
+	return readBlob(ctx, req, func(data []byte) error {
+		chunk := &pb.BlobChunk{Attr: req.Attr, Uid: req.Uid, Idx: idx, Data: data}
+		idx++
+		return stream.Send(chunk)
+	})
+}
+
+func readBlob(ctx context.Context, req *pb.BlobRequest, send func(data []byte) error) error {
+	if err := posting.Oracle().WaitForTs(ctx, req.ReadTs); err != nil {
+		return err
+	}
+	gid, err := groups().BelongsToReadOnly(req.Attr, req.ReadTs)
+	switch {
+	case err != nil:
+		return err
+	case gid == 0:
+		return errNonExistentTablet
+	case gid != groups().groupId():
+		return errUnservedTablet
+	}
+
+	l, err := posting.GetNoStore(x.DataKey(req.Attr, req.Uid), req.ReadTs)
+	if err != nil {
+		return err
+	}
+	r, typ, err := l.ValueReader(req.ReadTs)
+	if err == posting.ErrNoValue {
+		return errors.Errorf("no value found for predicate %s of node %#x", req.Attr, req.Uid)
+	}
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	switch typ {
+	case pb.Posting_STRING, pb.Posting_DEFAULT, pb.Posting_BINARY:
+	case pb.Posting_PASSWORD:
+		return errors.Errorf("predicate %s is of type password and can't be read", req.Attr)
+	default:
+		// Values of the other types are never split into chunks.
+		val, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		str, err := types.Convert(types.Val{Tid: types.TypeID(typ), Value: val}, types.StringID)
+		if err != nil {
+			return err
+		}
+		return send([]byte(str.Value.(string)))
+	}
+
+	buf := make([]byte, posting.BlobChunkSize())
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			if err := send(buf[:n]); err != nil {
+				return err
+			}
+		}
+		switch err {
+		case nil:
+		case io.EOF, io.ErrUnexpectedEOF:
+			return nil
+		default:
+			return err
+		}
+	}
+}
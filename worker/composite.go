@@ -0,0 +1,131 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/tok"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// CompositeIndexValue returns a value of one of the predicates a composite index spans as the
+// index stores it: converted to the type of the predicate, and then to a string. The values
+// given to functions and the values stored by mutations are then the same for the same value.
+func CompositeIndexValue(val types.Val, tid types.TypeID) (string, error) {
+	if tid == types.DefaultID {
+		tid = types.StringID
+	}
+	dst, err := types.Convert(val, tid)
+	if err != nil {
+		return "", err
+	}
+	out := types.ValueForType(types.StringID)
+	if err := types.Marshal(dst, &out); err != nil {
+		return "", err
+	}
+	return out.Value.(string), nil
+}
+
+// CompositeIndexTypes returns the types of the given predicates, which are spanned by composite
+// indexes. The schema of a predicate is kept by the group serving it. Predicates without a
+// schema are of the default type.
+func CompositeIndexTypes(ctx context.Context, preds []string) (map[string]types.TypeID, error) {
+	nodes, err := GetSchemaOverNetwork(ctx, &pb.SchemaRequest{
+		Predicates: preds,
+		Fields:     []string{"type"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	tids := make(map[string]types.TypeID, len(preds))
+	for _, pred := range preds {
+		tids[pred] = types.DefaultID
+	}
+	for _, node := range nodes {
+		if tid, ok := types.TypeForName(node.Type); ok {
+			tids[node.Predicate] = tid
+		}
+	}
+	return tids, nil
+}
+
+// parseCompositeFn parses a function looking up a composite index. The function takes a value
+// for each of the predicates the index spans, and two values for the last one with between. The
+// nodes must have the given values for all the predicates but the last one, whose value is
+// compared with the function.
+func parseCompositeFn(ctx context.Context, q *pb.Query, fc *functionContext) error {
+	attr := q.Attr
+	preds := x.CompositeIndexPredicates(attr)
+	args := q.SrcFunc.Args
+	n := len(preds)
+	if fc.fname == between {
+		n++
+	}
+	if len(args) != n {
+		return errors.Errorf("Function '%s' on composite index %s requires %d arguments, but"+
+			" got %d (%v)", q.SrcFunc.Name, attr, n, len(args), args)
+	}
+
+	leadingPreds := preds[:len(preds)-1]
+	tids, err := CompositeIndexTypes(ctx, leadingPreds)
+	if err != nil {
+		return err
+	}
+	leading := make([]string, len(leadingPreds))
+	for i, pred := range leadingPreds {
+		src := types.Val{Tid: types.StringID, Value: []byte(args[i])}
+		if leading[i], err = CompositeIndexValue(src, tids[pred]); err != nil {
+			return errors.Errorf("Got error: %v while converting %q for predicate %s of"+
+				" composite index %s", err, args[i], pred, attr)
+		}
+	}
+	for _, arg := range args[len(leadingPreds):] {
+		val, err := convertValue(attr, arg)
+		if err != nil {
+			return errors.Errorf("Got error: %v while running: %v", err, q.SrcFunc)
+		}
+		fc.eqTokens = append(fc.eqTokens, val)
+	}
+
+	if fc.tokens, fc.ineqValueToken, err = getInequalityTokens(ctx, q.ReadTs, attr, fc.fname,
+		"", leading, fc.eqTokens); err != nil {
+		return err
+	}
+	// The values of a composite index can't be compared directly, so the index is always used.
+	fc.n = len(fc.tokens)
+	return nil
+}
+
+// compositeLastValue returns the value of the last predicate of a composite index, stored in
+// a value of the index.
+func compositeLastValue(attr string, val types.Val) (types.Val, error) {
+	str, err := types.Convert(val, types.StringID)
+	if err != nil {
+		return val, err
+	}
+	values, err := tok.ParseCompositeValue(str.Value.(string),
+		len(x.CompositeIndexPredicates(attr)))
+	if err != nil {
+		return val, err
+	}
+	return types.Val{Tid: types.StringID, Value: []byte(values[len(values)-1])}, nil
+}
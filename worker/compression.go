@@ -0,0 +1,102 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/dgraph-io/badger/v2/y"
+	"github.com/golang/glog"
+
+	"github.com/dgraph-io/dgraph/posting"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+)
+
+// dictionaryTrainInterval is how often the leader of a group trains new dictionaries for the
+// predicates with @compression(zstd_dict).
+var dictionaryTrainInterval = 10 * time.Minute
+
+// processCompressionDictionaries trains the dictionaries the posting lists of the predicates of
+// this group with @compression(zstd_dict) are compressed with, when this node is the leader. A
+// new dictionary is only proposed if it compresses the lists noticeably better than the latest
+// one, so that the dictionaries follow the values as they change.
+func (n *node) processCompressionDictionaries() {
+	defer n.closer.Done() // CLOSER:1
+	tick := time.NewTicker(dictionaryTrainInterval)
+	defer tick.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-n.closer.HasBeenClosed()
+		cancel()
+	}()
+
+	for {
+		select {
+		case <-n.closer.HasBeenClosed():
+			return
+		case <-tick.C:
+			// Zstd needs cgo, the lists are stored uncompressed without it. The lists of a
+			// replica are compressed with the dictionaries of its primary.
+			if !y.CgoEnabled || !n.AmLeader() || IsReplica() {
+				continue
+			}
+			for _, pred := range schema.State().Predicates() {
+				if schema.State().Compression(pred) != schema.CompressionZstdDict {
+					continue
+				}
+				if gid, err := groups().BelongsToReadOnly(pred, 0); err != nil || gid != n.gid {
+					continue
+				}
+				if err := n.trainCompressionDictionary(ctx, pred); err != nil {
+					glog.Errorf("Error while training the compression dictionary of"+
+						" predicate %s: %v", pred, err)
+				}
+			}
+		}
+	}
+}
+
+// trainCompressionDictionary trains a dictionary on the lists of pred, and proposes it to the
+// group if it's better than the latest one.
+func (n *node) trainCompressionDictionary(ctx context.Context, pred string) error {
+	dict, err := posting.TrainCompressionDictionary(pred, posting.Oracle().MaxAssigned())
+	if err != nil || dict == nil {
+		return err
+	}
+	glog.Infof("Proposing a compression dictionary of %d bytes for predicate %s",
+		len(dict), pred)
+	m := &pb.Mutations{GroupId: n.gid, DictionaryPredicate: pred, Dictionary: dict}
+	return n.proposeAndWait(ctx, &pb.Proposal{Mutations: m})
+}
+
+// applyCompressionDictionary adds the dictionary of the proposal to the compression dictionaries
+// of its predicate. The posting lists are compressed with it as they're rolled up.
+func applyCompressionDictionary(m *pb.Mutations) error {
+	schemaWriteLock.Lock()
+	defer schemaWriteLock.Unlock()
+	su := schema.State().AddCompressionDictionary(m.DictionaryPredicate, m.Dictionary)
+	if su == nil {
+		return nil
+	}
+	glog.Infof("Predicate %s has %d compression dictionaries", m.DictionaryPredicate,
+		len(su.CompressionDictionaries))
+	return writeEncoding(su)
+}
@@ -47,6 +47,12 @@ type Options struct {
 	PostingDirCompressionLevel int
 	// WALDir is the path to the directory storing the write-ahead log.
 	WALDir string
+	// InMemory keeps the postings in memory instead of PostingDir, and the write-ahead log in a
+	// temporary directory instead of WALDir. All the data is lost once the server stops.
+	InMemory bool
+	// ReadOnly opens PostingDir read-only and serves queries from it without joining a cluster.
+	// There's no Zero, no Raft and no write-ahead log, and all the writes are rejected.
+	ReadOnly bool
 	// MutationsMode is the mode used to handle mutation requests.
 	MutationsMode int
 	// AuthToken is the token to be passed for Alter HTTP requests.
@@ -71,6 +77,35 @@ type Options struct {
 	CachePercentage string
 	// CacheMb is the total memory allocated between all the caches.
 	CacheMb int64
+
+	// MaintenanceWindows are the daily windows in which the value log GC and the full
+	// compactions of the postings run. They run at any time if there's none.
+	MaintenanceWindows []MaintenanceWindow
+	// VlogGCMaxRuns is the most value log files rewritten by a minutely GC. Zero means no limit.
+	VlogGCMaxRuns int
+	// VlogGCDiscardRatio is the fraction of a value log file that has to be discarded for the GC
+	// to rewrite it.
+	VlogGCDiscardRatio float64
+	// CompactionInterval is how often the postings are fully compacted within the maintenance
+	// windows. Zero disables the full compactions, except the ones asked through the admin API.
+	CompactionInterval time.Duration
+	// TableIOMode and VlogIOMode are the I/O modes of the tables and of the value log files of
+	// the postings, IOModeMmap or IOModeRAM.
+	TableIOMode string
+	VlogIOMode  string
+	// IntegrityCheckInterval is how often the postings are verified within the maintenance
+	// windows. Zero disables the scheduled verifications.
+	IntegrityCheckInterval time.Duration
+	// IntegrityCheckRate is the most lists read per second by a verification of the postings.
+	// Zero means no limit.
+	IntegrityCheckRate int
+	// SnapshotSendRate and SnapshotReceiveRate are the most bytes per second taken by all the
+	// snapshots streamed to and from the other members of the group. Zero means no limit.
+	SnapshotSendRate    int64
+	SnapshotReceiveRate int64
+	// SnapshotGoroutines is the number of goroutines reading the data of a snapshot sent to
+	// another member of the group.
+	SnapshotGoroutines int
 }
 
 // Config holds an instance of the server options..
@@ -89,6 +124,14 @@ func SetConfiguration(newConfig *Options) {
 var AvailableMemory int64
 
 func (opt *Options) validate() {
+	if opt.InMemory {
+		// Neither of the directories is used.
+		return
+	}
+	if opt.ReadOnly {
+		// The write-ahead log isn't used.
+		return
+	}
 	pd, err := filepath.Abs(opt.PostingDir)
 	x.Check(err)
 	wd, err := filepath.Abs(opt.WALDir)
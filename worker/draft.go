@@ -71,6 +71,9 @@ type node struct {
 	canCampaign bool
 	elog        trace.EventLog
 
+	// snapResume is only used by the goroutine retrieving the snapshots.
+	snapResume *snapshotResume
+
 	ex *executor
 }
 
@@ -217,6 +220,14 @@ func GetOngoingTasks() []string {
 	for id := range n.ops {
 		tasks = append(tasks, id.String())
 	}
+	for to, from := range schema.State().Renames() {
+		tasks = append(tasks, fmt.Sprintf("opRename %s to %s", from, to))
+	}
+	for pred, tier := range schema.State().Tiers() {
+		if tier != schema.TierCold {
+			tasks = append(tasks, fmt.Sprintf("opTier %s %s", pred, tier))
+		}
+	}
 	return tasks
 }
 
@@ -227,9 +238,10 @@ func newNode(store *raftwal.DiskStorage, gid uint32, id uint64, myAddr string) *
 	glog.Infof("Node ID: %#x with GroupID: %d\n", id, gid)
 
 	rc := &pb.RaftContext{
-		Addr:  myAddr,
-		Group: gid,
-		Id:    id,
+		Addr:      myAddr,
+		Group:     gid,
+		Id:        id,
+		IsLearner: x.WorkerConfig.Learner,
 	}
 	m := conn.NewNode(rc, store)
 
@@ -242,7 +254,7 @@ func newNode(store *raftwal.DiskStorage, gid uint32, id uint64, myAddr string) *
 		// to maintain quorum health.
 		applyCh: make(chan []*pb.Proposal, 1000),
 		elog:    trace.NewEventLog("Dgraph", "ApplyCh"),
-		closer:  z.NewCloser(4), // Matches CLOSER:1
+		closer:  z.NewCloser(8), // Matches CLOSER:1
 		ops:     make(map[op]*z.Closer),
 	}
 	if x.WorkerConfig.LudicrousMode {
@@ -310,6 +322,10 @@ func (n *node) applyMutations(ctx context.Context, proposal *pb.Proposal) (rerr
 		if err := posting.DeleteData(); err != nil {
 			return err
 		}
+		// The data of the offloaded predicates has been dropped as well.
+		if err := clearTiers(); err != nil {
+			return err
+		}
 
 		// Clear entire cache.
 		posting.ResetCache()
@@ -360,10 +376,62 @@ func (n *node) applyMutations(ctx context.Context, proposal *pb.Proposal) (rerr
 		return schema.State().DeleteType(proposal.Mutations.DropValue)
 	}
 
+	if m := proposal.Mutations; len(m.TierPredicate) > 0 {
+		span.Annotatef(nil, "Moving predicate %s to tier %q", m.TierPredicate, m.Tier)
+		return applyTier(ctx, m)
+	}
+
+	if m := proposal.Mutations; len(m.DictionaryPredicate) > 0 {
+		span.Annotatef(nil, "Adding a compression dictionary to predicate %s",
+			m.DictionaryPredicate)
+		return applyCompressionDictionary(m)
+	}
+
 	if proposal.Mutations.StartTs == 0 {
 		return errors.New("StartTs must be provided")
 	}
 
+	if m := proposal.Mutations; len(m.RenameFrom) > 0 {
+		span.Annotatef(nil, "Renaming predicate %s to %s", m.RenameFrom, m.RenameTo)
+		return startRename(ctx, m.RenameFrom, m.RenameTo)
+	}
+
+	if m := proposal.Mutations; len(m.Savepoint) > 0 || len(m.RollbackTo) > 0 {
+		txn := posting.Oracle().RegisterStartTs(m.StartTs)
+		if txn.ShouldAbort() {
+			span.Annotatef(nil, "Txn %d should abort.", m.StartTs)
+			return zero.ErrConflict
+		}
+		if len(m.Savepoint) > 0 {
+			span.Annotatef(nil, "Creating savepoint %q", m.Savepoint)
+			txn.Savepoint(m.Savepoint)
+			if txnListener != nil {
+				txnListener.Savepoint(m.StartTs, m.Savepoint)
+			}
+			return nil
+		}
+		span.Annotatef(nil, "Rolling back to savepoint %q", m.RollbackTo)
+		if err := txn.RollbackTo(m.RollbackTo); err != nil {
+			return err
+		}
+		if txnListener != nil {
+			txnListener.RollbackTo(m.StartTs, m.RollbackTo)
+		}
+		return nil
+	}
+
+	if m := proposal.Mutations; len(m.BlobChunks) > 0 {
+		// The chunks are written right away. Registering the transaction first keeps them from
+		// being taken for leftovers of an aborted transaction until it commits or aborts.
+		txn := posting.Oracle().RegisterStartTs(m.StartTs)
+		if txn.ShouldAbort() {
+			span.Annotatef(nil, "Txn %d should abort.", m.StartTs)
+			return zero.ErrConflict
+		}
+		span.Annotatef(nil, "Writing %d blob chunks", len(m.BlobChunks))
+		return posting.WriteBlobChunks(m.StartTs, m.BlobChunks)
+	}
+
 	if len(proposal.Mutations.Schema) > 0 || len(proposal.Mutations.Types) > 0 {
 		// MaxAssigned would ensure that everything that's committed up until this point
 		// would be picked up in building indexes. Any uncommitted txns would be cancelled
@@ -413,6 +481,13 @@ func (n *node) applyMutations(ctx context.Context, proposal *pb.Proposal) (rerr
 	// Since raft committed logs are serialized, we can derive
 	// schema here without any locking
 
+	if err := checkRenames(proposal.Mutations.Edges); err != nil {
+		return err
+	}
+	if err := checkTiers(proposal.Mutations.Edges); err != nil {
+		return err
+	}
+
 	// Stores a map of predicate and type of first mutation for each predicate.
 	schemaMap := make(map[string]types.TypeID)
 	for _, edge := range proposal.Mutations.Edges {
@@ -426,8 +501,10 @@ func (n *node) applyMutations(ctx context.Context, proposal *pb.Proposal) (rerr
 			span.Annotatef(nil, "Deleting predicate: %s", edge.Attr)
 			return posting.DeletePredicate(ctx, edge.Attr)
 		}
-		// Don't derive schema when doing deletion.
-		if edge.Op == pb.DirectedEdge_DEL {
+		// Don't derive schema when doing deletion. Edge properties and composite indexes only
+		// exist through their schema, so a dropped one isn't created again either.
+		if edge.Op == pb.DirectedEdge_DEL || x.IsEdgeProperty(edge.Attr) ||
+			x.IsCompositeIndex(edge.Attr) {
 			continue
 		}
 		if _, ok := schemaMap[edge.Attr]; !ok {
@@ -436,6 +513,10 @@ func (n *node) applyMutations(ctx context.Context, proposal *pb.Proposal) (rerr
 	}
 
 	total := len(proposal.Mutations.Edges)
+	if n.AmLeader() {
+		// The leader reports the mutations of the whole group to Zero.
+		loads.mutated(proposal.Mutations.Edges)
+	}
 
 	// TODO: Active mutations values can go up or down but with
 	// OpenCensus stats bucket boundaries start from 0, hence
@@ -477,6 +558,10 @@ func (n *node) applyMutations(ctx context.Context, proposal *pb.Proposal) (rerr
 	})
 
 	if x.WorkerConfig.LudicrousMode {
+		// The mutations are committed as they're applied.
+		if err := extendEncodings(m.Edges); err != nil {
+			return err
+		}
 		n.ex.addEdges(ctx, proposal)
 		return nil
 	}
@@ -512,25 +597,30 @@ func (n *node) applyMutations(ctx context.Context, proposal *pb.Proposal) (rerr
 	span.Annotatef(nil, "To apply: %d edges. NumGo: %d. Width: %d", len(m.Edges), numGo, width)
 
 	if numGo == 1 {
-		return process(m.Edges)
-	}
-	errCh := make(chan error, numGo)
-	for i := 0; i < numGo; i++ {
-		start := i * width
-		end := start + width
-		if end > len(m.Edges) {
-			end = len(m.Edges)
-		}
-		go func(start, end int) {
-			errCh <- process(m.Edges[start:end])
-		}(start, end)
-	}
-	for i := 0; i < numGo; i++ {
-		if err := <-errCh; err != nil {
+		if err := process(m.Edges); err != nil {
 			return err
 		}
+	} else {
+		errCh := make(chan error, numGo)
+		for i := 0; i < numGo; i++ {
+			start := i * width
+			end := start + width
+			if end > len(m.Edges) {
+				end = len(m.Edges)
+			}
+			go func(start, end int) {
+				errCh <- process(m.Edges[start:end])
+			}(start, end)
+		}
+		for i := 0; i < numGo; i++ {
+			if err := <-errCh; err != nil {
+				return err
+			}
+		}
 	}
-	return nil
+	txn.AddReferenceConflicts(m.Edges)
+	// The values of @unique predicates are checked once all the edges are applied.
+	return txn.CheckUnique(ctx, m.Edges)
 }
 
 func (n *node) applyCommitted(proposal *pb.Proposal) error {
@@ -555,6 +645,10 @@ func (n *node) applyCommitted(proposal *pb.Proposal) error {
 	case len(proposal.Kv) > 0:
 		return populateKeyValues(ctx, proposal.Kv)
 
+	case len(proposal.TierAccessed) > 0:
+		accesses.record(proposal.TierAccessed...)
+		return nil
+
 	case proposal.State != nil:
 		n.elog.Printf("Applying state for key: %s", proposal.Key)
 		// This state needn't be snapshotted in this group, on restart we would fetch
@@ -612,8 +706,9 @@ func (n *node) applyCommitted(proposal *pb.Proposal) error {
 			}
 			glog.Warningf("Error while calling CreateSnapshot: %v. Retrying...", err)
 		}
-		// We can now discard all invalid versions of keys below this ts.
-		pstore.SetDiscardTs(snap.ReadTs)
+		// We can now discard all invalid versions of keys below this ts, unless they're
+		// still within the history retention period or needed by a freeze point.
+		pstore.SetDiscardTs(historyDiscardTs(snap.ReadTs))
 		return nil
 
 	case proposal.Restore != nil:
@@ -759,6 +854,16 @@ func (n *node) commitOrAbort(pkey string, delta *pb.OracleDelta) error {
 			return
 		}
 		txn.Update()
+		if commit > 0 {
+			// The values must be in the dictionaries before they're written.
+			edges, err := txn.SetValues(commit)
+			if err == nil {
+				err = extendEncodings(edges)
+			}
+			if err != nil {
+				glog.Errorf("Error while extending the encodings of txn %d: %v", start, err)
+			}
+		}
 		err := x.RetryUntilSuccess(x.WorkerConfig.MaxRetries, 10*time.Millisecond, func() error {
 			return txn.CommitToDisk(writer, commit)
 		})
@@ -801,6 +906,8 @@ func (n *node) commitOrAbort(pkey string, delta *pb.OracleDelta) error {
 
 	// Now advance Oracle(), so we can service waiting reads.
 	posting.Oracle().ProcessDelta(delta)
+	recordHistory(posting.Oracle().MaxAssigned())
+	notifyTxnStatus(delta.Txns)
 	return nil
 }
 
@@ -1058,6 +1165,11 @@ func (n *node) Run() {
 		go x.StoreSync(n.Store, closer)
 		go x.StoreSync(pstore, closer)
 	}
+	if x.WorkerConfig.Learner && x.WorkerConfig.LearnerMaxStaleness > 0 {
+		closer := z.NewCloser(1)
+		defer closer.SignalAndWait()
+		go trackFreshness(closer)
+	}
 
 	applied, err := n.Store.Checkpoint()
 	if err != nil {
@@ -1306,18 +1418,20 @@ func (n *node) calculateTabletSizes() {
 	}
 	var total int64
 	tablets := make(map[string]*pb.Tablet)
-	updateSize := func(pred string, size int64) {
+	updateSize := func(pred string, size int64, keys uint64) {
 		if pred == "" {
 			return
 		}
 
 		if tablet, ok := tablets[pred]; ok {
 			tablet.Space += size
+			tablet.NumKeys += keys
 		} else {
 			tablets[pred] = &pb.Tablet{
 				GroupId:   n.gid,
 				Predicate: pred,
 				Space:     size,
+				NumKeys:   keys,
 			}
 		}
 		total += size
@@ -1326,6 +1440,7 @@ func (n *node) calculateTabletSizes() {
 	tableInfos := pstore.Tables()
 	previousLeft := ""
 	var previousSize int64
+	var previousKeys uint64
 	glog.V(2).Infof("Calculating tablet sizes. Found %d tables\n", len(tableInfos))
 	for _, tinfo := range tableInfos {
 		left, err := x.Parse(tinfo.Left)
@@ -1340,15 +1455,16 @@ func (n *node) calculateTabletSizes() {
 			// Instead, Dgraph only counts the previous table if the current one belongs to the
 			// same predicate.
 			// We could later specifically iterate over these tables to get their estimated sizes.
-			updateSize(previousLeft, previousSize)
+			updateSize(previousLeft, previousSize, previousKeys)
 		} else {
 			glog.V(3).Info("Skipping table not owned by one predicate")
 		}
 		previousLeft = left.Attr
 		previousSize = int64(tinfo.EstimatedSz)
+		previousKeys = uint64(tinfo.KeyCount)
 	}
 	// The last table has not been counted. Assign it to the predicate at the left of the table.
-	updateSize(previousLeft, previousSize)
+	updateSize(previousLeft, previousSize, previousKeys)
 
 	if len(tablets) == 0 {
 		glog.V(2).Infof("No tablets found.")
@@ -1673,12 +1789,16 @@ func (n *node) InitAndStartNode() {
 			// This causes a node to just hang on restart, because it finds a
 			// zero-member Raft group.
 			n.SetConfState(&sp.Metadata.ConfState)
+			n.checkLearner(&sp.Metadata.ConfState)
 
 			members := groups().members(n.gid)
-			for _, id := range sp.Metadata.ConfState.Nodes {
-				m, ok := members[id]
-				if ok {
-					n.Connect(id, m.Addr)
+			cs := sp.Metadata.ConfState
+			for _, ids := range [][]uint64{cs.Nodes, cs.Learners} {
+				for _, id := range ids {
+					m, ok := members[id]
+					if ok {
+						n.Connect(id, m.Addr)
+					}
 				}
 			}
 		}
@@ -1696,6 +1816,8 @@ func (n *node) InitAndStartNode() {
 			glog.Infoln("Trying to join peers.")
 			n.retryUntilSuccess(n.joinPeers, time.Second)
 			n.SetRaft(raft.StartNode(n.Cfg, nil))
+		} else if x.WorkerConfig.Learner {
+			x.Fatalf("A learner can't join group %d, which has no voting member.", n.gid)
 		} else {
 			peers := []raft.Peer{{ID: n.Id}}
 			n.SetRaft(raft.StartNode(n.Cfg, peers))
@@ -1704,6 +1826,10 @@ func (n *node) InitAndStartNode() {
 		}
 	}
 	go n.processTabletSizes()
+	go n.processRenames()
+	go n.processTiering()
+	go n.processCompressionDictionaries()
+	go n.processReplication()
 	go n.processApplyCh()
 	go n.BatchAndSendMessages()
 	// Ignoring the error since InitAndStartNode does not return an error and using x.Check would
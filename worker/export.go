@@ -44,6 +44,7 @@ import (
 	"github.com/dgraph-io/dgraph/ee/enc"
 	"github.com/dgraph-io/dgraph/posting"
 	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
 	"github.com/dgraph-io/dgraph/types"
 	"github.com/dgraph-io/dgraph/types/facets"
 	"github.com/dgraph-io/dgraph/x"
@@ -86,10 +87,12 @@ var rdfTypeMap = map[types.TypeID]string{
 	types.DateTimeID: "xs:dateTime",
 	types.IntID:      "xs:int",
 	types.FloatID:    "xs:float",
+	types.DecimalID:  "xs:decimal",
 	types.BoolID:     "xs:boolean",
 	types.GeoID:      "geo:geojson",
 	types.BinaryID:   "xs:base64Binary",
 	types.PasswordID: "xs:password",
+	types.VFloatID:   "xs:float32vector",
 }
 
 // UIDs like 0x1 look weird but 64-bit ones like 0x0000000000000001 are too long.
@@ -281,6 +284,16 @@ func (e *exporter) toRDF() (*bpb.KVList, error) {
 }
 
 func toSchema(attr string, update *pb.SchemaUpdate) (*bpb.KVList, error) {
+	kv := &bpb.KV{
+		Value:   []byte(PredicateSchema(attr, update) + " \n"),
+		Version: 2, // Schema value
+	}
+	return listWrap(kv), nil
+}
+
+// PredicateSchema returns the schema of the predicate attr as written in schema files, like
+// <name>:string @index(exact) .
+func PredicateSchema(attr string, update *pb.SchemaUpdate) string {
 	// bytes.Buffer never returns error for any of the writes. So, we don't need to check them.
 	var buf bytes.Buffer
 	x.Check2(buf.WriteRune('<'))
@@ -301,6 +314,12 @@ func toSchema(attr string, update *pb.SchemaUpdate) (*bpb.KVList, error) {
 		x.Check2(buf.WriteString(" @index("))
 		x.Check2(buf.WriteString(strings.Join(update.GetTokenizer(), ",")))
 		x.Check2(buf.WriteRune(')'))
+		if update.GetIndexWhere() != "" {
+			x.Check2(buf.WriteString(" @where(" + update.IndexWhere + ")"))
+		}
+	}
+	if update.GetReferences() != "" {
+		x.Check2(buf.WriteString(" @references(ondelete: " + update.References + ")"))
 	}
 	if update.GetCount() {
 		x.Check2(buf.WriteString(" @count"))
@@ -311,28 +330,63 @@ func toSchema(attr string, update *pb.SchemaUpdate) (*bpb.KVList, error) {
 	if update.GetUpsert() {
 		x.Check2(buf.WriteString(" @upsert"))
 	}
-	x.Check2(buf.WriteString(" . \n"))
+	if update.GetUnique() {
+		x.Check2(buf.WriteString(" @unique"))
+	}
+	if update.GetTtl() != "" {
+		x.Check2(buf.WriteString(ttlDirective(update.Ttl, update.TtlFrom)))
+	}
+	if update.GetDefaultValue() != "" {
+		x.Check2(buf.WriteString(fmt.Sprintf(" @default(%q", update.DefaultValue)))
+		if update.DefaultVirtual {
+			x.Check2(buf.WriteString(", mode: virtual"))
+		}
+		x.Check2(buf.WriteString(")"))
+	}
+	if len(update.GetEnumValues()) > 0 {
+		x.Check2(buf.WriteString(" @enum(" + strings.Join(update.EnumValues, ", ") + ")"))
+	}
+	if schema.HasValueCheck(update) {
+		x.Check2(buf.WriteString(" @check(" + schema.FormatValueCheck(update) + ")"))
+	}
+	if update.GetEncoding() != "" {
+		x.Check2(buf.WriteString(" @encoding(" + update.Encoding + ")"))
+	}
+	if update.GetCompression() != "" {
+		x.Check2(buf.WriteString(" @compression(" + update.Compression + ")"))
+	}
+	x.Check2(buf.WriteString(" ."))
+	return buf.String()
+}
+
+func toType(attr string, update pb.TypeUpdate) (*bpb.KVList, error) {
 	kv := &bpb.KV{
-		Value:   buf.Bytes(),
-		Version: 2, // Schema value
+		Value:   []byte(TypeSchema(attr, update) + "\n"),
+		Version: 2, // Type value
 	}
 	return listWrap(kv), nil
 }
 
-func toType(attr string, update pb.TypeUpdate) (*bpb.KVList, error) {
+// TypeSchema returns the definition of the type attr as written in schema files.
+func TypeSchema(attr string, update pb.TypeUpdate) string {
 	var buf bytes.Buffer
-	x.Check2(buf.WriteString(fmt.Sprintf("type <%s> {\n", attr)))
+	x.Check2(buf.WriteString(fmt.Sprintf("type <%s>", attr)))
+	if update.GetTtl() != "" {
+		x.Check2(buf.WriteString(ttlDirective(update.Ttl, update.TtlFrom)))
+	}
+	x.Check2(buf.WriteString(" {\n"))
 	for _, field := range update.Fields {
 		x.Check2(buf.WriteString(fieldToString(field)))
 	}
 
-	x.Check2(buf.WriteString("}\n"))
+	x.Check2(buf.WriteString("}"))
+	return buf.String()
+}
 
-	kv := &bpb.KV{
-		Value:   buf.Bytes(),
-		Version: 2, // Type value
-	}
-	return listWrap(kv), nil
+// ttlDirective returns the @ttl directive expiring the data once the value of from is older
+// than ttl.
+func ttlDirective(ttl, from string) string {
+	return fmt.Sprintf(" @ttl(%q, from: <%s>)", ttl, from)
 }
 
 func fieldToString(update *pb.SchemaUpdate) string {
@@ -347,6 +401,9 @@ func fieldToString(update *pb.SchemaUpdate) string {
 	} else {
 		x.Check2(builder.WriteString(update.Predicate))
 	}
+	if update.NonNullable {
+		x.Check2(builder.WriteString(" @required"))
+	}
 	x.Check2(builder.WriteString("\n"))
 	return builder.String()
 }
@@ -663,6 +720,18 @@ func exportInternal(ctx context.Context, in *pb.ExportRequest, db *badger.DB,
 			// Ignore this predicate.
 		case pk.Attr == "dgraph.graphql.schema_history":
 			// Ignore this predicate.
+		case pk.Attr == "dgraph.graphql.p_query":
+			// Ignore this predicate.
+		case pk.Attr == "dgraph.graphql.p_sha256hash":
+			// Ignore this predicate.
+		case x.IsSchemaHistoryPredicate(pk.Attr):
+			// Ignore the history of the schema, which is about this cluster.
+		case pk.IsData() && x.IsEdgeProperty(pk.Attr):
+			// Edge properties are built again from the facets of their edges by the live and
+			// bulk loaders, and when their schema is added.
+		case pk.IsData() && x.IsCompositeIndex(pk.Attr):
+			// Composite indexes are built again from the predicates they span by the live
+			// loader, and when their schema is added.
 		case pk.IsData() && pk.Attr == "dgraph.graphql.schema":
 			// Export the graphql schema.
 			pl, err := posting.ReadPostingList(key, itr)
@@ -704,7 +773,7 @@ func exportInternal(ctx context.Context, in *pb.ExportRequest, db *badger.DB,
 					if !ok {
 						return nil, errors.Errorf("cannot read value of dgraph.type entry")
 					}
-					if string(val) == "dgraph.graphql" {
+					if string(val) == "dgraph.graphql" || string(val) == "dgraph.schema.history" {
 						return nil, nil
 					}
 				}
@@ -537,6 +537,83 @@ func TestToSchema(t *testing.T) {
 			},
 			expected: "<data.base>:string @lang . \n",
 		},
+		{
+			skv: &skv{
+				attr: "score",
+				schema: pb.SchemaUpdate{
+					Predicate:      "",
+					ValueType:      pb.Posting_INT,
+					Directive:      pb.SchemaUpdate_NONE,
+					DefaultValue:   "0",
+					DefaultVirtual: true,
+				},
+			},
+			expected: "<score>:int @default(\"0\", mode: virtual) . \n",
+		},
+		{
+			skv: &skv{
+				attr: "status",
+				schema: pb.SchemaUpdate{
+					Predicate:  "",
+					ValueType:  pb.Posting_STRING,
+					Directive:  pb.SchemaUpdate_INDEX,
+					Tokenizer:  []string{"exact"},
+					IndexWhere: `ne: "archived"`,
+				},
+			},
+			expected: "<status>:string @index(exact) @where(ne: \"archived\") . \n",
+		},
+		{
+			skv: &skv{
+				attr: "state",
+				schema: pb.SchemaUpdate{
+					Predicate:  "",
+					ValueType:  pb.Posting_STRING,
+					Directive:  pb.SchemaUpdate_INDEX,
+					Tokenizer:  []string{"hash"},
+					EnumValues: []string{"ACTIVE", "PENDING"},
+					Dictionary: []string{"PENDING", "ACTIVE"},
+				},
+			},
+			expected: "<state>:string @index(hash) @enum(ACTIVE, PENDING) . \n",
+		},
+		{
+			skv: &skv{
+				attr: "temp",
+				schema: pb.SchemaUpdate{
+					Predicate: "",
+					ValueType: pb.Posting_FLOAT,
+					CheckMin:  "-273.15",
+					CheckMax:  "1000",
+				},
+			},
+			expected: "<temp>:float @check(min: \"-273.15\", max: 1000) . \n",
+		},
+		{
+			skv: &skv{
+				attr: "handle",
+				schema: pb.SchemaUpdate{
+					Predicate:      "",
+					ValueType:      pb.Posting_STRING,
+					CheckMaxLength: 15,
+					CheckPattern:   `^\w+$`,
+				},
+			},
+			expected: "<handle>:string @check(maxlength: 15, pattern: \"^\\\\w+$\") . \n",
+		},
+		{
+			skv: &skv{
+				attr: "owner",
+				schema: pb.SchemaUpdate{
+					Predicate:  "",
+					ValueType:  pb.Posting_UID,
+					Directive:  pb.SchemaUpdate_REVERSE,
+					Count:      true,
+					References: "cascade",
+				},
+			},
+			expected: "<owner>:uid @reverse @references(ondelete: cascade) @count . \n",
+		},
 	}
 	for _, testCase := range testCases {
 		list, err := toSchema(testCase.skv.attr, &testCase.skv.schema)
@@ -303,7 +303,7 @@ func (h *fileHandler) ExportBackup(backupDir, exportDir, format string,
 			return 0, errors.Wrapf(err, "cannot open DB at %s", dir)
 		}
 		defer db.Close()
-		_, err = loadFromBackup(db, gzReader, 0, preds)
+		_, err = loadFromBackup(db, gzReader, 0, preds, false)
 		if err != nil {
 			return 0, errors.Wrapf(err, "cannot load backup")
 		}
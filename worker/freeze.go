@@ -0,0 +1,74 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"context"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/dgraph/posting"
+	"github.com/dgraph-io/dgraph/protos/pb"
+)
+
+// A freeze point is a read timestamp leased by Zero, and applied by every group, that external
+// tools use to capture a consistent image of all the groups. Zero records the freeze points in
+// the membership state, and the Alphas keep the versions needed to read at them until they're
+// released.
+
+// FreezeGroup waits for this group to apply the read timestamp of a freeze point, and returns
+// the Raft index applied by this member then.
+func (w *grpcWorker) FreezeGroup(ctx context.Context, req *pb.FrozenGroup) (
+	*pb.FrozenGroup, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	g := groups()
+	if req.GroupId != g.groupId() {
+		return nil, errors.Errorf("Group id doesn't match, received request for %d, my gid: %d",
+			req.GroupId, g.groupId())
+	}
+	// All the transactions committed at or before the read timestamp have been applied once
+	// it's reached.
+	if err := posting.Oracle().WaitForTs(ctx, req.ReadTs); err != nil {
+		return nil, err
+	}
+	frozen := &pb.FrozenGroup{
+		GroupId: req.GroupId,
+		ReadTs:  req.ReadTs,
+		NodeId:  g.Node.Id,
+		Index:   g.Node.Applied.DoneUntil(),
+	}
+	glog.Infof("Group %d applied freeze point at ts %d, index %d", frozen.GroupId,
+		frozen.ReadTs, frozen.Index)
+	return frozen, nil
+}
+
+// freezeDiscardTs returns the timestamp to discard versions below, given ts, so that the versions
+// needed to read at the freeze points are kept.
+func freezeDiscardTs(ts uint64) uint64 {
+	g := groups()
+	g.RLock()
+	defer g.RUnlock()
+	for _, fp := range g.state.GetFreezePoints() {
+		if fp.ReadTs < ts {
+			ts = fp.ReadTs
+		}
+	}
+	return ts
+}
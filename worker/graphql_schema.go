@@ -198,5 +198,11 @@ func WaitForIndexingOrCtxError(ctx context.Context, shouldWait bool) error {
 // isGroupOneLeader returns true if the current server is the leader of Group One,
 // it returns false otherwise.
 func isGroupOneLeader() bool {
-	return groups().ServesGroup(1) && groups().Node.AmLeader()
+	return groups().ServesGroup(1) && groups().Node != nil && groups().Node.AmLeader()
+}
+
+// IsGroupOneLeader returns true if the current server is the leader of Group One, which runs
+// the background tasks that must only run once in the cluster.
+func IsGroupOneLeader() bool {
+	return isGroupOneLeader()
 }
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"io"
 	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -29,6 +30,7 @@ import (
 	"github.com/dgraph-io/dgo/v200/protos/api"
 	"github.com/dgraph-io/dgraph/conn"
 	"github.com/dgraph-io/dgraph/ee/enc"
+	"github.com/dgraph-io/dgraph/posting"
 	"github.com/dgraph-io/dgraph/protos/pb"
 	"github.com/dgraph-io/dgraph/raftwal"
 	"github.com/dgraph-io/dgraph/schema"
@@ -106,6 +108,12 @@ func StartRaftNodes(walStore *raftwal.DiskStorage, bindall bool) {
 	if m.GroupId > 0 {
 		m.ForceGroupId = true
 	}
+	if x.WorkerConfig.Learner {
+		m.Learner = true
+		if m.GroupId == 0 {
+			m.GroupId = x.WorkerConfig.LearnerGroup
+		}
+	}
 	var connState *pb.ConnectionState
 	var err error
 
@@ -158,6 +166,40 @@ func StartRaftNodes(walStore *raftwal.DiskStorage, bindall bool) {
 	gr.proposeInitialTypes()
 }
 
+// StartReadOnly serves the postings directory opened read-only as the only member of group 1,
+// without connecting to Zero or starting a Raft node. The group serves all the predicates
+// stored in the directory, and the data is read as of its latest version.
+func StartReadOnly() {
+	if x.WorkerConfig.MyAddr == "" {
+		x.WorkerConfig.MyAddr = fmt.Sprintf("localhost:%d", workerPort())
+	}
+	if x.WorkerConfig.RaftId == 0 {
+		x.WorkerConfig.RaftId = 1
+	}
+	x.Checkf(schema.LoadFromDb(), "Error while initializing schema")
+
+	group := &pb.Group{
+		Members: map[uint64]*pb.Member{x.WorkerConfig.RaftId: {
+			Id:      x.WorkerConfig.RaftId,
+			GroupId: 1,
+			Addr:    x.WorkerConfig.MyAddr,
+			Leader:  true,
+		}},
+		Tablets: make(map[string]*pb.Tablet),
+	}
+	for _, pred := range schema.State().Predicates() {
+		group.Tablets[pred] = &pb.Tablet{GroupId: 1, Predicate: pred}
+	}
+	gr.applyState(&pb.MembershipState{Groups: map[uint32]*pb.Group{1: group}})
+	posting.Oracle().ProcessDelta(&pb.OracleDelta{MaxAssigned: pstore.MaxVersion()})
+
+	gr.triggerCh = make(chan struct{}, 1)
+	gr.closer = z.NewCloser(0)
+	x.UpdateHealthStatus(true)
+	glog.Infof("Serving %d predicates read-only as of version %d",
+		len(group.Tablets), posting.Oracle().MaxAssigned())
+}
+
 func (g *groupi) Ctx() context.Context {
 	return g.closer.Ctx()
 }
@@ -423,6 +465,11 @@ func (g *groupi) BelongsToReadOnly(key string, ts uint64) (uint32, error) {
 		return tablet.GetGroupId(), nil
 	}
 
+	if Config.ReadOnly {
+		// All the stored predicates are known, nobody serves the others.
+		return 0, nil
+	}
+
 	// We don't know about this tablet. Talk to dgraphzero to find out who is
 	// serving this tablet.
 	pl := g.connToZeroLeader()
@@ -490,7 +537,7 @@ func (g *groupi) Tablet(key string) (*pb.Tablet, error) {
 	g.RLock()
 	tablet, ok := g.tablets[key]
 	g.RUnlock()
-	if ok {
+	if ok || Config.ReadOnly {
 		return tablet, nil
 	}
 
@@ -570,10 +617,11 @@ func (g *groupi) AnyServer(gid uint32) *conn.Pool {
 	return nil
 }
 
+// MyPeer returns the id of another voting member of this server's group, if there's one.
 func (g *groupi) MyPeer() (uint64, bool) {
 	members := g.members(g.groupId())
 	for _, m := range members {
-		if m.Id != g.Node.Id {
+		if m.Id != g.Node.Id && !m.Learner {
 			return m.Id, true
 		}
 	}
@@ -609,6 +657,45 @@ func (g *groupi) KnownGroups() (gids []uint32) {
 	return
 }
 
+// EdgeProperties returns the predicates storing the properties of the edges of the given
+// predicate. The tablets known to this Alpha cover every group, so no call to Zero is needed.
+func EdgeProperties(pred string) []string {
+	g := groups()
+	g.RLock()
+	defer g.RUnlock()
+	prefix := x.EdgeProperty(pred, "")
+	var props []string
+	for tablet := range g.tablets {
+		if strings.HasPrefix(tablet, prefix) {
+			props = append(props, tablet)
+		}
+	}
+	sort.Strings(props)
+	return props
+}
+
+// CompositeIndexes returns the composite indexes spanning the given predicate, sorted. Like the
+// edge properties, they are known through the tablets of the cluster.
+func CompositeIndexes(pred string) []string {
+	g := groups()
+	g.RLock()
+	defer g.RUnlock()
+	var indexes []string
+	for tablet := range g.tablets {
+		if !x.IsCompositeIndex(tablet) {
+			continue
+		}
+		for _, p := range x.CompositeIndexPredicates(tablet) {
+			if p == pred {
+				indexes = append(indexes, tablet)
+				break
+			}
+		}
+	}
+	sort.Strings(indexes)
+	return indexes
+}
+
 // KnownGroups returns the known groups using the global groupi instance.
 func KnownGroups() []uint32 {
 	return groups().KnownGroups()
@@ -696,9 +783,11 @@ func (g *groupi) doSendMembership(tablets map[string]*pb.Tablet) error {
 		Addr:       x.WorkerConfig.MyAddr,
 		Leader:     leader,
 		LastUpdate: uint64(time.Now().Unix()),
+		Learner:    x.WorkerConfig.Learner,
 	}
 	group := &pb.Group{
 		Members: make(map[uint64]*pb.Member),
+		Loads:   loads.drain(g.groupId()),
 	}
 	group.Members[member.Id] = member
 	if leader {
@@ -1046,6 +1135,39 @@ func (g *groupi) processOracleDeltaStream() {
 	}
 }
 
+// StrictSchemaEnabled returns true if the strict schema mode of the cluster is enabled otherwise
+// false. The mode is kept in the membership state streamed by Zero.
+func StrictSchemaEnabled() bool {
+	g := groups()
+	g.RLock()
+	defer g.RUnlock()
+	return g.state.GetStrictSchema().GetEnabled()
+}
+
+// UpdateStrictSchema enables or disables the strict schema mode of the cluster, and waits for
+// this Alpha to get the new mode from Zero.
+func UpdateStrictSchema(ctx context.Context, enabled bool) error {
+	pl := groups().Leader(0)
+	if pl == nil {
+		return conn.ErrNoConnection
+	}
+	zc := pb.NewZeroClient(pl.Get())
+	if _, err := zc.UpdateStrictSchema(ctx, &pb.StrictSchema{Enabled: enabled}); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for StrictSchemaEnabled() != enabled {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
 // EnterpriseEnabled returns whether enterprise features can be used or not.
 func EnterpriseEnabled() bool {
 	if !enc.EeBuild {
@@ -0,0 +1,157 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/pkg/errors"
+)
+
+// maxHistorySamples bounds the number of samples kept to map times to timestamps.
+const maxHistorySamples = 100000
+
+// historySample records the max timestamp assigned as of a point in time.
+type historySample struct {
+	at time.Time
+	ts uint64
+}
+
+// history keeps track of the timestamps the data can still be read at when versions are
+// retained for x.WorkerConfig.HistoryRetention. Reads at any timestamp not below the discard
+// timestamp see the data as it was committed then.
+type history struct {
+	sync.RWMutex
+	// samples is sorted by time and by timestamp.
+	samples []historySample
+	// discardTs is the last timestamp given to Badger, below which versions can be discarded.
+	discardTs uint64
+}
+
+var hist history
+
+// sampleEvery returns how often to record a sample so that the retention period doesn't
+// take more than maxHistorySamples.
+func sampleEvery(retention time.Duration) time.Duration {
+	every := retention / maxHistorySamples
+	if every < time.Second {
+		every = time.Second
+	}
+	return every
+}
+
+// record adds a sample for the max timestamp assigned at the given time, and drops the samples
+// no longer needed to find the start of the retention period.
+func (h *history) record(at time.Time, ts uint64, retention time.Duration) {
+	h.Lock()
+	defer h.Unlock()
+
+	if n := len(h.samples); n > 0 {
+		last := h.samples[n-1]
+		if ts <= last.ts || at.Sub(last.at) < sampleEvery(retention) {
+			return
+		}
+	}
+	h.samples = append(h.samples, historySample{at: at, ts: ts})
+
+	// Keep the last sample taken before the retention period, as it marks its start.
+	start := h.indexAsOf(at.Add(-retention))
+	if start > 0 {
+		h.samples = append(h.samples[:0], h.samples[start:]...)
+	}
+}
+
+// indexAsOf returns the index of the last sample taken at or before t, or -1 if there's none.
+func (h *history) indexAsOf(t time.Time) int {
+	return sort.Search(len(h.samples), func(i int) bool {
+		return h.samples[i].at.After(t)
+	}) - 1
+}
+
+// retainedDiscardTs returns the timestamp below which versions can be discarded, given the
+// timestamp the snapshot allows discarding versions below. It doesn't go past the start of the
+// retention period. Until this Alpha has been up for the retention period, the start is
+// unknown and no version is discarded.
+func (h *history) retainedDiscardTs(snapshotTs uint64, now time.Time,
+	retention time.Duration) uint64 {
+	h.Lock()
+	defer h.Unlock()
+
+	discardTs := snapshotTs
+	if retention > 0 {
+		idx := h.indexAsOf(now.Add(-retention))
+		switch {
+		case idx < 0:
+			discardTs = 0
+		case h.samples[idx].ts < discardTs:
+			discardTs = h.samples[idx].ts
+		}
+	}
+	h.discardTs = discardTs
+	return discardTs
+}
+
+// recordHistory records the max timestamp assigned now if history is retained.
+func recordHistory(ts uint64) {
+	if retention := x.WorkerConfig.HistoryRetention; retention > 0 {
+		hist.record(time.Now(), ts, retention)
+	}
+}
+
+// historyDiscardTs returns the timestamp to discard versions below after a snapshot at
+// snapshotTs. The versions needed by the freeze points are kept as well.
+func historyDiscardTs(snapshotTs uint64) uint64 {
+	return hist.retainedDiscardTs(freezeDiscardTs(snapshotTs), time.Now(),
+		x.WorkerConfig.HistoryRetention)
+}
+
+// TsAsOf returns the timestamp to read at to see the data as it was at the given time.
+func TsAsOf(t time.Time) (uint64, error) {
+	if x.WorkerConfig.HistoryRetention == 0 {
+		return 0, errors.Errorf("reading the data as of a time requires --history_retention")
+	}
+	if !t.Before(time.Now()) {
+		return 0, errors.Errorf("time to read the data as of should be in the past, got: %s",
+			t.Format(time.RFC3339))
+	}
+
+	hist.RLock()
+	defer hist.RUnlock()
+	idx := hist.indexAsOf(t)
+	if idx < 0 || hist.samples[idx].ts < hist.discardTs {
+		return 0, errors.Errorf("no history is retained as of %s", t.Format(time.RFC3339))
+	}
+	return hist.samples[idx].ts, nil
+}
+
+// CheckHistoricalRead returns an error if history is retained and the versions needed to read
+// at readTs might have been discarded.
+func CheckHistoricalRead(readTs uint64) error {
+	if x.WorkerConfig.HistoryRetention == 0 {
+		return nil
+	}
+	hist.RLock()
+	defer hist.RUnlock()
+	if readTs < hist.discardTs {
+		return errors.Errorf("startTs %d is older than the retained history, which starts "+
+			"at %d", readTs, hist.discardTs)
+	}
+	return nil
+}
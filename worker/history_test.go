@@ -0,0 +1,65 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistoryRecord(t *testing.T) {
+	var h history
+	start := time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC)
+	retention := time.Hour
+	for i := 0; i < 180; i++ {
+		h.record(start.Add(time.Duration(i)*time.Minute), uint64(10*(i+1)), retention)
+	}
+	// Samples with the same timestamp or too close to the last one are skipped.
+	h.record(start.Add(179*time.Minute), 2000, retention)
+	h.record(start.Add(180*time.Minute), 1800, retention)
+
+	now := start.Add(179 * time.Minute)
+	// Only the samples within the retention period and the one just before it are kept.
+	require.Len(t, h.samples, 61)
+	require.Equal(t, start.Add(119*time.Minute), h.samples[0].at)
+	require.Equal(t, uint64(1200), h.samples[0].ts)
+
+	require.Equal(t, 0, h.indexAsOf(now.Add(-retention)))
+	require.Equal(t, 30, h.indexAsOf(now.Add(-30*time.Minute).Add(time.Second)))
+	require.Equal(t, -1, h.indexAsOf(start))
+}
+
+func TestHistoryRetainedDiscardTs(t *testing.T) {
+	var h history
+	start := time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC)
+	retention := time.Hour
+
+	// Without retention, the snapshot decides.
+	require.Equal(t, uint64(100), h.retainedDiscardTs(100, start, 0))
+
+	// Nothing is discarded until the start of the retention period is known.
+	h.record(start, 50, retention)
+	require.Equal(t, uint64(0), h.retainedDiscardTs(100, start.Add(time.Minute), retention))
+
+	h.record(start.Add(30*time.Minute), 80, retention)
+	require.Equal(t, uint64(50), h.retainedDiscardTs(100, start.Add(time.Hour), retention))
+	require.Equal(t, uint64(80), h.retainedDiscardTs(100, start.Add(2*time.Hour), retention))
+	// The snapshot can still hold back discarding further.
+	require.Equal(t, uint64(60), h.retainedDiscardTs(60, start.Add(2*time.Hour), retention))
+}
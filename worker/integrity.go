@@ -0,0 +1,247 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/dgraph/posting"
+	"github.com/dgraph-io/dgraph/schema"
+)
+
+// IntegrityReport is the outcome of a verification of the postings stored by this Alpha.
+type IntegrityReport struct {
+	StartedAt time.Time `json:"startedAt"`
+	// FinishedAt is nil while the verification is running.
+	FinishedAt *time.Time `json:"finishedAt"`
+	// ReadTs is the timestamp the lists were read at.
+	ReadTs uint64 `json:"readTs"`
+	// ChecksumError is the error found verifying the checksums of the blocks of the postings.
+	ChecksumError string `json:"checksumError,omitempty"`
+	// Error is why the verification stopped before all the predicates were verified.
+	Error      string                        `json:"error,omitempty"`
+	Predicates []*posting.PredicateIntegrity `json:"predicates"`
+	// Skipped are the predicates that weren't verified because they were being indexed.
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+// Healthy tells whether the verification is done and found no problem.
+func (r *IntegrityReport) Healthy() bool {
+	if r.FinishedAt == nil || r.ChecksumError != "" || r.Error != "" {
+		return false
+	}
+	for _, pi := range r.Predicates {
+		if !pi.Healthy() {
+			return false
+		}
+	}
+	return true
+}
+
+// integrity tracks the verifications of the postings, which run every
+// Config.IntegrityCheckInterval within the maintenance windows, or when asked through the admin
+// API.
+type integrity struct {
+	sync.Mutex
+	running bool
+	// report is the report of the running verification, or of the last one.
+	report *IntegrityReport
+	// trigger asks for the given predicates, or all of them if there's none, to be verified right
+	// away.
+	trigger chan []string
+}
+
+var integ = &integrity{trigger: make(chan []string, 1)}
+
+// start marks a verification as running, unless one already is.
+func (ig *integrity) start() bool {
+	ig.Lock()
+	defer ig.Unlock()
+	if ig.running {
+		return false
+	}
+	ig.running = true
+	return true
+}
+
+func (ig *integrity) update(f func(r *IntegrityReport)) {
+	ig.Lock()
+	defer ig.Unlock()
+	f(ig.report)
+}
+
+// VerifyIntegrity starts a verification of the given predicates right away, regardless of the
+// maintenance windows and of any deferral. All the predicates served by this Alpha are verified
+// if none is given.
+func VerifyIntegrity(preds []string) error {
+	for _, pred := range preds {
+		if !servesForVerify(pred) {
+			return errors.Errorf("Predicate %s isn't stored by this Alpha.", pred)
+		}
+	}
+	if !integ.start() {
+		return errors.New("An integrity verification is already running.")
+	}
+	integ.trigger <- preds
+	return nil
+}
+
+// LastIntegrityReport returns the report of the running verification of the postings, or of
+// the last one. It returns nil if no verification has run yet.
+func LastIntegrityReport() *IntegrityReport {
+	integ.Lock()
+	defer integ.Unlock()
+	if integ.report == nil {
+		return nil
+	}
+	r := *integ.report
+	r.Predicates = append([]*posting.PredicateIntegrity{}, r.Predicates...)
+	r.Skipped = append([]string{}, r.Skipped...)
+	return &r
+}
+
+// servesForVerify tells whether the data of pred is stored by this Alpha.
+func servesForVerify(pred string) bool {
+	if _, ok := schema.State().Get(context.Background(), pred); !ok {
+		return false
+	}
+	if len(schema.State().Tier(pred)) > 0 {
+		return false
+	}
+	gid, err := groups().BelongsToReadOnly(pred, 0)
+	return err == nil && gid == groups().groupId()
+}
+
+// runIntegrityChecks verifies the postings every Config.IntegrityCheckInterval, whenever the
+// maintenance is allowed to run, and whenever asked through the admin API.
+func (s *ServerState) runIntegrityChecks(closer *z.Closer) {
+	defer closer.Done()
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-closer.HasBeenClosed():
+			return
+		case preds := <-integ.trigger:
+			glog.Infof("Verifying the integrity of the postings as asked through the admin API")
+			s.verifyIntegrity(closer.Ctx(), preds)
+		case <-ticker.C:
+			if Config.IntegrityCheckInterval == 0 || !maint.allowed(time.Now()) {
+				continue
+			}
+			integ.Lock()
+			due := integ.report == nil ||
+				time.Since(integ.report.StartedAt) >= Config.IntegrityCheckInterval
+			integ.Unlock()
+			if due && integ.start() {
+				s.verifyIntegrity(closer.Ctx(), nil)
+			}
+		}
+	}
+}
+
+// verifyIntegrity verifies the checksums of the postings, and then the lists of the given
+// predicates, or of all the ones served by this Alpha if there's none.
+func (s *ServerState) verifyIntegrity(ctx context.Context, preds []string) {
+	start := time.Now()
+	readTs := posting.Oracle().MaxAssigned()
+	integ.Lock()
+	integ.report = &IntegrityReport{StartedAt: start.UTC(), ReadTs: readTs}
+	integ.Unlock()
+	defer func() {
+		integ.Lock()
+		defer integ.Unlock()
+		finished := time.Now().UTC()
+		integ.report.FinishedAt = &finished
+		integ.running = false
+	}()
+
+	if err := s.Pstore.VerifyChecksum(); err != nil {
+		glog.Errorf("Checksum mismatch in the postings: %v", err)
+		integ.update(func(r *IntegrityReport) { r.ChecksumError = err.Error() })
+	}
+
+	if len(preds) == 0 {
+		for _, pred := range schema.State().Predicates() {
+			if servesForVerify(pred) {
+				preds = append(preds, pred)
+			}
+		}
+	}
+	indexing := make(map[string]bool)
+	for _, pred := range schema.GetIndexingPredicates() {
+		indexing[pred] = true
+	}
+
+	th := &keyThrottle{rate: Config.IntegrityCheckRate, start: time.Now()}
+	var problems int
+	for _, pred := range preds {
+		if indexing[pred] {
+			integ.update(func(r *IntegrityReport) { r.Skipped = append(r.Skipped, pred) })
+			continue
+		}
+		res, err := posting.VerifyPredicate(ctx, pred, readTs, func() error {
+			return th.wait(ctx)
+		})
+		integ.update(func(r *IntegrityReport) { r.Predicates = append(r.Predicates, res) })
+		if err != nil {
+			glog.Errorf("Stopped verifying the integrity of the postings at predicate %s: %v",
+				pred, err)
+			integ.update(func(r *IntegrityReport) { r.Error = err.Error() })
+			return
+		}
+		if !res.Healthy() {
+			problems++
+			glog.Errorf("Found %d corrupt lists, %d missing and %d dangling index entries"+
+				" in predicate %s", res.CorruptLists, res.MissingIndexEntries,
+				res.DanglingIndexEntries, pred)
+		}
+	}
+	glog.Infof("Verified the integrity of %d predicates in %s, %d of them have problems",
+		len(preds), time.Since(start).Round(time.Millisecond), problems)
+}
+
+// keyThrottle keeps the lists read by a verification under rate per second. A rate of zero
+// means no limit.
+type keyThrottle struct {
+	rate  int
+	start time.Time
+	n     int64
+}
+
+func (th *keyThrottle) wait(ctx context.Context) error {
+	th.n++
+	if th.rate > 0 {
+		ahead := time.Duration(th.n)*time.Second/time.Duration(th.rate) - time.Since(th.start)
+		// Sleeping for less than a few milliseconds isn't worth it, the lag is made up later.
+		if ahead > 10*time.Millisecond {
+			select {
+			case <-time.After(ahead):
+			case <-ctx.Done():
+			}
+		}
+	}
+	return ctx.Err()
+}
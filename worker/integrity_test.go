@@ -0,0 +1,60 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dgraph-io/dgraph/posting"
+)
+
+func TestKeyThrottle(t *testing.T) {
+	ctx := context.Background()
+	th := &keyThrottle{rate: 100, start: time.Now()}
+	for i := 0; i < 20; i++ {
+		require.NoError(t, th.wait(ctx))
+	}
+	// The 20th list can't be read before 200ms, give or take the lag made up later.
+	require.True(t, time.Since(th.start) >= 190*time.Millisecond)
+
+	unlimited := &keyThrottle{start: time.Now()}
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, unlimited.wait(ctx))
+	}
+	require.True(t, time.Since(unlimited.start) < 100*time.Millisecond)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	require.Equal(t, context.Canceled, th.wait(cancelled))
+}
+
+func TestIntegrityReportHealthy(t *testing.T) {
+	r := &IntegrityReport{Predicates: []*posting.PredicateIntegrity{{Predicate: "name"}}}
+	require.False(t, r.Healthy(), "a running verification isn't healthy yet")
+
+	finished := time.Now()
+	r.FinishedAt = &finished
+	require.True(t, r.Healthy())
+
+	r.Predicates = append(r.Predicates,
+		&posting.PredicateIntegrity{Predicate: "age", DanglingIndexEntries: 1})
+	require.False(t, r.Healthy())
+}
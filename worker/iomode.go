@@ -0,0 +1,126 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	ostats "go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+
+	"github.com/dgraph-io/dgraph/x"
+)
+
+const (
+	// IOModeMmap reads the memory-mapped files of the postings from the disk as they're accessed.
+	IOModeMmap = "mmap"
+	// IOModeRAM also reads the files of the postings into the page cache as soon as they're
+	// written, so that their reads don't wait on the disk as long as they fit in memory.
+	IOModeRAM = "ram"
+)
+
+// ValidateIOMode checks that mode is one of the I/O modes of the files of the postings.
+func ValidateIOMode(mode string) error {
+	switch mode {
+	case IOModeMmap, IOModeRAM:
+		return nil
+	}
+	return errors.Errorf("invalid I/O mode %q, expected %s or %s", mode, IOModeMmap, IOModeRAM)
+}
+
+// pageCache tracks the files of the postings already read into the page cache.
+type pageCache struct {
+	prefetched map[string]bool
+}
+
+// update reads the new files of the postings into the page cache as per their I/O mode, and
+// records how much of the files of each type are in it.
+func (pc *pageCache) update(dir string) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		glog.Errorf("Error while listing the posting files: %v", err)
+		return
+	}
+	resident := make(map[string]int64)
+	size := make(map[string]int64)
+	seen := make(map[string]bool)
+	for _, fi := range files {
+		var typ, mode string
+		switch filepath.Ext(fi.Name()) {
+		case ".sst":
+			typ, mode = "sst", Config.TableIOMode
+		case ".vlog":
+			typ, mode = "vlog", Config.VlogIOMode
+		default:
+			continue
+		}
+		path := filepath.Join(dir, fi.Name())
+		seen[path] = true
+		if mode == IOModeRAM && !pc.prefetched[path] {
+			if err := x.PrefetchFile(path); err != nil {
+				glog.V(2).Infof("Error while reading %s into the page cache: %v", path, err)
+			} else {
+				pc.prefetched[path] = true
+			}
+		}
+		// The file might have been removed by a compaction since it was listed.
+		r, n, err := x.PageCacheResidency(path)
+		if err != nil {
+			continue
+		}
+		resident[typ] += r
+		size[typ] += n
+	}
+	for path := range pc.prefetched {
+		if !seen[path] {
+			delete(pc.prefetched, path)
+		}
+	}
+
+	for _, typ := range []string{"sst", "vlog"} {
+		_ = ostats.RecordWithTags(context.Background(),
+			[]tag.Mutator{tag.Upsert(x.KeyFileType, typ)},
+			x.PageCacheResident.M(resident[typ]), x.PostingFilesSize.M(size[typ]))
+	}
+	ostats.Record(context.Background(), x.NumMajorPageFaults.M(x.MajorPageFaults()))
+}
+
+// monitorPageCache records every minute how much of the files of the postings are in the page
+// cache, and reads the new files into it as per Config.TableIOMode and Config.VlogIOMode.
+func (s *ServerState) monitorPageCache(closer *z.Closer) {
+	defer closer.Done()
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	pc := &pageCache{prefetched: make(map[string]bool)}
+	pc.update(Config.PostingDir)
+	for {
+		select {
+		case <-closer.HasBeenClosed():
+			return
+		case <-ticker.C:
+			pc.update(Config.PostingDir)
+		}
+	}
+}
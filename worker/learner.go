@@ -0,0 +1,102 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/golang/glog"
+	"go.etcd.io/etcd/raft/raftpb"
+
+	"github.com/dgraph-io/dgraph/posting"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// learnerFreshAt is when, in Unix nanoseconds, this learner last found out that it had applied
+// all the Raft entries committed by its group.
+var learnerFreshAt int64
+
+// checkLearner makes this Alpha a learner, or a voter, as per the Raft configuration of its
+// group, which wins over the learner flag once the Alpha has joined the group.
+func (n *node) checkLearner(cs *raftpb.ConfState) {
+	for _, id := range cs.Learners {
+		if id == n.Id && !x.WorkerConfig.Learner {
+			glog.Warningf("Raft node %#x is a learner of group %d, ignoring the learner flag.",
+				n.Id, n.gid)
+			x.WorkerConfig.Learner = true
+		}
+	}
+	for _, id := range cs.Nodes {
+		if id == n.Id && x.WorkerConfig.Learner {
+			glog.Warningf("Raft node %#x is a voter of group %d, ignoring the learner flag.",
+				n.Id, n.gid)
+			x.WorkerConfig.Learner = false
+		}
+	}
+	n.RaftContext.IsLearner = x.WorkerConfig.Learner
+}
+
+// trackFreshness regularly gets the latest read-only timestamp from Zero, and records in
+// learnerFreshAt when this learner has applied the transactions committed up to it.
+func trackFreshness(closer *z.Closer) {
+	defer closer.Done()
+
+	interval := x.WorkerConfig.LearnerMaxStaleness / 4
+	if interval < 100*time.Millisecond {
+		interval = 100 * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-closer.HasBeenClosed():
+			return
+		case <-ticker.C:
+			start := time.Now()
+			ctx, cancel := context.WithTimeout(closer.Ctx(), x.WorkerConfig.LearnerMaxStaleness)
+			ts, err := Timestamps(ctx, &pb.Num{ReadOnly: true})
+			if err == nil {
+				err = posting.Oracle().WaitForTs(ctx, ts.ReadOnly)
+			}
+			cancel()
+			if err != nil {
+				glog.V(2).Infof("Learner couldn't catch up with Zero: %v", err)
+				continue
+			}
+			atomic.StoreInt64(&learnerFreshAt, start.UnixNano())
+		}
+	}
+}
+
+// StaleReadTs returns the timestamp a read-only query can read the data of this Alpha at,
+// without getting a timestamp from Zero. It's only found on a learner that has caught up with
+// the leader of its group within x.WorkerConfig.LearnerMaxStaleness.
+func StaleReadTs() (uint64, bool) {
+	if !x.WorkerConfig.Learner || x.WorkerConfig.LearnerMaxStaleness == 0 {
+		return 0, false
+	}
+	freshAt := time.Unix(0, atomic.LoadInt64(&learnerFreshAt))
+	if time.Since(freshAt) > x.WorkerConfig.LearnerMaxStaleness {
+		return 0, false
+	}
+	return posting.Oracle().MaxAssigned(), true
+}
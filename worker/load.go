@@ -0,0 +1,100 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+)
+
+// tabletLoad counts the queries and the mutations of the tablets since they were last reported
+// to Zero, which takes them into account to rebalance the tablets. Every member of a group
+// counts the queries it serves, and the leader counts the mutated edges of the group.
+type tabletLoad struct {
+	sync.Mutex
+	since     time.Time
+	queries   map[string]uint64
+	mutations map[string]uint64
+}
+
+var loads = newTabletLoad()
+
+func newTabletLoad() *tabletLoad {
+	return &tabletLoad{
+		since:     time.Now(),
+		queries:   make(map[string]uint64),
+		mutations: make(map[string]uint64),
+	}
+}
+
+// queried counts a query of the predicate.
+func (l *tabletLoad) queried(pred string) {
+	l.Lock()
+	defer l.Unlock()
+	l.queries[pred]++
+}
+
+// mutated counts the mutated edges of each predicate.
+func (l *tabletLoad) mutated(edges []*pb.DirectedEdge) {
+	l.Lock()
+	defer l.Unlock()
+	for _, edge := range edges {
+		l.mutations[edge.Attr]++
+	}
+}
+
+// drain returns the rates per minute of the queries and the mutations counted since the last
+// call, and resets the counts.
+func (l *tabletLoad) drain(gid uint32) []*pb.Tablet {
+	l.Lock()
+	defer l.Unlock()
+
+	elapsed := time.Since(l.since)
+	if elapsed < time.Second {
+		// Too short to tell a rate, keep counting.
+		return nil
+	}
+	perMin := func(n uint64) uint64 {
+		return uint64(float64(n) * float64(time.Minute) / float64(elapsed))
+	}
+	tablets := make(map[string]*pb.Tablet)
+	tablet := func(pred string) *pb.Tablet {
+		t, ok := tablets[pred]
+		if !ok {
+			t = &pb.Tablet{GroupId: gid, Predicate: pred}
+			tablets[pred] = t
+		}
+		return t
+	}
+	for pred, n := range l.queries {
+		tablet(pred).QueriesPerMin = perMin(n)
+	}
+	for pred, n := range l.mutations {
+		tablet(pred).MutationsPerMin = perMin(n)
+	}
+	l.since = time.Now()
+	l.queries = make(map[string]uint64)
+	l.mutations = make(map[string]uint64)
+
+	res := make([]*pb.Tablet, 0, len(tablets))
+	for _, t := range tablets {
+		res = append(res, t)
+	}
+	return res
+}
@@ -0,0 +1,53 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+
+package worker
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+)
+
+func TestTabletLoadDrain(t *testing.T) {
+	l := newTabletLoad()
+	l.queried("name")
+	l.queried("name")
+	l.mutated([]*pb.DirectedEdge{{Attr: "name"}, {Attr: "age"}, {Attr: "age"}})
+
+	// The rates aren't reported until they can be told.
+	require.Nil(t, l.drain(1))
+
+	l.since = time.Now().Add(-30 * time.Second)
+	tablets := l.drain(1)
+	sort.Slice(tablets, func(i, j int) bool { return tablets[i].Predicate < tablets[j].Predicate })
+	require.Len(t, tablets, 2)
+	require.Equal(t, "age", tablets[0].Predicate)
+	require.Equal(t, uint32(1), tablets[0].GroupId)
+	require.Zero(t, tablets[0].QueriesPerMin)
+	require.InDelta(t, 4, tablets[0].MutationsPerMin, 1)
+	require.InDelta(t, 4, tablets[1].QueriesPerMin, 1)
+	require.InDelta(t, 2, tablets[1].MutationsPerMin, 1)
+
+	// The counts start over.
+	l.since = time.Now().Add(-time.Minute)
+	require.Empty(t, l.drain(1))
+}
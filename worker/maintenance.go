@@ -0,0 +1,246 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/ristretto/z"
+	humanize "github.com/dustin/go-humanize"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// MaintenanceWindow is a daily range of time, in UTC, in which the value log GC and the full
+// compactions of the postings run. A window that ends before it starts spans midnight.
+type MaintenanceWindow struct {
+	// Start and End are offsets from midnight.
+	Start, End time.Duration
+}
+
+func (w MaintenanceWindow) contains(t time.Time) bool {
+	t = t.UTC()
+	y, m, d := t.Date()
+	offset := t.Sub(time.Date(y, m, d, 0, 0, 0, 0, time.UTC))
+	if w.Start <= w.End {
+		return offset >= w.Start && offset < w.End
+	}
+	return offset >= w.Start || offset < w.End
+}
+
+// ParseMaintenanceWindows parses a comma separated list of windows, such as
+// "01:00-05:00,22:30-23:30". An empty string means that maintenance runs at any time.
+func ParseMaintenanceWindows(s string) ([]MaintenanceWindow, error) {
+	var windows []MaintenanceWindow
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		bounds := strings.Split(part, "-")
+		if len(bounds) != 2 {
+			return nil, errors.Errorf("invalid maintenance window %q, expected HH:MM-HH:MM", part)
+		}
+		var w MaintenanceWindow
+		for i, bound := range bounds {
+			t, err := time.Parse("15:04", strings.TrimSpace(bound))
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid maintenance window %q", part)
+			}
+			offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+			if i == 0 {
+				w.Start = offset
+			} else {
+				w.End = offset
+			}
+		}
+		if w.Start == w.End {
+			return nil, errors.Errorf("maintenance window %q is empty", part)
+		}
+		windows = append(windows, w)
+	}
+	return windows, nil
+}
+
+// maintenance schedules the value log GC and the full compactions of the postings, so that their
+// I/O can be kept out of the peak hours.
+type maintenance struct {
+	sync.Mutex
+	// deferredUntil is when the maintenance deferred through the admin API can run again.
+	deferredUntil time.Time
+	// lastCompaction is when the postings were last fully compacted.
+	lastCompaction time.Time
+	// trigger asks for the maintenance to run right away.
+	trigger chan struct{}
+}
+
+var maint = &maintenance{trigger: make(chan struct{}, 1)}
+
+// allowed tells whether the scheduled maintenance can run at t.
+func (m *maintenance) allowed(t time.Time) bool {
+	m.Lock()
+	deferred := t.Before(m.deferredUntil)
+	m.Unlock()
+	if deferred {
+		return false
+	}
+	if len(Config.MaintenanceWindows) == 0 {
+		return true
+	}
+	for _, w := range Config.MaintenanceWindows {
+		if w.contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// RunMaintenance starts the value log GC and a full compaction of the postings right away,
+// regardless of the maintenance windows and of any deferral.
+func RunMaintenance() error {
+	if Config.ReadOnly {
+		return errors.New("Maintenance doesn't run on a read-only server.")
+	}
+	select {
+	case maint.trigger <- struct{}{}:
+	default:
+		// A run has already been asked for.
+	}
+	return nil
+}
+
+// DeferMaintenance keeps the scheduled maintenance from running for the duration d, and returns
+// when it can run again. A duration of zero cancels the deferral.
+func DeferMaintenance(d time.Duration) time.Time {
+	maint.Lock()
+	defer maint.Unlock()
+	maint.deferredUntil = time.Now().Add(d)
+	return maint.deferredUntil
+}
+
+// MaintenanceDeferredUntil returns when the deferred maintenance can run again, or the zero time
+// if it isn't deferred.
+func MaintenanceDeferredUntil() time.Time {
+	maint.Lock()
+	defer maint.Unlock()
+	if time.Now().After(maint.deferredUntil) {
+		return time.Time{}
+	}
+	return maint.deferredUntil
+}
+
+// VlogGCThresholds returns the fraction of a value log file that has to be discarded for the GC to
+// rewrite it, and the most value log files rewritten by a scheduled GC.
+func VlogGCThresholds() (float64, int) {
+	maint.Lock()
+	defer maint.Unlock()
+	return Config.VlogGCDiscardRatio, Config.VlogGCMaxRuns
+}
+
+// UpdateVlogGCThresholds changes the thresholds of the value log GC, from its next run on. The
+// discard ratio must be between 0 and 1, and maxRuns non-negative.
+func UpdateVlogGCThresholds(discardRatio float64, maxRuns int) error {
+	if discardRatio <= 0 || discardRatio >= 1 {
+		return errors.Errorf("Invalid value log GC discard ratio %v, it must be between 0 and 1.",
+			discardRatio)
+	}
+	if maxRuns < 0 {
+		return errors.Errorf("Invalid value log GC max runs %d, it must be non-negative.",
+			maxRuns)
+	}
+	glog.Infof("Updating the value log GC discard ratio to %v and max runs to %d",
+		discardRatio, maxRuns)
+	maint.Lock()
+	defer maint.Unlock()
+	Config.VlogGCDiscardRatio, Config.VlogGCMaxRuns = discardRatio, maxRuns
+	return nil
+}
+
+// runMaintenance runs the value log GC every minute, and fully compacts the postings every
+// Config.CompactionInterval, whenever the maintenance is allowed to run.
+func (s *ServerState) runMaintenance(closer *z.Closer) {
+	defer closer.Done()
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	scheduled := func() {
+		if !maint.allowed(time.Now()) {
+			return
+		}
+		_, maxRuns := VlogGCThresholds()
+		s.runVlogGC(maxRuns)
+		maint.Lock()
+		due := Config.CompactionInterval > 0 &&
+			time.Since(maint.lastCompaction) >= Config.CompactionInterval
+		maint.Unlock()
+		if due {
+			s.compactPostings()
+		}
+	}
+
+	scheduled()
+	for {
+		select {
+		case <-closer.HasBeenClosed():
+			return
+		case <-maint.trigger:
+			glog.Infof("Running maintenance of the postings as asked through the admin API")
+			s.runVlogGC(0)
+			s.compactPostings()
+		case <-ticker.C:
+			scheduled()
+		}
+	}
+}
+
+// runVlogGC garbage collects the value log until there's nothing left to collect, or until it has
+// rewritten maxRuns files if maxRuns is greater than zero.
+func (s *ServerState) runVlogGC(maxRuns int) {
+	_, before := s.Pstore.Size()
+	discardRatio, _ := VlogGCThresholds()
+	var runs int
+	for err := error(nil); err == nil; {
+		if maxRuns > 0 && runs == maxRuns {
+			break
+		}
+		// If a GC is successful, immediately run it again.
+		runs++
+		err = s.Pstore.RunValueLogGC(discardRatio)
+	}
+	if runs == 0 {
+		return
+	}
+	_, after := s.Pstore.Size()
+	glog.V(2).Infof("Ran Value log GC %d times. Before: %s After: %s",
+		runs, humanize.IBytes(uint64(before)), humanize.IBytes(uint64(after)))
+}
+
+// compactPostings merges all the levels of the postings store into the last one.
+func (s *ServerState) compactPostings() {
+	start := time.Now()
+	if err := s.Pstore.Flatten(1); err != nil {
+		glog.Errorf("Error while compacting the postings: %v", err)
+		return
+	}
+	maint.Lock()
+	maint.lastCompaction = time.Now()
+	maint.Unlock()
+	glog.Infof("Compacted the postings in %s", time.Since(start).Round(time.Millisecond))
+}
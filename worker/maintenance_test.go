@@ -0,0 +1,73 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMaintenanceWindows(t *testing.T) {
+	windows, err := ParseMaintenanceWindows("")
+	require.NoError(t, err)
+	require.Empty(t, windows)
+
+	windows, err = ParseMaintenanceWindows("01:00-05:30, 22:00-02:00")
+	require.NoError(t, err)
+	require.Equal(t, []MaintenanceWindow{
+		{Start: time.Hour, End: 5*time.Hour + 30*time.Minute},
+		{Start: 22 * time.Hour, End: 2 * time.Hour},
+	}, windows)
+
+	for _, s := range []string{"01:00", "1am-5am", "01:00-01:00", "01:00-25:00"} {
+		_, err = ParseMaintenanceWindows(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestMaintenanceAllowed(t *testing.T) {
+	old := Config
+	defer func() { Config = old }()
+	defer DeferMaintenance(0)
+
+	at := func(hour, min int) time.Time {
+		return time.Date(2020, 10, 1, hour, min, 0, 0, time.UTC)
+	}
+	m := &maintenance{}
+	require.True(t, m.allowed(at(12, 0)))
+
+	// The second window spans midnight.
+	Config.MaintenanceWindows, _ = ParseMaintenanceWindows("01:00-05:30,22:00-00:30")
+	require.True(t, m.allowed(at(1, 0)))
+	require.False(t, m.allowed(at(5, 30)))
+	require.False(t, m.allowed(at(12, 0)))
+	require.True(t, m.allowed(at(23, 59)))
+	require.True(t, m.allowed(at(0, 15)))
+	require.False(t, m.allowed(at(0, 30)))
+
+	// Deferring it keeps it from running, even within a window.
+	m.deferredUntil = at(2, 0)
+	require.False(t, m.allowed(at(1, 0)))
+	require.True(t, m.allowed(at(2, 0)))
+
+	until := DeferMaintenance(time.Hour)
+	require.Equal(t, until, MaintenanceDeferredUntil())
+	DeferMaintenance(0)
+	require.True(t, MaintenanceDeferredUntil().IsZero())
+}
@@ -17,11 +17,17 @@
 package worker
 
 import (
+	"bytes"
+	"context"
+	"strings"
+
+	"github.com/dgraph-io/badger/v2"
 	"github.com/dgraph-io/dgraph/algo"
 	"github.com/dgraph-io/dgraph/posting"
 	"github.com/dgraph-io/dgraph/protos/pb"
 	"github.com/dgraph-io/dgraph/tok"
 	"github.com/dgraph-io/dgraph/x"
+	"github.com/pkg/errors"
 )
 
 // LevenshteinDistance measures the difference between two strings.
@@ -68,13 +74,217 @@ func min(a, b, c int) int {
 	return c
 }
 
-// matchFuzzy takes in a value (from posting) and compares it to our list of ngram tokens.
-// Returns true if value matches fuzzy tokens, false otherwise.
-func matchFuzzy(query, val string, max int) bool {
+// maxFuzzyTerms is the maximum number of index tokens that a fuzzy match can expand to when it
+// walks an exact or term index.
+const maxFuzzyTerms = 1000
+
+var errFuzzyTooWide = errors.Errorf(
+	"fuzzy match expands to more than %d terms and can't be executed efficiently. "+
+		"Use a smaller distance or a longer prefix", maxFuzzyTerms)
+
+// fuzzyMatcher matches strings within a maximum Levenshtein distance of a query, whose first
+// runes have to match exactly. Strings are consumed one rune at a time, computing a new row of
+// the distance matrix for each rune, so that matching can stop as soon as a prefix of the
+// string can't lead to a match anymore.
+type fuzzyMatcher struct {
+	prefix string
+	query  []rune
+	max    int
+}
+
+func newFuzzyMatcher(query string, max, prefixLen int) *fuzzyMatcher {
+	runes := []rune(query)
+	if prefixLen > len(runes) {
+		prefixLen = len(runes)
+	}
+	return &fuzzyMatcher{prefix: string(runes[:prefixLen]), query: runes[prefixLen:], max: max}
+}
+
+// start returns the row of the distance matrix for an empty string.
+func (m *fuzzyMatcher) start() []int {
+	row := make([]int, len(m.query)+1)
+	for i := range row {
+		row[i] = i
+	}
+	return row
+}
+
+// step returns the row of the distance matrix that follows row after consuming r.
+func (m *fuzzyMatcher) step(row []int, r rune) []int {
+	next := make([]int, len(row))
+	next[0] = row[0] + 1
+	for i := 1; i < len(row); i++ {
+		cost := 1
+		if m.query[i-1] == r {
+			cost = 0
+		}
+		next[i] = min(row[i]+1, next[i-1]+1, row[i-1]+cost)
+	}
+	return next
+}
+
+// canMatch returns false if no string starting with the runes consumed to get to row can match.
+func (m *fuzzyMatcher) canMatch(row []int) bool {
+	for _, d := range row {
+		if d <= m.max {
+			return true
+		}
+	}
+	return false
+}
+
+// isMatch returns true if the runes consumed to get to row match the query.
+func (m *fuzzyMatcher) isMatch(row []int) bool {
+	return row[len(row)-1] <= m.max
+}
+
+// match returns true if val starts with the prefix and the rest of it is within the maximum
+// distance of the rest of the query.
+func (m *fuzzyMatcher) match(val string) bool {
+	if !strings.HasPrefix(val, m.prefix) {
+		return false
+	}
+	row := m.start()
+	for _, r := range val[len(m.prefix):] {
+		if row = m.step(row, r); !m.canMatch(row) {
+			return false
+		}
+	}
+	return m.isMatch(row)
+}
+
+// matchFuzzy takes in a value (from posting) and compares it to the query of the matcher.
+// If terms isn't nil, the value matches if any of its terms, as given by the term tokenizer
+// terms, does.
+// Returns true if value matches, false otherwise.
+func matchFuzzy(m *fuzzyMatcher, val string, terms tok.Tokenizer) bool {
 	if val == "" {
 		return false
 	}
-	return levenshteinDistance(val, query) <= max
+	if terms == nil {
+		return m.match(val)
+	}
+	tokens, err := tok.BuildTokens(val, terms)
+	if err != nil {
+		return false
+	}
+	for _, t := range tokens {
+		// Skip the tokenizer identifier.
+		if m.match(t[1:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// fuzzyTerm returns the normalized form of a query that is matched against the tokens of a
+// term index built with the tokenizer terms.
+func fuzzyTerm(query string, terms tok.Tokenizer) (string, error) {
+	tokens, err := tok.BuildTokens(query, terms)
+	if err != nil {
+		return "", err
+	}
+	if len(tokens) != 1 {
+		return "", errors.Errorf("Fuzzy match over a term index requires a single term, got %q",
+			query)
+	}
+	return tokens[0][1:], nil
+}
+
+// uidsForFuzzyTerms collects the uids indexed under the tokens of the given tokenizer that are
+// matched by m. The keys of the index are sorted, so the rows of the distance matrix computed
+// for a token are reused for the prefix it shares with the next one, and all the tokens sharing
+// a prefix that can't lead to a match are skipped with a single seek.
+func uidsForFuzzyTerms(ctx context.Context, attr string, arg funcArgs, id byte,
+	m *fuzzyMatcher) (*pb.List, error) {
+	txn := pstore.NewTransactionAt(arg.q.ReadTs, false)
+	defer txn.Discard()
+
+	// Only the tokens that start with the prefix of the query need to be visited.
+	prefix := x.IndexKey(attr, string(id)+m.prefix)
+	itOpt := badger.DefaultIteratorOptions
+	itOpt.PrefetchValues = false
+	itOpt.Prefix = prefix
+	it := txn.NewIterator(itOpt)
+	defer it.Close()
+
+	var keys [][]byte
+	// rows[i] holds the row of the distance matrix after consuming the first i runes of last.
+	rows := [][]int{m.start()}
+	var last []rune
+	for it.Rewind(); it.Valid(); {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		item := it.Item()
+		key := item.KeyCopy(nil)
+		term := []rune(string(key[len(prefix):]))
+
+		shared := 0
+		for shared < len(last) && shared < len(term) && last[shared] == term[shared] {
+			shared++
+		}
+		rows = rows[:shared+1]
+		last = term
+		for i := shared; i < len(term); i++ {
+			row := m.step(rows[i], term[i])
+			rows = append(rows, row)
+			if !m.canMatch(row) {
+				last = term[:i+1]
+				break
+			}
+		}
+
+		if len(last) < len(term) {
+			// None of the tokens starting with last can match.
+			next := make([]byte, len(prefix), len(prefix)+len(string(last))+1)
+			copy(next, prefix)
+			next = append(next, string(last)...)
+			if next = prefixSuccessor(next); next == nil {
+				break
+			}
+			// Tokens that aren't valid UTF-8 don't round trip through runes, make sure the
+			// iterator always moves forward.
+			if bytes.Compare(next, key) > 0 {
+				it.Seek(next)
+				continue
+			}
+		} else if m.isMatch(rows[len(term)]) && item.UserMeta()&posting.BitEmptyPosting == 0 {
+			if len(keys) == maxFuzzyTerms {
+				return nil, errFuzzyTooWide
+			}
+			keys = append(keys, key)
+		}
+		it.Next()
+	}
+
+	opts := posting.ListOptions{ReadTs: arg.q.ReadTs}
+	uidMatrix := make([]*pb.List, len(keys))
+	for i, key := range keys {
+		pl, err := posting.GetNoStore(key, arg.q.ReadTs)
+		if err != nil {
+			return nil, err
+		}
+		if uidMatrix[i], err = pl.Uids(opts); err != nil {
+			return nil, err
+		}
+	}
+	return algo.MergeSorted(uidMatrix), nil
+}
+
+// prefixSuccessor returns the smallest key that is greater than all the keys starting with
+// prefix, or nil if there is no such key.
+func prefixSuccessor(prefix []byte) []byte {
+	for i := len(prefix) - 1; i >= 0; i-- {
+		if prefix[i] < 0xff {
+			prefix[i]++
+			return prefix[:i+1]
+		}
+	}
+	return nil
 }
 
 // uidsForMatch collects a list of uids that "might" match a fuzzy term based on the ngram
@@ -1,9 +1,15 @@
 package worker
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/tok"
+	"github.com/dgraph-io/dgraph/x"
 )
 
 func TestDistance(t *testing.T) {
@@ -18,3 +24,70 @@ func TestDistance(t *testing.T) {
 	require.Equal(t, 1, levenshteinDistance("detour", "detoar"))
 	require.Equal(t, 6, levenshteinDistance("detour", "DETOUR"))
 }
+
+func TestFuzzyMatcher(t *testing.T) {
+	m := newFuzzyMatcher("detour", 1, 0)
+	require.True(t, m.match("detour"))
+	require.True(t, m.match("detoar"))
+	require.True(t, m.match("detours"))
+	require.False(t, m.match("detoars"))
+	require.False(t, m.match(""))
+
+	// The prefix has to match exactly even if the distance allows a change.
+	m = newFuzzyMatcher("detour", 1, 2)
+	require.True(t, m.match("detoar"))
+	require.False(t, m.match("betour"))
+	require.False(t, m.match("dtour"))
+
+	// A prefix longer than the query locks the whole query.
+	m = newFuzzyMatcher("de", 2, 5)
+	require.True(t, m.match("de"))
+	require.True(t, m.match("deal"))
+	require.False(t, m.match("d"))
+}
+
+func TestMatchFuzzyTerms(t *testing.T) {
+	m := newFuzzyMatcher("tems", 1, 0)
+	require.True(t, matchFuzzy(m, "Two Terms", tok.TermTokenizer{}))
+	require.False(t, matchFuzzy(m, "Two Terms", nil))
+	require.False(t, matchFuzzy(m, "Two Words", tok.TermTokenizer{}))
+
+	term, err := fuzzyTerm("Terms", tok.TermTokenizer{})
+	require.NoError(t, err)
+	require.Equal(t, "terms", term)
+	_, err = fuzzyTerm("Two Terms", tok.TermTokenizer{})
+	require.Error(t, err)
+}
+
+func TestPrefixSuccessor(t *testing.T) {
+	require.Equal(t, []byte("ab"), prefixSuccessor([]byte("aa")))
+	require.Equal(t, []byte("b"), prefixSuccessor([]byte{'a', 0xff}))
+	require.Nil(t, prefixSuccessor([]byte{0xff, 0xff}))
+}
+
+func TestUidsForFuzzyTerms(t *testing.T) {
+	attr := "fuzzy_exact"
+	require.NoError(t, schema.ParseBytes([]byte(attr+": string @index(exact) ."), 1))
+	for uid, val := range map[uint64]string{
+		1: "detour", 2: "detoar", 3: "detours", 4: "betour", 5: "route",
+	} {
+		key := x.IndexKey(attr, string([]byte{tok.IdentExact})+val)
+		addEdge(t, &pb.DirectedEdge{ValueId: uid, Attr: attr, Entity: uid}, getOrCreate(key))
+	}
+
+	arg := funcArgs{q: &pb.Query{ReadTs: timestamp()}}
+	uids, err := uidsForFuzzyTerms(context.Background(), attr, arg, tok.IdentExact,
+		newFuzzyMatcher("detour", 1, 0))
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1, 2, 3, 4}, uids.Uids)
+
+	uids, err = uidsForFuzzyTerms(context.Background(), attr, arg, tok.IdentExact,
+		newFuzzyMatcher("detour", 1, 1))
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1, 2, 3}, uids.Uids)
+
+	uids, err = uidsForFuzzyTerms(context.Background(), attr, arg, tok.IdentExact,
+		newFuzzyMatcher("rout", 0, 0))
+	require.NoError(t, err)
+	require.Empty(t, uids.Uids)
+}
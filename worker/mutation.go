@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"context"
 	"math"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -47,6 +48,10 @@ var (
 	ErrNonExistentTabletMessage = "Requested predicate is not being served by any tablet"
 	errNonExistentTablet        = errors.Errorf(ErrNonExistentTabletMessage)
 	errUnservedTablet           = errors.Errorf("Tablet isn't being served by this instance")
+
+	// schemaWriteLock serializes the writes of the schema of predicates to disk with the changes
+	// they're made of, so that the dictionaries written last are the latest ones.
+	schemaWriteLock sync.Mutex
 )
 
 func isStarAll(v []byte) bool {
@@ -64,6 +69,11 @@ func runMutation(ctx context.Context, edge *pb.DirectedEdge, txn *posting.Txn) e
 	// We shouldn't check whether this Alpha serves this predicate or not. Membership information
 	// isn't consistent across the entire cluster. We should just apply whatever is given to us.
 	su, ok := schema.State().Get(ctx, edge.Attr)
+	if !ok && (x.IsEdgeProperty(edge.Attr) || x.IsCompositeIndex(edge.Attr)) {
+		// The property or the index has been dropped, but its tablet is still known to the
+		// Alpha that derived this edge from other ones. There's nothing to store.
+		return nil
+	}
 	if edge.Op == pb.DirectedEdge_SET {
 		if !ok {
 			return errors.Errorf("runMutation: Unable to find schema for %s", edge.Attr)
@@ -77,7 +87,7 @@ func runMutation(ctx context.Context, edge *pb.DirectedEdge, txn *posting.Txn) e
 	// Type check is done before proposing mutation, in case schema is not
 	// present, some invalid entries might be written initially
 	if err := ValidateAndConvert(edge, &su); err != nil {
-		return err
+		return x.InvalidArgument(err)
 	}
 
 	key := x.DataKey(edge.Attr, edge.Entity)
@@ -234,6 +244,11 @@ func runSchemaMutation(ctx context.Context, updates []*pb.SchemaUpdate, startTs
 		// disk only after schema mutations are successful.
 		schema.State().Set(su.Predicate, querySchema)
 		schema.State().SetMutSchema(su.Predicate, su)
+		if len(su.EnumValues) > 0 {
+			if err := addEnumValues(su); err != nil {
+				return err
+			}
+		}
 
 		// TODO(Aman): If we return an error, we may not have right schema reflected.
 		setup := func() error {
@@ -261,8 +276,15 @@ func runSchemaMutation(ctx context.Context, updates []*pb.SchemaUpdate, startTs
 // updateSchema commits the schema to disk in blocking way, should be ok because this happens
 // only during schema mutations or we see a new predicate.
 func updateSchema(s *pb.SchemaUpdate) error {
+	schemaWriteLock.Lock()
+	defer schemaWriteLock.Unlock()
 	schema.State().Set(s.Predicate, s)
 	schema.State().DeleteMutSchema(s.Predicate)
+	return writeSchema(s)
+}
+
+// writeSchema writes the schema of a predicate to disk, without changing it in memory.
+func writeSchema(s *pb.SchemaUpdate) error {
 	txn := pstore.NewTransactionAt(1, true)
 	defer txn.Discard()
 	data, err := s.Marshal()
@@ -278,6 +300,41 @@ func updateSchema(s *pb.SchemaUpdate) error {
 	return txn.CommitAt(1, nil)
 }
 
+// writeEncoding writes the dictionaries and the delta base of the given schema to the schema of
+// the predicate on disk. The rest of it is left as is, as the schema on disk is behind the one in
+// memory while indexes are built in the background.
+func writeEncoding(su *pb.SchemaUpdate) error {
+	txn := pstore.NewTransactionAt(1, true)
+	defer txn.Discard()
+	item, err := txn.Get(x.SchemaKey(su.Predicate))
+	switch {
+	case err == badger.ErrKeyNotFound:
+		return writeSchema(su)
+	case err != nil:
+		return err
+	}
+	var stored pb.SchemaUpdate
+	if err := item.Value(stored.Unmarshal); err != nil {
+		return err
+	}
+	stored.Dictionary = su.Dictionary
+	stored.EncodingBase, stored.EncodingBaseSet = su.EncodingBase, su.EncodingBaseSet
+	stored.CompressionDictionaries = su.CompressionDictionaries
+	data, err := stored.Marshal()
+	if err != nil {
+		return err
+	}
+	err = txn.SetEntry(&badger.Entry{
+		Key:      x.SchemaKey(su.Predicate),
+		Value:    data,
+		UserMeta: posting.BitSchemaPosting,
+	})
+	if err != nil {
+		return err
+	}
+	return txn.CommitAt(1, nil)
+}
+
 func createSchema(attr string, typ types.TypeID, hint pb.Metadata_HintType) error {
 	ctx := schema.GetWriteContext(context.Background())
 
@@ -308,6 +365,84 @@ func createSchema(attr string, typ types.TypeID, hint pb.Metadata_HintType) erro
 	return updateSchema(&s)
 }
 
+// extendEncodings adds the strings set by the edges to the dictionaries of their predicates, and
+// sets the delta base of the predicates that don't have one yet. It runs as the txns are
+// committed in the order of the Raft log, so that all the members of the group give the strings
+// the same ids, and the values of aborted txns don't take up room in the dictionaries. The values
+// are encoded as they're written.
+func extendEncodings(edges []*pb.DirectedEdge) error {
+	// The schema being applied in the background is the one all the members of the group have.
+	ctx := schema.GetWriteContext(context.Background())
+	values := make(map[string][]string)
+	bases := make(map[string]int64)
+	for _, edge := range edges {
+		if edge.Op != pb.DirectedEdge_SET || edge.ValueType == pb.Posting_UID {
+			continue
+		}
+		su, ok := schema.State().Get(ctx, edge.Attr)
+		if !ok {
+			continue
+		}
+		switch schema.ValueEncoding(&su) {
+		case schema.EncodingDictionary:
+			if tid := types.TypeID(edge.ValueType); tid == types.StringID || tid == types.DefaultID {
+				values[edge.Attr] = append(values[edge.Attr], string(edge.Value))
+			}
+		case schema.EncodingDelta:
+			if _, ok := bases[edge.Attr]; ok || su.EncodingBaseSet {
+				continue
+			}
+			// The base is the first value, as it's stored.
+			typ := types.TypeID(su.ValueType)
+			src := types.Val{Tid: types.TypeID(edge.ValueType), Value: edge.Value}
+			dst, err := types.Convert(src, typ)
+			if err != nil {
+				continue
+			}
+			b := types.ValueForType(types.BinaryID)
+			if err := types.Marshal(dst, &b); err != nil {
+				continue
+			}
+			if base, ok := posting.DeltaValue(typ, b.Value.([]byte)); ok {
+				bases[edge.Attr] = base
+			}
+		}
+	}
+	if len(values) == 0 && len(bases) == 0 {
+		return nil
+	}
+
+	schemaWriteLock.Lock()
+	defer schemaWriteLock.Unlock()
+	for attr, vals := range values {
+		if su := schema.State().ExtendEncoding(attr, vals, 0, false); su != nil {
+			if err := writeEncoding(su); err != nil {
+				return err
+			}
+		}
+	}
+	for attr, base := range bases {
+		if su := schema.State().ExtendEncoding(attr, nil, base, true); su != nil {
+			if err := writeEncoding(su); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// addEnumValues adds the values of the enum of the predicate to its dictionary, so that they're
+// given ids in the order of the enum.
+func addEnumValues(su *pb.SchemaUpdate) error {
+	schemaWriteLock.Lock()
+	defer schemaWriteLock.Unlock()
+	if updated := schema.State().ExtendEncoding(su.Predicate, su.EnumValues, 0,
+		false); updated != nil {
+		return writeEncoding(updated)
+	}
+	return nil
+}
+
 func runTypeMutation(ctx context.Context, update *pb.TypeUpdate) error {
 	current := *update
 	schema.State().SetType(update.TypeName, current)
@@ -401,6 +536,13 @@ func checkSchema(s *pb.SchemaUpdate) error {
 		return nil
 	}
 
+	// The schema of an offloaded predicate can't be changed, as its data isn't there to rebuild
+	// the indexes from.
+	if tier := schema.State().Tier(s.Predicate); len(tier) > 0 {
+		return errors.Errorf("Predicate %s is in the %s storage tier. Please query it to fetch"+
+			" it back, then retry", s.Predicate, tier)
+	}
+
 	// schema was defined already
 	switch {
 	case t.IsScalar() && (t.Enum() == pb.Posting_PASSWORD || s.ValueType == pb.Posting_PASSWORD):
@@ -428,6 +570,24 @@ func checkSchema(s *pb.SchemaUpdate) error {
 	return nil
 }
 
+// checkEnumValue returns an error if the value of the edge isn't one of the values of the enum of
+// its predicate.
+func checkEnumValue(edge *pb.DirectedEdge, su *pb.SchemaUpdate) error {
+	if edge.BlobSize > 0 {
+		return errors.Errorf("Large values can't be written to enum predicate %q", edge.Attr)
+	}
+	src := types.Val{Tid: types.TypeID(edge.ValueType), Value: edge.Value}
+	str, err := types.Convert(src, types.StringID)
+	if err != nil {
+		return errors.Wrapf(err, "Input for enum predicate %q has an invalid value", edge.Attr)
+	}
+	if val, _ := str.Value.(string); !schema.IsEnumValue(su, val) {
+		return errors.Errorf("Value %q isn't one of the values of enum predicate %q: %s", val,
+			edge.Attr, strings.Join(su.EnumValues, ", "))
+	}
+	return nil
+}
+
 // ValidateAndConvert checks compatibility or converts to the schema type if the storage type is
 // specified. If no storage type is specified then it converts to the schema type.
 func ValidateAndConvert(edge *pb.DirectedEdge, su *pb.SchemaUpdate) error {
@@ -437,6 +597,33 @@ func ValidateAndConvert(edge *pb.DirectedEdge, su *pb.SchemaUpdate) error {
 	if types.TypeID(edge.ValueType) == types.DefaultID && isStarAll(edge.Value) {
 		return nil
 	}
+	// Deleting an edge property only needs the destination of the edge.
+	if x.IsEdgeProperty(edge.Attr) && edge.Op == pb.DirectedEdge_DEL {
+		return nil
+	}
+	// The values of a composite index are strings holding the values of all the predicates it
+	// spans, whatever the type of the index is.
+	if x.IsCompositeIndex(edge.Attr) {
+		return nil
+	}
+	if len(su.GetEnumValues()) > 0 && edge.Op == pb.DirectedEdge_SET {
+		if err := checkEnumValue(edge, su); err != nil {
+			return err
+		}
+	}
+	// The value of a blob edge has already been stored in chunks, so it can't be converted.
+	if edge.BlobSize > 0 {
+		if su.GetList() || len(su.GetTokenizer()) > 0 {
+			return errors.Errorf("Large values can't be written to predicate %q with a list type "+
+				"or an index", edge.Attr)
+		}
+		switch types.TypeID(su.ValueType) {
+		case types.StringID, types.DefaultID, types.BinaryID:
+			return nil
+		}
+		return errors.Errorf("Large values can't be written to predicate %q of type %s",
+			edge.Attr, types.TypeID(su.ValueType).Name())
+	}
 
 	storageType := posting.TypeID(edge)
 	schemaType := types.TypeID(su.ValueType)
@@ -473,7 +660,8 @@ func ValidateAndConvert(edge *pb.DirectedEdge, su *pb.SchemaUpdate) error {
 	src := types.Val{Tid: types.TypeID(edge.ValueType), Value: edge.Value}
 	// check compatibility of schema type and storage type
 	if dst, err = types.Convert(src, schemaType); err != nil {
-		return err
+		return errors.Wrapf(err, "Input for predicate %q of type %s is scalar but has an invalid "+
+			"value", edge.Attr, schemaType.Name())
 	}
 
 	// convert to schema type
@@ -509,6 +697,20 @@ func AssignUidsOverNetwork(ctx context.Context, num *pb.Num) (*pb.AssignedIds, e
 	return c.AssignUids(ctx, num)
 }
 
+// MaxUidLease is the maximum number of uids that can be leased at once with LeaseUids.
+const MaxUidLease = 1e9
+
+// LeaseUids leases a contiguous range of num uids from the current zero leader. Dgraph doesn't
+// assign the uids of the range to any node, so clients can use them for their own nodes, e.g.
+// in the RDF files given to the bulk loader.
+func LeaseUids(ctx context.Context, num uint64) (*pb.AssignedIds, error) {
+	if num == 0 || num > MaxUidLease {
+		return nil, errors.Errorf("number of uids to lease should be between 1 and %d, got: %d",
+			uint64(MaxUidLease), num)
+	}
+	return AssignUidsOverNetwork(ctx, &pb.Num{Val: num})
+}
+
 // Timestamps sends a request to assign startTs for a new transaction to the current zero leader.
 func Timestamps(ctx context.Context, num *pb.Num) (*pb.AssignedIds, error) {
 	pl := groups().connToZeroLeader()
@@ -588,6 +790,20 @@ func populateMutationMap(src *pb.Mutations) (map[uint32]*pb.Mutations, error) {
 		mu.Metadata = src.Metadata
 	}
 
+	for _, chunk := range src.BlobChunks {
+		gid, err := groups().BelongsTo(chunk.Attr)
+		if err != nil {
+			return nil, err
+		}
+
+		mu := mm[gid]
+		if mu == nil {
+			mu = &pb.Mutations{GroupId: gid}
+			mm[gid] = mu
+		}
+		mu.BlobChunks = append(mu.BlobChunks, chunk)
+	}
+
 	for _, schema := range src.Schema {
 		gid, err := groups().BelongsTo(schema.Predicate)
 		if err != nil {
@@ -626,6 +842,19 @@ func populateMutationMap(src *pb.Mutations) (map[uint32]*pb.Mutations, error) {
 		}
 	}
 
+	// Savepoints are sent to all groups, as any of them might hold pending changes of the txn.
+	if len(src.Savepoint) > 0 || len(src.RollbackTo) > 0 {
+		for _, gid := range groups().KnownGroups() {
+			mu := mm[gid]
+			if mu == nil {
+				mu = &pb.Mutations{GroupId: gid}
+				mm[gid] = mu
+			}
+			mu.Savepoint = src.Savepoint
+			mu.RollbackTo = src.RollbackTo
+		}
+	}
+
 	return mm, nil
 }
 
@@ -791,6 +1020,17 @@ func CommitOverNetwork(ctx context.Context, tc *api.TxnContext) (uint64, error)
 	return tctx.CommitTs, nil
 }
 
+// IdempotencyOverNetwork looks up the commit made with the idempotency key in the current zero
+// leader. The returned record is empty if there isn't any within the retention window.
+func IdempotencyOverNetwork(ctx context.Context, key string) (*pb.IdempotencyRecord, error) {
+	pl := groups().Leader(0)
+	if pl == nil {
+		return nil, conn.ErrNoConnection
+	}
+	zc := pb.NewZeroClient(pl.Get())
+	return zc.Idempotency(ctx, &pb.IdempotencyRecord{Key: key})
+}
+
 func (w *grpcWorker) proposeAndWait(ctx context.Context, txnCtx *api.TxnContext,
 	m *pb.Mutations) error {
 	if x.WorkerConfig.StrictMutations {
@@ -17,11 +17,13 @@
 package worker
 
 import (
+	"context"
 	"reflect"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/dgraph-io/dgraph/conn"
 	"github.com/dgraph-io/dgraph/posting"
 	"github.com/dgraph-io/dgraph/protos/pb"
 	"github.com/dgraph-io/dgraph/schema"
@@ -114,6 +116,33 @@ func TestValidateEdgeTypeError(t *testing.T) {
 			ValueType: pb.Posting_ValType(types.DateTimeID),
 		})
 	require.Error(t, err)
+	require.Contains(t, err.Error(),
+		`Input for predicate "name" of type datetime is scalar but has an invalid value`)
+}
+
+func TestValidateBlobEdge(t *testing.T) {
+	edge := &pb.DirectedEdge{
+		Value:     []byte("chunks id"),
+		ValueType: pb.Posting_STRING,
+		Attr:      "name",
+		BlobSize:  1 << 30,
+	}
+	require.NoError(t, ValidateAndConvert(edge,
+		&pb.SchemaUpdate{ValueType: pb.Posting_ValType(types.BinaryID)}))
+	require.Equal(t, []byte("chunks id"), edge.Value)
+	require.Equal(t, pb.Posting_STRING, edge.ValueType)
+
+	err := ValidateAndConvert(edge, &pb.SchemaUpdate{
+		ValueType: pb.Posting_ValType(types.StringID),
+		Tokenizer: []string{"exact"},
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "with a list type or an index")
+
+	err = ValidateAndConvert(edge,
+		&pb.SchemaUpdate{ValueType: pb.Posting_ValType(types.IntID)})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `predicate "name" of type int`)
 }
 
 func TestPopulateMutationMap(t *testing.T) {
@@ -124,7 +153,11 @@ func TestPopulateMutationMap(t *testing.T) {
 	schema := []*pb.SchemaUpdate{{
 		Predicate: "name",
 	}}
-	m := &pb.Mutations{Edges: edges, Schema: schema}
+	chunks := []*pb.BlobChunk{{
+		Attr: "name",
+		Data: []byte("chunk"),
+	}}
+	m := &pb.Mutations{Edges: edges, Schema: schema, BlobChunks: chunks}
 
 	mutationsMap, err := populateMutationMap(m)
 	require.NoError(t, err)
@@ -132,6 +165,7 @@ func TestPopulateMutationMap(t *testing.T) {
 	require.NotNil(t, mu)
 	require.NotNil(t, mu.Edges)
 	require.NotNil(t, mu.Schema)
+	require.NotNil(t, mu.BlobChunks)
 }
 
 func TestCheckSchema(t *testing.T) {
@@ -267,3 +301,41 @@ func TestTypeSanityCheck(t *testing.T) {
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "Field in type definition cannot have tokenizers")
 }
+
+func TestLeaseUidsInvalidNum(t *testing.T) {
+	_, err := LeaseUids(context.Background(), 0)
+	require.EqualError(t, err, "number of uids to lease should be between 1 and 1000000000, got: 0")
+	_, err = LeaseUids(context.Background(), MaxUidLease+1)
+	require.EqualError(t, err,
+		"number of uids to lease should be between 1 and 1000000000, got: 1000000001")
+}
+
+func TestExtendEncodingsOnCommit(t *testing.T) {
+	attr := "encoded_color"
+	gr.tablets[attr] = &pb.Tablet{GroupId: 1}
+	require.NoError(t, schema.ParseBytes([]byte(attr+": string @encoding(dictionary) ."), 1))
+	n := &node{Node: &conn.Node{}}
+	mutate := func(uid uint64, val string) uint64 {
+		startTs := timestamp()
+		edge := &pb.DirectedEdge{Attr: attr, Entity: uid, Value: []byte(val),
+			ValueType: pb.Posting_STRING, Op: pb.DirectedEdge_SET}
+		require.NoError(t, n.applyMutations(context.Background(), &pb.Proposal{
+			Mutations: &pb.Mutations{StartTs: startTs, Edges: []*pb.DirectedEdge{edge}}}))
+		return startTs
+	}
+
+	// The values of aborted txns don't take up room in the dictionary.
+	startTs := mutate(1, "red")
+	require.NoError(t, n.commitOrAbort("", &pb.OracleDelta{
+		Txns: []*pb.TxnStatus{{StartTs: startTs}}}))
+	_, ok := schema.State().DictionaryID(attr, "red")
+	require.False(t, ok)
+
+	startTs = mutate(2, "blue")
+	require.NoError(t, n.commitOrAbort("", &pb.OracleDelta{
+		Txns: []*pb.TxnStatus{{StartTs: startTs, CommitTs: timestamp()}}}))
+	id, ok := schema.State().DictionaryID(attr, "blue")
+	require.True(t, ok)
+	su, _ := schema.State().Get(context.Background(), attr)
+	require.Equal(t, []string{"blue"}, su.Dictionary[id:])
+}
@@ -322,7 +322,7 @@ func writeBackup(ctx context.Context, req *pb.RestoreRequest) error {
 				return 0, errors.Wrapf(err, "couldn't create gzip reader")
 			}
 
-			maxUid, err := loadFromBackup(pstore, gzReader, req.RestoreTs, preds)
+			maxUid, err := loadFromBackup(pstore, gzReader, req.RestoreTs, preds, false)
 			if err != nil {
 				return 0, errors.Wrapf(err, "cannot write backup")
 			}
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"io"
 	"strconv"
+	"sync"
 
 	"github.com/golang/glog"
 	"github.com/pkg/errors"
@@ -41,6 +42,12 @@ var (
 	emptyPayload      = api.Payload{}
 )
 
+// sent is the progress of the predicate being sent by this Alpha to another group.
+var sent struct {
+	sync.Mutex
+	progress pb.MoveProgress
+}
+
 // size of kvs won't be too big, we would take care before proposing.
 func populateKeyValues(ctx context.Context, kvs []*bpb.KV) error {
 	glog.Infof("Writing %d keys\n", len(kvs))
@@ -54,11 +61,31 @@ func populateKeyValues(ctx context.Context, kvs []*bpb.KV) error {
 	if err := writer.Flush(); err != nil {
 		return err
 	}
-	pk, err := x.Parse(kvs[0].Key)
-	if err != nil {
-		return err
+	// The keys replicated from a primary cluster can span predicates and types, and overwrite
+	// the lists in the cache.
+	attrs := make(map[string]struct{})
+	var hasTypes bool
+	for _, kv := range kvs {
+		posting.RemoveCacheFor(kv.Key)
+		pk, err := x.Parse(kv.Key)
+		if err != nil {
+			return err
+		}
+		if pk.IsType() {
+			hasTypes = true
+			continue
+		}
+		attrs[pk.Attr] = struct{}{}
 	}
-	return schema.Load(pk.Attr)
+	for attr := range attrs {
+		if err := schema.Load(attr); err != nil {
+			return err
+		}
+	}
+	if hasTypes {
+		return schema.LoadTypesFromDb()
+	}
+	return nil
 }
 
 func batchAndProposeKeyValues(ctx context.Context, kvs chan *pb.KVS) error {
@@ -226,6 +253,18 @@ func (w *grpcWorker) MovePredicate(ctx context.Context,
 	return &emptyPayload, err
 }
 
+// MoveProgress returns the keys, and their bytes, sent so far by the move of the predicate.
+func (w *grpcWorker) MoveProgress(ctx context.Context,
+	in *pb.MovePredicatePayload) (*pb.MoveProgress, error) {
+	sent.Lock()
+	defer sent.Unlock()
+	if sent.progress.Predicate != in.Predicate {
+		return &pb.MoveProgress{Predicate: in.Predicate}, nil
+	}
+	progress := sent.progress
+	return &progress, nil
+}
+
 func movePredicateHelper(ctx context.Context, in *pb.MovePredicatePayload) error {
 	// Note: Manish thinks it *should* be OK for a predicate receiver to not have to stop other
 	// operations like snapshots and rollups. Note that this is the sender. This should stop other
@@ -301,8 +340,18 @@ func movePredicateHelper(ctx context.Context, in *pb.MovePredicatePayload) error
 		return &bpb.KVList{Kv: kvs}, err
 	}
 	stream.Send = func(list *bpb.KVList) error {
-		return s.Send(&pb.KVS{Kv: list.Kv})
+		if err := s.Send(&pb.KVS{Kv: list.Kv}); err != nil {
+			return err
+		}
+		sent.Lock()
+		sent.progress.Keys += uint64(len(list.Kv))
+		sent.progress.Bytes += uint64(list.Size())
+		sent.Unlock()
+		return nil
 	}
+	sent.Lock()
+	sent.progress = pb.MoveProgress{Predicate: in.Predicate}
+	sent.Unlock()
 	span.Annotatef(nil, "Starting stream list orchestrate")
 	if err := stream.Orchestrate(ctx); err != nil {
 		return err
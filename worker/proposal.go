@@ -169,13 +169,19 @@ func (n *node) proposeAndWait(ctx context.Context, proposal *pb.Proposal) (perr
 				// We don't allow mutations for reserved predicates if the schema for them doesn't
 				// already exist.
 				if x.IsReservedPredicate(edge.Attr) {
-					return errors.Errorf("Can't store predicate `%s` as it is prefixed with "+
-						"`dgraph.` which is reserved as the namespace for dgraph's internal "+
-						"types/predicates.",
-						edge.Attr)
+					return x.InvalidArgument(errors.Errorf("Can't store predicate `%s` as it is "+
+						"prefixed with `dgraph.` which is reserved as the namespace for dgraph's "+
+						"internal types/predicates.",
+						edge.Attr))
 				}
 				continue
 			} else if err := ValidateAndConvert(edge, &su); err != nil {
+				return x.InvalidArgument(err)
+			}
+		}
+
+		for _, chunk := range proposal.Mutations.BlobChunks {
+			if err := checkTablet(chunk.Attr); err != nil {
 				return err
 			}
 		}
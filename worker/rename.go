@@ -0,0 +1,255 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/badger/v2"
+	bpb "github.com/dgraph-io/badger/v2/pb"
+	"github.com/dgraph-io/dgraph/posting"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// RenamePredicateOverNetwork starts renaming the predicate from to to. The group serving from
+// starts serving to as well, with a copy of the schema of from. Its leader then copies the
+// data over in the background and drops from once it's done. Until then, to is read from the
+// keys of from and the mutations of both predicates are rejected.
+func RenamePredicateOverNetwork(ctx context.Context, from, to string, startTs uint64) error {
+	gid, err := groups().BelongsToReadOnly(from, 0)
+	switch {
+	case err != nil:
+		return err
+	case gid == 0:
+		return errNonExistentTablet
+	}
+
+	// The data is copied within the group, so the new name must be served by the same group.
+	tablet, err := groups().sendTablet(&pb.Tablet{GroupId: gid, Predicate: to})
+	if err != nil {
+		return err
+	}
+	if tablet.GetGroupId() != gid {
+		return errors.Errorf("Predicate %s is already served by group %d", to, tablet.GetGroupId())
+	}
+
+	m := &pb.Mutations{GroupId: gid, StartTs: startTs, RenameFrom: from, RenameTo: to}
+	resCh := make(chan res, 1)
+	proposeOrSend(ctx, gid, m, resCh)
+	return (<-resCh).err
+}
+
+// WaitForRename waits until the rename of a predicate to the given name is done or the context
+// errors out.
+func WaitForRename(ctx context.Context, to string) error {
+	req := &pb.SchemaRequest{Predicates: []string{to}, Fields: []string{"renamed_from"}}
+	for {
+		nodes, err := GetSchemaOverNetwork(ctx, req)
+		if err != nil {
+			return err
+		}
+		if len(nodes) == 0 || len(nodes[0].RenamedFrom) == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// startRename applies the proposal starting the rename of from to to. The schema of to is set
+// to the one of from, with a marker pointing at from that is cleared once the rename is done.
+func startRename(ctx context.Context, from, to string) error {
+	su, ok := schema.State().Get(ctx, from)
+	if !ok {
+		return errors.Errorf("Predicate %s doesn't exist", from)
+	}
+	if len(su.RenamedFrom) > 0 {
+		return errors.Errorf("Predicate %s is being renamed from %s", from, su.RenamedFrom)
+	}
+	if len(su.Tier) > 0 {
+		return errors.Errorf("Predicate %s is in the %s storage tier. Please retry once it's"+
+			" stored locally", from, su.Tier)
+	}
+	if cur, ok := schema.State().Get(ctx, to); ok {
+		if cur.RenamedFrom == from {
+			return errors.Errorf("Predicate %s is already being renamed to %s", from, to)
+		}
+		return errors.Errorf("Predicate %s already exists", to)
+	}
+	for _, pred := range schema.GetIndexingPredicates() {
+		if pred == from {
+			return errors.Errorf("Predicate %s is being indexed. Please retry later", from)
+		}
+	}
+	// The data of from is copied as of the time the rename starts, so pending transactions
+	// must be done before starting.
+	if err := detectPendingTxns(from); err != nil {
+		return err
+	}
+
+	su.Predicate = to
+	su.RenamedFrom = from
+	if su.TtlFrom == from {
+		su.TtlFrom = to
+	}
+	glog.Infof("Starting to rename predicate %s to %s", from, to)
+	return updateSchema(&su)
+}
+
+// checkRenames returns an error if any of the edges is for a predicate being renamed.
+func checkRenames(edges []*pb.DirectedEdge) error {
+	renames := schema.State().Renames()
+	if len(renames) == 0 {
+		return nil
+	}
+	for _, edge := range edges {
+		for to, from := range renames {
+			if edge.Attr == from || edge.Attr == to {
+				return errors.Errorf("Predicate %s is being renamed to %s. Please retry once the"+
+					" rename is done", from, to)
+			}
+		}
+	}
+	return nil
+}
+
+// renamedAttr returns the predicate whose keys hold the data of attr. That's the old name of
+// the predicate while a rename to attr is in progress, and attr itself otherwise.
+func renamedAttr(attr string) string {
+	if from := schema.State().RenamedFrom(attr); len(from) > 0 {
+		return from
+	}
+	return attr
+}
+
+// processRenames runs the renames of the predicates of this group when this node is the
+// leader. Renames are resumed from the start if the leader changes or restarts midway.
+func (n *node) processRenames() {
+	defer n.closer.Done() // CLOSER:1
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-n.closer.HasBeenClosed()
+		cancel()
+	}()
+
+	for {
+		select {
+		case <-n.closer.HasBeenClosed():
+			return
+		case <-tick.C:
+			if !n.AmLeader() {
+				continue
+			}
+			for to, from := range schema.State().Renames() {
+				if err := n.renamePredicate(ctx, from, to); err != nil {
+					glog.Errorf("Error while renaming predicate %s to %s: %v. Retrying...",
+						from, to, err)
+				}
+			}
+		}
+	}
+}
+
+// renamePredicate copies the keys of from over to to, and then finishes the rename. The
+// copied keys are proposed in batches, so that all the members of the group write them.
+func (n *node) renamePredicate(ctx context.Context, from, to string) error {
+	// The mutations of from are rejected while it's being renamed, so everything committed to
+	// it is visible at this timestamp.
+	readTs := posting.Oracle().MaxAssigned()
+	glog.Infof("Renaming predicate %s to %s at ts: %d", from, to, readTs)
+
+	var count int
+	stream := pstore.NewStreamAt(readTs)
+	stream.LogPrefix = fmt.Sprintf("Renaming predicate: [%s] to [%s]", from, to)
+	stream.Prefix = x.PredicatePrefix(from)
+	stream.ChooseKey = func(item *badger.Item) bool {
+		// The parts of a multi-part list are read from the main key.
+		pk, err := x.Parse(item.Key())
+		return err == nil && !pk.HasStartUid
+	}
+	stream.KeyToList = func(key []byte, itr *badger.Iterator) (*bpb.KVList, error) {
+		l, err := posting.ReadPostingList(key, itr)
+		if err != nil {
+			return nil, err
+		}
+		kvs, err := l.Rollup(stream.Allocator(itr.ThreadId))
+		if err != nil {
+			return nil, err
+		}
+		// The rolled up KVs live in the memory of the allocator, which must not point to memory
+		// managed by Go. So the renamed KVs are new ones, sharing only the values. These are
+		// valid until the list has been sent.
+		list := &bpb.KVList{}
+		for _, kv := range kvs {
+			pk, err := x.Parse(kv.Key)
+			if err != nil {
+				return nil, err
+			}
+			bk := pk.ToBackupKey()
+			bk.Attr = to
+			list.Kv = append(list.Kv, &bpb.KV{
+				Key:       x.FromBackupKey(bk),
+				Value:     kv.Value,
+				UserMeta:  kv.UserMeta,
+				Version:   readTs,
+				ExpiresAt: kv.ExpiresAt,
+			})
+		}
+		return list, nil
+	}
+	stream.Send = func(list *bpb.KVList) error {
+		if err := n.proposeAndWait(ctx, &pb.Proposal{Kv: list.Kv}); err != nil {
+			return err
+		}
+		count += len(list.Kv)
+		glog.Infof("Renaming predicate %s to %s: copied %d keys", from, to, count)
+		return nil
+	}
+	if err := stream.Orchestrate(ctx); err != nil {
+		return err
+	}
+
+	// Clearing the marker makes to read from its own keys and accept mutations again.
+	su, ok := schema.State().Get(ctx, to)
+	if !ok || su.RenamedFrom != from {
+		return errors.Errorf("Predicate %s is no longer being renamed to %s", from, to)
+	}
+	su.RenamedFrom = ""
+	m := &pb.Mutations{GroupId: n.gid, StartTs: readTs, Schema: []*pb.SchemaUpdate{&su}}
+	if err := n.proposeAndWait(ctx, &pb.Proposal{Mutations: m}); err != nil {
+		return err
+	}
+	if err := n.proposeAndWait(ctx, &pb.Proposal{CleanPredicate: from}); err != nil {
+		return err
+	}
+	glog.Infof("Done renaming predicate %s to %s. Copied %d keys", from, to, count)
+	return nil
+}
@@ -0,0 +1,612 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/dgraph-io/badger/v2"
+	bpb "github.com/dgraph-io/badger/v2/pb"
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	ostats "go.opencensus.io/stats"
+
+	"github.com/dgraph-io/dgraph/conn"
+	"github.com/dgraph-io/dgraph/posting"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// A replica cluster replicates the data of a primary cluster in another region through
+// incremental snapshots. Every x.WorkerConfig.ReplicationInterval, the leader of group one of the
+// replica gets a read timestamp from the primary, streams the keys of every group of the primary
+// changed since the previous snapshot, and writes them in its own groups at a timestamp of its
+// own. The queries on the replica read the data at the timestamp of the last complete snapshot,
+// so that they never see half of one.
+
+var errNotReplica = errors.Errorf("This cluster isn't a replica.")
+
+// replicationBatchSize is the size of the key-values written by a proposal of the replication.
+const replicationBatchSize = 32 << 20
+
+// IsReplica returns true if this cluster replicates the data of a primary cluster. The state of
+// the replication is kept in the membership state streamed by Zero.
+func IsReplica() bool {
+	g := groups()
+	g.RLock()
+	defer g.RUnlock()
+	return g.state.GetReplication().GetPrimary() != ""
+}
+
+// ReplicaReadTs returns the timestamp the queries on a replica read its data at, which is the one
+// the last snapshot of the primary was written at. It isn't found until the first snapshot is.
+func ReplicaReadTs() (uint64, bool) {
+	g := groups()
+	g.RLock()
+	defer g.RUnlock()
+	rs := g.state.GetReplication()
+	if rs.GetPrimary() == "" || rs.GetReadTs() == 0 {
+		return 0, false
+	}
+	return rs.ReadTs, true
+}
+
+// ReplicationStatus returns the state of the replication of this replica cluster, or nil if
+// it isn't a replica.
+func ReplicationStatus() *pb.ReplicationState {
+	g := groups()
+	g.RLock()
+	defer g.RUnlock()
+	rs := g.state.GetReplication()
+	if rs.GetPrimary() == "" {
+		return nil
+	}
+	return proto.Clone(rs).(*pb.ReplicationState)
+}
+
+// PromoteReplica asks Zero to promote this replica cluster, and waits for the leader of group one
+// to replicate the data committed in the primary one last time, if it can be reached, and to make
+// the cluster accept writes.
+func PromoteReplica(ctx context.Context) error {
+	if !IsReplica() {
+		return errNotReplica
+	}
+	pl := groups().Leader(0)
+	if pl == nil {
+		return conn.ErrNoConnection
+	}
+	zc := pb.NewZeroClient(pl.Get())
+	if _, err := zc.UpdateReplication(ctx, &pb.ReplicationState{Promote: true}); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for IsReplica() {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// ReplicateGroup streams the keys of the group changed since req.SinceTs, as of req.ReadTs, to
+// the replica cluster asking for them.
+func (w *grpcWorker) ReplicateGroup(req *pb.ReplicationRequest,
+	out pb.Worker_ReplicateGroupServer) error {
+	if gid := groups().groupId(); req.GroupId != gid {
+		return errors.Errorf("Group id doesn't match, received request for %d, my gid: %d",
+			req.GroupId, gid)
+	}
+	glog.Infof("Got ReplicateGroup request: %+v", req)
+	return streamReplication(out.Context(), req, out.Send)
+}
+
+func streamReplication(ctx context.Context, req *pb.ReplicationRequest,
+	send func(*pb.KVS) error) error {
+	// Any member of the group has the data of the snapshot once it's past the read timestamp.
+	if err := posting.Oracle().WaitForTs(ctx, req.ReadTs); err != nil {
+		return err
+	}
+
+	var num int
+	stream := pstore.NewStreamAt(req.ReadTs)
+	stream.LogPrefix = "Replicating group"
+	stream.ChooseKey = func(item *badger.Item) bool {
+		pk, err := x.Parse(item.Key())
+		if err != nil {
+			return false
+		}
+		switch {
+		case pk.HasStartUid:
+			// The parts of a list are sent along with its main key.
+			return false
+		case pk.IsSchema() || pk.IsType():
+			// Schema and type keys always have a timestamp of 1, they're sent every time.
+			return true
+		}
+		// The keys deleted since the last snapshot are sent as empty lists.
+		return item.Version() >= req.SinceTs
+	}
+	stream.KeyToList = func(key []byte, itr *badger.Iterator) (*bpb.KVList, error) {
+		pk, err := x.Parse(key)
+		if err != nil {
+			return nil, err
+		}
+		if pk.IsSchema() || pk.IsType() {
+			item := itr.Item()
+			val, err := item.ValueCopy(nil)
+			if err != nil {
+				return nil, err
+			}
+			kv := &bpb.KV{
+				Key:      item.KeyCopy(nil),
+				Value:    val,
+				UserMeta: []byte{item.UserMeta()},
+				Version:  1,
+			}
+			return &bpb.KVList{Kv: []*bpb.KV{kv}}, nil
+		}
+		// The complete lists are sent, so that they're written above the versions of the lists
+		// replicated before.
+		l, err := posting.ReadPostingList(key, itr)
+		if err != nil {
+			return nil, err
+		}
+		kvs, err := l.Rollup(stream.Allocator(itr.ThreadId))
+		return &bpb.KVList{Kv: kvs}, err
+	}
+	stream.Send = func(list *bpb.KVList) error {
+		num += len(list.Kv)
+		return send(&pb.KVS{Kv: list.Kv})
+	}
+
+	// The lists of the predicates and types let the replica delete the ones dropped since.
+	predicates := schema.State().Predicates()
+	types := schema.State().Types()
+	if err := stream.Orchestrate(ctx); err != nil {
+		return err
+	}
+	glog.Infof("Replication of group done. Sent %d entries.", num)
+	return send(&pb.KVS{Done: true, Predicates: predicates, Types: types})
+}
+
+// ReplicateKeys writes in this group the key-values replicated from the primary cluster.
+func (w *grpcWorker) ReplicateKeys(ctx context.Context, kvs *pb.KVS) (*api.Payload, error) {
+	if !IsReplica() {
+		return &emptyPayload, errNotReplica
+	}
+	if len(kvs.Kv) == 0 {
+		return &emptyPayload, nil
+	}
+	return &emptyPayload, groups().Node.proposeAndWait(ctx, &pb.Proposal{Kv: kvs.Kv})
+}
+
+// processReplication replicates the data of the primary cluster every
+// x.WorkerConfig.ReplicationInterval while this node is the leader of group one of a replica
+// cluster, and promotes the cluster once that's requested.
+func (n *node) processReplication() {
+	defer n.closer.Done() // CLOSER:1
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	var last time.Time
+	for {
+		select {
+		case <-n.closer.HasBeenClosed():
+			return
+		case <-ticker.C:
+			if n.gid != 1 || !n.AmLeader() {
+				continue
+			}
+			rs := ReplicationStatus()
+			if rs == nil {
+				continue
+			}
+			if rs.SnapshotAt > 0 {
+				lag := time.Since(time.Unix(rs.SnapshotAt, 0)).Seconds()
+				ostats.Record(n.closer.Ctx(), x.ReplicationLag.M(lag))
+			}
+			if !rs.Promote && time.Since(last) < x.WorkerConfig.ReplicationInterval {
+				continue
+			}
+			last = time.Now()
+
+			next, err := n.replicate(n.closer.Ctx(), rs)
+			if err != nil {
+				glog.Errorf("While replicating the data of %s: %v", rs.Primary, err)
+				next = rs
+			}
+			if !rs.Promote {
+				continue
+			}
+			// The data replicated so far is kept if the primary couldn't be reached.
+			if err := n.promote(n.closer.Ctx(), next); err != nil {
+				glog.Errorf("While promoting the replica of %s: %v", rs.Primary, err)
+			}
+		}
+	}
+}
+
+// replicate writes the changes of the primary cluster since the last snapshot in this cluster,
+// and records the new snapshot in Zero.
+func (n *node) replicate(ctx context.Context, rs *pb.ReplicationState) (
+	*pb.ReplicationState, error) {
+	start := time.Now()
+	primary, err := primaryState(ctx, rs.Primary)
+	if err != nil {
+		return nil, err
+	}
+	readTs, err := primaryReadTs(ctx, primary)
+	if err != nil {
+		return nil, err
+	}
+	next := &pb.ReplicationState{
+		Primary:    rs.Primary,
+		PrimaryTs:  rs.PrimaryTs,
+		ReadTs:     rs.ReadTs,
+		SnapshotAt: start.Unix(),
+	}
+
+	// A snapshot is only streamed if something was committed, or the schema was changed, since
+	// the last one.
+	if readTs > rs.PrimaryTs {
+		num, err := n.replicateSnapshot(ctx, primary, rs.PrimaryTs+1, readTs)
+		if err != nil {
+			return nil, err
+		}
+		// The timestamp is taken once all the keys are written, so that any member of the
+		// groups that's past it has written them.
+		ts, err := Timestamps(ctx, &pb.Num{Val: 1})
+		if err != nil {
+			return nil, err
+		}
+		next.PrimaryTs, next.ReadTs = readTs, ts.StartId
+		ostats.Record(ctx, x.ReplicatedKeys.M(int64(num)))
+		glog.Infof("Replicated %d keys of %s up to %d at %d", num, rs.Primary, readTs,
+			next.ReadTs)
+	}
+
+	if err := updateReplication(ctx, next); err != nil {
+		return nil, err
+	}
+	return next, nil
+}
+
+// promote stops the replication, which makes the cluster accept writes.
+func (n *node) promote(ctx context.Context, rs *pb.ReplicationState) error {
+	promoted := &pb.ReplicationState{
+		PrimaryTs:  rs.PrimaryTs,
+		ReadTs:     rs.ReadTs,
+		SnapshotAt: rs.SnapshotAt,
+	}
+	if err := updateReplication(ctx, promoted); err != nil {
+		return err
+	}
+	glog.Infof("Promoted the replica of %s, with the data committed up to %d", rs.Primary,
+		rs.PrimaryTs)
+	return nil
+}
+
+func updateReplication(ctx context.Context, rs *pb.ReplicationState) error {
+	pl := groups().Leader(0)
+	if pl == nil {
+		return conn.ErrNoConnection
+	}
+	_, err := pb.NewZeroClient(pl.Get()).UpdateReplication(ctx, rs)
+	return err
+}
+
+// primaryState returns the membership state of the primary cluster, from the Zero at addr.
+func primaryState(ctx context.Context, addr string) (*pb.MembershipState, error) {
+	pl := conn.GetPools().Connect(addr)
+	if pl == nil {
+		return nil, errors.Errorf("unable to connect to the primary at %s", addr)
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	cs, err := pb.NewZeroClient(pl.Get()).Connect(ctx, &pb.Member{ClusterInfoOnly: true})
+	if err != nil {
+		return nil, errors.Wrapf(err, "while getting the state of the primary")
+	}
+	return cs.GetState(), nil
+}
+
+// primaryReadTs returns a read timestamp of the primary cluster, from the leader of its Zeros.
+func primaryReadTs(ctx context.Context, state *pb.MembershipState) (uint64, error) {
+	for _, m := range state.GetZeros() {
+		if !m.Leader {
+			continue
+		}
+		pl := conn.GetPools().Connect(m.Addr)
+		if pl == nil {
+			break
+		}
+		ts, err := pb.NewZeroClient(pl.Get()).Timestamps(ctx, &pb.Num{ReadOnly: true})
+		if err != nil {
+			return 0, errors.Wrapf(err, "while getting a timestamp of the primary")
+		}
+		return ts.ReadOnly, nil
+	}
+	return 0, errors.Errorf("unable to reach the Zero leader of the primary")
+}
+
+// replicateSnapshot streams the keys of all the groups of the primary changed between sinceTs and
+// readTs, and writes them in this cluster. The keys are staged in a file until all the groups are
+// streamed, so that an error doesn't leave a part of the snapshot behind. It returns the number of
+// keys written.
+func (n *node) replicateSnapshot(ctx context.Context, primary *pb.MembershipState,
+	sinceTs, readTs uint64) (int, error) {
+	f, err := ioutil.TempFile("", "dgraph-replica-")
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		f.Close()
+		os.Remove(f.Name())
+	}()
+
+	w := bufio.NewWriter(f)
+	predicates := make(map[string]struct{})
+	types := make(map[string]struct{})
+	var gids []uint32
+	for gid := range primary.GetGroups() {
+		gids = append(gids, gid)
+	}
+	sort.Slice(gids, func(i, j int) bool { return gids[i] < gids[j] })
+	for _, gid := range gids {
+		done, err := streamPrimaryGroup(ctx, primary.Groups[gid], &pb.ReplicationRequest{
+			GroupId: gid,
+			SinceTs: sinceTs,
+			ReadTs:  readTs,
+		}, w)
+		if err != nil {
+			return 0, errors.Wrapf(err, "while replicating group %d", gid)
+		}
+		for _, pred := range done.Predicates {
+			predicates[pred] = struct{}{}
+		}
+		for _, typ := range done.Types {
+			types[typ] = struct{}{}
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return 0, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	// The keys replicated before were written below this timestamp.
+	ts, err := Timestamps(ctx, &pb.Num{Val: 1})
+	if err != nil {
+		return 0, err
+	}
+	num, err := n.writeSnapshot(ctx, bufio.NewReader(f), ts.StartId)
+	if err != nil {
+		return 0, err
+	}
+	if err := dropRemoved(ctx, predicates, types); err != nil {
+		return 0, err
+	}
+	// The UIDs leased by the primary mustn't be leased again once this cluster is promoted.
+	if lease := maxLeaseId(); primary.MaxLeaseId > lease {
+		if _, err := AssignUidsOverNetwork(ctx,
+			&pb.Num{Val: primary.MaxLeaseId - lease}); err != nil {
+			return 0, err
+		}
+	}
+	return num, nil
+}
+
+func maxLeaseId() uint64 {
+	g := groups()
+	g.RLock()
+	defer g.RUnlock()
+	return g.state.GetMaxLeaseId()
+}
+
+// streamPrimaryGroup writes the key-values streamed by a member of the group of the primary, and
+// returns the last message of the stream.
+func streamPrimaryGroup(ctx context.Context, group *pb.Group, req *pb.ReplicationRequest,
+	w io.Writer) (*pb.KVS, error) {
+	var addr string
+	for _, m := range group.GetMembers() {
+		if addr == "" || m.Leader {
+			addr = m.Addr
+		}
+	}
+	if addr == "" {
+		return nil, errors.Errorf("no member found")
+	}
+	pl := conn.GetPools().Connect(addr)
+	if pl == nil {
+		return nil, errors.Errorf("unable to connect to %s", addr)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := pb.NewWorkerClient(pl.Get()).ReplicateGroup(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var lenBuf [binary.MaxVarintLen64]byte
+	for {
+		kvs, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+		if kvs.Done {
+			return kvs, nil
+		}
+		data, err := kvs.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		n := binary.PutUvarint(lenBuf[:], uint64(len(data)))
+		if _, err := w.Write(lenBuf[:n]); err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// writeSnapshot writes the staged key-values in the groups serving them. The schema and type keys
+// are written at timestamp 1 like all of them, and the others at the given timestamp.
+func (n *node) writeSnapshot(ctx context.Context, r *bufio.Reader, ts uint64) (int, error) {
+	batches := make(map[uint32][]*bpb.KV)
+	sizes := make(map[uint32]int)
+	flush := func(gid uint32) error {
+		if len(batches[gid]) == 0 {
+			return nil
+		}
+		kvs := batches[gid]
+		batches[gid], sizes[gid] = nil, 0
+		if groups().ServesGroup(gid) {
+			return n.proposeAndWait(ctx, &pb.Proposal{Kv: kvs})
+		}
+		pl := groups().Leader(gid)
+		if pl == nil {
+			return conn.ErrNoConnection
+		}
+		_, err := pb.NewWorkerClient(pl.Get()).ReplicateKeys(ctx, &pb.KVS{Kv: kvs})
+		return err
+	}
+	add := func(gid uint32, kv *bpb.KV) error {
+		batches[gid] = append(batches[gid], kv)
+		sizes[gid] += len(kv.Key) + len(kv.Value)
+		if sizes[gid] >= replicationBatchSize {
+			return flush(gid)
+		}
+		return nil
+	}
+
+	var num int
+	for {
+		size, err := binary.ReadUvarint(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return num, err
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return num, err
+		}
+		var kvs pb.KVS
+		if err := kvs.Unmarshal(data); err != nil {
+			return num, err
+		}
+		for _, kv := range kvs.Kv {
+			pk, err := x.Parse(kv.Key)
+			if err != nil {
+				return num, err
+			}
+			num++
+			if pk.IsType() {
+				// The types are known by all the groups.
+				kv.Version = 1
+				for _, gid := range groups().KnownGroups() {
+					if err := add(gid, kv); err != nil {
+						return num, err
+					}
+				}
+				continue
+			}
+			kv.Version = ts
+			if pk.IsSchema() {
+				kv.Version = 1
+			}
+			gid, err := groups().BelongsTo(pk.Attr)
+			if err != nil {
+				return num, err
+			}
+			if err := add(gid, kv); err != nil {
+				return num, err
+			}
+		}
+	}
+	for gid := range batches {
+		if err := flush(gid); err != nil {
+			return num, err
+		}
+	}
+	return num, nil
+}
+
+// dropRemoved drops the predicates and types of this cluster that are no longer found in the
+// primary.
+func dropRemoved(ctx context.Context, predicates, types map[string]struct{}) error {
+	nodes, err := GetSchemaOverNetwork(ctx, &pb.SchemaRequest{})
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		pred := node.Predicate
+		if _, ok := predicates[pred]; ok || x.IsReservedPredicate(pred) {
+			continue
+		}
+		glog.Infof("Dropping predicate %s, which was dropped in the primary", pred)
+		m := &pb.Mutations{
+			StartTs: State.GetTimestamp(false),
+			Edges: []*pb.DirectedEdge{{
+				Attr:  pred,
+				Value: []byte(x.Star),
+				Op:    pb.DirectedEdge_DEL,
+			}},
+		}
+		if _, err := MutateOverNetwork(ctx, m); err != nil {
+			return errors.Wrapf(err, "while dropping predicate %s", pred)
+		}
+	}
+
+	typeUpdates, err := GetTypes(ctx, &pb.SchemaRequest{})
+	if err != nil {
+		return err
+	}
+	for _, tu := range typeUpdates {
+		typ := tu.TypeName
+		if _, ok := types[typ]; ok || x.IsPreDefinedType(typ) {
+			continue
+		}
+		glog.Infof("Dropping type %s, which was dropped in the primary", typ)
+		m := &pb.Mutations{
+			StartTs:   State.GetTimestamp(false),
+			DropOp:    pb.Mutations_TYPE,
+			DropValue: typ,
+		}
+		if _, err := MutateOverNetwork(ctx, m); err != nil {
+			return errors.Wrapf(err, "while dropping type %s", typ)
+		}
+	}
+	return nil
+}
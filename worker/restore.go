@@ -47,19 +47,10 @@ func RunRestore(pdir, location, backupId string, key x.SensitiveByteSlice) LoadR
 		func(r io.Reader, groupId uint32, preds predicateSet) (uint64, error) {
 
 			dir := filepath.Join(pdir, fmt.Sprintf("p%d", groupId))
-			r, err := enc.GetReader(key, r)
+			gzReader, err := newBackupReader(r, key)
 			if err != nil {
 				return 0, err
 			}
-
-			gzReader, err := gzip.NewReader(r)
-			if err != nil {
-				if len(key) != 0 {
-					err = errors.Wrap(err,
-						"Unable to read the backup. Ensure the encryption key is correct.")
-				}
-				return 0, err
-			}
 			// The badger DB should be opened only after creating the backup
 			// file reader and verifying the encryption in the backup file.
 			db, err := badger.OpenManaged(badger.DefaultOptions(dir).
@@ -76,7 +67,7 @@ func RunRestore(pdir, location, backupId string, key x.SensitiveByteSlice) LoadR
 			if !pathExist(dir) {
 				fmt.Println("Creating new db:", dir)
 			}
-			maxUid, err := loadFromBackup(db, gzReader, 0, preds)
+			maxUid, err := loadFromBackup(db, gzReader, 0, preds, false)
 			if err != nil {
 				return 0, err
 			}
@@ -84,19 +75,97 @@ func RunRestore(pdir, location, backupId string, key x.SensitiveByteSlice) LoadR
 		})
 }
 
+// AttachBackup loads the backup series at the given location into a DB meant to be used for
+// read-only inspection of the backed up data. All the groups in the backup are loaded into the
+// same DB, along with the schema of all of their predicates. If attrs is not empty, only those
+// predicates are loaded, and the backup files of the groups that serve none of them are skipped.
+// If dir is empty, the DB is kept in memory and nothing is written to disk. Otherwise, the DB is
+// stored in dir, which bounds the memory used to the caches of the DB.
+func AttachBackup(location, backupId, dir string, attrs []string, creds *Credentials,
+	key x.SensitiveByteSlice) (*badger.DB, LoadResult) {
+	opts := badger.DefaultOptions(dir).
+		WithSyncWrites(false).
+		WithNumVersionsToKeep(math.MaxInt32).
+		WithLogger(nil)
+	if dir == "" {
+		opts = opts.WithInMemory(true)
+	} else {
+		opts = opts.WithValueThreshold(1 << 10).
+			WithBlockCacheSize(100 * (1 << 20)).
+			WithIndexCacheSize(100 * (1 << 20))
+	}
+	db, err := badger.OpenManaged(opts)
+	if err != nil {
+		return nil, LoadResult{0, 0, err}
+	}
+
+	result := LoadBackup(location, backupId, 0, creds,
+		func(r io.Reader, groupId uint32, preds predicateSet) (uint64, error) {
+			if len(attrs) > 0 {
+				wanted := make(predicateSet)
+				for _, attr := range attrs {
+					if _, ok := preds[attr]; ok {
+						wanted[attr] = struct{}{}
+					}
+				}
+				if len(wanted) == 0 {
+					return 0, nil
+				}
+				preds = wanted
+			}
+			gzReader, err := newBackupReader(r, key)
+			if err != nil {
+				return 0, err
+			}
+			return loadFromBackup(db, gzReader, 0, preds, true)
+		})
+	if result.Err != nil {
+		db.Close()
+		return nil, result
+	}
+	return db, result
+}
+
+// newBackupReader returns a reader that decrypts and uncompresses the given backup file.
+func newBackupReader(r io.Reader, key x.SensitiveByteSlice) (*gzip.Reader, error) {
+	r, err := enc.GetReader(key, r)
+	if err != nil {
+		return nil, err
+	}
+
+	gzReader, err := gzip.NewReader(r)
+	if err != nil {
+		if len(key) != 0 {
+			err = errors.Wrap(err,
+				"Unable to read the backup. Ensure the encryption key is correct.")
+		}
+		return nil, err
+	}
+	return gzReader, nil
+}
+
 // loadFromBackup reads the backup, converts the keys and values to the required format,
 // and loads them to the given badger DB. The set of predicates is used to avoid restoring
 // values from predicates no longer assigned to this group.
 // If restoreTs is greater than zero, the key-value pairs will be written with that timestamp.
 // Otherwise, the original value is used.
+// If groupSchema is true, only the schema of the predicates in the set is replaced, so that the
+// backups of several groups can be loaded into the same DB.
 // TODO(DGRAPH-1234): Check whether restoreTs can be removed.
-func loadFromBackup(db *badger.DB, r io.Reader, restoreTs uint64, preds predicateSet) (
-	uint64, error) {
+func loadFromBackup(db *badger.DB, r io.Reader, restoreTs uint64, preds predicateSet,
+	groupSchema bool) (uint64, error) {
 	br := bufio.NewReaderSize(r, 16<<10)
 	unmarshalBuf := make([]byte, 1<<10)
 
-	// Delete schemas and types. Each backup file should have a complete copy of the schema.
-	if err := db.DropPrefix([]byte{x.ByteSchema}); err != nil {
+	// Delete schemas and types. Each backup file should have a complete copy of the types and
+	// of the schema of the predicates in its group.
+	if groupSchema {
+		for pred := range preds {
+			if err := db.DropPrefix(x.SchemaKey(pred)); err != nil {
+				return 0, err
+			}
+		}
+	} else if err := db.DropPrefix([]byte{x.ByteSchema}); err != nil {
 		return 0, err
 	}
 	if err := db.DropPrefix([]byte{x.ByteType}); err != nil {
@@ -0,0 +1,93 @@
+// +build !oss
+
+/*
+ * Copyright 2019 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Dgraph Community License (the "License"); you
+ * may not use this file except in compliance with the License. You
+ * may obtain a copy of the License at
+ *
+ *     https://github.com/dgraph-io/dgraph/blob/master/licenses/DCL.txt
+ */
+
+
+package worker
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/dgraph-io/badger/v2"
+	bpb "github.com/dgraph-io/badger/v2/pb"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dgraph-io/dgraph/posting"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// groupBackup returns the (uncompressed) backup of a group serving pred, holding its schema
+// and the type of the cluster.
+func groupBackup(t *testing.T, pred string) []byte {
+	key := func(typ pb.BackupKey_KeyType, attr string) []byte {
+		data, err := (&pb.BackupKey{Type: typ, Attr: attr}).Marshal()
+		require.NoError(t, err)
+		return data
+	}
+	su, err := (&pb.SchemaUpdate{Predicate: pred, ValueType: pb.Posting_STRING}).Marshal()
+	require.NoError(t, err)
+	tu, err := (&pb.TypeUpdate{TypeName: "Person",
+		Fields: []*pb.SchemaUpdate{{Predicate: "name"}, {Predicate: "age"}}}).Marshal()
+	require.NoError(t, err)
+
+	list := &bpb.KVList{Kv: []*bpb.KV{
+		{Key: key(pb.BackupKey_SCHEMA, pred), Value: su, Version: 1,
+			UserMeta: []byte{posting.BitSchemaPosting}},
+		{Key: key(pb.BackupKey_TYPE, "Person"), Value: tu, Version: 1,
+			UserMeta: []byte{posting.BitSchemaPosting}},
+	}}
+	data, err := list.Marshal()
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, binary.Write(&buf, binary.LittleEndian, uint64(len(data))))
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+func TestLoadGroupSchemas(t *testing.T) {
+	db, err := badger.OpenManaged(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	require.NoError(t, err)
+	defer db.Close()
+
+	load := func(pred string, groupSchema bool) {
+		_, err := loadFromBackup(db, bytes.NewReader(groupBackup(t, pred)), 0,
+			predicateSet{pred: struct{}{}}, groupSchema)
+		require.NoError(t, err)
+	}
+	hasKey := func(key []byte) bool {
+		txn := db.NewTransactionAt(math.MaxUint64, false)
+		defer txn.Discard()
+		_, err := txn.Get(key)
+		if err == badger.ErrKeyNotFound {
+			return false
+		}
+		require.NoError(t, err)
+		return true
+	}
+
+	// Each group only has the schema of its own predicates, so loading the groups into the
+	// same DB must keep the schema of all of them.
+	load("name", true)
+	load("age", true)
+	require.True(t, hasKey(x.SchemaKey("name")))
+	require.True(t, hasKey(x.SchemaKey("age")))
+	require.True(t, hasKey(x.TypeKey("Person")))
+
+	// A restore replaces the whole schema.
+	load("name", false)
+	require.True(t, hasKey(x.SchemaKey("name")))
+	require.False(t, hasKey(x.SchemaKey("age")))
+}
@@ -57,7 +57,9 @@ func getSchema(ctx context.Context, s *pb.SchemaRequest) (*pb.SchemaResult, erro
 		fields = s.Fields
 	} else {
 		fields = []string{"type", "index", "tokenizer", "reverse", "count", "list", "upsert",
-			"lang", "noconflict"}
+			"lang", "noconflict", "unique", "ttl", "renamed_from", "default",
+			"index_where", "encoding", "enum_values", "check", "references", "tier",
+			"compression"}
 	}
 
 	myGid := groups().groupId()
@@ -112,6 +114,43 @@ func populateSchema(attr string, fields []string) *pb.SchemaNode {
 			schemaNode.Lang = schema.State().HasLang(attr)
 		case "noconflict":
 			schemaNode.NoConflict = schema.State().HasNoConflict(attr)
+		case "unique":
+			schemaNode.Unique = schema.State().HasUnique(attr)
+		case "ttl":
+			schemaNode.Ttl, schemaNode.TtlFrom = schema.State().TTL(attr)
+		case "renamed_from":
+			schemaNode.RenamedFrom = schema.State().RenamedFrom(attr)
+		case "default":
+			if su, ok := schema.State().Get(ctx, attr); ok {
+				schemaNode.DefaultValue, schemaNode.DefaultVirtual = su.DefaultValue,
+					su.DefaultVirtual
+			}
+		case "index_where":
+			if su, ok := schema.State().Get(ctx, attr); ok {
+				schemaNode.IndexWhere = su.IndexWhere
+			}
+		case "encoding":
+			if su, ok := schema.State().Get(ctx, attr); ok {
+				schemaNode.Encoding = su.Encoding
+			}
+		case "enum_values":
+			if su, ok := schema.State().Get(ctx, attr); ok {
+				schemaNode.EnumValues = su.EnumValues
+			}
+		case "check":
+			if su, ok := schema.State().Get(ctx, attr); ok {
+				schemaNode.CheckMin, schemaNode.CheckMax = su.CheckMin, su.CheckMax
+				schemaNode.CheckMaxLength = su.CheckMaxLength
+				schemaNode.CheckPattern = su.CheckPattern
+			}
+		case "references":
+			if su, ok := schema.State().Get(ctx, attr); ok {
+				schemaNode.References = su.References
+			}
+		case "tier":
+			schemaNode.Tier = schema.State().Tier(attr)
+		case "compression":
+			schemaNode.Compression = schema.State().Compression(attr)
 		default:
 			//pass
 		}
@@ -0,0 +1,208 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"context"
+	"math"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/dgraph-io/dgraph/posting"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	ctask "github.com/dgraph-io/dgraph/task"
+	"github.com/dgraph-io/dgraph/tok"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/x"
+	otrace "go.opencensus.io/trace"
+)
+
+// Parameters of the BM25 ranking function. k1 controls how quickly the contribution of a term
+// saturates as its frequency grows and b controls how much the document length normalizes it.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// bm25Stats holds the collection statistics needed to score a document against a query.
+type bm25Stats struct {
+	// numDocs is the number of nodes that have a value for the predicate.
+	numDocs int
+	// docFreqs is the number of nodes whose value contains each query token.
+	docFreqs []int
+	// avgDocLen is the average number of tokens in a value.
+	avgDocLen float64
+}
+
+// bm25Score returns the BM25 score of a document with the given term counts and length for the
+// given query tokens.
+func bm25Score(tokens []string, termCounts map[string]int, docLen int, stats *bm25Stats) float64 {
+	var score float64
+	for i, token := range tokens {
+		tf := float64(termCounts[token])
+		if tf == 0 {
+			continue
+		}
+		df := float64(stats.docFreqs[i])
+		idf := math.Log(1 + (float64(stats.numDocs)-df+0.5)/(df+0.5))
+		norm := 1 - bm25B + bm25B*float64(docLen)/stats.avgDocLen
+		score += idf * tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+	}
+	return score
+}
+
+// handleScoreFunction computes the BM25 relevance score of the value of q.Attr of every uid in
+// q.UidList against the search text. Document frequencies and the corpus statistics are read
+// from the fulltext index.
+func (qs *queryState) handleScoreFunction(ctx context.Context, args funcArgs) error {
+	q := args.q
+	srcFn := args.srcFn
+
+	span := otrace.FromContext(ctx)
+	stop := x.SpanTimer(span, "handleScoreFunction")
+	defer stop()
+
+	stats := &bm25Stats{docFreqs: make([]int, len(srcFn.tokens))}
+	for i, token := range srcFn.tokens {
+		pl, err := qs.cache.Get(x.IndexKey(q.Attr, token))
+		if err != nil {
+			return err
+		}
+		stats.docFreqs[i] = pl.Length(q.ReadTs, 0)
+	}
+	if err := qs.readCorpusStats(ctx, q.Attr, q.ReadTs, stats); err != nil {
+		return err
+	}
+	// An index built before the lengths were indexed has no corpus statistics until it's
+	// rebuilt. The number of documents is then at least the document frequency of every token,
+	// and the average length is taken over the values being scored.
+	fromIndex := stats.numDocs > 0
+	for _, df := range stats.docFreqs {
+		if df > stats.numDocs {
+			stats.numDocs = df
+		}
+	}
+
+	lang := langForFunc(q.Langs)
+	if lang == "." {
+		lang = "en"
+	}
+	tokenizer, ok := tok.GetTokenizerForLang(stringIndexTokenizer(ctx, q.Attr, fullTextSearchFn),
+		lang).(tok.FullTextTokenizer)
+	x.AssertTrue(ok)
+	listType := schema.State().IsList(q.Attr)
+	termCounts := make([]map[string]int, len(q.UidList.Uids))
+	docLens := make([]int, len(q.UidList.Uids))
+	var totalLen, numScored int
+	for i, uid := range q.UidList.Uids {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		pl, err := qs.cache.Get(x.DataKey(q.Attr, uid))
+		if err != nil {
+			return err
+		}
+		vals, _, err := retrieveValuesAndFacets(args, pl, nil, listType)
+		switch {
+		case err == posting.ErrNoValue || (err == nil && len(vals) == 0):
+			continue
+		case err != nil:
+			return err
+		}
+
+		termCounts[i] = make(map[string]int)
+		for _, val := range vals {
+			sv, err := types.Convert(val, types.StringID)
+			if err != nil {
+				return err
+			}
+			counts, n := tokenizer.TermCounts(sv.Value.(string))
+			for token, count := range counts {
+				termCounts[i][token] += count
+			}
+			docLens[i] += n
+		}
+		totalLen += docLens[i]
+		numScored++
+	}
+	if !fromIndex && numScored > 0 {
+		stats.avgDocLen = float64(totalLen) / float64(numScored)
+	}
+
+	out := args.out
+	for i := range q.UidList.Uids {
+		var vl pb.ValueList
+		if termCounts[i] != nil {
+			score := bm25Score(srcFn.tokens, termCounts[i], docLens[i], stats)
+			vl.Values = append(vl.Values, ctask.FromFloat(score))
+		}
+		out.ValueMatrix = append(out.ValueMatrix, &vl)
+		out.FacetMatrix = append(out.FacetMatrix, &pb.FacetsList{})
+		// Add an empty UID list to make later processing consistent.
+		out.UidMatrix = append(out.UidMatrix, &pb.List{})
+	}
+	return nil
+}
+
+// readCorpusStats fills the number of values of the predicate and their average length into
+// stats. The fulltext index keeps the nodes under a token for the length of their value (see
+// tok.FullTextLengthToken), so the statistics are kept up to date by every mutation and can be
+// read without going through the values.
+func (qs *queryState) readCorpusStats(ctx context.Context, attr string, readTs uint64,
+	stats *bm25Stats) error {
+	txn := pstore.NewTransactionAt(readTs, false)
+	defer txn.Discard()
+
+	itOpt := badger.DefaultIteratorOptions
+	itOpt.PrefetchValues = false
+	itOpt.Prefix = x.IndexKey(attr, tok.FullTextLengthPrefix())
+	it := txn.NewIterator(itOpt)
+	defer it.Close()
+
+	var totalLen int
+	for it.Rewind(); it.Valid(); it.Next() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		key := it.Item().KeyCopy(nil)
+		pk, err := x.Parse(key)
+		if err != nil {
+			return err
+		}
+		n, err := tok.ParseFullTextLengthToken(pk.Term)
+		if err != nil {
+			return err
+		}
+		pl, err := qs.cache.Get(key)
+		if err != nil {
+			return err
+		}
+		numDocs := pl.Length(readTs, 0)
+		stats.numDocs += numDocs
+		totalLen += n * numDocs
+	}
+	if stats.numDocs > 0 {
+		stats.avgDocLen = float64(totalLen) / float64(stats.numDocs)
+	}
+	return nil
+}
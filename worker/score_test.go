@@ -0,0 +1,90 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/dgraph-io/dgraph/posting"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBm25Score(t *testing.T) {
+	tokens := []string{"fox", "quick"}
+	stats := &bm25Stats{numDocs: 10, docFreqs: []int{1, 5}, avgDocLen: 4}
+
+	idfFox := math.Log(1 + (10-1+0.5)/(1+0.5))
+	idfQuick := math.Log(1 + (10-5+0.5)/(5+0.5))
+
+	// A document of average length with one occurrence of each token scores the sum of the
+	// inverse document frequencies.
+	score := bm25Score(tokens, map[string]int{"fox": 1, "quick": 1}, 4, stats)
+	require.InDelta(t, idfFox+idfQuick, score, 1e-9)
+
+	// Rarer tokens contribute more to the score.
+	fox := bm25Score(tokens, map[string]int{"fox": 1}, 4, stats)
+	quick := bm25Score(tokens, map[string]int{"quick": 1}, 4, stats)
+	require.Greater(t, fox, quick)
+
+	// Repeating a token increases the score, but less than linearly.
+	twice := bm25Score(tokens, map[string]int{"fox": 2}, 4, stats)
+	require.Greater(t, twice, fox)
+	require.Less(t, twice, 2*fox)
+
+	// Longer documents score lower for the same term frequency.
+	long := bm25Score(tokens, map[string]int{"fox": 1}, 8, stats)
+	require.Less(t, long, fox)
+
+	// Documents that don't contain any of the tokens score zero.
+	require.Zero(t, bm25Score(tokens, map[string]int{"dog": 3}, 4, stats))
+}
+
+func TestReadCorpusStats(t *testing.T) {
+	attr := "score_text"
+	require.NoError(t, schema.ParseBytes([]byte(attr+": string @index(fulltext) ."), 1))
+	for uid, val := range map[uint64]string{
+		1: "quick brown fox", 2: "lazy dog", 3: "dog", 4: "brown fox jumps high",
+	} {
+		edge := &pb.DirectedEdge{Value: []byte(val), Attr: attr, Entity: uid}
+		addEdge(t, edge, getOrCreate(x.DataKey(attr, uid)))
+	}
+
+	stats := func() *bm25Stats {
+		readTs := timestamp()
+		qs := queryState{cache: posting.NoCache(readTs)}
+		stats := &bm25Stats{}
+		require.NoError(t, qs.readCorpusStats(context.Background(), attr, readTs, stats))
+		return stats
+	}
+	s := stats()
+	require.Equal(t, 4, s.numDocs)
+	require.InDelta(t, float64(3+2+1+4)/4, s.avgDocLen, 1e-9)
+
+	// Overwriting and deleting values keeps the statistics up to date.
+	edge := &pb.DirectedEdge{Value: []byte("fox"), Attr: attr, Entity: 4}
+	addEdge(t, edge, getOrCreate(x.DataKey(attr, 4)))
+	edge = &pb.DirectedEdge{Value: []byte("lazy dog"), Attr: attr, Entity: 2}
+	delEdge(t, edge, getOrCreate(x.DataKey(attr, 2)))
+	s = stats()
+	require.Equal(t, 3, s.numDocs)
+	require.InDelta(t, float64(3+1+1)/3, s.avgDocLen, 1e-9)
+}
@@ -18,11 +18,13 @@ package worker
 
 import (
 	"context"
+	"io/ioutil"
 	"math"
 	"os"
 	"time"
 
 	"github.com/dgraph-io/badger/v2"
+	"github.com/dgraph-io/dgraph/posting"
 	"github.com/dgraph-io/dgraph/protos/pb"
 	"github.com/dgraph-io/dgraph/raftwal"
 	"github.com/dgraph-io/dgraph/x"
@@ -36,7 +38,10 @@ type ServerState struct {
 
 	Pstore   *badger.DB
 	WALstore *raftwal.DiskStorage
-	gcCloser *z.Closer // closer for valueLogGC
+	gcCloser *z.Closer // closer for the maintenance of the postings
+
+	// tmpWALDir is the temporary directory storing the write-ahead log when running in memory.
+	tmpWALDir string
 
 	needTs chan tsReq
 }
@@ -54,6 +59,9 @@ func InitServerState() {
 	State.initStorage()
 	go State.fillTimestampRequests()
 
+	if Config.InMemory {
+		return
+	}
 	groupId, err := x.ReadGroupIdFile(Config.PostingDir)
 	if err != nil {
 		glog.Warningf("Could not read %s file inside posting directory %s.", x.GroupIdFileName,
@@ -95,18 +103,42 @@ func (s *ServerState) initStorage() {
 		}
 	}
 
-	{
+	if Config.InMemory {
+		glog.Warningf("Running in memory. All the data is lost once the server stops.")
+	}
+	if Config.ReadOnly {
+		glog.Infof("Opening %s read-only. Mutations and schema changes are rejected.",
+			Config.PostingDir)
+	}
+
+	if !Config.ReadOnly {
 		// Write Ahead Log directory
-		x.Checkf(os.MkdirAll(Config.WALDir, 0700), "Error while creating WAL dir.")
-		s.WALstore = raftwal.Init(Config.WALDir)
+		walDir := Config.WALDir
+		if Config.InMemory {
+			// The log is never synced, and it's removed along with the directory on shutdown.
+			walDir, err = ioutil.TempDir("", "dgraph_wal")
+			x.Checkf(err, "Error while creating temporary WAL dir.")
+			s.tmpWALDir = walDir
+		}
+		x.Checkf(os.MkdirAll(walDir, 0700), "Error while creating WAL dir.")
+		s.WALstore = raftwal.Init(walDir)
 		// TODO: Add encryption back to WALStore.
 	}
 	{
 		// Postings directory
 		// All the writes to posting store should be synchronous. We use batched writers
 		// for posting lists, so the cost of sync writes is amortized.
-		x.Check(os.MkdirAll(Config.PostingDir, 0700))
-		opt := badger.DefaultOptions(Config.PostingDir).
+		dir := Config.PostingDir
+		if Config.InMemory {
+			// Badger doesn't take a directory in memory.
+			dir = ""
+		} else if !Config.ReadOnly {
+			x.Check(os.MkdirAll(dir, 0700))
+		}
+		// The store isn't opened read-only in Badger, as it leaves a memtable behind that stops
+		// the directory from being opened read-only again. Dgraph doesn't write to it either way.
+		opt := badger.DefaultOptions(dir).
+			WithInMemory(Config.InMemory).
 			WithValueThreshold(1 << 10 /* 1KB */).
 			WithNumVersionsToKeep(math.MaxInt32).
 			WithBlockCacheSize(Config.PBlockCacheSize).
@@ -127,8 +159,18 @@ func (s *ServerState) initStorage() {
 		opt.EncryptionKey = nil
 	}
 
-	s.gcCloser = z.NewCloser(2)
-	go x.RunVlogGC(s.Pstore, s.gcCloser)
+	if Config.ReadOnly {
+		// The value log can't be garbage collected without writing to the store.
+		s.gcCloser = z.NewCloser(2)
+	} else {
+		s.gcCloser = z.NewCloser(3)
+		go s.runMaintenance(s.gcCloser)
+	}
+	go s.runIntegrityChecks(s.gcCloser)
+	if !Config.InMemory {
+		s.gcCloser.AddRunning(1)
+		go s.monitorPageCache(s.gcCloser)
+	}
 	// Commenting this out because Badger is doing its own cache checks.
 	go x.MonitorCacheHealth(s.Pstore, s.gcCloser)
 }
@@ -139,12 +181,23 @@ func (s *ServerState) Dispose() {
 	if err := s.Pstore.Close(); err != nil {
 		glog.Errorf("Error while closing postings store: %v", err)
 	}
-	if err := s.WALstore.Close(); err != nil {
-		glog.Errorf("Error while closing WAL store: %v", err)
+	if s.WALstore != nil {
+		if err := s.WALstore.Close(); err != nil {
+			glog.Errorf("Error while closing WAL store: %v", err)
+		}
+	}
+	if s.tmpWALDir != "" {
+		if err := os.RemoveAll(s.tmpWALDir); err != nil {
+			glog.Errorf("Error while removing WAL dir: %v", err)
+		}
 	}
 }
 
 func (s *ServerState) GetTimestamp(readOnly bool) uint64 {
+	if Config.ReadOnly {
+		// Nothing is ever committed, all the data is visible as of the latest version.
+		return posting.Oracle().MaxAssigned()
+	}
 	tr := tsReq{readOnly: readOnly, ch: make(chan uint64)}
 	s.needTs <- tr
 	return <-tr.ch
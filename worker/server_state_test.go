@@ -0,0 +1,93 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryStorage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "storage")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	old := Config
+	defer func() { Config = old }()
+	Config.PostingDir = filepath.Join(dir, "p")
+	Config.WALDir = filepath.Join(dir, "w")
+	Config.InMemory = true
+
+	var s ServerState
+	s.initStorage()
+	txn := s.Pstore.NewTransactionAt(1, true)
+	require.NoError(t, txn.Set([]byte("key"), []byte("value")))
+	require.NoError(t, txn.CommitAt(1, nil))
+	txn = s.Pstore.NewTransactionAt(2, false)
+	_, err = txn.Get([]byte("key"))
+	require.NoError(t, err)
+	txn.Discard()
+
+	// Neither of the directories is created, and the temporary WAL is gone once disposed.
+	_, err = os.Stat(Config.PostingDir)
+	require.True(t, os.IsNotExist(err))
+	_, err = os.Stat(Config.WALDir)
+	require.True(t, os.IsNotExist(err))
+	walDir := s.tmpWALDir
+	require.DirExists(t, walDir)
+
+	s.Dispose()
+	_, err = os.Stat(walDir)
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestReadOnlyStorage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "storage")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	old := Config
+	defer func() { Config = old }()
+	Config.PostingDir = filepath.Join(dir, "p")
+	Config.WALDir = filepath.Join(dir, "w")
+
+	var s ServerState
+	s.initStorage()
+	txn := s.Pstore.NewTransactionAt(1, true)
+	require.NoError(t, txn.Set([]byte("key"), []byte("value")))
+	require.NoError(t, txn.CommitAt(5, nil))
+	s.Dispose()
+	require.NoError(t, os.RemoveAll(Config.WALDir))
+
+	// The directory is served without the write-ahead log, as of its latest version.
+	Config.ReadOnly = true
+	s = ServerState{}
+	s.initStorage()
+	defer s.Dispose()
+	require.Nil(t, s.WALstore)
+	_, err = os.Stat(Config.WALDir)
+	require.True(t, os.IsNotExist(err))
+	require.Equal(t, uint64(5), s.Pstore.MaxVersion())
+	txn = s.Pstore.NewTransactionAt(5, false)
+	_, err = txn.Get([]byte("key"))
+	require.NoError(t, err)
+	txn.Discard()
+}
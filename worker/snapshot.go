@@ -17,7 +17,10 @@
 package worker
 
 import (
+	"bytes"
 	"context"
+	"sort"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -39,11 +42,26 @@ const (
 	MB = 1 << 20
 )
 
+var (
+	// The snapshots sent to, and received from, the other members of the group share these.
+	snapshotSendThrottle    byteThrottle
+	snapshotReceiveThrottle byteThrottle
+)
+
 type badgerWriter interface {
 	Write(kvs *bpb.KVList) error
 	Flush() error
 }
 
+// snapshotResume holds the key ranges of a snapshot written before its stream broke, so that the
+// retries of the snapshot only ask for the rest of it.
+type snapshotResume struct {
+	index    uint64
+	readTs   uint64
+	sinceTs  uint64
+	received []*pb.KeyRange
+}
+
 // populateSnapshot gets data for a shard from the leader and writes it to BadgerDB on the follower.
 func (n *node) populateSnapshot(snap pb.Snapshot, pl *conn.Pool) (int, error) {
 	con := pl.Get()
@@ -54,6 +72,15 @@ func (n *node) populateSnapshot(snap pb.Snapshot, pl *conn.Pool) (int, error) {
 	ctx, cancel := context.WithCancel(n.ctx)
 	defer cancel()
 
+	// Resume the stream of the same snapshot, if it broke the last time.
+	if r := n.snapResume; r != nil && r.index == snap.Index && r.readTs == snap.ReadTs &&
+		r.sinceTs == snap.SinceTs {
+		snap.Received = r.received
+		glog.Infof("Resuming the stream of the snapshot after %d key ranges", len(r.received))
+	} else {
+		n.snapResume = nil
+	}
+
 	// Set my RaftContext on the snapshot, so it's easier to locate me.
 	snap.Context = n.RaftContext
 	stream, err := c.StreamSnapshot(ctx)
@@ -66,7 +93,7 @@ func (n *node) populateSnapshot(snap pb.Snapshot, pl *conn.Pool) (int, error) {
 	}
 
 	var writer badgerWriter
-	if snap.SinceTs == 0 {
+	if snap.SinceTs == 0 && len(snap.Received) == 0 {
 		sw := pstore.NewStreamWriter()
 		if err := sw.Prepare(); err != nil {
 			return 0, err
@@ -74,16 +101,32 @@ func (n *node) populateSnapshot(snap pb.Snapshot, pl *conn.Pool) (int, error) {
 
 		writer = sw
 	} else {
+		// The keys written by the previous streams of the snapshot are kept.
 		writer = pstore.NewManagedWriteBatch()
 	}
 
+	// keepProgress flushes the keys received so far when the stream breaks, and keeps the
+	// ranges they cover for the next try.
+	tracker := newKeyRangeTracker(snap.Received)
+	keepProgress := func(err error) error {
+		if ferr := writer.Flush(); ferr != nil {
+			glog.Errorf("While flushing the keys of a broken snapshot stream: %v", ferr)
+			return err
+		}
+		if received := tracker.ranges(); len(received) > 0 {
+			n.snapResume = &snapshotResume{index: snap.Index, readTs: snap.ReadTs,
+				sinceTs: snap.SinceTs, received: received}
+		}
+		return err
+	}
+
 	// We can use count to check the number of posting lists returned in tests.
 	count := 0
 	var done *pb.KVS
 	for {
 		kvs, err := stream.Recv()
 		if err != nil {
-			return count, err
+			return count, keepProgress(err)
 		}
 		if kvs.Done {
 			done = kvs
@@ -92,14 +135,19 @@ func (n *node) populateSnapshot(snap pb.Snapshot, pl *conn.Pool) (int, error) {
 		}
 		select {
 		case <-ctx.Done():
-			return 0, ctx.Err()
+			return 0, keepProgress(ctx.Err())
 		default:
 		}
+		if err := snapshotReceiveThrottle.wait(ctx, kvs.Size(),
+			Config.SnapshotReceiveRate); err != nil {
+			return 0, keepProgress(err)
+		}
 
 		glog.V(1).Infof("Received a batch of %d keys. Total so far: %d\n", len(kvs.Kv), count)
 		if err := writer.Write(&bpb.KVList{Kv: kvs.Kv}); err != nil {
 			return 0, err
 		}
+		tracker.add(kvs.Kv)
 		count += len(kvs.Kv)
 	}
 	if err := writer.Flush(); err != nil {
@@ -116,6 +164,7 @@ func (n *node) populateSnapshot(snap pb.Snapshot, pl *conn.Pool) (int, error) {
 		return 0, err
 	}
 
+	n.snapResume = nil
 	x.VerifySnapshot(pstore, snap.ReadTs)
 	glog.Infof("Populated snapshot with %d keys.\n", count)
 	return count, nil
@@ -200,15 +249,31 @@ func doStreamSnapshot(snap *pb.Snapshot, out pb.Worker_StreamSnapshotServer) err
 	var num int
 	stream := pstore.NewStreamAt(snap.ReadTs)
 	stream.LogPrefix = "Sending Snapshot"
+	if Config.SnapshotGoroutines > 0 {
+		stream.NumGo = Config.SnapshotGoroutines
+	}
 	// Use the default implementation. We no longer try to generate a rolled up posting list here.
 	// Instead, we just stream out all the versions as they are.
 	stream.KeyToList = nil
 	stream.Send = func(list *bpb.KVList) error {
 		kvs := &pb.KVS{Kv: list.Kv}
+		if err := snapshotSendThrottle.wait(out.Context(), kvs.Size(),
+			Config.SnapshotSendRate); err != nil {
+			return err
+		}
 		num += len(kvs.Kv)
 		return out.Send(kvs)
 	}
+	// The keys already received by the follower aren't sent again.
+	received := mergeKeyRanges(snap.Received)
+	if len(received) > 0 {
+		glog.Infof("Resuming the snapshot after %d key ranges received by the follower",
+			len(received))
+	}
 	stream.ChooseKey = func(item *badger.Item) bool {
+		if inKeyRanges(received, item.Key()) {
+			return false
+		}
 		if item.Version() >= snap.SinceTs {
 			return true
 		}
@@ -294,3 +359,105 @@ func (w *grpcWorker) StreamSnapshot(stream pb.Worker_StreamSnapshotServer) error
 	glog.Infof("Stream snapshot: OK")
 	return nil
 }
+
+// keyRangeTracker tracks the ranges of keys fully received by the stream of a snapshot. The keys
+// of each stream of the sender come in order, with all the versions of a key one after the other,
+// so all the keys of a stream up to the one before the last key received are complete.
+type keyRangeTracker struct {
+	received []*pb.KeyRange
+	streams  map[uint32]*streamKeys
+}
+
+type streamKeys struct {
+	first []byte
+	// prev is the key received before last, or nil if only one key was received.
+	prev []byte
+	last []byte
+}
+
+func newKeyRangeTracker(received []*pb.KeyRange) *keyRangeTracker {
+	return &keyRangeTracker{received: received, streams: make(map[uint32]*streamKeys)}
+}
+
+func (t *keyRangeTracker) add(kvs []*bpb.KV) {
+	for _, kv := range kvs {
+		s, ok := t.streams[kv.StreamId]
+		if !ok {
+			s = &streamKeys{first: kv.Key}
+			t.streams[kv.StreamId] = s
+		}
+		if !bytes.Equal(kv.Key, s.last) {
+			s.prev, s.last = s.last, kv.Key
+		}
+	}
+}
+
+// ranges returns the ranges of keys fully received, including the ones received before.
+func (t *keyRangeTracker) ranges() []*pb.KeyRange {
+	ranges := append([]*pb.KeyRange{}, t.received...)
+	for _, s := range t.streams {
+		if s.prev != nil {
+			ranges = append(ranges, &pb.KeyRange{Start: s.first, End: s.prev})
+		}
+	}
+	return mergeKeyRanges(ranges)
+}
+
+// mergeKeyRanges returns the ranges sorted, with the overlapping ones merged.
+func mergeKeyRanges(ranges []*pb.KeyRange) []*pb.KeyRange {
+	ranges = append([]*pb.KeyRange{}, ranges...)
+	sort.Slice(ranges, func(i, j int) bool {
+		return bytes.Compare(ranges[i].Start, ranges[j].Start) < 0
+	})
+	var merged []*pb.KeyRange
+	for _, r := range ranges {
+		if last := len(merged) - 1; last >= 0 && bytes.Compare(r.Start, merged[last].End) <= 0 {
+			if bytes.Compare(r.End, merged[last].End) > 0 {
+				merged[last] = &pb.KeyRange{Start: merged[last].Start, End: r.End}
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// inKeyRanges returns true if the key is in one of the ranges, sorted and merged.
+func inKeyRanges(ranges []*pb.KeyRange, key []byte) bool {
+	i := sort.Search(len(ranges), func(i int) bool {
+		return bytes.Compare(ranges[i].Start, key) > 0
+	})
+	return i > 0 && bytes.Compare(key, ranges[i-1].End) <= 0
+}
+
+// byteThrottle keeps the bytes going through it, over all its users, under a rate per second.
+// A rate of zero means no limit.
+type byteThrottle struct {
+	sync.Mutex
+	// next is the time at which the bytes let through so far are paid for.
+	next time.Time
+}
+
+func (th *byteThrottle) wait(ctx context.Context, n int, rate int64) error {
+	if rate <= 0 {
+		return ctx.Err()
+	}
+	th.Lock()
+	now := time.Now()
+	// The bandwidth left unused while idle isn't saved up for later.
+	if th.next.Before(now) {
+		th.next = now
+	}
+	th.next = th.next.Add(time.Duration(int64(n) * int64(time.Second) / rate))
+	ahead := th.next.Sub(now)
+	th.Unlock()
+
+	// Sleeping for less than a few milliseconds isn't worth it, the lag is made up later.
+	if ahead > 10*time.Millisecond {
+		select {
+		case <-time.After(ahead):
+		case <-ctx.Done():
+		}
+	}
+	return ctx.Err()
+}
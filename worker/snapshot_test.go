@@ -27,8 +27,10 @@ import (
 	"testing"
 	"time"
 
+	bpb "github.com/dgraph-io/badger/v2/pb"
 	"github.com/dgraph-io/dgo/v200"
 	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/protos/pb"
 	"github.com/dgraph-io/dgraph/testutil"
 	"github.com/stretchr/testify/require"
 )
@@ -181,3 +183,57 @@ func waitForSnapshot(t *testing.T, prevSnapTs uint64) uint64 {
 		time.Sleep(time.Second)
 	}
 }
+
+func TestByteThrottle(t *testing.T) {
+	ctx := context.Background()
+	var th byteThrottle
+	start := time.Now()
+	for i := 0; i < 20; i++ {
+		require.NoError(t, th.wait(ctx, 10, 1000))
+	}
+	// The last 10 bytes can't go through before 200ms, give or take the lag made up later.
+	require.True(t, time.Since(start) >= 190*time.Millisecond)
+
+	var unlimited byteThrottle
+	start = time.Now()
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, unlimited.wait(ctx, 1<<20, 0))
+	}
+	require.True(t, time.Since(start) < 100*time.Millisecond)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	require.Equal(t, context.Canceled, th.wait(cancelled, 1<<20, 1000))
+}
+
+func TestKeyRanges(t *testing.T) {
+	kr := func(start, end string) *pb.KeyRange {
+		return &pb.KeyRange{Start: []byte(start), End: []byte(end)}
+	}
+	ranges := mergeKeyRanges([]*pb.KeyRange{kr("m", "p"), kr("a", "c"), kr("b", "d"),
+		kr("o", "o"), kr("f", "f")})
+	require.Equal(t, []*pb.KeyRange{kr("a", "d"), kr("f", "f"), kr("m", "p")}, ranges)
+
+	for key, in := range map[string]bool{"": false, "a": true, "bz": true, "d": true, "da": false,
+		"f": true, "g": false, "p": true, "q": false} {
+		require.Equal(t, in, inKeyRanges(ranges, []byte(key)), "key %q", key)
+	}
+	require.False(t, inKeyRanges(nil, []byte("a")))
+}
+
+func TestKeyRangeTracker(t *testing.T) {
+	kv := func(stream uint32, key string) *bpb.KV {
+		return &bpb.KV{StreamId: stream, Key: []byte(key)}
+	}
+	kr := func(start, end string) *pb.KeyRange {
+		return &pb.KeyRange{Start: []byte(start), End: []byte(end)}
+	}
+
+	tr := newKeyRangeTracker([]*pb.KeyRange{kr("x", "z")})
+	tr.add([]*bpb.KV{kv(1, "a"), kv(1, "a"), kv(2, "m")})
+	// The last key of a stream may have more versions to come.
+	require.Equal(t, []*pb.KeyRange{kr("x", "z")}, tr.ranges())
+
+	tr.add([]*bpb.KV{kv(1, "b"), kv(2, "n"), kv(1, "c"), kv(1, "c")})
+	require.Equal(t, []*pb.KeyRange{kr("a", "b"), kr("m", "m"), kr("x", "z")}, tr.ranges())
+}
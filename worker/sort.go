@@ -102,6 +102,10 @@ func (w *grpcWorker) Sort(ctx context.Context, s *pb.SortMessage) (*pb.SortResul
 	var reply *pb.SortResult
 	c := make(chan error, 1)
 	go func() {
+		// The sort is charged to the query memory budget of this Alpha.
+		ctx, memory := x.WithMemoryAccount(ctx)
+		defer memory.Close()
+
 		var err error
 		reply, err = processSort(ctx, s)
 		c <- err
@@ -166,7 +170,9 @@ func sortWithoutIndex(ctx context.Context, ts *pb.SortMessage) *sortresult {
 				multiSortOffsets = append(multiSortOffsets, offset)
 			}
 			tempList.Uids = tempList.Uids[start:end]
-			vals = vals[start:end]
+			if len(ts.Order) > 1 {
+				vals = vals[start:end]
+			}
 			r.UidMatrix = append(r.UidMatrix, tempList)
 			multiSortVals[i] = vals
 		}
@@ -200,6 +206,10 @@ func sortWithIndex(ctx context.Context, ts *pb.SortMessage) *sortresult {
 	if !schema.State().IsIndexed(ctx, order.Attr) {
 		return resultWithError(errors.Errorf("Attribute %s is not indexed.", order.Attr))
 	}
+	// A partial index doesn't have all the values, so they're sorted without it.
+	if _, ok := schema.State().IndexCondition(ctx, order.Attr); ok {
+		return resultWithError(errors.Errorf("Attribute %s has a partial index.", order.Attr))
+	}
 
 	tokenizers := schema.State().Tokenizer(ctx, order.Attr)
 	var tokenizer tok.Tokenizer
@@ -397,6 +407,10 @@ func multiSort(ctx context.Context, r *sortresult, ts *pb.SortMessage) error {
 	for _, o := range ts.Order {
 		desc = append(desc, o.Desc)
 	}
+	var lang string
+	if len(ts.Order[0].Langs) == 0 {
+		lang = collationOf(ctx, ts.Order[0].Attr)
+	}
 
 	// Values have been accumulated, now we do the multisort for each list.
 	for i, ul := range r.reply.UidMatrix {
@@ -406,7 +420,7 @@ func multiSort(ctx context.Context, r *sortresult, ts *pb.SortMessage) error {
 			x.AssertTrue(idx >= 0)
 			vals[j] = sortVals[idx]
 		}
-		if err := types.Sort(vals, &ul.Uids, desc, ""); err != nil {
+		if err := types.Sort(vals, &ul.Uids, desc, lang); err != nil {
 			return err
 		}
 		// Paginate
@@ -440,6 +454,19 @@ func processSort(ctx context.Context, ts *pb.SortMessage) (*pb.SortResult, error
 	}
 	span.Annotate(nil, "Done waiting")
 
+	if err := fetchTier(ctx, ts.Order[0].Attr); err != nil {
+		return nil, err
+	}
+
+	// The other orders are processed as tasks, which take care of renames by themselves.
+	if attr := renamedAttr(ts.Order[0].Attr); attr != ts.Order[0].Attr {
+		order := *ts.Order[0]
+		order.Attr = attr
+		renamed := *ts
+		renamed.Order = append([]*pb.Order{&order}, ts.Order[1:]...)
+		ts = &renamed
+	}
+
 	if ts.Count < 0 {
 		return nil, errors.Errorf(
 			"We do not yet support negative or infinite count with sorting: %s %d. "+
@@ -694,13 +721,15 @@ func paginate(ts *pb.SortMessage, dest *pb.List, vals []types.Val) (int, int, er
 	return start, end, nil
 }
 
-// sortByValue fetches values and sort UIDList.
+// sortValueCost is roughly the memory taken by the value of a uid being sorted.
+const sortValueCost = 64
+
+// sortByValue fetches values and sort UIDList. The values are charged to the query memory account.
+// Going over the budget spills the sort to disk, unless the values are needed for the other sort
+// orders.
 func sortByValue(ctx context.Context, ts *pb.SortMessage, ul *pb.List,
 	typ types.TypeID) ([]types.Val, error) {
 	lenList := len(ul.Uids)
-	uids := make([]uint64, 0, lenList)
-	values := make([][]types.Val, 0, lenList)
-	multiSortVals := make([]types.Val, 0, lenList)
 	order := ts.Order[0]
 
 	var lang string
@@ -708,8 +737,39 @@ func sortByValue(ctx context.Context, ts *pb.SortMessage, ul *pb.List,
 		lang = order.Langs[0]
 	} else if langCount > 1 {
 		return nil, errors.Errorf("Sorting on multiple language is not supported.")
+	} else {
+		// Untagged values are sorted in the collation of the exact index, if it has one.
+		lang = collationOf(ctx, order.Attr)
+	}
+
+	memory := x.MemoryAccountFrom(ctx)
+	cost := int64(lenList) * sortValueCost
+	if err := memory.Charge(cost); err != nil {
+		// The values of a sort by several orders are kept to apply the other orders.
+		if len(ts.Order) > 1 {
+			return nil, err
+		}
+		glog.V(2).Infof("Spilling sort of %d uids by %s to disk.", lenList, order.Attr)
+		uids, err := spillSort(ctx, ul.Uids, func(uid uint64) types.Val {
+			val, err := fetchValue(uid, order.Attr, order.Langs, typ, ts.ReadTs)
+			if err != nil {
+				val.Value = nil
+			}
+			return val
+		}, order.Desc, lang)
+		if err != nil {
+			return nil, err
+		}
+		ul.Uids = uids
+		return nil, nil
+	}
+	if len(ts.Order) == 1 {
+		defer memory.Release(cost)
 	}
 
+	uids := make([]uint64, 0, lenList)
+	values := make([][]types.Val, 0, lenList)
+	multiSortVals := make([]types.Val, 0, lenList)
 	for i := 0; i < lenList; i++ {
 		select {
 		case <-ctx.Done():
@@ -737,6 +797,17 @@ func sortByValue(ctx context.Context, ts *pb.SortMessage, ul *pb.List,
 	return multiSortVals, err
 }
 
+// collationOf returns the locale of the exact index of attr, or an empty string if the index
+// has no collation.
+func collationOf(ctx context.Context, attr string) string {
+	for _, t := range schema.State().Tokenizer(ctx, attr) {
+		if et, ok := t.(tok.ExactTokenizer); ok {
+			return et.Collation()
+		}
+	}
+	return ""
+}
+
 // fetchValue gets the value for a given UID.
 func fetchValue(uid uint64, attr string, langs []string, scalar types.TypeID,
 	readTs uint64) (types.Val, error) {
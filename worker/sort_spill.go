@@ -0,0 +1,228 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"bufio"
+	"container/heap"
+	"context"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
+	ostats "go.opencensus.io/stats"
+
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// spillRunSize is the number of values a sort spilled to disk sorts in memory at a time.
+var spillRunSize = 1 << 16
+
+// spillSort sorts uids by the value returned by fetch for each of them, like sortByValue does,
+// without keeping all the values in memory. Runs of spillRunSize values are sorted and written to
+// a temporary file in x.Config.QuerySpillDir, and then merged.
+func spillSort(ctx context.Context, uids []uint64, fetch func(uid uint64) types.Val,
+	desc bool, lang string) ([]uint64, error) {
+	ostats.Record(ctx, x.QuerySpills.M(1))
+
+	f, err := ioutil.TempFile(x.Config.QuerySpillDir, "dgraph-sort-")
+	if err != nil {
+		return nil, errors.Wrapf(err, "while creating the file to spill the sort to")
+	}
+	defer func() {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+	}()
+
+	// runs holds the offset of each run in the file, followed by the end of the last one.
+	var runs []int64
+	var offset int64
+	w := bufio.NewWriter(f)
+	runUids := make([]uint64, 0, spillRunSize)
+	runValues := make([][]types.Val, 0, spillRunSize)
+	writeRun := func() error {
+		if err := types.Sort(runValues, &runUids, []bool{desc}, lang); err != nil {
+			return err
+		}
+		runs = append(runs, offset)
+		for i, uid := range runUids {
+			n, err := writeSortEntry(w, uid, runValues[i][0])
+			if err != nil {
+				return errors.Wrapf(err, "while spilling the sort")
+			}
+			offset += n
+		}
+		runUids, runValues = runUids[:0], runValues[:0]
+		return nil
+	}
+
+	for _, uid := range uids {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		runUids = append(runUids, uid)
+		runValues = append(runValues, []types.Val{fetch(uid)})
+		if len(runUids) == spillRunSize {
+			if err := writeRun(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(runUids) > 0 {
+		if err := writeRun(); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return nil, errors.Wrapf(err, "while spilling the sort")
+	}
+	runs = append(runs, offset)
+	runUids, runValues = nil, nil
+
+	// Merge the runs, taking the least of their next entries each time.
+	h := &sortRunHeap{cmp: types.NewComparator([]bool{desc}, lang)}
+	for i := 0; i+1 < len(runs); i++ {
+		r := &sortRun{
+			idx: i,
+			r:   bufio.NewReader(io.NewSectionReader(f, runs[i], runs[i+1]-runs[i])),
+		}
+		if err := r.next(); err != nil {
+			return nil, err
+		}
+		h.runs = append(h.runs, r)
+	}
+	heap.Init(h)
+
+	sorted := make([]uint64, 0, len(uids))
+	for h.Len() > 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		r := h.runs[0]
+		sorted = append(sorted, r.uid)
+		switch err := r.next(); {
+		case err == io.EOF:
+			heap.Pop(h)
+		case err != nil:
+			return nil, err
+		default:
+			heap.Fix(h, 0)
+		}
+	}
+	return sorted, nil
+}
+
+// writeSortEntry writes the uid and its value to w, and returns the number of bytes written. An
+// entry is the uid, the type of the value, whether it has a value, and the length prefixed value
+// marshalled to binary.
+func writeSortEntry(w io.Writer, uid uint64, val types.Val) (int64, error) {
+	var data []byte
+	if val.Value != nil {
+		bin := types.Val{Tid: types.BinaryID}
+		if err := types.Marshal(val, &bin); err != nil {
+			return 0, err
+		}
+		data = bin.Value.([]byte)
+	}
+
+	buf := make([]byte, 10+binary.MaxVarintLen64, 10+binary.MaxVarintLen64+len(data))
+	binary.BigEndian.PutUint64(buf, uid)
+	buf[8] = byte(val.Tid)
+	if val.Value != nil {
+		buf[9] = 1
+	}
+	n := 10 + binary.PutUvarint(buf[10:], uint64(len(data)))
+	buf = append(buf[:n], data...)
+	_, err := w.Write(buf)
+	return int64(len(buf)), err
+}
+
+// readSortEntry reads an entry written by writeSortEntry from r.
+func readSortEntry(r *bufio.Reader) (uint64, types.Val, error) {
+	var hdr [10]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return 0, types.Val{}, err
+	}
+	sz, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, types.Val{}, errors.Wrapf(err, "while reading the spilled sort")
+	}
+	data := make([]byte, sz)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, types.Val{}, errors.Wrapf(err, "while reading the spilled sort")
+	}
+
+	uid := binary.BigEndian.Uint64(hdr[:8])
+	val := types.Val{Tid: types.TypeID(hdr[8])}
+	if hdr[9] == 0 {
+		return uid, val, nil
+	}
+	val, err = types.Convert(types.Val{Tid: types.BinaryID, Value: data}, val.Tid)
+	return uid, val, err
+}
+
+// sortRun reads back a run of a spilled sort.
+type sortRun struct {
+	idx int
+	r   *bufio.Reader
+	uid uint64
+	val []types.Val
+}
+
+// next reads the next entry of the run. It returns io.EOF at the end of the run.
+func (r *sortRun) next() error {
+	uid, val, err := readSortEntry(r.r)
+	if err != nil {
+		return err
+	}
+	r.uid, r.val = uid, []types.Val{val}
+	return nil
+}
+
+// sortRunHeap orders the runs by their next entry. Equal entries are taken from the earlier runs
+// first, so that the merge is deterministic.
+type sortRunHeap struct {
+	runs []*sortRun
+	cmp  *types.Comparator
+}
+
+func (h *sortRunHeap) Len() int { return len(h.runs) }
+
+func (h *sortRunHeap) Less(i, j int) bool {
+	a, b := h.runs[i], h.runs[j]
+	switch {
+	case h.cmp.Less(a.val, b.val):
+		return true
+	case h.cmp.Less(b.val, a.val):
+		return false
+	}
+	return a.idx < b.idx
+}
+
+func (h *sortRunHeap) Swap(i, j int) { h.runs[i], h.runs[j] = h.runs[j], h.runs[i] }
+
+func (h *sortRunHeap) Push(x interface{}) { h.runs = append(h.runs, x.(*sortRun)) }
+
+func (h *sortRunHeap) Pop() interface{} {
+	old := h.runs
+	r := old[len(old)-1]
+	h.runs = old[:len(old)-1]
+	return r
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dgraph-io/dgraph/types"
+)
+
+func TestSpillSort(t *testing.T) {
+	defer func(n int) { spillRunSize = n }(spillRunSize)
+	spillRunSize = 10
+
+	uids := make([]uint64, 95)
+	vals := make(map[uint64]types.Val)
+	for i := range uids {
+		uid := uint64(i + 1)
+		uids[i] = uid
+		vals[uid] = types.Val{Tid: types.IntID, Value: rand.Int63n(50)}
+		if uid%7 == 0 {
+			// Uids without a value sort last, or first if descending.
+			vals[uid] = types.Val{Tid: types.IntID}
+		}
+	}
+	fetch := func(uid uint64) types.Val { return vals[uid] }
+
+	for _, desc := range []bool{false, true} {
+		sorted, err := spillSort(context.Background(), uids, fetch, desc, "")
+		require.NoError(t, err)
+		require.ElementsMatch(t, uids, sorted)
+		require.True(t, sort.SliceIsSorted(sorted, func(i, j int) bool {
+			a, b := vals[sorted[i]], vals[sorted[j]]
+			switch {
+			case a.Value == nil:
+				return b.Value != nil && desc
+			case b.Value == nil:
+				return !desc
+			case desc:
+				return a.Value.(int64) > b.Value.(int64)
+			}
+			return a.Value.(int64) < b.Value.(int64)
+		}), "desc: %v", desc)
+	}
+
+	strs := []string{"b", "", "a", "c"}
+	svals := make(map[uint64]types.Val)
+	for i, s := range strs {
+		svals[uint64(i+1)] = types.Val{Tid: types.StringID, Value: s}
+	}
+	sorted, err := spillSort(context.Background(), []uint64{1, 2, 3, 4},
+		func(uid uint64) types.Val { return svals[uid] }, false, "")
+	require.NoError(t, err)
+	require.Equal(t, []uint64{2, 3, 1, 4}, sorted)
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"context"
+
+	"github.com/dgraph-io/dgraph/posting"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// TabletKeys returns the number of keys of the tablet of the given predicate, as last reported
+// by the group serving it. It returns false if the tablet is unknown or its keys haven't been
+// counted yet, which is the case for tablets too small to fill a table on disk. Like the edge
+// properties, the tablets are known through the membership state, so Zero isn't contacted.
+func TabletKeys(attr string) (uint64, bool) {
+	g := groups()
+	g.RLock()
+	defer g.RUnlock()
+	tablet, ok := g.tablets[attr]
+	if !ok || tablet.GetNumKeys() == 0 {
+		return 0, false
+	}
+	return tablet.GetNumKeys(), true
+}
+
+// EqUids returns the number of uids an eq function over the given values of attr matches, which
+// is the sum of the lengths of the index posting lists of their tokens. It returns false if the
+// number can't be read locally: attr isn't indexed or isn't served by this group, or a value
+// can't be converted to the type of attr.
+func EqUids(ctx context.Context, attr string, vals []string) (uint64, bool) {
+	g := groups()
+	g.RLock()
+	tablet, ok := g.tablets[attr]
+	g.RUnlock()
+	if !ok || tablet.GetGroupId() != g.groupId() || !schema.State().IsIndexed(ctx, attr) {
+		return 0, false
+	}
+
+	readTs := posting.Oracle().MaxAssigned()
+	var uids uint64
+	for _, val := range vals {
+		v, err := convertValue(attr, val)
+		if err != nil {
+			return 0, false
+		}
+		tokens, _, err := getInequalityTokens(ctx, readTs, attr, eq, "", nil, []types.Val{v})
+		if err != nil {
+			return 0, false
+		}
+		for _, token := range tokens {
+			pl, err := posting.GetNoStore(x.IndexKey(attr, token), readTs)
+			if err != nil {
+				return 0, false
+			}
+			if n := pl.Length(readTs, 0); n > 0 {
+				uids += uint64(n)
+			}
+		}
+	}
+	return uids, true
+}
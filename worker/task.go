@@ -19,6 +19,7 @@ package worker
 import (
 	"bytes"
 	"context"
+	"math/rand"
 	"sort"
 	"strconv"
 	"strings"
@@ -142,12 +143,20 @@ func ProcessTaskOverNetwork(ctx context.Context, q *pb.Query) (*pb.Result, error
 	span := otrace.FromContext(ctx)
 	if span != nil {
 		span.Annotatef(nil, "ProcessTaskOverNetwork. attr: %v gid: %v, readTs: %d, node id: %d",
-			attr, gid, q.ReadTs, groups().Node.Id)
+			attr, gid, q.ReadTs, x.WorkerConfig.RaftId)
 	}
 
 	if groups().ServesGroup(gid) {
 		// No need for a network call, as this should be run from within this instance.
-		return processTask(ctx, q, gid)
+		reply, err := processTask(ctx, q, gid)
+		if err != nil {
+			return nil, err
+		}
+		// The postings read by the query are kept until it's done.
+		if err := x.MemoryAccountFrom(ctx).Charge(int64(reply.Size())); err != nil {
+			return nil, err
+		}
+		return reply, nil
 	}
 
 	result, err := processWithBackupRequest(ctx, gid,
@@ -163,9 +172,22 @@ func ProcessTaskOverNetwork(ctx context.Context, q *pb.Query) (*pb.Result, error
 		span.Annotatef(nil, "Reply from server. len: %v gid: %v Attr: %v",
 			len(reply.UidMatrix), gid, attr)
 	}
+	if err := x.MemoryAccountFrom(ctx).Charge(int64(reply.Size())); err != nil {
+		return nil, err
+	}
 	return reply, nil
 }
 
+// ServingGroup returns the group serving the given predicate at readTs and whether
+// that group is served by this instance, i.e. tasks for it don't need a network call.
+func ServingGroup(attr string, readTs uint64) (uint32, bool, error) {
+	gid, err := groups().BelongsToReadOnly(attr, readTs)
+	if err != nil || gid == 0 {
+		return gid, false, err
+	}
+	return gid, groups().ServesGroup(gid), nil
+}
+
 // convertValue converts the data to the schema.State() type of predicate.
 func convertValue(attr, data string) (types.Val, error) {
 	// Parse given value and get token. There should be only one token.
@@ -181,6 +203,43 @@ func convertValue(attr, data string) (types.Val, error) {
 	return dst, err
 }
 
+// convertValueInLocation converts data like convertValue, but datetime values that don't
+// specify a time zone are interpreted in loc, if given.
+func convertValueInLocation(attr, data string, loc *time.Location) (types.Val, error) {
+	if loc == nil {
+		return convertValue(attr, data)
+	}
+	t, err := types.ParseTimeInLocation(data, loc)
+	if err != nil {
+		return types.Val{}, err
+	}
+	return types.Val{Tid: types.DateTimeID, Value: t}, nil
+}
+
+// parseTimeZoneArg returns the time zone passed as an extra last argument to an inequality
+// function over a datetime predicate, like ge(created, "2020-01-01", "Asia/Kolkata"), along with
+// the rest of the arguments. eq doesn't take a time zone as it can have any number of arguments.
+func parseTimeZoneArg(attr, fname string, args []string) (*time.Location, []string, error) {
+	numArgs := 1
+	switch fname {
+	case eq:
+		return nil, args, nil
+	case between:
+		numArgs = 2
+	}
+	if len(args) != numArgs+1 {
+		return nil, args, nil
+	}
+	if t, err := schema.State().TypeOf(attr); err != nil || t != types.DateTimeID {
+		return nil, args, nil
+	}
+	loc, err := types.LoadLocation(args[numArgs])
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "while parsing the time zone argument of %s", fname)
+	}
+	return loc, args[:numArgs], nil
+}
+
 // Returns nil byte on error
 func convertToType(v types.Val, typ types.TypeID) (*pb.TaskValue, error) {
 	result := &pb.TaskValue{ValType: typ.Enum(), Val: x.Nilbyte}
@@ -220,6 +279,8 @@ const (
 	uidInFn
 	customIndexFn
 	matchFn
+	scoreFn
+	similarToFn
 	standardFn = 100
 )
 
@@ -262,6 +323,10 @@ func parseFuncTypeHelper(name string) (FuncType, string) {
 		return customIndexFn, f
 	case "match":
 		return matchFn, f
+	case "bm25":
+		return scoreFn, f
+	case "similar_to":
+		return similarToFn, f
 	default:
 		if types.IsGeoFunc(f) {
 			return geoFn, f
@@ -280,7 +345,7 @@ func needsIndex(fnType FuncType, uidList *pb.List) bool {
 			return false
 		}
 		return true
-	case geoFn, fullTextSearchFn, standardFn, matchFn:
+	case geoFn, fullTextSearchFn, standardFn, matchFn, similarToFn:
 		return true
 	}
 	return false
@@ -304,7 +369,7 @@ type funcArgs struct {
 // The function tells us whether we want to fetch value posting lists or uid posting lists.
 func (srcFn *functionContext) needsValuePostings(typ types.TypeID) (bool, error) {
 	switch srcFn.fnType {
-	case aggregatorFn, passwordFn:
+	case aggregatorFn, passwordFn, scoreFn:
 		return true, nil
 	case compareAttrFn:
 		if len(srcFn.tokens) > 0 {
@@ -314,7 +379,7 @@ func (srcFn *functionContext) needsValuePostings(typ types.TypeID) (bool, error)
 	case geoFn, regexFn, fullTextSearchFn, standardFn, hasFn, customIndexFn, matchFn:
 		// All of these require an index, hence would require fetching uid postings.
 		return false, nil
-	case uidInFn, compareScalarFn:
+	case uidInFn, compareScalarFn, similarToFn:
 		// Operate on uid postings
 		return false, nil
 	case notAFunction:
@@ -341,7 +406,7 @@ func (qs *queryState) handleValuePostings(ctx context.Context, args funcArgs) er
 	}
 
 	switch srcFn.fnType {
-	case notAFunction, aggregatorFn, passwordFn, compareAttrFn:
+	case notAFunction, aggregatorFn, passwordFn, compareAttrFn, scoreFn:
 	default:
 		return errors.Errorf("Unhandled function in handleValuePostings: %s", srcFn.fname)
 	}
@@ -357,6 +422,9 @@ func (qs *queryState) handleValuePostings(ctx context.Context, args funcArgs) er
 	if srcFn.n == 0 {
 		return nil
 	}
+	if srcFn.fnType == scoreFn {
+		return qs.handleScoreFunction(ctx, args)
+	}
 
 	// srcFn.n should be equal to len(q.UidList.Uids) for below implementation(DivideAndRule and
 	// calculate) to work correctly. But we have seen some panics while forming DataKey in
@@ -377,6 +445,15 @@ func (qs *queryState) handleValuePostings(ctx context.Context, args funcArgs) er
 	outputs := make([]*pb.Result, numGo)
 	listType := schema.State().IsList(q.Attr)
 
+	// The nodes without a value get the virtual default of the predicate, if it has one. It's
+	// only returned when the values are fetched, so functions and filters don't see it.
+	var virtualDefault *types.Val
+	if srcFn.fnType == notAFunction && facetsTree == nil && len(q.Langs) == 0 {
+		if val, virtual, ok := schema.State().Default(q.Attr); ok && virtual {
+			virtualDefault = &val
+		}
+	}
+
 	calculate := func(start, end int) error {
 		x.AssertTrue(start%width == 0)
 		out := &pb.Result{}
@@ -409,6 +486,10 @@ func (qs *queryState) handleValuePostings(ctx context.Context, args funcArgs) er
 			}
 
 			vals, fcs, err := retrieveValuesAndFacets(args, pl, facetsTree, listType)
+			noValue := err == posting.ErrNoValue || (err == nil && len(vals) == 0)
+			if noValue && virtualDefault != nil {
+				vals, fcs, err = []types.Val{*virtualDefault}, &pb.FacetsList{}, nil
+			}
 			switch {
 			case err == posting.ErrNoValue || (err == nil && len(vals) == 0):
 				// This branch is taken when the value does not exist in the pl or
@@ -700,6 +781,9 @@ func (qs *queryState) handleUidPostings(
 	if srcFn.n == 0 {
 		return nil
 	}
+	if srcFn.fnType == scoreFn {
+		return qs.handleScoreFunction(ctx, args)
+	}
 
 	// srcFn.n should be equal to len(q.UidList.Uids) for below implementation(DivideAndRule and
 	// calculate) to work correctly. But we have seen some panics while forming DataKey in
@@ -882,7 +966,7 @@ func processTask(ctx context.Context, q *pb.Query, gid uint32) (*pb.Result, erro
 	defer stop()
 
 	span.Annotatef(nil, "Waiting for startTs: %d at node: %d, gid: %d",
-		q.ReadTs, groups().Node.Id, gid)
+		q.ReadTs, x.WorkerConfig.RaftId, gid)
 	if err := posting.Oracle().WaitForTs(ctx, q.ReadTs); err != nil {
 		return nil, err
 	}
@@ -911,6 +995,19 @@ func processTask(ctx context.Context, q *pb.Query, gid uint32) (*pb.Result, erro
 	case knownGid != groups().groupId():
 		return nil, errUnservedTablet
 	}
+	loads.queried(q.Attr)
+
+	// The data of an offloaded predicate is fetched back before it's read.
+	if err := fetchTier(ctx, q.Attr); err != nil {
+		return nil, err
+	}
+
+	// While a predicate is being renamed, its new name is read from the keys of the old one.
+	if attr := renamedAttr(q.Attr); attr != q.Attr {
+		renamed := *q
+		renamed.Attr = attr
+		q = &renamed
+	}
 
 	var qs queryState
 	if q.Cache == UseTxnCache {
@@ -988,6 +1085,34 @@ func (qs *queryState) helpProcessTask(ctx context.Context, q *pb.Query, gid uint
 	if srcFn.fnType != notAFunction && q.UidList != nil && len(q.UidList.Uids) > 0 {
 		opts.Intersect = q.UidList
 	}
+	// When an edge is paginated, only the UIDs the page needs are read from each list.
+	if srcFn.fnType == notAFunction && q.First > 0 {
+		opts.First = int(q.First)
+	}
+
+	if tokenizer := distinctCountTokenizer(ctx, q, srcFn); tokenizer != nil {
+		span.Annotate(nil, "handleDistinctCount")
+		if err := qs.handleDistinctCount(ctx, q, out, tokenizer); err != nil {
+			return nil, err
+		}
+		return out, nil
+	}
+
+	if srcFn.fnType == similarToFn {
+		span.Annotate(nil, "handleSimilarToFunction")
+		if err := qs.handleSimilarToFunction(ctx, q, out, srcFn); err != nil {
+			return nil, err
+		}
+		return out, nil
+	}
+
+	if q.EdgeFilter != nil {
+		span.Annotate(nil, "handleEdgeFilter")
+		if err := qs.handleEdgeFilter(ctx, q, out); err != nil {
+			return nil, err
+		}
+		return out, nil
+	}
 
 	args := funcArgs{q, gid, srcFn, out}
 	needsValPostings, err := srcFn.needsValuePostings(typ)
@@ -1061,9 +1186,31 @@ func (qs *queryState) helpProcessTask(ctx context.Context, q *pb.Query, gid uint
 	}
 
 	out.IntersectDest = srcFn.intersectDest
+	if q.Sample > 0 && q.UidList == nil {
+		// Only the sample is sent back, so the uids left out are never expanded.
+		sampleRoot(out, int(q.Sample))
+	}
 	return out, nil
 }
 
+// sampleRoot replaces the uids matched by a root function with a uniformly random sample of n of
+// them. The lists of the tokens matched are merged first, as the sample is taken out of their
+// union or, if the function requires all the tokens, out of their intersection.
+func sampleRoot(out *pb.Result, n int) {
+	var uids *pb.List
+	switch {
+	case len(out.UidMatrix) == 1:
+		uids = out.UidMatrix[0]
+	case out.IntersectDest:
+		uids = algo.IntersectSorted(out.UidMatrix)
+	default:
+		uids = algo.MergeSorted(out.UidMatrix)
+	}
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	out.UidMatrix = []*pb.List{algo.Sample(uids, n, rnd)}
+	out.IntersectDest = false
+}
+
 func needsStringFiltering(srcFn *functionContext, langs []string, attr string) bool {
 	if !srcFn.isStringFn {
 		return false
@@ -1288,6 +1435,12 @@ func (qs *queryState) handleCompareFunction(ctx context.Context, arg funcArgs) e
 					}
 					return false
 				}
+				if x.IsCompositeIndex(attr) {
+					if sv, err = compositeLastValue(attr, sv); err != nil {
+						filterErr = err
+						return false
+					}
+				}
 				dst, err := types.Convert(sv, typ)
 				return err == nil && compareFunc(dst)
 			case ".":
@@ -1375,7 +1528,26 @@ func (qs *queryState) handleMatchFunction(ctx context.Context, arg funcArgs) err
 	attr := arg.q.Attr
 	typ := arg.srcFn.atype
 	span.Annotatef(nil, "Attr: %s. Type: %s", attr, typ.Name())
+	lang := langForFunc(arg.q.Langs)
+
+	// Values are matched as a whole, unless the predicate only has a term index. In that case
+	// a value matches if any of its terms does.
+	matchTerms := !schema.State().HasTokenizer(ctx, tok.IdentTrigram, attr) &&
+		!schema.State().HasTokenizer(ctx, tok.IdentExact, attr) &&
+		schema.State().HasTokenizer(ctx, tok.IdentTerm, attr)
 	var uids *pb.List
+	var err error
+	var terms tok.Tokenizer
+	matchQuery := strings.Join(arg.srcFn.tokens, "")
+	if matchTerms {
+		terms = stringIndexTokenizer(ctx, attr, standardFn)
+		if matchQuery, err = fuzzyTerm(matchQuery, terms); err != nil {
+			return err
+		}
+	}
+	matcher := newFuzzyMatcher(matchQuery, int(arg.srcFn.threshold[0]),
+		int(arg.srcFn.threshold[1]))
+
 	switch {
 	case !typ.IsScalar():
 		return errors.Errorf("Attribute not scalar: %s %v", attr, typ)
@@ -1387,9 +1559,18 @@ func (qs *queryState) handleMatchFunction(ctx context.Context, arg funcArgs) err
 		uids = arg.q.UidList
 
 	case schema.State().HasTokenizer(ctx, tok.IdentTrigram, attr):
-		var err error
-		uids, err = uidsForMatch(attr, arg)
-		if err != nil {
+		if uids, err = uidsForMatch(attr, arg); err != nil {
+			return err
+		}
+
+	// Values with a language tag are indexed with a different exact tokenizer.
+	case lang == "" && schema.State().HasTokenizer(ctx, tok.IdentExact, attr):
+		if uids, err = uidsForFuzzyTerms(ctx, attr, arg, tok.IdentExact, matcher); err != nil {
+			return err
+		}
+
+	case matchTerms:
+		if uids, err = uidsForFuzzyTerms(ctx, attr, arg, tok.IdentTerm, matcher); err != nil {
 			return err
 		}
 
@@ -1401,11 +1582,9 @@ func (qs *queryState) handleMatchFunction(ctx context.Context, arg funcArgs) err
 	}
 
 	isList := schema.State().IsList(attr)
-	lang := langForFunc(arg.q.Langs)
 	span.Annotatef(nil, "Total uids: %d, list: %t lang: %v", len(uids.Uids), isList, lang)
 	arg.out.UidMatrix = append(arg.out.UidMatrix, uids)
 
-	matchQuery := strings.Join(arg.srcFn.tokens, "")
 	filtered := &pb.List{}
 	for _, uid := range uids.Uids {
 		select {
@@ -1436,11 +1615,10 @@ func (qs *queryState) handleMatchFunction(ctx context.Context, arg funcArgs) err
 			return err
 		}
 
-		max := int(arg.srcFn.threshold[0])
 		for _, val := range vals {
 			// convert data from binary to appropriate format
 			strVal, err := types.Convert(val, types.StringID)
-			if err == nil && matchFuzzy(matchQuery, strVal.Value.(string), max) {
+			if err == nil && matchFuzzy(matcher, strVal.Value.(string), terms) {
 				filtered.Uids = append(filtered.Uids, uid)
 				// NOTE: We only add the uid once.
 				break
@@ -1579,17 +1757,17 @@ func (qs *queryState) filterStringFunction(arg funcArgs) error {
 	case fullTextSearchFn:
 		filter.tokens = arg.srcFn.tokens
 		filter.match = defaultMatch
-		filter.tokName = "fulltext"
+		filter.tokName = arg.srcFn.tokName
 		filtered = matchStrings(filtered, values, &filter)
 	case standardFn:
 		filter.tokens = arg.srcFn.tokens
 		filter.match = defaultMatch
-		filter.tokName = "term"
+		filter.tokName = arg.srcFn.tokName
 		filtered = matchStrings(filtered, values, &filter)
 	case customIndexFn:
 		filter.tokens = arg.srcFn.tokens
 		filter.match = defaultMatch
-		filter.tokName = arg.q.SrcFunc.Args[0]
+		filter.tokName = arg.srcFn.tokName
 		filtered = matchStrings(filtered, values, &filter)
 	case compareAttrFn:
 		// filter.ineqValue = arg.srcFn.ineqValue
@@ -1654,6 +1832,11 @@ type functionContext struct {
 	isFuncAtRoot   bool
 	isStringFn     bool
 	atype          types.TypeID
+	// tokName is the name of the tokenizer used by the term, fulltext and custom index functions.
+	tokName string
+	// vector and vectorIndex are the query vector of similar_to and the index it's searched in.
+	vector      []float32
+	vectorIndex tok.HNSWTokenizer
 }
 
 const (
@@ -1688,6 +1871,21 @@ func langForFunc(langs []string) string {
 	return langs[0]
 }
 
+// coveredByPartialIndex returns whether all the values matched by the compare function satisfy
+// the condition of the partial index of attr, so that they're all in the index.
+func coveredByPartialIndex(cond schema.IndexCondition, attr, fname string, args []string,
+	loc *time.Location) (bool, error) {
+	vals := make([]types.Val, 0, len(args))
+	for _, arg := range args {
+		val, err := convertValueInLocation(attr, arg, loc)
+		if err != nil {
+			return false, err
+		}
+		vals = append(vals, val)
+	}
+	return cond.Covers(fname, vals), nil
+}
+
 func parseSrcFn(ctx context.Context, q *pb.Query) (*functionContext, error) {
 	fnType, f := parseFuncType(q.SrcFunc)
 	attr := q.Attr
@@ -1700,6 +1898,23 @@ func parseSrcFn(ctx context.Context, q *pb.Query) (*functionContext, error) {
 		fc.isStringFn = true
 	}
 
+	// Composite indexes have no values of their own to fetch, they can only be looked up.
+	if x.IsCompositeIndex(attr) && fnType != compareAttrFn && fnType != hasFn {
+		return nil, errors.Errorf("Composite index %s can only be used with the eq, ge, gt, le,"+
+			" lt, between and has functions", attr)
+	}
+
+	// A partial index only has the values satisfying its condition, so only the compare
+	// functions matching values that all satisfy it can be answered from the index.
+	cond, isPartial := schema.State().IndexCondition(ctx, attr)
+	switch fnType {
+	case geoFn, fullTextSearchFn, standardFn, matchFn, similarToFn, regexFn, customIndexFn:
+		if isPartial {
+			return nil, errors.Errorf("The partial index of predicate %s can only be used by"+
+				" the eq, ge, gt, le, lt and between functions", attr)
+		}
+	}
+
 	switch fnType {
 	case notAFunction:
 		fc.n = len(q.UidList.Uids)
@@ -1715,7 +1930,16 @@ func parseSrcFn(ctx context.Context, q *pb.Query) (*functionContext, error) {
 		}
 		fc.n = len(q.UidList.Uids)
 	case compareAttrFn:
-		args := q.SrcFunc.Args
+		if x.IsCompositeIndex(attr) {
+			if err := parseCompositeFn(ctx, q, fc); err != nil {
+				return nil, err
+			}
+			break
+		}
+		loc, args, err := parseTimeZoneArg(attr, fc.fname, q.SrcFunc.Args)
+		if err != nil {
+			return nil, err
+		}
 		if fc.fname == eq { // Only eq can have multiple args. It should have atleast one.
 			if len(args) < 1 {
 				return nil, errors.Errorf("eq expects atleast 1 argument.")
@@ -1731,13 +1955,29 @@ func parseSrcFn(ctx context.Context, q *pb.Query) (*functionContext, error) {
 			}
 		}
 
+		if isIndexedAttr && isPartial {
+			covered, err := coveredByPartialIndex(cond, attr, fc.fname, args, loc)
+			if err != nil {
+				return nil, errors.Errorf("Got error: %v while running: %v", err, q.SrcFunc)
+			}
+			switch {
+			case !covered && q.UidList == nil:
+				return nil, errors.Errorf("The partial index of predicate %s doesn't have all"+
+					" the values matched by %s(%s). Please use the function in a filter",
+					attr, fc.fname, strings.Join(args, ", "))
+			case !covered:
+				// The values of the nodes to filter are compared without the index.
+				isIndexedAttr = false
+			}
+		}
+
 		var tokens []string
 		var ineqValues []types.Val
 		// eq can have multiple args.
 		for idx := 0; idx < len(args); idx++ {
 			arg := args[idx]
 			ineqValues = ineqValues[:0]
-			ineqValue1, err := convertValue(attr, arg)
+			ineqValue1, err := convertValueInLocation(attr, arg, loc)
 			if err != nil {
 				return nil, errors.Errorf("Got error: %v while running: %v", err, q.SrcFunc)
 			}
@@ -1746,7 +1986,7 @@ func parseSrcFn(ctx context.Context, q *pb.Query) (*functionContext, error) {
 
 			// in case of between also pass other value.
 			if fc.fname == between {
-				ineqValue2, err := convertValue(attr, args[idx+1])
+				ineqValue2, err := convertValueInLocation(attr, args[idx+1], loc)
 				if err != nil {
 					return nil, errors.Errorf("Got error: %v while running: %v", err, q.SrcFunc)
 				}
@@ -1768,7 +2008,7 @@ func parseSrcFn(ctx context.Context, q *pb.Query) (*functionContext, error) {
 
 			// Get tokens ge/le ineqValueToken.
 			if tokens, fc.ineqValueToken, err = getInequalityTokens(ctx, q.ReadTs, attr, f, lang,
-				ineqValues); err != nil {
+				nil, ineqValues); err != nil {
 				return nil, err
 			}
 			if len(tokens) == 0 {
@@ -1814,7 +2054,8 @@ func parseSrcFn(ctx context.Context, q *pb.Query) (*functionContext, error) {
 		checkRoot(q, fc)
 	case geoFn:
 		// For geo functions, we get extra information used for filtering.
-		fc.tokens, fc.geoQuery, err = types.GetGeoTokens(q.SrcFunc)
+		fc.tokens, fc.geoQuery, err = types.GetGeoTokens(q.SrcFunc,
+			geoIndexTokenizer(ctx, attr).Options())
 		tok.EncodeGeoTokens(fc.tokens)
 		if err != nil {
 			return nil, err
@@ -1825,6 +2066,41 @@ func parseSrcFn(ctx context.Context, q *pb.Query) (*functionContext, error) {
 			return nil, err
 		}
 		fc.n = len(q.UidList.Uids)
+	case scoreFn:
+		if err = ensureArgsCount(q.SrcFunc, 1); err != nil {
+			return nil, err
+		}
+		if q.UidList == nil {
+			return nil, errors.Errorf("Function %s can only be used inside a query block", f)
+		}
+		required, found := verifyStringIndex(ctx, attr, fullTextSearchFn)
+		if !found {
+			return nil, errors.Errorf("Attribute %s is not indexed with type %s", attr, required)
+		}
+		if fc.tokens, err = getStringTokens(q.SrcFunc.Args, langForFunc(q.Langs),
+			stringIndexTokenizer(ctx, attr, fullTextSearchFn)); err != nil {
+			return nil, err
+		}
+		fc.n = len(q.UidList.Uids)
+	case similarToFn:
+		if err = ensureArgsCount(q.SrcFunc, 2); err != nil {
+			return nil, err
+		}
+		var found bool
+		if fc.vectorIndex, found = vectorIndexTokenizer(ctx, attr); !found {
+			return nil, errors.Errorf("Attribute %s is not indexed with type %s", attr,
+				tok.HNSWTokenizer{}.Name())
+		}
+		k, err := strconv.ParseInt(q.SrcFunc.Args[0], 10, 32)
+		if err != nil || k <= 0 {
+			return nil, errors.Errorf("The number of similar nodes must be a positive int, "+
+				"got %v", q.SrcFunc.Args[0])
+		}
+		if fc.vector, err = types.ParseVector(q.SrcFunc.Args[1]); err != nil {
+			return nil, err
+		}
+		fc.threshold = []int64{k}
+		fc.n = 1
 	case standardFn, fullTextSearchFn:
 		// srcfunc 0th val is func name and and [2:] are args.
 		// we tokenize the arguments of the query.
@@ -1835,14 +2111,20 @@ func parseSrcFn(ctx context.Context, q *pb.Query) (*functionContext, error) {
 		if !found {
 			return nil, errors.Errorf("Attribute %s is not indexed with type %s", attr, required)
 		}
-		if fc.tokens, err = getStringTokens(q.SrcFunc.Args, langForFunc(q.Langs), fnType); err != nil {
+		tokenizer := stringIndexTokenizer(ctx, attr, fnType)
+		if fc.tokens, err = getStringTokens(q.SrcFunc.Args, langForFunc(q.Langs),
+			tokenizer); err != nil {
 			return nil, err
 		}
+		fc.tokName = tokenizer.Name()
 		fc.intersectDest = needsIntersect(f)
 		fc.n = len(fc.tokens)
 	case matchFn:
-		if err = ensureArgsCount(q.SrcFunc, 2); err != nil {
-			return nil, err
+		// The length of the prefix that has to match exactly is optional.
+		if len(q.SrcFunc.Args) != 3 {
+			if err = ensureArgsCount(q.SrcFunc, 2); err != nil {
+				return nil, err
+			}
 		}
 		required, found := verifyStringIndex(ctx, attr, fnType)
 		if !found {
@@ -1850,8 +2132,7 @@ func parseSrcFn(ctx context.Context, q *pb.Query) (*functionContext, error) {
 		}
 		fc.intersectDest = needsIntersect(f)
 		// Max Levenshtein distance
-		var s string
-		s, q.SrcFunc.Args = q.SrcFunc.Args[1], q.SrcFunc.Args[:1]
+		s := q.SrcFunc.Args[1]
 		max, err := strconv.ParseInt(s, 10, 32)
 		if err != nil {
 			return nil, errors.Errorf("Levenshtein distance value must be an int, got %v", s)
@@ -1859,7 +2140,18 @@ func parseSrcFn(ctx context.Context, q *pb.Query) (*functionContext, error) {
 		if max < 0 {
 			return nil, errors.Errorf("Levenshtein distance value must be greater than 0, got %v", s)
 		}
-		fc.threshold = []int64{int64(max)}
+		var prefixLen int64
+		if len(q.SrcFunc.Args) == 3 {
+			s = q.SrcFunc.Args[2]
+			if prefixLen, err = strconv.ParseInt(s, 10, 32); err != nil {
+				return nil, errors.Errorf("Prefix length value must be an int, got %v", s)
+			}
+			if prefixLen < 0 {
+				return nil, errors.Errorf("Prefix length value can't be negative, got %v", s)
+			}
+		}
+		q.SrcFunc.Args = q.SrcFunc.Args[:1]
+		fc.threshold = []int64{max, prefixLen}
 		fc.tokens = q.SrcFunc.Args
 		fc.n = len(fc.tokens)
 	case customIndexFn:
@@ -1867,7 +2159,8 @@ func parseSrcFn(ctx context.Context, q *pb.Query) (*functionContext, error) {
 			return nil, err
 		}
 		tokerName := q.SrcFunc.Args[0]
-		if !verifyCustomIndex(ctx, q.Attr, tokerName) {
+		tokenizer, ok := customIndexTokenizer(ctx, q.Attr, tokerName)
+		if !ok {
 			return nil, errors.Errorf("Attribute %s is not indexed with custom tokenizer %s",
 				q.Attr, tokerName)
 		}
@@ -1875,10 +2168,7 @@ func parseSrcFn(ctx context.Context, q *pb.Query) (*functionContext, error) {
 		if err != nil {
 			return nil, err
 		}
-		tokenizer, ok := tok.GetTokenizer(tokerName)
-		if !ok {
-			return nil, errors.Errorf("Could not find tokenizer with name %q", tokerName)
-		}
+		fc.tokName = tokenizer.Name()
 		fc.tokens, _ = tok.BuildTokens(valToTok.Value,
 			tok.GetTokenizerForLang(tokenizer, langForFunc(q.Langs)))
 		fc.intersectDest = needsIntersect(f)
@@ -2297,6 +2587,153 @@ func (qs *queryState) evaluate(cp countParams, out *pb.Result) error {
 	return nil
 }
 
+// distinctCountTokenizer returns the exact or hash tokenizer of the predicate if the query is
+// for count(distinct) and can be answered from the index. The tokens of both tokenizers stand for
+// the values themselves, so the number of tokens is the number of distinct values.
+func distinctCountTokenizer(ctx context.Context, q *pb.Query, srcFn *functionContext) tok.Tokenizer {
+	if !q.DistinctCount || srcFn.fnType != notAFunction || q.Reverse || q.ExpandAll ||
+		len(q.Langs) > 0 || q.FacetParam != nil || len(q.UidList.GetUids()) == 0 {
+		return nil
+	}
+	// Values with a language tag are indexed separately for every language.
+	if schema.State().HasLang(q.Attr) {
+		return nil
+	}
+	for _, t := range schema.State().Tokenizer(ctx, q.Attr) {
+		if t.Name() == "exact" || t.Name() == "hash" {
+			return t
+		}
+	}
+	return nil
+}
+
+// handleDistinctCount iterates over the index tokens of the predicate and fills the distinct
+// buckets of out with the uids of every token that has any of the uids of the query. The values
+// aren't fetched, so the uid and value matrices are left empty.
+func (qs *queryState) handleDistinctCount(ctx context.Context, q *pb.Query, out *pb.Result,
+	tokenizer tok.Tokenizer) error {
+	span := otrace.FromContext(ctx)
+	stop := x.SpanTimer(span, "handleDistinctCount")
+	defer stop()
+
+	txn := pstore.NewTransactionAt(q.ReadTs, false)
+	defer txn.Discard()
+
+	itOpt := badger.DefaultIteratorOptions
+	itOpt.PrefetchValues = false
+	itOpt.Prefix = x.IndexKey(q.Attr, string(tokenizer.Identifier()))
+	itr := txn.NewIterator(itOpt)
+	defer itr.Close()
+
+	opts := posting.ListOptions{ReadTs: q.ReadTs, Intersect: q.UidList}
+	for itr.Rewind(); itr.Valid(); itr.Next() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		pl, err := qs.cache.Get(itr.Item().KeyCopy(nil))
+		if err != nil {
+			return err
+		}
+		uids, err := pl.Uids(opts)
+		if err != nil {
+			return err
+		}
+		if len(uids.Uids) > 0 {
+			out.DistinctBuckets = append(out.DistinctBuckets, uids)
+		}
+	}
+
+	for range q.UidList.Uids {
+		out.UidMatrix = append(out.UidMatrix, &pb.List{})
+		out.ValueMatrix = append(out.ValueMatrix, &pb.ValueList{})
+	}
+	return nil
+}
+
+// handleEdgeFilter filters the edges of the nodes in the query by the values of an edge
+// property. For each node, it returns the destinations of the edges whose value matches the
+// filter. The filter is applied the same way as a facets filter, with the value of the property
+// as the facet.
+func (qs *queryState) handleEdgeFilter(ctx context.Context, q *pb.Query, out *pb.Result) error {
+	span := otrace.FromContext(ctx)
+	stop := x.SpanTimer(span, "handleEdgeFilter")
+	defer stop()
+
+	if !x.IsEdgeProperty(q.Attr) {
+		return errors.Errorf("Predicate %s isn't an edge property", q.Attr)
+	}
+	if q.UidList == nil {
+		return errors.Errorf("Edge filter on %s requires the nodes of the edges", q.Attr)
+	}
+	ftree, err := preprocessFilter(q.EdgeFilter)
+	if err != nil {
+		return err
+	}
+	_, key := x.ParseEdgeProperty(q.Attr)
+	for _, uid := range q.UidList.Uids {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		pl, err := qs.cache.Get(x.DataKey(q.Attr, uid))
+		if err != nil {
+			return err
+		}
+		dests := &pb.List{}
+		err = pl.Iterate(q.ReadTs, 0, func(p *pb.Posting) error {
+			fc, err := edgePropertyFacet(key, p)
+			if err != nil {
+				return err
+			}
+			picked, err := applyFacetsTree([]*api.Facet{fc}, ftree)
+			if err != nil {
+				return err
+			}
+			if picked {
+				dests.Uids = append(dests.Uids, p.Uid)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		out.UidMatrix = append(out.UidMatrix, dests)
+	}
+	return nil
+}
+
+// edgePropertyFacet returns the value of an edge property as the facet it was built from.
+func edgePropertyFacet(key string, p *pb.Posting) (*api.Facet, error) {
+	fc := &api.Facet{Key: key, Value: p.Value}
+	switch types.TypeID(p.ValType) {
+	case types.IntID:
+		fc.ValType = api.Facet_INT
+	case types.FloatID:
+		fc.ValType = api.Facet_FLOAT
+	case types.BoolID:
+		fc.ValType = api.Facet_BOOL
+	case types.DateTimeID:
+		fc.ValType = api.Facet_DATETIME
+	case types.StringID, types.DefaultID:
+		fc.ValType = api.Facet_STRING
+		tokens, err := tok.GetTermTokens([]string{string(p.Value)})
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(tokens)
+		fc.Tokens = tokens
+	default:
+		return nil, errors.Errorf("Unsupported type %s for edge property",
+			types.TypeID(p.ValType).Name())
+	}
+	return fc, nil
+}
+
 func (qs *queryState) handleHasFunction(ctx context.Context, q *pb.Query, out *pb.Result,
 	srcFn *functionContext) error {
 	span := otrace.FromContext(ctx)
@@ -2322,6 +2759,24 @@ func (qs *queryState) handleHasFunction(ctx context.Context, q *pb.Query, out *p
 	}
 
 	result := &pb.List{}
+	// When sampling, only a reservoir of q.Sample uids is kept out of the numSeen uids found.
+	var numSeen int
+	var rnd *rand.Rand
+	if q.Sample > 0 {
+		rnd = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	addUid := func(uid uint64) {
+		numSeen++
+		switch {
+		case rnd == nil || len(result.Uids) < int(q.Sample):
+			result.Uids = append(result.Uids, uid)
+		default:
+			if j := rnd.Intn(numSeen); j < int(q.Sample) {
+				result.Uids[j] = uid
+			}
+		}
+	}
+
 	var prevKey []byte
 	itOpt := badger.DefaultIteratorOptions
 	itOpt.PrefetchValues = false
@@ -2386,10 +2841,10 @@ loop:
 			case err != nil:
 				return err
 			}
-			result.Uids = append(result.Uids, pk.Uid)
+			addUid(pk.Uid)
 
 			// We'll stop fetching if we fetch the required count.
-			if len(result.Uids) >= int(q.First) {
+			if rnd == nil && len(result.Uids) >= int(q.First) {
 				break
 			}
 			continue
@@ -2412,15 +2867,15 @@ loop:
 			case err != nil:
 				return err
 			}
-			result.Uids = append(result.Uids, pk.Uid)
+			addUid(pk.Uid)
 
 			// We'll stop fetching if we fetch the required count.
-			if len(result.Uids) >= int(q.First) {
+			if rnd == nil && len(result.Uids) >= int(q.First) {
 				break loop
 			}
 		}
 
-		if len(result.Uids)%100000 == 0 {
+		if numSeen%100000 == 0 {
 			select {
 			case <-ctx.Done():
 				return ctx.Err()
@@ -2429,7 +2884,11 @@ loop:
 		}
 	}
 	if span != nil {
-		span.Annotatef(nil, "handleHasFunction found %d uids", len(result.Uids))
+		span.Annotatef(nil, "handleHasFunction found %d uids", numSeen)
+	}
+	if rnd != nil {
+		// The reservoir isn't in the order of the keys.
+		sort.Slice(result.Uids, func(i, j int) bool { return result.Uids[i] < result.Uids[j] })
 	}
 	out.UidMatrix = append(out.UidMatrix, result)
 	return nil
@@ -0,0 +1,592 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/minio/minio-go/v6"
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/badger/v2"
+	bpb "github.com/dgraph-io/badger/v2/pb"
+	"github.com/dgraph-io/dgraph/posting"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// A predicate that hasn't been read or written for --tier_after is offloaded to the cold storage
+// tier: the leader of its group uploads its data to --tier_location and the group then drops its
+// keys, keeping only its schema. The first read or write of a cold predicate makes the leader
+// fetch the data back. The tier of the predicate is kept in its schema, so that all the members
+// of the group go through the same steps, and the leader resumes them if it changes midway.
+
+// tierStore stores the objects holding the data of the cold predicates.
+type tierStore interface {
+	// upload uploads the file at path as the object with the given name.
+	upload(name, path string) error
+	// open returns a reader of the object with the given name.
+	open(name string) (io.ReadCloser, error)
+	// remove deletes the object with the given name.
+	remove(name string) error
+}
+
+// newTierStore returns the store at location, which is either a directory or an s3:// or
+// minio:// URI.
+func newTierStore(location string) (tierStore, error) {
+	uri, err := url.Parse(location)
+	if err != nil {
+		return nil, err
+	}
+	switch uri.Scheme {
+	case "", "file":
+		if err := os.MkdirAll(uri.Path, 0700); err != nil {
+			return nil, err
+		}
+		return &localTierStore{dir: uri.Path}, nil
+	case "s3", "minio":
+		mc, err := newMinioClient(uri, &Credentials{})
+		if err != nil {
+			return nil, err
+		}
+		bucket, prefix, err := validateBucket(mc, uri)
+		if err != nil {
+			return nil, err
+		}
+		return &remoteTierStore{mc: mc, bucket: bucket, prefix: prefix}, nil
+	default:
+		return nil, errors.Errorf("Unsupported tier location: %s", location)
+	}
+}
+
+// localTierStore stores the objects as files in a directory.
+type localTierStore struct {
+	dir string
+}
+
+func (l *localTierStore) upload(name, path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	// The object is renamed into place once it's complete, so that it's never read partially.
+	dst, err := ioutil.TempFile(l.dir, name+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(dst.Name())
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Sync(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	return os.Rename(dst.Name(), filepath.Join(l.dir, name))
+}
+
+func (l *localTierStore) open(name string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(l.dir, name))
+}
+
+func (l *localTierStore) remove(name string) error {
+	return os.Remove(filepath.Join(l.dir, name))
+}
+
+// remoteTierStore stores the objects in a bucket of an S3 compatible storage.
+type remoteTierStore struct {
+	mc     *minio.Client
+	bucket string
+	prefix string
+}
+
+func (r *remoteTierStore) object(name string) string {
+	if r.prefix == "" {
+		return name
+	}
+	return r.prefix + "/" + name
+}
+
+func (r *remoteTierStore) upload(name, path string) error {
+	_, err := r.mc.FPutObject(r.bucket, r.object(name), path, minio.PutObjectOptions{
+		ContentType: "application/gzip",
+	})
+	return err
+}
+
+func (r *remoteTierStore) open(name string) (io.ReadCloser, error) {
+	return r.mc.GetObject(r.bucket, r.object(name), minio.GetObjectOptions{})
+}
+
+func (r *remoteTierStore) remove(name string) error {
+	return r.mc.RemoveObject(r.bucket, r.object(name))
+}
+
+// writeTierList writes the list to w, prefixed with its size.
+func writeTierList(list *bpb.KVList, w io.Writer) error {
+	buf, err := list.Marshal()
+	if err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, uint64(len(buf))); err != nil {
+		return err
+	}
+	_, err = w.Write(buf)
+	return err
+}
+
+// readTierLists calls fn with each of the lists written to r by writeTierList.
+func readTierLists(r io.Reader, fn func(list *bpb.KVList) error) error {
+	var buf []byte
+	for {
+		var sz uint64
+		switch err := binary.Read(r, binary.LittleEndian, &sz); {
+		case err == io.EOF:
+			return nil
+		case err != nil:
+			return err
+		}
+		if cap(buf) < int(sz) {
+			buf = make([]byte, sz)
+		}
+		buf = buf[:sz]
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return err
+		}
+		list := &bpb.KVList{}
+		if err := list.Unmarshal(buf); err != nil {
+			return err
+		}
+		if err := fn(list); err != nil {
+			return err
+		}
+	}
+}
+
+// tierAccesses keeps track of when the predicates have been accessed last. The reads are only
+// seen by the member of the group serving them, so they're reported to the rest of the group
+// through proposals. This way the leader judges whether a predicate is idle on behalf of the
+// whole group.
+type tierAccesses struct {
+	sync.Mutex
+	last map[string]time.Time
+	// pending holds the predicates read by this node since it last reported them.
+	pending map[string]struct{}
+}
+
+var accesses = &tierAccesses{
+	last:    make(map[string]time.Time),
+	pending: make(map[string]struct{}),
+}
+
+// touch records that pred has been read by this node.
+func (a *tierAccesses) touch(pred string) {
+	a.Lock()
+	defer a.Unlock()
+	a.last[pred] = time.Now()
+	a.pending[pred] = struct{}{}
+}
+
+// record records that the given predicates have been accessed by some member of the group.
+func (a *tierAccesses) record(preds ...string) {
+	a.Lock()
+	defer a.Unlock()
+	now := time.Now()
+	for _, pred := range preds {
+		a.last[pred] = now
+	}
+}
+
+// drain returns the predicates read by this node since the last call.
+func (a *tierAccesses) drain() []string {
+	a.Lock()
+	defer a.Unlock()
+	preds := make([]string, 0, len(a.pending))
+	for pred := range a.pending {
+		preds = append(preds, pred)
+	}
+	a.pending = make(map[string]struct{})
+	return preds
+}
+
+// idle returns how long pred hasn't been accessed for. The predicates this node hasn't seen
+// yet are counted from now, so that nothing is offloaded right after a restart.
+func (a *tierAccesses) idle(pred string) time.Duration {
+	a.Lock()
+	defer a.Unlock()
+	last, ok := a.last[pred]
+	if !ok {
+		a.last[pred] = time.Now()
+		return 0
+	}
+	return time.Since(last)
+}
+
+// fetchTier makes sure that the data of attr is stored locally before it's read. If attr has
+// been offloaded, it asks the group to fetch it back and waits until that's done.
+func fetchTier(ctx context.Context, attr string) error {
+	if x.WorkerConfig.TierAfter > 0 {
+		accesses.touch(attr)
+	}
+	for {
+		switch schema.State().Tier(attr) {
+		case "", schema.TierOffloading:
+			return nil
+		case schema.TierCold:
+			m := &pb.Mutations{GroupId: groups().groupId(), TierPredicate: attr,
+				Tier: schema.TierFetching}
+			if err := groups().Node.proposeAndWait(ctx, &pb.Proposal{Mutations: m}); err != nil {
+				return err
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// checkTiers returns an error if any of the edges is for a predicate whose data isn't stored
+// locally. The predicates that have been offloaded start being fetched back, so that the
+// mutation succeeds once it's retried.
+func checkTiers(edges []*pb.DirectedEdge) error {
+	var last string
+	for _, edge := range edges {
+		if edge.Attr == last {
+			continue
+		}
+		last = edge.Attr
+		accesses.record(edge.Attr)
+
+		switch tier := schema.State().Tier(edge.Attr); tier {
+		case "":
+			continue
+		case schema.TierCold:
+			// All the members of the group apply the same mutations, so they all agree on it.
+			su, _ := schema.State().Get(context.Background(), edge.Attr)
+			su.Tier = schema.TierFetching
+			if err := updateSchema(&su); err != nil {
+				return err
+			}
+			return errors.Errorf("Predicate %s has been offloaded to the cold storage tier."+
+				" Please retry once it's fetched back", edge.Attr)
+		default:
+			return errors.Errorf("Predicate %s is being moved between storage tiers (%s)."+
+				" Please retry later", edge.Attr, tier)
+		}
+	}
+	return nil
+}
+
+// applyTier applies the proposal moving a predicate to another storage tier.
+func applyTier(ctx context.Context, m *pb.Mutations) error {
+	pred := m.TierPredicate
+	su, ok := schema.State().Get(ctx, pred)
+	if !ok {
+		return errors.Errorf("Predicate %s doesn't exist", pred)
+	}
+
+	switch m.Tier {
+	case schema.TierOffloading:
+		if len(su.Tier) > 0 {
+			return errors.Errorf("Predicate %s is already in the %s tier", pred, su.Tier)
+		}
+		for to, from := range schema.State().Renames() {
+			if pred == from || pred == to {
+				return errors.Errorf("Predicate %s is being renamed", pred)
+			}
+		}
+		for _, indexing := range schema.GetIndexingPredicates() {
+			if pred == indexing {
+				return errors.Errorf("Predicate %s is being indexed", pred)
+			}
+		}
+		// The data is uploaded as of the time the offloading starts, so pending transactions
+		// must be done before starting.
+		if err := detectPendingTxns(pred); err != nil {
+			return err
+		}
+		glog.Infof("Starting to offload predicate %s", pred)
+
+	case schema.TierCold:
+		if su.Tier != schema.TierOffloading {
+			return errors.Errorf("Predicate %s isn't being offloaded", pred)
+		}
+		su.TierObject = m.TierObject
+		if err := posting.DeletePredicateData(pred); err != nil {
+			return err
+		}
+		posting.ResetCache()
+		glog.Infof("Offloaded predicate %s to object %s", pred, su.TierObject)
+
+	case schema.TierFetching:
+		if su.Tier != schema.TierCold {
+			// The predicate is already being fetched, or it has been fetched already.
+			return nil
+		}
+		glog.Infof("Starting to fetch predicate %s from object %s", pred, su.TierObject)
+
+	case "":
+		if len(su.Tier) == 0 {
+			return nil
+		}
+		su.TierObject = ""
+		accesses.record(pred)
+		glog.Infof("Predicate %s is stored locally again", pred)
+
+	default:
+		return errors.Errorf("Invalid tier %q for predicate %s", m.Tier, pred)
+	}
+
+	su.Tier = m.Tier
+	return updateSchema(&su)
+}
+
+// clearTiers moves all the predicates back to the local tier, without fetching their data. It's
+// used once all the data has been dropped.
+func clearTiers() error {
+	for pred := range schema.State().Tiers() {
+		su, ok := schema.State().Get(context.Background(), pred)
+		if !ok {
+			continue
+		}
+		if len(su.TierObject) > 0 {
+			glog.Infof("Predicate %s has been dropped. Object %s is no longer used",
+				pred, su.TierObject)
+		}
+		su.Tier, su.TierObject = "", ""
+		if err := updateSchema(&su); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// processTiering offloads the idle predicates of this group and fetches back the ones that are
+// accessed again, when this node is the leader. The other members report the predicates they
+// read instead.
+func (n *node) processTiering() {
+	defer n.closer.Done() // CLOSER:1
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-n.closer.HasBeenClosed()
+		cancel()
+	}()
+
+	reported := time.Now()
+	for {
+		select {
+		case <-n.closer.HasBeenClosed():
+			return
+		case <-tick.C:
+			tiers := schema.State().Tiers()
+			if x.WorkerConfig.TierAfter == 0 && len(tiers) == 0 {
+				continue
+			}
+			if n.AmLeader() {
+				// The reads of the leader are already known to it.
+				accesses.drain()
+				if err := n.processTiers(ctx, tiers); err != nil {
+					glog.Errorf("Error while moving predicates between storage tiers: %v."+
+						" Retrying...", err)
+				}
+				continue
+			}
+			if time.Since(reported) < x.WorkerConfig.TierAfter/4 {
+				continue
+			}
+			reported = time.Now()
+			preds := accesses.drain()
+			if len(preds) == 0 {
+				continue
+			}
+			if err := n.proposeAndWait(ctx, &pb.Proposal{TierAccessed: preds}); err != nil {
+				glog.Errorf("Error while reporting accessed predicates: %v", err)
+			}
+		}
+	}
+}
+
+// processTiers runs the offloading and fetching of the given predicates, and starts offloading
+// the next idle predicate, if any.
+func (n *node) processTiers(ctx context.Context, tiers map[string]string) error {
+	for pred, tier := range tiers {
+		if tier != schema.TierOffloading && tier != schema.TierFetching {
+			continue
+		}
+		store, err := newTierStore(x.WorkerConfig.TierLocation)
+		if err != nil {
+			return err
+		}
+		if tier == schema.TierOffloading {
+			err = n.offloadPredicate(ctx, store, pred)
+		} else {
+			err = n.fetchPredicate(ctx, store, pred)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "while moving predicate %s", pred)
+		}
+	}
+
+	if x.WorkerConfig.TierAfter == 0 {
+		return nil
+	}
+	for _, pred := range schema.State().Predicates() {
+		if x.IsReservedPredicate(pred) || len(schema.State().Tier(pred)) > 0 {
+			continue
+		}
+		if gid, err := groups().BelongsToReadOnly(pred, 0); err != nil || gid != n.gid {
+			continue
+		}
+		if accesses.idle(pred) < x.WorkerConfig.TierAfter {
+			continue
+		}
+		// The predicate is offloaded on the next tick, once all the members agree on it.
+		m := &pb.Mutations{GroupId: n.gid, TierPredicate: pred, Tier: schema.TierOffloading}
+		return n.proposeAndWait(ctx, &pb.Proposal{Mutations: m})
+	}
+	return nil
+}
+
+// offloadPredicate uploads the data of pred to the store, and then drops it from the group.
+func (n *node) offloadPredicate(ctx context.Context, store tierStore, pred string) error {
+	// The mutations of pred are rejected while it's being offloaded, so everything committed
+	// to it is visible at this timestamp.
+	readTs := posting.Oracle().MaxAssigned()
+	glog.Infof("Offloading predicate %s at ts: %d", pred, readTs)
+
+	tmp, err := ioutil.TempFile("", "tier")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	gw := gzip.NewWriter(tmp)
+
+	var count int
+	stream := pstore.NewStreamAt(readTs)
+	stream.LogPrefix = fmt.Sprintf("Offloading predicate: [%s]", pred)
+	stream.Prefix = x.PredicatePrefix(pred)
+	stream.ChooseKey = func(item *badger.Item) bool {
+		// The parts of a multi-part list are read from the main key.
+		pk, err := x.Parse(item.Key())
+		return err == nil && !pk.HasStartUid
+	}
+	stream.KeyToList = func(key []byte, itr *badger.Iterator) (*bpb.KVList, error) {
+		l, err := posting.ReadPostingList(key, itr)
+		if err != nil {
+			return nil, err
+		}
+		kvs, err := l.Rollup(stream.Allocator(itr.ThreadId))
+		if err != nil {
+			return nil, err
+		}
+		return &bpb.KVList{Kv: kvs}, nil
+	}
+	stream.Send = func(list *bpb.KVList) error {
+		count += len(list.Kv)
+		return writeTierList(list, gw)
+	}
+	if err := stream.Orchestrate(ctx); err != nil {
+		return err
+	}
+	if err := gw.Close(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("g%02d-%x-%d.tier", n.gid, pred, readTs)
+	if err := store.upload(name, tmp.Name()); err != nil {
+		return err
+	}
+	m := &pb.Mutations{GroupId: n.gid, TierPredicate: pred, Tier: schema.TierCold,
+		TierObject: name}
+	if err := n.proposeAndWait(ctx, &pb.Proposal{Mutations: m}); err != nil {
+		return err
+	}
+	glog.Infof("Done offloading predicate %s. Uploaded %d keys", pred, count)
+	return nil
+}
+
+// fetchPredicate writes the data of pred back to the group from its object in the store. The
+// keys are proposed in batches, so that all the members of the group write them.
+func (n *node) fetchPredicate(ctx context.Context, store tierStore, pred string) error {
+	su, ok := schema.State().Get(ctx, pred)
+	if !ok || su.Tier != schema.TierFetching {
+		return errors.Errorf("Predicate %s is no longer being fetched", pred)
+	}
+	glog.Infof("Fetching predicate %s from object %s", pred, su.TierObject)
+
+	rc, err := store.open(su.TierObject)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	gr, err := gzip.NewReader(rc)
+	if err != nil {
+		return err
+	}
+
+	var count int
+	err = readTierLists(gr, func(list *bpb.KVList) error {
+		if err := n.proposeAndWait(ctx, &pb.Proposal{Kv: list.Kv}); err != nil {
+			return err
+		}
+		count += len(list.Kv)
+		glog.Infof("Fetching predicate %s: copied %d keys", pred, count)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	m := &pb.Mutations{GroupId: n.gid, TierPredicate: pred}
+	if err := n.proposeAndWait(ctx, &pb.Proposal{Mutations: m}); err != nil {
+		return err
+	}
+	// The data is offloaded to a new object the next time, so this one is no longer needed.
+	if err := store.remove(su.TierObject); err != nil {
+		glog.Warningf("Error while removing object %s: %v", su.TierObject, err)
+	}
+	glog.Infof("Done fetching predicate %s. Copied %d keys", pred, count)
+	return nil
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	bpb "github.com/dgraph-io/badger/v2/pb"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dgraph-io/dgraph/x"
+)
+
+func TestTierObjectRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tier")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	store, err := newTierStore(filepath.Join(dir, "store"))
+	require.NoError(t, err)
+
+	lists := []*bpb.KVList{
+		{Kv: []*bpb.KV{
+			{Key: x.DataKey("name", 1), Value: []byte("alice"), Version: 5},
+			{Key: x.DataKey("name", 2), Value: []byte("bob"), Version: 7},
+		}},
+		{Kv: []*bpb.KV{{Key: x.IndexKey("name", "alice"), Value: []byte{1}, Version: 5}}},
+	}
+	f, err := ioutil.TempFile(dir, "object")
+	require.NoError(t, err)
+	gw := gzip.NewWriter(f)
+	for _, list := range lists {
+		require.NoError(t, writeTierList(list, gw))
+	}
+	require.NoError(t, gw.Close())
+	require.NoError(t, f.Close())
+	require.NoError(t, store.upload("g01-name-7.tier", f.Name()))
+
+	rc, err := store.open("g01-name-7.tier")
+	require.NoError(t, err)
+	gr, err := gzip.NewReader(rc)
+	require.NoError(t, err)
+	var read []*bpb.KVList
+	require.NoError(t, readTierLists(gr, func(list *bpb.KVList) error {
+		read = append(read, list)
+		return nil
+	}))
+	require.NoError(t, rc.Close())
+	require.Len(t, read, len(lists))
+	for i := range lists {
+		require.Equal(t, lists[i].Kv, read[i].Kv)
+	}
+
+	require.NoError(t, store.remove("g01-name-7.tier"))
+	_, err = store.open("g01-name-7.tier")
+	require.Error(t, err)
+
+	_, err = newTierStore("gs://bucket/tier")
+	require.Error(t, err)
+}
+
+func TestTierAccesses(t *testing.T) {
+	a := &tierAccesses{
+		last:    make(map[string]time.Time),
+		pending: make(map[string]struct{}),
+	}
+	// The predicates that haven't been seen yet aren't idle.
+	require.Equal(t, time.Duration(0), a.idle("name"))
+
+	a.last["name"] = time.Now().Add(-time.Hour)
+	require.True(t, a.idle("name") >= time.Hour)
+
+	// The reported accesses of other members count, but only the local reads are reported.
+	a.record("name")
+	require.True(t, a.idle("name") < time.Minute)
+	require.Empty(t, a.drain())
+
+	a.touch("age")
+	a.touch("friend")
+	preds := a.drain()
+	sort.Strings(preds)
+	require.Equal(t, []string{"age", "friend"}, preds)
+	require.Empty(t, a.drain())
+}
@@ -36,6 +36,11 @@ func verifyStringIndex(ctx context.Context, attr string, funcType FuncType) (str
 		requiredTokenizer = tok.FullTextTokenizer{}
 	case matchFn:
 		requiredTokenizer = tok.TrigramTokenizer{}
+		// Fuzzy matching can also walk the tokens of an exact or term index.
+		if schema.State().HasTokenizer(ctx, tok.IdentExact, attr) ||
+			schema.State().HasTokenizer(ctx, tok.IdentTerm, attr) {
+			return requiredTokenizer.Name(), true
+		}
 	default:
 		requiredTokenizer = tok.TermTokenizer{}
 	}
@@ -53,28 +58,90 @@ func verifyStringIndex(ctx context.Context, attr string, funcType FuncType) (str
 	return requiredTokenizer.Name(), false
 }
 
-func verifyCustomIndex(ctx context.Context, attr string, tokenizerName string) bool {
+// matchTokenizers are the built-in tokenizers that are queried with the anyof and allof
+// functions, by their names without options.
+var matchTokenizers = map[byte]string{
+	tok.IdentNgram:     tok.NgramTokenizer{}.Name(),
+	tok.IdentEdgeNgram: tok.EdgeNgramTokenizer{}.Name(),
+	tok.IdentSoundex:   tok.SoundexTokenizer{}.Name(),
+	tok.IdentMetaphone: tok.MetaphoneTokenizer{}.Name(),
+}
+
+// customIndexTokenizer returns the tokenizer of attr with the given name that is used by the
+// anyof and allof functions. Those work with custom tokenizers and with the matchTokenizers,
+// which can be named without their options, like ngram for ngram(min: 2, max: 4).
+func customIndexTokenizer(ctx context.Context, attr string, tokenizerName string) (
+	tok.Tokenizer, bool) {
+	if !schema.State().IsIndexed(ctx, attr) {
+		return nil, false
+	}
+	for _, t := range schema.State().Tokenizer(ctx, attr) {
+		switch name, ok := matchTokenizers[t.Identifier()]; {
+		case t.Identifier() >= tok.IdentCustom && t.Name() == tokenizerName:
+			return t, true
+		case ok && (t.Name() == tokenizerName || tokenizerName == name):
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// vectorIndexTokenizer returns the hnsw tokenizer of the attribute, if it has one.
+func vectorIndexTokenizer(ctx context.Context, attr string) (tok.HNSWTokenizer, bool) {
 	if !schema.State().IsIndexed(ctx, attr) {
-		return false
+		return tok.HNSWTokenizer{}, false
 	}
 	for _, t := range schema.State().Tokenizer(ctx, attr) {
-		if t.Identifier() >= tok.IdentCustom && t.Name() == tokenizerName {
-			return true
+		if vt, ok := t.(tok.HNSWTokenizer); ok {
+			return vt, true
 		}
 	}
-	return false
+	return tok.HNSWTokenizer{}, false
 }
 
-// Return string tokens from function arguments. It maps function type to correct tokenizer.
+// geoIndexTokenizer returns the geo tokenizer of the attribute, configured with the cell levels
+// the values were indexed with. It returns the geo tokenizer without options if attr doesn't
+// have one.
+func geoIndexTokenizer(ctx context.Context, attr string) tok.GeoTokenizer {
+	for _, t := range schema.State().Tokenizer(ctx, attr) {
+		if gt, ok := t.(tok.GeoTokenizer); ok {
+			return gt
+		}
+	}
+	return tok.GeoTokenizer{}
+}
+
+// stringIndexTokenizer returns the tokenizer of attr that the string function of the given type
+// is answered with, configured with the options the values were indexed with: the fulltext one
+// for the fulltext functions and the term one for the others. It returns the tokenizer without
+// options if attr doesn't have it.
+func stringIndexTokenizer(ctx context.Context, attr string, funcType FuncType) tok.Tokenizer {
+	var required tok.Tokenizer = tok.TermTokenizer{}
+	if funcType == fullTextSearchFn {
+		required = tok.FullTextTokenizer{}
+	}
+	for _, t := range schema.State().Tokenizer(ctx, attr) {
+		if t.Identifier() == required.Identifier() {
+			return t
+		}
+	}
+	return required
+}
+
+// Return string tokens from function arguments, built with the tokenizer t of the predicate. The
+// fulltext tokenizer uses the language of the function.
 // Note: regexp functions require regexp compilation of argument, not tokenization.
-func getStringTokens(funcArgs []string, lang string, funcType FuncType) ([]string, error) {
+func getStringTokens(funcArgs []string, lang string, t tok.Tokenizer) ([]string, error) {
+	if l := len(funcArgs); l != 1 {
+		return nil, errors.Errorf("Function requires 1 arguments, but got %d", l)
+	}
 	if lang == "." {
 		lang = "en"
 	}
-	if funcType == fullTextSearchFn {
-		return tok.GetFullTextTokens(funcArgs, lang)
+	if t.Identifier() == tok.IdentFullText {
+		t = tok.GetTokenizerForLang(t, lang)
 	}
-	return tok.GetTermTokens(funcArgs)
+	return tok.BuildTokens(funcArgs[0], t)
 }
 
 func pickTokenizer(ctx context.Context, attr string, f string) (tok.Tokenizer, error) {
@@ -112,8 +179,10 @@ func pickTokenizer(ctx context.Context, attr string, f string) (tok.Tokenizer, e
 // getInequalityTokens gets tokens ge/le/between compared to given tokens using the first sortable
 // index that is found for the predicate.
 // In case of ge/gt/le/lt/eq len(ineqValues) should be 1, else(between) len(ineqValues) should be 2.
+// For a composite index, leading holds the values the tokens must have for the predicates before
+// the last one.
 func getInequalityTokens(ctx context.Context, readTs uint64, attr, f, lang string,
-	ineqValues []types.Val) ([]string, []string, error) {
+	leading []string, ineqValues []types.Val) ([]string, []string, error) {
 
 	tokenizer, err := pickTokenizer(ctx, attr, f)
 	if err != nil {
@@ -130,6 +199,11 @@ func getInequalityTokens(ctx context.Context, readTs uint64, attr, f, lang strin
 		if err != nil {
 			return nil, nil, err
 		}
+		if leading != nil {
+			for i := range ineqTokens {
+				ineqTokens[i] = tok.CompositeToken(leading, ineqTokens[i])
+			}
+		}
 
 		switch {
 		case len(ineqTokens) == 0:
@@ -164,7 +238,8 @@ func getInequalityTokens(ctx context.Context, readTs uint64, attr, f, lang strin
 	itOpt := badger.DefaultIteratorOptions
 	itOpt.PrefetchValues = false
 	itOpt.Reverse = !isgeOrGt
-	itOpt.Prefix = x.IndexKey(attr, string(tokenizer.Identifier()))
+	// The tokens of a composite index with other leading values are left out.
+	itOpt.Prefix = x.IndexKey(attr, tok.CompositeToken(leading, string(tokenizer.Identifier())))
 	itr := txn.NewIterator(itOpt)
 	defer itr.Close()
 
@@ -0,0 +1,51 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"github.com/dgraph-io/dgraph/protos/pb"
+)
+
+// TxnListener is notified of the changes to the state of the transactions applied by this
+// Alpha. Savepoints and the status of transactions are applied by all the groups, so the
+// listener is notified whichever Alpha the request was sent to.
+type TxnListener interface {
+	// Savepoint is called once a savepoint has been created in the transaction.
+	Savepoint(startTs uint64, name string)
+	// RollbackTo is called once the transaction has been rolled back to a savepoint.
+	RollbackTo(startTs uint64, name string)
+	// Finished is called once the transaction has been committed, or aborted, in which case
+	// commitTs is zero.
+	Finished(startTs, commitTs uint64)
+}
+
+// txnListener is set before the Alpha starts serving requests, so it isn't guarded.
+var txnListener TxnListener
+
+// SetTxnListener sets the listener notified of the changes to the state of transactions.
+func SetTxnListener(l TxnListener) {
+	txnListener = l
+}
+
+func notifyTxnStatus(txns []*pb.TxnStatus) {
+	if txnListener == nil {
+		return
+	}
+	for _, status := range txns {
+		txnListener.Finished(status.StartTs, status.CommitTs)
+	}
+}
@@ -0,0 +1,95 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"context"
+	"sort"
+
+	"github.com/dgraph-io/dgraph/posting"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/x"
+	otrace "go.opencensus.io/trace"
+)
+
+// handleSimilarToFunction finds the k nodes whose vectors of q.Attr are the closest to the query
+// vector of similar_to. At the root of a query, they're searched in the hnsw index of the
+// predicate. In a filter, the vectors of the uids in q.UidList are compared with the query
+// vector instead, so that the k closest of those nodes are kept.
+func (qs *queryState) handleSimilarToFunction(ctx context.Context, q *pb.Query, out *pb.Result,
+	srcFn *functionContext) error {
+	span := otrace.FromContext(ctx)
+	stop := x.SpanTimer(span, "handleSimilarToFunction")
+	defer stop()
+
+	k := int(srcFn.threshold[0])
+	if q.UidList == nil {
+		uids, err := posting.SimilarTo(ctx, qs.cache, q.Attr, q.ReadTs, srcFn.vectorIndex,
+			srcFn.vector, k)
+		if err != nil {
+			return err
+		}
+		out.UidMatrix = append(out.UidMatrix, &pb.List{Uids: uids})
+		return nil
+	}
+
+	type scored struct {
+		uid  uint64
+		dist float64
+	}
+	metric := srcFn.vectorIndex.Metric()
+	var nodes []scored
+	for i, uid := range q.UidList.Uids {
+		if i%100 == 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
+			}
+		}
+		pl, err := qs.cache.Get(x.DataKey(q.Attr, uid))
+		if err != nil {
+			return err
+		}
+		val, err := pl.Value(q.ReadTs)
+		switch {
+		case err == posting.ErrNoValue:
+			continue
+		case err != nil:
+			return err
+		}
+		vec, err := types.Convert(val, types.VFloatID)
+		if err != nil {
+			return err
+		}
+		if v := vec.Value.([]float32); len(v) == len(srcFn.vector) {
+			nodes = append(nodes, scored{uid: uid, dist: metric(v, srcFn.vector)})
+		}
+	}
+	sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].dist < nodes[j].dist })
+	if len(nodes) > k {
+		nodes = nodes[:k]
+	}
+	result := &pb.List{}
+	for _, n := range nodes {
+		result.Uids = append(result.Uids, n.uid)
+	}
+	sort.Slice(result.Uids, func(i, j int) bool { return result.Uids[i] < result.Uids[j] })
+	out.UidMatrix = append(out.UidMatrix, result)
+	return nil
+}
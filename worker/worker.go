@@ -112,6 +112,12 @@ func StoreStats() string {
 func BlockingStop() {
 	glog.Infof("Stopping group...")
 	groups().closer.SignalAndWait()
+	if groups().Node == nil {
+		// Serving read-only, there's no Raft node.
+		glog.Infof("Stopping worker server...")
+		workerServer.Stop()
+		return
+	}
 
 	// Update checkpoint so that proposals are not replayed after the server restarts.
 	glog.Infof("Updating RAFT state before shutting down...")
@@ -148,6 +154,65 @@ func UpdateCacheMb(memoryMB int64) error {
 	if _, err := pstore.CacheMaxCost(badger.IndexCache, indexCacheSize); err != nil {
 		return errors.Wrapf(err, "cannot update index cache size")
 	}
+	Config.CacheMb = memoryMB
+	return nil
+}
+
+// CacheSizes holds the sizes, in bytes, of the caches of the postings.
+type CacheSizes struct {
+	PostingList int64
+	Block       int64
+	Index       int64
+}
+
+// CurrentCacheSizes returns the sizes the caches of the postings are currently limited to. The
+// disabled caches have a size of zero.
+func CurrentCacheSizes() CacheSizes {
+	pl, _ := posting.MaxCost()
+	block, _ := pstore.CacheMaxCost(badger.BlockCache, -1)
+	index, _ := pstore.CacheMaxCost(badger.IndexCache, -1)
+	return CacheSizes{PostingList: pl, Block: block, Index: index}
+}
+
+// UpdateCacheSizes resizes the caches of the postings, regardless of cache_percentage. The caches
+// with a size of zero are left as they are. No cache is resized if any of them is disabled, as
+// the caches can only be enabled when the alpha starts.
+func UpdateCacheSizes(sizes CacheSizes) error {
+	_, plEnabled := posting.MaxCost()
+	opts := pstore.Opts()
+	caches := []struct {
+		name    string
+		size    int64
+		enabled bool
+	}{
+		{"posting list", sizes.PostingList, plEnabled},
+		{"block", sizes.Block, opts.BlockCacheSize > 0},
+		{"index", sizes.Index, opts.IndexCacheSize > 0},
+	}
+	for _, c := range caches {
+		switch {
+		case c.size < 0:
+			return errors.Errorf("The size of the %s cache must be non-negative.", c.name)
+		case c.size > 0 && !c.enabled:
+			return errors.Errorf("The %s cache is disabled. It can only be enabled by"+
+				" restarting the alpha with cache_mb and cache_percentage.", c.name)
+		}
+	}
+
+	glog.Infof("Updating the cache sizes to %+v", sizes)
+	if sizes.PostingList > 0 {
+		posting.UpdateMaxCost(sizes.PostingList)
+	}
+	if sizes.Block > 0 {
+		if _, err := pstore.CacheMaxCost(badger.BlockCache, sizes.Block); err != nil {
+			return errors.Wrapf(err, "cannot update block cache size")
+		}
+	}
+	if sizes.Index > 0 {
+		if _, err := pstore.CacheMaxCost(badger.IndexCache, sizes.Index); err != nil {
+			return errors.Wrapf(err, "cannot update index cache size")
+		}
+	}
 	return nil
 }
 
@@ -32,6 +32,7 @@ import (
 	"github.com/dgraph-io/dgo/v200"
 	"github.com/dgraph-io/dgo/v200/protos/api"
 
+	"github.com/dgraph-io/dgraph/algo"
 	"github.com/dgraph-io/dgraph/posting"
 	"github.com/dgraph-io/dgraph/protos/pb"
 	"github.com/dgraph-io/dgraph/schema"
@@ -391,3 +392,147 @@ func TestMain(m *testing.M) {
 
 	os.Exit(m.Run())
 }
+
+func TestProcessTaskDistinctCount(t *testing.T) {
+	attr := "distinct_color"
+	require.NoError(t, schema.ParseBytes([]byte(attr+": string @index(hash) ."), 1))
+	for uid, val := range map[uint64]string{1: "red", 2: "blue", 3: "red", 4: "green"} {
+		edge := &pb.DirectedEdge{Value: []byte(val), Attr: attr, Entity: uid}
+		addEdge(t, edge, getOrCreate(x.DataKey(attr, uid)))
+	}
+
+	readTs := timestamp()
+	qs := queryState{cache: posting.NoCache(readTs)}
+	q := &pb.Query{
+		Attr:          attr,
+		ReadTs:        readTs,
+		UidList:       &pb.List{Uids: []uint64{1, 2, 3, 5}},
+		DistinctCount: true,
+	}
+	out, err := qs.helpProcessTask(context.Background(), q, 1)
+	require.NoError(t, err)
+	// The uids 1 and 3 share a value, 4 isn't in the query and 5 has no value.
+	require.Len(t, out.DistinctBuckets, 2)
+	require.Len(t, out.UidMatrix, 4)
+	require.Len(t, out.ValueMatrix, 4)
+
+	// Without the flag, the values are fetched as usual.
+	q.DistinctCount = false
+	out, err = qs.helpProcessTask(context.Background(), q, 1)
+	require.NoError(t, err)
+	require.Empty(t, out.DistinctBuckets)
+}
+
+func TestProcessTaskSample(t *testing.T) {
+	attr := "sample_name"
+	require.NoError(t, schema.ParseBytes([]byte(attr+": string ."), 1))
+	for uid := uint64(1); uid <= 20; uid++ {
+		edge := &pb.DirectedEdge{Value: []byte(fmt.Sprintf("n%d", uid)), Attr: attr, Entity: uid}
+		addEdge(t, edge, getOrCreate(x.DataKey(attr, uid)))
+	}
+
+	readTs := timestamp()
+	qs := queryState{cache: posting.NoCache(readTs)}
+	q := &pb.Query{
+		Attr:    attr,
+		ReadTs:  readTs,
+		SrcFunc: &pb.SrcFunction{Name: "has"},
+		First:   math.MaxInt32,
+		Sample:  5,
+	}
+	out, err := qs.helpProcessTask(context.Background(), q, 1)
+	require.NoError(t, err)
+	// Only the reservoir is returned, in the order of the uids.
+	require.Len(t, out.UidMatrix, 1)
+	uids := out.UidMatrix[0].Uids
+	require.Len(t, uids, 5)
+	for i, uid := range uids {
+		require.True(t, uid >= 1 && uid <= 20)
+		if i > 0 {
+			require.Less(t, uids[i-1], uid)
+		}
+	}
+
+	q.Sample = 0
+	out, err = qs.helpProcessTask(context.Background(), q, 1)
+	require.NoError(t, err)
+	require.Len(t, out.UidMatrix[0].Uids, 20)
+}
+
+func TestProcessTaskEdgeFilter(t *testing.T) {
+	attr := x.EdgeProperty("follows", "weight")
+	require.NoError(t, schema.ParseBytes([]byte("<"+attr+">: string ."), 1))
+	for _, e := range []struct {
+		src, dst uint64
+		val      string
+	}{{1, 2, "close friend"}, {1, 3, "old friend"}, {1, 4, "close colleague"}, {2, 3, "close"}} {
+		edge := &pb.DirectedEdge{Value: []byte(e.val), Attr: attr, Entity: e.src,
+			ValueId: e.dst, ValueType: pb.Posting_STRING}
+		addEdge(t, edge, getOrCreate(x.DataKey(attr, e.src)))
+	}
+
+	readTs := timestamp()
+	qs := queryState{cache: posting.NoCache(readTs)}
+	q := &pb.Query{
+		Attr:    attr,
+		ReadTs:  readTs,
+		UidList: &pb.List{Uids: []uint64{1, 2, 5}},
+		EdgeFilter: &pb.FilterTree{Func: &pb.Function{
+			Name: "anyofterms", Key: "weight", Args: []string{"close"}}},
+	}
+	out, err := qs.helpProcessTask(context.Background(), q, 1)
+	require.NoError(t, err)
+	// The destinations of the matching edges are returned for each node.
+	require.Equal(t, [][]uint64{{2, 4}, {3}, nil}, algo.ToUintsListForTest(out.UidMatrix))
+
+	q.EdgeFilter.Func = &pb.Function{Name: "eq", Key: "weight", Args: []string{"old friend"}}
+	out, err = qs.helpProcessTask(context.Background(), q, 1)
+	require.NoError(t, err)
+	require.Equal(t, [][]uint64{{3}, nil, nil}, algo.ToUintsListForTest(out.UidMatrix))
+}
+
+func TestStrictSchemaEnabled(t *testing.T) {
+	require.False(t, StrictSchemaEnabled())
+
+	gr.Lock()
+	oldState := gr.state
+	gr.state = &pb.MembershipState{StrictSchema: &pb.StrictSchema{Enabled: true}}
+	gr.Unlock()
+	defer func() {
+		gr.Lock()
+		gr.state = oldState
+		gr.Unlock()
+	}()
+
+	// The mode is the one of the cluster, streamed by Zero.
+	require.True(t, StrictSchemaEnabled())
+}
+
+func TestReplicaReadTs(t *testing.T) {
+	require.False(t, IsReplica())
+	_, ok := ReplicaReadTs()
+	require.False(t, ok)
+
+	gr.Lock()
+	oldState := gr.state
+	gr.state = &pb.MembershipState{Replication: &pb.ReplicationState{Primary: "zero:5080"}}
+	gr.Unlock()
+	defer func() {
+		gr.Lock()
+		gr.state = oldState
+		gr.Unlock()
+	}()
+
+	// The queries of a replica get timestamps from Zero until the first snapshot is written.
+	require.True(t, IsReplica())
+	_, ok = ReplicaReadTs()
+	require.False(t, ok)
+
+	gr.Lock()
+	gr.state.Replication.ReadTs = 42
+	gr.Unlock()
+	ts, ok := ReplicaReadTs()
+	require.True(t, ok)
+	require.Equal(t, uint64(42), ts)
+	require.Equal(t, "zero:5080", ReplicationStatus().Primary)
+}
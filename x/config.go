@@ -32,9 +32,40 @@ type Options struct {
 	QueryEdgeLimit uint64
 	// NormalizeNodeLimit is the maximum number of nodes allowed in a normalize query.
 	NormalizeNodeLimit int
+	// QueryTimeoutLimit is the maximum execution timeout a query can ask for. Zero means
+	// that queries are not bounded by a server-side timeout.
+	QueryTimeoutLimit time.Duration
+	// QueryNodeLimit is the maximum number of nodes and edges a query can return. Zero
+	// means that there is no limit.
+	QueryNodeLimit uint64
+	// QueryDepthLimit is the maximum traversal depth of a query. Zero means that there is
+	// no limit.
+	QueryDepthLimit uint64
+	// QueryCostBudget is the maximum estimated cost of a query. Zero means that the cost of
+	// queries is not checked.
+	QueryCostBudget uint64
+	// QueryCostQueue is the number of queries over the cost budget that can run concurrently.
+	// The rest of them wait for their turn. Zero means that such queries are rejected.
+	QueryCostQueue int
+	// QueryMemoryLimit is the memory, in bytes, the intermediate results of all the queries
+	// being run can take. Zero means that there is no limit.
+	QueryMemoryLimit int64
+	// QuerySpillDir is the directory the sorts going over QueryMemoryLimit spill to. The
+	// default directory for temporary files is used if it's empty.
+	QuerySpillDir string
 	// MutationsNQuadLimit is maximum number of nquads that can be present in a single
 	// mutation request.
 	MutationsNQuadLimit int
+	// IdempotencyWindow is how long the outcome of a commit made with an idempotency key is
+	// kept, to be returned to the retries of the same commit. Zero disables idempotency keys.
+	IdempotencyWindow time.Duration
+	// TTLInterval is how often the data of the predicates and types with @ttl is checked for
+	// expired values. Zero disables the expiry.
+	TTLInterval time.Duration
+	// TTLBatchSize is the number of nodes whose expired data is deleted per transaction.
+	TTLBatchSize int
+	// TTLBatchDelay is the pause between two batches of deletes of expired data.
+	TTLBatchDelay time.Duration
 	// PollInterval is the polling interval for graphql subscription.
 	PollInterval time.Duration
 	// GraphqlExtension will be set to see extensions in graphql results
@@ -43,6 +74,24 @@ type Options struct {
 	GraphqlDebug bool
 	// GraphqlLambdaUrl stores the URL of lambda functions for custom GraphQL resolvers
 	GraphqlLambdaUrl string
+	// GraphqlLambdaScript is the JavaScript file of the lambda functions run inside Alpha. It
+	// can't be set along with GraphqlLambdaUrl.
+	GraphqlLambdaScript string
+	// GraphqlPersistedQueryCacheSize is the number of persisted queries each Alpha keeps in
+	// memory, in front of the ones stored in Dgraph.
+	GraphqlPersistedQueryCacheSize int
+	// GraphqlPersistedQueriesOnly makes /graphql run only the persisted queries that have been
+	// added through /admin.
+	GraphqlPersistedQueriesOnly bool
+	// GraphqlMaxComplexity is the maximum complexity of a GraphQL request. Zero means that the
+	// complexity of requests is not checked.
+	GraphqlMaxComplexity int64
+	// GraphqlComplexityListMultiplier multiplies the complexity of the selection set of a list
+	// field that has no `first` argument.
+	GraphqlComplexityListMultiplier int64
+	// GraphqlErrorRedaction is the initial policy for redacting the messages of errors in
+	// GraphQL responses, NONE or INTERNAL.
+	GraphqlErrorRedaction string
 }
 
 // Config stores the global instance of this package's options.
@@ -83,6 +132,15 @@ type WorkerOptions struct {
 	AclEnabled bool
 	// AbortOlderThan tells Dgraph to discard transactions that are older than this duration.
 	AbortOlderThan time.Duration
+	// HistoryRetention is how long the versions of the data are kept after they're
+	// overwritten, so that read-only queries can read the data as of a past timestamp.
+	HistoryRetention time.Duration
+	// TierLocation is where the data of the predicates offloaded to the cold storage tier is
+	// stored, either a directory or an s3:// or minio:// URI.
+	TierLocation string
+	// TierAfter is how long a predicate must not be read or written before its data is
+	// offloaded to the cold storage tier. Zero disables offloading.
+	TierAfter time.Duration
 	// SnapshotAfter indicates the number of entries in the RAFT logs that are needed
 	// to allow a snapshot to be created.
 	SnapshotAfter int
@@ -104,6 +162,27 @@ type WorkerOptions struct {
 	LogRequest int32
 	// If true, we should call msync or fsync after every write to survive hard reboots.
 	HardSync bool
+	// WALSegmentSize is the size of the data a file of the Raft WAL can hold before it's
+	// rotated. Zero means that files are only rotated once they hold the maximum number of
+	// entries.
+	WALSegmentSize int64
+	// WALSegmentAge is the age a file of the Raft WAL can reach before it's rotated. Zero means
+	// that files aren't rotated for their age.
+	WALSegmentAge time.Duration
+	// Learner makes this Alpha join its group as a Raft learner, which replicates the data of the
+	// group and serves queries, but doesn't vote.
+	Learner bool
+	// LearnerGroup is the group a learner joins. Zero picks one if it's zero.
+	LearnerGroup uint32
+	// LearnerMaxStaleness is how far behind the leader of its group a learner can be for its
+	// read-only queries to read its data without a timestamp from Zero. Zero disables it.
+	LearnerMaxStaleness time.Duration
+	// ReplicationInterval is how often a replica cluster replicates the data committed in its
+	// primary cluster since the last time.
+	ReplicationInterval time.Duration
+	// UidBitmaps makes the posting lists store their dense blocks of uids as bitmaps, which
+	// versions before it can't read.
+	UidBitmaps bool
 }
 
 // WorkerConfig stores the global instance of the worker package's options.
@@ -122,4 +201,6 @@ func (w *WorkerOptions) Parse(conf *viper.Viper) {
 			"Invalid survival mode: %s", survive)
 		w.HardSync = survive == "filesystem"
 	}
+	w.WALSegmentSize = conf.GetInt64("wal_segment_mb") << 20
+	w.WALSegmentAge = conf.GetDuration("wal_segment_age")
 }
@@ -34,6 +34,8 @@ import (
 	"os"
 
 	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // Check logs fatal if err != nil.
@@ -119,3 +121,32 @@ func AssertTruefNoTrace(b bool, format string, args ...interface{}) {
 func Fatalf(format string, args ...interface{}) {
 	log.Fatalf("%+v", errors.Errorf(format, args...))
 }
+
+// invalidArgumentError is an error caused by an invalid input.
+type invalidArgumentError struct {
+	err error
+}
+
+func (e *invalidArgumentError) Error() string {
+	return e.err.Error()
+}
+
+// GRPCStatus lets gRPC report the error with the InvalidArgument code, so that it can still be
+// told apart from other errors once it has been sent over the network.
+func (e *invalidArgumentError) GRPCStatus() *status.Status {
+	return status.New(codes.InvalidArgument, e.err.Error())
+}
+
+// InvalidArgument marks err as caused by an invalid input. The message of err is kept as is.
+func InvalidArgument(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &invalidArgumentError{err: err}
+}
+
+// IsInvalidArgument returns true if err, or the error wrapped by it, has been caused by an invalid
+// input, either locally or on another server.
+func IsInvalidArgument(err error) bool {
+	return status.Code(errors.Cause(err)) == codes.InvalidArgument
+}
@@ -20,6 +20,11 @@ import (
 	"flag"
 	"os"
 	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestMain(m *testing.M) {
@@ -27,3 +32,20 @@ func TestMain(m *testing.M) {
 	flag.Set("debugmode", "true")
 	os.Exit(m.Run())
 }
+
+func TestInvalidArgument(t *testing.T) {
+	require.Nil(t, InvalidArgument(nil))
+	require.False(t, IsInvalidArgument(errors.New("oops")))
+
+	err := InvalidArgument(errors.New("oops"))
+	require.EqualError(t, err, "oops")
+	require.True(t, IsInvalidArgument(err))
+	require.True(t, IsInvalidArgument(errors.Wrapf(err, "while applying")))
+
+	// This is how the error reaches the server that sent the request over gRPC.
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	remote := st.Err()
+	require.True(t, IsInvalidArgument(remote))
+	require.Equal(t, codes.InvalidArgument, status.Code(remote))
+}
@@ -40,6 +40,15 @@ func FillCommonFlags(flag *pflag.FlagSet) {
 		of hard reboot. Most users should be OK with choosing "process".
 		`)
 
+	// WAL flags.
+	flag.Int64("wal_segment_mb", 1024,
+		"Size in MB of the data a file of the Raft write-ahead log can hold before a new one"+
+			" is started. Files are deleted whole once the entries they hold are snapshotted."+
+			" Set to 0 to disable.")
+	flag.Duration("wal_segment_age", 0,
+		"Age a file of the Raft write-ahead log can reach before a new one is started."+
+			" Set to 0 to disable.")
+
 	// Cache flags.
 	flag.Int64("cache_mb", 1024, "Total size of cache (in MB) to be used in Dgraph.")
 
@@ -40,6 +40,8 @@ const (
 	ByteCount = byte(0x08)
 	// ByteCountRev indicates the key stores a reverse count index.
 	ByteCountRev = ByteCount | ByteReverse
+	// ByteBlob indicates the key stores a chunk of a large value.
+	ByteBlob = byte(0x10)
 	// DefaultPrefix is the prefix used for data, index and reverse keys so that relative
 	// order of data doesn't change keys of same attributes are located together.
 	DefaultPrefix = byte(0x00)
@@ -191,6 +193,40 @@ func CountKey(attr string, count uint32, reverse bool) []byte {
 	return buf
 }
 
+// BlobKey generates a key storing one chunk of a large value.
+// The structure of a blob key is as follows:
+//
+// byte 0: key type prefix (set to DefaultPrefix)
+// byte 1-2: length of attr
+// next len(attr) bytes: value of attr
+// next byte: data type prefix (set to ByteBlob)
+// next len(id) bytes: id of the value the chunk belongs to
+// next four bytes: index of the chunk
+//
+// When parsed, the id is stored in the Term field and the index in the Count field.
+func BlobKey(attr string, id []byte, idx uint32) []byte {
+	prefixLen := 1 + 2 + len(attr)
+	totalLen := prefixLen + 1 + len(id) + 4
+	buf := generateKey(DefaultPrefix, attr, totalLen)
+
+	rest := buf[prefixLen:]
+	rest[0] = ByteBlob
+
+	rest = rest[1:]
+	AssertTrue(len(id) == copy(rest, id))
+
+	rest = rest[len(id):]
+	binary.BigEndian.PutUint32(rest, idx)
+	return buf
+}
+
+// BlobPrefix returns the prefix for all the chunks of the values whose id starts with the
+// given bytes.
+func BlobPrefix(attr string, id []byte) []byte {
+	key := BlobKey(attr, id, 0)
+	return key[:len(key)-4]
+}
+
 // ParsedKey represents a key that has been parsed into its multiple attributes.
 type ParsedKey struct {
 	ByteType    byte
@@ -233,6 +269,11 @@ func (p ParsedKey) IsIndex() bool {
 	return (p.bytePrefix == DefaultPrefix || p.bytePrefix == ByteSplit) && p.ByteType == ByteIndex
 }
 
+// IsBlob returns whether the key stores a chunk of a large value.
+func (p ParsedKey) IsBlob() bool {
+	return p.bytePrefix == DefaultPrefix && p.ByteType == ByteBlob
+}
+
 // IsSchema returns whether the key is a schema key.
 func (p ParsedKey) IsSchema() bool {
 	return p.bytePrefix == ByteSchema
@@ -254,6 +295,8 @@ func (p ParsedKey) IsOfType(typ byte) bool {
 		return p.IsIndex()
 	case ByteData:
 		return p.IsData()
+	case ByteBlob:
+		return p.IsBlob()
 	default:
 	}
 	return false
@@ -357,6 +400,8 @@ func (p ParsedKey) ToBackupKey() *pb.BackupKey {
 		key.Type = pb.BackupKey_SCHEMA
 	case p.IsType():
 		key.Type = pb.BackupKey_TYPE
+	case p.IsBlob():
+		key.Type = pb.BackupKey_BLOB
 	}
 	return &key
 }
@@ -383,6 +428,8 @@ func FromBackupKey(backupKey *pb.BackupKey) []byte {
 		key = SchemaKey(backupKey.Attr)
 	case pb.BackupKey_TYPE:
 		key = TypeKey(backupKey.Attr)
+	case pb.BackupKey_BLOB:
+		key = BlobKey(backupKey.Attr, []byte(backupKey.Term), backupKey.Count)
 	}
 
 	if backupKey.StartUid > 0 {
@@ -519,6 +566,12 @@ func Parse(key []byte) (ParsedKey, error) {
 
 		k = k[4:]
 		p.StartUid = binary.BigEndian.Uint64(k)
+	case ByteBlob:
+		if p.HasStartUid || len(k) < 4 {
+			return p, errors.Errorf("Invalid format for blob key: %q, parsed key: %+v", key, p)
+		}
+		p.Term = string(k[:len(k)-4])
+		p.Count = binary.BigEndian.Uint32(k[len(k)-4:])
 	default:
 		// Some other data type.
 		return p, errors.Errorf("Invalid data type")
@@ -546,6 +599,15 @@ var graphqlReservedPredicate = map[string]struct{}{
 	"dgraph.cors":                      {},
 	"dgraph.graphql.schema_history":    {},
 	"dgraph.graphql.schema_created_at": {},
+	"dgraph.graphql.p_query":           {},
+	"dgraph.graphql.p_sha256hash":      {},
+}
+
+// schemaHistoryPredicateMap stores the predicates of the history of the changes of the schema.
+var schemaHistoryPredicateMap = map[string]struct{}{
+	"dgraph.schema.change":     {},
+	"dgraph.schema.changed_by": {},
+	"dgraph.schema.changed_at": {},
 }
 
 // internalPredicateMap stores a set of Dgraph's internal predicate. An internal
@@ -556,11 +618,13 @@ var internalPredicateMap = map[string]struct{}{
 }
 
 var preDefinedTypeMap = map[string]struct{}{
-	"dgraph.graphql":         {},
-	"dgraph.type.User":       {},
-	"dgraph.type.Group":      {},
-	"dgraph.type.Rule":       {},
-	"dgraph.graphql.history": {},
+	"dgraph.graphql":                 {},
+	"dgraph.type.User":               {},
+	"dgraph.type.Group":              {},
+	"dgraph.type.Rule":               {},
+	"dgraph.graphql.history":         {},
+	"dgraph.graphql.persisted_query": {},
+	"dgraph.schema.history":          {},
 }
 
 // IsGraphqlReservedPredicate returns true if it is the predicate is reserved by graphql.
@@ -600,7 +664,15 @@ func IsReservedPredicate(pred string) bool {
 // Pre-defined predicates are subset of reserved predicates.
 func IsPreDefinedPredicate(pred string) bool {
 	_, ok := starAllPredicateMap[strings.ToLower(pred)]
-	return ok || IsAclPredicate(pred) || IsGraphqlReservedPredicate(pred)
+	return ok || IsAclPredicate(pred) || IsGraphqlReservedPredicate(pred) ||
+		IsSchemaHistoryPredicate(pred)
+}
+
+// IsSchemaHistoryPredicate returns true if the predicate is one of the predicates of the history
+// of the changes of the schema.
+func IsSchemaHistoryPredicate(pred string) bool {
+	_, ok := schemaHistoryPredicateMap[pred]
+	return ok
 }
 
 // IsAclPredicate returns true if the predicate is in the list of reserved
@@ -610,6 +682,38 @@ func IsAclPredicate(pred string) bool {
 	return ok
 }
 
+// IsEdgeProperty returns true if the predicate stores a property of the edges of another
+// predicate. Such predicates are named <edge>|<property>, like the facets they are built from.
+func IsEdgeProperty(pred string) bool {
+	return strings.Contains(pred, FacetDelimeter)
+}
+
+// EdgeProperty returns the name of the predicate storing the given property of the edges of
+// the given predicate.
+func EdgeProperty(edge, property string) string {
+	return edge + FacetDelimeter + property
+}
+
+// ParseEdgeProperty returns the predicate and the facet key an edge property is stored for.
+func ParseEdgeProperty(pred string) (edge, property string) {
+	idx := strings.Index(pred, FacetDelimeter)
+	if idx < 0 {
+		return pred, ""
+	}
+	return pred[:idx], pred[idx+1:]
+}
+
+// IsCompositeIndex returns true if the predicate is a composite index, indexing the values of
+// an ordered tuple of predicates of the same node. Such predicates are named <pred1^pred2^...>.
+func IsCompositeIndex(pred string) bool {
+	return strings.Contains(pred, CompositeDelimiter)
+}
+
+// CompositeIndexPredicates returns the predicates a composite index spans, in order.
+func CompositeIndexPredicates(pred string) []string {
+	return strings.Split(pred, CompositeDelimiter)
+}
+
 // StarAllPredicates returns the complete list of pre-defined predicates that needs to
 // be expanded when * is given as a predicate.
 func StarAllPredicates() []string {
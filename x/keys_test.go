@@ -17,6 +17,7 @@
 package x
 
 import (
+	"bytes"
 	"fmt"
 	"math"
 	"sort"
@@ -188,6 +189,27 @@ func TestCountKeyWithStartUid(t *testing.T) {
 	}
 }
 
+func TestBlobKey(t *testing.T) {
+	hash := []byte("0123456789abcdef0123456789abcdef")
+	var idx uint32
+	for idx = 0; idx < 1001; idx++ {
+		sattr := fmt.Sprintf("attr:%d", idx)
+
+		key := BlobKey(sattr, hash, idx)
+		require.True(t, bytes.HasPrefix(key, BlobPrefix(sattr, hash)))
+		require.True(t, bytes.HasPrefix(key, PredicatePrefix(sattr)))
+		pk, err := Parse(key)
+		require.NoError(t, err)
+
+		require.True(t, pk.IsBlob())
+		require.False(t, pk.IsData())
+		require.Equal(t, sattr, pk.Attr)
+		require.Equal(t, string(hash), pk.Term)
+		require.Equal(t, idx, pk.Count)
+		require.Equal(t, key, FromBackupKey(pk.ToBackupKey()))
+	}
+}
+
 func TestSchemaKey(t *testing.T) {
 	var uid uint64
 	for uid = 0; uid < 1001; uid++ {
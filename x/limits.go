@@ -0,0 +1,121 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package x
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// ErrorLimitExceeded is the error code returned when a request goes over one of its
+	// per-query limits.
+	ErrorLimitExceeded = "ErrorLimitExceeded"
+
+	// QueryTimeoutKey is the gRPC metadata key used to override the execution timeout of a
+	// query. The value must be a valid Go duration string, e.g. "500ms".
+	QueryTimeoutKey = "query-timeout"
+	// QueryMaxNodesKey is the gRPC metadata key used to override the maximum number of
+	// nodes and edges that a query is allowed to return.
+	QueryMaxNodesKey = "query-max-nodes"
+	// QueryMaxDepthKey is the gRPC metadata key used to override the maximum traversal
+	// depth of a query.
+	QueryMaxDepthKey = "query-max-depth"
+
+	// LimitTimeout names the execution timeout limit. Its values are in milliseconds.
+	LimitTimeout = "timeout"
+	// LimitMaxNodes names the limit on the number of returned nodes and edges.
+	LimitMaxNodes = "max_nodes"
+	// LimitMaxDepth names the limit on the traversal depth of a query.
+	LimitMaxDepth = "max_depth"
+	// LimitCost names the budget on the estimated cost of a query.
+	LimitCost = "cost"
+	// LimitMemory names the memory budget shared by the queries. Its values are in MB.
+	LimitMemory = "memory"
+)
+
+// queryLimitHeaders maps the HTTP headers that can carry per-query limits to their
+// corresponding gRPC metadata keys.
+var queryLimitHeaders = map[string]string{
+	"X-Dgraph-Query-Timeout":   QueryTimeoutKey,
+	"X-Dgraph-Query-Max-Nodes": QueryMaxNodesKey,
+	"X-Dgraph-Query-Max-Depth": QueryMaxDepthKey,
+}
+
+// LimitExceededError is returned when a request goes over one of its limits. Limit is
+// one of LimitTimeout, LimitMaxNodes, LimitMaxDepth, LimitCost or LimitMemory.
+type LimitExceededError struct {
+	Limit string
+	Max   uint64
+}
+
+func (e *LimitExceededError) Error() string {
+	switch e.Limit {
+	case LimitCost:
+		return fmt.Sprintf("Query exceeded the estimated cost budget of %d", e.Max)
+	case LimitTimeout:
+		return fmt.Sprintf("Query exceeded the %s limit of %dms", e.Limit, e.Max)
+	case LimitMemory:
+		return fmt.Sprintf("Query exceeded the %s limit of %dMB", e.Limit, e.Max)
+	default:
+		return fmt.Sprintf("Query exceeded the %s limit of %d", e.Limit, e.Max)
+	}
+}
+
+// GRPCStatus lets gRPC report the error to clients with the ResourceExhausted code.
+func (e *LimitExceededError) GRPCStatus() *status.Status {
+	return status.New(codes.ResourceExhausted, e.Error())
+}
+
+// Extensions returns the fields to be reported in the extensions of an HTTP error.
+func (e *LimitExceededError) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"code":  ErrorLimitExceeded,
+		"limit": e.Limit,
+		"max":   e.Max,
+	}
+}
+
+// AttachQueryLimits adds any per-query limit headers into the grpc context metadata.
+func AttachQueryLimits(ctx context.Context, r *http.Request) context.Context {
+	for header, key := range queryLimitHeaders {
+		val := r.Header.Get(header)
+		if val == "" {
+			continue
+		}
+		md, ok := metadata.FromIncomingContext(ctx)
+		if !ok {
+			md = metadata.New(nil)
+		}
+		md.Set(key, val)
+		ctx = metadata.NewIncomingContext(ctx, md)
+	}
+	return ctx
+}
+
+// SetStatusLimitExceeded writes the given limit error to the HTTP response, including the
+// limit details in the error extensions.
+func SetStatusLimitExceeded(w http.ResponseWriter, lerr *LimitExceededError) {
+	var qr QueryResWithData
+	qr.Errors = append(qr.Errors, &GqlError{Message: lerr.Error(), Extensions: lerr.Extensions()})
+	Reply(w, qr)
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package x
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// The intermediate results of the queries being run, the postings they read, the values they
+// sort and the groups they form, are accounted for against a memory budget shared by all the
+// queries of the Alpha. A query needing more than what's left of the budget spills its sorts to
+// disk, and fails with a LimitExceededError for what can't be spilled, rather than running the
+// Alpha out of memory.
+
+// queryMemoryUsed is the memory reserved by the queries being run. Accessed atomically.
+var queryMemoryUsed int64
+
+type memoryAccountKey struct{}
+
+// MemoryAccount holds the memory a query has reserved from the budget, until it's closed.
+type MemoryAccount struct {
+	used int64 // Accessed atomically.
+}
+
+// WithMemoryAccount returns a context carrying a new memory account. The account must be closed
+// once the query is done.
+func WithMemoryAccount(ctx context.Context) (context.Context, *MemoryAccount) {
+	a := &MemoryAccount{}
+	return context.WithValue(ctx, memoryAccountKey{}, a), a
+}
+
+// MemoryAccountFrom returns the memory account of the context, or nil if it has none. A nil
+// account doesn't account for anything.
+func MemoryAccountFrom(ctx context.Context) *MemoryAccount {
+	a, _ := ctx.Value(memoryAccountKey{}).(*MemoryAccount)
+	return a
+}
+
+// Reserve reserves n bytes of the budget, and returns false if that would go over it.
+func (a *MemoryAccount) Reserve(n int64) bool {
+	if a == nil || n <= 0 {
+		return true
+	}
+	used := atomic.AddInt64(&queryMemoryUsed, n)
+	if max := Config.QueryMemoryLimit; max > 0 && used > max {
+		atomic.AddInt64(&queryMemoryUsed, -n)
+		return false
+	}
+	atomic.AddInt64(&a.used, n)
+	return true
+}
+
+// Charge reserves n bytes of the budget, like Reserve, and returns a LimitExceededError if that
+// would go over it.
+func (a *MemoryAccount) Charge(n int64) error {
+	if a.Reserve(n) {
+		return nil
+	}
+	return &LimitExceededError{Limit: LimitMemory, Max: uint64(Config.QueryMemoryLimit >> 20)}
+}
+
+// Release gives n bytes reserved by the account back to the budget.
+func (a *MemoryAccount) Release(n int64) {
+	if a == nil || n <= 0 {
+		return
+	}
+	atomic.AddInt64(&a.used, -n)
+	atomic.AddInt64(&queryMemoryUsed, -n)
+}
+
+// Close gives everything reserved by the account back to the budget.
+func (a *MemoryAccount) Close() {
+	if a == nil {
+		return
+	}
+	atomic.AddInt64(&queryMemoryUsed, -atomic.SwapInt64(&a.used, 0))
+}
+
+// QueryMemoryUsed returns the memory reserved by the queries being run.
+func QueryMemoryUsed() int64 {
+	return atomic.LoadInt64(&queryMemoryUsed)
+}
@@ -0,0 +1,61 @@
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package x
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryAccount(t *testing.T) {
+	defer func(limit int64) { Config.QueryMemoryLimit = limit }(Config.QueryMemoryLimit)
+	Config.QueryMemoryLimit = 1 << 20
+
+	require.Nil(t, MemoryAccountFrom(context.Background()))
+	var none *MemoryAccount
+	require.NoError(t, none.Charge(2<<20))
+	none.Release(1)
+	none.Close()
+
+	_, a := WithMemoryAccount(context.Background())
+	ctx, b := WithMemoryAccount(context.Background())
+	require.Equal(t, b, MemoryAccountFrom(ctx))
+
+	require.NoError(t, a.Charge(512<<10))
+	require.True(t, b.Reserve(256<<10))
+	require.Equal(t, int64(768<<10), QueryMemoryUsed())
+
+	// The budget is shared by the accounts.
+	require.False(t, b.Reserve(512<<10))
+	err := b.Charge(512 << 10)
+	require.Equal(t, &LimitExceededError{Limit: LimitMemory, Max: 1}, err)
+	require.Equal(t, "Query exceeded the memory limit of 1MB", err.Error())
+
+	b.Release(128 << 10)
+	require.Equal(t, int64(640<<10), QueryMemoryUsed())
+	a.Close()
+	require.NoError(t, b.Charge(512<<10))
+	b.Close()
+	require.Zero(t, QueryMemoryUsed())
+
+	Config.QueryMemoryLimit = 0
+	require.NoError(t, a.Charge(1<<30))
+	a.Close()
+	require.Zero(t, QueryMemoryUsed())
+}
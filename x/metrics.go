@@ -91,6 +91,9 @@ var (
 	// MaxAssignedTs records the latest max assigned timestamp.
 	MaxAssignedTs = stats.Int64("max_assigned_ts",
 		"Latest max assigned timestamp", stats.UnitDimensionless)
+	// TTLExpired records the number of nodes whose expired data was deleted.
+	TTLExpired = stats.Int64("ttl_expired_total",
+		"Number of nodes whose expired data was deleted", stats.UnitDimensionless)
 	// TxnAborts records count of aborted transactions.
 	TxnAborts = stats.Int64("txn_aborts_total",
 		"Number of transaction aborts", stats.UnitDimensionless)
@@ -103,6 +106,46 @@ var (
 	// PLCacheHitRatio records the hit ratio of posting list cache.
 	PLCacheHitRatio = stats.Float64("hit_ratio_posting_cache",
 		"Hit ratio of posting list cache", stats.UnitDimensionless)
+	// PLCacheHits records the number of lists of a predicate found in the posting list cache.
+	PLCacheHits = stats.Int64("posting_cache_hits_total",
+		"Number of lists of a predicate found in posting list cache", stats.UnitDimensionless)
+	// PLCacheMisses records the number of lists of a predicate not found in the posting list cache.
+	PLCacheMisses = stats.Int64("posting_cache_misses_total",
+		"Number of lists of a predicate not found in posting list cache", stats.UnitDimensionless)
+	// PLCacheEvictions records the number of lists of a predicate evicted from the posting list
+	// cache.
+	PLCacheEvictions = stats.Int64("posting_cache_evictions_total",
+		"Number of lists of a predicate evicted from posting list cache", stats.UnitDimensionless)
+
+	// QueryMemory records the memory reserved by the queries being run.
+	QueryMemory = stats.Int64("query_memory_bytes",
+		"Memory reserved by the queries being run", stats.UnitBytes)
+	// QuerySpills records the number of sorts spilled to disk for going over the query memory
+	// budget.
+	QuerySpills = stats.Int64("query_spills_total",
+		"Number of sorts spilled to disk", stats.UnitDimensionless)
+
+	// PageCacheResident records the bytes of the files of the postings that are in the page cache.
+	PageCacheResident = stats.Int64("page_cache_resident_bytes",
+		"Bytes of the posting files in the page cache", stats.UnitBytes)
+	// PostingFilesSize records the bytes the files of the postings take on disk.
+	PostingFilesSize = stats.Int64("posting_files_bytes",
+		"Bytes the posting files take on disk", stats.UnitBytes)
+	// NumMajorPageFaults records the number of page faults that had to read from the disk.
+	NumMajorPageFaults = stats.Int64("major_page_faults_total",
+		"Number of page faults that read from the disk", stats.UnitDimensionless)
+
+	// ReplicationLag records the seconds between the last replication of a replica cluster and now.
+	ReplicationLag = stats.Float64("replication_lag_seconds",
+		"Seconds since the data of the primary cluster was last replicated", "s")
+	// ReplicatedKeys records the number of keys written by the replication of a replica cluster.
+	ReplicatedKeys = stats.Int64("replication_keys_total",
+		"Number of keys replicated from the primary cluster", stats.UnitDimensionless)
+
+	// DeadNodes records the number of Alphas unreachable from the Zero leader for longer than
+	// --dead_node_timeout.
+	DeadNodes = stats.Int64("dead_nodes",
+		"Number of Alphas found dead by the Zero leader", stats.UnitDimensionless)
 
 	// Conf holds the metrics config.
 	// TODO: Request statistics, latencies, 500, timeouts
@@ -114,6 +157,10 @@ var (
 	KeyStatus, _ = tag.NewKey("status")
 	// KeyMethod is the tag key used to record the method (e.g read or mutate).
 	KeyMethod, _ = tag.NewKey("method")
+	// KeyPredicate is the tag key used to record the predicate of per-predicate metrics.
+	KeyPredicate, _ = tag.NewKey("predicate")
+	// KeyFileType is the tag key used to record the type of the posting files, sst or vlog.
+	KeyFileType, _ = tag.NewKey("file_type")
 
 	// Tag values.
 
@@ -169,6 +216,13 @@ var (
 			Aggregation: view.Count(),
 			TagKeys:     allTagKeys,
 		},
+		{
+			Name:        TTLExpired.Name(),
+			Measure:     TTLExpired,
+			Description: TTLExpired.Description(),
+			Aggregation: view.Sum(),
+			TagKeys:     allTagKeys,
+		},
 		{
 			Name:        TxnAborts.Name(),
 			Measure:     TxnAborts,
@@ -248,6 +302,83 @@ var (
 			Aggregation: view.LastValue(),
 			TagKeys:     allTagKeys,
 		},
+		{
+			Name:        PLCacheHits.Name(),
+			Measure:     PLCacheHits,
+			Description: PLCacheHits.Description(),
+			Aggregation: view.LastValue(),
+			TagKeys:     []tag.Key{KeyPredicate},
+		},
+		{
+			Name:        PLCacheMisses.Name(),
+			Measure:     PLCacheMisses,
+			Description: PLCacheMisses.Description(),
+			Aggregation: view.LastValue(),
+			TagKeys:     []tag.Key{KeyPredicate},
+		},
+		{
+			Name:        PLCacheEvictions.Name(),
+			Measure:     PLCacheEvictions,
+			Description: PLCacheEvictions.Description(),
+			Aggregation: view.LastValue(),
+			TagKeys:     []tag.Key{KeyPredicate},
+		},
+		{
+			Name:        QueryMemory.Name(),
+			Measure:     QueryMemory,
+			Description: QueryMemory.Description(),
+			Aggregation: view.LastValue(),
+			TagKeys:     allTagKeys,
+		},
+		{
+			Name:        QuerySpills.Name(),
+			Measure:     QuerySpills,
+			Description: QuerySpills.Description(),
+			Aggregation: view.Count(),
+			TagKeys:     allTagKeys,
+		},
+		{
+			Name:        PageCacheResident.Name(),
+			Measure:     PageCacheResident,
+			Description: PageCacheResident.Description(),
+			Aggregation: view.LastValue(),
+			TagKeys:     []tag.Key{KeyFileType},
+		},
+		{
+			Name:        ReplicationLag.Name(),
+			Measure:     ReplicationLag,
+			Description: ReplicationLag.Description(),
+			Aggregation: view.LastValue(),
+			TagKeys:     nil,
+		},
+		{
+			Name:        ReplicatedKeys.Name(),
+			Measure:     ReplicatedKeys,
+			Description: ReplicatedKeys.Description(),
+			Aggregation: view.Sum(),
+			TagKeys:     nil,
+		},
+		{
+			Name:        DeadNodes.Name(),
+			Measure:     DeadNodes,
+			Description: DeadNodes.Description(),
+			Aggregation: view.LastValue(),
+			TagKeys:     nil,
+		},
+		{
+			Name:        PostingFilesSize.Name(),
+			Measure:     PostingFilesSize,
+			Description: PostingFilesSize.Description(),
+			Aggregation: view.LastValue(),
+			TagKeys:     []tag.Key{KeyFileType},
+		},
+		{
+			Name:        NumMajorPageFaults.Name(),
+			Measure:     NumMajorPageFaults,
+			Description: NumMajorPageFaults.Description(),
+			Aggregation: view.LastValue(),
+			TagKeys:     nil,
+		},
 	}
 )
 
@@ -465,7 +596,8 @@ func MonitorMemoryMetrics(lc *z.Closer) {
 		ostats.Record(context.Background(),
 			MemoryInUse.M(int64(inUse)),
 			MemoryIdle.M(int64(idle)),
-			MemoryProc.M(int64(getMemUsage())))
+			MemoryProc.M(int64(getMemUsage())),
+			QueryMemory.M(QueryMemoryUsed()))
 	}
 	// Call update immediately so that Dgraph reports memory stats without
 	// having to wait for the first tick.
@@ -0,0 +1,115 @@
+// +build linux
+
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package x
+
+import (
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// PageCacheResidency returns the bytes the file at path takes on disk, and how many of its bytes
+// are in the page cache. The files of the postings are preallocated, so that their size can be
+// much larger than the data written to them.
+func PageCacheResidency(path string) (resident, size int64, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	var st unix.Stat_t
+	if err := unix.Fstat(int(f.Fd()), &st); err != nil || st.Size == 0 {
+		return 0, 0, err
+	}
+	size = st.Size
+
+	// Mapping the file doesn't read it, mincore only tells which of its pages are cached.
+	data, err := unix.Mmap(int(f.Fd()), 0, int(size), unix.PROT_READ, unix.MAP_SHARED)
+	if err != nil {
+		return 0, size, err
+	}
+	defer func() {
+		_ = unix.Munmap(data)
+	}()
+	pageSize := int64(os.Getpagesize())
+	vec := make([]byte, (size+pageSize-1)/pageSize)
+	if _, _, errno := unix.Syscall(unix.SYS_MINCORE, uintptr(unsafe.Pointer(&data[0])),
+		uintptr(len(data)), uintptr(unsafe.Pointer(&vec[0]))); errno != 0 {
+		return 0, size, errno
+	}
+	for i, v := range vec {
+		if v&1 == 0 {
+			continue
+		}
+		if i == len(vec)-1 {
+			resident += size - int64(i)*pageSize
+		} else {
+			resident += pageSize
+		}
+	}
+	if allocated := st.Blocks * 512; allocated < size {
+		size = allocated
+	}
+	return resident, size, nil
+}
+
+// The whence of lseek to find the data and the holes of a file, missing from x/sys/unix.
+const (
+	seekData = 3
+	seekHole = 4
+)
+
+// PrefetchFile asks the kernel to read the data of the file at path into the page cache, in the
+// background. The holes of the file aren't read.
+func PrefetchFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fd := int(f.Fd())
+	for off := int64(0); ; {
+		start, err := unix.Seek(fd, off, seekData)
+		if err == unix.ENXIO {
+			// There's no data past off.
+			return nil
+		} else if err != nil {
+			return err
+		}
+		end, err := unix.Seek(fd, start, seekHole)
+		if err != nil {
+			return err
+		}
+		if err := unix.Fadvise(fd, start, end-start, unix.FADV_WILLNEED); err != nil {
+			return err
+		}
+		off = end
+	}
+}
+
+// MajorPageFaults returns the number of page faults of the process that had to read from the
+// disk, which for the memory-mapped files of the postings are reads missing the page cache.
+func MajorPageFaults() int64 {
+	var ru unix.Rusage
+	if err := unix.Getrusage(unix.RUSAGE_SELF, &ru); err != nil {
+		return 0
+	}
+	return ru.Majflt
+}
@@ -0,0 +1,63 @@
+// +build linux
+
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package x
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPageCacheResidency(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pagecache")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// A file just written is in the page cache, including its last partial page.
+	path := filepath.Join(dir, "000001.sst")
+	require.NoError(t, ioutil.WriteFile(path, make([]byte, 3*os.Getpagesize()+100), 0600))
+	resident, size, err := PageCacheResidency(path)
+	require.NoError(t, err)
+	require.Equal(t, int64(3*os.Getpagesize()+100), size)
+	require.Equal(t, size, resident)
+	require.NoError(t, PrefetchFile(path))
+
+	empty := filepath.Join(dir, "000002.sst")
+	require.NoError(t, ioutil.WriteFile(empty, nil, 0600))
+	resident, size, err = PageCacheResidency(empty)
+	require.NoError(t, err)
+	require.Zero(t, resident)
+	require.Zero(t, size)
+
+	// Only the bytes allocated to a preallocated file are counted, and its holes aren't read.
+	sparse := filepath.Join(dir, "000003.vlog")
+	require.NoError(t, ioutil.WriteFile(sparse, nil, 0600))
+	require.NoError(t, os.Truncate(sparse, 1<<20))
+	require.NoError(t, PrefetchFile(sparse))
+	resident, size, err = PageCacheResidency(sparse)
+	require.NoError(t, err)
+	require.Zero(t, resident)
+	require.Zero(t, size)
+
+	_, _, err = PageCacheResidency(filepath.Join(dir, "missing.sst"))
+	require.Error(t, err)
+}
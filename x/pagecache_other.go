@@ -0,0 +1,43 @@
+// +build !linux
+
+/*
+ * Copyright 2020 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package x
+
+import (
+	"github.com/pkg/errors"
+)
+
+var errPageCacheUnsupported = errors.New("the page cache can only be inspected on Linux")
+
+// PageCacheResidency returns the bytes the file at path takes on disk, and how many of its bytes
+// are in the page cache.
+func PageCacheResidency(path string) (resident, size int64, err error) {
+	return 0, 0, errPageCacheUnsupported
+}
+
+// PrefetchFile asks the kernel to read the data of the file at path into the page cache, in the
+// background.
+func PrefetchFile(path string) error {
+	return errPageCacheUnsupported
+}
+
+// MajorPageFaults returns the number of page faults of the process that had to read from the
+// disk, which for the memory-mapped files of the postings are reads missing the page cache.
+func MajorPageFaults() int64 {
+	return 0
+}
@@ -40,12 +40,10 @@ import (
 
 	"google.golang.org/grpc/peer"
 
-	"github.com/dgraph-io/badger/v2"
 	bo "github.com/dgraph-io/badger/v2/options"
 	"github.com/dgraph-io/dgo/v200"
 	"github.com/dgraph-io/dgo/v200/protos/api"
 	"github.com/dgraph-io/ristretto/z"
-	"github.com/dustin/go-humanize"
 
 	"github.com/golang/glog"
 	"github.com/pkg/errors"
@@ -82,6 +80,29 @@ const (
 	Error = "Error"
 	// ErrorNoData is an error returned when the requested data cannot be returned.
 	ErrorNoData = "ErrorNoData"
+	// ErrorValueMismatch is returned when a compare and set finds a value other than the
+	// expected one.
+	ErrorValueMismatch = "ErrorValueMismatch"
+	// ErrorMutationRejected is returned when a pre-commit hook rejects a mutation.
+	ErrorMutationRejected = "ErrorMutationRejected"
+	// ErrorStrictSchema is returned when a mutation uses predicates or types that aren't
+	// defined in the schema while the strict schema mode is enabled.
+	ErrorStrictSchema = "ErrorStrictSchema"
+	// ErrorRequiredPredicate is returned when a mutation gives a type to a node without a value
+	// for a predicate the type requires.
+	ErrorRequiredPredicate = "ErrorRequiredPredicate"
+	// ErrorValueCheck is returned when a mutation sets values that don't satisfy the
+	// constraints of the @check directives of their predicates.
+	ErrorValueCheck = "ErrorValueCheck"
+	// ErrorReference is returned when a mutation points to a node that doesn't exist with a
+	// predicate with @references, or deletes a node that such a predicate still points to.
+	ErrorReference = "ErrorReference"
+	// IdempotencyKey is the gRPC metadata key with which clients pass the idempotency key of
+	// a commit.
+	IdempotencyKey = "idempotency-key"
+	// IdempotencyRecordKey is the gRPC metadata key with which Alpha passes to Zero the record
+	// of a commit made with an idempotency key.
+	IdempotencyRecordKey = "idempotency-record-bin"
 	// ValidHostnameRegex is a regex that accepts our expected hostname format.
 	ValidHostnameRegex = "^(([a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9\\-]*[a-zA-Z0-9])\\.)*([A-Za-z0-9]" +
 		"|[A-Za-z0-9][A-Za-z0-9\\-]*[A-Za-z0-9])$"
@@ -112,6 +133,9 @@ const (
 	// FacetDelimeter is the symbol used to distinguish predicate names from facets.
 	FacetDelimeter = "|"
 
+	// CompositeDelimiter separates the predicates a composite index spans in its name.
+	CompositeDelimiter = "^"
+
 	// GrootId is the ID of the admin user for ACLs.
 	GrootId = "groot"
 	// GuardiansId is the ID of the admin group for ACLs.
@@ -146,6 +170,12 @@ const (
 }, {
 	"fields": [{"name": "dgraph.graphql.schema_history"},{"name": "dgraph.graphql.schema_created_at"}],
 	"name": "dgraph.graphql.history"
+}, {
+	"fields": [{"name": "dgraph.graphql.p_query"},{"name": "dgraph.graphql.p_sha256hash"}],
+	"name": "dgraph.graphql.persisted_query"
+}, {
+	"fields": [{"name": "dgraph.schema.change"},{"name": "dgraph.schema.changed_by"},{"name": "dgraph.schema.changed_at"}],
+	"name": "dgraph.schema.history"
 }]`
 
 	// GroupIdFileName is the name of the file storing the ID of the group to which
@@ -155,16 +185,32 @@ const (
 	GroupIdFileName = "group_id"
 
 	AccessControlAllowedHeaders = "X-Dgraph-AccessToken, " +
+		"X-Dgraph-Query-Timeout, X-Dgraph-Query-Max-Nodes, X-Dgraph-Query-Max-Depth, " +
 		"Content-Type, Content-Length, Accept-Encoding, Cache-Control, " +
 		"X-CSRF-Token, X-Auth-Token, X-Requested-With"
 	DgraphCostHeader = "Dgraph-TouchedUids"
+	// DgraphMetricsHeader is the gRPC header carrying the detailed metrics of a request as JSON.
+	// It's a binary header, so that gRPC encodes it and predicate names can be non-ASCII.
+	DgraphMetricsHeader = "Dgraph-Detailed-Metrics-Bin"
+	// DgraphReportHeader is the gRPC header carrying the mutation report of a request as JSON.
+	DgraphReportHeader = "Dgraph-Mutation-Report"
 
 	// GraphqlPredicates is the json representation of the predicate reserved for graphql system.
 	GraphqlPredicates = `
 {"predicate":"dgraph.graphql.schema", "type": "string"},
 {"predicate":"dgraph.graphql.schema_history", "type": "string"},
 {"predicate":"dgraph.graphql.schema_created_at", "type": "datetime"},
-{"predicate":"dgraph.graphql.xid","type":"string","index":true,"tokenizer":["exact"],"upsert":true}
+{"predicate":"dgraph.graphql.xid","type":"string","index":true,"tokenizer":["exact"],"upsert":true},
+{"predicate":"dgraph.graphql.p_query","type":"string"},
+{"predicate":"dgraph.graphql.p_sha256hash","type":"string","index":true,"tokenizer":["exact"],"upsert":true}
+`
+
+	// SchemaHistoryPredicates is the json representation of the predicates of the history of the
+	// changes of the schema.
+	SchemaHistoryPredicates = `
+{"predicate":"dgraph.schema.change","type":"string"},
+{"predicate":"dgraph.schema.changed_by","type":"string"},
+{"predicate":"dgraph.schema.changed_at","type":"datetime"}
 `
 )
 
@@ -448,6 +494,20 @@ func AttachAccessJwt(ctx context.Context, r *http.Request) context.Context {
 	return ctx
 }
 
+// AttachIdempotencyKey adds the idempotency key header, if any, into the grpc context metadata.
+func AttachIdempotencyKey(ctx context.Context, r *http.Request) context.Context {
+	if key := r.Header.Get("X-Dgraph-IdempotencyKey"); key != "" {
+		md, ok := metadata.FromIncomingContext(ctx)
+		if !ok {
+			md = metadata.New(nil)
+		}
+
+		md.Set(IdempotencyKey, key)
+		ctx = metadata.NewIncomingContext(ctx, md)
+	}
+	return ctx
+}
+
 // AttachRemoteIP adds any incoming IP data into the grpc context metadata
 func AttachRemoteIP(ctx context.Context, r *http.Request) context.Context {
 	if ip, port, err := net.SplitHostPort(r.RemoteAddr); err == nil {
@@ -1049,42 +1109,6 @@ func IsGuardian(groups []string) bool {
 	return false
 }
 
-// RunVlogGC runs value log gc on store. It runs GC unconditionally after every 10 minutes.
-// Additionally it also runs GC if vLogSize has grown more than 1 GB in last minute.
-func RunVlogGC(store *badger.DB, closer *z.Closer) {
-	defer closer.Done()
-
-	// Runs every 1m, checks size of vlog and runs GC conditionally.
-	ticker := time.NewTicker(1 * time.Minute)
-	defer ticker.Stop()
-
-	runGC := func() {
-		_, before := store.Size()
-		var runs int
-		for err := error(nil); err == nil; {
-			// If a GC is successful, immediately run it again.
-			runs++
-			err = store.RunValueLogGC(0.7)
-		}
-		if runs == 0 {
-			return
-		}
-		_, after := store.Size()
-		glog.V(2).Infof("Ran Value log GC %d times. Before: %s After: %s",
-			runs, humanize.IBytes(uint64(before)), humanize.IBytes(uint64(after)))
-	}
-
-	runGC()
-	for {
-		select {
-		case <-closer.HasBeenClosed():
-			return
-		case <-ticker.C:
-			runGC()
-		}
-	}
-}
-
 type DB interface {
 	Sync() error
 }